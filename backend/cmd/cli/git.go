@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// zeroSHA is sent by git for refs that are created or deleted
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+// reviewRange is a set of commits to review: the changes of head since base
+type reviewRange struct {
+	base string
+	head string
+	ref  string
+}
+
+// git runs a git command and returns its trimmed output
+func git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// defaultBase returns the upstream of the current branch, falling back to origin/HEAD
+func defaultBase() string {
+	for _, ref := range []string{"@{upstream}", "origin/HEAD"} {
+		cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", ref)
+		if err := cmd.Run(); err == nil {
+			return ref
+		}
+	}
+	return ""
+}
+
+// currentRef returns the full ref of the checked out branch
+func currentRef() string {
+	ref, err := git("symbolic-ref", "--quiet", "HEAD")
+	if err != nil {
+		return "HEAD"
+	}
+	return ref
+}
+
+// parsePrePush reads the "<local ref> <local sha> <remote ref> <remote sha>" lines
+// git passes to pre-push hooks. Deleted refs are skipped, new branches are
+// compared against newBase.
+func parsePrePush(r io.Reader, newBase string) ([]reviewRange, error) {
+	var ranges []reviewRange
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected line %q", scanner.Text())
+		}
+		localSHA, remoteRef, remoteSHA := fields[1], fields[2], fields[3]
+		if localSHA == zeroSHA {
+			continue
+		}
+
+		base := remoteSHA
+		if remoteSHA == zeroSHA {
+			if newBase == "" {
+				continue
+			}
+			base = newBase
+		}
+		ranges = append(ranges, reviewRange{base: base, head: localSHA, ref: remoteRef})
+	}
+	return ranges, scanner.Err()
+}
+
+// normalizeRemoteURL converts a git remote URL to the web URL CodeSentry stores for the project,
+// e.g. git@gitlab.example.com:group/project.git -> https://gitlab.example.com/group/project
+func normalizeRemoteURL(remote string) string {
+	url := strings.TrimSuffix(strings.TrimSpace(remote), "/")
+	url = strings.TrimSuffix(url, ".git")
+
+	switch {
+	case strings.HasPrefix(url, "ssh://"):
+		url = strings.TrimPrefix(url, "ssh://")
+		if i := strings.Index(url, "@"); i >= 0 {
+			url = url[i+1:]
+		}
+		// Drop the SSH port, the web UI is served on the default port
+		if slash := strings.Index(url, "/"); slash >= 0 {
+			if colon := strings.Index(url[:slash], ":"); colon >= 0 {
+				url = url[:colon] + url[slash:]
+			}
+		}
+		return "https://" + url
+	case strings.Contains(url, "://"):
+		// Strip credentials from http(s) URLs
+		scheme, rest, _ := strings.Cut(url, "://")
+		if at := strings.Index(rest, "@"); at >= 0 && at < strings.Index(rest+"/", "/") {
+			rest = rest[at+1:]
+		}
+		return scheme + "://" + rest
+	case strings.Contains(url, ":"):
+		// scp-like syntax: user@host:path
+		if i := strings.Index(url, "@"); i >= 0 {
+			url = url[i+1:]
+		}
+		return "https://" + strings.Replace(url, ":", "/", 1)
+	}
+	return url
+}
+
+const hookScript = `#!/bin/sh
+# Installed by codesentry-cli: review changes with CodeSentry before pushing.
+# Skip once with: git push --no-verify
+exec codesentry-cli pre-push "$@"
+`
+
+// installHook writes a pre-push hook that runs codesentry-cli
+func installHook(force bool) (string, error) {
+	hooksDir, err := git("rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(hooksDir, "pre-push")
+	if _, err := os.Stat(path); err == nil && !force {
+		return "", fmt.Errorf("%s already exists, use -force to overwrite", path)
+	}
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(hookScript), 0o755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
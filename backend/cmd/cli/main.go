@@ -0,0 +1,193 @@
+// Command codesentry-cli reviews local changes with CodeSentry before they are pushed.
+//
+// It computes the diff with git, sends it to POST /api/review/sync using the
+// project's webhook secret as API key and prints the result. The exit code is
+// 0 when the review passed, 1 when it failed and 2 on errors, so it can be used
+// directly as a git pre-push hook:
+//
+//	codesentry-cli install-hook
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/huangang/codesentry/backend/pkg/client"
+)
+
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitError  = 2
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  codesentry-cli review [-base ref] [-warn-only] [-fail-open]")
+	fmt.Fprintln(os.Stderr, "  codesentry-cli pre-push [-warn-only] [-fail-open]    (reads git pre-push input from stdin)")
+	fmt.Fprintln(os.Stderr, "  codesentry-cli install-hook [-force]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Environment:")
+	fmt.Fprintln(os.Stderr, "  CODESENTRY_URL      server URL (default http://localhost:8080)")
+	fmt.Fprintln(os.Stderr, "  CODESENTRY_API_KEY  webhook secret of the project")
+	fmt.Fprintln(os.Stderr, "  CODESENTRY_TIMEOUT  review timeout in seconds (default 180)")
+	fmt.Fprintln(os.Stderr, "  NO_COLOR            disable colored output")
+	os.Exit(exitError)
+}
+
+// options are shared by the review and pre-push commands
+type options struct {
+	serverURL  string
+	apiKey     string
+	projectURL string
+	timeout    time.Duration
+	warnOnly   bool
+	failOpen   bool
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	timeout, _ := strconv.Atoi(os.Getenv("CODESENTRY_TIMEOUT"))
+	if timeout <= 0 {
+		timeout = 180
+	}
+	fs.StringVar(&o.serverURL, "url", envOr("CODESENTRY_URL", "http://localhost:8080"), "CodeSentry server URL")
+	fs.StringVar(&o.apiKey, "key", os.Getenv("CODESENTRY_API_KEY"), "project API key (webhook secret)")
+	fs.StringVar(&o.projectURL, "project", "", "project URL as configured in CodeSentry (default derived from the origin remote)")
+	fs.DurationVar(&o.timeout, "timeout", time.Duration(timeout)*time.Second, "review timeout")
+	fs.BoolVar(&o.warnOnly, "warn-only", false, "print the review but never fail")
+	fs.BoolVar(&o.failOpen, "fail-open", false, "do not fail when the server cannot be reached")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	out := newPrinter(os.Stdout)
+
+	switch os.Args[1] {
+	case "review":
+		var opts options
+		fs := flag.NewFlagSet("review", flag.ExitOnError)
+		opts.register(fs)
+		base := fs.String("base", "", "base ref to diff against (default the upstream branch, then origin/HEAD)")
+		fs.Parse(os.Args[2:])
+
+		if *base == "" {
+			*base = defaultBase()
+		}
+		if *base == "" {
+			out.errorf("No base ref found; pass -base")
+			os.Exit(exitError)
+		}
+		os.Exit(run(out, &opts, []reviewRange{{base: *base, head: "HEAD", ref: currentRef()}}))
+
+	case "pre-push":
+		var opts options
+		fs := flag.NewFlagSet("pre-push", flag.ExitOnError)
+		opts.register(fs)
+		// git passes the remote name and URL as arguments
+		fs.Parse(os.Args[2:])
+
+		ranges, err := parsePrePush(os.Stdin, defaultBase())
+		if err != nil {
+			out.errorf("Failed to read pre-push input: %v", err)
+			os.Exit(exitError)
+		}
+		os.Exit(run(out, &opts, ranges))
+
+	case "install-hook":
+		fs := flag.NewFlagSet("install-hook", flag.ExitOnError)
+		force := fs.Bool("force", false, "overwrite an existing pre-push hook")
+		fs.Parse(os.Args[2:])
+
+		path, err := installHook(*force)
+		if err != nil {
+			out.errorf("Failed to install hook: %v", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Pre-push hook installed at %s\n", path)
+
+	default:
+		usage()
+	}
+}
+
+// run reviews each range and returns the process exit code
+func run(out *printer, opts *options, ranges []reviewRange) int {
+	if len(ranges) == 0 {
+		return exitPassed
+	}
+
+	projectURL := opts.projectURL
+	if projectURL == "" {
+		remote, err := git("config", "--get", "remote.origin.url")
+		if err != nil || remote == "" {
+			out.errorf("Cannot determine the project URL; pass -project")
+			return exitError
+		}
+		projectURL = normalizeRemoteURL(remote)
+	}
+
+	c := client.New(opts.serverURL, client.WithHTTPClient(&http.Client{Timeout: opts.timeout}))
+	code := exitPassed
+	for _, r := range ranges {
+		result, err := review(c, opts, projectURL, r)
+		if err != nil {
+			out.errorf("CodeSentry review failed: %v", err)
+			if !opts.failOpen {
+				code = exitError
+			}
+			continue
+		}
+		if result == nil {
+			out.infof("No changes to review in %s", r.ref)
+			continue
+		}
+		out.result(result)
+		if !result.Passed && !opts.warnOnly && code == exitPassed {
+			code = exitFailed
+		}
+	}
+	return code
+}
+
+func review(c *client.Client, opts *options, projectURL string, r reviewRange) (*client.SyncReviewResult, error) {
+	diffs, err := git("diff", r.base+"..."+r.head)
+	if err != nil {
+		return nil, err
+	}
+	if diffs == "" {
+		return nil, nil
+	}
+	sha, err := git("rev-parse", r.head)
+	if err != nil {
+		return nil, err
+	}
+	author, _ := git("log", "-1", "--format=%an", sha)
+	message, _ := git("log", "-1", "--format=%s", sha)
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	return c.SyncReview(ctx, opts.apiKey, &client.SyncReviewRequest{
+		ProjectURL: projectURL,
+		CommitSHA:  sha,
+		Ref:        r.ref,
+		Author:     author,
+		Message:    message,
+		Diffs:      diffs,
+	})
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/pkg/client"
+)
+
+const (
+	colorReset  = "\033[0m"
+	colorBold   = "\033[1m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// printer writes review output, colored when writing to a terminal
+type printer struct {
+	w     io.Writer
+	color bool
+}
+
+func newPrinter(f *os.File) *printer {
+	return &printer{w: f, color: useColor(f)}
+}
+
+// useColor reports whether f is a terminal and NO_COLOR is not set
+func useColor(f *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *printer) paint(color, s string) string {
+	if !p.color {
+		return s
+	}
+	return color + s + colorReset
+}
+
+func (p *printer) infof(format string, args ...interface{}) {
+	fmt.Fprintln(p.w, p.paint(colorCyan, "CodeSentry: ")+fmt.Sprintf(format, args...))
+}
+
+func (p *printer) errorf(format string, args ...interface{}) {
+	fmt.Fprintln(os.Stderr, p.paint(colorRed, fmt.Sprintf(format, args...)))
+}
+
+// result prints the review content followed by a pass/fail summary
+func (p *printer) result(result *client.SyncReviewResult) {
+	if result.FullContent != "" {
+		fmt.Fprintln(p.w)
+		for _, line := range strings.Split(strings.TrimSpace(result.FullContent), "\n") {
+			fmt.Fprintln(p.w, p.line(line))
+		}
+	}
+
+	fmt.Fprintln(p.w)
+	if result.Passed {
+		fmt.Fprintln(p.w, p.paint(colorBold+colorGreen, fmt.Sprintf("✔ CodeSentry review passed (score %.0f / 100, required %.0f)", result.Score, result.MinScore)))
+	} else {
+		fmt.Fprintln(p.w, p.paint(colorBold+colorRed, fmt.Sprintf("✘ CodeSentry review failed (score %.0f / 100, required %.0f)", result.Score, result.MinScore)))
+		if result.Message != "" {
+			fmt.Fprintln(p.w, "  "+result.Message)
+		}
+	}
+}
+
+// line highlights markdown headings and severity markers of a review line
+func (p *printer) line(line string) string {
+	trimmed := strings.TrimSpace(line)
+	lower := strings.ToLower(trimmed)
+	switch {
+	case strings.HasPrefix(trimmed, "#"):
+		return p.paint(colorBold, line)
+	case strings.Contains(lower, "critical") || strings.Contains(trimmed, "🔴"):
+		return p.paint(colorRed, line)
+	case strings.Contains(lower, "warning") || strings.Contains(trimmed, "🟡"):
+		return p.paint(colorYellow, line)
+	}
+	return line
+}
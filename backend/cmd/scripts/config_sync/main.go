@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage:")
+	fmt.Fprintln(os.Stderr, "  config_sync export [-o file]")
+	fmt.Fprintln(os.Stderr, "  config_sync apply -f file [-dry-run]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_PATH"))
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if err := models.InitDB(&cfg.Database); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := models.AutoMigrate(); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	service := services.NewConfigSyncService(models.GetDB())
+
+	switch os.Args[1] {
+	case "export":
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		output := fs.String("o", "", "output file (default stdout)")
+		fs.Parse(os.Args[2:])
+
+		data, err := service.ExportYAML()
+		if err != nil {
+			log.Fatalf("Failed to export configuration: %v", err)
+		}
+		if *output == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(*output, data, 0o600); err != nil {
+			log.Fatalf("Failed to write %s: %v", *output, err)
+		}
+		fmt.Printf("Configuration exported to %s\n", *output)
+
+	case "apply":
+		fs := flag.NewFlagSet("apply", flag.ExitOnError)
+		file := fs.String("f", "", "configuration file to apply")
+		dryRun := fs.Bool("dry-run", false, "show changes without applying them")
+		fs.Parse(os.Args[2:])
+		if *file == "" {
+			usage()
+		}
+
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *file, err)
+		}
+		bundle, err := services.ParseConfigBundle(data)
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		result, err := service.Apply(bundle, *dryRun)
+		if err != nil {
+			log.Fatalf("Failed to apply configuration: %v", err)
+		}
+
+		for _, change := range result.Changes {
+			if change.Action != "unchanged" {
+				fmt.Printf("%-8s %-16s %s\n", change.Action, change.Kind, change.Name)
+			}
+		}
+		prefix := "Applied"
+		if result.DryRun {
+			prefix = "Dry run"
+		}
+		fmt.Printf("%s: %d created, %d updated, %d unchanged\n", prefix, result.Created, result.Updated, result.Unchanged)
+
+	default:
+		usage()
+	}
+}
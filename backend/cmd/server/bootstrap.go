@@ -1,22 +1,28 @@
 package main
 
 import (
+	"time"
+
 	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/grpcserver"
 	"github.com/huangang/codesentry/backend/internal/handlers"
 	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/internal/services/webhook"
 	"github.com/huangang/codesentry/backend/internal/utils"
 	"github.com/huangang/codesentry/backend/pkg/logger"
+	"google.golang.org/grpc"
 )
 
 // appServices holds all initialized services and handlers needed by the application.
 type appServices struct {
 	openAICfg          *config.OpenAIConfig
+	sseCfg             *config.SSEConfig
 	webhookService     *webhook.Service
 	dailyReportService *services.DailyReportService
 	taskQueue          services.TaskQueue
-	worker             *services.Worker
+	worker             services.TaskWorker
+	grpcServer         *grpc.Server
 	authHandler        *handlers.AuthHandler
 	webhookHandler     *handlers.WebhookHandler
 }
@@ -25,6 +31,15 @@ type appServices struct {
 func bootstrap(cfg *config.Config) *appServices {
 	utils.SetJWTSecret(cfg.JWT.Secret)
 
+	// Build the outbound HTTP clients shared by the services
+	if err := services.InitHTTPClients(&cfg.HTTP); err != nil {
+		logger.Fatalf("Invalid HTTP client config: %v", err)
+	}
+
+	// Resolve env: and vault: secret references of tokens and keys when they are used
+	services.InitSecrets(&cfg.Vault)
+	services.StartSecretLeaseRenewal()
+
 	// Initialize database
 	if err := models.InitDB(&cfg.Database); err != nil {
 		logger.Fatalf("Failed to connect to database: %v", err)
@@ -55,26 +70,81 @@ func bootstrap(cfg *config.Config) *appServices {
 	// Start retry scheduler for failed reviews
 	services.StartRetryScheduler(models.GetDB(), &cfg.OpenAI)
 
+	// Start review log archive scheduler
+	services.StartReviewArchiveScheduler(models.GetDB())
+
+	// Start data retention scheduler
+	services.StartRetentionScheduler(models.GetDB())
+
+	// Start LDAP directory sync scheduler
+	services.StartLDAPSyncScheduler(models.GetDB())
+
+	// Start author avatar enrichment scheduler
+	services.StartAuthorEnrichmentScheduler(models.GetDB())
+
+	// Start retry scheduler for failed IM notifications
+	services.StartNotificationRetryScheduler(models.GetDB())
+
+	// Start digest scheduler for bots in digest mode
+	services.StartDigestScheduler(models.GetDB())
+
+	// Start daily personal digest scheduler
+	services.StartPersonalDigestScheduler(models.GetDB())
+
+	// Start scheduled compliance report delivery
+	services.StartComplianceReportScheduler(models.GetDB())
+
+	// Start the check for commits that never triggered a webhook
+	services.StartCoverageGapScheduler(models.GetDB())
+
+	// Reload the runtime-safe settings on SIGHUP and config file changes
+	services.StartConfigWatcher()
+
 	// Initialize and start daily report scheduler
 	aiService := services.NewAIService(models.GetDB(), &cfg.OpenAI)
 	notificationService := services.NewNotificationService(models.GetDB())
 	dailyReportService := services.NewDailyReportService(models.GetDB(), aiService, notificationService)
 	dailyReportService.StartScheduler()
 
-	// Initialize task queue (uses Redis if enabled, otherwise sync mode)
+	// Initialize task queue (uses the configured broker or Redis if enabled, otherwise sync mode)
 	webhookService := webhook.NewService(models.GetDB(), &cfg.OpenAI)
 	taskQueue := services.InitTaskQueue(cfg)
 	if syncQueue, ok := taskQueue.(*services.SyncQueue); ok {
 		syncQueue.SetProcessor(webhookService.ProcessReviewTask)
+		syncQueue.Start()
 	}
 
-	// Start async worker if Redis is enabled
-	var worker *services.Worker
+	// Replay the webhook deliveries deferred while reviews were over capacity
+	webhook.StartDeferredWebhookScheduler(webhookService)
+
+	// Continue the retroactive reviews of imported commits left by a previous run
+	go services.ResumeImportReviews(models.GetDB())
+
+	// Resume commit import jobs interrupted by a restart
+	services.StartImportJobRecovery(models.GetDB())
+
+	// Fan out real-time events to the clients of every replica when Redis is enabled
+	services.GetSSEHub().SetReplaySize(cfg.SSE.ReplayBuffer)
 	if cfg.Redis.Enabled {
-		worker = services.InitWorker(&cfg.Redis)
-		if worker != nil {
-			worker.SetProcessor(webhookService.ProcessReviewTask)
-			worker.Start()
+		if err := services.StartSSEFanout(&cfg.Redis); err != nil {
+			logger.Warn().Err(err).Msg("Redis unavailable, SSE events are delivered to this instance only")
+		}
+	}
+
+	// Start the async worker of the Redis or broker queue
+	worker := services.InitWorker(cfg)
+	if worker != nil {
+		worker.SetProcessor(webhookService.ProcessReviewTask)
+		worker.Start()
+	}
+
+	// Start the gRPC API if enabled
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		var err error
+		grpcServer, err = grpcserver.Start(cfg.Server.Host+":"+cfg.GRPC.Port, grpcserver.NewServer(models.GetDB(), webhookService))
+		if err != nil {
+			logger.Warn().Err(err).Msg("Failed to start gRPC server")
 		}
 	}
 
@@ -86,10 +156,12 @@ func bootstrap(cfg *config.Config) *appServices {
 
 	return &appServices{
 		openAICfg:          &cfg.OpenAI,
+		sseCfg:             &cfg.SSE,
 		webhookService:     webhookService,
 		dailyReportService: dailyReportService,
 		taskQueue:          taskQueue,
 		worker:             worker,
+		grpcServer:         grpcServer,
 		authHandler:        authHandler,
 		webhookHandler:     handlers.NewWebhookHandler(models.GetDB(), &cfg.OpenAI),
 	}
@@ -100,8 +172,25 @@ func (s *appServices) shutdown() {
 	s.dailyReportService.StopScheduler()
 	services.StopLogCleanupScheduler()
 	services.StopRetryScheduler()
+	services.StopImportJobRecovery()
+	services.StopAuthorEnrichmentScheduler()
+	services.StopDigestScheduler()
+	services.StopNotificationRetryScheduler()
+	services.StopPersonalDigestScheduler()
+	services.StopComplianceReportScheduler()
+	services.StopCoverageGapScheduler()
+	services.StopConfigWatcher()
+	services.StopSecretLeaseRenewal()
+	services.StopReviewArchiveScheduler()
+	services.StopRetentionScheduler()
+	services.StopLDAPSyncScheduler()
+	services.StopSSEFanout()
+	webhook.StopDeferredWebhookScheduler()
 	logger.Info().Msg("All schedulers stopped")
 
+	if s.grpcServer != nil {
+		grpcserver.Stop(s.grpcServer, 5*time.Second)
+	}
 	if s.worker != nil {
 		s.worker.Stop()
 	}
@@ -51,6 +51,10 @@ func main() {
 	// Set Gin mode and create router
 	gin.SetMode(cfg.Server.Mode)
 	r := gin.New()
+	// Without trusted proxies, X-Forwarded-For is ignored so client IPs cannot be spoofed
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Fatalf("Invalid server.trusted_proxies: %v", err)
+	}
 
 	// Register all routes
 	registerRoutes(r, svc)
@@ -12,7 +12,7 @@ import (
 // registerRoutes sets up all HTTP routes on the given Gin engine.
 func registerRoutes(r *gin.Engine, svc *appServices) {
 	// Middleware
-	r.Use(logger.GinLogger(), logger.GinRecovery())
+	r.Use(middleware.RequestID(), logger.GinLogger(), logger.GinRecovery())
 	r.RedirectTrailingSlash = false
 	r.RedirectFixedPath = false
 	r.Use(middleware.CORS())
@@ -20,6 +20,14 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 	// Rate limiter for webhook routes
 	webhookLimiter := middleware.NewRateLimiter(10, 20)
 
+	// IP allow-lists for the authenticated API and webhook endpoints (empty lists allow all)
+	ipAllowList := services.NewIPAllowListService(models.GetDB())
+	webhookAllowList := middleware.IPAllowList(ipAllowList, services.IPAllowListWebhook)
+	adminAllowList := middleware.IPAllowList(ipAllowList, services.IPAllowListAdmin)
+
+	// Replays responses of retried requests sent with an Idempotency-Key header
+	idempotency := middleware.Idempotency(services.NewIdempotencyService(models.GetDB()))
+
 	// Health check (enhanced)
 	healthHandler := handlers.NewHealthHandler()
 	r.GET("/health", healthHandler.CheckHealth)
@@ -28,12 +36,13 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 	r.GET("/metrics", handlers.Metrics)
 
 	// Root-level webhook routes (without /api prefix for compatibility)
-	rootWebhook := r.Group("", webhookLimiter.Middleware())
+	rootWebhook := r.Group("", webhookAllowList, webhookLimiter.Middleware())
 	{
 		rootWebhook.POST("/webhook", svc.webhookHandler.HandleUnifiedWebhook)
 		rootWebhook.POST("/review/webhook", svc.webhookHandler.HandleUnifiedWebhook)
-		rootWebhook.POST("/review/sync", svc.webhookHandler.HandleSyncReview)
+		rootWebhook.POST("/review/sync", idempotency, svc.webhookHandler.HandleSyncReview)
 		rootWebhook.GET("/review/score", svc.webhookHandler.GetReviewScore)
+		rootWebhook.GET("/review/status/:token", svc.webhookHandler.GetReviewStatus)
 	}
 
 	// API routes
@@ -47,23 +56,59 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			auth.GET("/config", svc.authHandler.GetAuthConfig)
 		}
 
+		// Tenant branding for the login page (public)
+		tenantHandler := handlers.NewTenantHandler(models.GetDB())
+		api.GET("/tenants/:slug/branding", tenantHandler.GetBrandingBySlug)
+
+		// README badges (public, opt-in per project, optional badge token)
+		badgeHandler := handlers.NewBadgeHandler(models.GetDB())
+		api.GET("/badges/:id/:badge", badgeHandler.Get)
+
 		// SSE Events (public route with internal token validation)
-		sseHandler := handlers.NewSSEHandler(services.GetSSEHub())
-		api.GET("/events/reviews", sseHandler.StreamReviewEvents)
-		api.GET("/events/imports", sseHandler.StreamImportEvents)
+		sseHandler := handlers.NewSSEHandler(models.GetDB(), services.GetSSEHub(), svc.sseCfg)
+		api.GET("/events/reviews", adminAllowList, sseHandler.StreamReviewEvents)
+		api.GET("/events/imports", adminAllowList, sseHandler.StreamImportEvents)
 
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthRequired())
+		protected.Use(adminAllowList, middleware.AuthRequired())
 		{
 			// Auth
 			protected.GET("/auth/me", svc.authHandler.GetCurrentUser)
 			protected.POST("/auth/logout", svc.authHandler.Logout)
 			protected.POST("/auth/change-password", svc.authHandler.ChangePassword)
+			protected.GET("/tenant/branding", tenantHandler.GetCurrentBranding)
+			protected.GET("/system/status", healthHandler.GetSystemStatus)
+
+			// Personal notifications (each user's own IM identity and subscriptions)
+			personalNotificationHandler := handlers.NewPersonalNotificationHandler(models.GetDB())
+			protected.GET("/me/notifications", personalNotificationHandler.Get)
+			protected.PUT("/me/notifications", personalNotificationHandler.Update)
+			protected.POST("/me/notifications/test", personalNotificationHandler.Test)
+			protected.POST("/me/notifications/verify", personalNotificationHandler.Verify)
 
 			// Dashboard (all users)
 			dashboardHandler := handlers.NewDashboardHandler(models.GetDB())
 			protected.GET("/dashboard/stats", dashboardHandler.GetStats)
+			protected.GET("/dashboard/trends", dashboardHandler.GetTrends)
+			protected.GET("/dashboard/compare", dashboardHandler.Compare)
+			protected.GET("/dashboard/branch-health", dashboardHandler.GetBranchHealth)
+
+			// Saved dashboards (owned by each user, optionally shared in the tenant)
+			savedDashboardHandler := handlers.NewSavedDashboardHandler(models.GetDB())
+			protected.GET("/dashboards", savedDashboardHandler.List)
+			protected.POST("/dashboards", savedDashboardHandler.Create)
+			protected.GET("/dashboards/:id", savedDashboardHandler.GetByID)
+			protected.PUT("/dashboards/:id", savedDashboardHandler.Update)
+			protected.DELETE("/dashboards/:id", savedDashboardHandler.Delete)
+			protected.GET("/dashboards/:id/data", savedDashboardHandler.GetData)
+
+			// Review finding analytics (all users)
+			findingHandler := handlers.NewReviewFindingHandler(models.GetDB())
+			protected.GET("/findings/trends", findingHandler.GetTrends)
+			protected.GET("/findings/authors", findingHandler.GetByAuthor)
+			protected.GET("/findings/recurring", findingHandler.GetRecurring)
+			protected.GET("/findings/compliance", findingHandler.GetCompliance)
 
 			// Global Search
 			searchHandler := handlers.NewSearchHandler(models.GetDB())
@@ -73,16 +118,30 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			reportHandler := handlers.NewReportHandler(models.GetDB())
 			protected.GET("/reports", reportHandler.GetReport)
 
+			// Leaderboards (opt-in via system config)
+			leaderboardHandler := handlers.NewLeaderboardHandler(models.GetDB())
+			protected.GET("/leaderboards", leaderboardHandler.Get)
+
 			// Projects (read for all users)
 			projectHandler := handlers.NewProjectHandler(models.GetDB())
 			protected.GET("/projects", projectHandler.List)
 			protected.GET("/projects/default-prompt", projectHandler.GetDefaultPrompt)
+			protected.POST("/projects/path-patterns/dry-run", projectHandler.DryRunPathPatterns)
 			protected.GET("/projects/:id", projectHandler.GetByID)
+			protected.GET("/projects/:id/risk-heatmap", projectHandler.GetRiskHeatmap)
+			protected.GET("/projects/:id/languages", projectHandler.GetLanguageStats)
 
 			// Review Logs (read for all users)
 			reviewLogHandler := handlers.NewReviewLogHandler(models.GetDB(), svc.openAICfg)
 			protected.GET("/review-logs", reviewLogHandler.List)
 			protected.GET("/review-logs/:id", reviewLogHandler.GetByID)
+			protected.GET("/projects/:id/reviews/latest", reviewLogHandler.GetLatest)
+			protected.GET("/review-logs/:id/render", reviewLogHandler.Render)
+			protected.GET("/review-logs/:id/summary", reviewLogHandler.Summary)
+			protected.GET("/review-logs/:id/export", reviewLogHandler.ExportReport)
+			protected.PUT("/review-logs/:id/verdict", reviewLogHandler.SetVerdict)
+			protected.DELETE("/review-logs/:id/verdict", reviewLogHandler.ClearVerdict)
+			protected.POST("/review-logs/:id/acknowledge-migration", reviewLogHandler.AcknowledgeMigration)
 
 			// Members (all users)
 			memberHandler := handlers.NewMemberHandler(models.GetDB())
@@ -90,18 +149,21 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			protected.GET("/members/detail", memberHandler.GetDetail)
 			protected.GET("/members/overview", memberHandler.GetTeamOverview)
 			protected.GET("/members/heatmap", memberHandler.GetHeatmap)
+			protected.GET("/members/scorecards", memberHandler.GetScorecards)
 
 			// Prompts (read for all users)
 			promptHandler := handlers.NewPromptHandler(models.GetDB())
 			protected.GET("/prompts", promptHandler.List)
 			protected.GET("/prompts/default", promptHandler.GetDefault)
 			protected.GET("/prompts/active", promptHandler.GetAllActive)
+			protected.GET("/prompts/variables", promptHandler.GetVariables)
 			protected.GET("/prompts/:id", promptHandler.GetByID)
 
 			// Review Templates (read for all users)
 			reviewTemplateHandler := handlers.NewReviewTemplateHandler(models.GetDB())
 			protected.GET("/review-templates", reviewTemplateHandler.List)
 			protected.GET("/review-templates/:id", reviewTemplateHandler.Get)
+			protected.GET("/templates/export", reviewTemplateHandler.Export)
 
 			// Review Feedbacks (interactive AI feedback)
 			reviewFeedbackHandler := handlers.NewReviewFeedbackHandler(models.GetDB(), svc.openAICfg)
@@ -110,16 +172,78 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			protected.POST("/review-feedbacks", reviewFeedbackHandler.Create)
 		}
 
-		// Admin only routes
-		admin := api.Group("")
-		admin.Use(middleware.AuthRequired(), middleware.AdminRequired(), middleware.AuditLog())
+		// Tenant admin routes: platform admins, or tenant admins within their own tenant
+		tenantAdmin := api.Group("")
+		tenantAdmin.Use(adminAllowList, middleware.AuthRequired(), middleware.TenantAdminRequired(), middleware.AuditLog())
 		{
 			// Projects (write operations)
 			projectHandler := handlers.NewProjectHandler(models.GetDB())
-			admin.POST("/projects", projectHandler.Create)
-			admin.PUT("/projects/:id", projectHandler.Update)
-			admin.DELETE("/projects/:id", projectHandler.Delete)
+			tenantAdmin.POST("/projects", projectHandler.Create)
+			tenantAdmin.POST("/projects/validate", projectHandler.Validate)
+			tenantAdmin.PUT("/projects/:id", projectHandler.Update)
+			tenantAdmin.DELETE("/projects/:id", projectHandler.Delete)
+			tenantAdmin.POST("/projects/archive-inactive", projectHandler.ArchiveInactive)
+			tenantAdmin.POST("/projects/:id/archive", projectHandler.Archive)
+			tenantAdmin.POST("/projects/:id/unarchive", projectHandler.Unarchive)
+			tenantAdmin.POST("/projects/:id/approve", projectHandler.Approve)
+			tenantAdmin.POST("/projects/:id/badge-token", badgeHandler.RotateToken)
+			tenantAdmin.DELETE("/projects/:id/badge-token", badgeHandler.ClearToken)
+
+			// Reviews triggered on demand
+			triggerHandler := handlers.NewReviewLogHandler(models.GetDB(), svc.openAICfg)
+			tenantAdmin.POST("/projects/:id/review", triggerHandler.TriggerReview)
+			tenantAdmin.POST("/projects/:id/simulate", triggerHandler.SimulateReview)
+
+			// Users
+			userHandler := handlers.NewUserHandler(models.GetDB())
+			tenantAdmin.GET("/users", userHandler.List)
+			tenantAdmin.PUT("/users/:id", userHandler.Update)
+			tenantAdmin.DELETE("/users/:id", userHandler.Delete)
+
+			// LLM Configs
+			llmConfigHandler := handlers.NewLLMConfigHandler(models.GetDB())
+			tenantAdmin.GET("/llm-configs", llmConfigHandler.List)
+			tenantAdmin.GET("/llm-configs/active", llmConfigHandler.GetActive)
+			tenantAdmin.GET("/llm-configs/presets", llmConfigHandler.GetPresets)
+			tenantAdmin.GET("/llm-configs/:id", llmConfigHandler.GetByID)
+			tenantAdmin.GET("/llm-configs/:id/health", llmConfigHandler.Health)
+			tenantAdmin.POST("/llm-configs", llmConfigHandler.Create)
+			tenantAdmin.PUT("/llm-configs/:id", llmConfigHandler.Update)
+			tenantAdmin.DELETE("/llm-configs/:id", llmConfigHandler.Delete)
+
+			// IM Bots
+			imBotHandler := handlers.NewIMBotHandler(models.GetDB())
+			tenantAdmin.GET("/im-bots", imBotHandler.List)
+			tenantAdmin.GET("/im-bots/active", imBotHandler.GetAllActive)
+			tenantAdmin.GET("/im-bots/:id", imBotHandler.GetByID)
+			tenantAdmin.POST("/im-bots", imBotHandler.Create)
+			tenantAdmin.POST("/im-bots/preview", imBotHandler.PreviewMessage)
+			tenantAdmin.POST("/im-bots/:id/test", imBotHandler.TestSend)
+			tenantAdmin.POST("/im-bots/:id/preview", imBotHandler.PreviewPayload)
+			tenantAdmin.POST("/im-bots/:id/digest/flush", imBotHandler.FlushDigest)
+			tenantAdmin.PUT("/im-bots/:id", imBotHandler.Update)
+			tenantAdmin.DELETE("/im-bots/:id", imBotHandler.Delete)
+
+			// Notification deliveries
+			deliveryHandler := handlers.NewNotificationDeliveryHandler(models.GetDB())
+			tenantAdmin.GET("/notifications/deliveries", deliveryHandler.List)
+			tenantAdmin.POST("/notifications/deliveries/:id/resend", deliveryHandler.Resend)
+
+			// Compliance
+			complianceHandler := handlers.NewComplianceHandler(models.GetDB())
+			tenantAdmin.GET("/compliance/report", complianceHandler.Report)
+			tenantAdmin.POST("/compliance/report/send", complianceHandler.Send)
+
+			// Coverage gaps
+			coverageGapHandler := handlers.NewCoverageGapHandler(models.GetDB())
+			tenantAdmin.POST("/coverage-gaps/scan", coverageGapHandler.Scan)
+			tenantAdmin.POST("/coverage-gaps/review", coverageGapHandler.ReviewMissed)
+		}
 
+		// Admin only routes
+		admin := api.Group("")
+		admin.Use(adminAllowList, middleware.AuthRequired(), middleware.AdminRequired(), middleware.AuditLog())
+		{
 			// Project Members
 			projectMemberHandler := handlers.NewProjectMemberHandler(models.GetDB())
 			admin.GET("/projects/:id/members", projectMemberHandler.List)
@@ -130,43 +254,29 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			// Review Logs (write operations)
 			reviewLogHandler := handlers.NewReviewLogHandler(models.GetDB(), svc.openAICfg)
 			admin.POST("/review-logs/:id/retry", reviewLogHandler.Retry)
-			admin.POST("/review-logs/manual", reviewLogHandler.CreateManualCommit)
-			admin.POST("/review-logs/import", reviewLogHandler.ImportCommits)
+			admin.POST("/review-logs/manual", idempotency, reviewLogHandler.CreateManualCommit)
+			admin.POST("/review-logs/import", idempotency, reviewLogHandler.ImportCommits)
 			admin.DELETE("/review-logs/:id", reviewLogHandler.Delete)
 			admin.GET("/review-logs/export", reviewLogHandler.Export)
 			admin.POST("/review-logs/batch-retry", reviewLogHandler.BatchRetry)
 			admin.POST("/review-logs/batch-delete", reviewLogHandler.BatchDelete)
 			admin.PUT("/review-logs/:id/score", reviewLogHandler.UpdateScore)
 
+			// Author avatar enrichment
+			admin.POST("/members/enrich", handlers.NewMemberHandler(models.GetDB()).Enrich)
+			admin.POST("/members/erase", handlers.NewMemberHandler(models.GetDB()).Erase)
+
+			// Commit import jobs
+			importJobHandler := handlers.NewImportJobHandler(models.GetDB())
+			admin.GET("/import-jobs", importJobHandler.List)
+			admin.GET("/import-jobs/:id", importJobHandler.GetByID)
+			admin.POST("/import-jobs/:id/cancel", importJobHandler.Cancel)
+
 			// Auto-Fix PR (AI-generated code fixes)
 			autoFixHandler := handlers.NewAutoFixHandler(models.GetDB(), svc.openAICfg)
 			admin.POST("/review-logs/:id/fix", autoFixHandler.RequestFix)
 			admin.GET("/review-logs/:id/fix-status", autoFixHandler.GetFixStatus)
 
-			// Users
-			userHandler := handlers.NewUserHandler(models.GetDB())
-			admin.GET("/users", userHandler.List)
-			admin.PUT("/users/:id", userHandler.Update)
-			admin.DELETE("/users/:id", userHandler.Delete)
-
-			// LLM Configs
-			llmConfigHandler := handlers.NewLLMConfigHandler(models.GetDB())
-			admin.GET("/llm-configs", llmConfigHandler.List)
-			admin.GET("/llm-configs/active", llmConfigHandler.GetActive)
-			admin.GET("/llm-configs/:id", llmConfigHandler.GetByID)
-			admin.POST("/llm-configs", llmConfigHandler.Create)
-			admin.PUT("/llm-configs/:id", llmConfigHandler.Update)
-			admin.DELETE("/llm-configs/:id", llmConfigHandler.Delete)
-
-			// IM Bots
-			imBotHandler := handlers.NewIMBotHandler(models.GetDB())
-			admin.GET("/im-bots", imBotHandler.List)
-			admin.GET("/im-bots/active", imBotHandler.GetAllActive)
-			admin.GET("/im-bots/:id", imBotHandler.GetByID)
-			admin.POST("/im-bots", imBotHandler.Create)
-			admin.PUT("/im-bots/:id", imBotHandler.Update)
-			admin.DELETE("/im-bots/:id", imBotHandler.Delete)
-
 			// Prompts
 			promptHandler := handlers.NewPromptHandler(models.GetDB())
 			admin.POST("/prompts", promptHandler.Create)
@@ -179,6 +289,9 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			admin.POST("/review-templates", reviewTemplateHandler.Create)
 			admin.PUT("/review-templates/:id", reviewTemplateHandler.Update)
 			admin.DELETE("/review-templates/:id", reviewTemplateHandler.Delete)
+			admin.GET("/review-templates/updates", reviewTemplateHandler.BuiltInUpdates)
+			admin.POST("/review-templates/updates/apply", reviewTemplateHandler.ApplyBuiltInUpdates)
+			admin.POST("/templates/import", reviewTemplateHandler.Import)
 
 			// Issue Trackers (Jira/Linear/GitHub)
 			issueTrackerHandler := handlers.NewIssueTrackerHandler(models.GetDB())
@@ -200,10 +313,24 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			systemLogHandler := handlers.NewSystemLogHandler(models.GetDB())
 			admin.GET("/system-logs", systemLogHandler.List)
 			admin.GET("/system-logs/modules", systemLogHandler.GetModules)
+			admin.GET("/system-logs/stream", systemLogHandler.Stream)
 			admin.GET("/system-logs/retention", systemLogHandler.GetRetentionDays)
 			admin.PUT("/system-logs/retention", systemLogHandler.SetRetentionDays)
 			admin.POST("/system-logs/cleanup", systemLogHandler.Cleanup)
 
+			// Queue and scheduler state
+			opsHandler := handlers.NewOpsHandler(models.GetDB())
+			admin.GET("/ops/queue", opsHandler.Queue)
+			admin.GET("/ops/schedulers", opsHandler.Schedulers)
+			admin.GET("/ops/jobs", opsHandler.Jobs)
+			admin.POST("/system/reload", opsHandler.ReloadConfig)
+
+			// Retries
+			retryHandler := handlers.NewRetryHandler(models.GetDB(), svc.openAICfg)
+			admin.GET("/retries", retryHandler.List)
+			admin.POST("/retries/:id/run", retryHandler.Run)
+			admin.POST("/retries/:id/cancel", retryHandler.Cancel)
+
 			// Git Credentials
 			gitCredentialHandler := handlers.NewGitCredentialHandler(models.GetDB())
 			admin.GET("/git-credentials", gitCredentialHandler.List)
@@ -212,11 +339,16 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			admin.POST("/git-credentials", gitCredentialHandler.Create)
 			admin.PUT("/git-credentials/:id", gitCredentialHandler.Update)
 			admin.DELETE("/git-credentials/:id", gitCredentialHandler.Delete)
+			admin.GET("/git-credentials/:id/propagation", gitCredentialHandler.PreviewPropagation)
+			admin.POST("/git-credentials/:id/propagate", gitCredentialHandler.Propagate)
+			admin.PUT("/git-credentials/:id/ca-cert", gitCredentialHandler.UploadCACert)
+			admin.DELETE("/git-credentials/:id/ca-cert", gitCredentialHandler.DeleteCACert)
 
 			// System Config
 			systemConfigHandler := handlers.NewSystemConfigHandler(models.GetDB())
 			admin.GET("/system-config/ldap", systemConfigHandler.GetLDAPConfig)
 			admin.PUT("/system-config/ldap", systemConfigHandler.UpdateLDAPConfig)
+			admin.POST("/system-config/ldap/sync", systemConfigHandler.SyncLDAP)
 			admin.GET("/system-config/auth-session", systemConfigHandler.GetAuthSessionConfig)
 			admin.PUT("/system-config/auth-session", systemConfigHandler.UpdateAuthSessionConfig)
 			admin.GET("/system-config/daily-report", systemConfigHandler.GetDailyReportConfig)
@@ -225,6 +357,44 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			admin.PUT("/system-config/chunked-review", systemConfigHandler.UpdateChunkedReviewConfig)
 			admin.GET("/system-config/file-context", systemConfigHandler.GetFileContextConfig)
 			admin.PUT("/system-config/file-context", systemConfigHandler.UpdateFileContextConfig)
+			admin.GET("/system-config/review-archive", systemConfigHandler.GetReviewArchiveConfig)
+			admin.PUT("/system-config/review-archive", systemConfigHandler.UpdateReviewArchiveConfig)
+			admin.GET("/system-config/retention", systemConfigHandler.GetRetentionConfig)
+			admin.PUT("/system-config/retention", systemConfigHandler.UpdateRetentionConfig)
+			admin.POST("/system-config/retention/run", systemConfigHandler.RunRetention)
+			admin.POST("/system-config/retention/purge-deleted", systemConfigHandler.PurgeDeleted)
+			admin.GET("/system-config/leaderboard", systemConfigHandler.GetLeaderboardConfig)
+			admin.PUT("/system-config/leaderboard", systemConfigHandler.UpdateLeaderboardConfig)
+			admin.GET("/system-config/diff-fetch", systemConfigHandler.GetDiffFetchConfig)
+			admin.PUT("/system-config/diff-fetch", systemConfigHandler.UpdateDiffFetchConfig)
+			admin.GET("/system-config/mention", systemConfigHandler.GetMentionConfig)
+			admin.PUT("/system-config/mention", systemConfigHandler.UpdateMentionConfig)
+			admin.GET("/system-config/privacy", systemConfigHandler.GetPrivacyConfig)
+			admin.PUT("/system-config/privacy", systemConfigHandler.UpdatePrivacyConfig)
+			admin.GET("/system-config/test-coverage", systemConfigHandler.GetTestCoverageConfig)
+			admin.PUT("/system-config/test-coverage", systemConfigHandler.UpdateTestCoverageConfig)
+			admin.GET("/system-config/scorecard", systemConfigHandler.GetScorecardConfig)
+			admin.PUT("/system-config/scorecard", systemConfigHandler.UpdateScorecardConfig)
+			admin.GET("/system-config/ip-allowlist", systemConfigHandler.GetIPAllowListConfig)
+			admin.PUT("/system-config/ip-allowlist", systemConfigHandler.UpdateIPAllowListConfig)
+			admin.GET("/system-config/shadow-review", systemConfigHandler.GetShadowReviewConfig)
+			admin.PUT("/system-config/shadow-review", systemConfigHandler.UpdateShadowReviewConfig)
+			admin.GET("/system-config/human-verdict", systemConfigHandler.GetHumanVerdictConfig)
+			admin.PUT("/system-config/human-verdict", systemConfigHandler.UpdateHumanVerdictConfig)
+			admin.GET("/system-config/llm-outage", systemConfigHandler.GetLLMOutageConfig)
+			admin.PUT("/system-config/llm-outage", systemConfigHandler.UpdateLLMOutageConfig)
+			admin.GET("/system-config/backpressure", systemConfigHandler.GetBackpressureConfig)
+			admin.PUT("/system-config/backpressure", systemConfigHandler.UpdateBackpressureConfig)
+			admin.GET("/system-config/error-notify", systemConfigHandler.GetErrorNotifyConfig)
+			admin.PUT("/system-config/error-notify", systemConfigHandler.UpdateErrorNotifyConfig)
+			admin.GET("/system-config/notification-links", systemConfigHandler.GetNotificationLinkConfig)
+			admin.PUT("/system-config/notification-links", systemConfigHandler.UpdateNotificationLinkConfig)
+			admin.GET("/system-config/log-shipping", systemConfigHandler.GetLogShippingConfig)
+			admin.PUT("/system-config/log-shipping", systemConfigHandler.UpdateLogShippingConfig)
+			admin.GET("/system-config/compliance-report", systemConfigHandler.GetComplianceReportConfig)
+			admin.PUT("/system-config/compliance-report", systemConfigHandler.UpdateComplianceReportConfig)
+			admin.GET("/system-config/coverage-gap", systemConfigHandler.GetCoverageGapConfig)
+			admin.PUT("/system-config/coverage-gap", systemConfigHandler.UpdateCoverageGapConfig)
 			admin.GET("/system-config/holiday-countries", systemConfigHandler.GetHolidayCountries)
 
 			// Daily Reports
@@ -233,16 +403,69 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			admin.GET("/daily-reports/:id", dailyReportHandler.Get)
 			admin.POST("/daily-reports/generate", dailyReportHandler.Generate)
 			admin.POST("/daily-reports/:id/resend", dailyReportHandler.Resend)
+			admin.POST("/daily-reports/:id/publish", dailyReportHandler.Publish)
+
+			reportPublisherHandler := handlers.NewReportPublisherHandler(models.GetDB())
+			admin.GET("/report-publishers", reportPublisherHandler.List)
+			admin.POST("/report-publishers", reportPublisherHandler.Create)
+			admin.PUT("/report-publishers/:id", reportPublisherHandler.Update)
+			admin.DELETE("/report-publishers/:id", reportPublisherHandler.Delete)
+			admin.POST("/report-publishers/:id/test", reportPublisherHandler.TestConnection)
+
+			// Review Hooks
+			reviewHookHandler := handlers.NewReviewHookHandler(models.GetDB())
+			admin.GET("/review-hooks", reviewHookHandler.List)
+			admin.POST("/review-hooks", reviewHookHandler.Create)
+			admin.PUT("/review-hooks/:id", reviewHookHandler.Update)
+			admin.DELETE("/review-hooks/:id", reviewHookHandler.Delete)
+			admin.POST("/review-hooks/:id/test", reviewHookHandler.Test)
+
+			// Feature flags
+			featureFlagHandler := handlers.NewFeatureFlagHandler(models.GetDB())
+			admin.GET("/feature-flags", featureFlagHandler.List)
+			admin.GET("/feature-flags/evaluate", featureFlagHandler.Evaluate)
+			admin.POST("/feature-flags", featureFlagHandler.Create)
+			admin.PUT("/feature-flags/:id", featureFlagHandler.Update)
+			admin.DELETE("/feature-flags/:id", featureFlagHandler.Delete)
+
+			// Experiments
+			experimentHandler := handlers.NewExperimentHandler(models.GetDB())
+			admin.GET("/experiments", experimentHandler.List)
+			admin.POST("/experiments", experimentHandler.Create)
+			admin.PUT("/experiments/:id", experimentHandler.Update)
+			admin.DELETE("/experiments/:id", experimentHandler.Delete)
+			admin.POST("/experiments/:id/start", experimentHandler.Start)
+			admin.POST("/experiments/:id/stop", experimentHandler.Stop)
+			admin.GET("/experiments/:id/results", experimentHandler.Results)
 
 			// AI Usage
 			aiUsageHandler := handlers.NewAIUsageHandler(models.GetDB())
 			admin.GET("/ai-usage/stats", aiUsageHandler.GetStats)
 			admin.GET("/ai-usage/trend", aiUsageHandler.GetDailyTrend)
 			admin.GET("/ai-usage/providers", aiUsageHandler.GetProviderBreakdown)
+			admin.GET("/ai-usage/prompts", aiUsageHandler.GetPromptStats)
+			admin.GET("/ai-usage/llm-configs", aiUsageHandler.GetLLMConfigStats)
+			admin.GET("/ai-usage/reviews/:id", aiUsageHandler.GetReviewUsage)
+
+			// Shadow reviews
+			shadowReviewHandler := handlers.NewShadowReviewHandler(models.GetDB())
+			admin.GET("/shadow-reviews", shadowReviewHandler.List)
+			admin.GET("/shadow-reviews/compare", shadowReviewHandler.Compare)
+
+			// Tenants (workspaces)
+			admin.GET("/tenants", tenantHandler.List)
+			admin.POST("/tenants", tenantHandler.Create)
+			admin.PUT("/tenants/:id", tenantHandler.Update)
+			admin.DELETE("/tenants/:id", tenantHandler.Delete)
+
+			// Declarative configuration export/apply
+			configSyncHandler := handlers.NewConfigSyncHandler(models.GetDB())
+			admin.GET("/config/export", configSyncHandler.Export)
+			admin.POST("/config/apply", configSyncHandler.Apply)
 		}
 
 		// Webhook routes (public with signature verification, rate limited)
-		apiWebhook := api.Group("", webhookLimiter.Middleware())
+		apiWebhook := api.Group("", webhookAllowList, webhookLimiter.Middleware())
 		{
 			apiWebhook.POST("/webhook/gitlab/:project_id", svc.webhookHandler.HandleGitLabWebhook)
 			apiWebhook.POST("/webhook/github/:project_id", svc.webhookHandler.HandleGitHubWebhook)
@@ -252,8 +475,13 @@ func registerRoutes(r *gin.Engine, svc *appServices) {
 			apiWebhook.POST("/webhook/bitbucket", svc.webhookHandler.HandleBitbucketWebhookGeneric)
 			apiWebhook.POST("/webhook", svc.webhookHandler.HandleUnifiedWebhook)
 			apiWebhook.POST("/review/webhook", svc.webhookHandler.HandleUnifiedWebhook)
-			apiWebhook.POST("/review/sync", svc.webhookHandler.HandleSyncReview)
+			apiWebhook.POST("/review/sync", idempotency, svc.webhookHandler.HandleSyncReview)
 			apiWebhook.GET("/review/score", svc.webhookHandler.GetReviewScore)
+			apiWebhook.GET("/review/status/:token", svc.webhookHandler.GetReviewStatus)
 		}
 	}
+
+	// OpenAPI document, generated from the routes registered above
+	openAPIHandler := handlers.NewOpenAPIHandler(r.Routes())
+	r.GET("/api/openapi.json", openAPIHandler.Get)
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadFile_QueueBackend(t *testing.T) {
+	tests := []struct {
+		backend string
+		wantErr bool
+	}{
+		{"", false},
+		{QueueBackendAsynq, false},
+		{QueueBackendStreams, false},
+		{"kafka", true}, // Brokers are selected with queue.backend
+		{"nats", true},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("redis:\n  queue_backend: \""+tt.backend+"\"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ReadFile(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ReadFile with queue_backend %q: error = %v, wantErr %v", tt.backend, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadFile_BrokerQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		queue   string
+		wantErr bool
+	}{
+		{"none", "", false},
+		{"nats", "backend: nats\n  nats:\n    url: nats://localhost:4222", false},
+		{"nats without url", "backend: nats", true},
+		{"kafka", "backend: kafka\n  kafka:\n    brokers: [\"localhost:9092\"]", false},
+		{"kafka without brokers", "backend: kafka", true},
+		{"unknown", "backend: rabbitmq", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte("queue:\n  "+tt.queue+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadFile with queue %q: error = %v, wantErr %v", tt.queue, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOverrideFromEnv_BrokerQueue(t *testing.T) {
+	t.Setenv("QUEUE_BACKEND", QueueBackendKafka)
+	t.Setenv("KAFKA_BROKERS", "kafka-1:9092, kafka-2:9092")
+	cfg := DefaultConfig()
+	cfg.overrideFromEnv()
+	if cfg.Queue.Backend != QueueBackendKafka || len(cfg.Queue.Kafka.Brokers) != 2 || cfg.Queue.Kafka.Brokers[1] != "kafka-2:9092" {
+		t.Errorf("queue config = %+v", cfg.Queue)
+	}
+}
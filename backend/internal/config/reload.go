@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// reloadMu guards the settings replaced by a reload while the services read them
+var reloadMu sync.RWMutex
+
+// reloadableKeys are the settings, or prefixes of settings, applied without a restart.
+// Everything else is read once at startup: listeners, database, Redis, queues and the
+// HTTP clients the services hold.
+var reloadableKeys = []string{"openai.", "sse.replay_buffer"}
+
+// secretKeys are the settings whose values are masked in reload reports
+var secretKeys = []string{"password", "secret", "api_key", "dsn", "token"}
+
+// ConfigChange is a setting whose value in the config file differs from the running one
+type ConfigChange struct {
+	Key     string `json:"key"` // Path in config.yaml, e.g. openai.model
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"` // False when the change takes effect after a restart
+}
+
+// ReloadReport lists the settings changed by a reload
+type ReloadReport struct {
+	Path            string         `json:"path"`
+	Changes         []ConfigChange `json:"changes"`
+	RestartRequired bool           `json:"restart_required"` // Some changes wait for a restart
+}
+
+// Changed reports whether a setting under the prefix was applied
+func (r *ReloadReport) Changed(prefix string) bool {
+	for _, change := range r.Changes {
+		if change.Applied && strings.HasPrefix(change.Key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Path returns the config file the running config was loaded from
+func Path() string {
+	return loadedPath
+}
+
+// Current returns the OpenAI fallback settings, which a reload may replace
+func (c *OpenAIConfig) Current() OpenAIConfig {
+	reloadMu.RLock()
+	defer reloadMu.RUnlock()
+	return *c
+}
+
+// Reload applies the reloadable settings of next to the running config, and reports every
+// setting that differs, applied or not
+func Reload(current, next *Config) *ReloadReport {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	report := &ReloadReport{Path: loadedPath, Changes: Diff(current, next)}
+	for _, change := range report.Changes {
+		if !change.Applied {
+			report.RestartRequired = true
+		}
+	}
+	current.OpenAI = next.OpenAI
+	current.SSE.ReplayBuffer = next.SSE.ReplayBuffer
+	return report
+}
+
+// Diff returns the settings that differ between two configs, sorted by key
+func Diff(old, next *Config) []ConfigChange {
+	oldValues, nextValues := map[string]string{}, map[string]string{}
+	flatten("", reflect.ValueOf(*old), oldValues)
+	flatten("", reflect.ValueOf(*next), nextValues)
+
+	var changes []ConfigChange
+	for key, value := range nextValues {
+		if oldValues[key] == value {
+			continue
+		}
+		change := ConfigChange{Key: key, Old: oldValues[key], New: value, Applied: isReloadable(key)}
+		if isSecret(key) {
+			change.Old, change.New = maskSecret(change.Old), maskSecret(change.New)
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// flatten collects the leaf settings of a config struct by their yaml path
+func flatten(prefix string, v reflect.Value, values map[string]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			flatten(prefix+name+".", field, values)
+			continue
+		}
+		values[prefix+name] = fmt.Sprint(field.Interface())
+	}
+}
+
+func isReloadable(key string) bool {
+	for _, reloadable := range reloadableKeys {
+		if key == reloadable || (strings.HasSuffix(reloadable, ".") && strings.HasPrefix(key, reloadable)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSecret(key string) bool {
+	for _, secret := range secretKeys {
+		if strings.HasSuffix(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "***"
+}
@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name            string
+		change          func(c *Config)
+		wantChanges     []ConfigChange
+		wantRestart     bool
+		wantOpenAIModel string
+	}{
+		{
+			name:            "no change",
+			change:          func(c *Config) {},
+			wantOpenAIModel: "gpt-4",
+		},
+		{
+			name:   "openai fallback applied, api key masked",
+			change: func(c *Config) { c.OpenAI.Model = "gpt-4o"; c.OpenAI.APIKey = "sk-new" },
+			wantChanges: []ConfigChange{
+				{Key: "openai.api_key", Old: "", New: "***", Applied: true},
+				{Key: "openai.model", Old: "gpt-4", New: "gpt-4o", Applied: true},
+			},
+			wantOpenAIModel: "gpt-4o",
+		},
+		{
+			name:   "redis toggle waits for a restart",
+			change: func(c *Config) { c.Redis.Enabled = true; c.OpenAI.Model = "gpt-4o" },
+			wantChanges: []ConfigChange{
+				{Key: "openai.model", Old: "gpt-4", New: "gpt-4o", Applied: true},
+				{Key: "redis.enabled", Old: "false", New: "true", Applied: false},
+			},
+			wantRestart:     true,
+			wantOpenAIModel: "gpt-4o",
+		},
+		{
+			name:            "list settings compared by value",
+			change:          func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/8"} },
+			wantChanges:     []ConfigChange{{Key: "server.trusted_proxies", Old: "[]", New: "[10.0.0.0/8]", Applied: false}},
+			wantRestart:     true,
+			wantOpenAIModel: "gpt-4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, next := DefaultConfig(), DefaultConfig()
+			tt.change(next)
+
+			report := Reload(current, next)
+			if len(report.Changes) != len(tt.wantChanges) {
+				t.Fatalf("changes = %+v, expected %+v", report.Changes, tt.wantChanges)
+			}
+			for i, change := range report.Changes {
+				if change != tt.wantChanges[i] {
+					t.Errorf("change %d = %+v, expected %+v", i, change, tt.wantChanges[i])
+				}
+			}
+			if report.RestartRequired != tt.wantRestart {
+				t.Errorf("RestartRequired = %v, expected %v", report.RestartRequired, tt.wantRestart)
+			}
+			if got := current.OpenAI.Current().Model; got != tt.wantOpenAIModel {
+				t.Errorf("running openai.model = %q, expected %q", got, tt.wantOpenAIModel)
+			}
+			if current.Redis.Enabled {
+				t.Error("redis.enabled must not change before a restart")
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package grpcserver serves the gRPC ReviewService defined in proto/codesentry/v1/review.proto.
+// It shares the service layer with the REST handlers.
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/internal/services/webhook"
+	"github.com/huangang/codesentry/backend/internal/utils"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/huangang/codesentry/backend/pkg/reviewpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// syncReviewTimeout matches the timeout of POST /api/review/sync
+const syncReviewTimeout = 3 * time.Minute
+
+// Server implements reviewpb.ReviewServiceServer
+type Server struct {
+	reviewpb.UnimplementedReviewServiceServer
+	db             *gorm.DB
+	projectService *services.ProjectService
+	webhookService *webhook.Service
+	hub            *services.SSEHub
+}
+
+func NewServer(db *gorm.DB, webhookService *webhook.Service) *Server {
+	return &Server{
+		db:             db,
+		projectService: services.NewProjectService(db),
+		webhookService: webhookService,
+		hub:            services.GetSSEHub(),
+	}
+}
+
+// SubmitDiff reviews a diff synchronously
+func (s *Server) SubmitDiff(ctx context.Context, req *reviewpb.SubmitDiffRequest) (*reviewpb.SubmitDiffResponse, error) {
+	if req.GetProjectUrl() == "" || req.GetCommitSha() == "" || req.GetDiffs() == "" {
+		return nil, status.Error(codes.InvalidArgument, "project_url, commit_sha and diffs are required")
+	}
+
+	projectURL := strings.TrimSuffix(req.GetProjectUrl(), ".git")
+	project, err := s.projectService.GetByURL(projectURL)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "project not found for URL: %s", projectURL)
+	}
+	if !services.VerifyWithSecret(ctx, project.WebhookSecret, func(secret string) bool { return metadataValue(ctx, "x-api-key") == secret }) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, syncReviewTimeout)
+	defer cancel()
+
+	result, err := s.webhookService.SyncReview(ctx, project, &webhook.SyncReviewRequest{
+		ProjectURL: req.GetProjectUrl(),
+		CommitSHA:  req.GetCommitSha(),
+		Ref:        req.GetRef(),
+		Author:     req.GetAuthor(),
+		Message:    req.GetMessage(),
+		Diffs:      req.GetDiffs(),
+	})
+	if err != nil {
+		logger.Errorf("[gRPC] SubmitDiff failed for project %d commit %s: %v", project.ID, req.GetCommitSha(), err)
+		return nil, status.Errorf(codes.Internal, "review failed: %v", err)
+	}
+
+	return &reviewpb.SubmitDiffResponse{
+		Passed:      result.Passed,
+		Score:       result.Score,
+		MinScore:    result.MinScore,
+		Message:     result.Message,
+		ReviewId:    uint32(result.ReviewID),
+		FullContent: result.FullContent,
+	}, nil
+}
+
+// GetScore returns the latest review of a commit
+func (s *Server) GetScore(ctx context.Context, req *reviewpb.GetScoreRequest) (*reviewpb.GetScoreResponse, error) {
+	if req.GetCommitSha() == "" {
+		return nil, status.Error(codes.InvalidArgument, "commit_sha is required")
+	}
+
+	result, err := s.webhookService.GetReviewScore(req.GetCommitSha())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	return &reviewpb.GetScoreResponse{
+		CommitSha: result.CommitSHA,
+		Status:    result.Status,
+		Score:     result.Score,
+		MinScore:  result.MinScore,
+		Passed:    result.Passed,
+		ReviewId:  uint32(result.ReviewID),
+		Message:   result.Message,
+	}, nil
+}
+
+// StreamEvents streams review status updates until the client disconnects.
+// Users other than platform admins only receive events of their own tenant's projects.
+func (s *Server) StreamEvents(req *reviewpb.StreamEventsRequest, stream grpc.ServerStreamingServer[reviewpb.ReviewEvent]) error {
+	ctx := stream.Context()
+	token := strings.TrimPrefix(metadataValue(ctx, "authorization"), "Bearer ")
+	if token == "" {
+		return status.Error(codes.Unauthenticated, "authorization required")
+	}
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		return status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	filter := &eventFilter{projectID: uint(req.GetProjectId()), db: s.db}
+	if claims.Role != "admin" {
+		filter.tenantID = claims.TenantID
+	}
+
+	clientID := uuid.New().String()
+	events := s.hub.Subscribe(clientID)
+	defer s.hub.Unsubscribe(clientID)
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if !filter.allows(event) {
+				continue
+			}
+			if err := stream.Send(&reviewpb.ReviewEvent{
+				Id:        uint32(event.ID),
+				ProjectId: uint32(event.ProjectID),
+				CommitSha: event.CommitSHA,
+				Status:    event.Status,
+				Score:     event.Score,
+				Error:     event.Error,
+			}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// eventFilter limits streamed events to one project and/or one tenant
+type eventFilter struct {
+	projectID uint
+	tenantID  uint // 0 = all tenants
+	db        *gorm.DB
+	tenants   map[uint]uint // project ID -> tenant ID
+}
+
+func (f *eventFilter) allows(event services.ReviewEvent) bool {
+	if f.projectID > 0 && event.ProjectID != f.projectID {
+		return false
+	}
+	if f.tenantID == 0 {
+		return true
+	}
+	if f.tenants == nil {
+		f.tenants = make(map[uint]uint)
+	}
+	tenantID, ok := f.tenants[event.ProjectID]
+	if !ok {
+		var project models.Project
+		if err := f.db.Select("id", "tenant_id").First(&project, event.ProjectID).Error; err != nil {
+			return false
+		}
+		tenantID = project.TenantID
+		f.tenants[event.ProjectID] = tenantID
+	}
+	return tenantID == f.tenantID
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Start listens on addr and serves the ReviewService in the background
+func Start(addr string, srv *Server) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	grpcServer := grpc.NewServer()
+	reviewpb.RegisterReviewServiceServer(grpcServer, srv)
+
+	go func() {
+		logger.Infof("[gRPC] Server listening on %s", addr)
+		if err := grpcServer.Serve(lis); err != nil {
+			logger.Errorf("[gRPC] Server stopped: %v", err)
+		}
+	}()
+	return grpcServer, nil
+}
+
+// Stop stops the server gracefully, closing open event streams after timeout
+func Stop(grpcServer *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		grpcServer.Stop()
+	}
+	logger.Infof("[gRPC] Server stopped")
+}
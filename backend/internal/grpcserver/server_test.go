@@ -0,0 +1,105 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/internal/utils"
+	"github.com/huangang/codesentry/backend/pkg/reviewpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func init() {
+	utils.SetJWTSecret("test-secret-for-grpc-testing")
+}
+
+// newTestClient serves srv over an in-memory connection and returns a client for it
+func newTestClient(t *testing.T, srv *Server) reviewpb.ReviewServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	reviewpb.RegisterReviewServiceServer(grpcServer, srv)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return reviewpb.NewReviewServiceClient(conn)
+}
+
+func TestValidation(t *testing.T) {
+	client := newTestClient(t, &Server{hub: services.NewSSEHub()})
+	ctx := context.Background()
+
+	_, err := client.GetScore(ctx, &reviewpb.GetScoreRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetScore: expected InvalidArgument, got %v", err)
+	}
+
+	_, err = client.SubmitDiff(ctx, &reviewpb.SubmitDiffRequest{ProjectUrl: "https://example.com/repo"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SubmitDiff: expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestStreamEvents_Unauthenticated(t *testing.T) {
+	client := newTestClient(t, &Server{hub: services.NewSSEHub()})
+
+	for _, token := range []string{"", "Bearer invalid"} {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
+		}
+		stream, err := client.StreamEvents(ctx, &reviewpb.StreamEventsRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("token %q: expected Unauthenticated, got %v", token, err)
+		}
+	}
+}
+
+func TestStreamEvents_ProjectFilter(t *testing.T) {
+	hub := services.NewSSEHub()
+	client := newTestClient(t, &Server{hub: hub})
+
+	token, _ := utils.GenerateToken(1, "admin", "admin", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+
+	stream, err := client.StreamEvents(ctx, &reviewpb.StreamEventsRequest{ProjectId: 2})
+	if err != nil {
+		t.Fatalf("StreamEvents() error = %v", err)
+	}
+
+	// Wait for the server to subscribe before publishing
+	for hub.ClientCount() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	score := 88.0
+	hub.Publish(services.ReviewEvent{ID: 1, ProjectID: 1, CommitSHA: "aaa", Status: "completed"})
+	hub.Publish(services.ReviewEvent{ID: 2, ProjectID: 2, CommitSHA: "bbb", Status: "completed", Score: &score})
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() error = %v", err)
+	}
+	if event.GetId() != 2 || event.GetCommitSha() != "bbb" || event.GetScore() != 88 {
+		t.Errorf("unexpected event: %v", event)
+	}
+}
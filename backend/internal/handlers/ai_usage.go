@@ -64,6 +64,23 @@ func (h *AIUsageHandler) GetDailyTrend(c *gin.Context) {
 	response.Success(c, trend)
 }
 
+// GetReviewUsage returns the LLM calls and prompt cache metrics of a review.
+func (h *AIUsageHandler) GetReviewUsage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	usage, err := h.usageService.GetReviewUsage(uint(id))
+	if err != nil {
+		response.ServerError(c, "failed to get review AI usage: "+err.Error())
+		return
+	}
+
+	response.Success(c, usage)
+}
+
 // GetProviderBreakdown returns AI usage grouped by provider/model.
 func (h *AIUsageHandler) GetProviderBreakdown(c *gin.Context) {
 	startDate := c.Query("start_date")
@@ -77,3 +94,37 @@ func (h *AIUsageHandler) GetProviderBreakdown(c *gin.Context) {
 
 	response.Success(c, providers)
 }
+
+// GetPromptStats returns review statistics per prompt.
+func (h *AIUsageHandler) GetPromptStats(c *gin.Context) {
+	var req services.ReviewConfigStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	stats, err := h.usageService.GetStatsByPrompt(&req)
+	if err != nil {
+		response.ServerError(c, "failed to get prompt stats: "+err.Error())
+		return
+	}
+
+	response.Success(c, stats)
+}
+
+// GetLLMConfigStats returns review statistics per LLM config.
+func (h *AIUsageHandler) GetLLMConfigStats(c *gin.Context) {
+	var req services.ReviewConfigStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	stats, err := h.usageService.GetStatsByLLMConfig(&req)
+	if err != nil {
+		response.ServerError(c, "failed to get LLM config stats: "+err.Error())
+		return
+	}
+
+	response.Success(c, stats)
+}
@@ -33,14 +33,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	result, err := h.authService.Login(&req, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		services.LogWarning("Auth", "LoginFailed", "Login failed: "+err.Error(), nil, c.ClientIP(), c.Request.UserAgent(), map[string]string{"username": req.Username})
+		services.LogWarning(c.Request.Context(), "Auth", "LoginFailed", "Login failed: "+err.Error(), nil, c.ClientIP(), c.Request.UserAgent(), map[string]string{"username": req.Username})
 		response.Unauthorized(c, err.Error())
 		return
 	}
 
 	h.setRefreshCookie(c, result.RefreshToken, result.RefreshExpireAt)
 
-	services.LogInfo("Auth", "LoginSuccess", "User logged in: "+req.Username, &result.User.ID, c.ClientIP(), c.Request.UserAgent(), nil)
+	services.LogInfo(c.Request.Context(), "Auth", "LoginSuccess", "User logged in: "+req.Username, &result.User.ID, c.ClientIP(), c.Request.UserAgent(), nil)
 	response.Success(c, gin.H{
 		"token":     result.AccessToken,
 		"user":      result.User,
@@ -93,7 +93,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if exists {
 		uid := userID.(uint)
-		services.LogInfo("Auth", "Logout", "User logged out", &uid, c.ClientIP(), c.Request.UserAgent(), nil)
+		services.LogInfo(c.Request.Context(), "Auth", "Logout", "User logged out", &uid, c.ClientIP(), c.Request.UserAgent(), nil)
 	}
 	response.Success(c, gin.H{"message": "logged out successfully"})
 }
@@ -140,6 +140,6 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 	}
 
 	uid := userID.(uint)
-	services.LogInfo("Auth", "ChangePassword", "User changed password", &uid, c.ClientIP(), c.Request.UserAgent(), nil)
+	services.LogInfo(c.Request.Context(), "Auth", "ChangePassword", "User changed password", &uid, c.ClientIP(), c.Request.UserAgent(), nil)
 	response.Success(c, gin.H{"message": "password changed successfully"})
 }
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type BadgeHandler struct {
+	badgeService     *services.BadgeService
+	projectService   *services.ProjectService
+	reviewLogService *services.ReviewLogService
+}
+
+func NewBadgeHandler(db *gorm.DB) *BadgeHandler {
+	return &BadgeHandler{
+		badgeService:     services.NewBadgeService(db),
+		projectService:   services.NewProjectService(db),
+		reviewLogService: services.NewReviewLogService(db),
+	}
+}
+
+// Get serves a public project badge as SVG or as shields.io endpoint JSON
+// GET /api/badges/:id/:badge (score.svg, score.json, pass-rate.svg, pass-rate.json, latest.svg?branch=main)
+func (h *BadgeHandler) Get(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	kind, format, _ := strings.Cut(c.Param("badge"), ".")
+	if (kind != services.BadgeScore && kind != services.BadgePassRate && kind != services.BadgeLatest) ||
+		(format != "svg" && format != "json") || (kind == services.BadgeLatest && format != "svg") {
+		response.NotFound(c, "badge not found")
+		return
+	}
+
+	project, err := h.badgeService.GetPublicProject(uint(id), c.Query("token"))
+	if err != nil {
+		response.NotFound(c, "badge not found")
+		return
+	}
+
+	if kind == services.BadgeLatest {
+		latest, err := h.reviewLogService.GetLatestScored(project.ID, c.Query("branch"))
+		if err != nil {
+			response.ServerError(c, err.Error())
+			return
+		}
+		c.Header("Cache-Control", "no-cache, max-age=0")
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(services.RenderReviewBadgeSVG(latest, h.reviewLogService.EffectiveMinScore(project))))
+		return
+	}
+
+	stats, err := h.badgeService.GetStats(project)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	badge := stats.Badge(kind)
+	c.Header("Cache-Control", "public, max-age=300")
+	if format == "json" {
+		c.JSON(http.StatusOK, badge)
+		return
+	}
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(badge.SVG()))
+}
+
+// RotateToken generates a new token required by the project's public badges
+// POST /api/projects/:id/badge-token
+func (h *BadgeHandler) RotateToken(c *gin.Context) {
+	id, ok := h.projectID(c)
+	if !ok {
+		return
+	}
+
+	token, err := h.badgeService.RotateToken(id)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"token": token})
+}
+
+// ClearToken makes the project's public badges accessible without a token
+// DELETE /api/projects/:id/badge-token
+func (h *BadgeHandler) ClearToken(c *gin.Context) {
+	id, ok := h.projectID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.badgeService.ClearToken(id); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"message": "badge token removed"})
+}
+
+// projectID parses the project ID and checks the caller may manage it
+func (h *BadgeHandler) projectID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return 0, false
+	}
+	if project, err := h.projectService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestBadgeGet_Latest(t *testing.T) {
+	db := newTestDB(t)
+	project := &models.Project{Name: "p", URL: "https://git.example.com/p", Platform: "gitlab", BadgeEnabled: true, BadgeToken: "secret"}
+	if err := db.Create(project).Error; err != nil {
+		t.Fatal(err)
+	}
+	score := 87.0
+	if err := db.Create(&models.ReviewLog{ProjectID: project.ID, EventType: "push", Branch: "main", Score: &score, ReviewStatus: "completed"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/badges/:id/:badge", NewBadgeHandler(db).Get)
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		return w
+	}
+
+	if w := get("/api/badges/1/latest.svg?branch=main"); w.Code != http.StatusNotFound {
+		t.Errorf("without the badge token: status = %d, want 404", w.Code)
+	}
+	if w := get("/api/badges/1/latest.json?token=secret"); w.Code != http.StatusNotFound {
+		t.Errorf("latest.json: status = %d, want 404", w.Code)
+	}
+	w := get("/api/badges/1/latest.svg?branch=main&token=secret")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "87") {
+		t.Errorf("with the badge token: status = %d, body = %q, want the latest score", w.Code, w.Body.String())
+	}
+}
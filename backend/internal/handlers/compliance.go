@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ComplianceHandler struct {
+	service *services.ComplianceReportService
+}
+
+func NewComplianceHandler(db *gorm.DB) *ComplianceHandler {
+	return &ComplianceHandler{service: services.NewComplianceReportService(db)}
+}
+
+// Report returns the compliance report of a date range: review coverage per project,
+// unreviewed pushes to protected branches, gate overrides and reviewer acknowledgments
+// GET /api/compliance/report?start_date=&end_date=&format=json|csv|pdf
+func (h *ComplianceHandler) Report(c *gin.Context) {
+	var req services.ComplianceReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		response.BadRequest(c, "format must be json, csv or pdf")
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	report, err := h.service.Generate(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	if format == "json" {
+		response.Success(c, report)
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Compliance", "ExportReport", fmt.Sprintf("Compliance report %s ~ %s exported as %s by %s", report.StartDate, report.EndDate, format, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"start_date": report.StartDate,
+		"end_date":   report.EndDate,
+		"format":     format,
+	})
+
+	filename := fmt.Sprintf("compliance-%s-%s.%s", report.StartDate, report.EndDate, format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "pdf" {
+		c.Data(http.StatusOK, "application/pdf", services.RenderComplianceReportPDF(report))
+		return
+	}
+	var buf bytes.Buffer
+	if err := services.WriteComplianceCSV(&buf, report); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+type SendComplianceReportRequest struct {
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+	ProjectID  uint   `json:"project_id"`
+	ProjectIDs string `json:"project_ids"`
+	IMBotIDs   []uint `json:"im_bot_ids" binding:"required,min=1"`
+}
+
+// Send delivers the summary of a compliance report to IM bots
+// POST /api/compliance/report/send
+func (h *ComplianceHandler) Send(c *gin.Context) {
+	var req SendComplianceReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	tenantID := middleware.GetTenantID(c)
+
+	report, err := h.service.Generate(&services.ComplianceReportRequest{
+		StartDate:  req.StartDate,
+		EndDate:    req.EndDate,
+		ProjectID:  req.ProjectID,
+		ProjectIDs: req.ProjectIDs,
+		TenantID:   tenantID,
+	})
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	sent, err := h.service.Send(report, req.IMBotIDs, tenantID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"sent": sent, "start_date": report.StartDate, "end_date": report.EndDate})
+}
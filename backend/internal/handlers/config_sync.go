@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+// maxConfigBundleSize limits the size of an uploaded configuration file
+const maxConfigBundleSize = 10 << 20
+
+type ConfigSyncHandler struct {
+	configSyncService *services.ConfigSyncService
+}
+
+func NewConfigSyncHandler(db *gorm.DB) *ConfigSyncHandler {
+	return &ConfigSyncHandler{
+		configSyncService: services.NewConfigSyncService(db),
+	}
+}
+
+// Export returns the instance configuration as YAML, without secrets
+// GET /api/config/export
+func (h *ConfigSyncHandler) Export(c *gin.Context) {
+	data, err := h.configSyncService.ExportYAML()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=codesentry-config.yaml")
+	c.Data(200, "application/x-yaml; charset=utf-8", data)
+}
+
+// Apply applies a YAML configuration idempotently; ?dry_run=true only reports the plan
+// POST /api/config/apply
+func (h *ConfigSyncHandler) Apply(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxConfigBundleSize))
+	if err != nil {
+		response.BadRequest(c, "failed to read request body")
+		return
+	}
+
+	bundle, err := services.ParseConfigBundle(data)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.configSyncService.Apply(bundle, c.Query("dry_run") == "true")
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
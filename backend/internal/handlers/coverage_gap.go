@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type CoverageGapHandler struct {
+	db      *gorm.DB
+	service *services.CoverageGapService
+}
+
+func NewCoverageGapHandler(db *gorm.DB) *CoverageGapHandler {
+	return &CoverageGapHandler{db: db, service: services.NewCoverageGapService(db)}
+}
+
+// Scan lists the recent commits of the projects' default branches and records those that
+// never triggered a webhook as missed reviews
+// POST /api/coverage-gaps/scan
+func (h *CoverageGapHandler) Scan(c *gin.Context) {
+	var req services.CoverageGapScanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	result, err := h.service.Scan(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Project", "CoverageGapScan", fmt.Sprintf("Coverage gap scan by %s: %d commits checked, %d missed", middleware.GetUsername(c), result.Checked, result.Missed), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"project_id": req.ProjectID,
+		"checked":    result.Checked,
+		"missed":     result.Missed,
+		"queued":     result.Queued,
+	})
+	response.Success(c, result)
+}
+
+type ReviewMissedRequest struct {
+	ProjectID uint `json:"project_id" binding:"required"`
+}
+
+// ReviewMissed queues reviews of the missed commits of a project
+// POST /api/coverage-gaps/review
+func (h *CoverageGapHandler) ReviewMissed(c *gin.Context) {
+	var req ReviewMissedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	var project models.Project
+	if err := h.db.First(&project, req.ProjectID).Error; err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	queued, err := h.service.ReviewMissed(&project)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"queued": queued})
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 )
@@ -27,7 +28,7 @@ func (h *DailyReportHandler) List(c *gin.Context) {
 		pageSize = 10
 	}
 
-	reports, total, err := h.service.List(page, pageSize)
+	reports, total, err := h.service.List(page, pageSize, middleware.GetTenantID(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -49,7 +50,7 @@ func (h *DailyReportHandler) Get(c *gin.Context) {
 	}
 
 	report, err := h.service.GetByID(uint(id))
-	if err != nil {
+	if err != nil || !middleware.CanAccessTenant(c, report.TenantID) {
 		response.NotFound(c, "report not found")
 		return
 	}
@@ -58,7 +59,7 @@ func (h *DailyReportHandler) Get(c *gin.Context) {
 }
 
 func (h *DailyReportHandler) Generate(c *gin.Context) {
-	report, err := h.service.GenerateReport()
+	report, err := h.service.GenerateReport(middleware.GetWriteTenantID(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -74,6 +75,11 @@ func (h *DailyReportHandler) Resend(c *gin.Context) {
 		return
 	}
 
+	if report, err := h.service.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, report.TenantID) {
+		response.NotFound(c, "report not found")
+		return
+	}
+
 	if err := h.service.ResendNotification(uint(id)); err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -81,3 +87,23 @@ func (h *DailyReportHandler) Resend(c *gin.Context) {
 
 	response.Success(c, gin.H{"message": "notification resent"})
 }
+
+func (h *DailyReportHandler) Publish(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	if report, err := h.service.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, report.TenantID) {
+		response.NotFound(c, "report not found")
+		return
+	}
+
+	if err := h.service.Publish(uint(id)); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"message": "report published"})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
@@ -9,11 +10,13 @@ import (
 
 type DashboardHandler struct {
 	dashboardService *services.DashboardService
+	configService    *services.SystemConfigService
 }
 
 func NewDashboardHandler(db *gorm.DB) *DashboardHandler {
 	return &DashboardHandler{
 		dashboardService: services.NewDashboardService(db),
+		configService:    services.NewSystemConfigService(db),
 	}
 }
 
@@ -23,6 +26,7 @@ func (h *DashboardHandler) GetStats(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.TenantID = middleware.GetTenantID(c)
 
 	resp, err := h.dashboardService.GetStats(&req)
 	if err != nil {
@@ -30,5 +34,65 @@ func (h *DashboardHandler) GetStats(c *gin.Context) {
 		return
 	}
 
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(resp)
+	}
+	response.Success(c, resp)
+}
+
+// GetTrends returns time-series review metrics
+// GET /api/dashboard/trends
+func (h *DashboardHandler) GetTrends(c *gin.Context) {
+	var req services.DashboardTrendRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.dashboardService.GetTrends(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetBranchHealth returns review metrics of default-branch pushes and feature branches separately
+// GET /api/dashboard/branch-health
+func (h *DashboardHandler) GetBranchHealth(c *gin.Context) {
+	var req services.BranchHealthRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.dashboardService.GetBranchHealth(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Compare returns period-over-period metrics (this week vs last week, this month vs last month)
+// GET /api/dashboard/compare
+func (h *DashboardHandler) Compare(c *gin.Context) {
+	var req services.DashboardCompareRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.dashboardService.Compare(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
 	response.Success(c, resp)
 }
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ExperimentHandler struct {
+	service        *services.ExperimentService
+	projectService *services.ProjectService
+}
+
+func NewExperimentHandler(db *gorm.DB) *ExperimentHandler {
+	return &ExperimentHandler{
+		service:        services.NewExperimentService(db),
+		projectService: services.NewProjectService(db),
+	}
+}
+
+// canAccessProject reports whether the caller's tenant owns the project
+func (h *ExperimentHandler) canAccessProject(c *gin.Context, projectID uint) bool {
+	project, err := h.projectService.GetByID(projectID)
+	return err == nil && middleware.CanAccessTenant(c, project.TenantID)
+}
+
+// getAccessible loads the experiment from the :id param, writing the error response on failure
+func (h *ExperimentHandler) getAccessible(c *gin.Context) *models.Experiment {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return nil
+	}
+	experiment, err := h.service.GetByID(uint(id))
+	if err != nil || !h.canAccessProject(c, experiment.ProjectID) {
+		response.NotFound(c, "experiment not found")
+		return nil
+	}
+	return experiment
+}
+
+// logChange records who changed an experiment in the system logs
+func (h *ExperimentHandler) logChange(c *gin.Context, action string, experiment *models.Experiment) {
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Experiment", action, fmt.Sprintf("Experiment %q of project %d %s by %s", experiment.Name, experiment.ProjectID, action, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"experiment_id": experiment.ID,
+		"project_id":    experiment.ProjectID,
+		"traffic_b":     experiment.TrafficB,
+	})
+}
+
+// GET /api/experiments?project_id=
+func (h *ExperimentHandler) List(c *gin.Context) {
+	projectID, _ := strconv.ParseUint(c.Query("project_id"), 10, 32)
+	experiments, err := h.service.List(middleware.GetTenantID(c), uint(projectID))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, experiments)
+}
+
+// POST /api/experiments
+func (h *ExperimentHandler) Create(c *gin.Context) {
+	var experiment models.Experiment
+	if err := c.ShouldBindJSON(&experiment); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if !h.canAccessProject(c, experiment.ProjectID) {
+		response.BadRequest(c, "project not found")
+		return
+	}
+	experiment.ID = 0
+	experiment.CreatedBy = middleware.GetUsername(c)
+	if err := h.service.Create(&experiment); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "created", &experiment)
+	response.Success(c, experiment)
+}
+
+// PUT /api/experiments/:id
+func (h *ExperimentHandler) Update(c *gin.Context) {
+	experiment := h.getAccessible(c)
+	if experiment == nil {
+		return
+	}
+	var req services.UpdateExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	updated, err := h.service.Update(experiment.ID, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "updated", updated)
+	response.Success(c, updated)
+}
+
+// DELETE /api/experiments/:id
+func (h *ExperimentHandler) Delete(c *gin.Context) {
+	experiment := h.getAccessible(c)
+	if experiment == nil {
+		return
+	}
+	if err := h.service.Delete(experiment.ID); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "deleted", experiment)
+	response.Success(c, gin.H{"message": "deleted"})
+}
+
+// POST /api/experiments/:id/start
+func (h *ExperimentHandler) Start(c *gin.Context) {
+	experiment := h.getAccessible(c)
+	if experiment == nil {
+		return
+	}
+	started, err := h.service.Start(experiment.ID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "started", started)
+	response.Success(c, started)
+}
+
+// POST /api/experiments/:id/stop
+func (h *ExperimentHandler) Stop(c *gin.Context) {
+	experiment := h.getAccessible(c)
+	if experiment == nil {
+		return
+	}
+	stopped, err := h.service.Stop(experiment.ID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "stopped", stopped)
+	response.Success(c, stopped)
+}
+
+// Results compares the score, latency and token cost of the variants
+// GET /api/experiments/:id/results
+func (h *ExperimentHandler) Results(c *gin.Context) {
+	experiment := h.getAccessible(c)
+	if experiment == nil {
+		return
+	}
+	results, err := h.service.Results(experiment.ID)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, results)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type FeatureFlagHandler struct {
+	service *services.FeatureFlagService
+}
+
+func NewFeatureFlagHandler(db *gorm.DB) *FeatureFlagHandler {
+	return &FeatureFlagHandler{service: services.NewFeatureFlagService(db)}
+}
+
+// get loads the flag from the :id param, writing the error response on failure
+func (h *FeatureFlagHandler) get(c *gin.Context) *models.FeatureFlag {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return nil
+	}
+	flag, err := h.service.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "feature flag not found")
+		return nil
+	}
+	return flag
+}
+
+// logChange records who changed a flag in the system logs
+func (h *FeatureFlagHandler) logChange(c *gin.Context, action string, flag *models.FeatureFlag) {
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "FeatureFlag", action, fmt.Sprintf("Feature flag %s %s by %s", flag.Key, action, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"key":                  flag.Key,
+		"enabled":              flag.Enabled,
+		"rollout":              flag.Rollout,
+		"project_ids":          flag.ProjectIDs,
+		"excluded_project_ids": flag.ExcludedProjectIDs,
+	})
+}
+
+// GET /api/feature-flags
+func (h *FeatureFlagHandler) List(c *gin.Context) {
+	flags, err := h.service.List()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"flags": flags, "known": services.KnownFeatureFlags})
+}
+
+// POST /api/feature-flags
+func (h *FeatureFlagHandler) Create(c *gin.Context) {
+	var req services.CreateFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	flag, err := h.service.Create(&req, middleware.GetUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "created", flag)
+	response.Success(c, flag)
+}
+
+// PUT /api/feature-flags/:id
+func (h *FeatureFlagHandler) Update(c *gin.Context) {
+	flag := h.get(c)
+	if flag == nil {
+		return
+	}
+	var req services.UpdateFeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	updated, err := h.service.Update(flag.ID, &req, middleware.GetUsername(c))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	h.logChange(c, "updated", updated)
+	response.Success(c, updated)
+}
+
+// DELETE /api/feature-flags/:id
+func (h *FeatureFlagHandler) Delete(c *gin.Context) {
+	flag := h.get(c)
+	if flag == nil {
+		return
+	}
+	if err := h.service.Delete(flag.ID); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	h.logChange(c, "deleted", flag)
+	response.Success(c, gin.H{"message": "deleted"})
+}
+
+// Evaluate explains whether a flag is on for a project
+// GET /api/feature-flags/evaluate?key=&project_id=
+func (h *FeatureFlagHandler) Evaluate(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		response.BadRequest(c, "key is required")
+		return
+	}
+	projectID, err := strconv.ParseUint(c.Query("project_id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project_id")
+		return
+	}
+	response.Success(c, h.service.Evaluate(key, uint(projectID)))
+}
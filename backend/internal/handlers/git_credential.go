@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -21,40 +26,64 @@ func NewGitCredentialHandler(db *gorm.DB) *GitCredentialHandler {
 }
 
 type GitCredentialResponse struct {
-	ID               uint   `json:"id"`
-	Name             string `json:"name"`
-	Platform         string `json:"platform"`
-	BaseURL          string `json:"base_url"`
-	AccessTokenMask  string `json:"access_token_mask"`
-	WebhookSecretSet bool   `json:"webhook_secret_set"`
-	AutoCreate       bool   `json:"auto_create"`
-	DefaultEnabled   bool   `json:"default_enabled"`
-	FileExtensions   string `json:"file_extensions"`
-	ReviewEvents     string `json:"review_events"`
-	IgnorePatterns   string `json:"ignore_patterns"`
-	IsActive         bool   `json:"is_active"`
-	CreatedBy        uint   `json:"created_by"`
-	CreatedAt        string `json:"created_at"`
-	UpdatedAt        string `json:"updated_at"`
+	ID                 uint                  `json:"id"`
+	Name               string                `json:"name"`
+	Platform           string                `json:"platform"`
+	BaseURL            string                `json:"base_url"`
+	AccessTokenMask    string                `json:"access_token_mask"`
+	WebhookSecretSet   bool                  `json:"webhook_secret_set"`
+	Namespaces         []string              `json:"namespaces"` // Group paths auto-creation is restricted to
+	AllowPatterns      string                `json:"allow_patterns"`
+	DenyPatterns       string                `json:"deny_patterns"`
+	MaxAutoCreated     int                   `json:"max_auto_created"`
+	RequireApproval    bool                  `json:"require_approval"`
+	AutoCreate         bool                  `json:"auto_create"`
+	DefaultEnabled     bool                  `json:"default_enabled"`
+	FileExtensions     string                `json:"file_extensions"`
+	ReviewEvents       string                `json:"review_events"`
+	IgnorePatterns     string                `json:"ignore_patterns"`
+	IsActive           bool                  `json:"is_active"`
+	CACerts            []services.CACertInfo `json:"ca_certs"` // CA certificates trusted for the base URL's host
+	InsecureSkipVerify bool                  `json:"insecure_skip_verify"`
+	BitbucketAuth      string                `json:"bitbucket_auth"`
+	Username           string                `json:"username"`
+	OAuthConfigured    bool                  `json:"oauth_configured"` // OAuth consumer key and secret are set
+	CreatedBy          uint                  `json:"created_by"`
+	CreatedAt          string                `json:"created_at"`
+	UpdatedAt          string                `json:"updated_at"`
 }
 
 func toGitCredentialResponse(cred *models.GitCredential) GitCredentialResponse {
+	caCerts := []services.CACertInfo{}
+	if cred.CACert != "" {
+		caCerts, _ = services.ParseCACerts(cred.CACert)
+	}
 	return GitCredentialResponse{
-		ID:               cred.ID,
-		Name:             cred.Name,
-		Platform:         cred.Platform,
-		BaseURL:          cred.BaseURL,
-		AccessTokenMask:  cred.MaskAccessToken(),
-		WebhookSecretSet: cred.WebhookSecret != "",
-		AutoCreate:       cred.AutoCreate,
-		DefaultEnabled:   cred.DefaultEnabled,
-		FileExtensions:   cred.FileExtensions,
-		ReviewEvents:     cred.ReviewEvents,
-		IgnorePatterns:   cred.IgnorePatterns,
-		IsActive:         cred.IsActive,
-		CreatedBy:        cred.CreatedBy,
-		CreatedAt:        cred.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:        cred.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ID:                 cred.ID,
+		Name:               cred.Name,
+		Platform:           cred.Platform,
+		BaseURL:            cred.BaseURL,
+		AccessTokenMask:    cred.MaskAccessToken(),
+		WebhookSecretSet:   cred.WebhookSecret != "",
+		Namespaces:         append([]string{}, services.ParseNamespaces(cred.Namespaces)...),
+		AllowPatterns:      cred.AllowPatterns,
+		DenyPatterns:       cred.DenyPatterns,
+		MaxAutoCreated:     cred.MaxAutoCreated,
+		RequireApproval:    cred.RequireApproval,
+		AutoCreate:         cred.AutoCreate,
+		DefaultEnabled:     cred.DefaultEnabled,
+		FileExtensions:     cred.FileExtensions,
+		ReviewEvents:       cred.ReviewEvents,
+		IgnorePatterns:     cred.IgnorePatterns,
+		IsActive:           cred.IsActive,
+		CACerts:            caCerts,
+		InsecureSkipVerify: cred.InsecureSkipVerify,
+		BitbucketAuth:      cred.BitbucketAuth,
+		Username:           cred.Username,
+		OAuthConfigured:    cred.OAuthKey != "" && cred.OAuthSecret != "",
+		CreatedBy:          cred.CreatedBy,
+		CreatedAt:          cred.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:          cred.UpdatedAt.Format("2006-01-02 15:04:05"),
 	}
 }
 
@@ -125,17 +154,28 @@ func (h *GitCredentialHandler) GetActive(c *gin.Context) {
 }
 
 type CreateGitCredentialRequest struct {
-	Name           string `json:"name" binding:"required"`
-	Platform       string `json:"platform" binding:"required"`
-	BaseURL        string `json:"base_url"`
-	AccessToken    string `json:"access_token"`
-	WebhookSecret  string `json:"webhook_secret"`
-	AutoCreate     bool   `json:"auto_create"`
-	DefaultEnabled bool   `json:"default_enabled"`
-	FileExtensions string `json:"file_extensions"`
-	ReviewEvents   string `json:"review_events"`
-	IgnorePatterns string `json:"ignore_patterns"`
-	IsActive       bool   `json:"is_active"`
+	Name               string `json:"name" binding:"required"`
+	Platform           string `json:"platform" binding:"required"`
+	BaseURL            string `json:"base_url"`
+	AccessToken        string `json:"access_token"`
+	WebhookSecret      string `json:"webhook_secret"`
+	Namespaces         string `json:"namespaces"`       // Group paths, comma or newline separated; empty covers the whole host
+	AllowPatterns      string `json:"allow_patterns"`   // Repository globs auto-creation is limited to
+	DenyPatterns       string `json:"deny_patterns"`    // Repository globs never auto-created
+	MaxAutoCreated     int    `json:"max_auto_created"` // 0 = no limit
+	RequireApproval    bool   `json:"require_approval"`
+	AutoCreate         bool   `json:"auto_create"`
+	DefaultEnabled     bool   `json:"default_enabled"`
+	FileExtensions     string `json:"file_extensions"`
+	ReviewEvents       string `json:"review_events"`
+	IgnorePatterns     string `json:"ignore_patterns"`
+	IsActive           bool   `json:"is_active"`
+	CACert             string `json:"ca_cert"` // PEM CA certificates trusted for the base URL's host
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	BitbucketAuth      string `json:"bitbucket_auth"` // Bitbucket only: token (default), oauth or basic
+	Username           string `json:"username"`       // Bitbucket basic auth username
+	OAuthKey           string `json:"oauth_key"`      // Bitbucket Cloud OAuth consumer key
+	OAuthSecret        string `json:"oauth_secret"`   // Bitbucket Cloud OAuth consumer secret
 }
 
 func (h *GitCredentialHandler) Create(c *gin.Context) {
@@ -145,21 +185,47 @@ func (h *GitCredentialHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if req.CACert != "" {
+		if _, err := services.ParseCACerts(req.CACert); err != nil {
+			response.BadRequest(c, "invalid ca_cert: "+err.Error())
+			return
+		}
+	}
+	if !services.ValidBitbucketAuth(req.BitbucketAuth) {
+		response.BadRequest(c, "invalid bitbucket_auth, expected token, oauth or basic")
+		return
+	}
+	if err := validateAutoCreateGuard(req.AllowPatterns, req.DenyPatterns, req.MaxAutoCreated); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
 	userID, _ := c.Get("user_id")
 
 	credential := &models.GitCredential{
-		Name:           req.Name,
-		Platform:       req.Platform,
-		BaseURL:        req.BaseURL,
-		AccessToken:    req.AccessToken,
-		WebhookSecret:  req.WebhookSecret,
-		AutoCreate:     req.AutoCreate,
-		DefaultEnabled: req.DefaultEnabled,
-		FileExtensions: req.FileExtensions,
-		ReviewEvents:   req.ReviewEvents,
-		IgnorePatterns: req.IgnorePatterns,
-		IsActive:       req.IsActive,
-		CreatedBy:      userID.(uint),
+		Name:               req.Name,
+		Platform:           req.Platform,
+		BaseURL:            req.BaseURL,
+		AccessToken:        req.AccessToken,
+		WebhookSecret:      req.WebhookSecret,
+		Namespaces:         strings.Join(services.ParseNamespaces(req.Namespaces), ","),
+		AllowPatterns:      req.AllowPatterns,
+		DenyPatterns:       req.DenyPatterns,
+		MaxAutoCreated:     req.MaxAutoCreated,
+		RequireApproval:    req.RequireApproval,
+		AutoCreate:         req.AutoCreate,
+		DefaultEnabled:     req.DefaultEnabled,
+		FileExtensions:     req.FileExtensions,
+		ReviewEvents:       req.ReviewEvents,
+		IgnorePatterns:     req.IgnorePatterns,
+		IsActive:           req.IsActive,
+		CACert:             req.CACert,
+		InsecureSkipVerify: req.InsecureSkipVerify,
+		BitbucketAuth:      req.BitbucketAuth,
+		Username:           req.Username,
+		OAuthKey:           req.OAuthKey,
+		OAuthSecret:        req.OAuthSecret,
+		CreatedBy:          userID.(uint),
 	}
 
 	if credential.FileExtensions == "" {
@@ -174,23 +240,37 @@ func (h *GitCredentialHandler) Create(c *gin.Context) {
 		return
 	}
 
-	services.LogInfo("GitCredential", "Create", "Git credential created: "+credential.Name, &credential.CreatedBy, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	services.LogInfo(c.Request.Context(), "GitCredential", "Create", "Git credential created: "+credential.Name, &credential.CreatedBy, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	if credential.InsecureSkipVerify {
+		auditTLSVerify(c, credential, credential.CreatedBy)
+	}
 
 	response.Success(c, toGitCredentialResponse(credential))
 }
 
 type UpdateGitCredentialRequest struct {
-	Name           string `json:"name"`
-	Platform       string `json:"platform"`
-	BaseURL        string `json:"base_url"`
-	AccessToken    string `json:"access_token"`
-	WebhookSecret  string `json:"webhook_secret"`
-	AutoCreate     *bool  `json:"auto_create"`
-	DefaultEnabled *bool  `json:"default_enabled"`
-	FileExtensions string `json:"file_extensions"`
-	ReviewEvents   string `json:"review_events"`
-	IgnorePatterns string `json:"ignore_patterns"`
-	IsActive       *bool  `json:"is_active"`
+	Name               string  `json:"name"`
+	Platform           string  `json:"platform"`
+	BaseURL            string  `json:"base_url"`
+	AccessToken        string  `json:"access_token"`
+	WebhookSecret      string  `json:"webhook_secret"`
+	Namespaces         *string `json:"namespaces"` // Replaces the namespaces; empty removes the restriction
+	AllowPatterns      *string `json:"allow_patterns"`
+	DenyPatterns       *string `json:"deny_patterns"`
+	MaxAutoCreated     *int    `json:"max_auto_created"`
+	RequireApproval    *bool   `json:"require_approval"`
+	AutoCreate         *bool   `json:"auto_create"`
+	DefaultEnabled     *bool   `json:"default_enabled"`
+	FileExtensions     string  `json:"file_extensions"`
+	ReviewEvents       string  `json:"review_events"`
+	IgnorePatterns     string  `json:"ignore_patterns"`
+	IsActive           *bool   `json:"is_active"`
+	CACert             *string `json:"ca_cert"` // Replaces the CA certificates; empty removes them
+	InsecureSkipVerify *bool   `json:"insecure_skip_verify"`
+	BitbucketAuth      string  `json:"bitbucket_auth"`
+	Username           string  `json:"username"`
+	OAuthKey           string  `json:"oauth_key"`
+	OAuthSecret        string  `json:"oauth_secret"`
 }
 
 func (h *GitCredentialHandler) Update(c *gin.Context) {
@@ -227,6 +307,25 @@ func (h *GitCredentialHandler) Update(c *gin.Context) {
 	if req.WebhookSecret != "" {
 		credential.WebhookSecret = req.WebhookSecret
 	}
+	if req.Namespaces != nil {
+		credential.Namespaces = strings.Join(services.ParseNamespaces(*req.Namespaces), ",")
+	}
+	if req.AllowPatterns != nil {
+		credential.AllowPatterns = *req.AllowPatterns
+	}
+	if req.DenyPatterns != nil {
+		credential.DenyPatterns = *req.DenyPatterns
+	}
+	if req.MaxAutoCreated != nil {
+		credential.MaxAutoCreated = *req.MaxAutoCreated
+	}
+	if err := validateAutoCreateGuard(credential.AllowPatterns, credential.DenyPatterns, credential.MaxAutoCreated); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.RequireApproval != nil {
+		credential.RequireApproval = *req.RequireApproval
+	}
 	if req.AutoCreate != nil {
 		credential.AutoCreate = *req.AutoCreate
 	}
@@ -245,6 +344,35 @@ func (h *GitCredentialHandler) Update(c *gin.Context) {
 	if req.IsActive != nil {
 		credential.IsActive = *req.IsActive
 	}
+	if req.CACert != nil {
+		if *req.CACert != "" {
+			if _, err := services.ParseCACerts(*req.CACert); err != nil {
+				response.BadRequest(c, "invalid ca_cert: "+err.Error())
+				return
+			}
+		}
+		credential.CACert = *req.CACert
+	}
+	if req.BitbucketAuth != "" {
+		if !services.ValidBitbucketAuth(req.BitbucketAuth) {
+			response.BadRequest(c, "invalid bitbucket_auth, expected token, oauth or basic")
+			return
+		}
+		credential.BitbucketAuth = req.BitbucketAuth
+	}
+	if req.Username != "" {
+		credential.Username = req.Username
+	}
+	if req.OAuthKey != "" {
+		credential.OAuthKey = req.OAuthKey
+	}
+	if req.OAuthSecret != "" {
+		credential.OAuthSecret = req.OAuthSecret
+	}
+	verifyChanged := req.InsecureSkipVerify != nil && *req.InsecureSkipVerify != credential.InsecureSkipVerify
+	if req.InsecureSkipVerify != nil {
+		credential.InsecureSkipVerify = *req.InsecureSkipVerify
+	}
 
 	if err := h.service.Update(credential); err != nil {
 		response.ServerError(c, err.Error())
@@ -253,11 +381,108 @@ func (h *GitCredentialHandler) Update(c *gin.Context) {
 
 	userID, _ := c.Get("user_id")
 	uid := userID.(uint)
-	services.LogInfo("GitCredential", "Update", "Git credential updated: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	services.LogInfo(c.Request.Context(), "GitCredential", "Update", "Git credential updated: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	if verifyChanged {
+		auditTLSVerify(c, credential, uid)
+	}
 
 	response.Success(c, toGitCredentialResponse(credential))
 }
 
+// validateAutoCreateGuard checks the auto-create patterns and cap of a credential
+func validateAutoCreateGuard(allow, deny string, maxAutoCreated int) error {
+	if _, err := services.ParseRepoPatterns(allow); err != nil {
+		return fmt.Errorf("allow_patterns: %w", err)
+	}
+	if _, err := services.ParseRepoPatterns(deny); err != nil {
+		return fmt.Errorf("deny_patterns: %w", err)
+	}
+	if maxAutoCreated < 0 {
+		return errors.New("max_auto_created must not be negative")
+	}
+	return nil
+}
+
+// PreviewPropagation lists the projects auto-created from the credential whose settings differ
+// from its current defaults
+// GET /api/git-credentials/:id/propagation?fields=file_extensions,ignore_patterns
+func (h *GitCredentialHandler) PreviewPropagation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	credential, err := h.service.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "credential not found")
+		return
+	}
+
+	var fields []string
+	if f := c.Query("fields"); f != "" {
+		fields = strings.Split(f, ",")
+	}
+	preview, err := h.service.PreviewPropagation(credential, fields)
+	switch {
+	case errors.Is(err, services.ErrUnknownPropagationField):
+		response.BadRequest(c, err.Error())
+		return
+	case err != nil:
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, preview)
+}
+
+type PropagateGitCredentialRequest struct {
+	ProjectIDs []uint   `json:"project_ids" binding:"required,min=1"`
+	Fields     []string `json:"fields"` // Defaults to file_extensions, review_events and ignore_patterns
+}
+
+// Propagate applies the current defaults of the credential to the selected auto-created projects
+// POST /api/git-credentials/:id/propagate
+func (h *GitCredentialHandler) Propagate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	credential, err := h.service.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "credential not found")
+		return
+	}
+
+	var req PropagateGitCredentialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.service.Propagate(credential, req.ProjectIDs, req.Fields)
+	switch {
+	case errors.Is(err, services.ErrUnknownPropagationField):
+		response.BadRequest(c, err.Error())
+		return
+	case err != nil:
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	services.LogInfo(c.Request.Context(), "GitCredential", "Propagate", "Git credential defaults propagated: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"credential_id": credential.ID,
+		"fields":        req.Fields,
+		"updated":       result.Updated,
+	})
+
+	response.Success(c, result)
+}
+
 func (h *GitCredentialHandler) Delete(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -278,7 +503,91 @@ func (h *GitCredentialHandler) Delete(c *gin.Context) {
 
 	userID, _ := c.Get("user_id")
 	uid := userID.(uint)
-	services.LogInfo("GitCredential", "Delete", "Git credential deleted: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	services.LogInfo(c.Request.Context(), "GitCredential", "Delete", "Git credential deleted: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
 
 	response.Success(c, gin.H{"message": "credential deleted"})
 }
+
+// maxCACertSize bounds an uploaded CA bundle
+const maxCACertSize = 1 << 20
+
+// UploadCACert replaces the CA certificates of a credential with a PEM bundle, sent as the
+// "file" field of a multipart form or as the request body
+func (h *GitCredentialHandler) UploadCACert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	credential, err := h.service.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "credential not found")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxCACertSize)
+	var body io.Reader = c.Request.Body
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		body = file
+	}
+	bundle, err := io.ReadAll(body)
+	if err != nil {
+		response.BadRequest(c, "failed to read CA certificate: "+err.Error())
+		return
+	}
+	if _, err := services.ParseCACerts(string(bundle)); err != nil {
+		response.BadRequest(c, "invalid CA certificate: "+err.Error())
+		return
+	}
+
+	credential.CACert = string(bundle)
+	if err := h.service.Update(credential); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	services.LogInfo(c.Request.Context(), "GitCredential", "UploadCACert", "CA certificate uploaded for Git credential: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+
+	response.Success(c, toGitCredentialResponse(credential))
+}
+
+// DeleteCACert removes the CA certificates of a credential
+func (h *GitCredentialHandler) DeleteCACert(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	credential, err := h.service.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "credential not found")
+		return
+	}
+
+	credential.CACert = ""
+	if err := h.service.Update(credential); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	services.LogInfo(c.Request.Context(), "GitCredential", "DeleteCACert", "CA certificate removed from Git credential: "+credential.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+
+	response.Success(c, toGitCredentialResponse(credential))
+}
+
+// auditTLSVerify records a change of a credential's TLS verification in the system logs
+func auditTLSVerify(c *gin.Context, credential *models.GitCredential, userID uint) {
+	extra := map[string]interface{}{"credential_id": credential.ID, "base_url": credential.BaseURL}
+	if credential.InsecureSkipVerify {
+		services.LogWarning(c.Request.Context(), "GitCredential", "TLSVerifyDisabled", "TLS verification disabled for Git credential: "+credential.Name, &userID, c.ClientIP(), c.GetHeader("User-Agent"), extra)
+		return
+	}
+	services.LogInfo(c.Request.Context(), "GitCredential", "TLSVerifyEnabled", "TLS verification enabled for Git credential: "+credential.Name, &userID, c.ClientIP(), c.GetHeader("User-Agent"), extra)
+}
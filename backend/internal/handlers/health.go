@@ -4,6 +4,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
 )
 
 // HealthHandler provides enhanced health check endpoints.
@@ -31,8 +32,15 @@ func (h *HealthHandler) CheckHealth(c *gin.Context) {
 	// Queue mode
 	taskQueue := services.GetTaskQueue()
 	queueMode := "sync"
-	if taskQueue != nil && taskQueue.IsAsync() {
-		queueMode = "async (Redis)"
+	switch taskQueue.(type) {
+	case *services.NATSQueue:
+		queueMode = "async (NATS JetStream)"
+	case *services.KafkaQueue:
+		queueMode = "async (Kafka)"
+	default:
+		if taskQueue != nil && taskQueue.IsAsync() {
+			queueMode = "async (Redis)"
+		}
 	}
 
 	// SSE connections
@@ -52,6 +60,35 @@ func (h *HealthHandler) CheckHealth(c *gin.Context) {
 			"queue_mode":      queueMode,
 			"sse_clients":     sseClients,
 			"pending_reviews": pendingCount,
+			"ai_review":       aiReviewStatus(services.GetLLMOutageStatus()),
+			"review_capacity": reviewCapacityStatus(services.GetReviewCapacityStatus()),
 		},
 	})
 }
+
+// GetSystemStatus returns the flags the UI shows as banners, such as degraded AI reviews
+// GET /api/system/status
+func (h *HealthHandler) GetSystemStatus(c *gin.Context) {
+	outage := services.GetLLMOutageStatus()
+	capacity := services.GetReviewCapacityStatus()
+	response.Success(c, gin.H{
+		"ai_review_degraded":       outage.Degraded,
+		"ai_review":                outage,
+		"review_capacity_exceeded": capacity.Overloaded,
+		"review_capacity":          capacity,
+	})
+}
+
+func aiReviewStatus(outage services.LLMOutageStatus) string {
+	if outage.Degraded {
+		return "degraded"
+	}
+	return "ok"
+}
+
+func reviewCapacityStatus(capacity services.ReviewCapacityStatus) string {
+	if capacity.Overloaded {
+		return "overloaded"
+	}
+	return "ok"
+}
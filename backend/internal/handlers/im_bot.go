@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
@@ -26,6 +27,7 @@ func (h *IMBotHandler) List(c *gin.Context) {
 		return
 	}
 
+	req.TenantID = middleware.GetTenantID(c)
 	resp, err := h.imBotService.List(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -43,7 +45,7 @@ func (h *IMBotHandler) GetByID(c *gin.Context) {
 	}
 
 	bot, err := h.imBotService.GetByID(uint(id))
-	if err != nil {
+	if err != nil || !middleware.CanAccessTenant(c, bot.TenantID) {
 		response.NotFound(c, "bot not found")
 		return
 	}
@@ -58,6 +60,12 @@ func (h *IMBotHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if err := services.CheckSecretRefs(middleware.IsPlatformAdmin(c), req.Secret); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	req.TenantID = middleware.GetWriteTenantID(c)
 	bot, err := h.imBotService.Create(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -74,11 +82,26 @@ func (h *IMBotHandler) Update(c *gin.Context) {
 		return
 	}
 
+	stored, err := h.imBotService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, stored.TenantID) {
+		response.NotFound(c, "bot not found")
+		return
+	}
+
 	var req services.UpdateIMBotRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	platformAdmin := middleware.IsPlatformAdmin(c)
+	if err := services.CheckSecretRefUpdate(platformAdmin, req.Secret, stored.Secret); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.CheckSecretRefTarget(platformAdmin, stored.Webhook, req.Webhook, stored.Secret); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
 
 	bot, err := h.imBotService.Update(uint(id), &req)
 	if err != nil {
@@ -96,6 +119,11 @@ func (h *IMBotHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if bot, err := h.imBotService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, bot.TenantID) {
+		response.NotFound(c, "bot not found")
+		return
+	}
+
 	if err := h.imBotService.Delete(uint(id)); err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -105,7 +133,7 @@ func (h *IMBotHandler) Delete(c *gin.Context) {
 }
 
 func (h *IMBotHandler) GetAllActive(c *gin.Context) {
-	bots, err := h.imBotService.GetAllActive()
+	bots, err := h.imBotService.GetAllActive(middleware.GetTenantID(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -113,3 +141,92 @@ func (h *IMBotHandler) GetAllActive(c *gin.Context) {
 
 	response.Success(c, bots)
 }
+
+// PreviewMessage renders a notification with the given template settings
+// POST /api/im-bots/preview
+func (h *IMBotHandler) PreviewMessage(c *gin.Context) {
+	var req services.PreviewIMBotMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	resp, err := h.imBotService.PreviewMessage(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// TestSend sends a sample review notification and a sample daily report to the bot and
+// returns the webhook responses
+// POST /api/im-bots/:id/test
+func (h *IMBotHandler) TestSend(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid bot id")
+		return
+	}
+
+	if bot, err := h.imBotService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, bot.TenantID) {
+		response.NotFound(c, "bot not found")
+		return
+	}
+
+	result, err := h.imBotService.SendTestMessage(uint(id))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// PreviewPayload returns the exact payloads the bot's webhook would receive for the
+// sample messages, without sending them
+// POST /api/im-bots/:id/preview
+func (h *IMBotHandler) PreviewPayload(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid bot id")
+		return
+	}
+
+	if bot, err := h.imBotService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, bot.TenantID) {
+		response.NotFound(c, "bot not found")
+		return
+	}
+
+	result, err := h.imBotService.PreviewPayload(uint(id))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// FlushDigest sends the bot's pending digest immediately
+// POST /api/im-bots/:id/digest/flush
+func (h *IMBotHandler) FlushDigest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid bot id")
+		return
+	}
+
+	if bot, err := h.imBotService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, bot.TenantID) {
+		response.NotFound(c, "bot not found")
+		return
+	}
+
+	sent, err := h.imBotService.FlushDigest(uint(id))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"sent": sent})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ImportJobHandler struct {
+	importJobService *services.ImportJobService
+}
+
+func NewImportJobHandler(db *gorm.DB) *ImportJobHandler {
+	return &ImportJobHandler{
+		importJobService: services.NewImportJobService(db),
+	}
+}
+
+// List returns commit import jobs with their progress
+// GET /api/import-jobs
+func (h *ImportJobHandler) List(c *gin.Context) {
+	var req services.ImportJobListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	resp, err := h.importJobService.List(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetByID returns an import job
+// GET /api/import-jobs/:id
+func (h *ImportJobHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid import job id")
+		return
+	}
+
+	job, err := h.importJobService.GetByID(uint(id))
+	if err != nil {
+		response.NotFound(c, "import job not found")
+		return
+	}
+
+	response.Success(c, job)
+}
+
+// Cancel stops a running import job after the page being processed
+// POST /api/import-jobs/:id/cancel
+func (h *ImportJobHandler) Cancel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid import job id")
+		return
+	}
+
+	job, err := h.importJobService.Cancel(uint(id))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "import job not found")
+	case errors.Is(err, services.ErrImportJobNotRunning):
+		response.Error(c, response.NewConflict(err.Error()))
+	case err != nil:
+		response.ServerError(c, err.Error())
+	default:
+		response.Success(c, job)
+	}
+}
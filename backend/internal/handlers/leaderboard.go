@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type LeaderboardHandler struct {
+	leaderboardService *services.LeaderboardService
+	configService      *services.SystemConfigService
+}
+
+func NewLeaderboardHandler(db *gorm.DB) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		leaderboardService: services.NewLeaderboardService(db),
+		configService:      services.NewSystemConfigService(db),
+	}
+}
+
+// Get returns the monthly leaderboards
+// GET /api/leaderboards
+func (h *LeaderboardHandler) Get(c *gin.Context) {
+	var req services.LeaderboardRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.leaderboardService.Get(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrLeaderboardDisabled) {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(resp)
+	}
+	response.Success(c, resp)
+}
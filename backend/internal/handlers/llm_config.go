@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
@@ -26,6 +27,7 @@ func (h *LLMConfigHandler) List(c *gin.Context) {
 		return
 	}
 
+	req.TenantID = middleware.GetTenantID(c)
 	resp, err := h.llmConfigService.List(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -43,7 +45,7 @@ func (h *LLMConfigHandler) GetByID(c *gin.Context) {
 	}
 
 	config, err := h.llmConfigService.GetByID(uint(id))
-	if err != nil {
+	if err != nil || !middleware.CanAccessTenant(c, config.TenantID) {
 		response.NotFound(c, "config not found")
 		return
 	}
@@ -58,6 +60,12 @@ func (h *LLMConfigHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if err := services.CheckSecretRefs(middleware.IsPlatformAdmin(c), req.APIKey); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	req.TenantID = middleware.GetWriteTenantID(c)
 	config, err := h.llmConfigService.Create(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -74,11 +82,26 @@ func (h *LLMConfigHandler) Update(c *gin.Context) {
 		return
 	}
 
+	stored, err := h.llmConfigService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, stored.TenantID) {
+		response.NotFound(c, "config not found")
+		return
+	}
+
 	var req services.UpdateLLMConfigRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	platformAdmin := middleware.IsPlatformAdmin(c)
+	if err := services.CheckSecretRefUpdate(platformAdmin, req.APIKey, stored.APIKey); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.CheckSecretRefTarget(platformAdmin, stored.BaseURL, req.BaseURL, stored.APIKey); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
 
 	config, err := h.llmConfigService.Update(uint(id), &req)
 	if err != nil {
@@ -96,6 +119,11 @@ func (h *LLMConfigHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if config, err := h.llmConfigService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, config.TenantID) {
+		response.NotFound(c, "config not found")
+		return
+	}
+
 	if err := h.llmConfigService.Delete(uint(id)); err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -104,8 +132,35 @@ func (h *LLMConfigHandler) Delete(c *gin.Context) {
 	response.Success(c, gin.H{"message": "config deleted successfully"})
 }
 
+// GET /api/llm-configs/presets
+func (h *LLMConfigHandler) GetPresets(c *gin.Context) {
+	response.Success(c, services.LLMProviderPresets)
+}
+
+// GET /api/llm-configs/:id/health
+// Checks a vLLM or TGI server and detects the context window of the configured model
+func (h *LLMConfigHandler) Health(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid config id")
+		return
+	}
+
+	config, err := h.llmConfigService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, config.TenantID) {
+		response.NotFound(c, "config not found")
+		return
+	}
+	if !services.IsSelfHostedProvider(config.Provider) {
+		response.BadRequest(c, "health checks are supported for vllm and tgi configs only")
+		return
+	}
+
+	response.Success(c, services.ProbeInferenceServer(c.Request.Context(), config))
+}
+
 func (h *LLMConfigHandler) GetActive(c *gin.Context) {
-	configs, err := h.llmConfigService.GetActive()
+	configs, err := h.llmConfigService.GetActive(middleware.GetTenantID(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -1,20 +1,54 @@
 package handlers
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
 
 type MemberHandler struct {
-	memberService *services.MemberService
+	memberService     *services.MemberService
+	enrichmentService *services.AuthorEnrichmentService
+	configService     *services.SystemConfigService
 }
 
 func NewMemberHandler(db *gorm.DB) *MemberHandler {
 	return &MemberHandler{
-		memberService: services.NewMemberService(db),
+		memberService:     services.NewMemberService(db),
+		enrichmentService: services.NewAuthorEnrichmentService(db),
+		configService:     services.NewSystemConfigService(db),
+	}
+}
+
+// privacyAliaser returns the author aliaser when privacy mode applies to the user, nil for
+// admins or when privacy mode is off
+func privacyAliaser(c *gin.Context, configService *services.SystemConfigService) *services.AuthorAliaser {
+	if middleware.GetRole(c) == "admin" {
+		return nil
+	}
+	return configService.GetAuthorAliaser()
+}
+
+// resolveAuthor maps an author alias back to the author name when privacy mode applies
+func (h *MemberHandler) resolveAuthor(c *gin.Context, aliaser *services.AuthorAliaser, author string) (string, bool) {
+	if aliaser == nil || author == "" {
+		return author, true
 	}
+	name, ok := h.memberService.ResolveAuthorAlias(aliaser, author)
+	if !ok {
+		response.NotFound(c, "member not found")
+	}
+	return name, ok
 }
 
 func (h *MemberHandler) List(c *gin.Context) {
@@ -23,6 +57,7 @@ func (h *MemberHandler) List(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.TenantID = middleware.GetTenantID(c)
 
 	result, err := h.memberService.List(&req)
 	if err != nil {
@@ -30,6 +65,9 @@ func (h *MemberHandler) List(c *gin.Context) {
 		return
 	}
 
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(result)
+	}
 	response.Success(c, result)
 }
 
@@ -39,13 +77,18 @@ func (h *MemberHandler) GetDetail(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.TenantID = middleware.GetTenantID(c)
 
 	author := c.Query("author")
 	if author == "" {
 		response.BadRequest(c, "author is required")
 		return
 	}
-	req.Author = author
+	aliaser := privacyAliaser(c, h.configService)
+	var ok bool
+	if req.Author, ok = h.resolveAuthor(c, aliaser, author); !ok {
+		return
+	}
 
 	result, err := h.memberService.GetDetail(&req)
 	if err != nil {
@@ -53,6 +96,9 @@ func (h *MemberHandler) GetDetail(c *gin.Context) {
 		return
 	}
 
+	if aliaser != nil {
+		aliaser.Apply(result)
+	}
 	response.Success(c, result)
 }
 
@@ -62,6 +108,7 @@ func (h *MemberHandler) GetTeamOverview(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.TenantID = middleware.GetTenantID(c)
 
 	result, err := h.memberService.GetTeamOverview(&req)
 	if err != nil {
@@ -69,6 +116,9 @@ func (h *MemberHandler) GetTeamOverview(c *gin.Context) {
 		return
 	}
 
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(result)
+	}
 	response.Success(c, result)
 }
 
@@ -78,6 +128,11 @@ func (h *MemberHandler) GetHeatmap(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	req.TenantID = middleware.GetTenantID(c)
+	var ok bool
+	if req.Author, ok = h.resolveAuthor(c, privacyAliaser(c, h.configService), req.Author); !ok {
+		return
+	}
 
 	result, err := h.memberService.GetHeatmap(&req)
 	if err != nil {
@@ -87,3 +142,97 @@ func (h *MemberHandler) GetHeatmap(c *gin.Context) {
 
 	response.Success(c, result)
 }
+
+// GetScorecards returns per-member scorecards for a period as JSON, or as CSV or PDF with
+// format=csv|pdf
+// GET /api/members/scorecards
+func (h *MemberHandler) GetScorecards(c *gin.Context) {
+	var req services.MemberScorecardRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	result, err := h.memberService.GetScorecards(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(result)
+	}
+
+	if req.Format == "pdf" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=scorecards_%s_%s.pdf", result.StartDate, result.EndDate))
+		c.Data(http.StatusOK, "application/pdf", services.RenderScorecardsPDF(result))
+		return
+	}
+	if req.Format != "csv" {
+		response.Success(c, result)
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=scorecards_%s_%s.csv", result.StartDate, result.EndDate))
+
+	w := csv.NewWriter(c.Writer)
+	header := []string{"Author", "Email"}
+	for _, kpi := range result.KPIs {
+		header = append(header, kpi.Key)
+		if kpi.Target != nil {
+			header = append(header, kpi.Key+"_target_met")
+		}
+	}
+	w.Write(append(header, "Top Categories"))
+
+	for _, card := range result.Items {
+		row := []string{card.Author, card.AuthorEmail}
+		for _, v := range card.KPIs {
+			row = append(row, strconv.FormatFloat(math.Round(v.Value*100)/100, 'f', -1, 64))
+			if v.Met != nil {
+				row = append(row, strconv.FormatBool(*v.Met))
+			}
+		}
+		categories := make([]string, 0, len(card.TopCategories))
+		for _, tc := range card.TopCategories {
+			categories = append(categories, fmt.Sprintf("%s:%d", tc.Category, tc.Count))
+		}
+		w.Write(append(row, strings.Join(categories, ";")))
+	}
+
+	w.Flush()
+}
+
+// Erase anonymizes an author's name and email across review logs, e.g. for a GDPR erasure request
+// POST /api/members/erase
+func (h *MemberHandler) Erase(c *gin.Context) {
+	var req services.EraseAuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.memberService.EraseAuthor(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrEraseAuthorRequired) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// Enrich starts resolving the avatars and profile URLs of authors recorded without them
+// POST /api/members/enrich
+func (h *MemberHandler) Enrich(c *gin.Context) {
+	if err := h.enrichmentService.Start(); err != nil {
+		response.Error(c, response.NewConflict(err.Error()))
+		return
+	}
+
+	response.Success(c, gin.H{"message": "Author enrichment started"})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/internal/services/webhook"
 )
 
 var startTime = time.Now()
@@ -50,22 +51,59 @@ func Metrics(c *gin.Context) {
 	if taskQueue != nil && taskQueue.IsAsync() {
 		queueAsync = 1.0
 	}
-	writeGauge(&b, "codesentry_queue_async_enabled", "Whether async queue (Redis) is enabled (1=yes, 0=no)", queueAsync)
+	writeGauge(&b, "codesentry_queue_async_enabled", "Whether an async queue (Redis, NATS or Kafka) is enabled (1=yes, 0=no)", queueAsync)
+	if syncQueue, ok := taskQueue.(*services.SyncQueue); ok {
+		writeGauge(&b, "codesentry_queue_workers", "Size of the in-process review worker pool", float64(syncQueue.Workers()))
+		writeGauge(&b, "codesentry_queue_active_tasks", "Reviews being processed by the in-process queue", float64(syncQueue.Active()))
+		writeGauge(&b, "codesentry_queue_pending_tasks", "Reviews queued or running in the in-process queue", float64(syncQueue.Pending()))
+	}
+
+	// -- Webhook metrics --
+	writeGauge(&b, "codesentry_webhook_deduplicated_events_total", "Webhook events skipped as duplicates of a recent review request", float64(webhook.DedupedEventCount()))
+	replayed, stale := webhook.ReplayRejectedCounts()
+	writeGauge(&b, "codesentry_webhook_replayed_deliveries_total", "Webhook deliveries rejected because their delivery ID was already received", float64(replayed))
+	writeGauge(&b, "codesentry_webhook_stale_deliveries_total", "Webhook deliveries rejected because the event was too old", float64(stale))
+	capacity := services.GetReviewCapacityStatus()
+	overloaded := 0.0
+	if capacity.Overloaded {
+		overloaded = 1.0
+	}
+	writeGauge(&b, "codesentry_review_capacity_exceeded", "Whether webhook deliveries are deferred because reviews are over capacity (1=yes, 0=no)", overloaded)
+	if db != nil {
+		var deferred int64
+		db.Model(&models.DeferredWebhook{}).Count(&deferred)
+		writeGauge(&b, "codesentry_webhook_deferred_deliveries", "Webhook deliveries deferred under backpressure waiting to be replayed", float64(deferred))
+	}
+
+	// -- Score parsing metrics --
+	writeCounter(&b, "codesentry_review_score_parse_failures_total", "AI reviews whose response had no parseable score", float64(services.ScoreParseFailureCount()))
+
+	// -- Review latency metrics --
+	writeReviewStageHistograms(&b, services.ReviewStageHistograms())
+
+	// -- Platform API cache metrics --
+	cacheStats := services.GetPlatformHTTPCacheStats()
+	writeGauge(&b, "codesentry_platform_api_cache_hits_total", "Platform API GETs served from cache without a request", float64(cacheStats.Hits))
+	writeGauge(&b, "codesentry_platform_api_cache_revalidated_total", "Platform API GETs served from cache after 304 Not Modified", float64(cacheStats.Revalidated))
+	writeGauge(&b, "codesentry_platform_api_cache_misses_total", "Platform API GETs fetched from the platform", float64(cacheStats.Misses))
+	writeGauge(&b, "codesentry_platform_api_cache_entries", "Platform API responses held in cache", float64(cacheStats.Entries))
 
 	// -- Review metrics --
 	if db != nil {
-		var totalReviews, pendingReviews, analyzingReviews, completedReviews, failedReviews int64
+		var totalReviews, pendingReviews, analyzingReviews, completedReviews, failedReviews, needsAttentionReviews int64
 		db.Model(&models.ReviewLog{}).Where("deleted_at IS NULL").Count(&totalReviews)
 		db.Model(&models.ReviewLog{}).Where("review_status = ? AND deleted_at IS NULL", "pending").Count(&pendingReviews)
 		db.Model(&models.ReviewLog{}).Where("review_status = ? AND deleted_at IS NULL", "analyzing").Count(&analyzingReviews)
 		db.Model(&models.ReviewLog{}).Where("review_status = ? AND deleted_at IS NULL", "completed").Count(&completedReviews)
 		db.Model(&models.ReviewLog{}).Where("review_status = ? AND deleted_at IS NULL", "failed").Count(&failedReviews)
+		db.Model(&models.ReviewLog{}).Where("review_status = ? AND deleted_at IS NULL", services.ReviewStatusNeedsAttention).Count(&needsAttentionReviews)
 
 		writeGauge(&b, "codesentry_reviews_total", "Total number of review logs", float64(totalReviews))
 		writeGauge(&b, "codesentry_reviews_pending", "Number of pending reviews", float64(pendingReviews))
 		writeGauge(&b, "codesentry_reviews_analyzing", "Number of currently analyzing reviews", float64(analyzingReviews))
 		writeGauge(&b, "codesentry_reviews_completed", "Number of completed reviews", float64(completedReviews))
 		writeGauge(&b, "codesentry_reviews_failed", "Number of failed reviews", float64(failedReviews))
+		writeGauge(&b, "codesentry_reviews_needs_attention", "Number of reviews without a parseable score", float64(needsAttentionReviews))
 
 		// Projects & Users
 		var projectCount, userCount int64
@@ -85,8 +123,31 @@ func Metrics(c *gin.Context) {
 	c.Data(200, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
 }
 
+// writeReviewStageHistograms writes the duration histogram of each review stage, labeled by stage
+func writeReviewStageHistograms(b *strings.Builder, histograms []services.ReviewStageHistogram) {
+	const name = "codesentry_review_stage_duration_seconds"
+	fmt.Fprintf(b, "# HELP %s Duration of review pipeline stages: diff_fetch, queue_wait, context_build, llm, comment_post, processing\n", name)
+	fmt.Fprintf(b, "# TYPE %s histogram\n", name)
+	for _, h := range histograms {
+		for i, bound := range h.Buckets {
+			fmt.Fprintf(b, "%s_bucket{stage=%q,le=\"%g\"} %d\n", name, h.Stage, bound, h.Counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket{stage=%q,le=\"+Inf\"} %d\n", name, h.Stage, h.Count)
+		fmt.Fprintf(b, "%s_sum{stage=%q} %g\n", name, h.Stage, h.Sum)
+		fmt.Fprintf(b, "%s_count{stage=%q} %d\n", name, h.Stage, h.Count)
+	}
+	b.WriteString("\n")
+}
+
 func writeGauge(b *strings.Builder, name, help string, value float64) {
 	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
 	fmt.Fprintf(b, "# TYPE %s gauge\n", name)
 	fmt.Fprintf(b, "%s %g\n\n", name, value)
 }
+
+// writeCounter writes a counter that only increases while the server runs; name ends in _total
+func writeCounter(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s counter\n", name)
+	fmt.Fprintf(b, "%s %g\n\n", name, value)
+}
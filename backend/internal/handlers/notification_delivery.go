@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type NotificationDeliveryHandler struct {
+	deliveryService *services.NotificationDeliveryService
+}
+
+func NewNotificationDeliveryHandler(db *gorm.DB) *NotificationDeliveryHandler {
+	return &NotificationDeliveryHandler{
+		deliveryService: services.NewNotificationDeliveryService(db),
+	}
+}
+
+// List returns the IM notification deliveries, newest first
+// GET /api/notifications/deliveries?bot_id=&project_id=&status=sent|retrying|failed
+func (h *NotificationDeliveryHandler) List(c *gin.Context) {
+	var req services.NotificationDeliveryListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	result, err := h.deliveryService.List(&req, middleware.GetTenantID(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// Resend sends a delivery's notification again and returns the new attempt
+// POST /api/notifications/deliveries/:id/resend
+func (h *NotificationDeliveryHandler) Resend(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid delivery id")
+		return
+	}
+	delivery, err := h.deliveryService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, delivery.TenantID) {
+		response.NotFound(c, "delivery not found")
+		return
+	}
+
+	attempt, err := h.deliveryService.Resend(delivery)
+	if attempt == nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Notification", "ResendDelivery", fmt.Sprintf("Delivery %d to bot %s resent by %s: %s", id, attempt.BotName, middleware.GetUsername(c), attempt.Status), &userID, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	response.Success(c, attempt)
+}
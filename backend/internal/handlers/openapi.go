@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/openapi"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/internal/services/webhook"
+)
+
+// reviewScoreQuery documents the query of GET /api/review/score
+type reviewScoreQuery struct {
+	CommitSHA string `form:"commit_sha" binding:"required"`
+}
+
+// latestReviewQuery documents the query of GET /api/projects/:id/reviews/latest
+type latestReviewQuery struct {
+	Branch   string `form:"branch"`
+	MRNumber int    `form:"mr_number"`
+}
+
+// reviewSummaryQuery documents the query of GET /api/review-logs/:id/summary
+type reviewSummaryQuery struct {
+	LLM bool `form:"llm"`
+}
+
+// reviewReportQuery documents the query of GET /api/review-logs/:id/export
+type reviewReportQuery struct {
+	Format string `form:"format"` // pdf (default) or html
+}
+
+// complianceReportQuery documents the query of GET /api/compliance/report
+type complianceReportQuery struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	Format     string `form:"format"` // json (default), csv or pdf
+}
+
+// opsJobsQuery documents the query of GET /api/ops/jobs
+type opsJobsQuery struct {
+	Hours int `form:"hours"` // Default 24, max 168
+}
+
+// featureFlagEvaluateQuery documents the query of GET /api/feature-flags/evaluate
+type featureFlagEvaluateQuery struct {
+	Key       string `form:"key"`
+	ProjectID uint   `form:"project_id"`
+}
+
+// experimentListQuery documents the query of GET /api/experiments
+type experimentListQuery struct {
+	ProjectID uint `form:"project_id"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+// openAPISpecs documents request and response types of the routes; routes without
+// an entry are still listed in the generated document.
+var openAPISpecs = map[string]openapi.RouteSpec{
+	// Auth
+	"POST /api/auth/login":              {Summary: "Log in and obtain an access token", Public: true, Request: services.LoginRequest{}, Response: services.LoginResponse{}},
+	"POST /api/auth/refresh":            {Summary: "Refresh the access token using the refresh cookie", Public: true},
+	"GET /api/auth/config":              {Summary: "Get login options", Public: true},
+	"GET /api/auth/me":                  {Summary: "Get the current user", Response: models.User{}},
+	"POST /api/auth/logout":             {Summary: "Log out", Response: messageResponse{}},
+	"GET /api/events/reviews":           {Summary: "Stream review events (SSE, token query parameter)", Public: true},
+	"GET /api/events/imports":           {Summary: "Stream import events (SSE, token query parameter)", Public: true},
+	"GET /api/tenants/:slug/branding":   {Summary: "Get tenant branding for the login page", Public: true, Response: services.TenantBranding{}},
+	"GET /api/tenant/branding":          {Summary: "Get the current tenant's branding", Response: services.TenantBranding{}},
+	"GET /api/me/notifications":         {Summary: "Get the caller's personal notification settings", Response: services.NotificationPreferenceResponse{}},
+	"PUT /api/me/notifications":         {Summary: "Update the caller's personal notification settings", Request: services.UpdateNotificationPreferenceRequest{}, Response: services.NotificationPreferenceResponse{}},
+	"POST /api/me/notifications/test":   {Summary: "Send the caller a test direct message, or the verification code of an unverified identity", Response: messageResponse{}},
+	"POST /api/me/notifications/verify": {Summary: "Verify the caller's linked IM identity", Request: services.VerifyNotificationRequest{}, Response: services.NotificationPreferenceResponse{}},
+
+	// Dashboard and reports
+	"GET /api/dashboard/stats":   {Summary: "Get dashboard statistics", Query: services.DashboardStatsRequest{}, Response: services.DashboardResponse{}},
+	"GET /api/dashboard/trends":  {Summary: "Get review trends", Query: services.DashboardTrendRequest{}, Response: services.DashboardTrendResponse{}},
+	"GET /api/dashboard/compare": {Summary: "Compare the current period with the previous one", Query: services.DashboardCompareRequest{}, Response: services.DashboardCompareResponse{}},
+	"GET /api/leaderboards":      {Summary: "Get team leaderboards", Query: services.LeaderboardRequest{}, Response: services.LeaderboardResponse{}},
+	"GET /api/daily-reports/:id": {Summary: "Get a daily report", Response: models.DailyReport{}},
+
+	// Compliance
+	"GET /api/compliance/report":               {Summary: "Get or export the compliance audit report of a date range", Query: complianceReportQuery{}, Response: services.ComplianceReport{}},
+	"POST /api/compliance/report/send":         {Summary: "Send the compliance report of a date range to IM bots", Request: SendComplianceReportRequest{}},
+	"GET /api/system-config/compliance-report": {Summary: "Get the compliance report settings", Response: services.ComplianceReportConfigResponse{}},
+	"PUT /api/system-config/compliance-report": {Summary: "Update the compliance report settings", Request: services.UpdateComplianceReportConfigRequest{}, Response: services.ComplianceReportConfigResponse{}},
+	"POST /api/coverage-gaps/scan":             {Summary: "Find recent commits that never triggered a webhook", Request: services.CoverageGapScanRequest{}, Response: services.CoverageGapScanResult{}},
+	"POST /api/coverage-gaps/review":           {Summary: "Queue reviews of a project's missed commits", Request: ReviewMissedRequest{}},
+	"GET /api/system-config/coverage-gap":      {Summary: "Get the coverage gap monitor settings", Response: services.CoverageGapConfigResponse{}},
+	"PUT /api/system-config/coverage-gap":      {Summary: "Update the coverage gap monitor settings", Request: services.UpdateCoverageGapConfigRequest{}, Response: services.CoverageGapConfigResponse{}},
+
+	// Operations
+	"GET /api/ops/queue":      {Summary: "Get the review queue length, its workers and the review capacity"},
+	"GET /api/ops/schedulers": {Summary: "Get the schedulers of the serving instance and the scheduler locks"},
+	"GET /api/ops/jobs":       {Summary: "Get recent review outcomes and import jobs", Query: opsJobsQuery{}, Response: services.RecentJobOutcomes{}},
+	"POST /api/system/reload": {Summary: "Reload config.yaml and report the applied changes and those requiring a restart", Response: config.ReloadReport{}},
+
+	// Retries
+	"GET /api/retries":             {Summary: "List failed reviews with their automatic retry schedule", Query: services.RetryListRequest{}, Response: services.RetryListResponse{}},
+	"POST /api/retries/:id/run":    {Summary: "Retry a failed review now", Response: models.ReviewLog{}},
+	"POST /api/retries/:id/cancel": {Summary: "Stop the automatic retries of a failed review", Response: models.ReviewLog{}},
+
+	// Feature flags
+	"GET /api/feature-flags":          {Summary: "List feature flags and the flags checked by the services"},
+	"GET /api/feature-flags/evaluate": {Summary: "Explain whether a feature flag is on for a project", Query: featureFlagEvaluateQuery{}, Response: services.FeatureFlagEvaluation{}},
+	"POST /api/feature-flags":         {Summary: "Create a feature flag", Request: services.CreateFeatureFlagRequest{}, Response: models.FeatureFlag{}},
+	"PUT /api/feature-flags/:id":      {Summary: "Update a feature flag", Request: services.UpdateFeatureFlagRequest{}, Response: models.FeatureFlag{}},
+	"DELETE /api/feature-flags/:id":   {Summary: "Delete a feature flag", Response: messageResponse{}},
+
+	// Experiments
+	"GET /api/experiments":             {Summary: "List prompt and model experiments", Query: experimentListQuery{}},
+	"POST /api/experiments":            {Summary: "Create a draft experiment", Request: models.Experiment{}, Response: models.Experiment{}},
+	"PUT /api/experiments/:id":         {Summary: "Update an experiment; variants and traffic are fixed while it runs", Request: services.UpdateExperimentRequest{}, Response: models.Experiment{}},
+	"DELETE /api/experiments/:id":      {Summary: "Delete an experiment that is not running", Response: messageResponse{}},
+	"POST /api/experiments/:id/start":  {Summary: "Start assigning the project's reviews to the variants", Response: models.Experiment{}},
+	"POST /api/experiments/:id/stop":   {Summary: "Stop an experiment", Response: models.Experiment{}},
+	"GET /api/experiments/:id/results": {Summary: "Compare the score, latency and token cost of the variants", Response: services.ExperimentResults{}},
+
+	// Projects
+	"GET /api/projects":                   {Summary: "List projects", Query: services.ProjectListRequest{}, Response: services.ProjectListResponse{}},
+	"GET /api/projects/:id":               {Summary: "Get a project", Response: models.Project{}},
+	"POST /api/projects":                  {Summary: "Create a project", Request: services.CreateProjectRequest{}, Response: models.Project{}},
+	"PUT /api/projects/:id":               {Summary: "Update a project", Request: services.UpdateProjectRequest{}, Response: models.Project{}},
+	"DELETE /api/projects/:id":            {Summary: "Delete a project", Response: messageResponse{}},
+	"POST /api/projects/:id/archive":      {Summary: "Archive a project, ignoring its webhooks and keeping its history", Response: models.Project{}},
+	"POST /api/projects/:id/unarchive":    {Summary: "Unarchive a project", Response: models.Project{}},
+	"POST /api/projects/:id/approve":      {Summary: "Approve a project auto-created pending approval", Response: models.Project{}},
+	"POST /api/projects/archive-inactive": {Summary: "Archive projects without reviews for a number of days", Request: services.ArchiveInactiveRequest{}, Response: services.ArchiveInactiveResponse{}},
+
+	// Review logs
+	"GET /api/review-logs":                 {Summary: "List review logs", Query: services.ReviewLogListRequest{}, Response: services.ReviewLogListResponse{}},
+	"GET /api/review-logs/:id":             {Summary: "Get a review log", Response: models.ReviewLog{}},
+	"GET /api/review-logs/:id/summary":     {Summary: "Get a compact summary of a review for IM cards and mobile widgets", Query: reviewSummaryQuery{}, Response: services.ReviewSummary{}},
+	"GET /api/review-logs/:id/export":      {Summary: "Export a printable PDF or HTML report of a review", Query: reviewReportQuery{}},
+	"GET /api/projects/:id/reviews/latest": {Summary: "Get the latest review of a branch or merge request", Query: latestReviewQuery{}, Response: services.LatestReview{}},
+	"POST /api/review-logs/:id/retry":      {Summary: "Retry a review, optionally scoped to paths or with another prompt as a new revision", Request: services.ScopedRetryRequest{}, Response: models.ReviewLog{}},
+	"PUT /api/review-logs/:id/score":       {Summary: "Override a review score", Request: services.UpdateScoreRequest{}, Response: models.ReviewLog{}},
+	"PUT /api/review-logs/:id/verdict":     {Summary: "Record a maintainer's verdict on a review", Request: services.ReviewVerdictRequest{}, Response: models.ReviewLog{}},
+	"DELETE /api/review-logs/:id/verdict":  {Summary: "Clear the verdict of a review", Response: models.ReviewLog{}},
+	"POST /api/review-logs/import":         {Summary: "Import commits, optionally with retroactive AI reviews", Request: services.ImportCommitsRequest{}, Response: services.ImportCommitsResponse{}},
+	"POST /api/members/enrich":             {Summary: "Resolve missing author avatars and profile URLs", Response: messageResponse{}},
+	"POST /api/members/erase":              {Summary: "Anonymize an author's name and email across review logs", Request: services.EraseAuthorRequest{}, Response: services.EraseAuthorResponse{}},
+	"GET /api/import-jobs":                 {Summary: "List commit import jobs", Query: services.ImportJobListRequest{}, Response: services.ImportJobListResponse{}},
+	"GET /api/import-jobs/:id":             {Summary: "Get a commit import job", Response: models.ImportJob{}},
+	"POST /api/import-jobs/:id/cancel":     {Summary: "Cancel a running commit import job", Response: models.ImportJob{}},
+
+	// LLM configs and IM bots
+	"GET /api/llm-configs":          {Summary: "List LLM configs", Query: services.LLMConfigListRequest{}, Response: services.LLMConfigListResponse{}},
+	"POST /api/llm-configs":         {Summary: "Create an LLM config", Request: services.CreateLLMConfigRequest{}, Response: models.LLMConfig{}},
+	"PUT /api/llm-configs/:id":      {Summary: "Update an LLM config", Request: services.UpdateLLMConfigRequest{}, Response: models.LLMConfig{}},
+	"GET /api/im-bots":              {Summary: "List IM bots", Query: services.IMBotListRequest{}, Response: services.IMBotListResponse{}},
+	"POST /api/im-bots":             {Summary: "Create an IM bot", Request: services.CreateIMBotRequest{}, Response: models.IMBot{}},
+	"PUT /api/im-bots/:id":          {Summary: "Update an IM bot", Request: services.UpdateIMBotRequest{}, Response: models.IMBot{}},
+	"POST /api/im-bots/:id/test":    {Summary: "Send a sample review and daily report to an IM bot", Response: services.IMBotSampleResponse{}},
+	"POST /api/im-bots/:id/preview": {Summary: "Preview the webhook payloads of the sample messages", Response: services.IMBotSampleResponse{}},
+
+	// Notification deliveries
+	"GET /api/notifications/deliveries":             {Summary: "List IM notification deliveries", Query: services.NotificationDeliveryListRequest{}, Response: services.NotificationDeliveryListResponse{}},
+	"POST /api/notifications/deliveries/:id/resend": {Summary: "Send a delivery's notification again", Response: models.NotificationDelivery{}},
+	"GET /api/system-config/notification-links":     {Summary: "Get the web UI URL linked from notification cards", Response: services.NotificationLinkConfigResponse{}},
+	"PUT /api/system-config/notification-links":     {Summary: "Update the web UI URL linked from notification cards", Request: services.UpdateNotificationLinkConfigRequest{}, Response: services.NotificationLinkConfigResponse{}},
+
+	// Tenants and configuration
+	"GET /api/tenants":                                {Summary: "List tenants", Response: []models.Tenant{}},
+	"POST /api/tenants":                               {Summary: "Create a tenant", Request: services.CreateTenantRequest{}, Response: models.Tenant{}},
+	"PUT /api/tenants/:id":                            {Summary: "Update a tenant", Request: services.UpdateTenantRequest{}, Response: models.Tenant{}},
+	"GET /api/config/export":                          {Summary: "Export configuration as YAML"},
+	"POST /api/config/apply":                          {Summary: "Apply a YAML configuration bundle (dry_run=true to preview)", Response: services.ConfigApplyResult{}},
+	"POST /api/system-config/retention/purge-deleted": {Summary: "Permanently remove rows soft-deleted more than days ago", Request: services.PurgeDeletedRequest{}},
+
+	// CI integration
+	"GET /api/review/score":         {Summary: "Get the review result of a commit", Public: true, Query: reviewScoreQuery{}, Response: webhook.ReviewScoreResponse{}},
+	"GET /api/review/status/:token": {Summary: "Poll the outcome of a webhook received with wait=true", Public: true, Response: webhook.PollStatusResponse{}},
+	"POST /api/review/sync":         {Summary: "Review a diff synchronously (X-API-Key header)", Public: true, Request: syncReviewRequest{}, Response: webhook.SyncReviewResponse{}},
+	"POST /api/review/webhook":      {Summary: "Receive a webhook from any supported platform (wait=true returns a poll_token)", Public: true},
+
+	// Webhooks (verified by signature instead of a bearer token)
+	"POST /api/webhook":                       {Summary: "Receive a webhook from any supported platform", Public: true},
+	"POST /api/webhook/gitlab":                {Summary: "Receive a GitLab webhook", Public: true},
+	"POST /api/webhook/github":                {Summary: "Receive a GitHub webhook", Public: true},
+	"POST /api/webhook/bitbucket":             {Summary: "Receive a Bitbucket webhook", Public: true},
+	"POST /api/webhook/gitlab/:project_id":    {Summary: "Receive a GitLab webhook for a project", Public: true},
+	"POST /api/webhook/github/:project_id":    {Summary: "Receive a GitHub webhook for a project", Public: true},
+	"POST /api/webhook/bitbucket/:project_id": {Summary: "Receive a Bitbucket webhook for a project", Public: true},
+}
+
+type OpenAPIHandler struct {
+	doc *openapi.Document
+}
+
+// NewOpenAPIHandler generates the OpenAPI document once from the registered routes
+func NewOpenAPIHandler(routes gin.RoutesInfo) *OpenAPIHandler {
+	info := openapi.Info{
+		Title:       "CodeSentry API",
+		Description: "AI code review platform. Responses use the {code, message, data} envelope.",
+		Version:     "1.0.0",
+	}
+	return &OpenAPIHandler{doc: openapi.Build(info, routes, openAPISpecs)}
+}
+
+// Get returns the OpenAPI 3 document
+// GET /api/openapi.json
+func (h *OpenAPIHandler) Get(c *gin.Context) {
+	c.JSON(200, h.doc)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+// OpsHandler exposes the state of the review queue and the background schedulers to admins
+type OpsHandler struct {
+	db *gorm.DB
+}
+
+func NewOpsHandler(db *gorm.DB) *OpsHandler {
+	return &OpsHandler{db: db}
+}
+
+// Queue returns the length of the review queue, its workers and the review capacity
+// GET /api/ops/queue
+func (h *OpsHandler) Queue(c *gin.Context) {
+	inspector, ok := services.GetTaskQueue().(services.QueueInspector)
+	if !ok {
+		response.ServerError(c, "task queue does not report its state")
+		return
+	}
+	stats, err := inspector.Stats(c.Request.Context())
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"queue":           stats,
+		"review_capacity": services.GetReviewCapacityStatus(),
+	})
+}
+
+// Schedulers returns the schedulers of the instance serving the request with their next
+// runs, and the scheduler locks held across instances
+// GET /api/ops/schedulers
+func (h *OpsHandler) Schedulers(c *gin.Context) {
+	locks, err := services.SchedulerLocks(h.db)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{
+		"schedulers": services.SchedulerStatuses(),
+		"locks":      locks,
+	})
+}
+
+// Jobs returns the outcomes of the reviews of the last hours (default 24, max 168) and
+// the latest import jobs
+// GET /api/ops/jobs
+func (h *OpsHandler) Jobs(c *gin.Context) {
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	hours = min(max(hours, 1), 168)
+
+	outcomes, err := services.RecentJobs(h.db, time.Now().Add(-time.Duration(hours)*time.Hour))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, outcomes)
+}
+
+// ReloadConfig reads config.yaml again, applies the settings safe to change at runtime and
+// reports every change, including those waiting for a restart
+// POST /api/system/reload
+func (h *OpsHandler) ReloadConfig(c *gin.Context) {
+	report, err := services.ReloadConfig(services.ConfigReloadAPI)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "System", "ConfigReloadRequested", fmt.Sprintf("Config reload requested by %s: %d changes", middleware.GetUsername(c), len(report.Changes)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	response.Success(c, report)
+}
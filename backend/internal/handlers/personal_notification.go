@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type PersonalNotificationHandler struct {
+	db      *gorm.DB
+	service *services.PersonalNotificationService
+}
+
+func NewPersonalNotificationHandler(db *gorm.DB) *PersonalNotificationHandler {
+	return &PersonalNotificationHandler{
+		db:      db,
+		service: services.NewPersonalNotificationService(db),
+	}
+}
+
+func (h *PersonalNotificationHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := h.db.First(&user, middleware.GetUserID(c)).Error; err != nil {
+		response.NotFound(c, "user not found")
+		return nil, false
+	}
+	return &user, true
+}
+
+// Get returns the caller's linked IM identity, subscriptions and the bots they can pick
+// GET /api/me/notifications
+func (h *PersonalNotificationHandler) Get(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	pref, err := h.service.GetPreference(user)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, pref)
+}
+
+// Update changes the caller's personal notification settings
+// PUT /api/me/notifications
+func (h *PersonalNotificationHandler) Update(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var req services.UpdateNotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	pref, err := h.service.UpdatePreference(user, &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, pref)
+}
+
+// Test sends the caller a direct message to check their linked IM identity. Until the identity
+// is verified, the message carries the code to verify it with.
+// POST /api/me/notifications/test
+func (h *PersonalNotificationHandler) Test(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	pref, err := h.service.GetPreference(user)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	if pref.VerifiedAt == nil {
+		if err := h.service.SendVerificationCode(&pref.UserNotificationPreference); err != nil {
+			response.BadRequest(c, "failed to send verification code: "+err.Error())
+			return
+		}
+		response.Success(c, gin.H{"message": "verification code sent"})
+		return
+	}
+	if err := h.service.SendDirectMessage(&pref.UserNotificationPreference, "✅ CodeSentry personal notifications are set up for "+user.Username); err != nil {
+		response.BadRequest(c, "failed to send test message: "+err.Error())
+		return
+	}
+	response.Success(c, gin.H{"message": "test message sent"})
+}
+
+// Verify confirms the caller's linked IM identity with the code sent by Test
+// POST /api/me/notifications/verify
+func (h *PersonalNotificationHandler) Verify(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	var req services.VerifyNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	pref, err := h.service.Verify(user, req.Code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, services.ErrInvalidVerificationCode) {
+			response.BadRequest(c, services.ErrInvalidVerificationCode.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, pref)
+}
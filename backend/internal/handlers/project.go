@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
@@ -12,11 +14,15 @@ import (
 
 type ProjectHandler struct {
 	projectService *services.ProjectService
+	riskService    *services.RiskHeatmapService
+	languageStats  *services.LanguageStatsService
 }
 
 func NewProjectHandler(db *gorm.DB) *ProjectHandler {
 	return &ProjectHandler{
 		projectService: services.NewProjectService(db),
+		riskService:    services.NewRiskHeatmapService(db),
+		languageStats:  services.NewLanguageStatsService(db),
 	}
 }
 
@@ -29,6 +35,7 @@ func (h *ProjectHandler) List(c *gin.Context) {
 		return
 	}
 
+	req.TenantID = middleware.GetTenantID(c)
 	resp, err := h.projectService.List(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -48,7 +55,7 @@ func (h *ProjectHandler) GetByID(c *gin.Context) {
 	}
 
 	project, err := h.projectService.GetByID(uint(id))
-	if err != nil {
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
 		response.NotFound(c, "project not found")
 		return
 	}
@@ -56,6 +63,67 @@ func (h *ProjectHandler) GetByID(c *gin.Context) {
 	response.Success(c, project)
 }
 
+// GetRiskHeatmap ranks the files or directories of a project by review risk
+// GET /api/projects/:id/risk-heatmap
+func (h *ProjectHandler) GetRiskHeatmap(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	var req services.RiskHeatmapRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	project, err := h.projectService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	resp, err := h.riskService.GetHeatmap(project, &req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetLanguageStats returns the changed lines and scores of a project's reviews per language
+// GET /api/projects/:id/languages
+func (h *ProjectHandler) GetLanguageStats(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	var req services.LanguageStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	project, err := h.projectService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	req.ProjectID = project.ID
+	stats, err := h.languageStats.Get(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, stats)
+}
+
 // Create creates a new project
 // POST /api/projects
 func (h *ProjectHandler) Create(c *gin.Context) {
@@ -65,7 +133,13 @@ func (h *ProjectHandler) Create(c *gin.Context) {
 		return
 	}
 
+	if err := services.CheckSecretRefs(middleware.IsPlatformAdmin(c), req.AccessToken, req.WebhookSecret); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
 	userID := middleware.GetUserID(c)
+	req.TenantID = middleware.GetWriteTenantID(c)
 	project, err := h.projectService.Create(&req, userID)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -84,11 +158,30 @@ func (h *ProjectHandler) Update(c *gin.Context) {
 		return
 	}
 
+	stored, err := h.projectService.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, stored.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
 	var req services.UpdateProjectRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	platformAdmin := middleware.IsPlatformAdmin(c)
+	if err := services.CheckSecretRefUpdate(platformAdmin, req.AccessToken, stored.AccessToken); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.CheckSecretRefUpdate(platformAdmin, req.WebhookSecret, stored.WebhookSecret); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.CheckSecretRefTarget(platformAdmin, stored.URL, req.URL, stored.AccessToken); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
 
 	project, err := h.projectService.Update(uint(id), &req)
 	if err != nil {
@@ -108,6 +201,11 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 		return
 	}
 
+	if project, err := h.projectService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
 	if err := h.projectService.Delete(uint(id)); err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -116,6 +214,118 @@ func (h *ProjectHandler) Delete(c *gin.Context) {
 	response.Success(c, gin.H{"message": "project deleted successfully"})
 }
 
+// Archive archives a project: its webhooks are ignored and its history is kept
+// POST /api/projects/:id/archive
+func (h *ProjectHandler) Archive(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// Unarchive makes an archived project active again
+// POST /api/projects/:id/unarchive
+func (h *ProjectHandler) Unarchive(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *ProjectHandler) setArchived(c *gin.Context, archived bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	if project, err := h.projectService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	var project *models.Project
+	if archived {
+		project, err = h.projectService.Archive(uint(id))
+	} else {
+		project, err = h.projectService.Unarchive(uint(id))
+	}
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, project)
+}
+
+// Approve enables a project that was auto-created pending approval
+// POST /api/projects/:id/approve
+func (h *ProjectHandler) Approve(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	if project, err := h.projectService.GetByID(uint(id)); err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	project, err := h.projectService.Approve(uint(id))
+	if err != nil {
+		if errors.Is(err, services.ErrProjectNotPending) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	uid := userID.(uint)
+	services.LogInfo(c.Request.Context(), "Project", "Approve", "Auto-created project approved: "+project.Name, &uid, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"project_id": project.ID,
+	})
+	response.Success(c, project)
+}
+
+// ArchiveInactive archives the projects without reviews for a number of days
+// POST /api/projects/archive-inactive
+func (h *ProjectHandler) ArchiveInactive(c *gin.Context) {
+	var req services.ArchiveInactiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	req.TenantID = middleware.GetTenantID(c)
+	resp, err := h.projectService.ArchiveInactive(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// DryRunPathPatterns tests file extensions, include and ignore patterns against a sample file list
+// POST /api/projects/path-patterns/dry-run
+func (h *ProjectHandler) DryRunPathPatterns(c *gin.Context) {
+	var req services.PathPatternDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, services.DryRunPathPatterns(&req))
+}
+
+// Validate checks a project configuration before it is saved
+// POST /api/projects/validate
+func (h *ProjectHandler) Validate(c *gin.Context) {
+	var req services.ValidateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+	response.Success(c, h.projectService.Validate(c.Request.Context(), &req))
+}
+
 // GetDefaultPrompt returns the default AI review prompt
 // GET /api/projects/default-prompt
 func (h *ProjectHandler) GetDefaultPrompt(c *gin.Context) {
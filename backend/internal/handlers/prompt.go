@@ -71,6 +71,12 @@ func (h *PromptHandler) GetDefault(c *gin.Context) {
 	response.Success(c, prompt)
 }
 
+// GetVariables lists the placeholders prompt templates can use
+// GET /api/prompts/variables
+func (h *PromptHandler) GetVariables(c *gin.Context) {
+	response.Success(c, services.PromptVariables)
+}
+
 func (h *PromptHandler) GetAllActive(c *gin.Context) {
 	prompts, err := h.service.GetAllActive()
 	if err != nil {
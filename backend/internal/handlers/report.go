@@ -4,7 +4,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
@@ -59,6 +61,7 @@ type ReportResponse struct {
 func (h *ReportHandler) GetReport(c *gin.Context) {
 	period := c.DefaultQuery("period", "weekly") // weekly or monthly
 	projectID := c.Query("project_id")
+	tenantID := middleware.GetTenantID(c)
 
 	now := time.Now()
 	var currentStart, previousStart, previousEnd time.Time
@@ -77,14 +80,14 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 		previousStart = currentStart.AddDate(0, 0, -7)
 	}
 
-	current := h.getPeriodStats(period, currentStart, now, projectID)
-	previous := h.getPeriodStats(period, previousStart, previousEnd, projectID)
+	current := h.getPeriodStats(period, currentStart, now, projectID, tenantID)
+	previous := h.getPeriodStats(period, previousStart, previousEnd, projectID, tenantID)
 
 	// Daily trend for last 14 days
-	trend := h.getDailyTrend(now.AddDate(0, 0, -13), now, projectID)
+	trend := h.getDailyTrend(now.AddDate(0, 0, -13), now, projectID, tenantID)
 
 	// Author rankings for current period
-	rankings := h.getAuthorRankings(currentStart, now, projectID)
+	rankings := h.getAuthorRankings(currentStart, now, projectID, tenantID)
 
 	response.Success(c, ReportResponse{
 		Current:  current,
@@ -94,48 +97,27 @@ func (h *ReportHandler) GetReport(c *gin.Context) {
 	})
 }
 
-func (h *ReportHandler) getPeriodStats(period string, start, end time.Time, projectID string) PeriodStats {
+func (h *ReportHandler) getPeriodStats(period string, start, end time.Time, projectID string, tenantID uint) PeriodStats {
 	stats := PeriodStats{
 		Period:    period,
 		StartDate: start.Format("2006-01-02"),
 		EndDate:   end.Format("2006-01-02"),
 	}
 
-	query := h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ?", start, end)
-	if projectID != "" {
-		query = query.Where("project_id = ?", projectID)
-	}
-
-	query.Count(&stats.TotalReviews)
-
-	h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ? AND review_status = 'completed'", start, end).
-		Where(h.projectFilter(projectID)).Count(&stats.Completed)
-	h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ? AND review_status = 'failed'", start, end).
-		Where(h.projectFilter(projectID)).Count(&stats.Failed)
+	h.reviewLogs(start, end, projectID, tenantID).Count(&stats.TotalReviews)
+	h.reviewLogs(start, end, projectID, tenantID).Where("review_status = 'completed'").Count(&stats.Completed)
+	h.reviewLogs(start, end, projectID, tenantID).Where("review_status = 'failed'").Count(&stats.Failed)
 
 	var avgScore *float64
-	h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ? AND score IS NOT NULL", start, end).
-		Where(h.projectFilter(projectID)).Select("AVG(score)").Scan(&avgScore)
+	h.reviewLogs(start, end, projectID, tenantID).Where("score IS NOT NULL").Select("AVG(score)").Scan(&avgScore)
 	if avgScore != nil {
 		stats.AvgScore = *avgScore
 	}
 
 	var totalFiles, totalAdds, totalDels *int64
-	baseQ := h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ?", start, end)
-	if projectID != "" {
-		baseQ = baseQ.Where("project_id = ?", projectID)
-	}
-	baseQ.Select("SUM(files_changed)").Scan(&totalFiles)
-	baseQ2 := h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ?", start, end)
-	if projectID != "" {
-		baseQ2 = baseQ2.Where("project_id = ?", projectID)
-	}
-	baseQ2.Select("SUM(additions)").Scan(&totalAdds)
-	baseQ3 := h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ?", start, end)
-	if projectID != "" {
-		baseQ3 = baseQ3.Where("project_id = ?", projectID)
-	}
-	baseQ3.Select("SUM(deletions)").Scan(&totalDels)
+	h.reviewLogs(start, end, projectID, tenantID).Select("SUM(files_changed)").Scan(&totalFiles)
+	h.reviewLogs(start, end, projectID, tenantID).Select("SUM(additions)").Scan(&totalAdds)
+	h.reviewLogs(start, end, projectID, tenantID).Select("SUM(deletions)").Scan(&totalDels)
 	if totalFiles != nil {
 		stats.TotalFiles = *totalFiles
 	}
@@ -146,45 +128,36 @@ func (h *ReportHandler) getPeriodStats(period string, start, end time.Time, proj
 		stats.TotalDels = *totalDels
 	}
 
-	h.db.Model(&models.ReviewLog{}).Where("created_at BETWEEN ? AND ?", start, end).
-		Where(h.projectFilter(projectID)).Distinct("author").Count(&stats.ActiveAuthors)
+	h.reviewLogs(start, end, projectID, tenantID).Distinct("author").Count(&stats.ActiveAuthors)
 
 	return stats
 }
 
-func (h *ReportHandler) projectFilter(projectID string) string {
+// reviewLogs returns a query of the tenant's review logs created in the range, optionally
+// limited to one project
+func (h *ReportHandler) reviewLogs(start, end time.Time, projectID string, tenantID uint) *gorm.DB {
+	query := services.ScopeStatsReviewLogs(h.db.Model(&models.ReviewLog{}), tenantID).
+		Where("created_at BETWEEN ? AND ?", start, end)
 	if projectID != "" {
-		return "project_id = " + projectID
+		query = query.Where("project_id = ?", projectID)
 	}
-	return "1=1"
+	return query
 }
 
-func (h *ReportHandler) getDailyTrend(start, end time.Time, projectID string) []TrendItem {
+func (h *ReportHandler) getDailyTrend(start, end time.Time, projectID string, tenantID uint) []TrendItem {
 	var results []TrendItem
-	query := h.db.Model(&models.ReviewLog{}).
+	h.reviewLogs(start, end, projectID, tenantID).
 		Select("DATE(created_at) as date, COUNT(*) as reviews, COALESCE(AVG(score),0) as avg_score, COALESCE(SUM(additions),0) as additions, COALESCE(SUM(deletions),0) as deletions").
-		Where("created_at BETWEEN ? AND ?", start, end).
-		Group("DATE(created_at)").Order("date ASC")
-
-	if projectID != "" {
-		query = query.Where("project_id = ?", projectID)
-	}
-
-	query.Scan(&results)
+		Group("DATE(created_at)").Order("date ASC").
+		Scan(&results)
 	return results
 }
 
-func (h *ReportHandler) getAuthorRankings(start, end time.Time, projectID string) []AuthorRanking {
+func (h *ReportHandler) getAuthorRankings(start, end time.Time, projectID string, tenantID uint) []AuthorRanking {
 	var results []AuthorRanking
-	query := h.db.Model(&models.ReviewLog{}).
+	h.reviewLogs(start, end, projectID, tenantID).
 		Select("author, COUNT(*) as review_count, COALESCE(AVG(score),0) as avg_score, COALESCE(SUM(additions),0) as total_additions, COALESCE(SUM(deletions),0) as total_deletions").
-		Where("created_at BETWEEN ? AND ?", start, end).
-		Group("author").Order("review_count DESC").Limit(20)
-
-	if projectID != "" {
-		query = query.Where("project_id = ?", projectID)
-	}
-
-	query.Scan(&results)
+		Group("author").Order("review_count DESC").Limit(20).
+		Scan(&results)
 	return results
 }
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ReportPublisherHandler struct {
+	service *services.ReportPublisherService
+}
+
+func NewReportPublisherHandler(db *gorm.DB) *ReportPublisherHandler {
+	return &ReportPublisherHandler{service: services.NewReportPublisherService(db)}
+}
+
+// getAccessible loads the publisher from the :id param, writing the error response on failure
+func (h *ReportPublisherHandler) getAccessible(c *gin.Context) *models.ReportPublisher {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return nil
+	}
+	publisher, err := h.service.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, publisher.TenantID) {
+		response.NotFound(c, "publisher not found")
+		return nil
+	}
+	return publisher
+}
+
+// GET /api/report-publishers
+func (h *ReportPublisherHandler) List(c *gin.Context) {
+	publishers, err := h.service.List(middleware.GetTenantID(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, publishers)
+}
+
+// POST /api/report-publishers
+func (h *ReportPublisherHandler) Create(c *gin.Context) {
+	var publisher models.ReportPublisher
+	if err := c.ShouldBindJSON(&publisher); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.ValidateReportPublisher(&publisher); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if scope := middleware.GetTenantID(c); scope > 0 {
+		publisher.TenantID = scope
+	}
+	if err := h.service.Create(&publisher); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, publisher)
+}
+
+// PUT /api/report-publishers/:id
+func (h *ReportPublisherHandler) Update(c *gin.Context) {
+	publisher := h.getAccessible(c)
+	if publisher == nil {
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if middleware.GetTenantID(c) > 0 {
+		delete(updates, "tenant_id")
+	}
+	updated, err := h.service.Update(publisher.ID, updates)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, updated)
+}
+
+// DELETE /api/report-publishers/:id
+func (h *ReportPublisherHandler) Delete(c *gin.Context) {
+	publisher := h.getAccessible(c)
+	if publisher == nil {
+		return
+	}
+	if err := h.service.Delete(publisher.ID); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"message": "deleted"})
+}
+
+// POST /api/report-publishers/:id/test
+func (h *ReportPublisherHandler) TestConnection(c *gin.Context) {
+	publisher := h.getAccessible(c)
+	if publisher == nil {
+		return
+	}
+	if err := h.service.TestConnection(publisher); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"message": "connection successful"})
+}
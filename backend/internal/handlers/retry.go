@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type RetryHandler struct {
+	retryService     *services.RetryService
+	reviewLogService *services.ReviewLogService
+}
+
+func NewRetryHandler(db *gorm.DB, aiCfg *config.OpenAIConfig) *RetryHandler {
+	return &RetryHandler{
+		retryService:     services.NewRetryService(db, aiCfg),
+		reviewLogService: services.NewReviewLogService(db),
+	}
+}
+
+// accessibleID parses the :id param of a review the caller's tenant owns, writing the error
+// response on failure
+func (h *RetryHandler) accessibleID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid review log id")
+		return 0, false
+	}
+	log, err := h.reviewLogService.GetByID(uint(id))
+	if err != nil || log.Project == nil || !middleware.CanAccessTenant(c, log.Project.TenantID) {
+		response.NotFound(c, "review log not found")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// List returns failed reviews with their next automatic retry
+// GET /api/retries?state=scheduled|exhausted|canceled&project_id=
+func (h *RetryHandler) List(c *gin.Context) {
+	var req services.RetryListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	result, err := h.retryService.ListRetries(&req, middleware.GetTenantID(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// Run retries a failed review now
+// POST /api/retries/:id/run
+func (h *RetryHandler) Run(c *gin.Context) {
+	id, ok := h.accessibleID(c)
+	if !ok {
+		return
+	}
+	review, err := h.retryService.RetryNow(id)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Retry", "RetryNow", fmt.Sprintf("Review %d retried by %s: %s", id, middleware.GetUsername(c), review.ReviewStatus), &userID, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	response.Success(c, review)
+}
+
+// Cancel stops the automatic retries of a failed review
+// POST /api/retries/:id/cancel
+func (h *RetryHandler) Cancel(c *gin.Context) {
+	id, ok := h.accessibleID(c)
+	if !ok {
+		return
+	}
+	review, err := h.retryService.CancelRetries(id)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "Retry", "CancelRetries", fmt.Sprintf("Automatic retries of review %d canceled by %s", id, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), nil)
+	response.Success(c, review)
+}
+
+func (h *RetryHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "review log not found")
+	case errors.Is(err, services.ErrNotRetryable):
+		response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrRetryInProgress):
+		response.Error(c, response.NewConflict(err.Error()))
+	default:
+		response.ServerError(c, err.Error())
+	}
+}
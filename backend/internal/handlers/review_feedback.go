@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
@@ -50,7 +52,11 @@ func (h *ReviewFeedbackHandler) Create(c *gin.Context) {
 	}
 
 	ctx := context.Background()
-	if err := h.service.Create(ctx, feedback); err != nil {
+	if err := h.service.Create(ctx, feedback, middleware.GetTenantID(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			response.NotFound(c, "review not found")
+			return
+		}
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -66,7 +72,7 @@ func (h *ReviewFeedbackHandler) ListByReview(c *gin.Context) {
 		return
 	}
 
-	feedbacks, err := h.service.ListByReviewLog(uint(id))
+	feedbacks, err := h.service.ListByReviewLog(uint(id), middleware.GetTenantID(c))
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -83,7 +89,7 @@ func (h *ReviewFeedbackHandler) Get(c *gin.Context) {
 		return
 	}
 
-	feedback, err := h.service.GetByID(uint(id))
+	feedback, err := h.service.GetByID(uint(id), middleware.GetTenantID(c))
 	if err != nil {
 		response.NotFound(c, "feedback not found")
 		return
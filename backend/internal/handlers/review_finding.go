@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ReviewFindingHandler struct {
+	findingService *services.ReviewFindingService
+	configService  *services.SystemConfigService
+}
+
+func NewReviewFindingHandler(db *gorm.DB) *ReviewFindingHandler {
+	return &ReviewFindingHandler{
+		findingService: services.NewReviewFindingService(db),
+		configService:  services.NewSystemConfigService(db),
+	}
+}
+
+// GetTrends returns finding counts per category over time
+// GET /api/findings/trends
+func (h *ReviewFindingHandler) GetTrends(c *gin.Context) {
+	var req services.FindingTrendRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.findingService.GetTrends(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetByAuthor returns finding counts per author and category
+// GET /api/findings/authors
+func (h *ReviewFindingHandler) GetByAuthor(c *gin.Context) {
+	var req services.FindingAuthorRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.findingService.GetByAuthor(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	if aliaser := privacyAliaser(c, h.configService); aliaser != nil {
+		aliaser.Apply(resp)
+	}
+	response.Success(c, resp)
+}
+
+// GetRecurring returns findings reported repeatedly within a project
+// GET /api/findings/recurring
+func (h *ReviewFindingHandler) GetRecurring(c *gin.Context) {
+	var req services.FindingRecurringRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.findingService.GetRecurring(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// GetCompliance returns findings on infrastructure-as-code files per cloud compliance category
+// GET /api/findings/compliance
+func (h *ReviewFindingHandler) GetCompliance(c *gin.Context) {
+	var req services.FindingComplianceRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	req.TenantID = middleware.GetTenantID(c)
+
+	resp, err := h.findingService.GetCompliance(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, resp)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ReviewHookHandler struct {
+	service *services.ReviewHookService
+}
+
+func NewReviewHookHandler(db *gorm.DB) *ReviewHookHandler {
+	return &ReviewHookHandler{service: services.NewReviewHookService(db)}
+}
+
+// getAccessible loads the hook from the :id param, writing the error response on failure
+func (h *ReviewHookHandler) getAccessible(c *gin.Context) *models.ReviewHook {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return nil
+	}
+	hook, err := h.service.GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, hook.TenantID) {
+		response.NotFound(c, "review hook not found")
+		return nil
+	}
+	return hook
+}
+
+// GET /api/review-hooks
+func (h *ReviewHookHandler) List(c *gin.Context) {
+	hooks, err := h.service.List(middleware.GetTenantID(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, hooks)
+}
+
+// POST /api/review-hooks
+func (h *ReviewHookHandler) Create(c *gin.Context) {
+	var hook models.ReviewHook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := services.ValidateReviewHook(&hook); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if scope := middleware.GetTenantID(c); scope > 0 {
+		hook.TenantID = scope
+	}
+	if err := h.service.Create(&hook); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, hook)
+}
+
+// PUT /api/review-hooks/:id
+func (h *ReviewHookHandler) Update(c *gin.Context) {
+	hook := h.getAccessible(c)
+	if hook == nil {
+		return
+	}
+	var updates map[string]interface{}
+	if err := c.ShouldBindJSON(&updates); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if middleware.GetTenantID(c) > 0 {
+		delete(updates, "tenant_id")
+	}
+	updated, err := h.service.Update(hook.ID, updates)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, updated)
+}
+
+// DELETE /api/review-hooks/:id
+func (h *ReviewHookHandler) Delete(c *gin.Context) {
+	hook := h.getAccessible(c)
+	if hook == nil {
+		return
+	}
+	if err := h.service.Delete(hook.ID); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"message": "deleted"})
+}
+
+// POST /api/review-hooks/:id/test
+func (h *ReviewHookHandler) Test(c *gin.Context) {
+	hook := h.getAccessible(c)
+	if hook == nil {
+		return
+	}
+	out, err := h.service.Test(c.Request.Context(), hook)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, out)
+}
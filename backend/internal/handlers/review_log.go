@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
+	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/internal/services/webhook"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
@@ -17,6 +23,8 @@ type ReviewLogHandler struct {
 	reviewLogService     *services.ReviewLogService
 	retryService         *services.RetryService
 	importCommitsService *services.ImportCommitsService
+	webhookService       *webhook.Service
+	aiService            *services.AIService
 }
 
 func NewReviewLogHandler(db *gorm.DB, aiCfg *config.OpenAIConfig) *ReviewLogHandler {
@@ -25,6 +33,8 @@ func NewReviewLogHandler(db *gorm.DB, aiCfg *config.OpenAIConfig) *ReviewLogHand
 		reviewLogService:     services.NewReviewLogService(db),
 		retryService:         services.NewRetryService(db, aiCfg),
 		importCommitsService: services.NewImportCommitsService(db),
+		webhookService:       webhook.NewService(db, aiCfg),
+		aiService:            services.NewAIService(db, aiCfg),
 	}
 }
 
@@ -35,6 +45,7 @@ func (h *ReviewLogHandler) List(c *gin.Context) {
 		return
 	}
 
+	req.TenantID = middleware.GetTenantID(c)
 	resp, err := h.reviewLogService.List(&req)
 	if err != nil {
 		response.ServerError(c, err.Error())
@@ -52,7 +63,7 @@ func (h *ReviewLogHandler) GetByID(c *gin.Context) {
 	}
 
 	log, err := h.reviewLogService.GetByID(uint(id))
-	if err != nil {
+	if err != nil || (log.Project != nil && !middleware.CanAccessTenant(c, log.Project.TenantID)) {
 		response.NotFound(c, "review log not found")
 		return
 	}
@@ -60,6 +71,235 @@ func (h *ReviewLogHandler) GetByID(c *gin.Context) {
 	response.Success(c, log)
 }
 
+// Render returns a review rendered for embedding in wikis and portals
+// GET /api/review-logs/:id/render?format=html|markdown
+func (h *ReviewLogHandler) Render(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid review log id")
+		return
+	}
+
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "markdown" {
+		response.BadRequest(c, "format must be html or markdown")
+		return
+	}
+
+	log, err := h.reviewLogService.GetByID(uint(id))
+	if err != nil || log.Project == nil || !middleware.CanAccessTenant(c, log.Project.TenantID) {
+		response.NotFound(c, "review log not found")
+		return
+	}
+	minScore := h.reviewLogService.EffectiveMinScore(log.Project)
+
+	if format == "markdown" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(services.RenderReviewMarkdown(log, minScore)))
+		return
+	}
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(services.RenderReviewHTML(log, minScore)))
+}
+
+// ExportReport returns a branded, printable report of a review for audit packets: metadata,
+// score breakdown, findings and diff statistics followed by the review itself
+// GET /api/review-logs/:id/export?format=pdf|html
+func (h *ReviewLogHandler) ExportReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid review log id")
+		return
+	}
+
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		response.BadRequest(c, "format must be pdf or html")
+		return
+	}
+
+	log, err := h.reviewLogService.GetByID(uint(id))
+	if err != nil || log.Project == nil || !middleware.CanAccessTenant(c, log.Project.TenantID) {
+		response.NotFound(c, "review log not found")
+		return
+	}
+	report, err := h.reviewLogService.BuildReport(log)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "ReviewLog", "ExportReport", fmt.Sprintf("Report of review %d exported as %s by %s", log.ID, format, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"review_log_id": log.ID,
+		"project_id":    log.ProjectID,
+		"format":        format,
+	})
+
+	filename := fmt.Sprintf("review-%d.%s", log.ID, format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "html" {
+		c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(services.RenderReviewReportHTML(report)))
+		return
+	}
+	c.Data(http.StatusOK, "application/pdf", services.RenderReviewReportPDF(report))
+}
+
+// Summary returns a compact summary of a review for IM cards and mobile widgets: score,
+// pass/fail, top findings and links. With llm=true the headline is written by a short call
+// to the LLM the review was made with, falling back to the review text on failure; the headline
+// is cached on the review until it is redone.
+// GET /api/review-logs/:id/summary?llm=true
+func (h *ReviewLogHandler) Summary(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid review log id")
+		return
+	}
+
+	log, err := h.reviewLogService.GetByID(uint(id))
+	if err != nil || log.Project == nil || !middleware.CanAccessTenant(c, log.Project.TenantID) {
+		response.NotFound(c, "review log not found")
+		return
+	}
+	summary, err := h.reviewLogService.Summary(log)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	if c.Query("llm") == "true" && log.ReviewStatus == "completed" {
+		headline, err := h.aiService.ReviewHeadline(c.Request.Context(), log)
+		if err != nil {
+			logger.Ctx(c.Request.Context()).Warn().Err(err).Uint("review_log_id", log.ID).Msg("Failed to summarize review, using the review text")
+		} else {
+			summary.Headline, summary.Source = headline, services.SummarySourceLLM
+		}
+	}
+	response.Success(c, summary)
+}
+
+// GetLatest returns the most recent review of a branch or merge request
+// GET /api/projects/:id/reviews/latest?branch=main or ?mr_number=12
+func (h *ReviewLogHandler) GetLatest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	branch := c.Query("branch")
+	var mrNumber *int
+	if v := c.Query("mr_number"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			response.BadRequest(c, "invalid mr_number")
+			return
+		}
+		mrNumber = &n
+	}
+	if branch == "" && mrNumber == nil {
+		response.BadRequest(c, "branch or mr_number is required")
+		return
+	}
+
+	project, err := services.NewProjectService(h.db).GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	latest, err := h.reviewLogService.GetLatest(project, branch, mrNumber)
+	if err != nil {
+		response.NotFound(c, "no review found")
+		return
+	}
+
+	response.Success(c, latest)
+}
+
+// TriggerReview queues a review of a commit or a range of the project, fetching the diff from the platform
+// POST /api/projects/:id/review
+func (h *ReviewLogHandler) TriggerReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	var req webhook.TriggerReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	project, err := services.NewProjectService(h.db).GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+	if project.Archived {
+		response.BadRequest(c, "project is archived")
+		return
+	}
+
+	reviewLog, err := h.webhookService.TriggerReview(c.Request.Context(), project, &req, middleware.GetUsername(c))
+	if err != nil {
+		if errors.Is(err, webhook.ErrTriggerNoChanges) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "ReviewLog", "TriggerReview", fmt.Sprintf("Review of project %s triggered by %s", project.Name, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"review_log_id": reviewLog.ID,
+		"project_id":    project.ID,
+		"commit_sha":    req.CommitSHA,
+		"from_ref":      req.FromRef,
+		"to_ref":        req.ToRef,
+	})
+
+	response.Created(c, reviewLog)
+}
+
+// SimulateReview runs a raw diff through the project's review pipeline and returns the
+// artifact of each step, without recording the review or posting anything
+// POST /api/projects/:id/simulate
+func (h *ReviewLogHandler) SimulateReview(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid project id")
+		return
+	}
+
+	var req webhook.SimulateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.EventType != "" && req.EventType != "push" && req.EventType != "merge_request" {
+		response.BadRequest(c, "event_type must be push or merge_request")
+		return
+	}
+
+	project, err := services.NewProjectService(h.db).GetByID(uint(id))
+	if err != nil || !middleware.CanAccessTenant(c, project.TenantID) {
+		response.NotFound(c, "project not found")
+		return
+	}
+
+	response.Success(c, h.webhookService.SimulateReview(c.Request.Context(), project, &req))
+}
+
+// Retry retries a failed review. With paths or a prompt in the body, it re-runs any review
+// focused on those paths or with that prompt and returns the new revision.
 func (h *ReviewLogHandler) Retry(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -67,7 +307,36 @@ func (h *ReviewLogHandler) Retry(c *gin.Context) {
 		return
 	}
 
+	var req services.ScopedRetryRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+	if !req.IsEmpty() {
+		revision, err := h.retryService.ScopedRetry(uint(id), &req)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				response.NotFound(c, "review log not found")
+				return
+			}
+			if errors.Is(err, services.ErrScopedRetryNoChanges) {
+				response.BadRequest(c, err.Error())
+				return
+			}
+			response.ServerError(c, err.Error())
+			return
+		}
+		response.Success(c, revision)
+		return
+	}
+
 	if err := h.retryService.ManualRetry(uint(id)); err != nil {
+		if errors.Is(err, services.ErrRetryInProgress) {
+			response.Error(c, response.NewConflict(err.Error()))
+			return
+		}
 		response.ServerError(c, err.Error())
 		return
 	}
@@ -157,7 +426,13 @@ func (h *ReviewLogHandler) ImportCommits(c *gin.Context) {
 		return
 	}
 
+	req.CreatedBy = middleware.GetUsername(c)
+
 	resp, err := h.importCommitsService.ImportCommits(&req)
+	if errors.Is(err, services.ErrImportRunning) {
+		response.Error(c, response.NewConflict(err.Error()))
+		return
+	}
 	if err != nil {
 		response.ServerError(c, err.Error())
 		return
@@ -189,6 +464,114 @@ func (h *ReviewLogHandler) UpdateScore(c *gin.Context) {
 	response.Success(c, log)
 }
 
+// SetVerdict records a maintainer's verdict on a review, kept apart from the AI score
+// PUT /api/review-logs/:id/verdict
+func (h *ReviewLogHandler) SetVerdict(c *gin.Context) {
+	log, ok := h.annotatableReview(c)
+	if !ok {
+		return
+	}
+
+	var req services.ReviewVerdictRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updated, err := h.reviewLogService.SetVerdict(log.ID, &req, middleware.GetUsername(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "ReviewLog", "Verdict", fmt.Sprintf("Review %d marked %s by %s", log.ID, req.Verdict, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"review_log_id":    log.ID,
+		"project_id":       log.ProjectID,
+		"verdict":          req.Verdict,
+		"human_score":      req.Score,
+		"ai_score":         log.Score,
+		"reason":           req.Reason,
+		"previous_verdict": log.HumanVerdict,
+	})
+
+	response.Success(c, updated)
+}
+
+// ClearVerdict removes the human verdict of a review
+// DELETE /api/review-logs/:id/verdict
+func (h *ReviewLogHandler) ClearVerdict(c *gin.Context) {
+	log, ok := h.annotatableReview(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.reviewLogService.ClearVerdict(log.ID)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "ReviewLog", "ClearVerdict", fmt.Sprintf("Verdict on review %d cleared by %s", log.ID, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"review_log_id":    log.ID,
+		"project_id":       log.ProjectID,
+		"previous_verdict": log.HumanVerdict,
+		"previous_score":   log.HumanScore,
+	})
+
+	response.Success(c, updated)
+}
+
+// AcknowledgeMigration acknowledges the destructive migrations of a review, releasing the commit status they held
+// POST /api/review-logs/:id/acknowledge-migration
+func (h *ReviewLogHandler) AcknowledgeMigration(c *gin.Context) {
+	log, ok := h.annotatableReview(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.webhookService.AcknowledgeMigration(log, middleware.GetUsername(c))
+	if err != nil {
+		if errors.Is(err, webhook.ErrMigrationAckNotPending) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(c)
+	services.LogInfo(c.Request.Context(), "ReviewLog", "AcknowledgeMigration", fmt.Sprintf("Destructive migration of review %d acknowledged by %s", log.ID, middleware.GetUsername(c)), &userID, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"review_log_id": log.ID,
+		"project_id":    log.ProjectID,
+		"commit":        log.CommitHash,
+		"statements":    log.MigrationStatements,
+	})
+
+	response.Success(c, updated)
+}
+
+// annotatableReview loads the review of the request and checks the user may annotate it
+func (h *ReviewLogHandler) annotatableReview(c *gin.Context) (*models.ReviewLog, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid review log id")
+		return nil, false
+	}
+
+	log, err := h.reviewLogService.GetByID(uint(id))
+	if err != nil || log.Project == nil || !middleware.CanAccessTenant(c, log.Project.TenantID) {
+		response.NotFound(c, "review log not found")
+		return nil, false
+	}
+	if !h.reviewLogService.CanAnnotate(log.ProjectID, middleware.GetUserID(c), middleware.GetRole(c)) {
+		response.Forbidden(c, services.ErrVerdictNotAllowed.Error())
+		return nil, false
+	}
+	return log, true
+}
+
 // Export exports review logs as CSV with the same filters as List.
 func (h *ReviewLogHandler) Export(c *gin.Context) {
 	var req services.ReviewLogListRequest
@@ -200,6 +583,7 @@ func (h *ReviewLogHandler) Export(c *gin.Context) {
 	// Override pagination to fetch all matching records
 	req.Page = 1
 	req.PageSize = 10000
+	req.TenantID = middleware.GetTenantID(c)
 
 	resp, err := h.reviewLogService.List(&req)
 	if err != nil {
@@ -212,7 +596,7 @@ func (h *ReviewLogHandler) Export(c *gin.Context) {
 
 	w := csv.NewWriter(c.Writer)
 	// Header
-	w.Write([]string{"ID", "Project", "Author", "Branch", "Event Type", "Commit Hash", "Commit Message", "Score", "Status", "Files Changed", "Additions", "Deletions", "Created At"})
+	w.Write([]string{"ID", "Project", "Author", "Branch", "Event Type", "Commit Hash", "Commit Message", "Score", "Human Verdict", "Status", "Files Changed", "Additions", "Deletions", "Created At"})
 
 	for _, log := range resp.Items {
 		projectName := ""
@@ -232,6 +616,7 @@ func (h *ReviewLogHandler) Export(c *gin.Context) {
 			log.CommitHash,
 			log.CommitMessage,
 			score,
+			log.HumanVerdict,
 			log.ReviewStatus,
 			strconv.Itoa(log.FilesChanged),
 			strconv.Itoa(log.Additions),
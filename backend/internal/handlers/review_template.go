@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,12 +13,14 @@ import (
 )
 
 type ReviewTemplateHandler struct {
-	service *services.ReviewTemplateService
+	service       *services.ReviewTemplateService
+	bundleService *services.TemplateBundleService
 }
 
 func NewReviewTemplateHandler(db *gorm.DB) *ReviewTemplateHandler {
 	return &ReviewTemplateHandler{
-		service: services.NewReviewTemplateService(db),
+		service:       services.NewReviewTemplateService(db),
+		bundleService: services.NewTemplateBundleService(db),
 	}
 }
 
@@ -109,6 +112,90 @@ func (h *ReviewTemplateHandler) Delete(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// Export downloads review and prompt templates as a JSON bundle
+// GET /api/templates/export
+func (h *ReviewTemplateHandler) Export(c *gin.Context) {
+	var req services.TemplateExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	bundle, err := h.bundleService.Export(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=codesentry-templates.json")
+	c.JSON(http.StatusOK, bundle)
+}
+
+// Import stores the templates of an exported bundle; ?on_conflict= resolves name conflicts
+// POST /api/templates/import
+func (h *ReviewTemplateHandler) Import(c *gin.Context) {
+	var req services.TemplateImportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	var bundle services.TemplateBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	var userID uint
+	if id, exists := c.Get("user_id"); exists {
+		userID = id.(uint)
+	}
+
+	result, err := h.bundleService.Import(&bundle, req.OnConflict, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTemplateBundle) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, result)
+}
+
+// BuiltInUpdates lists built-in templates with a newer bundled version
+// GET /api/review-templates/updates
+func (h *ReviewTemplateHandler) BuiltInUpdates(c *gin.Context) {
+	updates, err := h.service.BuiltInUpdates()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, updates)
+}
+
+type applyBuiltInUpdatesRequest struct {
+	Slugs []string `json:"slugs"` // Empty applies every available update
+}
+
+// ApplyBuiltInUpdates updates built-in templates to their bundled version
+// POST /api/review-templates/updates/apply
+func (h *ReviewTemplateHandler) ApplyBuiltInUpdates(c *gin.Context) {
+	var req applyBuiltInUpdatesRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	applied, err := h.service.ApplyBuiltInUpdates(req.Slugs)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, applied)
+}
+
 func (h *ReviewTemplateHandler) SeedTemplates() error {
 	return h.service.SeedDefaultTemplates()
 }
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type SavedDashboardHandler struct {
+	dashboardService *services.SavedDashboardService
+}
+
+func NewSavedDashboardHandler(db *gorm.DB) *SavedDashboardHandler {
+	return &SavedDashboardHandler{
+		dashboardService: services.NewSavedDashboardService(db),
+	}
+}
+
+func dashboardViewer(c *gin.Context) *services.DashboardViewer {
+	return &services.DashboardViewer{
+		UserID:   middleware.GetUserID(c),
+		IsAdmin:  middleware.GetRole(c) == "admin",
+		TenantID: middleware.GetTenantID(c),
+	}
+}
+
+// respondDashboardError maps lookup and ownership errors to 404 and 403
+func respondDashboardError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		response.NotFound(c, "dashboard not found")
+	case errors.Is(err, services.ErrDashboardForbidden):
+		response.Forbidden(c, err.Error())
+	default:
+		response.BadRequest(c, err.Error())
+	}
+}
+
+// List returns the caller's dashboards and the dashboards shared in their tenant
+// GET /api/dashboards
+func (h *SavedDashboardHandler) List(c *gin.Context) {
+	dashboards, err := h.dashboardService.List(dashboardViewer(c))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"items": dashboards, "metrics": services.DashboardMetrics})
+}
+
+// GetByID returns a saved dashboard definition
+// GET /api/dashboards/:id
+func (h *SavedDashboardHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid dashboard id")
+		return
+	}
+
+	dashboard, err := h.dashboardService.Get(uint(id), dashboardViewer(c))
+	if err != nil {
+		respondDashboardError(c, err)
+		return
+	}
+
+	response.Success(c, dashboard)
+}
+
+// GetData computes the metrics of a saved dashboard
+// GET /api/dashboards/:id/data
+func (h *SavedDashboardHandler) GetData(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid dashboard id")
+		return
+	}
+
+	var req services.DashboardDataRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	resp, err := h.dashboardService.GetData(uint(id), dashboardViewer(c), &req)
+	if err != nil {
+		respondDashboardError(c, err)
+		return
+	}
+
+	response.Success(c, resp)
+}
+
+// Create saves a dashboard owned by the caller
+// POST /api/dashboards
+func (h *SavedDashboardHandler) Create(c *gin.Context) {
+	var req services.CreateSavedDashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	req.UserID = middleware.GetUserID(c)
+	req.TenantID = middleware.GetWriteTenantID(c)
+	dashboard, err := h.dashboardService.Create(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, dashboard)
+}
+
+// Update changes a dashboard owned by the caller
+// PUT /api/dashboards/:id
+func (h *SavedDashboardHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid dashboard id")
+		return
+	}
+
+	var req services.UpdateSavedDashboardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	dashboard, err := h.dashboardService.Update(uint(id), dashboardViewer(c), &req)
+	if err != nil {
+		respondDashboardError(c, err)
+		return
+	}
+
+	response.Success(c, dashboard)
+}
+
+// Delete removes a dashboard owned by the caller
+// DELETE /api/dashboards/:id
+func (h *SavedDashboardHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid dashboard id")
+		return
+	}
+
+	if err := h.dashboardService.Delete(uint(id), dashboardViewer(c)); err != nil {
+		respondDashboardError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "dashboard deleted successfully"})
+}
@@ -4,7 +4,9 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
@@ -58,13 +60,15 @@ func (h *SearchHandler) Search(c *gin.Context) {
 	}
 
 	result := SearchResult{}
-	pattern := "%" + q + "%"
+	pattern := services.ContainsPattern(q)
+	tenantID := middleware.GetTenantID(c)
 
 	// Search review logs
 	var reviews []models.ReviewLog
-	h.db.Model(&models.ReviewLog{}).
+	services.ScopeReviewLogsByTenant(h.db.Model(&models.ReviewLog{}), tenantID).
 		Preload("Project").
-		Where("commit_message LIKE ? OR author LIKE ? OR commit_hash LIKE ? OR branch LIKE ?",
+		Where(services.LikeCondition("commit_message")+" OR "+services.LikeCondition("author")+" OR "+
+			services.LikeCondition("commit_hash")+" OR "+services.LikeCondition("branch"),
 			pattern, pattern, pattern, pattern).
 		Order("created_at DESC").
 		Limit(limit).
@@ -91,8 +95,8 @@ func (h *SearchHandler) Search(c *gin.Context) {
 
 	// Search projects
 	var projects []models.Project
-	h.db.Model(&models.Project{}).
-		Where("name LIKE ? OR url LIKE ?", pattern, pattern).
+	services.ScopeTenant(h.db.Model(&models.Project{}), tenantID).
+		Where(services.LikeCondition("name")+" OR "+services.LikeCondition("url"), pattern, pattern).
 		Limit(10).
 		Find(&projects)
 
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type ShadowReviewHandler struct {
+	service *services.ShadowReviewService
+}
+
+func NewShadowReviewHandler(db *gorm.DB) *ShadowReviewHandler {
+	return &ShadowReviewHandler{service: services.NewShadowReviewService(db)}
+}
+
+// GET /api/shadow-reviews
+func (h *ShadowReviewHandler) List(c *gin.Context) {
+	var req services.ShadowReviewListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	resp, err := h.service.List(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, resp)
+}
+
+// GET /api/shadow-reviews/compare
+func (h *ShadowReviewHandler) Compare(c *gin.Context) {
+	var req services.ShadowCompareRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	comparison, err := h.service.Compare(&req)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, comparison)
+}
@@ -3,29 +3,68 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/huangang/codesentry/backend/internal/config"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/internal/utils"
 	"github.com/huangang/codesentry/backend/pkg/logger"
 	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
 )
 
 // SSEHandler handles Server-Sent Events for real-time updates
 type SSEHandler struct {
+	db        *gorm.DB
 	hub       *services.SSEHub
 	importHub *services.ImportEventHub
+	heartbeat time.Duration
+	retryMs   int
 }
 
 // NewSSEHandler creates a new SSE handler
-func NewSSEHandler(hub *services.SSEHub) *SSEHandler {
+func NewSSEHandler(db *gorm.DB, hub *services.SSEHub, cfg *config.SSEConfig) *SSEHandler {
+	heartbeat := time.Duration(cfg.HeartbeatInterval) * time.Second
+	if heartbeat <= 0 {
+		heartbeat = config.DefaultSSEHeartbeatInterval * time.Second
+	}
+	retryMs := cfg.RetryMs
+	if retryMs <= 0 {
+		retryMs = config.DefaultSSERetryMs
+	}
 	return &SSEHandler{
+		db:        db,
 		hub:       hub,
 		importHub: services.GetImportHub(),
+		heartbeat: heartbeat,
+		retryMs:   retryMs,
+	}
+}
+
+// lastEventID returns the position a client resumes from: the Last-Event-ID header
+// browsers send on reconnect, or the last_event_id query parameter
+func lastEventID(c *gin.Context) uint64 {
+	raw := c.GetHeader("Last-Event-ID")
+	if raw == "" {
+		raw = c.Query("last_event_id")
 	}
+	id, _ := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	return id
+}
+
+// writeReviewEvent writes a review event with its id, so the client can resume after it
+func writeReviewEvent(c *gin.Context, event services.ReviewEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Error().Err(err).Msg("SSE marshal error")
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\ndata: %s\n\n", event.EventID, data)
+	c.Writer.Flush()
 }
 
 func extractToken(c *gin.Context) string {
@@ -39,6 +78,15 @@ func extractToken(c *gin.Context) string {
 	return token
 }
 
+// allowedProjects returns the projects the token's user may receive events of: those of
+// its tenant, or nil for platform admins
+func (h *SSEHandler) allowedProjects(claims *utils.Claims) (map[uint]bool, error) {
+	if claims.Role == "admin" {
+		return nil, nil
+	}
+	return services.TenantProjectIDs(h.db, claims.TenantID)
+}
+
 func setSSEHeaders(c *gin.Context) {
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -54,42 +102,55 @@ func (h *SSEHandler) StreamReviewEvents(c *gin.Context) {
 		response.Unauthorized(c, "Unauthorized")
 		return
 	}
-	if _, err := utils.ParseToken(token); err != nil {
+	claims, err := utils.ParseToken(token)
+	if err != nil {
 		response.Unauthorized(c, "Invalid token")
 		return
 	}
 
+	// Server-side filters: ?project_id=1,2&status=completed,failed&min_score=80
+	filter, err := services.ParseReviewEventFilter(c.QueryArray("project_id"), c.QueryArray("status"), c.Query("min_score"))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	allowed, err := h.allowedProjects(claims)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	filter = filter.Restrict(allowed)
+
 	setSSEHeaders(c)
 
 	clientID := uuid.New().String()
-	events := h.hub.Subscribe(clientID)
+	events, missed := h.hub.SubscribeFrom(clientID, lastEventID(c), filter)
 	defer h.hub.Unsubscribe(clientID)
 
-	logger.Info().Str("client_id", clientID).Int("total", h.hub.ClientCount()).Msg("SSE client connected")
+	logger.Info().Str("client_id", clientID).Int("total", h.hub.ClientCount()).Int("replayed", len(missed)).Msg("SSE client connected")
 
-	// Heartbeat ticker to prevent proxy timeouts (30s interval)
-	ticker := time.NewTicker(30 * time.Second)
+	// Heartbeat ticker to prevent proxy timeouts
+	ticker := time.NewTicker(h.heartbeat)
 	defer ticker.Stop()
 
 	ctx := c.Request.Context()
 
-	// Send initial connection event
-	fmt.Fprintf(c.Writer, ": connected\n\n")
+	// Send the reconnect delay and the initial connection event
+	fmt.Fprintf(c.Writer, "retry: %d\n: connected\n\n", h.retryMs)
 	c.Writer.Flush()
 
+	// Replay the events missed since the client's last event
+	for _, event := range missed {
+		writeReviewEvent(c, event)
+	}
+
 	for {
 		select {
 		case event, ok := <-events:
 			if !ok {
 				return
 			}
-			data, err := json.Marshal(event)
-			if err != nil {
-				logger.Error().Err(err).Msg("SSE marshal error")
-				continue
-			}
-			fmt.Fprintf(c.Writer, "data: %s\n\n", data)
-			c.Writer.Flush()
+			writeReviewEvent(c, event)
 		case <-ticker.C:
 			// Send heartbeat comment to keep connection alive
 			fmt.Fprintf(c.Writer, ": ping\n\n")
@@ -107,10 +168,16 @@ func (h *SSEHandler) StreamImportEvents(c *gin.Context) {
 		response.Unauthorized(c, "Unauthorized")
 		return
 	}
-	if _, err := utils.ParseToken(token); err != nil {
+	claims, err := utils.ParseToken(token)
+	if err != nil {
 		response.Unauthorized(c, "Invalid token")
 		return
 	}
+	allowed, err := h.allowedProjects(claims)
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
 
 	setSSEHeaders(c)
 
@@ -120,12 +187,12 @@ func (h *SSEHandler) StreamImportEvents(c *gin.Context) {
 
 	logger.Info().Str("client_id", clientID).Msg("Import SSE client connected")
 
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(h.heartbeat)
 	defer ticker.Stop()
 
 	ctx := c.Request.Context()
 
-	fmt.Fprintf(c.Writer, ": connected\n\n")
+	fmt.Fprintf(c.Writer, "retry: %d\n: connected\n\n", h.retryMs)
 	c.Writer.Flush()
 
 	for {
@@ -134,6 +201,9 @@ func (h *SSEHandler) StreamImportEvents(c *gin.Context) {
 			if !ok {
 				return
 			}
+			if allowed != nil && !allowed[event.ProjectID] {
+				continue
+			}
 			data, err := json.Marshal(event)
 			if err != nil {
 				logger.Error().Err(err).Msg("Import SSE marshal error")
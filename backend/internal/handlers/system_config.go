@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
@@ -8,14 +10,18 @@ import (
 )
 
 type SystemConfigHandler struct {
-	configService  *services.SystemConfigService
-	holidayService *services.HolidayService
+	configService    *services.SystemConfigService
+	holidayService   *services.HolidayService
+	retentionService *services.RetentionService
+	ldapService      *services.LDAPService
 }
 
 func NewSystemConfigHandler(db *gorm.DB) *SystemConfigHandler {
 	return &SystemConfigHandler{
-		configService:  services.NewSystemConfigService(db),
-		holidayService: services.NewHolidayService(),
+		configService:    services.NewSystemConfigService(db),
+		holidayService:   services.NewHolidayService(),
+		retentionService: services.NewRetentionService(db),
+		ldapService:      services.NewLDAPService(db),
 	}
 }
 
@@ -30,6 +36,12 @@ func (h *SystemConfigHandler) UpdateLDAPConfig(c *gin.Context) {
 		response.BadRequest(c, err.Error())
 		return
 	}
+	if req.GroupMappings != nil {
+		if err := services.ValidateLDAPGroupMappings(*req.GroupMappings); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
 
 	if err := h.configService.UpdateLDAPConfig(&req); err != nil {
 		response.ServerError(c, err.Error())
@@ -39,6 +51,17 @@ func (h *SystemConfigHandler) UpdateLDAPConfig(c *gin.Context) {
 	response.Success(c, h.configService.GetLDAPConfig())
 }
 
+// SyncLDAP syncs LDAP users with the directory immediately
+func (h *SystemConfigHandler) SyncLDAP(c *gin.Context) {
+	result, err := h.ldapService.SyncDirectory()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
 func (h *SystemConfigHandler) GetDailyReportConfig(c *gin.Context) {
 	config := h.configService.GetDailyReportConfig()
 	response.Success(c, config)
@@ -123,3 +146,415 @@ func (h *SystemConfigHandler) UpdateAuthSessionConfig(c *gin.Context) {
 
 	response.Success(c, h.configService.GetAuthSessionConfig())
 }
+
+func (h *SystemConfigHandler) GetReviewArchiveConfig(c *gin.Context) {
+	config := h.configService.GetReviewArchiveConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateReviewArchiveConfig(c *gin.Context) {
+	var req services.UpdateReviewArchiveConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateReviewArchiveConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetReviewArchiveConfig())
+}
+
+func (h *SystemConfigHandler) GetRetentionConfig(c *gin.Context) {
+	config := h.configService.GetRetentionConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateRetentionConfig(c *gin.Context) {
+	var req services.UpdateRetentionConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateRetentionConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetRetentionConfig())
+}
+
+// RunRetention applies the retention policies immediately
+func (h *SystemConfigHandler) RunRetention(c *gin.Context) {
+	result, err := h.retentionService.Run()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// PurgeDeleted permanently removes the rows soft-deleted more than the given days ago
+func (h *SystemConfigHandler) PurgeDeleted(c *gin.Context) {
+	var req services.PurgeDeletedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	purged, err := h.retentionService.PurgeDeleted(time.Now().AddDate(0, 0, -req.Days))
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"purged": purged})
+}
+
+func (h *SystemConfigHandler) GetDiffFetchConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetDiffFetchConfig())
+}
+
+func (h *SystemConfigHandler) UpdateDiffFetchConfig(c *gin.Context) {
+	var req services.UpdateDiffFetchConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateDiffFetchConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetDiffFetchConfig())
+}
+
+func (h *SystemConfigHandler) GetMentionConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetMentionConfig())
+}
+
+func (h *SystemConfigHandler) UpdateMentionConfig(c *gin.Context) {
+	var req services.UpdateMentionConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateMentionConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetMentionConfig())
+}
+
+func (h *SystemConfigHandler) GetNotificationLinkConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetNotificationLinkConfig())
+}
+
+func (h *SystemConfigHandler) UpdateNotificationLinkConfig(c *gin.Context) {
+	var req services.UpdateNotificationLinkConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateNotificationLinkConfig(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetNotificationLinkConfig())
+}
+
+func (h *SystemConfigHandler) GetPrivacyConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetPrivacyConfig())
+}
+
+func (h *SystemConfigHandler) UpdatePrivacyConfig(c *gin.Context) {
+	var req services.UpdatePrivacyConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdatePrivacyConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetPrivacyConfig())
+}
+
+func (h *SystemConfigHandler) GetLeaderboardConfig(c *gin.Context) {
+	config := h.configService.GetLeaderboardConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateLeaderboardConfig(c *gin.Context) {
+	var req services.UpdateLeaderboardConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateLeaderboardConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetLeaderboardConfig())
+}
+
+func (h *SystemConfigHandler) GetTestCoverageConfig(c *gin.Context) {
+	config := h.configService.GetTestCoverageConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateTestCoverageConfig(c *gin.Context) {
+	var req services.UpdateTestCoverageConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.Rules != nil {
+		if err := services.ValidateTestMappingRules(*req.Rules); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	if err := h.configService.UpdateTestCoverageConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetTestCoverageConfig())
+}
+
+func (h *SystemConfigHandler) GetScorecardConfig(c *gin.Context) {
+	config := h.configService.GetScorecardConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateScorecardConfig(c *gin.Context) {
+	var req services.UpdateScorecardConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.KPIs != nil {
+		if err := services.ValidateScorecardKPIs(*req.KPIs); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	if err := h.configService.UpdateScorecardConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetScorecardConfig())
+}
+
+func (h *SystemConfigHandler) GetShadowReviewConfig(c *gin.Context) {
+	config := h.configService.GetShadowReviewConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateShadowReviewConfig(c *gin.Context) {
+	var req services.UpdateShadowReviewConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateShadowReviewConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetShadowReviewConfig())
+}
+
+func (h *SystemConfigHandler) GetHumanVerdictConfig(c *gin.Context) {
+	config := h.configService.GetHumanVerdictConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateHumanVerdictConfig(c *gin.Context) {
+	var req services.UpdateHumanVerdictConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateHumanVerdictConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetHumanVerdictConfig())
+}
+
+func (h *SystemConfigHandler) GetLLMOutageConfig(c *gin.Context) {
+	config := h.configService.GetLLMOutageConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateLLMOutageConfig(c *gin.Context) {
+	var req services.UpdateLLMOutageConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateLLMOutageConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetLLMOutageConfig())
+}
+
+func (h *SystemConfigHandler) GetBackpressureConfig(c *gin.Context) {
+	config := h.configService.GetBackpressureConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateBackpressureConfig(c *gin.Context) {
+	var req services.UpdateBackpressureConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateBackpressureConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetBackpressureConfig())
+}
+
+func (h *SystemConfigHandler) GetErrorNotifyConfig(c *gin.Context) {
+	config := h.configService.GetErrorNotifyConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateErrorNotifyConfig(c *gin.Context) {
+	var req services.UpdateErrorNotifyConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateErrorNotifyConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetErrorNotifyConfig())
+}
+
+func (h *SystemConfigHandler) GetLogShippingConfig(c *gin.Context) {
+	config := h.configService.GetLogShippingConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateLogShippingConfig(c *gin.Context) {
+	var req services.UpdateLogShippingConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateLogShippingConfig(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetLogShippingConfig())
+}
+
+func (h *SystemConfigHandler) GetIPAllowListConfig(c *gin.Context) {
+	config := h.configService.GetIPAllowListConfig()
+	response.Success(c, config)
+}
+
+func (h *SystemConfigHandler) UpdateIPAllowListConfig(c *gin.Context) {
+	var req services.UpdateIPAllowListConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	if req.Admin != nil {
+		networks, err := services.ParseCIDRList(*req.Admin)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		// Refuse allow-lists that would lock the current admin out
+		if !services.IPAllowed(c.ClientIP(), networks) {
+			response.BadRequest(c, "admin allow-list must include your current IP address "+c.ClientIP())
+			return
+		}
+	}
+	if req.Webhook != nil {
+		if _, err := services.ParseCIDRList(*req.Webhook); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	if err := h.configService.UpdateIPAllowListConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetIPAllowListConfig())
+}
+
+func (h *SystemConfigHandler) GetComplianceReportConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetComplianceReportConfig())
+}
+
+func (h *SystemConfigHandler) UpdateComplianceReportConfig(c *gin.Context) {
+	var req services.UpdateComplianceReportConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateComplianceReportConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetComplianceReportConfig())
+}
+
+func (h *SystemConfigHandler) GetCoverageGapConfig(c *gin.Context) {
+	response.Success(c, h.configService.GetCoverageGapConfig())
+}
+
+func (h *SystemConfigHandler) UpdateCoverageGapConfig(c *gin.Context) {
+	var req services.UpdateCoverageGapConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.configService.UpdateCoverageGapConfig(&req); err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+
+	response.Success(c, h.configService.GetCoverageGapConfig())
+}
@@ -1,8 +1,16 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
@@ -33,6 +41,83 @@ func (h *SystemLogHandler) List(c *gin.Context) {
 	response.Success(c, resp)
 }
 
+// systemLogPollInterval is how often the log tail checks for new logs
+const systemLogPollInterval = 2 * time.Second
+
+// Stream tails the system logs matching the list filters as Server-Sent Events. New
+// connections first receive the latest backlog logs (default 20, max 200); reconnecting
+// clients resume after their Last-Event-ID instead.
+// GET /api/system-logs/stream
+func (h *SystemLogHandler) Stream(c *gin.Context) {
+	var filter services.SystemLogFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	backlog, _ := strconv.Atoi(c.DefaultQuery("backlog", "20"))
+	backlog = min(max(backlog, 0), 200)
+
+	lastID := uint(lastEventID(c))
+	var initial []models.SystemLog
+	var err error
+	if lastID > 0 {
+		initial, err = h.systemLogService.ListSince(&filter, lastID, 200)
+	} else {
+		if lastID, err = h.systemLogService.LatestID(); err == nil && backlog > 0 {
+			initial, err = h.systemLogService.ListRecent(&filter, backlog)
+		}
+	}
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	setSSEHeaders(c)
+	logger.Info().Str("filter", c.Request.URL.RawQuery).Msg("System log stream connected")
+
+	poll := time.NewTicker(systemLogPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(config.DefaultSSEHeartbeatInterval * time.Second)
+	defer heartbeat.Stop()
+
+	ctx := c.Request.Context()
+	fmt.Fprintf(c.Writer, "retry: %d\n: connected\n\n", config.DefaultSSERetryMs)
+	c.Writer.Flush()
+
+	writeLogs := func(logs []models.SystemLog) {
+		for _, log := range logs {
+			data, err := json.Marshal(log)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %d\ndata: %s\n\n", log.ID, data)
+			lastID = max(lastID, log.ID)
+		}
+		c.Writer.Flush()
+	}
+	writeLogs(initial)
+
+	for {
+		select {
+		case <-poll.C:
+			logs, err := h.systemLogService.ListSince(&filter, lastID, 200)
+			if err != nil {
+				logger.Error().Err(err).Msg("System log stream query error")
+				continue
+			}
+			if len(logs) > 0 {
+				writeLogs(logs)
+			}
+		case <-heartbeat.C:
+			fmt.Fprintf(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		case <-ctx.Done():
+			logger.Info().Msg("System log stream disconnected")
+			return
+		}
+	}
+}
+
 func (h *SystemLogHandler) GetModules(c *gin.Context) {
 	modules, err := h.systemLogService.GetModules()
 	if err != nil {
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/response"
+	"gorm.io/gorm"
+)
+
+type TenantHandler struct {
+	service *services.TenantService
+}
+
+func NewTenantHandler(db *gorm.DB) *TenantHandler {
+	return &TenantHandler{service: services.NewTenantService(db)}
+}
+
+// List returns all tenants
+// GET /api/tenants
+func (h *TenantHandler) List(c *gin.Context) {
+	tenants, err := h.service.List()
+	if err != nil {
+		response.ServerError(c, err.Error())
+		return
+	}
+	response.Success(c, tenants)
+}
+
+// Create creates a tenant
+// POST /api/tenants
+func (h *TenantHandler) Create(c *gin.Context) {
+	var req services.CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	tenant, err := h.service.Create(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Created(c, tenant)
+}
+
+// Update updates a tenant, including its branding
+// PUT /api/tenants/:id
+func (h *TenantHandler) Update(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	var req services.UpdateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	tenant, err := h.service.Update(uint(id), &req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, tenant)
+}
+
+// Delete deletes a tenant without users or projects
+// DELETE /api/tenants/:id
+func (h *TenantHandler) Delete(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "invalid id")
+		return
+	}
+
+	if err := h.service.Delete(uint(id)); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+	response.Success(c, gin.H{"message": "tenant deleted successfully"})
+}
+
+// GetCurrentBranding returns the branding of the current user's tenant
+// GET /api/tenant/branding
+func (h *TenantHandler) GetCurrentBranding(c *gin.Context) {
+	branding, err := h.service.GetBranding(middleware.GetUserTenantID(c))
+	if err != nil {
+		response.NotFound(c, "tenant not found")
+		return
+	}
+	response.Success(c, branding)
+}
+
+// GetBrandingBySlug returns the branding of a tenant for the login page
+// GET /api/tenants/:slug/branding
+func (h *TenantHandler) GetBrandingBySlug(c *gin.Context) {
+	branding, err := h.service.GetBrandingBySlug(c.Param("slug"))
+	if err != nil {
+		response.NotFound(c, "tenant not found")
+		return
+	}
+	response.Success(c, branding)
+}
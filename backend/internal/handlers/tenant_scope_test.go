@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/middleware"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB opens a migrated in-memory SQLite database as models.DB for the test
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	previous := models.DB
+	models.DB = db
+	t.Cleanup(func() {
+		models.DB = previous
+		sqlDB.Close()
+	})
+	if err := models.AutoMigrate(); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+// seedTenantReviews creates a project with a review in tenants 1 and 2, both matching
+// the search term "shared"
+func seedTenantReviews(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	for _, tenantID := range []uint{1, 2} {
+		name := map[uint]string{1: "shared-alpha", 2: "shared-beta"}[tenantID]
+		project := &models.Project{Name: name, URL: "https://git.example.com/" + name, Platform: "gitlab", TenantID: tenantID}
+		if err := db.Create(project).Error; err != nil {
+			t.Fatal(err)
+		}
+		score := 80.0
+		review := &models.ReviewLog{ProjectID: project.ID, EventType: "push", Author: name + "-dev", CommitMessage: "shared change", Score: &score, ReviewStatus: "completed"}
+		if err := db.Create(review).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// tenantRequest runs a handler as a user of the tenant and decodes the response data
+func tenantRequest(t *testing.T, handler gin.HandlerFunc, url string, tenantID uint, data interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", url, nil)
+	c.Set(middleware.ContextRole, "user")
+	c.Set(middleware.ContextTenantID, tenantID)
+	handler(c)
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	if err := json.Unmarshal(body.Data, data); err != nil {
+		t.Fatalf("decode data %q: %v", body.Data, err)
+	}
+}
+
+func TestSearch_TenantScope(t *testing.T) {
+	db := newTestDB(t)
+	seedTenantReviews(t, db)
+
+	var result SearchResult
+	tenantRequest(t, NewSearchHandler(db).Search, "/api/search?q=shared", 1, &result)
+	if len(result.Reviews) != 1 || result.Reviews[0].ProjectName != "shared-alpha" {
+		t.Errorf("search reviews of tenant 1 = %+v, want only shared-alpha", result.Reviews)
+	}
+	if len(result.Projects) != 1 || result.Projects[0].Name != "shared-alpha" {
+		t.Errorf("search projects of tenant 1 = %+v, want only shared-alpha", result.Projects)
+	}
+}
+
+func TestReport_TenantScope(t *testing.T) {
+	db := newTestDB(t)
+	seedTenantReviews(t, db)
+
+	var report ReportResponse
+	tenantRequest(t, NewReportHandler(db).GetReport, "/api/reports", 2, &report)
+	if report.Current.TotalReviews != 1 || report.Current.ActiveAuthors != 1 {
+		t.Errorf("report of tenant 2 = %+v, want only its review", report.Current)
+	}
+	if len(report.Rankings) != 1 || report.Rankings[0].Author != "shared-beta-dev" {
+		t.Errorf("report rankings of tenant 2 = %+v, want only its author", report.Rankings)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/huangang/codesentry/backend/internal/middleware"
 	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
 	"github.com/huangang/codesentry/backend/pkg/response"
 	"gorm.io/gorm"
 )
@@ -35,10 +36,10 @@ func (h *UserHandler) List(c *gin.Context) {
 	var users []models.User
 	var total int64
 
-	query := h.db.Model(&models.User{})
+	query := services.ScopeTenant(h.db.Model(&models.User{}), middleware.GetTenantID(c))
 
 	if username != "" {
-		query = query.Where("username LIKE ?", "%"+username+"%")
+		query = query.Where(services.LikeCondition("username"), services.ContainsPattern(username))
 	}
 	if role != "" {
 		query = query.Where("role = ?", role)
@@ -62,6 +63,7 @@ type UpdateUserRequest struct {
 	Role     *string `json:"role"`
 	IsActive *bool   `json:"is_active"`
 	Nickname *string `json:"nickname"`
+	TenantID *uint   `json:"tenant_id"` // Platform admins only
 }
 
 func (h *UserHandler) Update(c *gin.Context) {
@@ -78,10 +80,15 @@ func (h *UserHandler) Update(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.First(&user, id).Error; err != nil {
+	if err := h.db.First(&user, id).Error; err != nil || !middleware.CanAccessTenant(c, user.TenantID) {
 		response.NotFound(c, "user not found")
 		return
 	}
+	isPlatformAdmin := middleware.GetRole(c) == "admin"
+	if user.Role == "admin" && !isPlatformAdmin {
+		response.Forbidden(c, "cannot modify a platform admin")
+		return
+	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -91,12 +98,30 @@ func (h *UserHandler) Update(c *gin.Context) {
 
 	updates := make(map[string]interface{})
 	if req.Role != nil {
-		if *req.Role != "admin" && *req.Role != "developer" && *req.Role != "user" {
-			response.BadRequest(c, "invalid role, must be 'admin', 'developer', or 'user'")
+		switch *req.Role {
+		case "admin":
+			if !isPlatformAdmin {
+				response.Forbidden(c, "only platform admins can grant the admin role")
+				return
+			}
+		case "tenant_admin", "developer", "user":
+		default:
+			response.BadRequest(c, "invalid role, must be 'admin', 'tenant_admin', 'developer', or 'user'")
 			return
 		}
 		updates["role"] = *req.Role
 	}
+	if req.TenantID != nil {
+		if !isPlatformAdmin {
+			response.Forbidden(c, "only platform admins can move users between tenants")
+			return
+		}
+		if err := h.db.First(&models.Tenant{}, *req.TenantID).Error; err != nil {
+			response.BadRequest(c, "tenant not found")
+			return
+		}
+		updates["tenant_id"] = *req.TenantID
+	}
 	if req.IsActive != nil {
 		updates["is_active"] = *req.IsActive
 	}
@@ -132,10 +157,14 @@ func (h *UserHandler) Delete(c *gin.Context) {
 	}
 
 	var user models.User
-	if err := h.db.First(&user, id).Error; err != nil {
+	if err := h.db.First(&user, id).Error; err != nil || !middleware.CanAccessTenant(c, user.TenantID) {
 		response.NotFound(c, "user not found")
 		return
 	}
+	if user.Role == "admin" && middleware.GetRole(c) != "admin" {
+		response.Forbidden(c, "cannot delete a platform admin")
+		return
+	}
 
 	if err := h.db.Delete(&user).Error; err != nil {
 		response.ServerError(c, err.Error())
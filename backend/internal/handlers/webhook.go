@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -23,6 +24,7 @@ type WebhookHandler struct {
 	webhookService       *webhook.Service
 	projectService       *services.ProjectService
 	gitCredentialService *services.GitCredentialService
+	backpressureService  *services.BackpressureService
 }
 
 func NewWebhookHandler(db *gorm.DB, aiCfg *config.OpenAIConfig) *WebhookHandler {
@@ -30,6 +32,7 @@ func NewWebhookHandler(db *gorm.DB, aiCfg *config.OpenAIConfig) *WebhookHandler
 		webhookService:       webhook.NewService(db, aiCfg),
 		projectService:       services.NewProjectService(db),
 		gitCredentialService: services.NewGitCredentialService(db),
+		backpressureService:  services.NewBackpressureService(db),
 	}
 }
 
@@ -41,6 +44,7 @@ type webhookContext struct {
 	body        []byte
 	clientIP    string
 	userAgent   string
+	reqCtx      context.Context
 }
 
 type signatureVerifier func(secret string, body []byte, signature string) bool
@@ -52,21 +56,29 @@ func (h *WebhookHandler) resolveProject(ctx *webhookContext, signature string, v
 
 		credential, credErr := h.gitCredentialService.FindMatchingCredential(ctx.projectURL, ctx.platform)
 		if credErr != nil || credential == nil {
-			services.LogError("Webhook", "ProjectNotFound", "Project not registered and no matching credential: "+ctx.projectURL, nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+			services.LogError(ctx.reqCtx, "Webhook", "ProjectNotFound", "Project not registered and no matching credential: "+ctx.projectURL, nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 				"project_url": ctx.projectURL,
 				"event_type":  ctx.eventType,
 			})
 			return nil, err, http.StatusNotFound
 		}
 
-		if credential.WebhookSecret != "" && !verifyFn(credential.WebhookSecret, ctx.body, signature) {
-			services.LogWarning("Webhook", "InvalidSignature", "Invalid webhook signature for credential", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+		if !services.VerifyWithSecret(ctx.reqCtx, credential.WebhookSecret, func(secret string) bool { return verifyFn(secret, ctx.body, signature) }) {
+			services.LogWarning(ctx.reqCtx, "Webhook", "InvalidSignature", "Invalid webhook signature for credential", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 				"credential_id": credential.ID,
 				"project_url":   ctx.projectURL,
 			})
 			return nil, err, http.StatusUnauthorized
 		}
 
+		if guardErr := h.gitCredentialService.CheckAutoCreate(credential, ctx.projectURL); guardErr != nil {
+			services.LogWarning(ctx.reqCtx, "Webhook", "AutoCreateRefused", "Project not auto-created: "+guardErr.Error(), nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+				"project_url":   ctx.projectURL,
+				"credential_id": credential.ID,
+			})
+			return nil, guardErr, http.StatusNotFound
+		}
+
 		newProject := &services.CreateProjectParams{
 			Name:           ctx.projectName,
 			URL:            ctx.projectURL,
@@ -77,18 +89,32 @@ func (h *WebhookHandler) resolveProject(ctx *webhookContext, signature string, v
 			FileExtensions: credential.FileExtensions,
 			ReviewEvents:   credential.ReviewEvents,
 			IgnorePatterns: credential.IgnorePatterns,
+			CredentialID:   credential.ID,
+			Pending:        credential.RequireApproval,
+			MaxAutoCreated: credential.MaxAutoCreated,
 		}
 
 		project, err = h.projectService.CreateFromCredential(newProject)
+		if errors.Is(err, services.ErrAutoCreateLimit) {
+			services.LogWarning(ctx.reqCtx, "Webhook", "AutoCreateRefused", "Project not auto-created: "+err.Error(), nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+				"project_url":   ctx.projectURL,
+				"credential_id": credential.ID,
+			})
+			return nil, err, http.StatusNotFound
+		}
 		if err != nil {
-			services.LogError("Webhook", "AutoCreateFailed", "Failed to auto-create project: "+err.Error(), nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+			services.LogError(ctx.reqCtx, "Webhook", "AutoCreateFailed", "Failed to auto-create project: "+err.Error(), nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 				"project_url":   ctx.projectURL,
 				"credential_id": credential.ID,
 			})
 			return nil, err, http.StatusInternalServerError
 		}
 
-		services.LogInfo("Webhook", "AutoCreated", "Project auto-created from credential", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+		message := "Project auto-created from credential"
+		if project.PendingApproval {
+			message = "Project auto-created from credential, pending approval"
+		}
+		services.LogInfo(ctx.reqCtx, "Webhook", "AutoCreated", message, nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 			"project_id":    project.ID,
 			"project_name":  project.Name,
 			"credential_id": credential.ID,
@@ -96,8 +122,8 @@ func (h *WebhookHandler) resolveProject(ctx *webhookContext, signature string, v
 		return project, nil, http.StatusOK
 	}
 
-	if project.WebhookSecret != "" && !verifyFn(project.WebhookSecret, ctx.body, signature) {
-		services.LogWarning("Webhook", "InvalidSignature", "Invalid webhook signature", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+	if !services.VerifyWithSecret(ctx.reqCtx, project.WebhookSecret, func(secret string) bool { return verifyFn(secret, ctx.body, signature) }) {
+		services.LogWarning(ctx.reqCtx, "Webhook", "InvalidSignature", "Invalid webhook signature", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 			"project_id":  project.ID,
 			"project_url": ctx.projectURL,
 		})
@@ -117,12 +143,48 @@ func (h *WebhookHandler) tryFillFromCredential(project *models.Project, ctx *web
 		return
 	}
 	h.projectService.FillFromCredential(project, credential)
-	services.LogInfo("Webhook", "CredentialFilled", "Project credentials filled from git credential", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+	services.LogInfo(ctx.reqCtx, "Webhook", "CredentialFilled", "Project credentials filled from git credential", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 		"project_id":    project.ID,
 		"credential_id": credential.ID,
 	})
 }
 
+// rejectReplay responds with 409 Conflict to replayed or stale deliveries of projects with replay protection
+func (h *WebhookHandler) rejectReplay(c *gin.Context, project *models.Project, platform string, body []byte) bool {
+	err := h.webhookService.CheckReplay(project, platform, c.Request.Header, body)
+	if err == nil {
+		return false
+	}
+	services.LogWarning(c.Request.Context(), "Webhook", "ReplayRejected", err.Error(), nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"project_id": project.ID,
+		"platform":   platform,
+	})
+	response.Error(c, response.NewConflict(err.Error()))
+	return true
+}
+
+// deferOnBackpressure stores the delivery and responds with 202 Accepted and a Retry-After when
+// reviews are over capacity; the delivery is replayed once the backlog drains. It is processed
+// right away when it cannot be stored.
+func (h *WebhookHandler) deferOnBackpressure(c *gin.Context, projectID uint, platform, eventType string, body []byte) bool {
+	overloaded, retryAfter := h.backpressureService.Check()
+	if !overloaded {
+		return false
+	}
+	if err := h.backpressureService.Defer(c.Request.Context(), projectID, platform, eventType, body); err != nil {
+		logger.For(c.Request.Context(), "webhook").Error().Err(err).Uint("project_id", projectID).Msg("Failed to defer webhook under backpressure")
+		return false
+	}
+	services.LogInfo(c.Request.Context(), "Webhook", "Deferred", "Webhook deferred, reviews are over capacity", nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		"project_id": projectID,
+		"platform":   platform,
+		"event_type": eventType,
+	})
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	response.Accepted(c, gin.H{"message": "webhook deferred, reviews are over capacity", "project_id": projectID, "retry_after": retryAfter})
+	return true
+}
+
 func gitlabVerifier(secret string, _ []byte, token string) bool {
 	return webhook.VerifyGitLabSignature(secret, token)
 }
@@ -149,7 +211,7 @@ func (h *WebhookHandler) HandleGitLabWebhook(c *gin.Context) {
 	}
 
 	token := c.GetHeader("X-Gitlab-Token")
-	if project.WebhookSecret != "" && !webhook.VerifyGitLabSignature(project.WebhookSecret, token) {
+	if !services.VerifyWithSecret(c.Request.Context(), project.WebhookSecret, func(secret string) bool { return webhook.VerifyGitLabSignature(secret, token) }) {
 		response.Unauthorized(c, "invalid webhook token")
 		return
 	}
@@ -160,10 +222,18 @@ func (h *WebhookHandler) HandleGitLabWebhook(c *gin.Context) {
 		return
 	}
 
+	if h.rejectReplay(c, project, "gitlab", body) {
+		return
+	}
+
 	eventType := c.GetHeader("X-Gitlab-Event")
+	if h.deferOnBackpressure(c, uint(projectID), "gitlab", eventType, body) {
+		return
+	}
 
+	reqCtx := context.WithoutCancel(c.Request.Context())
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(reqCtx, 5*time.Minute)
 		defer cancel()
 		_ = h.webhookService.HandleGitLabWebhook(ctx, uint(projectID), eventType, body)
 	}()
@@ -191,15 +261,23 @@ func (h *WebhookHandler) HandleGitHubWebhook(c *gin.Context) {
 	}
 
 	signature := c.GetHeader("X-Hub-Signature-256")
-	if project.WebhookSecret != "" && !webhook.VerifyGitHubSignature(project.WebhookSecret, body, signature) {
+	if !services.VerifyWithSecret(c.Request.Context(), project.WebhookSecret, func(secret string) bool { return webhook.VerifyGitHubSignature(secret, body, signature) }) {
 		response.Unauthorized(c, "invalid webhook signature")
 		return
 	}
 
+	if h.rejectReplay(c, project, "github", body) {
+		return
+	}
+
 	eventType := c.GetHeader("X-GitHub-Event")
+	if h.deferOnBackpressure(c, uint(projectID), "github", eventType, body) {
+		return
+	}
 
+	reqCtx := context.WithoutCancel(c.Request.Context())
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(reqCtx, 5*time.Minute)
 		defer cancel()
 		_ = h.webhookService.HandleGitHubWebhook(ctx, uint(projectID), eventType, body)
 	}()
@@ -215,7 +293,8 @@ func (h *WebhookHandler) HandleGitLabWebhookGeneric(c *gin.Context) {
 	}
 
 	var payload struct {
-		Project struct {
+		EventName string `json:"event_name"`
+		Project   struct {
 			Name       string `json:"name"`
 			WebURL     string `json:"web_url"`
 			GitHTTPURL string `json:"git_http_url"`
@@ -232,6 +311,13 @@ func (h *WebhookHandler) HandleGitLabWebhookGeneric(c *gin.Context) {
 	}
 	projectURL = strings.TrimSuffix(projectURL, ".git")
 
+	// Group webhooks also deliver group-level events (project_create, user_add_to_group, ...)
+	// that concern no repository; acknowledge them so GitLab doesn't disable the hook
+	if projectURL == "" && payload.EventName != "" {
+		response.Success(c, gin.H{"message": "group event ignored"})
+		return
+	}
+
 	if projectURL == "" {
 		response.BadRequest(c, "project URL not found in webhook payload")
 		return
@@ -250,6 +336,7 @@ func (h *WebhookHandler) HandleGitLabWebhookGeneric(c *gin.Context) {
 		eventType:   c.GetHeader("X-Gitlab-Event"),
 		body:        body,
 		clientIP:    c.ClientIP(),
+		reqCtx:      c.Request.Context(),
 		userAgent:   c.GetHeader("User-Agent"),
 	}
 
@@ -266,20 +353,22 @@ func (h *WebhookHandler) HandleGitLabWebhookGeneric(c *gin.Context) {
 		}
 		return
 	}
+	if h.rejectReplay(c, project, ctx.platform, body) {
+		return
+	}
 
-	services.LogInfo("Webhook", "Received", "Webhook received from GitLab", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+	services.LogInfo(ctx.reqCtx, "Webhook", "Received", "Webhook received from GitLab", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 		"project_id":   project.ID,
 		"project_name": project.Name,
 		"event_type":   ctx.eventType,
 	})
 
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-		_ = h.webhookService.HandleGitLabWebhook(bgCtx, project.ID, ctx.eventType, body)
-	}()
-
-	response.Success(c, gin.H{"message": "webhook received", "project_id": project.ID})
+	if h.deferOnBackpressure(c, project.ID, ctx.platform, ctx.eventType, body) {
+		return
+	}
+	h.processAsync(c, project.ID, func(bgCtx context.Context) error {
+		return h.webhookService.HandleGitLabWebhook(bgCtx, project.ID, ctx.eventType, body)
+	})
 }
 
 func (h *WebhookHandler) HandleGitHubWebhookGeneric(c *gin.Context) {
@@ -325,6 +414,7 @@ func (h *WebhookHandler) HandleGitHubWebhookGeneric(c *gin.Context) {
 		eventType:   c.GetHeader("X-GitHub-Event"),
 		body:        body,
 		clientIP:    c.ClientIP(),
+		reqCtx:      c.Request.Context(),
 		userAgent:   c.GetHeader("User-Agent"),
 	}
 
@@ -341,20 +431,22 @@ func (h *WebhookHandler) HandleGitHubWebhookGeneric(c *gin.Context) {
 		}
 		return
 	}
+	if h.rejectReplay(c, project, ctx.platform, body) {
+		return
+	}
 
-	services.LogInfo("Webhook", "Received", "Webhook received from GitHub", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+	services.LogInfo(ctx.reqCtx, "Webhook", "Received", "Webhook received from GitHub", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 		"project_id":   project.ID,
 		"project_name": project.Name,
 		"event_type":   ctx.eventType,
 	})
 
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-		_ = h.webhookService.HandleGitHubWebhook(bgCtx, project.ID, ctx.eventType, body)
-	}()
-
-	response.Success(c, gin.H{"message": "webhook received", "project_id": project.ID})
+	if h.deferOnBackpressure(c, project.ID, ctx.platform, ctx.eventType, body) {
+		return
+	}
+	h.processAsync(c, project.ID, func(bgCtx context.Context) error {
+		return h.webhookService.HandleGitHubWebhook(bgCtx, project.ID, ctx.eventType, body)
+	})
 }
 
 func (h *WebhookHandler) HandleBitbucketWebhook(c *gin.Context) {
@@ -377,15 +469,23 @@ func (h *WebhookHandler) HandleBitbucketWebhook(c *gin.Context) {
 	}
 
 	signature := c.GetHeader("X-Hub-Signature")
-	if project.WebhookSecret != "" && !webhook.VerifyBitbucketSignature(project.WebhookSecret, body, signature) {
+	if !services.VerifyWithSecret(c.Request.Context(), project.WebhookSecret, func(secret string) bool { return webhook.VerifyBitbucketSignature(secret, body, signature) }) {
 		response.Unauthorized(c, "invalid webhook signature")
 		return
 	}
 
+	if h.rejectReplay(c, project, "bitbucket", body) {
+		return
+	}
+
 	eventType := c.GetHeader("X-Event-Key")
+	if h.deferOnBackpressure(c, uint(projectID), "bitbucket", eventType, body) {
+		return
+	}
 
+	reqCtx := context.WithoutCancel(c.Request.Context())
 	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		ctx, cancel := context.WithTimeout(reqCtx, 5*time.Minute)
 		defer cancel()
 		_ = h.webhookService.HandleBitbucketWebhook(ctx, uint(projectID), eventType, body)
 	}()
@@ -408,6 +508,7 @@ func (h *WebhookHandler) HandleBitbucketWebhookGeneric(c *gin.Context) {
 				HTML struct {
 					Href string `json:"href"`
 				} `json:"html"`
+				Self json.RawMessage `json:"self"` // Bitbucket Server: [{"href": ".../repos/slug/browse"}]
 			} `json:"links"`
 		} `json:"repository"`
 	}
@@ -417,6 +518,14 @@ func (h *WebhookHandler) HandleBitbucketWebhookGeneric(c *gin.Context) {
 	}
 
 	projectURL := payload.Repository.Links.HTML.Href
+	if projectURL == "" {
+		var self []struct {
+			Href string `json:"href"`
+		}
+		if json.Unmarshal(payload.Repository.Links.Self, &self) == nil && len(self) > 0 {
+			projectURL = strings.TrimSuffix(self[0].Href, "/browse")
+		}
+	}
 	if projectURL == "" && payload.Repository.FullName != "" {
 		projectURL = "https://bitbucket.org/" + payload.Repository.FullName
 	}
@@ -440,6 +549,7 @@ func (h *WebhookHandler) HandleBitbucketWebhookGeneric(c *gin.Context) {
 		eventType:   c.GetHeader("X-Event-Key"),
 		body:        body,
 		clientIP:    c.ClientIP(),
+		reqCtx:      c.Request.Context(),
 		userAgent:   c.GetHeader("User-Agent"),
 	}
 
@@ -456,20 +566,22 @@ func (h *WebhookHandler) HandleBitbucketWebhookGeneric(c *gin.Context) {
 		}
 		return
 	}
+	if h.rejectReplay(c, project, ctx.platform, body) {
+		return
+	}
 
-	services.LogInfo("Webhook", "Received", "Webhook received from Bitbucket", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
+	services.LogInfo(ctx.reqCtx, "Webhook", "Received", "Webhook received from Bitbucket", nil, ctx.clientIP, ctx.userAgent, map[string]interface{}{
 		"project_id":   project.ID,
 		"project_name": project.Name,
 		"event_type":   ctx.eventType,
 	})
 
-	go func() {
-		bgCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
-		_ = h.webhookService.HandleBitbucketWebhook(bgCtx, project.ID, ctx.eventType, body)
-	}()
-
-	response.Success(c, gin.H{"message": "webhook received", "project_id": project.ID})
+	if h.deferOnBackpressure(c, project.ID, ctx.platform, ctx.eventType, body) {
+		return
+	}
+	h.processAsync(c, project.ID, func(bgCtx context.Context) error {
+		return h.webhookService.HandleBitbucketWebhook(bgCtx, project.ID, ctx.eventType, body)
+	})
 }
 
 func (h *WebhookHandler) HandleUnifiedWebhook(c *gin.Context) {
@@ -488,15 +600,56 @@ func (h *WebhookHandler) HandleUnifiedWebhook(c *gin.Context) {
 	}
 }
 
-func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
-	var req struct {
-		ProjectURL string `json:"project_url" binding:"required"`
-		CommitSHA  string `json:"commit_sha" binding:"required"`
-		Ref        string `json:"ref"`
-		Author     string `json:"author"`
-		Message    string `json:"message"`
-		Diffs      string `json:"diffs" binding:"required"`
+// processAsync handles a webhook in the background and responds immediately.
+// With ?wait=true the response carries a polling token for GET /review/status/:token.
+func (h *WebhookHandler) processAsync(c *gin.Context, projectID uint, handle func(ctx context.Context) error) {
+	var token string
+	if wait, _ := strconv.ParseBool(c.Query("wait")); wait {
+		var err error
+		if token, err = h.webhookService.CreatePoll(projectID); err != nil {
+			response.ServerError(c, "failed to create poll token")
+			return
+		}
 	}
+
+	// Detached from the request, but keeping its request ID
+	reqCtx := context.WithoutCancel(c.Request.Context())
+	go func() {
+		bgCtx, cancel := context.WithTimeout(reqCtx, 5*time.Minute)
+		defer cancel()
+		if token != "" {
+			bgCtx = webhook.WithPollToken(bgCtx, token)
+		}
+		err := handle(bgCtx)
+		if token != "" {
+			h.webhookService.FinishPoll(token, err)
+		}
+	}()
+
+	data := gin.H{"message": "webhook received", "project_id": projectID}
+	if token != "" {
+		data["poll_token"] = token
+		statusURL := "/review/status/" + token
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			statusURL = "/api" + statusURL
+		}
+		data["status_url"] = statusURL
+	}
+	response.Success(c, data)
+}
+
+// syncReviewRequest is the body of a synchronous (CI) review request
+type syncReviewRequest struct {
+	ProjectURL string `json:"project_url" binding:"required"`
+	CommitSHA  string `json:"commit_sha" binding:"required"`
+	Ref        string `json:"ref"`
+	Author     string `json:"author"`
+	Message    string `json:"message"`
+	Diffs      string `json:"diffs" binding:"required"`
+}
+
+func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
+	var req syncReviewRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		response.BadRequest(c, "invalid request: "+err.Error())
 		return
@@ -505,7 +658,7 @@ func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
 	projectURL := strings.TrimSuffix(req.ProjectURL, ".git")
 	project, err := h.projectService.GetByURL(projectURL)
 	if err != nil {
-		services.LogError("SyncReview", "ProjectNotFound", "Project not registered: "+projectURL, nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		services.LogError(c.Request.Context(), "SyncReview", "ProjectNotFound", "Project not registered: "+projectURL, nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
 			"project_url": projectURL,
 			"commit_sha":  req.CommitSHA,
 		})
@@ -514,8 +667,8 @@ func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
 	}
 
 	apiKey := c.GetHeader("X-API-Key")
-	if project.WebhookSecret != "" && apiKey != project.WebhookSecret {
-		services.LogWarning("SyncReview", "InvalidAPIKey", "Invalid API key", nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+	if !services.VerifyWithSecret(c.Request.Context(), project.WebhookSecret, func(secret string) bool { return apiKey == secret }) {
+		services.LogWarning(c.Request.Context(), "SyncReview", "InvalidAPIKey", "Invalid API key", nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
 			"project_id":  project.ID,
 			"project_url": projectURL,
 		})
@@ -523,7 +676,7 @@ func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
 		return
 	}
 
-	services.LogInfo("SyncReview", "Received", "Sync review request received", nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+	services.LogInfo(c.Request.Context(), "SyncReview", "Received", "Sync review request received", nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
 		"project_id":   project.ID,
 		"project_name": project.Name,
 		"commit_sha":   req.CommitSHA,
@@ -542,7 +695,12 @@ func (h *WebhookHandler) HandleSyncReview(c *gin.Context) {
 		Diffs:      req.Diffs,
 	})
 	if err != nil {
-		services.LogError("SyncReview", "ReviewFailed", err.Error(), nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
+		logFailure := services.LogError
+		if errors.Is(err, services.ErrAllLLMsFailed) {
+			// LLM outages raise one aggregated alert instead of one per failed review
+			logFailure = services.LogWarning
+		}
+		logFailure(c.Request.Context(), "SyncReview", "ReviewFailed", err.Error(), nil, c.ClientIP(), c.GetHeader("User-Agent"), map[string]interface{}{
 			"project_id": project.ID,
 			"commit_sha": req.CommitSHA,
 		})
@@ -568,3 +726,15 @@ func (h *WebhookHandler) GetReviewScore(c *gin.Context) {
 
 	response.Success(c, result)
 }
+
+// GetReviewStatus returns the outcome of a webhook received in wait mode
+// GET /review/status/:token
+func (h *WebhookHandler) GetReviewStatus(c *gin.Context) {
+	result, err := h.webhookService.GetPollStatus(c.Param("token"))
+	if err != nil {
+		response.NotFound(c, err.Error())
+		return
+	}
+
+	response.Success(c, result)
+}
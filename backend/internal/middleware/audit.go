@@ -51,7 +51,7 @@ func AuditLog() gin.HandlerFunc {
 			uid = &userID
 		}
 
-		services.LogInfo(module, action, message, uid, ip, userAgent, map[string]interface{}{
+		services.LogInfo(c.Request.Context(), module, action, message, uid, ip, userAgent, map[string]interface{}{
 			"method": method,
 			"path":   c.Request.URL.Path,
 			"status": status,
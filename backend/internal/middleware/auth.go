@@ -12,6 +12,7 @@ const (
 	ContextUserID   = "user_id"
 	ContextUsername = "username"
 	ContextRole     = "role"
+	ContextTenantID = "tenant_id"
 )
 
 // AuthRequired is a middleware that checks for a valid JWT token
@@ -46,11 +47,18 @@ func AuthRequired() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// Tokens issued before tenants existed carry no tenant; force a refresh instead of granting unscoped access
+		if claims.TenantID == 0 && claims.Role != "admin" {
+			response.Unauthorized(c, "invalid or expired token")
+			c.Abort()
+			return
+		}
 
 		// Set user info in context
 		c.Set(ContextUserID, claims.UserID)
 		c.Set(ContextUsername, claims.Username)
 		c.Set(ContextRole, claims.Role)
+		c.Set(ContextTenantID, claims.TenantID)
 
 		c.Next()
 	}
@@ -14,8 +14,8 @@ func CORS() gin.HandlerFunc {
 			return origin != ""
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Gitlab-Token", "X-Gitlab-Event", "X-GitHub-Event", "X-Hub-Signature", "X-Hub-Signature-256"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "X-Gitlab-Token", "X-Gitlab-Event", "X-GitHub-Event", "X-Hub-Signature", "X-Hub-Signature-256", "X-Request-ID"},
+		ExposeHeaders:    []string{"Content-Length", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
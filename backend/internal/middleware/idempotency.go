@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/huangang/codesentry/backend/pkg/response"
+)
+
+// HeaderIdempotencyKey lets clients retry a request without repeating its effects
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the stored key
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore persists idempotency keys, see services.IdempotencyService
+type IdempotencyStore interface {
+	Begin(scope, key, requestHash string) (*models.IdempotencyKey, bool, error)
+	Complete(id uint, status int, body []byte) error
+	Release(id uint) error
+}
+
+// Idempotency replays the stored response when a request is retried with the same
+// Idempotency-Key header, so CI retries do not create duplicate reviews or LLM calls.
+// Keys are scoped per route and caller; reusing a key with a different body is rejected.
+// Requests without the header are passed through unchanged.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			response.BadRequest(c, fmt.Sprintf("%s must be at most %d characters", HeaderIdempotencyKey, maxIdempotencyKeyLength))
+			c.Abort()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+		}
+		sum := sha256.Sum256(body)
+		requestHash := hex.EncodeToString(sum[:])
+
+		record, created, err := store.Begin(idempotencyScope(c), key, requestHash)
+		if err != nil {
+			logger.Errorf("[Idempotency] Failed to claim key: %v", err)
+			response.ServerError(c, "failed to process idempotency key")
+			c.Abort()
+			return
+		}
+
+		if !created {
+			switch {
+			case record.RequestHash != requestHash:
+				response.Fail(c, http.StatusUnprocessableEntity, 422, "idempotency key was already used for a different request")
+			case record.Status != "completed":
+				response.Error(c, response.NewConflict("a request with this idempotency key is still in progress"))
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.ResponseStatus, "application/json; charset=utf-8", []byte(record.ResponseBody))
+			}
+			c.Abort()
+			return
+		}
+
+		// A panicking handler frees the key before the recovery middleware answers 500
+		defer func() {
+			if r := recover(); r != nil {
+				if err := store.Release(record.ID); err != nil {
+					logger.Warnf("[Idempotency] Failed to release key %s: %v", key, err)
+				}
+				panic(r)
+			}
+		}()
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// Server errors are not stored so the request can be retried
+		if status := writer.Status(); status >= http.StatusInternalServerError {
+			err = store.Release(record.ID)
+		} else {
+			err = store.Complete(record.ID, status, writer.body.Bytes())
+		}
+		if err != nil {
+			logger.Warnf("[Idempotency] Failed to store result for key %s: %v", key, err)
+		}
+	}
+}
+
+// idempotencyScope identifies the route and the caller a key belongs to
+func idempotencyScope(c *gin.Context) string {
+	caller := "anonymous"
+	if userID := GetUserID(c); userID > 0 {
+		caller = fmt.Sprintf("user:%d", userID)
+	} else if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		caller = "api-key:" + hex.EncodeToString(sum[:8])
+	}
+	return c.Request.Method + " " + c.FullPath() + " " + caller
+}
+
+// bodyCaptureWriter copies the response body for storage
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
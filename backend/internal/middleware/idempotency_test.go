@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// memoryIdempotencyStore is an in-memory IdempotencyStore for tests
+type memoryIdempotencyStore struct {
+	records map[string]*models.IdempotencyKey
+	nextID  uint
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{records: make(map[string]*models.IdempotencyKey)}
+}
+
+func (s *memoryIdempotencyStore) Begin(scope, key, requestHash string) (*models.IdempotencyKey, bool, error) {
+	if record, ok := s.records[scope+"|"+key]; ok {
+		return record, false, nil
+	}
+	s.nextID++
+	record := &models.IdempotencyKey{ID: s.nextID, Scope: scope, Key: key, RequestHash: requestHash, Status: "processing"}
+	s.records[scope+"|"+key] = record
+	return record, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(id uint, status int, body []byte) error {
+	for _, record := range s.records {
+		if record.ID == id {
+			record.Status = "completed"
+			record.ResponseStatus = status
+			record.ResponseBody = string(body)
+		}
+	}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(id uint) error {
+	for k, record := range s.records {
+		if record.ID == id {
+			delete(s.records, k)
+		}
+	}
+	return nil
+}
+
+func hashOf(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+func newIdempotencyRouter(store IdempotencyStore, status *int, calls *int) *gin.Engine {
+	router := gin.New()
+	router.POST("/review/sync", Idempotency(store), func(c *gin.Context) {
+		*calls++
+		c.JSON(*status, gin.H{"call": *calls})
+	})
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/review/sync", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(HeaderIdempotencyKey, key)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysResponse(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &status, &calls)
+
+	first := postWithKey(router, "abc", `{"commit_sha":"1"}`)
+	second := postWithKey(router, "abc", `{"commit_sha":"1"}`)
+
+	if calls != 1 {
+		t.Errorf("expected handler to run once, ran %d times", calls)
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("expected replayed response, got %d %s", second.Code, second.Body.String())
+	}
+}
+
+func TestIdempotency_Rejections(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	store := newMemoryIdempotencyStore()
+	router := newIdempotencyRouter(store, &status, &calls)
+
+	postWithKey(router, "abc", `{"commit_sha":"1"}`)
+	if w := postWithKey(router, "abc", `{"commit_sha":"2"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: expected 422, got %d", w.Code)
+	}
+
+	store.Begin("POST /review/sync anonymous", "pending", hashOf(`{}`))
+	if w := postWithKey(router, "pending", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("in-progress key: expected 409, got %d", w.Code)
+	}
+}
+
+func TestIdempotency_ServerErrorsAreRetryable(t *testing.T) {
+	status, calls := http.StatusInternalServerError, 0
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &status, &calls)
+
+	postWithKey(router, "abc", `{}`)
+	status = http.StatusOK
+	if w := postWithKey(router, "abc", `{}`); w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("expected retry to run the handler again, got %d after %d calls", w.Code, calls)
+	}
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	status, calls := http.StatusOK, 0
+	router := newIdempotencyRouter(newMemoryIdempotencyStore(), &status, &calls)
+
+	postWithKey(router, "", `{}`)
+	postWithKey(router, "", `{}`)
+	if calls != 2 {
+		t.Errorf("expected requests without a key to pass through, ran %d times", calls)
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	store := newMemoryIdempotencyStore()
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) { c.AbortWithStatus(http.StatusInternalServerError) }))
+	router.POST("/review/sync", Idempotency(store), func(c *gin.Context) { panic("boom") })
+
+	if w := postWithKey(router, "abc", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	if len(store.records) != 0 {
+		t.Errorf("expected the key of a panicking request to be released, got %d records", len(store.records))
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/huangang/codesentry/backend/pkg/response"
+)
+
+// IPAllowListSource provides the allowed networks of a scope, see services.IPAllowListService
+type IPAllowListSource interface {
+	AllowedNetworks(scope string) []*net.IPNet
+}
+
+// IPAllowList rejects requests from client IPs outside the allow-list of a scope.
+// Requests pass when no networks are configured for the scope. Behind a reverse proxy,
+// server.trusted_proxies must be set so the client IP cannot be spoofed via X-Forwarded-For.
+func IPAllowList(source IPAllowListSource, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		if !services.IPAllowed(ip, source.AllowedNetworks(scope)) {
+			logger.Warnf("[IPAllowList] Rejected %s %s from %s (scope: %s)", c.Request.Method, c.Request.URL.Path, ip, scope)
+			response.Forbidden(c, "access from this IP address is not allowed")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
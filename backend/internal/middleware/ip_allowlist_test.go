@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type staticAllowList map[string][]*net.IPNet
+
+func (s staticAllowList) AllowedNetworks(scope string) []*net.IPNet {
+	return s[scope]
+}
+
+func TestIPAllowList(t *testing.T) {
+	_, office, _ := net.ParseCIDR("10.0.0.0/8")
+	source := staticAllowList{"admin": {office}}
+
+	tests := []struct {
+		name       string
+		scope      string
+		remoteAddr string
+		wantStatus int
+	}{
+		{"allowed ip", "admin", "10.1.2.3:5000", http.StatusOK},
+		{"rejected ip", "admin", "192.168.1.1:5000", http.StatusForbidden},
+		{"scope without allow-list", "webhook", "192.168.1.1:5000", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(IPAllowList(source, tt.scope))
+			router.GET("/test", func(c *gin.Context) {
+				c.JSON(200, gin.H{"status": "ok"})
+			})
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+		})
+	}
+}
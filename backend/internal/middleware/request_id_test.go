@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	var seen string
+	router.GET("/test", func(c *gin.Context) {
+		seen = logger.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		wantSame bool
+	}{
+		{"generated", "", false},
+		{"reused", "trace-42.a_b:c", true},
+		{"too long", strings.Repeat("a", 65), false},
+		{"unsafe characters", "id\nforged log line", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/test", nil)
+			if tt.incoming != "" {
+				req.Header.Set(logger.RequestIDHeader, tt.incoming)
+			}
+			router.ServeHTTP(w, req)
+
+			header := w.Header().Get(logger.RequestIDHeader)
+			if header == "" || header != seen {
+				t.Fatalf("response header %q should match the context request ID %q", header, seen)
+			}
+			if (header == tt.incoming) != tt.wantSame {
+				t.Errorf("request ID = %q, incoming %q, want reused %v", header, tt.incoming, tt.wantSame)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/pkg/response"
+)
+
+// HeaderTenantID lets platform admins scope a request to a single tenant
+const HeaderTenantID = "X-Tenant-ID"
+
+// TenantAdminRequired is a middleware that allows platform admins and tenant admins
+func TenantAdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := GetRole(c)
+		if role != "admin" && role != "tenant_admin" {
+			response.Forbidden(c, "admin access required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// IsPlatformAdmin reports whether the current user is a platform admin, not bound to a tenant
+func IsPlatformAdmin(c *gin.Context) bool {
+	return GetRole(c) == "admin"
+}
+
+// GetUserTenantID gets the tenant the current user belongs to
+func GetUserTenantID(c *gin.Context) uint {
+	if id, exists := c.Get(ContextTenantID); exists {
+		return id.(uint)
+	}
+	return 0
+}
+
+// GetTenantID returns the tenant data access is scoped to. Platform admins see all
+// tenants (0) unless they select one with the X-Tenant-ID header; everyone else is
+// limited to their own tenant.
+func GetTenantID(c *gin.Context) uint {
+	if GetRole(c) != "admin" {
+		return GetUserTenantID(c)
+	}
+	if header := c.GetHeader(HeaderTenantID); header != "" {
+		if id, err := strconv.ParseUint(header, 10, 32); err == nil {
+			return uint(id)
+		}
+	}
+	return 0
+}
+
+// GetWriteTenantID returns the tenant new records are created in: the scoped tenant,
+// or the user's own tenant when a platform admin is not scoped to one
+func GetWriteTenantID(c *gin.Context) uint {
+	if id := GetTenantID(c); id > 0 {
+		return id
+	}
+	return GetUserTenantID(c)
+}
+
+// CanAccessTenant reports whether the current user may access records of the given tenant
+func CanAccessTenant(c *gin.Context, tenantID uint) bool {
+	scope := GetTenantID(c)
+	return scope == 0 || scope == tenantID
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/internal/utils"
+)
+
+func newTenantContext(role string, tenantID uint, header string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/", nil)
+	if header != "" {
+		c.Request.Header.Set(HeaderTenantID, header)
+	}
+	c.Set(ContextRole, role)
+	c.Set(ContextTenantID, tenantID)
+	return c
+}
+
+func TestGetTenantID(t *testing.T) {
+	tests := []struct {
+		name      string
+		role      string
+		tenantID  uint
+		header    string
+		wantScope uint
+		wantWrite uint
+	}{
+		{"admin sees all tenants", "admin", 1, "", 0, 1},
+		{"admin selects tenant", "admin", 1, "3", 3, 3},
+		{"admin with invalid header", "admin", 1, "abc", 0, 1},
+		{"tenant admin is scoped", "tenant_admin", 2, "", 2, 2},
+		{"header ignored for non-admin", "user", 2, "3", 2, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTenantContext(tt.role, tt.tenantID, tt.header)
+			if got := GetTenantID(c); got != tt.wantScope {
+				t.Errorf("GetTenantID() = %d, want %d", got, tt.wantScope)
+			}
+			if got := GetWriteTenantID(c); got != tt.wantWrite {
+				t.Errorf("GetWriteTenantID() = %d, want %d", got, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestCanAccessTenant(t *testing.T) {
+	if !CanAccessTenant(newTenantContext("admin", 1, ""), 5) {
+		t.Error("admin should access any tenant")
+	}
+	if CanAccessTenant(newTenantContext("admin", 1, "2"), 5) {
+		t.Error("admin scoped to tenant 2 should not access tenant 5")
+	}
+	if !CanAccessTenant(newTenantContext("user", 2, ""), 2) {
+		t.Error("user should access own tenant")
+	}
+	if CanAccessTenant(newTenantContext("tenant_admin", 2, ""), 3) {
+		t.Error("tenant admin should not access another tenant")
+	}
+}
+
+func TestTenantAdminRequired(t *testing.T) {
+	tests := []struct {
+		role     string
+		wantCode int
+	}{
+		{"admin", http.StatusOK},
+		{"tenant_admin", http.StatusOK},
+		{"user", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set(ContextRole, tt.role)
+			c.Next()
+		})
+		router.Use(TenantAdminRequired())
+		router.GET("/tenant-admin", func(c *gin.Context) {
+			c.JSON(200, gin.H{"status": "ok"})
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/tenant-admin", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Errorf("role %q: expected status %d, got %d", tt.role, tt.wantCode, w.Code)
+		}
+	}
+}
+
+func TestAuthRequired_TokenWithoutTenant(t *testing.T) {
+	router := gin.New()
+	router.Use(AuthRequired())
+	router.GET("/protected", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	legacy, _ := utils.GenerateToken(1, "testuser", "user", 24)
+	scoped, _ := utils.GenerateTenantToken(1, 2, "testuser", "user", 24)
+
+	for token, wantCode := range map[string]int{legacy: http.StatusUnauthorized, scoped: http.StatusOK} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+
+		if w.Code != wantCode {
+			t.Errorf("expected status %d, got %d", wantCode, w.Code)
+		}
+	}
+}
@@ -13,6 +13,8 @@ type AIUsageLog struct {
 	PromptTokens     int       `json:"prompt_tokens"`
 	CompletionTokens int       `json:"completion_tokens"`
 	TotalTokens      int       `json:"total_tokens"`
+	CachedTokens     int       `json:"cached_tokens"`      // Prompt tokens read from the provider prompt cache
+	CacheWriteTokens int       `json:"cache_write_tokens"` // Prompt tokens written to the provider prompt cache
 	LatencyMs        int64     `json:"latency_ms"`
 	Success          bool      `json:"success"`
 	ErrorMessage     string    `gorm:"size:500" json:"error_message,omitempty"`
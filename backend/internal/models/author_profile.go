@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AuthorProfile caches the platform user a commit author email resolves to, so each
+// email is looked up once per platform host
+type AuthorProfile struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Host       string    `gorm:"size:255;uniqueIndex:idx_author_profiles_host_email" json:"host"` // e.g. github.com or gitlab.example.com
+	Email      string    `gorm:"size:255;uniqueIndex:idx_author_profiles_host_email" json:"email"`
+	Username   string    `gorm:"size:200" json:"username"`
+	AvatarURL  string    `gorm:"size:500" json:"avatar_url"`
+	ProfileURL string    `gorm:"size:500" json:"profile_url"`
+	Found      bool      `gorm:"default:false" json:"found"` // False when no platform user has the email
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+func (AuthorProfile) TableName() string { return "author_profiles" }
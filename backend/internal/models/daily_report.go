@@ -5,7 +5,8 @@ import "time"
 // DailyReport represents a daily code review report
 type DailyReport struct {
 	ID         uint      `gorm:"primaryKey" json:"id"`
-	ReportDate time.Time `gorm:"uniqueIndex;not null" json:"report_date"`
+	TenantID   uint      `gorm:"uniqueIndex:idx_daily_reports_tenant_date;default:0" json:"tenant_id"`
+	ReportDate time.Time `gorm:"uniqueIndex:idx_daily_reports_tenant_date;not null" json:"report_date"`
 	ReportType string    `gorm:"size:20;default:daily" json:"report_type"` // daily, weekly
 
 	TotalProjects  int     `json:"total_projects"`
@@ -21,6 +22,7 @@ type DailyReport struct {
 	TopProjects     string `gorm:"type:text" json:"top_projects"`
 	TopAuthors      string `gorm:"type:text" json:"top_authors"`
 	LowScoreReviews string `gorm:"type:text" json:"low_score_reviews"`
+	BranchHealth    string `gorm:"type:text" json:"branch_health"` // Default branch vs feature branch statistics (JSON)
 
 	AIAnalysis  string `gorm:"type:text" json:"ai_analysis"`
 	AIModelUsed string `gorm:"size:100" json:"ai_model_used"`
@@ -41,7 +41,15 @@ func InitDB(cfg *config.DatabaseConfig) error {
 }
 
 func AutoMigrate() error {
+	// Daily reports are unique per tenant and date; drop the legacy date-only unique index
+	if DB.Migrator().HasTable(&DailyReport{}) && DB.Migrator().HasIndex(&DailyReport{}, "idx_daily_reports_report_date") {
+		if err := DB.Migrator().DropIndex(&DailyReport{}, "idx_daily_reports_report_date"); err != nil {
+			return err
+		}
+	}
+
 	return DB.AutoMigrate(
+		&Tenant{},
 		&User{},
 		&RefreshToken{},
 		&Project{},
@@ -60,6 +68,27 @@ func AutoMigrate() error {
 		&ProjectMember{},
 		&IssueTracker{},
 		&ReviewRule{},
+		&NotificationDigestItem{},
+		&ReviewLogArchive{},
+		&ProjectTemplateBinding{},
+		&TargetBranchPolicy{},
+		&ReviewPoll{},
+		&IdempotencyKey{},
+		&PendingReviewTask{},
+		&ReviewFinding{},
+		&ReviewFile{},
+		&SavedDashboard{},
+		&ReportPublisher{},
+		&ShadowReview{},
+		&ImportJob{},
+		&AuthorProfile{},
+		&ReviewHook{},
+		&DeferredWebhook{},
+		&UserNotificationPreference{},
+		&FeatureFlag{},
+		&Experiment{},
+		&NotificationDelivery{},
+		&WebhookDelivery{},
 	)
 }
 
@@ -69,6 +98,10 @@ func GetDB() *gorm.DB {
 
 // SeedDefaultData creates default data if not exists
 func SeedDefaultData() error {
+	if err := ensureDefaultTenant(); err != nil {
+		return err
+	}
+
 	// Create default prompt templates (Chinese and English)
 	var promptCount int64
 	DB.Model(&PromptTemplate{}).Where("is_system = ?", true).Count(&promptCount)
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// DeferredWebhook is a webhook delivery accepted while reviews were over capacity, kept to be
+// processed once the backlog drains
+type DeferredWebhook struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	ProjectID uint   `gorm:"index" json:"project_id"`
+	Platform  string `gorm:"size:20" json:"platform"` // gitlab, github, bitbucket
+	EventType string `gorm:"size:100" json:"event_type"`
+	Body      string `gorm:"type:MEDIUMTEXT;not null" json:"-"`
+	RequestID string `gorm:"size:64" json:"request_id"` // Request that delivered the webhook, to correlate its logs
+	// ClaimedUntil is when the replay of the replica that claimed the delivery is given up,
+	// so another replica replays it again
+	ClaimedUntil *time.Time `gorm:"index" json:"claimed_until,omitempty"`
+	Attempts     int        `gorm:"default:0" json:"attempts"` // Replays claimed so far
+	CreatedAt    time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (DeferredWebhook) TableName() string { return "deferred_webhooks" }
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Experiment statuses
+const (
+	ExperimentStatusDraft   = "draft"
+	ExperimentStatusRunning = "running"
+	ExperimentStatusStopped = "stopped"
+)
+
+// Experiment variants recorded on reviews
+const (
+	ExperimentVariantA = "a"
+	ExperimentVariantB = "b"
+)
+
+// Experiment splits a project's reviews between two prompts or LLM configs. A commit is
+// always assigned the same variant, so retries and later batches of a review compare alike.
+// A variant without a prompt or LLM config uses the project's regular one.
+type Experiment struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	ProjectID           uint       `gorm:"index;not null" json:"project_id"`
+	Name                string     `gorm:"size:100;not null" json:"name"`
+	Description         string     `gorm:"size:500" json:"description"`
+	Status              string     `gorm:"size:20;default:draft;index" json:"status"` // draft, running, stopped
+	TrafficB            int        `gorm:"default:50" json:"traffic_b"`               // Percentage of commits assigned variant B, 0-100
+	VariantAPromptID    *uint      `json:"variant_a_prompt_id"`
+	VariantALLMConfigID *uint      `json:"variant_a_llm_config_id"`
+	VariantBPromptID    *uint      `json:"variant_b_prompt_id"`
+	VariantBLLMConfigID *uint      `json:"variant_b_llm_config_id"`
+	StartedAt           *time.Time `json:"started_at"`
+	StoppedAt           *time.Time `json:"stopped_at"`
+	CreatedBy           string     `gorm:"size:100" json:"created_by"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func (Experiment) TableName() string { return "experiments" }
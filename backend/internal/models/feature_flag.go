@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// FeatureFlag turns a risky feature on for some projects, or a stable percentage of them,
+// without a redeploy
+type FeatureFlag struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	Key                string    `gorm:"uniqueIndex;size:100;not null" json:"key"` // e.g. chunked_review
+	Description        string    `gorm:"size:500" json:"description"`
+	Enabled            bool      `gorm:"default:false" json:"enabled"`          // Kill switch: off everywhere when false
+	Rollout            int       `gorm:"default:0" json:"rollout"`              // Percentage of the other projects the flag is on for, 0-100
+	ProjectIDs         string    `gorm:"size:1000" json:"project_ids"`          // Comma-separated projects always on
+	ExcludedProjectIDs string    `gorm:"size:1000" json:"excluded_project_ids"` // Comma-separated projects always off
+	UpdatedBy          string    `gorm:"size:100" json:"updated_by"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+func (FeatureFlag) TableName() string { return "feature_flags" }
@@ -8,36 +8,53 @@ import (
 
 // GitCredential represents a Git platform credential for auto-creating projects
 type GitCredential struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	Name           string         `gorm:"size:200;not null" json:"name"`
-	Platform       string         `gorm:"size:50;not null" json:"platform"`    // github, gitlab
-	BaseURL        string         `gorm:"size:500" json:"base_url"`            // For self-hosted GitLab, e.g., https://gitlab.example.com
-	AccessToken    string         `gorm:"size:500" json:"-"`                   // Token for API access
-	WebhookSecret  string         `gorm:"size:255" json:"-"`                   // Secret for webhook verification
-	AutoCreate     bool           `gorm:"default:true" json:"auto_create"`     // Auto-create projects on webhook
-	DefaultEnabled bool           `gorm:"default:true" json:"default_enabled"` // Default AI enabled for new projects
-	FileExtensions string         `gorm:"size:1000" json:"file_extensions"`    // Default file extensions for new projects
-	ReviewEvents   string         `gorm:"size:200" json:"review_events"`       // Default review events: push,merge_request
-	IgnorePatterns string         `gorm:"size:2000" json:"ignore_patterns"`    // Default ignore patterns
-	IsActive       bool           `gorm:"default:true" json:"is_active"`       // Whether this credential is active
-	CreatedBy      uint           `json:"created_by"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                 uint           `gorm:"primaryKey" json:"id"`
+	Name               string         `gorm:"size:200;not null" json:"name"`
+	Platform           string         `gorm:"size:50;not null" json:"platform"`          // github, gitlab
+	BaseURL            string         `gorm:"size:500" json:"base_url"`                  // For self-hosted GitLab, e.g., https://gitlab.example.com
+	AccessToken        string         `gorm:"size:500" json:"-"`                         // Token for API access
+	WebhookSecret      string         `gorm:"size:255" json:"-"`                         // Secret for webhook verification
+	BitbucketAuth      string         `gorm:"size:20" json:"bitbucket_auth"`             // Bitbucket: token (bearer, default), oauth (Cloud consumer) or basic
+	Username           string         `gorm:"size:200" json:"username"`                  // Bitbucket basic auth user, with AccessToken as its password
+	OAuthKey           string         `gorm:"size:255" json:"-"`                         // Bitbucket Cloud OAuth consumer key
+	OAuthSecret        string         `gorm:"size:255" json:"-"`                         // Bitbucket Cloud OAuth consumer secret
+	Namespaces         string         `gorm:"size:2000" json:"namespaces"`               // Group paths the credential is restricted to, comma separated; empty covers the whole host
+	AllowPatterns      string         `gorm:"size:2000" json:"allow_patterns"`           // Repository globs auto-creation is limited to, e.g. platform/**,mobile/app-*
+	DenyPatterns       string         `gorm:"size:2000" json:"deny_patterns"`            // Repository globs never auto-created, e.g. */sandbox-*
+	MaxAutoCreated     int            `gorm:"default:0" json:"max_auto_created"`         // Cap on projects auto-created from the credential (0 = no limit)
+	RequireApproval    bool           `gorm:"default:false" json:"require_approval"`     // Auto-created projects stay disabled until an admin approves them
+	AutoCreate         bool           `gorm:"default:true" json:"auto_create"`           // Auto-create projects on webhook
+	DefaultEnabled     bool           `gorm:"default:true" json:"default_enabled"`       // Default AI enabled for new projects
+	FileExtensions     string         `gorm:"size:1000" json:"file_extensions"`          // Default file extensions for new projects
+	ReviewEvents       string         `gorm:"size:200" json:"review_events"`             // Default review events: push,merge_request
+	IgnorePatterns     string         `gorm:"size:2000" json:"ignore_patterns"`          // Default ignore patterns
+	IsActive           bool           `gorm:"default:true" json:"is_active"`             // Whether this credential is active
+	CACert             string         `gorm:"type:text" json:"-"`                        // PEM CA certificates trusted for BaseURL's host besides the system roots
+	InsecureSkipVerify bool           `gorm:"default:false" json:"insecure_skip_verify"` // Disables TLS verification for BaseURL's host
+	CreatedBy          uint           `json:"created_by"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (GitCredential) TableName() string { return "git_credentials" }
 
-// MaskAccessToken returns masked access token for display
+// MaskAccessToken returns masked access token for display; secret references are shown as is
 func (g *GitCredential) MaskAccessToken() string {
+	if IsSecretRef(g.AccessToken) {
+		return g.AccessToken
+	}
 	if len(g.AccessToken) <= 8 {
 		return "****"
 	}
 	return g.AccessToken[:4] + "****" + g.AccessToken[len(g.AccessToken)-4:]
 }
 
-// MaskWebhookSecret returns masked webhook secret for display
+// MaskWebhookSecret returns masked webhook secret for display; secret references are shown as is
 func (g *GitCredential) MaskWebhookSecret() string {
+	if IsSecretRef(g.WebhookSecret) {
+		return g.WebhookSecret
+	}
 	if len(g.WebhookSecret) <= 8 {
 		return "****"
 	}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// IdempotencyKey stores the response of a request sent with an Idempotency-Key header
+// so that retries return the original result instead of repeating the work
+type IdempotencyKey struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	Scope          string    `gorm:"uniqueIndex:idx_idempotency_scope_key;size:255;not null" json:"scope"` // method, route and caller
+	Key            string    `gorm:"column:idempotency_key;uniqueIndex:idx_idempotency_scope_key;size:255;not null" json:"key"`
+	RequestHash    string    `gorm:"size:64;not null" json:"request_hash"`
+	Status         string    `gorm:"size:20;default:processing" json:"status"` // processing, completed
+	ResponseStatus int       `json:"response_status"`
+	ResponseBody   string    `gorm:"type:text" json:"response_body"`
+	ExpiresAt      time.Time `gorm:"index" json:"expires_at"` // End of the processing lease, then of the replay TTL
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (IdempotencyKey) TableName() string { return "idempotency_keys" }
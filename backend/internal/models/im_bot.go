@@ -15,8 +15,20 @@ type IMBot struct {
 	Secret             string         `gorm:"size:255" json:"-"`
 	Extra              string         `gorm:"size:500" json:"extra"` // Extra config (e.g., Telegram chat_id)
 	IsActive           bool           `gorm:"default:true" json:"is_active"`
-	ErrorNotify        bool           `gorm:"default:false" json:"error_notify"`         // Whether to receive error notifications
-	DailyReportEnabled bool           `gorm:"default:false" json:"daily_report_enabled"` // Whether to receive daily reports
+	ErrorNotify        bool           `gorm:"default:false" json:"error_notify"`          // Whether to receive error notifications
+	DailyReportEnabled bool           `gorm:"default:false" json:"daily_report_enabled"`  // Whether to receive daily reports
+	MessageTemplate    string         `gorm:"type:text" json:"message_template"`          // Go template or {{placeholder}} syntax; empty uses the built-in format
+	MessageFields      string         `gorm:"size:255" json:"message_fields"`             // Comma-separated fields for the built-in format; empty shows all
+	MessageMaxLength   int            `gorm:"default:0" json:"message_max_length"`        // Max message length in characters, 0 = unlimited
+	MessageLanguage    string         `gorm:"size:10;default:en" json:"message_language"` // en, zh
+	DigestEnabled      bool           `gorm:"default:false" json:"digest_enabled"`        // Batch review notifications into periodic summaries
+	DigestInterval     int            `gorm:"default:0" json:"digest_interval"`           // Digest interval in minutes, 0 = once at day end
+	QuietHoursStart    string         `gorm:"size:5" json:"quiet_hours_start"`            // HH:MM, non-critical notifications are deferred until quiet hours end
+	QuietHoursEnd      string         `gorm:"size:5" json:"quiet_hours_end"`              // HH:MM, may be earlier than start for overnight windows
+	Timezone           string         `gorm:"size:64" json:"timezone"`                    // Timezone for quiet hours and holidays, empty = server local
+	SkipHolidays       bool           `gorm:"default:false" json:"skip_holidays"`         // Defer non-critical notifications on non-workdays
+	HolidayCountry     string         `gorm:"size:10" json:"holiday_country"`             // Holiday calendar, empty = daily report country
+	TenantID           uint           `gorm:"index;default:0" json:"tenant_id"`
 	CreatedAt          time.Time      `json:"created_at"`
 	UpdatedAt          time.Time      `json:"updated_at"`
 	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`
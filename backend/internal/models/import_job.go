@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// ImportJob is a commit import of a project. Its progress is persisted after every page,
+// so jobs can be listed, cancelled and resumed after a restart.
+type ImportJob struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	ProjectID      uint       `gorm:"index" json:"project_id"`
+	Project        *Project   `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
+	StartDate      string     `gorm:"size:10" json:"start_date"` // 2006-01-02
+	EndDate        string     `gorm:"size:10" json:"end_date"`
+	Review         bool       `gorm:"default:false" json:"review"` // Review the imported commits retroactively
+	ReviewRate     int        `gorm:"default:0" json:"review_rate"`
+	MergeRequests  bool       `gorm:"default:false" json:"merge_requests"` // Import merge requests after the commits
+	Status         string     `gorm:"size:20;index" json:"status"`         // running, completed, failed, cancelled
+	Phase          string     `gorm:"size:20" json:"phase"`                // commits, merge_requests
+	Cursor         string     `gorm:"size:1000" json:"-"`                  // Next page of the phase: page number for GitLab and GitHub, URL for Bitbucket
+	PagesProcessed int        `gorm:"default:0" json:"pages_processed"`
+	Imported       int        `gorm:"default:0" json:"imported"`
+	Skipped        int        `gorm:"default:0" json:"skipped"`
+	MRImported     int        `gorm:"default:0" json:"mr_imported"`
+	MRSkipped      int        `gorm:"default:0" json:"mr_skipped"`
+	Errors         string     `gorm:"type:text" json:"errors"` // Per-commit errors, one per line
+	ErrorMessage   string     `gorm:"type:text" json:"error_message"`
+	Attempt        int        `gorm:"default:1" json:"attempt"` // Incremented whenever the job is resumed
+	CreatedBy      string     `gorm:"size:100" json:"created_by"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (ImportJob) TableName() string { return "import_jobs" }
@@ -10,15 +10,18 @@ import (
 type LLMConfig struct {
 	ID          uint           `gorm:"primaryKey" json:"id"`
 	Name        string         `gorm:"size:100;not null" json:"name"`
-	Provider    string         `gorm:"size:50;default:openai" json:"provider"` // openai, azure, anthropic, etc.
+	Provider    string         `gorm:"size:50;default:openai" json:"provider"` // openai, azure, anthropic, gemini, ollama, vllm, tgi
 	BaseURL     string         `gorm:"size:500;not null" json:"base_url"`
 	APIKey      string         `gorm:"size:500" json:"-"`
 	APIKeyMask  string         `gorm:"-" json:"api_key_mask"` // For display only
 	Model       string         `gorm:"size:100" json:"model"`
 	MaxTokens   int            `gorm:"default:4096" json:"max_tokens"`
 	Temperature float64        `gorm:"default:0.3" json:"temperature"`
+	PromptCache bool           `gorm:"default:false" json:"prompt_cache"` // Mark the static prompt preamble as cacheable (Anthropic cache_control)
+	JSONMode    bool           `gorm:"default:false" json:"json_mode"`    // Enforce the review schema via JSON schema, tool use or structured output
 	IsDefault   bool           `gorm:"default:false" json:"is_default"`
 	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	TenantID    uint           `gorm:"index;default:0" json:"tenant_id"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
@@ -26,8 +29,11 @@ type LLMConfig struct {
 
 func (LLMConfig) TableName() string { return "llm_configs" }
 
-// MaskAPIKey returns masked API key for display
+// MaskAPIKey returns masked API key for display; secret references are shown as is
 func (l *LLMConfig) MaskAPIKey() string {
+	if IsSecretRef(l.APIKey) {
+		return l.APIKey
+	}
 	if len(l.APIKey) <= 8 {
 		return "****"
 	}
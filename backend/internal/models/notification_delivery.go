@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// NotificationDelivery is one attempt to send a notification to an IM bot. Retries and
+// resends of a notification are new attempts pointing to its first one.
+type NotificationDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	ParentID      *uint      `gorm:"index" json:"parent_id"` // First attempt of a retried or resent notification
+	Attempt       int        `gorm:"default:1" json:"attempt"`
+	BotID         uint       `gorm:"index" json:"bot_id"`
+	BotName       string     `gorm:"size:100" json:"bot_name"`
+	BotType       string     `gorm:"size:50" json:"bot_type"`
+	TenantID      uint       `gorm:"index;default:0" json:"tenant_id"`
+	ProjectID     *uint      `gorm:"index" json:"project_id"`
+	Kind          string     `gorm:"size:20" json:"kind"`               // review, text
+	Payload       string     `gorm:"type:text" json:"-"`                // JSON of the notification, to retry or resend it
+	PayloadHash   string     `gorm:"size:64;index" json:"payload_hash"` // SHA-256 of Payload
+	Summary       string     `gorm:"size:200" json:"summary"`           // Project and score, or the first line of a text message
+	Status        string     `gorm:"size:20;index" json:"status"`       // sent, retrying, failed
+	Error         string     `gorm:"size:1000" json:"error,omitempty"`
+	DurationMs    int64      `json:"duration_ms"`
+	NextAttemptAt *time.Time `gorm:"index" json:"next_attempt_at"` // Retrying: when the notification is sent again
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+}
+
+func (NotificationDelivery) TableName() string { return "notification_deliveries" }
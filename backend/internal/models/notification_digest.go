@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// NotificationDigestItem is a review notification queued for a bot in digest mode
+type NotificationDigestItem struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	BotID         uint      `gorm:"index;not null" json:"bot_id"`
+	ProjectID     uint      `json:"project_id"`
+	ProjectName   string    `gorm:"size:255" json:"project_name"`
+	Branch        string    `gorm:"size:255" json:"branch"`
+	Author        string    `gorm:"size:255" json:"author"`
+	CommitMessage string    `gorm:"size:500" json:"commit_message"`
+	Score         float64   `json:"score"`
+	EventType     string    `gorm:"size:50" json:"event_type"`
+	MRURL         string    `gorm:"size:500" json:"mr_url"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+func (NotificationDigestItem) TableName() string { return "notification_digest_items" }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PendingReviewTask persists a review task of the in-process queue so that
+// queued reviews survive a restart when Redis is not used
+type PendingReviewTask struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ReviewLogID uint       `gorm:"index" json:"review_log_id"`
+	Payload     string     `gorm:"type:MEDIUMTEXT;not null" json:"-"`           // JSON encoded services.ReviewTask
+	Status      string     `gorm:"size:20;default:pending;index" json:"status"` // pending, running
+	LockedBy    string     `gorm:"size:100" json:"locked_by"`
+	StartedAt   *time.Time `json:"started_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (PendingReviewTask) TableName() string { return "pending_review_tasks" }
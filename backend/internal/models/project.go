@@ -8,28 +8,57 @@ import (
 
 // Project represents a code repository project
 type Project struct {
-	ID             uint           `gorm:"primaryKey" json:"id"`
-	Name           string         `gorm:"size:200;not null" json:"name"`
-	URL            string         `gorm:"size:500;not null" json:"url"`
-	Platform       string         `gorm:"size:50;not null" json:"platform"` // github, gitlab
-	AccessToken    string         `gorm:"size:500" json:"-"`
-	WebhookSecret  string         `gorm:"size:255" json:"-"`
-	FileExtensions string         `gorm:"size:1000" json:"file_extensions"` // .js,.ts,.go,...
-	ReviewEvents   string         `gorm:"size:200" json:"review_events"`    // push,merge_request
-	BranchFilter   string         `gorm:"size:1000" json:"branch_filter"`   // Branches to ignore: main,master,release/*
-	AIEnabled      bool           `gorm:"column:ai_enabled;default:true" json:"ai_enabled"`
-	AIPromptID     *uint          `gorm:"column:a_iprompt_id" json:"ai_prompt_id"`     // Reference to PromptTemplate
-	AIPrompt       string         `gorm:"column:a_iprompt;type:text" json:"ai_prompt"` // Custom prompt override
-	LLMConfigID    *uint          `gorm:"column:llm_config_id" json:"llm_config_id"`   // Reference to LLMConfig
-	IgnorePatterns string         `gorm:"size:2000" json:"ignore_patterns"`            // Patterns to ignore: vendor/,node_modules/,*.min.js
-	CommentEnabled bool           `gorm:"default:false" json:"comment_enabled"`
-	IMEnabled      bool           `gorm:"default:false" json:"im_enabled"`
-	IMBotID        *uint          `json:"im_bot_id"`
-	MinScore       float64        `gorm:"default:0" json:"min_score"` // Minimum score to pass (0 = use system default)
-	CreatedBy      uint           `json:"created_by"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Name             string         `gorm:"size:200;not null" json:"name"`
+	URL              string         `gorm:"size:500;not null" json:"url"`
+	Platform         string         `gorm:"size:50;not null" json:"platform"` // github, gitlab
+	AccessToken      string         `gorm:"size:500" json:"-"`
+	WebhookSecret    string         `gorm:"size:255" json:"-"`
+	CredentialID     *uint          `gorm:"index" json:"credential_id"`                       // Git credential the project was auto-created from
+	FileExtensions   string         `gorm:"size:1000" json:"file_extensions"`                 // .js,.ts,.go,...
+	ReviewEvents     string         `gorm:"size:200" json:"review_events"`                    // push,merge_request
+	BranchFilter     string         `gorm:"size:1000" json:"branch_filter"`                   // Branch patterns: main,release/*,*-hotfix
+	BranchFilterMode string         `gorm:"size:20;default:ignore" json:"branch_filter_mode"` // ignore: skip matching branches, allow: review only matching branches
+	AIEnabled        bool           `gorm:"column:ai_enabled;default:true" json:"ai_enabled"`
+	AIPromptID       *uint          `gorm:"column:a_iprompt_id" json:"ai_prompt_id"`     // Reference to PromptTemplate
+	AIPrompt         string         `gorm:"column:a_iprompt;type:text" json:"ai_prompt"` // Custom prompt override
+	LLMConfigID      *uint          `gorm:"column:llm_config_id" json:"llm_config_id"`   // Reference to LLMConfig
+	IncludePatterns  string         `gorm:"size:2000" json:"include_patterns"`           // Review only matching paths: src/**,libs/shared/ (empty = all)
+	IgnorePatterns   string         `gorm:"size:2000" json:"ignore_patterns"`            // Patterns to ignore: vendor/,node_modules/,*.min.js
+	TechProfile      string         `gorm:"size:500" json:"tech_profile"`                // Languages and frameworks, e.g. Go + Gin + GORM ({{project_profile}} in prompts)
+	CommentEnabled   bool           `gorm:"default:false" json:"comment_enabled"`
+	IMEnabled        bool           `gorm:"default:false" json:"im_enabled"`
+	IMBotID          *uint          `json:"im_bot_id"`
+	ReleaseIMBotID   *uint          `json:"release_im_bot_id"`                           // IM bot for tag/release reviews (defaults to IMBotID)
+	MinScore         float64        `gorm:"default:0" json:"min_score"`                  // Minimum score to pass (0 = use system default)
+	MaxChangedLines  int            `gorm:"default:0" json:"max_changed_lines"`          // Skip reviews changing more lines (0 = no limit)
+	MaxFiles         int            `gorm:"default:0" json:"max_files"`                  // Skip reviews changing more files (0 = no limit)
+	MaxDiffBytes     int            `gorm:"default:0" json:"max_diff_bytes"`             // Skip reviews with a larger diff (0 = no limit)
+	MaxFileBytes     int            `gorm:"default:0" json:"max_file_bytes"`             // Leave out files with a larger diff (0 = no limit)
+	RetryMaxAttempts *int           `json:"retry_max_attempts"`                          // Automatic retries of a failed review (nil = 3, 0 = none)
+	RetryBackoff     int            `gorm:"default:0" json:"retry_backoff"`              // Minutes before the first retry, doubled for each next one (0 = 5)
+	BadgeEnabled     bool           `gorm:"default:false" json:"badge_enabled"`          // Serve public score badges
+	BadgeToken       string         `gorm:"size:64" json:"-"`                            // Required by public badges when set
+	ReplayProtection bool           `gorm:"default:false" json:"replay_protection"`      // Reject replayed and stale webhook deliveries
+	SignaturePolicy  string         `gorm:"size:20;default:off" json:"signature_policy"` // off, annotate, enforce: check commits are GPG/SSH signed
+	SignedBranches   string         `gorm:"size:500" json:"signed_branches"`             // Branches where enforce fails unsigned commits (empty = all)
+	Archived         bool           `gorm:"default:false;index" json:"archived"`         // Webhooks are ignored and the project is hidden from default lists
+	ArchivedAt       *time.Time     `json:"archived_at"`
+	PendingApproval  bool           `gorm:"default:false;index" json:"pending_approval"`                        // Auto-created and disabled until an admin approves it
+	IaCReviewMode    string         `gorm:"column:iac_review_mode;size:20;default:auto" json:"iac_review_mode"` // auto, off: review Terraform/Kubernetes/CloudFormation changes with the IaC prompt
+	MigrationPolicy  string         `gorm:"size:20;default:review" json:"migration_policy"`                     // off, review, acknowledge: destructive migrations hold the commit status until acknowledged
+	PerCommitReview  bool           `gorm:"default:false" json:"per_commit_review"`                             // Review each commit of a push separately, with one notification per push
+	SkipDirectives   bool           `gorm:"default:false" json:"skip_directives"`                               // Honor [skip review] / [codesentry skip] in commit messages and the codesentry.skip push option
+	SkipBranches     string         `gorm:"size:500" json:"skip_branches"`                                      // Branches where skip directives are honored (empty = all)
+	DefaultBranch    string         `gorm:"size:255" json:"default_branch"`                                     // Tracked from push events and the platform API; separates main branch statistics
+	TenantID         uint           `gorm:"index;default:0" json:"tenant_id"`
+	CreatedBy        uint           `json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TemplateBindings     []ProjectTemplateBinding `gorm:"foreignKey:ProjectID" json:"template_bindings,omitempty"`      // Review templates by event type and branch
+	TargetBranchPolicies []TargetBranchPolicy     `gorm:"foreignKey:ProjectID" json:"target_branch_policies,omitempty"` // Merge request rules by target branch
 }
 
 func (Project) TableName() string { return "projects" }
@@ -13,7 +13,8 @@ type ProjectMember struct {
 	Project   *Project       `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
 	UserID    uint           `gorm:"uniqueIndex:idx_project_user;not null" json:"user_id"`
 	User      *User          `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Role      string         `gorm:"size:50;default:viewer" json:"role"` // owner, maintainer, viewer
+	Role      string         `gorm:"size:50;default:viewer" json:"role"`   // owner, maintainer, viewer
+	Source    string         `gorm:"size:20;default:manual" json:"source"` // manual, ldap
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ProjectTemplateBinding selects a review template for a project by event type and branch pattern
+type ProjectTemplateBinding struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	ProjectID     uint      `gorm:"not null;index" json:"project_id"`
+	TemplateID    uint      `gorm:"not null" json:"template_id"`    // Reference to ReviewTemplate
+	EventType     string    `gorm:"size:50" json:"event_type"`      // push, merge_request; empty = any
+	BranchPattern string    `gorm:"size:200" json:"branch_pattern"` // main, release/*; empty = any
+	Priority      int       `gorm:"default:0" json:"priority"`      // Higher wins between equally specific bindings
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (ProjectTemplateBinding) TableName() string { return "project_template_bindings" }
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReportPublisher publishes generated reports to a Confluence page or a repository wiki page.
+type ReportPublisher struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Name            string         `gorm:"size:100;not null" json:"name"`
+	Type            string         `gorm:"size:50;not null" json:"type"` // confluence, gitlab_wiki
+	BaseURL         string         `gorm:"size:500" json:"base_url"`     // e.g., https://yourcompany.atlassian.net/wiki
+	Username        string         `gorm:"size:200" json:"username"`     // Confluence Cloud account email, empty uses a bearer token
+	APIToken        string         `gorm:"size:500" json:"api_token,omitempty"`
+	APITokenMask    string         `gorm:"-" json:"api_token_mask"`
+	Space           string         `gorm:"size:200" json:"space"`                      // Confluence space key or GitLab project path/ID
+	ParentPageID    string         `gorm:"size:100" json:"parent_page_id"`             // Confluence only: pages are created under this page
+	ReportTypes     string         `gorm:"size:100;default:daily" json:"report_types"` // Comma-separated: daily, weekly
+	TitleTemplate   string         `gorm:"size:255" json:"title_template"`             // Supports {type}, {date} and {tenant}
+	TenantID        uint           `gorm:"index;default:0" json:"tenant_id"`           // 0 publishes the reports of every tenant
+	IsActive        bool           `gorm:"default:true" json:"is_active"`
+	LastPublishedAt *time.Time     `json:"last_published_at"`
+	LastError       string         `gorm:"type:text" json:"last_error"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (ReportPublisher) TableName() string { return "report_publishers" }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ReviewFile is a file changed by a completed review, kept for per-path analytics
+type ReviewFile struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ReviewLogID uint      `gorm:"index;not null" json:"review_log_id"`
+	ProjectID   uint      `gorm:"index:idx_review_files_project_created,priority:1" json:"project_id"`
+	Path        string    `gorm:"size:500" json:"path"`
+	Language    string    `gorm:"size:30" json:"language"`
+	Additions   int       `json:"additions"`
+	Deletions   int       `json:"deletions"`
+	CreatedAt   time.Time `gorm:"index:idx_review_files_project_created,priority:2" json:"created_at"`
+}
+
+func (ReviewFile) TableName() string { return "review_files" }
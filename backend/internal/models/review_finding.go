@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReviewFinding is a single issue extracted from a completed review, kept for analytics
+type ReviewFinding struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ReviewLogID uint      `gorm:"index;not null" json:"review_log_id"`
+	ProjectID   uint      `gorm:"index" json:"project_id"`
+	Author      string    `gorm:"size:255;index" json:"author"`
+	Category    string    `gorm:"size:30;index" json:"category"` // security, correctness, performance, maintainability, testing, other
+	Title       string    `gorm:"size:500" json:"title"`
+	FilePath    string    `gorm:"size:500" json:"file_path"`       // Changed file the finding mentions, if any
+	Compliance  string    `gorm:"size:30;index" json:"compliance"` // Cloud compliance category of findings on IaC files: network-exposure, iam, secrets, encryption, logging, drift
+	CreatedAt   time.Time `gorm:"index" json:"created_at"`
+}
+
+func (ReviewFinding) TableName() string { return "review_findings" }
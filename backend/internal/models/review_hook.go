@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReviewHook is an outbound HTTP hook called before or after the AI review of each diff,
+// so organizations can add their own checks without changing CodeSentry
+type ReviewHook struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	Name           string         `gorm:"size:100;not null" json:"name"`
+	Stage          string         `gorm:"size:20;not null" json:"stage"` // pre_review, post_review
+	URL            string         `gorm:"size:500;not null" json:"url"`
+	Secret         string         `gorm:"size:255" json:"secret,omitempty"` // Signs payloads with HMAC-SHA256 when set
+	SecretMask     string         `gorm:"-" json:"secret_mask"`
+	TimeoutSeconds int            `gorm:"default:10" json:"timeout_seconds"`
+	Required       bool           `gorm:"default:false" json:"required"`    // A failing required pre-review hook fails the review
+	ProjectIDs     string         `gorm:"size:500" json:"project_ids"`      // Comma-separated, empty = every project of the tenant
+	TenantID       uint           `gorm:"index;default:0" json:"tenant_id"` // 0 applies to the projects of every tenant
+	Priority       int            `gorm:"default:0" json:"priority"`        // Hooks of a stage run in ascending priority
+	IsActive       bool           `gorm:"default:true" json:"is_active"`
+	LastCalledAt   *time.Time     `json:"last_called_at"`
+	LastError      string         `gorm:"type:text" json:"last_error"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (ReviewHook) TableName() string { return "review_hooks" }
@@ -9,13 +9,13 @@ import (
 // ReviewLog represents a code review record
 type ReviewLog struct {
 	ID                  uint           `gorm:"primaryKey" json:"id"`
-	ProjectID           uint           `gorm:"index;not null" json:"project_id"`
+	ProjectID           uint           `gorm:"index;index:idx_review_logs_project_created,priority:1;not null" json:"project_id"`
 	Project             *Project       `gorm:"foreignKey:ProjectID" json:"project,omitempty"`
-	EventType           string         `gorm:"size:50;not null" json:"event_type"` // push, merge_request
+	EventType           string         `gorm:"size:50;not null" json:"event_type"` // push, merge_request, release
 	CommitHash          string         `gorm:"size:100;index" json:"commit_hash"`
 	CommitURL           string         `gorm:"size:500" json:"commit_url"`
 	Branch              string         `gorm:"size:200" json:"branch"`
-	Author              string         `gorm:"size:200" json:"author"`
+	Author              string         `gorm:"size:200;index:idx_review_logs_author_created,priority:1" json:"author"`
 	AuthorEmail         string         `gorm:"size:255" json:"author_email"`
 	AuthorAvatar        string         `gorm:"size:500" json:"author_avatar"`
 	AuthorURL           string         `gorm:"size:500" json:"author_url"`
@@ -27,19 +27,56 @@ type ReviewLog struct {
 	OriginalScore       *float64       `json:"original_score"`                        // AI original score, preserved when manually overridden
 	ScoreOverrideReason string         `gorm:"size:500" json:"score_override_reason"` // Reason for manual score override
 	ReviewResult        string         `gorm:"type:text" json:"review_result"`
-	ReviewStatus        string         `gorm:"size:50;default:pending" json:"review_status"` // pending, completed, failed
+	ReviewStatus        string         `gorm:"size:50;default:pending;index:idx_review_logs_status_created,priority:1" json:"review_status"` // pending, analyzing, completed, failed, skipped, skipped_too_large
 	CommentPosted       bool           `gorm:"default:false" json:"comment_posted"`
 	ErrorMessage        string         `gorm:"type:text" json:"error_message"`
 	RetryCount          int            `gorm:"default:0" json:"retry_count"`
+	NextRetryAt         *time.Time     `gorm:"index" json:"next_retry_at"`                // Next automatic retry of a failed review, set by the retry scheduler
+	RetryCanceled       bool           `gorm:"default:false" json:"retry_canceled"`       // Automatic retries stopped by an admin
+	RequestID           string         `gorm:"size:64;index" json:"request_id,omitempty"` // Correlation ID of the last processing, to find its logs
 	IsManual            bool           `gorm:"default:false" json:"is_manual"`
-	LLMConfigID         *uint          `json:"llm_config_id"` // Which LLM was used
-	MRNumber            *int           `json:"mr_number"`     // Merge Request number
+	Retroactive         bool           `gorm:"default:false" json:"retroactive"`  // Review of an imported historical commit: no notifications, comments or commit statuses
+	LLMConfigID         *uint          `json:"llm_config_id"`                     // Which LLM was used
+	PromptRef           string         `gorm:"size:50;index" json:"prompt_ref"`   // Prompt the review was made with, e.g. prompt_template:3
+	ExperimentID        *uint          `gorm:"index" json:"experiment_id"`        // Experiment the review took part in
+	ExperimentVariant   string         `gorm:"size:10" json:"experiment_variant"` // a, b
+	MRNumber            *int           `json:"mr_number"`                         // Merge Request number
+	TargetBranch        string         `gorm:"size:255" json:"target_branch"`     // Branch a merge request merges into, selects its target branch policy
 	MRURL               string         `gorm:"size:500" json:"mr_url"`
-	DiffContent         string         `gorm:"type:MEDIUMTEXT" json:"-"`       // Raw diff for diff viewer (not in list API)
-	DiffHash            string         `gorm:"size:64;index" json:"diff_hash"` // SHA-256 of filtered diff for cache dedup
-	FixPRURL            string         `gorm:"size:500" json:"fix_pr_url"`     // URL of auto-generated fix PR/MR
-	FixStatus           string         `gorm:"size:50" json:"fix_status"`      // pending, completed, failed
-	CreatedAt           time.Time      `gorm:"index" json:"created_at"`
+	DiffContent         string         `gorm:"type:MEDIUMTEXT" json:"-"`          // Raw diff for diff viewer (not in list API)
+	DiffHash            string         `gorm:"size:64;index" json:"diff_hash"`    // SHA-256 of filtered diff for cache dedup
+	FixPRURL            string         `gorm:"size:500" json:"fix_pr_url"`        // URL of auto-generated fix PR/MR
+	FixStatus           string         `gorm:"size:50" json:"fix_status"`         // pending, completed, failed
+	Archived            bool           `gorm:"default:false" json:"archived"`     // ReviewResult/DiffContent moved to review_log_archives
+	UntestedFiles       int            `gorm:"default:0" json:"untested_files"`   // Changed source files without a matching test change
+	UnsignedCommits     int            `gorm:"default:0" json:"unsigned_commits"` // Commits without a verified signature, when checked
+	LanguageStats       string         `gorm:"size:1000" json:"language_stats"`   // JSON array: [{"language":"go","additions":10,"deletions":2,"percent":60}]
+	PrimaryLanguage     string         `gorm:"size:30;index" json:"primary_language"`
+	HumanVerdict        string         `gorm:"size:20;index" json:"human_verdict"` // accepted, rejected; empty when no maintainer annotated the review
+	HumanScore          *float64       `json:"human_score"`                        // Maintainer's score, kept apart from the AI score
+	HumanVerdictReason  string         `gorm:"size:1000" json:"human_verdict_reason"`
+	HumanVerdictBy      string         `gorm:"size:100" json:"human_verdict_by"`
+	HumanVerdictAt      *time.Time     `json:"human_verdict_at"`
+	RevisionOf          *uint          `gorm:"index" json:"revision_of"`              // Original review this scoped retry re-runs
+	ReviewScope         string         `gorm:"size:1000" json:"review_scope"`         // Comma-separated paths a scoped retry was limited to
+	PromptOverride      string         `gorm:"type:text" json:"prompt_override"`      // Prompt a scoped retry was reviewed with
+	MigrationStatements string         `gorm:"type:text" json:"migration_statements"` // Destructive migration statements found, one per line
+	MigrationAck        string         `gorm:"size:20;index" json:"migration_ack"`    // pending, acknowledged; empty when the commit status needs no acknowledgment
+	MigrationAckBy      string         `gorm:"size:100" json:"migration_ack_by"`
+	MigrationAckAt      *time.Time     `json:"migration_ack_at"`
+	SummaryHeadline     string         `gorm:"size:500" json:"-"`                         // LLM-written summary headline, cached for the review text it was written for
+	SummaryHash         string         `gorm:"size:64" json:"-"`                          // Hash of the review text SummaryHeadline summarizes
+	PushHead            string         `gorm:"size:100;index" json:"push_head,omitempty"` // Per-commit reviews: head commit of the push the commit came in
+	PushNotified        bool           `gorm:"default:false" json:"-"`                    // Per-commit reviews: set on the head review once the push notification is sent
+	QueuedAt            *time.Time     `json:"queued_at"`                                 // Review task queued
+	StartedAt           *time.Time     `json:"started_at"`                                // A worker started processing the review
+	FinishedAt          *time.Time     `json:"finished_at"`                               // Processing ended, whatever the outcome
+	DiffFetchMs         int64          `gorm:"default:0" json:"diff_fetch_ms"`            // Fetching the diff from the platform
+	QueueWaitMs         int64          `gorm:"default:0" json:"queue_wait_ms"`            // Queued until a worker started it
+	ContextBuildMs      int64          `gorm:"default:0" json:"context_build_ms"`         // Reading the file context
+	LLMMs               int64          `gorm:"default:0" json:"llm_ms"`                   // Every LLM call, with chunk batches and fallbacks
+	CommentPostMs       int64          `gorm:"default:0" json:"comment_post_ms"`          // Posting the review comment
+	CreatedAt           time.Time      `gorm:"index;index:idx_review_logs_project_created,priority:2;index:idx_review_logs_author_created,priority:2;index:idx_review_logs_status_created,priority:2" json:"created_at"`
 	UpdatedAt           time.Time      `json:"updated_at"`
 	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// ReviewLogArchive holds the bulky text columns of old review logs moved out of review_logs
+type ReviewLogArchive struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ReviewLogID  uint      `gorm:"uniqueIndex;not null" json:"review_log_id"`
+	ReviewResult string    `gorm:"type:text" json:"review_result"`
+	DiffContent  string    `gorm:"type:MEDIUMTEXT" json:"-"`
+	ArchivedAt   time.Time `gorm:"index" json:"archived_at"`
+}
+
+func (ReviewLogArchive) TableName() string { return "review_log_archives" }
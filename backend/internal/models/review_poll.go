@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ReviewPoll tracks a webhook received in wait mode so CI can poll for its outcome
+type ReviewPoll struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Token       string    `gorm:"uniqueIndex;size:64;not null" json:"token"`
+	ProjectID   uint      `gorm:"index;not null" json:"project_id"`
+	ReviewLogID *uint     `gorm:"index" json:"review_log_id"`            // Set once the webhook created a review
+	Status      string    `gorm:"size:20;default:pending" json:"status"` // pending, done, failed
+	Error       string    `gorm:"type:text" json:"error"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (ReviewPoll) TableName() string { return "review_polls" }
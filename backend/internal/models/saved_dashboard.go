@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedDashboard is a named set of filters and metrics computed server-side for a custom dashboard
+type SavedDashboard struct {
+	ID          uint           `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"size:100;not null" json:"name"`
+	Description string         `gorm:"size:500" json:"description"`
+	UserID      uint           `gorm:"index" json:"user_id"`                   // Owner
+	Shared      bool           `gorm:"default:false" json:"shared"`            // Visible to all users of the tenant
+	ProjectIDs  string         `gorm:"size:500" json:"project_ids"`            // Comma-separated, empty = all projects
+	RangeDays   int            `gorm:"default:30" json:"range_days"`           // Relative date range, used without fixed dates
+	StartDate   string         `gorm:"size:10" json:"start_date"`              // Fixed range start, YYYY-MM-DD
+	EndDate     string         `gorm:"size:10" json:"end_date"`                // Fixed range end, YYYY-MM-DD, empty = today
+	Metrics     string         `gorm:"size:500;not null" json:"metrics"`       // Comma-separated metric keys
+	Granularity string         `gorm:"size:10;default:day" json:"granularity"` // day, week, month
+	TenantID    uint           `gorm:"index;default:0" json:"tenant_id"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (SavedDashboard) TableName() string { return "saved_dashboards" }
@@ -0,0 +1,15 @@
+package models
+
+import "strings"
+
+// Prefixes of secret references stored instead of plaintext tokens and keys
+const (
+	SecretRefEnv   = "env:"
+	SecretRefVault = "vault:"
+)
+
+// IsSecretRef reports whether a token or key field holds a reference to an environment
+// variable (env:NAME) or a Vault secret (vault:path#field) instead of the secret itself
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefEnv) || strings.HasPrefix(value, SecretRefVault)
+}
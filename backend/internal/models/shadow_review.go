@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ShadowReview is a review made by the shadow LLM config or prompt alongside a primary
+// review. It never affects commit statuses or notifications.
+type ShadowReview struct {
+	ID                 uint      `gorm:"primaryKey" json:"id"`
+	ReviewLogID        uint      `gorm:"index" json:"review_log_id"`
+	ProjectID          uint      `gorm:"index" json:"project_id"`
+	PrimaryLLMConfigID uint      `json:"primary_llm_config_id"` // 0 when a chunked review spanned several configs
+	ShadowLLMConfigID  uint      `gorm:"index" json:"shadow_llm_config_id"`
+	ShadowPromptID     *uint     `json:"shadow_prompt_id"`
+	MinScore           float64   `json:"min_score"` // Effective minimum score of the project at review time
+	PrimaryScore       float64   `json:"primary_score"`
+	ShadowScore        *float64  `json:"shadow_score"`
+	PrimaryLatencyMs   int64     `json:"primary_latency_ms"`
+	ShadowLatencyMs    int64     `json:"shadow_latency_ms"`
+	ShadowResult       string    `gorm:"type:text" json:"shadow_result"`
+	Success            bool      `json:"success"`
+	ErrorMessage       string    `gorm:"size:500" json:"error_message,omitempty"`
+	CreatedAt          time.Time `gorm:"index" json:"created_at"`
+}
+
+func (ShadowReview) TableName() string { return "shadow_reviews" }
@@ -13,6 +13,7 @@ type SystemLog struct {
 	IP        string    `gorm:"size:50" json:"ip"`
 	UserAgent string    `gorm:"size:500" json:"user_agent"`
 	Extra     string    `gorm:"type:text" json:"extra"` // JSON extra data
+	RequestID string    `gorm:"size:64;index" json:"request_id,omitempty"`
 	CreatedAt time.Time `gorm:"index" json:"created_at"`
 }
 
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TargetBranchPolicy sets stricter review rules for merge requests into matching target branches
+type TargetBranchPolicy struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	ProjectID           uint      `gorm:"not null;index" json:"project_id"`
+	BranchPattern       string    `gorm:"size:200;not null" json:"branch_pattern"` // Target branch: main, release/*
+	MinScore            float64   `gorm:"default:0" json:"min_score"`              // Minimum score to pass (0 = project minimum)
+	MaxCriticalFindings *int      `json:"max_critical_findings"`                   // Security and correctness findings allowed (nil = no limit)
+	ReviewTemplateID    *uint     `json:"review_template_id"`                      // Review template used instead of the bound one
+	Urgent              bool      `gorm:"default:false" json:"urgent"`             // Notifications bypass quiet hours and digests
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+func (TargetBranchPolicy) TableName() string { return "target_branch_policies" }
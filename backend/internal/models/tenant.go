@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultTenantSlug identifies the workspace that pre-existing data is assigned to
+const DefaultTenantSlug = "default"
+
+// Tenant represents an isolated workspace (e.g. a business unit) within one deployment
+type Tenant struct {
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Name         string         `gorm:"size:100;not null" json:"name"`
+	Slug         string         `gorm:"uniqueIndex;size:50;not null" json:"slug"`
+	IsActive     bool           `gorm:"default:true" json:"is_active"`
+	DisplayName  string         `gorm:"size:200" json:"display_name"` // Branding: title shown in the UI, empty = CodeSentry
+	LogoURL      string         `gorm:"size:500" json:"logo_url"`     // Branding: logo image URL
+	PrimaryColor string         `gorm:"size:20" json:"primary_color"` // Branding: theme color, e.g. #1677ff
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (Tenant) TableName() string { return "tenants" }
+
+// DefaultTenantID returns the ID of the default tenant, or 0 if it has not been created
+func DefaultTenantID() uint {
+	var tenant Tenant
+	if err := DB.Where("slug = ?", DefaultTenantSlug).First(&tenant).Error; err != nil {
+		return 0
+	}
+	return tenant.ID
+}
+
+// tenantScopedModels are the models that belong to a tenant
+var tenantScopedModels = []interface{}{&User{}, &Project{}, &LLMConfig{}, &IMBot{}, &DailyReport{}}
+
+// ensureDefaultTenant creates the default tenant and assigns all unassigned data to it
+func ensureDefaultTenant() error {
+	var tenant Tenant
+	err := DB.Where("slug = ?", DefaultTenantSlug).First(&tenant).Error
+	if err == gorm.ErrRecordNotFound {
+		tenant = Tenant{Name: "Default", Slug: DefaultTenantSlug, IsActive: true}
+		if err := DB.Create(&tenant).Error; err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	for _, model := range tenantScopedModels {
+		if err := DB.Model(model).Where("tenant_id = ?", 0).Update("tenant_id", tenant.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -14,9 +14,10 @@ type User struct {
 	Email     string         `gorm:"size:255" json:"email"`
 	Nickname  string         `gorm:"size:100" json:"nickname"`
 	Avatar    string         `gorm:"size:500" json:"avatar"`
-	Role      string         `gorm:"size:50;default:user" json:"role"`       // admin, developer, user
+	Role      string         `gorm:"size:50;default:user" json:"role"`       // admin, tenant_admin, developer, user
 	AuthType  string         `gorm:"size:20;default:local" json:"auth_type"` // local, ldap
 	IsActive  bool           `gorm:"default:true" json:"is_active"`
+	TenantID  uint           `gorm:"index;default:0" json:"tenant_id"`
 	LastLogin *time.Time     `json:"last_login"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
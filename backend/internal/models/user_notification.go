@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// UserNotificationPreference holds a user's linked IM identity and the personal notifications
+// they subscribed to, delivered as direct messages by an IM bot
+type UserNotificationPreference struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	UserID             uint       `gorm:"uniqueIndex;not null" json:"user_id"`
+	IMBotID            *uint      `json:"im_bot_id"`                                // Telegram bot, or Slack bot with a bot token, sending the direct messages
+	SlackUserID        string     `gorm:"size:50" json:"slack_user_id"`             // e.g. U024BE7LH
+	TelegramChatID     string     `gorm:"size:50" json:"telegram_chat_id"`          // Chat of the user with the Telegram bot
+	VerificationCode   string     `gorm:"size:64" json:"-"`                         // SHA-256 of the code last sent to the linked identity
+	VerifiedAt         *time.Time `json:"verified_at"`                              // When the user confirmed the linked identity, nil = unverified
+	MyCommits          bool       `gorm:"default:false" json:"my_commits"`          // Reviews of the user's commits
+	MaintainedProjects bool       `gorm:"default:false" json:"maintained_projects"` // Reviews in projects the user owns or maintains
+	ScoreBelow         float64    `gorm:"default:0" json:"score_below"`             // Only reviews scoring below this (0 = all)
+	DailyDigest        bool       `gorm:"default:false" json:"daily_digest"`        // Daily summary of the subscribed reviews
+	DigestTime         string     `gorm:"size:5" json:"digest_time"`                // HH:MM the digest is sent at, 09:00 when empty
+	Timezone           string     `gorm:"size:64" json:"timezone"`                  // Timezone of the digest time, empty = server local
+	LastDigestAt       *time.Time `json:"last_digest_at"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+func (UserNotificationPreference) TableName() string { return "user_notification_preferences" }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WebhookDelivery is a delivery ID received by a project with replay protection, kept for
+// the replay window so every replica rejects a replayed delivery, also after a restart
+type WebhookDelivery struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProjectID  uint      `gorm:"uniqueIndex:idx_webhook_delivery;not null" json:"project_id"`
+	Platform   string    `gorm:"uniqueIndex:idx_webhook_delivery;size:20;not null" json:"platform"`
+	DeliveryID string    `gorm:"uniqueIndex:idx_webhook_delivery;size:255;not null" json:"delivery_id"`
+	ReceivedAt time.Time `gorm:"index" json:"received_at"`
+}
+
+func (WebhookDelivery) TableName() string { return "webhook_deliveries" }
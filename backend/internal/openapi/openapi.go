@@ -0,0 +1,394 @@
+// Package openapi generates an OpenAPI 3 document from the registered Gin routes.
+// Every route is listed; routes with a RouteSpec additionally get a summary and
+// request/response schemas derived from their Go types by reflection.
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI specification version the document conforms to
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// RouteSpec documents one route. Query, Request and Response are zero values of the
+// Go types bound from the query string, bound from the JSON body and returned as data.
+type RouteSpec struct {
+	Summary  string
+	Public   bool // No bearer token required
+	Query    interface{}
+	Request  interface{}
+	Response interface{}
+}
+
+// Builder assembles a Document, collecting named struct schemas as components
+type Builder struct {
+	doc   *Document
+	names map[reflect.Type]string
+}
+
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]map[string]*Operation),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					"bearerAuth": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Build generates the document for routes, keyed in specs by "METHOD /path"
+func Build(info Info, routes gin.RoutesInfo, specs map[string]RouteSpec) *Document {
+	b := NewBuilder(info)
+	sorted := make(gin.RoutesInfo, len(routes))
+	copy(sorted, routes)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Path == sorted[j].Path {
+			return sorted[i].Method < sorted[j].Method
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	used := make(map[string]int)
+	for _, route := range sorted {
+		spec, ok := specs[route.Method+" "+route.Path]
+		if !ok && !strings.HasPrefix(route.Path, "/api/") {
+			// Undocumented root-level routes (SPA assets, compatibility aliases) are left out
+			continue
+		}
+		id := operationID(route.Handler)
+		used[id]++
+		if used[id] > 1 {
+			id = fmt.Sprintf("%s%d", id, used[id])
+		}
+		b.AddOperation(route.Method, route.Path, id, spec)
+	}
+	return b.doc
+}
+
+// AddOperation adds one route to the document
+func (b *Builder) AddOperation(method, ginPath, operationID string, spec RouteSpec) {
+	path, params := convertPath(ginPath)
+	op := &Operation{
+		OperationID: operationID,
+		Summary:     spec.Summary,
+		Tags:        []string{routeTag(ginPath)},
+		Parameters:  params,
+		Responses: map[string]*Response{
+			"200":     {Description: "Success", Content: jsonContent(b.envelope(spec.Response))},
+			"default": {Description: "Error", Content: jsonContent(b.envelope(nil))},
+		},
+	}
+	if spec.Query != nil {
+		op.Parameters = append(op.Parameters, b.queryParameters(reflect.TypeOf(spec.Query))...)
+	}
+	if spec.Request != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: jsonContent(b.SchemaFor(reflect.TypeOf(spec.Request)))}
+	}
+	if !spec.Public && strings.HasPrefix(ginPath, "/api/") {
+		op.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	if b.doc.Paths[path] == nil {
+		b.doc.Paths[path] = make(map[string]*Operation)
+	}
+	b.doc.Paths[path][strings.ToLower(method)] = op
+}
+
+// envelope wraps a data schema in the unified {code, message, data} response format
+func (b *Builder) envelope(data interface{}) *Schema {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+	if data != nil {
+		s.Properties["data"] = b.SchemaFor(reflect.TypeOf(data))
+	}
+	return s
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaFor returns the schema of a Go type; named structs are emitted as component references
+func (b *Builder) SchemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		// $ref siblings are ignored in OpenAPI 3.0, so references can't be marked nullable
+		return &Schema{Ref: "#/components/schemas/" + b.component(t)}
+	case t.Kind() == reflect.Struct:
+		s = b.structSchema(t)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		s = &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		s = &Schema{Type: "array", Items: b.SchemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		s = &Schema{Type: "object", AdditionalProperties: b.SchemaFor(t.Elem())}
+	case t.Kind() == reflect.Interface:
+		s = &Schema{}
+	default:
+		s = primitiveSchema(t)
+	}
+	s.Nullable = nullable
+	return s
+}
+
+// component registers a named struct under components/schemas and returns its name
+func (b *Builder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+	name := exportName(t.Name())
+	if _, taken := b.doc.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = exportName(pkg[strings.LastIndex(pkg, "/")+1:]) + name
+	}
+	b.names[t] = name
+	b.doc.Components.Schemas[name] = &Schema{} // Placeholder, breaks recursion
+	*b.doc.Components.Schemas[name] = *b.structSchema(t)
+	return name
+}
+
+func (b *Builder) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	b.collectFields(t, s)
+	return s
+}
+
+func (b *Builder) collectFields(t reflect.Type, s *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := jsonName(f)
+		if !ok {
+			continue
+		}
+		if f.Anonymous && f.Tag.Get("json") == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.collectFields(ft, s)
+				continue
+			}
+		}
+		s.Properties[name] = b.SchemaFor(f.Type)
+		if isRequired(f) {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// queryParameters lists the form-tagged fields of a query struct
+func (b *Builder) queryParameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("form"), ",")[0]
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		params = append(params, Parameter{Name: name, In: "query", Required: isRequired(f), Schema: b.SchemaFor(f.Type)})
+	}
+	return params
+}
+
+func primitiveSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	default:
+		return &Schema{Type: "string"}
+	}
+}
+
+func jsonName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return f.Name, true
+}
+
+func isRequired(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// convertPath turns a Gin path (/projects/:id) into an OpenAPI path (/projects/{id}) with its parameters
+func convertPath(ginPath string) (string, []Parameter) {
+	segments := strings.Split(ginPath, "/")
+	var params []Parameter
+	for i, seg := range segments {
+		if len(seg) < 2 || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		name := seg[1:]
+		segments[i] = "{" + name + "}"
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "ID") {
+			schema = &Schema{Type: "integer", Format: "int32"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// routeTag groups operations by their first path segment after /api
+func routeTag(ginPath string) string {
+	trimmed := strings.TrimPrefix(strings.TrimPrefix(ginPath, "/api"), "/")
+	if idx := strings.Index(trimmed, "/"); idx >= 0 {
+		trimmed = trimmed[:idx]
+	}
+	if trimmed == "" || trimmed[0] == ':' {
+		return "default"
+	}
+	return trimmed
+}
+
+// operationID derives an ID like "projectList" from a handler name such as
+// ".../handlers.(*ProjectHandler).List-fm"
+func operationID(handler string) string {
+	name := handler[strings.LastIndex(handler, "/")+1:]
+	name = strings.TrimSuffix(name, "-fm")
+	if idx := strings.Index(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = strings.NewReplacer("(", "", ")", "", "*", "", "Handler", "").Replace(name)
+	parts := strings.Split(name, ".")
+	for i := range parts {
+		parts[i] = exportName(parts[i])
+	}
+	id := strings.Join(parts, "")
+	if id == "" {
+		return "operation"
+	}
+	r := []rune(id)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func exportName(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,125 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testItem struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name" binding:"required"`
+	Secret    string    `json:"-"`
+	Score     *float64  `json:"score"`
+	Tags      []string  `json:"tags"`
+	Parent    *testItem `json:"parent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testQuery struct {
+	Page     int  `form:"page"`
+	TenantID uint `form:"-"`
+}
+
+func TestConvertPath(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		params []string
+	}{
+		{"/api/projects", "/api/projects", nil},
+		{"/api/projects/:id/members/:memberID", "/api/projects/{id}/members/{memberID}", []string{"id", "memberID"}},
+		{"/api/tenants/:slug/branding", "/api/tenants/{slug}/branding", []string{"slug"}},
+	}
+
+	for _, tt := range tests {
+		got, params := convertPath(tt.in)
+		if got != tt.want {
+			t.Errorf("convertPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if len(params) != len(tt.params) {
+			t.Fatalf("convertPath(%q) returned %d params, want %d", tt.in, len(params), len(tt.params))
+		}
+		for i, p := range params {
+			if p.Name != tt.params[i] || p.In != "path" || !p.Required {
+				t.Errorf("unexpected param %+v", p)
+			}
+		}
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	tests := map[string]string{
+		"github.com/huangang/codesentry/backend/internal/handlers.(*ProjectHandler).List-fm":             "projectList",
+		"github.com/huangang/codesentry/backend/internal/handlers.(*WebhookHandler).HandleSyncReview-fm": "webhookHandleSyncReview",
+		"github.com/huangang/codesentry/backend/internal/handlers.Metrics":                               "metrics",
+	}
+	for in, want := range tests {
+		if got := operationID(in); got != want {
+			t.Errorf("operationID(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	ref := b.SchemaFor(reflect.TypeOf([]testItem{}))
+	if ref.Type != "array" || ref.Items.Ref != "#/components/schemas/TestItem" {
+		t.Fatalf("unexpected schema: %+v", ref)
+	}
+
+	item := b.doc.Components.Schemas["TestItem"]
+	if item == nil {
+		t.Fatal("TestItem component not registered")
+	}
+	if _, ok := item.Properties["Secret"]; ok {
+		t.Error("json:\"-\" field should be skipped")
+	}
+	if !item.Properties["score"].Nullable || item.Properties["score"].Type != "number" {
+		t.Errorf("unexpected score schema: %+v", item.Properties["score"])
+	}
+	if item.Properties["created_at"].Format != "date-time" {
+		t.Errorf("unexpected created_at schema: %+v", item.Properties["created_at"])
+	}
+	if item.Properties["parent"].Ref != "#/components/schemas/TestItem" {
+		t.Errorf("recursive field should reference the component: %+v", item.Properties["parent"])
+	}
+	if len(item.Required) != 1 || item.Required[0] != "name" {
+		t.Errorf("unexpected required fields: %v", item.Required)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	noop := func(c *gin.Context) {}
+	routes := gin.RoutesInfo{
+		{Method: "GET", Path: "/api/items", Handler: "handlers.(*ItemHandler).List-fm", HandlerFunc: noop},
+		{Method: "POST", Path: "/api/login", Handler: "handlers.(*AuthHandler).Login-fm", HandlerFunc: noop},
+		{Method: "GET", Path: "/assets/*filepath", Handler: "main.static", HandlerFunc: noop},
+	}
+	specs := map[string]RouteSpec{
+		"GET /api/items":  {Summary: "List items", Query: testQuery{}, Response: []testItem{}},
+		"POST /api/login": {Summary: "Log in", Public: true, Request: testItem{}},
+	}
+
+	doc := Build(Info{Title: "test", Version: "1"}, routes, specs)
+
+	if _, ok := doc.Paths["/assets/{filepath}"]; ok {
+		t.Error("undocumented root-level routes should be skipped")
+	}
+	list := doc.Paths["/api/items"]["get"]
+	if list == nil || list.OperationID != "itemList" || list.Tags[0] != "items" {
+		t.Fatalf("unexpected operation: %+v", list)
+	}
+	if len(list.Parameters) != 1 || list.Parameters[0].Name != "page" {
+		t.Errorf("unexpected parameters: %+v", list.Parameters)
+	}
+	if len(list.Security) != 1 {
+		t.Error("protected route should require bearer auth")
+	}
+	login := doc.Paths["/api/login"]["post"]
+	if login.Security != nil || login.RequestBody == nil {
+		t.Errorf("unexpected login operation: %+v", login)
+	}
+}
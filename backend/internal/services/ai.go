@@ -3,12 +3,12 @@ package services
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/huangang/codesentry/backend/pkg/logger"
@@ -26,15 +26,16 @@ import (
 // Pre-compiled regex patterns for score extraction and file context processing
 var (
 	scorePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?im)^\s*SCORE:\s*(\d+(?:\.\d+)?)\s*$`),
 		regexp.MustCompile(`总分[:：]\s*(\d+)分?`),
 		regexp.MustCompile(`[Tt]otal\s*[Ss]core[:：]?\s*(\d+)`),
 		regexp.MustCompile(`[Ss]core[:：]?\s*(\d+)\s*/\s*100`),
 		regexp.MustCompile(`(\d+)\s*/\s*100\s*分?`),
 		regexp.MustCompile(`评分[:：]\s*(\d+)`),
 	}
-	ifBlockRegex    = regexp.MustCompile(`(?s)\{\{#if_file_context\}\}(.*?)\{\{/if_file_context\}\}`)
-	thinkBlockRegex = regexp.MustCompile(`(?s)<think>.*?</think>`)
-	markdownFmtRegex = regexp.MustCompile(`\*{1,2}|_{1,2}|` + "`")
+	profileBlockRegex = regexp.MustCompile(`(?s)\{\{#if_project_profile\}\}(.*?)\{\{/if_project_profile\}\}`)
+	thinkBlockRegex   = regexp.MustCompile(`(?s)<think>.*?</think>`)
+	markdownFmtRegex  = regexp.MustCompile(`\*{1,2}|_{1,2}|` + "`")
 )
 
 type AIService struct {
@@ -59,6 +60,14 @@ type ReviewRequest struct {
 	Commits      string
 	FileContext  string
 	CustomPrompt string
+	Findings     string // Heuristic findings, e.g. missing tests, appended to the prompt
+	Author       string // Fills {{author}}
+	EventType    string // push, merge_request; selects bound review templates
+	Branch       string
+	TargetBranch string // Merge request events: selects the review template of the target branch policy
+	ReviewLogID  uint   // Attributes AI usage, including prompt cache hits, to the review
+	CommitHash   string // Assigns the review a variant of the project's running experiment
+	Simulation   bool   // Dry run: no experiment variant, no usage log and no effect on the LLM outage state
 }
 
 type ReviewResult struct {
@@ -67,6 +76,41 @@ type ReviewResult struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+	CachedTokens     int    // Prompt tokens read from the provider prompt cache
+	CacheWriteTokens int    // Prompt tokens written to the provider prompt cache
+	LLMConfigID      uint   // LLM config that produced the review
+	PromptRef        string // Prompt the review was made with, see resolvePrompt
+	ScoreMissing     bool   // No score could be parsed from the response, Score is 0
+	ExperimentID     uint   // Experiment the review was assigned to, 0 for none
+	Variant          string // Variant of the experiment, see assignExperiment
+}
+
+// llmCallMeta attributes an LLM call and marks the cacheable prompt prefix
+type llmCallMeta struct {
+	ProjectID   uint
+	ReviewLogID uint
+	CachePrefix int  // Length of the static prompt prefix, 0 = nothing to cache
+	Structured  bool // Request the structured review schema when the LLM config enables JSON mode
+	Simulation  bool // Leave the call out of usage logs and metrics, see ReviewRequest.Simulation
+}
+
+// promptPlaceholders are replaced per review; the prompt text before the first one is static
+var promptPlaceholders = []string{"{{diffs}}", "{{commits}}", "{{#if_file_context}}", "{{file_context}}", "{{#if_project_profile}}", "{{project_profile}}",
+	"{{#if ", "{{#unless ", "{{#each ", "{{project_name}}", "{{branch}}", "{{author}}", "{{event_type}}", "{{mr_title}}", "{{changed_files}}", "{{language_breakdown}}"}
+
+// promptCachePrefixLen returns the length of the static preamble of a prompt template,
+// the text before the first per-review placeholder
+func promptCachePrefixLen(template string) int {
+	prefix := len(template)
+	for _, p := range promptPlaceholders {
+		if i := strings.Index(template, p); i >= 0 && i < prefix {
+			prefix = i
+		}
+	}
+	if strings.TrimSpace(template[:prefix]) == "" {
+		return 0
+	}
+	return prefix
 }
 
 func (s *AIService) Review(ctx context.Context, req *ReviewRequest) (*ReviewResult, error) {
@@ -75,47 +119,96 @@ func (s *AIService) Review(ctx context.Context, req *ReviewRequest) (*ReviewResu
 		return nil, fmt.Errorf("project not found: %w", err)
 	}
 
-	prompt := s.getPromptForProject(&project, req.CustomPrompt)
-
-	prompt = strings.ReplaceAll(prompt, "{{diffs}}", req.Diffs)
-	prompt = strings.ReplaceAll(prompt, "{{commits}}", req.Commits)
-
-	prompt = s.processFileContextBlock(prompt, req.FileContext)
-
-	// Inject language-specific review hints based on diff file extensions
-	if langHints := GenerateLanguageHints(req.Diffs); langHints != "" {
-		prompt += langHints
+	template, promptRef := s.resolvePrompt(&project, req)
+	llmConfigs := s.getOrderedLLMConfigs(&project)
+	var experiment *experimentAssignment
+	if !req.Simulation {
+		experiment = s.assignExperiment(&project, req)
 	}
-
-	logger.Infof("[AI] Prompt length: %d chars, Diffs length: %d chars, Commits length: %d chars, FileContext length: %d chars",
-		len(prompt), len(req.Diffs), len(req.Commits), len(req.FileContext))
-
-	if len(prompt) > 500 {
-		logger.Infof("[AI] Prompt preview (first 500 chars): %s...", prompt[:500])
-	} else {
-		logger.Infof("[AI] Prompt: %s", prompt)
+	if experiment != nil {
+		template, promptRef, llmConfigs = experiment.apply(template, promptRef, llmConfigs)
 	}
+	prompt, meta := s.buildReviewPrompt(&project, req, template)
+	meta.Simulation = req.Simulation
 
-	llmConfigs := s.getOrderedLLMConfigs(&project)
 	if len(llmConfigs) == 0 {
 		return nil, fmt.Errorf("no LLM configuration available")
 	}
 
 	var lastErr error
 	for i, llmConfig := range llmConfigs {
-		logger.Infof("[AI] Attempting LLM %d/%d: %s (model: %s)", i+1, len(llmConfigs), llmConfig.Name, llmConfig.Model)
+		logger.Ctx(ctx).Info().Msgf("[AI] Attempting LLM %d/%d: %s (model: %s)", i+1, len(llmConfigs), llmConfig.Name, llmConfig.Model)
 
-		result, err := s.callLLM(ctx, &llmConfig, prompt)
+		result, err := s.callLLMWithMeta(ctx, &llmConfig, prompt, meta)
 		if err == nil {
-			logger.Infof("[AI] Success with LLM: %s", llmConfig.Name)
+			logger.Ctx(ctx).Info().Msgf("[AI] Success with LLM: %s", llmConfig.Name)
+			result.LLMConfigID = llmConfig.ID
+			result.PromptRef = promptRef
+			if experiment != nil {
+				result.ExperimentID, result.Variant = experiment.ExperimentID, experiment.Variant
+			}
+			if !req.Simulation {
+				s.recordLLMChainResult(nil)
+			}
 			return result, nil
 		}
 
 		lastErr = err
-		logger.Infof("[AI] LLM %s failed: %v, trying next...", llmConfig.Name, err)
+		logger.Ctx(ctx).Info().Msgf("[AI] LLM %s failed: %v, trying next...", llmConfig.Name, err)
+	}
+
+	err := fmt.Errorf("%w, last error: %w", ErrAllLLMsFailed, lastErr)
+	if !req.Simulation {
+		s.recordLLMChainResult(err)
+	}
+	return nil, err
+}
+
+// buildReviewPrompt fills a prompt template with the request's diffs, commits, file context,
+// language hints and findings
+func (s *AIService) buildReviewPrompt(project *models.Project, req *ReviewRequest, template string) (string, llmCallMeta) {
+	prompt := template
+	meta := llmCallMeta{ProjectID: project.ID, ReviewLogID: req.ReviewLogID, CachePrefix: promptCachePrefixLen(prompt), Structured: true}
+
+	// The profile is filled first, so diffs mentioning the placeholder stay unchanged
+	prompt = processProjectProfile(prompt, project.TechProfile)
+
+	files := ParseDiffToFiles(req.Diffs)
+	prompt = renderPrompt(prompt, promptVariableValues(project, req, files), files)
+
+	// Inject language-specific review hints based on diff file extensions
+	if langHints := GenerateLanguageHints(req.Diffs); langHints != "" {
+		prompt += langHints
+	}
+
+	// IaC changes reviewed with another prompt get the IaC checklist
+	if project.IaCReviewMode != IaCReviewOff && template != iacReviewPrompt {
+		prompt += GenerateIaCHints(files)
+	}
+	if project.MigrationPolicy != MigrationPolicyOff {
+		prompt += GenerateMigrationHints(files)
+	}
+
+	if req.Findings != "" {
+		prompt += "\n\n--- Automated Findings ---\n" + req.Findings + "\nTake these findings into account in the review and score.\n"
+	}
+
+	logger.Infof("[AI] Prompt length: %d chars, Diffs length: %d chars, Commits length: %d chars, FileContext length: %d chars",
+		len(prompt), len(req.Diffs), len(req.Commits), len(req.FileContext))
+
+	if len(prompt) > 500 {
+		logger.Infof("[AI] Prompt preview (first 500 chars): %s...", prompt[:500])
+	} else {
+		logger.Infof("[AI] Prompt: %s", prompt)
 	}
+	return prompt, meta
+}
 
-	return nil, fmt.Errorf("all LLMs failed, last error: %w", lastErr)
+// BuildPrompt returns the prompt a review of the request sends, before the structured
+// review instruction added for LLMs in JSON mode
+func (s *AIService) BuildPrompt(project *models.Project, req *ReviewRequest) string {
+	prompt, _ := s.buildReviewPrompt(project, req, s.getPromptForProject(project, req))
+	return prompt
 }
 
 func (s *AIService) getOrderedLLMConfigs(project *models.Project) []models.LLMConfig {
@@ -129,7 +222,7 @@ func (s *AIService) getOrderedLLMConfigs(project *models.Project) []models.LLMCo
 	}
 
 	var defaultConfig models.LLMConfig
-	if err := s.db.Where("is_default = ? AND is_active = ?", true, true).First(&defaultConfig).Error; err == nil {
+	if err := ScopeTenant(s.db, project.TenantID).Where("is_default = ? AND is_active = ?", true, true).First(&defaultConfig).Error; err == nil {
 		if len(configs) == 0 || configs[0].ID != defaultConfig.ID {
 			configs = append(configs, defaultConfig)
 		}
@@ -140,7 +233,7 @@ func (s *AIService) getOrderedLLMConfigs(project *models.Project) []models.LLMCo
 	for _, c := range configs {
 		existingIDs[c.ID] = true
 	}
-	s.db.Where("is_active = ?", true).Order("id ASC").Find(&backupConfigs)
+	ScopeTenant(s.db, project.TenantID).Where("is_active = ?", true).Order("id ASC").Find(&backupConfigs)
 	for _, c := range backupConfigs {
 		if !existingIDs[c.ID] {
 			configs = append(configs, c)
@@ -148,11 +241,12 @@ func (s *AIService) getOrderedLLMConfigs(project *models.Project) []models.LLMCo
 	}
 
 	if len(configs) == 0 {
+		fallback := s.config.Current()
 		configs = append(configs, models.LLMConfig{
 			Name:    "fallback",
-			BaseURL: s.config.BaseURL,
-			APIKey:  s.config.APIKey,
-			Model:   s.config.Model,
+			BaseURL: fallback.BaseURL,
+			APIKey:  fallback.APIKey,
+			Model:   fallback.Model,
 		})
 	}
 
@@ -162,7 +256,22 @@ func (s *AIService) getOrderedLLMConfigs(project *models.Project) []models.LLMCo
 // callLLM dispatches to the appropriate provider-specific function based on Provider field
 // and records usage metrics (tokens, latency, success/failure).
 func (s *AIService) callLLM(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
-	logger.Infof("[AI] Using provider: %s, model: %s, baseURL: %s", llmConfig.Provider, llmConfig.Model, llmConfig.BaseURL)
+	return s.callLLMWithMeta(ctx, llmConfig, prompt, llmCallMeta{})
+}
+
+// callLLMWithMeta is callLLM for a call attributed to a project and review, with an
+// optional cacheable prompt prefix
+func (s *AIService) callLLMWithMeta(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
+	logger.Ctx(ctx).Info().Msgf("[AI] Using provider: %s, model: %s, baseURL: %s", llmConfig.Provider, llmConfig.Model, llmConfig.BaseURL)
+
+	// Reviews ask LLMs in JSON mode for the structured schema and others for a final score line
+	review := meta.Structured
+	meta.Structured = meta.Structured && llmConfig.JSONMode
+	if meta.Structured {
+		prompt += structuredReviewInstruction
+	} else if review {
+		prompt += scoreLineInstruction
+	}
 
 	start := time.Now()
 	var result *ReviewResult
@@ -170,21 +279,36 @@ func (s *AIService) callLLM(ctx context.Context, llmConfig *models.LLMConfig, pr
 
 	switch llmConfig.Provider {
 	case "anthropic":
-		result, err = s.callAnthropic(ctx, llmConfig, prompt)
+		result, err = s.callAnthropic(ctx, llmConfig, prompt, meta)
 	case "ollama":
-		result, err = s.callOllama(ctx, llmConfig, prompt)
+		result, err = s.callOllama(ctx, llmConfig, prompt, meta)
 	case "gemini":
-		result, err = s.callGemini(ctx, llmConfig, prompt)
+		result, err = s.callGemini(ctx, llmConfig, prompt, meta)
 	case "azure":
-		result, err = s.callAzure(ctx, llmConfig, prompt)
-	default:
-		result, err = s.callOpenAI(ctx, llmConfig, prompt)
+		result, err = s.callAzure(ctx, llmConfig, prompt, meta)
+	default: // openai and the OpenAI-compatible vllm and tgi servers
+		result, err = s.callOpenAI(ctx, llmConfig, prompt, meta)
+	}
+	structured := false
+	if err == nil && meta.Structured {
+		if structured = applyStructuredReview(result); !structured {
+			logger.Ctx(ctx).Warn().Msgf("[AI] %s returned no valid structured review, falling back to text parsing", llmConfig.Name)
+		}
+	}
+	if err == nil && review && !structured {
+		if _, pattern := ExplainScore(result.Content); pattern == "" {
+			result.ScoreMissing = true
+			if !meta.Simulation {
+				scoreParseFailures.Add(1)
+			}
+			logger.Ctx(ctx).Warn().Uint("project_id", meta.ProjectID).Uint("review_id", meta.ReviewLogID).Str("llm", llmConfig.Name).Msg("[AI] No score found in the review")
+		}
 	}
 
 	latencyMs := time.Since(start).Milliseconds()
 
 	// Record usage asynchronously
-	if s.usageService != nil {
+	if s.usageService != nil && !meta.Simulation {
 		usageLog := &models.AIUsageLog{
 			LLMConfigID: llmConfig.ID,
 			Provider:    llmConfig.Provider,
@@ -192,6 +316,12 @@ func (s *AIService) callLLM(ctx context.Context, llmConfig *models.LLMConfig, pr
 			LatencyMs:   latencyMs,
 			Success:     err == nil,
 		}
+		if meta.ProjectID > 0 {
+			usageLog.ProjectID = &meta.ProjectID
+		}
+		if meta.ReviewLogID > 0 {
+			usageLog.ReviewLogID = &meta.ReviewLogID
+		}
 		if err != nil {
 			errMsg := err.Error()
 			if len(errMsg) > 500 {
@@ -203,6 +333,8 @@ func (s *AIService) callLLM(ctx context.Context, llmConfig *models.LLMConfig, pr
 			usageLog.PromptTokens = result.PromptTokens
 			usageLog.CompletionTokens = result.CompletionTokens
 			usageLog.TotalTokens = result.TotalTokens
+			usageLog.CachedTokens = result.CachedTokens
+			usageLog.CacheWriteTokens = result.CacheWriteTokens
 		}
 		s.usageService.Record(usageLog)
 	}
@@ -211,8 +343,9 @@ func (s *AIService) callLLM(ctx context.Context, llmConfig *models.LLMConfig, pr
 }
 
 // callOpenAI handles OpenAI and OpenAI-compatible APIs (including custom endpoints)
-func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
+func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
 	clientConfig := openai.DefaultConfig(llmConfig.APIKey)
+	clientConfig.HTTPClient = LLMHTTPClient()
 	if llmConfig.BaseURL != "" {
 		clientConfig.BaseURL = llmConfig.BaseURL
 	}
@@ -223,6 +356,12 @@ func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig,
 		temperature = float32(llmConfig.Temperature)
 	}
 
+	// Self-hosted servers reject requests whose prompt and max_tokens exceed the context window
+	maxTokens, err := s.completionTokenLimit(ctx, llmConfig, prompt)
+	if err != nil {
+		return nil, err
+	}
+
 	resp, err := client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
 		Model: llmConfig.Model,
 		Messages: []openai.ChatCompletionMessage{
@@ -231,11 +370,13 @@ func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig,
 				Content: prompt,
 			},
 		},
-		Temperature: temperature,
+		MaxTokens:      maxTokens,
+		Temperature:    temperature,
+		ResponseFormat: openAIResponseFormat(meta.Structured),
 	})
 
 	if err != nil {
-		logger.Infof("[AI] OpenAI API error: %v", err)
+		logger.Ctx(ctx).Info().Msgf("[AI] OpenAI API error: %v", err)
 		return nil, fmt.Errorf("OpenAI API error: %w", err)
 	}
 
@@ -244,7 +385,7 @@ func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig,
 	}
 
 	content := resp.Choices[0].Message.Content
-	logger.Infof("[AI] OpenAI response length: %d chars, tokens: %d", len(content), resp.Usage.TotalTokens)
+	logger.Ctx(ctx).Info().Msgf("[AI] OpenAI response length: %d chars, tokens: %d, cached: %d", len(content), resp.Usage.TotalTokens, openAICachedTokens(resp.Usage))
 
 	return &ReviewResult{
 		Content:          content,
@@ -252,13 +393,41 @@ func (s *AIService) callOpenAI(ctx context.Context, llmConfig *models.LLMConfig,
 		PromptTokens:     resp.Usage.PromptTokens,
 		CompletionTokens: resp.Usage.CompletionTokens,
 		TotalTokens:      resp.Usage.TotalTokens,
+		CachedTokens:     openAICachedTokens(resp.Usage),
 	}, nil
 }
 
-// callAnthropic handles Anthropic Claude API using the native SDK
-func (s *AIService) callAnthropic(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
+// openAIResponseFormat requests the structured review JSON schema in strict mode
+func openAIResponseFormat(structured bool) *openai.ChatCompletionResponseFormat {
+	if !structured {
+		return nil
+	}
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   structuredReviewToolName,
+			Schema: structuredReviewSchemaJSON{},
+			Strict: true,
+		},
+	}
+}
+
+// openAICachedTokens returns the prompt tokens served from OpenAI's automatic prompt cache,
+// which applies to prompts sharing a prefix of 1024 tokens or more
+func openAICachedTokens(usage openai.Usage) int {
+	if usage.PromptTokensDetails == nil {
+		return 0
+	}
+	return usage.PromptTokensDetails.CachedTokens
+}
+
+// callAnthropic handles Anthropic Claude API using the native SDK. With prompt caching
+// enabled, the static prompt prefix is sent as a cacheable block. Structured reviews are
+// forced through a tool call whose input is the review.
+func (s *AIService) callAnthropic(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
 	opts := []option.RequestOption{
 		option.WithAPIKey(llmConfig.APIKey),
+		option.WithHTTPClient(LLMHTTPClient()),
 	}
 	if llmConfig.BaseURL != "" {
 		opts = append(opts, option.WithBaseURL(llmConfig.BaseURL))
@@ -275,15 +444,20 @@ func (s *AIService) callAnthropic(ctx context.Context, llmConfig *models.LLMConf
 		model = "claude-sonnet-4-20250514"
 	}
 
-	resp, err := client.Messages.New(ctx, anthropic.MessageNewParams{
+	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(model),
 		MaxTokens: maxTokens,
 		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+			anthropic.NewUserMessage(anthropicPromptBlocks(prompt, meta.CachePrefix, llmConfig.PromptCache)...),
 		},
-	})
+	}
+	if meta.Structured {
+		params.Tools = []anthropic.ToolUnionParam{anthropicReviewTool()}
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(structuredReviewToolName)
+	}
+	resp, err := client.Messages.New(ctx, params)
 	if err != nil {
-		logger.Infof("[AI] Anthropic API error: %v", err)
+		logger.Ctx(ctx).Info().Msgf("[AI] Anthropic API error: %v", err)
 		return nil, fmt.Errorf("Anthropic API error: %w", err)
 	}
 
@@ -293,22 +467,50 @@ func (s *AIService) callAnthropic(ctx context.Context, llmConfig *models.LLMConf
 		if block.Type == "text" {
 			content += block.Text
 		}
+		if block.Type == "tool_use" && block.Name == structuredReviewToolName {
+			content = string(block.Input)
+			break
+		}
 	}
 
-	logger.Infof("[AI] Anthropic response length: %d chars, input_tokens: %d, output_tokens: %d",
-		len(content), resp.Usage.InputTokens, resp.Usage.OutputTokens)
+	logger.Ctx(ctx).Info().Msgf("[AI] Anthropic response length: %d chars, input_tokens: %d, output_tokens: %d, cache_read: %d, cache_write: %d",
+		len(content), resp.Usage.InputTokens, resp.Usage.OutputTokens, resp.Usage.CacheReadInputTokens, resp.Usage.CacheCreationInputTokens)
 
+	// input_tokens excludes cached tokens; count them in the prompt like OpenAI does
+	promptTokens := resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheCreationInputTokens
 	return &ReviewResult{
 		Content:          content,
 		Score:            extractScore(content),
-		PromptTokens:     int(resp.Usage.InputTokens),
+		PromptTokens:     int(promptTokens),
 		CompletionTokens: int(resp.Usage.OutputTokens),
-		TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
+		TotalTokens:      int(promptTokens + resp.Usage.OutputTokens),
+		CachedTokens:     int(resp.Usage.CacheReadInputTokens),
+		CacheWriteTokens: int(resp.Usage.CacheCreationInputTokens),
 	}, nil
 }
 
+// anthropicReviewTool is the tool Anthropic models submit structured reviews with
+func anthropicReviewTool() anthropic.ToolUnionParam {
+	tool := anthropic.ToolUnionParamOfTool(anthropic.ToolInputSchemaParam{
+		Properties: structuredReviewSchema["properties"],
+		Required:   structuredReviewSchema["required"].([]string),
+	}, structuredReviewToolName)
+	tool.OfTool.Description = anthropic.String("Submit the code review")
+	return tool
+}
+
+// anthropicPromptBlocks splits the prompt into a cacheable static prefix and the per-review rest
+func anthropicPromptBlocks(prompt string, cachePrefix int, cache bool) []anthropic.ContentBlockParamUnion {
+	if !cache || cachePrefix <= 0 || cachePrefix >= len(prompt) {
+		return []anthropic.ContentBlockParamUnion{anthropic.NewTextBlock(prompt)}
+	}
+	static := anthropic.NewTextBlock(prompt[:cachePrefix])
+	static.OfText.CacheControl = anthropic.NewCacheControlEphemeralParam()
+	return []anthropic.ContentBlockParamUnion{static, anthropic.NewTextBlock(prompt[cachePrefix:])}
+}
+
 // callOllama handles Ollama API using the native SDK
-func (s *AIService) callOllama(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
+func (s *AIService) callOllama(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
 	baseURL := llmConfig.BaseURL
 	if baseURL == "" {
 		baseURL = "http://localhost:11434"
@@ -319,15 +521,14 @@ func (s *AIService) callOllama(ctx context.Context, llmConfig *models.LLMConfig,
 	if err != nil {
 		return nil, fmt.Errorf("invalid Ollama base URL: %w", err)
 	}
-	client := api.NewClient(u, http.DefaultClient)
+	client := api.NewClient(u, LLMHTTPClient())
 
 	model := llmConfig.Model
 	if model == "" {
 		model = "llama3"
 	}
 
-	var content strings.Builder
-	err = client.Chat(ctx, &api.ChatRequest{
+	chatReq := &api.ChatRequest{
 		Model: model,
 		Messages: []api.Message{
 			{Role: "user", Content: prompt},
@@ -335,18 +536,24 @@ func (s *AIService) callOllama(ctx context.Context, llmConfig *models.LLMConfig,
 		Options: map[string]interface{}{
 			"temperature": llmConfig.Temperature,
 		},
-	}, func(resp api.ChatResponse) error {
+	}
+	if meta.Structured {
+		chatReq.Format, _ = structuredReviewSchemaJSON{}.MarshalJSON()
+	}
+
+	var content strings.Builder
+	err = client.Chat(ctx, chatReq, func(resp api.ChatResponse) error {
 		content.WriteString(resp.Message.Content)
 		return nil
 	})
 
 	if err != nil {
-		logger.Infof("[AI] Ollama API error: %v", err)
+		logger.Ctx(ctx).Info().Msgf("[AI] Ollama API error: %v", err)
 		return nil, fmt.Errorf("Ollama API error: %w", err)
 	}
 
 	result := content.String()
-	logger.Infof("[AI] Ollama response length: %d chars", len(result))
+	logger.Ctx(ctx).Info().Msgf("[AI] Ollama response length: %d chars", len(result))
 
 	return &ReviewResult{
 		Content: result,
@@ -355,9 +562,10 @@ func (s *AIService) callOllama(ctx context.Context, llmConfig *models.LLMConfig,
 }
 
 // callGemini handles Google Gemini API using the native SDK
-func (s *AIService) callGemini(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
+func (s *AIService) callGemini(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
 	cfg := &genai.ClientConfig{
-		APIKey: llmConfig.APIKey,
+		APIKey:     llmConfig.APIKey,
+		HTTPClient: LLMHTTPClient(),
 	}
 	if llmConfig.BaseURL != "" {
 		cfg.HTTPOptions = genai.HTTPOptions{
@@ -374,14 +582,21 @@ func (s *AIService) callGemini(ctx context.Context, llmConfig *models.LLMConfig,
 		model = "gemini-3.0-flash"
 	}
 
-	resp, err := client.Models.GenerateContent(ctx, model, genai.Text(prompt), nil)
+	var genConfig *genai.GenerateContentConfig
+	if meta.Structured {
+		genConfig = &genai.GenerateContentConfig{
+			ResponseMIMEType:   "application/json",
+			ResponseJsonSchema: structuredReviewSchema,
+		}
+	}
+	resp, err := client.Models.GenerateContent(ctx, model, genai.Text(prompt), genConfig)
 	if err != nil {
-		logger.Infof("[AI] Gemini API error: %v", err)
+		logger.Ctx(ctx).Info().Msgf("[AI] Gemini API error: %v", err)
 		return nil, fmt.Errorf("Gemini API error: %w", err)
 	}
 
 	content := resp.Text()
-	logger.Infof("[AI] Gemini response length: %d chars", len(content))
+	logger.Ctx(ctx).Info().Msgf("[AI] Gemini response length: %d chars", len(content))
 
 	return &ReviewResult{
 		Content: content,
@@ -390,10 +605,11 @@ func (s *AIService) callGemini(ctx context.Context, llmConfig *models.LLMConfig,
 }
 
 // callAzure handles Azure OpenAI API using special configuration
-func (s *AIService) callAzure(ctx context.Context, llmConfig *models.LLMConfig, prompt string) (*ReviewResult, error) {
+func (s *AIService) callAzure(ctx context.Context, llmConfig *models.LLMConfig, prompt string, meta llmCallMeta) (*ReviewResult, error) {
 	// Azure requires BaseURL format: https://{resource-name}.openai.azure.com
 	// Model field is used as deployment name
 	config := openai.DefaultAzureConfig(llmConfig.APIKey, llmConfig.BaseURL)
+	config.HTTPClient = LLMHTTPClient()
 	client := openai.NewClientWithConfig(config)
 
 	temperature := float32(0.3)
@@ -406,11 +622,12 @@ func (s *AIService) callAzure(ctx context.Context, llmConfig *models.LLMConfig,
 		Messages: []openai.ChatCompletionMessage{
 			{Role: openai.ChatMessageRoleUser, Content: prompt},
 		},
-		Temperature: temperature,
+		Temperature:    temperature,
+		ResponseFormat: openAIResponseFormat(meta.Structured),
 	})
 
 	if err != nil {
-		logger.Infof("[AI] Azure OpenAI API error: %v", err)
+		logger.Ctx(ctx).Info().Msgf("[AI] Azure OpenAI API error: %v", err)
 		return nil, fmt.Errorf("Azure OpenAI API error: %w", err)
 	}
 
@@ -419,7 +636,7 @@ func (s *AIService) callAzure(ctx context.Context, llmConfig *models.LLMConfig,
 	}
 
 	content := resp.Choices[0].Message.Content
-	logger.Infof("[AI] Azure OpenAI response length: %d chars, tokens: %d", len(content), resp.Usage.TotalTokens)
+	logger.Ctx(ctx).Info().Msgf("[AI] Azure OpenAI response length: %d chars, tokens: %d", len(content), resp.Usage.TotalTokens)
 
 	return &ReviewResult{
 		Content:          content,
@@ -427,24 +644,49 @@ func (s *AIService) callAzure(ctx context.Context, llmConfig *models.LLMConfig,
 		PromptTokens:     resp.Usage.PromptTokens,
 		CompletionTokens: resp.Usage.CompletionTokens,
 		TotalTokens:      resp.Usage.TotalTokens,
+		CachedTokens:     openAICachedTokens(resp.Usage),
 	}, nil
 }
 
-func (s *AIService) getPromptForProject(project *models.Project, customPrompt string) string {
-	var prompt string
+// getPromptForProject resolves the review prompt, see resolvePrompt
+func (s *AIService) getPromptForProject(project *models.Project, req *ReviewRequest) string {
+	prompt, _ := s.resolvePrompt(project, req)
+	return prompt
+}
+
+// resolvePrompt resolves the review prompt and the reference recorded on the review, see
+// PromptRef. Precedence: request custom prompt, review template of the target branch
+// policy, review template bound to the event type and branch, project custom prompt,
+// linked prompt template, IaC prompt for changes made only of infrastructure as code,
+// system default.
+func (s *AIService) resolvePrompt(project *models.Project, req *ReviewRequest) (string, string) {
+	var prompt, ref string
 	var isSystemDefault bool
 
-	if customPrompt != "" {
+	if req.CustomPrompt != "" {
 		logger.Infof("[AI] Using custom prompt from request")
-		prompt = customPrompt
+		prompt, ref = req.CustomPrompt, PromptRefCustom
+	} else if template := s.getPolicyTemplate(project, req.TargetBranch); template != nil {
+		logger.Infof("[AI] Using target branch policy template: %s (ID: %d) for merge into %s", template.Name, template.ID, req.TargetBranch)
+		prompt, ref = template.Content, PromptRef(PromptRefReviewTemplate, template.ID)
+	} else if template := s.getBoundTemplate(project, req.EventType, req.Branch); template != nil {
+		logger.Infof("[AI] Using bound review template: %s (ID: %d) for %s on %s", template.Name, template.ID, req.EventType, req.Branch)
+		prompt, ref = template.Content, PromptRef(PromptRefReviewTemplate, template.ID)
 	} else if project.AIPrompt != "" {
 		logger.Infof("[AI] Using project custom prompt")
-		prompt = project.AIPrompt
+		prompt, ref = project.AIPrompt, PromptRefProject
 	} else if project.AIPromptID != nil {
 		var promptTemplate models.PromptTemplate
 		if err := s.db.First(&promptTemplate, *project.AIPromptID).Error; err == nil {
 			logger.Infof("[AI] Using linked prompt template: %s (ID: %d)", promptTemplate.Name, promptTemplate.ID)
-			prompt = promptTemplate.Content
+			prompt, ref = promptTemplate.Content, PromptRef(PromptRefPromptTemplate, promptTemplate.ID)
+		}
+	}
+
+	if prompt == "" && project.IaCReviewMode != IaCReviewOff {
+		if kinds, all := detectIaC(ParseDiffToFiles(req.Diffs)); all {
+			logger.Infof("[AI] Using IaC review prompt for %s changes", strings.Join(kinds, ", "))
+			return iacReviewPrompt, PromptRefIaC
 		}
 	}
 
@@ -452,10 +694,10 @@ func (s *AIService) getPromptForProject(project *models.Project, customPrompt st
 		var defaultPrompt models.PromptTemplate
 		if err := s.db.Where("is_default = ?", true).First(&defaultPrompt).Error; err == nil {
 			logger.Infof("[AI] Using system default prompt: %s (ID: %d)", defaultPrompt.Name, defaultPrompt.ID)
-			prompt = defaultPrompt.Content
+			prompt, ref = defaultPrompt.Content, PromptRef(PromptRefPromptTemplate, defaultPrompt.ID)
 		} else {
 			logger.Infof("[AI] Using hardcoded default prompt")
-			prompt = NewProjectService(s.db).GetDefaultPrompt()
+			prompt, ref = NewProjectService(s.db).GetDefaultPrompt(), PromptRefDefault
 		}
 		isSystemDefault = true
 	}
@@ -465,7 +707,49 @@ func (s *AIService) getPromptForProject(project *models.Project, customPrompt st
 		prompt = appendScoringInstruction(prompt)
 	}
 
-	return prompt
+	return prompt, ref
+}
+
+// getBoundTemplate returns the active review template bound to the project for the event and branch
+func (s *AIService) getBoundTemplate(project *models.Project, eventType, branch string) *models.ReviewTemplate {
+	var bindings []models.ProjectTemplateBinding
+	if err := s.db.Where("project_id = ?", project.ID).Find(&bindings).Error; err != nil || len(bindings) == 0 {
+		return nil
+	}
+
+	binding := resolveTemplateBinding(bindings, eventType, branch)
+	if binding == nil {
+		return nil
+	}
+
+	var template models.ReviewTemplate
+	if err := s.db.Where("id = ? AND is_active = ?", binding.TemplateID, true).First(&template).Error; err != nil {
+		logger.Warnf("[AI] Bound review template %d unavailable: %v", binding.TemplateID, err)
+		return nil
+	}
+	return &template
+}
+
+// getPolicyTemplate returns the active review template of the target branch policy of a merge request
+func (s *AIService) getPolicyTemplate(project *models.Project, targetBranch string) *models.ReviewTemplate {
+	if targetBranch == "" {
+		return nil
+	}
+	var policies []models.TargetBranchPolicy
+	if err := s.db.Where("project_id = ?", project.ID).Find(&policies).Error; err != nil {
+		return nil
+	}
+	policy := ResolveTargetBranchPolicy(policies, targetBranch)
+	if policy == nil || policy.ReviewTemplateID == nil {
+		return nil
+	}
+
+	var template models.ReviewTemplate
+	if err := s.db.Where("id = ? AND is_active = ?", *policy.ReviewTemplateID, true).First(&template).Error; err != nil {
+		logger.Warnf("[AI] Target branch policy template %d unavailable: %v", *policy.ReviewTemplateID, err)
+		return nil
+	}
+	return &template
 }
 
 func containsScoringInstruction(prompt string) bool {
@@ -500,22 +784,74 @@ Score breakdown (adjust based on your review focus):
 	return prompt + scoringInstruction
 }
 
-func (s *AIService) processFileContextBlock(prompt string, fileContext string) string {
-	if strings.TrimSpace(fileContext) != "" {
-		prompt = ifBlockRegex.ReplaceAllString(prompt, "$1")
-		prompt = strings.ReplaceAll(prompt, "{{file_context}}", fileContext)
+// processProjectProfile fills {{project_profile}} and its {{#if_project_profile}} block.
+// Prompts without the placeholder get the profile appended, so projects using the
+// default prompt still get framework-specific reviews.
+func processProjectProfile(prompt, profile string) string {
+	profile = strings.TrimSpace(profile)
+	hasPlaceholder := strings.Contains(prompt, "{{project_profile}}") || strings.Contains(prompt, "{{#if_project_profile}}")
+	if profile != "" {
+		prompt = profileBlockRegex.ReplaceAllString(prompt, "$1")
 	} else {
-		prompt = ifBlockRegex.ReplaceAllString(prompt, "")
-		prompt = strings.ReplaceAll(prompt, "{{file_context}}", "")
+		prompt = profileBlockRegex.ReplaceAllString(prompt, "")
 	}
+	prompt = strings.ReplaceAll(prompt, "{{project_profile}}", profile)
 
+	if profile != "" && !hasPlaceholder {
+		prompt += "\n\n--- Project Profile ---\nThis project uses " + profile +
+			". Apply the conventions and best practices of these languages and frameworks in the review.\n"
+	}
 	return prompt
 }
 
+// ReviewStatusNeedsAttention marks reviews whose LLM response has no parseable score; they
+// are neither passed nor failed by their score until retried or checked by a person
+const ReviewStatusNeedsAttention = "needs_attention"
+
+// scoreLineInstruction asks LLMs outside JSON mode for a machine-readable score
+const scoreLineInstruction = "\n\nEnd your reply with a last line of the form `SCORE: <0-100>`, e.g. `SCORE: 85`, with nothing after it.\n"
+
+// scoreParseFailures counts reviews whose LLM response had no parseable score
+var scoreParseFailures atomic.Int64
+
+// ScoreParseFailureCount returns the number of reviews without a parseable score since start
+func ScoreParseFailureCount() int64 {
+	return scoreParseFailures.Load()
+}
+
+// ApplyReviewResult records an LLM review on its review log: completed with its score, or
+// needs_attention without one when the response had no parseable score
+func ApplyReviewResult(reviewLog *models.ReviewLog, result *ReviewResult) {
+	reviewLog.ReviewResult = result.Content
+	reviewLog.PromptRef = result.PromptRef
+	if result.LLMConfigID > 0 {
+		llmConfigID := result.LLMConfigID
+		reviewLog.LLMConfigID = &llmConfigID
+	}
+	if result.ExperimentID > 0 {
+		experimentID := result.ExperimentID
+		reviewLog.ExperimentID, reviewLog.ExperimentVariant = &experimentID, result.Variant
+	}
+	if result.ScoreMissing {
+		reviewLog.ReviewStatus = ReviewStatusNeedsAttention
+		reviewLog.Score = nil
+		return
+	}
+	reviewLog.ReviewStatus = "completed"
+	reviewLog.Score = &result.Score
+}
+
 // extractScore extracts the score from review content.
 // It strips <think> blocks first to avoid matching intermediate scores from AI reasoning,
 // then uses the LAST match of each pattern since the total score is typically at the end.
 func extractScore(content string) float64 {
+	score, _ := ExplainScore(content)
+	return score
+}
+
+// ExplainScore is extractScore that also returns the score pattern that matched, empty
+// when none did and the score is 0
+func ExplainScore(content string) (float64, string) {
 	// Strip <think>...</think> blocks to avoid matching scores in AI reasoning
 	cleaned := thinkBlockRegex.ReplaceAllString(content, "")
 
@@ -531,13 +867,13 @@ func extractScore(content string) float64 {
 			if len(lastMatch) >= 2 {
 				if score, err := strconv.ParseFloat(lastMatch[1], 64); err == nil {
 					if score >= 0 && score <= 100 {
-						return score
+						return score, re.String()
 					}
 				}
 			}
 		}
 	}
-	return 0
+	return 0, ""
 }
 
 func (s *AIService) CallWithConfig(ctx context.Context, llmConfigID uint, prompt string) (string, string, error) {
@@ -545,7 +881,7 @@ func (s *AIService) CallWithConfig(ctx context.Context, llmConfigID uint, prompt
 
 	if llmConfigID > 0 {
 		if err := s.db.Where("id = ? AND is_active = ?", llmConfigID, true).First(&llmConfig).Error; err != nil {
-			logger.Infof("[AI] Specified LLM config %d not found or inactive, falling back to default", llmConfigID)
+			logger.Ctx(ctx).Info().Msgf("[AI] Specified LLM config %d not found or inactive, falling back to default", llmConfigID)
 		}
 	}
 
@@ -559,7 +895,7 @@ func (s *AIService) CallWithConfig(ctx context.Context, llmConfigID uint, prompt
 		}
 	}
 
-	logger.Infof("[AI] CallWithConfig using LLM: %s (ID: %d)", llmConfig.Name, llmConfig.ID)
+	logger.Ctx(ctx).Info().Msgf("[AI] CallWithConfig using LLM: %s (ID: %d)", llmConfig.Name, llmConfig.ID)
 
 	result, err := s.callLLM(ctx, &llmConfig, prompt)
 	if err != nil {
@@ -569,8 +905,11 @@ func (s *AIService) CallWithConfig(ctx context.Context, llmConfigID uint, prompt
 	return result.Content, llmConfig.Name, nil
 }
 
-func (s *AIService) getChunkedReviewEnabled() bool {
-	return s.configService.GetWithDefault("chunked_review_enabled", "true") == "true"
+// getChunkedReviewEnabled applies the chunked_review feature flag of the project, falling
+// back to the global setting
+func (s *AIService) getChunkedReviewEnabled(projectID uint) bool {
+	enabled := s.configService.GetWithDefault("chunked_review_enabled", "true") == "true"
+	return IsFeatureEnabled(FeatureChunkedReview, projectID, enabled)
 }
 
 func (s *AIService) getChunkThreshold() int {
@@ -589,32 +928,56 @@ func (s *AIService) getMaxTokensPerBatch() int {
 	return val
 }
 
+// ReviewChunked reviews a change, splitting large diffs into batches, and starts a shadow
+// review of the change when shadow mode samples it
 func (s *AIService) ReviewChunked(ctx context.Context, req *ReviewRequest) (*ReviewResult, error) {
-	if !s.getChunkedReviewEnabled() {
+	start := time.Now()
+	result, err := s.reviewChunked(ctx, req)
+	if err == nil {
+		s.startShadowReview(req, result, time.Since(start))
+	}
+	return result, err
+}
+
+func (s *AIService) reviewChunked(ctx context.Context, req *ReviewRequest) (*ReviewResult, error) {
+	if !s.getChunkedReviewEnabled(req.ProjectID) {
 		return s.Review(ctx, req)
 	}
 
 	diffSize := len(req.Diffs)
 	threshold := s.getChunkThreshold()
 
+	// A self-hosted model's context window bounds the batch size, and diffs that do not
+	// fit are chunked even below the threshold
+	maxTokens := s.getMaxTokensPerBatch()
+	if limit := s.selfHostedBatchLimit(ctx, req.ProjectID); limit > 0 {
+		if limit < maxTokens {
+			maxTokens = limit
+		}
+		if diffSize/4 > limit && diffSize < threshold {
+			threshold = diffSize
+		}
+	}
+
 	if diffSize < threshold {
 		return s.Review(ctx, req)
 	}
 
 	files := ParseDiffToFiles(req.Diffs)
 	if len(files) <= 1 {
-		logger.Infof("[AI] Large diff (%d chars) but only %d file(s), using regular review", diffSize, len(files))
+		logger.Ctx(ctx).Info().Msgf("[AI] Large diff (%d chars) but only %d file(s), using regular review", diffSize, len(files))
 		return s.Review(ctx, req)
 	}
 
-	maxTokens := s.getMaxTokensPerBatch()
 	batches := CreateBatches(files, maxTokens)
 
-	logger.Infof("[AI] Large diff detected (%d chars, %d files), using chunked review with %d batches",
+	logger.Ctx(ctx).Info().Msgf("[AI] Large diff detected (%d chars, %d files), using chunked review with %d batches",
 		diffSize, len(files), len(batches))
 
 	var (
 		batchResults []BatchResult
+		usage        ReviewResult // Tokens of the batches, LLM config and prompt of the last one
+		lastErr      error
 		mu           sync.Mutex
 		wg           sync.WaitGroup
 	)
@@ -628,47 +991,72 @@ func (s *AIService) ReviewChunked(ctx context.Context, req *ReviewRequest) (*Rev
 			fileNames := GetBatchFileNames(b)
 			weight := GetBatchWeight(b)
 
-			logger.Infof("[AI] Reviewing batch %d/%d: %d files, ~%d tokens",
+			logger.Ctx(ctx).Info().Msgf("[AI] Reviewing batch %d/%d: %d files, ~%d tokens",
 				batchIdx+1, len(batches), len(b.Files), b.TotalTokens)
 
 			result, err := s.Review(ctx, &ReviewRequest{
-				ProjectID: req.ProjectID,
-				Diffs:     batchDiff,
-				Commits:   req.Commits,
+				ProjectID:    req.ProjectID,
+				Diffs:        batchDiff,
+				Commits:      req.Commits,
+				CustomPrompt: req.CustomPrompt,
+				Findings:     req.Findings,
+				Author:       req.Author,
+				EventType:    req.EventType,
+				Branch:       req.Branch,
+				TargetBranch: req.TargetBranch,
+				ReviewLogID:  req.ReviewLogID,
+				CommitHash:   req.CommitHash,
 			})
 
 			if err != nil {
-				logger.Infof("[AI] Batch %d/%d failed: %v", batchIdx+1, len(batches), err)
+				logger.Ctx(ctx).Info().Msgf("[AI] Batch %d/%d failed: %v", batchIdx+1, len(batches), err)
+				mu.Lock()
+				lastErr = err
+				mu.Unlock()
 				return
 			}
 
 			mu.Lock()
 			batchResults = append(batchResults, BatchResult{
-				BatchIndex: batchIdx,
-				Files:      fileNames,
-				Score:      result.Score,
-				Content:    result.Content,
-				Weight:     weight,
+				BatchIndex:   batchIdx,
+				Files:        fileNames,
+				Score:        result.Score,
+				Content:      result.Content,
+				Weight:       weight,
+				ScoreMissing: result.ScoreMissing,
 			})
+			usage.PromptTokens += result.PromptTokens
+			usage.CompletionTokens += result.CompletionTokens
+			usage.TotalTokens += result.TotalTokens
+			usage.LLMConfigID, usage.PromptRef = result.LLMConfigID, result.PromptRef
+			usage.ExperimentID, usage.Variant = result.ExperimentID, result.Variant
 			mu.Unlock()
 
-			logger.Infof("[AI] Batch %d/%d completed: score=%.0f", batchIdx+1, len(batches), result.Score)
+			logger.Ctx(ctx).Info().Msgf("[AI] Batch %d/%d completed: score=%.0f", batchIdx+1, len(batches), result.Score)
 		}(i, batch)
 	}
 
 	wg.Wait()
 
 	if len(batchResults) == 0 {
-		return nil, fmt.Errorf("all batches failed during chunked review")
+		return nil, fmt.Errorf("all batches failed during chunked review: %w", lastErr)
 	}
 
 	aggregated := AggregateResults(batchResults)
 
-	logger.Infof("[AI] Chunked review completed: %d/%d batches succeeded, aggregated score=%.0f",
+	logger.Ctx(ctx).Info().Msgf("[AI] Chunked review completed: %d/%d batches succeeded, aggregated score=%.0f",
 		len(batchResults), len(batches), aggregated.Score)
 
 	return &ReviewResult{
-		Content: aggregated.Content,
-		Score:   aggregated.Score,
+		Content:          aggregated.Content,
+		Score:            aggregated.Score,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+		LLMConfigID:      usage.LLMConfigID,
+		PromptRef:        usage.PromptRef,
+		ScoreMissing:     aggregated.ScoreMissing,
+		ExperimentID:     usage.ExperimentID,
+		Variant:          usage.Variant,
 	}, nil
 }
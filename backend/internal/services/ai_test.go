@@ -1,7 +1,15 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestExtractScore(t *testing.T) {
@@ -45,6 +53,16 @@ func TestExtractScore(t *testing.T) {
 			content:  "## Review\n\n**Total Score: 65**\n\nDetails...",
 			expected: 65,
 		},
+		{
+			name:     "score line",
+			content:  "Looks good\nSecurity: 20/30\nSCORE: 72\n",
+			expected: 72,
+		},
+		{
+			name:     "decimal score line",
+			content:  "Review...\nScore: 87.5",
+			expected: 87.5,
+		},
 		{
 			name:     "no score found",
 			content:  "This is just some text without any score",
@@ -204,8 +222,6 @@ func TestAppendScoringInstruction(t *testing.T) {
 }
 
 func TestProcessFileContextBlock(t *testing.T) {
-	service := &AIService{}
-
 	tests := []struct {
 		name        string
 		prompt      string
@@ -234,7 +250,7 @@ func TestProcessFileContextBlock(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.processFileContextBlock(tt.prompt, tt.fileContext)
+			result := renderPrompt(tt.prompt, map[string]string{"{{file_context}}": tt.fileContext}, nil)
 
 			if tt.shouldKeep {
 				if containsSubstring(result, "{{#if_file_context}}") {
@@ -254,3 +270,196 @@ func TestProcessFileContextBlock(t *testing.T) {
 		})
 	}
 }
+
+func TestPromptCachePrefixLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     int
+	}{
+		{"prefix before diffs", "Guidelines\n{{diffs}}\n{{commits}}", len("Guidelines\n")},
+		{"commits first", "Rules {{commits}} then {{diffs}}", len("Rules ")},
+		{"file context block first", "Rules {{#if_file_context}}{{file_context}}{{/if_file_context}} {{diffs}}", len("Rules ")},
+		{"project profile first", "Rules for {{project_profile}}: {{diffs}}", len("Rules for ")},
+		{"no placeholders", "Review the change", len("Review the change")},
+		{"placeholder at start", "{{diffs}} review", 0},
+		{"whitespace prefix", "  \n{{diffs}}", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptCachePrefixLen(tt.template); got != tt.want {
+				t.Errorf("promptCachePrefixLen() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnthropicPromptBlocks(t *testing.T) {
+	prompt := "static guidelines\ndiff"
+	tests := []struct {
+		name        string
+		cachePrefix int
+		cache       bool
+		wantBlocks  int
+	}{
+		{"caching disabled", 18, false, 1},
+		{"no prefix", 0, true, 1},
+		{"prefix covers prompt", len(prompt), true, 1},
+		{"split", 18, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := anthropicPromptBlocks(prompt, tt.cachePrefix, tt.cache)
+			if len(blocks) != tt.wantBlocks {
+				t.Fatalf("got %d blocks, want %d", len(blocks), tt.wantBlocks)
+			}
+			if tt.wantBlocks == 1 {
+				if blocks[0].OfText.Text != prompt || blocks[0].OfText.CacheControl.Type != "" {
+					t.Errorf("single block = %+v, want the whole prompt without cache control", blocks[0].OfText)
+				}
+				return
+			}
+			if blocks[0].OfText.Text != "static guidelines\n" || blocks[1].OfText.Text != "diff" {
+				t.Errorf("blocks = %q, %q", blocks[0].OfText.Text, blocks[1].OfText.Text)
+			}
+			if blocks[0].OfText.CacheControl.Type != "ephemeral" || blocks[1].OfText.CacheControl.Type != "" {
+				t.Errorf("only the static block should carry cache control")
+			}
+		})
+	}
+}
+
+func TestOpenAICachedTokens(t *testing.T) {
+	if got := openAICachedTokens(openai.Usage{PromptTokens: 100}); got != 0 {
+		t.Errorf("openAICachedTokens() without details = %d, want 0", got)
+	}
+	usage := openai.Usage{PromptTokens: 2000, PromptTokensDetails: &openai.PromptTokensDetails{CachedTokens: 1536}}
+	if got := openAICachedTokens(usage); got != 1536 {
+		t.Errorf("openAICachedTokens() = %d, want 1536", got)
+	}
+}
+
+func TestProcessProjectProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		prompt  string
+		profile string
+		want    string
+	}{
+		{
+			name:    "placeholder filled",
+			prompt:  "Stack: {{project_profile}}\n{{diffs}}",
+			profile: "Go + Gin + GORM",
+			want:    "Stack: Go + Gin + GORM\n{{diffs}}",
+		},
+		{
+			name:    "conditional block kept",
+			prompt:  "{{#if_project_profile}}Stack: {{project_profile}}\n{{/if_project_profile}}{{diffs}}",
+			profile: " React + TS ",
+			want:    "Stack: React + TS\n{{diffs}}",
+		},
+		{
+			name:    "conditional block removed without profile",
+			prompt:  "{{#if_project_profile}}Stack: {{project_profile}}\n{{/if_project_profile}}{{diffs}}",
+			profile: "",
+			want:    "{{diffs}}",
+		},
+		{
+			name:    "profile appended without placeholder",
+			prompt:  "{{diffs}}",
+			profile: "React + TS",
+			want:    "{{diffs}}\n\n--- Project Profile ---\nThis project uses React + TS. Apply the conventions and best practices of these languages and frameworks in the review.\n",
+		},
+		{
+			name:    "nothing appended without profile",
+			prompt:  "{{diffs}}",
+			profile: "  ",
+			want:    "{{diffs}}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processProjectProfile(tt.prompt, tt.profile); got != tt.want {
+				t.Errorf("processProjectProfile() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallLLMWithMeta_ScoreMissing(t *testing.T) {
+	tests := []struct {
+		name        string
+		reply       string
+		wantMissing bool
+	}{
+		{"score line", "Looks fine.\nSCORE: 85", false},
+		{"no score", "Looks fine.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prompt string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req openai.ChatCompletionRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				prompt = req.Messages[len(req.Messages)-1].Content
+				json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+					Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: tt.reply}}},
+				})
+			}))
+			defer srv.Close()
+
+			before := ScoreParseFailureCount()
+			s := &AIService{}
+			result, err := s.callLLMWithMeta(context.Background(), &models.LLMConfig{BaseURL: srv.URL, Model: "test"}, "Review this", llmCallMeta{Structured: true})
+			if err != nil {
+				t.Fatalf("callLLMWithMeta() error = %v", err)
+			}
+			if !strings.Contains(prompt, "SCORE: <0-100>") {
+				t.Error("review prompt should ask for a score line")
+			}
+			if result.ScoreMissing != tt.wantMissing {
+				t.Errorf("ScoreMissing = %v, want %v", result.ScoreMissing, tt.wantMissing)
+			}
+			if failures := ScoreParseFailureCount() - before; (failures == 1) != tt.wantMissing {
+				t.Errorf("parse failures increased by %d", failures)
+			}
+		})
+	}
+}
+
+func TestApplyReviewResult(t *testing.T) {
+	var reviewLog models.ReviewLog
+	ApplyReviewResult(&reviewLog, &ReviewResult{Content: "ok", Score: 80})
+	if reviewLog.ReviewStatus != "completed" || reviewLog.Score == nil || *reviewLog.Score != 80 {
+		t.Errorf("scored review recorded as %s with score %v", reviewLog.ReviewStatus, reviewLog.Score)
+	}
+
+	ApplyReviewResult(&reviewLog, &ReviewResult{Content: "no score", ScoreMissing: true})
+	if reviewLog.ReviewStatus != ReviewStatusNeedsAttention || reviewLog.Score != nil {
+		t.Errorf("unscored review recorded as %s with score %v", reviewLog.ReviewStatus, reviewLog.Score)
+	}
+}
+
+func TestReview_Simulation(t *testing.T) {
+	db := newTestDB(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	project := &models.Project{Name: "p", URL: "https://gitlab.example.com/g/p", Platform: "gitlab"}
+	mustCreate(t, db, project, &models.LLMConfig{Name: "down", Provider: "openai", BaseURL: srv.URL, Model: "test", IsActive: true, IsDefault: true})
+
+	before := GetLLMOutageStatus().Failures
+	s := NewAIService(db, nil)
+	if _, err := s.Review(context.Background(), &ReviewRequest{ProjectID: project.ID, Diffs: "diff --git a/x b/x", Simulation: true}); err == nil {
+		t.Fatal("Review() error = nil, want the LLM failure")
+	}
+	if after := GetLLMOutageStatus().Failures; after != before {
+		t.Errorf("outage failures = %d, want %d: a simulation counted as a chain failure", after, before)
+	}
+	var usage int64
+	db.Model(&models.AIUsageLog{}).Count(&usage)
+	if usage != 0 {
+		t.Errorf("usage logs = %d, want none for a simulation", usage)
+	}
+}
@@ -37,6 +37,9 @@ type UsageStats struct {
 	SuccessCount     int64   `json:"success_count"`
 	FailureCount     int64   `json:"failure_count"`
 	CacheHits        int64   `json:"cache_hits"`
+	CachedTokens     int64   `json:"cached_tokens"`      // Prompt tokens read from provider prompt caches
+	CacheWriteTokens int64   `json:"cache_write_tokens"` // Prompt tokens written to provider prompt caches
+	PromptCacheRate  float64 `json:"prompt_cache_rate"`  // Percentage of prompt tokens read from cache
 }
 
 // GetStats returns aggregated usage statistics for the given time range.
@@ -58,6 +61,8 @@ func (s *AIUsageService) GetStats(startDate, endDate string, projectID *uint) (*
 			"COALESCE(SUM(total_tokens), 0) as total_tokens, " +
 			"COALESCE(SUM(prompt_tokens), 0) as prompt_tokens, " +
 			"COALESCE(SUM(completion_tokens), 0) as completion_tokens, " +
+			"COALESCE(SUM(cached_tokens), 0) as cached_tokens, " +
+			"COALESCE(SUM(cache_write_tokens), 0) as cache_write_tokens, " +
 			"COALESCE(AVG(latency_ms), 0) as avg_latency_ms, " +
 			"COALESCE(SUM(CASE WHEN success = 1 THEN 1 ELSE 0 END), 0) as success_count, " +
 			"COALESCE(SUM(CASE WHEN success = 0 THEN 1 ELSE 0 END), 0) as failure_count",
@@ -69,6 +74,9 @@ func (s *AIUsageService) GetStats(startDate, endDate string, projectID *uint) (*
 	if stats.TotalCalls > 0 {
 		stats.SuccessRate = float64(stats.SuccessCount) / float64(stats.TotalCalls) * 100
 	}
+	if stats.PromptTokens > 0 {
+		stats.PromptCacheRate = float64(stats.CachedTokens) / float64(stats.PromptTokens) * 100
+	}
 
 	// Count cache hits from review_logs (reviews with duplicate diff_hash in same project)
 	cacheQuery := s.db.Model(&models.ReviewLog{}).Where(
@@ -96,6 +104,33 @@ func (s *AIUsageService) GetStats(startDate, endDate string, projectID *uint) (*
 	return &stats, nil
 }
 
+// ReviewUsage holds the LLM calls made for one review with their prompt cache metrics.
+type ReviewUsage struct {
+	ReviewLogID      uint                `json:"review_log_id"`
+	PromptTokens     int                 `json:"prompt_tokens"`
+	CachedTokens     int                 `json:"cached_tokens"`
+	CacheWriteTokens int                 `json:"cache_write_tokens"`
+	PromptCacheRate  float64             `json:"prompt_cache_rate"`
+	Calls            []models.AIUsageLog `json:"calls"`
+}
+
+// GetReviewUsage returns the LLM calls recorded for a review.
+func (s *AIUsageService) GetReviewUsage(reviewLogID uint) (*ReviewUsage, error) {
+	usage := &ReviewUsage{ReviewLogID: reviewLogID}
+	if err := s.db.Where("review_log_id = ?", reviewLogID).Order("id ASC").Find(&usage.Calls).Error; err != nil {
+		return nil, err
+	}
+	for _, call := range usage.Calls {
+		usage.PromptTokens += call.PromptTokens
+		usage.CachedTokens += call.CachedTokens
+		usage.CacheWriteTokens += call.CacheWriteTokens
+	}
+	if usage.PromptTokens > 0 {
+		usage.PromptCacheRate = float64(usage.CachedTokens) / float64(usage.PromptTokens) * 100
+	}
+	return usage, nil
+}
+
 // DailyUsage holds usage data for a single day.
 type DailyUsage struct {
 	Date         string `json:"date"`
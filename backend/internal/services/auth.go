@@ -11,6 +11,7 @@ import (
 	"github.com/huangang/codesentry/backend/internal/config"
 	"github.com/huangang/codesentry/backend/internal/models"
 	"github.com/huangang/codesentry/backend/internal/utils"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 	"gorm.io/gorm"
 )
 
@@ -79,11 +80,14 @@ func (s *AuthService) Login(req *LoginRequest, clientIP, userAgent string) (*Log
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkTenantActive(user); err != nil {
+		return nil, err
+	}
 
 	accessHours := s.getAccessTokenExpireHours()
 	refreshHours := s.getRefreshTokenExpireHours()
 
-	token, err := utils.GenerateToken(user.ID, user.Username, user.Role, accessHours)
+	token, err := utils.GenerateTenantToken(user.ID, user.TenantID, user.Username, user.Role, accessHours)
 	if err != nil {
 		return nil, err
 	}
@@ -155,11 +159,14 @@ func (s *AuthService) Refresh(refreshToken string, clientIP, userAgent string) (
 	if !user.IsActive {
 		return nil, errors.New("user is disabled")
 	}
+	if err := s.checkTenantActive(&user); err != nil {
+		return nil, err
+	}
 
 	accessHours := s.getAccessTokenExpireHours()
 	refreshHours := s.getRefreshTokenExpireHours()
 
-	newAccessToken, err := utils.GenerateToken(user.ID, user.Username, user.Role, accessHours)
+	newAccessToken, err := utils.GenerateTenantToken(user.ID, user.TenantID, user.Username, user.Role, accessHours)
 	if err != nil {
 		return nil, err
 	}
@@ -295,6 +302,7 @@ func (s *AuthService) ldapAuth(username, password string) (*models.User, error)
 			Role:     "user",
 			AuthType: "ldap",
 			IsActive: true,
+			TenantID: models.DefaultTenantID(),
 		}
 		if err := s.db.Create(&user).Error; err != nil {
 			return nil, err
@@ -312,9 +320,26 @@ func (s *AuthService) ldapAuth(username, password string) (*models.User, error)
 	user.Nickname = ldapUser.Nickname
 	s.db.Save(&user)
 
+	if err := s.ldapService.ApplyGroupMappings(&user, ldapUser.Groups); err != nil {
+		logger.Warnf("[LDAP] Failed to apply group mappings for %s: %v", user.Username, err)
+	}
+
 	return &user, nil
 }
 
+// checkTenantActive rejects users whose workspace has been disabled.
+// Platform admins can always sign in to manage tenants.
+func (s *AuthService) checkTenantActive(user *models.User) error {
+	if user.Role == "admin" || user.TenantID == 0 {
+		return nil
+	}
+	var tenant models.Tenant
+	if err := s.db.First(&tenant, user.TenantID).Error; err != nil || !tenant.IsActive {
+		return errors.New("workspace is disabled")
+	}
+	return nil
+}
+
 // GetUserByID retrieves a user by ID
 func (s *AuthService) GetUserByID(id uint) (*models.User, error) {
 	var user models.User
@@ -342,6 +367,7 @@ func (s *AuthService) CreateAdminIfNotExists() error {
 			Role:     "admin",
 			AuthType: "local",
 			IsActive: true,
+			TenantID: models.DefaultTenantID(),
 		}
 
 		return s.db.Create(&admin).Error
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// AuthorEnrichmentInterval is how often authors without avatars are resolved
+	AuthorEnrichmentInterval = time.Hour
+	// AuthorEnrichmentBatchSize caps the platform lookups of one run
+	AuthorEnrichmentBatchSize = 50
+	// authorProfileRecheckAfter is how long an email without a platform user is not looked up again
+	authorProfileRecheckAfter = 7 * 24 * time.Hour
+)
+
+// ErrAuthorEnrichmentRunning is returned when an enrichment run is already in progress
+var ErrAuthorEnrichmentRunning = errors.New("author enrichment is already running")
+
+// authorEnrichmentRunning guards against concurrent runs in one instance
+var authorEnrichmentRunning atomic.Bool
+
+// AuthorEnrichmentService resolves commit author emails to GitLab and GitHub users and
+// backfills the avatars and profile URLs of review logs recorded without them, such as
+// imported commits and sync reviews
+type AuthorEnrichmentService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewAuthorEnrichmentService(db *gorm.DB) *AuthorEnrichmentService {
+	return &AuthorEnrichmentService{
+		db:         db,
+		httpClient: PlatformHTTPClient(),
+	}
+}
+
+type AuthorEnrichmentResult struct {
+	Authors  int   `json:"authors"`  // Project authors without an avatar
+	Lookups  int   `json:"lookups"`  // Platform lookups made
+	Resolved int   `json:"resolved"` // Authors resolved to a platform user
+	Updated  int64 `json:"updated"`  // Review logs backfilled
+}
+
+// authorWithoutAvatar is an author email of a project whose review logs lack an avatar
+type authorWithoutAvatar struct {
+	ProjectID   uint
+	AuthorEmail string
+	CommitHash  string
+}
+
+// Run resolves authors without avatars, at most AuthorEnrichmentBatchSize platform lookups
+// per run; resolved and unknown emails are cached in author profiles
+func (s *AuthorEnrichmentService) Run() (*AuthorEnrichmentResult, error) {
+	if !authorEnrichmentRunning.CompareAndSwap(false, true) {
+		return nil, ErrAuthorEnrichmentRunning
+	}
+	defer authorEnrichmentRunning.Store(false)
+
+	var authors []authorWithoutAvatar
+	if err := s.db.Model(&models.ReviewLog{}).
+		Select("project_id, author_email, MAX(commit_hash) as commit_hash").
+		Where("author_email <> '' AND (author_avatar = '' OR author_avatar IS NULL)").
+		Group("project_id, author_email").
+		Scan(&authors).Error; err != nil {
+		return nil, err
+	}
+
+	result := &AuthorEnrichmentResult{Authors: len(authors)}
+	projects := make(map[uint]*models.Project)
+	for _, author := range authors {
+		project, ok := projects[author.ProjectID]
+		if !ok {
+			project = &models.Project{}
+			if err := s.db.First(project, author.ProjectID).Error; err != nil {
+				project = nil
+			}
+			projects[author.ProjectID] = project
+		}
+		if project == nil || project.AccessToken == "" || (project.Platform != "gitlab" && project.Platform != "github") {
+			continue
+		}
+
+		profile, looked, err := s.resolve(project, author, result.Lookups < AuthorEnrichmentBatchSize)
+		if looked {
+			result.Lookups++
+		}
+		if err != nil {
+			logger.Warnf("[AuthorEnrichment] Failed to resolve %s on project %d: %v", author.AuthorEmail, project.ID, err)
+			continue
+		}
+		if profile == nil || !profile.Found {
+			continue
+		}
+
+		result.Resolved++
+		updated := s.db.Model(&models.ReviewLog{}).
+			Where("project_id = ? AND author_email = ? AND (author_avatar = '' OR author_avatar IS NULL)", project.ID, author.AuthorEmail).
+			Updates(map[string]interface{}{"author_avatar": profile.AvatarURL, "author_url": profile.ProfileURL})
+		if updated.Error != nil {
+			logger.Warnf("[AuthorEnrichment] Failed to backfill review logs of %s: %v", author.AuthorEmail, updated.Error)
+			continue
+		}
+		result.Updated += updated.RowsAffected
+	}
+
+	if result.Updated > 0 || result.Lookups > 0 {
+		logger.Infof("[AuthorEnrichment] Resolved %d of %d authors with %d lookups, backfilled %d review logs",
+			result.Resolved, result.Authors, result.Lookups, result.Updated)
+	}
+	return result, nil
+}
+
+// Start runs an enrichment in the background
+func (s *AuthorEnrichmentService) Start() error {
+	if authorEnrichmentRunning.Load() {
+		return ErrAuthorEnrichmentRunning
+	}
+	go func() {
+		if _, err := s.Run(); err != nil && !errors.Is(err, ErrAuthorEnrichmentRunning) {
+			logger.Errorf("[AuthorEnrichment] Run failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// resolve returns the cached profile of an author email, or looks it up on the platform
+// when allowed. It reports whether a lookup was made.
+func (s *AuthorEnrichmentService) resolve(project *models.Project, author authorWithoutAvatar, canLookup bool) (*models.AuthorProfile, bool, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, false, err
+	}
+	host := "github.com"
+	if project.Platform == "gitlab" {
+		if u, err := url.Parse(info.baseURL); err == nil {
+			host = u.Host
+		}
+	}
+
+	var cached models.AuthorProfile
+	err = s.db.Where("host = ? AND email = ?", host, author.AuthorEmail).First(&cached).Error
+	if err == nil && (cached.Found || time.Since(cached.CheckedAt) < authorProfileRecheckAfter) {
+		return &cached, false, nil
+	}
+	if !canLookup {
+		return nil, false, nil
+	}
+
+	var profile *models.AuthorProfile
+	if project.Platform == "gitlab" {
+		profile, err = s.lookupGitLabUser(project, info, author.AuthorEmail)
+	} else {
+		profile, err = s.lookupGitHubUser(project, info, author)
+	}
+	if err != nil {
+		return nil, true, err
+	}
+	if profile == nil {
+		profile = &models.AuthorProfile{}
+	}
+	profile.Host = host
+	profile.Email = author.AuthorEmail
+	profile.CheckedAt = time.Now()
+	if cached.ID > 0 {
+		profile.ID = cached.ID
+	}
+	if err := s.db.Save(profile).Error; err != nil {
+		return nil, true, err
+	}
+	return profile, true, nil
+}
+
+// lookupGitLabUser searches GitLab users by email; the email must be public unless the
+// project's token belongs to an administrator
+func (s *AuthorEnrichmentService) lookupGitLabUser(project *models.Project, info *repoInfo, email string) (*models.AuthorProfile, error) {
+	apiURL := fmt.Sprintf("%s/api/v4/users?search=%s", info.baseURL, url.QueryEscape(email))
+	req, _ := http.NewRequest("GET", apiURL, nil)
+	req.Header.Set("PRIVATE-TOKEN", project.AccessToken)
+
+	var users []struct {
+		Username  string `json:"username"`
+		AvatarURL string `json:"avatar_url"`
+		WebURL    string `json:"web_url"`
+	}
+	if err := s.getJSON(req, "GitLab", &users); err != nil {
+		return nil, err
+	}
+	if len(users) != 1 {
+		return nil, nil
+	}
+	return &models.AuthorProfile{Username: users[0].Username, AvatarURL: users[0].AvatarURL, ProfileURL: users[0].WebURL, Found: true}, nil
+}
+
+// lookupGitHubUser searches GitHub users by public email, falling back to the account
+// GitHub linked to one of the author's commits
+func (s *AuthorEnrichmentService) lookupGitHubUser(project *models.Project, info *repoInfo, author authorWithoutAvatar) (*models.AuthorProfile, error) {
+	type gitHubUser struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+		HTMLURL   string `json:"html_url"`
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.github.com/search/users?q="+url.QueryEscape(author.AuthorEmail+" in:email"), nil)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+project.AccessToken)
+	var search struct {
+		Items []gitHubUser `json:"items"`
+	}
+	if err := s.getJSON(req, "GitHub", &search); err != nil {
+		return nil, err
+	}
+	if len(search.Items) == 1 {
+		user := search.Items[0]
+		return &models.AuthorProfile{Username: user.Login, AvatarURL: user.AvatarURL, ProfileURL: user.HTMLURL, Found: true}, nil
+	}
+	if author.CommitHash == "" {
+		return nil, nil
+	}
+
+	req, _ = http.NewRequest("GET", fmt.Sprintf("https://api.github.com/repos/%s/%s/commits/%s", info.owner, info.repo, author.CommitHash), nil)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("Authorization", "token "+project.AccessToken)
+	var commit struct {
+		Commit struct {
+			Author struct {
+				Email string `json:"email"`
+			} `json:"author"`
+		} `json:"commit"`
+		Author *gitHubUser `json:"author"`
+	}
+	if err := s.getJSON(req, "GitHub", &commit); err != nil {
+		return nil, err
+	}
+	if commit.Author == nil || !strings.EqualFold(commit.Commit.Author.Email, author.AuthorEmail) {
+		return nil, nil
+	}
+	return &models.AuthorProfile{Username: commit.Author.Login, AvatarURL: commit.Author.AvatarURL, ProfileURL: commit.Author.HTMLURL, Found: true}, nil
+}
+
+func (s *AuthorEnrichmentService) getJSON(req *http.Request, platform string, out interface{}) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s API returned %d: %s", platform, resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+var authorEnrichmentStopChan chan struct{}
+
+// StartAuthorEnrichmentScheduler starts a goroutine that backfills author avatars hourly
+func StartAuthorEnrichmentScheduler(db *gorm.DB) {
+	authorEnrichmentStopChan = make(chan struct{})
+	go func() {
+		service := NewAuthorEnrichmentService(db)
+		RegisterScheduler("author_enrichment", AuthorEnrichmentInterval)
+		ticker := time.NewTicker(AuthorEnrichmentInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("author_enrichment", func() error {
+					_, err := service.Run()
+					if err != nil && !errors.Is(err, ErrAuthorEnrichmentRunning) {
+						logger.Errorf("[AuthorEnrichment] Run failed: %v", err)
+						return err
+					}
+					return nil
+				})
+			case <-authorEnrichmentStopChan:
+				logger.Infof("[AuthorEnrichment] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopAuthorEnrichmentScheduler stops the author enrichment scheduler
+func StopAuthorEnrichmentScheduler() {
+	if authorEnrichmentStopChan != nil {
+		close(authorEnrichmentStopChan)
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestLookupGitLabUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v4/users" || r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Query().Get("search") {
+		case "alice@example.com":
+			w.Write([]byte(`[{"username":"alice","avatar_url":"https://gitlab/a.png","web_url":"https://gitlab/alice"}]`))
+		case "shared@example.com":
+			w.Write([]byte(`[{"username":"a"},{"username":"b"}]`))
+		default:
+			w.Write([]byte(`[]`))
+		}
+	}))
+	defer server.Close()
+
+	s := &AuthorEnrichmentService{httpClient: server.Client()}
+	project := &models.Project{Platform: "gitlab", URL: server.URL + "/group/repo", AccessToken: "secret"}
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		t.Fatalf("parseRepoInfo: %v", err)
+	}
+
+	profile, err := s.lookupGitLabUser(project, info, "alice@example.com")
+	if err != nil {
+		t.Fatalf("lookupGitLabUser: %v", err)
+	}
+	if profile == nil || !profile.Found || profile.Username != "alice" || profile.AvatarURL != "https://gitlab/a.png" || profile.ProfileURL != "https://gitlab/alice" {
+		t.Errorf("profile = %+v", profile)
+	}
+
+	// Ambiguous and unknown emails resolve to nobody
+	for _, email := range []string{"shared@example.com", "nobody@example.com"} {
+		if profile, err := s.lookupGitLabUser(project, info, email); err != nil || profile != nil {
+			t.Errorf("lookupGitLabUser(%q) = %+v, %v", email, profile, err)
+		}
+	}
+
+	project.AccessToken = "wrong"
+	if _, err := s.lookupGitLabUser(project, info, "alice@example.com"); err == nil {
+		t.Error("expected an error for a rejected token")
+	}
+}
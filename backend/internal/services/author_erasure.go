@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ErrEraseAuthorRequired is returned when an erasure names neither an author nor an email
+var ErrEraseAuthorRequired = errors.New("author or email is required")
+
+type EraseAuthorRequest struct {
+	Author string `json:"author"` // Author name as recorded on commits
+	Email  string `json:"email"`  // Author email as recorded on commits
+}
+
+type EraseAuthorResponse struct {
+	Pseudonym       string `json:"pseudonym"` // Name the author's records now carry
+	ReviewLogs      int64  `json:"review_logs"`
+	Findings        int64  `json:"findings"`
+	DigestItems     int64  `json:"digest_items"`
+	ProfilesDeleted int64  `json:"profiles_deleted"`
+}
+
+// authorPseudonym returns the stable name that replaces an erased author, so the erased
+// author's reviews still count as one member in statistics. It is keyed by a server secret,
+// so the pseudonym cannot be matched against the hashes of known emails.
+func authorPseudonym(secret, author, email string) string {
+	key := strings.ToLower(strings.TrimSpace(email))
+	if key == "" {
+		key = strings.TrimSpace(author)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(key))
+	return "erased-" + hex.EncodeToString(mac.Sum(nil))[:10]
+}
+
+// EraseAuthor anonymizes an author across review logs, including deleted ones, findings
+// and queued digest notifications, and forgets the author's cached platform profile. Review
+// logs matching the name or the email are anonymized, as are the other names the email was
+// recorded with. The names and emails are also replaced in commit messages and in the
+// archived review text of the anonymized logs.
+func (s *MemberService) EraseAuthor(req *EraseAuthorRequest) (*EraseAuthorResponse, error) {
+	author, email := strings.TrimSpace(req.Author), strings.TrimSpace(req.Email)
+	if author == "" && email == "" {
+		return nil, ErrEraseAuthorRequired
+	}
+
+	matching := s.db.Unscoped().Model(&models.ReviewLog{})
+	switch {
+	case author != "" && email != "":
+		matching = matching.Where("author = ? OR author_email = ?", author, email)
+	case author != "":
+		matching = matching.Where("author = ?", author)
+	default:
+		matching = matching.Where("author_email = ?", email)
+	}
+
+	var names, emails []string
+	if err := matching.Session(&gorm.Session{}).Distinct().Pluck("author", &names).Error; err != nil {
+		return nil, err
+	}
+	if err := matching.Session(&gorm.Session{}).Where("author_email <> ''").Distinct().Pluck("author_email", &emails).Error; err != nil {
+		return nil, err
+	}
+	if author != "" {
+		names = appendUnique(names, author)
+	}
+	if email != "" {
+		emails = appendUnique(emails, email)
+	}
+
+	secret, err := NewSystemConfigService(s.db).privacyAliasKey()
+	if err != nil {
+		return nil, err
+	}
+	resp := &EraseAuthorResponse{Pseudonym: authorPseudonym(secret, author, email)}
+	// Longer values first, so an email is replaced before a name it contains
+	identities := append(append([]string{}, emails...), names...)
+	sort.SliceStable(identities, func(i, j int) bool { return len(identities[i]) > len(identities[j]) })
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var reviewLogIDs []uint
+		if err := tx.Unscoped().Model(&models.ReviewLog{}).
+			Where("author IN ? OR author_email IN ?", names, emails).
+			Pluck("id", &reviewLogIDs).Error; err != nil {
+			return err
+		}
+
+		res := tx.Unscoped().Model(&models.ReviewLog{}).
+			Where("author IN ? OR author_email IN ?", names, emails).
+			Updates(map[string]interface{}{
+				"author":        resp.Pseudonym,
+				"author_email":  "",
+				"author_avatar": "",
+				"author_url":    "",
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		resp.ReviewLogs = res.RowsAffected
+
+		for _, identity := range identities {
+			if identity == "" {
+				continue
+			}
+			if err := tx.Unscoped().Model(&models.ReviewLog{}).
+				Where(LikeCondition("commit_message"), ContainsPattern(identity)).
+				Update("commit_message", gorm.Expr("REPLACE(commit_message, ?, ?)", identity, resp.Pseudonym)).Error; err != nil {
+				return err
+			}
+			if len(reviewLogIDs) == 0 {
+				continue
+			}
+			if err := tx.Model(&models.ReviewLogArchive{}).
+				Where("review_log_id IN ?", reviewLogIDs).
+				Updates(map[string]interface{}{
+					"review_result": gorm.Expr("REPLACE(review_result, ?, ?)", identity, resp.Pseudonym),
+					"diff_content":  gorm.Expr("REPLACE(diff_content, ?, ?)", identity, resp.Pseudonym),
+				}).Error; err != nil {
+				return err
+			}
+		}
+
+		res = tx.Model(&models.ReviewFinding{}).Where("author IN ?", names).Update("author", resp.Pseudonym)
+		if res.Error != nil {
+			return res.Error
+		}
+		resp.Findings = res.RowsAffected
+
+		res = tx.Model(&models.NotificationDigestItem{}).Where("author IN ?", names).Update("author", resp.Pseudonym)
+		if res.Error != nil {
+			return res.Error
+		}
+		resp.DigestItems = res.RowsAffected
+
+		res = tx.Where("email IN ?", emails).Delete(&models.AuthorProfile{})
+		if res.Error != nil {
+			return res.Error
+		}
+		resp.ProfilesDeleted = res.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("[Member] Erased author as %s: review logs %d, findings %d, digest items %d, profiles %d",
+		resp.Pseudonym, resp.ReviewLogs, resp.Findings, resp.DigestItems, resp.ProfilesDeleted)
+	return resp, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
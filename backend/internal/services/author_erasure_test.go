@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestAuthorPseudonym(t *testing.T) {
+	alice := authorPseudonym("secret", "Alice", "alice@example.com")
+	if !strings.HasPrefix(alice, "erased-") || len(alice) != len("erased-")+10 {
+		t.Fatalf("pseudonym = %q", alice)
+	}
+
+	tests := []struct {
+		name   string
+		author string
+		email  string
+		same   bool
+	}{
+		{"email decides over the name", "Alice Smith", "alice@example.com", true},
+		{"email is case insensitive", "Alice", " ALICE@example.com", true},
+		{"other email", "Alice", "bob@example.com", false},
+		{"name only", "Alice", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := authorPseudonym("secret", tt.author, tt.email)
+			if (got == alice) != tt.same {
+				t.Errorf("authorPseudonym(%q, %q) = %q, alice = %q", tt.author, tt.email, got, alice)
+			}
+		})
+	}
+
+	if authorPseudonym("secret", "Alice", "") != authorPseudonym("secret", " Alice ", "") {
+		t.Error("name-only pseudonym should ignore surrounding spaces")
+	}
+	if authorPseudonym("other", "Alice", "alice@example.com") == alice {
+		t.Error("pseudonym should depend on the secret")
+	}
+}
+
+func TestEraseAuthorRequiresAuthorOrEmail(t *testing.T) {
+	s := &MemberService{}
+	if _, err := s.EraseAuthor(&EraseAuthorRequest{Author: " ", Email: ""}); !errors.Is(err, ErrEraseAuthorRequired) {
+		t.Errorf("err = %v, want ErrEraseAuthorRequired", err)
+	}
+}
+
+func TestEraseAuthor_ScrubsCommitMessagesAndArchives(t *testing.T) {
+	db := newTestDB(t)
+	project := &models.Project{Name: "app", URL: "https://git.example.com/app", Platform: "gitlab"}
+	mustCreate(t, db, project)
+	erased := &models.ReviewLog{ProjectID: project.ID, EventType: "push", Author: "Alice", AuthorEmail: "alice@example.com",
+		CommitMessage: "Fix login\n\nSigned-off-by: Alice <alice@example.com>", ReviewStatus: "completed"}
+	other := &models.ReviewLog{ProjectID: project.ID, EventType: "push", Author: "Bob", AuthorEmail: "bob@example.com",
+		CommitMessage: "Pair with Alice", ReviewStatus: "completed"}
+	mustCreate(t, db, erased, other)
+	mustCreate(t, db, &models.ReviewLogArchive{ReviewLogID: erased.ID, ReviewResult: "Alice broke the build", DiffContent: "+// alice@example.com"})
+
+	resp, err := NewMemberService(db).EraseAuthor(&EraseAuthorRequest{Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs []models.ReviewLog
+	db.Order("id").Find(&logs)
+	want := "Fix login\n\nSigned-off-by: " + resp.Pseudonym + " <" + resp.Pseudonym + ">"
+	if logs[0].Author != resp.Pseudonym || logs[0].CommitMessage != want {
+		t.Errorf("erased log = %q / %q, want %q / %q", logs[0].Author, logs[0].CommitMessage, resp.Pseudonym, want)
+	}
+	if logs[1].Author != "Bob" || logs[1].CommitMessage != "Pair with "+resp.Pseudonym {
+		t.Errorf("other log = %q / %q, want the name scrubbed from its message only", logs[1].Author, logs[1].CommitMessage)
+	}
+
+	var archive models.ReviewLogArchive
+	db.First(&archive)
+	if strings.Contains(archive.ReviewResult, "Alice") || strings.Contains(archive.DiffContent, "alice@example.com") {
+		t.Errorf("archive still names the author: %+v", archive)
+	}
+}
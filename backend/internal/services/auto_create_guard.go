@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Reasons a credential refuses to auto-create a project
+var (
+	ErrAutoCreateDenied = errors.New("repository is not allowed to be auto-created")
+	ErrAutoCreateLimit  = errors.New("auto-created project limit reached")
+)
+
+// repoPattern matches a repository path (namespace/repo) and everything below it against a glob:
+// "*" and "?" stay within a path segment and "**" crosses them, so "platform" and "platform/*"
+// both cover platform/infra/deploy
+type repoPattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// ParseRepoPatterns compiles a comma or newline separated list of repository globs
+func ParseRepoPatterns(patterns string) ([]repoPattern, error) {
+	var result []repoPattern
+	for _, p := range strings.FieldsFunc(patterns, func(r rune) bool { return r == ',' || r == '\n' }) {
+		p = strings.ToLower(strings.Trim(strings.TrimSpace(p), "/"))
+		if p == "" {
+			continue
+		}
+		re, err := regexp.Compile("^" + globToRegexp(p) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository pattern %q: %w", p, err)
+		}
+		result = append(result, repoPattern{pattern: p, re: re})
+	}
+	return result, nil
+}
+
+// match reports whether the repository path or one of its namespaces matches the pattern
+func (p repoPattern) match(repoPath string) bool {
+	for {
+		if p.re.MatchString(repoPath) {
+			return true
+		}
+		i := strings.LastIndexByte(repoPath, '/')
+		if i < 0 {
+			return false
+		}
+		repoPath = repoPath[:i]
+	}
+}
+
+// credentialRepoPath returns the lower-cased namespace/repo path of a project URL under the
+// credential's base URL
+func credentialRepoPath(cred *models.GitCredential, projectURL string) string {
+	projectURL = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(projectURL, "/"), ".git"))
+	return strings.TrimPrefix(projectURL, credentialBaseURL(cred)+"/")
+}
+
+// checkAutoCreatePatterns applies the deny patterns of a credential, then its allow patterns
+// when it has any
+func checkAutoCreatePatterns(cred *models.GitCredential, repoPath string) error {
+	deny, err := ParseRepoPatterns(cred.DenyPatterns)
+	if err != nil {
+		return err
+	}
+	for _, p := range deny {
+		if p.match(repoPath) {
+			return fmt.Errorf("%w: %s matches deny pattern %q", ErrAutoCreateDenied, repoPath, p.pattern)
+		}
+	}
+	allow, err := ParseRepoPatterns(cred.AllowPatterns)
+	if err != nil {
+		return err
+	}
+	if len(allow) == 0 {
+		return nil
+	}
+	for _, p := range allow {
+		if p.match(repoPath) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s matches no allow pattern", ErrAutoCreateDenied, repoPath)
+}
+
+// CheckAutoCreate reports whether a credential may auto-create a project for a URL: the
+// repository must pass its allow and deny patterns, and the credential must be under its cap
+// of auto-created projects
+func (s *GitCredentialService) CheckAutoCreate(cred *models.GitCredential, projectURL string) error {
+	if err := checkAutoCreatePatterns(cred, credentialRepoPath(cred, projectURL)); err != nil {
+		return err
+	}
+	if cred.MaxAutoCreated <= 0 {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.Project{}).Where("credential_id = ?", cred.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(cred.MaxAutoCreated) {
+		return fmt.Errorf("%w: %d of %d projects", ErrAutoCreateLimit, count, cred.MaxAutoCreated)
+	}
+	return nil
+}
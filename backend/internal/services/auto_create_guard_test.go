@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestRepoPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		repoPath string
+		want     bool
+	}{
+		{"platform", "platform/api", true},
+		{"platform", "platform/infra/deploy", true},
+		{"platform", "platform-legacy/api", false},
+		{"platform/*", "platform/api", true},
+		{"platform/*", "platform/infra/deploy", true},
+		{"*/sandbox-*", "mobile/sandbox-ios", true},
+		{"*/sandbox-*", "mobile/apps/sandbox-ios", false},
+		{"**/sandbox-*", "mobile/apps/sandbox-ios", true},
+		{"Mobile/App-?", "mobile/app-1", true},
+		{"mobile/app-?", "mobile/app-10", false},
+	}
+	for _, tt := range tests {
+		patterns, err := ParseRepoPatterns(tt.pattern)
+		if err != nil || len(patterns) != 1 {
+			t.Fatalf("ParseRepoPatterns(%q) = %v, %v", tt.pattern, patterns, err)
+		}
+		if got := patterns[0].match(tt.repoPath); got != tt.want {
+			t.Errorf("%q matches %q = %v, want %v", tt.pattern, tt.repoPath, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAutoCreatePatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		allow    string
+		deny     string
+		repoPath string
+		wantErr  bool
+	}{
+		{"no patterns", "", "", "anyone/repo", false},
+		{"allowed", "platform/**, mobile", "", "mobile/app", false},
+		{"not allowed", "platform/**\nmobile", "", "personal/repo", true},
+		{"denied", "", "*/sandbox-*", "platform/sandbox-api", true},
+		{"deny wins over allow", "platform", "platform/secret", "platform/secret", true},
+		{"allowed and not denied", "platform", "platform/secret", "platform/api", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred := &models.GitCredential{AllowPatterns: tt.allow, DenyPatterns: tt.deny}
+			err := checkAutoCreatePatterns(cred, tt.repoPath)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrAutoCreateDenied)) {
+				t.Errorf("checkAutoCreatePatterns(%q) = %v, want denied %v", tt.repoPath, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentialRepoPath(t *testing.T) {
+	cred := &models.GitCredential{Platform: "gitlab", BaseURL: "https://gitlab.example.com/"}
+	if got := credentialRepoPath(cred, "https://GitLab.example.com/Platform/Infra/deploy.git"); got != "platform/infra/deploy" {
+		t.Errorf("credentialRepoPath() = %q, want platform/infra/deploy", got)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/huangang/codesentry/backend/internal/config"
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -29,7 +28,7 @@ func NewAutoFixService(db *gorm.DB, aiCfg *config.OpenAIConfig) *AutoFixService
 	return &AutoFixService{
 		db:         db,
 		aiService:  NewAIService(db, aiCfg),
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		httpClient: PlatformHTTPClient(),
 	}
 }
 
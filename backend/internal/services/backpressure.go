@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// backpressureCheckInterval is how long a measure of the review load is reused, so a burst
+	// of webhooks doesn't count the reviews for each delivery
+	backpressureCheckInterval = 5 * time.Second
+	// backpressureLoadWindow bounds the reviews counted as load; older ones stuck in progress
+	// are left to the retry scheduler
+	backpressureLoadWindow = 24 * time.Hour
+	// DeferredReplayBatch caps the deferred webhook deliveries replayed at once
+	DeferredReplayBatch = 20
+	// deferredClaimLease is how long a replica has to replay the deferred deliveries it claimed
+	// before they are handed to another one
+	deferredClaimLease = 10 * time.Minute
+	// deferredMaxAttempts is how many replays of a deferred delivery may fail before it is dropped
+	deferredMaxAttempts = 5
+)
+
+// ReviewCapacityStatus tells whether webhook deliveries are deferred because reviews are over capacity
+type ReviewCapacityStatus struct {
+	Overloaded bool       `json:"overloaded"`
+	Since      *time.Time `json:"since,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	QueueDepth int64      `json:"queue_depth"` // Reviews waiting for a worker
+	InFlight   int64      `json:"in_flight"`   // Reviews being processed
+	Deferred   int64      `json:"deferred"`    // Webhook deliveries waiting to be replayed
+	CheckedAt  *time.Time `json:"checked_at,omitempty"`
+}
+
+// reviewCapacityTracker keeps the last measure of the review load and raises a single alert
+// when it goes over capacity, and another when it is back under
+type reviewCapacityTracker struct {
+	mu     sync.Mutex
+	status ReviewCapacityStatus
+	now    func() time.Time
+	notify func(message string)
+}
+
+var reviewCapacity = &reviewCapacityTracker{now: time.Now, notify: notifyErrorBots}
+
+// GetReviewCapacityStatus returns the last measured review capacity status
+func GetReviewCapacityStatus() ReviewCapacityStatus {
+	return reviewCapacity.snapshot()
+}
+
+func (t *reviewCapacityTracker) snapshot() ReviewCapacityStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// fresh returns the last status while it is recent enough to reuse
+func (t *reviewCapacityTracker) fresh() (ReviewCapacityStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.status.CheckedAt == nil || t.now().Sub(*t.status.CheckedAt) >= backpressureCheckInterval {
+		return t.status, false
+	}
+	return t.status, true
+}
+
+// record stores a measure of the review load, reason being why it is over capacity ("" when
+// it is not), and alerts when the overload begins or ends
+func (t *reviewCapacityTracker) record(queueDepth, inFlight, deferred int64, reason string) ReviewCapacityStatus {
+	t.mu.Lock()
+	now := t.now()
+	wasOverloaded, since := t.status.Overloaded, t.status.Since
+	t.status.QueueDepth, t.status.InFlight, t.status.Deferred = queueDepth, inFlight, deferred
+	t.status.Overloaded, t.status.Reason = reason != "", reason
+	t.status.CheckedAt = &now
+	switch {
+	case reason != "" && !wasOverloaded:
+		t.status.Since = &now
+	case reason == "":
+		t.status.Since = nil
+	}
+	status := t.status
+	t.mu.Unlock()
+
+	switch {
+	case reason != "" && !wasOverloaded:
+		logger.Warnf("[Backpressure] Review capacity exceeded: %s", reason)
+		LogWarning(context.Background(), "Webhook", "CapacityExceeded", "Review capacity exceeded, webhook deliveries are deferred", nil, "", "", map[string]interface{}{
+			"queue_depth": queueDepth,
+			"in_flight":   inFlight,
+			"reason":      reason,
+		})
+		go t.notify(fmt.Sprintf(`🚦 **Review Capacity Exceeded**
+
+%s; new webhook deliveries are deferred and replayed once the backlog drains.
+**Since**: %s`, reason, now.Format("2006-01-02 15:04:05")))
+	case reason == "" && wasOverloaded:
+		duration := now.Sub(*since).Round(time.Second)
+		logger.Infof("[Backpressure] Review capacity recovered after %s", duration)
+		LogInfo(context.Background(), "Webhook", "CapacityRecovered", fmt.Sprintf("Review capacity recovered after %s", duration), nil, "", "", map[string]interface{}{
+			"queue_depth": queueDepth,
+			"in_flight":   inFlight,
+			"deferred":    deferred,
+		})
+		go t.notify(fmt.Sprintf(`✅ **Review Capacity Recovered**
+
+Reviews are back under capacity after %s; %d deferred webhook deliveries are being replayed.`, duration, deferred))
+	}
+	return status
+}
+
+// overloadReason describes which limit the review load exceeds, "" when it is under capacity.
+// A limit of 0 is not enforced.
+func overloadReason(cfg *BackpressureConfigResponse, queueDepth, inFlight int64) string {
+	var reasons []string
+	if cfg.MaxQueueDepth > 0 && queueDepth >= int64(cfg.MaxQueueDepth) {
+		reasons = append(reasons, fmt.Sprintf("%d reviews queued (limit %d)", queueDepth, cfg.MaxQueueDepth))
+	}
+	if cfg.MaxInFlight > 0 && inFlight >= int64(cfg.MaxInFlight) {
+		reasons = append(reasons, fmt.Sprintf("%d reviews in progress (limit %d)", inFlight, cfg.MaxInFlight))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// replayRoom returns how many deferred deliveries can be replayed without going over capacity
+func replayRoom(cfg *BackpressureConfigResponse, status ReviewCapacityStatus) int {
+	if status.Overloaded {
+		return 0
+	}
+	room := DeferredReplayBatch
+	if cfg.MaxQueueDepth > 0 {
+		room = min(room, cfg.MaxQueueDepth-int(status.QueueDepth))
+	}
+	if cfg.MaxInFlight > 0 {
+		room = min(room, cfg.MaxInFlight-int(status.InFlight))
+	}
+	return max(room, 0)
+}
+
+// BackpressureService decides whether webhook deliveries are processed right away or deferred
+// until the review backlog drains
+type BackpressureService struct {
+	db            *gorm.DB
+	configService *SystemConfigService
+}
+
+func NewBackpressureService(db *gorm.DB) *BackpressureService {
+	return &BackpressureService{
+		db:            db,
+		configService: NewSystemConfigService(db),
+	}
+}
+
+// Check measures the review load, reusing a recent measure, and reports whether new
+// deliveries must be deferred along with the Retry-After to send, in seconds
+func (s *BackpressureService) Check() (overloaded bool, retryAfter int) {
+	cfg := s.configService.GetBackpressureConfig()
+	if !cfg.Enabled {
+		if reviewCapacity.snapshot().Overloaded {
+			reviewCapacity.record(0, 0, 0, "")
+		}
+		return false, 0
+	}
+	status := s.status(cfg)
+	return status.Overloaded, cfg.RetryAfterSeconds
+}
+
+func (s *BackpressureService) status(cfg *BackpressureConfigResponse) ReviewCapacityStatus {
+	if status, ok := reviewCapacity.fresh(); ok {
+		return status
+	}
+
+	since := time.Now().Add(-backpressureLoadWindow)
+	var queueDepth, inFlight, deferred int64
+	if err := s.db.Model(&models.ReviewLog{}).
+		Where("review_status = ? AND created_at > ?", "pending", since).
+		Count(&queueDepth).Error; err != nil {
+		logger.Warnf("[Backpressure] Failed to count queued reviews: %v", err)
+		return reviewCapacity.snapshot()
+	}
+	if err := s.db.Model(&models.ReviewLog{}).
+		Where("review_status IN ? AND created_at > ?", []string{"processing", "analyzing"}, since).
+		Count(&inFlight).Error; err != nil {
+		logger.Warnf("[Backpressure] Failed to count reviews in progress: %v", err)
+		return reviewCapacity.snapshot()
+	}
+	s.db.Model(&models.DeferredWebhook{}).Count(&deferred)
+	return reviewCapacity.record(queueDepth, inFlight, deferred, overloadReason(cfg, queueDepth, inFlight))
+}
+
+// Defer stores a webhook delivery to process once reviews are back under capacity
+func (s *BackpressureService) Defer(ctx context.Context, projectID uint, platform, eventType string, body []byte) error {
+	return s.db.Create(&models.DeferredWebhook{
+		ProjectID: projectID,
+		Platform:  platform,
+		EventType: eventType,
+		Body:      string(body),
+		RequestID: logger.RequestID(ctx),
+	}).Error
+}
+
+// ClaimDeferred leases the oldest deferred deliveries, as many as reviews have room for. A
+// delivery is only returned to the replica whose lease took, and stays in the table until
+// CompleteDeferred, so a replica dying mid-replay leaves it to be claimed again once the lease ends.
+func (s *BackpressureService) ClaimDeferred() []models.DeferredWebhook {
+	cfg := s.configService.GetBackpressureConfig()
+	limit := DeferredReplayBatch
+	if cfg.Enabled {
+		limit = replayRoom(cfg, s.status(cfg))
+	}
+	if limit == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	var candidates []models.DeferredWebhook
+	if err := s.db.Where("claimed_until IS NULL OR claimed_until < ?", now).Order("id").Limit(limit).Find(&candidates).Error; err != nil {
+		logger.Warnf("[Backpressure] Failed to load deferred webhooks: %v", err)
+		return nil
+	}
+	until := now.Add(deferredClaimLease)
+	claimed := candidates[:0]
+	for _, d := range candidates {
+		result := s.db.Model(&models.DeferredWebhook{}).
+			Where("id = ? AND (claimed_until IS NULL OR claimed_until < ?)", d.ID, now).
+			Updates(map[string]interface{}{"claimed_until": until, "attempts": gorm.Expr("attempts + 1")})
+		if result.Error == nil && result.RowsAffected == 1 {
+			d.ClaimedUntil = &until
+			d.Attempts++
+			claimed = append(claimed, d)
+		}
+	}
+	return claimed
+}
+
+// CompleteDeferred removes a claimed delivery once its replay went through
+func (s *BackpressureService) CompleteDeferred(d *models.DeferredWebhook) error {
+	return s.db.Delete(&models.DeferredWebhook{}, d.ID).Error
+}
+
+// ReleaseDeferred hands a claimed delivery whose replay failed back for the next replay, or
+// drops it after deferredMaxAttempts failed replays and reports so
+func (s *BackpressureService) ReleaseDeferred(d *models.DeferredWebhook) (dropped bool, err error) {
+	if d.Attempts >= deferredMaxAttempts {
+		return true, s.db.Delete(&models.DeferredWebhook{}, d.ID).Error
+	}
+	return false, s.db.Model(&models.DeferredWebhook{}).Where("id = ?", d.ID).Update("claimed_until", nil).Error
+}
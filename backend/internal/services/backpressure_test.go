@@ -0,0 +1,157 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestOverloadReason(t *testing.T) {
+	cfg := &BackpressureConfigResponse{Enabled: true, MaxQueueDepth: 100, MaxInFlight: 10}
+	tests := []struct {
+		name       string
+		cfg        *BackpressureConfigResponse
+		queueDepth int64
+		inFlight   int64
+		want       string
+	}{
+		{"under capacity", cfg, 99, 9, ""},
+		{"queue full", cfg, 100, 3, "100 reviews queued (limit 100)"},
+		{"workers busy", cfg, 0, 12, "12 reviews in progress (limit 10)"},
+		{"both", cfg, 150, 10, "150 reviews queued (limit 100), 10 reviews in progress (limit 10)"},
+		{"no limits", &BackpressureConfigResponse{Enabled: true}, 5000, 500, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overloadReason(tt.cfg, tt.queueDepth, tt.inFlight); got != tt.want {
+				t.Errorf("overloadReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayRoom(t *testing.T) {
+	cfg := &BackpressureConfigResponse{Enabled: true, MaxQueueDepth: 100, MaxInFlight: 50}
+	tests := []struct {
+		name   string
+		cfg    *BackpressureConfigResponse
+		status ReviewCapacityStatus
+		want   int
+	}{
+		{"overloaded", cfg, ReviewCapacityStatus{Overloaded: true}, 0},
+		{"idle", cfg, ReviewCapacityStatus{}, DeferredReplayBatch},
+		{"queue nearly full", cfg, ReviewCapacityStatus{QueueDepth: 95}, 5},
+		{"workers nearly busy", cfg, ReviewCapacityStatus{InFlight: 47}, 3},
+		{"no limits", &BackpressureConfigResponse{Enabled: true}, ReviewCapacityStatus{QueueDepth: 1000}, DeferredReplayBatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replayRoom(tt.cfg, tt.status); got != tt.want {
+				t.Errorf("replayRoom() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewCapacityTracker(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	var sent []string
+	tracker := &reviewCapacityTracker{
+		now: func() time.Time { return now },
+		notify: func(message string) {
+			mu.Lock()
+			sent = append(sent, message)
+			mu.Unlock()
+		},
+	}
+	notified := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sent...)
+	}
+
+	if status := tracker.record(10, 2, 0, ""); status.Overloaded {
+		t.Fatalf("status = %+v, want under capacity", status)
+	}
+	if _, ok := tracker.fresh(); !ok {
+		t.Error("measure just recorded is not reused")
+	}
+	now = now.Add(backpressureCheckInterval)
+	if _, ok := tracker.fresh(); ok {
+		t.Error("measure older than the check interval is reused")
+	}
+
+	overloadedAt := now
+	tracker.record(200, 2, 1, "200 reviews queued (limit 200)")
+	now = now.Add(time.Minute)
+	status := tracker.record(250, 2, 30, "250 reviews queued (limit 200)")
+	if !status.Overloaded || status.Since == nil || !status.Since.Equal(overloadedAt) || status.Deferred != 30 {
+		t.Fatalf("status = %+v, want overloaded since %s", status, overloadedAt)
+	}
+	messages := waitNotified(t, notified, 1)
+	if !strings.Contains(messages[0], "Review Capacity Exceeded") || !strings.Contains(messages[0], "200 reviews queued") {
+		t.Errorf("alert = %q", messages[0])
+	}
+
+	now = now.Add(9 * time.Minute)
+	if status := tracker.record(50, 2, 30, ""); status.Overloaded || status.Since != nil {
+		t.Errorf("status after recovery = %+v, want under capacity", status)
+	}
+	messages = waitNotified(t, notified, 2)
+	if !strings.Contains(messages[1], "Review Capacity Recovered") || !strings.Contains(messages[1], "10m0s") {
+		t.Errorf("recovery = %q", messages[1])
+	}
+
+	// Staying under capacity doesn't notify again
+	tracker.record(10, 1, 0, "")
+	waitNotified(t, notified, 2)
+}
+
+func TestClaimDeferred_Lease(t *testing.T) {
+	db := newTestDB(t)
+	s := NewBackpressureService(db)
+	mustCreate(t, db,
+		&models.DeferredWebhook{ProjectID: 1, Platform: "gitlab", EventType: "Push Hook", Body: "{}"},
+		&models.DeferredWebhook{ProjectID: 1, Platform: "gitlab", EventType: "Push Hook", Body: "{}"},
+	)
+
+	claimed := s.ClaimDeferred()
+	if len(claimed) != 2 || claimed[0].Attempts != 1 || claimed[0].ClaimedUntil == nil {
+		t.Fatalf("ClaimDeferred() = %+v, want both deliveries leased", claimed)
+	}
+	if again := s.ClaimDeferred(); len(again) != 0 {
+		t.Errorf("ClaimDeferred() during the lease = %d deliveries, want 0", len(again))
+	}
+
+	if err := s.CompleteDeferred(&claimed[0]); err != nil {
+		t.Fatalf("CompleteDeferred() error = %v", err)
+	}
+	if dropped, err := s.ReleaseDeferred(&claimed[1]); err != nil || dropped {
+		t.Fatalf("ReleaseDeferred() = %v, %v, want released", dropped, err)
+	}
+	again := s.ClaimDeferred()
+	if len(again) != 1 || again[0].ID != claimed[1].ID || again[0].Attempts != 2 {
+		t.Fatalf("ClaimDeferred() after release = %+v, want the released delivery", again)
+	}
+
+	// An expired lease is claimed again
+	db.Model(&models.DeferredWebhook{}).Where("id = ?", again[0].ID).Update("claimed_until", time.Now().Add(-time.Minute))
+	again = s.ClaimDeferred()
+	if len(again) != 1 || again[0].Attempts != 3 {
+		t.Fatalf("ClaimDeferred() after the lease ended = %+v", again)
+	}
+
+	again[0].Attempts = deferredMaxAttempts
+	if dropped, err := s.ReleaseDeferred(&again[0]); err != nil || !dropped {
+		t.Errorf("ReleaseDeferred() at max attempts = %v, %v, want dropped", dropped, err)
+	}
+	var left int64
+	db.Model(&models.DeferredWebhook{}).Count(&left)
+	if left != 0 {
+		t.Errorf("deferred deliveries left = %d, want 0", left)
+	}
+}
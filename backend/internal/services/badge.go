@@ -0,0 +1,145 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// badgeStatsWindow is the period public badges aggregate over
+const badgeStatsWindow = 30 * 24 * time.Hour
+
+// Public badge kinds
+const (
+	BadgeScore    = "score"
+	BadgePassRate = "pass-rate"
+	BadgeLatest   = "latest" // Score of the latest review, SVG only
+)
+
+// BadgeService serves the public README badges of projects
+type BadgeService struct {
+	db *gorm.DB
+}
+
+func NewBadgeService(db *gorm.DB) *BadgeService {
+	return &BadgeService{db: db}
+}
+
+// BadgeStats aggregates the scored reviews of a project over the badge window
+type BadgeStats struct {
+	Reviews      int64
+	AverageScore float64
+	PassRate     float64 // Percentage of reviews at or above the minimum score
+	MinScore     float64
+}
+
+// ShieldsEndpoint is the response schema of shields.io endpoint badges
+type ShieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// ErrBadgeNotFound is returned for projects without public badges and for wrong tokens,
+// so callers cannot tell disabled badges from missing projects
+var ErrBadgeNotFound = errors.New("badge not found")
+
+// GetPublicProject returns a project whose badges may be served for the given token
+func (s *BadgeService) GetPublicProject(projectID uint, token string) (*models.Project, error) {
+	var project models.Project
+	if err := s.db.First(&project, projectID).Error; err != nil || !project.BadgeEnabled {
+		return nil, ErrBadgeNotFound
+	}
+	if project.BadgeToken != "" && subtle.ConstantTimeCompare([]byte(project.BadgeToken), []byte(token)) != 1 {
+		return nil, ErrBadgeNotFound
+	}
+	return &project, nil
+}
+
+// GetStats aggregates the scored reviews of the last 30 days
+func (s *BadgeService) GetStats(project *models.Project) (*BadgeStats, error) {
+	minScore := NewReviewLogService(s.db).EffectiveMinScore(project)
+
+	var row struct {
+		Reviews      int64
+		AverageScore float64
+		Passed       int64
+	}
+	err := s.db.Model(&models.ReviewLog{}).
+		Select("COUNT(*) as reviews, COALESCE(AVG(score), 0) as average_score, COALESCE(SUM(CASE WHEN score >= ? THEN 1 ELSE 0 END), 0) as passed", minScore).
+		Where("project_id = ? AND score IS NOT NULL AND revision_of IS NULL AND created_at >= ?", project.ID, time.Now().Add(-badgeStatsWindow)).
+		Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &BadgeStats{Reviews: row.Reviews, AverageScore: row.AverageScore, MinScore: minScore}
+	if row.Reviews > 0 {
+		stats.PassRate = float64(row.Passed) * 100 / float64(row.Reviews)
+	}
+	return stats, nil
+}
+
+// Badge returns the label, message and color of a badge kind
+func (st *BadgeStats) Badge(kind string) ShieldsEndpoint {
+	badge := ShieldsEndpoint{SchemaVersion: 1, Label: "codesentry", Color: strings.TrimPrefix(BadgeColorGrey, "#")}
+	if kind == BadgePassRate {
+		badge.Label = "codesentry pass rate"
+	}
+	if st.Reviews == 0 {
+		badge.Message = "no reviews"
+		return badge
+	}
+
+	color := ScoreColor(st.AverageScore, st.MinScore)
+	badge.Message = fmt.Sprintf("%.0f/100", st.AverageScore)
+	if kind == BadgePassRate {
+		color = passRateColor(st.PassRate)
+		badge.Message = fmt.Sprintf("%.0f%%", st.PassRate)
+	}
+	// shields.io accepts hex colors without the leading #
+	badge.Color = strings.TrimPrefix(color, "#")
+	return badge
+}
+
+// SVG renders the badge as an SVG image
+func (b ShieldsEndpoint) SVG() string {
+	return RenderBadgeSVG(b.Label, b.Message, "#"+b.Color)
+}
+
+func passRateColor(rate float64) string {
+	switch {
+	case rate >= 90:
+		return BadgeColorGreen
+	case rate >= 70:
+		return BadgeColorYellow
+	default:
+		return BadgeColorRed
+	}
+}
+
+// RotateToken generates a new badge token for a project, invalidating the previous one
+func (s *BadgeService) RotateToken(projectID uint) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := s.db.Model(&models.Project{}).Where("id = ?", projectID).Update("badge_token", token).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ClearToken makes the badges of a project accessible without a token
+func (s *BadgeService) ClearToken(projectID uint) error {
+	return s.db.Model(&models.Project{}).Where("id = ?", projectID).Update("badge_token", "").Error
+}
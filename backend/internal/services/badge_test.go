@@ -0,0 +1,47 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBadgeStats_Badge(t *testing.T) {
+	tests := []struct {
+		name        string
+		stats       BadgeStats
+		kind        string
+		wantLabel   string
+		wantMessage string
+		wantColor   string
+	}{
+		{"no reviews", BadgeStats{}, BadgeScore, "codesentry", "no reviews", "9f9f9f"},
+		{"high score", BadgeStats{Reviews: 3, AverageScore: 91.6, MinScore: 60}, BadgeScore, "codesentry", "92/100", "4c1"},
+		{"passing score", BadgeStats{Reviews: 3, AverageScore: 65, MinScore: 60}, BadgeScore, "codesentry", "65/100", "dfb317"},
+		{"failing score", BadgeStats{Reviews: 3, AverageScore: 40, MinScore: 60}, BadgeScore, "codesentry", "40/100", "e05d44"},
+		{"pass rate high", BadgeStats{Reviews: 10, PassRate: 95}, BadgePassRate, "codesentry pass rate", "95%", "4c1"},
+		{"pass rate medium", BadgeStats{Reviews: 10, PassRate: 75}, BadgePassRate, "codesentry pass rate", "75%", "dfb317"},
+		{"pass rate low", BadgeStats{Reviews: 10, PassRate: 50}, BadgePassRate, "codesentry pass rate", "50%", "e05d44"},
+		{"pass rate without reviews", BadgeStats{}, BadgePassRate, "codesentry pass rate", "no reviews", "9f9f9f"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			badge := tt.stats.Badge(tt.kind)
+			if badge.SchemaVersion != 1 {
+				t.Errorf("SchemaVersion = %d, want 1", badge.SchemaVersion)
+			}
+			if badge.Label != tt.wantLabel || badge.Message != tt.wantMessage || badge.Color != tt.wantColor {
+				t.Errorf("Badge() = %+v, want %s / %s / %s", badge, tt.wantLabel, tt.wantMessage, tt.wantColor)
+			}
+		})
+	}
+}
+
+func TestShieldsEndpoint_SVG(t *testing.T) {
+	svg := ShieldsEndpoint{SchemaVersion: 1, Label: "codesentry", Message: "85/100", Color: "4c1"}.SVG()
+	for _, want := range []string{"<svg", "codesentry", "85/100", `fill="#4c1"`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("svg does not contain %q: %s", want, svg)
+		}
+	}
+}
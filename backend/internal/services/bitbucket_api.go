@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Bitbucket authentication modes of a git credential
+const (
+	BitbucketAuthToken = "token" // Bearer access token: Cloud repository/workspace token or Server HTTP access token (default)
+	BitbucketAuthOAuth = "oauth" // Cloud OAuth consumer: key and secret exchanged for access tokens, refreshed before they expire
+	BitbucketAuthBasic = "basic" // HTTP basic: username with a Cloud app password, or a Server password or personal access token
+)
+
+// ValidBitbucketAuth reports whether mode is a Bitbucket authentication mode ("" is the default, token)
+func ValidBitbucketAuth(mode string) bool {
+	switch mode {
+	case "", BitbucketAuthToken, BitbucketAuthOAuth, BitbucketAuthBasic:
+		return true
+	}
+	return false
+}
+
+const bitbucketCloudHost = "bitbucket.org"
+
+// ErrBitbucketServerImport is returned when importing the history of a Bitbucket Server repository
+var ErrBitbucketServerImport = errors.New("importing commits and pull requests is only supported for Bitbucket Cloud")
+
+// bitbucketOAuthTokenURL is where Bitbucket Cloud OAuth consumers get access tokens
+var bitbucketOAuthTokenURL = "https://bitbucket.org/site/oauth2/access_token"
+
+// BitbucketAPI addresses the API of a Bitbucket repository: the 2.0 API of Bitbucket Cloud for
+// bitbucket.org, the REST 1.0 API of Bitbucket Server / Data Center for any other host
+type BitbucketAPI struct {
+	Server   bool
+	baseURL  string // Server root, with its context path
+	repoPath string // Cloud: workspace/repo, Server: projects/KEY/repos/slug
+	token    string
+	cred     *models.GitCredential // Selects the authentication, nil for a bearer token
+	client   *http.Client
+}
+
+// NewBitbucketAPI returns the API of a Bitbucket project. Requests are authenticated as
+// configured on the git credential the project was auto-created from, or else, for a project
+// of the default tenant without an access token, the active credential whose base URL the
+// project is under; without one, the project access token is sent as a bearer token.
+func NewBitbucketAPI(db *gorm.DB, client *http.Client, project *models.Project) (*BitbucketAPI, error) {
+	server, baseURL, repoPath, err := parseBitbucketRepo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = PlatformHTTPClient()
+	}
+	return &BitbucketAPI{
+		Server:   server,
+		baseURL:  baseURL,
+		repoPath: repoPath,
+		token:    project.AccessToken,
+		cred:     bitbucketCredential(db, project),
+		client:   client,
+	}, nil
+}
+
+// HasPlatformCredentials reports whether API calls for a project can be authenticated: with its
+// access token, or for Bitbucket with the OAuth consumer or basic auth of its git credential
+func HasPlatformCredentials(db *gorm.DB, project *models.Project) bool {
+	if project.AccessToken != "" {
+		return true
+	}
+	return project.Platform == "bitbucket" && bitbucketCredential(db, project) != nil
+}
+
+// bitbucketCredential returns the git credential a Bitbucket project authenticates with, if
+// any: the credential it was auto-created from, or else the active credential covering its
+// URL. Git credentials are managed by platform admins for the default tenant, so only
+// projects of that tenant without an access token of their own fall back to them.
+func bitbucketCredential(db *gorm.DB, project *models.Project) *models.GitCredential {
+	if db == nil {
+		return nil
+	}
+	if project.CredentialID != nil {
+		var cred models.GitCredential
+		if err := db.First(&cred, *project.CredentialID).Error; err != nil {
+			return nil
+		}
+		return &cred
+	}
+	if project.AccessToken != "" || !inDefaultTenant(db, project.TenantID) {
+		return nil
+	}
+	var credentials []models.GitCredential
+	if err := db.Where("platform = ? AND is_active = ?", "bitbucket", true).Order("id DESC").Find(&credentials).Error; err != nil {
+		return nil
+	}
+	return bestCredentialMatch(credentials, project.URL)
+}
+
+// parseBitbucketRepo splits the URL of a Bitbucket repository. Bitbucket Server repositories
+// are recognized from their browse URL (/projects/KEY/repos/slug, /users/name/repos/slug) or
+// clone URL (/scm/KEY/slug), below an optional context path.
+func parseBitbucketRepo(projectURL string) (server bool, baseURL, repoPath string, err error) {
+	u, err := url.Parse(strings.TrimSuffix(strings.TrimSuffix(projectURL, "/"), ".git"))
+	if err != nil || u.Host == "" {
+		return false, "", "", fmt.Errorf("invalid Bitbucket repository URL: %s", projectURL)
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if strings.EqualFold(u.Hostname(), bitbucketCloudHost) {
+		if len(parts) < 2 {
+			return false, "", "", fmt.Errorf("invalid Bitbucket repository URL (need workspace/repo): %s", projectURL)
+		}
+		return false, "https://" + bitbucketCloudHost, parts[0] + "/" + parts[1], nil
+	}
+
+	root := u.Scheme + "://" + u.Host
+	for i := 0; i+2 < len(parts); i++ {
+		contextPath := ""
+		if i > 0 {
+			contextPath = "/" + strings.Join(parts[:i], "/")
+		}
+		switch {
+		case parts[i] == "scm":
+			// Project keys are upper case, clone URLs lower case them; personal ones are ~user
+			key := parts[i+1]
+			if !strings.HasPrefix(key, "~") {
+				key = strings.ToUpper(key)
+			}
+			return true, root + contextPath, "projects/" + key + "/repos/" + parts[i+2], nil
+		case (parts[i] == "projects" || parts[i] == "users") && i+3 < len(parts) && parts[i+2] == "repos":
+			key := parts[i+1]
+			if parts[i] == "users" {
+				key = "~" + key
+			}
+			return true, root + contextPath, "projects/" + key + "/repos/" + parts[i+3], nil
+		}
+	}
+	return false, "", "", fmt.Errorf("invalid Bitbucket Server repository URL (expected /projects/KEY/repos/slug or /scm/KEY/slug): %s", projectURL)
+}
+
+func (a *BitbucketAPI) repoURL() string {
+	if a.Server {
+		return a.baseURL + "/rest/api/1.0/" + a.repoPath
+	}
+	return "https://api.bitbucket.org/2.0/repositories/" + a.repoPath
+}
+
+// DefaultBranchURL returns the URL of the default branch: the repository on Cloud (mainbranch.name),
+// the default branch on Server (displayId)
+func (a *BitbucketAPI) DefaultBranchURL() string {
+	if a.Server {
+		return a.repoURL() + "/branches/default"
+	}
+	return a.repoURL()
+}
+
+// CommitURL returns the API URL of a commit
+func (a *BitbucketAPI) CommitURL(ref string) string {
+	if a.Server {
+		return a.repoURL() + "/commits/" + url.PathEscape(ref)
+	}
+	return a.repoURL() + "/commit/" + url.PathEscape(ref)
+}
+
+// CommitWebURL returns the URL of a commit in the Bitbucket UI
+func (a *BitbucketAPI) CommitWebURL(sha string) string {
+	return a.baseURL + "/" + a.repoPath + "/commits/" + sha
+}
+
+// CommitDiffURL returns the URL of the unified diff of a commit against its parent
+func (a *BitbucketAPI) CommitDiffURL(sha string) string {
+	if a.Server {
+		return a.repoURL() + "/patch?until=" + url.QueryEscape(sha)
+	}
+	return a.repoURL() + "/diff/" + sha
+}
+
+// CompareDiffURL returns the URL of the unified diff between two commits
+func (a *BitbucketAPI) CompareDiffURL(from, to string) string {
+	if a.Server {
+		return a.repoURL() + "/patch?since=" + url.QueryEscape(from) + "&until=" + url.QueryEscape(to)
+	}
+	return fmt.Sprintf("%s/diff/%s..%s", a.repoURL(), from, to)
+}
+
+// PullRequestDiffURL returns the URL of the unified diff of a pull request
+func (a *BitbucketAPI) PullRequestDiffURL(number int) string {
+	if a.Server {
+		return fmt.Sprintf("%s/pull-requests/%d.diff", a.repoURL(), number)
+	}
+	return fmt.Sprintf("%s/pullrequests/%d/diff", a.repoURL(), number)
+}
+
+// CommitsURL returns the URL of the first page of the commits of a Bitbucket Cloud repository
+func (a *BitbucketAPI) CommitsURL() string {
+	return a.repoURL() + "/commits?pagelen=50"
+}
+
+// PullRequestsURL returns the URL of the first page of the pull requests of a Bitbucket Cloud
+// repository in any state, newest first
+func (a *BitbucketAPI) PullRequestsURL() string {
+	return a.repoURL() + "/pullrequests?state=OPEN&state=MERGED&state=DECLINED&state=SUPERSEDED&sort=-created_on&pagelen=50"
+}
+
+// CommitStatusURL returns the URL build statuses of a commit are posted to
+func (a *BitbucketAPI) CommitStatusURL(sha string) string {
+	if a.Server {
+		return a.baseURL + "/rest/build-status/1.0/commits/" + sha
+	}
+	return a.repoURL() + "/commit/" + sha + "/statuses/build"
+}
+
+// CommitCommentURL returns the URL comments on a commit are posted to
+func (a *BitbucketAPI) CommitCommentURL(sha string) string {
+	if a.Server {
+		return a.repoURL() + "/commits/" + sha + "/comments"
+	}
+	return a.repoURL() + "/commit/" + sha + "/comments"
+}
+
+// PullRequestCommentURL returns the URL comments on a pull request are posted to
+func (a *BitbucketAPI) PullRequestCommentURL(number int) string {
+	if a.Server {
+		return fmt.Sprintf("%s/pull-requests/%d/comments", a.repoURL(), number)
+	}
+	return fmt.Sprintf("%s/pullrequests/%d/comments", a.repoURL(), number)
+}
+
+// CommentBody returns the JSON body of a comment
+func (a *BitbucketAPI) CommentBody(comment string) interface{} {
+	if a.Server {
+		return map[string]string{"text": comment}
+	}
+	return map[string]interface{}{"content": map[string]string{"raw": comment}}
+}
+
+// FileURL returns the URL of the raw content of a file at a ref
+func (a *BitbucketAPI) FileURL(filePath, ref string) string {
+	if a.Server {
+		return a.repoURL() + "/raw/" + filePath + "?at=" + url.QueryEscape(ref)
+	}
+	return a.repoURL() + "/src/" + ref + "/" + filePath
+}
+
+// Authorization returns the Authorization header of API requests, "" when there are no credentials
+func (a *BitbucketAPI) Authorization(ctx context.Context) (string, error) {
+	mode := BitbucketAuthToken
+	if a.cred != nil && a.cred.BitbucketAuth != "" {
+		mode = a.cred.BitbucketAuth
+	}
+	switch mode {
+	case BitbucketAuthOAuth:
+		if a.Server {
+			return "", fmt.Errorf("OAuth consumers are only supported by Bitbucket Cloud")
+		}
+		token, err := bitbucketOAuth.token(ctx, a.client, a.cred)
+		if err != nil {
+			return "", err
+		}
+		return "Bearer " + token, nil
+	case BitbucketAuthBasic:
+		password := a.token
+		if password == "" {
+			password = a.cred.AccessToken
+		}
+		password, err := ResolveSecret(ctx, password)
+		if err != nil {
+			return "", err
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.cred.Username+":"+password)), nil
+	}
+	if a.token == "" {
+		return "", nil
+	}
+	return "Bearer " + a.token, nil
+}
+
+// Authorize sets the Authorization header of an API request
+func (a *BitbucketAPI) Authorize(req *http.Request) error {
+	auth, err := a.Authorization(req.Context())
+	if err != nil {
+		return err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	return nil
+}
+
+// GetText fetches a plain text resource of the API, such as a diff
+func (a *BitbucketAPI) GetText(ctx context.Context, apiURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := a.Authorize(req); err != nil {
+		return "", err
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Bitbucket API returned %d: %s", resp.StatusCode, truncateRunes(string(body), 200))
+	}
+	return string(body), nil
+}
+
+// bitbucketOAuthToken is an access token of an OAuth consumer
+type bitbucketOAuthToken struct {
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+}
+
+// bitbucketOAuthTokens caches the access tokens of OAuth consumers, by credential and consumer key
+type bitbucketOAuthTokens struct {
+	mu     sync.Mutex
+	tokens map[string]*bitbucketOAuthToken
+	now    func() time.Time
+}
+
+var bitbucketOAuth = &bitbucketOAuthTokens{tokens: map[string]*bitbucketOAuthToken{}, now: time.Now}
+
+// bitbucketTokenLeeway is how long before it expires an access token is refreshed
+const bitbucketTokenLeeway = time.Minute
+
+// token returns a valid access token of the credential's consumer: the cached one, a refreshed
+// one once it is about to expire, or a new one from the client credentials grant
+func (c *bitbucketOAuthTokens) token(ctx context.Context, client *http.Client, cred *models.GitCredential) (string, error) {
+	if cred.OAuthKey == "" || cred.OAuthSecret == "" {
+		return "", fmt.Errorf("git credential %d has no OAuth consumer key and secret", cred.ID)
+	}
+	key := fmt.Sprintf("%d:%s", cred.ID, cred.OAuthKey)
+
+	c.mu.Lock()
+	cached := c.tokens[key]
+	c.mu.Unlock()
+	if cached != nil && c.now().Add(bitbucketTokenLeeway).Before(cached.expiresAt) {
+		return cached.accessToken, nil
+	}
+
+	// Token requests run without the lock so a slow token endpoint does not hold up the other
+	// credentials; concurrent refreshes of one credential each get a valid token
+	var token *bitbucketOAuthToken
+	var err error
+	if cached != nil && cached.refreshToken != "" {
+		token, err = c.request(ctx, client, cred, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {cached.refreshToken}})
+	}
+	if token == nil {
+		token, err = c.request(ctx, client, cred, url.Values{"grant_type": {"client_credentials"}})
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		if c.tokens[key] == cached {
+			delete(c.tokens, key)
+		}
+		return "", err
+	}
+	c.tokens[key] = token
+	return token.accessToken, nil
+}
+
+func (c *bitbucketOAuthTokens) request(ctx context.Context, client *http.Client, cred *models.GitCredential, form url.Values) (*bitbucketOAuthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", bitbucketOAuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	secret, err := ResolveSecret(ctx, cred.OAuthSecret)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(cred.OAuthKey, secret)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Bitbucket OAuth token request (%s) returned %d: %s", form.Get("grant_type"), resp.StatusCode, truncateRunes(string(body), 200))
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	if result.AccessToken == "" {
+		return nil, fmt.Errorf("Bitbucket OAuth token response has no access token")
+	}
+	return &bitbucketOAuthToken{
+		accessToken:  result.AccessToken,
+		refreshToken: result.RefreshToken,
+		expiresAt:    c.now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// forget drops the cached access tokens of a credential, whose consumer may have changed
+func (c *bitbucketOAuthTokens) forget(credentialID uint) {
+	prefix := fmt.Sprintf("%d:", credentialID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.tokens {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.tokens, key)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestParseBitbucketRepo(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		server   bool
+		baseURL  string
+		repoPath string
+		wantErr  bool
+	}{
+		{"cloud", "https://bitbucket.org/team/repo.git", false, "https://bitbucket.org", "team/repo", false},
+		{"cloud browse", "https://bitbucket.org/team/repo/src/main/", false, "https://bitbucket.org", "team/repo", false},
+		{"cloud without repo", "https://bitbucket.org/team", false, "", "", true},
+		{"server browse", "https://git.example.com/projects/PRJ/repos/repo/browse", true, "https://git.example.com", "projects/PRJ/repos/repo", false},
+		{"server personal", "https://git.example.com/users/alice/repos/repo", true, "https://git.example.com", "projects/~alice/repos/repo", false},
+		{"server clone", "https://git.example.com/scm/prj/repo.git", true, "https://git.example.com", "projects/PRJ/repos/repo", false},
+		{"server personal clone", "https://git.example.com/scm/~alice/repo.git", true, "https://git.example.com", "projects/~alice/repos/repo", false},
+		{"server context path", "https://example.com:7990/bitbucket/projects/PRJ/repos/repo", true, "https://example.com:7990/bitbucket", "projects/PRJ/repos/repo", false},
+		{"server without project", "https://git.example.com/team/repo", false, "", "", true},
+		{"no host", "team/repo", false, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, baseURL, repoPath, err := parseBitbucketRepo(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBitbucketRepo(%q) error = %v, want error %v", tt.url, err, tt.wantErr)
+			}
+			if server != tt.server || baseURL != tt.baseURL || repoPath != tt.repoPath {
+				t.Errorf("parseBitbucketRepo(%q) = %v, %q, %q, want %v, %q, %q", tt.url, server, baseURL, repoPath, tt.server, tt.baseURL, tt.repoPath)
+			}
+		})
+	}
+}
+
+func TestBitbucketAPIURLs(t *testing.T) {
+	cloud, _ := NewBitbucketAPI(nil, http.DefaultClient, &models.Project{URL: "https://bitbucket.org/team/repo"})
+	server, _ := NewBitbucketAPI(nil, http.DefaultClient, &models.Project{URL: "https://git.example.com/scm/prj/repo.git"})
+	tests := []struct {
+		got, want string
+	}{
+		{cloud.CommitDiffURL("abc"), "https://api.bitbucket.org/2.0/repositories/team/repo/diff/abc"},
+		{server.CommitDiffURL("abc"), "https://git.example.com/rest/api/1.0/projects/PRJ/repos/repo/patch?until=abc"},
+		{cloud.CompareDiffURL("a", "b"), "https://api.bitbucket.org/2.0/repositories/team/repo/diff/a..b"},
+		{server.CompareDiffURL("a", "b"), "https://git.example.com/rest/api/1.0/projects/PRJ/repos/repo/patch?since=a&until=b"},
+		{server.PullRequestDiffURL(7), "https://git.example.com/rest/api/1.0/projects/PRJ/repos/repo/pull-requests/7.diff"},
+		{cloud.CommitStatusURL("abc"), "https://api.bitbucket.org/2.0/repositories/team/repo/commit/abc/statuses/build"},
+		{server.CommitStatusURL("abc"), "https://git.example.com/rest/build-status/1.0/commits/abc"},
+		{server.CommitWebURL("abc"), "https://git.example.com/projects/PRJ/repos/repo/commits/abc"},
+		{server.FileURL("src/main.go", "main"), "https://git.example.com/rest/api/1.0/projects/PRJ/repos/repo/raw/src/main.go?at=main"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestBitbucketAuthorization(t *testing.T) {
+	basic := func(user, password string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	}
+	tests := []struct {
+		name    string
+		url     string
+		token   string
+		cred    *models.GitCredential
+		want    string
+		wantErr bool
+	}{
+		{"project token", "https://bitbucket.org/team/repo", "tok", nil, "Bearer tok", false},
+		{"no credentials", "https://bitbucket.org/team/repo", "", nil, "", false},
+		{"token mode", "https://git.example.com/scm/prj/repo", "tok", &models.GitCredential{BitbucketAuth: BitbucketAuthToken}, "Bearer tok", false},
+		{"basic with project token", "https://git.example.com/scm/prj/repo", "pat", &models.GitCredential{BitbucketAuth: BitbucketAuthBasic, Username: "ci", AccessToken: "cred"}, basic("ci", "pat"), false},
+		{"basic with credential token", "https://bitbucket.org/team/repo", "", &models.GitCredential{BitbucketAuth: BitbucketAuthBasic, Username: "ci", AccessToken: "app-password"}, basic("ci", "app-password"), false},
+		{"oauth on server", "https://git.example.com/scm/prj/repo", "", &models.GitCredential{BitbucketAuth: BitbucketAuthOAuth, OAuthKey: "k", OAuthSecret: "s"}, "", true},
+		{"oauth without consumer", "https://bitbucket.org/team/repo", "", &models.GitCredential{BitbucketAuth: BitbucketAuthOAuth}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, err := NewBitbucketAPI(nil, http.DefaultClient, &models.Project{URL: tt.url, AccessToken: tt.token})
+			if err != nil {
+				t.Fatal(err)
+			}
+			api.cred = tt.cred
+			got, err := api.Authorization(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Authorization() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBitbucketOAuthTokens(t *testing.T) {
+	var grants []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, secret, _ := r.BasicAuth(); key != "key" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+		grant := r.PostForm.Get("grant_type")
+		grants = append(grants, grant)
+		if grant == "refresh_token" && r.PostForm.Get("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"access-%d","refresh_token":"refresh-%d","expires_in":7200}`, len(grants), len(grants))
+	}))
+	defer srv.Close()
+	defer func(u string) { bitbucketOAuthTokenURL = u }(bitbucketOAuthTokenURL)
+	bitbucketOAuthTokenURL = srv.URL
+
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	cache := &bitbucketOAuthTokens{tokens: map[string]*bitbucketOAuthToken{}, now: func() time.Time { return now }}
+	cred := &models.GitCredential{ID: 1, OAuthKey: "key", OAuthSecret: "secret"}
+	token := func() string {
+		t.Helper()
+		got, err := cache.token(context.Background(), srv.Client(), cred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := token(); got != "access-1" {
+		t.Errorf("first token = %q, want access-1", got)
+	}
+	now = now.Add(time.Hour)
+	if got := token(); got != "access-1" {
+		t.Errorf("cached token = %q, want access-1", got)
+	}
+	now = now.Add(time.Hour - bitbucketTokenLeeway/2)
+	if got := token(); got != "access-2" {
+		t.Errorf("token about to expire = %q, want refreshed access-2", got)
+	}
+	if want := []string{"client_credentials", "refresh_token"}; fmt.Sprint(grants) != fmt.Sprint(want) {
+		t.Errorf("grants = %v, want %v", grants, want)
+	}
+
+	cache.forget(cred.ID)
+	if got := token(); got != "access-3" || grants[len(grants)-1] != "client_credentials" {
+		t.Errorf("token after forget = %q (grants %v), want a new client credentials token", got, grants)
+	}
+
+	cred.OAuthSecret = "wrong"
+	cache.forget(cred.ID)
+	if _, err := cache.token(context.Background(), srv.Client(), cred); err == nil {
+		t.Error("token with a wrong secret succeeded")
+	}
+}
+
+func TestBitbucketCredential(t *testing.T) {
+	db := newTestDB(t)
+	defaultTenant := &models.Tenant{Name: "Default", Slug: models.DefaultTenantSlug}
+	otherTenant := &models.Tenant{Name: "Other", Slug: "other"}
+	mustCreate(t, db, defaultTenant, otherTenant)
+	shared := &models.GitCredential{Name: "shared", Platform: "bitbucket", BitbucketAuth: BitbucketAuthBasic, Username: "bot", IsActive: true}
+	own := &models.GitCredential{Name: "own", Platform: "bitbucket", BitbucketAuth: BitbucketAuthOAuth, IsActive: true}
+	mustCreate(t, db, own, shared)
+
+	repoURL := "https://bitbucket.org/team/repo"
+	tests := []struct {
+		name    string
+		project models.Project
+		want    string // Credential name, "" for none
+	}{
+		{"auto-created project uses its credential", models.Project{URL: repoURL, TenantID: otherTenant.ID, AccessToken: "token", CredentialID: &own.ID}, "own"},
+		{"default tenant without token falls back", models.Project{URL: repoURL, TenantID: defaultTenant.ID}, "shared"},
+		{"unassigned project falls back", models.Project{URL: repoURL}, "shared"},
+		{"project with its own token does not fall back", models.Project{URL: repoURL, TenantID: defaultTenant.ID, AccessToken: "token"}, ""},
+		{"other tenant does not fall back", models.Project{URL: repoURL, TenantID: otherTenant.ID}, ""},
+		{"project outside the credential's host", models.Project{URL: "https://bitbucket.example.com/scm/key/repo", TenantID: defaultTenant.ID}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.project.Platform = "bitbucket"
+			got := ""
+			if cred := bitbucketCredential(db, &tt.project); cred != nil {
+				got = cred.Name
+			}
+			if got != tt.want {
+				t.Errorf("bitbucketCredential() = %q, want %q", got, tt.want)
+			}
+			if has := HasPlatformCredentials(db, &tt.project); has != (tt.want != "" || tt.project.AccessToken != "") {
+				t.Errorf("HasPlatformCredentials() = %v", has)
+			}
+		})
+	}
+}
+
+func TestBitbucketOAuthTokens_NoLockAcrossRequests(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, _, _ := r.BasicAuth(); key == "slow" {
+			<-release
+		}
+		fmt.Fprint(w, `{"access_token":"access","expires_in":7200}`)
+	}))
+	defer srv.Close()
+	defer close(release)
+	defer func(u string) { bitbucketOAuthTokenURL = u }(bitbucketOAuthTokenURL)
+	bitbucketOAuthTokenURL = srv.URL
+
+	cache := &bitbucketOAuthTokens{tokens: map[string]*bitbucketOAuthToken{}, now: time.Now}
+	go cache.token(context.Background(), srv.Client(), &models.GitCredential{ID: 1, OAuthKey: "slow", OAuthSecret: "secret"})
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.token(context.Background(), srv.Client(), &models.GitCredential{ID: 2, OAuthKey: "fast", OAuthSecret: "secret"})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("token of one credential waited for the token request of another")
+	}
+}
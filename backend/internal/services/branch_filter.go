@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Branch filter modes of a project
+const (
+	BranchFilterModeIgnore = "ignore" // Review all branches except the listed ones (default)
+	BranchFilterModeAllow  = "allow"  // Review only the listed branches
+)
+
+// ShouldReviewBranch reports whether events on branch are reviewed for project.
+// BranchFilter is a comma separated list of branch patterns (main, release/*, *-hotfix);
+// BranchFilterMode selects whether matching branches are ignored or the only ones reviewed.
+// An empty allow-list reviews every branch.
+func ShouldReviewBranch(project *models.Project, branch string) bool {
+	matched, empty := matchBranchFilter(project.BranchFilter, branch)
+	if empty {
+		return true
+	}
+	if project.BranchFilterMode == BranchFilterModeAllow {
+		return matched
+	}
+	return !matched
+}
+
+// ShouldReviewMergeRequest reports whether a merge request from source into target is
+// reviewed for project. An allow-list names the branches merged into, so it is matched
+// against target; an ignore list skips merge requests from the listed branches.
+func ShouldReviewMergeRequest(project *models.Project, source, target string) bool {
+	if project.BranchFilterMode == BranchFilterModeAllow {
+		return ShouldReviewBranch(project, target)
+	}
+	return ShouldReviewBranch(project, source)
+}
+
+// matchBranchFilter reports whether branch matches one of the comma separated patterns,
+// and whether the filter has no patterns at all
+func matchBranchFilter(filter, branch string) (matched bool, empty bool) {
+	empty = true
+	for _, pattern := range strings.Split(filter, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		empty = false
+		if matchBranchPattern(pattern, branch) {
+			return true, false
+		}
+	}
+	return false, empty
+}
+
+// ValidBranchFilterMode reports whether mode is a known branch filter mode; empty means the default
+func ValidBranchFilterMode(mode string) bool {
+	return mode == "" || mode == BranchFilterModeIgnore || mode == BranchFilterModeAllow
+}
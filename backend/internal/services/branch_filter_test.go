@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestShouldReviewBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		mode   string
+		branch string
+		want   bool
+	}{
+		{"no filter", "", "", "feature/x", true},
+		{"ignore exact", "main,master", "", "main", false},
+		{"ignore other branch", "main,master", "ignore", "develop", true},
+		{"ignore prefix", "wip/*", "", "wip/a/b", false},
+		{"ignore suffix glob", "*-draft", "", "login-draft", false},
+		{"allow exact", "main, release/*, hotfix/*", "allow", "main", true},
+		{"allow prefix", "main, release/*, hotfix/*", "allow", "release/2.1", true},
+		{"allow rejects others", "main, release/*, hotfix/*", "allow", "feature/login", false},
+		{"allow glob", "v[0-9]*", "allow", "v2", true},
+		{"empty allow-list reviews all", " , ", "allow", "feature/login", true},
+	}
+
+	for _, tt := range tests {
+		project := &models.Project{BranchFilter: tt.filter, BranchFilterMode: tt.mode}
+		if got := ShouldReviewBranch(project, tt.branch); got != tt.want {
+			t.Errorf("%s: ShouldReviewBranch(%q) = %v, want %v", tt.name, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestShouldReviewMergeRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		source string
+		target string
+		want   bool
+	}{
+		{"allow matches target", "allow", "feature/login", "main", true},
+		{"allow rejects other target", "allow", "main", "develop", false},
+		{"ignore matches source", "ignore", "main", "develop", false},
+		{"ignore reviews other source", "", "feature/login", "main", true},
+	}
+
+	for _, tt := range tests {
+		project := &models.Project{BranchFilter: "main", BranchFilterMode: tt.mode}
+		if got := ShouldReviewMergeRequest(project, tt.source, tt.target); got != tt.want {
+			t.Errorf("%s: ShouldReviewMergeRequest(%q, %q) = %v, want %v", tt.name, tt.source, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestValidBranchFilterMode(t *testing.T) {
+	for _, mode := range []string{"", "ignore", "allow"} {
+		if !ValidBranchFilterMode(mode) {
+			t.Errorf("ValidBranchFilterMode(%q) = false, want true", mode)
+		}
+	}
+	if ValidBranchFilterMode("deny") {
+		t.Error("ValidBranchFilterMode(\"deny\") = true, want false")
+	}
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"gorm.io/gorm"
+)
+
+// Branch kinds of a review in branch health statistics
+const (
+	BranchKindDefault   = "default"   // Push to the project's default branch
+	BranchKindFeature   = "feature"   // Push to another branch, or a merge request
+	BranchKindUntracked = "untracked" // Review of a project whose default branch is not known yet
+)
+
+// branchKindSQL classifies review logs joined with their project by branch kind
+const branchKindSQL = "CASE WHEN projects.default_branch IS NULL OR projects.default_branch = '' THEN '" + BranchKindUntracked + "' " +
+	"WHEN review_logs.event_type = 'push' AND review_logs.branch = projects.default_branch THEN '" + BranchKindDefault + "' " +
+	"ELSE '" + BranchKindFeature + "' END"
+
+// BranchStats summarizes the reviews of one branch kind
+type BranchStats struct {
+	Reviews  int64   `json:"reviews"`
+	Scored   int64   `json:"scored"`
+	Passed   int64   `json:"passed"`
+	AvgScore float64 `json:"avg_score"`
+	PassRate float64 `json:"pass_rate"` // Percentage of the scored reviews that passed
+}
+
+// BranchHealth separates pushes to the default branch from feature branch work, whose
+// work-in-progress scores would otherwise skew the pass rate of the main line
+type BranchHealth struct {
+	DefaultBranch   BranchStats `json:"default_branch"`   // Pushes to the project's default branch
+	FeatureBranches BranchStats `json:"feature_branches"` // Pushes to other branches and merge requests
+	Untracked       int64       `json:"untracked"`        // Reviews of projects whose default branch is not known yet
+}
+
+type BranchHealthRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"` // Comma-separated project group
+	TenantID   uint   `form:"-"`           // Set from the request context, 0 = all tenants
+}
+
+// GetBranchHealth returns the review statistics of default-branch pushes and feature
+// branches, over the last 30 days by default
+func (s *DashboardService) GetBranchHealth(req *BranchHealthRequest) (*BranchHealth, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 30)
+	query := s.scopeReviewLogs(startDate, endDate, parseProjectIDs(req.ProjectID, req.ProjectIDs), req.TenantID)
+	return collectBranchHealth(query, "(CASE WHEN projects.min_score > 0 THEN projects.min_score ELSE ? END)", s.globalMinScore())
+}
+
+// collectBranchHealth aggregates the reviews of query, which must join their projects, by
+// branch kind; reviews scoring at least passSQL pass
+func collectBranchHealth(query *gorm.DB, passSQL string, passArgs ...interface{}) (*BranchHealth, error) {
+	var rows []branchKindRow
+	err := query.
+		Select(branchKindSQL+" as kind, COUNT(*) as reviews, "+
+			"COUNT(review_logs.score) as scored, "+
+			"COALESCE(SUM(CASE WHEN review_logs.score >= "+passSQL+" THEN 1 ELSE 0 END), 0) as passed, "+
+			"COALESCE(AVG(review_logs.score), 0) as avg_score", passArgs...).
+		Group(branchKindSQL).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return buildBranchHealth(rows), nil
+}
+
+// branchKindRow is a row of collectBranchHealth
+type branchKindRow struct {
+	Kind string
+	BranchStats
+}
+
+// buildBranchHealth assembles the per-kind rows of collectBranchHealth
+func buildBranchHealth(rows []branchKindRow) *BranchHealth {
+	health := &BranchHealth{}
+	for _, row := range rows {
+		stats := row.BranchStats
+		if stats.Scored > 0 {
+			stats.PassRate = float64(stats.Passed) / float64(stats.Scored) * 100
+		}
+		switch row.Kind {
+		case BranchKindDefault:
+			health.DefaultBranch = stats
+		case BranchKindFeature:
+			health.FeatureBranches = stats
+		default:
+			health.Untracked = stats.Reviews
+		}
+	}
+	return health
+}
@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestBuildBranchHealth(t *testing.T) {
+	health := buildBranchHealth([]branchKindRow{
+		{Kind: BranchKindDefault, BranchStats: BranchStats{Reviews: 10, Scored: 8, Passed: 6, AvgScore: 82}},
+		{Kind: BranchKindFeature, BranchStats: BranchStats{Reviews: 30, Scored: 25, Passed: 10, AvgScore: 61}},
+		{Kind: BranchKindUntracked, BranchStats: BranchStats{Reviews: 4, Scored: 4, Passed: 4}},
+	})
+
+	if health.DefaultBranch.Reviews != 10 || health.DefaultBranch.PassRate != 75 {
+		t.Errorf("DefaultBranch = %+v, want 10 reviews at 75%%", health.DefaultBranch)
+	}
+	if health.FeatureBranches.Reviews != 30 || health.FeatureBranches.PassRate != 40 {
+		t.Errorf("FeatureBranches = %+v, want 30 reviews at 40%%", health.FeatureBranches)
+	}
+	if health.Untracked != 4 {
+		t.Errorf("Untracked = %d, want 4", health.Untracked)
+	}
+
+	empty := buildBranchHealth([]branchKindRow{{Kind: BranchKindDefault, BranchStats: BranchStats{Reviews: 2}}})
+	if empty.DefaultBranch.PassRate != 0 {
+		t.Errorf("PassRate without scored reviews = %v, want 0", empty.DefaultBranch.PassRate)
+	}
+}
+
+func TestDecodeBranchHealth(t *testing.T) {
+	if decodeBranchHealth("") != nil || decodeBranchHealth("{") != nil {
+		t.Error("decodeBranchHealth() of empty or invalid JSON should be nil")
+	}
+	health := decodeBranchHealth(`{"default_branch":{"reviews":3,"pass_rate":66.7},"untracked":1}`)
+	if health == nil || health.DefaultBranch.Reviews != 3 || health.Untracked != 1 {
+		t.Errorf("decodeBranchHealth() = %+v", health)
+	}
+}
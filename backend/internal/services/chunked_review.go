@@ -31,15 +31,17 @@ type ChunkedReviewResult struct {
 	Score        float64
 	BatchCount   int
 	BatchResults []BatchResult
+	ScoreMissing bool // No batch had a parseable score
 }
 
 // BatchResult represents the result from a single batch review
 type BatchResult struct {
-	BatchIndex int
-	Files      []string
-	Score      float64
-	Content    string
-	Weight     int // Weight for aggregation (based on additions + deletions)
+	BatchIndex   int
+	Files        []string
+	Score        float64
+	Content      string
+	Weight       int  // Weight for aggregation (based on additions + deletions)
+	ScoreMissing bool // The batch review had no parseable score and is left out of the aggregated score
 }
 
 // ParseDiffToFiles splits a unified diff string into individual file diffs
@@ -191,8 +193,10 @@ func AggregateResults(results []BatchResult) *ChunkedReviewResult {
 		if weight <= 0 {
 			weight = 1 // Minimum weight
 		}
-		totalWeight += weight
-		weightedScoreSum += result.Score * float64(weight)
+		if !result.ScoreMissing {
+			totalWeight += weight
+			weightedScoreSum += result.Score * float64(weight)
+		}
 
 		// Build combined content
 		if i > 0 {
@@ -212,7 +216,11 @@ func AggregateResults(results []BatchResult) *ChunkedReviewResult {
 	var summaryBuilder strings.Builder
 	summaryBuilder.WriteString("# Chunked Code Review Summary\n\n")
 	summaryBuilder.WriteString(fmt.Sprintf("**Total Batches:** %d\n", len(results)))
-	summaryBuilder.WriteString(fmt.Sprintf("**Aggregated Score:** %.0f/100\n\n", finalScore))
+	if totalWeight > 0 {
+		summaryBuilder.WriteString(fmt.Sprintf("**Aggregated Score:** %.0f/100\n\n", finalScore))
+	} else {
+		summaryBuilder.WriteString("**Aggregated Score:** n/a, no batch review has a score\n\n")
+	}
 
 	// List all files reviewed
 	summaryBuilder.WriteString("**All Files Reviewed:**\n")
@@ -231,6 +239,7 @@ func AggregateResults(results []BatchResult) *ChunkedReviewResult {
 		Score:        finalScore,
 		BatchCount:   len(results),
 		BatchResults: results,
+		ScoreMissing: totalWeight == 0,
 	}
 }
 
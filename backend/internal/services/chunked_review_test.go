@@ -256,6 +256,15 @@ func TestAggregateResults(t *testing.T) {
 			expectedScore: 80,
 			expectedCount: 1,
 		},
+		{
+			name: "batch without score left out",
+			results: []BatchResult{
+				{BatchIndex: 0, Score: 90, Weight: 10, Files: []string{"a.go"}, Content: "Good"},
+				{BatchIndex: 1, Score: 0, Weight: 30, Files: []string{"b.go"}, Content: "No score", ScoreMissing: true},
+			},
+			expectedScore: 90,
+			expectedCount: 2,
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,6 +280,18 @@ func TestAggregateResults(t *testing.T) {
 	}
 }
 
+func TestAggregateResults_NoScores(t *testing.T) {
+	aggregated := AggregateResults([]BatchResult{
+		{BatchIndex: 0, Weight: 10, Files: []string{"a.go"}, Content: "No score", ScoreMissing: true},
+	})
+	if !aggregated.ScoreMissing {
+		t.Error("ScoreMissing should be set when no batch has a score")
+	}
+	if _, pattern := ExplainScore(aggregated.Content); pattern != "" {
+		t.Errorf("aggregated content should carry no score, matched %s", pattern)
+	}
+}
+
 func TestAggregateResults_ContentFormat(t *testing.T) {
 	results := []BatchResult{
 		{BatchIndex: 0, Score: 80, Weight: 10, Files: []string{"a.go", "b.go"}, Content: "Review 1"},
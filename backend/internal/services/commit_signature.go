@@ -0,0 +1,75 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Commit signature policies of a project
+const (
+	SignaturePolicyOff      = "off"      // Commit signatures are not checked (default)
+	SignaturePolicyAnnotate = "annotate" // Unsigned commits are listed in the review
+	SignaturePolicyEnforce  = "enforce"  // Unsigned commits also fail the commit status on SignedBranches
+)
+
+// ValidSignaturePolicy reports whether policy is a known signature policy; empty means the default
+func ValidSignaturePolicy(policy string) bool {
+	return policy == "" || policy == SignaturePolicyOff || policy == SignaturePolicyAnnotate || policy == SignaturePolicyEnforce
+}
+
+// SignatureCheck is the result of verifying the commit signatures of a review
+type SignatureCheck struct {
+	Checked    int
+	Unsigned   []string // SHAs of commits without a verified signature
+	Unverified string   // Why the signatures could not all be checked, "" when they were
+	Enforced   bool     // Unsigned commits fail the commit status
+}
+
+// NewSignatureCheck prepares the signature check of a review on branch; nil when the policy is off.
+// Enforcement applies to branches matching SignedBranches, or every branch when it is empty.
+func NewSignatureCheck(policy, signedBranches, branch string) *SignatureCheck {
+	switch policy {
+	case SignaturePolicyAnnotate:
+		return &SignatureCheck{}
+	case SignaturePolicyEnforce:
+		matched, empty := matchBranchFilter(signedBranches, branch)
+		return &SignatureCheck{Enforced: matched || empty}
+	}
+	return nil
+}
+
+// Finding describes the unsigned commits as a review finding
+func (c *SignatureCheck) Finding() string {
+	if c == nil || len(c.Unsigned) == 0 && c.Unverified == "" {
+		return ""
+	}
+	var b strings.Builder
+	if len(c.Unsigned) > 0 {
+		b.WriteString(fmt.Sprintf("**Unsigned commits**: %d of %d commit(s) have no verified GPG/SSH signature:\n", len(c.Unsigned), c.Checked))
+		for _, sha := range c.Unsigned {
+			if len(sha) > 8 {
+				sha = sha[:8]
+			}
+			b.WriteString("- `" + sha + "`\n")
+		}
+	}
+	if c.Unverified != "" {
+		b.WriteString("**Unverified commit signatures**: " + c.Unverified + "\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Gate fails a commit status when signatures are enforced and a commit is unsigned or could
+// not be verified
+func (c *SignatureCheck) Gate(state, description string) (string, string) {
+	if c == nil || !c.Enforced {
+		return state, description
+	}
+	if len(c.Unsigned) > 0 {
+		return "failed", fmt.Sprintf("%s; %d unsigned commit(s)", description, len(c.Unsigned))
+	}
+	if c.Unverified != "" {
+		return "failed", description + "; commit signatures could not be verified"
+	}
+	return state, description
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewSignatureCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		branches     string
+		branch       string
+		wantNil      bool
+		wantEnforced bool
+	}{
+		{"off", SignaturePolicyOff, "", "main", true, false},
+		{"empty policy", "", "", "main", true, false},
+		{"annotate", SignaturePolicyAnnotate, "main", "main", false, false},
+		{"enforce on all branches", SignaturePolicyEnforce, "", "feature/x", false, true},
+		{"enforce on protected branch", SignaturePolicyEnforce, "main, release/*", "release/1.2", false, true},
+		{"enforce elsewhere annotates", SignaturePolicyEnforce, "main, release/*", "feature/x", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewSignatureCheck(tt.policy, tt.branches, tt.branch)
+			if (got == nil) != tt.wantNil {
+				t.Fatalf("got %v, wantNil %v", got, tt.wantNil)
+			}
+			if got != nil && got.Enforced != tt.wantEnforced {
+				t.Errorf("Enforced = %v, want %v", got.Enforced, tt.wantEnforced)
+			}
+		})
+	}
+}
+
+func TestSignatureCheckGate(t *testing.T) {
+	tests := []struct {
+		name      string
+		check     *SignatureCheck
+		wantState string
+		wantDesc  string
+	}{
+		{"no check", nil, "success", "AI Review Passed: 90/60"},
+		{"all signed", &SignatureCheck{Checked: 2, Enforced: true}, "success", "AI Review Passed: 90/60"},
+		{"annotate only", &SignatureCheck{Checked: 2, Unsigned: []string{"abc"}}, "success", "AI Review Passed: 90/60"},
+		{"enforced", &SignatureCheck{Checked: 2, Unsigned: []string{"abc"}, Enforced: true}, "failed", "AI Review Passed: 90/60; 1 unsigned commit(s)"},
+		{"unverified annotated", &SignatureCheck{Unverified: "API error"}, "success", "AI Review Passed: 90/60"},
+		{"unverified enforced", &SignatureCheck{Unverified: "API error", Enforced: true}, "failed", "AI Review Passed: 90/60; commit signatures could not be verified"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, desc := tt.check.Gate("success", "AI Review Passed: 90/60")
+			if state != tt.wantState || desc != tt.wantDesc {
+				t.Errorf("Gate() = %q, %q, want %q, %q", state, desc, tt.wantState, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestSignatureCheckFinding(t *testing.T) {
+	var none *SignatureCheck
+	if got := none.Finding(); got != "" {
+		t.Errorf("nil check finding = %q, want empty", got)
+	}
+	if got := (&SignatureCheck{Checked: 3}).Finding(); got != "" {
+		t.Errorf("signed commits finding = %q, want empty", got)
+	}
+
+	if got := (&SignatureCheck{Unverified: "GitHub API returned 502"}).Finding(); !strings.Contains(got, "GitHub API returned 502") {
+		t.Errorf("unverified finding = %q, want the reason", got)
+	}
+
+	got := (&SignatureCheck{Checked: 3, Unsigned: []string{"0123456789abcdef", "fedcba98"}}).Finding()
+	for _, want := range []string{"2 of 3 commit(s)", "- `01234567`", "- `fedcba98`"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("finding %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,681 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Compliance report periods
+const (
+	ComplianceReportWeekly  = "weekly"
+	ComplianceReportMonthly = "monthly"
+)
+
+// Gate override kinds in compliance reports
+const (
+	OverrideScore     = "score_override"         // Score changed by hand
+	OverrideVerdict   = "verdict_accepted"       // Review below the minimum score accepted by a maintainer
+	OverrideMigration = "migration_acknowledged" // Destructive migration acknowledged
+)
+
+// ComplianceReportMaxItems caps the unreviewed pushes and gate overrides listed in a report
+const ComplianceReportMaxItems = 500
+
+// ComplianceReportCheckInterval is how often the scheduler checks whether a report is due
+const ComplianceReportCheckInterval = time.Hour
+
+type ComplianceReportRequest struct {
+	StartDate  string `form:"start_date"` // YYYY-MM-DD, 30 days before the end date by default
+	EndDate    string `form:"end_date"`   // YYYY-MM-DD, today by default
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"` // Comma-separated project group
+	TenantID   uint   `form:"-" json:"-"`
+}
+
+// ComplianceCoverage is the share of pushed commits that were reviewed, for a project or
+// overall. A push review counts each commit it lists; merge request and release reviews one.
+type ComplianceCoverage struct {
+	ProjectID           uint    `json:"project_id,omitempty"`
+	ProjectName         string  `json:"project_name"`
+	Commits             int64   `json:"commits"`
+	Reviewed            int64   `json:"reviewed"` // Covered by completed reviews
+	Skipped             int64   `json:"skipped"`
+	Failed              int64   `json:"failed"`          // Review or diff fetch failed
+	NeedsAttention      int64   `json:"needs_attention"` // Review without a parseable score
+	Missed              int64   `json:"missed"`          // Found by the coverage gap monitor without a webhook
+	Pending             int64   `json:"pending"`         // Still queued or in progress
+	Coverage            float64 `json:"coverage"`        // Percentage of the commits reviewed
+	UnreviewedProtected int64   `json:"unreviewed_protected"`
+}
+
+// ComplianceUnreviewedPush is a push to a protected branch without a completed review
+type ComplianceUnreviewedPush struct {
+	ReviewLogID uint      `json:"review_log_id"`
+	ProjectName string    `json:"project_name"`
+	Branch      string    `json:"branch"`
+	CommitHash  string    `json:"commit_hash"`
+	Author      string    `json:"author"`
+	Status      string    `json:"status"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ComplianceOverride is a manual change of a review's gate outcome
+type ComplianceOverride struct {
+	ReviewLogID   uint       `json:"review_log_id"`
+	ProjectName   string     `json:"project_name"`
+	Branch        string     `json:"branch"`
+	CommitHash    string     `json:"commit_hash"`
+	Kind          string     `json:"kind"` // score_override, verdict_accepted, migration_acknowledged
+	By            string     `json:"by"`   // Empty for score overrides, which don't record who made them
+	Reason        string     `json:"reason"`
+	Score         *float64   `json:"score"`
+	OriginalScore *float64   `json:"original_score"`
+	At            *time.Time `json:"at"`
+}
+
+// ComplianceReviewerStats counts the acknowledgments of one reviewer
+type ComplianceReviewerStats struct {
+	Reviewer      string `json:"reviewer"`
+	Verdicts      int64  `json:"verdicts"`
+	Accepted      int64  `json:"accepted"`
+	Rejected      int64  `json:"rejected"`
+	MigrationAcks int64  `json:"migration_acks"`
+}
+
+// ComplianceAcknowledgments summarizes how reviewers followed up on reviews
+type ComplianceAcknowledgments struct {
+	FailedReviews          int64                     `json:"failed_reviews"` // Completed below the minimum score
+	Acknowledged           int64                     `json:"acknowledged"`   // Failed reviews with a human verdict
+	AckRate                float64                   `json:"ack_rate"`
+	Accepted               int64                     `json:"accepted"`
+	Rejected               int64                     `json:"rejected"`
+	AvgHoursToVerdict      float64                   `json:"avg_hours_to_verdict"`
+	MigrationsPending      int64                     `json:"migrations_pending"`
+	MigrationsAcknowledged int64                     `json:"migrations_acknowledged"`
+	Reviewers              []ComplianceReviewerStats `json:"reviewers"`
+}
+
+// ComplianceReport is the review compliance of a date range for audits
+type ComplianceReport struct {
+	StartDate         string                     `json:"start_date"`
+	EndDate           string                     `json:"end_date"`
+	GeneratedAt       time.Time                  `json:"generated_at"`
+	ProtectedBranches []string                   `json:"protected_branches"` // Patterns besides each project's default branch
+	Totals            ComplianceCoverage         `json:"totals"`
+	Projects          []ComplianceCoverage       `json:"projects"`
+	UnreviewedPushes  []ComplianceUnreviewedPush `json:"unreviewed_pushes"`
+	UnreviewedTotal   int                        `json:"unreviewed_total"`
+	Overrides         []ComplianceOverride       `json:"overrides"`
+	OverridesTotal    int                        `json:"overrides_total"`
+	Acknowledgments   ComplianceAcknowledgments  `json:"acknowledgments"`
+}
+
+type ComplianceReportService struct {
+	db                  *gorm.DB
+	configService       *SystemConfigService
+	notificationService *NotificationService
+}
+
+func NewComplianceReportService(db *gorm.DB) *ComplianceReportService {
+	return &ComplianceReportService{
+		db:                  db,
+		configService:       NewSystemConfigService(db),
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// isProtectedBranch reports whether a branch is the project's default branch or matches one
+// of the protected branch patterns
+func isProtectedBranch(project *models.Project, patterns, branch string) bool {
+	if project != nil && project.DefaultBranch != "" && branch == project.DefaultBranch {
+		return true
+	}
+	matched, _ := matchBranchFilter(patterns, branch)
+	return matched
+}
+
+// Generate builds the compliance report of a date range
+func (s *ComplianceReportService) Generate(req *ComplianceReportRequest) (*ComplianceReport, error) {
+	start, end := parseDateRange(req.StartDate, req.EndDate, 30)
+
+	query := s.db.Model(&models.ReviewLog{}).
+		Select("id", "project_id", "event_type", "branch", "commit_hash", "commit_message", "author", "review_status", "error_message",
+			"score", "original_score", "score_override_reason", "human_verdict", "human_verdict_by", "human_verdict_at",
+			"human_verdict_reason", "migration_ack", "migration_ack_by", "migration_ack_at", "created_at", "updated_at").
+		Where("created_at BETWEEN ? AND ? AND is_manual = ? AND revision_of IS NULL", start, end, false)
+	if ids := parseProjectIDs(req.ProjectID, req.ProjectIDs); len(ids) > 0 {
+		query = query.Where("project_id IN ?", ids)
+	}
+	var reviews []models.ReviewLog
+	if err := ScopeReviewLogsByTenant(query, req.TenantID).Order("created_at, id").Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	projectIDs := make([]uint, 0)
+	seen := make(map[uint]bool)
+	for _, r := range reviews {
+		if !seen[r.ProjectID] {
+			seen[r.ProjectID] = true
+			projectIDs = append(projectIDs, r.ProjectID)
+		}
+	}
+	var projects []models.Project
+	if len(projectIDs) > 0 {
+		if err := s.db.Unscoped().Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+			return nil, err
+		}
+	}
+	reviewLogs := NewReviewLogService(s.db)
+	projectByID := make(map[uint]*models.Project, len(projects))
+	minScores := make(map[uint]float64, len(projects))
+	for i := range projects {
+		projectByID[projects[i].ID] = &projects[i]
+		minScores[projects[i].ID] = reviewLogs.EffectiveMinScore(&projects[i])
+	}
+
+	patterns := s.configService.GetComplianceReportConfig().ProtectedBranches
+	report := buildComplianceReport(reviews, projectByID, minScores, patterns)
+	report.StartDate, report.EndDate = start.Format("2006-01-02"), end.Format("2006-01-02")
+	report.GeneratedAt = time.Now()
+	return report, nil
+}
+
+// reviewedCommitCount returns the number of commits a review covers: the commits a push
+// review lists, otherwise one
+func reviewedCommitCount(r *models.ReviewLog) int64 {
+	if r.EventType == "push" {
+		if n := len(pushCommitLineRegex.FindAllStringIndex(r.CommitMessage, -1)); n > 0 {
+			return int64(n)
+		}
+	}
+	return 1
+}
+
+// countReviewStatus adds the commits of a review to the counter of its status
+func countReviewStatus(c *ComplianceCoverage, status string, commits int64) {
+	c.Commits += commits
+	switch {
+	case status == "completed":
+		c.Reviewed += commits
+	case status == "skipped", strings.HasPrefix(status, "skipped_"):
+		c.Skipped += commits
+	case status == ReviewStatusNeedsAttention:
+		c.NeedsAttention += commits
+	case status == ReviewStatusMissed:
+		c.Missed += commits
+	case status == "pending", status == "processing", status == "analyzing", status == ReviewStatusImportQueued:
+		c.Pending += commits
+	default:
+		// failed, diff_fetch_failed and any status unknown to the report, which must not
+		// pass as in progress
+		c.Failed += commits
+	}
+}
+
+// buildComplianceReport aggregates the reviews of a report
+func buildComplianceReport(reviews []models.ReviewLog, projects map[uint]*models.Project, minScores map[uint]float64, patterns string) *ComplianceReport {
+	report := &ComplianceReport{
+		ProtectedBranches: splitAndTrim(patterns, ","),
+		Totals:            ComplianceCoverage{ProjectName: "All projects"},
+		Projects:          []ComplianceCoverage{},
+		UnreviewedPushes:  []ComplianceUnreviewedPush{},
+		Overrides:         []ComplianceOverride{},
+	}
+	if report.ProtectedBranches == nil {
+		report.ProtectedBranches = []string{}
+	}
+	acks := &report.Acknowledgments
+	coverage := make(map[uint]*ComplianceCoverage)
+	reviewers := make(map[string]*ComplianceReviewerStats)
+	reviewer := func(name string) *ComplianceReviewerStats {
+		if reviewers[name] == nil {
+			reviewers[name] = &ComplianceReviewerStats{Reviewer: name}
+		}
+		return reviewers[name]
+	}
+	var verdictHours float64
+	var timedVerdicts int
+
+	for i := range reviews {
+		r := &reviews[i]
+		project := projects[r.ProjectID]
+		projectName := fmt.Sprintf("#%d", r.ProjectID)
+		if project != nil {
+			projectName = project.Name
+		}
+		c := coverage[r.ProjectID]
+		if c == nil {
+			c = &ComplianceCoverage{ProjectID: r.ProjectID, ProjectName: projectName}
+			coverage[r.ProjectID] = c
+		}
+
+		countReviewStatus(c, r.ReviewStatus, reviewedCommitCount(r))
+
+		if r.ReviewStatus != "completed" && r.EventType == "push" && isProtectedBranch(project, patterns, r.Branch) {
+			c.UnreviewedProtected++
+			reason := firstLine(r.ErrorMessage)
+			if reason == "" {
+				reason = r.ReviewStatus
+			}
+			report.UnreviewedPushes = append(report.UnreviewedPushes, ComplianceUnreviewedPush{
+				ReviewLogID: r.ID,
+				ProjectName: projectName,
+				Branch:      r.Branch,
+				CommitHash:  r.CommitHash,
+				Author:      r.Author,
+				Status:      r.ReviewStatus,
+				Reason:      truncateRunes(reason, 200),
+				CreatedAt:   r.CreatedAt,
+			})
+		}
+
+		override := func(kind, by, reason string, at *time.Time) {
+			report.Overrides = append(report.Overrides, ComplianceOverride{
+				ReviewLogID:   r.ID,
+				ProjectName:   projectName,
+				Branch:        r.Branch,
+				CommitHash:    r.CommitHash,
+				Kind:          kind,
+				By:            by,
+				Reason:        reason,
+				Score:         r.Score,
+				OriginalScore: r.OriginalScore,
+				At:            at,
+			})
+		}
+		if r.OriginalScore != nil {
+			updatedAt := r.UpdatedAt
+			override(OverrideScore, "", r.ScoreOverrideReason, &updatedAt)
+		}
+
+		failed := r.ReviewStatus == "completed" && r.Score != nil && *r.Score < minScores[r.ProjectID]
+		if failed {
+			acks.FailedReviews++
+			if r.HumanVerdict != "" {
+				acks.Acknowledged++
+			}
+		}
+		if r.HumanVerdict != "" {
+			stats := reviewer(r.HumanVerdictBy)
+			stats.Verdicts++
+			if r.HumanVerdict == VerdictAccepted {
+				acks.Accepted++
+				stats.Accepted++
+				if failed {
+					override(OverrideVerdict, r.HumanVerdictBy, r.HumanVerdictReason, r.HumanVerdictAt)
+				}
+			} else {
+				acks.Rejected++
+				stats.Rejected++
+			}
+			if r.HumanVerdictAt != nil {
+				verdictHours += r.HumanVerdictAt.Sub(r.CreatedAt).Hours()
+				timedVerdicts++
+			}
+		}
+
+		switch r.MigrationAck {
+		case MigrationAckPending:
+			acks.MigrationsPending++
+		case MigrationAckAcknowledged:
+			acks.MigrationsAcknowledged++
+			reviewer(r.MigrationAckBy).MigrationAcks++
+			override(OverrideMigration, r.MigrationAckBy, "", r.MigrationAckAt)
+		}
+	}
+
+	for _, c := range coverage {
+		c.Coverage = percentOf(c.Reviewed, c.Commits)
+		report.Projects = append(report.Projects, *c)
+
+		t := &report.Totals
+		t.Commits += c.Commits
+		t.Reviewed += c.Reviewed
+		t.Skipped += c.Skipped
+		t.Failed += c.Failed
+		t.NeedsAttention += c.NeedsAttention
+		t.Missed += c.Missed
+		t.Pending += c.Pending
+		t.UnreviewedProtected += c.UnreviewedProtected
+	}
+	report.Totals.Coverage = percentOf(report.Totals.Reviewed, report.Totals.Commits)
+	// Least covered projects first
+	sort.Slice(report.Projects, func(i, j int) bool {
+		a, b := report.Projects[i], report.Projects[j]
+		if a.Coverage != b.Coverage {
+			return a.Coverage < b.Coverage
+		}
+		return a.ProjectName < b.ProjectName
+	})
+
+	acks.AckRate = percentOf(acks.Acknowledged, acks.FailedReviews)
+	if timedVerdicts > 0 {
+		acks.AvgHoursToVerdict = verdictHours / float64(timedVerdicts)
+	}
+	acks.Reviewers = []ComplianceReviewerStats{}
+	for _, stats := range reviewers {
+		acks.Reviewers = append(acks.Reviewers, *stats)
+	}
+	sort.Slice(acks.Reviewers, func(i, j int) bool {
+		a, b := acks.Reviewers[i], acks.Reviewers[j]
+		if a.Verdicts+a.MigrationAcks != b.Verdicts+b.MigrationAcks {
+			return a.Verdicts+a.MigrationAcks > b.Verdicts+b.MigrationAcks
+		}
+		return a.Reviewer < b.Reviewer
+	})
+
+	report.UnreviewedTotal = len(report.UnreviewedPushes)
+	if len(report.UnreviewedPushes) > ComplianceReportMaxItems {
+		report.UnreviewedPushes = report.UnreviewedPushes[:ComplianceReportMaxItems]
+	}
+	report.OverridesTotal = len(report.Overrides)
+	if len(report.Overrides) > ComplianceReportMaxItems {
+		report.Overrides = report.Overrides[:ComplianceReportMaxItems]
+	}
+	return report
+}
+
+func percentOf(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total) * 100
+}
+
+func formatOptionalScore(score *float64) string {
+	if score == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*score, 'f', 0, 64)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// WriteComplianceCSV writes a report as CSV, one section after the other
+func WriteComplianceCSV(out io.Writer, report *ComplianceReport) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"Compliance report", report.StartDate, report.EndDate})
+	w.Write([]string{"Protected branches", "default branch " + strings.Join(report.ProtectedBranches, " ")})
+	w.Write(nil)
+
+	w.Write([]string{"Project", "Commits", "Reviewed", "Skipped", "Failed", "Needs attention", "Missed", "Pending", "Coverage %", "Unreviewed protected pushes"})
+	for _, c := range append(report.Projects, report.Totals) {
+		w.Write([]string{c.ProjectName, strconv.FormatInt(c.Commits, 10), strconv.FormatInt(c.Reviewed, 10),
+			strconv.FormatInt(c.Skipped, 10), strconv.FormatInt(c.Failed, 10), strconv.FormatInt(c.NeedsAttention, 10),
+			strconv.FormatInt(c.Missed, 10), strconv.FormatInt(c.Pending, 10),
+			strconv.FormatFloat(c.Coverage, 'f', 1, 64), strconv.FormatInt(c.UnreviewedProtected, 10)})
+	}
+	w.Write(nil)
+
+	w.Write([]string{"Unreviewed push", "Project", "Branch", "Commit", "Author", "Status", "Reason", "Pushed at"})
+	for _, p := range report.UnreviewedPushes {
+		w.Write([]string{strconv.FormatUint(uint64(p.ReviewLogID), 10), p.ProjectName, p.Branch, p.CommitHash, p.Author,
+			p.Status, p.Reason, p.CreatedAt.Format("2006-01-02 15:04:05")})
+	}
+	w.Write(nil)
+
+	w.Write([]string{"Gate override", "Project", "Branch", "Commit", "Kind", "By", "Reason", "Score", "Original score", "At"})
+	for _, o := range report.Overrides {
+		w.Write([]string{strconv.FormatUint(uint64(o.ReviewLogID), 10), o.ProjectName, o.Branch, o.CommitHash, o.Kind,
+			o.By, o.Reason, formatOptionalScore(o.Score), formatOptionalScore(o.OriginalScore), formatOptionalTime(o.At)})
+	}
+	w.Write(nil)
+
+	acks := report.Acknowledgments
+	w.Write([]string{"Reviewer", "Verdicts", "Accepted", "Rejected", "Migration acknowledgments"})
+	for _, r := range acks.Reviewers {
+		w.Write([]string{r.Reviewer, strconv.FormatInt(r.Verdicts, 10), strconv.FormatInt(r.Accepted, 10),
+			strconv.FormatInt(r.Rejected, 10), strconv.FormatInt(r.MigrationAcks, 10)})
+	}
+	w.Write([]string{"Failed reviews acknowledged", fmt.Sprintf("%d/%d", acks.Acknowledged, acks.FailedReviews),
+		strconv.FormatFloat(acks.AckRate, 'f', 1, 64) + "%"})
+	w.Write([]string{"Average hours to verdict", strconv.FormatFloat(acks.AvgHoursToVerdict, 'f', 1, 64)})
+	w.Write([]string{"Migrations pending", strconv.FormatInt(acks.MigrationsPending, 10)})
+
+	w.Flush()
+	return w.Error()
+}
+
+// protectedBranchesLabel describes the protected branches of a report
+func (r *ComplianceReport) protectedBranchesLabel() string {
+	if len(r.ProtectedBranches) == 0 {
+		return "default branch"
+	}
+	return "default branch, " + strings.Join(r.ProtectedBranches, ", ")
+}
+
+// RenderComplianceReportPDF renders a report as a PDF document
+func RenderComplianceReportPDF(report *ComplianceReport) []byte {
+	accent := parsePDFColor(ReviewReportColor, pdfBlack)
+	d := newPDFDocument(fmt.Sprintf("%s Compliance Report %s - %s", ReviewReportBrand, report.StartDate, report.EndDate),
+		fmt.Sprintf("%s - generated %s", ReviewReportBrand, report.GeneratedAt.Format("2006-01-02 15:04 MST")))
+	d.rect(0, pdfPageHeight-8, pdfPageWidth, 8, accent)
+	d.paragraph(pdfFontBold, 10, accent, 0, strings.ToUpper(ReviewReportBrand))
+	d.paragraph(pdfFontBold, 20, pdfBlack, 0, "Compliance Report")
+	d.paragraph(pdfFontRegular, 11, pdfGrey, 0, report.StartDate+" to "+report.EndDate)
+
+	t := report.Totals
+	d.heading("Summary", accent)
+	d.row("Commits", strconv.FormatInt(t.Commits, 10))
+	d.row("Reviewed", fmt.Sprintf("%d (%.1f%%)", t.Reviewed, t.Coverage))
+	d.row("Not reviewed", fmt.Sprintf("%d skipped, %d failed, %d needing attention, %d missed, %d pending",
+		t.Skipped, t.Failed, t.NeedsAttention, t.Missed, t.Pending))
+	d.row("Protected branches", report.protectedBranchesLabel())
+	d.row("Unreviewed pushes", fmt.Sprintf("%d to protected branches", report.UnreviewedTotal))
+	d.row("Gate overrides", strconv.Itoa(report.OverridesTotal))
+	acks := report.Acknowledgments
+	d.row("Failed reviews", fmt.Sprintf("%d, %d acknowledged (%.1f%%)", acks.FailedReviews, acks.Acknowledged, acks.AckRate))
+
+	d.heading("Review Coverage by Project", accent)
+	d.paragraph(pdfFontMono, 8, pdfGrey, 0, fmt.Sprintf("%-28s %7s %8s %7s %6s %6s %8s %10s", "Project", "Commits", "Reviewed", "Skipped", "Failed", "Missed", "Coverage", "Unreviewed"))
+	for _, c := range append(report.Projects, report.Totals) {
+		d.paragraph(pdfFontMono, 8, pdfBlack, 0, fmt.Sprintf("%-28s %7d %8d %7d %6d %6d %7.1f%% %10d",
+			truncateRunes(c.ProjectName, 28), c.Commits, c.Reviewed, c.Skipped, c.Failed+c.NeedsAttention, c.Missed, c.Coverage, c.UnreviewedProtected))
+	}
+
+	d.heading(fmt.Sprintf("Unreviewed Pushes to Protected Branches (%d)", report.UnreviewedTotal), accent)
+	if report.UnreviewedTotal == 0 {
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, "None.")
+	}
+	for _, p := range report.UnreviewedPushes {
+		d.paragraph(pdfFontRegular, 9, pdfBlack, 0, fmt.Sprintf("%s  %s@%s %s by %s - %s: %s", p.CreatedAt.Format("2006-01-02 15:04"),
+			p.ProjectName, p.Branch, shortCommit(p.CommitHash), p.Author, p.Status, p.Reason))
+	}
+
+	d.heading(fmt.Sprintf("Gate Overrides (%d)", report.OverridesTotal), accent)
+	if report.OverridesTotal == 0 {
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, "None.")
+	}
+	for _, o := range report.Overrides {
+		line := fmt.Sprintf("%s  %s@%s %s - %s", formatOptionalTime(o.At), o.ProjectName, o.Branch, shortCommit(o.CommitHash), o.Kind)
+		if o.By != "" {
+			line += " by " + o.By
+		}
+		if o.OriginalScore != nil {
+			line += fmt.Sprintf(", score %s -> %s", formatOptionalScore(o.OriginalScore), formatOptionalScore(o.Score))
+		} else if o.Score != nil {
+			line += ", score " + formatOptionalScore(o.Score)
+		}
+		if o.Reason != "" {
+			line += ": " + o.Reason
+		}
+		d.paragraph(pdfFontRegular, 9, pdfBlack, 0, line)
+	}
+
+	d.heading("Reviewer Acknowledgments", accent)
+	d.row("Verdicts", fmt.Sprintf("%d accepted, %d rejected", acks.Accepted, acks.Rejected))
+	d.row("Avg. time to verdict", fmt.Sprintf("%.1f hours", acks.AvgHoursToVerdict))
+	d.row("Migrations", fmt.Sprintf("%d acknowledged, %d pending", acks.MigrationsAcknowledged, acks.MigrationsPending))
+	for _, r := range acks.Reviewers {
+		d.row(truncateRunes(r.Reviewer, 22), fmt.Sprintf("%d verdicts (%d accepted, %d rejected), %d migration acknowledgments",
+			r.Verdicts, r.Accepted, r.Rejected, r.MigrationAcks))
+	}
+	return d.Bytes()
+}
+
+// complianceReportMessage is the summary of a report sent to bots
+func complianceReportMessage(report *ComplianceReport) string {
+	t := report.Totals
+	acks := report.Acknowledgments
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📋 **Compliance Report** %s ~ %s\n\n", report.StartDate, report.EndDate)
+	fmt.Fprintf(&sb, "- Reviewed commits: %d/%d (%.1f%%)\n", t.Reviewed, t.Commits, t.Coverage)
+	fmt.Fprintf(&sb, "- Unreviewed pushes to protected branches (%s): %d\n", report.protectedBranchesLabel(), report.UnreviewedTotal)
+	fmt.Fprintf(&sb, "- Gate overrides: %d\n", report.OverridesTotal)
+	fmt.Fprintf(&sb, "- Failed reviews acknowledged: %d/%d (%.1f%%)\n", acks.Acknowledged, acks.FailedReviews, acks.AckRate)
+	if acks.MigrationsPending > 0 {
+		fmt.Fprintf(&sb, "- Destructive migrations awaiting acknowledgment: %d\n", acks.MigrationsPending)
+	}
+
+	var lagging []string
+	for _, c := range report.Projects {
+		if c.Coverage < 100 && len(lagging) < 5 {
+			lagging = append(lagging, fmt.Sprintf("%s %.0f%%", c.ProjectName, c.Coverage))
+		}
+	}
+	if len(lagging) > 0 {
+		fmt.Fprintf(&sb, "\nLowest coverage: %s\n", strings.Join(lagging, ", "))
+	}
+	for i, p := range report.UnreviewedPushes {
+		if i == 5 {
+			fmt.Fprintf(&sb, "...and %d more unreviewed pushes\n", report.UnreviewedTotal-5)
+			break
+		}
+		if i == 0 {
+			sb.WriteString("\nUnreviewed pushes:\n")
+		}
+		fmt.Fprintf(&sb, "- %s@%s %s by %s (%s)\n", p.ProjectName, p.Branch, shortCommit(p.CommitHash), p.Author, p.Status)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// Send delivers the summary of a report to IM bots of a tenant
+func (s *ComplianceReportService) Send(report *ComplianceReport, botIDs []uint, tenantID uint) (int, error) {
+	if len(botIDs) == 0 {
+		return 0, fmt.Errorf("no IM bots selected")
+	}
+	var bots []models.IMBot
+	if err := ScopeTenant(s.db.Where("id IN ? AND is_active = ?", botIDs, true), tenantID).Find(&bots).Error; err != nil {
+		return 0, err
+	}
+	if len(bots) == 0 {
+		return 0, fmt.Errorf("no active IM bots found")
+	}
+
+	message := complianceReportMessage(report)
+	sent := 0
+	var lastErr error
+	for i := range bots {
+		if err := s.notificationService.SendErrorNotification(&bots[i], message); err != nil {
+			logger.Module("compliance").Warn().Err(err).Str("bot", bots[i].Name).Msg("Failed to send compliance report")
+			lastErr = err
+			continue
+		}
+		sent++
+	}
+	if sent == 0 {
+		return 0, lastErr
+	}
+	return sent, nil
+}
+
+// lastCompliancePeriod returns the last complete week (Monday to Sunday) or month before now
+func lastCompliancePeriod(period string, now time.Time) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if period == ComplianceReportMonthly {
+		end := today.AddDate(0, 0, 1-today.Day())
+		return end.AddDate(0, -1, 0), end.AddDate(0, 0, -1)
+	}
+	end := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	return end.AddDate(0, 0, -7), end.AddDate(0, 0, -1)
+}
+
+// SendDueReport sends the report of the last complete period to the configured bots, once.
+// Each bot receives the report of its own tenant.
+func (s *ComplianceReportService) SendDueReport(now time.Time) error {
+	cfg := s.configService.GetComplianceReportConfig()
+	if !cfg.Enabled || len(cfg.IMBotIDs) == 0 {
+		return nil
+	}
+	start, end := lastCompliancePeriod(cfg.Period, now)
+	key := cfg.Period + ":" + start.Format("2006-01-02")
+	if s.configService.GetWithDefault("compliance_report_last_sent", "") == key {
+		return nil
+	}
+
+	var bots []models.IMBot
+	if err := s.db.Where("id IN ? AND is_active = ?", cfg.IMBotIDs, true).Find(&bots).Error; err != nil {
+		return err
+	}
+	botsByTenant := make(map[uint][]uint)
+	for _, bot := range bots {
+		botsByTenant[bot.TenantID] = append(botsByTenant[bot.TenantID], bot.ID)
+	}
+	for tenantID, botIDs := range botsByTenant {
+		report, err := s.Generate(&ComplianceReportRequest{
+			StartDate: start.Format("2006-01-02"),
+			EndDate:   end.Format("2006-01-02"),
+			TenantID:  tenantID,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := s.Send(report, botIDs, tenantID); err != nil {
+			logger.Module("compliance").Warn().Err(err).Uint("tenant_id", tenantID).Msg("Failed to send scheduled compliance report")
+		}
+	}
+	logger.Infof("[Compliance] Sent the %s compliance report of %s", cfg.Period, start.Format("2006-01-02"))
+	return s.configService.Set("compliance_report_last_sent", key)
+}
+
+var complianceStopChan chan struct{}
+
+// StartComplianceReportScheduler starts a goroutine sending the compliance report of each past
+// period to the configured bots
+func StartComplianceReportScheduler(db *gorm.DB) {
+	complianceStopChan = make(chan struct{})
+	go func() {
+		service := NewComplianceReportService(db)
+		RegisterScheduler("compliance_report", ComplianceReportCheckInterval)
+		ticker := time.NewTicker(ComplianceReportCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("compliance_report", func() error {
+					err := service.SendDueReport(time.Now())
+					if err != nil {
+						logger.Warnf("[Compliance] Failed to send the scheduled report: %v", err)
+					}
+					return err
+				})
+			case <-complianceStopChan:
+				logger.Infof("[Compliance] Report scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopComplianceReportScheduler stops the compliance report scheduler
+func StopComplianceReportScheduler() {
+	if complianceStopChan != nil {
+		close(complianceStopChan)
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIsProtectedBranch(t *testing.T) {
+	project := &models.Project{DefaultBranch: "main"}
+	tests := []struct {
+		name     string
+		project  *models.Project
+		patterns string
+		branch   string
+		want     bool
+	}{
+		{"default branch", project, "", "main", true},
+		{"other branch", project, "", "feature/x", false},
+		{"matching pattern", project, "release/*, hotfix/*", "release/1.2", true},
+		{"non-matching pattern", project, "release/*", "feature/x", false},
+		{"no project", nil, "", "main", false},
+		{"no default branch", &models.Project{}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProtectedBranch(tt.project, tt.patterns, tt.branch); got != tt.want {
+				t.Errorf("isProtectedBranch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func complianceTestReviews() []models.ReviewLog {
+	created := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	verdictAt := created.Add(4 * time.Hour)
+	ackAt := created.Add(2 * time.Hour)
+	score := func(v float64) *float64 { return &v }
+	return []models.ReviewLog{
+		{ID: 1, ProjectID: 1, EventType: "push", Branch: "main", ReviewStatus: "completed", Score: score(90), CreatedAt: created},
+		{ID: 2, ProjectID: 1, EventType: "push", Branch: "main", ReviewStatus: "skipped_rate_limit", Author: "bob", CreatedAt: created},
+		{ID: 3, ProjectID: 1, EventType: "push", Branch: "feature/x", ReviewStatus: "failed", ErrorMessage: "timeout\ndetails", CreatedAt: created},
+		{ID: 4, ProjectID: 1, EventType: "merge_request", Branch: "main", ReviewStatus: "completed", Score: score(40),
+			HumanVerdict: VerdictAccepted, HumanVerdictBy: "alice", HumanVerdictReason: "false positive", HumanVerdictAt: &verdictAt, CreatedAt: created},
+		{ID: 5, ProjectID: 2, EventType: "push", Branch: "release/1.0", ReviewStatus: "failed", ErrorMessage: "LLM error", CreatedAt: created},
+		{ID: 6, ProjectID: 2, EventType: "push", Branch: "develop", ReviewStatus: "completed", Score: score(70), OriginalScore: score(30),
+			ScoreOverrideReason: "reviewed by hand", MigrationAck: MigrationAckAcknowledged, MigrationAckBy: "carol", MigrationAckAt: &ackAt, CreatedAt: created},
+		{ID: 7, ProjectID: 2, EventType: "push", Branch: "develop", ReviewStatus: "completed", Score: score(20),
+			HumanVerdict: VerdictRejected, HumanVerdictBy: "alice", MigrationAck: MigrationAckPending, CreatedAt: created},
+	}
+}
+
+func TestBuildComplianceReport(t *testing.T) {
+	projects := map[uint]*models.Project{
+		1: {Name: "api", DefaultBranch: "main"},
+		2: {Name: "web", DefaultBranch: "develop"},
+	}
+	minScores := map[uint]float64{1: 60, 2: 60}
+	report := buildComplianceReport(complianceTestReviews(), projects, minScores, "release/*")
+
+	totals := report.Totals
+	if totals.Commits != 7 || totals.Reviewed != 4 || totals.Skipped != 1 || totals.Failed != 2 || totals.Pending != 0 {
+		t.Errorf("totals = %+v", totals)
+	}
+	if totals.UnreviewedProtected != 2 {
+		t.Errorf("UnreviewedProtected = %d, want 2", totals.UnreviewedProtected)
+	}
+	if len(report.Projects) != 2 || report.Projects[0].ProjectName != "api" || report.Projects[0].Coverage != 50 {
+		t.Errorf("projects = %+v, want api first with 50%% coverage", report.Projects)
+	}
+
+	if report.UnreviewedTotal != 2 {
+		t.Fatalf("UnreviewedTotal = %d, want 2", report.UnreviewedTotal)
+	}
+	if p := report.UnreviewedPushes[0]; p.ReviewLogID != 2 || p.Reason != "skipped_rate_limit" {
+		t.Errorf("first unreviewed push = %+v", p)
+	}
+	if p := report.UnreviewedPushes[1]; p.ReviewLogID != 5 || p.ProjectName != "web" || p.Reason != "LLM error" {
+		t.Errorf("second unreviewed push = %+v", p)
+	}
+
+	kinds := make([]string, len(report.Overrides))
+	for i, o := range report.Overrides {
+		kinds[i] = o.Kind
+	}
+	if got := strings.Join(kinds, ","); got != "verdict_accepted,score_override,migration_acknowledged" {
+		t.Errorf("override kinds = %s", got)
+	}
+	if o := report.Overrides[0]; o.By != "alice" || o.Reason != "false positive" {
+		t.Errorf("verdict override = %+v", o)
+	}
+
+	acks := report.Acknowledgments
+	if acks.FailedReviews != 2 || acks.Acknowledged != 2 || acks.AckRate != 100 {
+		t.Errorf("failed reviews = %d, acknowledged = %d, rate = %v", acks.FailedReviews, acks.Acknowledged, acks.AckRate)
+	}
+	if acks.Accepted != 1 || acks.Rejected != 1 || acks.MigrationsPending != 1 || acks.MigrationsAcknowledged != 1 {
+		t.Errorf("acknowledgments = %+v", acks)
+	}
+	if acks.AvgHoursToVerdict != 4 {
+		t.Errorf("AvgHoursToVerdict = %v, want 4", acks.AvgHoursToVerdict)
+	}
+	if len(acks.Reviewers) != 2 || acks.Reviewers[0].Reviewer != "alice" || acks.Reviewers[0].Verdicts != 2 {
+		t.Errorf("reviewers = %+v", acks.Reviewers)
+	}
+}
+
+func TestBuildComplianceReport_CommitsAndStatuses(t *testing.T) {
+	projects := map[uint]*models.Project{1: {Name: "api", DefaultBranch: "main"}}
+	reviews := []models.ReviewLog{
+		{ID: 1, ProjectID: 1, EventType: "push", Branch: "main", ReviewStatus: "completed",
+			CommitMessage: "0123abcd: first\n4567cdef: second\n89abcdef: third"},
+		{ID: 2, ProjectID: 1, EventType: "push", Branch: "main", ReviewStatus: ReviewStatusMissed, CommitMessage: "lost push"},
+		{ID: 3, ProjectID: 1, EventType: "merge_request", Branch: "feature", ReviewStatus: ReviewStatusNeedsAttention},
+		{ID: 4, ProjectID: 1, EventType: "push", Branch: "feature", ReviewStatus: ReviewStatusDiffFetchFailed},
+		{ID: 5, ProjectID: 1, EventType: "push", Branch: "feature", ReviewStatus: ReviewStatusImportQueued},
+	}
+	report := buildComplianceReport(reviews, projects, map[uint]float64{1: 60}, "")
+
+	want := ComplianceCoverage{ProjectName: "All projects", Commits: 7, Reviewed: 3, Failed: 1, NeedsAttention: 1, Missed: 1, Pending: 1,
+		Coverage: percentOf(3, 7), UnreviewedProtected: 1}
+	if report.Totals != want {
+		t.Errorf("totals = %+v, want %+v", report.Totals, want)
+	}
+}
+
+func TestBuildComplianceReportEmpty(t *testing.T) {
+	report := buildComplianceReport(nil, nil, nil, "")
+	if report.Totals.Coverage != 0 || report.Projects == nil || report.UnreviewedPushes == nil || report.ProtectedBranches == nil {
+		t.Errorf("empty report = %+v", report)
+	}
+}
+
+func TestLastCompliancePeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		period    string
+		now       time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{"weekly on wednesday", ComplianceReportWeekly, time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC), "2026-02-23", "2026-03-01"},
+		{"weekly on monday", ComplianceReportWeekly, time.Date(2026, 3, 2, 0, 30, 0, 0, time.UTC), "2026-02-23", "2026-03-01"},
+		{"weekly on sunday", ComplianceReportWeekly, time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC), "2026-02-23", "2026-03-01"},
+		{"monthly", ComplianceReportMonthly, time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC), "2026-02-01", "2026-02-28"},
+		{"monthly in january", ComplianceReportMonthly, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), "2025-12-01", "2025-12-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := lastCompliancePeriod(tt.period, tt.now)
+			if got := start.Format("2006-01-02"); got != tt.wantStart {
+				t.Errorf("start = %s, want %s", got, tt.wantStart)
+			}
+			if got := end.Format("2006-01-02"); got != tt.wantEnd {
+				t.Errorf("end = %s, want %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestComplianceReportExports(t *testing.T) {
+	projects := map[uint]*models.Project{1: {Name: "api", DefaultBranch: "main"}, 2: {Name: "web", DefaultBranch: "develop"}}
+	report := buildComplianceReport(complianceTestReviews(), projects, map[uint]float64{1: 60, 2: 60}, "release/*")
+	report.StartDate, report.EndDate = "2026-03-01", "2026-03-31"
+
+	var buf bytes.Buffer
+	if err := WriteComplianceCSV(&buf, report); err != nil {
+		t.Fatalf("WriteComplianceCSV() error = %v", err)
+	}
+	r := csv.NewReader(&buf)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("CSV does not parse: %v", err)
+	}
+	found := false
+	for _, record := range records {
+		if record[0] == "All projects" && record[1] == "7" && record[8] == "57.1" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("CSV has no totals row: %v", records)
+	}
+
+	pdf := RenderComplianceReportPDF(report)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte("Compliance Report")) {
+		t.Error("RenderComplianceReportPDF() did not render a compliance PDF")
+	}
+
+	message := complianceReportMessage(report)
+	for _, want := range []string{"2026-03-01 ~ 2026-03-31", "4/7 (57.1%)", "protected branches (default branch, release/*): 2", "Lowest coverage: api 50%", "web@release/1.0"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message missing %q:\n%s", want, message)
+		}
+	}
+}
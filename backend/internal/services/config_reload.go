@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// ConfigWatchInterval is how often the config file is checked for changes
+const ConfigWatchInterval = 10 * time.Second
+
+// Sources of a config reload
+const (
+	ConfigReloadAPI    = "api"
+	ConfigReloadSignal = "sighup"
+	ConfigReloadFile   = "file"
+)
+
+// configReloadMu serializes the reloads of the different sources
+var configReloadMu sync.Mutex
+
+// ReloadConfig reads the config file again and applies the settings that are safe to
+// change at runtime; the report also lists the changes waiting for a restart
+func ReloadConfig(source string) (*config.ReloadReport, error) {
+	configReloadMu.Lock()
+	defer configReloadMu.Unlock()
+
+	current := config.GlobalConfig
+	if current == nil {
+		return nil, fmt.Errorf("config not loaded")
+	}
+	next, err := config.ReadFile(config.Path())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", config.Path(), err)
+	}
+
+	report := config.Reload(current, next)
+	if report.Changed("sse.replay_buffer") {
+		GetSSEHub().SetReplaySize(current.SSE.ReplayBuffer)
+	}
+
+	if len(report.Changes) > 0 {
+		keys := make([]string, len(report.Changes))
+		for i, change := range report.Changes {
+			keys[i] = change.Key
+		}
+		LogInfo(context.Background(), "System", "ConfigReload", fmt.Sprintf("Config reloaded (%s): %s", source, strings.Join(keys, ", ")), nil, "", "", map[string]interface{}{
+			"source":           source,
+			"changes":          report.Changes,
+			"restart_required": report.RestartRequired,
+		})
+	}
+	return report, nil
+}
+
+var configWatchStopChan chan struct{}
+
+// StartConfigWatcher reloads the config on SIGHUP and when the config file changes
+func StartConfigWatcher() {
+	configWatchStopChan = make(chan struct{})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	ticker := time.NewTicker(ConfigWatchInterval)
+	modTime := configModTime()
+
+	go func() {
+		defer ticker.Stop()
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-hup:
+				modTime = configModTime()
+				reloadConfigLogged(ConfigReloadSignal)
+			case <-ticker.C:
+				if current := configModTime(); !current.Equal(modTime) {
+					modTime = current
+					reloadConfigLogged(ConfigReloadFile)
+				}
+			case <-configWatchStopChan:
+				return
+			}
+		}
+	}()
+	logger.Infof("[Config] Watching %s for changes (SIGHUP reloads it immediately)", config.Path())
+}
+
+func StopConfigWatcher() {
+	if configWatchStopChan != nil {
+		close(configWatchStopChan)
+	}
+}
+
+func reloadConfigLogged(source string) {
+	report, err := ReloadConfig(source)
+	if err != nil {
+		logger.Module("config").Warn().Err(err).Str("source", source).Msg("Config reload failed")
+		return
+	}
+	for _, change := range report.Changes {
+		if !change.Applied {
+			logger.Module("config").Warn().Str("key", change.Key).Msg("Config change requires a restart")
+		}
+	}
+}
+
+// configModTime returns the modification time of the config file, zero if it is missing
+func configModTime() time.Time {
+	info, err := os.Stat(config.Path())
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,937 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// ConfigBundleVersion is the schema version written to exported configuration files
+const ConfigBundleVersion = 1
+
+// errConfigDryRun rolls back the apply transaction in dry-run mode
+var errConfigDryRun = errors.New("dry run")
+
+// ConfigBundle is the declarative, secret-free representation of an instance's configuration.
+// Entities are keyed by name (projects by URL) and reference each other by name, so a bundle
+// can be applied to another instance. Secret fields are never exported; when applying they
+// may be given literally or as ${ENV_VAR} references and are left untouched when empty.
+type ConfigBundle struct {
+	Version         int                  `yaml:"version"`
+	SystemConfig    map[string]string    `yaml:"system_config,omitempty"`
+	Prompts         []PromptSpec         `yaml:"prompts,omitempty"`
+	ReviewTemplates []ReviewTemplateSpec `yaml:"review_templates,omitempty"`
+	LLMConfigs      []LLMConfigSpec      `yaml:"llm_configs,omitempty"`
+	IMBots          []IMBotSpec          `yaml:"im_bots,omitempty"`
+	GitCredentials  []GitCredentialSpec  `yaml:"git_credentials,omitempty"`
+	Projects        []ProjectSpec        `yaml:"projects,omitempty"`
+}
+
+type PromptSpec struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Content     string `yaml:"content"`
+	Variables   string `yaml:"variables,omitempty"`
+	IsDefault   bool   `yaml:"is_default,omitempty"`
+}
+
+type ReviewTemplateSpec struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`
+	Description string `yaml:"description,omitempty"`
+	Content     string `yaml:"content"`
+	IsActive    bool   `yaml:"is_active"`
+}
+
+type LLMConfigSpec struct {
+	Name        string  `yaml:"name"`
+	Provider    string  `yaml:"provider"`
+	BaseURL     string  `yaml:"base_url"`
+	Model       string  `yaml:"model,omitempty"`
+	MaxTokens   int     `yaml:"max_tokens,omitempty"`
+	Temperature float64 `yaml:"temperature"`
+	PromptCache bool    `yaml:"prompt_cache,omitempty"`
+	JSONMode    bool    `yaml:"json_mode,omitempty"`
+	IsDefault   bool    `yaml:"is_default,omitempty"`
+	IsActive    bool    `yaml:"is_active"`
+	APIKey      string  `yaml:"api_key,omitempty"` // Apply only
+}
+
+type IMBotSpec struct {
+	Name               string `yaml:"name"`
+	Type               string `yaml:"type"`
+	Webhook            string `yaml:"webhook"`
+	Extra              string `yaml:"extra,omitempty"`
+	IsActive           bool   `yaml:"is_active"`
+	ErrorNotify        bool   `yaml:"error_notify,omitempty"`
+	DailyReportEnabled bool   `yaml:"daily_report_enabled,omitempty"`
+	MessageTemplate    string `yaml:"message_template,omitempty"`
+	MessageFields      string `yaml:"message_fields,omitempty"`
+	MessageMaxLength   int    `yaml:"message_max_length,omitempty"`
+	MessageLanguage    string `yaml:"message_language,omitempty"`
+	DigestEnabled      bool   `yaml:"digest_enabled,omitempty"`
+	DigestInterval     int    `yaml:"digest_interval,omitempty"`
+	QuietHoursStart    string `yaml:"quiet_hours_start,omitempty"`
+	QuietHoursEnd      string `yaml:"quiet_hours_end,omitempty"`
+	Timezone           string `yaml:"timezone,omitempty"`
+	SkipHolidays       bool   `yaml:"skip_holidays,omitempty"`
+	HolidayCountry     string `yaml:"holiday_country,omitempty"`
+	Secret             string `yaml:"secret,omitempty"` // Apply only
+}
+
+type GitCredentialSpec struct {
+	Name           string `yaml:"name"`
+	Platform       string `yaml:"platform"`
+	BaseURL        string `yaml:"base_url,omitempty"`
+	AutoCreate     bool   `yaml:"auto_create"`
+	DefaultEnabled bool   `yaml:"default_enabled"`
+	FileExtensions string `yaml:"file_extensions,omitempty"`
+	ReviewEvents   string `yaml:"review_events,omitempty"`
+	IgnorePatterns string `yaml:"ignore_patterns,omitempty"`
+	IsActive       bool   `yaml:"is_active"`
+	AccessToken    string `yaml:"access_token,omitempty"`   // Apply only
+	WebhookSecret  string `yaml:"webhook_secret,omitempty"` // Apply only
+}
+
+type TemplateBindingSpec struct {
+	Template      string `yaml:"template"` // Review template name
+	EventType     string `yaml:"event_type,omitempty"`
+	BranchPattern string `yaml:"branch_pattern,omitempty"`
+	Priority      int    `yaml:"priority,omitempty"`
+}
+
+type TargetBranchPolicySpec struct {
+	BranchPattern       string  `yaml:"branch_pattern"`
+	MinScore            float64 `yaml:"min_score,omitempty"`
+	MaxCriticalFindings *int    `yaml:"max_critical_findings,omitempty"`
+	Template            string  `yaml:"template,omitempty"` // Review template name
+	Urgent              bool    `yaml:"urgent,omitempty"`
+}
+
+type ProjectSpec struct {
+	Name                 string                   `yaml:"name"`
+	URL                  string                   `yaml:"url"`
+	Platform             string                   `yaml:"platform"`
+	FileExtensions       string                   `yaml:"file_extensions,omitempty"`
+	ReviewEvents         string                   `yaml:"review_events,omitempty"`
+	BranchFilter         string                   `yaml:"branch_filter,omitempty"`
+	BranchFilterMode     string                   `yaml:"branch_filter_mode,omitempty"` // ignore (default), allow
+	AIEnabled            bool                     `yaml:"ai_enabled"`
+	Prompt               string                   `yaml:"prompt,omitempty"`     // Prompt template name
+	AIPrompt             string                   `yaml:"ai_prompt,omitempty"`  // Inline custom prompt
+	LLMConfig            string                   `yaml:"llm_config,omitempty"` // LLM config name
+	IncludePatterns      string                   `yaml:"include_patterns,omitempty"`
+	IgnorePatterns       string                   `yaml:"ignore_patterns,omitempty"`
+	TechProfile          string                   `yaml:"tech_profile,omitempty"` // Languages and frameworks, e.g. React + TS
+	CommentEnabled       bool                     `yaml:"comment_enabled,omitempty"`
+	IMEnabled            bool                     `yaml:"im_enabled,omitempty"`
+	IMBot                string                   `yaml:"im_bot,omitempty"`         // IM bot name
+	ReleaseIMBot         string                   `yaml:"release_im_bot,omitempty"` // IM bot name for tag/release reviews
+	MinScore             float64                  `yaml:"min_score,omitempty"`
+	MaxChangedLines      int                      `yaml:"max_changed_lines,omitempty"`
+	MaxFiles             int                      `yaml:"max_files,omitempty"`
+	MaxDiffBytes         int                      `yaml:"max_diff_bytes,omitempty"`
+	MaxFileBytes         int                      `yaml:"max_file_bytes,omitempty"`
+	BadgeEnabled         bool                     `yaml:"badge_enabled,omitempty"`
+	ReplayProtection     bool                     `yaml:"replay_protection,omitempty"`
+	SignaturePolicy      string                   `yaml:"signature_policy,omitempty"` // off (default), annotate, enforce
+	SignedBranches       string                   `yaml:"signed_branches,omitempty"`
+	IaCReviewMode        string                   `yaml:"iac_review_mode,omitempty"`  // auto (default), off
+	MigrationPolicy      string                   `yaml:"migration_policy,omitempty"` // off, review (default), acknowledge
+	PerCommitReview      bool                     `yaml:"per_commit_review,omitempty"`
+	SkipDirectives       bool                     `yaml:"skip_directives,omitempty"`
+	SkipBranches         string                   `yaml:"skip_branches,omitempty"`
+	TemplateBindings     []TemplateBindingSpec    `yaml:"template_bindings,omitempty"`
+	TargetBranchPolicies []TargetBranchPolicySpec `yaml:"target_branch_policies,omitempty"`
+	AccessToken          string                   `yaml:"access_token,omitempty"`   // Apply only
+	WebhookSecret        string                   `yaml:"webhook_secret,omitempty"` // Apply only
+}
+
+// ConfigChange describes what applying a bundle did (or would do) to one entity
+type ConfigChange struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"` // create, update, unchanged
+}
+
+type ConfigApplyResult struct {
+	DryRun    bool           `json:"dry_run"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Changes   []ConfigChange `json:"changes"`
+}
+
+func (r *ConfigApplyResult) record(kind, name, action string) {
+	switch action {
+	case "create":
+		r.Created++
+	case "update":
+		r.Updated++
+	default:
+		r.Unchanged++
+	}
+	r.Changes = append(r.Changes, ConfigChange{Kind: kind, Name: name, Action: action})
+}
+
+// ConfigSyncService exports the instance configuration to YAML and applies such files idempotently
+type ConfigSyncService struct {
+	db *gorm.DB
+}
+
+func NewConfigSyncService(db *gorm.DB) *ConfigSyncService {
+	return &ConfigSyncService{db: db}
+}
+
+// isSecretConfigKey reports whether a system config key holds a credential that must not be exported
+func isSecretConfigKey(key string) bool {
+	for _, suffix := range []string{"_password", "_secret", "_token", "_api_key"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Export builds a bundle from the current configuration
+func (s *ConfigSyncService) Export() (*ConfigBundle, error) {
+	bundle := &ConfigBundle{Version: ConfigBundleVersion, SystemConfig: map[string]string{}}
+
+	var configs []models.SystemConfig
+	if err := s.db.Order("`key` ASC").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		if !isSecretConfigKey(c.Key) {
+			bundle.SystemConfig[c.Key] = c.Value
+		}
+	}
+
+	var prompts []models.PromptTemplate
+	if err := s.db.Where("is_system = ?", false).Order("name ASC").Find(&prompts).Error; err != nil {
+		return nil, err
+	}
+	for i := range prompts {
+		bundle.Prompts = append(bundle.Prompts, promptSpecOf(&prompts[i]))
+	}
+
+	var templates []models.ReviewTemplate
+	if err := s.db.Where("is_built_in = ?", false).Order("name ASC").Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	for i := range templates {
+		bundle.ReviewTemplates = append(bundle.ReviewTemplates, reviewTemplateSpecOf(&templates[i]))
+	}
+
+	var llms []models.LLMConfig
+	if err := s.db.Order("name ASC").Find(&llms).Error; err != nil {
+		return nil, err
+	}
+	for i := range llms {
+		bundle.LLMConfigs = append(bundle.LLMConfigs, llmConfigSpecOf(&llms[i]))
+	}
+
+	var bots []models.IMBot
+	if err := s.db.Order("name ASC").Find(&bots).Error; err != nil {
+		return nil, err
+	}
+	for i := range bots {
+		bundle.IMBots = append(bundle.IMBots, imBotSpecOf(&bots[i]))
+	}
+
+	var credentials []models.GitCredential
+	if err := s.db.Order("name ASC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	for i := range credentials {
+		bundle.GitCredentials = append(bundle.GitCredentials, gitCredentialSpecOf(&credentials[i]))
+	}
+
+	refs, err := s.loadConfigRefs(s.db)
+	if err != nil {
+		return nil, err
+	}
+	var projects []models.Project
+	if err := s.db.Preload("TemplateBindings").Preload("TargetBranchPolicies").Order("url ASC").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	for i := range projects {
+		bundle.Projects = append(bundle.Projects, projectSpecOf(&projects[i], refs))
+	}
+
+	return bundle, nil
+}
+
+// ExportYAML returns the current configuration as a YAML document
+func (s *ConfigSyncService) ExportYAML() ([]byte, error) {
+	bundle, err := s.Export()
+	if err != nil {
+		return nil, err
+	}
+	out, err := yaml.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# CodeSentry configuration exported at %s\n", time.Now().Format(time.RFC3339))
+	return append([]byte(header), out...), nil
+}
+
+// ParseConfigBundle decodes and validates a YAML bundle
+func ParseConfigBundle(data []byte) (*ConfigBundle, error) {
+	var bundle ConfigBundle
+	if err := yaml.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid yaml: %w", err)
+	}
+	if bundle.Version != 0 && bundle.Version != ConfigBundleVersion {
+		return nil, fmt.Errorf("unsupported config version %d", bundle.Version)
+	}
+	if err := bundle.validate(); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func (b *ConfigBundle) validate() error {
+	seen := make(map[string]bool)
+	check := func(kind, key string) error {
+		if key == "" {
+			return fmt.Errorf("%s entry is missing its name", kind)
+		}
+		if seen[kind+"/"+key] {
+			return fmt.Errorf("duplicate %s: %s", kind, key)
+		}
+		seen[kind+"/"+key] = true
+		return nil
+	}
+	for _, p := range b.Prompts {
+		if err := check("prompt", p.Name); err != nil {
+			return err
+		}
+	}
+	for _, t := range b.ReviewTemplates {
+		if err := check("review_template", t.Name); err != nil {
+			return err
+		}
+	}
+	for _, l := range b.LLMConfigs {
+		if err := check("llm_config", l.Name); err != nil {
+			return err
+		}
+	}
+	for _, bot := range b.IMBots {
+		if err := check("im_bot", bot.Name); err != nil {
+			return err
+		}
+		if err := ValidateQuietHours(bot.QuietHoursStart, bot.QuietHoursEnd, bot.Timezone); err != nil {
+			return fmt.Errorf("im_bot %s: %w", bot.Name, err)
+		}
+	}
+	for _, c := range b.GitCredentials {
+		if err := check("git_credential", c.Name); err != nil {
+			return err
+		}
+	}
+	for _, p := range b.Projects {
+		if err := check("project", p.URL); err != nil {
+			return err
+		}
+		if !ValidBranchFilterMode(p.BranchFilterMode) {
+			return fmt.Errorf("project %s: invalid branch_filter_mode %q (expected ignore or allow)", p.URL, p.BranchFilterMode)
+		}
+		if !ValidSignaturePolicy(p.SignaturePolicy) {
+			return fmt.Errorf("project %s: invalid signature_policy %q (expected off, annotate or enforce)", p.URL, p.SignaturePolicy)
+		}
+		if !ValidIaCReviewMode(p.IaCReviewMode) {
+			return fmt.Errorf("project %s: invalid iac_review_mode %q (expected auto or off)", p.URL, p.IaCReviewMode)
+		}
+		if !ValidMigrationPolicy(p.MigrationPolicy) {
+			return fmt.Errorf("project %s: invalid migration_policy %q (expected off, review or acknowledge)", p.URL, p.MigrationPolicy)
+		}
+	}
+	return nil
+}
+
+// Apply makes the configuration match the bundle. Entities missing from the bundle are left
+// alone. In dry-run mode all changes are rolled back and only the plan is returned.
+func (s *ConfigSyncService) Apply(bundle *ConfigBundle, dryRun bool) (*ConfigApplyResult, error) {
+	result := &ConfigApplyResult{DryRun: dryRun, Changes: []ConfigChange{}}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		steps := []func(*gorm.DB, *ConfigBundle, *ConfigApplyResult) error{
+			s.applySystemConfig,
+			s.applyPrompts,
+			s.applyReviewTemplates,
+			s.applyLLMConfigs,
+			s.applyIMBots,
+			s.applyGitCredentials,
+			s.applyProjects,
+		}
+		for _, step := range steps {
+			if err := step(tx, bundle, result); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errConfigDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errConfigDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *ConfigSyncService) applySystemConfig(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	keys := make([]string, 0, len(b.SystemConfig))
+	for k := range b.SystemConfig {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := b.SystemConfig[key]
+		if isSecretConfigKey(key) {
+			value = os.ExpandEnv(value)
+		}
+		var cfg models.SystemConfig
+		err := tx.Where("`key` = ?", key).First(&cfg).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&models.SystemConfig{Key: key, Value: value}).Error; err != nil {
+				return err
+			}
+			r.record("system_config", key, "create")
+		case err != nil:
+			return err
+		case cfg.Value == value:
+			r.record("system_config", key, "unchanged")
+		default:
+			if err := tx.Model(&cfg).Update("value", value).Error; err != nil {
+				return err
+			}
+			r.record("system_config", key, "update")
+		}
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyPrompts(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	for _, spec := range b.Prompts {
+		var prompt models.PromptTemplate
+		found, err := findByName(tx, &prompt, spec.Name)
+		if err != nil {
+			return err
+		}
+		if found && reflect.DeepEqual(promptSpecOf(&prompt), spec) {
+			r.record("prompt", spec.Name, "unchanged")
+			continue
+		}
+		prompt.Name = spec.Name
+		prompt.Description = spec.Description
+		prompt.Content = spec.Content
+		prompt.Variables = spec.Variables
+		prompt.IsDefault = spec.IsDefault
+		if err := tx.Save(&prompt).Error; err != nil {
+			return err
+		}
+		r.record("prompt", spec.Name, createOrUpdate(found))
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyReviewTemplates(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	for _, spec := range b.ReviewTemplates {
+		var template models.ReviewTemplate
+		found, err := findByName(tx, &template, spec.Name)
+		if err != nil {
+			return err
+		}
+		if found && reflect.DeepEqual(reviewTemplateSpecOf(&template), spec) {
+			r.record("review_template", spec.Name, "unchanged")
+			continue
+		}
+		template.Name = spec.Name
+		template.Type = spec.Type
+		template.Description = spec.Description
+		template.Content = spec.Content
+		template.IsActive = spec.IsActive
+		if err := tx.Save(&template).Error; err != nil {
+			return err
+		}
+		r.record("review_template", spec.Name, createOrUpdate(found))
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyLLMConfigs(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	for _, spec := range b.LLMConfigs {
+		var llm models.LLMConfig
+		found, err := findByName(tx, &llm, spec.Name)
+		if err != nil {
+			return err
+		}
+		apiKey := os.ExpandEnv(spec.APIKey)
+		spec.APIKey = ""
+		secretChanged := apiKey != "" && apiKey != llm.APIKey
+		if found && !secretChanged && reflect.DeepEqual(llmConfigSpecOf(&llm), spec) {
+			r.record("llm_config", spec.Name, "unchanged")
+			continue
+		}
+		llm.Name = spec.Name
+		llm.Provider = spec.Provider
+		llm.BaseURL = spec.BaseURL
+		llm.Model = spec.Model
+		llm.MaxTokens = spec.MaxTokens
+		llm.Temperature = spec.Temperature
+		llm.PromptCache = spec.PromptCache
+		llm.JSONMode = spec.JSONMode
+		llm.IsDefault = spec.IsDefault
+		llm.IsActive = spec.IsActive
+		if apiKey != "" {
+			llm.APIKey = apiKey
+		}
+		if err := tx.Save(&llm).Error; err != nil {
+			return err
+		}
+		r.record("llm_config", spec.Name, createOrUpdate(found))
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyIMBots(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	for _, spec := range b.IMBots {
+		var bot models.IMBot
+		found, err := findByName(tx, &bot, spec.Name)
+		if err != nil {
+			return err
+		}
+		secret := os.ExpandEnv(spec.Secret)
+		spec.Secret = ""
+		secretChanged := secret != "" && secret != bot.Secret
+		if found && !secretChanged && reflect.DeepEqual(imBotSpecOf(&bot), spec) {
+			r.record("im_bot", spec.Name, "unchanged")
+			continue
+		}
+		bot.Name = spec.Name
+		bot.Type = spec.Type
+		bot.Webhook = spec.Webhook
+		bot.Extra = spec.Extra
+		bot.IsActive = spec.IsActive
+		bot.ErrorNotify = spec.ErrorNotify
+		bot.DailyReportEnabled = spec.DailyReportEnabled
+		bot.MessageTemplate = spec.MessageTemplate
+		bot.MessageFields = spec.MessageFields
+		bot.MessageMaxLength = spec.MessageMaxLength
+		bot.MessageLanguage = spec.MessageLanguage
+		bot.DigestEnabled = spec.DigestEnabled
+		bot.DigestInterval = spec.DigestInterval
+		bot.QuietHoursStart = spec.QuietHoursStart
+		bot.QuietHoursEnd = spec.QuietHoursEnd
+		bot.Timezone = spec.Timezone
+		bot.SkipHolidays = spec.SkipHolidays
+		bot.HolidayCountry = spec.HolidayCountry
+		if secret != "" {
+			bot.Secret = secret
+		}
+		if err := tx.Save(&bot).Error; err != nil {
+			return err
+		}
+		r.record("im_bot", spec.Name, createOrUpdate(found))
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyGitCredentials(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	for _, spec := range b.GitCredentials {
+		var credential models.GitCredential
+		found, err := findByName(tx, &credential, spec.Name)
+		if err != nil {
+			return err
+		}
+		token, secret := os.ExpandEnv(spec.AccessToken), os.ExpandEnv(spec.WebhookSecret)
+		spec.AccessToken, spec.WebhookSecret = "", ""
+		secretChanged := (token != "" && token != credential.AccessToken) ||
+			(secret != "" && secret != credential.WebhookSecret)
+		if found && !secretChanged && reflect.DeepEqual(gitCredentialSpecOf(&credential), spec) {
+			r.record("git_credential", spec.Name, "unchanged")
+			continue
+		}
+		credential.Name = spec.Name
+		credential.Platform = spec.Platform
+		credential.BaseURL = spec.BaseURL
+		credential.AutoCreate = spec.AutoCreate
+		credential.DefaultEnabled = spec.DefaultEnabled
+		credential.FileExtensions = spec.FileExtensions
+		credential.ReviewEvents = spec.ReviewEvents
+		credential.IgnorePatterns = spec.IgnorePatterns
+		credential.IsActive = spec.IsActive
+		if token != "" {
+			credential.AccessToken = token
+		}
+		if secret != "" {
+			credential.WebhookSecret = secret
+		}
+		if err := tx.Save(&credential).Error; err != nil {
+			return err
+		}
+		r.record("git_credential", spec.Name, createOrUpdate(found))
+	}
+	return nil
+}
+
+func (s *ConfigSyncService) applyProjects(tx *gorm.DB, b *ConfigBundle, r *ConfigApplyResult) error {
+	refs, err := s.loadConfigRefs(tx)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range b.Projects {
+		spec.URL = strings.TrimSuffix(spec.URL, ".git")
+		var project models.Project
+		err := tx.Preload("TemplateBindings").Preload("TargetBranchPolicies").Where("url = ?", spec.URL).First(&project).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		token, secret := os.ExpandEnv(spec.AccessToken), os.ExpandEnv(spec.WebhookSecret)
+		spec.AccessToken, spec.WebhookSecret = "", ""
+		secretChanged := (token != "" && token != project.AccessToken) ||
+			(secret != "" && secret != project.WebhookSecret)
+		if found && !secretChanged && reflect.DeepEqual(projectSpecOf(&project, refs), spec) {
+			r.record("project", spec.URL, "unchanged")
+			continue
+		}
+
+		promptID, err := refs.lookup("prompt", refs.prompts, spec.Prompt)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", spec.URL, err)
+		}
+		llmID, err := refs.lookup("llm_config", refs.llmConfigs, spec.LLMConfig)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", spec.URL, err)
+		}
+		botID, err := refs.lookup("im_bot", refs.imBots, spec.IMBot)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", spec.URL, err)
+		}
+		releaseBotID, err := refs.lookup("im_bot", refs.imBots, spec.ReleaseIMBot)
+		if err != nil {
+			return fmt.Errorf("project %s: %w", spec.URL, err)
+		}
+		bindings := make([]TemplateBindingInput, 0, len(spec.TemplateBindings))
+		for _, bs := range spec.TemplateBindings {
+			templateID, err := refs.lookup("review_template", refs.reviewTemplates, bs.Template)
+			if err != nil || templateID == nil {
+				return fmt.Errorf("project %s: unknown review_template %q", spec.URL, bs.Template)
+			}
+			bindings = append(bindings, TemplateBindingInput{
+				TemplateID:    *templateID,
+				EventType:     bs.EventType,
+				BranchPattern: bs.BranchPattern,
+				Priority:      bs.Priority,
+			})
+		}
+		policies := make([]TargetBranchPolicyInput, 0, len(spec.TargetBranchPolicies))
+		for _, ps := range spec.TargetBranchPolicies {
+			templateID, err := refs.lookup("review_template", refs.reviewTemplates, ps.Template)
+			if err != nil {
+				return fmt.Errorf("project %s: unknown review_template %q", spec.URL, ps.Template)
+			}
+			policies = append(policies, TargetBranchPolicyInput{
+				BranchPattern:       ps.BranchPattern,
+				MinScore:            ps.MinScore,
+				MaxCriticalFindings: ps.MaxCriticalFindings,
+				ReviewTemplateID:    templateID,
+				Urgent:              ps.Urgent,
+			})
+		}
+
+		project.Name = spec.Name
+		project.URL = spec.URL
+		project.Platform = spec.Platform
+		project.FileExtensions = spec.FileExtensions
+		project.ReviewEvents = spec.ReviewEvents
+		project.BranchFilter = spec.BranchFilter
+		project.BranchFilterMode = spec.BranchFilterMode
+		if project.BranchFilterMode == "" {
+			project.BranchFilterMode = BranchFilterModeIgnore
+		}
+		project.AIEnabled = spec.AIEnabled
+		project.AIPromptID = promptID
+		project.AIPrompt = spec.AIPrompt
+		project.LLMConfigID = llmID
+		project.IncludePatterns = spec.IncludePatterns
+		project.IgnorePatterns = spec.IgnorePatterns
+		project.TechProfile = spec.TechProfile
+		project.CommentEnabled = spec.CommentEnabled
+		project.IMEnabled = spec.IMEnabled
+		project.IMBotID = botID
+		project.ReleaseIMBotID = releaseBotID
+		project.MinScore = spec.MinScore
+		project.MaxChangedLines = spec.MaxChangedLines
+		project.MaxFiles = spec.MaxFiles
+		project.MaxDiffBytes = spec.MaxDiffBytes
+		project.MaxFileBytes = spec.MaxFileBytes
+		project.BadgeEnabled = spec.BadgeEnabled
+		project.ReplayProtection = spec.ReplayProtection
+		project.SignaturePolicy = spec.SignaturePolicy
+		if project.SignaturePolicy == "" {
+			project.SignaturePolicy = SignaturePolicyOff
+		}
+		project.SignedBranches = spec.SignedBranches
+		project.IaCReviewMode = spec.IaCReviewMode
+		if project.IaCReviewMode == "" {
+			project.IaCReviewMode = IaCReviewAuto
+		}
+		project.MigrationPolicy = spec.MigrationPolicy
+		if project.MigrationPolicy == "" {
+			project.MigrationPolicy = MigrationPolicyReview
+		}
+		project.PerCommitReview = spec.PerCommitReview
+		project.SkipDirectives = spec.SkipDirectives
+		project.SkipBranches = spec.SkipBranches
+		if token != "" {
+			project.AccessToken = token
+		}
+		if secret != "" {
+			project.WebhookSecret = secret
+		}
+		project.TemplateBindings = nil
+		project.TargetBranchPolicies = nil
+		if err := tx.Omit("TemplateBindings", "TargetBranchPolicies").Save(&project).Error; err != nil {
+			return err
+		}
+		if err := replaceTemplateBindings(tx, project.ID, project.TenantID, bindings); err != nil {
+			return err
+		}
+		if err := replaceTargetBranchPolicies(tx, project.ID, project.TenantID, policies); err != nil {
+			return err
+		}
+		r.record("project", spec.URL, createOrUpdate(found))
+	}
+	return nil
+}
+
+// configRefs maps entity IDs to names and back for cross-references in a bundle
+type configRefs struct {
+	prompts, llmConfigs, imBots, reviewTemplates map[string]uint
+	names                                        map[string]map[uint]string
+}
+
+func (s *ConfigSyncService) loadConfigRefs(db *gorm.DB) (*configRefs, error) {
+	refs := &configRefs{names: make(map[string]map[uint]string)}
+	load := func(kind string, model interface{}) (map[string]uint, error) {
+		var rows []struct {
+			ID   uint
+			Name string
+		}
+		if err := db.Model(model).Select("id, name").Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		byName := make(map[string]uint, len(rows))
+		byID := make(map[uint]string, len(rows))
+		for _, row := range rows {
+			if _, ok := byName[row.Name]; !ok {
+				byName[row.Name] = row.ID
+			}
+			byID[row.ID] = row.Name
+		}
+		refs.names[kind] = byID
+		return byName, nil
+	}
+
+	var err error
+	if refs.prompts, err = load("prompt", &models.PromptTemplate{}); err != nil {
+		return nil, err
+	}
+	if refs.llmConfigs, err = load("llm_config", &models.LLMConfig{}); err != nil {
+		return nil, err
+	}
+	if refs.imBots, err = load("im_bot", &models.IMBot{}); err != nil {
+		return nil, err
+	}
+	if refs.reviewTemplates, err = load("review_template", &models.ReviewTemplate{}); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (r *configRefs) lookup(kind string, byName map[string]uint, name string) (*uint, error) {
+	if name == "" {
+		return nil, nil
+	}
+	id, ok := byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown %s %q", kind, name)
+	}
+	return &id, nil
+}
+
+func (r *configRefs) name(kind string, id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return r.names[kind][*id]
+}
+
+func findByName(tx *gorm.DB, dest interface{}, name string) (bool, error) {
+	err := tx.Where("name = ?", name).First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func createOrUpdate(found bool) string {
+	if found {
+		return "update"
+	}
+	return "create"
+}
+
+func promptSpecOf(p *models.PromptTemplate) PromptSpec {
+	return PromptSpec{
+		Name:        p.Name,
+		Description: p.Description,
+		Content:     p.Content,
+		Variables:   p.Variables,
+		IsDefault:   p.IsDefault,
+	}
+}
+
+func reviewTemplateSpecOf(t *models.ReviewTemplate) ReviewTemplateSpec {
+	return ReviewTemplateSpec{
+		Name:        t.Name,
+		Type:        t.Type,
+		Description: t.Description,
+		Content:     t.Content,
+		IsActive:    t.IsActive,
+	}
+}
+
+func llmConfigSpecOf(l *models.LLMConfig) LLMConfigSpec {
+	return LLMConfigSpec{
+		Name:        l.Name,
+		Provider:    l.Provider,
+		BaseURL:     l.BaseURL,
+		Model:       l.Model,
+		MaxTokens:   l.MaxTokens,
+		Temperature: l.Temperature,
+		PromptCache: l.PromptCache,
+		JSONMode:    l.JSONMode,
+		IsDefault:   l.IsDefault,
+		IsActive:    l.IsActive,
+	}
+}
+
+func imBotSpecOf(b *models.IMBot) IMBotSpec {
+	return IMBotSpec{
+		Name:               b.Name,
+		Type:               b.Type,
+		Webhook:            b.Webhook,
+		Extra:              b.Extra,
+		IsActive:           b.IsActive,
+		ErrorNotify:        b.ErrorNotify,
+		DailyReportEnabled: b.DailyReportEnabled,
+		MessageTemplate:    b.MessageTemplate,
+		MessageFields:      b.MessageFields,
+		MessageMaxLength:   b.MessageMaxLength,
+		MessageLanguage:    b.MessageLanguage,
+		DigestEnabled:      b.DigestEnabled,
+		DigestInterval:     b.DigestInterval,
+		QuietHoursStart:    b.QuietHoursStart,
+		QuietHoursEnd:      b.QuietHoursEnd,
+		Timezone:           b.Timezone,
+		SkipHolidays:       b.SkipHolidays,
+		HolidayCountry:     b.HolidayCountry,
+	}
+}
+
+func gitCredentialSpecOf(c *models.GitCredential) GitCredentialSpec {
+	return GitCredentialSpec{
+		Name:           c.Name,
+		Platform:       c.Platform,
+		BaseURL:        c.BaseURL,
+		AutoCreate:     c.AutoCreate,
+		DefaultEnabled: c.DefaultEnabled,
+		FileExtensions: c.FileExtensions,
+		ReviewEvents:   c.ReviewEvents,
+		IgnorePatterns: c.IgnorePatterns,
+		IsActive:       c.IsActive,
+	}
+}
+
+func projectSpecOf(p *models.Project, refs *configRefs) ProjectSpec {
+	spec := ProjectSpec{
+		Name:             p.Name,
+		URL:              p.URL,
+		Platform:         p.Platform,
+		FileExtensions:   p.FileExtensions,
+		ReviewEvents:     p.ReviewEvents,
+		BranchFilter:     p.BranchFilter,
+		AIEnabled:        p.AIEnabled,
+		Prompt:           refs.name("prompt", p.AIPromptID),
+		AIPrompt:         p.AIPrompt,
+		LLMConfig:        refs.name("llm_config", p.LLMConfigID),
+		IncludePatterns:  p.IncludePatterns,
+		IgnorePatterns:   p.IgnorePatterns,
+		TechProfile:      p.TechProfile,
+		CommentEnabled:   p.CommentEnabled,
+		IMEnabled:        p.IMEnabled,
+		IMBot:            refs.name("im_bot", p.IMBotID),
+		ReleaseIMBot:     refs.name("im_bot", p.ReleaseIMBotID),
+		MinScore:         p.MinScore,
+		MaxChangedLines:  p.MaxChangedLines,
+		MaxFiles:         p.MaxFiles,
+		MaxDiffBytes:     p.MaxDiffBytes,
+		MaxFileBytes:     p.MaxFileBytes,
+		BadgeEnabled:     p.BadgeEnabled,
+		ReplayProtection: p.ReplayProtection,
+		SignedBranches:   p.SignedBranches,
+		PerCommitReview:  p.PerCommitReview,
+		SkipDirectives:   p.SkipDirectives,
+		SkipBranches:     p.SkipBranches,
+	}
+	if p.SignaturePolicy != SignaturePolicyOff {
+		spec.SignaturePolicy = p.SignaturePolicy
+	}
+	if p.IaCReviewMode == IaCReviewOff {
+		spec.IaCReviewMode = IaCReviewOff
+	}
+	if p.MigrationPolicy != "" && p.MigrationPolicy != MigrationPolicyReview {
+		spec.MigrationPolicy = p.MigrationPolicy
+	}
+	// The default ignore mode is left out so bundles only mention allow-lists
+	if p.BranchFilterMode == BranchFilterModeAllow {
+		spec.BranchFilterMode = BranchFilterModeAllow
+	}
+	for _, b := range p.TemplateBindings {
+		templateID := b.TemplateID
+		spec.TemplateBindings = append(spec.TemplateBindings, TemplateBindingSpec{
+			Template:      refs.name("review_template", &templateID),
+			EventType:     b.EventType,
+			BranchPattern: b.BranchPattern,
+			Priority:      b.Priority,
+		})
+	}
+	for _, tp := range p.TargetBranchPolicies {
+		spec.TargetBranchPolicies = append(spec.TargetBranchPolicies, TargetBranchPolicySpec{
+			BranchPattern:       tp.BranchPattern,
+			MinScore:            tp.MinScore,
+			MaxCriticalFindings: tp.MaxCriticalFindings,
+			Template:            refs.name("review_template", tp.ReviewTemplateID),
+			Urgent:              tp.Urgent,
+		})
+	}
+	return spec
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSecretConfigKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"ldap_bind_password", true},
+		{"github_app_secret", true},
+		{"slack_bot_token", true},
+		{"auth_access_token_expire_hours", false},
+		{"system.min_score", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSecretConfigKey(tt.key); got != tt.want {
+			t.Errorf("isSecretConfigKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestParseConfigBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name: "valid",
+			yaml: `
+version: 1
+im_bots:
+  - name: team
+    type: slack
+    webhook: https://hooks.slack.com/x
+    quiet_hours_start: "22:00"
+    quiet_hours_end: "08:00"
+projects:
+  - name: api
+    url: https://github.com/acme/api
+    platform: github
+    im_bot: team
+    template_bindings:
+      - template: Security Review
+        branch_pattern: release/*
+`,
+		},
+		{name: "unsupported version", yaml: "version: 2", wantErr: "unsupported config version"},
+		{name: "invalid yaml", yaml: "projects: [", wantErr: "invalid yaml"},
+		{
+			name:    "duplicate project",
+			yaml:    "projects:\n  - url: https://a\n  - url: https://a\n",
+			wantErr: "duplicate project",
+		},
+		{name: "missing name", yaml: "llm_configs:\n  - base_url: https://a\n", wantErr: "missing its name"},
+		{
+			name:    "invalid quiet hours",
+			yaml:    "im_bots:\n  - name: b\n    quiet_hours_start: \"25:00\"\n    quiet_hours_end: \"08:00\"\n",
+			wantErr: "invalid quiet hours start",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := ParseConfigBundle([]byte(tt.yaml))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if len(bundle.Projects) != 1 || bundle.Projects[0].TemplateBindings[0].Template != "Security Review" {
+					t.Errorf("unexpected bundle: %+v", bundle)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ReviewStatusMissed marks commits found on a branch by the coverage gap monitor that never
+// triggered a webhook, e.g. lost deliveries or pushes while the server was down
+const ReviewStatusMissed = "missed"
+
+// CoverageGapGracePeriod leaves out the newest commits, whose webhook may still be on its way
+const CoverageGapGracePeriod = 30 * time.Minute
+
+// coverageGapMaxPages caps the pages of commits listed per project and scan
+const coverageGapMaxPages = 5
+
+// pushCommitLineRegex matches the "<short sha>: <message>" lines push reviews list their
+// commits with
+var pushCommitLineRegex = regexp.MustCompile(`(?m)^([0-9a-f]{8}): `)
+
+// platformCommit is a commit listed by the platform API
+type platformCommit struct {
+	SHA         string
+	Message     string
+	Author      string
+	AuthorEmail string
+	URL         string
+	Date        time.Time
+}
+
+type CoverageGapScanRequest struct {
+	ProjectID     uint `json:"project_id"`     // Scan one project, all active projects when 0
+	LookbackHours int  `json:"lookback_hours"` // Defaults to the configured lookback
+	Review        bool `json:"review"`         // Queue reviews of the missed commits
+	TenantID      uint `json:"-"`
+}
+
+// CoverageGapProjectResult is the scan of one project
+type CoverageGapProjectResult struct {
+	ProjectID   uint     `json:"project_id"`
+	ProjectName string   `json:"project_name"`
+	Branch      string   `json:"branch"` // Empty when the platform's default branch was listed
+	Checked     int      `json:"checked"`
+	Missed      []string `json:"missed"` // Commits without a webhook
+	Error       string   `json:"error,omitempty"`
+}
+
+// CoverageGapScanResult reports the commits found without a webhook
+type CoverageGapScanResult struct {
+	Projects []CoverageGapProjectResult `json:"projects"`
+	Checked  int                        `json:"checked"`
+	Missed   int                        `json:"missed"`
+	Queued   int                        `json:"queued"` // Missed commits queued for review
+}
+
+type CoverageGapService struct {
+	db            *gorm.DB
+	imports       *ImportCommitsService
+	configService *SystemConfigService
+}
+
+func NewCoverageGapService(db *gorm.DB) *CoverageGapService {
+	return &CoverageGapService{
+		db:            db,
+		imports:       NewImportCommitsService(db),
+		configService: NewSystemConfigService(db),
+	}
+}
+
+// pushedShortSHAs returns the short SHAs of the commits listed by push reviews
+func pushedShortSHAs(messages []string) map[string]bool {
+	seen := make(map[string]bool)
+	for _, message := range messages {
+		for _, m := range pushCommitLineRegex.FindAllStringSubmatch(message, -1) {
+			seen[m[1]] = true
+		}
+	}
+	return seen
+}
+
+// findCoverageGaps returns the commits older than cutoff that no review log knows of, neither
+// by their hash nor as part of a push, oldest first
+func findCoverageGaps(commits []platformCommit, known map[string]bool, pushed map[string]bool, cutoff time.Time) []platformCommit {
+	var gaps []platformCommit
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if c.Date.After(cutoff) || known[c.SHA] {
+			continue
+		}
+		if len(c.SHA) >= 8 && pushed[c.SHA[:8]] {
+			continue
+		}
+		gaps = append(gaps, c)
+	}
+	return gaps
+}
+
+// Scan lists the recent commits of the default branch of projects through the platform API
+// and records those that never triggered a webhook as missed reviews
+func (s *CoverageGapService) Scan(req *CoverageGapScanRequest) (*CoverageGapScanResult, error) {
+	lookback := req.LookbackHours
+	if lookback <= 0 {
+		lookback = s.configService.GetCoverageGapConfig().LookbackHours
+	}
+	since := time.Now().Add(-time.Duration(lookback) * time.Hour)
+
+	query := ScopeTenant(s.db.Where("archived = ? AND pending_approval = ?", false, false), req.TenantID)
+	if req.ProjectID > 0 {
+		query = query.Where("id = ?", req.ProjectID)
+	}
+	var projects []models.Project
+	if err := query.Order("id").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	if req.ProjectID > 0 && len(projects) == 0 {
+		return nil, fmt.Errorf("project not found")
+	}
+
+	result := &CoverageGapScanResult{Projects: []CoverageGapProjectResult{}}
+	for i := range projects {
+		project := &projects[i]
+		if !strings.Contains(project.ReviewEvents, "push") || !HasPlatformCredentials(s.db, project) {
+			continue
+		}
+		if project.DefaultBranch != "" && !ShouldReviewBranch(project, project.DefaultBranch) {
+			continue
+		}
+		scan := s.scanProject(project, since, req.Review)
+		result.Checked += scan.Checked
+		result.Missed += len(scan.Missed)
+		if req.Review {
+			result.Queued += len(scan.Missed)
+		}
+		result.Projects = append(result.Projects, *scan)
+	}
+	return result, nil
+}
+
+// scanProject checks the commits of a project's default branch since a time
+func (s *CoverageGapService) scanProject(project *models.Project, since time.Time, review bool) *CoverageGapProjectResult {
+	result := &CoverageGapProjectResult{ProjectID: project.ID, ProjectName: project.Name, Branch: project.DefaultBranch, Missed: []string{}}
+	commits, err := s.listCommits(project, project.DefaultBranch, since)
+	if err != nil {
+		logger.Module("coverage_gap").Warn().Err(err).Uint("project_id", project.ID).Msg("Failed to list commits")
+		result.Error = err.Error()
+		return result
+	}
+	result.Checked = len(commits)
+	if len(commits) == 0 {
+		return result
+	}
+
+	shas := make([]string, len(commits))
+	for i, c := range commits {
+		shas[i] = c.SHA
+	}
+	var knownHashes, messages []string
+	if err := s.db.Model(&models.ReviewLog{}).Where("project_id = ? AND commit_hash IN ?", project.ID, shas).
+		Pluck("commit_hash", &knownHashes).Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	// Pushes list their commits in the commit message; they are received after the commits
+	// were made, so a day before the window is enough
+	if err := s.db.Model(&models.ReviewLog{}).Where("project_id = ? AND event_type = ? AND created_at >= ?", project.ID, "push", since.AddDate(0, 0, -1)).
+		Pluck("commit_message", &messages).Error; err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	known := make(map[string]bool, len(knownHashes))
+	for _, sha := range knownHashes {
+		known[sha] = true
+	}
+
+	status := ReviewStatusMissed
+	if review {
+		status = ReviewStatusImportQueued
+	}
+	for _, c := range findCoverageGaps(commits, known, pushedShortSHAs(messages), time.Now().Add(-CoverageGapGracePeriod)) {
+		reviewLog := &models.ReviewLog{
+			ProjectID:     project.ID,
+			EventType:     "push",
+			CommitHash:    c.SHA,
+			CommitURL:     c.URL,
+			Branch:        project.DefaultBranch,
+			Author:        c.Author,
+			AuthorEmail:   c.AuthorEmail,
+			CommitMessage: c.Message,
+			ReviewStatus:  status,
+			CreatedAt:     c.Date,
+		}
+		if !review {
+			reviewLog.ErrorMessage = "No webhook was received for this commit"
+		}
+		if err := s.db.Create(reviewLog).Error; err != nil {
+			logger.Module("coverage_gap").Warn().Err(err).Uint("project_id", project.ID).Str("commit", c.SHA).Msg("Failed to record missed commit")
+			continue
+		}
+		result.Missed = append(result.Missed, c.SHA)
+	}
+
+	if len(result.Missed) > 0 {
+		logger.Module("coverage_gap").Warn().Uint("project_id", project.ID).Str("project", project.Name).Int("missed", len(result.Missed)).Msg("Commits without a webhook found")
+		if review {
+			go s.imports.reviewQueuedCommits(project, DefaultImportReviewRate)
+		}
+	}
+	return result
+}
+
+// ReviewMissed queues reviews of the missed commits of a project
+func (s *CoverageGapService) ReviewMissed(project *models.Project) (int64, error) {
+	res := s.db.Model(&models.ReviewLog{}).
+		Where("project_id = ? AND review_status = ?", project.ID, ReviewStatusMissed).
+		Updates(map[string]interface{}{"review_status": ReviewStatusImportQueued, "error_message": ""})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if res.RowsAffected > 0 {
+		go s.imports.reviewQueuedCommits(project, DefaultImportReviewRate)
+	}
+	return res.RowsAffected, nil
+}
+
+// listCommits lists the commits of a branch since a time, newest first. An empty branch
+// lists the platform's default branch.
+func (s *CoverageGapService) listCommits(project *models.Project, branch string, since time.Time) ([]platformCommit, error) {
+	switch project.Platform {
+	case "gitlab":
+		return s.listGitLabCommits(project, branch, since)
+	case "github":
+		return s.listGitHubCommits(project, branch, since)
+	case "bitbucket":
+		return s.listBitbucketCommits(project, branch, since)
+	}
+	return nil, fmt.Errorf("unsupported platform: %s", project.Platform)
+}
+
+func (s *CoverageGapService) listGitLabCommits(project *models.Project, branch string, since time.Time) ([]platformCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits?since=%s&per_page=100",
+		info.baseURL, url.PathEscape(info.projectPath), url.QueryEscape(since.Format(time.RFC3339)))
+	if branch != "" {
+		apiURL += "&ref_name=" + url.QueryEscape(branch)
+	}
+
+	var commits []platformCommit
+	for page := 1; page <= coverageGapMaxPages; page++ {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s&page=%d", apiURL, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("PRIVATE-TOKEN", project.AccessToken)
+
+		var pageCommits []gitLabCommit
+		if err := s.imports.getImportPage(req, "GitLab", &pageCommits); err != nil {
+			return nil, err
+		}
+		for _, c := range pageCommits {
+			commits = append(commits, platformCommit{SHA: c.ID, Message: c.Message, Author: c.AuthorName, AuthorEmail: c.AuthorEmail, URL: c.WebURL, Date: c.CommittedDate})
+		}
+		if len(pageCommits) < 100 {
+			break
+		}
+	}
+	return commits, nil
+}
+
+func (s *CoverageGapService) listGitHubCommits(project *models.Project, branch string, since time.Time) ([]platformCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits?since=%s&per_page=100",
+		info.owner, info.repo, url.QueryEscape(since.Format(time.RFC3339)))
+	if branch != "" {
+		apiURL += "&sha=" + url.QueryEscape(branch)
+	}
+
+	var commits []platformCommit
+	for page := 1; page <= coverageGapMaxPages; page++ {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s&page=%d", apiURL, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		req.Header.Set("Authorization", "token "+project.AccessToken)
+
+		var pageCommits []gitHubCommit
+		if err := s.imports.getImportPage(req, "GitHub", &pageCommits); err != nil {
+			return nil, err
+		}
+		for _, c := range pageCommits {
+			commits = append(commits, platformCommit{SHA: c.SHA, Message: c.Commit.Message, Author: c.Commit.Author.Name, AuthorEmail: c.Commit.Author.Email, URL: c.HTMLURL, Date: c.Commit.Author.Date})
+		}
+		if len(pageCommits) < 100 {
+			break
+		}
+	}
+	return commits, nil
+}
+
+func (s *CoverageGapService) listBitbucketCommits(project *models.Project, branch string, since time.Time) ([]platformCommit, error) {
+	api, err := NewBitbucketAPI(s.db, s.imports.httpClient, project)
+	if err != nil {
+		return nil, err
+	}
+	if api.Server {
+		return nil, ErrBitbucketServerImport
+	}
+	nextURL := api.CommitsURL()
+	if branch != "" {
+		nextURL += "&include=" + url.QueryEscape(branch)
+	}
+
+	var commits []platformCommit
+	for page := 1; page <= coverageGapMaxPages && nextURL != ""; page++ {
+		req, err := http.NewRequest("GET", nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := api.Authorize(req); err != nil {
+			return nil, err
+		}
+
+		var resp bitbucketCommitResponse
+		if err := s.imports.getImportPage(req, "Bitbucket", &resp); err != nil {
+			return nil, err
+		}
+		nextURL = resp.Next
+		for _, c := range resp.Values {
+			// Commits come newest first
+			if c.Date.Before(since) {
+				nextURL = ""
+				break
+			}
+			author, email := parseGitAuthor(c.Author.Raw)
+			if c.Author.User.DisplayName != "" {
+				author = c.Author.User.DisplayName
+			}
+			commits = append(commits, platformCommit{SHA: c.Hash, Message: c.Message, Author: author, AuthorEmail: email, URL: c.Links.HTML.Href, Date: c.Date})
+		}
+	}
+	return commits, nil
+}
+
+// parseGitAuthor splits a raw "Name <email>" author
+func parseGitAuthor(raw string) (name, email string) {
+	name = raw
+	if idx := strings.Index(raw, " <"); idx != -1 {
+		name = raw[:idx]
+		if end := strings.Index(raw, ">"); end > idx {
+			email = raw[idx+2 : end]
+		}
+	}
+	return name, email
+}
+
+// runScheduledScan scans all projects when the configured interval has passed since lastRun
+func (s *CoverageGapService) runScheduledScan(lastRun *time.Time) {
+	cfg := s.configService.GetCoverageGapConfig()
+	if !cfg.Enabled {
+		return
+	}
+	if !lastRun.IsZero() && time.Since(*lastRun) < time.Duration(cfg.IntervalHours)*time.Hour {
+		return
+	}
+	*lastRun = time.Now()
+
+	result, err := s.Scan(&CoverageGapScanRequest{LookbackHours: cfg.LookbackHours, Review: cfg.AutoReview})
+	if err != nil {
+		logger.Errorf("[CoverageGap] Scan failed: %v", err)
+		return
+	}
+	logger.Infof("[CoverageGap] Scanned %d projects: checked %d commits, %d missed, %d queued for review",
+		len(result.Projects), result.Checked, result.Missed, result.Queued)
+}
+
+var coverageGapStopChan chan struct{}
+
+// StartCoverageGapScheduler starts a goroutine that looks for commits without a webhook at
+// the configured interval
+func StartCoverageGapScheduler(db *gorm.DB) {
+	coverageGapStopChan = make(chan struct{})
+	go func() {
+		service := NewCoverageGapService(db)
+		var lastRun time.Time
+
+		RegisterScheduler("coverage_gap", time.Hour)
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("coverage_gap", func() error { service.runScheduledScan(&lastRun); return nil })
+			case <-coverageGapStopChan:
+				logger.Infof("[CoverageGap] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopCoverageGapScheduler stops the coverage gap scheduler
+func StopCoverageGapScheduler() {
+	if coverageGapStopChan != nil {
+		close(coverageGapStopChan)
+	}
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestPushedShortSHAs(t *testing.T) {
+	got := pushedShortSHAs([]string{
+		"a1b2c3d4: Fix login\nmore text\ne5f6a7b8: Add tests",
+		"Single commit message without a list",
+		"deadbeef: Refactor",
+	})
+	want := map[string]bool{"a1b2c3d4": true, "e5f6a7b8": true, "deadbeef": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pushedShortSHAs() = %v, want %v", got, want)
+	}
+}
+
+func TestFindCoverageGaps(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	commits := []platformCommit{
+		{SHA: "ffff000011112222", Date: now.Add(-5 * time.Minute)}, // Within the grace period
+		{SHA: "eeee000011112222", Date: now.Add(-1 * time.Hour)},   // Reviewed by hash
+		{SHA: "dddd000011112222", Date: now.Add(-2 * time.Hour)},   // Listed by a push review
+		{SHA: "cccc000011112222", Date: now.Add(-3 * time.Hour)},
+		{SHA: "bbbb000011112222", Date: now.Add(-4 * time.Hour)},
+	}
+	known := map[string]bool{"eeee000011112222": true}
+	pushed := map[string]bool{"dddd0000": true}
+
+	var got []string
+	for _, c := range findCoverageGaps(commits, known, pushed, now.Add(-CoverageGapGracePeriod)) {
+		got = append(got, c.SHA)
+	}
+	want := []string{"bbbb000011112222", "cccc000011112222"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findCoverageGaps() = %v, want %v (oldest first)", got, want)
+	}
+}
+
+func TestParseGitAuthor(t *testing.T) {
+	tests := []struct {
+		raw       string
+		wantName  string
+		wantEmail string
+	}{
+		{"Jane Doe <jane@example.com>", "Jane Doe", "jane@example.com"},
+		{"Jane Doe", "Jane Doe", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		name, email := parseGitAuthor(tt.raw)
+		if name != tt.wantName || email != tt.wantEmail {
+			t.Errorf("parseGitAuthor(%q) = %q, %q, want %q, %q", tt.raw, name, email, tt.wantName, tt.wantEmail)
+		}
+	}
+}
+
+func TestListGitLabCommits(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/api/v4/projects/group%2Frepo/repository/commits" {
+			http.NotFound(w, r)
+			return
+		}
+		gotQuery = r.URL.Query().Get("ref_name")
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"id": "abc123abc123", "message": "Fix bug", "author_name": "Jane", "author_email": "jane@example.com",
+				"committed_date": "2026-03-02T10:00:00Z", "web_url": "https://gitlab.example.com/c/abc123"},
+		})
+	}))
+	defer server.Close()
+
+	s := &CoverageGapService{imports: &ImportCommitsService{httpClient: server.Client()}}
+	project := &models.Project{Platform: "gitlab", URL: server.URL + "/group/repo", AccessToken: "secret"}
+	commits, err := s.listCommits(project, "main", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("listCommits() error = %v", err)
+	}
+	if gotQuery != "main" {
+		t.Errorf("ref_name = %q, want main", gotQuery)
+	}
+	if len(commits) != 1 || commits[0].SHA != "abc123abc123" || commits[0].Author != "Jane" || commits[0].Date.IsZero() {
+		t.Errorf("commits = %+v", commits)
+	}
+
+	project.AccessToken = "wrong"
+	if _, err := s.listCommits(project, "main", time.Now()); err == nil {
+		t.Error("listCommits() with a rejected token succeeded")
+	}
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Credential defaults that can be propagated to the projects auto-created from the credential
+const (
+	PropagateFileExtensions = "file_extensions"
+	PropagateReviewEvents   = "review_events"
+	PropagateIgnorePatterns = "ignore_patterns"
+)
+
+var propagatableFields = []string{PropagateFileExtensions, PropagateReviewEvents, PropagateIgnorePatterns}
+
+// ErrUnknownPropagationField is returned for fields that cannot be propagated
+var ErrUnknownPropagationField = errors.New("unknown field")
+
+// CredentialFieldChange is a project setting that differs from the credential default
+type CredentialFieldChange struct {
+	Field   string `json:"field"`
+	Current string `json:"current"` // Project value
+	Default string `json:"default"` // Credential value it would be set to
+}
+
+// CredentialPropagationProject lists the settings of an auto-created project that differ from
+// the credential defaults
+type CredentialPropagationProject struct {
+	ProjectID   uint                    `json:"project_id"`
+	ProjectName string                  `json:"project_name"`
+	ProjectURL  string                  `json:"project_url"`
+	Changes     []CredentialFieldChange `json:"changes"`
+}
+
+// CredentialPropagationPreview lists what propagating the credential defaults would change
+type CredentialPropagationPreview struct {
+	CredentialID uint                           `json:"credential_id"`
+	Fields       []string                       `json:"fields"`
+	Projects     []CredentialPropagationProject `json:"projects"` // Projects with settings to update
+	UpToDate     int                            `json:"up_to_date"`
+}
+
+// CredentialPropagationResult reports the projects updated by a propagation
+type CredentialPropagationResult struct {
+	Updated []uint `json:"updated"`
+	Skipped []uint `json:"skipped"` // Not auto-created from the credential, or already up to date
+}
+
+// propagationFields validates the fields to propagate, all of them when none is given
+func propagationFields(fields []string) ([]string, error) {
+	if len(fields) == 0 {
+		return propagatableFields, nil
+	}
+	for _, field := range fields {
+		if !slices.Contains(propagatableFields, field) {
+			return nil, fmt.Errorf("%w %q, expected %s", ErrUnknownPropagationField, field, strings.Join(propagatableFields, ", "))
+		}
+	}
+	return fields, nil
+}
+
+// propagationChanges returns the fields of a project that differ from the credential defaults
+func propagationChanges(cred *models.GitCredential, project *models.Project, fields []string) []CredentialFieldChange {
+	var changes []CredentialFieldChange
+	for _, field := range fields {
+		var current, def string
+		switch field {
+		case PropagateFileExtensions:
+			current, def = project.FileExtensions, cred.FileExtensions
+		case PropagateReviewEvents:
+			current, def = project.ReviewEvents, cred.ReviewEvents
+		case PropagateIgnorePatterns:
+			current, def = project.IgnorePatterns, cred.IgnorePatterns
+		}
+		if current != def {
+			changes = append(changes, CredentialFieldChange{Field: field, Current: current, Default: def})
+		}
+	}
+	return changes
+}
+
+// isAutoCreatedFrom reports whether a project was auto-created from a credential. Projects
+// created before the credential was recorded are recognized by having no creator and a URL
+// covered by the credential.
+func isAutoCreatedFrom(cred *models.GitCredential, project *models.Project) bool {
+	if project.CredentialID != nil {
+		return *project.CredentialID == cred.ID
+	}
+	return project.CreatedBy == 0 && project.Platform == cred.Platform && credentialMatch(cred, project.URL) >= 0
+}
+
+// AutoCreatedProjects returns the projects auto-created from a credential
+func (s *GitCredentialService) AutoCreatedProjects(cred *models.GitCredential) ([]models.Project, error) {
+	var candidates []models.Project
+	if err := s.db.Where("credential_id = ? OR (credential_id IS NULL AND created_by = ? AND platform = ?)", cred.ID, 0, cred.Platform).
+		Order("id").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	projects := candidates[:0]
+	for i := range candidates {
+		if isAutoCreatedFrom(cred, &candidates[i]) {
+			projects = append(projects, candidates[i])
+		}
+	}
+	return projects, nil
+}
+
+// PreviewPropagation lists the auto-created projects whose settings differ from the current
+// defaults of the credential, field by field
+func (s *GitCredentialService) PreviewPropagation(cred *models.GitCredential, fields []string) (*CredentialPropagationPreview, error) {
+	fields, err := propagationFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.AutoCreatedProjects(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &CredentialPropagationPreview{CredentialID: cred.ID, Fields: fields, Projects: []CredentialPropagationProject{}}
+	for i := range projects {
+		p := &projects[i]
+		changes := propagationChanges(cred, p, fields)
+		if len(changes) == 0 {
+			preview.UpToDate++
+			continue
+		}
+		preview.Projects = append(preview.Projects, CredentialPropagationProject{
+			ProjectID:   p.ID,
+			ProjectName: p.Name,
+			ProjectURL:  p.URL,
+			Changes:     changes,
+		})
+	}
+	return preview, nil
+}
+
+// Propagate sets the selected auto-created projects to the current defaults of the credential
+// for the given fields. Projects that were not auto-created from it are skipped.
+func (s *GitCredentialService) Propagate(cred *models.GitCredential, projectIDs []uint, fields []string) (*CredentialPropagationResult, error) {
+	fields, err := propagationFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	var projects []models.Project
+	if err := s.db.Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	found := make(map[uint]*models.Project, len(projects))
+	for i := range projects {
+		found[projects[i].ID] = &projects[i]
+	}
+
+	result := &CredentialPropagationResult{Updated: []uint{}, Skipped: []uint{}}
+	for _, id := range projectIDs {
+		p, ok := found[id]
+		if !ok || !isAutoCreatedFrom(cred, p) {
+			result.Skipped = append(result.Skipped, id)
+			continue
+		}
+		changes := propagationChanges(cred, p, fields)
+		updates := map[string]interface{}{"credential_id": cred.ID}
+		for _, change := range changes {
+			updates[change.Field] = change.Default
+		}
+		if err := s.db.Model(&models.Project{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return result, err
+		}
+		if len(changes) == 0 {
+			result.Skipped = append(result.Skipped, id)
+			continue
+		}
+		result.Updated = append(result.Updated, id)
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestPropagationFields(t *testing.T) {
+	tests := []struct {
+		fields  []string
+		want    []string
+		wantErr bool
+	}{
+		{nil, propagatableFields, false},
+		{[]string{PropagateIgnorePatterns}, []string{PropagateIgnorePatterns}, false},
+		{[]string{PropagateReviewEvents, "access_token"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := propagationFields(tt.fields)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrUnknownPropagationField)) {
+			t.Errorf("propagationFields(%v) error = %v, want error %v", tt.fields, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("propagationFields(%v) = %v, want %v", tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestPropagationChanges(t *testing.T) {
+	cred := &models.GitCredential{FileExtensions: ".go,.ts", ReviewEvents: "push,merge_request", IgnorePatterns: "vendor/,dist/"}
+	project := &models.Project{FileExtensions: ".go", ReviewEvents: "push,merge_request", IgnorePatterns: "vendor/"}
+
+	tests := []struct {
+		name   string
+		fields []string
+		want   []CredentialFieldChange
+	}{
+		{"all fields", propagatableFields, []CredentialFieldChange{
+			{Field: PropagateFileExtensions, Current: ".go", Default: ".go,.ts"},
+			{Field: PropagateIgnorePatterns, Current: "vendor/", Default: "vendor/,dist/"},
+		}},
+		{"selected field", []string{PropagateIgnorePatterns}, []CredentialFieldChange{
+			{Field: PropagateIgnorePatterns, Current: "vendor/", Default: "vendor/,dist/"},
+		}},
+		{"up to date", []string{PropagateReviewEvents}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := propagationChanges(cred, project, tt.fields); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("propagationChanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAutoCreatedFrom(t *testing.T) {
+	cred := &models.GitCredential{ID: 2, Platform: "gitlab", BaseURL: "https://GitLab.example.com/"}
+	credentialID := func(id uint) *uint { return &id }
+	tests := []struct {
+		name    string
+		project models.Project
+		want    bool
+	}{
+		{"recorded credential", models.Project{CredentialID: credentialID(2), URL: "https://elsewhere.com/a/b"}, true},
+		{"other credential", models.Project{CredentialID: credentialID(3), Platform: "gitlab", URL: "https://gitlab.example.com/a/b"}, false},
+		{"created before credentials were recorded", models.Project{Platform: "gitlab", URL: "https://gitlab.example.com/a/b"}, true},
+		{"created by a user", models.Project{Platform: "gitlab", URL: "https://gitlab.example.com/a/b", CreatedBy: 1}, false},
+		{"other host", models.Project{Platform: "gitlab", URL: "https://gitlab.com/a/b"}, false},
+		{"other platform", models.Project{Platform: "github", URL: "https://gitlab.example.com/a/b"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isAutoCreatedFrom(cred, &tt.project); got != tt.want {
+				t.Errorf("isAutoCreatedFrom() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ type DailyReportService struct {
 	notificationService *NotificationService
 	configService       *SystemConfigService
 	holidayService      *HolidayService
+	publisherService    *ReportPublisherService
 	cronScheduler       *cron.Cron
 	currentEntryID      cron.EntryID
 }
@@ -32,6 +33,7 @@ func NewDailyReportService(db *gorm.DB, aiService *AIService, notificationServic
 		notificationService: notificationService,
 		configService:       NewSystemConfigService(db),
 		holidayService:      NewHolidayService(),
+		publisherService:    NewReportPublisherService(db),
 	}
 }
 
@@ -45,6 +47,9 @@ type ReportStats struct {
 	PassedCount    int     `json:"passed_count"`
 	FailedCount    int     `json:"failed_count"`
 	PendingCount   int     `json:"pending_count"`
+
+	// Default-branch pushes and feature branches, whose pass rates differ by nature
+	BranchHealth *BranchHealth `json:"branch_health,omitempty"`
 }
 
 type ProjectStat struct {
@@ -73,7 +78,7 @@ func (s *DailyReportService) StartScheduler() {
 	s.updateSchedule()
 
 	s.cronScheduler.Start()
-	logger.Infof("[DailyReport] Scheduler started with timezone: %s", loc.String())
+	logger.Module("daily_report").Info().Str("timezone", loc.String()).Msg("Scheduler started")
 }
 
 func (s *DailyReportService) getTimezone() string {
@@ -84,7 +89,7 @@ func (s *DailyReportService) getTimezoneLocation() *time.Location {
 	tz := s.getTimezone()
 	loc, err := time.LoadLocation(tz)
 	if err != nil {
-		logger.Infof("[DailyReport] Invalid timezone %s, using Asia/Shanghai: %v", tz, err)
+		logger.Module("daily_report").Warn().Err(err).Str("timezone", tz).Msg("Invalid timezone, using Asia/Shanghai")
 		loc, _ = time.LoadLocation("Asia/Shanghai")
 	}
 	return loc
@@ -104,6 +109,35 @@ func (s *DailyReportService) getHolidayCountry() string {
 	return s.configService.GetWithDefault("daily_report_holiday_country", "CN")
 }
 
+func (s *DailyReportService) getHolidayMode() string {
+	return s.configService.GetWithDefault("daily_report_holiday_mode", "skip")
+}
+
+// reportPeriodStart returns the start of the period covered by the report for the given day.
+// In shift mode, the non-workdays directly before the day are folded into its report.
+func (s *DailyReportService) reportPeriodStart(startOfDay time.Time) time.Time {
+	if !s.isWorkdaysOnly() || s.getHolidayMode() != "shift" {
+		return startOfDay
+	}
+	return foldNonWorkdays(startOfDay, func(t time.Time) bool {
+		return s.holidayService.IsWorkday(t, s.getHolidayCountry())
+	})
+}
+
+// foldNonWorkdays walks back from startOfDay over consecutive non-workdays (at most 14)
+// and returns the start of the earliest one
+func foldNonWorkdays(startOfDay time.Time, isWorkday func(time.Time) bool) time.Time {
+	start := startOfDay
+	for i := 0; i < 14; i++ {
+		prev := start.AddDate(0, 0, -1)
+		if isWorkday(prev) {
+			break
+		}
+		start = prev
+	}
+	return start
+}
+
 func (s *DailyReportService) updateSchedule() {
 	if s.currentEntryID != 0 {
 		s.cronScheduler.Remove(s.currentEntryID)
@@ -121,15 +155,24 @@ func (s *DailyReportService) updateSchedule() {
 	cronExpr := fmt.Sprintf("%s %s * * *", minute, hour)
 
 	entryID, err := s.cronScheduler.AddFunc(cronExpr, func() {
-		s.GenerateAndSendReport()
+		RunScheduled("daily_report", func() error {
+			err := s.GenerateAndSendReport()
+			if publishErr := s.PublishWeeklyReports(); err == nil {
+				err = publishErr
+			}
+			return err
+		})
 	})
 	if err != nil {
-		logger.Infof("[DailyReport] Failed to add cron job: %v", err)
+		logger.Module("daily_report").Error().Err(err).Str("cron", cronExpr).Msg("Failed to add cron job")
 		return
 	}
 
 	s.currentEntryID = entryID
-	logger.Infof("[DailyReport] Scheduled at %s (cron: %s)", reportTime, cronExpr)
+	RegisterCronScheduler("daily_report", func() time.Time {
+		return s.cronScheduler.Entry(entryID).Next
+	})
+	logger.Module("daily_report").Info().Str("time", reportTime).Str("cron", cronExpr).Msg("Report scheduled")
 }
 
 func (s *DailyReportService) getReportTime() string {
@@ -178,7 +221,7 @@ func (s *DailyReportService) releaseLock(lockName, lockKey string) {
 
 func (s *DailyReportService) GenerateAndSendReport() error {
 	if !s.isEnabled() {
-		logger.Infof("[DailyReport] Daily report is disabled, skipping")
+		logger.Module("daily_report").Info().Msg("Daily report is disabled, skipping")
 		return nil
 	}
 
@@ -188,26 +231,44 @@ func (s *DailyReportService) GenerateAndSendReport() error {
 	if s.isWorkdaysOnly() {
 		countryCode := s.getHolidayCountry()
 		if !s.holidayService.IsWorkday(now, countryCode) {
-			logger.Infof("[DailyReport] Today is not a workday in %s, skipping", countryCode)
+			logger.Module("daily_report").Info().Str("country", countryCode).Msg("Today is not a workday, skipping")
 			return nil
 		}
 	}
 
-	today := now.Format("2006-01-02")
+	var tenants []models.Tenant
+	if err := s.db.Where("is_active = ?", true).Order("id ASC").Find(&tenants).Error; err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, tenant := range tenants {
+		if err := s.generateAndSendTenantReport(now.Format("2006-01-02"), tenant.ID); err != nil {
+			logger.Module("daily_report").Error().Err(err).Uint("tenant_id", tenant.ID).Str("tenant", tenant.Slug).Msg("Failed to send report")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+func (s *DailyReportService) generateAndSendTenantReport(today string, tenantID uint) error {
 	lockName := "daily_report"
-	lockKey := today
+	lockKey := fmt.Sprintf("%s:%d", today, tenantID)
 
 	if !s.acquireLock(lockName, lockKey, 10*time.Minute) {
-		logger.Infof("[DailyReport] Failed to acquire lock for %s, another pod is processing", today)
+		logger.Module("daily_report").Info().Str("lock", lockKey).Msg("Failed to acquire lock, another pod is processing")
 		return nil
 	}
 	defer s.releaseLock(lockName, lockKey)
 
-	report, err := s.GenerateReport()
+	report, err := s.GenerateReport(tenantID)
 	if err != nil {
 		return err
 	}
 
+	// Publishers record their own errors; a failed page update must not block IM delivery
+	s.publisherService.Publish(report, s.reportMessage(report))
+
 	if err := s.sendNotifications(report); err != nil {
 		report.NotifyError = err.Error()
 		s.db.Save(report)
@@ -218,59 +279,128 @@ func (s *DailyReportService) GenerateAndSendReport() error {
 	report.NotifiedAt = &notifiedAt
 	s.db.Save(report)
 
-	logger.Infof("[DailyReport] Report generated and sent successfully (ID: %d)", report.ID)
+	logger.Module("daily_report").Info().Uint("report_id", report.ID).Uint("tenant_id", tenantID).Msg("Report generated and sent")
 	return nil
 }
 
-func (s *DailyReportService) GenerateReport() (*models.DailyReport, error) {
-	logger.Infof("[DailyReport] Generating daily report...")
+func (s *DailyReportService) getWeeklyDay() time.Weekday {
+	day, err := strconv.Atoi(s.configService.GetWithDefault("daily_report_weekly_day", "1"))
+	if err != nil || day < 0 || day > 6 {
+		return time.Monday
+	}
+	return time.Weekday(day)
+}
+
+// PublishWeeklyReports publishes a report of the last seven days to the weekly report
+// publishers on the configured weekday. Weekly reports are not stored or sent to IM bots.
+func (s *DailyReportService) PublishWeeklyReports() error {
+	now := time.Now().In(s.getTimezoneLocation())
+	if now.Weekday() != s.getWeeklyDay() || !s.publisherService.HasActive("weekly") {
+		return nil
+	}
+	endOfPeriod := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfPeriod := endOfPeriod.AddDate(0, 0, -7)
+
+	var tenants []models.Tenant
+	if err := s.db.Where("is_active = ?", true).Order("id ASC").Find(&tenants).Error; err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, tenant := range tenants {
+		lockKey := fmt.Sprintf("%s:%d", endOfPeriod.Format("2006-01-02"), tenant.ID)
+		if !s.acquireLock("weekly_report", lockKey, 10*time.Minute) {
+			logger.Module("daily_report").Info().Str("lock", lockKey).Msg("Failed to acquire weekly report lock, another pod is processing")
+			continue
+		}
+		report, err := s.generateReport(tenant.ID, "weekly", startOfPeriod, endOfPeriod)
+		if err == nil {
+			report.TenantID = tenant.ID
+			err = s.publisherService.Publish(report, s.reportMessage(report))
+		}
+		s.releaseLock("weekly_report", lockKey)
+		if err != nil {
+			logger.Module("daily_report").Error().Err(err).Uint("tenant_id", tenant.ID).Str("tenant", tenant.Slug).Msg("Failed to publish weekly report")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// GenerateReport generates (or regenerates) today's report for a tenant
+func (s *DailyReportService) GenerateReport(tenantID uint) (*models.DailyReport, error) {
+	logger.Module("daily_report").Info().Uint("tenant_id", tenantID).Msg("Generating daily report")
 
 	today := time.Now()
 	startOfDay := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
 	endOfDay := startOfDay.Add(24 * time.Hour)
+	periodStart := s.reportPeriodStart(startOfDay)
 
-	report, err := s.generateReport(startOfDay, endOfDay)
+	report, err := s.generateReport(tenantID, "daily", periodStart, endOfDay)
 	if err != nil {
-		logger.Infof("[DailyReport] Failed to generate report: %v", err)
+		logger.Module("daily_report").Error().Err(err).Uint("tenant_id", tenantID).Msg("Failed to generate report")
 		return nil, err
 	}
+	report.TenantID = tenantID
+	report.ReportDate = startOfDay
+	if !periodStart.Equal(startOfDay) {
+		logger.Module("daily_report").Info().Uint("tenant_id", tenantID).Str("since", periodStart.Format("2006-01-02")).Msg("Report covers non-workdays")
+	}
 
 	var existingReport models.DailyReport
-	if err := s.db.Where("report_date = ?", startOfDay).First(&existingReport).Error; err == nil {
+	if err := s.db.Where("tenant_id = ? AND report_date = ?", tenantID, startOfDay).First(&existingReport).Error; err == nil {
 		report.ID = existingReport.ID
 		report.CreatedAt = existingReport.CreatedAt
 		report.NotifiedAt = existingReport.NotifiedAt
 		if err := s.db.Save(report).Error; err != nil {
-			logger.Infof("[DailyReport] Failed to update report: %v", err)
+			logger.Module("daily_report").Error().Err(err).Uint("report_id", report.ID).Msg("Failed to update report")
 			return nil, err
 		}
-		logger.Infof("[DailyReport] Updated existing report (ID: %d)", report.ID)
+		logger.Module("daily_report").Info().Uint("report_id", report.ID).Uint("tenant_id", tenantID).Msg("Updated existing report")
 	} else {
 		if err := s.db.Create(report).Error; err != nil {
-			logger.Infof("[DailyReport] Failed to save report: %v", err)
+			logger.Module("daily_report").Error().Err(err).Uint("tenant_id", tenantID).Msg("Failed to save report")
 			return nil, err
 		}
-		logger.Infof("[DailyReport] Created new report (ID: %d)", report.ID)
+		logger.Module("daily_report").Info().Uint("report_id", report.ID).Uint("tenant_id", tenantID).Msg("Created new report")
 	}
 
 	return report, nil
 }
 
-func (s *DailyReportService) generateReport(startTime, endTime time.Time) (*models.DailyReport, error) {
-	stats := s.collectStats(startTime, endTime)
-	topProjects := s.getTopProjects(startTime, endTime, 5)
-	topAuthors := s.getTopAuthors(startTime, endTime, 5)
-	lowScoreReviews := s.getLowScoreReviews(startTime, endTime)
+func (s *DailyReportService) generateReport(tenantID uint, reportType string, startTime, endTime time.Time) (*models.DailyReport, error) {
+	reviews := func() *gorm.DB {
+		return ScopeStatsReviewLogs(s.db.Model(&models.ReviewLog{}), tenantID).
+			Where("review_logs.created_at BETWEEN ? AND ?", startTime, endTime)
+	}
+	stats := s.collectStats(reviews())
+	stats.BranchHealth = s.collectBranchHealth(reviews())
+	topProjects := s.getTopProjects(reviews(), 5)
+	topAuthors := s.getTopAuthors(reviews(), 5)
+	lowScoreReviews := s.getLowScoreReviews(reviews())
+	// Reports are sent to IM groups, so privacy mode pseudonymizes them for everyone
+	if aliaser := s.configService.GetAuthorAliaser(); aliaser != nil {
+		for i := range topAuthors {
+			topAuthors[i].Name = aliaser.Alias(topAuthors[i].Name)
+		}
+		for i := range lowScoreReviews {
+			lowScoreReviews[i].Author = aliaser.Alias(lowScoreReviews[i].Author)
+		}
+	}
 
 	topProjectsJSON, _ := json.Marshal(topProjects)
 	topAuthorsJSON, _ := json.Marshal(topAuthors)
 	lowScoreReviewsJSON, _ := json.Marshal(lowScoreReviews)
+	var branchHealthJSON []byte
+	if stats.BranchHealth != nil {
+		branchHealthJSON, _ = json.Marshal(stats.BranchHealth)
+	}
 
-	aiAnalysis, modelUsed := s.generateAIAnalysis(stats, topProjects, topAuthors, lowScoreReviews)
+	aiAnalysis, modelUsed := s.generateAIAnalysis(reportType, stats, topProjects, topAuthors, lowScoreReviews)
 
 	report := &models.DailyReport{
 		ReportDate:      startTime,
-		ReportType:      "daily",
+		ReportType:      reportType,
 		TotalProjects:   stats.TotalProjects,
 		TotalCommits:    stats.TotalCommits,
 		TotalAuthors:    stats.TotalAuthors,
@@ -283,6 +413,7 @@ func (s *DailyReportService) generateReport(startTime, endTime time.Time) (*mode
 		TopProjects:     string(topProjectsJSON),
 		TopAuthors:      string(topAuthorsJSON),
 		LowScoreReviews: string(lowScoreReviewsJSON),
+		BranchHealth:    string(branchHealthJSON),
 		AIAnalysis:      aiAnalysis,
 		AIModelUsed:     modelUsed,
 	}
@@ -290,7 +421,7 @@ func (s *DailyReportService) generateReport(startTime, endTime time.Time) (*mode
 	return report, nil
 }
 
-func (s *DailyReportService) collectStats(startTime, endTime time.Time) ReportStats {
+func (s *DailyReportService) collectStats(reviews *gorm.DB) ReportStats {
 	var stats ReportStats
 	threshold := s.getLowScoreThreshold()
 
@@ -307,8 +438,7 @@ func (s *DailyReportService) collectStats(startTime, endTime time.Time) ReportSt
 		PendingCount   int64   `gorm:"column:pending_count"`
 	}
 
-	s.db.Model(&models.ReviewLog{}).
-		Where("created_at BETWEEN ? AND ?", startTime, endTime).
+	reviews.
 		Select(`
 			COUNT(DISTINCT project_id) AS total_projects,
 			COUNT(*) AS total_commits,
@@ -335,16 +465,29 @@ func (s *DailyReportService) collectStats(startTime, endTime time.Time) ReportSt
 	return stats
 }
 
-func (s *DailyReportService) getTopProjects(startTime, endTime time.Time, limit int) []ProjectStat {
+// collectBranchHealth splits the automatic reviews between default-branch pushes and
+// feature branches, passing at the low score threshold like the report totals
+func (s *DailyReportService) collectBranchHealth(reviews *gorm.DB) *BranchHealth {
+	query := reviews.
+		Joins("LEFT JOIN projects ON projects.id = review_logs.project_id").
+		Where("review_logs.is_manual = ?", false)
+	health, err := collectBranchHealth(query, "?", s.getLowScoreThreshold())
+	if err != nil {
+		logger.Module("daily_report").Warn().Err(err).Msg("Failed to collect branch health")
+		return nil
+	}
+	return health
+}
+
+func (s *DailyReportService) getTopProjects(reviews *gorm.DB, limit int) []ProjectStat {
 	var results []struct {
 		ProjectID   uint
 		CommitCount int
 		AvgScore    float64
 	}
 
-	s.db.Model(&models.ReviewLog{}).
+	reviews.
 		Select("project_id, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score").
-		Where("created_at BETWEEN ? AND ?", startTime, endTime).
 		Group("project_id").
 		Order("commit_count DESC").
 		Limit(limit).
@@ -365,16 +508,15 @@ func (s *DailyReportService) getTopProjects(startTime, endTime time.Time, limit
 	return stats
 }
 
-func (s *DailyReportService) getTopAuthors(startTime, endTime time.Time, limit int) []AuthorStat {
+func (s *DailyReportService) getTopAuthors(reviews *gorm.DB, limit int) []AuthorStat {
 	var results []struct {
 		Author      string
 		CommitCount int
 		AvgScore    float64
 	}
 
-	s.db.Model(&models.ReviewLog{}).
+	reviews.
 		Select("author, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score").
-		Where("created_at BETWEEN ? AND ?", startTime, endTime).
 		Group("author").
 		Order("commit_count DESC").
 		Limit(limit).
@@ -392,18 +534,18 @@ func (s *DailyReportService) getTopAuthors(startTime, endTime time.Time, limit i
 	return stats
 }
 
-func (s *DailyReportService) getLowScoreReviews(startTime, endTime time.Time) []LowScoreReview {
+func (s *DailyReportService) getLowScoreReviews(reviews *gorm.DB) []LowScoreReview {
 	threshold := s.getLowScoreThreshold()
 
-	var reviews []models.ReviewLog
-	s.db.Preload("Project").
-		Where("created_at BETWEEN ? AND ? AND score IS NOT NULL AND score < ?", startTime, endTime, threshold).
+	var logs []models.ReviewLog
+	reviews.Preload("Project").
+		Where("score IS NOT NULL AND score < ?", threshold).
 		Order("score ASC").
 		Limit(10).
-		Find(&reviews)
+		Find(&logs)
 
 	var lowScores []LowScoreReview
-	for _, r := range reviews {
+	for _, r := range logs {
 		projectName := ""
 		if r.Project != nil {
 			projectName = r.Project.Name
@@ -425,15 +567,24 @@ func (s *DailyReportService) getLowScoreReviews(startTime, endTime time.Time) []
 	return lowScores
 }
 
-func (s *DailyReportService) generateAIAnalysis(stats ReportStats, topProjects []ProjectStat, topAuthors []AuthorStat, lowScores []LowScoreReview) (string, string) {
+// reportLabels returns the report name and overview heading of a report type
+func reportLabels(reportType string) (string, string) {
+	if reportType == "weekly" {
+		return "周报", "本周概览"
+	}
+	return "日报", "今日概览"
+}
+
+func (s *DailyReportService) generateAIAnalysis(reportType string, stats ReportStats, topProjects []ProjectStat, topAuthors []AuthorStat, lowScores []LowScoreReview) (string, string) {
 	if s.aiService == nil {
-		return s.buildDefaultSummary(stats, topProjects, topAuthors, lowScores), ""
+		return s.buildDefaultSummary(reportType, stats, topProjects, topAuthors, lowScores), ""
 	}
+	name, overview := reportLabels(reportType)
 
 	lowScoreThreshold := s.getLowScoreThreshold()
 
 	contextData := map[string]interface{}{
-		"report_type":         "daily_summary",
+		"report_type":         reportType + "_summary",
 		"date":                time.Now().Format("2006-01-02"),
 		"metrics":             stats,
 		"top_projects":        topProjects,
@@ -444,7 +595,7 @@ func (s *DailyReportService) generateAIAnalysis(stats ReportStats, topProjects [
 
 	contextJSON, _ := json.Marshal(contextData)
 
-	prompt := fmt.Sprintf(`你是一位技术团队经理，请根据以下代码审查数据生成一份简洁的日报摘要。
+	prompt := fmt.Sprintf(`你是一位技术团队经理，请根据以下代码审查数据生成一份简洁的%s摘要。
 
 数据：
 %s
@@ -453,39 +604,45 @@ func (s *DailyReportService) generateAIAnalysis(stats ReportStats, topProjects [
 - 低分阈值为 %.0f 分，低于此分数的提交需要特别关注
 - passed_count 表示分数 >= %.0f 的提交数
 - failed_count 表示分数 < %.0f 的提交数
+- branch_health 区分默认分支（主干）推送与功能分支（含合并请求）的审查，功能分支上的在制代码通常分数更低；评价主干健康度请使用 default_branch 的通过率，untracked 为尚未获取默认分支的项目的审查数
 
-请生成一份 Markdown 格式的日报，包含：
-1. 今日概览（审查数、通过率、平均分、贡献者数）
+请生成一份 Markdown 格式的%s，包含：
+1. %s（审查数、通过率、平均分、贡献者数，并分别给出主干与功能分支的通过率）
 2. Top 活跃项目（最多5个）
 3. 需要关注的低分提交（分数 < %.0f，如果有）
 4. 1-2 条简短的 AI 洞察/建议
 
-注意：输出要简洁，适合在 IM 群里阅读，总字数控制在 500 字以内。`, string(contextJSON), lowScoreThreshold, lowScoreThreshold, lowScoreThreshold, lowScoreThreshold)
+注意：输出要简洁，适合在 IM 群里阅读，总字数控制在 500 字以内。`, name, string(contextJSON), lowScoreThreshold, lowScoreThreshold, lowScoreThreshold, name, overview, lowScoreThreshold)
 
 	llmConfigID := s.getLLMConfigID()
 	content, modelName, err := s.aiService.CallWithConfig(context.Background(), llmConfigID, prompt)
 
 	if err != nil {
-		logger.Infof("[DailyReport] AI analysis failed: %v", err)
-		return s.buildDefaultSummary(stats, topProjects, topAuthors, lowScores), ""
+		logger.Module("daily_report").Warn().Err(err).Str("report_type", reportType).Msg("AI analysis failed")
+		return s.buildDefaultSummary(reportType, stats, topProjects, topAuthors, lowScores), ""
 	}
 
 	return content, modelName
 }
 
-func (s *DailyReportService) buildDefaultSummary(stats ReportStats, topProjects []ProjectStat, topAuthors []AuthorStat, lowScores []LowScoreReview) string {
+func (s *DailyReportService) buildDefaultSummary(reportType string, stats ReportStats, topProjects []ProjectStat, topAuthors []AuthorStat, lowScores []LowScoreReview) string {
 	var sb strings.Builder
+	name, overview := reportLabels(reportType)
 
-	sb.WriteString(fmt.Sprintf("## 📊 CodeSentry 日报 - %s\n\n", time.Now().Format("2006-01-02")))
+	sb.WriteString(fmt.Sprintf("## 📊 CodeSentry %s - %s\n\n", name, time.Now().Format("2006-01-02")))
 
 	passRate := 0.0
 	if stats.TotalCommits > 0 {
 		passRate = float64(stats.PassedCount) / float64(stats.PassedCount+stats.FailedCount) * 100
 	}
 
-	sb.WriteString("### 今日概览\n")
+	sb.WriteString("### " + overview + "\n")
 	sb.WriteString(fmt.Sprintf("- 🔍 审查数：%d（通过 %d / 未通过 %d）\n", stats.TotalCommits, stats.PassedCount, stats.FailedCount))
 	sb.WriteString(fmt.Sprintf("- 📈 平均分：%.1f 分 | 通过率：%.0f%%\n", stats.AverageScore, passRate))
+	if h := stats.BranchHealth; h != nil && (h.DefaultBranch.Reviews > 0 || h.FeatureBranches.Reviews > 0) {
+		sb.WriteString(fmt.Sprintf("- 🌳 主干：%d 次审查，通过率 %.0f%% | 功能分支：%d 次审查，通过率 %.0f%%\n",
+			h.DefaultBranch.Reviews, h.DefaultBranch.PassRate, h.FeatureBranches.Reviews, h.FeatureBranches.PassRate))
+	}
 	sb.WriteString(fmt.Sprintf("- 👥 贡献者：%d 人\n", stats.TotalAuthors))
 	sb.WriteString(fmt.Sprintf("- 📁 活跃项目：%d 个\n\n", stats.TotalProjects))
 
@@ -533,45 +690,34 @@ func (s *DailyReportService) getIMBotIDs() []uint {
 func (s *DailyReportService) sendNotifications(report *models.DailyReport) error {
 	var bots []models.IMBot
 
+	// A tenant's report only goes to that tenant's bots
+	query := ScopeTenant(s.db, report.TenantID)
 	botIDs := s.getIMBotIDs()
 	if len(botIDs) > 0 {
-		if err := s.db.Where("id IN ? AND is_active = ?", botIDs, true).Find(&bots).Error; err != nil {
+		if err := query.Where("id IN ? AND is_active = ?", botIDs, true).Find(&bots).Error; err != nil {
 			return err
 		}
 	} else {
-		if err := s.db.Where("is_active = ? AND daily_report_enabled = ?", true, true).Find(&bots).Error; err != nil {
+		if err := query.Where("is_active = ? AND daily_report_enabled = ?", true, true).Find(&bots).Error; err != nil {
 			return err
 		}
 	}
 
 	if len(bots) == 0 {
-		logger.Infof("[DailyReport] No bots enabled for daily report")
+		logger.Module("daily_report").Info().Uint("report_id", report.ID).Msg("No bots enabled for daily report")
 		return nil
 	}
 
-	message := report.AIAnalysis
-	if message == "" {
-		message = s.buildDefaultSummary(
-			ReportStats{
-				TotalProjects: report.TotalProjects,
-				TotalCommits:  report.TotalCommits,
-				TotalAuthors:  report.TotalAuthors,
-				AverageScore:  report.AverageScore,
-				PassedCount:   report.PassedCount,
-				FailedCount:   report.FailedCount,
-			},
-			nil, nil, nil,
-		)
-	}
+	message := s.reportMessage(report)
 
 	var lastErr error
 	successCount := 0
 	for _, bot := range bots {
 		if err := s.notificationService.SendErrorNotification(&bot, message); err != nil {
-			logger.Infof("[DailyReport] Failed to send to bot %s: %v", bot.Name, err)
+			logger.Module("daily_report").Warn().Err(err).Uint("report_id", report.ID).Str("bot", bot.Name).Msg("Failed to send report to bot")
 			lastErr = err
 		} else {
-			logger.Infof("[DailyReport] Sent to bot %s", bot.Name)
+			logger.Module("daily_report").Info().Uint("report_id", report.ID).Str("bot", bot.Name).Msg("Report sent to bot")
 			successCount++
 		}
 	}
@@ -582,14 +728,48 @@ func (s *DailyReportService) sendNotifications(report *models.DailyReport) error
 	return nil
 }
 
-func (s *DailyReportService) List(page, pageSize int) ([]models.DailyReport, int64, error) {
+// reportMessage returns the markdown body of a report, falling back to the default summary
+func (s *DailyReportService) reportMessage(report *models.DailyReport) string {
+	if report.AIAnalysis != "" {
+		return report.AIAnalysis
+	}
+	return s.buildDefaultSummary(
+		report.ReportType,
+		ReportStats{
+			TotalProjects: report.TotalProjects,
+			TotalCommits:  report.TotalCommits,
+			TotalAuthors:  report.TotalAuthors,
+			AverageScore:  report.AverageScore,
+			PassedCount:   report.PassedCount,
+			FailedCount:   report.FailedCount,
+			BranchHealth:  decodeBranchHealth(report.BranchHealth),
+		},
+		nil, nil, nil,
+	)
+}
+
+// decodeBranchHealth decodes the branch health stored with a report; nil for reports
+// generated before it was recorded
+func decodeBranchHealth(raw string) *BranchHealth {
+	if raw == "" {
+		return nil
+	}
+	var health BranchHealth
+	if err := json.Unmarshal([]byte(raw), &health); err != nil {
+		return nil
+	}
+	return &health
+}
+
+// List returns reports of a tenant, 0 = all tenants
+func (s *DailyReportService) List(page, pageSize int, tenantID uint) ([]models.DailyReport, int64, error) {
 	var reports []models.DailyReport
 	var total int64
 
-	s.db.Model(&models.DailyReport{}).Count(&total)
+	ScopeTenant(s.db.Model(&models.DailyReport{}), tenantID).Count(&total)
 
 	offset := (page - 1) * pageSize
-	if err := s.db.Order("report_date DESC").Offset(offset).Limit(pageSize).Find(&reports).Error; err != nil {
+	if err := ScopeTenant(s.db, tenantID).Order("report_date DESC").Offset(offset).Limit(pageSize).Find(&reports).Error; err != nil {
 		return nil, 0, err
 	}
 
@@ -604,6 +784,15 @@ func (s *DailyReportService) GetByID(id uint) (*models.DailyReport, error) {
 	return &report, nil
 }
 
+// Publish publishes a stored report to its report publishers
+func (s *DailyReportService) Publish(id uint) error {
+	report, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	return s.publisherService.Publish(report, s.reportMessage(report))
+}
+
 func (s *DailyReportService) ResendNotification(id uint) error {
 	report, err := s.GetByID(id)
 	if err != nil {
@@ -7,6 +7,22 @@ import (
 	"gorm.io/gorm"
 )
 
+// testsMissingCountSQL counts the reviews flagged by test coverage nudging
+const testsMissingCountSQL = "COALESCE(SUM(CASE WHEN untested_files > 0 THEN 1 ELSE 0 END), 0) as tests_missing_count"
+
+// humanVerdictCountSQL counts the reviews maintainers accepted or rejected
+const humanVerdictCountSQL = "COALESCE(SUM(CASE WHEN human_verdict = 'accepted' THEN 1 ELSE 0 END), 0) as human_accepted, " +
+	"COALESCE(SUM(CASE WHEN human_verdict = 'rejected' THEN 1 ELSE 0 END), 0) as human_rejected"
+
+// statsScoreColumn returns the score averaged in statistics: the AI score, or the human
+// score where a maintainer gave one when the human verdict config says so
+func statsScoreColumn(db *gorm.DB) string {
+	if NewSystemConfigService(db).GetHumanVerdictConfig().ScoreInStats {
+		return "COALESCE(human_score, score)"
+	}
+	return "score"
+}
+
 type DashboardService struct {
 	db *gorm.DB
 }
@@ -20,6 +36,8 @@ type DashboardStatsRequest struct {
 	EndDate      string `form:"end_date"`
 	ProjectLimit int    `form:"project_limit"`
 	AuthorLimit  int    `form:"author_limit"`
+	ProjectIDs   string `form:"project_ids"` // Comma-separated project group
+	TenantID     uint   `form:"-"`           // Set from the request context, 0 = all tenants
 }
 
 type DashboardStats struct {
@@ -27,6 +45,8 @@ type DashboardStats struct {
 	Contributors   int64   `json:"contributors"`
 	TotalCommits   int64   `json:"total_commits"`
 	AverageScore   float64 `json:"average_score"`
+	HumanAccepted  int64   `json:"human_accepted"` // Reviews accepted by a maintainer's verdict
+	HumanRejected  int64   `json:"human_rejected"` // Reviews rejected by a maintainer's verdict
 }
 
 type ProjectStats struct {
@@ -36,6 +56,8 @@ type ProjectStats struct {
 	AvgScore    float64 `json:"avg_score"`
 	Additions   int64   `json:"additions"`
 	Deletions   int64   `json:"deletions"`
+	// Reviews changing source files without a matching test change
+	TestsMissingCount int64 `json:"tests_missing_count"`
 }
 
 type AuthorStats struct {
@@ -44,6 +66,8 @@ type AuthorStats struct {
 	AvgScore    float64 `json:"avg_score"`
 	Additions   int64   `json:"additions"`
 	Deletions   int64   `json:"deletions"`
+	// Reviews changing source files without a matching test change
+	TestsMissingCount int64 `json:"tests_missing_count"`
 }
 
 type DashboardResponse struct {
@@ -52,6 +76,20 @@ type DashboardResponse struct {
 	AuthorStats  []AuthorStats  `json:"author_stats"`
 }
 
+// reviewLogs returns a review_logs query limited to the projects of a tenant
+func (s *DashboardService) reviewLogs(tenantID uint) *gorm.DB {
+	return ScopeStatsReviewLogs(s.db.Model(&models.ReviewLog{}), tenantID)
+}
+
+// statsLogs returns the review logs of a stats request in the date range
+func (s *DashboardService) statsLogs(req *DashboardStatsRequest, startDate, endDate time.Time) *gorm.DB {
+	query := s.reviewLogs(req.TenantID).Where("created_at BETWEEN ? AND ?", startDate, endDate)
+	if projectIDs := parseProjectIDs(0, req.ProjectIDs); len(projectIDs) > 0 {
+		query = query.Where("project_id IN ?", projectIDs)
+	}
+	return query
+}
+
 func (s *DashboardService) GetStats(req *DashboardStatsRequest) (*DashboardResponse, error) {
 	var startDate, endDate time.Time
 	var err error
@@ -93,29 +131,35 @@ func (s *DashboardService) GetStats(req *DashboardStatsRequest) (*DashboardRespo
 
 	var stats DashboardStats
 
-	s.db.Model(&models.ReviewLog{}).
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	s.statsLogs(req, startDate, endDate).
 		Distinct("project_id").
 		Count(&stats.ActiveProjects)
 
-	s.db.Model(&models.ReviewLog{}).
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	s.statsLogs(req, startDate, endDate).
 		Distinct("author").
 		Count(&stats.Contributors)
 
-	s.db.Model(&models.ReviewLog{}).
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	s.statsLogs(req, startDate, endDate).
 		Count(&stats.TotalCommits)
 
-	s.db.Model(&models.ReviewLog{}).
-		Where("created_at BETWEEN ? AND ? AND score IS NOT NULL AND is_manual = false", startDate, endDate).
-		Select("COALESCE(AVG(score), 0)").
+	scoreColumn := statsScoreColumn(s.db)
+	s.statsLogs(req, startDate, endDate).
+		Where("score IS NOT NULL AND is_manual = false").
+		Select("COALESCE(AVG(" + scoreColumn + "), 0)").
 		Scan(&stats.AverageScore)
 
+	var verdicts struct {
+		HumanAccepted int64
+		HumanRejected int64
+	}
+	s.statsLogs(req, startDate, endDate).
+		Select(humanVerdictCountSQL).
+		Scan(&verdicts)
+	stats.HumanAccepted, stats.HumanRejected = verdicts.HumanAccepted, verdicts.HumanRejected
+
 	var projectStats []ProjectStats
-	s.db.Model(&models.ReviewLog{}).
-		Select("project_id, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score, COALESCE(SUM(additions), 0) as additions, COALESCE(SUM(deletions), 0) as deletions").
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	s.statsLogs(req, startDate, endDate).
+		Select("project_id, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN " + scoreColumn + " END), 0) as avg_score, COALESCE(SUM(additions), 0) as additions, COALESCE(SUM(deletions), 0) as deletions, " + testsMissingCountSQL).
 		Group("project_id").
 		Order("commit_count DESC").
 		Limit(projectLimit).
@@ -129,9 +173,8 @@ func (s *DashboardService) GetStats(req *DashboardStatsRequest) (*DashboardRespo
 	}
 
 	var authorStats []AuthorStats
-	s.db.Model(&models.ReviewLog{}).
-		Select("author, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score, COALESCE(SUM(additions), 0) as additions, COALESCE(SUM(deletions), 0) as deletions").
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+	s.statsLogs(req, startDate, endDate).
+		Select("author, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN " + scoreColumn + " END), 0) as avg_score, COALESCE(SUM(additions), 0) as additions, COALESCE(SUM(deletions), 0) as deletions, " + testsMissingCountSQL).
 		Group("author").
 		Order("commit_count DESC").
 		Limit(authorLimit).
@@ -2,6 +2,9 @@ package services
 
 import (
 	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
 )
 
 func TestDashboardStatsRequest_Defaults(t *testing.T) {
@@ -163,3 +166,121 @@ func TestDashboardStatsRequest_DateFormat(t *testing.T) {
 		}
 	}
 }
+
+func TestGroupTrendPointsByWeek(t *testing.T) {
+	daily := []TrendPoint{
+		{Date: "2024-01-07", Reviews: 1, Scored: 1, Passed: 1, AvgScore: 90, Tokens: 10}, // Sunday
+		{Date: "2024-01-08", Reviews: 2, Scored: 2, Passed: 1, AvgScore: 60, Tokens: 20}, // Monday
+		{Date: "2024-01-09", Reviews: 2, Scored: 2, Passed: 2, AvgScore: 80, Tokens: 30},
+	}
+
+	weeks := groupTrendPointsByWeek(daily)
+	if len(weeks) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(weeks))
+	}
+	if weeks[0].Date != "2024-01-01" || weeks[1].Date != "2024-01-08" {
+		t.Errorf("unexpected week starts: %s, %s", weeks[0].Date, weeks[1].Date)
+	}
+	w := weeks[1]
+	if w.Reviews != 4 || w.Passed != 3 || w.Tokens != 50 {
+		t.Errorf("unexpected totals: %+v", w)
+	}
+	if w.AvgScore != 70 {
+		t.Errorf("AvgScore = %v, expected 70", w.AvgScore)
+	}
+}
+
+func TestParseProjectIDs(t *testing.T) {
+	ids := parseProjectIDs(3, "1, 2,abc,0")
+	expected := []uint{3, 1, 2}
+	if len(ids) != len(expected) {
+		t.Fatalf("parseProjectIDs() = %v, expected %v", ids, expected)
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			t.Errorf("parseProjectIDs()[%d] = %d, expected %d", i, ids[i], expected[i])
+		}
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		previous, current, expected float64
+	}{
+		{0, 0, 0},
+		{0, 5, 100},
+		{10, 15, 50},
+		{20, 10, -50},
+	}
+	for _, tt := range tests {
+		if got := percentChange(tt.previous, tt.current); got != tt.expected {
+			t.Errorf("percentChange(%v, %v) = %v, expected %v", tt.previous, tt.current, got, tt.expected)
+		}
+	}
+}
+
+func TestStats_ExcludeRevisions(t *testing.T) {
+	f := seedTenants(t)
+	original := f.reviews[1]
+	score := 10.0
+	revision := &models.ReviewLog{
+		ProjectID: original.ProjectID, EventType: original.EventType, Branch: original.Branch, Author: "alice",
+		AuthorEmail: original.AuthorEmail, CommitHash: original.CommitHash, Additions: 20, Deletions: 5,
+		Score: &score, ReviewStatus: "completed", RevisionOf: &original.ID,
+	}
+	mustCreate(t, f.db, revision)
+	mustCreate(t, f.db, &models.ReviewFinding{ReviewLogID: revision.ID, ProjectID: original.ProjectID, Author: "alice", Category: "security", Title: "Again", CreatedAt: revision.CreatedAt})
+
+	stats, err := NewDashboardService(f.db).GetStats(&DashboardStatsRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Stats.TotalCommits != 2 || stats.Stats.AverageScore != 80 {
+		t.Errorf("GetStats counts the revision: %+v", stats.Stats)
+	}
+
+	members, err := NewMemberService(f.db).List(&MemberListRequest{TenantID: 1})
+	if err != nil || len(members.Items) != 1 || members.Items[0].CommitCount != 2 {
+		t.Errorf("member List counts the revision: %+v, %v", members, err)
+	}
+
+	config := NewSystemConfigService(f.db)
+	config.Set("leaderboard_enabled", "true")
+	config.Set("leaderboard_min_commits", "1")
+	board, err := NewLeaderboardService(f.db).Get(&LeaderboardRequest{TenantID: 1})
+	if err != nil || len(board.TopQuality) != 1 || board.TopQuality[0].AvgScore != 80 {
+		t.Errorf("leaderboard counts the revision: %+v, %v", board, err)
+	}
+
+	cards, err := NewMemberService(f.db).GetScorecards(&MemberScorecardRequest{TenantID: 1})
+	if err != nil || len(cards.Items) != 1 || cards.Items[0].Findings != 2 {
+		t.Errorf("scorecards count the revision's findings: %+v, %v", cards, err)
+	}
+}
+
+func TestParseDateRange_Capped(t *testing.T) {
+	start, end := parseDateRange("0001-01-01", "2024-06-30", 30)
+	if days := int(end.Sub(start).Hours()/24) + 1; days != maxDateRangeDays {
+		t.Errorf("range spans %d days, want %d", days, maxDateRangeDays)
+	}
+	start, _ = parseDateRange("2024-01-01", "2024-06-30", 30)
+	if start.Format("2006-01-02") != "2024-01-01" {
+		t.Errorf("start = %s, want 2024-01-01", start.Format("2006-01-02"))
+	}
+}
+
+func TestGetTrends_TokensWithoutReviews(t *testing.T) {
+	db := newTestDB(t)
+	project := &models.Project{Name: "p", URL: "https://gitlab.example.com/g/p", Platform: "gitlab"}
+	mustCreate(t, db, project)
+	day := time.Date(2024, 3, 5, 12, 0, 0, 0, time.Local)
+	mustCreate(t, db, &models.AIUsageLog{ProjectID: &project.ID, TotalTokens: 1500, Success: true, CreatedAt: day})
+
+	trends, err := NewDashboardService(db).GetTrends(&DashboardTrendRequest{StartDate: "2024-03-04", EndDate: "2024-03-06"})
+	if err != nil {
+		t.Fatalf("GetTrends() error = %v", err)
+	}
+	if len(trends.Points) != 3 || trends.Points[1].Date != "2024-03-05" || trends.Points[1].Tokens != 1500 || trends.Points[1].Reviews != 0 {
+		t.Errorf("points = %+v, want the tokens of 2024-03-05", trends.Points)
+	}
+}
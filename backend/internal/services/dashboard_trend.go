@@ -0,0 +1,336 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+type DashboardTrendRequest struct {
+	StartDate   string `form:"start_date"`
+	EndDate     string `form:"end_date"`
+	ProjectID   uint   `form:"project_id"`
+	ProjectIDs  string `form:"project_ids"` // Comma-separated project group
+	Granularity string `form:"granularity" binding:"omitempty,oneof=day week"`
+	TenantID    uint   `form:"-"` // Set from the request context, 0 = all tenants
+}
+
+type TrendPoint struct {
+	Date     string  `json:"date"`
+	Reviews  int64   `json:"reviews"`
+	Scored   int64   `json:"scored"`
+	Passed   int64   `json:"passed"`
+	AvgScore float64 `json:"avg_score"`
+	PassRate float64 `json:"pass_rate"`
+	Tokens   int64   `json:"tokens"`
+}
+
+type DashboardTrendResponse struct {
+	Granularity string       `json:"granularity"`
+	Points      []TrendPoint `json:"points"`
+}
+
+type DashboardCompareRequest struct {
+	Period     string `form:"period" binding:"omitempty,oneof=week month"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	TenantID   uint   `form:"-"`
+}
+
+type PeriodSummary struct {
+	StartDate string  `json:"start_date"`
+	EndDate   string  `json:"end_date"`
+	Reviews   int64   `json:"reviews"`
+	AvgScore  float64 `json:"avg_score"`
+	PassRate  float64 `json:"pass_rate"`
+	Tokens    int64   `json:"tokens"`
+	Authors   int64   `json:"authors"`
+}
+
+type PeriodChange struct {
+	Reviews  float64 `json:"reviews"`   // Percent change
+	AvgScore float64 `json:"avg_score"` // Absolute change in points
+	PassRate float64 `json:"pass_rate"` // Absolute change in percentage points
+	Tokens   float64 `json:"tokens"`    // Percent change
+	Authors  float64 `json:"authors"`   // Percent change
+}
+
+type DashboardCompareResponse struct {
+	Period   string        `json:"period"`
+	Current  PeriodSummary `json:"current"`
+	Previous PeriodSummary `json:"previous"`
+	Change   PeriodChange  `json:"change"`
+}
+
+// GetTrends returns per-day (or per-week) review counts, average score, pass rate and token spend
+func (s *DashboardService) GetTrends(req *DashboardTrendRequest) (*DashboardTrendResponse, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 30)
+	projectIDs := parseProjectIDs(req.ProjectID, req.ProjectIDs)
+	minScore := s.globalMinScore()
+
+	var reviews []TrendPoint
+	err := s.scopeReviewLogs(startDate, endDate, projectIDs, req.TenantID).
+		Select("DATE(review_logs.created_at) as date, COUNT(*) as reviews, "+
+			"COUNT(review_logs.score) as scored, "+
+			"COALESCE(SUM(CASE WHEN review_logs.score >= (CASE WHEN projects.min_score > 0 THEN projects.min_score ELSE ? END) THEN 1 ELSE 0 END), 0) as passed, "+
+			"COALESCE(AVG(review_logs.score), 0) as avg_score", minScore).
+		Group("DATE(review_logs.created_at)").
+		Order("date ASC").
+		Scan(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []struct {
+		Date   string
+		Tokens int64
+	}
+	tokenQuery := s.scopeUsageLogs(startDate, endDate, projectIDs, req.TenantID).
+		Select("DATE(created_at) as date, COALESCE(SUM(total_tokens), 0) as tokens")
+	if err := tokenQuery.Group("DATE(created_at)").Scan(&tokens).Error; err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*TrendPoint)
+	for i := range reviews {
+		reviews[i].Date = normalizeDate(reviews[i].Date)
+		byDate[reviews[i].Date] = &reviews[i]
+	}
+	for _, t := range tokens {
+		date := normalizeDate(t.Date)
+		// Tokens are also spent on days without a review, e.g. by retries and chat
+		p, ok := byDate[date]
+		if !ok {
+			p = &TrendPoint{Date: date}
+			byDate[date] = p
+		}
+		p.Tokens = t.Tokens
+	}
+
+	points := fillTrendPoints(startDate, endDate, byDate)
+	granularity := "day"
+	if req.Granularity == "week" {
+		granularity = "week"
+		points = groupTrendPointsByWeek(points)
+	}
+	for i := range points {
+		if points[i].Scored > 0 {
+			points[i].PassRate = float64(points[i].Passed) / float64(points[i].Scored) * 100
+		}
+	}
+
+	return &DashboardTrendResponse{Granularity: granularity, Points: points}, nil
+}
+
+// Compare returns the current period (this week/month so far) against the equivalent previous period
+func (s *DashboardService) Compare(req *DashboardCompareRequest) (*DashboardCompareResponse, error) {
+	period := req.Period
+	if period == "" {
+		period = "week"
+	}
+	projectIDs := parseProjectIDs(req.ProjectID, req.ProjectIDs)
+
+	now := time.Now()
+	var currentStart, previousStart time.Time
+	if period == "month" {
+		currentStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		previousStart = currentStart.AddDate(0, -1, 0)
+	} else {
+		weekday := int(now.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		currentStart = time.Date(now.Year(), now.Month(), now.Day()-weekday+1, 0, 0, 0, 0, now.Location())
+		previousStart = currentStart.AddDate(0, 0, -7)
+	}
+	// Compare like-for-like: the previous period is cut at the same offset as "now" in the current one
+	previousEnd := previousStart.Add(now.Sub(currentStart))
+
+	current := s.periodSummary(currentStart, now, projectIDs, req.TenantID)
+	previous := s.periodSummary(previousStart, previousEnd, projectIDs, req.TenantID)
+
+	return &DashboardCompareResponse{
+		Period:   period,
+		Current:  current,
+		Previous: previous,
+		Change: PeriodChange{
+			Reviews:  percentChange(float64(previous.Reviews), float64(current.Reviews)),
+			AvgScore: current.AvgScore - previous.AvgScore,
+			PassRate: current.PassRate - previous.PassRate,
+			Tokens:   percentChange(float64(previous.Tokens), float64(current.Tokens)),
+			Authors:  percentChange(float64(previous.Authors), float64(current.Authors)),
+		},
+	}, nil
+}
+
+func (s *DashboardService) periodSummary(start, end time.Time, projectIDs []uint, tenantID uint) PeriodSummary {
+	summary := PeriodSummary{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   end.Format("2006-01-02"),
+	}
+
+	var row struct {
+		Reviews  int64
+		Scored   int64
+		Passed   int64
+		AvgScore float64
+		Authors  int64
+	}
+	s.scopeReviewLogs(start, end, projectIDs, tenantID).
+		Select("COUNT(*) as reviews, COUNT(review_logs.score) as scored, "+
+			"COALESCE(SUM(CASE WHEN review_logs.score >= (CASE WHEN projects.min_score > 0 THEN projects.min_score ELSE ? END) THEN 1 ELSE 0 END), 0) as passed, "+
+			"COALESCE(AVG(review_logs.score), 0) as avg_score, "+
+			"COUNT(DISTINCT review_logs.author) as authors", s.globalMinScore()).
+		Scan(&row)
+
+	summary.Reviews = row.Reviews
+	summary.AvgScore = row.AvgScore
+	summary.Authors = row.Authors
+	if row.Scored > 0 {
+		summary.PassRate = float64(row.Passed) / float64(row.Scored) * 100
+	}
+
+	s.scopeUsageLogs(start, end, projectIDs, tenantID).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Scan(&summary.Tokens)
+
+	return summary
+}
+
+// scopeReviewLogs returns a review_logs query joined with projects, limited to AI-scored
+// reviews of a tenant in the date range and optional project set
+func (s *DashboardService) scopeReviewLogs(start, end time.Time, projectIDs []uint, tenantID uint) *gorm.DB {
+	query := s.reviewLogs(tenantID).
+		Joins("LEFT JOIN projects ON projects.id = review_logs.project_id").
+		Where("review_logs.created_at BETWEEN ? AND ? AND review_logs.is_manual = ?", start, end, false)
+	if len(projectIDs) > 0 {
+		query = query.Where("review_logs.project_id IN ?", projectIDs)
+	}
+	return query
+}
+
+// scopeUsageLogs returns an ai_usage_logs query limited to a tenant's projects in the date
+// range and optional project set
+func (s *DashboardService) scopeUsageLogs(start, end time.Time, projectIDs []uint, tenantID uint) *gorm.DB {
+	query := ScopeProjectColumnByTenant(s.db.Model(&models.AIUsageLog{}), "project_id", tenantID).
+		Where("created_at BETWEEN ? AND ?", start, end)
+	if len(projectIDs) > 0 {
+		query = query.Where("project_id IN ?", projectIDs)
+	}
+	return query
+}
+
+func (s *DashboardService) globalMinScore() float64 {
+	minScore, _ := strconv.ParseFloat(NewSystemConfigService(s.db).GetWithDefault("system.min_score", "60"), 64)
+	if minScore <= 0 {
+		return 60
+	}
+	return minScore
+}
+
+// maxDateRangeDays caps the days a requested date range spans, since reports fill in every day of it
+const maxDateRangeDays = 3 * 366
+
+// parseDateRange parses YYYY-MM-DD dates, defaulting to the last defaultDays days. The start is
+// moved up so the range spans at most maxDateRangeDays.
+func parseDateRange(start, end string, defaultDays int) (time.Time, time.Time) {
+	now := time.Now()
+	endDate := now
+	if end != "" {
+		if t, err := time.ParseInLocation("2006-01-02", end, now.Location()); err == nil {
+			endDate = t.Add(24*time.Hour - time.Second)
+		}
+	}
+	startDate := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(defaultDays - 1))
+	if start != "" {
+		if t, err := time.ParseInLocation("2006-01-02", start, now.Location()); err == nil {
+			startDate = t
+		}
+	}
+	if earliest := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -(maxDateRangeDays - 1)); startDate.Before(earliest) {
+		startDate = earliest
+	}
+	return startDate, endDate
+}
+
+func parseProjectIDs(projectID uint, projectIDs string) []uint {
+	var ids []uint
+	if projectID > 0 {
+		ids = append(ids, projectID)
+	}
+	for _, s := range splitAndTrim(projectIDs, ",") {
+		if id, err := strconv.ParseUint(s, 10, 32); err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// normalizeDate trims driver-specific DATE() output (e.g. "2024-01-02T00:00:00Z") to YYYY-MM-DD
+func normalizeDate(date string) string {
+	if len(date) > 10 {
+		return date[:10]
+	}
+	return date
+}
+
+// fillTrendPoints returns one point per day in range, using zero values for days without data
+func fillTrendPoints(start, end time.Time, byDate map[string]*TrendPoint) []TrendPoint {
+	var points []TrendPoint
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	for !day.After(end) {
+		date := day.Format("2006-01-02")
+		if p, ok := byDate[date]; ok {
+			points = append(points, *p)
+		} else {
+			points = append(points, TrendPoint{Date: date})
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return points
+}
+
+// groupTrendPointsByWeek merges daily points into weeks starting on Monday
+func groupTrendPointsByWeek(daily []TrendPoint) []TrendPoint {
+	var weeks []TrendPoint
+	index := make(map[string]int)
+	for _, p := range daily {
+		day, err := time.Parse("2006-01-02", p.Date)
+		if err != nil {
+			continue
+		}
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		week := day.AddDate(0, 0, -(weekday - 1)).Format("2006-01-02")
+
+		i, ok := index[week]
+		if !ok {
+			weeks = append(weeks, TrendPoint{Date: week})
+			i = len(weeks) - 1
+			index[week] = i
+		}
+		w := &weeks[i]
+		if p.Scored > 0 {
+			w.AvgScore = (w.AvgScore*float64(w.Scored) + p.AvgScore*float64(p.Scored)) / float64(w.Scored+p.Scored)
+		}
+		w.Reviews += p.Reviews
+		w.Scored += p.Scored
+		w.Passed += p.Passed
+		w.Tokens += p.Tokens
+	}
+	return weeks
+}
+
+func percentChange(previous, current float64) float64 {
+	if previous == 0 {
+		if current == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (current - previous) / previous * 100
+}
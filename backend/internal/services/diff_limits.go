@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// ReviewStatusSkippedTooLarge marks reviews whose diff exceeds the project's size limits
+const ReviewStatusSkippedTooLarge = "skipped_too_large"
+
+// ReviewStatusDiffFetchFailed marks reviews whose diff couldn't be fetched from the platform;
+// the retry scheduler fetches it again and queues the review
+const ReviewStatusDiffFetchFailed = "diff_fetch_failed"
+
+// DiffLimits are the size guardrails of a project; zero disables a limit
+type DiffLimits struct {
+	MaxChangedLines int // additions + deletions of the whole review
+	MaxFiles        int // changed files of the whole review
+	MaxDiffBytes    int // size of the whole diff
+	MaxFileBytes    int // size of a single file diff; larger files are left out of the review
+}
+
+// DiffLimitsOf returns the size guardrails configured for project
+func DiffLimitsOf(project *models.Project) DiffLimits {
+	return DiffLimits{
+		MaxChangedLines: project.MaxChangedLines,
+		MaxFiles:        project.MaxFiles,
+		MaxDiffBytes:    project.MaxDiffBytes,
+		MaxFileBytes:    project.MaxFileBytes,
+	}
+}
+
+// DropOversizedFiles removes the diffs of files larger than MaxFileBytes, such as
+// generated code or vendored bundles, and returns the remaining diff and the removed paths
+func (l DiffLimits) DropOversizedFiles(diff string) (string, []string) {
+	if l.MaxFileBytes <= 0 || len(diff) <= l.MaxFileBytes {
+		return diff, nil
+	}
+
+	files := ParseDiffToFiles(diff)
+	kept := make([]FileDiff, 0, len(files))
+	var dropped []string
+	for _, file := range files {
+		if len(file.Content) > l.MaxFileBytes {
+			dropped = append(dropped, file.FilePath)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	if len(dropped) == 0 {
+		return diff, nil
+	}
+	return ReconstructDiff(kept), dropped
+}
+
+// Exceeded returns why diff exceeds the review-wide limits, or "" if it is within them
+func (l DiffLimits) Exceeded(diff string) string {
+	if l.MaxDiffBytes > 0 && len(diff) > l.MaxDiffBytes {
+		return fmt.Sprintf("diff is %d bytes (limit %d)", len(diff), l.MaxDiffBytes)
+	}
+	if l.MaxFiles <= 0 && l.MaxChangedLines <= 0 {
+		return ""
+	}
+
+	files := ParseDiffToFiles(diff)
+	if l.MaxFiles > 0 && len(files) > l.MaxFiles {
+		return fmt.Sprintf("%d files changed (limit %d)", len(files), l.MaxFiles)
+	}
+	changed := 0
+	for _, file := range files {
+		changed += file.Additions + file.Deletions
+	}
+	if l.MaxChangedLines > 0 && changed > l.MaxChangedLines {
+		return fmt.Sprintf("%d lines changed (limit %d)", changed, l.MaxChangedLines)
+	}
+	return ""
+}
+
+// FormatDroppedFiles describes the files left out of a review for exceeding MaxFileBytes
+func (l DiffLimits) FormatDroppedFiles(dropped []string) string {
+	if len(dropped) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("> Not reviewed (file diff larger than %d bytes): %s", l.MaxFileBytes, strings.Join(dropped, ", "))
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const limitsTestDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,2 +1,3 @@
+ package main
++import "fmt"
+-var x = 1
+diff --git a/gen/api.pb.go b/gen/api.pb.go
+--- a/gen/api.pb.go
++++ b/gen/api.pb.go
+@@ -1,1 +1,5 @@
++line 1 of a large generated file
++line 2 of a large generated file
++line 3 of a large generated file
++line 4 of a large generated file
+`
+
+func TestDiffLimits_Exceeded(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits DiffLimits
+		want   string
+	}{
+		{"no limits", DiffLimits{}, ""},
+		{"within limits", DiffLimits{MaxChangedLines: 6, MaxFiles: 2, MaxDiffBytes: 10000}, ""},
+		{"too many lines", DiffLimits{MaxChangedLines: 5}, "6 lines changed (limit 5)"},
+		{"too many files", DiffLimits{MaxFiles: 1}, "2 files changed (limit 1)"},
+		{"too many bytes", DiffLimits{MaxDiffBytes: 100}, "bytes (limit 100)"},
+	}
+
+	for _, tt := range tests {
+		got := tt.limits.Exceeded(limitsTestDiff)
+		if tt.want == "" && got != "" {
+			t.Errorf("%s: Exceeded() = %q, want no violation", tt.name, got)
+		}
+		if tt.want != "" && !strings.Contains(got, tt.want) {
+			t.Errorf("%s: Exceeded() = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLimits_DropOversizedFiles(t *testing.T) {
+	limits := DiffLimits{MaxFileBytes: 150}
+	diff, dropped := limits.DropOversizedFiles(limitsTestDiff)
+
+	if len(dropped) != 1 || dropped[0] != "gen/api.pb.go" {
+		t.Fatalf("dropped = %v, want [gen/api.pb.go]", dropped)
+	}
+	if !strings.Contains(diff, "main.go") || strings.Contains(diff, "api.pb.go") {
+		t.Errorf("remaining diff should only contain main.go:\n%s", diff)
+	}
+	if note := limits.FormatDroppedFiles(dropped); !strings.Contains(note, "gen/api.pb.go") {
+		t.Errorf("FormatDroppedFiles() = %q, want it to list the dropped file", note)
+	}
+
+	if _, dropped := (DiffLimits{}).DropOversizedFiles(limitsTestDiff); dropped != nil {
+		t.Errorf("without a file limit nothing should be dropped, got %v", dropped)
+	}
+}
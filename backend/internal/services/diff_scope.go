@@ -0,0 +1,45 @@
+package services
+
+import (
+	"path"
+	"strings"
+)
+
+// ScopeDiffToPaths keeps the files of a diff under one of paths: a directory such as
+// "src/payment", a file, or a glob such as "src/*.go". It returns an empty diff when no
+// file matches, and the diff unchanged when paths is empty.
+func ScopeDiffToPaths(diff string, paths []string) string {
+	if len(paths) == 0 {
+		return diff
+	}
+
+	var result strings.Builder
+	for _, file := range ParseDiffToFiles(diff) {
+		if inScopePaths(file.NewPath, paths) || inScopePaths(file.OldPath, paths) {
+			result.WriteString(file.Content)
+		}
+	}
+	return result.String()
+}
+
+// inScopePaths reports whether a file is one of paths or lies under one of them
+func inScopePaths(file string, paths []string) bool {
+	if file == "" {
+		return false
+	}
+	for _, p := range paths {
+		p = strings.Trim(strings.TrimPrefix(p, "./"), "/")
+		if p == "" {
+			continue
+		}
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+		if strings.ContainsAny(p, "*?[") {
+			if ok, _ := path.Match(p, file); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeDiffToPaths(t *testing.T) {
+	diff := "diff --git a/src/payment/charge.go b/src/payment/charge.go\n--- a/src/payment/charge.go\n+++ b/src/payment/charge.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"diff --git a/src/payments.go b/src/payments.go\n--- a/src/payments.go\n+++ b/src/payments.go\n@@ -1 +1 @@\n-c\n+d\n" +
+		"diff --git a/docs/README.md b/docs/README.md\n--- a/docs/README.md\n+++ b/docs/README.md\n@@ -1 +1 @@\n-e\n+f\n"
+
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{"no paths keeps the diff", nil, []string{"src/payment/charge.go", "src/payments.go", "docs/README.md"}},
+		{"directory", []string{"src/payment"}, []string{"src/payment/charge.go"}},
+		{"directory with slashes", []string{"./src/payment/"}, []string{"src/payment/charge.go"}},
+		{"file", []string{"docs/README.md"}, []string{"docs/README.md"}},
+		{"glob", []string{"src/*.go"}, []string{"src/payments.go"}},
+		{"several paths", []string{"docs", "src/payment"}, []string{"src/payment/charge.go", "docs/README.md"}},
+		{"no match", []string{"lib"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScopeDiffToPaths(diff, tt.paths)
+			var files []string
+			for _, f := range ParseDiffToFiles(got) {
+				files = append(files, f.FilePath)
+			}
+			if strings.Join(files, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("files = %v, want %v", files, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// DigestCheckInterval is how often pending digests are checked for delivery
+	DigestCheckInterval = time.Minute
+	// DigestMaxItems caps the number of reviews listed in one digest message
+	DigestMaxItems = 50
+)
+
+// DigestService accumulates review notifications for bots in digest mode
+// and delivers them as consolidated summaries.
+type DigestService struct {
+	db         *gorm.DB
+	quietHours *quietHoursChecker
+}
+
+func NewDigestService(db *gorm.DB) *DigestService {
+	return &DigestService{
+		db:         db,
+		quietHours: newQuietHoursChecker(db),
+	}
+}
+
+// Enqueue stores a review notification for later delivery in the bot's next digest
+func (s *DigestService) Enqueue(bot *models.IMBot, projectID uint, n *ReviewNotification) error {
+	item := models.NotificationDigestItem{
+		BotID:         bot.ID,
+		ProjectID:     projectID,
+		ProjectName:   n.ProjectName,
+		Branch:        n.Branch,
+		Author:        n.Author,
+		CommitMessage: truncateRunes(n.CommitMessage, 500),
+		Score:         n.Score,
+		EventType:     n.EventType,
+		MRURL:         n.MRURL,
+	}
+	return s.db.Create(&item).Error
+}
+
+// FlushDue delivers pending notifications for every bot whose digest interval has elapsed.
+// Notifications deferred by quiet hours are delivered once the bot's quiet period is over.
+func (s *DigestService) FlushDue(now time.Time) {
+	var bots []models.IMBot
+	pending := s.db.Model(&models.NotificationDigestItem{}).Distinct("bot_id")
+	if err := s.db.Where("is_active = ? AND id IN (?)", true, pending).Find(&bots).Error; err != nil {
+		logger.Errorf("[Digest] Failed to load bots with pending notifications: %v", err)
+		return
+	}
+
+	for i := range bots {
+		bot := &bots[i]
+		if s.quietHours.isQuiet(bot, now) {
+			continue
+		}
+		if bot.DigestEnabled {
+			var oldest models.NotificationDigestItem
+			if err := s.db.Where("bot_id = ?", bot.ID).Order("created_at ASC").First(&oldest).Error; err != nil {
+				continue
+			}
+			if !isDigestDue(bot, oldest.CreatedAt, now) {
+				continue
+			}
+		}
+		if _, err := s.Flush(bot); err != nil {
+			logger.Errorf("[Digest] Failed to send digest to bot %d: %v", bot.ID, err)
+		}
+	}
+}
+
+// Flush sends all pending items for a bot as a single digest and returns the number delivered
+func (s *DigestService) Flush(bot *models.IMBot) (int, error) {
+	var items []models.NotificationDigestItem
+	if err := s.db.Where("bot_id = ?", bot.ID).Order("created_at ASC").Find(&items).Error; err != nil {
+		return 0, err
+	}
+	if len(items) == 0 {
+		return 0, nil
+	}
+
+	message := buildDigestMessage(items, bot.MessageLanguage)
+	// A digest scheduled for a retry is left to the delivery retries, not sent again with new items
+	if delivery, err := sendDelivery(s.db, bot, nil, &notificationPayload{Text: message}, nil, 1); err != nil {
+		if delivery == nil || delivery.Status != DeliveryStatusRetrying {
+			return 0, err
+		}
+		logger.Warnf("[Digest] Digest to bot %s failed, retrying: %v", bot.Name, err)
+	}
+
+	ids := make([]uint, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	if err := s.db.Where("id IN ?", ids).Delete(&models.NotificationDigestItem{}).Error; err != nil {
+		return len(items), err
+	}
+
+	logger.Infof("[Digest] Sent digest with %d reviews to bot %s", len(items), bot.Name)
+	return len(items), nil
+}
+
+// isDigestDue reports whether a bot's pending digest should be sent.
+// An interval of 0 sends once per day, after the day of the oldest item has ended.
+func isDigestDue(bot *models.IMBot, oldest, now time.Time) bool {
+	if bot.DigestInterval <= 0 {
+		y, m, d := oldest.In(now.Location()).Date()
+		endOfDay := time.Date(y, m, d, 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1)
+		return !now.Before(endOfDay)
+	}
+	return now.Sub(oldest) >= time.Duration(bot.DigestInterval)*time.Minute
+}
+
+func buildDigestMessage(items []models.NotificationDigestItem, lang string) string {
+	title := "📬 **Code Review Digest**"
+	summaryFmt := "%d reviews, average score %.1f, %d below 60"
+	moreFmt := "...and %d more"
+	if lang == "zh" {
+		title = "📬 **代码审查汇总**"
+		summaryFmt = "共 %d 次审查，平均分 %.1f，%d 次低于 60 分"
+		moreFmt = "...以及其他 %d 条"
+	}
+
+	var total float64
+	var low int
+	for _, item := range items {
+		total += item.Score
+		if item.Score < 60 {
+			low++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	sb.WriteString("\n\n")
+	fmt.Fprintf(&sb, summaryFmt, len(items), total/float64(len(items)), low)
+	sb.WriteString("\n")
+
+	for i, item := range items {
+		if i >= DigestMaxItems {
+			sb.WriteString("\n")
+			fmt.Fprintf(&sb, moreFmt, len(items)-DigestMaxItems)
+			break
+		}
+		line := fmt.Sprintf("\n%s **%.0f** %s@%s %s: %s", scoreEmoji(item.Score), item.Score,
+			item.ProjectName, item.Branch, item.Author, truncateRunes(firstLine(item.CommitMessage), 60))
+		if item.MRURL != "" {
+			line += fmt.Sprintf(" ([MR/PR](%s))", item.MRURL)
+		}
+		sb.WriteString(line)
+	}
+
+	return sb.String()
+}
+
+func firstLine(s string) string {
+	if idx := strings.Index(s, "\n"); idx >= 0 {
+		return s[:idx]
+	}
+	return s
+}
+
+var digestStopChan chan struct{}
+
+// StartDigestScheduler starts a goroutine that periodically delivers due digests
+func StartDigestScheduler(db *gorm.DB) {
+	digestStopChan = make(chan struct{})
+	go func() {
+		service := NewDigestService(db)
+		RegisterScheduler("digest", DigestCheckInterval)
+		ticker := time.NewTicker(DigestCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("digest", func() error { service.FlushDue(time.Now()); return nil })
+			case <-digestStopChan:
+				logger.Infof("[Digest] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopDigestScheduler stops the digest scheduler
+func StopDigestScheduler() {
+	if digestStopChan != nil {
+		close(digestStopChan)
+	}
+}
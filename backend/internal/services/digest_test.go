@@ -0,0 +1,60 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIsDigestDue(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		name     string
+		interval int
+		oldest   time.Time
+		expected bool
+	}{
+		{"interval not elapsed", 30, now.Add(-10 * time.Minute), false},
+		{"interval elapsed", 30, now.Add(-30 * time.Minute), true},
+		{"day end not reached", 0, time.Date(2024, 5, 10, 1, 0, 0, 0, loc), false},
+		{"day end passed", 0, time.Date(2024, 5, 9, 23, 0, 0, 0, loc), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot := &models.IMBot{DigestInterval: tt.interval}
+			if got := isDigestDue(bot, tt.oldest, now); got != tt.expected {
+				t.Errorf("isDigestDue() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildDigestMessage(t *testing.T) {
+	items := []models.NotificationDigestItem{
+		{ProjectName: "api", Branch: "main", Author: "john", CommitMessage: "feat: one\n\nbody", Score: 90},
+		{ProjectName: "web", Branch: "dev", Author: "jane", CommitMessage: "fix: two", Score: 50, MRURL: "https://example.com/mr/1"},
+	}
+
+	msg := buildDigestMessage(items, "en")
+	for _, s := range []string{"Code Review Digest", "2 reviews", "average score 70.0", "1 below 60", "api@main john: feat: one", "https://example.com/mr/1"} {
+		if !strings.Contains(msg, s) {
+			t.Errorf("digest should contain %q, got:\n%s", s, msg)
+		}
+	}
+	if strings.Contains(msg, "body") {
+		t.Error("digest should only include the first line of commit messages")
+	}
+}
+
+func TestBuildDigestMessage_Truncated(t *testing.T) {
+	items := make([]models.NotificationDigestItem, DigestMaxItems+5)
+	msg := buildDigestMessage(items, "en")
+	if !strings.Contains(msg, "...and 5 more") {
+		t.Errorf("digest should note omitted items, got tail: %s", msg[len(msg)-50:])
+	}
+}
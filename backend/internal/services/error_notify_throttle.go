@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// botRateWindow is the window of the per-bot alert rate limit
+const botRateWindow = time.Hour
+
+// errorNotifyThrottle collapses repeated error alerts and limits the alerts each bot receives
+type errorNotifyThrottle struct {
+	mu      sync.Mutex
+	seen    map[string]*errorFingerprint // Alerts sent within their dedup window, by fingerprint
+	sent    map[uint][]time.Time         // Alerts sent to each bot within the rate window
+	dropped map[uint]int                 // Alerts dropped by each bot's rate limit since its last alert
+	now     func() time.Time
+	after   func(d time.Duration, f func())
+}
+
+// errorFingerprint counts the repeats of an alert within its dedup window
+type errorFingerprint struct {
+	module  string
+	action  string
+	message string
+	repeats int
+}
+
+var errorNotifies = newErrorNotifyThrottle()
+
+func newErrorNotifyThrottle() *errorNotifyThrottle {
+	return &errorNotifyThrottle{
+		seen:    make(map[string]*errorFingerprint),
+		sent:    make(map[uint][]time.Time),
+		dropped: make(map[uint]int),
+		now:     time.Now,
+		after:   func(d time.Duration, f func()) { time.AfterFunc(d, f) },
+	}
+}
+
+// admit reports whether an alert is sent now. Repeats of an alert within the window are
+// counted instead, and summarized by a single message from repeated at the end of the window;
+// the window restarts as long as the alert keeps repeating.
+func (t *errorNotifyThrottle) admit(module, action, message string, window time.Duration, repeated func(fp errorFingerprint)) bool {
+	if window <= 0 {
+		return true
+	}
+	key := module + "\x00" + action + "\x00" + message
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if fp, ok := t.seen[key]; ok {
+		fp.repeats++
+		return false
+	}
+	t.seen[key] = &errorFingerprint{module: module, action: action, message: message}
+	t.scheduleFlush(key, window, repeated)
+	return true
+}
+
+func (t *errorNotifyThrottle) scheduleFlush(key string, window time.Duration, repeated func(fp errorFingerprint)) {
+	t.after(window, func() {
+		t.mu.Lock()
+		fp, ok := t.seen[key]
+		if !ok || fp.repeats == 0 {
+			delete(t.seen, key)
+			t.mu.Unlock()
+			return
+		}
+		summary := *fp
+		fp.repeats = 0
+		t.scheduleFlush(key, window, repeated)
+		t.mu.Unlock()
+		repeated(summary)
+	})
+}
+
+// allowBot reports whether a bot may receive another alert under a limit of alerts per
+// hour (0 = unlimited), and how many alerts it missed since its last one
+func (t *errorNotifyThrottle) allowBot(botID uint, limit int) (bool, int) {
+	if limit <= 0 {
+		return true, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	kept := t.sent[botID][:0]
+	for _, at := range t.sent[botID] {
+		if now.Sub(at) < botRateWindow {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) >= limit {
+		t.sent[botID] = kept
+		t.dropped[botID]++
+		return false, 0
+	}
+	t.sent[botID] = append(kept, now)
+	dropped := t.dropped[botID]
+	delete(t.dropped, botID)
+	return true, dropped
+}
+
+// buildRepeatedErrorMessage summarizes the repeats of an alert within its dedup window
+func buildRepeatedErrorMessage(fp errorFingerprint, window time.Duration) string {
+	return fmt.Sprintf(`🔁 **System Error Repeated**
+
+**Module**: %s
+**Action**: %s
+**Message**: %s
+**Repeats**: %d more time(s) in the last %s`, fp.module, fp.action, fp.message, fp.repeats, window)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestErrorNotifyThrottleDedup(t *testing.T) {
+	throttle := newErrorNotifyThrottle()
+	var timers []func()
+	throttle.after = func(d time.Duration, f func()) { timers = append(timers, f) }
+	flush := func() {
+		pending := timers
+		timers = nil
+		for _, f := range pending {
+			f()
+		}
+	}
+
+	var summaries []errorFingerprint
+	repeated := func(fp errorFingerprint) { summaries = append(summaries, fp) }
+	admit := func(message string) bool {
+		return throttle.admit("Webhook", "ProjectNotFound", message, 10*time.Minute, repeated)
+	}
+
+	if !admit("project a") {
+		t.Fatal("first alert should be sent")
+	}
+	if admit("project a") || admit("project a") {
+		t.Error("repeats within the window should be collapsed")
+	}
+	if !admit("project b") {
+		t.Error("a different message should be sent")
+	}
+
+	flush()
+	if len(summaries) != 1 || summaries[0].message != "project a" || summaries[0].repeats != 2 {
+		t.Fatalf("summaries = %+v, want one for project a with 2 repeats", summaries)
+	}
+
+	// The alert keeps repeating, so its window restarted
+	if admit("project a") {
+		t.Error("alert repeating across windows should still be collapsed")
+	}
+	flush()
+	if len(summaries) != 2 || summaries[1].repeats != 1 {
+		t.Fatalf("summaries = %+v, want a second summary with 1 repeat", summaries)
+	}
+
+	// A quiet window ends the deduplication
+	flush()
+	if len(summaries) != 2 {
+		t.Errorf("quiet window should not send a summary: %+v", summaries)
+	}
+	if !admit("project a") {
+		t.Error("alert after a quiet window should be sent again")
+	}
+
+	if !throttle.admit("Webhook", "ProjectNotFound", "project a", 0, repeated) {
+		t.Error("deduplication should be off with a zero window")
+	}
+}
+
+func TestErrorNotifyThrottleBotRateLimit(t *testing.T) {
+	throttle := newErrorNotifyThrottle()
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	throttle.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := throttle.allowBot(1, 2); !ok {
+			t.Fatalf("alert %d should be allowed", i+1)
+		}
+	}
+	if ok, _ := throttle.allowBot(1, 2); ok {
+		t.Error("third alert within the hour should be dropped")
+	}
+	throttle.allowBot(1, 2)
+	if ok, _ := throttle.allowBot(2, 2); !ok {
+		t.Error("limits are per bot")
+	}
+
+	now = now.Add(time.Hour)
+	ok, dropped := throttle.allowBot(1, 2)
+	if !ok || dropped != 2 {
+		t.Errorf("allowBot() after the window = %v, %d dropped, want true, 2", ok, dropped)
+	}
+	if ok, dropped := throttle.allowBot(1, 2); !ok || dropped != 0 {
+		t.Errorf("dropped count should be reported once, got %v, %d", ok, dropped)
+	}
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := throttle.allowBot(3, 0); !ok {
+			t.Fatal("a zero limit should be unlimited")
+		}
+	}
+}
+
+func TestBuildRepeatedErrorMessage(t *testing.T) {
+	msg := buildRepeatedErrorMessage(errorFingerprint{module: "SyncReview", action: "ReviewFailed", message: "timeout", repeats: 7}, 10*time.Minute)
+	for _, want := range []string{"SyncReview", "ReviewFailed", "timeout", "7 more time(s) in the last 10m0s"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
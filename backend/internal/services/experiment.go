@@ -0,0 +1,400 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// experimentAssignment is the variant of a running experiment a review is assigned to, with
+// the prompt and LLM config replacing the project's regular ones
+type experimentAssignment struct {
+	ExperimentID uint
+	Variant      string
+	Prompt       *models.PromptTemplate
+	LLMConfig    *models.LLMConfig
+}
+
+// assignExperiment assigns a review to a variant of the project's running experiment. Reviews
+// without a commit, or with the prompt of the request, stay out of experiments, and so do
+// reviews whose variant prompt or LLM config is unavailable, to keep the comparison clean.
+func (s *AIService) assignExperiment(project *models.Project, req *ReviewRequest) *experimentAssignment {
+	if req.CommitHash == "" || req.CustomPrompt != "" || !IsFeatureEnabled(FeatureExperiments, project.ID, true) {
+		return nil
+	}
+	var experiment models.Experiment
+	if err := s.db.Where("project_id = ? AND status = ?", project.ID, models.ExperimentStatusRunning).
+		Order("id DESC").First(&experiment).Error; err != nil {
+		return nil
+	}
+
+	assignment := &experimentAssignment{ExperimentID: experiment.ID, Variant: assignVariant(&experiment, req.CommitHash)}
+	promptID, llmConfigID := experimentVariantConfig(&experiment, assignment.Variant)
+	if promptID != nil {
+		var prompt models.PromptTemplate
+		if err := s.db.First(&prompt, *promptID).Error; err != nil {
+			logger.Warnf("[Experiment] Prompt %d of experiment %d not found, reviewing without the experiment", *promptID, experiment.ID)
+			return nil
+		}
+		assignment.Prompt = &prompt
+	}
+	if llmConfigID != nil {
+		var llmConfig models.LLMConfig
+		if err := s.db.Where("id = ? AND is_active = ?", *llmConfigID, true).First(&llmConfig).Error; err != nil {
+			logger.Warnf("[Experiment] LLM config %d of experiment %d unavailable, reviewing without the experiment", *llmConfigID, experiment.ID)
+			return nil
+		}
+		assignment.LLMConfig = &llmConfig
+	}
+	logger.Infof("[AI] Experiment %q assigned variant %s to commit %s", experiment.Name, assignment.Variant, req.CommitHash)
+	return assignment
+}
+
+// apply replaces the prompt with the variant's and moves the variant's LLM config to the
+// front of the chain, keeping the others as fallbacks
+func (a *experimentAssignment) apply(template, promptRef string, llmConfigs []models.LLMConfig) (string, string, []models.LLMConfig) {
+	if a.Prompt != nil {
+		template, promptRef = a.Prompt.Content, PromptRef(PromptRefPromptTemplate, a.Prompt.ID)
+		if !containsScoringInstruction(template) {
+			template = appendScoringInstruction(template)
+		}
+	}
+	if a.LLMConfig != nil {
+		chain := []models.LLMConfig{*a.LLMConfig}
+		for _, c := range llmConfigs {
+			if c.ID != a.LLMConfig.ID {
+				chain = append(chain, c)
+			}
+		}
+		llmConfigs = chain
+	}
+	return template, promptRef, llmConfigs
+}
+
+// assignVariant assigns a commit variant B when its stable bucket falls within the traffic
+// of B, so every review of the commit gets the same variant
+func assignVariant(experiment *models.Experiment, commitHash string) string {
+	if stableBucket(fmt.Sprintf("experiment:%d:%s", experiment.ID, commitHash)) < experiment.TrafficB {
+		return models.ExperimentVariantB
+	}
+	return models.ExperimentVariantA
+}
+
+// experimentVariantConfig returns the prompt and LLM config of a variant, nil for the
+// project's regular ones
+func experimentVariantConfig(experiment *models.Experiment, variant string) (promptID, llmConfigID *uint) {
+	if variant == models.ExperimentVariantB {
+		return experiment.VariantBPromptID, experiment.VariantBLLMConfigID
+	}
+	return experiment.VariantAPromptID, experiment.VariantALLMConfigID
+}
+
+// ValidateExperiment checks the name, the traffic split and that the variants differ
+func ValidateExperiment(experiment *models.Experiment) error {
+	if strings.TrimSpace(experiment.Name) == "" {
+		return errors.New("name is required")
+	}
+	if experiment.ProjectID == 0 {
+		return errors.New("project_id is required")
+	}
+	if experiment.TrafficB < 0 || experiment.TrafficB > 100 {
+		return errors.New("traffic_b must be between 0 and 100")
+	}
+	if equalUintPtr(experiment.VariantAPromptID, experiment.VariantBPromptID) &&
+		equalUintPtr(experiment.VariantALLMConfigID, experiment.VariantBLLMConfigID) {
+		return errors.New("variants A and B must differ in prompt or LLM config")
+	}
+	return nil
+}
+
+func equalUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ExperimentService manages experiments and compares their variants
+type ExperimentService struct {
+	db *gorm.DB
+}
+
+func NewExperimentService(db *gorm.DB) *ExperimentService {
+	return &ExperimentService{db: db}
+}
+
+// List returns the experiments of a tenant's projects, or of one project, newest first
+func (s *ExperimentService) List(tenantID, projectID uint) ([]models.Experiment, error) {
+	query := s.db.Model(&models.Experiment{})
+	if tenantID > 0 {
+		query = query.Where("project_id IN (?)", s.db.Model(&models.Project{}).Select("id").Where("tenant_id = ?", tenantID))
+	}
+	if projectID > 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	var experiments []models.Experiment
+	err := query.Order("id DESC").Find(&experiments).Error
+	return experiments, err
+}
+
+func (s *ExperimentService) GetByID(id uint) (*models.Experiment, error) {
+	var experiment models.Experiment
+	if err := s.db.First(&experiment, id).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// Create stores a draft experiment
+func (s *ExperimentService) Create(experiment *models.Experiment) error {
+	experiment.Status = models.ExperimentStatusDraft
+	experiment.StartedAt, experiment.StoppedAt = nil, nil
+	if err := s.validate(experiment); err != nil {
+		return err
+	}
+	return s.db.Create(experiment).Error
+}
+
+// experimentEditableFields are the fields Update changes; the status changes with Start and Stop
+var experimentEditableFields = []string{"Name", "Description", "TrafficB", "VariantAPromptID", "VariantALLMConfigID", "VariantBPromptID", "VariantBLLMConfigID"}
+
+// UpdateExperimentRequest changes the given fields of an experiment. A variant prompt or LLM
+// config of 0 clears it, so the variant uses the project's regular one.
+type UpdateExperimentRequest struct {
+	Name                *string `json:"name" binding:"omitempty,max=100"`
+	Description         *string `json:"description" binding:"omitempty,max=500"`
+	TrafficB            *int    `json:"traffic_b" binding:"omitempty,min=0,max=100"`
+	VariantAPromptID    *uint   `json:"variant_a_prompt_id"`
+	VariantALLMConfigID *uint   `json:"variant_a_llm_config_id"`
+	VariantBPromptID    *uint   `json:"variant_b_prompt_id"`
+	VariantBLLMConfigID *uint   `json:"variant_b_llm_config_id"`
+}
+
+// setVariantID applies a variant reference of an update request, 0 clearing it
+func setVariantID(field **uint, value *uint) {
+	if value == nil {
+		return
+	}
+	if *value == 0 {
+		*field = nil
+		return
+	}
+	id := *value
+	*field = &id
+}
+
+// Update applies the given fields to an experiment. The variants and traffic of a running
+// experiment cannot change, as its results would mix both setups.
+func (s *ExperimentService) Update(id uint, req *UpdateExperimentRequest) (*models.Experiment, error) {
+	current, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	next := *current
+	if req.Name != nil {
+		next.Name = *req.Name
+	}
+	if req.Description != nil {
+		next.Description = *req.Description
+	}
+	if req.TrafficB != nil {
+		next.TrafficB = *req.TrafficB
+	}
+	setVariantID(&next.VariantAPromptID, req.VariantAPromptID)
+	setVariantID(&next.VariantALLMConfigID, req.VariantALLMConfigID)
+	setVariantID(&next.VariantBPromptID, req.VariantBPromptID)
+	setVariantID(&next.VariantBLLMConfigID, req.VariantBLLMConfigID)
+
+	if current.Status == models.ExperimentStatusRunning && (next.TrafficB != current.TrafficB ||
+		!equalUintPtr(next.VariantAPromptID, current.VariantAPromptID) || !equalUintPtr(next.VariantALLMConfigID, current.VariantALLMConfigID) ||
+		!equalUintPtr(next.VariantBPromptID, current.VariantBPromptID) || !equalUintPtr(next.VariantBLLMConfigID, current.VariantBLLMConfigID)) {
+		return nil, errors.New("stop the experiment to change its variants or traffic")
+	}
+	if err := s.validate(&next); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(current).Select(experimentEditableFields).Updates(&next).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// validate checks the experiment and that its prompts and LLM configs exist in the tenant
+// of its project
+func (s *ExperimentService) validate(experiment *models.Experiment) error {
+	if err := ValidateExperiment(experiment); err != nil {
+		return err
+	}
+	var project models.Project
+	if err := s.db.Select("id, tenant_id").First(&project, experiment.ProjectID).Error; err != nil {
+		return fmt.Errorf("project %d not found", experiment.ProjectID)
+	}
+	for _, id := range []*uint{experiment.VariantAPromptID, experiment.VariantBPromptID} {
+		if id != nil && !PromptTemplateInTenant(s.db, *id, project.TenantID) {
+			return fmt.Errorf("prompt template %d not found", *id)
+		}
+	}
+	for _, id := range []*uint{experiment.VariantALLMConfigID, experiment.VariantBLLMConfigID} {
+		if id != nil && !LLMConfigInTenant(s.db, *id, project.TenantID) {
+			return fmt.Errorf("LLM config %d not found", *id)
+		}
+	}
+	return nil
+}
+
+// Start runs an experiment; a project runs one experiment at a time
+func (s *ExperimentService) Start(id uint) (*models.Experiment, error) {
+	experiment, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status == models.ExperimentStatusRunning {
+		return experiment, nil
+	}
+	var running int64
+	s.db.Model(&models.Experiment{}).Where("project_id = ? AND status = ? AND id <> ?", experiment.ProjectID, models.ExperimentStatusRunning, id).Count(&running)
+	if running > 0 {
+		return nil, errors.New("the project already runs an experiment, stop it first")
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.ExperimentStatusRunning, "stopped_at": nil}
+	if experiment.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	if err := s.db.Model(experiment).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Stop ends an experiment; its reviews are kept for the results
+func (s *ExperimentService) Stop(id uint) (*models.Experiment, error) {
+	experiment, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentStatusRunning {
+		return nil, errors.New("the experiment is not running")
+	}
+	if err := s.db.Model(experiment).Updates(map[string]interface{}{"status": models.ExperimentStatusStopped, "stopped_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Delete removes a stopped or draft experiment; reviews keep their experiment reference
+func (s *ExperimentService) Delete(id uint) error {
+	experiment, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if experiment.Status == models.ExperimentStatusRunning {
+		return errors.New("stop the experiment before deleting it")
+	}
+	return s.db.Delete(experiment).Error
+}
+
+// ExperimentVariantResult is the review outcome, latency and token cost of a variant
+type ExperimentVariantResult struct {
+	ReviewConfigStats
+	Variant      string `json:"variant"`
+	Prompt       string `json:"prompt"`
+	LLMConfig    string `json:"llm_config"`
+	P50LatencyMs int64  `json:"p50_latency_ms"` // LLM time of a review, with chunk batches and fallbacks
+	P90LatencyMs int64  `json:"p90_latency_ms"`
+}
+
+// ExperimentResults compares the variants of an experiment
+type ExperimentResults struct {
+	Experiment          models.Experiment         `json:"experiment"`
+	Variants            []ExperimentVariantResult `json:"variants"`               // A then B
+	AvgScoreDiff        float64                   `json:"avg_score_diff"`         // B minus A
+	P50LatencyDiffMs    int64                     `json:"p50_latency_diff_ms"`    // B minus A
+	TokensPerReviewDiff float64                   `json:"tokens_per_review_diff"` // B minus A
+}
+
+// Results compares the reviews made with each variant of an experiment
+func (s *ExperimentService) Results(id uint) (*ExperimentResults, error) {
+	experiment, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	reviewQuery := s.db.Model(&models.ReviewLog{}).
+		Where("review_logs.experiment_id = ? AND review_logs.review_status IN ?", id, []string{"completed", ReviewStatusNeedsAttention})
+	var reviews []configReviewRow
+	if err := reviewQuery.Session(&gorm.Session{}).Select(configReviewColumns("review_logs.experiment_variant")).
+		Group("review_logs.experiment_variant, DATE(review_logs.created_at)").Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	var usage []configUsageRow
+	if err := s.db.Model(&models.AIUsageLog{}).Joins("JOIN review_logs ON review_logs.id = ai_usage_logs.review_log_id").
+		Where("review_logs.experiment_id = ?", id).
+		Select(configUsageColumns("review_logs.experiment_variant")).
+		Group("review_logs.experiment_variant, DATE(ai_usage_logs.created_at)").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	var latencies []struct {
+		ExperimentVariant string
+		LLMMs             int64
+	}
+	if err := reviewQuery.Session(&gorm.Session{}).Select("review_logs.experiment_variant, review_logs.llm_ms").
+		Where("review_logs.llm_ms > 0").Scan(&latencies).Error; err != nil {
+		return nil, err
+	}
+	variantLatencies := make(map[string][]int64)
+	for _, l := range latencies {
+		variantLatencies[l.ExperimentVariant] = append(variantLatencies[l.ExperimentVariant], l.LLMMs)
+	}
+
+	results := &ExperimentResults{Experiment: *experiment}
+	stats := mergeConfigStats(reviews, usage, "")
+	for _, variant := range []string{models.ExperimentVariantA, models.ExperimentVariantB} {
+		result := ExperimentVariantResult{ReviewConfigStats: ReviewConfigStats{Key: variant}, Variant: variant}
+		for _, st := range stats {
+			if st.Key == variant {
+				result.ReviewConfigStats = st
+			}
+		}
+		result.P50LatencyMs = latencyPercentile(variantLatencies[variant], 50)
+		result.P90LatencyMs = latencyPercentile(variantLatencies[variant], 90)
+		result.Prompt, result.LLMConfig = s.variantNames(experiment, variant)
+		result.Name = fmt.Sprintf("%s: %s / %s", strings.ToUpper(variant), result.Prompt, result.LLMConfig)
+		results.Variants = append(results.Variants, result)
+	}
+
+	a, b := results.Variants[0], results.Variants[1]
+	results.AvgScoreDiff = round1(b.AvgScore - a.AvgScore)
+	results.P50LatencyDiffMs = b.P50LatencyMs - a.P50LatencyMs
+	results.TokensPerReviewDiff = round1(b.TokensPerReview - a.TokensPerReview)
+	return results, nil
+}
+
+// variantNames names the prompt and LLM config of a variant
+func (s *ExperimentService) variantNames(experiment *models.Experiment, variant string) (prompt, llmConfig string) {
+	prompt, llmConfig = "Project prompt", "Project LLM config"
+	promptID, llmConfigID := experimentVariantConfig(experiment, variant)
+	if promptID != nil {
+		var template models.PromptTemplate
+		if err := s.db.Select("id, name").First(&template, *promptID).Error; err == nil {
+			prompt = template.Name
+		} else {
+			prompt = fmt.Sprintf("Prompt template %d (deleted)", *promptID)
+		}
+	}
+	if llmConfigID != nil {
+		var config models.LLMConfig
+		if err := s.db.Select("id, name, model").First(&config, *llmConfigID).Error; err == nil {
+			llmConfig = fmt.Sprintf("%s (%s)", config.Name, config.Model)
+		} else {
+			llmConfig = fmt.Sprintf("LLM config %d (deleted)", *llmConfigID)
+		}
+	}
+	return prompt, llmConfig
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func uintPtr(v uint) *uint { return &v }
+
+func TestAssignVariant(t *testing.T) {
+	tests := []struct {
+		name     string
+		trafficB int
+		minB     int
+		maxB     int
+	}{
+		{name: "all A", trafficB: 0, minB: 0, maxB: 0},
+		{name: "even split", trafficB: 50, minB: 400, maxB: 600},
+		{name: "small B", trafficB: 10, minB: 50, maxB: 150},
+		{name: "all B", trafficB: 100, minB: 1000, maxB: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experiment := &models.Experiment{ID: 7, TrafficB: tt.trafficB}
+			countB := 0
+			for i := 0; i < 1000; i++ {
+				commit := fmt.Sprintf("%040x", i)
+				variant := assignVariant(experiment, commit)
+				if again := assignVariant(experiment, commit); again != variant {
+					t.Fatalf("commit %s assigned %s then %s", commit, variant, again)
+				}
+				if variant == models.ExperimentVariantB {
+					countB++
+				}
+			}
+			if countB < tt.minB || countB > tt.maxB {
+				t.Errorf("variant B assigned %d of 1000 commits, want %d-%d", countB, tt.minB, tt.maxB)
+			}
+		})
+	}
+}
+
+func TestExperimentAssignmentApply(t *testing.T) {
+	chain := []models.LLMConfig{{ID: 1, Name: "default"}, {ID: 2, Name: "backup"}, {ID: 3, Name: "other"}}
+
+	tests := []struct {
+		name      string
+		a         experimentAssignment
+		wantRef   string
+		wantChain []uint
+	}{
+		{
+			name:      "control keeps the project setup",
+			a:         experimentAssignment{Variant: models.ExperimentVariantA},
+			wantRef:   PromptRefProject,
+			wantChain: []uint{1, 2, 3},
+		},
+		{
+			name:      "variant prompt",
+			a:         experimentAssignment{Variant: models.ExperimentVariantB, Prompt: &models.PromptTemplate{ID: 9, Content: "Review {{diffs}}"}},
+			wantRef:   "prompt_template:9",
+			wantChain: []uint{1, 2, 3},
+		},
+		{
+			name:      "variant LLM config moves first",
+			a:         experimentAssignment{Variant: models.ExperimentVariantB, LLMConfig: &models.LLMConfig{ID: 2, Name: "backup"}},
+			wantRef:   PromptRefProject,
+			wantChain: []uint{2, 1, 3},
+		},
+		{
+			name:      "variant LLM config outside the chain",
+			a:         experimentAssignment{Variant: models.ExperimentVariantB, LLMConfig: &models.LLMConfig{ID: 5, Name: "candidate"}},
+			wantRef:   PromptRefProject,
+			wantChain: []uint{5, 1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, ref, configs := tt.a.apply("project prompt", PromptRefProject, chain)
+			if ref != tt.wantRef {
+				t.Errorf("prompt ref = %q, want %q", ref, tt.wantRef)
+			}
+			if tt.a.Prompt != nil && !containsScoringInstruction(template) {
+				t.Errorf("variant prompt is missing the scoring instruction")
+			}
+			var ids []uint
+			for _, c := range configs {
+				ids = append(ids, c.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.wantChain) {
+				t.Errorf("chain = %v, want %v", ids, tt.wantChain)
+			}
+		})
+	}
+}
+
+func TestValidateExperiment(t *testing.T) {
+	tests := []struct {
+		name       string
+		experiment models.Experiment
+		wantErr    bool
+	}{
+		{name: "prompt against project prompt", experiment: models.Experiment{Name: "x", ProjectID: 1, TrafficB: 50, VariantBPromptID: uintPtr(2)}},
+		{name: "two models", experiment: models.Experiment{Name: "x", ProjectID: 1, TrafficB: 20, VariantALLMConfigID: uintPtr(1), VariantBLLMConfigID: uintPtr(2)}},
+		{name: "missing name", experiment: models.Experiment{ProjectID: 1, VariantBPromptID: uintPtr(2)}, wantErr: true},
+		{name: "missing project", experiment: models.Experiment{Name: "x", VariantBPromptID: uintPtr(2)}, wantErr: true},
+		{name: "traffic above 100", experiment: models.Experiment{Name: "x", ProjectID: 1, TrafficB: 101, VariantBPromptID: uintPtr(2)}, wantErr: true},
+		{name: "identical variants", experiment: models.Experiment{Name: "x", ProjectID: 1, VariantAPromptID: uintPtr(2), VariantBPromptID: uintPtr(2)}, wantErr: true},
+		{name: "both project defaults", experiment: models.Experiment{Name: "x", ProjectID: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateExperiment(&tt.experiment); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateExperiment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExperimentService_TenantVariants(t *testing.T) {
+	db := newTestDB(t)
+	project := &models.Project{Name: "p", URL: "https://gitlab.example.com/g/p", Platform: "gitlab", TenantID: 1}
+	otherUser := &models.User{Username: "other", Role: "tenant_admin", TenantID: 2}
+	mustCreate(t, db, project, otherUser)
+	own := &models.LLMConfig{Name: "own", Provider: "openai", Model: "m", TenantID: 1}
+	foreign := &models.LLMConfig{Name: "foreign", Provider: "openai", Model: "m", TenantID: 2}
+	shared := &models.PromptTemplate{Name: "shared", Content: "{{diffs}}"}
+	foreignPrompt := &models.PromptTemplate{Name: "foreign", Content: "{{diffs}}", CreatedBy: otherUser.ID}
+	mustCreate(t, db, own, foreign, shared, foreignPrompt)
+
+	s := NewExperimentService(db)
+	tests := []struct {
+		name       string
+		experiment models.Experiment
+		wantErr    bool
+	}{
+		{"own LLM config", models.Experiment{Name: "x", ProjectID: project.ID, VariantBLLMConfigID: uintPtr(own.ID)}, false},
+		{"shared prompt", models.Experiment{Name: "x", ProjectID: project.ID, VariantBPromptID: uintPtr(shared.ID)}, false},
+		{"LLM config of another tenant", models.Experiment{Name: "x", ProjectID: project.ID, VariantBLLMConfigID: uintPtr(foreign.ID)}, true},
+		{"prompt of another tenant", models.Experiment{Name: "x", ProjectID: project.ID, VariantBPromptID: uintPtr(foreignPrompt.ID)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			experiment := tt.experiment
+			if err := s.Create(&experiment); (err != nil) != tt.wantErr {
+				t.Errorf("Create() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var experiment models.Experiment
+	db.Where(&models.Experiment{VariantBLLMConfigID: &own.ID}).First(&experiment)
+	traffic := 30
+	if _, err := s.Update(experiment.ID, &UpdateExperimentRequest{VariantBLLMConfigID: uintPtr(foreign.ID)}); err == nil {
+		t.Error("Update() to an LLM config of another tenant error = nil")
+	}
+	updated, err := s.Update(experiment.ID, &UpdateExperimentRequest{TrafficB: &traffic, VariantBPromptID: uintPtr(shared.ID), VariantBLLMConfigID: uintPtr(0)})
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if updated.TrafficB != 30 || updated.VariantBLLMConfigID != nil || updated.VariantBPromptID == nil || updated.Name != "x" {
+		t.Errorf("Update() = %+v, want traffic 30 and the LLM config replaced by the prompt", updated)
+	}
+}
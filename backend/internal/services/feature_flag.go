@@ -0,0 +1,284 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Feature flags checked by the services. Features without a defined flag keep their
+// regular behavior.
+const (
+	// FeatureChunkedReview overrides the chunked_review_enabled setting per project
+	FeatureChunkedReview = "chunked_review"
+	// FeatureExperiments turns running prompt experiments off for some projects
+	FeatureExperiments = "experiments"
+)
+
+// KnownFeatureFlags describes the flags the services check, for the admin UI. Only these
+// keys can be created, a flag no service checks would have no effect.
+var KnownFeatureFlags = map[string]string{
+	FeatureChunkedReview: "Split large diffs into batches reviewed separately (overrides the chunked review setting)",
+	FeatureExperiments:   "Assign reviews to the variants of the project's running experiment (on without a flag)",
+}
+
+// FeatureFlagCacheTTL is how long flags are cached; other instances see changes after it
+const FeatureFlagCacheTTL = 30 * time.Second
+
+// Reasons of a flag evaluation
+const (
+	FlagReasonUndefined  = "undefined"   // No flag, the feature's default applies
+	FlagReasonDisabled   = "disabled"    // Kill switch off
+	FlagReasonExcluded   = "excluded"    // Project listed in excluded_project_ids
+	FlagReasonProject    = "project"     // Project listed in project_ids
+	FlagReasonRollout    = "rollout"     // Project within the rollout percentage
+	FlagReasonNotRolled  = "not_rolled"  // Project outside the rollout percentage
+	FlagReasonAllProject = "all_project" // Rollout of 100%
+)
+
+var featureFlagKeyRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,99}$`)
+
+// FeatureFlagEvaluation is the outcome of a flag for a project
+type FeatureFlagEvaluation struct {
+	Key       string `json:"key"`
+	ProjectID uint   `json:"project_id"`
+	Enabled   bool   `json:"enabled"`
+	Reason    string `json:"reason"`
+	Bucket    int    `json:"bucket"` // Stable 0-99 position of the project in percentage rollouts
+}
+
+// featureFlagCache holds the flags by key, reloaded from the database once expired
+type featureFlagCache struct {
+	mu       sync.RWMutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+	now      func() time.Time
+}
+
+var featureFlags = &featureFlagCache{now: time.Now}
+
+func (c *featureFlagCache) get(db *gorm.DB, key string) (models.FeatureFlag, bool) {
+	c.mu.RLock()
+	fresh := c.flags != nil && c.now().Sub(c.loadedAt) < FeatureFlagCacheTTL
+	flag, ok := c.flags[key]
+	c.mu.RUnlock()
+	if fresh || db == nil {
+		return flag, ok
+	}
+
+	var list []models.FeatureFlag
+	if err := db.Find(&list).Error; err != nil {
+		logger.Warnf("[FeatureFlag] Failed to load feature flags: %v", err)
+		return flag, ok
+	}
+	flags := make(map[string]models.FeatureFlag, len(list))
+	for _, f := range list {
+		flags[f.Key] = f
+	}
+	c.mu.Lock()
+	c.flags, c.loadedAt = flags, c.now()
+	c.mu.Unlock()
+	flag, ok = flags[key]
+	return flag, ok
+}
+
+// invalidate makes the next check reload the flags
+func (c *featureFlagCache) invalidate() {
+	c.mu.Lock()
+	c.flags = nil
+	c.mu.Unlock()
+}
+
+// IsFeatureEnabled reports whether a feature is on for a project, or fallback when the
+// feature has no flag
+func IsFeatureEnabled(key string, projectID uint, fallback bool) bool {
+	flag, ok := featureFlags.get(models.GetDB(), key)
+	if !ok {
+		return fallback
+	}
+	return evaluateFeatureFlag(&flag, projectID).Enabled
+}
+
+// evaluateFeatureFlag applies the kill switch, the project lists and the rollout percentage
+func evaluateFeatureFlag(flag *models.FeatureFlag, projectID uint) FeatureFlagEvaluation {
+	eval := FeatureFlagEvaluation{Key: flag.Key, ProjectID: projectID, Bucket: rolloutBucket(flag.Key, projectID)}
+	excluded, _ := parseIDList(flag.ExcludedProjectIDs)
+	included, _ := parseIDList(flag.ProjectIDs)
+	switch {
+	case !flag.Enabled:
+		eval.Reason = FlagReasonDisabled
+	case slices.Contains(excluded, projectID):
+		eval.Reason = FlagReasonExcluded
+	case slices.Contains(included, projectID):
+		eval.Enabled, eval.Reason = true, FlagReasonProject
+	case flag.Rollout >= 100:
+		eval.Enabled, eval.Reason = true, FlagReasonAllProject
+	case eval.Bucket < flag.Rollout:
+		eval.Enabled, eval.Reason = true, FlagReasonRollout
+	default:
+		eval.Reason = FlagReasonNotRolled
+	}
+	return eval
+}
+
+// rolloutBucket places a project at a stable 0-99 position per flag, so raising the
+// percentage only adds projects and different flags roll out to different projects first
+func rolloutBucket(key string, projectID uint) int {
+	return stableBucket(fmt.Sprintf("%s:%d", key, projectID))
+}
+
+// stableBucket hashes a value to a 0-99 bucket
+func stableBucket(value string) int {
+	h := fnv.New32a()
+	h.Write([]byte(value))
+	return int(h.Sum32() % 100)
+}
+
+// ValidateFeatureFlag checks the key, the rollout percentage and the project lists of a flag
+func ValidateFeatureFlag(flag *models.FeatureFlag) error {
+	if !featureFlagKeyRegex.MatchString(flag.Key) {
+		return fmt.Errorf("invalid key %q: use lowercase letters, digits, '_', '.' and '-'", flag.Key)
+	}
+	if _, ok := KnownFeatureFlags[flag.Key]; !ok {
+		return fmt.Errorf("unknown feature flag %q: no feature checks it", flag.Key)
+	}
+	if flag.Rollout < 0 || flag.Rollout > 100 {
+		return errors.New("rollout must be between 0 and 100")
+	}
+	if _, err := parseIDList(flag.ProjectIDs); err != nil {
+		return fmt.Errorf("project_ids: %w", err)
+	}
+	if _, err := parseIDList(flag.ExcludedProjectIDs); err != nil {
+		return fmt.Errorf("excluded_project_ids: %w", err)
+	}
+	return nil
+}
+
+type CreateFeatureFlagRequest struct {
+	Key                string `json:"key" binding:"required"`
+	Description        string `json:"description" binding:"max=500"`
+	Enabled            bool   `json:"enabled"`
+	Rollout            int    `json:"rollout" binding:"min=0,max=100"`
+	ProjectIDs         string `json:"project_ids"`
+	ExcludedProjectIDs string `json:"excluded_project_ids"`
+}
+
+// UpdateFeatureFlagRequest changes the given fields of a flag; its key cannot change
+type UpdateFeatureFlagRequest struct {
+	Description        *string `json:"description" binding:"omitempty,max=500"`
+	Enabled            *bool   `json:"enabled"`
+	Rollout            *int    `json:"rollout" binding:"omitempty,min=0,max=100"`
+	ProjectIDs         *string `json:"project_ids"`
+	ExcludedProjectIDs *string `json:"excluded_project_ids"`
+}
+
+// FeatureFlagService manages the feature flags
+type FeatureFlagService struct {
+	db *gorm.DB
+}
+
+func NewFeatureFlagService(db *gorm.DB) *FeatureFlagService {
+	return &FeatureFlagService{db: db}
+}
+
+func (s *FeatureFlagService) List() ([]models.FeatureFlag, error) {
+	var flags []models.FeatureFlag
+	err := s.db.Order("id ASC").Find(&flags).Error
+	return flags, err
+}
+
+func (s *FeatureFlagService) GetByID(id uint) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	if err := s.db.First(&flag, id).Error; err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (s *FeatureFlagService) Create(req *CreateFeatureFlagRequest, updatedBy string) (*models.FeatureFlag, error) {
+	flag := &models.FeatureFlag{
+		Key:                req.Key,
+		Description:        req.Description,
+		Enabled:            req.Enabled,
+		Rollout:            req.Rollout,
+		ProjectIDs:         req.ProjectIDs,
+		ExcludedProjectIDs: req.ExcludedProjectIDs,
+		UpdatedBy:          updatedBy,
+	}
+	if err := ValidateFeatureFlag(flag); err != nil {
+		return nil, err
+	}
+	var count int64
+	s.db.Model(&models.FeatureFlag{}).Where(&models.FeatureFlag{Key: flag.Key}).Count(&count)
+	if count > 0 {
+		return nil, fmt.Errorf("feature flag %s already exists", flag.Key)
+	}
+	if err := s.db.Create(flag).Error; err != nil {
+		return nil, err
+	}
+	featureFlags.invalidate()
+	return flag, nil
+}
+
+// Update applies the given fields to a flag
+func (s *FeatureFlagService) Update(id uint, req *UpdateFeatureFlagRequest, updatedBy string) (*models.FeatureFlag, error) {
+	flag, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"updated_by": updatedBy}
+	check := *flag
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Enabled != nil {
+		updates["enabled"] = *req.Enabled
+	}
+	if req.Rollout != nil {
+		check.Rollout = *req.Rollout
+		updates["rollout"] = *req.Rollout
+	}
+	if req.ProjectIDs != nil {
+		check.ProjectIDs = *req.ProjectIDs
+		updates["project_ids"] = *req.ProjectIDs
+	}
+	if req.ExcludedProjectIDs != nil {
+		check.ExcludedProjectIDs = *req.ExcludedProjectIDs
+		updates["excluded_project_ids"] = *req.ExcludedProjectIDs
+	}
+	if err := ValidateFeatureFlag(&check); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(flag).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	featureFlags.invalidate()
+	return s.GetByID(id)
+}
+
+func (s *FeatureFlagService) Delete(id uint) error {
+	if err := s.db.Delete(&models.FeatureFlag{}, id).Error; err != nil {
+		return err
+	}
+	featureFlags.invalidate()
+	return nil
+}
+
+// Evaluate returns the outcome of a flag for a project
+func (s *FeatureFlagService) Evaluate(key string, projectID uint) FeatureFlagEvaluation {
+	var flag models.FeatureFlag
+	if err := s.db.Where(&models.FeatureFlag{Key: key}).First(&flag).Error; err != nil {
+		return FeatureFlagEvaluation{Key: key, ProjectID: projectID, Reason: FlagReasonUndefined, Bucket: rolloutBucket(key, projectID)}
+	}
+	return evaluateFeatureFlag(&flag, projectID)
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestEvaluateFeatureFlag(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       models.FeatureFlag
+		projectID  uint
+		wantOn     bool
+		wantReason string
+	}{
+		{
+			name:       "kill switch off",
+			flag:       models.FeatureFlag{Key: "chunked_review", Rollout: 100, ProjectIDs: "1"},
+			projectID:  1,
+			wantReason: FlagReasonDisabled,
+		},
+		{
+			name:       "excluded wins over listed",
+			flag:       models.FeatureFlag{Key: "chunked_review", Enabled: true, Rollout: 100, ProjectIDs: "1", ExcludedProjectIDs: "1, 2"},
+			projectID:  1,
+			wantReason: FlagReasonExcluded,
+		},
+		{
+			name:       "listed project",
+			flag:       models.FeatureFlag{Key: "chunked_review", Enabled: true, ProjectIDs: "3,7"},
+			projectID:  7,
+			wantOn:     true,
+			wantReason: FlagReasonProject,
+		},
+		{
+			name:       "full rollout",
+			flag:       models.FeatureFlag{Key: "chunked_review", Enabled: true, Rollout: 100},
+			projectID:  42,
+			wantOn:     true,
+			wantReason: FlagReasonAllProject,
+		},
+		{
+			name:       "no rollout",
+			flag:       models.FeatureFlag{Key: "chunked_review", Enabled: true},
+			projectID:  42,
+			wantReason: FlagReasonNotRolled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := evaluateFeatureFlag(&tt.flag, tt.projectID)
+			if eval.Enabled != tt.wantOn || eval.Reason != tt.wantReason {
+				t.Errorf("evaluateFeatureFlag() = %v (%s), want %v (%s)", eval.Enabled, eval.Reason, tt.wantOn, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagRollout(t *testing.T) {
+	flag := models.FeatureFlag{Key: "experiments", Enabled: true, Rollout: 30}
+	enabled := make(map[uint]bool)
+	for id := uint(1); id <= 1000; id++ {
+		eval := evaluateFeatureFlag(&flag, id)
+		if eval.Enabled != (eval.Bucket < 30) {
+			t.Fatalf("project %d: enabled %v with bucket %d", id, eval.Enabled, eval.Bucket)
+		}
+		if again := evaluateFeatureFlag(&flag, id); again.Enabled != eval.Enabled {
+			t.Fatalf("project %d: evaluation is not stable", id)
+		}
+		enabled[id] = eval.Enabled
+	}
+	if n := countTrue(enabled); n < 250 || n > 350 {
+		t.Errorf("30%% rollout enabled %d of 1000 projects", n)
+	}
+
+	// Raising the percentage keeps the projects already rolled out to
+	flag.Rollout = 60
+	for id, on := range enabled {
+		if on && !evaluateFeatureFlag(&flag, id).Enabled {
+			t.Errorf("project %d dropped when raising the rollout", id)
+		}
+	}
+}
+
+func countTrue(values map[uint]bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+func TestValidateFeatureFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    models.FeatureFlag
+		wantErr bool
+	}{
+		{name: "valid", flag: models.FeatureFlag{Key: "chunked_review", Rollout: 50, ProjectIDs: "1,2", ExcludedProjectIDs: "3"}},
+		{name: "empty key", flag: models.FeatureFlag{}, wantErr: true},
+		{name: "uppercase key", flag: models.FeatureFlag{Key: "Chunked"}, wantErr: true},
+		{name: "unknown key", flag: models.FeatureFlag{Key: "inline_comments"}, wantErr: true},
+		{name: "rollout above 100", flag: models.FeatureFlag{Key: "chunked_review", Rollout: 101}, wantErr: true},
+		{name: "negative rollout", flag: models.FeatureFlag{Key: "chunked_review", Rollout: -1}, wantErr: true},
+		{name: "invalid project list", flag: models.FeatureFlag{Key: "chunked_review", ProjectIDs: "1,abc"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateFeatureFlag(&tt.flag); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFeatureFlag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagService_Update(t *testing.T) {
+	db := newTestDB(t)
+	s := NewFeatureFlagService(db)
+	flag, err := s.Create(&CreateFeatureFlagRequest{Key: FeatureChunkedReview, Enabled: true, Rollout: 20, ProjectIDs: "1"}, "admin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	rollout := 50
+	updated, err := s.Update(flag.ID, &UpdateFeatureFlagRequest{Rollout: &rollout}, "ops")
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if !updated.Enabled || updated.Rollout != 50 || updated.ProjectIDs != "1" || updated.UpdatedBy != "ops" {
+		t.Errorf("Update() = %+v, want only the rollout changed", updated)
+	}
+
+	disabled, invalid := false, "1,abc"
+	if _, err := s.Update(flag.ID, &UpdateFeatureFlagRequest{Enabled: &disabled, ProjectIDs: &invalid}, "ops"); err == nil {
+		t.Error("Update() with an invalid project list error = nil")
+	}
+	if updated, _ := s.GetByID(flag.ID); !updated.Enabled {
+		t.Error("a rejected update turned the flag off")
+	}
+	if updated, err := s.Update(flag.ID, &UpdateFeatureFlagRequest{Enabled: &disabled}, "ops"); err != nil || updated.Enabled {
+		t.Errorf("Update(enabled=false) = %+v, %v, want the flag off", updated, err)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"regexp"
 	"sort"
 	"strings"
-	"time"
 
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
@@ -23,7 +22,7 @@ type FileContextService struct {
 
 func NewFileContextService(configService *SystemConfigService) *FileContextService {
 	return &FileContextService{
-		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		httpClient:    PlatformHTTPClient(),
 		configService: configService,
 	}
 }
@@ -66,7 +65,7 @@ func (s *FileContextService) BuildFileContext(project *models.Project, diff stri
 		return s.BuildFunctionContext(project, diff, ref)
 	}
 
-	files := ParseDiffToFiles(diff)
+	files := contextFiles(project, ParseDiffToFiles(diff))
 	if len(files) == 0 {
 		return "", nil
 	}
@@ -89,12 +88,12 @@ func (s *FileContextService) BuildFileContext(project *models.Project, diff stri
 
 		content, err := s.fetchFileContent(project, file.FilePath, ref)
 		if err != nil {
-			logger.Infof("[FileContext] Failed to fetch %s: %v", file.FilePath, err)
+			logger.Module("file_context").Warn().Err(err).Uint("project_id", project.ID).Str("file", file.FilePath).Msg("Failed to fetch file")
 			continue
 		}
 
 		if len(content) > maxFileSize {
-			logger.Infof("[FileContext] File %s exceeds max size (%d > %d), skipping", file.FilePath, len(content), maxFileSize)
+			logger.Module("file_context").Info().Uint("project_id", project.ID).Str("file", file.FilePath).Int("size", len(content)).Int("max_size", maxFileSize).Msg("File exceeds max size, skipping")
 			continue
 		}
 
@@ -115,10 +114,22 @@ func (s *FileContextService) BuildFileContext(project *models.Project, diff stri
 	return formatFileContexts(contexts), nil
 }
 
+// contextFiles leaves out the files the project's path filter excludes from review
+func contextFiles(project *models.Project, files []FileDiff) []FileDiff {
+	filter := NewProjectPathFilter(project)
+	kept := files[:0]
+	for _, file := range files {
+		if filter.Reviewed(file.FilePath) {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
 // BuildFunctionContext extracts function/method definitions that contain modified lines
 // This provides more focused context to AI by only including relevant code blocks
 func (s *FileContextService) BuildFunctionContext(project *models.Project, diff string, ref string) (string, error) {
-	files := ParseDiffToFiles(diff)
+	files := contextFiles(project, ParseDiffToFiles(diff))
 	if len(files) == 0 {
 		return "", nil
 	}
@@ -145,12 +156,12 @@ func (s *FileContextService) BuildFunctionContext(project *models.Project, diff
 
 		content, err := s.fetchFileContent(project, file.FilePath, ref)
 		if err != nil {
-			logger.Infof("[FileContext] Failed to fetch %s: %v", file.FilePath, err)
+			logger.Module("file_context").Warn().Err(err).Uint("project_id", project.ID).Str("file", file.FilePath).Msg("Failed to fetch file")
 			continue
 		}
 
 		if len(content) > maxFileSize {
-			logger.Infof("[FileContext] File %s exceeds max size, skipping", file.FilePath)
+			logger.Module("file_context").Info().Uint("project_id", project.ID).Str("file", file.FilePath).Int("size", len(content)).Int("max_size", maxFileSize).Msg("File exceeds max size, skipping")
 			continue
 		}
 
@@ -175,7 +186,7 @@ func (s *FileContextService) BuildFunctionContext(project *models.Project, diff
 		return "", nil
 	}
 
-	logger.Infof("[FileContext] Extracted %d function(s) from modified files", totalFunctions)
+	logger.Module("file_context").Info().Uint("project_id", project.ID).Str("commit", ref).Int("functions", totalFunctions).Msg("Extracted functions from modified files")
 	return builder.String(), nil
 }
 
@@ -212,7 +223,7 @@ func (s *FileContextService) fetchGitLabFile(project *models.Project, filePath,
 		req.Header.Set("PRIVATE-TOKEN", project.AccessToken)
 	}
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.httpClient.Do(WithCacheTTL(req, FileContentCacheTTL))
 	if err != nil {
 		return "", err
 	}
@@ -248,7 +259,7 @@ func (s *FileContextService) fetchGitHubFile(project *models.Project, filePath,
 		req.Header.Set("Authorization", "token "+project.AccessToken)
 	}
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.httpClient.Do(WithCacheTTL(req, FileContentCacheTTL))
 	if err != nil {
 		return "", err
 	}
@@ -278,23 +289,20 @@ func (s *FileContextService) fetchGitHubFile(project *models.Project, filePath,
 }
 
 func (s *FileContextService) fetchBitbucketFile(project *models.Project, filePath, ref string) (string, error) {
-	info, err := parseRepoInfo(project.URL)
+	api, err := NewBitbucketAPI(models.GetDB(), s.httpClient, project)
 	if err != nil {
 		return "", err
 	}
 
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/src/%s/%s",
-		info.projectPath, ref, filePath)
-
-	req, err := http.NewRequest("GET", apiURL, nil)
+	req, err := http.NewRequest("GET", api.FileURL(filePath, ref), nil)
 	if err != nil {
 		return "", err
 	}
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+	if err := api.Authorize(req); err != nil {
+		return "", err
 	}
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := s.httpClient.Do(WithCacheTTL(req, FileContentCacheTTL))
 	if err != nil {
 		return "", err
 	}
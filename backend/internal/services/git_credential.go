@@ -1,6 +1,7 @@
 package services
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -30,7 +31,7 @@ func (s *GitCredentialService) List(params *GitCredentialListParams) ([]models.G
 	query := s.db.Model(&models.GitCredential{})
 
 	if params.Name != "" {
-		query = query.Where("name LIKE ?", "%"+params.Name+"%")
+		query = query.Where(LikeCondition("name"), ContainsPattern(params.Name))
 	}
 	if params.Platform != "" {
 		query = query.Where("platform = ?", params.Platform)
@@ -60,14 +61,19 @@ func (s *GitCredentialService) GetByID(id uint) (*models.GitCredential, error) {
 }
 
 func (s *GitCredentialService) Create(credential *models.GitCredential) error {
+	defer invalidatePlatformTLS()
 	return s.db.Create(credential).Error
 }
 
 func (s *GitCredentialService) Update(credential *models.GitCredential) error {
+	defer invalidatePlatformTLS()
+	defer bitbucketOAuth.forget(credential.ID)
 	return s.db.Save(credential).Error
 }
 
 func (s *GitCredentialService) Delete(id uint) error {
+	defer invalidatePlatformTLS()
+	defer bitbucketOAuth.forget(id)
 	return s.db.Delete(&models.GitCredential{}, id).Error
 }
 
@@ -77,34 +83,77 @@ func (s *GitCredentialService) GetActive() ([]models.GitCredential, error) {
 	return credentials, err
 }
 
+// FindMatchingCredential returns the auto-create credential covering a project URL. When
+// several do, the one scoped to the most specific namespace wins, so a group credential takes
+// precedence over a host-wide one; ties go to the newest.
 func (s *GitCredentialService) FindMatchingCredential(projectURL string, platform string) (*models.GitCredential, error) {
-	projectURL = strings.TrimSuffix(projectURL, ".git")
-	projectURL = strings.ToLower(projectURL)
-
 	var credentials []models.GitCredential
 	err := s.db.Where("platform = ? AND is_active = ? AND auto_create = ?", platform, true, true).
 		Order("id DESC").Find(&credentials).Error
 	if err != nil {
 		return nil, err
 	}
+	return bestCredentialMatch(credentials, projectURL), nil
+}
 
-	for _, cred := range credentials {
-		baseURL := strings.ToLower(strings.TrimSuffix(cred.BaseURL, "/"))
-		if baseURL == "" {
-			switch platform {
-			case "github":
-				baseURL = "https://github.com"
-			case "gitlab":
-				baseURL = "https://gitlab.com"
-			case "bitbucket":
-				baseURL = "https://bitbucket.org"
-			}
+func bestCredentialMatch(credentials []models.GitCredential, projectURL string) *models.GitCredential {
+	var best *models.GitCredential
+	bestLen := -1
+	for i := range credentials {
+		if n := credentialMatch(&credentials[i], projectURL); n > bestLen {
+			best, bestLen = &credentials[i], n
 		}
+	}
+	return best
+}
 
-		if strings.HasPrefix(projectURL, baseURL) {
-			return &cred, nil
+// credentialMatch reports how specifically a credential covers a project URL: the length of
+// the namespace the project is under, 0 for a credential without namespaces, -1 when the
+// project is not under the credential's base URL or any of its namespaces
+func credentialMatch(cred *models.GitCredential, projectURL string) int {
+	projectURL = strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(projectURL, "/"), ".git"))
+	path, ok := strings.CutPrefix(projectURL, credentialBaseURL(cred)+"/")
+	if !ok {
+		return -1
+	}
+	namespaces := ParseNamespaces(cred.Namespaces)
+	if len(namespaces) == 0 {
+		return 0
+	}
+	best := -1
+	for _, ns := range namespaces {
+		if strings.HasPrefix(path, ns+"/") && len(ns) > best {
+			best = len(ns)
 		}
 	}
+	return best
+}
 
-	return nil, nil
+// ParseNamespaces splits a comma or newline separated list of group paths, normalized to lower
+// case without surrounding slashes
+func ParseNamespaces(namespaces string) []string {
+	var result []string
+	for _, ns := range strings.FieldsFunc(namespaces, func(r rune) bool { return r == ',' || r == '\n' }) {
+		ns = strings.ToLower(strings.Trim(strings.TrimSpace(ns), "/"))
+		if ns != "" && !slices.Contains(result, ns) {
+			result = append(result, ns)
+		}
+	}
+	return result
+}
+
+// credentialBaseURL returns the lower-cased URL the repositories of a credential start with
+func credentialBaseURL(cred *models.GitCredential) string {
+	baseURL := strings.ToLower(strings.TrimSuffix(cred.BaseURL, "/"))
+	if baseURL == "" {
+		switch cred.Platform {
+		case "github":
+			baseURL = "https://github.com"
+		case "gitlab":
+			baseURL = "https://gitlab.com"
+		case "bitbucket":
+			baseURL = "https://bitbucket.org"
+		}
+	}
+	return baseURL
 }
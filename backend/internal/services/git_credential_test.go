@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"platform", []string{"platform"}},
+		{" /Platform/Infra/ , mobile\nplatform/infra,", []string{"platform/infra", "mobile"}},
+	}
+	for _, tt := range tests {
+		if got := ParseNamespaces(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseNamespaces(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCredentialMatch(t *testing.T) {
+	hostWide := &models.GitCredential{Platform: "gitlab", BaseURL: "https://gitlab.example.com"}
+	group := &models.GitCredential{Platform: "gitlab", BaseURL: "https://gitlab.example.com/", Namespaces: "platform,mobile"}
+	subgroup := &models.GitCredential{Platform: "gitlab", BaseURL: "https://gitlab.example.com", Namespaces: "platform/infra"}
+	tests := []struct {
+		name string
+		cred *models.GitCredential
+		url  string
+		want int
+	}{
+		{"host wide", hostWide, "https://gitlab.example.com/anyone/repo", 0},
+		{"other host", hostWide, "https://gitlab.com/anyone/repo", -1},
+		{"host prefix", hostWide, "https://gitlab.example.com.evil.io/anyone/repo", -1},
+		{"in group", group, "https://GitLab.example.com/Platform/api.git", len("platform")},
+		{"in nested group", group, "https://gitlab.example.com/platform/infra/deploy", len("platform")},
+		{"outside groups", group, "https://gitlab.example.com/personal/repo", -1},
+		{"group name prefix", group, "https://gitlab.example.com/platform-legacy/repo", -1},
+		{"group itself", subgroup, "https://gitlab.example.com/platform/infra", -1},
+		{"in subgroup", subgroup, "https://gitlab.example.com/platform/infra/deploy", len("platform/infra")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := credentialMatch(tt.cred, tt.url); got != tt.want {
+				t.Errorf("credentialMatch(%q) = %d, want %d", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestCredentialMatch(t *testing.T) {
+	// Newest first, as FindMatchingCredential loads them
+	credentials := []models.GitCredential{
+		{ID: 4, Platform: "gitlab", BaseURL: "https://gitlab.example.com"},
+		{ID: 3, Platform: "gitlab", BaseURL: "https://gitlab.example.com", Namespaces: "platform/infra"},
+		{ID: 2, Platform: "gitlab", BaseURL: "https://gitlab.example.com", Namespaces: "platform"},
+		{ID: 1, Platform: "gitlab", BaseURL: "https://gitlab.example.com"},
+	}
+	tests := []struct {
+		url  string
+		want uint
+	}{
+		{"https://gitlab.example.com/platform/infra/deploy", 3},
+		{"https://gitlab.example.com/platform/api", 2},
+		{"https://gitlab.example.com/personal/repo", 4},
+		{"https://gitlab.com/platform/api", 0},
+	}
+	for _, tt := range tests {
+		var got uint
+		if cred := bestCredentialMatch(credentials, tt.url); cred != nil {
+			got = cred.ID
+		}
+		if got != tt.want {
+			t.Errorf("bestCredentialMatch(%q) = credential %d, want %d", tt.url, got, tt.want)
+		}
+	}
+
+	scoped := credentials[1:3]
+	if cred := bestCredentialMatch(scoped, "https://gitlab.example.com/personal/repo"); cred != nil {
+		t.Errorf("project outside the namespaces matched credential %d", cred.ID)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// httpCacheMaxEntries bounds the platform API responses kept in memory
+	httpCacheMaxEntries = 1000
+	// httpCacheMaxBodySize skips caching of large responses
+	httpCacheMaxBodySize = 2 << 20
+	// FileContentCacheTTL is how long file content at a ref is served without revalidation
+	FileContentCacheTTL = 5 * time.Minute
+)
+
+// HTTPCache is an http.RoundTripper caching GET responses of the Git platform APIs.
+// Cached responses with an ETag or Last-Modified header are revalidated with a
+// conditional request, which does not count against GitHub's rate limit. Requests
+// carrying a TTL (see WithCacheTTL) are served from cache without a request while fresh.
+type HTTPCache struct {
+	base       http.RoundTripper
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	misses      atomic.Int64
+}
+
+type httpCacheEntry struct {
+	key       string
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// HTTPCacheStats counts how platform API GETs were served
+type HTTPCacheStats struct {
+	Hits        int64 // served from cache without a request
+	Revalidated int64 // served from cache after a 304 Not Modified
+	Misses      int64 // fetched from the platform
+	Entries     int
+}
+
+type cacheTTLKey struct{}
+
+// WithCacheTTL marks a request as cacheable for ttl without revalidation.
+// Use it for content addressed by an immutable ref, such as a file at a commit.
+func WithCacheTTL(req *http.Request, ttl time.Duration) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), cacheTTLKey{}, ttl))
+}
+
+// NewHTTPCache wraps base (http.DefaultTransport if nil) with a response cache
+func NewHTTPCache(base http.RoundTripper, maxEntries int) *HTTPCache {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &HTTPCache{
+		base:       base,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// platformHTTPCache is the response cache of the platform HTTP client
+var platformHTTPCache *HTTPCache
+
+// GetPlatformHTTPCacheStats returns the statistics of the shared platform API cache
+func GetPlatformHTTPCacheStats() HTTPCacheStats {
+	return platformHTTPCache.Stats()
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *HTTPCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return c.base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	entry := c.get(key)
+	if entry != nil && time.Now().Before(entry.expiresAt) {
+		c.hits.Add(1)
+		return entry.response(req), nil
+	}
+
+	outReq := req
+	if entry != nil {
+		etag, lastModified := entry.header.Get("ETag"), entry.header.Get("Last-Modified")
+		if etag != "" || lastModified != "" {
+			outReq = req.Clone(req.Context())
+			if etag != "" {
+				outReq.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				outReq.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	resp, err := c.base.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		resp.Body.Close()
+		c.revalidated.Add(1)
+		refreshed := *entry
+		refreshed.expiresAt = time.Now().Add(requestCacheTTL(req))
+		c.put(&refreshed)
+		return refreshed.response(req), nil
+	}
+
+	c.misses.Add(1)
+	if resp.StatusCode != http.StatusOK || !isCacheable(resp) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpCacheMaxBodySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > httpCacheMaxBodySize {
+		// Too large to cache: hand back the already read prefix followed by the rest
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	ttl := requestCacheTTL(req)
+	if ttl > 0 || resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "" {
+		c.put(&httpCacheEntry{
+			key:       key,
+			status:    resp.StatusCode,
+			header:    resp.Header.Clone(),
+			body:      body,
+			expiresAt: time.Now().Add(ttl),
+		})
+	}
+	return resp, nil
+}
+
+// Stats returns the cache statistics
+func (c *HTTPCache) Stats() HTTPCacheStats {
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+	return HTTPCacheStats{
+		Hits:        c.hits.Load(),
+		Revalidated: c.revalidated.Load(),
+		Misses:      c.misses.Load(),
+		Entries:     entries,
+	}
+}
+
+func (c *HTTPCache) get(key string) *httpCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*httpCacheEntry)
+}
+
+func (c *HTTPCache) put(entry *httpCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[entry.key]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*httpCacheEntry).key)
+	}
+}
+
+func (e *httpCacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cacheKey identifies a request by URL and the headers selecting the response,
+// so responses are never shared between different access tokens
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	for _, name := range []string{"Authorization", "PRIVATE-TOKEN", "Accept"} {
+		h.Write([]byte(name + ":" + req.Header.Get(name) + "\n"))
+	}
+	return req.URL.String() + "#" + hex.EncodeToString(h.Sum(nil))
+}
+
+func requestCacheTTL(req *http.Request) time.Duration {
+	ttl, _ := req.Context().Value(cacheTTLKey{}).(time.Duration)
+	return ttl
+}
+
+func isCacheable(resp *http.Response) bool {
+	return !strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store")
+}
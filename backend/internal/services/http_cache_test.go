@@ -0,0 +1,113 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func cachedGet(t *testing.T, client *http.Client, url, token string, ttl time.Duration) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("PRIVATE-TOKEN", token)
+	if ttl > 0 {
+		req = WithCacheTTL(req, ttl)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHTTPCache_Revalidation(t *testing.T) {
+	requests, conditional := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("content"))
+	}))
+	defer srv.Close()
+
+	cache := NewHTTPCache(nil, 10)
+	client := &http.Client{Transport: cache}
+
+	for i := 0; i < 3; i++ {
+		if status, body := cachedGet(t, client, srv.URL+"/mr", "token", 0); status != 200 || body != "content" {
+			t.Fatalf("request %d: got %d %q", i, status, body)
+		}
+	}
+	if requests != 3 || conditional != 2 {
+		t.Errorf("expected 3 requests with 2 conditional, got %d and %d", requests, conditional)
+	}
+
+	// A different token must not share the cached response
+	cachedGet(t, client, srv.URL+"/mr", "other", 0)
+	if conditional != 2 {
+		t.Error("response was revalidated with another token's ETag")
+	}
+
+	stats := cache.Stats()
+	if stats.Revalidated != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestHTTPCache_TTL(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("file"))
+	}))
+	defer srv.Close()
+
+	cache := NewHTTPCache(nil, 10)
+	client := &http.Client{Transport: cache}
+
+	cachedGet(t, client, srv.URL+"/file", "token", time.Minute)
+	if _, body := cachedGet(t, client, srv.URL+"/file", "token", time.Minute); body != "file" || requests != 1 {
+		t.Errorf("expected fresh entry to be served from cache, got %q after %d requests", body, requests)
+	}
+
+	// Without a TTL or validators nothing is cached
+	cachedGet(t, client, srv.URL+"/plain", "token", 0)
+	cachedGet(t, client, srv.URL+"/plain", "token", 0)
+	if requests != 3 {
+		t.Errorf("expected uncacheable response to be fetched twice, got %d requests", requests)
+	}
+
+	// Errors are never cached
+	cachedGet(t, client, srv.URL+"/missing", "token", time.Minute)
+	if status, _ := cachedGet(t, client, srv.URL+"/missing", "token", time.Minute); status != 404 || requests != 5 {
+		t.Errorf("expected 404 to be refetched, got %d after %d requests", status, requests)
+	}
+}
+
+func TestHTTPCache_Eviction(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	cache := NewHTTPCache(nil, 2)
+	client := &http.Client{Transport: cache}
+	for _, path := range []string{"/a", "/b", "/c"} {
+		cachedGet(t, client, srv.URL+path, "token", time.Minute)
+	}
+	if entries := cache.Stats().Entries; entries != 2 {
+		t.Errorf("expected 2 entries after eviction, got %d", entries)
+	}
+}
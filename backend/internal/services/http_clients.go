@@ -0,0 +1,235 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// maxRetryAfter caps the Retry-After delay a retry honors
+const maxRetryAfter = 30 * time.Second
+
+// httpClients are the outbound HTTP clients of the integrations, built once from the
+// config and shared by every service
+var httpClients = newHTTPClients(&config.HTTPConfig{})
+
+type integrationClients struct {
+	platform     *http.Client
+	llm          *http.Client
+	notification *http.Client
+	outbound     http.RoundTripper // Other external services, through the global proxy
+}
+
+// InitHTTPClients builds the integration HTTP clients from the config. Call it at startup,
+// before the services are created.
+func InitHTTPClients(cfg *config.HTTPConfig) error {
+	if err := validateProxies(cfg); err != nil {
+		return err
+	}
+	httpClients = newHTTPClients(cfg)
+	return nil
+}
+
+func newHTTPClients(cfg *config.HTTPConfig) *integrationClients {
+	platform := withGlobalProxy(cfg.Platform.WithDefaults(config.DefaultPlatformHTTP), cfg)
+	platformTLS = newPlatformTLSRouter(newTransport(platform))
+	platformHTTPCache = NewHTTPCache(withRetries(withSecretRefs(platformTLS), platform), httpCacheMaxEntries)
+	return &integrationClients{
+		platform:     &http.Client{Timeout: seconds(platform.Timeout), Transport: platformHTTPCache},
+		llm:          newIntegrationClient(withGlobalProxy(cfg.LLM.WithDefaults(config.DefaultLLMHTTP), cfg)),
+		notification: newIntegrationClient(withGlobalProxy(cfg.Notification.WithDefaults(config.DefaultNotificationHTTP), cfg)),
+		outbound:     withSecretRefs(newTransport(config.HTTPClientConfig{Proxy: cfg.Proxy, NoProxy: cfg.NoProxy}.WithDefaults(config.DefaultNotificationHTTP))),
+	}
+}
+
+// withGlobalProxy applies the global proxy settings the integration doesn't override
+func withGlobalProxy(c config.HTTPClientConfig, cfg *config.HTTPConfig) config.HTTPClientConfig {
+	if c.Proxy == "" {
+		c.Proxy = cfg.Proxy
+	}
+	if c.NoProxy == "" {
+		c.NoProxy = cfg.NoProxy
+	}
+	return c
+}
+
+// PlatformHTTPClient returns the client for Git platform APIs, which shares one response cache
+func PlatformHTTPClient() *http.Client {
+	return httpClients.platform
+}
+
+// LLMHTTPClient returns the client for LLM providers
+func LLMHTTPClient() *http.Client {
+	return httpClients.llm
+}
+
+// NotificationHTTPClient returns the client for IM bots
+func NotificationHTTPClient() *http.Client {
+	return httpClients.notification
+}
+
+// OutboundHTTPClient returns a client for other external services, such as review hooks,
+// issue trackers and report publishers, going through the global proxy
+func OutboundHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: httpClients.outbound}
+}
+
+func newIntegrationClient(cfg config.HTTPClientConfig) *http.Client {
+	return &http.Client{Timeout: seconds(cfg.Timeout), Transport: withRetries(withSecretRefs(newTransport(cfg)), cfg)}
+}
+
+// newTransport builds the pooled transport of an integration
+func newTransport(cfg config.HTTPClientConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = cfg.MaxIdleConns
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = seconds(cfg.IdleConnTimeout)
+	transport.Proxy = proxyFunc(cfg.Proxy, cfg.NoProxy)
+	return transport
+}
+
+// proxyFunc selects the proxy of a request: none for "direct" and the hosts of noProxy,
+// the environment (HTTPS_PROXY/HTTP_PROXY/NO_PROXY) when no proxy is configured
+func proxyFunc(proxy, noProxy string) func(*http.Request) (*url.URL, error) {
+	switch proxy {
+	case "":
+		return http.ProxyFromEnvironment
+	case config.ProxyDirect:
+		return nil
+	}
+	selectProxy := (&httpproxy.Config{HTTPProxy: proxy, HTTPSProxy: proxy, NoProxy: noProxy}).ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return selectProxy(req.URL)
+	}
+}
+
+// validateProxies rejects proxy URLs that would only fail on the first request
+func validateProxies(cfg *config.HTTPConfig) error {
+	proxies := map[string]string{
+		"http.proxy":              cfg.Proxy,
+		"http.platform.proxy":     cfg.Platform.Proxy,
+		"http.llm.proxy":          cfg.LLM.Proxy,
+		"http.notification.proxy": cfg.Notification.Proxy,
+	}
+	for name, proxy := range proxies {
+		if proxy == "" || proxy == config.ProxyDirect {
+			continue
+		}
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return fmt.Errorf("invalid %s: scheme must be http, https or socks5", name)
+		}
+		if u.Hostname() == "" {
+			return fmt.Errorf("invalid %s: missing host", name)
+		}
+	}
+	return nil
+}
+
+// withRetries retries the failed requests of transport as configured
+func withRetries(transport http.RoundTripper, cfg config.HTTPClientConfig) http.RoundTripper {
+	if cfg.Retries <= 0 {
+		return transport
+	}
+	return &retryTransport{
+		base:    transport,
+		retries: cfg.Retries,
+		backoff: time.Duration(cfg.RetryBackoffMs) * time.Millisecond,
+	}
+}
+
+// retryTransport retries failed requests with exponential backoff. Requests that never
+// reached the server are retried whatever their method; idempotent requests also on other
+// network errors, 429 and 502-504.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !shouldRetry(req, resp, err) || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := t.backoff << attempt
+		if resp != nil {
+			if after := retryAfter(resp); after > 0 {
+				delay = min(after, maxRetryAfter)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			return true
+		}
+		return isIdempotent(req.Method)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return isIdempotent(req.Method)
+	}
+	return false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the delay of a Retry-After header in seconds
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// IaC review modes of a project
+const (
+	IaCReviewAuto = "auto" // Infrastructure-as-code changes get the IaC prompt or checklist (default)
+	IaCReviewOff  = "off"  // IaC changes are reviewed like any other code
+)
+
+// ValidIaCReviewMode reports whether mode is a known IaC review mode; empty means the default
+func ValidIaCReviewMode(mode string) bool {
+	return mode == "" || mode == IaCReviewAuto || mode == IaCReviewOff
+}
+
+// Infrastructure-as-code kinds
+const (
+	IaCTerraform      = "terraform"
+	IaCKubernetes     = "kubernetes"
+	IaCCloudFormation = "cloudformation"
+)
+
+// Cloud compliance categories of findings on IaC files
+const (
+	ComplianceNetworkExposure = "network-exposure"
+	ComplianceIAM             = "iam"
+	ComplianceSecrets         = "secrets"
+	ComplianceEncryption      = "encryption"
+	ComplianceLogging         = "logging"
+	ComplianceDrift           = "drift"
+)
+
+// ComplianceCategories lists the compliance categories in classification priority order
+var ComplianceCategories = []string{ComplianceNetworkExposure, ComplianceIAM, ComplianceSecrets, ComplianceEncryption, ComplianceLogging, ComplianceDrift}
+
+// complianceKeywords are matched case-insensitively against findings on IaC files
+var complianceKeywords = map[string][]string{
+	ComplianceNetworkExposure: {"security group", "security_group", "0.0.0.0/0", "::/0", "ingress", "egress", "public ip", "publicly",
+		"public access", "internet", "firewall", "open port", "load balancer", "networkpolicy", "network policy", "hostnetwork",
+		"安全组", "公网", "暴露", "入站", "防火墙", "端口"},
+	ComplianceIAM: {"iam", "role", "policy", "privilege", "permission", "least privilege", "wildcard", "\"*\"", "assume",
+		"rbac", "serviceaccount", "service account", "clusterrole", "runasroot", "privileged",
+		"权限", "角色", "策略", "最小权限", "提权"},
+	ComplianceSecrets: {"secret", "password", "credential", "token", "api key", "access key", "private key", "plaintext",
+		"密钥", "密码", "凭证", "明文"},
+	ComplianceEncryption: {"encrypt", "kms", "tls", "ssl", "at rest", "in transit", "certificate",
+		"加密", "证书"},
+	ComplianceLogging: {"logging", "audit", "cloudtrail", "flow log", "monitoring", "retention", "access log",
+		"日志", "审计", "监控"},
+	ComplianceDrift: {"drift", "terraform state", "state file", "remote state", "destroy", "replace", "recreat", "force_new", "forces replacement", "lifecycle",
+		"prevent_destroy", "ignore_changes", "manual change", "data loss", "version pin", "pinned",
+		"漂移", "销毁", "重建", "替换", "数据丢失", "版本锁定"},
+}
+
+var (
+	// complianceCategoryRegex matches an explicit compliance line, as requested by the IaC prompt
+	complianceCategoryRegex = regexp.MustCompile(`(?i)\*\*compliance:\*\*\s*([a-z-]+)`)
+	k8sAPIVersionRegex      = regexp.MustCompile(`(?m)^[+ -]?apiVersion:\s*\S+`)
+	k8sKindRegex            = regexp.MustCompile(`(?m)^[+ -]?kind:\s*[A-Z]\w*`)
+	cloudFormationRegex     = regexp.MustCompile(`AWSTemplateFormatVersion|["']?Type["']?\s*:\s*["']?AWS::`)
+)
+
+// k8sPathSegments are directories whose YAML files are treated as Kubernetes manifests
+var k8sPathSegments = map[string]bool{"k8s": true, "kubernetes": true, "manifests": true, "helm": true, "charts": true, "kustomize": true}
+
+// IaCKind returns the infrastructure-as-code kind of a changed file, or "" for other files.
+// Terraform is detected by extension, Kubernetes and CloudFormation by the diff content,
+// falling back to the directory for manifests whose header is outside the diff.
+func IaCKind(file FileDiff) string {
+	p := strings.ToLower(file.FilePath)
+	base := path.Base(p)
+	switch {
+	case strings.HasSuffix(p, ".tf"), strings.HasSuffix(p, ".tf.json"), strings.HasSuffix(p, ".tfvars"), strings.HasSuffix(p, ".hcl"):
+		return IaCTerraform
+	case strings.HasSuffix(p, ".yaml"), strings.HasSuffix(p, ".yml"), strings.HasSuffix(p, ".json"), strings.HasSuffix(p, ".template"):
+	default:
+		return ""
+	}
+
+	if cloudFormationRegex.MatchString(file.Content) {
+		return IaCCloudFormation
+	}
+	if strings.HasSuffix(p, ".json") || strings.HasSuffix(p, ".template") {
+		return ""
+	}
+	if k8sAPIVersionRegex.MatchString(file.Content) && k8sKindRegex.MatchString(file.Content) {
+		return IaCKubernetes
+	}
+	if base == "kustomization.yaml" || base == "kustomization.yml" {
+		return IaCKubernetes
+	}
+	for _, segment := range strings.Split(path.Dir(p), "/") {
+		if k8sPathSegments[segment] {
+			return IaCKubernetes
+		}
+	}
+	return ""
+}
+
+// detectIaC returns the IaC kinds among the changed files, in detection order, and
+// whether every changed file is infrastructure as code
+func detectIaC(files []FileDiff) ([]string, bool) {
+	var kinds []string
+	all := len(files) > 0
+	for _, f := range files {
+		kind := IaCKind(f)
+		if kind == "" {
+			all = false
+			continue
+		}
+		if !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds, all
+}
+
+// iacFilePaths returns the changed files that are infrastructure as code
+func iacFilePaths(files []FileDiff) map[string]bool {
+	paths := make(map[string]bool)
+	for _, f := range files {
+		if IaCKind(f) != "" {
+			paths[f.FilePath] = true
+		}
+	}
+	return paths
+}
+
+// ClassifyCompliance returns the compliance category whose keywords match a finding best,
+// or "" when none does. An explicit "**Compliance:** iam" line takes precedence.
+func ClassifyCompliance(title, body string) string {
+	if m := complianceCategoryRegex.FindStringSubmatch(body); m != nil && slices.Contains(ComplianceCategories, strings.ToLower(m[1])) {
+		return strings.ToLower(m[1])
+	}
+	title, body = strings.ToLower(title), strings.ToLower(body)
+	best, bestScore := "", 0
+	for _, category := range ComplianceCategories {
+		score := 0
+		for _, keyword := range complianceKeywords[category] {
+			if strings.Contains(title, keyword) {
+				score += 3
+			}
+			if strings.Contains(body, keyword) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = category, score
+		}
+	}
+	return best
+}
+
+// GenerateIaCHints returns the IaC checklist appended to prompts of changes that touch
+// infrastructure as code alongside other files
+func GenerateIaCHints(files []FileDiff) string {
+	kinds, _ := detectIaC(files)
+	if len(kinds) == 0 {
+		return ""
+	}
+	return "\n\n--- Infrastructure-as-Code Review Guidelines (" + strings.Join(kinds, ", ") + ") ---\n" + iacChecklist +
+		"\nFor each issue on these files add a line `**Compliance:** <category>` with one of: " +
+		strings.Join(ComplianceCategories, ", ") + ".\n"
+}
+
+const iacChecklist = `- Network exposure: security groups, firewall rules and ingress open to 0.0.0.0/0 or ::/0, public IPs and buckets, Kubernetes Services of type LoadBalancer/NodePort, hostNetwork
+- IAM: wildcard actions or resources, admin or privileged roles, trust policies, RBAC ClusterRoles, service accounts, privileged or root containers
+- Secrets: hardcoded passwords, tokens and keys, secrets in variables, outputs, ConfigMaps or Parameters without NoEcho
+- Encryption: storage, databases and queues without encryption at rest, missing KMS keys, listeners without TLS
+- Logging: disabled access logs, flow logs, CloudTrail or audit logs, short log retention
+- Drift and change risk: changes forcing resource replacement or destruction, missing prevent_destroy or deletion protection, ignore_changes hiding drift, unpinned provider, module and image versions
+`
+
+// iacReviewPrompt is the prompt of changes made only of infrastructure as code, used
+// when no template or project prompt applies
+const iacReviewPrompt = `你是一位资深的云基础设施与安全工程师。请对以下基础设施即代码（Terraform、Kubernetes、CloudFormation）变更进行审查，重点关注云上安全合规与变更风险。
+
+## 审查清单
+` + iacChecklist + `
+## 评分维度（总分100分）
+1. **网络暴露与访问控制（30分）**：安全组、防火墙、公网访问、IAM 与 RBAC 是否遵循最小权限。
+2. **密钥与加密（25分）**：是否存在硬编码密钥，存储与传输是否加密。
+3. **变更风险与漂移（25分）**：是否会导致资源销毁或替换，是否锁定版本，是否掩盖状态漂移。
+4. **日志与可审计性（10分）**：访问日志、审计日志与保留策略。
+5. **规范与可维护性（10分）**：命名、标签、变量校验与模块划分。
+
+## 重要规则（必须严格遵守）
+- 请**仅关注并输出最重要的前三个问题（Top 3）**，不得多于 3 个。
+- 每个问题需注明所在文件，并单独一行注明合规类别，格式为 ` + "`**Compliance:** <类别>`" + `，类别取值：network-exposure、iam、secrets、encryption、logging、drift。
+
+## 输出格式（Markdown）
+### 一、关键问题与优化建议（仅限 Top 3）
+- 每个问题需包含：问题描述、影响分析、优化建议，必要时给出修改示例。
+
+### 二、评分明细
+- 按五个评分维度分别给出具体分数，并简要说明理由。
+
+### 三、总分（特别重要）
+- 格式必须为："总分:XX分"（例如：总分:80分）。
+- 必须确保可通过正则表达式 r"总分[:：]\s*(\d+)分?" 正确解析出总分值。
+
+---
+{{#if file_context}}
+**完整文件上下文**（` + "`»`" + ` 标记的行是本次修改的行）:
+{{file_context}}
+
+{{/if}}**代码变更内容**：
+{{diffs}}
+
+**提交历史（commits）**：
+{{commits}}`
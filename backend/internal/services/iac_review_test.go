@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIaCKind(t *testing.T) {
+	tests := []struct {
+		name string
+		file FileDiff
+		want string
+	}{
+		{"terraform", FileDiff{FilePath: "infra/main.tf"}, IaCTerraform},
+		{"tfvars", FileDiff{FilePath: "infra/prod.tfvars"}, IaCTerraform},
+		{"terragrunt", FileDiff{FilePath: "live/terragrunt.hcl"}, IaCTerraform},
+		{"kubernetes manifest", FileDiff{FilePath: "deploy/app.yaml", Content: "+apiVersion: apps/v1\n+kind: Deployment\n"}, IaCKubernetes},
+		{"kubernetes directory", FileDiff{FilePath: "k8s/base/service.yml", Content: "+  replicas: 3\n"}, IaCKubernetes},
+		{"kustomization", FileDiff{FilePath: "overlays/prod/kustomization.yaml"}, IaCKubernetes},
+		{"cloudformation yaml", FileDiff{FilePath: "stack.yaml", Content: "+  Bucket:\n+    Type: AWS::S3::Bucket\n"}, IaCCloudFormation},
+		{"cloudformation json", FileDiff{FilePath: "stack.json", Content: `+  "AWSTemplateFormatVersion": "2010-09-09",`}, IaCCloudFormation},
+		{"plain yaml", FileDiff{FilePath: ".github/workflows/ci.yml", Content: "+on: push\n"}, ""},
+		{"plain json", FileDiff{FilePath: "package.json", Content: "+  \"kind\": \"lib\"\n"}, ""},
+		{"source", FileDiff{FilePath: "main.go"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IaCKind(tt.file); got != tt.want {
+				t.Errorf("IaCKind(%q) = %q, want %q", tt.file.FilePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectIaC(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     []FileDiff
+		wantKinds []string
+		wantAll   bool
+	}{
+		{"none", nil, nil, false},
+		{"iac only", []FileDiff{{FilePath: "main.tf"}, {FilePath: "k8s/app.yaml"}, {FilePath: "vars.tf"}}, []string{IaCTerraform, IaCKubernetes}, true},
+		{"mixed", []FileDiff{{FilePath: "main.tf"}, {FilePath: "main.go"}}, []string{IaCTerraform}, false},
+		{"no iac", []FileDiff{{FilePath: "main.go"}}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds, all := detectIaC(tt.files)
+			if !reflect.DeepEqual(kinds, tt.wantKinds) || all != tt.wantAll {
+				t.Errorf("detectIaC() = %v, %v, want %v, %v", kinds, all, tt.wantKinds, tt.wantAll)
+			}
+		})
+	}
+}
+
+func TestClassifyCompliance(t *testing.T) {
+	tests := []struct {
+		title string
+		body  string
+		want  string
+	}{
+		{"Security group allows SSH from 0.0.0.0/0", "", ComplianceNetworkExposure},
+		{"IAM policy grants wildcard actions", "", ComplianceIAM},
+		{"Database password hardcoded in variables", "", ComplianceSecrets},
+		{"S3 bucket without encryption at rest", "", ComplianceEncryption},
+		{"CloudTrail logging disabled", "", ComplianceLogging},
+		{"Renaming the instance forces replacement", "", ComplianceDrift},
+		{"Missing tags", "**Compliance:** IAM\nThe role has no owner tag.", ComplianceIAM},
+		{"Missing description on variable", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ClassifyCompliance(tt.title, tt.body); got != tt.want {
+			t.Errorf("ClassifyCompliance(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateIaCHints(t *testing.T) {
+	if hints := GenerateIaCHints([]FileDiff{{FilePath: "main.go"}}); hints != "" {
+		t.Errorf("GenerateIaCHints() without IaC files = %q, want empty", hints)
+	}
+
+	hints := GenerateIaCHints([]FileDiff{{FilePath: "main.go"}, {FilePath: "infra/main.tf"}})
+	for _, want := range []string{"(terraform)", "0.0.0.0/0", "**Compliance:**", ComplianceDrift} {
+		if !strings.Contains(hints, want) {
+			t.Errorf("GenerateIaCHints() missing %q:\n%s", want, hints)
+		}
+	}
+}
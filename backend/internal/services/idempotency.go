@@ -0,0 +1,74 @@
+package services
+
+import (
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// IdempotencyTTL is how long the response of an idempotent request is kept for replay
+const IdempotencyTTL = 24 * time.Hour
+
+// IdempotencyLease is how long a key stays claimed by a request in progress. A key whose
+// request crashed or was killed before completing is freed after it, instead of
+// answering 409 until the TTL expires.
+const IdempotencyLease = 15 * time.Minute
+
+// IdempotencyService persists Idempotency-Key records
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{db: db}
+}
+
+// Begin claims key within scope for a request with the given body hash.
+// If the key was already used, the existing record is returned with created=false.
+func (s *IdempotencyService) Begin(scope, key, requestHash string) (record *models.IdempotencyKey, created bool, err error) {
+	if existing := s.find(scope, key); existing != nil {
+		return existing, false, nil
+	}
+	// Free the key of an expired record that has not been cleaned up yet
+	s.db.Where("scope = ? AND idempotency_key = ? AND expires_at <= ?", scope, key, time.Now()).Delete(&models.IdempotencyKey{})
+
+	record = &models.IdempotencyKey{
+		Scope:       scope,
+		Key:         key,
+		RequestHash: requestHash,
+		Status:      "processing",
+		ExpiresAt:   time.Now().Add(IdempotencyLease),
+	}
+	if err := s.db.Create(record).Error; err != nil {
+		// Lost the race against a concurrent request with the same key
+		if existing := s.find(scope, key); existing != nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return record, true, nil
+}
+
+// Complete stores the response of a claimed key for replay, kept for IdempotencyTTL
+func (s *IdempotencyService) Complete(id uint, status int, body []byte) error {
+	return s.db.Model(&models.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          "completed",
+		"response_status": status,
+		"response_body":   string(body),
+		"expires_at":      time.Now().Add(IdempotencyTTL),
+	}).Error
+}
+
+// Release deletes a claimed key so the request can be retried
+func (s *IdempotencyService) Release(id uint) error {
+	return s.db.Delete(&models.IdempotencyKey{}, id).Error
+}
+
+func (s *IdempotencyService) find(scope, key string) *models.IdempotencyKey {
+	var record models.IdempotencyKey
+	if err := s.db.Where("scope = ? AND idempotency_key = ? AND expires_at > ?", scope, key, time.Now()).First(&record).Error; err != nil {
+		return nil
+	}
+	return &record
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIdempotencyService_ProcessingLease(t *testing.T) {
+	db := newTestDB(t)
+	service := NewIdempotencyService(db)
+
+	record, created, err := service.Begin("POST /review/sync anonymous", "abc", "hash")
+	if err != nil || !created {
+		t.Fatalf("Begin() = %v, %v, want a new record", created, err)
+	}
+	if record.ExpiresAt.After(time.Now().Add(IdempotencyLease)) {
+		t.Errorf("processing key expires at %v, want within the lease", record.ExpiresAt)
+	}
+	if _, created, _ := service.Begin("POST /review/sync anonymous", "abc", "hash"); created {
+		t.Error("Begin() claimed a key that is still in progress")
+	}
+
+	// The request was killed before completing: the key is claimable once the lease ended
+	db.Model(&models.IdempotencyKey{}).Where("id = ?", record.ID).UpdateColumn("expires_at", time.Now().Add(-time.Second))
+	retried, created, err := service.Begin("POST /review/sync anonymous", "abc", "hash")
+	if err != nil || !created {
+		t.Fatalf("Begin() after the lease = %v, %v, want a new record", created, err)
+	}
+
+	if err := service.Complete(retried.ID, 200, []byte(`{}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	var stored models.IdempotencyKey
+	db.First(&stored, retried.ID)
+	if stored.Status != "completed" || stored.ExpiresAt.Before(time.Now().Add(IdempotencyTTL-time.Minute)) {
+		t.Errorf("completed key = %s expiring at %v, want it kept for the TTL", stored.Status, stored.ExpiresAt)
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// DefaultIgnorePatterns - files that should be skipped by default (config, lock, generated files)
+const DefaultIgnorePatterns = "*.json,*.yaml,*.yml,*.toml,*.xml,*.ini,*.env,*.config," +
+	"*.lock,package-lock.json,yarn.lock,pnpm-lock.yaml,go.sum,Cargo.lock,composer.lock,Gemfile.lock,poetry.lock," +
+	"*.min.js,*.min.css,*.bundle.js,*.bundle.css," +
+	"dist/,build/,out/,target/,.next/," +
+	"vendor/,node_modules/,__pycache__/,.venv/,venv/"
+
+// IgnoreMatcher matches file paths against ignore patterns with gitignore semantics:
+//   - a pattern without a slash matches a file or directory name at any depth
+//   - a leading or middle slash anchors the pattern to the repository root
+//   - a trailing slash matches directories only, and so every file below them
+//   - "*" and "?" do not cross slashes; "**/", "/**" and "/**/" match across directories
+//   - "!" re-includes paths excluded by an earlier pattern, the last matching pattern wins,
+//     and files below an excluded directory cannot be re-included
+//
+// Patterns are separated by commas or newlines, "#" starts a comment line, and paths
+// are matched case-insensitively.
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	pattern string // As written, for explaining matches
+	negate  bool
+	dirOnly bool
+	re      *regexp.Regexp
+}
+
+// NewIgnoreMatcher compiles pattern lists; later lists take precedence over earlier ones
+func NewIgnoreMatcher(patternLists ...string) *IgnoreMatcher {
+	m := &IgnoreMatcher{}
+	for _, list := range patternLists {
+		for _, line := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' }) {
+			if rule, ok := parseIgnoreRule(line); ok {
+				m.rules = append(m.rules, rule)
+			}
+		}
+	}
+	return m
+}
+
+// NewProjectIgnoreMatcher returns the matcher of the default and the project's ignore patterns
+func NewProjectIgnoreMatcher(project *models.Project) *IgnoreMatcher {
+	return NewIgnoreMatcher(DefaultIgnorePatterns, project.IgnorePatterns)
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{pattern: line}
+
+	p := line
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return ignoreRule{}, false
+	}
+
+	expr := globToRegexp(strings.ToLower(p))
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob to a regular expression
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Leading "**/" and "/**/" match zero or more directories
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// Match reports whether the file path is ignored
+func (m *IgnoreMatcher) Match(filePath string) bool {
+	ignored, _ := m.Explain(filePath)
+	return ignored
+}
+
+// Explain reports whether the file path is ignored and the pattern that decided it,
+// empty when no pattern matched
+func (m *IgnoreMatcher) Explain(filePath string) (bool, string) {
+	p := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(filePath, "./"), "/"))
+	if p == "" || len(m.rules) == 0 {
+		return false, ""
+	}
+
+	// An excluded directory excludes everything below it
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		if ignored, rule := m.evaluate(strings.Join(parts[:i], "/"), true); ignored {
+			return true, rule
+		}
+	}
+	return m.evaluate(p, false)
+}
+
+// explainNested is Explain without the parent directory rule: the last pattern matching
+// the file or any of its directories decides, so a negation can carve a directory out of
+// an earlier pattern. Include patterns are matched this way.
+func (m *IgnoreMatcher) explainNested(filePath string) (bool, string) {
+	p := strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(filePath, "./"), "/"))
+	parts := strings.Split(p, "/")
+	matched, decidedBy := false, ""
+	for _, rule := range m.rules {
+		hit := !rule.dirOnly && rule.re.MatchString(p)
+		for i := 1; i < len(parts) && !hit; i++ {
+			hit = rule.re.MatchString(strings.Join(parts[:i], "/"))
+		}
+		if hit {
+			matched, decidedBy = !rule.negate, rule.pattern
+		}
+	}
+	return matched, decidedBy
+}
+
+// evaluate applies the rules to a single path; the last matching rule wins
+func (m *IgnoreMatcher) evaluate(p string, isDir bool) (bool, string) {
+	ignored, decidedBy := false, ""
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(p) {
+			ignored, decidedBy = !rule.negate, rule.pattern
+		}
+	}
+	return ignored, decidedBy
+}
+
+// Path filter reasons for leaving a file out of review
+const (
+	PathSkipIgnored     = "ignored"      // Matched by an ignore pattern
+	PathSkipNotIncluded = "not_included" // Outside the include patterns
+	PathSkipExtension   = "extension"    // Extension not in the file extensions
+)
+
+// PathFilter decides which changed files of a project are reviewed. Ignore patterns take
+// precedence over include patterns: a file is reviewed when no ignore pattern excludes it,
+// it matches the include patterns (all files when there are none) and it has one of the
+// file extensions (any extension when there are none). Include patterns use the ignore
+// pattern syntax, but a negation may exclude a directory below an included one.
+type PathFilter struct {
+	ignore     *IgnoreMatcher
+	include    *IgnoreMatcher
+	extensions map[string]bool
+}
+
+// NewPathFilter builds a filter from comma-separated extensions and pattern lists
+func NewPathFilter(extensions, includePatterns string, ignore *IgnoreMatcher) *PathFilter {
+	f := &PathFilter{ignore: ignore, extensions: make(map[string]bool)}
+	if include := NewIgnoreMatcher(includePatterns); len(include.rules) > 0 {
+		f.include = include
+	}
+	for _, ext := range strings.Split(extensions, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		f.extensions[ext] = true
+	}
+	return f
+}
+
+// NewProjectPathFilter returns the path filter of a project, after the default ignore patterns
+func NewProjectPathFilter(project *models.Project) *PathFilter {
+	return NewPathFilter(project.FileExtensions, project.IncludePatterns, NewProjectIgnoreMatcher(project))
+}
+
+// Reviewed reports whether the file is reviewed
+func (f *PathFilter) Reviewed(filePath string) bool {
+	reason, _ := f.Explain(filePath)
+	return reason == ""
+}
+
+// Explain returns why the file is left out of review, empty when it is reviewed, and the
+// pattern that decided it
+func (f *PathFilter) Explain(filePath string) (string, string) {
+	if ignored, pattern := f.ignore.Explain(filePath); ignored {
+		return PathSkipIgnored, pattern
+	}
+	if f.include != nil {
+		included, pattern := f.include.explainNested(filePath)
+		if !included {
+			return PathSkipNotIncluded, pattern
+		}
+	}
+	if len(f.extensions) > 0 && !f.extensions[strings.ToLower(path.Ext(filePath))] {
+		return PathSkipExtension, ""
+	}
+	return "", ""
+}
+
+type PathPatternDryRunRequest struct {
+	FileExtensions  string   `json:"file_extensions"`
+	IncludePatterns string   `json:"include_patterns"`
+	IgnorePatterns  string   `json:"ignore_patterns"`
+	Files           []string `json:"files" binding:"required,min=1,max=1000"`
+	IncludeDefaults *bool    `json:"include_defaults"` // Apply DefaultIgnorePatterns first, default true
+}
+
+type PathPatternResult struct {
+	Path     string `json:"path"`
+	Reviewed bool   `json:"reviewed"`
+	Reason   string `json:"reason,omitempty"`  // ignored, not_included or extension
+	Pattern  string `json:"pattern,omitempty"` // Pattern that decided the result
+}
+
+type PathPatternDryRunResponse struct {
+	Results  []PathPatternResult `json:"results"`
+	Reviewed int                 `json:"reviewed"`
+	Skipped  int                 `json:"skipped"`
+}
+
+// DryRunPathPatterns reports which of the sample files a project's extensions, include and
+// ignore patterns would review
+func DryRunPathPatterns(req *PathPatternDryRunRequest) *PathPatternDryRunResponse {
+	ignore := NewIgnoreMatcher(req.IgnorePatterns)
+	if req.IncludeDefaults == nil || *req.IncludeDefaults {
+		ignore = NewIgnoreMatcher(DefaultIgnorePatterns, req.IgnorePatterns)
+	}
+	filter := NewPathFilter(req.FileExtensions, req.IncludePatterns, ignore)
+
+	resp := &PathPatternDryRunResponse{Results: make([]PathPatternResult, 0, len(req.Files))}
+	for _, file := range req.Files {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+		reason, pattern := filter.Explain(file)
+		resp.Results = append(resp.Results, PathPatternResult{Path: file, Reviewed: reason == "", Reason: reason, Pattern: pattern})
+		if reason == "" {
+			resp.Reviewed++
+		} else {
+			resp.Skipped++
+		}
+	}
+	return resp
+}
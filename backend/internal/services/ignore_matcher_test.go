@@ -0,0 +1,127 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns string
+		path     string
+		want     bool
+	}{
+		{"basename glob at any depth", "*.min.js", "web/static/app.min.js", true},
+		{"no substring match", "test", "internal/latest/handler.go", false},
+		{"name matches directory at any depth", "testdata", "pkg/parser/testdata/input.go", true},
+		{"directory only pattern", "build/", "cmd/build/main.go", true},
+		{"directory only pattern skips files", "build/", "scripts/build", false},
+		{"anchored pattern", "/docs", "docs/index.md", true},
+		{"anchored pattern not nested", "/docs", "api/docs/index.md", false},
+		{"middle slash anchors", "api/gen", "api/gen/client.go", true},
+		{"middle slash not nested", "api/gen", "v2/api/gen/client.go", false},
+		{"single star stays in directory", "api/*.go", "api/v1/handler.go", false},
+		{"leading double star", "**/mocks", "internal/services/mocks/repo.go", true},
+		{"trailing double star", "assets/**", "assets/img/logo.svg", true},
+		{"middle double star zero dirs", "src/**/gen.go", "src/gen.go", true},
+		{"middle double star many dirs", "src/**/gen.go", "src/a/b/gen.go", true},
+		{"question mark", "file?.txt", "file1.txt", true},
+		{"character class", "*.[ch]", "lib/util.h", true},
+		{"negated character class", "*.[!ch]", "lib/util.h", false},
+		{"negation re-includes", "*.go,!main.go", "cmd/main.go", false},
+		{"last match wins", "!main.go,*.go", "cmd/main.go", true},
+		{"excluded directory cannot be re-included", "vendor/,!vendor/keep.go", "vendor/keep.go", true},
+		{"newline separated with comment", "# generated\n*.pb.go\n", "api/service.pb.go", true},
+		{"case insensitive", "*.PNG", "img/Logo.png", true},
+		{"leading dot slash path", "/Makefile", "./Makefile", true},
+		{"escaped bang", `\!important.txt`, "!important.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewIgnoreMatcher(tt.patterns).Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreMatcherDefaultsOverride(t *testing.T) {
+	m := NewIgnoreMatcher(DefaultIgnorePatterns, "!deploy/*.yaml")
+	if m.Match("deploy/app.yaml") {
+		t.Error("project negation should re-include a default-ignored file")
+	}
+	if !m.Match("config/app.yaml") {
+		t.Error("default pattern should still ignore other yaml files")
+	}
+	if !m.Match("web/node_modules/react/index.js") {
+		t.Error("default directory pattern should ignore nested node_modules")
+	}
+}
+
+func TestPathFilter(t *testing.T) {
+	project := &models.Project{
+		FileExtensions:  ".go,.ts",
+		IncludePatterns: "services/billing/,libs/shared/,!services/billing/legacy/",
+		IgnorePatterns:  "*_gen.go",
+	}
+	filter := NewProjectPathFilter(project)
+
+	tests := []struct {
+		path        string
+		wantReason  string
+		wantPattern string
+	}{
+		{"services/billing/invoice.go", "", "services/billing/"},
+		{"libs/shared/util/date.ts", "", "libs/shared/"},
+		{"services/auth/login.go", PathSkipNotIncluded, ""},
+		{"services/billing/legacy/old.go", PathSkipNotIncluded, "!services/billing/legacy/"},
+		{"services/billing/api_gen.go", PathSkipIgnored, "*_gen.go"},
+		{"services/billing/package.json", PathSkipIgnored, "*.json"},
+		{"services/billing/README.md", PathSkipExtension, ""},
+		{"src/app/main.ts", PathSkipNotIncluded, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			reason, pattern := filter.Explain(tt.path)
+			if reason != tt.wantReason {
+				t.Errorf("Explain(%q) reason = %q, want %q", tt.path, reason, tt.wantReason)
+			}
+			if tt.wantReason != PathSkipExtension && tt.wantReason != "" && pattern != tt.wantPattern {
+				t.Errorf("Explain(%q) pattern = %q, want %q", tt.path, pattern, tt.wantPattern)
+			}
+		})
+	}
+
+	if !NewPathFilter("", "src/**", NewIgnoreMatcher()).Reviewed("src/app/main.ts") {
+		t.Error("src/** should include files below src")
+	}
+	if !NewPathFilter("", "", NewIgnoreMatcher()).Reviewed("any/file.txt") {
+		t.Error("an empty filter should review every file")
+	}
+}
+
+func TestDryRunPathPatterns(t *testing.T) {
+	noDefaults := false
+	resp := DryRunPathPatterns(&PathPatternDryRunRequest{
+		IncludePatterns: "api/",
+		IgnorePatterns:  "*.pb.go,!keep.pb.go",
+		Files:           []string{"api/a.pb.go", "api/keep.pb.go", "main.go", " ", "api/package.json"},
+		IncludeDefaults: &noDefaults,
+	})
+	if resp.Reviewed != 2 || resp.Skipped != 2 || len(resp.Results) != 4 {
+		t.Fatalf("reviewed/skipped/results = %d/%d/%d, want 2/2/4", resp.Reviewed, resp.Skipped, len(resp.Results))
+	}
+	if r := resp.Results[0]; r.Reviewed || r.Reason != PathSkipIgnored || r.Pattern != "*.pb.go" {
+		t.Errorf("api/a.pb.go = %+v, want ignored by *.pb.go", r)
+	}
+	if r := resp.Results[2]; r.Reviewed || r.Reason != PathSkipNotIncluded {
+		t.Errorf("main.go = %+v, want not included", r)
+	}
+
+	withDefaults := DryRunPathPatterns(&PathPatternDryRunRequest{Files: []string{"package.json"}})
+	if withDefaults.Skipped != 1 {
+		t.Error("defaults should apply when include_defaults is unset")
+	}
+}
@@ -21,6 +21,7 @@ type IMBotListRequest struct {
 	Name     string `form:"name"`
 	Type     string `form:"type"`
 	IsActive *bool  `form:"is_active"`
+	TenantID uint   `form:"-"` // Set from the request context, 0 = all tenants
 }
 
 type IMBotListResponse struct {
@@ -39,17 +40,55 @@ type CreateIMBotRequest struct {
 	IsActive           bool   `json:"is_active"`
 	ErrorNotify        bool   `json:"error_notify"`
 	DailyReportEnabled bool   `json:"daily_report_enabled"`
+	MessageTemplate    string `json:"message_template"`
+	MessageFields      string `json:"message_fields"`
+	MessageMaxLength   int    `json:"message_max_length" binding:"omitempty,min=0"`
+	MessageLanguage    string `json:"message_language" binding:"omitempty,oneof=en zh"`
+	DigestEnabled      bool   `json:"digest_enabled"`
+	DigestInterval     *int   `json:"digest_interval" binding:"omitempty,min=0"`
+	QuietHoursStart    string `json:"quiet_hours_start"`
+	QuietHoursEnd      string `json:"quiet_hours_end"`
+	Timezone           string `json:"timezone"`
+	SkipHolidays       bool   `json:"skip_holidays"`
+	HolidayCountry     string `json:"holiday_country"`
+	TenantID           uint   `json:"-"`
 }
 
 type UpdateIMBotRequest struct {
-	Name               string `json:"name"`
-	Type               string `json:"type" binding:"omitempty,oneof=wechat_work dingtalk feishu slack discord teams telegram"`
-	Webhook            string `json:"webhook"`
-	Secret             string `json:"secret"`
-	Extra              string `json:"extra"`
-	IsActive           *bool  `json:"is_active"`
-	ErrorNotify        *bool  `json:"error_notify"`
-	DailyReportEnabled *bool  `json:"daily_report_enabled"`
+	Name               string  `json:"name"`
+	Type               string  `json:"type" binding:"omitempty,oneof=wechat_work dingtalk feishu slack discord teams telegram"`
+	Webhook            string  `json:"webhook"`
+	Secret             string  `json:"secret"`
+	Extra              string  `json:"extra"`
+	IsActive           *bool   `json:"is_active"`
+	ErrorNotify        *bool   `json:"error_notify"`
+	DailyReportEnabled *bool   `json:"daily_report_enabled"`
+	MessageTemplate    *string `json:"message_template"`
+	MessageFields      *string `json:"message_fields"`
+	MessageMaxLength   *int    `json:"message_max_length" binding:"omitempty,min=0"`
+	MessageLanguage    *string `json:"message_language" binding:"omitempty,oneof=en zh"`
+	DigestEnabled      *bool   `json:"digest_enabled"`
+	DigestInterval     *int    `json:"digest_interval" binding:"omitempty,min=0"`
+	QuietHoursStart    *string `json:"quiet_hours_start"`
+	QuietHoursEnd      *string `json:"quiet_hours_end"`
+	Timezone           *string `json:"timezone"`
+	SkipHolidays       *bool   `json:"skip_holidays"`
+	HolidayCountry     *string `json:"holiday_country"`
+}
+
+// PreviewIMBotMessageRequest renders a message with unsaved template settings.
+// Notification is optional; sample data is used when omitted.
+type PreviewIMBotMessageRequest struct {
+	MessageTemplate  string              `json:"message_template"`
+	MessageFields    string              `json:"message_fields"`
+	MessageMaxLength int                 `json:"message_max_length" binding:"omitempty,min=0"`
+	MessageLanguage  string              `json:"message_language" binding:"omitempty,oneof=en zh"`
+	Notification     *ReviewNotification `json:"notification"`
+}
+
+type PreviewIMBotMessageResponse struct {
+	Content string `json:"content"`
+	Length  int    `json:"length"`
 }
 
 // List returns paginated IM bots
@@ -64,10 +103,10 @@ func (s *IMBotService) List(req *IMBotListRequest) (*IMBotListResponse, error) {
 	var bots []models.IMBot
 	var total int64
 
-	query := s.db.Model(&models.IMBot{})
+	query := ScopeTenant(s.db.Model(&models.IMBot{}), req.TenantID)
 
 	if req.Name != "" {
-		query = query.Where("name LIKE ?", "%"+req.Name+"%")
+		query = query.Where(LikeCondition("name"), ContainsPattern(req.Name))
 	}
 	if req.Type != "" {
 		query = query.Where("type = ?", req.Type)
@@ -102,6 +141,23 @@ func (s *IMBotService) GetByID(id uint) (*models.IMBot, error) {
 
 // Create creates a new IM bot
 func (s *IMBotService) Create(req *CreateIMBotRequest) (*models.IMBot, error) {
+	if err := ValidateMessageFields(req.MessageFields); err != nil {
+		return nil, err
+	}
+	if err := ValidateMessageTemplate(req.MessageTemplate); err != nil {
+		return nil, err
+	}
+	if err := ValidateQuietHours(req.QuietHoursStart, req.QuietHoursEnd, req.Timezone); err != nil {
+		return nil, err
+	}
+	if req.MessageLanguage == "" {
+		req.MessageLanguage = "en"
+	}
+	digestInterval := 60
+	if req.DigestInterval != nil {
+		digestInterval = *req.DigestInterval
+	}
+
 	bot := models.IMBot{
 		Name:               req.Name,
 		Type:               req.Type,
@@ -111,6 +167,18 @@ func (s *IMBotService) Create(req *CreateIMBotRequest) (*models.IMBot, error) {
 		IsActive:           req.IsActive,
 		ErrorNotify:        req.ErrorNotify,
 		DailyReportEnabled: req.DailyReportEnabled,
+		MessageTemplate:    req.MessageTemplate,
+		MessageFields:      req.MessageFields,
+		MessageMaxLength:   req.MessageMaxLength,
+		MessageLanguage:    req.MessageLanguage,
+		DigestEnabled:      req.DigestEnabled,
+		DigestInterval:     digestInterval,
+		QuietHoursStart:    req.QuietHoursStart,
+		QuietHoursEnd:      req.QuietHoursEnd,
+		Timezone:           req.Timezone,
+		SkipHolidays:       req.SkipHolidays,
+		HolidayCountry:     req.HolidayCountry,
+		TenantID:           req.TenantID,
 	}
 
 	if err := s.db.Create(&bot).Error; err != nil {
@@ -153,6 +221,54 @@ func (s *IMBotService) Update(id uint, req *UpdateIMBotRequest) (*models.IMBot,
 	if req.DailyReportEnabled != nil {
 		updates["daily_report_enabled"] = *req.DailyReportEnabled
 	}
+	if req.MessageTemplate != nil {
+		if err := ValidateMessageTemplate(*req.MessageTemplate); err != nil {
+			return nil, err
+		}
+		updates["message_template"] = *req.MessageTemplate
+	}
+	if req.MessageFields != nil {
+		if err := ValidateMessageFields(*req.MessageFields); err != nil {
+			return nil, err
+		}
+		updates["message_fields"] = *req.MessageFields
+	}
+	if req.MessageMaxLength != nil {
+		updates["message_max_length"] = *req.MessageMaxLength
+	}
+	if req.MessageLanguage != nil {
+		updates["message_language"] = *req.MessageLanguage
+	}
+	if req.DigestEnabled != nil {
+		updates["digest_enabled"] = *req.DigestEnabled
+	}
+	if req.DigestInterval != nil {
+		updates["digest_interval"] = *req.DigestInterval
+	}
+	if req.QuietHoursStart != nil || req.QuietHoursEnd != nil || req.Timezone != nil {
+		start, end, tz := bot.QuietHoursStart, bot.QuietHoursEnd, bot.Timezone
+		if req.QuietHoursStart != nil {
+			start = *req.QuietHoursStart
+			updates["quiet_hours_start"] = start
+		}
+		if req.QuietHoursEnd != nil {
+			end = *req.QuietHoursEnd
+			updates["quiet_hours_end"] = end
+		}
+		if req.Timezone != nil {
+			tz = *req.Timezone
+			updates["timezone"] = tz
+		}
+		if err := ValidateQuietHours(start, end, tz); err != nil {
+			return nil, err
+		}
+	}
+	if req.SkipHolidays != nil {
+		updates["skip_holidays"] = *req.SkipHolidays
+	}
+	if req.HolidayCountry != nil {
+		updates["holiday_country"] = *req.HolidayCountry
+	}
 
 	if err := s.db.Model(&bot).Updates(updates).Error; err != nil {
 		return nil, err
@@ -175,10 +291,10 @@ func (s *IMBotService) Delete(id uint) error {
 	return nil
 }
 
-// GetAllActive returns all active IM bots
-func (s *IMBotService) GetAllActive() ([]models.IMBot, error) {
+// GetAllActive returns all active IM bots of a tenant, 0 = all tenants
+func (s *IMBotService) GetAllActive(tenantID uint) ([]models.IMBot, error) {
 	var bots []models.IMBot
-	if err := s.db.Where("is_active = ?", true).Find(&bots).Error; err != nil {
+	if err := ScopeTenant(s.db, tenantID).Where("is_active = ?", true).Find(&bots).Error; err != nil {
 		return nil, err
 	}
 	return bots, nil
@@ -192,3 +308,94 @@ func (s *IMBotService) GetErrorNotifyBots() ([]models.IMBot, error) {
 	}
 	return bots, nil
 }
+
+// PreviewMessage renders a notification with the given template settings without sending it
+func (s *IMBotService) PreviewMessage(req *PreviewIMBotMessageRequest) (*PreviewIMBotMessageResponse, error) {
+	if err := ValidateMessageFields(req.MessageFields); err != nil {
+		return nil, err
+	}
+	if err := ValidateMessageTemplate(req.MessageTemplate); err != nil {
+		return nil, err
+	}
+
+	bot := &models.IMBot{
+		MessageTemplate:  req.MessageTemplate,
+		MessageFields:    req.MessageFields,
+		MessageMaxLength: req.MessageMaxLength,
+		MessageLanguage:  req.MessageLanguage,
+	}
+	notification := req.Notification
+	if notification == nil {
+		notification = sampleReviewNotification()
+	}
+
+	content := renderBotMessage(bot, notification)
+	return &PreviewIMBotMessageResponse{
+		Content: content,
+		Length:  len([]rune(content)),
+	}, nil
+}
+
+// IMBotSampleMessage is a sample message sent, or previewed, to a bot with the exact
+// payloads of its webhook calls
+type IMBotSampleMessage struct {
+	Success bool          `json:"success"`
+	Error   string        `json:"error,omitempty"`
+	Calls   []WebhookCall `json:"calls"`
+}
+
+type IMBotSampleResponse struct {
+	Review IMBotSampleMessage `json:"review"` // Sample review notification
+	Report IMBotSampleMessage `json:"report"` // Sample daily report
+}
+
+// SendTestMessage sends a sample review notification and a sample daily report to the bot
+// using its saved settings, and returns the payloads with the webhook responses
+func (s *IMBotService) SendTestMessage(id uint) (*IMBotSampleResponse, error) {
+	return s.sampleMessages(id, true)
+}
+
+// PreviewPayload returns the payloads the bot's webhook would receive for a sample review
+// notification and a sample daily report, without sending them
+func (s *IMBotService) PreviewPayload(id uint) (*IMBotSampleResponse, error) {
+	return s.sampleMessages(id, false)
+}
+
+func (s *IMBotService) sampleMessages(id uint, send bool) (*IMBotSampleResponse, error) {
+	bot, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return &IMBotSampleResponse{
+		Review: recordSample(bot, send, func(a NotificationAdapter) error {
+			return a.SendRichMessage(bot.Webhook, bot, sampleReviewNotification())
+		}),
+		Report: recordSample(bot, send, func(a NotificationAdapter) error {
+			return a.SendTextMessage(bot.Webhook, bot, sampleReportMessage())
+		}),
+	}, nil
+}
+
+// recordSample runs a send with an adapter recording its webhook calls. Sent messages fail on
+// an error status or on the error code a provider answers with status 200.
+func recordSample(bot *models.IMBot, send bool, fn func(NotificationAdapter) error) IMBotSampleMessage {
+	recorder := &webhookRecorder{send: send, botType: bot.Type}
+	err := fn(newAdapter(bot.Type, recorder.post))
+	result := IMBotSampleMessage{Success: err == nil, Calls: recorder.calls}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if result.Calls == nil {
+		result.Calls = []WebhookCall{}
+	}
+	return result
+}
+
+// FlushDigest immediately sends the bot's pending digest and returns the number of reviews delivered
+func (s *IMBotService) FlushDigest(id uint) (int, error) {
+	bot, err := s.GetByID(id)
+	if err != nil {
+		return 0, err
+	}
+	return NewDigestService(s.db).Flush(bot)
+}
@@ -1,7 +1,12 @@
 package services
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
 )
 
 func TestIMBotListRequest_Defaults(t *testing.T) {
@@ -158,3 +163,82 @@ func TestIMBotTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordSamplePreview(t *testing.T) {
+	tests := []struct {
+		name      string
+		bot       models.IMBot
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "slack", bot: models.IMBot{Type: "slack", Webhook: "https://hooks.example.com/slack"}, wantCalls: 1},
+		{name: "dingtalk", bot: models.IMBot{Type: "dingtalk", Webhook: "https://oapi.example.com/robot/send?access_token=x"}, wantCalls: 1},
+		{name: "generic", bot: models.IMBot{Type: "webhook", Webhook: "https://example.com/hook"}, wantCalls: 1},
+		{name: "telegram without chat id", bot: models.IMBot{Type: "telegram", Webhook: "https://api.telegram.org/botx/sendMessage"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := recordSample(&tt.bot, false, func(a NotificationAdapter) error {
+				return a.SendRichMessage(tt.bot.Webhook, &tt.bot, sampleReviewNotification())
+			})
+			if result.Success == tt.wantErr || (result.Error != "") != tt.wantErr {
+				t.Fatalf("recordSample() success = %v, error = %q, wantErr %v", result.Success, result.Error, tt.wantErr)
+			}
+			if len(result.Calls) != tt.wantCalls {
+				t.Fatalf("recordSample() recorded %d calls, want %d", len(result.Calls), tt.wantCalls)
+			}
+			for _, call := range result.Calls {
+				if call.Payload == nil || call.StatusCode != 0 {
+					t.Errorf("preview call = %+v, want a payload and no response", call)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordSampleSend_ProviderErrorCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":310000,"errmsg":"keywords not in content"}`))
+	}))
+	defer server.Close()
+
+	bot := &models.IMBot{Type: "dingtalk", Webhook: server.URL + "/robot/send?access_token=x"}
+	result := recordSample(bot, true, func(a NotificationAdapter) error {
+		return a.SendTextMessage(bot.Webhook, bot, sampleReportMessage())
+	})
+	if result.Success || !strings.Contains(result.Error, "310000") {
+		t.Fatalf("recordSample() success = %v, error = %q, want the error code", result.Success, result.Error)
+	}
+	if len(result.Calls) != 1 || result.Calls[0].StatusCode != http.StatusOK || !strings.Contains(result.Calls[0].Error, "keywords not in content") {
+		t.Errorf("calls = %+v, want the 200 response with its error", result.Calls)
+	}
+}
+
+func TestIMBotServiceCreate_DigestInterval(t *testing.T) {
+	db := newTestDB(t)
+	s := NewIMBotService(db)
+	zero := 0
+
+	tests := []struct {
+		name     string
+		interval *int
+		want     int
+	}{
+		{"default", nil, 60},
+		{"once at day end", &zero, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, err := s.Create(&CreateIMBotRequest{Name: tt.name, Type: "dingtalk", Webhook: "https://oapi.dingtalk.com/robot/send", DigestEnabled: true, DigestInterval: tt.interval})
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			var stored models.IMBot
+			db.First(&stored, bot.ID)
+			if stored.DigestInterval != tt.want {
+				t.Errorf("digest_interval = %d, want %d", stored.DigestInterval, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"github.com/huangang/codesentry/backend/pkg/logger"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,7 +24,7 @@ type ImportCommitsService struct {
 func NewImportCommitsService(db *gorm.DB) *ImportCommitsService {
 	return &ImportCommitsService{
 		db:         db,
-		httpClient: &http.Client{Timeout: 60 * time.Second},
+		httpClient: PlatformHTTPClient(),
 	}
 }
 
@@ -30,6 +32,12 @@ type ImportCommitsRequest struct {
 	ProjectID uint   `json:"project_id" binding:"required"`
 	StartDate string `json:"start_date" binding:"required"` // Format: 2006-01-02
 	EndDate   string `json:"end_date" binding:"required"`   // Format: 2006-01-02
+	// Review also enqueues AI reviews of the imported commits, at most ReviewRate per minute
+	Review     bool `json:"review"`
+	ReviewRate int  `json:"review_rate" binding:"omitempty,min=1,max=600"`
+	// MergeRequests also imports the merge requests opened in the date range
+	MergeRequests bool   `json:"merge_requests"`
+	CreatedBy     string `json:"-"`
 }
 
 type ImportCommitsResponse struct {
@@ -37,6 +45,7 @@ type ImportCommitsResponse struct {
 	Skipped  int      `json:"skipped"`
 	Errors   []string `json:"errors,omitempty"`
 	Async    bool     `json:"async,omitempty"`
+	JobID    uint     `json:"job_id,omitempty"`
 	Message  string   `json:"message,omitempty"`
 }
 
@@ -106,60 +115,125 @@ func (s *ImportCommitsService) ImportCommits(req *ImportCommitsRequest) (*Import
 		return nil, fmt.Errorf("project not found: %w", err)
 	}
 
-	if project.AccessToken == "" {
+	if !HasPlatformCredentials(s.db, &project) {
 		return nil, fmt.Errorf("project does not have an access token configured")
 	}
 
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid start_date format: %w", err)
+	if _, _, err := importDateRange(req.StartDate, req.EndDate); err != nil {
+		return nil, err
 	}
 
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
-	if err != nil {
-		return nil, fmt.Errorf("invalid end_date format: %w", err)
+	var running int64
+	s.db.Model(&models.ImportJob{}).Where("project_id = ? AND status = ?", project.ID, ImportJobRunning).Count(&running)
+	if running > 0 {
+		return nil, ErrImportRunning
 	}
 
-	// Add one day to end date to include the entire day
-	endDate = endDate.Add(24*time.Hour - time.Second)
+	job := &models.ImportJob{
+		ProjectID:     project.ID,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		Review:        req.Review,
+		ReviewRate:    req.ReviewRate,
+		MergeRequests: req.MergeRequests,
+		Status:        ImportJobRunning,
+		Phase:         ImportPhaseCommits,
+		Attempt:       1,
+		CreatedBy:     req.CreatedBy,
+	}
+	if err := s.db.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
 
-	logger.Infof("[ImportCommits] Starting async import for project %d (%s) from %s to %s",
-		project.ID, project.Name, req.StartDate, req.EndDate)
+	logger.Module("import").Info().Uint("job_id", job.ID).Uint("project_id", project.ID).Str("project", project.Name).
+		Str("start_date", req.StartDate).Str("end_date", req.EndDate).Msg("Starting import job")
 
-	go s.importCommitsAsync(&project, startDate, endDate)
+	go s.runImportJob(&project, job)
 
+	message := "Import started, you will be notified when complete"
+	if req.Review {
+		message = "Import started, imported commits will be reviewed afterwards"
+	}
 	return &ImportCommitsResponse{
 		Async:   true,
-		Message: "Import started, you will be notified when complete",
+		JobID:   job.ID,
+		Message: message,
 	}, nil
 }
 
-func (s *ImportCommitsService) importCommitsAsync(project *models.Project, startDate, endDate time.Time) {
+// importDateRange parses the dates of an import; the end date includes the entire day
+func importDateRange(start, end string) (time.Time, time.Time, error) {
+	startDate, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start_date format: %w", err)
+	}
+	endDate, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end_date format: %w", err)
+	}
+	return startDate, endDate.Add(24*time.Hour - time.Second), nil
+}
+
+// runImportJob imports the commits and merge requests of a job, continuing from its
+// persisted phase and cursor
+func (s *ImportCommitsService) runImportJob(project *models.Project, job *models.ImportJob) {
+	run := &importRun{db: s.db, job: job}
+	startDate, endDate, err := importDateRange(job.StartDate, job.EndDate)
+
 	var response *ImportCommitsResponse
-	var err error
-
-	switch project.Platform {
-	case "gitlab":
-		response, err = s.importGitLabCommits(project, startDate, endDate)
-	case "github":
-		response, err = s.importGitHubCommits(project, startDate, endDate)
-	case "bitbucket":
-		response, err = s.importBitbucketCommits(project, startDate, endDate)
-	default:
-		err = fmt.Errorf("unsupported platform: %s", project.Platform)
+	if err == nil && job.Phase == ImportPhaseMergeRequests {
+		response = &ImportCommitsResponse{Imported: job.Imported, Skipped: job.Skipped}
+	} else if err == nil {
+		switch project.Platform {
+		case "gitlab":
+			response, err = s.importGitLabCommits(run, project, startDate, endDate)
+		case "github":
+			response, err = s.importGitHubCommits(run, project, startDate, endDate)
+		case "bitbucket":
+			response, err = s.importBitbucketCommits(run, project, startDate, endDate)
+		default:
+			err = fmt.Errorf("unsupported platform: %s", project.Platform)
+		}
+	}
+	mergeRequests := 0
+	if err == nil && job.MergeRequests {
+		if job.Phase != ImportPhaseMergeRequests {
+			err = run.startPhase(ImportPhaseMergeRequests)
+		}
+		if err == nil {
+			var mrResponse *ImportCommitsResponse
+			if mrResponse, err = s.importMergeRequests(run, project, startDate, endDate); err == nil {
+				mergeRequests = mrResponse.Imported
+			}
+		}
 	}
 
+	if errors.Is(err, errImportStopped) {
+		logger.Module("import").Info().Uint("job_id", job.ID).Uint("project_id", project.ID).Msg("Import job stopped: cancelled or resumed elsewhere")
+		return
+	}
 	if err != nil {
-		logger.Infof("[ImportCommits] Async import failed for project %d: %v", project.ID, err)
-		PublishImportEvent(project.ID, project.Name, 0, 0, err.Error())
-	} else {
-		logger.Infof("[ImportCommits] Async import complete for project %d: imported=%d, skipped=%d",
-			project.ID, response.Imported, response.Skipped)
-		PublishImportEvent(project.ID, project.Name, response.Imported, response.Skipped, "")
+		logger.Module("import").Warn().Err(err).Uint("job_id", job.ID).Uint("project_id", project.ID).Msg("Import job failed")
+		run.finish(ImportJobFailed, err.Error())
+		PublishImportEvent(project.ID, project.Name, 0, 0, 0, err.Error())
+		return
+	}
+	logger.Module("import").Info().Uint("job_id", job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).
+		Int("skipped", response.Skipped).Int("merge_requests", mergeRequests).Msg("Import job complete")
+	run.finish(ImportJobCompleted, "")
+	PublishImportEvent(project.ID, project.Name, response.Imported, response.Skipped, mergeRequests, "")
+
+	// Commits imported by an earlier run without reviews are reviewed as well
+	if job.Review {
+		if err := s.queueImportedReviews(project.ID, startDate, endDate); err != nil {
+			logger.Module("import").Error().Err(err).Uint("project_id", project.ID).Msg("Failed to queue retroactive reviews")
+			return
+		}
+		s.reviewQueuedCommits(project, job.ReviewRate)
 	}
 }
 
-func (s *ImportCommitsService) importGitLabCommits(project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+func (s *ImportCommitsService) importGitLabCommits(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
 	info, err := parseRepoInfo(project.URL)
 	if err != nil {
 		return nil, err
@@ -169,8 +243,8 @@ func (s *ImportCommitsService) importGitLabCommits(project *models.Project, star
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits?since=%s&until=%s&with_stats=true&per_page=100",
 		info.baseURL, encodedPath, startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
 
-	response := &ImportCommitsResponse{}
-	page := 1
+	response := run.response()
+	page := run.startPage()
 
 	for {
 		pageURL := fmt.Sprintf("%s&page=%d", apiURL, page)
@@ -236,13 +310,16 @@ func (s *ImportCommitsService) importGitLabCommits(project *models.Project, star
 		}
 
 		page++
+		if err := run.pageDone(response, strconv.Itoa(page)); err != nil {
+			return nil, err
+		}
 	}
 
-	logger.Infof("[ImportCommits] GitLab import complete: imported=%d, skipped=%d", response.Imported, response.Skipped)
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("GitLab commit import complete")
 	return response, nil
 }
 
-func (s *ImportCommitsService) importGitHubCommits(project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+func (s *ImportCommitsService) importGitHubCommits(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
 	info, err := parseRepoInfo(project.URL)
 	if err != nil {
 		return nil, err
@@ -251,8 +328,8 @@ func (s *ImportCommitsService) importGitHubCommits(project *models.Project, star
 	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits?since=%s&until=%s&per_page=100",
 		info.owner, info.repo, startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
 
-	response := &ImportCommitsResponse{}
-	page := 1
+	response := run.response()
+	page := run.startPage()
 
 	for {
 		pageURL := fmt.Sprintf("%s&page=%d", apiURL, page)
@@ -316,9 +393,12 @@ func (s *ImportCommitsService) importGitHubCommits(project *models.Project, star
 		}
 
 		page++
+		if err := run.pageDone(response, strconv.Itoa(page)); err != nil {
+			return nil, err
+		}
 	}
 
-	logger.Infof("[ImportCommits] GitHub import complete: imported=%d, skipped=%d", response.Imported, response.Skipped)
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("GitHub commit import complete")
 	return response, nil
 }
 
@@ -355,24 +435,31 @@ func (s *ImportCommitsService) fetchGitHubCommitStats(project *models.Project, i
 	return
 }
 
-func (s *ImportCommitsService) importBitbucketCommits(project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
-	info, err := parseRepoInfo(project.URL)
+func (s *ImportCommitsService) importBitbucketCommits(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+	api, err := NewBitbucketAPI(s.db, s.httpClient, project)
 	if err != nil {
 		return nil, err
 	}
+	if api.Server {
+		return nil, ErrBitbucketServerImport
+	}
 
-	// Bitbucket uses workspace/repo format
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/commits?pagelen=50", info.projectPath)
+	apiURL := api.CommitsURL()
 
-	response := &ImportCommitsResponse{}
+	response := run.response()
 	nextURL := apiURL
+	if run.job.Cursor != "" {
+		nextURL = run.job.Cursor
+	}
 
 	for nextURL != "" {
 		req, err := http.NewRequest("GET", nextURL, nil)
 		if err != nil {
 			return nil, err
 		}
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+		if err := api.Authorize(req); err != nil {
+			return nil, err
+		}
 
 		resp, err := s.httpClient.Do(req)
 		if err != nil {
@@ -390,6 +477,7 @@ func (s *ImportCommitsService) importBitbucketCommits(project *models.Project, s
 			return nil, err
 		}
 
+		nextURL = commitsResp.Next
 		for _, commit := range commitsResp.Values {
 			// Check date range
 			if commit.Date.Before(startDate) {
@@ -440,10 +528,12 @@ func (s *ImportCommitsService) importBitbucketCommits(project *models.Project, s
 			response.Imported++
 		}
 
-		nextURL = commitsResp.Next
+		if err := run.pageDone(response, nextURL); err != nil {
+			return nil, err
+		}
 	}
 
-	logger.Infof("[ImportCommits] Bitbucket import complete: imported=%d, skipped=%d", response.Imported, response.Skipped)
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("Bitbucket commit import complete")
 	return response, nil
 }
 
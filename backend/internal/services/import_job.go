@@ -0,0 +1,264 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Import job statuses
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+	ImportJobCancelled = "cancelled"
+)
+
+const (
+	// ImportJobStaleTimeout is how long a running job may go without progress before it is
+	// considered interrupted and resumed
+	ImportJobStaleTimeout = 5 * time.Minute
+	// ImportJobRecoveryInterval is how often interrupted jobs are looked for
+	ImportJobRecoveryInterval = time.Minute
+	// maxImportJobErrors caps the per-commit errors kept on a job
+	maxImportJobErrors = 100
+)
+
+var (
+	// ErrImportRunning is returned when an import of the project is already running
+	ErrImportRunning = errors.New("an import of this project is already running")
+	// ErrImportJobNotRunning is returned when cancelling a job that already finished
+	ErrImportJobNotRunning = errors.New("import job is not running")
+	// errImportStopped stops an import whose job was cancelled or resumed by another instance
+	errImportStopped = errors.New("import job stopped")
+)
+
+// importRun persists the progress of an import job while its pages are fetched
+type importRun struct {
+	db  *gorm.DB
+	job *models.ImportJob
+}
+
+// response returns the counts of the current phase's pages processed so far
+func (r *importRun) response() *ImportCommitsResponse {
+	resp := &ImportCommitsResponse{Imported: r.job.Imported, Skipped: r.job.Skipped}
+	if r.job.Phase == ImportPhaseMergeRequests {
+		resp.Imported, resp.Skipped = r.job.MRImported, r.job.MRSkipped
+	}
+	if r.job.Errors != "" {
+		resp.Errors = strings.Split(r.job.Errors, "\n")
+	}
+	return resp
+}
+
+// startPage returns the page a GitLab or GitHub import continues from
+func (r *importRun) startPage() int {
+	if page, err := strconv.Atoi(r.job.Cursor); err == nil && page > 0 {
+		return page
+	}
+	return 1
+}
+
+// pageDone persists the progress after a page and the cursor of the next page. It returns
+// errImportStopped when the job was cancelled or resumed by another instance meanwhile.
+func (r *importRun) pageDone(resp *ImportCommitsResponse, cursor string) error {
+	errs := resp.Errors
+	if len(errs) > maxImportJobErrors {
+		errs = errs[len(errs)-maxImportJobErrors:]
+	}
+	r.job.PagesProcessed++
+	r.job.Cursor = cursor
+	r.job.Errors = strings.Join(errs, "\n")
+	updates := map[string]interface{}{
+		"pages_processed": r.job.PagesProcessed,
+		"cursor":          r.job.Cursor,
+		"errors":          r.job.Errors,
+	}
+	if r.job.Phase == ImportPhaseMergeRequests {
+		r.job.MRImported, r.job.MRSkipped = resp.Imported, resp.Skipped
+		updates["mr_imported"], updates["mr_skipped"] = resp.Imported, resp.Skipped
+	} else {
+		r.job.Imported, r.job.Skipped = resp.Imported, resp.Skipped
+		updates["imported"], updates["skipped"] = resp.Imported, resp.Skipped
+	}
+	return r.save(updates)
+}
+
+// startPhase moves the job to the next phase, which starts from its first page
+func (r *importRun) startPhase(phase string) error {
+	r.job.Phase = phase
+	r.job.Cursor = ""
+	return r.save(map[string]interface{}{"phase": phase, "cursor": ""})
+}
+
+// save persists job updates while the job is still run by this instance
+func (r *importRun) save(updates map[string]interface{}) error {
+	result := r.db.Model(&models.ImportJob{}).
+		Where("id = ? AND status = ? AND attempt = ?", r.job.ID, ImportJobRunning, r.job.Attempt).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errImportStopped
+	}
+	return nil
+}
+
+// finish records the outcome of the job, unless it was cancelled or resumed elsewhere
+func (r *importRun) finish(status, errMsg string) {
+	now := time.Now()
+	r.db.Model(&models.ImportJob{}).
+		Where("id = ? AND status = ? AND attempt = ?", r.job.ID, ImportJobRunning, r.job.Attempt).
+		Updates(map[string]interface{}{
+			"status":        status,
+			"error_message": errMsg,
+			"finished_at":   &now,
+		})
+}
+
+// ImportJobService lists and cancels commit import jobs
+type ImportJobService struct {
+	db *gorm.DB
+}
+
+func NewImportJobService(db *gorm.DB) *ImportJobService {
+	return &ImportJobService{db: db}
+}
+
+type ImportJobListRequest struct {
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	ProjectID uint   `form:"project_id"`
+	Status    string `form:"status"`
+}
+
+type ImportJobListResponse struct {
+	Total    int64              `json:"total"`
+	Page     int                `json:"page"`
+	PageSize int                `json:"page_size"`
+	Items    []models.ImportJob `json:"items"`
+}
+
+// List returns import jobs, newest first
+func (s *ImportJobService) List(req *ImportJobListRequest) (*ImportJobListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 10
+	}
+
+	query := s.db.Model(&models.ImportJob{})
+	if req.ProjectID > 0 {
+		query = query.Where("project_id = ?", req.ProjectID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	var jobs []models.ImportJob
+	if err := query.Preload("Project").Order("created_at DESC").
+		Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	return &ImportJobListResponse{Total: total, Page: req.Page, PageSize: req.PageSize, Items: jobs}, nil
+}
+
+func (s *ImportJobService) GetByID(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	if err := s.db.Preload("Project").First(&job, id).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Cancel stops a running job; the importer stops after the page it is processing
+func (s *ImportJobService) Cancel(id uint) (*models.ImportJob, error) {
+	job, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	result := s.db.Model(&models.ImportJob{}).Where("id = ? AND status = ?", id, ImportJobRunning).
+		Updates(map[string]interface{}{"status": ImportJobCancelled, "finished_at": &now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrImportJobNotRunning
+	}
+	logger.Module("import").Info().Uint("job_id", job.ID).Uint("project_id", job.ProjectID).Msg("Import job cancelled")
+	return s.GetByID(id)
+}
+
+var importJobStopChan chan struct{}
+
+// StartImportJobRecovery resumes running import jobs without progress for
+// ImportJobStaleTimeout, such as jobs interrupted by a restart
+func StartImportJobRecovery(db *gorm.DB) {
+	s := NewImportCommitsService(db)
+	ticker := time.NewTicker(ImportJobRecoveryInterval)
+	importJobStopChan = make(chan struct{})
+	RegisterScheduler("import_job_recovery", ImportJobRecoveryInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			RunScheduled("import_job_recovery", func() error { s.resumeStaleJobs(); return nil })
+			select {
+			case <-ticker.C:
+			case <-importJobStopChan:
+				logger.Module("import").Info().Msg("Job recovery stopped")
+				return
+			}
+		}
+	}()
+}
+
+func StopImportJobRecovery() {
+	if importJobStopChan != nil {
+		close(importJobStopChan)
+	}
+}
+
+// resumeStaleJobs claims stale running jobs by bumping their attempt, so a job is resumed
+// by a single instance and its previous runner stops at its next page
+func (s *ImportCommitsService) resumeStaleJobs() {
+	var jobs []models.ImportJob
+	if err := s.db.Where("status = ? AND updated_at < ?", ImportJobRunning, time.Now().Add(-ImportJobStaleTimeout)).
+		Find(&jobs).Error; err != nil {
+		logger.Module("import").Error().Err(err).Msg("Failed to find interrupted import jobs")
+		return
+	}
+
+	for i := range jobs {
+		job := &jobs[i]
+		claim := s.db.Model(&models.ImportJob{}).
+			Where("id = ? AND status = ? AND attempt = ?", job.ID, ImportJobRunning, job.Attempt).
+			Update("attempt", job.Attempt+1)
+		if claim.Error != nil || claim.RowsAffected == 0 {
+			continue
+		}
+		job.Attempt++
+
+		var project models.Project
+		if err := s.db.First(&project, job.ProjectID).Error; err != nil {
+			(&importRun{db: s.db, job: job}).finish(ImportJobFailed, "project not found")
+			continue
+		}
+		logger.Module("import").Info().Uint("job_id", job.ID).Uint("project_id", project.ID).
+			Int("page", job.PagesProcessed+1).Int("attempt", job.Attempt).Msg("Resuming import job")
+		go s.runImportJob(&project, job)
+	}
+}
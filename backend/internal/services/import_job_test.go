@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestImportRunStartPage(t *testing.T) {
+	tests := []struct {
+		cursor string
+		want   int
+	}{
+		{"", 1},
+		{"4", 4},
+		{"0", 1},
+		{"https://api.bitbucket.org/2.0/repositories/team/repo/commits?page=3", 1},
+	}
+
+	for _, tt := range tests {
+		run := &importRun{job: &models.ImportJob{Cursor: tt.cursor}}
+		if got := run.startPage(); got != tt.want {
+			t.Errorf("startPage() with cursor %q = %d, want %d", tt.cursor, got, tt.want)
+		}
+	}
+}
+
+func TestImportRunResponse(t *testing.T) {
+	run := &importRun{job: &models.ImportJob{Imported: 12, Skipped: 3, Errors: "a failed\nb failed"}}
+	resp := run.response()
+	if resp.Imported != 12 || resp.Skipped != 3 || len(resp.Errors) != 2 || resp.Errors[1] != "b failed" {
+		t.Errorf("response() = %+v", resp)
+	}
+
+	if resp := (&importRun{job: &models.ImportJob{}}).response(); resp.Errors != nil {
+		t.Errorf("response() of a new job has errors %v", resp.Errors)
+	}
+
+	// The merge request phase counts merge requests
+	run = &importRun{job: &models.ImportJob{Phase: ImportPhaseMergeRequests, Imported: 12, MRImported: 4, MRSkipped: 1}}
+	if resp := run.response(); resp.Imported != 4 || resp.Skipped != 1 {
+		t.Errorf("response() in merge request phase = %+v", resp)
+	}
+}
+
+func TestImportDateRange(t *testing.T) {
+	start, end, err := importDateRange("2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("importDateRange: %v", err)
+	}
+	if !start.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("start = %v", start)
+	}
+	// The end date includes the entire day
+	if !end.Equal(time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("end = %v", end)
+	}
+
+	if _, _, err := importDateRange("01/01/2024", "2024-01-31"); err == nil {
+		t.Error("expected an error for an invalid start date")
+	}
+	if _, _, err := importDateRange("2024-01-01", ""); err == nil {
+		t.Error("expected an error for a missing end date")
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// Import job phases: commits are imported first, then merge requests when requested
+const (
+	ImportPhaseCommits       = "commits"
+	ImportPhaseMergeRequests = "merge_requests"
+)
+
+// GitLab merge request structure
+type gitLabMergeRequest struct {
+	IID          int       `json:"iid"`
+	Title        string    `json:"title"`
+	SourceBranch string    `json:"source_branch"`
+	SHA          string    `json:"sha"`
+	WebURL       string    `json:"web_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	Author       struct {
+		Username  string `json:"username"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"author"`
+}
+
+// GitHub pull request structure
+type gitHubPullRequest struct {
+	Number    int       `json:"number"`
+	Title     string    `json:"title"`
+	HTMLURL   string    `json:"html_url"`
+	CreatedAt time.Time `json:"created_at"`
+	User      struct {
+		Login     string `json:"login"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	Head struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// Bitbucket pull request structure
+type bitbucketPullRequestResponse struct {
+	Values []struct {
+		ID        int       `json:"id"`
+		Title     string    `json:"title"`
+		CreatedOn time.Time `json:"created_on"`
+		Author    struct {
+			DisplayName string `json:"display_name"`
+		} `json:"author"`
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Commit struct {
+				Hash string `json:"hash"`
+			} `json:"commit"`
+		} `json:"source"`
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	} `json:"values"`
+	Next string `json:"next"`
+}
+
+// importMergeRequests imports the merge requests of a project opened in the date range
+func (s *ImportCommitsService) importMergeRequests(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+	switch project.Platform {
+	case "gitlab":
+		return s.importGitLabMergeRequests(run, project, startDate, endDate)
+	case "github":
+		return s.importGitHubPullRequests(run, project, startDate, endDate)
+	case "bitbucket":
+		return s.importBitbucketPullRequests(run, project, startDate, endDate)
+	default:
+		return nil, fmt.Errorf("unsupported platform: %s", project.Platform)
+	}
+}
+
+// createImportedMergeRequest records a historical merge request as a manual record, unless
+// it was recorded before
+func (s *ImportCommitsService) createImportedMergeRequest(response *ImportCommitsResponse, reviewLog *models.ReviewLog) {
+	if s.isMergeRequestExists(reviewLog.ProjectID, *reviewLog.MRNumber) {
+		response.Skipped++
+		return
+	}
+	reviewLog.EventType = "merge_request"
+	reviewLog.ReviewStatus = "manual"
+	reviewLog.IsManual = true
+	if err := s.db.Create(reviewLog).Error; err != nil {
+		response.Errors = append(response.Errors, fmt.Sprintf("Failed to create record for merge request #%d: %v", *reviewLog.MRNumber, err))
+		return
+	}
+	response.Imported++
+}
+
+func (s *ImportCommitsService) importGitLabMergeRequests(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=all&created_after=%s&created_before=%s&per_page=100",
+		info.baseURL, url.PathEscape(info.projectPath), startDate.Format(time.RFC3339), endDate.Format(time.RFC3339))
+
+	response := run.response()
+	page := run.startPage()
+
+	for {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s&page=%d", apiURL, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("PRIVATE-TOKEN", project.AccessToken)
+
+		var mrs []gitLabMergeRequest
+		if err := s.getImportPage(req, "GitLab", &mrs); err != nil {
+			return nil, err
+		}
+		if len(mrs) == 0 {
+			break
+		}
+
+		for _, mr := range mrs {
+			iid := mr.IID
+			s.createImportedMergeRequest(response, &models.ReviewLog{
+				ProjectID:     project.ID,
+				CommitHash:    mr.SHA,
+				Branch:        mr.SourceBranch,
+				Author:        mr.Author.Username,
+				AuthorAvatar:  mr.Author.AvatarURL,
+				CommitMessage: mr.Title,
+				MRNumber:      &iid,
+				MRURL:         mr.WebURL,
+				CreatedAt:     mr.CreatedAt,
+			})
+		}
+
+		page++
+		if err := run.pageDone(response, strconv.Itoa(page)); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("GitLab merge request import complete")
+	return response, nil
+}
+
+func (s *ImportCommitsService) importGitHubPullRequests(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pull requests cannot be filtered by date: they are listed newest first instead
+	apiURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls?state=all&sort=created&direction=desc&per_page=100",
+		info.owner, info.repo)
+
+	response := run.response()
+	page := run.startPage()
+
+	for {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s&page=%d", apiURL, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github.v3+json")
+		req.Header.Set("Authorization", "token "+project.AccessToken)
+
+		var prs []gitHubPullRequest
+		if err := s.getImportPage(req, "GitHub", &prs); err != nil {
+			return nil, err
+		}
+		if len(prs) == 0 {
+			break
+		}
+
+		reachedStart := false
+		for _, pr := range prs {
+			if pr.CreatedAt.Before(startDate) {
+				reachedStart = true
+				break
+			}
+			if pr.CreatedAt.After(endDate) {
+				continue
+			}
+			number := pr.Number
+			s.createImportedMergeRequest(response, &models.ReviewLog{
+				ProjectID:     project.ID,
+				CommitHash:    pr.Head.SHA,
+				Branch:        pr.Head.Ref,
+				Author:        pr.User.Login,
+				AuthorAvatar:  pr.User.AvatarURL,
+				CommitMessage: pr.Title,
+				MRNumber:      &number,
+				MRURL:         pr.HTMLURL,
+				CreatedAt:     pr.CreatedAt,
+			})
+		}
+
+		page++
+		if err := run.pageDone(response, strconv.Itoa(page)); err != nil {
+			return nil, err
+		}
+		if reachedStart {
+			break
+		}
+	}
+
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("GitHub pull request import complete")
+	return response, nil
+}
+
+func (s *ImportCommitsService) importBitbucketPullRequests(run *importRun, project *models.Project, startDate, endDate time.Time) (*ImportCommitsResponse, error) {
+	api, err := NewBitbucketAPI(s.db, s.httpClient, project)
+	if err != nil {
+		return nil, err
+	}
+	if api.Server {
+		return nil, ErrBitbucketServerImport
+	}
+
+	response := run.response()
+	nextURL := api.PullRequestsURL()
+	if run.job.Cursor != "" {
+		nextURL = run.job.Cursor
+	}
+
+	for nextURL != "" {
+		req, err := http.NewRequest("GET", nextURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := api.Authorize(req); err != nil {
+			return nil, err
+		}
+
+		var prsResp bitbucketPullRequestResponse
+		if err := s.getImportPage(req, "Bitbucket", &prsResp); err != nil {
+			return nil, err
+		}
+
+		nextURL = prsResp.Next
+		for _, pr := range prsResp.Values {
+			// Pull requests are listed newest first
+			if pr.CreatedOn.Before(startDate) {
+				nextURL = ""
+				break
+			}
+			if pr.CreatedOn.After(endDate) {
+				continue
+			}
+			number := pr.ID
+			s.createImportedMergeRequest(response, &models.ReviewLog{
+				ProjectID:     project.ID,
+				CommitHash:    pr.Source.Commit.Hash,
+				Branch:        pr.Source.Branch.Name,
+				Author:        pr.Author.DisplayName,
+				CommitMessage: pr.Title,
+				MRNumber:      &number,
+				MRURL:         pr.Links.HTML.Href,
+				CreatedAt:     pr.CreatedOn,
+			})
+		}
+
+		if err := run.pageDone(response, nextURL); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Module("import").Info().Uint("job_id", run.job.ID).Uint("project_id", project.ID).Int("imported", response.Imported).Int("skipped", response.Skipped).Msg("Bitbucket pull request import complete")
+	return response, nil
+}
+
+// getImportPage fetches a page of a platform API and decodes it into out
+func (s *ImportCommitsService) getImportPage(req *http.Request, platform string, out interface{}) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("%s API returned %d: %s", platform, resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (s *ImportCommitsService) isMergeRequestExists(projectID uint, number int) bool {
+	var count int64
+	s.db.Model(&models.ReviewLog{}).
+		Where("project_id = ? AND event_type = ? AND mr_number = ?", projectID, "merge_request", number).
+		Count(&count)
+	return count > 0
+}
+
+// fetchMergeRequestDiff fetches the diff of a merge request from the project's platform
+func fetchMergeRequestDiff(client *http.Client, project *models.Project, number int) (string, error) {
+	if project.URL == "" || !HasPlatformCredentials(models.GetDB(), project) {
+		return "", fmt.Errorf("project URL or access token not configured")
+	}
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return "", err
+	}
+
+	var apiURL string
+	switch project.Platform {
+	case "gitlab":
+		apiURL = fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/diffs",
+			info.baseURL, url.PathEscape(info.projectPath), number)
+		return fetchGitLabDiffs(client, apiURL, project.AccessToken)
+	case "github":
+		apiURL = fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", info.owner, info.repo, number)
+	case "bitbucket":
+		api, err := NewBitbucketAPI(models.GetDB(), client, project)
+		if err != nil {
+			return "", err
+		}
+		return api.GetText(context.Background(), api.PullRequestDiffURL(number))
+	default:
+		return "", fmt.Errorf("unsupported platform: %s", project.Platform)
+	}
+
+	req, _ := http.NewRequest("GET", apiURL, nil)
+	req.Header.Set("Accept", "application/vnd.github.v3.diff")
+	req.Header.Set("Authorization", "token "+project.AccessToken)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s API returned status %d", project.Platform, resp.StatusCode)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	// ReviewStatusImportQueued marks imported commits waiting for a retroactive AI review.
+	// The status is persisted, so the reviews resume after a restart.
+	ReviewStatusImportQueued = "import_queued"
+	// DefaultImportReviewRate is the number of retroactive reviews enqueued per minute
+	DefaultImportReviewRate = 10
+	// ImportPhaseReview is the phase of import events reporting retroactive review progress
+	ImportPhaseReview = "review"
+
+	importReviewBatchSize = 50
+)
+
+// importReviewRunning holds the projects whose queued commits are being reviewed, so a
+// project is never worked on twice by one instance
+var importReviewRunning sync.Map
+
+// importReviewInterval returns the delay between two retroactive reviews for a rate per minute
+func importReviewInterval(ratePerMinute int) time.Duration {
+	if ratePerMinute <= 0 {
+		ratePerMinute = DefaultImportReviewRate
+	}
+	return time.Minute / time.Duration(ratePerMinute)
+}
+
+// queueImportedReviews marks the manual records of a project's commits and merge requests
+// in the date range for a retroactive review
+func (s *ImportCommitsService) queueImportedReviews(projectID uint, startDate, endDate time.Time) error {
+	return s.db.Model(&models.ReviewLog{}).
+		Where("project_id = ? AND review_status = ? AND is_manual = ?", projectID, "manual", true).
+		Where("(commit_hash <> '' OR mr_number IS NOT NULL)").
+		Where("created_at BETWEEN ? AND ?", startDate, endDate).
+		Update("review_status", ReviewStatusImportQueued).Error
+}
+
+// reviewQueuedCommits fetches the diff of each queued commit of a project and enqueues its
+// AI review, at most ratePerMinute per minute, oldest commit first. Commits queued while it
+// runs are picked up as well.
+func (s *ImportCommitsService) reviewQueuedCommits(project *models.Project, ratePerMinute int) {
+	if _, running := importReviewRunning.LoadOrStore(project.ID, true); running {
+		logger.Module("import").Info().Uint("project_id", project.ID).Msg("Retroactive reviews already running")
+		return
+	}
+	defer importReviewRunning.Delete(project.ID)
+
+	queue := GetTaskQueue()
+	if queue == nil {
+		logger.Module("import").Warn().Uint("project_id", project.ID).Msg("Task queue not initialized, retroactive reviews stay queued")
+		return
+	}
+
+	ticker := time.NewTicker(importReviewInterval(ratePerMinute))
+	defer ticker.Stop()
+
+	queued, failed := 0, 0
+	for {
+		var commits []models.ReviewLog
+		if err := s.db.Where("project_id = ? AND review_status = ?", project.ID, ReviewStatusImportQueued).
+			Order("created_at ASC").Limit(importReviewBatchSize).Find(&commits).Error; err != nil {
+			logger.Module("import").Error().Err(err).Uint("project_id", project.ID).Msg("Failed to load queued commits")
+			return
+		}
+		if len(commits) == 0 {
+			break
+		}
+		var remaining int64
+		s.db.Model(&models.ReviewLog{}).Where("project_id = ? AND review_status = ?", project.ID, ReviewStatusImportQueued).Count(&remaining)
+		total := queued + failed + int(remaining)
+
+		for i := range commits {
+			if queued+failed > 0 {
+				<-ticker.C
+			}
+			claimed, err := s.queueRetroactiveReview(queue, project, &commits[i])
+			if !claimed {
+				if err != nil {
+					logger.Module("import").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", commits[i].ID).Str("commit", commits[i].CommitHash).Msg("Failed to claim queued commit")
+					return
+				}
+				continue
+			}
+			if err != nil {
+				failed++
+				logger.Module("import").Warn().Err(err).Uint("project_id", project.ID).Uint("review_id", commits[i].ID).Str("commit", commits[i].CommitHash).Msg("Retroactive review not queued")
+			} else {
+				queued++
+			}
+			PublishImportReviewProgress(project.ID, project.Name, queued, failed, total, false)
+		}
+	}
+
+	logger.Module("import").Info().Uint("project_id", project.ID).Int("queued", queued).Int("failed", failed).Msg("Retroactive reviews queued")
+	PublishImportReviewProgress(project.ID, project.Name, queued, failed, queued+failed, true)
+}
+
+// queueRetroactiveReview claims a queued commit, fetches its diff and enqueues its review.
+// It reports false when another instance claimed the commit first. A commit whose diff
+// cannot be fetched is put back as a manual record, so a later import queues it again.
+func (s *ImportCommitsService) queueRetroactiveReview(queue TaskQueue, project *models.Project, log *models.ReviewLog) (bool, error) {
+	claim := s.db.Model(&models.ReviewLog{}).
+		Where("id = ? AND review_status = ?", log.ID, ReviewStatusImportQueued).
+		Updates(map[string]interface{}{"review_status": "pending", "is_manual": false, "retroactive": true})
+	if claim.Error != nil {
+		return false, claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return false, nil
+	}
+
+	var diff string
+	var err error
+	if log.EventType == "merge_request" && log.MRNumber != nil {
+		diff, err = fetchMergeRequestDiff(s.httpClient, project, *log.MRNumber)
+	} else {
+		diff, err = fetchCommitDiff(s.httpClient, project, log.CommitHash)
+	}
+	if err == nil {
+		err = queue.Enqueue(&ReviewTask{
+			ReviewLogID:   log.ID,
+			ProjectID:     project.ID,
+			CommitSHA:     log.CommitHash,
+			EventType:     log.EventType,
+			Branch:        log.Branch,
+			Author:        log.Author,
+			AuthorEmail:   log.AuthorEmail,
+			CommitMessage: log.CommitMessage,
+			Diff:          diff,
+			CommitURL:     log.CommitURL,
+			MRNumber:      log.MRNumber,
+			MRURL:         log.MRURL,
+		})
+	}
+	if err != nil {
+		s.db.Model(&models.ReviewLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+			"review_status": "manual",
+			"is_manual":     true,
+			"retroactive":   false,
+			"error_message": fmt.Sprintf("Retroactive review not queued: %v", err),
+		})
+		return true, err
+	}
+	return true, nil
+}
+
+// ResumeImportReviews continues the retroactive reviews left queued by a previous run,
+// at the default rate
+func ResumeImportReviews(db *gorm.DB) {
+	var projectIDs []uint
+	if err := db.Model(&models.ReviewLog{}).Where("review_status = ?", ReviewStatusImportQueued).
+		Distinct().Pluck("project_id", &projectIDs).Error; err != nil {
+		logger.Module("import").Error().Err(err).Msg("Failed to find queued retroactive reviews")
+		return
+	}
+
+	s := NewImportCommitsService(db)
+	for _, projectID := range projectIDs {
+		var project models.Project
+		if err := db.First(&project, projectID).Error; err != nil {
+			logger.Module("import").Warn().Err(err).Uint("project_id", projectID).Msg("Project of queued retroactive reviews not found")
+			continue
+		}
+		logger.Module("import").Info().Uint("project_id", project.ID).Str("project", project.Name).Msg("Resuming retroactive reviews")
+		go s.reviewQueuedCommits(&project, DefaultImportReviewRate)
+	}
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImportReviewInterval(t *testing.T) {
+	tests := []struct {
+		rate int
+		want time.Duration
+	}{
+		{0, 6 * time.Second}, // Default rate
+		{-5, 6 * time.Second},
+		{1, time.Minute},
+		{60, time.Second},
+		{600, 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := importReviewInterval(tt.rate); got != tt.want {
+			t.Errorf("importReviewInterval(%d) = %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// Self-hosted inference server providers. Both serve the OpenAI chat completions API and
+// batch concurrent requests on the server (continuous batching).
+const (
+	ProviderVLLM = "vllm"
+	ProviderTGI  = "tgi"
+)
+
+const (
+	// inferenceInfoTTL is how long detected server metadata is reused before probing again
+	inferenceInfoTTL = 10 * time.Minute
+	// inferenceContextMargin is reserved from the context window for the chat template and
+	// the inaccuracy of the prompt token estimate
+	inferenceContextMargin = 256
+	// minCompletionTokens is the smallest completion budget a review is sent with
+	minCompletionTokens = 512
+)
+
+// LLMProviderPreset holds the defaults of a provider for creating an LLM config
+type LLMProviderPreset struct {
+	Provider    string  `json:"provider"`
+	Label       string  `json:"label"`
+	BaseURL     string  `json:"base_url"`
+	Temperature float64 `json:"temperature"`
+	JSONMode    bool    `json:"json_mode"`
+	Description string  `json:"description"`
+}
+
+// LLMProviderPresets are the presets of self-hosted inference servers. The model and
+// context window are detected from the server, so no max_tokens is preset.
+var LLMProviderPresets = []LLMProviderPreset{
+	{
+		Provider:    ProviderVLLM,
+		Label:       "vLLM",
+		BaseURL:     "http://localhost:8000/v1",
+		Temperature: 0.3,
+		JSONMode:    true,
+		Description: "vLLM OpenAI-compatible server (vllm serve <model>); JSON mode uses guided decoding",
+	},
+	{
+		Provider:    ProviderTGI,
+		Label:       "Text Generation Inference",
+		BaseURL:     "http://localhost:8080/v1",
+		Temperature: 0.3,
+		Description: "Hugging Face TGI Messages API",
+	},
+}
+
+// IsSelfHostedProvider reports whether the provider is a self-hosted inference server
+func IsSelfHostedProvider(provider string) bool {
+	return provider == ProviderVLLM || provider == ProviderTGI
+}
+
+// InferenceServerInfo is the health and model metadata reported by an inference server
+type InferenceServerInfo struct {
+	Provider         string   `json:"provider"`
+	Healthy          bool     `json:"healthy"`
+	Model            string   `json:"model"`
+	Models           []string `json:"models,omitempty"`
+	MaxContextTokens int      `json:"max_context_tokens"` // Prompt plus completion tokens the server accepts
+	MaxInputTokens   int      `json:"max_input_tokens,omitempty"`
+	LatencyMs        int64    `json:"latency_ms"`
+	Error            string   `json:"error,omitempty"`
+}
+
+type cachedInferenceInfo struct {
+	info      *InferenceServerInfo
+	expiresAt time.Time
+}
+
+// inferenceProbeTimeout bounds the metadata requests to an inference server
+const inferenceProbeTimeout = 10 * time.Second
+
+var (
+	inferenceInfoMu    sync.Mutex
+	inferenceInfoCache = make(map[string]cachedInferenceInfo)
+)
+
+// inferenceServerRoot strips the OpenAI API version path from a base URL; the health and
+// metadata endpoints of vLLM and TGI are served from the server root
+func inferenceServerRoot(baseURL string) string {
+	root := strings.TrimRight(baseURL, "/")
+	return strings.TrimSuffix(root, "/v1")
+}
+
+// ProbeInferenceServer checks the health of a vLLM or TGI server and reads the context
+// window of the configured model. Failures are reported in the returned info.
+func ProbeInferenceServer(ctx context.Context, cfg *models.LLMConfig) *InferenceServerInfo {
+	info := &InferenceServerInfo{Provider: cfg.Provider, Model: cfg.Model}
+	if !IsSelfHostedProvider(cfg.Provider) {
+		info.Error = fmt.Sprintf("provider %q is not a self-hosted inference server", cfg.Provider)
+		return info
+	}
+
+	root := inferenceServerRoot(cfg.BaseURL)
+	start := time.Now()
+	if err := inferenceGet(ctx, cfg, root+"/health", nil); err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Healthy = true
+	info.LatencyMs = time.Since(start).Milliseconds()
+
+	var err error
+	if cfg.Provider == ProviderTGI {
+		err = probeTGI(ctx, cfg, root, info)
+	} else {
+		err = probeVLLM(ctx, cfg, root, info)
+	}
+	if err != nil {
+		info.Error = err.Error()
+	}
+	return info
+}
+
+// probeVLLM reads max_model_len of the served model from /v1/models
+func probeVLLM(ctx context.Context, cfg *models.LLMConfig, root string, info *InferenceServerInfo) error {
+	var models struct {
+		Data []struct {
+			ID          string `json:"id"`
+			MaxModelLen int    `json:"max_model_len"`
+		} `json:"data"`
+	}
+	if err := inferenceGet(ctx, cfg, root+"/v1/models", &models); err != nil {
+		return err
+	}
+	if len(models.Data) == 0 {
+		return fmt.Errorf("server serves no models")
+	}
+	for _, m := range models.Data {
+		info.Models = append(info.Models, m.ID)
+	}
+
+	served := models.Data[0]
+	for _, m := range models.Data {
+		if m.ID == cfg.Model {
+			served = m
+			break
+		}
+	}
+	if cfg.Model != "" && served.ID != cfg.Model {
+		return fmt.Errorf("model %q is not served, available: %s", cfg.Model, strings.Join(info.Models, ", "))
+	}
+	info.Model = served.ID
+	info.MaxContextTokens = served.MaxModelLen
+	return nil
+}
+
+// probeTGI reads the token limits from /info. TGI serves a single model, and older
+// versions report max_input_length instead of max_input_tokens.
+func probeTGI(ctx context.Context, cfg *models.LLMConfig, root string, info *InferenceServerInfo) error {
+	var tgi struct {
+		ModelID        string `json:"model_id"`
+		MaxTotalTokens int    `json:"max_total_tokens"`
+		MaxInputTokens int    `json:"max_input_tokens"`
+		MaxInputLength int    `json:"max_input_length"`
+	}
+	if err := inferenceGet(ctx, cfg, root+"/info", &tgi); err != nil {
+		return err
+	}
+	info.Model = tgi.ModelID
+	info.Models = []string{tgi.ModelID}
+	info.MaxContextTokens = tgi.MaxTotalTokens
+	info.MaxInputTokens = tgi.MaxInputTokens
+	if info.MaxInputTokens == 0 {
+		info.MaxInputTokens = tgi.MaxInputLength
+	}
+	return nil
+}
+
+func inferenceGet(ctx context.Context, cfg *models.LLMConfig, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+cfg.APIKey)
+	}
+	client := &http.Client{Timeout: inferenceProbeTimeout, Transport: LLMHTTPClient().Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// inferenceServerInfo returns the metadata of a self-hosted server, probing it at most
+// once per inferenceInfoTTL. It returns nil for other providers.
+func inferenceServerInfo(ctx context.Context, cfg *models.LLMConfig) *InferenceServerInfo {
+	if !IsSelfHostedProvider(cfg.Provider) {
+		return nil
+	}
+	key := cfg.Provider + "|" + cfg.BaseURL + "|" + cfg.Model
+
+	inferenceInfoMu.Lock()
+	cached, ok := inferenceInfoCache[key]
+	inferenceInfoMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.info
+	}
+
+	info := ProbeInferenceServer(ctx, cfg)
+	if info.Error != "" {
+		logger.Warnf("[AI] Failed to detect the context window of %s: %s", cfg.Name, info.Error)
+	}
+	inferenceInfoMu.Lock()
+	inferenceInfoCache[key] = cachedInferenceInfo{info: info, expiresAt: time.Now().Add(inferenceInfoTTL)}
+	inferenceInfoMu.Unlock()
+	return info
+}
+
+// completionTokenLimit negotiates max_tokens for a self-hosted server from its detected
+// context window. It returns 0, leaving max_tokens unset, for other providers and when
+// the context window is unknown.
+func (s *AIService) completionTokenLimit(ctx context.Context, cfg *models.LLMConfig, prompt string) (int, error) {
+	info := inferenceServerInfo(ctx, cfg)
+	if info == nil || info.MaxContextTokens == 0 {
+		return 0, nil
+	}
+	promptTokens := len(prompt) / 4
+	if info.MaxInputTokens > 0 && promptTokens > info.MaxInputTokens {
+		return 0, fmt.Errorf("prompt of ~%d tokens exceeds the input limit of %d tokens of %s", promptTokens, info.MaxInputTokens, info.Model)
+	}
+	return negotiateMaxTokens(info.MaxContextTokens, promptTokens, cfg.MaxTokens)
+}
+
+// negotiateMaxTokens fits the completion budget into the context window left by the
+// prompt, capped at the configured max tokens when set
+func negotiateMaxTokens(contextTokens, promptTokens, configured int) (int, error) {
+	available := contextTokens - promptTokens - inferenceContextMargin
+	if available < minCompletionTokens {
+		return 0, fmt.Errorf("prompt of ~%d tokens leaves no room for the review in a context of %d tokens", promptTokens, contextTokens)
+	}
+	if configured > 0 && configured < available {
+		return configured, nil
+	}
+	return available, nil
+}
+
+// selfHostedBatchLimit returns the diff tokens a chunked review batch may hold on the
+// project's preferred self-hosted model, half its context window, or 0 when the preferred
+// model is not self-hosted or its context window is unknown
+func (s *AIService) selfHostedBatchLimit(ctx context.Context, projectID uint) int {
+	var project models.Project
+	if err := s.db.First(&project, projectID).Error; err != nil {
+		return 0
+	}
+	configs := s.getOrderedLLMConfigs(&project)
+	if len(configs) == 0 {
+		return 0
+	}
+	info := inferenceServerInfo(ctx, &configs[0])
+	if info == nil {
+		return 0
+	}
+	return info.MaxContextTokens / 2
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestInferenceServerRoot(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"http://localhost:8000/v1", "http://localhost:8000"},
+		{"http://localhost:8000/v1/", "http://localhost:8000"},
+		{"http://tgi.internal:8080", "http://tgi.internal:8080"},
+	}
+	for _, tt := range tests {
+		if got := inferenceServerRoot(tt.baseURL); got != tt.want {
+			t.Errorf("inferenceServerRoot(%q) = %q, want %q", tt.baseURL, got, tt.want)
+		}
+	}
+}
+
+func TestNegotiateMaxTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		context    int
+		prompt     int
+		configured int
+		want       int
+		wantErr    bool
+	}{
+		{"configured fits", 32768, 10000, 4096, 4096, false},
+		{"capped by context", 8192, 6000, 4096, 1936, false},
+		{"unset uses remaining context", 8192, 2000, 0, 5936, false},
+		{"prompt too long", 8192, 7800, 4096, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := negotiateMaxTokens(tt.context, tt.prompt, tt.configured)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("negotiateMaxTokens() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("negotiateMaxTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeInferenceServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/models":
+			w.Write([]byte(`{"data":[{"id":"qwen-coder","max_model_len":32768},{"id":"llama","max_model_len":8192}]}`))
+		case "/info":
+			w.Write([]byte(`{"model_id":"bigcode/starcoder2","max_total_tokens":16384,"max_input_length":12288}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		config      models.LLMConfig
+		wantModel   string
+		wantContext int
+		wantInput   int
+		wantErr     bool
+	}{
+		{"vllm served model", models.LLMConfig{Provider: ProviderVLLM, BaseURL: server.URL + "/v1", Model: "llama"}, "llama", 8192, 0, false},
+		{"vllm first model", models.LLMConfig{Provider: ProviderVLLM, BaseURL: server.URL + "/v1"}, "qwen-coder", 32768, 0, false},
+		{"vllm unknown model", models.LLMConfig{Provider: ProviderVLLM, BaseURL: server.URL + "/v1", Model: "gpt-4"}, "gpt-4", 0, 0, true},
+		{"tgi", models.LLMConfig{Provider: ProviderTGI, BaseURL: server.URL + "/v1", Model: "tgi"}, "bigcode/starcoder2", 16384, 12288, false},
+		{"not self-hosted", models.LLMConfig{Provider: "openai", BaseURL: server.URL}, "", 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.APIKey = "secret"
+			info := ProbeInferenceServer(context.Background(), &tt.config)
+			if (info.Error != "") != tt.wantErr {
+				t.Fatalf("error = %q, wantErr %v", info.Error, tt.wantErr)
+			}
+			if info.Model != tt.wantModel || info.MaxContextTokens != tt.wantContext || info.MaxInputTokens != tt.wantInput {
+				t.Errorf("info = %+v, want model %q context %d input %d", info, tt.wantModel, tt.wantContext, tt.wantInput)
+			}
+		})
+	}
+}
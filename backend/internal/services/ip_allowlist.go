@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// IP allow-list scopes, one per protected route group
+const (
+	IPAllowListAdmin   = "admin"
+	IPAllowListWebhook = "webhook"
+)
+
+// ipAllowListCacheTTL bounds how long other instances keep serving an outdated allow-list
+const ipAllowListCacheTTL = 30 * time.Second
+
+var ipAllowListCache = struct {
+	sync.RWMutex
+	networks map[string][]*net.IPNet
+	loadedAt time.Time
+}{}
+
+// IPAllowListService provides the allowed networks of each scope, see middleware.IPAllowList
+type IPAllowListService struct {
+	db *gorm.DB
+}
+
+func NewIPAllowListService(db *gorm.DB) *IPAllowListService {
+	return &IPAllowListService{db: db}
+}
+
+// AllowedNetworks returns the networks allowed to reach a scope; nil allows everyone
+func (s *IPAllowListService) AllowedNetworks(scope string) []*net.IPNet {
+	ipAllowListCache.RLock()
+	if time.Since(ipAllowListCache.loadedAt) < ipAllowListCacheTTL {
+		networks := ipAllowListCache.networks[scope]
+		ipAllowListCache.RUnlock()
+		return networks
+	}
+	ipAllowListCache.RUnlock()
+
+	cfg := NewSystemConfigService(s.db).GetIPAllowListConfig()
+	loaded := make(map[string][]*net.IPNet, 2)
+	for scope, entries := range map[string][]string{IPAllowListAdmin: cfg.Admin, IPAllowListWebhook: cfg.Webhook} {
+		networks, err := ParseCIDRList(entries)
+		if err != nil {
+			// Stored lists are validated on update; never lock everyone out over a bad entry
+			logger.Errorf("[IPAllowList] Ignoring invalid %s allow-list: %v", scope, err)
+			continue
+		}
+		loaded[scope] = networks
+	}
+
+	ipAllowListCache.Lock()
+	ipAllowListCache.networks = loaded
+	ipAllowListCache.loadedAt = time.Now()
+	ipAllowListCache.Unlock()
+	return loaded[scope]
+}
+
+// invalidateIPAllowListCache makes the next request reload the allow-lists
+func invalidateIPAllowListCache() {
+	ipAllowListCache.Lock()
+	ipAllowListCache.loadedAt = time.Time{}
+	ipAllowListCache.Unlock()
+}
+
+// ParseCIDRList parses CIDR ranges; bare IP addresses are treated as single hosts
+func ParseCIDRList(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address or CIDR range %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address or CIDR range %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// IPAllowed reports whether ip is in one of the networks; an empty list allows every address
+func IPAllowed(ip string, networks []*net.IPNet) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
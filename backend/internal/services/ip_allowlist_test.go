@@ -0,0 +1,62 @@
+package services
+
+import "testing"
+
+func TestParseCIDRList(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		wantLen int
+		wantErr bool
+	}{
+		{"empty", nil, 0, false},
+		{"cidr ranges", []string{"10.0.0.0/8", " 192.168.0.0/16 ", "2001:db8::/32"}, 3, false},
+		{"bare addresses", []string{"203.0.113.7", "::1"}, 2, false},
+		{"blank entries skipped", []string{"", "  "}, 0, false},
+		{"invalid address", []string{"10.0.0.300"}, 0, true},
+		{"invalid range", []string{"10.0.0.0/33"}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCIDRList(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestIPAllowed(t *testing.T) {
+	networks, err := ParseCIDRList([]string{"10.0.0.0/8", "203.0.113.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ip       string
+		networks bool
+		want     bool
+	}{
+		{"10.20.30.40", true, true},
+		{"203.0.113.7", true, true},
+		{"203.0.113.8", true, false},
+		{"2001:db8::1", true, true},
+		{"::ffff:10.0.0.1", true, true},
+		{"not-an-ip", true, false},
+		{"192.168.1.1", false, true},
+	}
+
+	for _, tt := range tests {
+		list := networks
+		if !tt.networks {
+			list = nil
+		}
+		if got := IPAllowed(tt.ip, list); got != tt.want {
+			t.Errorf("IPAllowed(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -21,7 +21,7 @@ type IssueTrackerService struct {
 func NewIssueTrackerService(db *gorm.DB) *IssueTrackerService {
 	return &IssueTrackerService{
 		db:         db,
-		httpClient: &http.Client{Timeout: 15 * time.Second},
+		httpClient: OutboundHTTPClient(15 * time.Second),
 	}
 }
 
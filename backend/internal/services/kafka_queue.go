@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// Default names of the Kafka review queue
+const (
+	defaultKafkaTopic   = "codesentry.review_tasks"
+	defaultKafkaGroupID = "codesentry-workers"
+)
+
+// Headers of review task messages
+const (
+	kafkaDeliveriesHeader = "codesentry-deliveries" // Deliveries of the task so far, 1 if missing
+	kafkaErrorHeader      = "codesentry-error"      // Why a dead-lettered task failed
+)
+
+// KafkaQueue implements TaskQueue on a Kafka topic consumed by a consumer group shared by
+// all instances. Delivery is at-least-once: each consumer processes its messages in order
+// and commits a message's offset after it was processed. Tasks of a consumer that stops
+// without committing (e.g. its instance crashed) are redelivered once the group rebalances
+// after the session timeout. Failed tasks are published again to the end of the topic with
+// their delivery count, and tasks failing streamMaxDeliveries times are moved to the
+// <topic>.dead topic. Create the topic with at least as many partitions as consumers across
+// all instances, since a partition is read by one consumer at a time.
+type KafkaQueue struct {
+	writer      *kafka.Writer
+	brokers     []string
+	topic       string
+	deadTopic   string
+	groupID     string
+	concurrency int
+	processor   func(context.Context, *ReviewTask) error
+
+	// fetchCtx ends the fetches of the consumers on Stop; ctx cancels the running tasks
+	fetchCtx    context.Context
+	fetchCancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	mu          sync.Mutex
+	running     bool
+}
+
+// NewKafkaQueue checks that a broker is reachable and creates the producer of the queue
+func NewKafkaQueue(cfg *config.QueueConfig) (*KafkaQueue, error) {
+	if err := pingKafka(cfg.Kafka.Brokers); err != nil {
+		return nil, err
+	}
+
+	topic := valueOr(cfg.Kafka.Topic, defaultKafkaTopic)
+	concurrency := cfg.Workers
+	if concurrency <= 0 {
+		concurrency = DefaultQueueWorkers
+	}
+	fetchCtx, fetchCancel := context.WithCancel(context.Background())
+	queueCtx, queueCancel := context.WithCancel(context.Background())
+	return &KafkaQueue{
+		writer: &kafka.Writer{
+			Addr:                   kafka.TCP(cfg.Kafka.Brokers...),
+			Balancer:               &kafka.Hash{},
+			RequiredAcks:           kafka.RequireAll,
+			AllowAutoTopicCreation: true,
+		},
+		brokers:     cfg.Kafka.Brokers,
+		topic:       topic,
+		deadTopic:   topic + ".dead",
+		groupID:     valueOr(cfg.Kafka.GroupID, defaultKafkaGroupID),
+		concurrency: concurrency,
+		fetchCtx:    fetchCtx,
+		fetchCancel: fetchCancel,
+		ctx:         queueCtx,
+		cancel:      queueCancel,
+	}, nil
+}
+
+// pingKafka returns an error unless one of the brokers accepts a connection
+func pingKafka(brokers []string) error {
+	var err error
+	for _, broker := range brokers {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		var conn *kafka.Conn
+		conn, err = kafka.DialContext(ctx, "tcp", broker)
+		cancel()
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	if err == nil {
+		err = errors.New("no Kafka brokers configured")
+	}
+	return err
+}
+
+// Enqueue publishes a review task to the topic
+func (q *KafkaQueue) Enqueue(task *ReviewTask) error {
+	task.markQueued()
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = q.writer.WriteMessages(ctx, kafka.Message{
+		Topic: q.topic,
+		Key:   []byte(strconv.FormatUint(uint64(task.ReviewLogID), 10)),
+		Value: payload,
+	})
+	if err != nil {
+		return err
+	}
+	logger.Infof("[KafkaQueue] Task enqueued: topic=%s, review_log_id=%d", q.topic, task.ReviewLogID)
+	return nil
+}
+
+// IsAsync returns true for the Kafka queue
+func (q *KafkaQueue) IsAsync() bool {
+	return true
+}
+
+// Close stops the consumers and closes the producer
+func (q *KafkaQueue) Close() error {
+	q.Stop()
+	return q.writer.Close()
+}
+
+// SetProcessor sets the function to process review tasks
+func (q *KafkaQueue) SetProcessor(processor func(context.Context, *ReviewTask) error) {
+	q.processor = processor
+}
+
+// Start runs the consumers of this instance
+func (q *KafkaQueue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running {
+		return nil
+	}
+	if q.processor == nil {
+		return errors.New("no processor set")
+	}
+	q.running = true
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.consume()
+	}
+	logger.Infof("[KafkaQueue] Started %d consumers in group %s on topic %s", q.concurrency, q.groupID, q.topic)
+	return nil
+}
+
+// Stop stops the consumers. Running tasks get a grace period and are cancelled
+// afterwards; uncommitted tasks are redelivered to the consumer group.
+func (q *KafkaQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.running {
+		return
+	}
+	q.running = false
+	q.fetchCancel()
+	drainConsumers("KafkaQueue", &q.wg, q.cancel)
+}
+
+// consume reads tasks with a group member of its own. A task that can neither be committed
+// nor published again leaves the member, so the group redelivers it from the last commit.
+func (q *KafkaQueue) consume() {
+	defer q.wg.Done()
+	for q.fetchCtx.Err() == nil {
+		reader := kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     q.brokers,
+			GroupID:     q.groupID,
+			Topic:       q.topic,
+			StartOffset: kafka.FirstOffset,
+			MaxWait:     streamReadBlock,
+		})
+		q.read(reader)
+		reader.Close()
+		select {
+		case <-q.fetchCtx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// read processes the tasks of a reader until the queue stops or a task is left uncommitted
+func (q *KafkaQueue) read(reader *kafka.Reader) {
+	for {
+		msg, err := reader.FetchMessage(q.fetchCtx)
+		if err != nil {
+			if q.fetchCtx.Err() != nil {
+				return
+			}
+			logger.Warnf("[KafkaQueue] Failed to fetch tasks: %v", err)
+			select {
+			case <-q.fetchCtx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		if !q.handle(&msg) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = reader.CommitMessages(ctx, msg)
+		cancel()
+		if err != nil {
+			logger.Warnf("[KafkaQueue] Failed to commit task %d/%d: %v", msg.Partition, msg.Offset, err)
+			return
+		}
+	}
+}
+
+// handle processes a task and reports whether its offset may be committed: it was
+// processed, published again for a retry or dead-lettered
+func (q *KafkaQueue) handle(msg *kafka.Message) bool {
+	deliveries := kafkaDeliveries(msg)
+	if deliveries > 1 {
+		logger.Infof("[KafkaQueue] Redelivering task %d/%d (delivery %d)", msg.Partition, msg.Offset, deliveries)
+	}
+
+	var task ReviewTask
+	if err := json.Unmarshal(msg.Value, &task); err != nil {
+		return q.deadLetter(msg, fmt.Sprintf("invalid payload: %v", err))
+	}
+
+	err := runReviewTask(q.ctx, q.processor, &task)
+	if q.ctx.Err() != nil {
+		// Cancelled by shutdown: redelivered from the last commit
+		return false
+	}
+	if err == nil {
+		return true
+	}
+	logger.Infof("[KafkaQueue] Task %d/%d failed: %v", msg.Partition, msg.Offset, err)
+	if deliveries >= streamMaxDeliveries {
+		return q.deadLetter(msg, fmt.Sprintf("failed after %d deliveries", deliveries))
+	}
+	return q.publish(kafkaRetryMessage(msg, q.topic, deliveries+1, ""))
+}
+
+// deadLetter moves a task that cannot be processed to the dead-letter topic
+func (q *KafkaQueue) deadLetter(msg *kafka.Message, reason string) bool {
+	logger.Warnf("[KafkaQueue] Moving task %d/%d to %s: %s", msg.Partition, msg.Offset, q.deadTopic, reason)
+	return q.publish(kafkaRetryMessage(msg, q.deadTopic, kafkaDeliveries(msg), reason))
+}
+
+// publish writes a message, reporting whether it was written
+func (q *KafkaQueue) publish(msg kafka.Message) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := q.writer.WriteMessages(ctx, msg); err != nil {
+		logger.Warnf("[KafkaQueue] Failed to publish task to %s: %v", msg.Topic, err)
+		return false
+	}
+	return true
+}
+
+// kafkaDeliveries returns how often a task was delivered, counting this delivery
+func kafkaDeliveries(msg *kafka.Message) int {
+	for _, h := range msg.Headers {
+		if h.Key == kafkaDeliveriesHeader {
+			if n, err := strconv.Atoi(string(h.Value)); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 1
+}
+
+// kafkaRetryMessage copies a task to topic with its delivery count and, for dead-lettered
+// tasks, the reason it failed
+func kafkaRetryMessage(msg *kafka.Message, topic string, deliveries int, reason string) kafka.Message {
+	headers := []kafka.Header{{Key: kafkaDeliveriesHeader, Value: []byte(strconv.Itoa(deliveries))}}
+	if reason != "" {
+		headers = append(headers, kafka.Header{Key: kafkaErrorHeader, Value: []byte(reason)})
+	}
+	return kafka.Message{Topic: topic, Key: msg.Key, Value: msg.Value, Headers: headers}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestKafkaDeliveries(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers []kafka.Header
+		want    int
+	}{
+		{"first delivery", nil, 1},
+		{"retried", []kafka.Header{{Key: kafkaDeliveriesHeader, Value: []byte("3")}}, 3},
+		{"invalid", []kafka.Header{{Key: kafkaDeliveriesHeader, Value: []byte("x")}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kafkaDeliveries(&kafka.Message{Headers: tt.headers}); got != tt.want {
+				t.Errorf("kafkaDeliveries() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKafkaRetryMessage(t *testing.T) {
+	msg := &kafka.Message{Topic: "tasks", Key: []byte("7"), Value: []byte(`{"review_log_id":7}`),
+		Headers: []kafka.Header{{Key: kafkaDeliveriesHeader, Value: []byte("1")}}}
+
+	retry := kafkaRetryMessage(msg, "tasks", 2, "")
+	if retry.Topic != "tasks" || string(retry.Key) != "7" || string(retry.Value) != string(msg.Value) || kafkaDeliveries(&retry) != 2 {
+		t.Errorf("retry message = %+v", retry)
+	}
+
+	dead := kafkaRetryMessage(msg, "tasks.dead", 4, "failed after 4 deliveries")
+	if dead.Topic != "tasks.dead" || len(dead.Headers) != 2 || string(dead.Headers[1].Value) != "failed after 4 deliveries" {
+		t.Errorf("dead-letter message = %+v", dead)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// LanguageOther is the language of files with an unknown extension
+const LanguageOther = "other"
+
+// fileLanguages maps file extensions to the language reported in review statistics
+var fileLanguages = map[string]string{
+	".go": "go", ".py": "python", ".pyw": "python",
+	".js": "javascript", ".jsx": "javascript", ".mjs": "javascript", ".cjs": "javascript",
+	".ts": "typescript", ".tsx": "typescript",
+	".java": "java", ".kt": "kotlin", ".kts": "kotlin", ".scala": "scala",
+	".rs": "rust", ".rb": "ruby", ".php": "php", ".swift": "swift", ".cs": "csharp",
+	".c": "c", ".h": "c", ".cpp": "cpp", ".cc": "cpp", ".cxx": "cpp", ".hpp": "cpp",
+	".vue": "vue", ".svelte": "svelte", ".html": "html", ".css": "css", ".scss": "css", ".less": "css",
+	".sql": "sql", ".sh": "shell", ".bash": "shell", ".ps1": "powershell",
+	".yaml": "yaml", ".yml": "yaml", ".json": "json", ".toml": "toml", ".xml": "xml",
+	".tf": "terraform", ".hcl": "terraform", ".proto": "protobuf", ".graphql": "graphql",
+	".md": "markdown", ".dart": "dart", ".lua": "lua", ".ex": "elixir", ".exs": "elixir",
+}
+
+// fileNameLanguages maps extensionless file names to their language
+var fileNameLanguages = map[string]string{
+	"dockerfile":  "dockerfile",
+	"makefile":    "makefile",
+	"jenkinsfile": "groovy",
+}
+
+// LanguageOfFile returns the language of a changed file from its extension or name
+func LanguageOfFile(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if lang, ok := fileLanguages[path.Ext(name)]; ok {
+		return lang
+	}
+	if lang, ok := fileNameLanguages[strings.SplitN(name, ".", 2)[0]]; ok {
+		return lang
+	}
+	return LanguageOther
+}
+
+// LanguageShare is the changed lines of one language in a review
+type LanguageShare struct {
+	Language  string  `json:"language"`
+	Additions int     `json:"additions"`
+	Deletions int     `json:"deletions"`
+	Percent   float64 `json:"percent"` // Share of the review's changed lines
+}
+
+// LanguageBreakdown returns the changed lines per language of a diff's files, largest first
+func LanguageBreakdown(files []FileDiff) []LanguageShare {
+	byLanguage := make(map[string]*LanguageShare)
+	total := 0
+	for _, f := range files {
+		if f.FilePath == "" || f.FilePath == "unknown" {
+			continue
+		}
+		lang := LanguageOfFile(f.FilePath)
+		share, ok := byLanguage[lang]
+		if !ok {
+			share = &LanguageShare{Language: lang}
+			byLanguage[lang] = share
+		}
+		share.Additions += f.Additions
+		share.Deletions += f.Deletions
+		total += f.Additions + f.Deletions
+	}
+
+	shares := make([]LanguageShare, 0, len(byLanguage))
+	for _, share := range byLanguage {
+		if total > 0 {
+			share.Percent = math.Round(float64(share.Additions+share.Deletions)*1000/float64(total)) / 10
+		}
+		shares = append(shares, *share)
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		li, lj := shares[i].Additions+shares[i].Deletions, shares[j].Additions+shares[j].Deletions
+		if li != lj {
+			return li > lj
+		}
+		return shares[i].Language < shares[j].Language
+	})
+	return shares
+}
+
+// encodeLanguageStats returns the JSON stored in ReviewLog.LanguageStats and the primary language
+func encodeLanguageStats(shares []LanguageShare) (string, string) {
+	if len(shares) == 0 {
+		return "", ""
+	}
+	data, err := json.Marshal(shares)
+	if err != nil {
+		return "", ""
+	}
+	return string(data), shares[0].Language
+}
+
+// LanguageStatsService aggregates the changed lines and scores of reviews per language
+type LanguageStatsService struct {
+	db *gorm.DB
+}
+
+func NewLanguageStatsService(db *gorm.DB) *LanguageStatsService {
+	return &LanguageStatsService{db: db}
+}
+
+type LanguageStatsRequest struct {
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+	ProjectID uint   `form:"-"`
+	Author    string `form:"-"`
+	TenantID  uint   `form:"-"`
+}
+
+// LanguageStat summarizes the reviews touching a language
+type LanguageStat struct {
+	Language  string  `json:"language"`
+	Reviews   int     `json:"reviews"`
+	Files     int     `json:"files"`
+	Additions int     `json:"additions"`
+	Deletions int     `json:"deletions"`
+	Percent   float64 `json:"percent"`   // Share of all changed lines
+	AvgScore  float64 `json:"avg_score"` // Average score of the scored reviews touching the language
+}
+
+// Get aggregates the changed files of completed reviews per language, over the last 30
+// days by default, optionally for one project or author
+func (s *LanguageStatsService) Get(req *LanguageStatsRequest) ([]LanguageStat, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 30)
+
+	reviewQuery := ScopeStatsReviewLogs(s.db.Model(&models.ReviewLog{}), req.TenantID).Select("id, score").
+		Where("review_status = ? AND created_at BETWEEN ? AND ?", "completed", startDate, endDate)
+	if req.ProjectID > 0 {
+		reviewQuery = reviewQuery.Where("project_id = ?", req.ProjectID)
+	}
+	if req.Author != "" {
+		reviewQuery = reviewQuery.Where("author = ?", req.Author)
+	}
+	var reviews []struct {
+		ID    uint
+		Score *float64
+	}
+	if err := reviewQuery.Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	if len(reviews) == 0 {
+		return []LanguageStat{}, nil
+	}
+
+	ids := make([]uint, 0, len(reviews))
+	scores := make(map[uint]*float64, len(reviews))
+	for _, r := range reviews {
+		ids = append(ids, r.ID)
+		scores[r.ID] = r.Score
+	}
+
+	var files []models.ReviewFile
+	for start := 0; start < len(ids); start += 500 {
+		end := start + 500
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch []models.ReviewFile
+		if err := s.db.Where("review_log_id IN ?", ids[start:end]).Find(&batch).Error; err != nil {
+			return nil, err
+		}
+		files = append(files, batch...)
+	}
+	return aggregateLanguageStats(files, scores), nil
+}
+
+// aggregateLanguageStats sums changed files per language; files recorded before languages
+// were stored get theirs from the path. Reviews touching several files of a language count once.
+func aggregateLanguageStats(files []models.ReviewFile, scores map[uint]*float64) []LanguageStat {
+	type accumulator struct {
+		stat     LanguageStat
+		reviews  map[uint]bool
+		scored   int
+		scoreSum float64
+	}
+	byLanguage := make(map[string]*accumulator)
+	total := 0
+	for _, f := range files {
+		lang := f.Language
+		if lang == "" {
+			lang = LanguageOfFile(f.Path)
+		}
+		acc, ok := byLanguage[lang]
+		if !ok {
+			acc = &accumulator{stat: LanguageStat{Language: lang}, reviews: make(map[uint]bool)}
+			byLanguage[lang] = acc
+		}
+		acc.stat.Files++
+		acc.stat.Additions += f.Additions
+		acc.stat.Deletions += f.Deletions
+		total += f.Additions + f.Deletions
+		if acc.reviews[f.ReviewLogID] {
+			continue
+		}
+		acc.reviews[f.ReviewLogID] = true
+		acc.stat.Reviews++
+		if score := scores[f.ReviewLogID]; score != nil {
+			acc.scored++
+			acc.scoreSum += *score
+		}
+	}
+
+	stats := make([]LanguageStat, 0, len(byLanguage))
+	for _, acc := range byLanguage {
+		if total > 0 {
+			acc.stat.Percent = math.Round(float64(acc.stat.Additions+acc.stat.Deletions)*1000/float64(total)) / 10
+		}
+		if acc.scored > 0 {
+			acc.stat.AvgScore = math.Round(acc.scoreSum/float64(acc.scored)*10) / 10
+		}
+		stats = append(stats, acc.stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		li, lj := stats[i].Additions+stats[i].Deletions, stats[j].Additions+stats[j].Deletions
+		if li != lj {
+			return li > lj
+		}
+		return stats[i].Language < stats[j].Language
+	})
+	return stats
+}
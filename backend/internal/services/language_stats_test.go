@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestLanguageOfFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"internal/services/ai.go", "go"},
+		{"web/src/App.TSX", "typescript"},
+		{"migrations/001_init.sql", "sql"},
+		{"deploy/values.yml", "yaml"},
+		{"Dockerfile", "dockerfile"},
+		{"build/Dockerfile.prod", "dockerfile"},
+		{"Makefile", "makefile"},
+		{"LICENSE", LanguageOther},
+		{"assets/logo.png", LanguageOther},
+	}
+
+	for _, tt := range tests {
+		if got := LanguageOfFile(tt.path); got != tt.want {
+			t.Errorf("LanguageOfFile(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestLanguageBreakdown(t *testing.T) {
+	files := []FileDiff{
+		{FilePath: "main.go", Additions: 40, Deletions: 10},
+		{FilePath: "handler.go", Additions: 10},
+		{FilePath: "schema.sql", Additions: 20},
+		{FilePath: "config.yaml", Additions: 15, Deletions: 5},
+		{FilePath: "unknown", Additions: 100},
+	}
+
+	shares := LanguageBreakdown(files)
+	if len(shares) != 3 {
+		t.Fatalf("expected 3 languages, got %+v", shares)
+	}
+	want := []LanguageShare{
+		{Language: "go", Additions: 50, Deletions: 10, Percent: 60},
+		{Language: "sql", Additions: 20, Percent: 20},
+		{Language: "yaml", Additions: 15, Deletions: 5, Percent: 20},
+	}
+	for i, w := range want {
+		if shares[i] != w {
+			t.Errorf("shares[%d] = %+v, want %+v", i, shares[i], w)
+		}
+	}
+
+	stats, primary := encodeLanguageStats(shares)
+	if primary != "go" || stats == "" {
+		t.Errorf("encodeLanguageStats = %q, %q", stats, primary)
+	}
+	if stats, primary := encodeLanguageStats(nil); stats != "" || primary != "" {
+		t.Errorf("encodeLanguageStats(nil) = %q, %q", stats, primary)
+	}
+}
+
+func TestAggregateLanguageStats(t *testing.T) {
+	low, high := 50.0, 90.0
+	files := []models.ReviewFile{
+		{ReviewLogID: 1, Path: "a.go", Language: "go", Additions: 30},
+		{ReviewLogID: 1, Path: "b.go", Language: "go", Additions: 10},
+		{ReviewLogID: 2, Path: "c.go", Additions: 20}, // Recorded before languages were stored
+		{ReviewLogID: 2, Path: "q.sql", Language: "sql", Additions: 20, Deletions: 20},
+		{ReviewLogID: 3, Path: "d.go", Language: "go"},
+	}
+	scores := map[uint]*float64{1: &low, 2: &high, 3: nil}
+
+	stats := aggregateLanguageStats(files, scores)
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 languages, got %+v", stats)
+	}
+	goStat := stats[0]
+	if goStat.Language != "go" || goStat.Reviews != 3 || goStat.Files != 4 || goStat.Additions != 60 {
+		t.Errorf("go = %+v", goStat)
+	}
+	// Unscored reviews are left out of the average
+	if goStat.AvgScore != 70 || goStat.Percent != 60 {
+		t.Errorf("go avg score = %v, percent = %v", goStat.AvgScore, goStat.Percent)
+	}
+	if sql := stats[1]; sql.Language != "sql" || sql.Reviews != 1 || sql.AvgScore != 90 || sql.Percent != 40 {
+		t.Errorf("sql = %+v", sql)
+	}
+}
@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"strconv"
 
 	"github.com/go-ldap/ldap/v3"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 	"gorm.io/gorm"
 )
 
@@ -26,11 +28,18 @@ type ldapConfig struct {
 	BindPassword string
 	UserFilter   string
 	UseSSL       bool
+	// GroupAttribute lists the groups of a user entry, e.g. memberOf
+	GroupAttribute string
+	GroupMappings  []LDAPGroupMapping
 }
 
 func (s *LDAPService) getConfig() *ldapConfig {
 	configService := NewSystemConfigService(s.db)
 	port, _ := strconv.Atoi(configService.GetWithDefault("ldap_port", "389"))
+	mappings, err := ParseLDAPGroupMappings(configService.GetWithDefault("ldap_group_mappings", ""))
+	if err != nil {
+		logger.Warnf("[LDAP] Ignoring group mappings: %v", err)
+	}
 	return &ldapConfig{
 		Enabled:      configService.GetWithDefault("ldap_enabled", "false") == "true",
 		Host:         configService.GetWithDefault("ldap_host", ""),
@@ -40,15 +49,14 @@ func (s *LDAPService) getConfig() *ldapConfig {
 		BindPassword: configService.GetWithDefault("ldap_bind_password", ""),
 		UserFilter:   configService.GetWithDefault("ldap_user_filter", "(uid=%s)"),
 		UseSSL:       configService.GetWithDefault("ldap_use_ssl", "false") == "true",
-	}
-}
 
-func (s *LDAPService) Authenticate(username, password string) (*LDAPUser, error) {
-	cfg := s.getConfig()
-	if !cfg.Enabled {
-		return nil, fmt.Errorf("LDAP is not enabled")
+		GroupAttribute: configService.GetWithDefault("ldap_group_attribute", "memberOf"),
+		GroupMappings:  mappings,
 	}
+}
 
+// connect dials the LDAP server and binds with the service account, if configured
+func (s *LDAPService) connect(cfg *ldapConfig) (*ldap.Conn, error) {
 	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 	var conn *ldap.Conn
 	var err error
@@ -62,21 +70,33 @@ func (s *LDAPService) Authenticate(username, password string) (*LDAPUser, error)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
 	}
-	defer conn.Close()
 
 	if cfg.BindDN != "" {
-		err = conn.Bind(cfg.BindDN, cfg.BindPassword)
+		password, err := ResolveSecret(context.Background(), cfg.BindPassword)
 		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to resolve the bind password: %w", err)
+		}
+		if err = conn.Bind(cfg.BindDN, password); err != nil {
+			conn.Close()
 			return nil, fmt.Errorf("failed to bind with service account: %w", err)
 		}
 	}
+	return conn, nil
+}
 
+// searchUser looks up the entries matching the user filter for a username
+func (s *LDAPService) searchUser(conn *ldap.Conn, cfg *ldapConfig, username string) ([]*ldap.Entry, error) {
 	searchFilter := fmt.Sprintf(cfg.UserFilter, ldap.EscapeFilter(username))
+	attributes := []string{"dn", "cn", "mail", "uid", "sAMAccountName"}
+	if cfg.GroupAttribute != "" {
+		attributes = append(attributes, cfg.GroupAttribute)
+	}
 	searchRequest := ldap.NewSearchRequest(
 		cfg.BaseDN,
 		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		searchFilter,
-		[]string{"dn", "cn", "mail", "uid", "sAMAccountName"},
+		attributes,
 		nil,
 	)
 
@@ -84,25 +104,46 @@ func (s *LDAPService) Authenticate(username, password string) (*LDAPUser, error)
 	if err != nil {
 		return nil, fmt.Errorf("LDAP search failed: %w", err)
 	}
+	return result.Entries, nil
+}
 
-	if len(result.Entries) == 0 {
+func (s *LDAPService) Authenticate(username, password string) (*LDAPUser, error) {
+	cfg := s.getConfig()
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("LDAP is not enabled")
+	}
+
+	conn, err := s.connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entries, err := s.searchUser(conn, cfg, username)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
 		return nil, fmt.Errorf("user not found in LDAP")
 	}
 
-	if len(result.Entries) > 1 {
+	if len(entries) > 1 {
 		return nil, fmt.Errorf("multiple users found in LDAP")
 	}
 
-	userDN := result.Entries[0].DN
+	userDN := entries[0].DN
 
 	err = conn.Bind(userDN, password)
 	if err != nil {
 		return nil, fmt.Errorf("invalid credentials")
 	}
 
-	entry := result.Entries[0]
+	return newLDAPUser(entries[0], cfg), nil
+}
+
+func newLDAPUser(entry *ldap.Entry, cfg *ldapConfig) *LDAPUser {
 	user := &LDAPUser{
-		DN:       userDN,
+		DN:       entry.DN,
 		Username: entry.GetAttributeValue("uid"),
 		Email:    entry.GetAttributeValue("mail"),
 		Nickname: entry.GetAttributeValue("cn"),
@@ -111,8 +152,11 @@ func (s *LDAPService) Authenticate(username, password string) (*LDAPUser, error)
 	if user.Username == "" {
 		user.Username = entry.GetAttributeValue("sAMAccountName")
 	}
+	if cfg.GroupAttribute != "" {
+		user.Groups = entry.GetAttributeValues(cfg.GroupAttribute)
+	}
 
-	return user, nil
+	return user
 }
 
 func (s *LDAPService) IsEnabled() bool {
@@ -125,4 +169,5 @@ type LDAPUser struct {
 	Username string
 	Email    string
 	Nickname string
+	Groups   []string
 }
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// LDAPGroupMapping grants a role and project memberships to the members of an LDAP group.
+// Group is matched case-insensitively against the full group DN or its CN.
+type LDAPGroupMapping struct {
+	Group    string               `json:"group"`
+	Role     string               `json:"role,omitempty"` // admin, tenant_admin, developer, user
+	Projects []LDAPProjectMapping `json:"projects,omitempty"`
+}
+
+// LDAPProjectMapping is a project membership granted by an LDAP group
+type LDAPProjectMapping struct {
+	ProjectID uint   `json:"project_id"`
+	Role      string `json:"role"` // owner, maintainer, viewer
+}
+
+// LDAPGroupResolution is the access an LDAP user is granted by their groups
+type LDAPGroupResolution struct {
+	// Role is empty when no mapping manages roles, leaving roles to admins
+	Role     string
+	Projects map[uint]string
+}
+
+// LDAPSyncResult summarizes a directory sync run
+type LDAPSyncResult struct {
+	Checked     int `json:"checked"`
+	Updated     int `json:"updated"`
+	Deactivated int `json:"deactivated"`
+	Skipped     int `json:"skipped"`
+}
+
+// Project membership source values
+const (
+	MemberSourceManual = "manual"
+	MemberSourceLDAP   = "ldap"
+)
+
+var userRoleRank = map[string]int{"user": 1, "developer": 2, "tenant_admin": 3, "admin": 4}
+
+var memberRoleRank = map[string]int{"viewer": 1, "maintainer": 2, "owner": 3}
+
+// ParseLDAPGroupMappings decodes the configured group mappings
+func ParseLDAPGroupMappings(raw string) ([]LDAPGroupMapping, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var mappings []LDAPGroupMapping
+	if err := json.Unmarshal([]byte(raw), &mappings); err != nil {
+		return nil, fmt.Errorf("invalid LDAP group mappings: %w", err)
+	}
+	if err := ValidateLDAPGroupMappings(mappings); err != nil {
+		return nil, err
+	}
+	return mappings, nil
+}
+
+// ValidateLDAPGroupMappings checks groups, roles and project references of the mappings
+func ValidateLDAPGroupMappings(mappings []LDAPGroupMapping) error {
+	for i, m := range mappings {
+		if strings.TrimSpace(m.Group) == "" {
+			return fmt.Errorf("mapping %d: group is required", i+1)
+		}
+		if m.Role == "" && len(m.Projects) == 0 {
+			return fmt.Errorf("mapping %d (%s): a role or at least one project is required", i+1, m.Group)
+		}
+		if m.Role != "" && userRoleRank[m.Role] == 0 {
+			return fmt.Errorf("mapping %d (%s): invalid role %q", i+1, m.Group, m.Role)
+		}
+		for _, p := range m.Projects {
+			if p.ProjectID == 0 {
+				return fmt.Errorf("mapping %d (%s): project_id is required", i+1, m.Group)
+			}
+			if memberRoleRank[p.Role] == 0 {
+				return fmt.Errorf("mapping %d (%s): invalid project role %q", i+1, m.Group, p.Role)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveLDAPGroups returns the highest role and project roles granted by the groups of a user.
+// When any mapping sets a role, users matching none of them fall back to "user".
+func ResolveLDAPGroups(groups []string, mappings []LDAPGroupMapping) LDAPGroupResolution {
+	res := LDAPGroupResolution{Projects: map[uint]string{}}
+	for _, m := range mappings {
+		if m.Role != "" && res.Role == "" {
+			res.Role = "user"
+		}
+	}
+
+	for _, m := range mappings {
+		if !ldapGroupMatches(groups, m.Group) {
+			continue
+		}
+		if userRoleRank[m.Role] > userRoleRank[res.Role] {
+			res.Role = m.Role
+		}
+		for _, p := range m.Projects {
+			if memberRoleRank[p.Role] > memberRoleRank[res.Projects[p.ProjectID]] {
+				res.Projects[p.ProjectID] = p.Role
+			}
+		}
+	}
+	return res
+}
+
+// ldapGroupMatches reports whether group names one of the user's groups by DN or CN
+func ldapGroupMatches(groups []string, group string) bool {
+	group = strings.TrimSpace(group)
+	for _, g := range groups {
+		if strings.EqualFold(g, group) || strings.EqualFold(ldapGroupCN(g), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// ldapGroupCN extracts the CN of a group DN, e.g. "developers" from "cn=developers,ou=groups,dc=example,dc=com"
+func ldapGroupCN(dn string) string {
+	first := strings.SplitN(dn, ",", 2)[0]
+	key, value, ok := strings.Cut(first, "=")
+	if !ok || !strings.EqualFold(strings.TrimSpace(key), "cn") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// ApplyGroupMappings updates the role and LDAP-managed project memberships of a user
+// from their groups. Memberships added manually are never changed.
+func (s *LDAPService) ApplyGroupMappings(user *models.User, groups []string) error {
+	return s.applyGroupMappings(user, ResolveLDAPGroups(groups, s.getConfig().GroupMappings))
+}
+
+func (s *LDAPService) applyGroupMappings(user *models.User, res LDAPGroupResolution) error {
+	if res.Role != "" && res.Role != user.Role {
+		if err := s.db.Model(user).Update("role", res.Role).Error; err != nil {
+			return err
+		}
+		user.Role = res.Role
+		logger.Infof("[LDAP] Role of %s set to %s from group mappings", user.Username, res.Role)
+	}
+
+	var members []models.ProjectMember
+	if err := s.db.Unscoped().Where("user_id = ?", user.ID).Find(&members).Error; err != nil {
+		return err
+	}
+	existing := make(map[uint]*models.ProjectMember, len(members))
+	for i := range members {
+		m := &members[i]
+		existing[m.ProjectID] = m
+		if _, granted := res.Projects[m.ProjectID]; !granted && m.Source == MemberSourceLDAP && !m.DeletedAt.Valid {
+			if err := s.db.Delete(m).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	for projectID, role := range res.Projects {
+		m := existing[projectID]
+		switch {
+		case m == nil:
+			var count int64
+			s.db.Model(&models.Project{}).Where("id = ?", projectID).Count(&count)
+			if count == 0 {
+				logger.Warnf("[LDAP] Group mapping references unknown project %d", projectID)
+				continue
+			}
+			member := models.ProjectMember{ProjectID: projectID, UserID: user.ID, Role: role, Source: MemberSourceLDAP}
+			if err := s.db.Create(&member).Error; err != nil {
+				return err
+			}
+		case m.DeletedAt.Valid || (m.Source == MemberSourceLDAP && m.Role != role):
+			err := s.db.Unscoped().Model(m).Updates(map[string]interface{}{
+				"role":       role,
+				"source":     MemberSourceLDAP,
+				"deleted_at": nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ldapSyncMaxDeactivatedShare and ldapSyncMaxDeactivatedUsers bound the users one directory
+// sync may deactivate: more than this share of the checked users, beyond a handful, looks
+// like a misconfigured base DN or filter rather than people leaving
+const (
+	ldapSyncMaxDeactivatedShare = 0.2
+	ldapSyncMaxDeactivatedUsers = 5
+)
+
+// checkLDAPDeactivations refuses a sync that would deactivate every checked user, or a
+// sharply larger part of them than people usually leave
+func checkLDAPDeactivations(checked, missing int) error {
+	if missing == 0 {
+		return nil
+	}
+	if missing == checked {
+		return fmt.Errorf("none of the %d LDAP users was found in the directory, check the base DN and user filter; no user was deactivated", checked)
+	}
+	if missing > ldapSyncMaxDeactivatedUsers && float64(missing) > float64(checked)*ldapSyncMaxDeactivatedShare {
+		return fmt.Errorf("%d of the %d LDAP users were not found in the directory, more than %.0f%%; no user was deactivated",
+			missing, checked, ldapSyncMaxDeactivatedShare*100)
+	}
+	return nil
+}
+
+// SyncDirectory refreshes every active LDAP user from the directory: users no longer
+// found are deactivated, emails and display names are updated and group mappings reapplied.
+// The run aborts on search errors so an unreachable directory never deactivates users, and
+// before changing anyone when the directory misses all or a sharply larger part of the
+// users than usual, see checkLDAPDeactivations.
+func (s *LDAPService) SyncDirectory() (*LDAPSyncResult, error) {
+	cfg := s.getConfig()
+	if !cfg.Enabled {
+		return nil, fmt.Errorf("LDAP is not enabled")
+	}
+
+	conn, err := s.connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var users []models.User
+	if err := s.db.Where("auth_type = ? AND is_active = ?", "ldap", true).Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LDAPSyncResult{}
+	found := make([][]*ldap.Entry, len(users))
+	missing := 0
+	for i := range users {
+		entries, err := s.searchUser(conn, cfg, users[i].Username)
+		if err != nil {
+			return result, err
+		}
+		found[i] = entries
+		if len(entries) == 0 {
+			missing++
+		}
+	}
+	if err := checkLDAPDeactivations(len(users), missing); err != nil {
+		return result, err
+	}
+
+	for i := range users {
+		user := &users[i]
+		result.Checked++
+
+		entries := found[i]
+		if len(entries) == 0 {
+			if err := s.db.Model(user).Update("is_active", false).Error; err != nil {
+				return result, err
+			}
+			result.Deactivated++
+			logger.Infof("[LDAP] Deactivated %s: no longer in the directory", user.Username)
+			continue
+		}
+		if len(entries) > 1 {
+			result.Skipped++
+			logger.Warnf("[LDAP] Skipped %s: multiple directory entries found", user.Username)
+			continue
+		}
+
+		ldapUser := newLDAPUser(entries[0], cfg)
+		if ldapUser.Email != user.Email || ldapUser.Nickname != user.Nickname {
+			err := s.db.Model(user).Updates(map[string]interface{}{
+				"email":    ldapUser.Email,
+				"nickname": ldapUser.Nickname,
+			}).Error
+			if err != nil {
+				return result, err
+			}
+			result.Updated++
+		}
+		if err := s.applyGroupMappings(user, ResolveLDAPGroups(ldapUser.Groups, cfg.GroupMappings)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (s *LDAPService) runScheduledSync(lastRun *time.Time) {
+	configService := NewSystemConfigService(s.db)
+	if configService.GetWithDefault("ldap_sync_enabled", "false") != "true" || !s.IsEnabled() {
+		return
+	}
+	hours, _ := strconv.Atoi(configService.GetWithDefault("ldap_sync_interval_hours", "24"))
+	if hours <= 0 {
+		hours = 24
+	}
+	if !lastRun.IsZero() && time.Since(*lastRun) < time.Duration(hours)*time.Hour {
+		return
+	}
+	*lastRun = time.Now()
+
+	result, err := s.SyncDirectory()
+	if err != nil {
+		logger.Errorf("[LDAP] Directory sync failed: %v", err)
+		return
+	}
+	logger.Infof("[LDAP] Directory sync: checked %d, updated %d, deactivated %d, skipped %d",
+		result.Checked, result.Updated, result.Deactivated, result.Skipped)
+}
+
+var ldapSyncStopChan chan struct{}
+
+// StartLDAPSyncScheduler starts a goroutine that syncs LDAP users at the configured interval
+func StartLDAPSyncScheduler(db *gorm.DB) {
+	ldapSyncStopChan = make(chan struct{})
+	go func() {
+		service := NewLDAPService(db)
+		var lastRun time.Time
+		RegisterScheduler("ldap_sync", time.Hour)
+		RunScheduled("ldap_sync", func() error { service.runScheduledSync(&lastRun); return nil })
+
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("ldap_sync", func() error { service.runScheduledSync(&lastRun); return nil })
+			case <-ldapSyncStopChan:
+				logger.Infof("[LDAP] Sync scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopLDAPSyncScheduler stops the LDAP sync scheduler
+func StopLDAPSyncScheduler() {
+	if ldapSyncStopChan != nil {
+		close(ldapSyncStopChan)
+	}
+}
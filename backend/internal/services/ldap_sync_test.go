@@ -0,0 +1,114 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveLDAPGroups(t *testing.T) {
+	mappings := []LDAPGroupMapping{
+		{Group: "cn=admins,ou=groups,dc=example,dc=com", Role: "admin"},
+		{Group: "developers", Role: "developer", Projects: []LDAPProjectMapping{{ProjectID: 1, Role: "maintainer"}}},
+		{Group: "Backend", Projects: []LDAPProjectMapping{{ProjectID: 1, Role: "viewer"}, {ProjectID: 2, Role: "owner"}}},
+	}
+
+	tests := []struct {
+		name         string
+		groups       []string
+		mappings     []LDAPGroupMapping
+		wantRole     string
+		wantProjects map[uint]string
+	}{
+		{"matched by DN", []string{"CN=Admins,OU=Groups,DC=example,DC=com"}, mappings, "admin", map[uint]string{}},
+		{"matched by CN", []string{"cn=developers,ou=groups,dc=example,dc=com"}, mappings, "developer", map[uint]string{1: "maintainer"}},
+		{"highest roles win", []string{"cn=developers,dc=x", "cn=backend,dc=x", "cn=admins,ou=groups,dc=example,dc=com"}, mappings,
+			"admin", map[uint]string{1: "maintainer", 2: "owner"}},
+		{"no match falls back to user", []string{"cn=sales,dc=x"}, mappings, "user", map[uint]string{}},
+		{"roles unmanaged without role mappings", []string{"cn=backend,dc=x"}, mappings[2:], "", map[uint]string{1: "viewer", 2: "owner"}},
+		{"no mappings", []string{"cn=backend,dc=x"}, nil, "", map[uint]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveLDAPGroups(tt.groups, tt.mappings)
+			if got.Role != tt.wantRole {
+				t.Errorf("Role = %q, want %q", got.Role, tt.wantRole)
+			}
+			if !reflect.DeepEqual(got.Projects, tt.wantProjects) {
+				t.Errorf("Projects = %v, want %v", got.Projects, tt.wantProjects)
+			}
+		})
+	}
+}
+
+func TestLDAPGroupCN(t *testing.T) {
+	tests := []struct {
+		dn   string
+		want string
+	}{
+		{"cn=developers,ou=groups,dc=example,dc=com", "developers"},
+		{"CN = Ops Team ,OU=Groups", "Ops Team"},
+		{"ou=groups,dc=example", ""},
+		{"developers", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ldapGroupCN(tt.dn); got != tt.want {
+			t.Errorf("ldapGroupCN(%q) = %q, want %q", tt.dn, got, tt.want)
+		}
+	}
+}
+
+func TestParseLDAPGroupMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantLen int
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"valid", `[{"group":"devs","role":"developer","projects":[{"project_id":3,"role":"viewer"}]}]`, 1, false},
+		{"invalid json", `{`, 0, true},
+		{"missing group", `[{"role":"admin"}]`, 0, true},
+		{"nothing granted", `[{"group":"devs"}]`, 0, true},
+		{"invalid role", `[{"group":"devs","role":"root"}]`, 0, true},
+		{"invalid project role", `[{"group":"devs","projects":[{"project_id":3,"role":"admin"}]}]`, 0, true},
+		{"missing project id", `[{"group":"devs","projects":[{"role":"viewer"}]}]`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLDAPGroupMappings(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantLen {
+				t.Errorf("len = %d, want %d", len(got), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestCheckLDAPDeactivations(t *testing.T) {
+	tests := []struct {
+		name    string
+		checked int
+		missing int
+		wantErr bool
+	}{
+		{name: "nobody left", checked: 40, missing: 0},
+		{name: "a few left", checked: 40, missing: 3},
+		{name: "a handful of a small team", checked: 8, missing: 5},
+		{name: "empty directory result", checked: 40, missing: 40, wantErr: true},
+		{name: "only user missing", checked: 1, missing: 1, wantErr: true},
+		{name: "sharp drop", checked: 40, missing: 12, wantErr: true},
+		{name: "many of a large directory", checked: 1000, missing: 150},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkLDAPDeactivations(tt.checked, tt.missing); (err != nil) != tt.wantErr {
+				t.Errorf("checkLDAPDeactivations(%d, %d) = %v, wantErr %v", tt.checked, tt.missing, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrLeaderboardDisabled is returned when leaderboards are not enabled for this instance
+var ErrLeaderboardDisabled = errors.New("leaderboards are disabled")
+
+// LeaderboardService computes opt-in team leaderboards from review history.
+// Manual reviews and tiny commits are excluded and authors need a minimum
+// number of qualifying commits, so boards can't be gamed with trivial pushes.
+type LeaderboardService struct {
+	db            *gorm.DB
+	configService *SystemConfigService
+}
+
+func NewLeaderboardService(db *gorm.DB) *LeaderboardService {
+	return &LeaderboardService{
+		db:            db,
+		configService: NewSystemConfigService(db),
+	}
+}
+
+type LeaderboardRequest struct {
+	Month     string `form:"month"` // YYYY-MM, defaults to the current month
+	ProjectID uint   `form:"project_id"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	TenantID  uint   `form:"-"` // Set from the request context, 0 = all tenants
+}
+
+type QualityEntry struct {
+	Author      string  `json:"author"`
+	CommitCount int64   `json:"commit_count"`
+	AvgScore    float64 `json:"avg_score"`
+}
+
+type ImprovementEntry struct {
+	Author        string  `json:"author"`
+	CommitCount   int64   `json:"commit_count"`
+	AvgScore      float64 `json:"avg_score"`
+	PreviousScore float64 `json:"previous_score"`
+	Improvement   float64 `json:"improvement"`
+}
+
+type StreakEntry struct {
+	Author        string `json:"author"`
+	LongestStreak int    `json:"longest_streak"`
+	CurrentStreak int    `json:"current_streak"`
+}
+
+type LeaderboardResponse struct {
+	Month        string             `json:"month"`
+	MinCommits   int                `json:"min_commits"`
+	TopQuality   []QualityEntry     `json:"top_quality"`
+	MostImproved []ImprovementEntry `json:"most_improved"`
+	Streaks      []StreakEntry      `json:"streaks"`
+}
+
+// Get returns the top-quality, most-improved and score-streak boards for a month
+func (s *LeaderboardService) Get(req *LeaderboardRequest) (*LeaderboardResponse, error) {
+	cfg := s.configService.GetLeaderboardConfig()
+	if !cfg.Enabled {
+		return nil, ErrLeaderboardDisabled
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	monthStart := time.Now()
+	if req.Month != "" {
+		t, err := time.ParseInLocation("2006-01", req.Month, time.Local)
+		if err != nil {
+			return nil, errors.New("invalid month, expected YYYY-MM")
+		}
+		monthStart = t
+	}
+	monthStart = time.Date(monthStart.Year(), monthStart.Month(), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	prevStart := monthStart.AddDate(0, -1, 0)
+
+	current, err := s.authorAverages(monthStart, monthEnd, req, cfg)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.authorAverages(prevStart, monthStart, req, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	topQuality := make([]QualityEntry, 0, len(current))
+	topQuality = append(topQuality, current...)
+	sort.SliceStable(topQuality, func(i, j int) bool {
+		if topQuality[i].AvgScore == topQuality[j].AvgScore {
+			return topQuality[i].CommitCount > topQuality[j].CommitCount
+		}
+		return topQuality[i].AvgScore > topQuality[j].AvgScore
+	})
+	if len(topQuality) > limit {
+		topQuality = topQuality[:limit]
+	}
+
+	streaks, err := s.streaks(monthStart, monthEnd, req, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(streaks) > limit {
+		streaks = streaks[:limit]
+	}
+
+	mostImproved := rankImprovement(current, previous)
+	if len(mostImproved) > limit {
+		mostImproved = mostImproved[:limit]
+	}
+
+	return &LeaderboardResponse{
+		Month:        monthStart.Format("2006-01"),
+		MinCommits:   cfg.MinCommits,
+		TopQuality:   topQuality,
+		MostImproved: mostImproved,
+		Streaks:      streaks,
+	}, nil
+}
+
+// qualifyingReviews returns the scored, non-manual reviews of the request's tenant and
+// project large enough to count
+func (s *LeaderboardService) qualifyingReviews(start, end time.Time, req *LeaderboardRequest, cfg *LeaderboardConfigResponse) *gorm.DB {
+	query := ScopeStatsReviewLogs(s.db.Model(&models.ReviewLog{}), req.TenantID).
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Where("score IS NOT NULL AND is_manual = ? AND author <> ''", false).
+		Where("additions + deletions >= ?", cfg.MinLines)
+	if req.ProjectID > 0 {
+		query = query.Where("project_id = ?", req.ProjectID)
+	}
+	return query
+}
+
+func (s *LeaderboardService) authorAverages(start, end time.Time, req *LeaderboardRequest, cfg *LeaderboardConfigResponse) ([]QualityEntry, error) {
+	var entries []QualityEntry
+	err := s.qualifyingReviews(start, end, req, cfg).
+		Select("author, COUNT(*) as commit_count, AVG(score) as avg_score").
+		Group("author").
+		Having("COUNT(*) >= ?", cfg.MinCommits).
+		Scan(&entries).Error
+	return entries, err
+}
+
+func (s *LeaderboardService) streaks(start, end time.Time, req *LeaderboardRequest, cfg *LeaderboardConfigResponse) ([]StreakEntry, error) {
+	var rows []struct {
+		Author string
+		Score  float64
+	}
+	err := s.qualifyingReviews(start, end, req, cfg).
+		Select("author, score").
+		Order("created_at ASC, id ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	minScore, _ := strconv.ParseFloat(s.configService.GetWithDefault("system.min_score", "60"), 64)
+	if minScore <= 0 {
+		minScore = 60
+	}
+
+	scores := make(map[string][]float64)
+	for _, r := range rows {
+		scores[r.Author] = append(scores[r.Author], r.Score)
+	}
+
+	entries := make([]StreakEntry, 0, len(scores))
+	for author, list := range scores {
+		if len(list) < cfg.MinCommits {
+			continue
+		}
+		longest, current := scoreStreaks(list, minScore)
+		if longest == 0 {
+			continue
+		}
+		entries = append(entries, StreakEntry{Author: author, LongestStreak: longest, CurrentStreak: current})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].LongestStreak == entries[j].LongestStreak {
+			return entries[i].Author < entries[j].Author
+		}
+		return entries[i].LongestStreak > entries[j].LongestStreak
+	})
+	return entries, nil
+}
+
+// scoreStreaks returns the longest and the trailing run of consecutive passing scores
+func scoreStreaks(scores []float64, minScore float64) (longest, current int) {
+	for _, score := range scores {
+		if score >= minScore {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+	return longest, current
+}
+
+// rankImprovement ranks authors present in both periods by average score gain
+func rankImprovement(current, previous []QualityEntry) []ImprovementEntry {
+	prev := make(map[string]float64, len(previous))
+	for _, p := range previous {
+		prev[p.Author] = p.AvgScore
+	}
+
+	entries := make([]ImprovementEntry, 0)
+	for _, c := range current {
+		before, ok := prev[c.Author]
+		if !ok || c.AvgScore <= before {
+			continue
+		}
+		entries = append(entries, ImprovementEntry{
+			Author:        c.Author,
+			CommitCount:   c.CommitCount,
+			AvgScore:      c.AvgScore,
+			PreviousScore: before,
+			Improvement:   c.AvgScore - before,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Improvement == entries[j].Improvement {
+			return entries[i].Author < entries[j].Author
+		}
+		return entries[i].Improvement > entries[j].Improvement
+	})
+	return entries
+}
@@ -0,0 +1,51 @@
+package services
+
+import "testing"
+
+func TestScoreStreaks(t *testing.T) {
+	tests := []struct {
+		name            string
+		scores          []float64
+		expectedLongest int
+		expectedCurrent int
+	}{
+		{"empty", nil, 0, 0},
+		{"all passing", []float64{80, 90, 70}, 3, 3},
+		{"broken streak", []float64{80, 90, 50, 70}, 2, 1},
+		{"ends failing", []float64{80, 90, 95, 40}, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			longest, current := scoreStreaks(tt.scores, 60)
+			if longest != tt.expectedLongest || current != tt.expectedCurrent {
+				t.Errorf("scoreStreaks() = (%d, %d), expected (%d, %d)", longest, current, tt.expectedLongest, tt.expectedCurrent)
+			}
+		})
+	}
+}
+
+func TestRankImprovement(t *testing.T) {
+	current := []QualityEntry{
+		{Author: "alice", CommitCount: 6, AvgScore: 85},
+		{Author: "bob", CommitCount: 8, AvgScore: 70},
+		{Author: "carol", CommitCount: 5, AvgScore: 90},
+		{Author: "dave", CommitCount: 7, AvgScore: 60},
+	}
+	previous := []QualityEntry{
+		{Author: "alice", AvgScore: 80},
+		{Author: "bob", AvgScore: 50},
+		{Author: "dave", AvgScore: 75},
+	}
+
+	entries := rankImprovement(current, previous)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+	if entries[0].Author != "bob" || entries[0].Improvement != 20 {
+		t.Errorf("first entry = %+v, expected bob +20", entries[0])
+	}
+	if entries[1].Author != "alice" || entries[1].Improvement != 5 {
+		t.Errorf("second entry = %+v, expected alice +5", entries[1])
+	}
+}
@@ -0,0 +1,19 @@
+package services
+
+import "strings"
+
+// likeEscaper escapes the LIKE wildcards of a search term with "!", which unlike "\" needs
+// no escaping in the string literals of any supported database
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// LikeCondition returns a condition matching column against a pattern built by
+// ContainsPattern
+func LikeCondition(column string) string {
+	return column + " LIKE ? ESCAPE '!'"
+}
+
+// ContainsPattern returns the LIKE pattern of values containing s, with "%" and "_" in s
+// matched literally
+func ContainsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestContainsPattern(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"main", "%main%"},
+		{"50%", "%50!%%"},
+		{"feature_x", "%feature!_x%"},
+		{"wow!", "%wow!!%"},
+	}
+	for _, tt := range tests {
+		if got := ContainsPattern(tt.in); got != tt.want {
+			t.Errorf("ContainsPattern(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLikeCondition_MatchesLiterally(t *testing.T) {
+	db := newTestDB(t)
+	for _, name := range []string{"50% off", "500 errors", "feature_x", "featureAx", "wow!"} {
+		mustCreate(t, db, &models.Project{Name: name, URL: "https://git.example.com/" + name, Platform: "gitlab"})
+	}
+
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{"50%", []string{"50% off"}},
+		{"_x", []string{"feature_x"}},
+		{"!", []string{"wow!"}},
+		{"feature", []string{"featureAx", "feature_x"}},
+	}
+	for _, tt := range tests {
+		var names []string
+		db.Model(&models.Project{}).Where(LikeCondition("name"), ContainsPattern(tt.search)).Order("name").Pluck("name", &names)
+		if len(names) != len(tt.want) {
+			t.Errorf("search %q = %v, want %v", tt.search, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("search %q = %v, want %v", tt.search, names, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -21,6 +21,7 @@ type LLMConfigListRequest struct {
 	Name     string `form:"name"`
 	Provider string `form:"provider"`
 	IsActive *bool  `form:"is_active"`
+	TenantID uint   `form:"-"` // Set from the request context, 0 = all tenants
 }
 
 type LLMConfigListResponse struct {
@@ -38,8 +39,11 @@ type CreateLLMConfigRequest struct {
 	Model       string  `json:"model" binding:"required"`
 	MaxTokens   int     `json:"max_tokens"`
 	Temperature float64 `json:"temperature"`
+	PromptCache bool    `json:"prompt_cache"`
+	JSONMode    bool    `json:"json_mode"`
 	IsDefault   bool    `json:"is_default"`
 	IsActive    bool    `json:"is_active"`
+	TenantID    uint    `json:"-"`
 }
 
 type UpdateLLMConfigRequest struct {
@@ -50,6 +54,8 @@ type UpdateLLMConfigRequest struct {
 	Model       string   `json:"model"`
 	MaxTokens   *int     `json:"max_tokens"`
 	Temperature *float64 `json:"temperature"`
+	PromptCache *bool    `json:"prompt_cache"`
+	JSONMode    *bool    `json:"json_mode"`
 	IsDefault   *bool    `json:"is_default"`
 	IsActive    *bool    `json:"is_active"`
 }
@@ -66,10 +72,10 @@ func (s *LLMConfigService) List(req *LLMConfigListRequest) (*LLMConfigListRespon
 	var configs []models.LLMConfig
 	var total int64
 
-	query := s.db.Model(&models.LLMConfig{})
+	query := ScopeTenant(s.db.Model(&models.LLMConfig{}), req.TenantID)
 
 	if req.Name != "" {
-		query = query.Where("name LIKE ? OR model LIKE ?", "%"+req.Name+"%", "%"+req.Name+"%")
+		query = query.Where(LikeCondition("name")+" OR "+LikeCondition("model"), ContainsPattern(req.Name), ContainsPattern(req.Name))
 	}
 	if req.Provider != "" {
 		query = query.Where("provider = ?", req.Provider)
@@ -144,13 +150,16 @@ func (s *LLMConfigService) Create(req *CreateLLMConfigRequest) (*models.LLMConfi
 		Model:       req.Model,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		PromptCache: req.PromptCache,
+		JSONMode:    req.JSONMode,
 		IsDefault:   req.IsDefault,
 		IsActive:    req.IsActive,
+		TenantID:    req.TenantID,
 	}
 
-	// If this is set as default, unset other defaults
+	// If this is set as default, unset other defaults of the tenant
 	if req.IsDefault {
-		s.db.Model(&models.LLMConfig{}).Where("is_default = ?", true).Update("is_default", false)
+		ScopeTenant(s.db.Model(&models.LLMConfig{}), req.TenantID).Where("is_default = ?", true).Update("is_default", false)
 	}
 
 	if err := s.db.Create(&config).Error; err != nil {
@@ -191,10 +200,16 @@ func (s *LLMConfigService) Update(id uint, req *UpdateLLMConfigRequest) (*models
 	if req.Temperature != nil {
 		updates["temperature"] = *req.Temperature
 	}
+	if req.PromptCache != nil {
+		updates["prompt_cache"] = *req.PromptCache
+	}
+	if req.JSONMode != nil {
+		updates["json_mode"] = *req.JSONMode
+	}
 	if req.IsDefault != nil {
 		if *req.IsDefault {
 			// Unset other defaults
-			s.db.Model(&models.LLMConfig{}).Where("is_default = ? AND id != ?", true, id).Update("is_default", false)
+			ScopeTenant(s.db.Model(&models.LLMConfig{}), config.TenantID).Where("is_default = ? AND id != ?", true, id).Update("is_default", false)
 		}
 		updates["is_default"] = *req.IsDefault
 	}
@@ -224,9 +239,9 @@ func (s *LLMConfigService) Delete(id uint) error {
 	return nil
 }
 
-func (s *LLMConfigService) GetActive() ([]models.LLMConfig, error) {
+func (s *LLMConfigService) GetActive(tenantID uint) ([]models.LLMConfig, error) {
 	var configs []models.LLMConfig
-	if err := s.db.Where("is_active = ?", true).Order("is_default DESC, created_at DESC").Find(&configs).Error; err != nil {
+	if err := ScopeTenant(s.db, tenantID).Where("is_active = ?", true).Order("is_default DESC, created_at DESC").Find(&configs).Error; err != nil {
 		return nil, err
 	}
 	for i := range configs {
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// ErrAllLLMsFailed is wrapped by the errors of reviews for which every LLM of the chain failed
+var ErrAllLLMsFailed = errors.New("all LLMs failed")
+
+// LLMOutageStatus tells whether AI reviews are degraded because the whole LLM chain keeps failing
+type LLMOutageStatus struct {
+	Degraded      bool       `json:"degraded"`
+	Since         *time.Time `json:"since,omitempty"` // First failure of the outage
+	Failures      int        `json:"failures"`        // Chain failures since the outage began, or in the window before it
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// llmOutageTracker counts the failures of the whole LLM chain within a window and raises a
+// single alert when they reach the threshold, and another when a review succeeds again
+type llmOutageTracker struct {
+	mu       sync.Mutex
+	failures []time.Time // Chain failures in the window, while not degraded
+	status   LLMOutageStatus
+	now      func() time.Time
+	notify   func(message string)
+}
+
+var llmOutage = &llmOutageTracker{now: time.Now, notify: notifyErrorBots}
+
+// GetLLMOutageStatus returns the current LLM outage status
+func GetLLMOutageStatus() LLMOutageStatus {
+	return llmOutage.snapshot()
+}
+
+func (t *llmOutageTracker) snapshot() LLMOutageStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status
+}
+
+// recordFailure records a failure of the whole chain; threshold 0 disables the alert
+func (t *llmOutageTracker) recordFailure(err error, threshold int, window time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+	t.mu.Lock()
+	now := t.now()
+	t.status.LastError = truncateRunes(err.Error(), 500)
+	t.status.LastFailureAt = &now
+	if t.status.Degraded {
+		t.status.Failures++
+		t.mu.Unlock()
+		return
+	}
+
+	kept := t.failures[:0]
+	for _, at := range t.failures {
+		if now.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+	t.failures = append(kept, now)
+	t.status.Failures = len(t.failures)
+	if len(t.failures) < threshold {
+		t.mu.Unlock()
+		return
+	}
+
+	since := t.failures[0]
+	t.status.Degraded = true
+	t.status.Since = &since
+	t.failures = nil
+	message := fmt.Sprintf(`🚨 **AI Review Degraded**
+
+All configured LLMs failed %d times in %s; reviews are failing until a provider recovers.
+**Last Error**: %s
+**Since**: %s`, t.status.Failures, window, t.status.LastError, since.Format("2006-01-02 15:04:05"))
+	t.mu.Unlock()
+
+	logger.Errorf("[AI] Review degraded: all LLMs failed %d times in %s", threshold, window)
+	LogWarning(context.Background(), "AI", "ReviewDegraded", "All LLMs failed repeatedly, AI review degraded", nil, "", "", map[string]interface{}{
+		"failures":   threshold,
+		"window":     window.String(),
+		"last_error": err.Error(),
+	})
+	go t.notify(message)
+}
+
+// recordSuccess records a review the chain completed, ending an outage
+func (t *llmOutageTracker) recordSuccess() {
+	t.mu.Lock()
+	degraded, since, failures := t.status.Degraded, t.status.Since, t.status.Failures
+	t.failures = nil
+	t.status = LLMOutageStatus{}
+	t.mu.Unlock()
+	if !degraded {
+		return
+	}
+
+	duration := t.now().Sub(*since).Round(time.Second)
+	logger.Infof("[AI] Review recovered after %s (%d failures)", duration, failures)
+	LogInfo(context.Background(), "AI", "ReviewRecovered", fmt.Sprintf("AI review recovered after %s", duration), nil, "", "", map[string]interface{}{
+		"failures": failures,
+	})
+	go t.notify(fmt.Sprintf(`✅ **AI Review Recovered**
+
+LLM reviews are succeeding again after %s and %d failed attempts.`, duration, failures))
+}
+
+// recordLLMChainResult feeds the outcome of a chain of LLM calls to the outage tracker
+func (s *AIService) recordLLMChainResult(err error) {
+	if err == nil {
+		llmOutage.recordSuccess()
+		return
+	}
+	cfg := NewSystemConfigService(s.db).GetLLMOutageConfig()
+	llmOutage.recordFailure(err, cfg.Threshold, time.Duration(cfg.WindowMinutes)*time.Minute)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestOutageTracker(now *time.Time) (*llmOutageTracker, func() []string) {
+	var mu sync.Mutex
+	var sent []string
+	tracker := &llmOutageTracker{
+		now: func() time.Time { return *now },
+		notify: func(message string) {
+			mu.Lock()
+			sent = append(sent, message)
+			mu.Unlock()
+		},
+	}
+	return tracker, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sent...)
+	}
+}
+
+func waitNotified(t *testing.T, sent func() []string, want int) []string {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for len(sent()) < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	messages := sent()
+	if len(messages) != want {
+		t.Fatalf("sent %d notifications, want %d: %q", len(messages), want, messages)
+	}
+	return messages
+}
+
+func TestLLMOutageTracker(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tracker, sent := newTestOutageTracker(&now)
+	failure := errors.New("503 service unavailable")
+
+	// Failures spread beyond the window don't add up
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	now = now.Add(6 * time.Minute)
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	now = now.Add(6 * time.Minute)
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	if status := tracker.snapshot(); status.Degraded || status.Failures != 2 {
+		t.Fatalf("status = %+v, want 2 failures in the window and not degraded", status)
+	}
+
+	now = now.Add(time.Minute)
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	status := tracker.snapshot()
+	if !status.Degraded || status.Since == nil || status.LastError != failure.Error() {
+		t.Fatalf("status = %+v, want degraded", status)
+	}
+
+	// Further failures during the outage are counted without new alerts
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	if status := tracker.snapshot(); status.Failures != 5 {
+		t.Errorf("Failures = %d, want 5", status.Failures)
+	}
+	messages := waitNotified(t, sent, 1)
+	if !strings.Contains(messages[0], "AI Review Degraded") || !strings.Contains(messages[0], failure.Error()) {
+		t.Errorf("alert = %q", messages[0])
+	}
+
+	now = now.Add(30 * time.Minute)
+	tracker.recordSuccess()
+	if status := tracker.snapshot(); status.Degraded || status.Failures != 0 {
+		t.Errorf("status after success = %+v, want reset", status)
+	}
+	messages = waitNotified(t, sent, 2)
+	if !strings.Contains(messages[1], "AI Review Recovered") {
+		t.Errorf("recovery = %q", messages[1])
+	}
+
+	// A success outside an outage only resets the count
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	tracker.recordSuccess()
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	tracker.recordFailure(failure, 3, 10*time.Minute)
+	if status := tracker.snapshot(); status.Degraded {
+		t.Errorf("status = %+v, want not degraded", status)
+	}
+	waitNotified(t, sent, 2)
+}
+
+func TestLLMOutageTrackerDisabled(t *testing.T) {
+	now := time.Now()
+	tracker, sent := newTestOutageTracker(&now)
+	for i := 0; i < 5; i++ {
+		tracker.recordFailure(errors.New("timeout"), 0, time.Minute)
+	}
+	if status := tracker.snapshot(); status.Degraded || status.Failures != 0 {
+		t.Errorf("status = %+v, want untouched when disabled", status)
+	}
+	waitNotified(t, sent, 0)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// llmProbeTimeout bounds the request checking an LLM provider is reachable
+const llmProbeTimeout = 10 * time.Second
+
+// ProbeLLM checks that the provider of an LLM config is reachable and accepts its API key by
+// listing its models, which costs no tokens
+func ProbeLLM(ctx context.Context, cfg *models.LLMConfig) error {
+	req, err := llmProbeRequest(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: llmProbeTimeout, Transport: LLMHTTPClient().Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the API key (status %d)", req.URL.Host, resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
+
+// llmProbeRequest builds the model list request of a provider
+func llmProbeRequest(ctx context.Context, cfg *models.LLMConfig) (*http.Request, error) {
+	base := strings.TrimSuffix(cfg.BaseURL, "/")
+	var url string
+	header := http.Header{}
+	switch cfg.Provider {
+	case "anthropic":
+		if base == "" {
+			base = "https://api.anthropic.com"
+		}
+		url = strings.TrimSuffix(base, "/v1") + "/v1/models"
+		header.Set("x-api-key", cfg.APIKey)
+		header.Set("anthropic-version", "2023-06-01")
+	case "ollama":
+		if base == "" {
+			base = "http://localhost:11434"
+		}
+		url = base + "/api/tags"
+	case "gemini":
+		if base == "" {
+			base = "https://generativelanguage.googleapis.com"
+		}
+		url = base + "/v1beta/models"
+		header.Set("x-goog-api-key", cfg.APIKey)
+	case "azure":
+		if base == "" {
+			return nil, fmt.Errorf("azure config has no base URL")
+		}
+		url = base + "/openai/models?api-version=2024-10-21"
+		header.Set("api-key", cfg.APIKey)
+	default: // openai and the OpenAI-compatible vllm and tgi servers
+		if base == "" {
+			base = "https://api.openai.com/v1"
+		}
+		url = base + "/models"
+		if cfg.APIKey != "" {
+			header.Set("Authorization", "Bearer "+cfg.APIKey)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	req.Header = header
+	return req, nil
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// Log shipping targets
+const (
+	LogShippingSyslog = "syslog"
+	LogShippingLoki   = "loki"
+)
+
+const (
+	// logShippingCacheTTL bounds how long other instances keep shipping with an outdated config
+	logShippingCacheTTL  = 30 * time.Second
+	logShippingQueueSize = 1000
+	logShippingBatchSize = 100
+	logShippingInterval  = 2 * time.Second
+	logShippingTimeout   = 10 * time.Second
+)
+
+// systemLogLevels ranks the system log levels for min_level
+var systemLogLevels = map[string]int{"info": 0, "warning": 1, "error": 2}
+
+// logShipper forwards system logs to an external syslog server or Loki in batches. Shipping
+// failures are only written to the process log, never to system logs, which would loop.
+type logShipper struct {
+	mu       sync.RWMutex
+	cfg      *LogShippingConfigResponse
+	loadedAt time.Time
+
+	start   sync.Once
+	queue   chan models.SystemLog
+	dropped int
+}
+
+var logShipping = &logShipper{
+	queue: make(chan models.SystemLog, logShippingQueueSize),
+}
+
+// shipLog queues a system log for shipping when shipping is enabled for its level
+func shipLog(log *models.SystemLog) {
+	cfg := logShipping.config()
+	if !cfg.Enabled || systemLogLevels[log.Level] < systemLogLevels[cfg.MinLevel] {
+		return
+	}
+	logShipping.start.Do(func() { go logShipping.run() })
+
+	select {
+	case logShipping.queue <- *log:
+	default:
+		logShipping.mu.Lock()
+		logShipping.dropped++
+		logShipping.mu.Unlock()
+	}
+}
+
+func (s *logShipper) config() *LogShippingConfigResponse {
+	s.mu.RLock()
+	if s.cfg != nil && time.Since(s.loadedAt) < logShippingCacheTTL {
+		cfg := s.cfg
+		s.mu.RUnlock()
+		return cfg
+	}
+	s.mu.RUnlock()
+
+	cfg := NewSystemConfigService(globalDB).GetLogShippingConfig()
+	s.mu.Lock()
+	s.cfg = cfg
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return cfg
+}
+
+// invalidateLogShippingConfig makes the next system log reload the shipping config
+func invalidateLogShippingConfig() {
+	logShipping.mu.Lock()
+	logShipping.loadedAt = time.Time{}
+	logShipping.mu.Unlock()
+}
+
+func (s *logShipper) run() {
+	ticker := time.NewTicker(logShippingInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.SystemLog, 0, logShippingBatchSize)
+	for {
+		select {
+		case log := <-s.queue:
+			batch = append(batch, log)
+			if len(batch) < logShippingBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		s.flush(batch)
+		batch = batch[:0]
+	}
+}
+
+func (s *logShipper) flush(batch []models.SystemLog) {
+	s.mu.Lock()
+	cfg, dropped := s.cfg, s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+	if dropped > 0 {
+		logger.Warnf("[LogShipping] Dropped %d system logs, the shipping queue was full", dropped)
+	}
+	if cfg == nil || !cfg.Enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), logShippingTimeout)
+	defer cancel()
+	var err error
+	switch cfg.Type {
+	case LogShippingSyslog:
+		err = sendSyslogBatch(ctx, cfg.Endpoint, batch)
+	case LogShippingLoki:
+		err = sendLokiBatch(ctx, OutboundHTTPClient(logShippingTimeout), cfg, batch)
+	default:
+		err = fmt.Errorf("unsupported log shipping type: %s", cfg.Type)
+	}
+	if err != nil {
+		logger.Errorf("[LogShipping] Failed to ship %d system logs to %s: %v", len(batch), cfg.Type, err)
+	}
+}
+
+// ValidateLogShippingEndpoint checks an endpoint of a log shipping type: a udp:// or tcp://
+// address for syslog, an http(s) base URL for Loki
+func ValidateLogShippingEndpoint(shippingType, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	switch shippingType {
+	case LogShippingSyslog:
+		if u.Scheme != "udp" && u.Scheme != "tcp" {
+			return fmt.Errorf("syslog endpoint must be udp://host:port or tcp://host:port")
+		}
+		if u.Port() == "" {
+			return fmt.Errorf("syslog endpoint must include a port")
+		}
+	case LogShippingLoki:
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("loki endpoint must be an http(s) URL")
+		}
+	default:
+		return fmt.Errorf("unsupported log shipping type: %s", shippingType)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("endpoint must include a host")
+	}
+	return nil
+}
+
+// syslogSeverities maps the system log levels to syslog severities
+var syslogSeverities = map[string]int{"info": 6, "warning": 4, "error": 3}
+
+// syslogFacility is the local0 facility
+const syslogFacility = 16
+
+// formatSyslogLine formats a system log as an RFC 5424 message
+func formatSyslogLine(log *models.SystemLog, hostname string) string {
+	severity, ok := syslogSeverities[log.Level]
+	if !ok {
+		severity = 6
+	}
+	sd := fmt.Sprintf(`[codesentry action="%s"`, escapeSDParam(log.Action))
+	if log.UserID != nil {
+		sd += fmt.Sprintf(` user_id="%d"`, *log.UserID)
+	}
+	if log.IP != "" {
+		sd += fmt.Sprintf(` ip="%s"`, escapeSDParam(log.IP))
+	}
+	sd += "]"
+
+	msg := log.Message
+	if log.Extra != "" {
+		msg += " extra=" + log.Extra
+	}
+	return fmt.Sprintf("<%d>1 %s %s codesentry %d %s %s %s",
+		syslogFacility*8+severity, log.CreatedAt.Format(time.RFC3339Nano), syslogHeaderField(hostname, 255),
+		os.Getpid(), syslogHeaderField(log.Module, 32), sd, strings.ReplaceAll(msg, "\n", " "))
+}
+
+// syslogHeaderField makes a header field printable ASCII without spaces, "-" when empty
+func syslogHeaderField(s string, maxLen int) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+func escapeSDParam(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// sendSyslogBatch writes system logs to a syslog server, one datagram per message over
+// UDP and newline-delimited over TCP
+func sendSyslogBatch(ctx context.Context, endpoint string, logs []models.SystemLog) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, u.Scheme, u.Host)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	hostname, _ := os.Hostname()
+	for i := range logs {
+		line := formatSyslogLine(&logs[i], hostname)
+		if u.Scheme == "tcp" {
+			line += "\n"
+		}
+		if _, err := conn.Write([]byte(line)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lokiPushRequest is the body of the Loki push API
+type lokiPushRequest struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"` // Nanosecond timestamp and log line
+}
+
+// buildLokiPush groups system logs into one stream per level and module
+func buildLokiPush(logs []models.SystemLog) *lokiPushRequest {
+	push := &lokiPushRequest{}
+	streams := make(map[string]int)
+	for _, log := range logs {
+		key := log.Level + "\x00" + log.Module
+		idx, ok := streams[key]
+		if !ok {
+			idx = len(push.Streams)
+			streams[key] = idx
+			push.Streams = append(push.Streams, lokiStream{
+				Stream: map[string]string{"app": "codesentry", "level": log.Level, "module": log.Module},
+			})
+		}
+		line, _ := json.Marshal(log)
+		push.Streams[idx].Values = append(push.Streams[idx].Values,
+			[2]string{strconv.FormatInt(log.CreatedAt.UnixNano(), 10), string(line)})
+	}
+	return push
+}
+
+// sendLokiBatch pushes system logs to Loki
+func sendLokiBatch(ctx context.Context, client *http.Client, cfg *LogShippingConfigResponse, logs []models.SystemLog) error {
+	body, err := json.Marshal(buildLokiPush(logs))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(cfg.Endpoint, "/")+"/loki/api/v1/push", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", cfg.TenantID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("loki returned status %d", resp.StatusCode)
+	}
+	return nil
+}
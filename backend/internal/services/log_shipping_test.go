@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestValidateLogShippingEndpoint(t *testing.T) {
+	tests := []struct {
+		shippingType string
+		endpoint     string
+		wantErr      bool
+	}{
+		{LogShippingSyslog, "udp://syslog.internal:514", false},
+		{LogShippingSyslog, "tcp://10.0.0.5:6514", false},
+		{LogShippingSyslog, "udp://syslog.internal", true},
+		{LogShippingSyslog, "http://syslog.internal:514", true},
+		{LogShippingLoki, "http://loki:3100", false},
+		{LogShippingLoki, "https://logs.example.com/", false},
+		{LogShippingLoki, "tcp://loki:3100", true},
+		{LogShippingLoki, "", true},
+		{"elastic", "http://es:9200", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateLogShippingEndpoint(tt.shippingType, tt.endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLogShippingEndpoint(%q, %q) error = %v, wantErr %v", tt.shippingType, tt.endpoint, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatSyslogLine(t *testing.T) {
+	userID := uint(7)
+	log := &models.SystemLog{
+		Level:     "error",
+		Module:    "Webhook",
+		Action:    `Process "push"`,
+		Message:   "Review failed\nretrying",
+		UserID:    &userID,
+		IP:        "10.0.0.1",
+		Extra:     `{"project_id":3}`,
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	want := fmt.Sprintf(`<131>1 2026-01-02T03:04:05Z web-1 codesentry %d Webhook [codesentry action="Process \"push\"" user_id="7" ip="10.0.0.1"] Review failed retrying extra={"project_id":3}`, os.Getpid())
+	if got := formatSyslogLine(log, "web-1"); got != want {
+		t.Errorf("formatSyslogLine() =\n%s\nwant\n%s", got, want)
+	}
+
+	minimal := formatSyslogLine(&models.SystemLog{Level: "info", Module: "Auth Service", Action: "Login"}, "")
+	if !strings.HasPrefix(minimal, "<134>1 ") || !strings.Contains(minimal, " - codesentry ") || !strings.Contains(minimal, " Auth_Service ") {
+		t.Errorf("formatSyslogLine() = %q, want info priority, nil hostname and sanitized module", minimal)
+	}
+}
+
+func TestSendLokiBatch(t *testing.T) {
+	var got lokiPushRequest
+	var tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("path = %s, want /loki/api/v1/push", r.URL.Path)
+		}
+		tenant = r.Header.Get("X-Scope-OrgID")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode push: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	at := time.Unix(1700000000, 5)
+	logs := []models.SystemLog{
+		{ID: 1, Level: "info", Module: "Auth", Message: "login", CreatedAt: at},
+		{ID: 2, Level: "error", Module: "Webhook", Message: "failed", CreatedAt: at},
+		{ID: 3, Level: "info", Module: "Auth", Message: "logout", CreatedAt: at},
+	}
+	cfg := &LogShippingConfigResponse{Enabled: true, Type: LogShippingLoki, Endpoint: server.URL + "/", TenantID: "ops"}
+	if err := sendLokiBatch(context.Background(), server.Client(), cfg, logs); err != nil {
+		t.Fatalf("sendLokiBatch() error = %v", err)
+	}
+
+	if tenant != "ops" {
+		t.Errorf("X-Scope-OrgID = %q, want ops", tenant)
+	}
+	if len(got.Streams) != 2 {
+		t.Fatalf("streams = %d, want 2", len(got.Streams))
+	}
+	auth := got.Streams[0]
+	if auth.Stream["app"] != "codesentry" || auth.Stream["level"] != "info" || auth.Stream["module"] != "Auth" {
+		t.Errorf("stream labels = %v", auth.Stream)
+	}
+	if len(auth.Values) != 2 || auth.Values[0][0] != "1700000000000000005" || !strings.Contains(auth.Values[1][1], `"message":"logout"`) {
+		t.Errorf("stream values = %v", auth.Values)
+	}
+}
+
+func TestSendLokiBatchReportsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	cfg := &LogShippingConfigResponse{Type: LogShippingLoki, Endpoint: server.URL}
+	err := sendLokiBatch(context.Background(), server.Client(), cfg, []models.SystemLog{{Level: "info", Module: "Auth"}})
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("sendLokiBatch() error = %v, want status 429", err)
+	}
+}
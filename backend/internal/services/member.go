@@ -24,11 +24,14 @@ type MemberListRequest struct {
 	EndDate   string `form:"end_date"`
 	SortBy    string `form:"sort_by"`
 	SortOrder string `form:"sort_order"`
+	TenantID  uint   `form:"-"` // Set from the request context, 0 = all tenants
 }
 
 type MemberStats struct {
 	Author       string  `json:"author"`
 	AuthorEmail  string  `json:"author_email"`
+	AuthorAvatar string  `json:"author_avatar"`
+	AuthorURL    string  `json:"author_url"`
 	CommitCount  int64   `json:"commit_count"`
 	AvgScore     float64 `json:"avg_score"`
 	MaxScore     float64 `json:"max_score"`
@@ -50,6 +53,7 @@ type MemberDetailRequest struct {
 	Author    string `form:"author"`
 	StartDate string `form:"start_date"`
 	EndDate   string `form:"end_date"`
+	TenantID  uint   `form:"-"`
 }
 
 type MemberProjectStats struct {
@@ -73,6 +77,12 @@ type MemberDetailResponse struct {
 	TotalStats   MemberStats          `json:"total_stats"`
 	ProjectStats []MemberProjectStats `json:"project_stats"`
 	Trend        []MemberTrendItem    `json:"trend"`
+	Languages    []LanguageStat       `json:"languages"`
+}
+
+// reviewLogs returns a review_logs query limited to the projects of a tenant
+func (s *MemberService) reviewLogs(tenantID uint) *gorm.DB {
+	return ScopeStatsReviewLogs(s.db.Model(&models.ReviewLog{}), tenantID)
 }
 
 func (s *MemberService) List(req *MemberListRequest) (*MemberListResponse, error) {
@@ -106,12 +116,12 @@ func (s *MemberService) List(req *MemberListRequest) (*MemberListResponse, error
 	}
 
 	var total int64
-	countQuery := s.db.Model(&models.ReviewLog{}).
+	countQuery := s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''")
 
 	if req.Name != "" {
-		countQuery = countQuery.Where("author LIKE ?", "%"+req.Name+"%")
+		countQuery = countQuery.Where(LikeCondition("author"), ContainsPattern(req.Name))
 	}
 	if req.ProjectID != nil {
 		countQuery = countQuery.Where("project_id = ?", *req.ProjectID)
@@ -121,10 +131,12 @@ func (s *MemberService) List(req *MemberListRequest) (*MemberListResponse, error
 
 	var members []MemberStats
 
-	query := s.db.Model(&models.ReviewLog{}).
+	query := s.reviewLogs(req.TenantID).
 		Select(`
 			author,
 			MAX(author_email) as author_email,
+			MAX(author_avatar) as author_avatar,
+			MAX(author_url) as author_url,
 			COUNT(*) as commit_count,
 			COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score,
 			COALESCE(MAX(CASE WHEN is_manual = false THEN score END), 0) as max_score,
@@ -139,7 +151,7 @@ func (s *MemberService) List(req *MemberListRequest) (*MemberListResponse, error
 		Group("author")
 
 	if req.Name != "" {
-		query = query.Where("author LIKE ?", "%"+req.Name+"%")
+		query = query.Where(LikeCondition("author"), ContainsPattern(req.Name))
 	}
 	if req.ProjectID != nil {
 		query = query.Where("project_id = ?", *req.ProjectID)
@@ -190,10 +202,12 @@ func (s *MemberService) GetDetail(req *MemberDetailRequest) (*MemberDetailRespon
 	}
 
 	var totalStats MemberStats
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Select(`
 			author,
 			MAX(author_email) as author_email,
+			MAX(author_avatar) as author_avatar,
+			MAX(author_url) as author_url,
 			COUNT(*) as commit_count,
 			COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score,
 			COALESCE(MAX(CASE WHEN is_manual = false THEN score END), 0) as max_score,
@@ -209,7 +223,7 @@ func (s *MemberService) GetDetail(req *MemberDetailRequest) (*MemberDetailRespon
 		Scan(&totalStats)
 
 	var projectStats []MemberProjectStats
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Select(`
 			project_id,
 			COUNT(*) as commit_count,
@@ -231,7 +245,7 @@ func (s *MemberService) GetDetail(req *MemberDetailRequest) (*MemberDetailRespon
 	}
 
 	var trend []MemberTrendItem
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Select(`
 			DATE(created_at) as date,
 			COUNT(*) as commit_count,
@@ -243,12 +257,23 @@ func (s *MemberService) GetDetail(req *MemberDetailRequest) (*MemberDetailRespon
 		Order("date ASC").
 		Scan(&trend)
 
+	languages, err := NewLanguageStatsService(s.db).Get(&LanguageStatsRequest{
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Author:    req.Author,
+		TenantID:  req.TenantID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return &MemberDetailResponse{
 		Author:       req.Author,
 		AuthorEmail:  totalStats.AuthorEmail,
 		TotalStats:   totalStats,
 		ProjectStats: projectStats,
 		Trend:        trend,
+		Languages:    languages,
 	}, nil
 }
 
@@ -257,6 +282,7 @@ type TeamOverviewRequest struct {
 	StartDate string `form:"start_date"`
 	EndDate   string `form:"end_date"`
 	ProjectID *uint  `form:"project_id"`
+	TenantID  uint   `form:"-"`
 }
 
 type TeamOverviewResponse struct {
@@ -281,6 +307,7 @@ type HeatmapRequest struct {
 	EndDate   string `form:"end_date"`
 	ProjectID *uint  `form:"project_id"`
 	Author    string `form:"author"`
+	TenantID  uint   `form:"-"`
 }
 
 type HeatmapDataPoint struct {
@@ -323,7 +350,7 @@ func (s *MemberService) GetHeatmap(req *HeatmapRequest) (*HeatmapResponse, error
 		endDate = time.Now()
 	}
 
-	query := s.db.Model(&models.ReviewLog{}).
+	query := s.reviewLogs(req.TenantID).
 		Select(`
 			DATE(created_at) as date,
 			COUNT(*) as count,
@@ -342,26 +369,33 @@ func (s *MemberService) GetHeatmap(req *HeatmapRequest) (*HeatmapResponse, error
 		query = query.Where("author = ?", req.Author)
 	}
 
+	// SQLite returns DATE() as a string, MySQL and PostgreSQL as a date
 	var rawData []struct {
-		Date      time.Time
+		Date      string
 		Count     int64
 		Additions int64
 		Deletions int64
 	}
-	query.Scan(&rawData)
+	if err := query.Scan(&rawData).Error; err != nil {
+		return nil, err
+	}
 
 	dataMap := make(map[string]HeatmapDataPoint)
 	var totalCount, maxCount int64
 
 	for _, d := range rawData {
-		dateStr := d.Date.Format("2006-01-02")
-		_, week := d.Date.ISOWeek()
+		dateStr := normalizeDate(d.Date)
+		date, err := time.ParseInLocation("2006-01-02", dateStr, startDate.Location())
+		if err != nil {
+			continue
+		}
+		_, week := date.ISOWeek()
 		point := HeatmapDataPoint{
 			Date:       dateStr,
 			Count:      d.Count,
 			Additions:  d.Additions,
 			Deletions:  d.Deletions,
-			WeekDay:    int(d.Date.Weekday()),
+			WeekDay:    int(date.Weekday()),
 			WeekOfYear: week,
 		}
 		dataMap[dateStr] = point
@@ -421,7 +455,7 @@ func (s *MemberService) GetTeamOverview(req *TeamOverviewRequest) (*TeamOverview
 		endDate = time.Now()
 	}
 
-	baseQuery := s.db.Model(&models.ReviewLog{}).
+	baseQuery := s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''")
 
@@ -436,7 +470,7 @@ func (s *MemberService) GetTeamOverview(req *TeamOverviewRequest) (*TeamOverview
 
 	baseQuery.Distinct("author").Count(&totalMembers)
 
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''").
 		Select("COUNT(*) as total_commits, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score, COALESCE(SUM(additions), 0) as total_additions, COALESCE(SUM(deletions), 0) as total_deletions").
@@ -444,7 +478,7 @@ func (s *MemberService) GetTeamOverview(req *TeamOverviewRequest) (*TeamOverview
 
 	// Team trend
 	var trend []MemberTrendItem
-	trendQuery := s.db.Model(&models.ReviewLog{}).
+	trendQuery := s.reviewLogs(req.TenantID).
 		Select(`DATE(created_at) as date, COUNT(*) as commit_count, COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score`).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''").
@@ -458,10 +492,12 @@ func (s *MemberService) GetTeamOverview(req *TeamOverviewRequest) (*TeamOverview
 
 	// Top 10 members
 	var topMembers []MemberStats
-	topQuery := s.db.Model(&models.ReviewLog{}).
+	topQuery := s.reviewLogs(req.TenantID).
 		Select(`
 			author,
 			MAX(author_email) as author_email,
+			MAX(author_avatar) as author_avatar,
+			MAX(author_url) as author_url,
 			COUNT(*) as commit_count,
 			COALESCE(AVG(CASE WHEN is_manual = false THEN score END), 0) as avg_score,
 			COALESCE(MAX(CASE WHEN is_manual = false THEN score END), 0) as max_score,
@@ -485,21 +521,21 @@ func (s *MemberService) GetTeamOverview(req *TeamOverviewRequest) (*TeamOverview
 	// Score distribution (exclude manual records)
 	var excellent, good, needWork int64
 
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''").
 		Where("is_manual = false").
 		Where("score >= 80").
 		Distinct("author").Count(&excellent)
 
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''").
 		Where("is_manual = false").
 		Where("score >= 60 AND score < 80").
 		Distinct("author").Count(&good)
 
-	s.db.Model(&models.ReviewLog{}).
+	s.reviewLogs(req.TenantID).
 		Where("created_at BETWEEN ? AND ?", startDate, endDate).
 		Where("author != ''").
 		Where("is_manual = false").
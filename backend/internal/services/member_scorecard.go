@@ -0,0 +1,360 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scorecard KPI keys
+const (
+	KPICommits        = "commits"
+	KPIAvgScore       = "avg_score"
+	KPIPassRate       = "pass_rate"
+	KPIGatingFailures = "gating_failures"
+	KPIRecoveryHours  = "recovery_hours"
+	KPIFindings       = "findings"
+)
+
+// scorecardKPIHigherIsBetter lists the known KPIs and whether a higher value meets the target
+var scorecardKPIHigherIsBetter = map[string]bool{
+	KPICommits:        true,
+	KPIAvgScore:       true,
+	KPIPassRate:       true,
+	KPIGatingFailures: false,
+	KPIRecoveryHours:  false,
+	KPIFindings:       false,
+}
+
+// ScorecardKPI is a KPI shown on member scorecards, optionally with a target value
+type ScorecardKPI struct {
+	Key    string   `json:"key"`
+	Target *float64 `json:"target,omitempty"`
+}
+
+// DefaultScorecardKPIs are used when no KPIs are configured
+var DefaultScorecardKPIs = []ScorecardKPI{
+	{Key: KPICommits},
+	{Key: KPIAvgScore},
+	{Key: KPIPassRate},
+	{Key: KPIGatingFailures},
+	{Key: KPIRecoveryHours},
+	{Key: KPIFindings},
+}
+
+// ParseScorecardKPIs decodes configured KPIs, falling back to the defaults when empty
+func ParseScorecardKPIs(raw string) ([]ScorecardKPI, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultScorecardKPIs, nil
+	}
+	var kpis []ScorecardKPI
+	if err := json.Unmarshal([]byte(raw), &kpis); err != nil {
+		return nil, fmt.Errorf("invalid scorecard KPIs: %w", err)
+	}
+	if err := ValidateScorecardKPIs(kpis); err != nil {
+		return nil, err
+	}
+	return kpis, nil
+}
+
+// ValidateScorecardKPIs checks that every KPI is known and listed once
+func ValidateScorecardKPIs(kpis []ScorecardKPI) error {
+	seen := make(map[string]bool, len(kpis))
+	for _, kpi := range kpis {
+		if _, ok := scorecardKPIHigherIsBetter[kpi.Key]; !ok {
+			return fmt.Errorf("unknown scorecard KPI %q", kpi.Key)
+		}
+		if seen[kpi.Key] {
+			return fmt.Errorf("scorecard KPI %q is listed twice", kpi.Key)
+		}
+		seen[kpi.Key] = true
+	}
+	return nil
+}
+
+type MemberScorecardRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	Author     string `form:"author"` // Limit to one member
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	Format     string `form:"format" binding:"omitempty,oneof=json csv pdf"`
+	TenantID   uint   `form:"-"` // Set from the request context, 0 = all tenants
+}
+
+type ScorecardKPIValue struct {
+	Key    string   `json:"key"`
+	Value  float64  `json:"value"`
+	Target *float64 `json:"target,omitempty"`
+	Met    *bool    `json:"met,omitempty"` // Only set when the KPI has a target
+}
+
+type FindingCategoryCount struct {
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+type MemberScorecard struct {
+	Author         string                 `json:"author"`
+	AuthorEmail    string                 `json:"author_email"`
+	Commits        int64                  `json:"commits"`
+	AvgScore       float64                `json:"avg_score"`
+	PassRate       float64                `json:"pass_rate"`
+	GatingFailures int64                  `json:"gating_failures"` // Scored reviews below the minimum score
+	Recovered      int64                  `json:"recovered"`       // Gating failures fixed by a later passing review on the same branch
+	RecoveryHours  float64                `json:"recovery_hours"`  // Average hours from a gating failure to its fix
+	Findings       int64                  `json:"findings"`
+	TopCategories  []FindingCategoryCount `json:"top_categories"`
+	KPIs           []ScorecardKPIValue    `json:"kpis"`
+}
+
+type MemberScorecardResponse struct {
+	StartDate string            `json:"start_date"`
+	EndDate   string            `json:"end_date"`
+	KPIs      []ScorecardKPI    `json:"kpis"`
+	Items     []MemberScorecard `json:"items"`
+}
+
+// scorecardReview is the part of a review log scorecards are computed from
+type scorecardReview struct {
+	Author      string
+	AuthorEmail string
+	ProjectID   uint
+	Branch      string
+	Score       *float64
+	MinScore    float64 // Effective minimum score of the project
+	CreatedAt   time.Time
+}
+
+// GetScorecards returns a scorecard per member for the period, members with the most commits first
+func (s *MemberService) GetScorecards(req *MemberScorecardRequest) (*MemberScorecardResponse, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 30)
+	projectIDs := parseProjectIDs(req.ProjectID, req.ProjectIDs)
+	kpis := NewSystemConfigService(s.db).GetScorecardConfig().KPIs
+	globalMin := NewDashboardService(s.db).globalMinScore()
+
+	query := s.reviewLogs(req.TenantID).
+		Select("review_logs.author, review_logs.author_email, review_logs.project_id, review_logs.branch, review_logs.score, review_logs.created_at, "+
+			"CASE WHEN projects.min_score > 0 THEN projects.min_score ELSE ? END as min_score", globalMin).
+		Joins("LEFT JOIN projects ON projects.id = review_logs.project_id").
+		Where("review_logs.created_at BETWEEN ? AND ? AND review_logs.is_manual = ? AND review_logs.author != ''", startDate, endDate, false).
+		Order("review_logs.created_at ASC")
+	if req.Author != "" {
+		query = query.Where("review_logs.author = ?", req.Author)
+	}
+	if len(projectIDs) > 0 {
+		query = query.Where("review_logs.project_id IN ?", projectIDs)
+	}
+	var reviews []scorecardReview
+	if err := query.Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+
+	var findings []struct {
+		Author   string
+		Category string
+		Count    int64
+	}
+	findingQuery := NewReviewFindingService(s.db).scopeFindings(startDate, endDate, projectIDs, "", req.TenantID).
+		Select("author, category, COUNT(*) as count").
+		Group("author, category")
+	if req.Author != "" {
+		findingQuery = findingQuery.Where("author = ?", req.Author)
+	}
+	if err := findingQuery.Scan(&findings).Error; err != nil {
+		return nil, err
+	}
+	findingCounts := make(map[string]map[string]int64)
+	for _, f := range findings {
+		if findingCounts[f.Author] == nil {
+			findingCounts[f.Author] = make(map[string]int64)
+		}
+		findingCounts[f.Author][f.Category] += f.Count
+	}
+
+	return &MemberScorecardResponse{
+		StartDate: startDate.Format("2006-01-02"),
+		EndDate:   endDate.Format("2006-01-02"),
+		KPIs:      kpis,
+		Items:     buildScorecards(reviews, findingCounts, kpis),
+	}, nil
+}
+
+// buildScorecards aggregates reviews ordered by creation time into member scorecards
+func buildScorecards(reviews []scorecardReview, findingCounts map[string]map[string]int64, kpis []ScorecardKPI) []MemberScorecard {
+	type branchKey struct {
+		projectID uint
+		branch    string
+	}
+	type accumulator struct {
+		card          *MemberScorecard
+		scored        int64
+		scoreSum      float64
+		recoveryHours float64
+		// Unfixed gating failures per branch, oldest first
+		failures map[branchKey][]time.Time
+	}
+
+	byAuthor := make(map[string]*accumulator)
+	var order []string
+	for _, r := range reviews {
+		acc, ok := byAuthor[r.Author]
+		if !ok {
+			acc = &accumulator{card: &MemberScorecard{Author: r.Author}, failures: make(map[branchKey][]time.Time)}
+			byAuthor[r.Author] = acc
+			order = append(order, r.Author)
+		}
+		card := acc.card
+		if r.AuthorEmail != "" {
+			card.AuthorEmail = r.AuthorEmail
+		}
+		card.Commits++
+		if r.Score == nil {
+			continue
+		}
+
+		acc.scored++
+		acc.scoreSum += *r.Score
+		key := branchKey{r.ProjectID, r.Branch}
+		if *r.Score < r.MinScore {
+			card.GatingFailures++
+			acc.failures[key] = append(acc.failures[key], r.CreatedAt)
+			continue
+		}
+		// A passing review fixes every earlier failure on the same branch
+		for _, failedAt := range acc.failures[key] {
+			card.Recovered++
+			acc.recoveryHours += r.CreatedAt.Sub(failedAt).Hours()
+		}
+		delete(acc.failures, key)
+	}
+
+	for author, counts := range findingCounts {
+		if _, ok := byAuthor[author]; !ok {
+			byAuthor[author] = &accumulator{card: &MemberScorecard{Author: author}}
+			order = append(order, author)
+		}
+		card := byAuthor[author].card
+		for category, count := range counts {
+			card.Findings += count
+			card.TopCategories = append(card.TopCategories, FindingCategoryCount{Category: category, Count: count})
+		}
+		sort.Slice(card.TopCategories, func(i, j int) bool {
+			if card.TopCategories[i].Count != card.TopCategories[j].Count {
+				return card.TopCategories[i].Count > card.TopCategories[j].Count
+			}
+			return card.TopCategories[i].Category < card.TopCategories[j].Category
+		})
+		if len(card.TopCategories) > 3 {
+			card.TopCategories = card.TopCategories[:3]
+		}
+	}
+
+	cards := make([]MemberScorecard, 0, len(order))
+	for _, author := range order {
+		acc := byAuthor[author]
+		card := acc.card
+		if acc.scored > 0 {
+			card.AvgScore = acc.scoreSum / float64(acc.scored)
+			card.PassRate = float64(acc.scored-card.GatingFailures) / float64(acc.scored) * 100
+		}
+		if card.Recovered > 0 {
+			card.RecoveryHours = acc.recoveryHours / float64(card.Recovered)
+		}
+		if card.TopCategories == nil {
+			card.TopCategories = []FindingCategoryCount{}
+		}
+		card.KPIs = scorecardKPIValues(card, kpis)
+		cards = append(cards, *card)
+	}
+	sort.SliceStable(cards, func(i, j int) bool {
+		if cards[i].Commits != cards[j].Commits {
+			return cards[i].Commits > cards[j].Commits
+		}
+		return cards[i].Author < cards[j].Author
+	})
+	return cards
+}
+
+// scorecardKPIValues evaluates the configured KPIs of a scorecard against their targets
+func scorecardKPIValues(card *MemberScorecard, kpis []ScorecardKPI) []ScorecardKPIValue {
+	values := make([]ScorecardKPIValue, 0, len(kpis))
+	for _, kpi := range kpis {
+		v := ScorecardKPIValue{Key: kpi.Key, Value: card.kpiValue(kpi.Key), Target: kpi.Target}
+		if kpi.Target != nil {
+			met := v.Value >= *kpi.Target
+			if !scorecardKPIHigherIsBetter[kpi.Key] {
+				met = v.Value <= *kpi.Target
+			}
+			v.Met = &met
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+func (card *MemberScorecard) kpiValue(key string) float64 {
+	switch key {
+	case KPICommits:
+		return float64(card.Commits)
+	case KPIAvgScore:
+		return card.AvgScore
+	case KPIPassRate:
+		return card.PassRate
+	case KPIGatingFailures:
+		return float64(card.GatingFailures)
+	case KPIRecoveryHours:
+		return card.RecoveryHours
+	case KPIFindings:
+		return float64(card.Findings)
+	}
+	return 0
+}
+
+// RenderScorecardsPDF renders member scorecards as a PDF document, a section per member
+// with its KPIs against their targets and its most flagged finding categories
+func RenderScorecardsPDF(result *MemberScorecardResponse) []byte {
+	accent := parsePDFColor(ReviewReportColor, pdfBlack)
+	d := newPDFDocument(fmt.Sprintf("%s Member Scorecards %s - %s", ReviewReportBrand, result.StartDate, result.EndDate),
+		fmt.Sprintf("%s - generated %s", ReviewReportBrand, time.Now().Format("2006-01-02 15:04 MST")))
+	d.rect(0, pdfPageHeight-8, pdfPageWidth, 8, accent)
+	d.paragraph(pdfFontBold, 10, accent, 0, strings.ToUpper(ReviewReportBrand))
+	d.paragraph(pdfFontBold, 20, pdfBlack, 0, "Member Scorecards")
+	d.paragraph(pdfFontRegular, 11, pdfGrey, 0, result.StartDate+" to "+result.EndDate)
+
+	if len(result.Items) == 0 {
+		d.space(14)
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, "No reviews in this period.")
+	}
+	for _, card := range result.Items {
+		title := card.Author
+		if card.AuthorEmail != "" {
+			title += " <" + card.AuthorEmail + ">"
+		}
+		d.heading(title, accent)
+		for _, kpi := range card.KPIs {
+			value := strconv.FormatFloat(math.Round(kpi.Value*100)/100, 'f', -1, 64)
+			if kpi.Target != nil {
+				status := "missed"
+				if kpi.Met != nil && *kpi.Met {
+					status = "met"
+				}
+				value += fmt.Sprintf(" (target %s, %s)", strconv.FormatFloat(*kpi.Target, 'f', -1, 64), status)
+			}
+			d.row(kpi.Key, value)
+		}
+		categories := make([]string, 0, len(card.TopCategories))
+		for _, tc := range card.TopCategories {
+			categories = append(categories, fmt.Sprintf("%s (%d)", tc.Category, tc.Count))
+		}
+		if len(categories) == 0 {
+			categories = append(categories, "None")
+		}
+		d.row("Top categories", strings.Join(categories, ", "))
+	}
+	return d.Bytes()
+}
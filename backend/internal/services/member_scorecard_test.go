@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBuildScorecards(t *testing.T) {
+	base := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	score := func(v float64) *float64 { return &v }
+	reviews := []scorecardReview{
+		{Author: "alice", AuthorEmail: "alice@example.com", ProjectID: 1, Branch: "main", Score: score(50), MinScore: 60, CreatedAt: base},
+		{Author: "bob", ProjectID: 1, Branch: "main", Score: score(90), MinScore: 60, CreatedAt: base.Add(time.Hour)},
+		{Author: "alice", ProjectID: 1, Branch: "feature", Score: score(40), MinScore: 60, CreatedAt: base.Add(2 * time.Hour)},
+		{Author: "alice", ProjectID: 1, Branch: "main", Score: score(80), MinScore: 60, CreatedAt: base.Add(4 * time.Hour)},
+		{Author: "alice", ProjectID: 2, Branch: "main", Score: nil, MinScore: 60, CreatedAt: base.Add(5 * time.Hour)},
+	}
+	findings := map[string]map[string]int64{
+		"alice": {FindingSecurity: 2, FindingStyle: 5, FindingTesting: 1, FindingOther: 1},
+		"carol": {FindingPerformance: 1},
+	}
+	target := 70.0
+	kpis := []ScorecardKPI{{Key: KPIAvgScore, Target: &target}, {Key: KPIGatingFailures}}
+
+	cards := buildScorecards(reviews, findings, kpis)
+	if len(cards) != 3 || cards[0].Author != "alice" || cards[1].Author != "bob" || cards[2].Author != "carol" {
+		t.Fatalf("unexpected scorecards order: %+v", cards)
+	}
+
+	alice := cards[0]
+	if alice.Commits != 4 || alice.GatingFailures != 2 || alice.AuthorEmail != "alice@example.com" {
+		t.Errorf("alice = %+v", alice)
+	}
+	if alice.AvgScore != float64(50+40+80)/3 {
+		t.Errorf("alice avg score = %v", alice.AvgScore)
+	}
+	// Only the failure on main was fixed, four hours later
+	if alice.Recovered != 1 || alice.RecoveryHours != 4 {
+		t.Errorf("alice recovery = %d, %v hours", alice.Recovered, alice.RecoveryHours)
+	}
+	if alice.Findings != 9 {
+		t.Errorf("alice findings = %d", alice.Findings)
+	}
+	wantTop := []FindingCategoryCount{{FindingStyle, 5}, {FindingSecurity, 2}, {FindingOther, 1}}
+	if !reflect.DeepEqual(alice.TopCategories, wantTop) {
+		t.Errorf("alice top categories = %v, want %v", alice.TopCategories, wantTop)
+	}
+	if len(alice.KPIs) != 2 || alice.KPIs[0].Met == nil || *alice.KPIs[0].Met || alice.KPIs[1].Met != nil {
+		t.Errorf("alice KPIs = %+v", alice.KPIs)
+	}
+
+	bob := cards[1]
+	if bob.PassRate != 100 || !*bob.KPIs[0].Met || len(bob.TopCategories) != 0 {
+		t.Errorf("bob = %+v", bob)
+	}
+}
+
+func TestScorecardKPITargets(t *testing.T) {
+	target := 2.0
+	card := &MemberScorecard{GatingFailures: 3, Findings: 1}
+	values := scorecardKPIValues(card, []ScorecardKPI{{Key: KPIGatingFailures, Target: &target}, {Key: KPIFindings, Target: &target}})
+	if *values[0].Met || !*values[1].Met {
+		t.Errorf("lower-is-better KPIs evaluated wrongly: %+v", values)
+	}
+}
+
+func TestParseScorecardKPIs(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr bool
+		wantLen int
+	}{
+		{"empty uses defaults", "", false, len(DefaultScorecardKPIs)},
+		{"custom", `[{"key":"avg_score","target":75},{"key":"commits"}]`, false, 2},
+		{"unknown kpi", `[{"key":"velocity"}]`, true, 0},
+		{"duplicate kpi", `[{"key":"commits"},{"key":"commits"}]`, true, 0},
+		{"invalid json", `{`, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kpis, err := ParseScorecardKPIs(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseScorecardKPIs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(kpis) != tt.wantLen {
+				t.Errorf("ParseScorecardKPIs() returned %d KPIs, want %d", len(kpis), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestRenderScorecardsPDF(t *testing.T) {
+	target, met := 80.0, false
+	result := &MemberScorecardResponse{
+		StartDate: "2026-03-01",
+		EndDate:   "2026-03-31",
+		Items: []MemberScorecard{{
+			Author:        "alice",
+			AuthorEmail:   "alice@example.com",
+			TopCategories: []FindingCategoryCount{{Category: "security", Count: 3}},
+			KPIs: []ScorecardKPIValue{
+				{Key: KPICommits, Value: 12},
+				{Key: KPIAvgScore, Value: 76.456, Target: &target, Met: &met},
+			},
+		}},
+	}
+
+	pdf := RenderScorecardsPDF(result)
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Fatal("RenderScorecardsPDF() did not render a PDF")
+	}
+	for _, want := range []string{"Member Scorecards", "alice <alice@example.com>", "76.46 \\(target 80, missed\\)", "security \\(3\\)"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF missing %q", want)
+		}
+	}
+}
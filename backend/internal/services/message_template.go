@@ -0,0 +1,317 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// MessageFields lists the fields a bot can show in the built-in notification layout.
+var MessageFields = []string{"project", "event", "branch", "author", "commit", "score", "result", "mr_url"}
+
+// MessageFieldSummary shows the top findings of the review, a compact alternative to the full
+// result for cards and mobile clients. It is only shown when listed in the bot's fields.
+const MessageFieldSummary = "summary"
+
+// MessageTemplateData is the data exposed to per-bot message templates.
+type MessageTemplateData struct {
+	ProjectName   string
+	Branch        string
+	Author        string
+	CommitMessage string
+	Score         float64
+	ScoreEmoji    string
+	ReviewResult  string
+	EventType     string
+	EventTypeText string
+	MRURL         string
+	Summary       string // Top findings of the review
+}
+
+// messagePlaceholders maps simple {{placeholder}} names to Go template actions.
+var messagePlaceholders = map[string]string{
+	"project_name":   "{{.ProjectName}}",
+	"branch":         "{{.Branch}}",
+	"author":         "{{.Author}}",
+	"commit_message": "{{.CommitMessage}}",
+	"score":          `{{printf "%.0f" .Score}}`,
+	"score_emoji":    "{{.ScoreEmoji}}",
+	"review_result":  "{{.ReviewResult}}",
+	"event_type":     "{{.EventTypeText}}",
+	"mr_url":         "{{.MRURL}}",
+	"summary":        "{{.Summary}}",
+}
+
+var messagePlaceholderRegex = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+var messageLabels = map[string]map[string]string{
+	"en": {
+		"title":         "📋 **Code Review Report**",
+		"project":       "Project",
+		"event":         "Event",
+		"branch":        "Branch",
+		"author":        "Author",
+		"commit":        "Commit",
+		"score":         "Score",
+		"mr_url":        "View MR/PR",
+		"summary":       "Top findings",
+		"summary_more":  "...and %d more",
+		"no_findings":   "No issues found",
+		"push":          "Push",
+		"merge_request": "Merge Request",
+		"release":       "Release",
+		"card_title":    "Code Review: %s",
+		"view_review":   "View Review",
+		"retry":         "Retry",
+	},
+	"zh": {
+		"title":         "📋 **代码审查报告**",
+		"project":       "项目",
+		"event":         "事件",
+		"branch":        "分支",
+		"author":        "作者",
+		"commit":        "提交",
+		"score":         "评分",
+		"mr_url":        "查看 MR/PR",
+		"summary":       "主要问题",
+		"summary_more":  "……另有 %d 个问题",
+		"no_findings":   "未发现问题",
+		"push":          "推送",
+		"merge_request": "合并请求",
+		"release":       "发布",
+		"card_title":    "代码审查：%s",
+		"view_review":   "查看审查",
+		"retry":         "重新审查",
+	},
+}
+
+var messageTemplateFuncs = template.FuncMap{
+	"truncate": func(n int, s string) string { return truncateRunes(s, n) },
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+}
+
+func newMessageTemplateData(n *ReviewNotification, lang string) *MessageTemplateData {
+	labels := messageLabelsFor(lang)
+	eventTypeText := labels["push"]
+	switch n.EventType {
+	case "merge_request", EventTypeRelease:
+		eventTypeText = labels[n.EventType]
+	}
+	findings := summaryFindings(n.ReviewResult)
+	summary := labels["no_findings"]
+	if len(findings) > 0 {
+		summary = formatTopFindings(topFindings(findings, ReviewSummaryMaxFindings), len(findings), labels["summary_more"])
+	}
+	return &MessageTemplateData{
+		ProjectName:   n.ProjectName,
+		Branch:        n.Branch,
+		Author:        n.Author,
+		CommitMessage: n.CommitMessage,
+		Score:         n.Score,
+		ScoreEmoji:    scoreEmoji(n.Score),
+		ReviewResult:  n.ReviewResult,
+		EventType:     n.EventType,
+		EventTypeText: eventTypeText,
+		MRURL:         n.MRURL,
+		Summary:       summary,
+	}
+}
+
+func messageLabelsFor(lang string) map[string]string {
+	if labels, ok := messageLabels[lang]; ok {
+		return labels
+	}
+	return messageLabels["en"]
+}
+
+func scoreEmoji(score float64) string {
+	if score < 60 {
+		return "🔴"
+	} else if score < 80 {
+		return "🟡"
+	}
+	return "🟢"
+}
+
+// parseMessageTemplate expands simple placeholders and parses the result as a Go template.
+func parseMessageTemplate(tpl string) (*template.Template, error) {
+	expanded := messagePlaceholderRegex.ReplaceAllStringFunc(tpl, func(m string) string {
+		name := messagePlaceholderRegex.FindStringSubmatch(m)[1]
+		if action, ok := messagePlaceholders[name]; ok {
+			return action
+		}
+		return m
+	})
+	return template.New("message").Funcs(messageTemplateFuncs).Option("missingkey=zero").Parse(expanded)
+}
+
+// ValidateMessageTemplate checks that a bot message template parses and renders with sample data.
+func ValidateMessageTemplate(tpl string) error {
+	if strings.TrimSpace(tpl) == "" {
+		return nil
+	}
+	_, err := renderMessageTemplate(tpl, sampleReviewNotification(), "en")
+	return err
+}
+
+// ValidateMessageFields checks that a comma-separated field list only contains known fields.
+func ValidateMessageFields(fields string) error {
+	for _, f := range splitAndTrim(fields, ",") {
+		known := f == MessageFieldSummary
+		for _, mf := range MessageFields {
+			if f == mf {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown message field: %s", f)
+		}
+	}
+	return nil
+}
+
+func renderMessageTemplate(tpl string, n *ReviewNotification, lang string) (string, error) {
+	t, err := parseMessageTemplate(tpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newMessageTemplateData(n, lang)); err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// buildMessageWithFields renders the built-in layout limited to the given fields and language.
+func buildMessageWithFields(n *ReviewNotification, fields []string, lang string) string {
+	show := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		show[f] = true
+	}
+	labels := messageLabelsFor(lang)
+	data := newMessageTemplateData(n, lang)
+
+	var sb strings.Builder
+	sb.WriteString(labels["title"])
+	sb.WriteString("\n")
+
+	meta := []struct{ key, value string }{
+		{"project", data.ProjectName},
+		{"event", data.EventTypeText},
+		{"branch", data.Branch},
+		{"author", data.Author},
+		{"commit", truncateRunes(data.CommitMessage, 100)},
+	}
+	wroteMeta := false
+	for _, m := range meta {
+		if !show[m.key] {
+			continue
+		}
+		if !wroteMeta {
+			sb.WriteString("\n")
+			wroteMeta = true
+		}
+		fmt.Fprintf(&sb, "**%s**: %s\n", labels[m.key], m.value)
+	}
+
+	if show["score"] {
+		fmt.Fprintf(&sb, "\n%s **%s**: %.0f/100\n", data.ScoreEmoji, labels["score"], data.Score)
+	}
+	if show[MessageFieldSummary] {
+		fmt.Fprintf(&sb, "\n**%s**:\n%s\n", labels["summary"], data.Summary)
+	}
+	if show["result"] && data.ReviewResult != "" {
+		sb.WriteString("\n---\n")
+		sb.WriteString(data.ReviewResult)
+	}
+	if show["mr_url"] && data.MRURL != "" {
+		fmt.Fprintf(&sb, "\n\n🔗 [%s](%s)", labels["mr_url"], data.MRURL)
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// hasCustomMessageFormat reports whether the bot overrides the built-in message format.
+func hasCustomMessageFormat(bot *models.IMBot) bool {
+	if bot == nil {
+		return false
+	}
+	return strings.TrimSpace(bot.MessageTemplate) != "" ||
+		strings.TrimSpace(bot.MessageFields) != "" ||
+		(bot.MessageLanguage != "" && bot.MessageLanguage != "en") ||
+		bot.MessageMaxLength > 0
+}
+
+// renderBotMessage builds the notification text for a bot, honoring its template settings.
+// Falls back to the built-in format when the bot has no template or the template fails.
+func renderBotMessage(bot *models.IMBot, n *ReviewNotification) string {
+	if !hasCustomMessageFormat(bot) {
+		return buildMessage(n)
+	}
+
+	var msg string
+	if strings.TrimSpace(bot.MessageTemplate) != "" {
+		rendered, err := renderMessageTemplate(bot.MessageTemplate, n, bot.MessageLanguage)
+		if err != nil {
+			logger.Warnf("[Notification] Bot %d template error, using default format: %v", bot.ID, err)
+		} else {
+			msg = rendered
+		}
+	}
+	if msg == "" {
+		fields := MessageFields
+		if strings.TrimSpace(bot.MessageFields) != "" {
+			fields = splitAndTrim(bot.MessageFields, ",")
+		}
+		msg = buildMessageWithFields(n, fields, bot.MessageLanguage)
+	}
+
+	if bot.MessageMaxLength > 0 {
+		msg = truncateRunes(msg, bot.MessageMaxLength)
+	}
+	return msg
+}
+
+// truncateRunes shortens s to at most max runes, appending "..." when truncated.
+func truncateRunes(s string, max int) string {
+	if max <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if max <= 3 {
+		return string(runes[:max])
+	}
+	return string(runes[:max-3]) + "..."
+}
+
+// sampleReviewNotification returns fixed data used for template previews and test sends.
+// sampleReportMessage returns a daily report with sample statistics
+func sampleReportMessage() string {
+	stats := ReportStats{TotalProjects: 3, TotalCommits: 12, TotalAuthors: 4, AverageScore: 82.5, PassedCount: 10, FailedCount: 2}
+	topProjects := []ProjectStat{{Name: "example/project", CommitCount: 8, AvgScore: 85}}
+	lowScores := []LowScoreReview{{Project: "example/project", Author: "octocat", Score: 45}}
+	return new(DailyReportService).buildDefaultSummary("daily", stats, topProjects, nil, lowScores)
+}
+
+func sampleReviewNotification() *ReviewNotification {
+	return &ReviewNotification{
+		ProjectName:   "example/project",
+		Branch:        "main",
+		Author:        "octocat",
+		CommitMessage: "feat: add sample feature",
+		Score:         85,
+		ReviewResult:  "### Key Issues\n1. Consider handling the error returned by `Close()`.\n\nTotal Score: 85/100",
+		EventType:     "merge_request",
+		MRURL:         "https://git.example.com/example/project/-/merge_requests/1",
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestRenderBotMessage(t *testing.T) {
+	n := &ReviewNotification{
+		ProjectName:   "TestProject",
+		Branch:        "main",
+		Author:        "john",
+		CommitMessage: "feat: add feature",
+		Score:         55,
+		ReviewResult:  "Needs work",
+		EventType:     "merge_request",
+		MRURL:         "https://gitlab.com/test/mr/1",
+	}
+
+	tests := []struct {
+		name             string
+		bot              *models.IMBot
+		shouldContain    []string
+		shouldNotContain []string
+	}{
+		{
+			name:          "no customization uses default format",
+			bot:           &models.IMBot{},
+			shouldContain: []string{"Code Review Report", "TestProject", "55/100", "View MR/PR"},
+		},
+		{
+			name:             "simple placeholders",
+			bot:              &models.IMBot{MessageTemplate: "{{project_name}}@{{branch}} by {{author}}: {{score_emoji}} {{score}}"},
+			shouldContain:    []string{"TestProject@main by john: 🔴 55"},
+			shouldNotContain: []string{"Code Review Report"},
+		},
+		{
+			name:          "go template syntax",
+			bot:           &models.IMBot{MessageTemplate: `{{.ProjectName}}{{if .MRURL}} <{{.MRURL}}>{{end}} {{upper .Author}}`},
+			shouldContain: []string{"TestProject <https://gitlab.com/test/mr/1> JOHN"},
+		},
+		{
+			name:             "field selection",
+			bot:              &models.IMBot{MessageFields: "project,score"},
+			shouldContain:    []string{"TestProject", "55/100"},
+			shouldNotContain: []string{"john", "Needs work", "View MR/PR"},
+		},
+		{
+			name:             "summary field",
+			bot:              &models.IMBot{MessageFields: "project,score,summary"},
+			shouldContain:    []string{"TestProject", "**Top findings**:\nNo issues found"},
+			shouldNotContain: []string{"Needs work"},
+		},
+		{
+			name:          "summary placeholder",
+			bot:           &models.IMBot{MessageTemplate: "{{project_name}}: {{summary}}"},
+			shouldContain: []string{"TestProject: No issues found"},
+		},
+		{
+			name:          "chinese labels",
+			bot:           &models.IMBot{MessageLanguage: "zh"},
+			shouldContain: []string{"代码审查报告", "合并请求", "评分"},
+		},
+		{
+			name:          "invalid template falls back to built-in format",
+			bot:           &models.IMBot{MessageTemplate: "{{.ProjectName"},
+			shouldContain: []string{"Code Review Report", "TestProject"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := renderBotMessage(tt.bot, n)
+			for _, s := range tt.shouldContain {
+				if !strings.Contains(msg, s) {
+					t.Errorf("renderBotMessage() should contain %q, got:\n%s", s, msg)
+				}
+			}
+			for _, s := range tt.shouldNotContain {
+				if strings.Contains(msg, s) {
+					t.Errorf("renderBotMessage() should not contain %q, got:\n%s", s, msg)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderBotMessage_MaxLength(t *testing.T) {
+	bot := &models.IMBot{MessageMaxLength: 20}
+	n := &ReviewNotification{ProjectName: "p", ReviewResult: strings.Repeat("x", 500)}
+
+	msg := renderBotMessage(bot, n)
+	if len([]rune(msg)) != 20 {
+		t.Errorf("expected 20 runes, got %d", len([]rune(msg)))
+	}
+	if !strings.HasSuffix(msg, "...") {
+		t.Errorf("truncated message should end with ..., got %q", msg)
+	}
+}
+
+func TestValidateMessageTemplate(t *testing.T) {
+	tests := []struct {
+		tpl     string
+		wantErr bool
+	}{
+		{"", false},
+		{"{{project_name}} {{score}}", false},
+		{"{{.ProjectName}} {{truncate 10 .ReviewResult}}", false},
+		{"{{.ProjectName", true},
+		{"{{.Unknown}}", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateMessageTemplate(tt.tpl)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateMessageTemplate(%q) error = %v, wantErr %v", tt.tpl, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateMessageFields(t *testing.T) {
+	if err := ValidateMessageFields("project, score,mr_url,summary"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateMessageFields("project,unknown"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
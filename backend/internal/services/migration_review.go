@@ -0,0 +1,154 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Migration policies of a project
+const (
+	MigrationPolicyOff         = "off"         // Migrations are reviewed like any other code
+	MigrationPolicyReview      = "review"      // Migrations get the migration checklist and destructive statements are listed (default)
+	MigrationPolicyAcknowledge = "acknowledge" // Destructive statements also hold the commit status until acknowledged
+)
+
+// Migration acknowledgment states of a review log
+const (
+	MigrationAckPending      = "pending"
+	MigrationAckAcknowledged = "acknowledged"
+)
+
+// ValidMigrationPolicy reports whether policy is a known migration policy; empty means the default
+func ValidMigrationPolicy(policy string) bool {
+	return policy == "" || policy == MigrationPolicyOff || policy == MigrationPolicyReview || policy == MigrationPolicyAcknowledge
+}
+
+// maxMigrationStatements caps the destructive statements listed for a review
+const maxMigrationStatements = 20
+
+// migrationPathSegments are directories whose files are treated as database migrations
+var migrationPathSegments = map[string]bool{"migrations": true, "migration": true, "migrate": true, "alembic": true, "flyway": true, "liquibase": true}
+
+// destructiveDDLRegex matches statements that drop data or break running application
+// versions, in SQL and in the common migration frameworks
+var destructiveDDLRegex = regexp.MustCompile(`(?i)\b(` +
+	`drop\s+(table|column|schema|database|view|index|constraint|type)|truncate\s+(table\s+)?\w+|` +
+	`rename\s+(table|column|to)|alter\s+column\s+\S+\s+(set\s+data\s+)?type|delete\s+from|` +
+	`remove_column|drop_table|rename_column|rename_table|change_column|` +
+	`removefield|deletemodel|renamefield|renamemodel|alterfield|` +
+	`op\.drop_(table|column|index|constraint)|op\.alter_column|dropcolumn|droptable|dropindex|renamecolumn)\b`)
+
+// IsMigrationFile reports whether a changed file is a database migration: a SQL file or a
+// file under a migrations directory
+func IsMigrationFile(filePath string) bool {
+	p := strings.ToLower(filePath)
+	if strings.HasSuffix(p, ".sql") {
+		return true
+	}
+	for _, segment := range strings.Split(path.Dir(p), "/") {
+		if migrationPathSegments[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// isDownMigration reports whether a migration file only rolls back another, such as
+// 0002_orders.down.sql
+func isDownMigration(filePath string) bool {
+	base := strings.ToLower(path.Base(filePath))
+	base = strings.TrimSuffix(base, path.Ext(base))
+	return strings.HasSuffix(base, ".down") || strings.HasSuffix(base, "_down") || strings.HasSuffix(base, "-down")
+}
+
+// MigrationCheck is the result of inspecting the migrations of a review
+type MigrationCheck struct {
+	Files       []string // Changed migration files
+	Destructive []string // Destructive statements added, as "file: statement"
+	RequireAck  bool     // Destructive statements hold the commit status until acknowledged
+}
+
+// NewMigrationCheck inspects the migration files of a change; nil when the policy is off
+// or no migration changed
+func NewMigrationCheck(policy string, files []FileDiff) *MigrationCheck {
+	if policy == MigrationPolicyOff {
+		return nil
+	}
+	check := &MigrationCheck{RequireAck: policy == MigrationPolicyAcknowledge}
+	for _, f := range files {
+		if !IsMigrationFile(f.FilePath) {
+			continue
+		}
+		check.Files = append(check.Files, f.FilePath)
+		if isDownMigration(f.FilePath) {
+			// Rollbacks undo the up migration and drop what it created by design
+			continue
+		}
+		for _, line := range strings.Split(f.Content, "\n") {
+			if !strings.HasPrefix(line, "+") || strings.HasPrefix(line, "+++") {
+				continue
+			}
+			statement := strings.TrimSpace(line[1:])
+			if strings.HasPrefix(statement, "--") || strings.HasPrefix(statement, "#") || !destructiveDDLRegex.MatchString(statement) {
+				continue
+			}
+			if len(check.Destructive) < maxMigrationStatements {
+				check.Destructive = append(check.Destructive, f.FilePath+": "+truncateRunes(statement, 200))
+			}
+		}
+	}
+	if len(check.Files) == 0 {
+		return nil
+	}
+	return check
+}
+
+// Finding describes the destructive statements as a review finding
+func (c *MigrationCheck) Finding() string {
+	if c == nil || len(c.Destructive) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("**Destructive migration statements**: %d statement(s) may drop data or break the running version:\n", len(c.Destructive)))
+	for _, statement := range c.Destructive {
+		b.WriteString("- `" + strings.ReplaceAll(statement, "`", "'") + "`\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// NeedsAck reports whether the commit status waits for the destructive statements to be acknowledged
+func (c *MigrationCheck) NeedsAck() bool {
+	return c != nil && c.RequireAck && len(c.Destructive) > 0
+}
+
+// Gate holds a passing commit status while destructive statements await acknowledgment
+func (c *MigrationCheck) Gate(state, description string) (string, string) {
+	if !c.NeedsAck() || state != "success" {
+		return state, description
+	}
+	return "pending", fmt.Sprintf("%s; destructive migration awaiting acknowledgment", description)
+}
+
+// GenerateMigrationHints returns the migration checklist appended to prompts of changes
+// that touch database migrations
+func GenerateMigrationHints(files []FileDiff) string {
+	var migrations []string
+	for _, f := range files {
+		if IsMigrationFile(f.FilePath) {
+			migrations = append(migrations, f.FilePath)
+		}
+	}
+	if len(migrations) == 0 {
+		return ""
+	}
+	return "\n\n--- Database Migration Review Guidelines (" + strings.Join(migrations, ", ") + ") ---\n" + migrationChecklist
+}
+
+const migrationChecklist = `- Backwards compatibility: the previous application version must keep working while the migration runs and after it; prefer expand/contract over renaming or dropping columns in use
+- Locking: statements that rewrite or lock large tables (adding columns with defaults on old engines, changing column types, building indexes without CONCURRENTLY/ONLINE, adding foreign keys without NOT VALID)
+- Destructive statements: DROP, TRUNCATE, DELETE and type changes that lose data; check there is a backup or a reversible path
+- Reversibility: down migrations or rollback steps exist and match the up migration
+- Data migrations: batched updates, idempotency and transaction size
+`
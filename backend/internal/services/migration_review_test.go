@@ -0,0 +1,132 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMigrationFile(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"db/schema.sql", true},
+		{"db/migrate/20240101_add_users.rb", true},
+		{"internal/migrations/0003_orders.go", true},
+		{"app/migrations/0002_auto.py", true},
+		{"alembic/versions/abc123_drop_orders.py", true},
+		{"internal/services/migrate.go", false},
+		{"README.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsMigrationFile(tt.path); got != tt.want {
+			t.Errorf("IsMigrationFile(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestNewMigrationCheck(t *testing.T) {
+	files := []FileDiff{
+		{FilePath: "migrations/002_orders.sql", Content: "+ALTER TABLE orders DROP COLUMN legacy_id;\n" +
+			"+-- DROP TABLE orders_old;\n" +
+			"+CREATE INDEX idx_orders_user ON orders(user_id);\n" +
+			"-DROP TABLE audit;\n" +
+			"+TRUNCATE TABLE sessions;\n"},
+		{FilePath: "migrations/002_orders.down.sql", Content: "+DROP TABLE orders;\n"},
+		{FilePath: "db/migrate/20240101_rename.rb", Content: "+    rename_column :users, :name, :full_name\n"},
+		{FilePath: "main.go", Content: "+db.Exec(\"DROP TABLE tmp\")\n"},
+	}
+
+	tests := []struct {
+		name            string
+		policy          string
+		files           []FileDiff
+		wantNil         bool
+		wantFiles       int
+		wantDestructive []string
+		wantAck         bool
+	}{
+		{"off", MigrationPolicyOff, files, true, 0, nil, false},
+		{"no migrations", MigrationPolicyReview, files[3:], true, 0, nil, false},
+		{"review", MigrationPolicyReview, files, false, 3, []string{
+			"migrations/002_orders.sql: ALTER TABLE orders DROP COLUMN legacy_id;",
+			"migrations/002_orders.sql: TRUNCATE TABLE sessions;",
+			"db/migrate/20240101_rename.rb: rename_column :users, :name, :full_name",
+		}, false},
+		{"acknowledge", MigrationPolicyAcknowledge, files[:1], false, 1, []string{
+			"migrations/002_orders.sql: ALTER TABLE orders DROP COLUMN legacy_id;",
+			"migrations/002_orders.sql: TRUNCATE TABLE sessions;",
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := NewMigrationCheck(tt.policy, tt.files)
+			if (check == nil) != tt.wantNil {
+				t.Fatalf("NewMigrationCheck() = %+v, want nil %v", check, tt.wantNil)
+			}
+			if check == nil {
+				return
+			}
+			if len(check.Files) != tt.wantFiles {
+				t.Errorf("Files = %v, want %d files", check.Files, tt.wantFiles)
+			}
+			if strings.Join(check.Destructive, "\n") != strings.Join(tt.wantDestructive, "\n") {
+				t.Errorf("Destructive = %q, want %q", check.Destructive, tt.wantDestructive)
+			}
+			if check.NeedsAck() != tt.wantAck {
+				t.Errorf("NeedsAck() = %v, want %v", check.NeedsAck(), tt.wantAck)
+			}
+		})
+	}
+}
+
+func TestMigrationCheckGate(t *testing.T) {
+	pending := &MigrationCheck{RequireAck: true, Destructive: []string{"a.sql: DROP TABLE users;"}}
+	tests := []struct {
+		name      string
+		check     *MigrationCheck
+		state     string
+		wantState string
+	}{
+		{"no check", nil, "success", "success"},
+		{"not required", &MigrationCheck{Destructive: pending.Destructive}, "success", "success"},
+		{"nothing destructive", &MigrationCheck{RequireAck: true}, "success", "success"},
+		{"held", pending, "success", "pending"},
+		{"failed stays failed", pending, "failed", "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, desc := tt.check.Gate(tt.state, "AI Review Passed: 80/60")
+			if state != tt.wantState {
+				t.Errorf("Gate() state = %q, want %q", state, tt.wantState)
+			}
+			if held := strings.Contains(desc, "awaiting acknowledgment"); held != (tt.wantState == "pending") {
+				t.Errorf("Gate() description = %q", desc)
+			}
+		})
+	}
+}
+
+func TestMigrationFindingAndHints(t *testing.T) {
+	var none *MigrationCheck
+	if none.Finding() != "" {
+		t.Error("Finding() of nil check should be empty")
+	}
+	check := &MigrationCheck{Destructive: []string{"a.sql: DROP TABLE `users`;"}}
+	if finding := check.Finding(); !strings.Contains(finding, "1 statement(s)") || !strings.Contains(finding, "DROP TABLE 'users'") {
+		t.Errorf("Finding() = %q", finding)
+	}
+
+	if hints := GenerateMigrationHints([]FileDiff{{FilePath: "main.go"}}); hints != "" {
+		t.Errorf("GenerateMigrationHints() without migrations = %q, want empty", hints)
+	}
+	hints := GenerateMigrationHints([]FileDiff{{FilePath: "main.go"}, {FilePath: "migrations/001.sql"}})
+	for _, want := range []string{"migrations/001.sql", "Backwards compatibility", "Locking"} {
+		if !strings.Contains(hints, want) {
+			t.Errorf("GenerateMigrationHints() missing %q", want)
+		}
+	}
+}
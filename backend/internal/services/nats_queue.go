@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Default names of the NATS JetStream review queue
+const (
+	defaultNATSStream  = "CODESENTRY_REVIEWS"
+	defaultNATSSubject = "codesentry.review_tasks"
+	defaultNATSDurable = "codesentry-workers"
+)
+
+// NATSQueue implements TaskQueue on a NATS JetStream work-queue stream with a durable pull
+// consumer shared by all instances. Delivery works like the Redis stream queue: a task is
+// acknowledged after it was processed, a task not acknowledged within the visibility
+// timeout (the consumer's ack wait) is redelivered, running tasks extend their visibility
+// with a heartbeat, and tasks failing streamMaxDeliveries times are moved to the
+// <subject>.dead subject of the stream.
+type NATSQueue struct {
+	conn        *nats.Conn
+	js          jetstream.JetStream
+	consumer    jetstream.Consumer
+	subject     string
+	deadSubject string
+	visibility  time.Duration
+	concurrency int
+	processor   func(context.Context, *ReviewTask) error
+
+	stop    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewNATSQueue connects to NATS and creates the stream and its consumer if needed
+func NewNATSQueue(cfg *config.QueueConfig) (*NATSQueue, error) {
+	visibility := time.Duration(cfg.VisibilityTimeout) * time.Second
+	if visibility <= 0 {
+		visibility = config.DefaultVisibilityTimeout * time.Second
+	}
+	return newNATSQueue(cfg, visibility)
+}
+
+func newNATSQueue(cfg *config.QueueConfig, visibility time.Duration) (*NATSQueue, error) {
+	conn, err := nats.Connect(cfg.NATS.URL, nats.Name("codesentry"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	stream := valueOr(cfg.NATS.Stream, defaultNATSStream)
+	subject := valueOr(cfg.NATS.Subject, defaultNATSSubject)
+	deadSubject := subject + ".dead"
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      stream,
+		Subjects:  []string{subject, deadSubject},
+		Retention: jetstream.WorkQueuePolicy,
+		Storage:   jetstream.FileStorage,
+		MaxMsgs:   reviewStreamMaxLen,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create stream: %w", err)
+	}
+	// Deliveries are limited by the queue, which dead-letters the task, not by the server
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, jetstream.ConsumerConfig{
+		Durable:       valueOr(cfg.NATS.Durable, defaultNATSDurable),
+		FilterSubject: subject,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       visibility,
+		MaxDeliver:    -1,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("create consumer: %w", err)
+	}
+
+	concurrency := cfg.Workers
+	if concurrency <= 0 {
+		concurrency = DefaultQueueWorkers
+	}
+	queueCtx, queueCancel := context.WithCancel(context.Background())
+	return &NATSQueue{
+		conn:        conn,
+		js:          js,
+		consumer:    consumer,
+		subject:     subject,
+		deadSubject: deadSubject,
+		visibility:  visibility,
+		concurrency: concurrency,
+		stop:        make(chan struct{}),
+		ctx:         queueCtx,
+		cancel:      queueCancel,
+	}, nil
+}
+
+// valueOr returns value, or def if it is empty
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// Enqueue publishes a review task to the stream
+func (q *NATSQueue) Enqueue(task *ReviewTask) error {
+	task.markQueued()
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ack, err := q.js.Publish(ctx, q.subject, payload)
+	if err != nil {
+		return err
+	}
+	logger.Infof("[NATSQueue] Task enqueued: seq=%d, review_log_id=%d", ack.Sequence, task.ReviewLogID)
+	return nil
+}
+
+// IsAsync returns true for the NATS queue
+func (q *NATSQueue) IsAsync() bool {
+	return true
+}
+
+// Close stops the consumers and closes the NATS connection
+func (q *NATSQueue) Close() error {
+	q.Stop()
+	q.conn.Close()
+	return nil
+}
+
+// SetProcessor sets the function to process review tasks
+func (q *NATSQueue) SetProcessor(processor func(context.Context, *ReviewTask) error) {
+	q.processor = processor
+}
+
+// Start runs the consumers of this instance
+func (q *NATSQueue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running {
+		return nil
+	}
+	if q.processor == nil {
+		return errors.New("no processor set")
+	}
+	q.running = true
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.consume()
+	}
+	logger.Infof("[NATSQueue] Started %d consumers (visibility timeout %s)", q.concurrency, q.visibility)
+	return nil
+}
+
+// Stop stops the consumers. Running tasks get a grace period and are cancelled
+// afterwards; unacknowledged tasks are redelivered after the visibility timeout.
+func (q *NATSQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.running {
+		return
+	}
+	q.running = false
+	close(q.stop)
+	drainConsumers("NATSQueue", &q.wg, q.cancel)
+}
+
+func (q *NATSQueue) consume() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		batch, err := q.consumer.Fetch(1, jetstream.FetchMaxWait(streamReadBlock))
+		if err == nil {
+			for msg := range batch.Messages() {
+				q.handle(msg)
+			}
+			err = batch.Error()
+		}
+		if err != nil {
+			if q.ctx.Err() != nil {
+				return
+			}
+			logger.Warnf("[NATSQueue] Failed to fetch tasks: %v", err)
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}
+}
+
+func (q *NATSQueue) handle(msg jetstream.Msg) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		logger.Warnf("[NATSQueue] Task without metadata: %v", err)
+		return
+	}
+	seq := meta.Sequence.Stream
+	if meta.NumDelivered > streamMaxDeliveries {
+		q.deadLetter(msg, seq, fmt.Sprintf("failed after %d deliveries", meta.NumDelivered-1))
+		return
+	}
+	if meta.NumDelivered > 1 {
+		logger.Infof("[NATSQueue] Redelivering task %d (delivery %d)", seq, meta.NumDelivered)
+	}
+
+	var task ReviewTask
+	if err := json.Unmarshal(msg.Data(), &task); err != nil {
+		q.deadLetter(msg, seq, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+
+	heartbeatDone := make(chan struct{})
+	go q.heartbeat(msg, seq, heartbeatDone)
+	err = runReviewTask(q.ctx, q.processor, &task)
+	close(heartbeatDone)
+
+	if err != nil {
+		// Left unacknowledged: retried once the visibility timeout expires
+		logger.Infof("[NATSQueue] Task %d failed: %v", seq, err)
+		return
+	}
+	if q.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := msg.DoubleAck(ctx); err != nil {
+		logger.Warnf("[NATSQueue] Failed to acknowledge task %d: %v", seq, err)
+	}
+}
+
+// heartbeat keeps a running task from being redelivered by resetting its ack wait
+func (q *NATSQueue) heartbeat(msg jetstream.Msg, seq uint64, done <-chan struct{}) {
+	ticker := time.NewTicker(q.visibility / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := msg.InProgress(); err != nil && q.ctx.Err() == nil {
+				logger.Warnf("[NATSQueue] Heartbeat for task %d failed: %v", seq, err)
+			}
+		}
+	}
+}
+
+// deadLetter moves a task that cannot be processed to the dead-letter subject
+func (q *NATSQueue) deadLetter(msg jetstream.Msg, seq uint64, reason string) {
+	logger.Warnf("[NATSQueue] Moving task %d to %s: %s", seq, q.deadSubject, reason)
+	dead := nats.NewMsg(q.deadSubject)
+	dead.Data = msg.Data()
+	dead.Header.Set("Codesentry-Sequence", strconv.FormatUint(seq, 10))
+	dead.Header.Set("Codesentry-Error", reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := q.js.PublishMsg(ctx, dead); err != nil {
+		logger.Warnf("[NATSQueue] Failed to dead-letter task %d: %v", seq, err)
+		return
+	}
+	if err := msg.Term(); err != nil {
+		logger.Warnf("[NATSQueue] Failed to remove dead-lettered task %d: %v", seq, err)
+	}
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func newTestNATSQueue(t *testing.T, visibility time.Duration) *NATSQueue {
+	t.Helper()
+	srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("start NATS server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	q, err := newNATSQueue(&config.QueueConfig{Workers: 2, NATS: config.NATSQueueConfig{URL: srv.ClientURL()}}, visibility)
+	if err != nil {
+		t.Fatalf("newNATSQueue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func TestNATSQueue_RedeliversFailedTasks(t *testing.T) {
+	q := newTestNATSQueue(t, 300*time.Millisecond)
+
+	var mu sync.Mutex
+	attempts := map[uint]int{}
+	done := make(chan uint, 2)
+	q.SetProcessor(func(ctx context.Context, task *ReviewTask) error {
+		mu.Lock()
+		attempts[task.ReviewLogID]++
+		n := attempts[task.ReviewLogID]
+		mu.Unlock()
+		if task.ReviewLogID == 2 && n == 1 {
+			return errors.New("LLM unavailable")
+		}
+		done <- task.ReviewLogID
+		return nil
+	})
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []uint{1, 2} {
+		if err := q.Enqueue(&ReviewTask{ReviewLogID: id}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("tasks were not processed")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts[1] != 1 || attempts[2] != 2 {
+		t.Errorf("attempts = %v, want task 1 once and the failed task 2 twice", attempts)
+	}
+}
+
+func TestNATSQueue_HeartbeatKeepsLongTasks(t *testing.T) {
+	q := newTestNATSQueue(t, 300*time.Millisecond)
+
+	var mu sync.Mutex
+	attempts := 0
+	done := make(chan struct{}, 1)
+	q.SetProcessor(func(ctx context.Context, task *ReviewTask) error {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		time.Sleep(time.Second) // Longer than the visibility timeout
+		done <- struct{}{}
+		return nil
+	})
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(&ReviewTask{ReviewLogID: 1}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("task was not processed")
+	}
+	time.Sleep(500 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 1 {
+		t.Errorf("long task processed %d times, want once", attempts)
+	}
+}
+
+func TestNATSQueue_DeadLettersFailingTasks(t *testing.T) {
+	q := newTestNATSQueue(t, 200*time.Millisecond)
+
+	var mu sync.Mutex
+	attempts := 0
+	q.SetProcessor(func(ctx context.Context, task *ReviewTask) error {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		return errors.New("always fails")
+	})
+	if err := q.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Enqueue(&ReviewTask{ReviewLogID: 1}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		stream, err := q.js.Stream(ctx, defaultNATSStream)
+		if err != nil {
+			cancel()
+			t.Fatal(err)
+		}
+		msg, err := stream.GetLastMsgForSubject(ctx, q.deadSubject)
+		cancel()
+		if err == nil {
+			if got := msg.Header.Get("Codesentry-Error"); got != "failed after 4 deliveries" {
+				t.Errorf("dead-letter reason = %q", got)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("task was not dead-lettered: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != streamMaxDeliveries {
+		t.Errorf("attempts = %d, want %d", attempts, streamMaxDeliveries)
+	}
+}
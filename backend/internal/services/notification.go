@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -11,51 +13,93 @@ import (
 )
 
 type NotificationService struct {
-	db           *gorm.DB
-	emailService *EmailService
-	httpClient   *http.Client
+	db            *gorm.DB
+	emailService  *EmailService
+	digestService *DigestService
+	configService *SystemConfigService
+	quietHours    *quietHoursChecker
+	personal      *PersonalNotificationService
+	httpClient    *http.Client
 }
 
 func NewNotificationService(db *gorm.DB) *NotificationService {
 	return &NotificationService{
-		db:           db,
-		emailService: NewEmailService(db),
-		httpClient:   &http.Client{Timeout: 10 * time.Second},
+		db:            db,
+		emailService:  NewEmailService(db),
+		digestService: NewDigestService(db),
+		configService: NewSystemConfigService(db),
+		quietHours:    newQuietHoursChecker(db),
+		personal:      NewPersonalNotificationService(db),
+		httpClient:    NotificationHTTPClient(),
 	}
 }
 
 type ReviewNotification struct {
-	ProjectName   string
-	Branch        string
-	Author        string
-	CommitMessage string
-	Score         float64
-	ReviewResult  string
-	EventType     string
-	MRURL         string
+	ProjectName   string  `json:"project_name"`
+	Branch        string  `json:"branch"`
+	Author        string  `json:"author"`
+	CommitMessage string  `json:"commit_message"`
+	Score         float64 `json:"score"`
+	ReviewResult  string  `json:"review_result"`
+	EventType     string  `json:"event_type"`
+	MRURL         string  `json:"mr_url"`
+	Urgent        bool    `json:"urgent,omitempty"` // Sent right away, even in quiet hours or to digest bots
+	ReviewLogID   uint    `json:"review_log_id,omitempty"`
+	ReviewURL     string  `json:"review_url,omitempty"` // Review in the web UI, set from the external URL setting
+	RetryURL      string  `json:"retry_url,omitempty"`  // Review in the web UI with its retry action
 }
 
-func (s *NotificationService) SendReviewNotification(project *models.Project, notification *ReviewNotification) error {
+// reviewLinks returns the web UI links of a review, empty without an external URL. The
+// retry link, which the review logs page confirms before retrying, is only given for
+// reviews that can be retried.
+func reviewLinks(externalURL string, reviewLogID uint, status string) (reviewURL, retryURL string) {
+	if externalURL == "" || reviewLogID == 0 {
+		return "", ""
+	}
+	reviewURL = fmt.Sprintf("%s/admin/review-logs?id=%d", externalURL, reviewLogID)
+	if isRetryableStatus(status) {
+		retryURL = reviewURL + "&action=retry"
+	}
+	return reviewURL, retryURL
+}
+
+// addLinks sets the web UI links of a notification's review
+func (s *NotificationService) addLinks(notification *ReviewNotification) {
+	externalURL := s.configService.GetNotificationLinkConfig().ExternalURL
+	if externalURL == "" || notification.ReviewLogID == 0 {
+		return
+	}
+	var status string
+	s.db.Model(&models.ReviewLog{}).Where("id = ?", notification.ReviewLogID).Select("review_status").Scan(&status)
+	notification.ReviewURL, notification.RetryURL = reviewLinks(externalURL, notification.ReviewLogID, status)
+}
+
+func (s *NotificationService) SendReviewNotification(ctx context.Context, project *models.Project, notification *ReviewNotification) error {
 	var imErr, emailErr error
+	s.addLinks(notification)
 
 	if project.IMEnabled && project.IMBotID != nil {
 		var bot models.IMBot
 		if err := s.db.First(&bot, *project.IMBotID).Error; err != nil {
 			imErr = fmt.Errorf("IM bot not found: %w", err)
 		} else if !bot.IsActive {
-			logger.Infof("[Notification] IM bot %d is not active", bot.ID)
+			logger.Ctx(ctx).Info().Msgf("[Notification] IM bot %d is not active", bot.ID)
+		} else if (bot.DigestEnabled || s.quietHours.isQuiet(&bot, time.Now())) && !notification.Urgent && !s.isGatingFailure(project, notification.Score) {
+			logger.Ctx(ctx).Info().Msgf("[Notification] Deferring notification for bot %s (digest or quiet hours)", bot.Name)
+			imErr = s.digestService.Enqueue(&bot, project.ID, notification)
 		} else {
-			logger.Infof("[Notification] Sending notification to bot %s (type: %s)", bot.Name, bot.Type)
-			adapter := getAdapter(bot.Type)
-			imErr = adapter.SendRichMessage(bot.Webhook, &bot, notification)
+			logger.Ctx(ctx).Info().Msgf("[Notification] Sending notification to bot %s (type: %s)", bot.Name, bot.Type)
+			imErr = deliverNotification(s.db, &bot, &project.ID, &notificationPayload{Review: notification})
 		}
 	}
 
+	var authorEmail string
 	if notification.Author != "" {
 		var recipients []string
 		var reviewLog models.ReviewLog
 		s.db.Where("project_id = ? AND author = ?", project.ID, notification.Author).
 			Order("created_at DESC").First(&reviewLog)
+		authorEmail = reviewLog.AuthorEmail
 		if reviewLog.AuthorEmail != "" {
 			recipients = append(recipients, reviewLog.AuthorEmail)
 		}
@@ -64,11 +108,13 @@ func (s *NotificationService) SendReviewNotification(project *models.Project, no
 		}
 	}
 
+	s.personal.NotifyReview(ctx, project, notification, authorEmail)
+
 	if imErr != nil {
-		logger.Infof("[Notification] IM notification failed: %v", imErr)
+		logger.Ctx(ctx).Info().Msgf("[Notification] IM notification failed: %v", imErr)
 	}
 	if emailErr != nil {
-		logger.Infof("[Notification] Email notification failed: %v", emailErr)
+		logger.Ctx(ctx).Info().Msgf("[Notification] Email notification failed: %v", emailErr)
 	}
 
 	if imErr != nil {
@@ -77,11 +123,60 @@ func (s *NotificationService) SendReviewNotification(project *models.Project, no
 	return emailErr
 }
 
+// SendReleaseNotification sends a release review to the project's release IM bot,
+// falling back to the regular IM bot when none is configured
+func (s *NotificationService) SendReleaseNotification(project *models.Project, notification *ReviewNotification) error {
+	botID := project.ReleaseIMBotID
+	if botID == nil {
+		botID = project.IMBotID
+	}
+	if !project.IMEnabled || botID == nil {
+		return nil
+	}
+	var bot models.IMBot
+	if err := s.db.First(&bot, *botID).Error; err != nil {
+		return fmt.Errorf("IM bot not found: %w", err)
+	}
+	if !bot.IsActive {
+		logger.Infof("[Notification] IM bot %d is not active", bot.ID)
+		return nil
+	}
+	logger.Infof("[Notification] Sending release notification to bot %s (type: %s)", bot.Name, bot.Type)
+	s.addLinks(notification)
+	return deliverNotification(s.db, &bot, &project.ID, &notificationPayload{Review: notification})
+}
+
+// SendTextNotification sends a plain message to the project's IM bot, if configured
+func (s *NotificationService) SendTextNotification(project *models.Project, message string) error {
+	if !project.IMEnabled || project.IMBotID == nil {
+		return nil
+	}
+	var bot models.IMBot
+	if err := s.db.First(&bot, *project.IMBotID).Error; err != nil {
+		return fmt.Errorf("IM bot not found: %w", err)
+	}
+	if !bot.IsActive {
+		return nil
+	}
+	return deliverNotification(s.db, &bot, &project.ID, &notificationPayload{Text: message})
+}
+
 func (s *NotificationService) SendErrorNotification(bot *models.IMBot, message string) error {
 	if !bot.IsActive {
 		return nil
 	}
+	return deliverNotification(s.db, bot, nil, &notificationPayload{Text: message})
+}
 
-	adapter := getAdapter(bot.Type)
-	return adapter.SendTextMessage(bot.Webhook, bot, message)
+// isGatingFailure reports whether a score falls below the project's effective minimum score.
+// Gating failures bypass digest mode and are delivered immediately.
+func (s *NotificationService) isGatingFailure(project *models.Project, score float64) bool {
+	minScore := project.MinScore
+	if minScore <= 0 {
+		minScore, _ = strconv.ParseFloat(s.configService.GetWithDefault("system.min_score", "60"), 64)
+	}
+	if minScore <= 0 {
+		minScore = 60
+	}
+	return score < minScore
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
@@ -25,45 +26,92 @@ type NotificationAdapter interface {
 
 // getAdapter returns the appropriate notification adapter for the given bot type
 func getAdapter(botType string) NotificationAdapter {
+	return newAdapter(botType, func(webhookURL string, payload interface{}) error {
+		_, _, err := postJSONResponse(NotificationHTTPClient(), botType, webhookURL, payload)
+		return err
+	})
+}
+
+// newAdapter returns the adapter for the bot type, posting its payloads with post
+func newAdapter(botType string, post webhookPost) NotificationAdapter {
+	sender := webhookSender{post: post}
 	switch botType {
 	case "wechat_work":
-		return &wecomAdapter{}
+		return &wecomAdapter{sender}
 	case "dingtalk":
-		return &dingtalkAdapter{}
+		return &dingtalkAdapter{sender}
 	case "feishu":
-		return &feishuAdapter{}
+		return &feishuAdapter{sender}
 	case "slack":
-		return &slackAdapter{}
+		return &slackAdapter{sender}
 	case "discord":
-		return &discordAdapter{}
+		return &discordAdapter{sender}
 	case "teams":
-		return &teamsAdapter{}
+		return &teamsAdapter{sender}
 	case "telegram":
-		return &telegramAdapter{}
+		return &telegramAdapter{sender}
 	default:
-		return &genericAdapter{}
+		return &genericAdapter{sender}
 	}
 }
 
+// webhookPost sends a JSON payload to a bot webhook
+type webhookPost func(webhookURL string, payload interface{}) error
+
+// webhookSender is embedded by the adapters to post their payloads
+type webhookSender struct {
+	post webhookPost
+}
+
+// WebhookCall is a payload posted, or to be posted, to a bot webhook, with the response
+type WebhookCall struct {
+	Payload    interface{} `json:"payload"`
+	StatusCode int         `json:"status_code,omitempty"`
+	Response   string      `json:"response,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// webhookRecorder records the payloads an adapter posts; they are only sent when send is set
+type webhookRecorder struct {
+	send    bool
+	botType string // Decides how responses are checked, see checkWebhookResponse
+	calls   []WebhookCall
+}
+
+func (r *webhookRecorder) post(webhookURL string, payload interface{}) error {
+	call := WebhookCall{Payload: payload}
+	var err error
+	if r.send {
+		call.StatusCode, call.Response, err = postJSONResponse(NotificationHTTPClient(), r.botType, webhookURL, payload)
+		if err != nil {
+			call.Error = err.Error()
+		}
+	}
+	r.calls = append(r.calls, call)
+	return err
+}
+
 // --- Helper functions shared by adapters ---
 
-func postJSONWithClient(client *http.Client, webhookURL string, payload interface{}) error {
+// postJSONResponse posts a payload and returns the webhook's response status and body. Error
+// statuses and, for the bot types answering 200 with an error code, error codes fail.
+func postJSONResponse(client *http.Client, botType, webhookURL string, payload interface{}) (int, string, error) {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 
 	logger.Infof("[Notification] POST %s, payload length: %d", webhookURL, len(body))
 
 	req, err := http.NewRequest("POST", webhookURL, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return err
+		return 0, "", err
 	}
 	defer resp.Body.Close()
 
@@ -71,13 +119,70 @@ func postJSONWithClient(client *http.Client, webhookURL string, payload interfac
 	logger.Infof("[Notification] Response: %d - %s", resp.StatusCode, string(respBody))
 
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, string(respBody))
+		return resp.StatusCode, string(respBody), &webhookStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
-	return nil
+	return resp.StatusCode, string(respBody), checkWebhookResponse(botType, respBody)
+}
+
+// Error codes of rate limits and busy servers, which are worth retrying
+var webhookTransientCodes = map[string]map[int]bool{
+	"dingtalk":    {-1: true, 130101: true},  // System busy, sending too fast
+	"wechat_work": {-1: true, 45009: true},   // System busy, API frequency limit
+	"feishu":      {9499: true, 11232: true}, // Too many requests, frequency limited
+}
+
+// webhookEnvelope is the response of the bots answering 200 with an error code: DingTalk and
+// WeCom errcode/errmsg, Feishu code/msg or, on older endpoints, StatusCode/StatusMessage
+type webhookEnvelope struct {
+	ErrCode       *int   `json:"errcode"`
+	ErrMsg        string `json:"errmsg"`
+	Code          *int   `json:"code"`
+	Msg           string `json:"msg"`
+	StatusCode    *int   `json:"StatusCode"`
+	StatusMessage string `json:"StatusMessage"`
 }
 
-var notificationHTTPClient = &http.Client{Timeout: 10 * time.Second}
+// checkWebhookResponse returns the error a bot webhook reported in a successful response.
+// Responses that aren't the bot type's envelope are taken as success.
+func checkWebhookResponse(botType string, body []byte) error {
+	var envelope webhookEnvelope
+	if _, ok := webhookTransientCodes[botType]; !ok || json.Unmarshal(body, &envelope) != nil {
+		return nil
+	}
+	code, message := envelope.ErrCode, envelope.ErrMsg
+	if botType == "feishu" {
+		code, message = envelope.Code, envelope.Msg
+		if code == nil {
+			code, message = envelope.StatusCode, envelope.StatusMessage
+		}
+	}
+	if code == nil || *code == 0 {
+		return nil
+	}
+	return &webhookCodeError{Code: *code, Message: message, Transient: webhookTransientCodes[botType][*code]}
+}
+
+// webhookCodeError is an error code returned by a bot webhook with a successful status
+type webhookCodeError struct {
+	Code      int
+	Message   string
+	Transient bool
+}
+
+func (e *webhookCodeError) Error() string {
+	return fmt.Sprintf("webhook returned error code %d: %s", e.Code, e.Message)
+}
+
+// webhookStatusError is an error status returned by a bot webhook
+type webhookStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook returned status %d: %s", e.StatusCode, e.Body)
+}
 
 func splitMessage(msg string, maxLen int) []string {
 	if len(msg) <= maxLen {
@@ -119,8 +224,11 @@ func buildMessage(n *ReviewNotification) string {
 	}
 
 	eventTypeText := "Push"
-	if n.EventType == "merge_request" {
+	switch n.EventType {
+	case "merge_request":
 		eventTypeText = "Merge Request"
+	case EventTypeRelease:
+		eventTypeText = "Release"
 	}
 
 	commitMsg := n.CommitMessage
@@ -161,22 +269,33 @@ func feishuSign(timestamp int64, secret string) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func dingTalkWebhookURL(webhook, secret string) string {
+func dingTalkWebhookURL(webhook, secret string) (string, error) {
 	if secret == "" {
-		return webhook
+		return webhook, nil
+	}
+	secret, err := ResolveSecret(context.Background(), secret)
+	if err != nil {
+		return "", err
 	}
 	timestamp := time.Now().UnixMilli()
 	sign := dingTalkSign(timestamp, secret)
-	return fmt.Sprintf("%s&timestamp=%d&sign=%s", webhook, timestamp, url.QueryEscape(sign))
+	return fmt.Sprintf("%s&timestamp=%d&sign=%s", webhook, timestamp, url.QueryEscape(sign)), nil
 }
 
 // --- Adapter implementations ---
 
 // wecomAdapter handles WeCom (Enterprise WeChat) bot notifications
-type wecomAdapter struct{}
+type wecomAdapter struct{ webhookSender }
 
 func (a *wecomAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
+	// Template cards need a link for the card action; reviews without links keep the markdown message
+	if usesReviewCard(bot) {
+		if card := newReviewCard(bot, n); len(card.Buttons) > 0 {
+			return a.post(webhook, card.wecomTemplateCard())
+		}
+	}
+
+	msg := renderBotMessage(bot, n)
 	const maxLen = 4000
 
 	if len(msg) <= maxLen {
@@ -186,7 +305,7 @@ func (a *wecomAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *Rev
 				"content": msg,
 			},
 		}
-		return postJSONWithClient(notificationHTTPClient, webhook, payload)
+		return a.post(webhook, payload)
 	}
 
 	parts := splitMessage(msg, maxLen)
@@ -201,7 +320,7 @@ func (a *wecomAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *Rev
 				"content": content,
 			},
 		}
-		if err := postJSONWithClient(notificationHTTPClient, webhook, payload); err != nil {
+		if err := a.post(webhook, payload); err != nil {
 			return err
 		}
 	}
@@ -215,17 +334,27 @@ func (a *wecomAdapter) SendTextMessage(webhook string, bot *models.IMBot, messag
 			"content": message,
 		},
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 // dingtalkAdapter handles DingTalk bot notifications
-type dingtalkAdapter struct{}
+type dingtalkAdapter struct{ webhookSender }
 
 func (a *dingtalkAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
+	msg := renderBotMessage(bot, n)
 	const maxLen = 19000
 
-	webhookURL := dingTalkWebhookURL(bot.Webhook, bot.Secret)
+	webhookURL, err := dingTalkWebhookURL(bot.Webhook, bot.Secret)
+	if err != nil {
+		return err
+	}
+
+	// ActionCards need a button; reviews without links keep the markdown message
+	if usesReviewCard(bot) {
+		if card := newReviewCard(bot, n); len(card.Buttons) > 0 {
+			return a.post(webhookURL, card.dingTalkActionCard())
+		}
+	}
 
 	if len(msg) <= maxLen {
 		payload := map[string]interface{}{
@@ -235,7 +364,7 @@ func (a *dingtalkAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *
 				"text":  msg,
 			},
 		}
-		return postJSONWithClient(notificationHTTPClient, webhookURL, payload)
+		return a.post(webhookURL, payload)
 	}
 
 	parts := splitMessage(msg, maxLen)
@@ -248,7 +377,7 @@ func (a *dingtalkAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *
 				"text":  part,
 			},
 		}
-		if err := postJSONWithClient(notificationHTTPClient, webhookURL, payload); err != nil {
+		if err := a.post(webhookURL, payload); err != nil {
 			return err
 		}
 	}
@@ -256,7 +385,10 @@ func (a *dingtalkAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *
 }
 
 func (a *dingtalkAdapter) SendTextMessage(webhook string, bot *models.IMBot, message string) error {
-	webhookURL := dingTalkWebhookURL(bot.Webhook, bot.Secret)
+	webhookURL, err := dingTalkWebhookURL(bot.Webhook, bot.Secret)
+	if err != nil {
+		return err
+	}
 	payload := map[string]interface{}{
 		"msgtype": "markdown",
 		"markdown": map[string]string{
@@ -264,37 +396,44 @@ func (a *dingtalkAdapter) SendTextMessage(webhook string, bot *models.IMBot, mes
 			"text":  message,
 		},
 	}
-	return postJSONWithClient(notificationHTTPClient, webhookURL, payload)
+	return a.post(webhookURL, payload)
 }
 
 // feishuAdapter handles Feishu (Lark) bot notifications
-type feishuAdapter struct{}
+type feishuAdapter struct{ webhookSender }
 
 func (a *feishuAdapter) sendFeishu(webhook, secret, content string) error {
-	if secret != "" {
-		timestamp := time.Now().Unix()
-		sign := feishuSign(timestamp, secret)
-		payload := map[string]interface{}{
-			"timestamp": fmt.Sprintf("%d", timestamp),
-			"sign":      sign,
-			"msg_type":  "text",
-			"content": map[string]string{
-				"text": content,
-			},
-		}
-		return postJSONWithClient(notificationHTTPClient, webhook, payload)
-	}
-	payload := map[string]interface{}{
+	return a.postSigned(webhook, secret, map[string]interface{}{
 		"msg_type": "text",
 		"content": map[string]string{
 			"text": content,
 		},
+	})
+}
+
+// postSigned posts a payload, signed with the timestamp and sign fields when the bot has a secret
+func (a *feishuAdapter) postSigned(webhook, secret string, payload map[string]interface{}) error {
+	if secret != "" {
+		secret, err := ResolveSecret(context.Background(), secret)
+		if err != nil {
+			return err
+		}
+		timestamp := time.Now().Unix()
+		payload["timestamp"] = fmt.Sprintf("%d", timestamp)
+		payload["sign"] = feishuSign(timestamp, secret)
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 func (a *feishuAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
+	if usesReviewCard(bot) {
+		return a.postSigned(webhook, bot.Secret, map[string]interface{}{
+			"msg_type": "interactive",
+			"card":     newReviewCard(bot, n).feishuCard(),
+		})
+	}
+
+	msg := renderBotMessage(bot, n)
 	const maxLen = 4000
 
 	if len(msg) <= maxLen {
@@ -319,9 +458,18 @@ func (a *feishuAdapter) SendTextMessage(webhook string, bot *models.IMBot, messa
 }
 
 // slackAdapter handles Slack bot notifications
-type slackAdapter struct{}
+type slackAdapter struct{ webhookSender }
 
 func (a *slackAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
+	if hasCustomMessageFormat(bot) {
+		for _, part := range splitMessage(renderBotMessage(bot, n), 3000) {
+			if err := a.SendTextMessage(webhook, bot, part); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	scoreEmoji := ":large_green_circle:"
 	if n.Score < 60 {
 		scoreEmoji = ":red_circle:"
@@ -355,7 +503,7 @@ func (a *slackAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *Rev
 				},
 			},
 		}
-		return postJSONWithClient(notificationHTTPClient, webhook, payload)
+		return a.post(webhook, payload)
 	}
 
 	parts := splitMessage(reviewResult, maxLen)
@@ -385,7 +533,7 @@ func (a *slackAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *Rev
 				},
 			},
 		}
-		if err := postJSONWithClient(notificationHTTPClient, webhook, payload); err != nil {
+		if err := a.post(webhook, payload); err != nil {
 			return err
 		}
 	}
@@ -396,29 +544,29 @@ func (a *slackAdapter) SendTextMessage(webhook string, bot *models.IMBot, messag
 	payload := map[string]interface{}{
 		"text": message,
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 // discordAdapter handles Discord webhook notifications
-type discordAdapter struct{}
+type discordAdapter struct{ webhookSender }
 
 func (a *discordAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
+	msg := renderBotMessage(bot, n)
 	payload := map[string]interface{}{
 		"content": msg,
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 func (a *discordAdapter) SendTextMessage(webhook string, bot *models.IMBot, message string) error {
 	payload := map[string]interface{}{
 		"content": message,
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 // teamsAdapter handles Microsoft Teams webhook notifications
-type teamsAdapter struct{}
+type teamsAdapter struct{ webhookSender }
 
 func buildAdaptiveCard(text string) map[string]interface{} {
 	return map[string]interface{}{
@@ -444,16 +592,16 @@ func buildAdaptiveCard(text string) map[string]interface{} {
 }
 
 func (a *teamsAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
-	return postJSONWithClient(notificationHTTPClient, webhook, buildAdaptiveCard(msg))
+	msg := renderBotMessage(bot, n)
+	return a.post(webhook, buildAdaptiveCard(msg))
 }
 
 func (a *teamsAdapter) SendTextMessage(webhook string, bot *models.IMBot, message string) error {
-	return postJSONWithClient(notificationHTTPClient, webhook, buildAdaptiveCard(message))
+	return a.post(webhook, buildAdaptiveCard(message))
 }
 
 // telegramAdapter handles Telegram bot notifications
-type telegramAdapter struct{}
+type telegramAdapter struct{ webhookSender }
 
 func (a *telegramAdapter) sendTelegram(webhook, chatID, text string) error {
 	if chatID == "" {
@@ -464,11 +612,11 @@ func (a *telegramAdapter) sendTelegram(webhook, chatID, text string) error {
 		"text":       text,
 		"parse_mode": "Markdown",
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
 
 func (a *telegramAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
-	msg := buildMessage(n)
+	msg := renderBotMessage(bot, n)
 	return a.sendTelegram(webhook, bot.Extra, msg)
 }
 
@@ -477,7 +625,7 @@ func (a *telegramAdapter) SendTextMessage(webhook string, bot *models.IMBot, mes
 }
 
 // genericAdapter handles generic webhook notifications
-type genericAdapter struct{}
+type genericAdapter struct{ webhookSender }
 
 func (a *genericAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *ReviewNotification) error {
 	payload := map[string]interface{}{
@@ -490,7 +638,10 @@ func (a *genericAdapter) SendRichMessage(webhook string, bot *models.IMBot, n *R
 		"event_type":     n.EventType,
 		"mr_url":         n.MRURL,
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	if hasCustomMessageFormat(bot) {
+		payload["message"] = renderBotMessage(bot, n)
+	}
+	return a.post(webhook, payload)
 }
 
 func (a *genericAdapter) SendTextMessage(webhook string, bot *models.IMBot, message string) error {
@@ -498,5 +649,5 @@ func (a *genericAdapter) SendTextMessage(webhook string, bot *models.IMBot, mess
 		"type":    "error",
 		"message": message,
 	}
-	return postJSONWithClient(notificationHTTPClient, webhook, payload)
+	return a.post(webhook, payload)
 }
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Score colors of the review cards
+const (
+	cardColorGreen  = "green"
+	cardColorYellow = "yellow"
+	cardColorRed    = "red"
+)
+
+// reviewCard is the content of a review notification shown as an interactive card by
+// WeCom, DingTalk and Feishu
+type reviewCard struct {
+	Title    string
+	Score    float64
+	Color    string
+	Fields   []cardField
+	Findings string
+	Buttons  []cardButton
+	labels   map[string]string
+}
+
+type cardField struct {
+	Label string
+	Value string
+}
+
+type cardButton struct {
+	Title string
+	URL   string
+}
+
+// usesReviewCard reports whether a bot's review notifications are sent as cards; bots with
+// a message template keep their rendered markdown
+func usesReviewCard(bot *models.IMBot) bool {
+	return bot == nil || strings.TrimSpace(bot.MessageTemplate) == ""
+}
+
+func scoreColor(score float64) string {
+	if score < 60 {
+		return cardColorRed
+	} else if score < 80 {
+		return cardColorYellow
+	}
+	return cardColorGreen
+}
+
+// newReviewCard builds the card of a notification with the bot's language and fields
+func newReviewCard(bot *models.IMBot, n *ReviewNotification) *reviewCard {
+	lang := ""
+	fields := MessageFields
+	if bot != nil {
+		lang = bot.MessageLanguage
+		if strings.TrimSpace(bot.MessageFields) != "" {
+			fields = splitAndTrim(bot.MessageFields, ",")
+		}
+	}
+	show := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		show[f] = true
+	}
+	labels := messageLabelsFor(lang)
+	data := newMessageTemplateData(n, lang)
+
+	card := &reviewCard{
+		Title:    fmt.Sprintf(labels["card_title"], data.ProjectName),
+		Score:    data.Score,
+		Color:    scoreColor(data.Score),
+		Findings: data.Summary,
+		labels:   labels,
+	}
+	meta := []struct{ key, value string }{
+		{"event", data.EventTypeText},
+		{"branch", data.Branch},
+		{"author", data.Author},
+		{"commit", truncateRunes(firstLine(data.CommitMessage), 60)},
+	}
+	for _, m := range meta {
+		if show[m.key] && m.value != "" {
+			card.Fields = append(card.Fields, cardField{Label: labels[m.key], Value: m.value})
+		}
+	}
+
+	if n.ReviewURL != "" {
+		card.Buttons = append(card.Buttons, cardButton{Title: labels["view_review"], URL: n.ReviewURL})
+	}
+	if show["mr_url"] && n.MRURL != "" {
+		card.Buttons = append(card.Buttons, cardButton{Title: labels["mr_url"], URL: n.MRURL})
+	}
+	if n.RetryURL != "" {
+		card.Buttons = append(card.Buttons, cardButton{Title: labels["retry"], URL: n.RetryURL})
+	}
+	return card
+}
+
+// markdown renders the card body for platforms whose cards take markdown text
+func (c *reviewCard) markdown(colorize func(color, text string) string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "### %s\n\n", c.Title)
+	fmt.Fprintf(&sb, "**%s**: %s\n\n", c.labels["score"], colorize(c.Color, fmt.Sprintf("%.0f/100", c.Score)))
+	for _, f := range c.Fields {
+		fmt.Fprintf(&sb, "**%s**: %s\n\n", f.Label, f.Value)
+	}
+	fmt.Fprintf(&sb, "**%s**:\n\n%s", c.labels["summary"], c.Findings)
+	return sb.String()
+}
+
+var dingTalkScoreColors = map[string]string{
+	cardColorGreen:  "#52c41a",
+	cardColorYellow: "#faad14",
+	cardColorRed:    "#f5222d",
+}
+
+// dingTalkActionCard renders the card as a DingTalk ActionCard, which needs at least one button
+func (c *reviewCard) dingTalkActionCard() map[string]interface{} {
+	text := c.markdown(func(color, text string) string {
+		return fmt.Sprintf("<font color=%s>%s</font>", dingTalkScoreColors[color], text)
+	})
+	btns := make([]map[string]string, 0, len(c.Buttons))
+	for _, b := range c.Buttons {
+		btns = append(btns, map[string]string{"title": b.Title, "actionURL": b.URL})
+	}
+	return map[string]interface{}{
+		"msgtype": "actionCard",
+		"actionCard": map[string]interface{}{
+			"title":          c.Title,
+			"text":           text,
+			"btnOrientation": "1",
+			"btns":           btns,
+		},
+	}
+}
+
+var feishuHeaderTemplates = map[string]string{
+	cardColorGreen:  "green",
+	cardColorYellow: "yellow",
+	cardColorRed:    "red",
+}
+
+// feishuCard renders the card as a Feishu interactive card
+func (c *reviewCard) feishuCard() map[string]interface{} {
+	fields := []map[string]interface{}{feishuShortField(c.labels["score"], fmt.Sprintf("%.0f/100", c.Score))}
+	for _, f := range c.Fields {
+		fields = append(fields, feishuShortField(f.Label, f.Value))
+	}
+	elements := []map[string]interface{}{
+		{"tag": "div", "fields": fields},
+		{"tag": "hr"},
+		{"tag": "div", "text": map[string]string{"tag": "lark_md", "content": fmt.Sprintf("**%s**\n%s", c.labels["summary"], c.Findings)}},
+	}
+	if len(c.Buttons) > 0 {
+		actions := make([]map[string]interface{}, 0, len(c.Buttons))
+		for i, b := range c.Buttons {
+			buttonType := "default"
+			if i == 0 {
+				buttonType = "primary"
+			}
+			actions = append(actions, map[string]interface{}{
+				"tag":  "button",
+				"text": map[string]string{"tag": "plain_text", "content": b.Title},
+				"url":  b.URL,
+				"type": buttonType,
+			})
+		}
+		elements = append(elements, map[string]interface{}{"tag": "action", "actions": actions})
+	}
+	return map[string]interface{}{
+		"config":   map[string]bool{"wide_screen_mode": true},
+		"header":   map[string]interface{}{"title": map[string]string{"tag": "plain_text", "content": c.Title}, "template": feishuHeaderTemplates[c.Color]},
+		"elements": elements,
+	}
+}
+
+func feishuShortField(label, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"is_short": true,
+		"text":     map[string]string{"tag": "lark_md", "content": fmt.Sprintf("**%s**\n%s", label, value)},
+	}
+}
+
+// WeCom template card limits: six horizontal items, three jump links, and short texts
+const (
+	wecomCardMaxFields   = 6
+	wecomCardMaxLinks    = 3
+	wecomCardValueLength = 26
+	wecomCardDescLength  = 112
+)
+
+var wecomSourceColors = map[string]int{
+	cardColorGreen:  3,
+	cardColorYellow: 0,
+	cardColorRed:    2,
+}
+
+// wecomTemplateCard renders the card as a WeCom text notice template card, which needs a
+// link for the card action
+func (c *reviewCard) wecomTemplateCard() map[string]interface{} {
+	horizontal := make([]map[string]interface{}, 0, len(c.Fields))
+	for _, f := range c.Fields {
+		if len(horizontal) == wecomCardMaxFields {
+			break
+		}
+		horizontal = append(horizontal, map[string]interface{}{"keyname": f.Label, "value": truncateRunes(f.Value, wecomCardValueLength)})
+	}
+	jumps := make([]map[string]interface{}, 0, len(c.Buttons))
+	for _, b := range c.Buttons {
+		if len(jumps) == wecomCardMaxLinks {
+			break
+		}
+		jumps = append(jumps, map[string]interface{}{"type": 1, "title": b.Title, "url": b.URL})
+	}
+	return map[string]interface{}{
+		"msgtype": "template_card",
+		"template_card": map[string]interface{}{
+			"card_type":               "text_notice",
+			"source":                  map[string]interface{}{"desc": "CodeSentry", "desc_color": wecomSourceColors[c.Color]},
+			"main_title":              map[string]string{"title": truncateRunes(c.Title, 26)},
+			"emphasis_content":        map[string]string{"title": fmt.Sprintf("%.0f", c.Score), "desc": c.labels["score"]},
+			"sub_title_text":          truncateRunes(c.Findings, wecomCardDescLength),
+			"horizontal_content_list": horizontal,
+			"jump_list":               jumps,
+			"card_action":             map[string]interface{}{"type": 1, "url": c.Buttons[0].URL},
+		},
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestReviewLinks(t *testing.T) {
+	tests := []struct {
+		name        string
+		externalURL string
+		reviewLogID uint
+		status      string
+		wantReview  string
+		wantRetry   string
+	}{
+		{name: "no external URL", reviewLogID: 7},
+		{name: "no review", externalURL: "https://cs.example.com"},
+		{
+			name:        "failed review",
+			externalURL: "https://cs.example.com",
+			reviewLogID: 7,
+			status:      "failed",
+			wantReview:  "https://cs.example.com/admin/review-logs?id=7",
+			wantRetry:   "https://cs.example.com/admin/review-logs?id=7&action=retry",
+		},
+		{
+			name:        "review needing attention",
+			externalURL: "https://cs.example.com",
+			reviewLogID: 7,
+			status:      ReviewStatusNeedsAttention,
+			wantReview:  "https://cs.example.com/admin/review-logs?id=7",
+			wantRetry:   "https://cs.example.com/admin/review-logs?id=7&action=retry",
+		},
+		{
+			name:        "completed review",
+			externalURL: "https://cs.example.com",
+			reviewLogID: 7,
+			status:      "completed",
+			wantReview:  "https://cs.example.com/admin/review-logs?id=7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review, retry := reviewLinks(tt.externalURL, tt.reviewLogID, tt.status)
+			if review != tt.wantReview || retry != tt.wantRetry {
+				t.Errorf("reviewLinks() = %q, %q, want %q, %q", review, retry, tt.wantReview, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestNewReviewCard(t *testing.T) {
+	notification := sampleReviewNotification()
+	notification.Score = 55
+	notification.ReviewURL, notification.RetryURL = reviewLinks("https://cs.example.com", 7, "failed")
+
+	tests := []struct {
+		name        string
+		bot         models.IMBot
+		wantTitle   string
+		wantFields  int
+		wantButtons []string
+	}{
+		{
+			name:        "default fields",
+			bot:         models.IMBot{},
+			wantTitle:   "Code Review: example/project",
+			wantFields:  4,
+			wantButtons: []string{"View Review", "View MR/PR", "Retry"},
+		},
+		{
+			name:        "chinese with selected fields",
+			bot:         models.IMBot{MessageLanguage: "zh", MessageFields: "branch,score"},
+			wantTitle:   "代码审查：example/project",
+			wantFields:  1,
+			wantButtons: []string{"查看审查", "重新审查"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := newReviewCard(&tt.bot, notification)
+			if card.Title != tt.wantTitle || card.Color != cardColorRed {
+				t.Errorf("card title %q color %q, want %q red", card.Title, card.Color, tt.wantTitle)
+			}
+			if len(card.Fields) != tt.wantFields {
+				t.Errorf("card has %d fields, want %d", len(card.Fields), tt.wantFields)
+			}
+			var buttons []string
+			for _, b := range card.Buttons {
+				buttons = append(buttons, b.Title)
+			}
+			if strings.Join(buttons, ",") != strings.Join(tt.wantButtons, ",") {
+				t.Errorf("card buttons = %v, want %v", buttons, tt.wantButtons)
+			}
+		})
+	}
+}
+
+func TestReviewCardPayloads(t *testing.T) {
+	notification := sampleReviewNotification()
+	notification.ReviewURL, notification.RetryURL = reviewLinks("https://cs.example.com", 7, "failed")
+
+	tests := []struct {
+		botType  string
+		template string
+		want     []string
+	}{
+		{botType: "feishu", want: []string{`"msg_type":"interactive"`, `"template":"green"`, `"tag":"button"`}},
+		{botType: "dingtalk", want: []string{`"msgtype":"actionCard"`, `"actionURL":"https://cs.example.com/admin/review-logs?id=7"`}},
+		{botType: "wechat_work", want: []string{`"msgtype":"template_card"`, `"card_type":"text_notice"`, `"jump_list"`}},
+		{botType: "wechat_work", template: "{{project_name}}: {{score}}", want: []string{`"msgtype":"markdown_v2"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.botType+tt.template, func(t *testing.T) {
+			bot := &models.IMBot{Type: tt.botType, Webhook: "https://im.example.com/hook?access_token=x", MessageTemplate: tt.template}
+			result := recordSample(bot, false, func(a NotificationAdapter) error {
+				return a.SendRichMessage(bot.Webhook, bot, notification)
+			})
+			if !result.Success || len(result.Calls) != 1 {
+				t.Fatalf("recordSample() = %+v, want one call", result)
+			}
+			payload, _ := json.Marshal(result.Calls[0].Payload)
+			for _, want := range tt.want {
+				if !strings.Contains(string(payload), want) {
+					t.Errorf("payload %s does not contain %s", payload, want)
+				}
+			}
+		})
+	}
+}
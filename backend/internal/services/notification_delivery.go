@@ -0,0 +1,293 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Kinds of notification deliveries
+const (
+	DeliveryKindReview = "review" // Review or release message, rendered per bot
+	DeliveryKindText   = "text"   // Plain message: alerts, digests, reports
+)
+
+// Statuses of notification deliveries
+const (
+	DeliveryStatusSent     = "sent"
+	DeliveryStatusRetrying = "retrying" // Transient failure, sent again at next_attempt_at
+	DeliveryStatusFailed   = "failed"   // Permanent failure, retries used, or superseded by a later attempt
+)
+
+const (
+	NotificationMaxAttempts         = 4                   // Attempts of a notification failing transiently
+	NotificationRetryBackoff        = 30 * time.Second    // Wait before the second attempt, doubled for each next one
+	NotificationRetryInterval       = 15 * time.Second    // How often due retries are sent
+	NotificationDeliveryRetention   = 30 * 24 * time.Hour // Deliveries older than this are deleted
+	notificationRetryBatchSize      = 20
+	notificationDeliverySummarySize = 200
+)
+
+// notificationPayload is what a delivery sends, stored to retry or resend it
+type notificationPayload struct {
+	Review *ReviewNotification `json:"review,omitempty"`
+	Text   string              `json:"text,omitempty"`
+}
+
+func (p *notificationPayload) kind() string {
+	if p.Review != nil {
+		return DeliveryKindReview
+	}
+	return DeliveryKindText
+}
+
+func (p *notificationPayload) summary() string {
+	if p.Review != nil {
+		return truncateRunes(fmt.Sprintf("%s@%s by %s: %.0f", p.Review.ProjectName, p.Review.Branch, p.Review.Author, p.Review.Score), notificationDeliverySummarySize)
+	}
+	return truncateRunes(firstLine(p.Text), notificationDeliverySummarySize)
+}
+
+func (p *notificationPayload) send(bot *models.IMBot) error {
+	adapter := getAdapter(bot.Type)
+	if p.Review != nil {
+		return adapter.SendRichMessage(bot.Webhook, bot, p.Review)
+	}
+	return adapter.SendTextMessage(bot.Webhook, bot, p.Text)
+}
+
+// isTransientDeliveryError reports whether a failed send may succeed later: network errors,
+// timeouts, 429 or 5xx responses and the error codes of rate limits. Other error statuses and
+// codes mean the bot or message is wrong.
+func isTransientDeliveryError(err error) bool {
+	var statusErr *webhookStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+	}
+	var codeErr *webhookCodeError
+	if errors.As(err, &codeErr) {
+		return codeErr.Transient
+	}
+	return true
+}
+
+// notificationRetryDelay returns the wait before the next attempt after the given one
+func notificationRetryDelay(attempt int) time.Duration {
+	return NotificationRetryBackoff << (attempt - 1)
+}
+
+// deliverNotification sends a notification to a bot and records the attempt
+func deliverNotification(db *gorm.DB, bot *models.IMBot, projectID *uint, payload *notificationPayload) error {
+	_, err := sendDelivery(db, bot, projectID, payload, nil, 1)
+	return err
+}
+
+// sendDelivery sends a payload and records the attempt; transient failures are scheduled
+// for a retry until the attempts are used
+func sendDelivery(db *gorm.DB, bot *models.IMBot, projectID *uint, payload *notificationPayload, parentID *uint, attempt int) (*models.NotificationDelivery, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(data)
+	delivery := &models.NotificationDelivery{
+		ParentID:    parentID,
+		Attempt:     attempt,
+		BotID:       bot.ID,
+		BotName:     bot.Name,
+		BotType:     bot.Type,
+		TenantID:    bot.TenantID,
+		ProjectID:   projectID,
+		Kind:        payload.kind(),
+		Payload:     string(data),
+		PayloadHash: hex.EncodeToString(hash[:]),
+		Summary:     payload.summary(),
+		Status:      DeliveryStatusSent,
+	}
+
+	start := time.Now()
+	sendErr := payload.send(bot)
+	delivery.DurationMs = time.Since(start).Milliseconds()
+	if sendErr != nil {
+		delivery.Status = DeliveryStatusFailed
+		delivery.Error = truncateRunes(sendErr.Error(), 1000)
+		if attempt < NotificationMaxAttempts && isTransientDeliveryError(sendErr) {
+			next := time.Now().Add(notificationRetryDelay(attempt))
+			delivery.Status, delivery.NextAttemptAt = DeliveryStatusRetrying, &next
+		}
+	}
+	if db != nil {
+		if err := db.Create(delivery).Error; err != nil {
+			logger.Warnf("[Notification] Failed to record delivery to bot %s: %v", bot.Name, err)
+		}
+	}
+	return delivery, sendErr
+}
+
+// NotificationDeliveryService lists, retries and resends notification deliveries
+type NotificationDeliveryService struct {
+	db *gorm.DB
+}
+
+func NewNotificationDeliveryService(db *gorm.DB) *NotificationDeliveryService {
+	return &NotificationDeliveryService{db: db}
+}
+
+type NotificationDeliveryListRequest struct {
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	BotID     uint   `form:"bot_id"`
+	ProjectID uint   `form:"project_id"`
+	Status    string `form:"status" binding:"omitempty,oneof=sent retrying failed"`
+}
+
+type NotificationDeliveryListResponse struct {
+	Total    int64                         `json:"total"`
+	Page     int                           `json:"page"`
+	PageSize int                           `json:"page_size"`
+	Items    []models.NotificationDelivery `json:"items"`
+}
+
+// List returns paginated deliveries of a tenant's bots, newest first
+func (s *NotificationDeliveryService) List(req *NotificationDeliveryListRequest, tenantID uint) (*NotificationDeliveryListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	query := ScopeTenant(s.db.Model(&models.NotificationDelivery{}), tenantID)
+	if req.BotID > 0 {
+		query = query.Where("bot_id = ?", req.BotID)
+	}
+	if req.ProjectID > 0 {
+		query = query.Where("project_id = ?", req.ProjectID)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.NotificationDelivery
+	if err := query.Order("id DESC").Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return &NotificationDeliveryListResponse{Total: total, Page: req.Page, PageSize: req.PageSize, Items: items}, nil
+}
+
+func (s *NotificationDeliveryService) GetByID(id uint) (*models.NotificationDelivery, error) {
+	var delivery models.NotificationDelivery
+	if err := s.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Resend sends a delivery's notification again with the bot's current settings, recorded
+// as the next attempt of the notification. A pending retry of the delivery is dropped.
+// The returned attempt is nil when nothing was sent.
+func (s *NotificationDeliveryService) Resend(delivery *models.NotificationDelivery) (*models.NotificationDelivery, error) {
+	if _, err := s.claimRetry(delivery.ID); err != nil {
+		return nil, err
+	}
+	return s.resend(delivery)
+}
+
+// claimRetry drops the pending retry of a delivery and reports whether this call did, so a
+// due retry is sent by only one of the instances polling for it
+func (s *NotificationDeliveryService) claimRetry(id uint) (bool, error) {
+	res := s.db.Model(&models.NotificationDelivery{}).
+		Where("id = ? AND status = ?", id, DeliveryStatusRetrying).
+		UpdateColumns(map[string]interface{}{"status": DeliveryStatusFailed, "next_attempt_at": nil})
+	return res.RowsAffected == 1, res.Error
+}
+
+func (s *NotificationDeliveryService) resend(delivery *models.NotificationDelivery) (*models.NotificationDelivery, error) {
+	var bot models.IMBot
+	if err := s.db.First(&bot, delivery.BotID).Error; err != nil {
+		return nil, fmt.Errorf("IM bot not found: %w", err)
+	}
+	if !bot.IsActive {
+		return nil, fmt.Errorf("IM bot %s is not active", bot.Name)
+	}
+	var payload notificationPayload
+	if err := json.Unmarshal([]byte(delivery.Payload), &payload); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	rootID := delivery.ID
+	if delivery.ParentID != nil {
+		rootID = *delivery.ParentID
+	}
+	var lastAttempt int
+	s.db.Model(&models.NotificationDelivery{}).Where("id = ? OR parent_id = ?", rootID, rootID).
+		Select("COALESCE(MAX(attempt), 0)").Scan(&lastAttempt)
+	return sendDelivery(s.db, &bot, delivery.ProjectID, &payload, &rootID, lastAttempt+1)
+}
+
+// RetryDue sends the deliveries whose retry is due, each claimed first so it isn't sent twice,
+// and deletes expired deliveries
+func (s *NotificationDeliveryService) RetryDue(now time.Time) error {
+	var due []models.NotificationDelivery
+	if err := s.db.Where("status = ? AND next_attempt_at <= ?", DeliveryStatusRetrying, now).
+		Order("next_attempt_at ASC").Limit(notificationRetryBatchSize).Find(&due).Error; err != nil {
+		return err
+	}
+	for i := range due {
+		claimed, err := s.claimRetry(due[i].ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			// Sent by another instance, or resent by hand
+			continue
+		}
+		next, err := s.resend(&due[i])
+		if err != nil && next == nil {
+			logger.Warnf("[Notification] Retry of delivery %d dropped: %v", due[i].ID, err)
+		} else if err != nil {
+			logger.Warnf("[Notification] Retry %d of delivery to bot %s failed: %v", next.Attempt, next.BotName, err)
+		}
+	}
+	return s.db.Where("created_at < ?", now.Add(-NotificationDeliveryRetention)).Delete(&models.NotificationDelivery{}).Error
+}
+
+var notificationRetryStopChan chan struct{}
+
+// StartNotificationRetryScheduler resends notifications that failed transiently
+func StartNotificationRetryScheduler(db *gorm.DB) {
+	notificationRetryStopChan = make(chan struct{})
+	service := NewNotificationDeliveryService(db)
+	RegisterScheduler("notification_retry", NotificationRetryInterval)
+	go func() {
+		ticker := time.NewTicker(NotificationRetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("notification_retry", func() error { return service.RetryDue(time.Now()) })
+			case <-notificationRetryStopChan:
+				logger.Infof("[Notification] Retry scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopNotificationRetryScheduler stops the notification retry scheduler
+func StopNotificationRetryScheduler() {
+	if notificationRetryStopChan != nil {
+		close(notificationRetryStopChan)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIsTransientDeliveryError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "network error", err: errors.New("dial tcp: connection refused"), want: true},
+		{name: "rate limited", err: &webhookStatusError{StatusCode: 429}, want: true},
+		{name: "server error", err: &webhookStatusError{StatusCode: 502}, want: true},
+		{name: "wrapped server error", err: fmt.Errorf("send: %w", &webhookStatusError{StatusCode: 503}), want: true},
+		{name: "bad request", err: &webhookStatusError{StatusCode: 400}},
+		{name: "not found", err: &webhookStatusError{StatusCode: 404}},
+		{name: "rate limit code", err: &webhookCodeError{Code: 130101, Transient: true}, want: true},
+		{name: "invalid token code", err: &webhookCodeError{Code: 300001}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransientDeliveryError(tt.err); got != tt.want {
+				t.Errorf("isTransientDeliveryError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckWebhookResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		botType   string
+		body      string
+		wantCode  int
+		transient bool
+	}{
+		{name: "dingtalk ok", botType: "dingtalk", body: `{"errcode":0,"errmsg":"ok"}`},
+		{name: "dingtalk rate limit", botType: "dingtalk", body: `{"errcode":130101,"errmsg":"send too fast"}`, wantCode: 130101, transient: true},
+		{name: "dingtalk keyword missing", botType: "dingtalk", body: `{"errcode":310000,"errmsg":"keywords not in content"}`, wantCode: 310000},
+		{name: "wecom frequency limit", botType: "wechat_work", body: `{"errcode":45009,"errmsg":"api freq out of limit"}`, wantCode: 45009, transient: true},
+		{name: "wecom invalid key", botType: "wechat_work", body: `{"errcode":93000,"errmsg":"invalid webhook url"}`, wantCode: 93000},
+		{name: "feishu ok", botType: "feishu", body: `{"code":0,"msg":"success"}`},
+		{name: "feishu legacy ok", botType: "feishu", body: `{"StatusCode":0,"StatusMessage":"success"}`},
+		{name: "feishu sign mismatch", botType: "feishu", body: `{"code":19021,"msg":"sign match fail"}`, wantCode: 19021},
+		{name: "feishu too many requests", botType: "feishu", body: `{"code":9499,"msg":"too many request"}`, wantCode: 9499, transient: true},
+		{name: "slack body is not checked", botType: "slack", body: `{"errcode":1}`},
+		{name: "plain text body", botType: "dingtalk", body: `ok`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkWebhookResponse(tt.botType, []byte(tt.body))
+			var codeErr *webhookCodeError
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Errorf("checkWebhookResponse() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.As(err, &codeErr) || codeErr.Code != tt.wantCode || codeErr.Transient != tt.transient {
+				t.Errorf("checkWebhookResponse() = %#v, want code %d, transient %v", err, tt.wantCode, tt.transient)
+			}
+		})
+	}
+}
+
+// newDingTalkServer answers webhook posts with the given errcode, counting the posts
+func newDingTalkServer(t *testing.T, errcode *atomic.Int64, posts *atomic.Int64) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+		fmt.Fprintf(w, `{"errcode":%d,"errmsg":"test"}`, errcode.Load())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSendDelivery(t *testing.T) {
+	db := newTestDB(t)
+	var errcode, posts atomic.Int64
+	server := newDingTalkServer(t, &errcode, &posts)
+	bot := &models.IMBot{Name: "team", Type: "dingtalk", Webhook: server.URL + "/robot/send?access_token=x", IsActive: true}
+	mustCreate(t, db, bot)
+
+	tests := []struct {
+		name    string
+		errcode int64
+		attempt int
+		want    string
+	}{
+		{name: "sent", errcode: 0, attempt: 1, want: DeliveryStatusSent},
+		{name: "rate limited", errcode: 130101, attempt: 1, want: DeliveryStatusRetrying},
+		{name: "rate limited on the last attempt", errcode: 130101, attempt: NotificationMaxAttempts, want: DeliveryStatusFailed},
+		{name: "rejected", errcode: 310000, attempt: 1, want: DeliveryStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errcode.Store(tt.errcode)
+			delivery, err := sendDelivery(db, bot, nil, &notificationPayload{Text: "hello"}, nil, tt.attempt)
+			if (err != nil) != (tt.errcode != 0) {
+				t.Errorf("sendDelivery() error = %v", err)
+			}
+			var stored models.NotificationDelivery
+			if err := db.First(&stored, delivery.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.want || (stored.NextAttemptAt != nil) != (tt.want == DeliveryStatusRetrying) {
+				t.Errorf("stored delivery = %s, next attempt %v, want %s", stored.Status, stored.NextAttemptAt, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotificationDeliveryRetry(t *testing.T) {
+	db := newTestDB(t)
+	var errcode, posts atomic.Int64
+	server := newDingTalkServer(t, &errcode, &posts)
+	bot := &models.IMBot{Name: "team", Type: "dingtalk", Webhook: server.URL + "/robot/send?access_token=x", IsActive: true}
+	mustCreate(t, db, bot)
+	service := NewNotificationDeliveryService(db)
+
+	errcode.Store(130101)
+	first, _ := sendDelivery(db, bot, nil, &notificationPayload{Text: "hello"}, nil, 1)
+	errcode.Store(0)
+
+	// Not due yet
+	if err := service.RetryDue(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if posts.Load() != 1 {
+		t.Fatalf("posts = %d before the retry is due, want 1", posts.Load())
+	}
+
+	// Another instance claimed the retry between listing and sending it
+	due := first.NextAttemptAt.Add(time.Second)
+	claimed, err := service.claimRetry(first.ID)
+	if err != nil || !claimed {
+		t.Fatalf("claimRetry() = %v, %v, want true", claimed, err)
+	}
+	if err := service.RetryDue(due); err != nil {
+		t.Fatal(err)
+	}
+	if posts.Load() != 1 {
+		t.Errorf("posts = %d after the retry was claimed elsewhere, want 1", posts.Load())
+	}
+
+	// Due and unclaimed: sent once as the second attempt, however often the scheduler runs
+	db.Model(&models.NotificationDelivery{}).Where("id = ?", first.ID).
+		UpdateColumns(map[string]interface{}{"status": DeliveryStatusRetrying, "next_attempt_at": first.NextAttemptAt})
+	for i := 0; i < 2; i++ {
+		if err := service.RetryDue(due); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if posts.Load() != 2 {
+		t.Errorf("posts = %d after the retry, want 2", posts.Load())
+	}
+	var attempts []models.NotificationDelivery
+	db.Order("id").Find(&attempts)
+	if len(attempts) != 2 || attempts[0].Status != DeliveryStatusFailed || attempts[1].Attempt != 2 ||
+		attempts[1].Status != DeliveryStatusSent || attempts[1].ParentID == nil || *attempts[1].ParentID != first.ID {
+		t.Fatalf("attempts = %+v", attempts)
+	}
+
+	// Resending by hand records the next attempt of the same notification
+	next, err := service.Resend(&attempts[1])
+	if err != nil || next.Attempt != 3 || *next.ParentID != first.ID {
+		t.Errorf("Resend() = %+v, %v, want attempt 3 of delivery %d", next, err, first.ID)
+	}
+
+	db.Model(bot).Update("is_active", false)
+	if next, err := service.Resend(&attempts[1]); err == nil || next != nil {
+		t.Errorf("Resend() to an inactive bot = %+v, %v, want an error", next, err)
+	}
+}
+
+func TestNotificationRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 3, want: 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := notificationRetryDelay(tt.attempt); got != tt.want {
+			t.Errorf("notificationRetryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestNotificationPayloadSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  notificationPayload
+		wantKind string
+		want     string
+	}{
+		{
+			name:     "review",
+			payload:  notificationPayload{Review: &ReviewNotification{ProjectName: "api", Branch: "main", Author: "alice", Score: 82.4}},
+			wantKind: DeliveryKindReview,
+			want:     "api@main by alice: 82",
+		},
+		{name: "text", payload: notificationPayload{Text: "Daily digest\n3 reviews"}, wantKind: DeliveryKindText, want: "Daily digest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.payload.kind(); got != tt.wantKind {
+				t.Errorf("kind() = %q, want %q", got, tt.wantKind)
+			}
+			if got := tt.payload.summary(); got != tt.want {
+				t.Errorf("summary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
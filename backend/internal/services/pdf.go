@@ -0,0 +1,341 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A4 page layout of generated PDF documents, in points
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+	pdfMargin     = 50.0
+)
+
+// Fonts of generated PDF documents: the standard Type 1 fonts every reader provides, and
+// for other characters such as Chinese the Adobe CJK font readers substitute a system font for
+const (
+	pdfFontRegular = "F1" // Helvetica
+	pdfFontBold    = "F2" // Helvetica-Bold
+	pdfFontMono    = "F3" // Courier
+	pdfFontCJK     = "F4" // STSong-Light, UTF-16 encoded
+)
+
+// pdfWinAnsi maps the characters of WinAnsiEncoding outside Latin-1 to their code
+var pdfWinAnsi = map[rune]byte{
+	'€': 0x80, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+// pdfColor is an RGB color with components from 0 to 1
+type pdfColor [3]float64
+
+var (
+	pdfBlack = pdfColor{0, 0, 0}
+	pdfGrey  = pdfColor{0.45, 0.45, 0.45}
+	pdfWhite = pdfColor{1, 1, 1}
+)
+
+// parsePDFColor parses a #rgb or #rrggbb color, returning fallback when it isn't one
+func parsePDFColor(hex string, fallback pdfColor) pdfColor {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return fallback
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return fallback
+	}
+	return pdfColor{float64(v>>16&0xff) / 255, float64(v>>8&0xff) / 255, float64(v&0xff) / 255}
+}
+
+// pdfDocument lays out text top to bottom on A4 pages. Characters of WinAnsiEncoding use the
+// standard fonts and the other characters of the Basic Multilingual Plane the CJK font, so
+// documents need no embedded fonts; characters beyond it, such as emoji, print as "?".
+type pdfDocument struct {
+	title  string
+	footer string
+	pages  []*bytes.Buffer
+	y      float64 // Baseline of the next line on the current page
+}
+
+func newPDFDocument(title, footer string) *pdfDocument {
+	d := &pdfDocument{title: title, footer: footer}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfPageHeight - pdfMargin
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// ensure starts a new page when less than height is left on the current one
+func (d *pdfDocument) ensure(height float64) {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+}
+
+// space moves down by height points
+func (d *pdfDocument) space(height float64) {
+	d.y -= height
+}
+
+// rect fills a rectangle, y being its bottom edge
+func (d *pdfDocument) rect(x, y, w, h float64, color pdfColor) {
+	fmt.Fprintf(d.page(), "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", color[0], color[1], color[2], x, y, w, h)
+}
+
+// textAt draws a single line of text at a position
+func (d *pdfDocument) textAt(x, y float64, font string, size float64, color pdfColor, text string) {
+	pdfText(d.page(), x, y, font, size, color, text)
+}
+
+func pdfText(content *bytes.Buffer, x, y float64, font string, size float64, color pdfColor, text string) {
+	fmt.Fprintf(content, "BT %.3f %.3f %.3f rg %.2f %.2f Td", color[0], color[1], color[2], x, y)
+	for _, run := range pdfRuns(text) {
+		if run.wide {
+			fmt.Fprintf(content, " /%s %.1f Tf <%s> Tj", pdfFontCJK, size, pdfUTF16Hex(run.text))
+		} else {
+			fmt.Fprintf(content, " /%s %.1f Tf (%s) Tj", font, size, pdfEscape(run.text))
+		}
+	}
+	content.WriteString(" ET\n")
+}
+
+// pdfRun is a part of a line drawn in one font, wide runs in the CJK font
+type pdfRun struct {
+	text string
+	wide bool
+}
+
+// pdfRuns splits text into the runs of the standard fonts and of the CJK font
+func pdfRuns(text string) []pdfRun {
+	var runs []pdfRun
+	for _, r := range text {
+		wide := pdfWide(r)
+		if n := len(runs); n > 0 && runs[n-1].wide == wide {
+			runs[n-1].text += string(r)
+		} else {
+			runs = append(runs, pdfRun{text: string(r), wide: wide})
+		}
+	}
+	return runs
+}
+
+// pdfWinAnsiByte returns the WinAnsiEncoding code of a character
+func pdfWinAnsiByte(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+		return byte(r), true
+	case pdfWinAnsi[r] != 0:
+		return pdfWinAnsi[r], true
+	}
+	return 0, false
+}
+
+// pdfWide reports whether a character is drawn in the CJK font
+func pdfWide(r rune) bool {
+	if _, ok := pdfWinAnsiByte(r); ok {
+		return false
+	}
+	return r > 0xff && r <= 0xffff && !(r >= 0xd800 && r <= 0xdfff)
+}
+
+// pdfUTF16Hex encodes text as the hex digits of UTF-16BE
+func pdfUTF16Hex(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// pdfTextString encodes text as a PDF text string such as the document title: a literal
+// when it fits WinAnsiEncoding, otherwise UTF-16BE with a byte order mark
+func pdfTextString(text string) string {
+	for _, r := range text {
+		if _, ok := pdfWinAnsiByte(r); !ok {
+			return "<FEFF" + pdfUTF16Hex(strings.Map(func(r rune) rune {
+				if r > 0xffff {
+					return '?'
+				}
+				return r
+			}, text)) + ">"
+		}
+	}
+	return "(" + pdfEscape(text) + ")"
+}
+
+// paragraph draws text wrapped to the page width, starting indent points from the margin
+func (d *pdfDocument) paragraph(font string, size float64, color pdfColor, indent float64, text string) {
+	leading := size * 1.35
+	width := pdfPageWidth - 2*pdfMargin - indent
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\t", "    "), "\n") {
+		for _, line := range pdfWrap(raw, font, size, width) {
+			d.ensure(leading)
+			d.y -= leading
+			d.textAt(pdfMargin+indent, d.y+size*0.3, font, size, color, line)
+		}
+	}
+}
+
+// heading draws a section title underlined in color
+func (d *pdfDocument) heading(text string, color pdfColor) {
+	d.ensure(40)
+	d.space(14)
+	d.paragraph(pdfFontBold, 13, pdfBlack, 0, text)
+	d.rect(pdfMargin, d.y, pdfPageWidth-2*pdfMargin, 1, color)
+	d.space(6)
+}
+
+// row draws a label and a value side by side, the value wrapped in its column
+func (d *pdfDocument) row(label, value string) {
+	const labelWidth = 120
+	lines := pdfWrap(value, pdfFontRegular, 10, pdfPageWidth-2*pdfMargin-labelWidth)
+	for i, line := range lines {
+		d.ensure(14)
+		d.y -= 14
+		if i == 0 {
+			d.textAt(pdfMargin, d.y+3, pdfFontBold, 10, pdfGrey, label)
+		}
+		d.textAt(pdfMargin+labelWidth, d.y+3, pdfFontRegular, 10, pdfBlack, line)
+	}
+}
+
+// Bytes returns the document, with the footer and page numbers on every page
+func (d *pdfDocument) Bytes() []byte {
+	for i, content := range d.pages {
+		pdfText(content, pdfMargin, pdfMargin/2, pdfFontRegular, 8, pdfGrey, d.footer)
+		label := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		pdfText(content, pdfPageWidth-pdfMargin-pdfTextWidth(label, pdfFontRegular, 8), pdfMargin/2, pdfFontRegular, 8, pdfGrey, label)
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1-9 are fixed, then a page and its content per page
+	const firstPage = 10
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title %s /Producer (CodeSentry) /CreationDate (D:%s) >>",
+		pdfTextString(d.title), time.Now().UTC().Format("20060102150405Z")))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UTF16-H /DescendantFonts [8 0 R] >>")
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> /FontDescriptor 9 0 R /DW 1000 >>")
+	object("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+		"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R /F4 7 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfEncode converts text to WinAnsiEncoding, replacing other characters by "?"
+func pdfEncode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		if c, ok := pdfWinAnsiByte(r); ok {
+			out = append(out, c)
+		} else {
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// pdfEscape encodes text as the content of a PDF string literal
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, c := range pdfEncode(text) {
+		if c == '\\' || c == '(' || c == ')' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the width of text in points. Courier and the full-width CJK font
+// are exact; Helvetica uses its average widths, with room to spare for wide letters.
+func pdfTextWidth(text, font string, size float64) float64 {
+	factor := 0.55
+	switch font {
+	case pdfFontBold, pdfFontMono:
+		factor = 0.6
+	}
+	width := 0.0
+	for _, r := range text {
+		if pdfWide(r) {
+			width += size
+		} else {
+			width += size * factor
+		}
+	}
+	return width
+}
+
+// pdfWrap splits a line of text at spaces into lines fitting width, breaking words that don't
+func pdfWrap(text, font string, size, width float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Split(text, " ") {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if pdfTextWidth(candidate, font, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		line = word
+		for pdfTextWidth(line, font, size) > width {
+			runes := []rune(line)
+			n := 1
+			for n < len(runes) && pdfTextWidth(string(runes[:n+1]), font, size) <= width {
+				n++
+			}
+			if n >= len(runes) {
+				break
+			}
+			lines = append(lines, string(runes[:n]))
+			line = string(runes[n:])
+		}
+	}
+	return append(lines, line)
+}
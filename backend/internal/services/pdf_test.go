@@ -0,0 +1,108 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`a (b) \c`, `a \(b\) \\c`},
+		{"café – “ok”", "caf\xe9 \x96 \x93ok\x94"},
+		{"代码 🚀", "?? ?"},
+	}
+	for _, tt := range tests {
+		if got := pdfEscape(tt.in); got != tt.want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFWrap(t *testing.T) {
+	width := pdfTextWidth("0123456789", pdfFontMono, 10)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"short", []string{"short"}},
+		{"aaaa bbbb cccc", []string{"aaaa bbbb", "cccc"}},
+		{"abcdefghijklmnopqrstuvwxy", []string{"abcdefghij", "klmnopqrst", "uvwxy"}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := pdfWrap(tt.text, pdfFontMono, 10, width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pdfWrap(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPDFText_CJK(t *testing.T) {
+	var content bytes.Buffer
+	pdfText(&content, 10, 20, pdfFontRegular, 10, pdfBlack, "Fix 代码 🚀")
+	want := "BT 0.000 0.000 0.000 rg 10.00 20.00 Td /F1 10.0 Tf (Fix ) Tj /F4 10.0 Tf <4EE37801> Tj /F1 10.0 Tf ( ?) Tj ET\n"
+	if content.String() != want {
+		t.Errorf("pdfText = %q, want %q", content.String(), want)
+	}
+
+	width := pdfTextWidth("代码代码", pdfFontRegular, 10)
+	if got := pdfWrap("代码代码代码", pdfFontRegular, 10, width); !reflect.DeepEqual(got, []string{"代码代码", "代码"}) {
+		t.Errorf("pdfWrap of CJK text = %q", got)
+	}
+	if got := pdfTextString("周报"); got != "<FEFF546862A5>" {
+		t.Errorf("pdfTextString = %q", got)
+	}
+}
+
+func TestParsePDFColor(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want pdfColor
+	}{
+		{"#ffffff", pdfWhite},
+		{"#000", pdfBlack},
+		{"#4c1", pdfColor{0x44 / 255.0, 0xcc / 255.0, 0x11 / 255.0}},
+		{"blue", pdfGrey},
+		{"#12345g", pdfGrey},
+	}
+	for _, tt := range tests {
+		if got := parsePDFColor(tt.hex, pdfGrey); got != tt.want {
+			t.Errorf("parsePDFColor(%q) = %v, want %v", tt.hex, got, tt.want)
+		}
+	}
+}
+
+func TestPDFDocument(t *testing.T) {
+	d := newPDFDocument("Report (draft)", "footer")
+	for i := 0; i < 80; i++ {
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, "line")
+	}
+	out := d.Bytes()
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF document:\n%s", out)
+	}
+	if len(d.pages) != 2 || !bytes.Contains(out, []byte("/Count 2")) || !bytes.Contains(out, []byte("(Page 2 of 2)")) {
+		t.Errorf("pages = %d, want 2", len(d.pages))
+	}
+	if !bytes.Contains(out, []byte(`/Title (Report \(draft\))`)) {
+		t.Error("title not escaped")
+	}
+
+	// The cross-reference table points at each object
+	xref := bytes.LastIndex(out, []byte("\nxref\n")) + 1
+	table := strings.Split(string(out[xref:]), "\n")[3:]
+	for i, entry := range table[:len(d.pages)*2+9] {
+		offset, err := strconv.Atoi(strings.Fields(entry)[0])
+		if err != nil {
+			t.Fatalf("xref entry %q: %v", entry, err)
+		}
+		if want := strconv.Itoa(i+1) + " 0 obj"; !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, out[offset:offset+10])
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// PendingTask is a queued review task claimed by a worker
+type PendingTask struct {
+	ID   uint
+	Task *ReviewTask
+}
+
+// PendingTaskStore keeps the tasks of the in-process queue until they are processed
+type PendingTaskStore interface {
+	// Add queues a task
+	Add(task *ReviewTask) error
+	// Claim marks the oldest queued task as running and returns it, or nil if none is queued
+	Claim() (*PendingTask, error)
+	// Done removes a processed task
+	Done(id uint) error
+	// Requeue returns a claimed task to the queue
+	Requeue(id uint) error
+	// Recover re-queues tasks left running by a previous process, and tasks running longer than staleAfter
+	Recover(staleAfter time.Duration) (int64, error)
+	// Count returns the number of queued and running tasks
+	Count() (int64, error)
+}
+
+// DBTaskStore persists queued tasks in the pending_review_tasks table
+type DBTaskStore struct {
+	db       *gorm.DB
+	workerID string
+}
+
+// NewDBTaskStore creates a database backed task store
+func NewDBTaskStore(db *gorm.DB) *DBTaskStore {
+	hostname, _ := os.Hostname()
+	return &DBTaskStore{db: db, workerID: hostname}
+}
+
+func (s *DBTaskStore) Add(task *ReviewTask) error {
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return s.db.Create(&models.PendingReviewTask{
+		ReviewLogID: task.ReviewLogID,
+		Payload:     string(payload),
+		Status:      "pending",
+	}).Error
+}
+
+func (s *DBTaskStore) Claim() (*PendingTask, error) {
+	for {
+		var row models.PendingReviewTask
+		err := s.db.Where("status = ?", "pending").Order("id ASC").First(&row).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Conditional update so that a row is claimed by one worker only
+		now := time.Now()
+		result := s.db.Model(&models.PendingReviewTask{}).
+			Where("id = ? AND status = ?", row.ID, "pending").
+			Updates(map[string]interface{}{"status": "running", "locked_by": s.workerID, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		var task ReviewTask
+		if err := json.Unmarshal([]byte(row.Payload), &task); err != nil {
+			// A corrupt payload can never be processed; drop it
+			s.db.Delete(&models.PendingReviewTask{}, row.ID)
+			return nil, err
+		}
+		return &PendingTask{ID: row.ID, Task: &task}, nil
+	}
+}
+
+func (s *DBTaskStore) Done(id uint) error {
+	return s.db.Delete(&models.PendingReviewTask{}, id).Error
+}
+
+func (s *DBTaskStore) Requeue(id uint) error {
+	return s.db.Model(&models.PendingReviewTask{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": "pending", "locked_by": "", "started_at": nil}).Error
+}
+
+func (s *DBTaskStore) Recover(staleAfter time.Duration) (int64, error) {
+	result := s.db.Model(&models.PendingReviewTask{}).
+		Where("status = ? AND (locked_by = ? OR started_at < ?)", "running", s.workerID, time.Now().Add(-staleAfter)).
+		Updates(map[string]interface{}{"status": "pending", "locked_by": "", "started_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+func (s *DBTaskStore) Count() (int64, error) {
+	var count int64
+	err := s.db.Model(&models.PendingReviewTask{}).Count(&count).Error
+	return count, err
+}
+
+// MemoryTaskStore keeps queued tasks in memory; they are lost on restart
+type MemoryTaskStore struct {
+	mu      sync.Mutex
+	nextID  uint
+	pending []*PendingTask
+	running map[uint]*PendingTask
+}
+
+// NewMemoryTaskStore creates an in-memory task store
+func NewMemoryTaskStore() *MemoryTaskStore {
+	return &MemoryTaskStore{running: make(map[uint]*PendingTask)}
+}
+
+func (s *MemoryTaskStore) Add(task *ReviewTask) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	s.pending = append(s.pending, &PendingTask{ID: s.nextID, Task: task})
+	return nil
+}
+
+func (s *MemoryTaskStore) Claim() (*PendingTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == 0 {
+		return nil, nil
+	}
+	task := s.pending[0]
+	s.pending = s.pending[1:]
+	s.running[task.ID] = task
+	return task, nil
+}
+
+func (s *MemoryTaskStore) Done(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+	return nil
+}
+
+func (s *MemoryTaskStore) Requeue(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task, ok := s.running[id]; ok {
+		delete(s.running, id)
+		s.pending = append([]*PendingTask{task}, s.pending...)
+	}
+	return nil
+}
+
+func (s *MemoryTaskStore) Recover(time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (s *MemoryTaskStore) Count() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.pending) + len(s.running)), nil
+}
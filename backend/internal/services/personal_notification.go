@@ -0,0 +1,507 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// slackPostMessageURL is the Slack Web API method direct messages are sent with
+var slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// defaultDigestTime is when personal digests are sent when the user didn't choose a time
+const defaultDigestTime = "09:00"
+
+// PersonalDigestMaxItems caps the number of low-score reviews listed in a personal digest
+const PersonalDigestMaxItems = 10
+
+// ErrNoDirectMessageBot is returned when a user's preferences have no bot able to send them direct messages
+var ErrNoDirectMessageBot = errors.New("no IM bot is set up to send direct messages")
+
+// ErrInvalidVerificationCode is returned when a code does not match the one sent to the linked identity
+var ErrInvalidVerificationCode = errors.New("invalid verification code")
+
+// PersonalNotificationService delivers the personal notifications users subscribed to as direct
+// messages: reviews of their commits, reviews in the projects they maintain, and a daily digest
+type PersonalNotificationService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewPersonalNotificationService(db *gorm.DB) *PersonalNotificationService {
+	return &PersonalNotificationService{db: db, httpClient: NotificationHTTPClient()}
+}
+
+// DirectMessageBot is an IM bot users can receive direct messages from
+type DirectMessageBot struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"` // slack, telegram
+}
+
+// NotificationPreferenceResponse is a user's personal notification settings
+type NotificationPreferenceResponse struct {
+	models.UserNotificationPreference
+	Bots []DirectMessageBot `json:"bots"` // Bots the user can pick
+}
+
+// UpdateNotificationPreferenceRequest changes a user's personal notification settings
+type UpdateNotificationPreferenceRequest struct {
+	IMBotID            *uint    `json:"im_bot_id"` // 0 unlinks the bot
+	SlackUserID        *string  `json:"slack_user_id"`
+	TelegramChatID     *string  `json:"telegram_chat_id"`
+	MyCommits          *bool    `json:"my_commits"`
+	MaintainedProjects *bool    `json:"maintained_projects"`
+	ScoreBelow         *float64 `json:"score_below" binding:"omitempty,min=0,max=100"`
+	DailyDigest        *bool    `json:"daily_digest"`
+	DigestTime         *string  `json:"digest_time"`
+	Timezone           *string  `json:"timezone"`
+}
+
+// directMessageBots returns the active bots able to send direct messages in a tenant
+func (s *PersonalNotificationService) directMessageBots(tenantID uint) ([]DirectMessageBot, error) {
+	var bots []models.IMBot
+	query := s.db.Where("is_active = ? AND (type = ? OR (type = ? AND secret <> ?))", true, "telegram", "slack", "")
+	if err := ScopeTenant(query, tenantID).Order("id").Find(&bots).Error; err != nil {
+		return nil, err
+	}
+	result := make([]DirectMessageBot, len(bots))
+	for i, bot := range bots {
+		result[i] = DirectMessageBot{ID: bot.ID, Name: bot.Name, Type: bot.Type}
+	}
+	return result, nil
+}
+
+// GetPreference returns the personal notification settings of a user, defaults when unset
+func (s *PersonalNotificationService) GetPreference(user *models.User) (*NotificationPreferenceResponse, error) {
+	pref := models.UserNotificationPreference{UserID: user.ID, DigestTime: defaultDigestTime}
+	if err := s.db.Where("user_id = ?", user.ID).First(&pref).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	bots, err := s.directMessageBots(user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationPreferenceResponse{UserNotificationPreference: pref, Bots: bots}, nil
+}
+
+// UpdatePreference applies the given changes to a user's personal notification settings
+func (s *PersonalNotificationService) UpdatePreference(user *models.User, req *UpdateNotificationPreferenceRequest) (*NotificationPreferenceResponse, error) {
+	pref := models.UserNotificationPreference{UserID: user.ID, DigestTime: defaultDigestTime}
+	if err := s.db.Where("user_id = ?", user.ID).First(&pref).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	identity := linkedIdentity(&pref)
+	if req.IMBotID != nil {
+		pref.IMBotID = nil
+		if *req.IMBotID != 0 {
+			bots, err := s.directMessageBots(user.TenantID)
+			if err != nil {
+				return nil, err
+			}
+			if !containsDirectMessageBot(bots, *req.IMBotID) {
+				return nil, fmt.Errorf("IM bot %d cannot send direct messages: use an active Telegram bot, or a Slack bot with a bot token as its secret", *req.IMBotID)
+			}
+			pref.IMBotID = req.IMBotID
+		}
+	}
+	if req.SlackUserID != nil {
+		pref.SlackUserID = strings.TrimSpace(*req.SlackUserID)
+	}
+	if req.TelegramChatID != nil {
+		pref.TelegramChatID = strings.TrimSpace(*req.TelegramChatID)
+	}
+	// Anyone can type in a Slack user or Telegram chat ID, so a changed identity receives
+	// nothing until its owner enters the code sent to it
+	if linkedIdentity(&pref) != identity {
+		pref.VerifiedAt = nil
+		pref.VerificationCode = ""
+	}
+	if req.MyCommits != nil {
+		pref.MyCommits = *req.MyCommits
+	}
+	if req.MaintainedProjects != nil {
+		pref.MaintainedProjects = *req.MaintainedProjects
+	}
+	if req.ScoreBelow != nil {
+		pref.ScoreBelow = *req.ScoreBelow
+	}
+	if req.DailyDigest != nil {
+		pref.DailyDigest = *req.DailyDigest
+	}
+	if req.DigestTime != nil {
+		pref.DigestTime = strings.TrimSpace(*req.DigestTime)
+		if pref.DigestTime == "" {
+			pref.DigestTime = defaultDigestTime
+		}
+		if _, ok := parseClock(pref.DigestTime); !ok {
+			return nil, fmt.Errorf("invalid digest time %q, expected HH:MM", pref.DigestTime)
+		}
+	}
+	if req.Timezone != nil {
+		pref.Timezone = strings.TrimSpace(*req.Timezone)
+		if pref.Timezone != "" {
+			if _, err := time.LoadLocation(pref.Timezone); err != nil {
+				return nil, fmt.Errorf("invalid timezone: %s", pref.Timezone)
+			}
+		}
+	}
+
+	if err := s.db.Save(&pref).Error; err != nil {
+		return nil, err
+	}
+	return s.GetPreference(user)
+}
+
+func containsDirectMessageBot(bots []DirectMessageBot, id uint) bool {
+	for _, bot := range bots {
+		if bot.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyNotificationRequest confirms a user's linked IM identity
+type VerifyNotificationRequest struct {
+	Code string `json:"code" binding:"required"` // Code sent by the test message
+}
+
+// linkedIdentity identifies where a user's direct messages go
+func linkedIdentity(pref *models.UserNotificationPreference) string {
+	var botID uint
+	if pref.IMBotID != nil {
+		botID = *pref.IMBotID
+	}
+	return fmt.Sprintf("%d|%s|%s", botID, pref.SlackUserID, pref.TelegramChatID)
+}
+
+// hashVerificationCode returns the stored form of a verification code
+func hashVerificationCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// SendVerificationCode sends a one-time code to the user's linked identity; entering it with
+// Verify proves the identity is theirs
+func (s *PersonalNotificationService) SendVerificationCode(pref *models.UserNotificationPreference) error {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return err
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	if err := s.SendDirectMessage(pref, "🔑 Your CodeSentry verification code is "+code); err != nil {
+		return err
+	}
+	return s.db.Model(&models.UserNotificationPreference{}).Where("user_id = ?", pref.UserID).
+		Updates(map[string]interface{}{"verification_code": hashVerificationCode(code), "verified_at": nil}).Error
+}
+
+// Verify confirms the user's linked identity with the code sent to it
+func (s *PersonalNotificationService) Verify(user *models.User, code string) (*NotificationPreferenceResponse, error) {
+	var pref models.UserNotificationPreference
+	if err := s.db.Where("user_id = ?", user.ID).First(&pref).Error; err != nil {
+		return nil, err
+	}
+	if pref.VerificationCode == "" || hashVerificationCode(strings.TrimSpace(code)) != pref.VerificationCode {
+		return nil, ErrInvalidVerificationCode
+	}
+	if err := s.db.Model(&pref).Updates(map[string]interface{}{"verification_code": "", "verified_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetPreference(user)
+}
+
+// isAuthoredBy reports whether a commit is by the user. Only the email an admin or the directory
+// set on the account counts: commit author names are free text anyone can claim.
+func isAuthoredBy(user *models.User, authorEmail string) bool {
+	return user.Email != "" && strings.EqualFold(user.Email, authorEmail)
+}
+
+// canSeeProject reports whether a user may receive reviews of a project of the tenant: platform
+// admins see every project, other users only those of their own tenant
+func canSeeProject(user *models.User, tenantID uint) bool {
+	if user.Role == "admin" {
+		return true
+	}
+	return user.TenantID != 0 && user.TenantID == tenantID
+}
+
+// personalReason returns why a user is notified of a review, "" when they aren't
+func personalReason(pref *models.UserNotificationPreference, authored, maintainer bool, score float64) string {
+	if pref.ScoreBelow > 0 && score >= pref.ScoreBelow {
+		return ""
+	}
+	switch {
+	case pref.MyCommits && authored:
+		return "Your commit was reviewed"
+	case pref.MaintainedProjects && maintainer:
+		return "Review in a project you maintain"
+	}
+	return ""
+}
+
+// NotifyReview sends a direct message to each user subscribed to a review: its author, and the
+// maintainers of its project. Failures are logged, they never fail the review.
+func (s *PersonalNotificationService) NotifyReview(ctx context.Context, project *models.Project, n *ReviewNotification, authorEmail string) {
+	var prefs []models.UserNotificationPreference
+	if err := s.db.Where("im_bot_id IS NOT NULL AND verified_at IS NOT NULL AND (my_commits = ? OR maintained_projects = ?)", true, true).Find(&prefs).Error; err != nil || len(prefs) == 0 {
+		return
+	}
+
+	maintainers := map[uint]bool{}
+	var memberIDs []uint
+	s.db.Model(&models.ProjectMember{}).Where("project_id = ? AND role IN ?", project.ID, []string{"owner", "maintainer"}).Pluck("user_id", &memberIDs)
+	for _, id := range memberIDs {
+		maintainers[id] = true
+	}
+
+	for i := range prefs {
+		pref := &prefs[i]
+		var user models.User
+		if err := s.db.Where("id = ? AND is_active = ?", pref.UserID, true).First(&user).Error; err != nil {
+			continue
+		}
+		if !canSeeProject(&user, project.TenantID) {
+			continue
+		}
+		authored := isAuthoredBy(&user, authorEmail)
+		reason := personalReason(pref, authored, maintainers[user.ID], n.Score)
+		if reason == "" {
+			continue
+		}
+		message := fmt.Sprintf("🔔 %s\n\n%s", reason, buildMessage(n))
+		if err := s.SendDirectMessage(pref, message); err != nil {
+			logger.Ctx(ctx).Warn().Err(err).Uint("user_id", user.ID).Uint("project_id", project.ID).Msg("[Notification] Failed to send personal notification")
+		}
+	}
+}
+
+// SendDirectMessage sends a message to a user through the bot of their preferences
+func (s *PersonalNotificationService) SendDirectMessage(pref *models.UserNotificationPreference, message string) error {
+	if pref.IMBotID == nil {
+		return ErrNoDirectMessageBot
+	}
+	var bot models.IMBot
+	if err := s.db.Where("id = ? AND is_active = ?", *pref.IMBotID, true).First(&bot).Error; err != nil {
+		return fmt.Errorf("%w: bot %d not found or inactive", ErrNoDirectMessageBot, *pref.IMBotID)
+	}
+	switch bot.Type {
+	case "telegram":
+		if pref.TelegramChatID == "" {
+			return fmt.Errorf("no Telegram chat linked")
+		}
+		return (&telegramAdapter{}).sendTelegram(bot.Webhook, pref.TelegramChatID, message)
+	case "slack":
+		if pref.SlackUserID == "" {
+			return fmt.Errorf("no Slack user ID linked")
+		}
+		return s.sendSlackDirectMessage(bot.Secret, pref.SlackUserID, message)
+	}
+	return fmt.Errorf("%w: %s bots cannot send direct messages", ErrNoDirectMessageBot, bot.Type)
+}
+
+// sendSlackDirectMessage posts a message to a user's Slack DM with a bot token
+func (s *PersonalNotificationService) sendSlackDirectMessage(token, userID, message string) error {
+	if token == "" {
+		return fmt.Errorf("the Slack bot has no bot token")
+	}
+	for _, part := range splitMessage(message, 3000) {
+		body, _ := json.Marshal(map[string]interface{}{"channel": userID, "text": part, "mrkdwn": true})
+		req, err := http.NewRequest("POST", slackPostMessageURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		resp.Body.Close()
+
+		// Slack answers 200 with ok=false for API errors
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if resp.StatusCode >= 400 || json.Unmarshal(respBody, &result) != nil || !result.OK {
+			return fmt.Errorf("slack chat.postMessage failed (%d): %s", resp.StatusCode, truncateRunes(string(respBody), 200))
+		}
+	}
+	return nil
+}
+
+// isPersonalDigestDue reports whether a user's daily digest should be sent at now: the digest
+// time has passed today, in the user's timezone, and no digest was sent since
+func isPersonalDigestDue(pref *models.UserNotificationPreference, now time.Time) bool {
+	local := now
+	if pref.Timezone != "" {
+		if loc, err := time.LoadLocation(pref.Timezone); err == nil {
+			local = now.In(loc)
+		}
+	}
+	digestTime := pref.DigestTime
+	if digestTime == "" {
+		digestTime = defaultDigestTime
+	}
+	minutes, ok := parseClock(digestTime)
+	if !ok {
+		return false
+	}
+	y, m, d := local.Date()
+	due := time.Date(y, m, d, minutes/60, minutes%60, 0, 0, local.Location())
+	if local.Before(due) {
+		return false
+	}
+	return pref.LastDigestAt == nil || pref.LastDigestAt.Before(due)
+}
+
+// SendDueDigests sends the daily digests that are due
+func (s *PersonalNotificationService) SendDueDigests(now time.Time) {
+	var prefs []models.UserNotificationPreference
+	if err := s.db.Where("daily_digest = ? AND im_bot_id IS NOT NULL AND verified_at IS NOT NULL", true).Find(&prefs).Error; err != nil {
+		logger.Errorf("[Notification] Failed to load personal digest subscriptions: %v", err)
+		return
+	}
+	for i := range prefs {
+		pref := &prefs[i]
+		if !isPersonalDigestDue(pref, now) {
+			continue
+		}
+		if err := s.sendDigest(pref, now); err != nil {
+			logger.Warnf("[Notification] Failed to send personal digest to user %d: %v", pref.UserID, err)
+		}
+	}
+}
+
+// sendDigest sends a user the digest of the subscribed reviews of the last day
+func (s *PersonalNotificationService) sendDigest(pref *models.UserNotificationPreference, now time.Time) error {
+	var user models.User
+	if err := s.db.Where("id = ? AND is_active = ?", pref.UserID, true).First(&user).Error; err != nil {
+		return err
+	}
+	since := now.Add(-24 * time.Hour)
+	if pref.LastDigestAt != nil && pref.LastDigestAt.After(since) {
+		since = *pref.LastDigestAt
+	}
+
+	query := s.db.Model(&models.ReviewLog{}).Preload("Project").
+		Where("review_status = ? AND score IS NOT NULL AND created_at >= ? AND created_at < ?", "completed", since, now)
+	if user.Role != "admin" {
+		// Users outside a tenant see no tenant's projects
+		if user.TenantID == 0 {
+			return s.db.Model(pref).Update("last_digest_at", now).Error
+		}
+		query = ScopeReviewLogsByTenant(query, user.TenantID)
+	}
+	var conditions []string
+	var args []interface{}
+	if pref.MyCommits && user.Email != "" {
+		conditions = append(conditions, "LOWER(author_email) = ?")
+		args = append(args, strings.ToLower(user.Email))
+	}
+	if pref.MaintainedProjects {
+		conditions = append(conditions, "project_id IN (?)")
+		args = append(args, s.db.Model(&models.ProjectMember{}).Select("project_id").
+			Where("user_id = ? AND role IN ?", user.ID, []string{"owner", "maintainer"}))
+	}
+	var reviews []models.ReviewLog
+	if len(conditions) > 0 {
+		if err := query.Where("("+strings.Join(conditions, ") OR (")+")", args...).Order("score ASC").Find(&reviews).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(reviews) > 0 {
+		if err := s.SendDirectMessage(pref, buildPersonalDigest(reviews, pref.ScoreBelow)); err != nil {
+			return err
+		}
+	}
+	return s.db.Model(pref).Update("last_digest_at", now).Error
+}
+
+// buildPersonalDigest summarizes the reviews of a day, listing the lowest scores first
+func buildPersonalDigest(reviews []models.ReviewLog, scoreBelow float64) string {
+	threshold := scoreBelow
+	if threshold <= 0 {
+		threshold = 60
+	}
+	var total float64
+	var low []models.ReviewLog
+	for _, r := range reviews {
+		total += *r.Score
+		if *r.Score < threshold {
+			low = append(low, r)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📬 **Your Daily Review Digest**\n\n")
+	fmt.Fprintf(&sb, "%d reviews, average score %.1f, %d below %.0f\n", len(reviews), total/float64(len(reviews)), len(low), threshold)
+	for i, r := range low {
+		if i >= PersonalDigestMaxItems {
+			fmt.Fprintf(&sb, "\n...and %d more", len(low)-PersonalDigestMaxItems)
+			break
+		}
+		projectName := ""
+		if r.Project != nil {
+			projectName = r.Project.Name
+		}
+		line := fmt.Sprintf("\n%s **%.0f** %s@%s %s: %s", scoreEmoji(*r.Score), *r.Score, projectName, r.Branch, r.Author,
+			truncateRunes(firstLine(r.CommitMessage), 60))
+		if r.MRURL != "" {
+			line += fmt.Sprintf(" ([MR/PR](%s))", r.MRURL)
+		} else if r.CommitURL != "" {
+			line += fmt.Sprintf(" ([commit](%s))", r.CommitURL)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+var personalDigestStopChan chan struct{}
+
+// StartPersonalDigestScheduler starts a goroutine that sends the daily personal digests
+func StartPersonalDigestScheduler(db *gorm.DB) {
+	personalDigestStopChan = make(chan struct{})
+	go func() {
+		service := NewPersonalNotificationService(db)
+		RegisterScheduler("personal_digest", DigestCheckInterval)
+		ticker := time.NewTicker(DigestCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("personal_digest", func() error { service.SendDueDigests(time.Now()); return nil })
+			case <-personalDigestStopChan:
+				logger.Infof("[Notification] Personal digest scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopPersonalDigestScheduler stops the personal digest scheduler
+func StopPersonalDigestScheduler() {
+	if personalDigestStopChan != nil {
+		close(personalDigestStopChan)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestIsAuthoredBy(t *testing.T) {
+	user := &models.User{Email: "Alice@example.com"}
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"alice@example.com", true},
+		{"ALICE@example.com", true},
+		{"bob@example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isAuthoredBy(user, tt.email); got != tt.want {
+			t.Errorf("isAuthoredBy(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+	if isAuthoredBy(&models.User{}, "") {
+		t.Error("a user without email authored a commit without email")
+	}
+}
+
+func TestCanSeeProject(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     models.User
+		tenantID uint
+		want     bool
+	}{
+		{"platform admin", models.User{Role: "admin"}, 2, true},
+		{"own tenant", models.User{Role: "user", TenantID: 2}, 2, true},
+		{"other tenant", models.User{Role: "user", TenantID: 1}, 2, false},
+		{"user without tenant", models.User{Role: "user"}, 2, false},
+	}
+	for _, tt := range tests {
+		if got := canSeeProject(&tt.user, tt.tenantID); got != tt.want {
+			t.Errorf("%s: canSeeProject() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPersonalReason(t *testing.T) {
+	both := &models.UserNotificationPreference{MyCommits: true, MaintainedProjects: true}
+	tests := []struct {
+		name       string
+		pref       *models.UserNotificationPreference
+		authored   bool
+		maintainer bool
+		score      float64
+		want       string
+	}{
+		{"own commit", both, true, true, 90, "Your commit was reviewed"},
+		{"maintained project", both, false, true, 90, "Review in a project you maintain"},
+		{"unrelated", both, false, false, 40, ""},
+		{"not subscribed to own commits", &models.UserNotificationPreference{MaintainedProjects: true}, true, false, 40, ""},
+		{"score above threshold", &models.UserNotificationPreference{MyCommits: true, ScoreBelow: 60}, true, false, 75, ""},
+		{"score below threshold", &models.UserNotificationPreference{MyCommits: true, ScoreBelow: 60}, true, false, 55, "Your commit was reviewed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := personalReason(tt.pref, tt.authored, tt.maintainer, tt.score); got != tt.want {
+				t.Errorf("personalReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPersonalDigestDue(t *testing.T) {
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(hour, minute int) time.Time { return time.Date(2026, 10, 16, hour, minute, 0, 0, shanghai) }
+	sent := at(9, 1)
+	yesterday := at(9, 0).AddDate(0, 0, -1)
+	tests := []struct {
+		name string
+		pref models.UserNotificationPreference
+		now  time.Time
+		want bool
+	}{
+		{"before digest time", models.UserNotificationPreference{DigestTime: "09:00", Timezone: "Asia/Shanghai"}, at(8, 59), false},
+		{"never sent", models.UserNotificationPreference{DigestTime: "09:00", Timezone: "Asia/Shanghai"}, at(9, 0), true},
+		{"sent yesterday", models.UserNotificationPreference{DigestTime: "09:00", Timezone: "Asia/Shanghai", LastDigestAt: &yesterday}, at(18, 0), true},
+		{"already sent today", models.UserNotificationPreference{DigestTime: "09:00", Timezone: "Asia/Shanghai", LastDigestAt: &sent}, at(18, 0), false},
+		{"default time", models.UserNotificationPreference{Timezone: "Asia/Shanghai"}, at(9, 30), true},
+		{"user timezone", models.UserNotificationPreference{DigestTime: "09:00", Timezone: "Europe/London"}, at(9, 30), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPersonalDigestDue(&tt.pref, tt.now); got != tt.want {
+				t.Errorf("isPersonalDigestDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPersonalDigest(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+	reviews := []models.ReviewLog{
+		{Score: score(42), Author: "alice", Branch: "main", CommitMessage: "fix: handle nil\n\ndetails", Project: &models.Project{Name: "api"}, MRURL: "https://example.com/mr/1"},
+		{Score: score(88), Author: "alice", Branch: "dev", Project: &models.Project{Name: "web"}},
+	}
+	msg := buildPersonalDigest(reviews, 0)
+	for _, want := range []string{"2 reviews, average score 65.0, 1 below 60", "api@main alice: fix: handle nil", "(https://example.com/mr/1)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("digest missing %q:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "web@dev") {
+		t.Errorf("digest lists a review above the threshold:\n%s", msg)
+	}
+}
+
+func TestSendSlackDirectMessage(t *testing.T) {
+	var got map[string]interface{}
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["channel"] == "UNKNOWN" {
+			w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	defer func(u string) { slackPostMessageURL = u }(slackPostMessageURL)
+	slackPostMessageURL = srv.URL
+
+	s := &PersonalNotificationService{httpClient: srv.Client()}
+	if err := s.sendSlackDirectMessage("xoxb-token", "U024BE7LH", "hello"); err != nil {
+		t.Fatalf("sendSlackDirectMessage() error = %v", err)
+	}
+	if auth != "Bearer xoxb-token" || got["channel"] != "U024BE7LH" || got["text"] != "hello" {
+		t.Errorf("request = %v (auth %q)", got, auth)
+	}
+	if err := s.sendSlackDirectMessage("xoxb-token", "UNKNOWN", "hello"); err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("error = %v, want channel_not_found", err)
+	}
+	if err := s.sendSlackDirectMessage("", "U024BE7LH", "hello"); err == nil {
+		t.Error("message sent without a bot token")
+	}
+}
+
+func TestPersonalNotification_VerifyIdentity(t *testing.T) {
+	db := newTestDB(t)
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		sent = append(sent, body["text"].(string))
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+	defer func(u string) { slackPostMessageURL = u }(slackPostMessageURL)
+	slackPostMessageURL = srv.URL
+
+	bot := &models.IMBot{Name: "slack", Type: "slack", Webhook: "https://hooks.slack.com/x", Secret: "xoxb-token", IsActive: true, TenantID: 1}
+	user := &models.User{Username: "alice", Email: "alice@example.com", Role: "user", TenantID: 1}
+	own := &models.Project{Name: "api", URL: "https://git.example.com/api", Platform: "gitlab", TenantID: 1}
+	foreign := &models.Project{Name: "other", URL: "https://git.example.com/other", Platform: "gitlab", TenantID: 2}
+	mustCreate(t, db, bot, user, own, foreign)
+	service := &PersonalNotificationService{db: db, httpClient: srv.Client()}
+
+	botID, slackID, subscribe := bot.ID, "U024BE7LH", true
+	pref, err := service.UpdatePreference(user, &UpdateNotificationPreferenceRequest{IMBotID: &botID, SlackUserID: &slackID, MyCommits: &subscribe})
+	if err != nil {
+		t.Fatalf("UpdatePreference: %v", err)
+	}
+	review := &ReviewNotification{ProjectName: "api", Author: "Alice", Score: 40}
+	service.NotifyReview(context.Background(), own, review, "alice@example.com")
+	if len(sent) != 0 {
+		t.Fatalf("unverified identity received %v", sent)
+	}
+
+	if err := service.SendVerificationCode(&pref.UserNotificationPreference); err != nil {
+		t.Fatalf("SendVerificationCode: %v", err)
+	}
+	code := sent[len(sent)-1][len(sent[len(sent)-1])-6:]
+	if _, err := service.Verify(user, "000000x"); !errors.Is(err, ErrInvalidVerificationCode) {
+		t.Errorf("Verify with a wrong code = %v, want ErrInvalidVerificationCode", err)
+	}
+	verified, err := service.Verify(user, code)
+	if err != nil || verified.VerifiedAt == nil {
+		t.Fatalf("Verify = %+v, %v", verified, err)
+	}
+
+	sent = nil
+	service.NotifyReview(context.Background(), own, review, "alice@example.com")
+	service.NotifyReview(context.Background(), own, review, "mallory@example.com")
+	service.NotifyReview(context.Background(), foreign, review, "alice@example.com")
+	if len(sent) != 1 {
+		t.Errorf("verified user got %d notifications, want only the one of their commit in their tenant", len(sent))
+	}
+
+	otherID := "U999"
+	changed, _ := service.UpdatePreference(user, &UpdateNotificationPreferenceRequest{SlackUserID: &otherID})
+	if changed.VerifiedAt != nil {
+		t.Error("changing the Slack user kept the identity verified")
+	}
+}
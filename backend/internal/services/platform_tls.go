@@ -0,0 +1,220 @@
+package services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// platformTLSCacheTTL bounds how long other instances keep outdated TLS settings
+const platformTLSCacheTTL = 30 * time.Second
+
+// platformTLSSettings are the TLS settings of the Git credentials of a platform host
+type platformTLSSettings struct {
+	caCerts            string // Concatenated PEM CA certificates
+	insecureSkipVerify bool
+}
+
+// platformTLSRouter sends platform API requests to a transport with the TLS settings of the
+// active Git credentials for their host: custom CA certificates, trusted besides the system
+// roots, or disabled verification. Other hosts use the base transport.
+type platformTLSRouter struct {
+	base *http.Transport
+	load func() ([]models.GitCredential, error)
+
+	mu         sync.RWMutex
+	settings   map[string]platformTLSSettings
+	transports map[string]*http.Transport
+	loadedAt   time.Time
+}
+
+// platformTLS is the TLS router of the platform HTTP client
+var platformTLS *platformTLSRouter
+
+func newPlatformTLSRouter(base *http.Transport) *platformTLSRouter {
+	return &platformTLSRouter{base: base, load: loadPlatformTLSCredentials}
+}
+
+func loadPlatformTLSCredentials() ([]models.GitCredential, error) {
+	if globalDB == nil {
+		return nil, nil
+	}
+	var credentials []models.GitCredential
+	err := globalDB.Where("is_active = ? AND (ca_cert <> '' OR insecure_skip_verify = ?)", true, true).
+		Find(&credentials).Error
+	return credentials, err
+}
+
+// invalidatePlatformTLS makes the next platform request reload the TLS settings
+func invalidatePlatformTLS() {
+	if platformTLS == nil {
+		return
+	}
+	platformTLS.mu.Lock()
+	platformTLS.loadedAt = time.Time{}
+	platformTLS.mu.Unlock()
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *platformTLSRouter) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.transportFor(req.URL).RoundTrip(req)
+}
+
+func (r *platformTLSRouter) transportFor(u *url.URL) http.RoundTripper {
+	if u.Scheme != "https" {
+		return r.base
+	}
+	r.refresh()
+	r.mu.RLock()
+	transport := r.transports[tlsHostKey(u)]
+	r.mu.RUnlock()
+	if transport == nil {
+		return r.base
+	}
+	return transport
+}
+
+func (r *platformTLSRouter) refresh() {
+	r.mu.RLock()
+	fresh := time.Since(r.loadedAt) < platformTLSCacheTTL
+	r.mu.RUnlock()
+	if fresh {
+		return
+	}
+
+	credentials, err := r.load()
+	if err != nil {
+		// Keep the current settings until the database answers again
+		logger.Module("platform_tls").Error().Err(err).Msg("Failed to load credential TLS settings")
+		r.mu.Lock()
+		r.loadedAt = time.Now()
+		r.mu.Unlock()
+		return
+	}
+	r.apply(collectPlatformTLS(credentials))
+}
+
+// collectPlatformTLS merges the TLS settings of the credentials by host: their CA
+// certificates are all trusted, and verification is off if any credential disables it
+func collectPlatformTLS(credentials []models.GitCredential) map[string]platformTLSSettings {
+	settings := make(map[string]platformTLSSettings)
+	for _, cred := range credentials {
+		if cred.CACert == "" && !cred.InsecureSkipVerify {
+			continue
+		}
+		u, err := url.Parse(strings.TrimSpace(cred.BaseURL))
+		if err != nil || u.Host == "" {
+			continue
+		}
+		key := tlsHostKey(u)
+		s := settings[key]
+		if cred.CACert != "" {
+			s.caCerts += strings.TrimSpace(cred.CACert) + "\n"
+		}
+		s.insecureSkipVerify = s.insecureSkipVerify || cred.InsecureSkipVerify
+		settings[key] = s
+	}
+	return settings
+}
+
+// apply builds a transport per host, keeping the transports (and their connections) of
+// hosts whose settings didn't change
+func (r *platformTLSRouter) apply(settings map[string]platformTLSSettings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transports := make(map[string]*http.Transport, len(settings))
+	for host, s := range settings {
+		if old, ok := r.transports[host]; ok && r.settings[host] == s {
+			transports[host] = old
+			continue
+		}
+		transport, err := newPlatformTLSTransport(r.base, s)
+		if err != nil {
+			logger.Module("platform_tls").Error().Err(err).Str("host", host).Msg("Ignoring invalid CA certificates")
+			continue
+		}
+		if s.insecureSkipVerify {
+			logger.Module("platform_tls").Warn().Str("host", host).Msg("TLS certificate verification disabled")
+		}
+		transports[host] = transport
+	}
+	for host, old := range r.transports {
+		if transports[host] != old {
+			old.CloseIdleConnections()
+		}
+	}
+	r.settings, r.transports, r.loadedAt = settings, transports, time.Now()
+}
+
+func newPlatformTLSTransport(base *http.Transport, s platformTLSSettings) (*http.Transport, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: s.insecureSkipVerify}
+	if s.caCerts != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(s.caCerts)) {
+			return nil, errors.New("no valid PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := base.Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// tlsHostKey identifies the host of a URL, without the default port of its scheme
+func tlsHostKey(u *url.URL) string {
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if port == "" || (u.Scheme == "https" && port == "443") || (u.Scheme == "http" && port == "80") {
+		return host
+	}
+	return host + ":" + port
+}
+
+// CACertInfo describes a CA certificate of a Git credential
+type CACertInfo struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// ParseCACerts validates a PEM bundle of CA certificates and describes its certificates
+func ParseCACerts(bundle string) ([]CACertInfo, error) {
+	var infos []CACertInfo
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected PEM block %q, only certificates are accepted", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		infos = append(infos, CACertInfo{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+		})
+	}
+	if len(infos) == 0 {
+		return nil, errors.New("no PEM certificate found")
+	}
+	return infos, nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Privacy Config - pseudonymizes authors in member analytics shown to non-admin users
+type PrivacyConfigResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (s *SystemConfigService) GetPrivacyConfig() *PrivacyConfigResponse {
+	return &PrivacyConfigResponse{
+		Enabled: s.GetWithDefault("privacy_mode_enabled", "false") == "true",
+	}
+}
+
+type UpdatePrivacyConfigRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func (s *SystemConfigService) UpdatePrivacyConfig(req *UpdatePrivacyConfigRequest) error {
+	if req.Enabled == nil {
+		return nil
+	}
+	if *req.Enabled {
+		if _, err := s.privacyAliasKey(); err != nil {
+			return err
+		}
+	}
+	return s.Set("privacy_mode_enabled", strconv.FormatBool(*req.Enabled))
+}
+
+// GetAuthorAliaser returns the aliaser of privacy mode, or nil when privacy mode is off
+func (s *SystemConfigService) GetAuthorAliaser() *AuthorAliaser {
+	if !s.GetPrivacyConfig().Enabled {
+		return nil
+	}
+	key, err := s.privacyAliasKey()
+	if err != nil {
+		return nil
+	}
+	return NewAuthorAliaser(key)
+}
+
+// privacyAliasKey returns the secret aliases are derived from, generating it on first use.
+// The key keeps aliases stable while not letting anyone recompute them from known names.
+func (s *SystemConfigService) privacyAliasKey() (string, error) {
+	if key := s.GetWithDefault("privacy_alias_secret", ""); key != "" {
+		return key, nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	if err := s.Set("privacy_alias_secret", hex.EncodeToString(buf)); err != nil {
+		return "", err
+	}
+	return s.Get("privacy_alias_secret")
+}
+
+// AuthorAliaser replaces author names with stable aliases such as "member-1a2b3c4d"
+type AuthorAliaser struct {
+	key []byte
+}
+
+func NewAuthorAliaser(key string) *AuthorAliaser {
+	return &AuthorAliaser{key: []byte(key)}
+}
+
+// Alias returns the alias of an author name; the same name always gets the same alias
+func (a *AuthorAliaser) Alias(author string) string {
+	if author == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(author))
+	return "member-" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// Members pseudonymizes member statistics, dropping emails, avatars and profile links
+func (a *AuthorAliaser) Members(items []MemberStats) {
+	for i := range items {
+		items[i].Author = a.Alias(items[i].Author)
+		items[i].AuthorEmail = ""
+		items[i].AuthorAvatar = ""
+		items[i].AuthorURL = ""
+	}
+}
+
+// Apply pseudonymizes the authors of a member analytics response in place. Responses
+// without authors are left unchanged.
+func (a *AuthorAliaser) Apply(value interface{}) {
+	switch v := value.(type) {
+	case *MemberListResponse:
+		a.Members(v.Items)
+	case *MemberDetailResponse:
+		stats := []MemberStats{v.TotalStats}
+		a.Members(stats)
+		v.Author, v.AuthorEmail, v.TotalStats = a.Alias(v.Author), "", stats[0]
+	case *TeamOverviewResponse:
+		a.Members(v.TopMembers)
+	case *MemberScorecardResponse:
+		for i := range v.Items {
+			v.Items[i].Author = a.Alias(v.Items[i].Author)
+			v.Items[i].AuthorEmail = ""
+		}
+	case *DashboardResponse:
+		for i := range v.AuthorStats {
+			v.AuthorStats[i].Author = a.Alias(v.AuthorStats[i].Author)
+		}
+	case *LeaderboardResponse:
+		for i := range v.TopQuality {
+			v.TopQuality[i].Author = a.Alias(v.TopQuality[i].Author)
+		}
+		for i := range v.MostImproved {
+			v.MostImproved[i].Author = a.Alias(v.MostImproved[i].Author)
+		}
+		for i := range v.Streaks {
+			v.Streaks[i].Author = a.Alias(v.Streaks[i].Author)
+		}
+	case []FindingAuthorStats:
+		for i := range v {
+			v[i].Author = a.Alias(v[i].Author)
+		}
+	}
+}
+
+// ResolveAuthorAlias returns the author name an alias stands for, looking among the recorded authors
+func (s *MemberService) ResolveAuthorAlias(a *AuthorAliaser, alias string) (string, bool) {
+	var authors []string
+	if err := s.db.Model(&models.ReviewLog{}).Where("author <> ''").Distinct().Pluck("author", &authors).Error; err != nil {
+		return "", false
+	}
+	for _, author := range authors {
+		if a.Alias(author) == alias {
+			return author, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAuthorAliaser(t *testing.T) {
+	a := NewAuthorAliaser("key")
+
+	alice := a.Alias("alice")
+	if !strings.HasPrefix(alice, "member-") || len(alice) != len("member-")+8 {
+		t.Fatalf("alias = %q", alice)
+	}
+
+	tests := []struct {
+		name  string
+		alias string
+		same  bool
+	}{
+		{"same author is stable", a.Alias("alice"), true},
+		{"other author", a.Alias("bob"), false},
+		{"names are case sensitive like member statistics", a.Alias("Alice"), false},
+		{"other key", NewAuthorAliaser("other").Alias("alice"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if (tt.alias == alice) != tt.same {
+				t.Errorf("alias = %q, alice = %q, want same = %v", tt.alias, alice, tt.same)
+			}
+		})
+	}
+
+	if got := a.Alias(""); got != "" {
+		t.Errorf("Alias(\"\") = %q, want empty", got)
+	}
+}
+
+func TestAuthorAliaserMembers(t *testing.T) {
+	a := NewAuthorAliaser("key")
+	items := []MemberStats{{
+		Author:       "alice",
+		AuthorEmail:  "alice@example.com",
+		AuthorAvatar: "https://example.com/a.png",
+		AuthorURL:    "https://example.com/alice",
+		CommitCount:  3,
+	}}
+
+	a.Members(items)
+
+	got := items[0]
+	if got.Author != a.Alias("alice") || got.AuthorEmail != "" || got.AuthorAvatar != "" || got.AuthorURL != "" {
+		t.Errorf("member = %+v", got)
+	}
+	if got.CommitCount != 3 {
+		t.Errorf("CommitCount = %d, want 3", got.CommitCount)
+	}
+}
+
+func TestAuthorAliaserApply(t *testing.T) {
+	a := NewAuthorAliaser("key")
+	alias := a.Alias("alice")
+
+	detail := &MemberDetailResponse{Author: "alice", AuthorEmail: "alice@example.com", TotalStats: MemberStats{Author: "alice", AuthorEmail: "alice@example.com"}}
+	a.Apply(detail)
+	if detail.Author != alias || detail.AuthorEmail != "" || detail.TotalStats.Author != alias || detail.TotalStats.AuthorEmail != "" {
+		t.Errorf("detail = %+v", detail)
+	}
+
+	scorecards := &MemberScorecardResponse{Items: []MemberScorecard{{Author: "alice", AuthorEmail: "alice@example.com"}}}
+	a.Apply(scorecards)
+	if scorecards.Items[0].Author != alias || scorecards.Items[0].AuthorEmail != "" {
+		t.Errorf("scorecard = %+v", scorecards.Items[0])
+	}
+
+	leaderboard := &LeaderboardResponse{
+		TopQuality:   []QualityEntry{{Author: "alice"}},
+		MostImproved: []ImprovementEntry{{Author: "alice"}},
+		Streaks:      []StreakEntry{{Author: "alice"}},
+	}
+	a.Apply(leaderboard)
+	if leaderboard.TopQuality[0].Author != alias || leaderboard.MostImproved[0].Author != alias || leaderboard.Streaks[0].Author != alias {
+		t.Errorf("leaderboard = %+v", leaderboard)
+	}
+
+	findings := []FindingAuthorStats{{Author: "alice", Total: 2}}
+	a.Apply(findings)
+	if findings[0].Author != alias || findings[0].Total != 2 {
+		t.Errorf("findings = %+v", findings)
+	}
+
+	// Responses without authors are left unchanged
+	a.Apply(&HeatmapResponse{TotalCount: 1})
+}
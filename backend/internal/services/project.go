@@ -2,7 +2,9 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/huangang/codesentry/backend/internal/models"
 	"gorm.io/gorm"
@@ -21,8 +23,18 @@ type ProjectListRequest struct {
 	PageSize int    `form:"page_size" binding:"omitempty,min=1,max=100"`
 	Name     string `form:"name"`
 	Platform string `form:"platform"`
+	Status   string `form:"status" binding:"omitempty,oneof=active archived pending all"` // Default active
+	TenantID uint   `form:"-"`                                                            // Set from the request context, 0 = all tenants
 }
 
+// Project list statuses
+const (
+	ProjectStatusActive   = "active"
+	ProjectStatusArchived = "archived"
+	ProjectStatusPending  = "pending" // Auto-created, awaiting approval
+	ProjectStatusAll      = "all"
+)
+
 type ProjectListResponse struct {
 	Total    int64            `json:"total"`
 	Page     int              `json:"page"`
@@ -31,37 +43,82 @@ type ProjectListResponse struct {
 }
 
 type CreateProjectRequest struct {
-	Name           string  `json:"name" binding:"required"`
-	URL            string  `json:"url" binding:"required"`
-	Platform       string  `json:"platform" binding:"required,oneof=github gitlab bitbucket"`
-	AccessToken    string  `json:"access_token"`
-	WebhookSecret  string  `json:"webhook_secret"`
-	FileExtensions string  `json:"file_extensions"`
-	ReviewEvents   string  `json:"review_events"`
-	AIEnabled      bool    `json:"ai_enabled"`
-	AIPrompt       string  `json:"ai_prompt"`
-	IMEnabled      bool    `json:"im_enabled"`
-	IMBotID        *uint   `json:"im_bot_id"`
-	MinScore       float64 `json:"min_score"`
+	Name             string  `json:"name" binding:"required"`
+	URL              string  `json:"url" binding:"required"`
+	Platform         string  `json:"platform" binding:"required,oneof=github gitlab bitbucket"`
+	AccessToken      string  `json:"access_token"`
+	WebhookSecret    string  `json:"webhook_secret"`
+	FileExtensions   string  `json:"file_extensions"`
+	IncludePatterns  string  `json:"include_patterns"`
+	ReviewEvents     string  `json:"review_events"`
+	BranchFilter     string  `json:"branch_filter"`
+	BranchFilterMode string  `json:"branch_filter_mode" binding:"omitempty,oneof=ignore allow"`
+	AIEnabled        bool    `json:"ai_enabled"`
+	AIPrompt         string  `json:"ai_prompt"`
+	TechProfile      string  `json:"tech_profile" binding:"max=500"`
+	IMEnabled        bool    `json:"im_enabled"`
+	IMBotID          *uint   `json:"im_bot_id"`
+	ReleaseIMBotID   *uint   `json:"release_im_bot_id"`
+	MinScore         float64 `json:"min_score"`
+	MaxChangedLines  int     `json:"max_changed_lines" binding:"min=0"`
+	MaxFiles         int     `json:"max_files" binding:"min=0"`
+	MaxDiffBytes     int     `json:"max_diff_bytes" binding:"min=0"`
+	MaxFileBytes     int     `json:"max_file_bytes" binding:"min=0"`
+	BadgeEnabled     bool    `json:"badge_enabled"`
+	ReplayProtection bool    `json:"replay_protection"`
+	SignaturePolicy  string  `json:"signature_policy" binding:"omitempty,oneof=off annotate enforce"`
+	SignedBranches   string  `json:"signed_branches"`
+	IaCReviewMode    string  `json:"iac_review_mode" binding:"omitempty,oneof=auto off"`
+	MigrationPolicy  string  `json:"migration_policy" binding:"omitempty,oneof=off review acknowledge"`
+	PerCommitReview  bool    `json:"per_commit_review"`
+	SkipDirectives   bool    `json:"skip_directives"`
+	SkipBranches     string  `json:"skip_branches"`
+
+	TemplateBindings     []TemplateBindingInput    `json:"template_bindings" binding:"omitempty,dive"`
+	TargetBranchPolicies []TargetBranchPolicyInput `json:"target_branch_policies" binding:"omitempty,dive"`
+	TenantID             uint                      `json:"-"`
 }
 
 type UpdateProjectRequest struct {
-	Name           string   `json:"name"`
-	URL            string   `json:"url"`
-	Platform       string   `json:"platform" binding:"omitempty,oneof=github gitlab bitbucket"`
-	AccessToken    string   `json:"access_token"`
-	WebhookSecret  string   `json:"webhook_secret"`
-	FileExtensions string   `json:"file_extensions"`
-	ReviewEvents   string   `json:"review_events"`
-	AIEnabled      *bool    `json:"ai_enabled"`
-	AIPromptID     *uint    `json:"ai_prompt_id"`
-	AIPrompt       *string  `json:"ai_prompt"`
-	LLMConfigID    *uint    `json:"llm_config_id"`
-	IgnorePatterns *string  `json:"ignore_patterns"`
-	CommentEnabled *bool    `json:"comment_enabled"`
-	IMEnabled      *bool    `json:"im_enabled"`
-	IMBotID        *uint    `json:"im_bot_id"`
-	MinScore       *float64 `json:"min_score"`
+	Name             string   `json:"name"`
+	URL              string   `json:"url"`
+	Platform         string   `json:"platform" binding:"omitempty,oneof=github gitlab bitbucket"`
+	AccessToken      string   `json:"access_token"`
+	WebhookSecret    string   `json:"webhook_secret"`
+	FileExtensions   string   `json:"file_extensions"`
+	IncludePatterns  *string  `json:"include_patterns"`
+	ReviewEvents     string   `json:"review_events"`
+	BranchFilter     *string  `json:"branch_filter"`
+	BranchFilterMode string   `json:"branch_filter_mode" binding:"omitempty,oneof=ignore allow"`
+	AIEnabled        *bool    `json:"ai_enabled"`
+	AIPromptID       *uint    `json:"ai_prompt_id"`
+	AIPrompt         *string  `json:"ai_prompt"`
+	LLMConfigID      *uint    `json:"llm_config_id"`
+	IgnorePatterns   *string  `json:"ignore_patterns"`
+	TechProfile      *string  `json:"tech_profile" binding:"omitempty,max=500"`
+	CommentEnabled   *bool    `json:"comment_enabled"`
+	IMEnabled        *bool    `json:"im_enabled"`
+	IMBotID          *uint    `json:"im_bot_id"`
+	ReleaseIMBotID   *uint    `json:"release_im_bot_id"`
+	MinScore         *float64 `json:"min_score"`
+	MaxChangedLines  *int     `json:"max_changed_lines" binding:"omitempty,min=0"`
+	MaxFiles         *int     `json:"max_files" binding:"omitempty,min=0"`
+	MaxDiffBytes     *int     `json:"max_diff_bytes" binding:"omitempty,min=0"`
+	MaxFileBytes     *int     `json:"max_file_bytes" binding:"omitempty,min=0"`
+	RetryMaxAttempts *int     `json:"retry_max_attempts" binding:"omitempty,min=0,max=20"`
+	RetryBackoff     *int     `json:"retry_backoff" binding:"omitempty,min=0,max=1440"`
+	BadgeEnabled     *bool    `json:"badge_enabled"`
+	ReplayProtection *bool    `json:"replay_protection"`
+	SignaturePolicy  string   `json:"signature_policy" binding:"omitempty,oneof=off annotate enforce"`
+	SignedBranches   *string  `json:"signed_branches"`
+	IaCReviewMode    string   `json:"iac_review_mode" binding:"omitempty,oneof=auto off"`
+	MigrationPolicy  string   `json:"migration_policy" binding:"omitempty,oneof=off review acknowledge"`
+	PerCommitReview  *bool    `json:"per_commit_review"`
+	SkipDirectives   *bool    `json:"skip_directives"`
+	SkipBranches     *string  `json:"skip_branches"`
+
+	TemplateBindings     *[]TemplateBindingInput    `json:"template_bindings" binding:"omitempty,dive"`      // Replaces all bindings when set
+	TargetBranchPolicies *[]TargetBranchPolicyInput `json:"target_branch_policies" binding:"omitempty,dive"` // Replaces all policies when set
 }
 
 // List returns paginated projects
@@ -76,14 +133,23 @@ func (s *ProjectService) List(req *ProjectListRequest) (*ProjectListResponse, er
 	var projects []models.Project
 	var total int64
 
-	query := s.db.Model(&models.Project{})
+	query := ScopeTenant(s.db.Model(&models.Project{}), req.TenantID)
 
 	if req.Name != "" {
-		query = query.Where("name LIKE ?", "%"+req.Name+"%")
+		query = query.Where(LikeCondition("name"), ContainsPattern(req.Name))
 	}
 	if req.Platform != "" {
 		query = query.Where("platform = ?", req.Platform)
 	}
+	switch req.Status {
+	case ProjectStatusAll:
+	case ProjectStatusArchived:
+		query = query.Where("archived = ?", true)
+	case ProjectStatusPending:
+		query = query.Where("archived = ? AND pending_approval = ?", false, true)
+	default:
+		query = query.Where("archived = ?", false)
+	}
 
 	query.Count(&total)
 
@@ -103,7 +169,7 @@ func (s *ProjectService) List(req *ProjectListRequest) (*ProjectListResponse, er
 // GetByID returns a project by ID
 func (s *ProjectService) GetByID(id uint) (*models.Project, error) {
 	var project models.Project
-	if err := s.db.First(&project, id).Error; err != nil {
+	if err := s.db.Preload("TemplateBindings").Preload("TargetBranchPolicies").First(&project, id).Error; err != nil {
 		return nil, err
 	}
 	return &project, nil
@@ -120,26 +186,60 @@ func (s *ProjectService) Create(req *CreateProjectRequest, userID uint) (*models
 	}
 
 	project := models.Project{
-		Name:           req.Name,
-		URL:            strings.TrimSuffix(req.URL, ".git"),
-		Platform:       req.Platform,
-		AccessToken:    req.AccessToken,
-		WebhookSecret:  req.WebhookSecret,
-		FileExtensions: req.FileExtensions,
-		ReviewEvents:   req.ReviewEvents,
-		AIEnabled:      req.AIEnabled,
-		AIPrompt:       req.AIPrompt,
-		IMEnabled:      req.IMEnabled,
-		IMBotID:        req.IMBotID,
-		MinScore:       req.MinScore,
-		CreatedBy:      userID,
-	}
-
-	if err := s.db.Create(&project).Error; err != nil {
+		Name:             req.Name,
+		URL:              strings.TrimSuffix(req.URL, ".git"),
+		Platform:         req.Platform,
+		AccessToken:      req.AccessToken,
+		WebhookSecret:    req.WebhookSecret,
+		FileExtensions:   req.FileExtensions,
+		IncludePatterns:  req.IncludePatterns,
+		ReviewEvents:     req.ReviewEvents,
+		BranchFilter:     req.BranchFilter,
+		BranchFilterMode: req.BranchFilterMode,
+		AIEnabled:        req.AIEnabled,
+		AIPrompt:         req.AIPrompt,
+		TechProfile:      strings.TrimSpace(req.TechProfile),
+		IMEnabled:        req.IMEnabled,
+		IMBotID:          req.IMBotID,
+		ReleaseIMBotID:   req.ReleaseIMBotID,
+		MinScore:         req.MinScore,
+		MaxChangedLines:  req.MaxChangedLines,
+		MaxFiles:         req.MaxFiles,
+		MaxDiffBytes:     req.MaxDiffBytes,
+		MaxFileBytes:     req.MaxFileBytes,
+		BadgeEnabled:     req.BadgeEnabled,
+		ReplayProtection: req.ReplayProtection,
+		SignaturePolicy:  req.SignaturePolicy,
+		SignedBranches:   req.SignedBranches,
+		IaCReviewMode:    req.IaCReviewMode,
+		MigrationPolicy:  req.MigrationPolicy,
+		PerCommitReview:  req.PerCommitReview,
+		SkipDirectives:   req.SkipDirectives,
+		SkipBranches:     req.SkipBranches,
+		TenantID:         req.TenantID,
+		CreatedBy:        userID,
+	}
+	if err := s.checkTenantRefs(req.TenantID, nil, req.IMBotID); err != nil {
+		return nil, err
+	}
+	if err := s.checkTenantRefs(req.TenantID, nil, req.ReleaseIMBotID); err != nil {
 		return nil, err
 	}
 
-	return &project, nil
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("TemplateBindings", "TargetBranchPolicies").Create(&project).Error; err != nil {
+			return err
+		}
+		if err := replaceTargetBranchPolicies(tx, project.ID, project.TenantID, req.TargetBranchPolicies); err != nil {
+			return err
+		}
+		return replaceTemplateBindings(tx, project.ID, project.TenantID, req.TemplateBindings)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(project.ID)
 }
 
 // Update updates a project
@@ -149,6 +249,13 @@ func (s *ProjectService) Update(id uint, req *UpdateProjectRequest) (*models.Pro
 		return nil, err
 	}
 
+	if err := s.checkTenantRefs(project.TenantID, req.LLMConfigID, req.IMBotID); err != nil {
+		return nil, err
+	}
+	if err := s.checkTenantRefs(project.TenantID, nil, req.ReleaseIMBotID); err != nil {
+		return nil, err
+	}
+
 	updates := make(map[string]interface{})
 
 	if req.Name != "" {
@@ -172,6 +279,12 @@ func (s *ProjectService) Update(id uint, req *UpdateProjectRequest) (*models.Pro
 	if req.ReviewEvents != "" {
 		updates["review_events"] = req.ReviewEvents
 	}
+	if req.BranchFilter != nil {
+		updates["branch_filter"] = *req.BranchFilter
+	}
+	if req.BranchFilterMode != "" {
+		updates["branch_filter_mode"] = req.BranchFilterMode
+	}
 	if req.AIEnabled != nil {
 		updates["ai_enabled"] = *req.AIEnabled
 	}
@@ -184,9 +297,15 @@ func (s *ProjectService) Update(id uint, req *UpdateProjectRequest) (*models.Pro
 	if req.LLMConfigID != nil {
 		updates["llm_config_id"] = req.LLMConfigID
 	}
+	if req.IncludePatterns != nil {
+		updates["include_patterns"] = *req.IncludePatterns
+	}
 	if req.IgnorePatterns != nil {
 		updates["ignore_patterns"] = *req.IgnorePatterns
 	}
+	if req.TechProfile != nil {
+		updates["tech_profile"] = strings.TrimSpace(*req.TechProfile)
+	}
 	if req.CommentEnabled != nil {
 		updates["comment_enabled"] = *req.CommentEnabled
 	}
@@ -196,15 +315,101 @@ func (s *ProjectService) Update(id uint, req *UpdateProjectRequest) (*models.Pro
 	if req.IMBotID != nil {
 		updates["im_bot_id"] = req.IMBotID
 	}
+	if req.ReleaseIMBotID != nil {
+		updates["release_im_bot_id"] = req.ReleaseIMBotID
+	}
 	if req.MinScore != nil {
 		updates["min_score"] = *req.MinScore
 	}
+	if req.MaxChangedLines != nil {
+		updates["max_changed_lines"] = *req.MaxChangedLines
+	}
+	if req.MaxFiles != nil {
+		updates["max_files"] = *req.MaxFiles
+	}
+	if req.MaxDiffBytes != nil {
+		updates["max_diff_bytes"] = *req.MaxDiffBytes
+	}
+	if req.MaxFileBytes != nil {
+		updates["max_file_bytes"] = *req.MaxFileBytes
+	}
+	if req.RetryMaxAttempts != nil {
+		updates["retry_max_attempts"] = *req.RetryMaxAttempts
+	}
+	if req.RetryBackoff != nil {
+		updates["retry_backoff"] = *req.RetryBackoff
+	}
+	if req.BadgeEnabled != nil {
+		updates["badge_enabled"] = *req.BadgeEnabled
+	}
+	if req.ReplayProtection != nil {
+		updates["replay_protection"] = *req.ReplayProtection
+	}
+	if req.SignaturePolicy != "" {
+		updates["signature_policy"] = req.SignaturePolicy
+	}
+	if req.SignedBranches != nil {
+		updates["signed_branches"] = *req.SignedBranches
+	}
+	if req.IaCReviewMode != "" {
+		updates["iac_review_mode"] = req.IaCReviewMode
+	}
+	if req.MigrationPolicy != "" {
+		updates["migration_policy"] = req.MigrationPolicy
+	}
+	if req.PerCommitReview != nil {
+		updates["per_commit_review"] = *req.PerCommitReview
+	}
+	if req.SkipDirectives != nil {
+		updates["skip_directives"] = *req.SkipDirectives
+	}
+	if req.SkipBranches != nil {
+		updates["skip_branches"] = *req.SkipBranches
+	}
 
-	if err := s.db.Model(&project).Updates(updates).Error; err != nil {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&project).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if req.TargetBranchPolicies != nil {
+			if err := replaceTargetBranchPolicies(tx, project.ID, project.TenantID, *req.TargetBranchPolicies); err != nil {
+				return err
+			}
+		}
+		if req.TemplateBindings != nil {
+			return replaceTemplateBindings(tx, project.ID, project.TenantID, *req.TemplateBindings)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &project, nil
+	return s.GetByID(project.ID)
+}
+
+// checkTenantRefs ensures the LLM config and IM bot a project points at belong to its tenant
+func (s *ProjectService) checkTenantRefs(tenantID uint, llmConfigID, imBotID *uint) error {
+	if tenantID == 0 {
+		return nil
+	}
+	if llmConfigID != nil && *llmConfigID > 0 {
+		var count int64
+		s.db.Model(&models.LLMConfig{}).Where("id = ? AND tenant_id = ?", *llmConfigID, tenantID).Count(&count)
+		if count == 0 {
+			return errors.New("llm config not found")
+		}
+	}
+	if imBotID != nil && *imBotID > 0 {
+		var count int64
+		s.db.Model(&models.IMBot{}).Where("id = ? AND tenant_id = ?", *imBotID, tenantID).Count(&count)
+		if count == 0 {
+			return errors.New("im bot not found")
+		}
+	}
+	return nil
 }
 
 // Delete deletes a project
@@ -219,6 +424,104 @@ func (s *ProjectService) Delete(id uint) error {
 	return nil
 }
 
+// Archive stops reviewing a project's webhooks and hides it from default lists, keeping its
+// review history and statistics, unlike Delete
+func (s *ProjectService) Archive(id uint) (*models.Project, error) {
+	now := time.Now()
+	if err := s.db.Model(&models.Project{}).Where("id = ? AND archived = ?", id, false).
+		Updates(map[string]interface{}{"archived": true, "archived_at": &now}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Unarchive makes an archived project active again
+func (s *ProjectService) Unarchive(id uint) (*models.Project, error) {
+	if err := s.db.Model(&models.Project{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"archived": false, "archived_at": nil}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// ErrProjectNotPending is returned when approving a project that is not awaiting approval
+var ErrProjectNotPending = errors.New("project is not pending approval")
+
+// Approve enables an auto-created project that was awaiting approval. AI review is enabled as
+// configured on the credential it was created from.
+func (s *ProjectService) Approve(id uint) (*models.Project, error) {
+	project, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !project.PendingApproval {
+		return nil, ErrProjectNotPending
+	}
+	aiEnabled := true
+	if project.CredentialID != nil {
+		var cred models.GitCredential
+		if err := s.db.Unscoped().First(&cred, *project.CredentialID).Error; err == nil {
+			aiEnabled = cred.DefaultEnabled
+		}
+	}
+	if err := s.db.Model(&models.Project{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"pending_approval": false, "ai_enabled": aiEnabled}).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+type ArchiveInactiveRequest struct {
+	Days     int  `json:"days" binding:"required,min=1"` // Archive projects without reviews for this many days
+	DryRun   bool `json:"dry_run"`                       // Only list the projects that would be archived
+	TenantID uint `json:"-"`
+}
+
+type InactiveProject struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ArchiveInactiveResponse struct {
+	DryRun   bool              `json:"dry_run"`
+	Archived int               `json:"archived"`
+	Projects []InactiveProject `json:"projects"`
+}
+
+// ArchiveInactive archives the active projects created more than req.Days days ago that
+// have no review logs since then
+func (s *ProjectService) ArchiveInactive(req *ArchiveInactiveRequest) (*ArchiveInactiveResponse, error) {
+	cutoff := time.Now().AddDate(0, 0, -req.Days)
+
+	projects := []InactiveProject{}
+	query := ScopeTenant(s.db.Model(&models.Project{}), req.TenantID).
+		Where("archived = ? AND created_at < ?", false, cutoff).
+		Where("NOT EXISTS (SELECT 1 FROM review_logs WHERE review_logs.project_id = projects.id AND review_logs.created_at >= ? AND review_logs.deleted_at IS NULL)", cutoff)
+	if err := query.Select("id, name, url, created_at").Order("id").Scan(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &ArchiveInactiveResponse{DryRun: req.DryRun, Projects: projects}
+	if req.DryRun || len(projects) == 0 {
+		return resp, nil
+	}
+
+	ids := make([]uint, len(projects))
+	for i, p := range projects {
+		ids[i] = p.ID
+	}
+	now := time.Now()
+	result := s.db.Model(&models.Project{}).Where("id IN ? AND archived = ?", ids, false).
+		Updates(map[string]interface{}{"archived": true, "archived_at": &now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	resp.Archived = int(result.RowsAffected)
+	return resp, nil
+}
+
 // GetByWebhookSecret finds a project by webhook secret
 func (s *ProjectService) GetByWebhookSecret(secret string) (*models.Project, error) {
 	var project models.Project
@@ -249,6 +552,9 @@ type CreateProjectParams struct {
 	FileExtensions string
 	ReviewEvents   string
 	IgnorePatterns string
+	CredentialID   uint
+	Pending        bool // Create the project disabled until an admin approves it
+	MaxAutoCreated int  // Cap of projects auto-created from the credential, 0 = unlimited
 }
 
 func (s *ProjectService) CreateFromCredential(params *CreateProjectParams) (*models.Project, error) {
@@ -271,8 +577,41 @@ func (s *ProjectService) CreateFromCredential(params *CreateProjectParams) (*mod
 		AIEnabled:      params.AIEnabled,
 		CreatedBy:      0,
 	}
-
-	if err := s.db.Create(&project).Error; err != nil {
+	if params.CredentialID != 0 {
+		project.CredentialID = &params.CredentialID
+	}
+	if params.Pending {
+		project.PendingApproval = true
+		project.AIEnabled = false
+	}
+
+	aiEnabled := project.AIEnabled
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if params.CredentialID != 0 && params.MaxAutoCreated > 0 {
+			// Touching the credential row locks it, so concurrent webhooks for new repositories
+			// of the same credential count and create one after another
+			if err := tx.Model(&models.GitCredential{}).Where("id = ?", params.CredentialID).
+				Update("updated_at", time.Now()).Error; err != nil {
+				return err
+			}
+			var count int64
+			if err := tx.Model(&models.Project{}).Where("credential_id = ?", params.CredentialID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(params.MaxAutoCreated) {
+				return fmt.Errorf("%w: %d of %d projects", ErrAutoCreateLimit, count, params.MaxAutoCreated)
+			}
+		}
+		if err := tx.Create(&project).Error; err != nil {
+			return err
+		}
+		// ai_enabled defaults to true in the schema, so Create stores a false value as true
+		if !aiEnabled {
+			return tx.Model(&project).Update("ai_enabled", false).Error
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -1,7 +1,14 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
 )
 
 func TestProjectListRequest_Defaults(t *testing.T) {
@@ -266,3 +273,160 @@ func containsStr(s, substr string) bool {
 	}
 	return false
 }
+
+func TestCreateFromCredential_PendingApproval(t *testing.T) {
+	db := newTestDB(t)
+	cred := &models.GitCredential{Name: "gitlab", Platform: "gitlab", BaseURL: "https://git.example.com", MaxAutoCreated: 1}
+	mustCreate(t, db, cred)
+	service := NewProjectService(db)
+
+	project, err := service.CreateFromCredential(&CreateProjectParams{
+		Name: "repo", URL: "https://git.example.com/team/repo", Platform: "gitlab",
+		AIEnabled: true, CredentialID: cred.ID, Pending: true, MaxAutoCreated: cred.MaxAutoCreated,
+	})
+	if err != nil {
+		t.Fatalf("CreateFromCredential: %v", err)
+	}
+	stored, _ := service.GetByID(project.ID)
+	if !stored.PendingApproval || stored.AIEnabled {
+		t.Errorf("pending project = pending %v, ai_enabled %v; want pending and disabled", stored.PendingApproval, stored.AIEnabled)
+	}
+
+	approved, err := service.Approve(project.ID)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	if approved.PendingApproval || !approved.AIEnabled {
+		t.Errorf("approved project = pending %v, ai_enabled %v; want enabled", approved.PendingApproval, approved.AIEnabled)
+	}
+	if _, err := service.Approve(project.ID); !errors.Is(err, ErrProjectNotPending) {
+		t.Errorf("Approve twice = %v, want ErrProjectNotPending", err)
+	}
+}
+
+func TestCreateFromCredential_Disabled(t *testing.T) {
+	db := newTestDB(t)
+	project, err := NewProjectService(db).CreateFromCredential(&CreateProjectParams{Name: "repo", URL: "https://git.example.com/team/repo", Platform: "gitlab"})
+	if err != nil {
+		t.Fatalf("CreateFromCredential: %v", err)
+	}
+	var stored models.Project
+	db.First(&stored, project.ID)
+	if stored.AIEnabled {
+		t.Error("project created with AI disabled is stored enabled")
+	}
+}
+
+func TestCreateFromCredential_Cap(t *testing.T) {
+	db := newTestDB(t)
+	cred := &models.GitCredential{Name: "gitlab", Platform: "gitlab", BaseURL: "https://git.example.com", MaxAutoCreated: 2}
+	mustCreate(t, db, cred)
+	service := NewProjectService(db)
+
+	for i := 0; i < 3; i++ {
+		_, err := service.CreateFromCredential(&CreateProjectParams{
+			Name: fmt.Sprintf("repo-%d", i), URL: fmt.Sprintf("https://git.example.com/team/repo-%d", i), Platform: "gitlab",
+			CredentialID: cred.ID, MaxAutoCreated: cred.MaxAutoCreated,
+		})
+		if i < 2 && err != nil {
+			t.Fatalf("project %d under the cap: %v", i, err)
+		}
+		if i == 2 && !errors.Is(err, ErrAutoCreateLimit) {
+			t.Errorf("project over the cap = %v, want ErrAutoCreateLimit", err)
+		}
+	}
+	var count int64
+	db.Model(&models.Project{}).Where("credential_id = ?", cred.ID).Count(&count)
+	if count != 2 {
+		t.Errorf("projects created from the credential = %d, want 2", count)
+	}
+}
+
+// projectNames returns the names of the projects listed with a status
+func projectNames(t *testing.T, service *ProjectService, status string) []string {
+	t.Helper()
+	resp, err := service.List(&ProjectListRequest{Status: status})
+	if err != nil {
+		t.Fatalf("List(%q): %v", status, err)
+	}
+	names := make([]string, 0, len(resp.Items))
+	for _, p := range resp.Items {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestProjectService_ArchiveAndUnarchive(t *testing.T) {
+	db := newTestDB(t)
+	service := NewProjectService(db)
+	legacy := &models.Project{Name: "legacy", URL: "https://git.example.com/legacy", Platform: "gitlab"}
+	current := &models.Project{Name: "current", URL: "https://git.example.com/current", Platform: "gitlab"}
+	mustCreate(t, db, legacy, current)
+
+	archived, err := service.Archive(legacy.ID)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if !archived.Archived || archived.ArchivedAt == nil {
+		t.Fatalf("archived project = archived %v at %v, want archived with a time", archived.Archived, archived.ArchivedAt)
+	}
+	if again, _ := service.Archive(legacy.ID); again.ArchivedAt == nil || !again.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Errorf("archiving twice moved archived_at from %v to %v", archived.ArchivedAt, again.ArchivedAt)
+	}
+
+	for status, want := range map[string][]string{
+		"":                    {"current"},
+		ProjectStatusActive:   {"current"},
+		ProjectStatusArchived: {"legacy"},
+		ProjectStatusAll:      {"current", "legacy"},
+	} {
+		if got := projectNames(t, service, status); !reflect.DeepEqual(got, want) {
+			t.Errorf("List(%q) = %v, want %v", status, got, want)
+		}
+	}
+
+	restored, err := service.Unarchive(legacy.ID)
+	if err != nil {
+		t.Fatalf("Unarchive: %v", err)
+	}
+	if restored.Archived || restored.ArchivedAt != nil {
+		t.Errorf("restored project = archived %v at %v, want active", restored.Archived, restored.ArchivedAt)
+	}
+	if got := projectNames(t, service, ""); !reflect.DeepEqual(got, []string{"current", "legacy"}) {
+		t.Errorf("List() after restoring = %v, want both projects", got)
+	}
+}
+
+func TestProjectService_ArchiveInactive(t *testing.T) {
+	db := newTestDB(t)
+	service := NewProjectService(db)
+	old := time.Now().AddDate(0, 0, -100)
+	idle := &models.Project{Name: "idle", URL: "https://git.example.com/idle", Platform: "gitlab", CreatedAt: old}
+	busy := &models.Project{Name: "busy", URL: "https://git.example.com/busy", Platform: "gitlab", CreatedAt: old}
+	fresh := &models.Project{Name: "fresh", URL: "https://git.example.com/fresh", Platform: "gitlab"}
+	mustCreate(t, db, idle, busy, fresh)
+	mustCreate(t, db, &models.ReviewLog{ProjectID: busy.ID, CommitHash: "abc", ReviewStatus: "completed"})
+
+	dryRun, err := service.ArchiveInactive(&ArchiveInactiveRequest{Days: 30, DryRun: true})
+	if err != nil {
+		t.Fatalf("ArchiveInactive dry run: %v", err)
+	}
+	if len(dryRun.Projects) != 1 || dryRun.Projects[0].ID != idle.ID || dryRun.Archived != 0 {
+		t.Errorf("dry run = %+v, want only idle listed and nothing archived", dryRun)
+	}
+	if got := projectNames(t, service, ProjectStatusArchived); len(got) != 0 {
+		t.Errorf("dry run archived %v", got)
+	}
+
+	resp, err := service.ArchiveInactive(&ArchiveInactiveRequest{Days: 30})
+	if err != nil {
+		t.Fatalf("ArchiveInactive: %v", err)
+	}
+	if resp.Archived != 1 {
+		t.Errorf("archived %d projects, want 1", resp.Archived)
+	}
+	if got := projectNames(t, service, ProjectStatusArchived); !reflect.DeepEqual(got, []string{"idle"}) {
+		t.Errorf("archived projects = %v, want [idle]", got)
+	}
+}
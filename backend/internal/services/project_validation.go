@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Severities of project validation issues
+const (
+	ValidationError   = "error"   // The project cannot work as configured
+	ValidationWarning = "warning" // The project works, probably not as intended
+)
+
+// knownReviewEvents are the review_events a project can enable
+var knownReviewEvents = map[string]bool{"push": true, "merge_request": true, "tag": true, "comment": true}
+
+// promptPlaceholderRegex matches every {{...}} of a prompt template
+var promptPlaceholderRegex = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// ValidateProjectRequest is a project configuration to check before saving it
+type ValidateProjectRequest struct {
+	URL              string `json:"url"`
+	Platform         string `json:"platform"`
+	ReviewEvents     string `json:"review_events"`
+	BranchFilter     string `json:"branch_filter"`
+	BranchFilterMode string `json:"branch_filter_mode"`
+	FileExtensions   string `json:"file_extensions"`
+	AIEnabled        *bool  `json:"ai_enabled"`
+	AIPromptID       *uint  `json:"ai_prompt_id"`
+	AIPrompt         string `json:"ai_prompt"`
+	LLMConfigID      *uint  `json:"llm_config_id"`
+	SkipLLMCheck     bool   `json:"skip_llm_check"` // Don't contact the LLM provider
+	TenantID         uint   `json:"-"`
+}
+
+// ProjectValidationIssue is a problem found in a project configuration
+type ProjectValidationIssue struct {
+	Field    string `json:"field"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+// ProjectValidationResult lists the problems of a project configuration
+type ProjectValidationResult struct {
+	Valid  bool                     `json:"valid"` // No errors; warnings don't block saving
+	Issues []ProjectValidationIssue `json:"issues"`
+}
+
+func (r *ProjectValidationResult) add(field, severity, code, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ProjectValidationIssue{
+		Field:    field,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+	if severity == ValidationError {
+		r.Valid = false
+	}
+}
+
+// Validate checks a project configuration: URL and platform, review events, branch filter,
+// prompt variables and the LLM, which it contacts unless SkipLLMCheck is set
+func (s *ProjectService) Validate(ctx context.Context, req *ValidateProjectRequest) *ProjectValidationResult {
+	result := &ProjectValidationResult{Valid: true, Issues: []ProjectValidationIssue{}}
+	validateProjectURL(result, req.URL, req.Platform)
+	validateReviewEvents(result, req.ReviewEvents)
+	validateBranchFilter(result, req.BranchFilter, req.BranchFilterMode)
+	validateFileExtensions(result, req.FileExtensions)
+
+	if req.AIEnabled != nil && !*req.AIEnabled {
+		return result
+	}
+	if prompt, field := s.validationPrompt(result, req); prompt != "" {
+		validatePromptTemplate(result, field, prompt)
+	}
+	s.validateLLM(ctx, result, req)
+	return result
+}
+
+// validateProjectURL checks the URL parses as a repository of the platform
+func validateProjectURL(result *ProjectValidationResult, projectURL, platform string) {
+	if strings.TrimSpace(projectURL) == "" {
+		result.add("url", ValidationError, "url_required", "URL is required")
+		return
+	}
+	u, err := url.Parse(projectURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		result.add("url", ValidationError, "invalid_url", "URL must be an http(s) repository URL, e.g. https://gitlab.example.com/group/repo")
+		return
+	}
+	info, err := parseRepoInfo(projectURL)
+	if err != nil {
+		result.add("url", ValidationError, "invalid_url", "%v", err)
+		return
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for knownHost, knownPlatform := range map[string]string{"github.com": "github", "gitlab.com": "gitlab", "bitbucket.org": "bitbucket"} {
+		if host == knownHost && platform != "" && platform != knownPlatform {
+			result.add("platform", ValidationError, "platform_mismatch", "%s hosts %s repositories, not %s", knownHost, knownPlatform, platform)
+			return
+		}
+	}
+
+	switch platform {
+	case "github", "bitbucket":
+		if platform == "bitbucket" && host != "bitbucket.org" {
+			if _, _, _, err := parseBitbucketRepo(projectURL); err != nil {
+				result.add("url", ValidationError, "invalid_url", "%v", err)
+			}
+			break
+		}
+		if strings.Count(info.projectPath, "/") != 1 {
+			result.add("url", ValidationWarning, "nested_path", "%s repositories are owner/repo; %q has nested groups, API calls will use %s/%s", platform, info.projectPath, info.owner, info.repo)
+		}
+	case "gitlab":
+	case "":
+		result.add("platform", ValidationError, "platform_required", "platform is required")
+	default:
+		result.add("platform", ValidationError, "unknown_platform", "unknown platform %q, expected github, gitlab or bitbucket", platform)
+	}
+}
+
+func validateReviewEvents(result *ProjectValidationResult, events string) {
+	if strings.TrimSpace(events) == "" {
+		return
+	}
+	reviewed := false
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !knownReviewEvents[event] {
+			result.add("review_events", ValidationWarning, "unknown_event", "unknown review event %q, expected push, merge_request, tag or comment", event)
+			continue
+		}
+		reviewed = reviewed || event == "push" || event == "merge_request"
+	}
+	if !reviewed {
+		result.add("review_events", ValidationWarning, "no_code_events", "neither push nor merge_request is enabled, commits are never reviewed")
+	}
+}
+
+// validateBranchFilter checks the glob syntax of the branch patterns and flags filters
+// that review every branch or none
+func validateBranchFilter(result *ProjectValidationResult, filter, mode string) {
+	if !ValidBranchFilterMode(mode) {
+		result.add("branch_filter_mode", ValidationError, "invalid_mode", "branch filter mode must be ignore or allow")
+		return
+	}
+	patterns := 0
+	for _, pattern := range strings.Split(filter, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		patterns++
+		if _, err := path.Match(pattern, ""); err != nil {
+			result.add("branch_filter", ValidationError, "invalid_pattern", "invalid branch pattern %q: %v", pattern, err)
+			continue
+		}
+		if pattern == "*" && mode != BranchFilterModeAllow {
+			result.add("branch_filter", ValidationWarning, "ignores_all", "pattern * ignores every branch, nothing is reviewed")
+		}
+	}
+	if patterns == 0 && mode == BranchFilterModeAllow {
+		result.add("branch_filter", ValidationWarning, "empty_allow_list", "allow mode without patterns reviews every branch")
+	}
+}
+
+func validateFileExtensions(result *ProjectValidationResult, extensions string) {
+	for _, ext := range strings.Split(extensions, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext != "" && !strings.HasPrefix(ext, ".") {
+			result.add("file_extensions", ValidationWarning, "extension_without_dot", "file extension %q doesn't start with a dot and matches no file", ext)
+		}
+	}
+}
+
+// validationPrompt returns the prompt the project would use and the field it comes from;
+// the system default prompt is not checked
+func (s *ProjectService) validationPrompt(result *ProjectValidationResult, req *ValidateProjectRequest) (string, string) {
+	if strings.TrimSpace(req.AIPrompt) != "" {
+		return req.AIPrompt, "ai_prompt"
+	}
+	if req.AIPromptID == nil {
+		return "", ""
+	}
+	var template models.PromptTemplate
+	if err := s.db.First(&template, *req.AIPromptID).Error; err != nil {
+		result.add("ai_prompt_id", ValidationError, "prompt_not_found", "prompt template %d not found", *req.AIPromptID)
+		return "", ""
+	}
+	return template.Content, "ai_prompt_id"
+}
+
+// validatePromptTemplate checks the placeholders and blocks of a prompt resolve at review time
+func validatePromptTemplate(result *ProjectValidationResult, field, prompt string) {
+	known := make(map[string]bool, len(PromptVariables))
+	for _, v := range PromptVariables {
+		known[v.Name] = true
+	}
+
+	seen := make(map[string]bool)
+	for _, placeholder := range promptPlaceholderRegex.FindAllString(prompt, -1) {
+		if seen[placeholder] {
+			continue
+		}
+		seen[placeholder] = true
+		if m := promptTagRegex.FindStringSubmatch(placeholder); m != nil && m[0] == placeholder {
+			name := m[2] + m[3]
+			if name != "" && !known["{{"+name+"}}"] {
+				result.add(field, ValidationWarning, "unknown_condition", "%s tests unknown variable %q and is always empty", placeholder, name)
+			}
+			if m[1] == "each" && m[2] != "changed_files" {
+				result.add(field, ValidationWarning, "unknown_each", "%s: only changed_files can be iterated", placeholder)
+			}
+			continue
+		}
+		if !known[placeholder] {
+			result.add(field, ValidationWarning, "unknown_variable", "unknown prompt variable %s is sent to the LLM as is", placeholder)
+		}
+	}
+
+	if _, ok := parsePromptTemplate(tokenizePrompt(prompt)); !ok {
+		result.add(field, ValidationError, "unbalanced_blocks", "unbalanced {{#if}}/{{#unless}}/{{#each}} blocks, the prompt would be sent without expanding them")
+	}
+	if !strings.Contains(prompt, "{{diffs}}") {
+		result.add(field, ValidationWarning, "missing_diffs", "the prompt doesn't include {{diffs}}, the LLM won't see the changes")
+	}
+	if !containsScoringInstruction(prompt) {
+		result.add(field, ValidationWarning, "missing_scoring", "the prompt has no scoring instructions; they are appended automatically")
+	}
+}
+
+// validateLLM checks the LLM config exists, is active and, unless skipped, reachable
+func (s *ProjectService) validateLLM(ctx context.Context, result *ProjectValidationResult, req *ValidateProjectRequest) {
+	field := "llm_config_id"
+	var cfg *models.LLMConfig
+	if req.LLMConfigID != nil {
+		var found models.LLMConfig
+		if err := s.db.First(&found, *req.LLMConfigID).Error; err != nil || (req.TenantID != 0 && found.TenantID != 0 && found.TenantID != req.TenantID) {
+			result.add(field, ValidationError, "llm_not_found", "LLM config %d not found", *req.LLMConfigID)
+			return
+		}
+		if !found.IsActive {
+			result.add(field, ValidationWarning, "llm_inactive", "LLM config %q is inactive, reviews fall back to the default LLM", found.Name)
+			return
+		}
+		cfg = &found
+	} else {
+		found, err := NewLLMConfigService(s.db).GetDefault()
+		if err != nil {
+			result.add(field, ValidationError, "no_llm", "no active LLM config, reviews will fail")
+			return
+		}
+		cfg = found
+	}
+
+	if req.SkipLLMCheck {
+		return
+	}
+	if err := ProbeLLM(ctx, cfg); err != nil {
+		result.add(field, ValidationWarning, "llm_unreachable", "LLM %q is not reachable: %v", cfg.Name, err)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func issueCodes(r *ProjectValidationResult) []string {
+	codes := make([]string, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		codes = append(codes, issue.Code)
+	}
+	return codes
+}
+
+func hasCode(r *ProjectValidationResult, code string) bool {
+	for _, issue := range r.Issues {
+		if issue.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateProjectURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       string
+		platform  string
+		wantCode  string
+		wantValid bool
+	}{
+		{"gitlab", "https://gitlab.example.com/group/sub/repo", "gitlab", "", true},
+		{"github", "https://github.com/owner/repo.git", "github", "", true},
+		{"empty", "", "gitlab", "url_required", false},
+		{"no scheme", "gitlab.example.com/group/repo", "gitlab", "invalid_url", false},
+		{"ssh", "git@github.com:owner/repo.git", "github", "invalid_url", false},
+		{"no repo", "https://gitlab.example.com/group", "gitlab", "invalid_url", false},
+		{"platform mismatch", "https://github.com/owner/repo", "gitlab", "platform_mismatch", false},
+		{"nested github path", "https://github.example.com/owner/team/repo", "github", "nested_path", true},
+		{"bitbucket server", "https://git.example.com/projects/PRJ/repos/repo", "bitbucket", "", true},
+		{"bitbucket server without project", "https://git.example.com/team/repo", "bitbucket", "invalid_url", false},
+		{"unknown platform", "https://gitea.example.com/owner/repo", "gitea", "unknown_platform", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ProjectValidationResult{Valid: true}
+			validateProjectURL(result, tt.url, tt.platform)
+			if result.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (issues %v)", result.Valid, tt.wantValid, issueCodes(result))
+			}
+			if tt.wantCode == "" && len(result.Issues) > 0 || tt.wantCode != "" && !hasCode(result, tt.wantCode) {
+				t.Errorf("issues = %v, want %q", issueCodes(result), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateBranchFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		filter    string
+		mode      string
+		wantCode  string
+		wantValid bool
+	}{
+		{"patterns", "main, release/*, feature-*", "allow", "", true},
+		{"empty ignore list", "", "ignore", "", true},
+		{"bad glob", "release/[", "ignore", "invalid_pattern", false},
+		{"bad mode", "main", "only", "invalid_mode", false},
+		{"ignore everything", "*", "ignore", "ignores_all", true},
+		{"empty allow list", "", "allow", "empty_allow_list", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ProjectValidationResult{Valid: true}
+			validateBranchFilter(result, tt.filter, tt.mode)
+			if result.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (issues %v)", result.Valid, tt.wantValid, issueCodes(result))
+			}
+			if tt.wantCode == "" && len(result.Issues) > 0 || tt.wantCode != "" && !hasCode(result, tt.wantCode) {
+				t.Errorf("issues = %v, want %q", issueCodes(result), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	const scoring = "\nScore out of 100. Total score: X/100"
+	tests := []struct {
+		name      string
+		prompt    string
+		wantCodes []string
+		wantValid bool
+	}{
+		{"valid", "Review {{project_name}}:\n{{diffs}}" + scoring, nil, true},
+		{"unknown variable", "Review {{projct_name}}:\n{{diffs}}" + scoring, []string{"unknown_variable"}, true},
+		{"unknown condition", "{{#if ticket}}x{{/if}}{{diffs}}" + scoring, []string{"unknown_condition"}, true},
+		{"unbalanced", "{{#if commits}}{{commits}}\n{{diffs}}" + scoring, []string{"unbalanced_blocks"}, false},
+		{"no diffs", "Review {{commits}}" + scoring, []string{"missing_diffs"}, true},
+		{"no scoring", "{{diffs}}", []string{"missing_scoring"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ProjectValidationResult{Valid: true}
+			validatePromptTemplate(result, "ai_prompt", tt.prompt)
+			if result.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (issues %v)", result.Valid, tt.wantValid, issueCodes(result))
+			}
+			if len(result.Issues) != len(tt.wantCodes) {
+				t.Fatalf("issues = %v, want %v", issueCodes(result), tt.wantCodes)
+			}
+			for _, code := range tt.wantCodes {
+				if !hasCode(result, code) {
+					t.Errorf("issues = %v, want %q", issueCodes(result), code)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeLLM(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"reachable", http.StatusOK, false},
+		{"bad key", http.StatusUnauthorized, true},
+		{"server error", http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer sk-test" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			cfg := &models.LLMConfig{Provider: "openai", BaseURL: srv.URL + "/v1", APIKey: "sk-test"}
+			if err := ProbeLLM(context.Background(), cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ProbeLLM() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
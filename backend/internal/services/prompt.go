@@ -32,7 +32,7 @@ func (s *PromptService) List(params PromptListParams) (*PromptListResult, error)
 	query := s.db.Model(&models.PromptTemplate{})
 
 	if params.Name != "" {
-		query = query.Where("name LIKE ?", "%"+params.Name+"%")
+		query = query.Where(LikeCondition("name"), ContainsPattern(params.Name))
 	}
 	if params.IsSystem != nil {
 		query = query.Where("is_system = ?", *params.IsSystem)
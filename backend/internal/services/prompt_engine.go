@@ -0,0 +1,189 @@
+package services
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// promptTagRegex matches the block tags of prompt templates: {{#if name}}, {{#unless name}},
+// {{#each changed_files}}, {{else}}, their closing tags and the legacy {{#if_name}} form
+var promptTagRegex = regexp.MustCompile(`\{\{(?:#(if|unless|each) ([a-z_]+)|#if_([a-z_]+)|(else)|/(if|unless|each)|/if_([a-z_]+))\}\}`)
+
+type promptTokenKind int
+
+const (
+	promptText promptTokenKind = iota
+	promptOpen
+	promptElse
+	promptClose
+)
+
+type promptToken struct {
+	kind  promptTokenKind
+	block string // if, unless, each
+	name  string // Variable of an opening tag
+	text  string
+}
+
+// promptNode is a text or a block of a parsed prompt template
+type promptNode struct {
+	text     string
+	block    string
+	name     string
+	children []promptNode
+	orElse   []promptNode
+}
+
+// renderPrompt expands the blocks of a prompt template and fills its placeholders in one
+// pass, so placeholders inside the diff, the file context or other values are left as they
+// are. values maps placeholders such as "{{branch}}" to their value; files are the items of
+// {{#each changed_files}}. Empty and "0" values count as unset in conditions. A template
+// with unbalanced blocks is filled without expanding them.
+func renderPrompt(template string, values map[string]string, files []FileDiff) string {
+	if nodes, ok := parsePromptTemplate(tokenizePrompt(template)); ok {
+		template = renderPromptNodes(nodes, values, files)
+	}
+	replacements := make([]string, 0, len(values)*2)
+	for name, value := range values {
+		replacements = append(replacements, name, value)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
+
+func tokenizePrompt(template string) []promptToken {
+	var tokens []promptToken
+	last := 0
+	for _, m := range promptTagRegex.FindAllStringSubmatchIndex(template, -1) {
+		if m[0] > last {
+			tokens = append(tokens, promptToken{kind: promptText, text: template[last:m[0]]})
+		}
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return template[m[2*i]:m[2*i+1]]
+		}
+		switch {
+		case group(1) != "":
+			tokens = append(tokens, promptToken{kind: promptOpen, block: group(1), name: group(2)})
+		case group(3) != "":
+			tokens = append(tokens, promptToken{kind: promptOpen, block: "if", name: group(3)})
+		case group(4) != "":
+			tokens = append(tokens, promptToken{kind: promptElse})
+		case group(5) != "":
+			tokens = append(tokens, promptToken{kind: promptClose, block: group(5)})
+		default:
+			tokens = append(tokens, promptToken{kind: promptClose, block: "if"})
+		}
+		last = m[1]
+	}
+	if last < len(template) {
+		tokens = append(tokens, promptToken{kind: promptText, text: template[last:]})
+	}
+	return tokens
+}
+
+// parsePromptTemplate builds the block tree of a token list; it reports false when blocks are unbalanced
+func parsePromptTemplate(tokens []promptToken) ([]promptNode, bool) {
+	nodes, rest, ok := parsePromptNodes(tokens, "")
+	return nodes, ok && len(rest) == 0
+}
+
+// parsePromptNodes parses nodes up to the closing tag of the enclosing block, returning the
+// tokens after it. An {{else}} inside an if or unless block starts its alternative.
+func parsePromptNodes(tokens []promptToken, enclosing string) ([]promptNode, []promptToken, bool) {
+	var nodes []promptNode
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		tokens = tokens[1:]
+		switch tok.kind {
+		case promptText:
+			nodes = append(nodes, promptNode{text: tok.text})
+		case promptOpen:
+			node := promptNode{block: tok.block, name: tok.name}
+			var ok bool
+			if node.children, tokens, ok = parsePromptNodes(tokens, tok.block); !ok {
+				return nil, nil, false
+			}
+			if len(tokens) > 0 && tokens[0].kind == promptElse {
+				if node.orElse, tokens, ok = parsePromptNodes(tokens[1:], tok.block); !ok {
+					return nil, nil, false
+				}
+			}
+			if len(tokens) == 0 || tokens[0].kind != promptClose {
+				return nil, nil, false
+			}
+			tokens = tokens[1:]
+			nodes = append(nodes, node)
+		case promptElse:
+			if enclosing != "if" && enclosing != "unless" {
+				return nil, nil, false
+			}
+			return nodes, append([]promptToken{tok}, tokens...), true
+		case promptClose:
+			if tok.block != enclosing {
+				return nil, nil, false
+			}
+			return nodes, append([]promptToken{tok}, tokens...), true
+		}
+	}
+	return nodes, nil, enclosing == ""
+}
+
+func renderPromptNodes(nodes []promptNode, values map[string]string, files []FileDiff) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.block {
+		case "":
+			b.WriteString(node.text)
+		case "if", "unless":
+			value := strings.TrimSpace(values["{{"+node.name+"}}"])
+			set := value != "" && value != "0"
+			if set == (node.block == "if") {
+				b.WriteString(renderPromptNodes(node.children, values, files))
+			} else {
+				b.WriteString(renderPromptNodes(node.orElse, values, files))
+			}
+		case "each":
+			if node.name == "changed_files" {
+				b.WriteString(renderChangedFiles(node.children, values, files))
+			}
+		}
+	}
+	return b.String()
+}
+
+// renderChangedFiles renders the body of {{#each changed_files}} once per file, at most
+// maxChangedFilesInPrompt times, with {{path}}, {{language}}, {{additions}} and {{deletions}}
+func renderChangedFiles(body []promptNode, values map[string]string, files []FileDiff) string {
+	var b strings.Builder
+	count := 0
+	for _, f := range files {
+		if f.FilePath == "" || f.FilePath == "unknown" {
+			continue
+		}
+		if count == maxChangedFilesInPrompt {
+			break
+		}
+		count++
+
+		item := map[string]string{
+			"{{path}}":      f.FilePath,
+			"{{language}}":  LanguageOfFile(f.FilePath),
+			"{{additions}}": strconv.Itoa(f.Additions),
+			"{{deletions}}": strconv.Itoa(f.Deletions),
+		}
+		scope := make(map[string]string, len(values)+len(item))
+		for k, v := range values {
+			scope[k] = v
+		}
+		replacements := make([]string, 0, len(item)*2)
+		for k, v := range item {
+			scope[k] = v
+			replacements = append(replacements, k, v)
+		}
+		b.WriteString(strings.NewReplacer(replacements...).Replace(renderPromptNodes(body, scope, files)))
+	}
+	return b.String()
+}
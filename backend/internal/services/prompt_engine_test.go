@@ -0,0 +1,46 @@
+package services
+
+import "testing"
+
+func TestRenderPrompt(t *testing.T) {
+	values := map[string]string{
+		"{{branch}}":       "main",
+		"{{mr_title}}":     "Add login",
+		"{{event_type}}":   "merge_request",
+		"{{file_context}}": "",
+		"{{diffs}}":        "+x := \"{{branch}}\"",
+	}
+	files := []FileDiff{
+		{FilePath: "main.go", Additions: 3, Deletions: 1},
+		{FilePath: "deploy.yaml", Additions: 1},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"plain variables", "Branch {{branch}}", "Branch main"},
+		{"if set", "{{#if mr_title}}MR: {{mr_title}}{{/if}}", "MR: Add login"},
+		{"if unset", "{{#if file_context}}Context{{/if}}Done", "Done"},
+		{"if else", "{{#if file_context}}Context{{else}}No context{{/if}}", "No context"},
+		{"unless", "{{#unless file_context}}No context{{/unless}}", "No context"},
+		{"unknown variable is unset", "{{#if nothing}}x{{else}}y{{/if}}", "y"},
+		{"legacy block", "{{#if_file_context}}Context{{/if_file_context}}", ""},
+		{"nested", "{{#if mr_title}}{{#if branch}}{{branch}}{{/if}}{{/if}}", "main"},
+		{"each", "{{#each changed_files}}- {{path}} ({{language}}, +{{additions}}/-{{deletions}})\n{{/each}}",
+			"- main.go (go, +3/-1)\n- deploy.yaml (yaml, +1/-0)\n"},
+		{"if inside each", "{{#each changed_files}}{{#if deletions}}{{path}} {{/if}}{{/each}}", "main.go "},
+		{"values are not expanded", "{{diffs}}", "+x := \"{{branch}}\""},
+		{"unbalanced blocks are left as is", "{{#if mr_title}}{{branch}}", "{{#if mr_title}}main"},
+		{"stray else is left as is", "a{{else}}b", "a{{else}}b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderPrompt(tt.template, values, files); got != tt.want {
+				t.Errorf("renderPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
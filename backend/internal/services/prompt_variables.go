@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// maxChangedFilesInPrompt caps the file list of {{changed_files}}
+const maxChangedFilesInPrompt = 100
+
+// PromptVariable is a placeholder prompt templates can use, resolved at review time
+type PromptVariable struct {
+	Name        string `json:"name"` // Placeholder as written in templates, e.g. {{branch}}
+	Description string `json:"description"`
+	Example     string `json:"example"`
+}
+
+// PromptVariables is the registry of prompt placeholders, served to prompt authors
+var PromptVariables = []PromptVariable{
+	{"{{diffs}}", "Unified diff under review", "diff --git a/main.go b/main.go ..."},
+	{"{{commits}}", "Commit messages, or the merge request title and description", "Fix login redirect"},
+	{"{{file_context}}", "Full content of the changed files, when file context is enabled; use inside {{#if_file_context}}...{{/if_file_context}}", ""},
+	{"{{project_profile}}", "Languages and frameworks of the project; use inside {{#if_project_profile}}...{{/if_project_profile}}", "Go + Gin + GORM"},
+	{"{{project_name}}", "Name of the project", "payment-service"},
+	{"{{branch}}", "Branch of the push, or the source branch of the merge request", "feature/login"},
+	{"{{author}}", "Author of the commits or the merge request", "alice"},
+	{"{{event_type}}", "Event being reviewed: push or merge_request", "merge_request"},
+	{"{{mr_title}}", "Merge request title, empty for pushes", "Add OAuth login"},
+	{"{{changed_files}}", "Changed files with their added and deleted lines, one per line", "- internal/auth/login.go (+42/-7)"},
+	{"{{language_breakdown}}", "Share of changed lines per language", "go 80%, yaml 20%"},
+	{"{{path}}", "Inside {{#each changed_files}}...{{/each}}: path of the file", "internal/auth/login.go"},
+	{"{{language}}", "Inside {{#each changed_files}}...{{/each}}: language of the file", "go"},
+	{"{{additions}}", "Inside {{#each changed_files}}...{{/each}}: added lines of the file", "42"},
+	{"{{deletions}}", "Inside {{#each changed_files}}...{{/each}}: deleted lines of the file", "7"},
+}
+
+// promptVariableValues resolves the placeholders of a request; files are the request's parsed diff
+func promptVariableValues(project *models.Project, req *ReviewRequest, files []FileDiff) map[string]string {
+	mrTitle := ""
+	if req.EventType == "merge_request" {
+		mrTitle = strings.TrimSpace(strings.SplitN(req.Commits, "\n", 2)[0])
+	}
+
+	return map[string]string{
+		"{{diffs}}":              req.Diffs,
+		"{{commits}}":            req.Commits,
+		"{{file_context}}":       req.FileContext,
+		"{{project_profile}}":    strings.TrimSpace(project.TechProfile),
+		"{{project_name}}":       project.Name,
+		"{{branch}}":             req.Branch,
+		"{{author}}":             req.Author,
+		"{{event_type}}":         req.EventType,
+		"{{mr_title}}":           mrTitle,
+		"{{changed_files}}":      formatChangedFiles(files),
+		"{{language_breakdown}}": formatLanguageBreakdown(LanguageBreakdown(files)),
+	}
+}
+
+// formatChangedFiles lists changed files with their line counts, at most maxChangedFilesInPrompt
+func formatChangedFiles(files []FileDiff) string {
+	var lines []string
+	for _, f := range files {
+		if f.FilePath == "" || f.FilePath == "unknown" {
+			continue
+		}
+		if len(lines) == maxChangedFilesInPrompt {
+			lines = append(lines, fmt.Sprintf("- ... and %d more files", len(files)-maxChangedFilesInPrompt))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("- %s (+%d/-%d)", f.FilePath, f.Additions, f.Deletions))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatLanguageBreakdown renders language shares as "go 80%, yaml 20%"
+func formatLanguageBreakdown(shares []LanguageShare) string {
+	parts := make([]string, 0, len(shares))
+	for _, share := range shares {
+		parts = append(parts, fmt.Sprintf("%s %g%%", share.Language, share.Percent))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestPromptVariableValues(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,2 +1,3 @@\n-old\n+new\n+more\n" +
+		"diff --git a/deploy.yaml b/deploy.yaml\n--- a/deploy.yaml\n+++ b/deploy.yaml\n@@ -1 +1 @@\n-a: 1\n+a: 2\n"
+	project := &models.Project{Name: "api"}
+
+	tests := []struct {
+		name string
+		req  *ReviewRequest
+		want map[string]string
+	}{
+		{
+			name: "merge request",
+			req:  &ReviewRequest{Diffs: diff, Commits: "Add login\n\nDetails", EventType: "merge_request", Branch: "feature/login", Author: "alice"},
+			want: map[string]string{
+				"{{project_name}}":       "api",
+				"{{branch}}":             "feature/login",
+				"{{author}}":             "alice",
+				"{{event_type}}":         "merge_request",
+				"{{mr_title}}":           "Add login",
+				"{{changed_files}}":      "- main.go (+2/-1)\n- deploy.yaml (+1/-1)",
+				"{{language_breakdown}}": "go 60%, yaml 40%",
+			},
+		},
+		{
+			name: "push has no MR title",
+			req:  &ReviewRequest{Diffs: "", Commits: "Fix bug", EventType: "push", Branch: "main"},
+			want: map[string]string{
+				"{{mr_title}}":           "",
+				"{{changed_files}}":      "",
+				"{{language_breakdown}}": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := promptVariableValues(project, tt.req, ParseDiffToFiles(tt.req.Diffs))
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPromptVariablesRegistryCoversPlaceholders(t *testing.T) {
+	values := promptVariableValues(&models.Project{}, &ReviewRequest{}, nil)
+	registered := make(map[string]bool)
+	for _, v := range PromptVariables {
+		registered[v.Name] = true
+	}
+	for name := range values {
+		if !registered[name] {
+			t.Errorf("%s is resolved but missing from PromptVariables", name)
+		}
+	}
+	for _, p := range promptPlaceholders {
+		if !strings.HasPrefix(p, "{{#") && !registered[p] {
+			t.Errorf("%s is a prompt placeholder but missing from PromptVariables", p)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Queue backends reported by QueueStats
+const (
+	QueueBackendSync = "sync"
+)
+
+// QueueWorkerStatus is a worker process consuming review tasks
+type QueueWorkerStatus struct {
+	Name        string `json:"name"` // Host and process, asynq server ID or stream consumer
+	Concurrency int    `json:"concurrency,omitempty"`
+	Active      int64  `json:"active"`            // Tasks being processed
+	IdleMs      int64  `json:"idle_ms,omitempty"` // Stream consumers: time since the last read
+	Status      string `json:"status,omitempty"`
+}
+
+// QueueStats describes the review task queue
+type QueueStats struct {
+	Backend   string              `json:"backend"` // sync, asynq, streams
+	Pending   int64               `json:"pending"` // Waiting for a worker
+	Active    int64               `json:"active"`  // Being processed
+	Retry     int64               `json:"retry"`   // asynq: waiting to be retried
+	Scheduled int64               `json:"scheduled"`
+	Dead      int64               `json:"dead"`                 // Failed for good: asynq archived tasks, stream dead letters
+	Processed int64               `json:"processed_today"`      // asynq: processed since midnight UTC
+	Failed    int64               `json:"failed_today"`         // asynq: failed since midnight UTC
+	LatencyMs int64               `json:"latency_ms,omitempty"` // asynq: age of the oldest pending task
+	Workers   []QueueWorkerStatus `json:"workers"`
+}
+
+// QueueInspector is implemented by task queues that report their state
+type QueueInspector interface {
+	Stats(ctx context.Context) (*QueueStats, error)
+}
+
+// Stats reports the tasks of the in-process queue; its workers are those of this instance
+func (q *SyncQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	total, err := q.store.Count()
+	if err != nil {
+		return nil, err
+	}
+	active := q.Active()
+	hostname, _ := os.Hostname()
+	return &QueueStats{
+		Backend: QueueBackendSync,
+		Pending: max(total-active, 0),
+		Active:  active,
+		Workers: []QueueWorkerStatus{{Name: hostname, Concurrency: q.workers, Active: active}},
+	}, nil
+}
+
+// Stats reports the default asynq queue and the asynq servers consuming it
+func (q *AsyncQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	info, err := q.inspector.GetQueueInfo("default")
+	if err != nil {
+		return nil, err
+	}
+	stats := &QueueStats{
+		Backend:   config.QueueBackendAsynq,
+		Pending:   int64(info.Pending),
+		Active:    int64(info.Active),
+		Retry:     int64(info.Retry),
+		Scheduled: int64(info.Scheduled),
+		Dead:      int64(info.Archived),
+		Processed: int64(info.Processed),
+		Failed:    int64(info.Failed),
+		LatencyMs: info.Latency.Milliseconds(),
+		Workers:   []QueueWorkerStatus{},
+	}
+	servers, err := q.inspector.Servers()
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		stats.Workers = append(stats.Workers, QueueWorkerStatus{
+			Name:        server.ID,
+			Concurrency: server.Concurrency,
+			Active:      int64(len(server.ActiveWorkers)),
+			Status:      server.Status,
+		})
+	}
+	return stats, nil
+}
+
+// Stats reports the review stream: undelivered and unacknowledged tasks, dead letters and
+// the consumers of the group
+func (q *StreamQueue) Stats(ctx context.Context) (*QueueStats, error) {
+	stats := &QueueStats{Backend: config.QueueBackendStreams, Workers: []QueueWorkerStatus{}}
+	groups, err := q.client.XInfoGroups(ctx, reviewStreamKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		if group.Name == reviewStreamGroup {
+			stats.Pending = max(group.Lag, 0)
+			stats.Active = group.Pending
+		}
+	}
+	if stats.Dead, err = q.client.XLen(ctx, reviewDeadLetterStreamKey).Result(); err != nil {
+		return nil, err
+	}
+
+	consumers, err := q.client.XInfoConsumers(ctx, reviewStreamKey, reviewStreamGroup).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, consumer := range consumers {
+		worker := QueueWorkerStatus{Name: consumer.Name, Active: consumer.Pending, IdleMs: consumer.Idle.Milliseconds()}
+		if consumer.Name == q.consumer {
+			worker.Concurrency = q.concurrency
+		}
+		stats.Workers = append(stats.Workers, worker)
+	}
+	return stats, nil
+}
+
+// RecentJobOutcomes summarizes the review and import jobs finished recently
+type RecentJobOutcomes struct {
+	Since          time.Time          `json:"since"`
+	ReviewStatuses map[string]int64   `json:"review_statuses"` // Reviews created since, by status
+	FailedReviews  []models.ReviewLog `json:"failed_reviews"`
+	ImportJobs     []models.ImportJob `json:"import_jobs"`
+}
+
+// recentFailedReviewLimit and recentImportJobLimit cap the jobs listed by RecentJobs
+const (
+	recentFailedReviewLimit = 20
+	recentImportJobLimit    = 10
+)
+
+// RecentJobs returns the review outcomes since the given time with the latest failures,
+// and the latest import jobs
+func RecentJobs(db *gorm.DB, since time.Time) (*RecentJobOutcomes, error) {
+	outcomes := &RecentJobOutcomes{Since: since, ReviewStatuses: make(map[string]int64)}
+
+	var counts []struct {
+		ReviewStatus string
+		Count        int64
+	}
+	if err := db.Model(&models.ReviewLog{}).
+		Select("review_status, COUNT(*) AS count").
+		Where("created_at >= ?", since).
+		Group("review_status").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, count := range counts {
+		outcomes.ReviewStatuses[count.ReviewStatus] = count.Count
+	}
+
+	if err := db.Select("id", "project_id", "commit_hash", "branch", "author", "review_status", "error_message", "retry_count", "created_at", "updated_at").
+		Where("review_status = ? AND updated_at >= ?", "failed", since).
+		Order("updated_at DESC").
+		Limit(recentFailedReviewLimit).
+		Find(&outcomes.FailedReviews).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Order("created_at DESC").Limit(recentImportJobLimit).Find(&outcomes.ImportJobs).Error; err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSyncQueue_Stats(t *testing.T) {
+	queue := NewSyncQueueWithStore(NewMemoryTaskStore(), 3)
+	for i := 1; i <= 4; i++ {
+		queue.Enqueue(&ReviewTask{ReviewLogID: uint(i)})
+	}
+	queue.active.Store(1)
+
+	stats, err := queue.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Backend != QueueBackendSync || stats.Pending != 3 || stats.Active != 1 {
+		t.Errorf("Stats() = %s pending %d active %d, expected sync pending 3 active 1", stats.Backend, stats.Pending, stats.Active)
+	}
+	if len(stats.Workers) != 1 || stats.Workers[0].Concurrency != 3 || stats.Workers[0].Active != 1 {
+		t.Errorf("Workers = %+v, expected one worker pool of 3 with 1 active", stats.Workers)
+	}
+}
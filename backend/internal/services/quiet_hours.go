@@ -0,0 +1,107 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// quietHoursChecker decides whether non-critical notifications to a bot should be
+// deferred because of its quiet hours or a public holiday.
+type quietHoursChecker struct {
+	holidayService *HolidayService
+	configService  *SystemConfigService
+}
+
+func newQuietHoursChecker(db *gorm.DB) *quietHoursChecker {
+	return &quietHoursChecker{
+		holidayService: NewHolidayService(),
+		configService:  NewSystemConfigService(db),
+	}
+}
+
+// isQuiet reports whether the bot is inside its quiet hours or on a skipped holiday at t
+func (q *quietHoursChecker) isQuiet(bot *models.IMBot, t time.Time) bool {
+	local := t
+	if bot.Timezone != "" {
+		if loc, err := time.LoadLocation(bot.Timezone); err == nil {
+			local = t.In(loc)
+		}
+	}
+
+	if inQuietHours(bot.QuietHoursStart, bot.QuietHoursEnd, local) {
+		return true
+	}
+
+	if bot.SkipHolidays {
+		country := bot.HolidayCountry
+		if country == "" {
+			country = q.configService.GetWithDefault("daily_report_holiday_country", "CN")
+		}
+		if !q.holidayService.IsWorkday(local, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// inQuietHours reports whether t falls within the [start, end) HH:MM window.
+// Windows where end is before start span midnight (e.g. 22:00-08:00).
+func inQuietHours(start, end string, t time.Time) bool {
+	startMin, ok := parseClock(start)
+	if !ok {
+		return false
+	}
+	endMin, ok := parseClock(end)
+	if !ok || startMin == endMin {
+		return false
+	}
+
+	m := t.Hour()*60 + t.Minute()
+	if startMin < endMin {
+		return m >= startMin && m < endMin
+	}
+	return m >= startMin || m < endMin
+}
+
+// parseClock parses HH:MM into minutes since midnight
+func parseClock(v string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(v), ":")
+	if len(parts) != 2 {
+		return 0, false
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil || h < 0 || h > 23 {
+		return 0, false
+	}
+	m, err := strconv.Atoi(parts[1])
+	if err != nil || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// ValidateQuietHours checks the quiet hours window and timezone of a bot
+func ValidateQuietHours(start, end, timezone string) error {
+	if (start == "") != (end == "") {
+		return fmt.Errorf("quiet hours start and end must both be set")
+	}
+	if start != "" {
+		if _, ok := parseClock(start); !ok {
+			return fmt.Errorf("invalid quiet hours start: %s", start)
+		}
+		if _, ok := parseClock(end); !ok {
+			return fmt.Errorf("invalid quiet hours end: %s", end)
+		}
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return fmt.Errorf("invalid timezone: %s", timezone)
+		}
+	}
+	return nil
+}
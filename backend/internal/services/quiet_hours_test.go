@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 1, 10, h, m, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name       string
+		start, end string
+		t          time.Time
+		want       bool
+	}{
+		{"no window", "", "", at(23, 0), false},
+		{"same day inside", "12:00", "14:00", at(13, 0), true},
+		{"same day end exclusive", "12:00", "14:00", at(14, 0), false},
+		{"overnight late", "22:00", "08:00", at(23, 30), true},
+		{"overnight early", "22:00", "08:00", at(7, 59), true},
+		{"overnight outside", "22:00", "08:00", at(9, 0), false},
+		{"invalid clock", "25:00", "08:00", at(1, 0), false},
+		{"empty window", "08:00", "08:00", at(8, 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := inQuietHours(tt.start, tt.end, tt.t); got != tt.want {
+				t.Errorf("inQuietHours(%q, %q) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateQuietHours(t *testing.T) {
+	tests := []struct {
+		name           string
+		start, end, tz string
+		wantErr        bool
+	}{
+		{"empty", "", "", "", false},
+		{"valid", "22:00", "07:30", "Asia/Shanghai", false},
+		{"missing end", "22:00", "", "", true},
+		{"bad minute", "22:61", "07:00", "", true},
+		{"bad timezone", "22:00", "07:00", "Mars/Base", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateQuietHours(tt.start, tt.end, tt.tz)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuietHours() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFoldNonWorkdays(t *testing.T) {
+	weekdaysOnly := func(t time.Time) bool {
+		return t.Weekday() != time.Saturday && t.Weekday() != time.Sunday
+	}
+
+	monday := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if got := foldNonWorkdays(monday, weekdaysOnly); !got.Equal(monday.AddDate(0, 0, -2)) {
+		t.Errorf("monday should fold weekend, got %v", got)
+	}
+
+	tuesday := monday.AddDate(0, 0, 1)
+	if got := foldNonWorkdays(tuesday, weekdaysOnly); !got.Equal(tuesday) {
+		t.Errorf("tuesday should not fold, got %v", got)
+	}
+
+	never := func(time.Time) bool { return false }
+	if got := foldNonWorkdays(monday, never); !got.Equal(monday.AddDate(0, 0, -14)) {
+		t.Errorf("fold should stop after 14 days, got %v", got)
+	}
+}
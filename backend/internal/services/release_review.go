@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// EventTypeRelease is the review event type of tag pushes and published releases
+const EventTypeRelease = "release"
+
+// maxReleaseDiffChars bounds the diff included in a release review prompt.
+// Release ranges are often large; the commit list carries the changelog either way.
+const maxReleaseDiffChars = 60000
+
+// ReleaseReviewRequest describes the changes shipped by a tag
+type ReleaseReviewRequest struct {
+	ProjectID   uint
+	Tag         string
+	PreviousTag string // Empty for the first tag of a repository
+	Commits     string // One commit per line
+	Diffs       string
+}
+
+const releasePromptTemplate = `You are a senior engineer reviewing a release before it ships.
+
+Release: {{tag}}
+Changes since: {{previous_tag}}
+
+## Commits
+{{commits}}
+
+## Diff
+{{diffs}}
+
+Write a release review in Markdown with the following sections:
+
+### Changelog
+Group the commits into Features, Fixes and Other changes, one line per change. Skip merge commits.
+
+### Risk Assessment
+List the changes most likely to cause problems in production (breaking API changes,
+database migrations, configuration changes, security-sensitive code) with their impact.
+
+### Release Readiness
+Summarize whether the release is ready to ship and what should be verified first.
+
+End with a line "Score: X/100" rating the release readiness, where 100 means safe to ship.`
+
+// buildReleasePrompt renders the release review prompt, truncating the diff
+func buildReleasePrompt(req *ReleaseReviewRequest) string {
+	previous := req.PreviousTag
+	if previous == "" {
+		previous = "the beginning of the repository"
+	}
+	diffs := req.Diffs
+	if len(diffs) > maxReleaseDiffChars {
+		diffs = diffs[:maxReleaseDiffChars] + "\n... (diff truncated, review the commit list for the remaining changes)"
+	}
+	return strings.NewReplacer(
+		"{{tag}}", req.Tag,
+		"{{previous_tag}}", previous,
+		"{{commits}}", req.Commits,
+		"{{diffs}}", diffs,
+	).Replace(releasePromptTemplate)
+}
+
+// ReviewRelease generates a changelog and risk assessment for the commits shipped by a tag
+func (s *AIService) ReviewRelease(ctx context.Context, req *ReleaseReviewRequest) (*ReviewResult, error) {
+	var project models.Project
+	if err := s.db.First(&project, req.ProjectID).Error; err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+
+	prompt := buildReleasePrompt(req)
+	logger.Infof("[AI] Release review prompt for %s: %d chars", req.Tag, len(prompt))
+
+	llmConfigs := s.getOrderedLLMConfigs(&project)
+	if len(llmConfigs) == 0 {
+		return nil, fmt.Errorf("no LLM configuration available")
+	}
+
+	var lastErr error
+	for _, llmConfig := range llmConfigs {
+		result, err := s.callLLM(ctx, &llmConfig, prompt)
+		if err == nil {
+			s.recordLLMChainResult(nil)
+			return result, nil
+		}
+		lastErr = err
+		logger.Infof("[AI] LLM %s failed on release review: %v, trying next...", llmConfig.Name, err)
+	}
+
+	err := fmt.Errorf("%w, last error: %w", ErrAllLLMsFailed, lastErr)
+	s.recordLLMChainResult(err)
+	return nil, err
+}
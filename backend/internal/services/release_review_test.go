@@ -0,0 +1,43 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildReleasePrompt(t *testing.T) {
+	prompt := buildReleasePrompt(&ReleaseReviewRequest{
+		Tag:         "v1.2.0",
+		PreviousTag: "v1.1.0",
+		Commits:     "01234567: Add login page",
+		Diffs:       "diff --git a/main.go b/main.go",
+	})
+	for _, want := range []string{"Release: v1.2.0", "Changes since: v1.1.0", "01234567: Add login page", "diff --git a/main.go b/main.go", "Score: X/100"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt does not contain %q", want)
+		}
+	}
+	if strings.Contains(prompt, "{{") {
+		t.Errorf("prompt contains unreplaced placeholders:\n%s", prompt)
+	}
+}
+
+func TestBuildReleasePrompt_FirstRelease(t *testing.T) {
+	prompt := buildReleasePrompt(&ReleaseReviewRequest{Tag: "v1.0.0"})
+	if !strings.Contains(prompt, "Changes since: the beginning of the repository") {
+		t.Errorf("first release prompt should mention the repository start:\n%s", prompt)
+	}
+}
+
+func TestBuildReleasePrompt_TruncatesDiff(t *testing.T) {
+	prompt := buildReleasePrompt(&ReleaseReviewRequest{
+		Tag:   "v1.0.0",
+		Diffs: strings.Repeat("x", maxReleaseDiffChars+1000),
+	})
+	if !strings.Contains(prompt, "diff truncated") {
+		t.Error("expected truncation note")
+	}
+	if len(prompt) > maxReleaseDiffChars+len(releasePromptTemplate)+200 {
+		t.Errorf("prompt length %d exceeds the diff limit", len(prompt))
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Report publisher types
+const (
+	PublisherConfluence = "confluence"
+	PublisherGitLabWiki = "gitlab_wiki"
+)
+
+// defaultReportTitleTemplate is used when a publisher has no title template
+const defaultReportTitleTemplate = "CodeSentry {type} report {date}"
+
+// ReportPublisherService manages report publishers and publishes reports to Confluence and wikis.
+type ReportPublisherService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewReportPublisherService(db *gorm.DB) *ReportPublisherService {
+	return &ReportPublisherService{
+		db:         db,
+		httpClient: OutboundHTTPClient(30 * time.Second),
+	}
+}
+
+// Publish sends a report to every active publisher of its tenant that handles its report type.
+// Each publisher records its own outcome; the last error is returned.
+func (s *ReportPublisherService) Publish(report *models.DailyReport, markdown string) error {
+	var publishers []models.ReportPublisher
+	err := s.db.Where("is_active = ? AND (tenant_id = 0 OR tenant_id = ?)", true, report.TenantID).
+		Find(&publishers).Error
+	if err != nil {
+		return err
+	}
+
+	tenant := ""
+	if report.TenantID > 0 {
+		tenant = fmt.Sprintf("tenant-%d", report.TenantID)
+		var t models.Tenant
+		if s.db.Select("slug").First(&t, report.TenantID).Error == nil && t.Slug != "" {
+			tenant = t.Slug
+		}
+	}
+
+	var lastErr error
+	for i := range publishers {
+		p := &publishers[i]
+		if !publisherHandlesReport(p, report.ReportType) {
+			continue
+		}
+		title := reportPageTitle(p.TitleTemplate, report.ReportType, report.ReportDate, tenant)
+		now := time.Now()
+		updates := map[string]interface{}{"last_published_at": &now, "last_error": ""}
+		if err := s.publishTo(p, title, markdown); err != nil {
+			logger.Warnf("[ReportPublisher] Failed to publish %s report to %s: %v", report.ReportType, p.Name, err)
+			updates = map[string]interface{}{"last_error": err.Error()}
+			lastErr = err
+		} else {
+			logger.Infof("[ReportPublisher] Published %s report to %s: %s", report.ReportType, p.Name, title)
+		}
+		s.db.Model(p).Updates(updates)
+	}
+	return lastErr
+}
+
+// HasActive reports whether an active publisher handles the report type
+func (s *ReportPublisherService) HasActive(reportType string) bool {
+	var publishers []models.ReportPublisher
+	if err := s.db.Where("is_active = ?", true).Find(&publishers).Error; err != nil {
+		return false
+	}
+	for i := range publishers {
+		if publisherHandlesReport(&publishers[i], reportType) {
+			return true
+		}
+	}
+	return false
+}
+
+// publisherHandlesReport reports whether the publisher is configured for the report type
+func publisherHandlesReport(p *models.ReportPublisher, reportType string) bool {
+	for _, t := range splitAndTrim(p.ReportTypes, ",") {
+		if t == reportType {
+			return true
+		}
+	}
+	return false
+}
+
+// reportPageTitle renders a title template for a report. A tenant's report always names the
+// tenant, appended when the template has no {tenant}, so the reports of the tenants sharing
+// a global publisher don't update each other's page.
+func reportPageTitle(template, reportType string, date time.Time, tenant string) string {
+	if strings.TrimSpace(template) == "" {
+		template = defaultReportTitleTemplate
+	}
+	if tenant != "" && !strings.Contains(template, "{tenant}") {
+		template += " ({tenant})"
+	}
+	title := strings.NewReplacer(
+		"{type}", reportType,
+		"{date}", date.Format("2006-01-02"),
+		"{tenant}", tenant,
+	).Replace(template)
+	return strings.Join(strings.Fields(title), " ")
+}
+
+func (s *ReportPublisherService) publishTo(p *models.ReportPublisher, title, markdown string) error {
+	switch p.Type {
+	case PublisherConfluence:
+		return s.publishConfluence(p, title, markdown)
+	case PublisherGitLabWiki:
+		return s.publishGitLabWiki(p, title, markdown)
+	default:
+		return fmt.Errorf("unsupported report publisher type: %s", p.Type)
+	}
+}
+
+// confluenceStorage converts report markdown to Confluence storage format (XHTML)
+func confluenceStorage(markdown string) string {
+	return strings.NewReplacer("<br>", "<br />", "<hr>", "<hr />").Replace(markdownToHTML(markdown))
+}
+
+func confluenceAuth(p *models.ReportPublisher) (string, error) {
+	if p.Username == "" {
+		return "Bearer " + p.APIToken, nil
+	}
+	token, err := ResolveSecret(context.Background(), p.APIToken)
+	if err != nil {
+		return "", err
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(p.Username+":"+token)), nil
+}
+
+// publishConfluence creates the page, or adds a new version when a page with the title exists
+func (s *ReportPublisherService) publishConfluence(p *models.ReportPublisher, title, markdown string) error {
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	query := url.Values{"spaceKey": {p.Space}, "title": {title}, "expand": {"version"}}
+	var found struct {
+		Results []struct {
+			ID      string `json:"id"`
+			Version struct {
+				Number int `json:"number"`
+			} `json:"version"`
+		} `json:"results"`
+	}
+	if err := s.doJSON(p, "GET", baseURL+"/rest/api/content?"+query.Encode(), nil, &found); err != nil {
+		return err
+	}
+
+	payload := map[string]interface{}{
+		"type":  "page",
+		"title": title,
+		"space": map[string]string{"key": p.Space},
+		"body": map[string]interface{}{
+			"storage": map[string]string{"value": confluenceStorage(markdown), "representation": "storage"},
+		},
+	}
+	if len(found.Results) > 0 {
+		page := found.Results[0]
+		payload["version"] = map[string]int{"number": page.Version.Number + 1}
+		return s.doJSON(p, "PUT", baseURL+"/rest/api/content/"+page.ID, payload, nil)
+	}
+	if p.ParentPageID != "" {
+		payload["ancestors"] = []map[string]string{{"id": p.ParentPageID}}
+	}
+	return s.doJSON(p, "POST", baseURL+"/rest/api/content", payload, nil)
+}
+
+// gitLabWikiSlug returns the wiki slug GitLab derives from a page title
+func gitLabWikiSlug(title string) string {
+	return strings.ReplaceAll(title, " ", "-")
+}
+
+// publishGitLabWiki updates the wiki page, creating it when it does not exist yet
+func (s *ReportPublisherService) publishGitLabWiki(p *models.ReportPublisher, title, markdown string) error {
+	wikiURL := fmt.Sprintf("%s/api/v4/projects/%s/wikis", strings.TrimRight(p.BaseURL, "/"), url.PathEscape(p.Space))
+	payload := map[string]string{"title": title, "content": markdown, "format": "markdown"}
+
+	err := s.doJSON(p, "PUT", wikiURL+"/"+url.PathEscape(gitLabWikiSlug(title)), payload, nil)
+	if statusErr, ok := err.(*publisherStatusError); ok && statusErr.status == http.StatusNotFound {
+		return s.doJSON(p, "POST", wikiURL, payload, nil)
+	}
+	return err
+}
+
+type publisherStatusError struct {
+	target string
+	status int
+}
+
+func (e *publisherStatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.target, e.status)
+}
+
+// doJSON sends an authenticated JSON request and decodes the response into out when given
+func (s *ReportPublisherService) doJSON(p *models.ReportPublisher, method, apiURL string, payload, out interface{}) error {
+	var data []byte
+	if payload != nil {
+		var err error
+		if data, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, apiURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if p.Type == PublisherGitLabWiki {
+		req.Header.Set("PRIVATE-TOKEN", p.APIToken)
+	} else {
+		auth, err := confluenceAuth(p)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return &publisherStatusError{target: p.Type, status: resp.StatusCode}
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// TestConnection verifies the publisher credentials by reading the target space or project.
+func (s *ReportPublisherService) TestConnection(p *models.ReportPublisher) error {
+	baseURL := strings.TrimRight(p.BaseURL, "/")
+	switch p.Type {
+	case PublisherConfluence:
+		return s.doJSON(p, "GET", baseURL+"/rest/api/space/"+url.PathEscape(p.Space), nil, nil)
+	case PublisherGitLabWiki:
+		return s.doJSON(p, "GET", baseURL+"/api/v4/projects/"+url.PathEscape(p.Space)+"/wikis", nil, nil)
+	default:
+		return fmt.Errorf("unsupported report publisher type: %s", p.Type)
+	}
+}
+
+// ValidateReportPublisher checks the type and report types of a publisher
+func ValidateReportPublisher(p *models.ReportPublisher) error {
+	if p.Type != PublisherConfluence && p.Type != PublisherGitLabWiki {
+		return fmt.Errorf("unsupported report publisher type: %s", p.Type)
+	}
+	for _, t := range splitAndTrim(p.ReportTypes, ",") {
+		if t != "daily" && t != "weekly" {
+			return fmt.Errorf("unknown report type %q", t)
+		}
+	}
+	return nil
+}
+
+// --- CRUD for ReportPublisher ---
+
+// List returns the publishers of a tenant, 0 = all tenants, with API tokens masked
+func (s *ReportPublisherService) List(tenantID uint) ([]models.ReportPublisher, error) {
+	var publishers []models.ReportPublisher
+	if err := ScopeTenant(s.db, tenantID).Find(&publishers).Error; err != nil {
+		return nil, err
+	}
+	for i := range publishers {
+		maskPublisherToken(&publishers[i])
+	}
+	return publishers, nil
+}
+
+func maskPublisherToken(p *models.ReportPublisher) {
+	if len(p.APIToken) > 8 {
+		p.APITokenMask = p.APIToken[:4] + "****" + p.APIToken[len(p.APIToken)-4:]
+	} else if p.APIToken != "" {
+		p.APITokenMask = "****"
+	}
+	p.APIToken = ""
+}
+
+func (s *ReportPublisherService) GetByID(id uint) (*models.ReportPublisher, error) {
+	var publisher models.ReportPublisher
+	if err := s.db.First(&publisher, id).Error; err != nil {
+		return nil, err
+	}
+	return &publisher, nil
+}
+
+func (s *ReportPublisherService) Create(publisher *models.ReportPublisher) error {
+	if err := ValidateReportPublisher(publisher); err != nil {
+		return err
+	}
+	if err := s.db.Create(publisher).Error; err != nil {
+		return err
+	}
+	maskPublisherToken(publisher)
+	return nil
+}
+
+// Update applies updates to a publisher; an empty api_token keeps the stored token
+func (s *ReportPublisherService) Update(id uint, updates map[string]interface{}) (*models.ReportPublisher, error) {
+	publisher, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := updates["api_token"]; ok && token == "" {
+		delete(updates, "api_token")
+	}
+	delete(updates, "id")
+	delete(updates, "last_published_at")
+	delete(updates, "last_error")
+
+	check := *publisher
+	if v, ok := updates["type"].(string); ok {
+		check.Type = v
+	}
+	if v, ok := updates["report_types"].(string); ok {
+		check.ReportTypes = v
+	}
+	if err := ValidateReportPublisher(&check); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(publisher).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	publisher, err = s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	maskPublisherToken(publisher)
+	return publisher, nil
+}
+
+func (s *ReportPublisherService) Delete(id uint) error {
+	return s.db.Delete(&models.ReportPublisher{}, id).Error
+}
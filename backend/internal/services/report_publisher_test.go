@@ -0,0 +1,122 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestReportPageTitle(t *testing.T) {
+	date := time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		template string
+		tenant   string
+		want     string
+	}{
+		{"default template", "", "", "CodeSentry weekly report 2024-03-11"},
+		{"custom template", "Reviews/{tenant}/{type}-{date}", "acme", "Reviews/acme/weekly-2024-03-11"},
+		{"empty tenant collapses spaces", "{tenant} {type} {date}", "", "weekly 2024-03-11"},
+		{"tenant appended to the default template", "", "acme", "CodeSentry weekly report 2024-03-11 (acme)"},
+		{"tenant appended to a template without it", "{type}-{date}", "acme", "weekly-2024-03-11 (acme)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reportPageTitle(tt.template, "weekly", date, tt.tenant); got != tt.want {
+				t.Errorf("reportPageTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisherHandlesReport(t *testing.T) {
+	tests := []struct {
+		reportTypes string
+		reportType  string
+		want        bool
+	}{
+		{"daily", "daily", true},
+		{"daily", "weekly", false},
+		{"daily, weekly", "weekly", true},
+		{"", "daily", false},
+	}
+	for _, tt := range tests {
+		p := &models.ReportPublisher{ReportTypes: tt.reportTypes}
+		if got := publisherHandlesReport(p, tt.reportType); got != tt.want {
+			t.Errorf("publisherHandlesReport(%q, %q) = %v, want %v", tt.reportTypes, tt.reportType, got, tt.want)
+		}
+	}
+}
+
+func TestValidateReportPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       models.ReportPublisher
+		wantErr bool
+	}{
+		{"confluence", models.ReportPublisher{Type: PublisherConfluence, ReportTypes: "daily,weekly"}, false},
+		{"gitlab wiki", models.ReportPublisher{Type: PublisherGitLabWiki, ReportTypes: "weekly"}, false},
+		{"unknown type", models.ReportPublisher{Type: "notion", ReportTypes: "daily"}, true},
+		{"unknown report type", models.ReportPublisher{Type: PublisherConfluence, ReportTypes: "monthly"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateReportPublisher(&tt.p); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateReportPublisher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfluenceStorage(t *testing.T) {
+	got := confluenceStorage("## Overview\nline one\nline two\n\n---\n- item")
+	for _, want := range []string{"<h2>Overview</h2>", "line one<br />", "<hr />", "<li>item</li>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("confluenceStorage() = %q, missing %q", got, want)
+		}
+	}
+	if strings.Contains(got, "<br>") || strings.Contains(got, "<hr>") {
+		t.Errorf("confluenceStorage() = %q, contains unclosed void elements", got)
+	}
+}
+
+func TestPublishGitLabWikiCreatesMissingPage(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.EscapedPath())
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPut {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var payload map[string]string
+		json.NewDecoder(r.Body).Decode(&payload)
+		if payload["title"] != "Daily report" || payload["format"] != "markdown" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	s := NewReportPublisherService(nil)
+	p := &models.ReportPublisher{Type: PublisherGitLabWiki, BaseURL: server.URL, APIToken: "secret", Space: "group/project"}
+	if err := s.publishTo(p, "Daily report", "# Report"); err != nil {
+		t.Fatalf("publishTo() error = %v", err)
+	}
+	want := []string{
+		"PUT /api/v4/projects/group%2Fproject/wikis/Daily-report",
+		"POST /api/v4/projects/group%2Fproject/wikis",
+	}
+	if strings.Join(calls, "|") != strings.Join(want, "|") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
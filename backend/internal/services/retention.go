@@ -0,0 +1,235 @@
+package services
+
+import (
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// RetentionService enforces per-entity data retention policies
+type RetentionService struct {
+	db            *gorm.DB
+	configService *SystemConfigService
+}
+
+func NewRetentionService(db *gorm.DB) *RetentionService {
+	return &RetentionService{
+		db:            db,
+		configService: NewSystemConfigService(db),
+	}
+}
+
+// RetentionResult reports how many rows each policy affected
+type RetentionResult struct {
+	ReviewTextCleared     int64 `json:"review_text_cleared"`
+	ReviewLogsDeleted     int64 `json:"review_logs_deleted"`
+	ReviewArchivesDeleted int64 `json:"review_archives_deleted"`
+	DailyReportsDeleted   int64 `json:"daily_reports_deleted"`
+	FeedbacksDeleted      int64 `json:"feedbacks_deleted"`
+	AIUsageLogsDeleted    int64 `json:"ai_usage_logs_deleted"`
+	DeletedRowsPurged     int64 `json:"deleted_rows_purged"`
+}
+
+// softDeletedModels are the models whose deletes are soft and purged by PurgeDeleted, besides
+// review logs which are purged with their dependent rows
+var softDeletedModels = []interface{}{
+	&models.Project{},
+	&models.ProjectMember{},
+	&models.IMBot{},
+	&models.GitCredential{},
+	&models.IssueTracker{},
+	&models.LLMConfig{},
+	&models.PromptTemplate{},
+	&models.ReportPublisher{},
+	&models.ReviewHook{},
+	&models.ReviewRule{},
+	&models.ReviewTemplate{},
+	&models.SavedDashboard{},
+	&models.Tenant{},
+	&models.User{},
+}
+
+// Run applies every configured retention policy once
+func (s *RetentionService) Run() (*RetentionResult, error) {
+	cfg := s.configService.GetRetentionConfig()
+	result := &RetentionResult{}
+	now := time.Now()
+
+	if cfg.ReviewLogDays > 0 {
+		n, err := s.deleteReviewLogs("created_at < ?", now.AddDate(0, 0, -cfg.ReviewLogDays))
+		if err != nil {
+			return result, err
+		}
+		result.ReviewLogsDeleted = n
+	}
+
+	if cfg.ReviewTextDays > 0 {
+		cutoff := now.AddDate(0, 0, -cfg.ReviewTextDays)
+		res := s.db.Model(&models.ReviewLog{}).
+			Where("created_at < ? AND (review_result <> '' OR diff_content <> '')", cutoff).
+			Updates(map[string]interface{}{"review_result": "", "diff_content": ""})
+		if res.Error != nil {
+			return result, res.Error
+		}
+		result.ReviewTextCleared = res.RowsAffected
+
+		archived := s.db.Where("review_log_id IN (?)",
+			s.db.Model(&models.ReviewLog{}).Select("id").Where("created_at < ?", cutoff),
+		).Delete(&models.ReviewLogArchive{})
+		if archived.Error != nil {
+			return result, archived.Error
+		}
+		result.ReviewArchivesDeleted += archived.RowsAffected
+	}
+
+	if cfg.ReviewArchiveDays > 0 {
+		res := s.db.Where("archived_at < ?", now.AddDate(0, 0, -cfg.ReviewArchiveDays)).Delete(&models.ReviewLogArchive{})
+		if res.Error != nil {
+			return result, res.Error
+		}
+		result.ReviewArchivesDeleted += res.RowsAffected
+	}
+
+	if cfg.DailyReportDays > 0 {
+		res := s.db.Where("created_at < ?", now.AddDate(0, 0, -cfg.DailyReportDays)).Delete(&models.DailyReport{})
+		if res.Error != nil {
+			return result, res.Error
+		}
+		result.DailyReportsDeleted = res.RowsAffected
+	}
+
+	if cfg.FeedbackDays > 0 {
+		res := s.db.Where("created_at < ?", now.AddDate(0, 0, -cfg.FeedbackDays)).Delete(&models.ReviewFeedback{})
+		if res.Error != nil {
+			return result, res.Error
+		}
+		result.FeedbacksDeleted = res.RowsAffected
+	}
+
+	if cfg.AIUsageDays > 0 {
+		n, err := NewAIUsageService(s.db).CleanupBefore(now.AddDate(0, 0, -cfg.AIUsageDays))
+		if err != nil {
+			return result, err
+		}
+		result.AIUsageLogsDeleted = n
+	}
+
+	if cfg.DeletedDays > 0 {
+		n, err := s.PurgeDeleted(now.AddDate(0, 0, -cfg.DeletedDays))
+		if err != nil {
+			return result, err
+		}
+		result.DeletedRowsPurged = n
+	}
+
+	return result, nil
+}
+
+type PurgeDeletedRequest struct {
+	Days int `json:"days" binding:"required,min=1"` // Purge rows deleted more than this many days ago
+}
+
+// PurgeDeleted permanently removes the rows soft-deleted before cutoff. Purged projects take
+// their template bindings and target branch policies with them, purged users their refresh tokens.
+func (s *RetentionService) PurgeDeleted(cutoff time.Time) (int64, error) {
+	purged, err := s.deleteReviewLogs("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+	if err != nil {
+		return purged, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		projectIDs := tx.Unscoped().Model(&models.Project{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err := tx.Where("project_id IN (?)", projectIDs).Delete(&models.ProjectTemplateBinding{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("project_id IN (?)", projectIDs).Delete(&models.TargetBranchPolicy{}).Error; err != nil {
+			return err
+		}
+		userIDs := tx.Unscoped().Model(&models.User{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff)
+		if err := tx.Where("user_id IN (?)", userIDs).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		for _, model := range softDeletedModels {
+			res := tx.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(model)
+			if res.Error != nil {
+				return res.Error
+			}
+			purged += res.RowsAffected
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// deleteReviewLogs permanently removes the review logs matching the condition, soft-deleted or not, together with their feedback, archives, findings and changed files
+func (s *RetentionService) deleteReviewLogs(query string, args ...interface{}) (int64, error) {
+	var deleted int64
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Unscoped().Model(&models.ReviewLog{}).Select("id").Where(query, args...)
+		if err := tx.Where("review_log_id IN (?)", ids).Delete(&models.ReviewFeedback{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_log_id IN (?)", ids).Delete(&models.ReviewLogArchive{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_log_id IN (?)", ids).Delete(&models.ReviewFinding{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_log_id IN (?)", ids).Delete(&models.ReviewFile{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where(query, args...).Delete(&models.ReviewLog{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
+
+func (s *RetentionService) runRetention() {
+	if !s.configService.GetRetentionConfig().Enabled {
+		return
+	}
+
+	result, err := s.Run()
+	if err != nil {
+		logger.Errorf("[Retention] Failed to apply retention policies: %v", err)
+		return
+	}
+	logger.Infof("[Retention] Applied retention policies: review text cleared %d, review logs %d, archives %d, daily reports %d, feedbacks %d, ai usage logs %d, soft-deleted rows purged %d",
+		result.ReviewTextCleared, result.ReviewLogsDeleted, result.ReviewArchivesDeleted,
+		result.DailyReportsDeleted, result.FeedbacksDeleted, result.AIUsageLogsDeleted, result.DeletedRowsPurged)
+}
+
+var retentionStopChan chan struct{}
+
+// StartRetentionScheduler starts a goroutine that enforces retention policies daily
+func StartRetentionScheduler(db *gorm.DB) {
+	retentionStopChan = make(chan struct{})
+	go func() {
+		service := NewRetentionService(db)
+		RegisterScheduler("retention", 24*time.Hour)
+		RunScheduled("retention", func() error { service.runRetention(); return nil })
+
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("retention", func() error { service.runRetention(); return nil })
+			case <-retentionStopChan:
+				logger.Infof("[Retention] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopRetentionScheduler stops the retention scheduler
+func StopRetentionScheduler() {
+	if retentionStopChan != nil {
+		close(retentionStopChan)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
 	"github.com/huangang/codesentry/backend/internal/config"
@@ -17,8 +18,8 @@ import (
 )
 
 const (
-	MaxRetryCount  = 3
-	RetryInterval  = 5 * time.Minute
+	MaxRetryCount  = 3 // Default automatic retries of a failed review, see ProjectRetryPolicy
+	RetryInterval  = time.Minute
 	RetryBatchSize = 10
 	StuckTimeout   = 10 * time.Minute // Reviews stuck in pending/analyzing for more than this will be marked as failed
 )
@@ -35,7 +36,7 @@ func NewRetryService(db *gorm.DB, aiCfg *config.OpenAIConfig) *RetryService {
 		db:                  db,
 		aiService:           NewAIService(db, aiCfg),
 		notificationService: NewNotificationService(db),
-		httpClient:          &http.Client{Timeout: 30 * time.Second},
+		httpClient:          PlatformHTTPClient(),
 	}
 }
 
@@ -45,6 +46,7 @@ func StartRetryScheduler(db *gorm.DB, aiCfg *config.OpenAIConfig) {
 	service := NewRetryService(db, aiCfg)
 	ticker := time.NewTicker(RetryInterval)
 	retryStopChan = make(chan struct{})
+	RegisterScheduler("retry", RetryInterval)
 
 	// Immediately process any stuck reviews from previous service restart
 	go func() {
@@ -58,8 +60,11 @@ func StartRetryScheduler(db *gorm.DB, aiCfg *config.OpenAIConfig) {
 		for {
 			select {
 			case <-ticker.C:
-				service.ProcessStuckReviews() // Clean up stuck reviews first
-				service.ProcessFailedReviews()
+				RunScheduled("retry", func() error {
+					service.ProcessStuckReviews() // Clean up stuck reviews first
+					service.ProcessFailedReviews()
+					return nil
+				})
 			case <-retryStopChan:
 				logger.Infof("[Retry] Scheduler stopped")
 				return
@@ -67,7 +72,7 @@ func StartRetryScheduler(db *gorm.DB, aiCfg *config.OpenAIConfig) {
 		}
 	}()
 
-	logger.Infof("[Retry] Scheduler started, interval: %v, max retries: %d, stuck timeout: %v", RetryInterval, MaxRetryCount, StuckTimeout)
+	logger.Infof("[Retry] Scheduler started, interval: %v, default max retries: %d, default backoff: %v, stuck timeout: %v", RetryInterval, MaxRetryCount, DefaultRetryBackoff, StuckTimeout)
 }
 
 func StopRetryScheduler() {
@@ -97,7 +102,7 @@ func (s *RetryService) ProcessStuckReviews() {
 	}
 
 	logger.Warnf("[Retry] WARNING: Found %d stuck reviews (pending/analyzing > %v), marking as failed", len(stuckReviews), StuckTimeout)
-	LogWarning("Retry", "StuckReviews", "Found stuck reviews to be marked as failed", nil, "", "", map[string]interface{}{
+	LogWarning(context.Background(), "Retry", "StuckReviews", "Found stuck reviews to be marked as failed", nil, "", "", map[string]interface{}{
 		"count":   len(stuckReviews),
 		"timeout": StuckTimeout.String(),
 	})
@@ -119,11 +124,15 @@ func (s *RetryService) ProcessStuckReviews() {
 	}
 }
 
+// ProcessFailedReviews schedules the retries of newly failed reviews per their project's
+// retry policy, then retries the reviews whose retry is due
 func (s *RetryService) ProcessFailedReviews() {
-	var failedReviews []models.ReviewLog
+	statuses := s.retryStatuses()
+	s.scheduleRetries(statuses)
 
-	err := s.db.Where("review_status = ? AND retry_count < ?", "failed", MaxRetryCount).
-		Order("created_at DESC").
+	var failedReviews []models.ReviewLog
+	err := s.db.Where("review_status IN ? AND retry_canceled = ? AND next_retry_at <= ?", statuses, false, time.Now()).
+		Order("next_retry_at ASC").
 		Limit(RetryBatchSize).
 		Find(&failedReviews).Error
 
@@ -139,22 +148,52 @@ func (s *RetryService) ProcessFailedReviews() {
 	logger.Infof("[Retry] Processing %d failed reviews", len(failedReviews))
 
 	for _, review := range failedReviews {
-		s.retryReview(&review)
+		if s.claimRetry(&review, review.RetryCount+1) {
+			s.retryReview(&review)
+		}
 	}
 }
 
+// claimRetry records attempt as the retry count of a review, unless another replica or a
+// manual retry claimed the review since it was loaded. Only the caller whose claim took
+// runs the retry.
+func (s *RetryService) claimRetry(review *models.ReviewLog, attempt int) bool {
+	result := s.db.Model(&models.ReviewLog{}).
+		Where("id = ? AND review_status = ? AND retry_count = ?", review.ID, review.ReviewStatus, review.RetryCount).
+		Updates(map[string]interface{}{"retry_count": attempt, "next_retry_at": nil, "retry_canceled": false})
+	if result.Error != nil || result.RowsAffected != 1 {
+		return false
+	}
+	review.RetryCount, review.NextRetryAt, review.RetryCanceled = attempt, nil, false
+	return true
+}
+
 func (s *RetryService) retryReview(review *models.ReviewLog) {
-	logger.Infof("[Retry] Retrying review ID %d (attempt %d/%d)", review.ID, review.RetryCount+1, MaxRetryCount)
+	ctx := logger.WithRequestID(context.Background(), uuid.New().String())
 
 	var project models.Project
 	if err := s.db.First(&project, review.ProjectID).Error; err != nil {
 		logger.Infof("[Retry] Project not found for review %d: %v", review.ID, err)
 		return
 	}
+	policy := ProjectRetryPolicy(&project)
+	logger.Ctx(ctx).Info().Msgf("[Retry] Retrying review ID %d (attempt %d/%d)", review.ID, review.RetryCount, policy.MaxAttempts)
+
+	// The retry count was raised by claimRetry, a failure of this attempt is scheduled
+	// again from it
+	review.RequestID = logger.RequestID(ctx)
+	StartReviewTiming(review, 0)
+
+	// Commits of a push reviewed one by one go through the queue too, so the notification
+	// of their push is sent again with the new result
+	if review.ReviewStatus == ReviewStatusDiffFetchFailed || review.PushHead != "" {
+		s.requeueWithDiff(ctx, review, &project)
+		return
+	}
 
-	review.RetryCount++
-
-	diff, err := s.fetchCommitDiff(&project, review.CommitHash)
+	fetchStart := time.Now()
+	diff, err := fetchCommitDiff(s.httpClient, &project, review.CommitHash)
+	review.DiffFetchMs = ObserveReviewStage(ReviewStageDiffFetch, time.Since(fetchStart))
 	if err != nil {
 		logger.Infof("[Retry] Failed to re-fetch diff for review %d: %v", review.ID, err)
 		review.ErrorMessage = fmt.Sprintf("Failed to re-fetch diff: %v", err)
@@ -167,63 +206,144 @@ func (s *RetryService) retryReview(review *models.ReviewLog) {
 		review.ReviewStatus = "skipped"
 		review.ReviewResult = "Empty commit - no code changes to review (merge commit)"
 		review.ErrorMessage = ""
+		FinishReviewTiming(review)
 		s.db.Save(review)
 		PublishReviewEvent(review.ID, review.ProjectID, review.CommitHash, "skipped", nil, "Empty commit - merge commit with no direct changes")
 		return
 	}
 
-	result, err := s.aiService.Review(context.Background(), &ReviewRequest{
-		ProjectID: project.ID,
-		Diffs:     diff,
-		Commits:   review.CommitMessage,
+	llmStart := time.Now()
+	result, err := s.aiService.Review(ctx, &ReviewRequest{
+		ProjectID:    project.ID,
+		Diffs:        diff,
+		Commits:      review.CommitMessage,
+		Author:       review.Author,
+		EventType:    review.EventType,
+		Branch:       review.Branch,
+		TargetBranch: review.TargetBranch,
+		ReviewLogID:  review.ID,
+		CommitHash:   review.CommitHash,
 	})
+	review.LLMMs = ObserveReviewStage(ReviewStageLLM, time.Since(llmStart))
 
 	if err != nil {
 		logger.Infof("[Retry] Review %d failed again: %v", review.ID, err)
 		review.ErrorMessage = err.Error()
-		if review.RetryCount >= MaxRetryCount {
+		if review.RetryCount >= policy.MaxAttempts {
 			logger.Infof("[Retry] Review %d exceeded max retries, marking as permanently failed", review.ID)
 		}
 	} else {
 		logger.Infof("[Retry] Review %d succeeded on retry", review.ID)
-		review.ReviewStatus = "completed"
-		review.ReviewResult = result.Content
-		review.Score = &result.Score
+		ApplyReviewResult(review, result)
 		review.ErrorMessage = ""
 
-		s.notificationService.SendReviewNotification(&project, &ReviewNotification{
-			ProjectName:   project.Name,
-			Branch:        review.Branch,
-			Author:        review.Author,
-			CommitMessage: review.CommitMessage,
-			Score:         result.Score,
-			ReviewResult:  result.Content,
-			EventType:     review.EventType,
-			MRURL:         review.MRURL,
-		})
+		// Retroactive reviews of imported history are recorded without notifications
+		if !review.Retroactive && !result.ScoreMissing {
+			s.notificationService.SendReviewNotification(ctx, &project, &ReviewNotification{
+				ProjectName:   project.Name,
+				Branch:        review.Branch,
+				Author:        review.Author,
+				CommitMessage: review.CommitMessage,
+				Score:         result.Score,
+				ReviewResult:  result.Content,
+				EventType:     review.EventType,
+				MRURL:         review.MRURL,
+				ReviewLogID:   review.ID,
+			})
+		}
 	}
 
+	FinishReviewTiming(review)
 	s.db.Save(review)
+	if err := NewReviewFindingService(s.db).Record(review, diff); err != nil {
+		logger.Warnf("[Retry] Failed to record findings of review %d: %v", review.ID, err)
+	}
+}
+
+// requeueWithDiff fetches the diff of a review whose fetch failed, or of a commit of a push
+// reviewed per commit, again and queues the review, so it runs through the regular pipeline
+// with its commit status and notifications. Pushes are reviewed with the diff of their head commit.
+func (s *RetryService) requeueWithDiff(ctx context.Context, review *models.ReviewLog, project *models.Project) {
+	log := logger.For(ctx, "retry").With().Uint("project_id", project.ID).Uint("review_id", review.ID).Str("commit", review.CommitHash).Logger()
+	status := review.ReviewStatus
+
+	var diff string
+	var err error
+	fetchStart := time.Now()
+	if review.MRNumber != nil {
+		diff, err = fetchMergeRequestDiff(s.httpClient, project, *review.MRNumber)
+	} else {
+		diff, err = fetchCommitDiff(s.httpClient, project, review.CommitHash)
+	}
+	if err != nil {
+		log.Warn().Err(err).Int("attempt", review.RetryCount).Msg("Diff fetch failed again")
+		review.ErrorMessage = "Failed to fetch diff: " + err.Error()
+		s.db.Save(review)
+		return
+	}
+
+	review.ReviewStatus = "pending"
+	review.ErrorMessage = ""
+	review.DiffFetchMs = ObserveReviewStage(ReviewStageDiffFetch, time.Since(fetchStart))
+	if err := s.db.Save(review).Error; err != nil {
+		log.Warn().Err(err).Msg("Failed to update review")
+		return
+	}
+	task := &ReviewTask{
+		ReviewLogID:   review.ID,
+		ProjectID:     project.ID,
+		CommitSHA:     review.CommitHash,
+		EventType:     review.EventType,
+		Branch:        review.Branch,
+		TargetBranch:  review.TargetBranch,
+		Author:        review.Author,
+		AuthorEmail:   review.AuthorEmail,
+		AuthorAvatar:  review.AuthorAvatar,
+		CommitMessage: review.CommitMessage,
+		Diff:          diff,
+		CommitURL:     review.CommitURL,
+		MRNumber:      review.MRNumber,
+		MRURL:         review.MRURL,
+		RequestID:     review.RequestID,
+		PushHead:      review.PushHead,
+	}
+	if review.PushHead != "" {
+		task.CommitSHAs = []string{review.CommitHash}
+		// The push is notified again once its commit reviews are all done
+		if err := s.db.Model(&models.ReviewLog{}).Where("project_id = ? AND push_head = ?", project.ID, review.PushHead).
+			Update("push_notified", false).Error; err != nil {
+			log.Warn().Err(err).Msg("Failed to reset the push notification")
+		}
+	}
+	if err := GetTaskQueue().Enqueue(task); err != nil {
+		log.Warn().Err(err).Msg("Failed to queue review after fetching its diff")
+		review.ReviewStatus = status
+		review.ErrorMessage = "Failed to enqueue: " + err.Error()
+		s.db.Save(review)
+		return
+	}
+	log.Info().Int("attempt", review.RetryCount).Msg("Diff fetched, review queued")
 }
 
-func (s *RetryService) fetchCommitDiff(project *models.Project, commitSHA string) (string, error) {
-	if project.URL == "" || project.AccessToken == "" {
+// fetchCommitDiff fetches the diff of a single commit from the project's platform
+func fetchCommitDiff(client *http.Client, project *models.Project, commitSHA string) (string, error) {
+	if project.URL == "" || !HasPlatformCredentials(models.GetDB(), project) {
 		return "", fmt.Errorf("project URL or access token not configured")
 	}
 
 	switch project.Platform {
 	case "gitlab":
-		return s.fetchGitLabCommitDiff(project, commitSHA)
+		return fetchGitLabCommitDiff(client, project, commitSHA)
 	case "github":
-		return s.fetchGitHubCommitDiff(project, commitSHA)
+		return fetchGitHubCommitDiff(client, project, commitSHA)
 	case "bitbucket":
-		return s.fetchBitbucketCommitDiff(project, commitSHA)
+		return fetchBitbucketCommitDiff(client, project, commitSHA)
 	default:
 		return "", fmt.Errorf("unsupported platform: %s", project.Platform)
 	}
 }
 
-func (s *RetryService) fetchGitLabCommitDiff(project *models.Project, commitSHA string) (string, error) {
+func fetchGitLabCommitDiff(client *http.Client, project *models.Project, commitSHA string) (string, error) {
 	urlStr := strings.TrimSuffix(project.URL, ".git")
 	protocolIdx := strings.Index(urlStr, "://")
 	if protocolIdx == -1 {
@@ -239,48 +359,74 @@ func (s *RetryService) fetchGitLabCommitDiff(project *models.Project, commitSHA
 
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s/diff",
 		baseURL, strings.ReplaceAll(projectPath, "/", "%2F"), commitSHA)
+	return fetchGitLabDiffs(client, apiURL, project.AccessToken)
+}
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("PRIVATE-TOKEN", project.AccessToken)
+// gitLabDiffPageSize and gitLabDiffMaxPages bound the pages of file diffs read from GitLab
+const (
+	gitLabDiffPageSize = 100
+	gitLabDiffMaxPages = 100
+)
 
-	resp, err := s.httpClient.Do(req)
-	if err != nil {
-		return "", err
+// fetchGitLabDiffs fetches a list of GitLab file diffs, page by page, and joins them into a
+// unified diff
+func fetchGitLabDiffs(client *http.Client, apiURL, accessToken string) (string, error) {
+	sep := "?"
+	if strings.Contains(apiURL, "?") {
+		sep = "&"
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
+	var result strings.Builder
+	for page := 1; page <= gitLabDiffMaxPages; page++ {
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s%sper_page=%d&page=%d", apiURL, sep, gitLabDiffPageSize, page), nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("PRIVATE-TOKEN", accessToken)
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitLab API returned status %d", resp.StatusCode)
-	}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
 
-	var diffs []struct {
-		Diff    string `json:"diff"`
-		OldPath string `json:"old_path"`
-		NewPath string `json:"new_path"`
-	}
-	if err := json.Unmarshal(body, &diffs); err != nil {
-		return string(body), nil
-	}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("GitLab API returned status %d", resp.StatusCode)
+		}
 
-	var result strings.Builder
-	for _, d := range diffs {
-		result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", d.OldPath, d.NewPath))
-		result.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", d.OldPath, d.NewPath))
-		result.WriteString(d.Diff)
-		if !strings.HasSuffix(d.Diff, "\n") {
-			result.WriteString("\n")
+		var diffs []struct {
+			Diff    string `json:"diff"`
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+		}
+		if err := json.Unmarshal(body, &diffs); err != nil {
+			if page == 1 {
+				return string(body), nil
+			}
+			return "", fmt.Errorf("failed to parse GitLab diffs page %d: %w", page, err)
+		}
+
+		for _, d := range diffs {
+			result.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", d.OldPath, d.NewPath))
+			result.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", d.OldPath, d.NewPath))
+			result.WriteString(d.Diff)
+			if !strings.HasSuffix(d.Diff, "\n") {
+				result.WriteString("\n")
+			}
+		}
+		if len(diffs) < gitLabDiffPageSize {
+			break
 		}
 	}
 
 	return result.String(), nil
 }
 
-func (s *RetryService) fetchGitHubCommitDiff(project *models.Project, commitSHA string) (string, error) {
+func fetchGitHubCommitDiff(client *http.Client, project *models.Project, commitSHA string) (string, error) {
 	urlStr := strings.TrimSuffix(project.URL, ".git")
 	parts := strings.Split(urlStr, "/")
 	if len(parts) < 2 {
@@ -295,7 +441,7 @@ func (s *RetryService) fetchGitHubCommitDiff(project *models.Project, commitSHA
 	req.Header.Set("Accept", "application/vnd.github.v3.diff")
 	req.Header.Set("Authorization", "token "+project.AccessToken)
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -305,40 +451,33 @@ func (s *RetryService) fetchGitHubCommitDiff(project *models.Project, commitSHA
 	return string(body), nil
 }
 
-func (s *RetryService) fetchBitbucketCommitDiff(project *models.Project, commitSHA string) (string, error) {
-	urlStr := strings.TrimSuffix(project.URL, ".git")
-	parts := strings.Split(urlStr, "/")
-	if len(parts) < 2 {
-		return "", fmt.Errorf("invalid project URL: %s", project.URL)
-	}
-	projectPath := parts[len(parts)-2] + "/" + parts[len(parts)-1]
-
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/diff/%s", projectPath, commitSHA)
-
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	req.Header.Set("Authorization", "Bearer "+project.AccessToken)
-
-	resp, err := s.httpClient.Do(req)
+func fetchBitbucketCommitDiff(client *http.Client, project *models.Project, commitSHA string) (string, error) {
+	api, err := NewBitbucketAPI(models.GetDB(), client, project)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
+	return api.GetText(context.Background(), api.CommitDiffURL(commitSHA))
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	return string(body), nil
+// isRetryableStatus reports whether a review in the status can be retried by hand
+func isRetryableStatus(status string) bool {
+	return status == "failed" || status == ReviewStatusNeedsAttention || status == ReviewStatusDiffFetchFailed
 }
 
+// ManualRetry retries a review by hand, restarting its automatic retries from the first
 func (s *RetryService) ManualRetry(reviewID uint) error {
 	var review models.ReviewLog
 	if err := s.db.First(&review, reviewID).Error; err != nil {
 		return err
 	}
 
-	if review.ReviewStatus != "failed" {
+	if !isRetryableStatus(review.ReviewStatus) {
 		return nil
 	}
 
-	review.RetryCount = 0
+	if !s.claimRetry(&review, 1) {
+		return ErrRetryInProgress
+	}
 	s.retryReview(&review)
 	return nil
 }
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+const (
+	DefaultRetryBackoff    = 5 * time.Minute // Delay before the first retry of projects without a backoff
+	MaxRetryBackoff        = 24 * time.Hour
+	retryScheduleBatchSize = 100
+)
+
+// SQL fragments of the retry queries, which apply the retry policy of each review's project
+const (
+	retryProjectJoin        = "JOIN projects ON projects.id = review_logs.project_id"
+	retryMaxAttemptsColumn  = "COALESCE(projects.retry_max_attempts, ?)"
+	retryCandidateCondition = "review_logs.review_status IN ? AND review_logs.retry_canceled = ?"
+)
+
+// Retry states listed by ListRetries
+const (
+	RetryStateScheduled = "scheduled" // Waiting for its next automatic retry
+	RetryStateExhausted = "exhausted" // Used all its automatic retries
+	RetryStateCanceled  = "canceled"  // Automatic retries stopped by an admin
+)
+
+// RetryPolicy is how often and how soon failed reviews of a project are retried
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration // Delay before the first retry, doubled for each next one
+}
+
+// ProjectRetryPolicy returns the project's retry policy, with the defaults for unset values
+func ProjectRetryPolicy(project *models.Project) RetryPolicy {
+	policy := RetryPolicy{MaxAttempts: MaxRetryCount, Backoff: DefaultRetryBackoff}
+	if project.RetryMaxAttempts != nil {
+		policy.MaxAttempts = *project.RetryMaxAttempts
+	}
+	if project.RetryBackoff > 0 {
+		policy.Backoff = time.Duration(project.RetryBackoff) * time.Minute
+	}
+	return policy
+}
+
+// Delay returns the wait after a failure when attempts retries were already made
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	delay := p.Backoff
+	for i := 0; i < attempts && delay < MaxRetryBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryBackoff)
+}
+
+// NextAttempt returns when a failed review is retried next, nil when it will not be
+func (p RetryPolicy) NextAttempt(review *models.ReviewLog) *time.Time {
+	if review.RetryCanceled || review.RetryCount >= p.MaxAttempts {
+		return nil
+	}
+	if review.NextRetryAt != nil {
+		return review.NextRetryAt
+	}
+	next := review.UpdatedAt.Add(p.Delay(review.RetryCount))
+	return &next
+}
+
+// retryStatuses are the review statuses retried automatically
+func (s *RetryService) retryStatuses() []string {
+	statuses := []string{"failed"}
+	if NewSystemConfigService(s.db).GetDiffFetchConfig().Retry {
+		statuses = append(statuses, ReviewStatusDiffFetchFailed)
+	}
+	return statuses
+}
+
+// scheduleRetries sets the next retry of failed reviews not scheduled yet, from their last
+// update and their project's backoff
+func (s *RetryService) scheduleRetries(statuses []string) {
+	var rows []struct {
+		ID               uint
+		RetryCount       int
+		UpdatedAt        time.Time
+		RetryMaxAttempts *int
+		RetryBackoff     int
+	}
+	if err := s.db.Model(&models.ReviewLog{}).
+		Select("review_logs.id, review_logs.retry_count, review_logs.updated_at, projects.retry_max_attempts, projects.retry_backoff").
+		Joins(retryProjectJoin).
+		Where(retryCandidateCondition+" AND review_logs.next_retry_at IS NULL", statuses, false).
+		Where("review_logs.retry_count < "+retryMaxAttemptsColumn, MaxRetryCount).
+		Limit(retryScheduleBatchSize).
+		Scan(&rows).Error; err != nil {
+		logger.Warnf("[Retry] Failed to fetch reviews to schedule: %v", err)
+		return
+	}
+	for _, row := range rows {
+		policy := ProjectRetryPolicy(&models.Project{RetryMaxAttempts: row.RetryMaxAttempts, RetryBackoff: row.RetryBackoff})
+		next := row.UpdatedAt.Add(policy.Delay(row.RetryCount))
+		s.db.Model(&models.ReviewLog{}).Where("id = ?", row.ID).UpdateColumn("next_retry_at", next)
+	}
+}
+
+// RetryListRequest filters the failed reviews listed by ListRetries
+type RetryListRequest struct {
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
+	ProjectID uint   `form:"project_id"`
+	State     string `form:"state" binding:"omitempty,oneof=scheduled exhausted canceled"` // Default scheduled
+}
+
+// RetryItem is a failed review with its automatic retry schedule
+type RetryItem struct {
+	ReviewLogID   uint       `json:"review_log_id"`
+	ProjectID     uint       `json:"project_id"`
+	ProjectName   string     `json:"project_name"`
+	CommitHash    string     `json:"commit_hash"`
+	Branch        string     `json:"branch"`
+	ReviewStatus  string     `json:"review_status"`
+	ErrorMessage  string     `json:"error_message"`
+	Attempts      int        `json:"attempts"` // Retries made so far
+	MaxAttempts   int        `json:"max_attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	State         string     `json:"state"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type RetryListResponse struct {
+	Total    int64       `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Items    []RetryItem `json:"items"`
+}
+
+// ListRetries returns the failed reviews in a retry state, the next retries first
+func (s *RetryService) ListRetries(req *RetryListRequest, tenantID uint) (*RetryListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+	if req.State == "" {
+		req.State = RetryStateScheduled
+	}
+
+	query := ScopeReviewLogsByTenant(s.db.Model(&models.ReviewLog{}).Joins(retryProjectJoin), tenantID).
+		Where("review_logs.review_status IN ?", s.retryStatuses())
+	if req.ProjectID > 0 {
+		query = query.Where("review_logs.project_id = ?", req.ProjectID)
+	}
+	switch req.State {
+	case RetryStateCanceled:
+		query = query.Where("review_logs.retry_canceled = ?", true)
+	case RetryStateExhausted:
+		query = query.Where("review_logs.retry_canceled = ? AND review_logs.retry_count >= "+retryMaxAttemptsColumn, false, MaxRetryCount)
+	default:
+		query = query.Where("review_logs.retry_canceled = ? AND review_logs.retry_count < "+retryMaxAttemptsColumn, false, MaxRetryCount)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		models.ReviewLog
+		ProjectName      string
+		RetryMaxAttempts *int
+		RetryBackoff     int
+	}
+	if err := query.Select("review_logs.id, review_logs.project_id, review_logs.commit_hash, review_logs.branch, review_logs.review_status, review_logs.error_message, " +
+		"review_logs.retry_count, review_logs.next_retry_at, review_logs.retry_canceled, review_logs.updated_at, " +
+		"projects.name AS project_name, projects.retry_max_attempts, projects.retry_backoff").
+		Order("review_logs.next_retry_at IS NULL, review_logs.next_retry_at ASC, review_logs.updated_at DESC").
+		Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]RetryItem, 0, len(rows))
+	for _, row := range rows {
+		policy := ProjectRetryPolicy(&models.Project{RetryMaxAttempts: row.RetryMaxAttempts, RetryBackoff: row.RetryBackoff})
+		items = append(items, RetryItem{
+			ReviewLogID:   row.ID,
+			ProjectID:     row.ProjectID,
+			ProjectName:   row.ProjectName,
+			CommitHash:    row.CommitHash,
+			Branch:        row.Branch,
+			ReviewStatus:  row.ReviewStatus,
+			ErrorMessage:  row.ErrorMessage,
+			Attempts:      row.RetryCount,
+			MaxAttempts:   policy.MaxAttempts,
+			NextAttemptAt: policy.NextAttempt(&row.ReviewLog),
+			State:         req.State,
+			UpdatedAt:     row.UpdatedAt,
+		})
+	}
+	return &RetryListResponse{Total: total, Page: req.Page, PageSize: req.PageSize, Items: items}, nil
+}
+
+// ErrNotRetryable is returned for reviews that did not fail
+var ErrNotRetryable = errors.New("the review did not fail")
+
+// ErrRetryInProgress is returned when a review is retried by another request or the scheduler
+var ErrRetryInProgress = errors.New("the review is already being retried")
+
+// failedReview loads a review that failed, or errors with ErrNotRetryable
+func (s *RetryService) failedReview(reviewID uint) (*models.ReviewLog, error) {
+	var review models.ReviewLog
+	if err := s.db.First(&review, reviewID).Error; err != nil {
+		return nil, err
+	}
+	if review.ReviewStatus != "failed" && review.ReviewStatus != ReviewStatusDiffFetchFailed {
+		return nil, ErrNotRetryable
+	}
+	return &review, nil
+}
+
+// RetryNow runs the next retry of a failed review immediately, even when its automatic
+// retries were used or canceled. The attempt counts toward the retries.
+func (s *RetryService) RetryNow(reviewID uint) (*models.ReviewLog, error) {
+	review, err := s.failedReview(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if !s.claimRetry(review, review.RetryCount+1) {
+		return nil, ErrRetryInProgress
+	}
+	s.retryReview(review)
+	return review, nil
+}
+
+// CancelRetries stops the automatic retries of a failed review
+func (s *RetryService) CancelRetries(reviewID uint) (*models.ReviewLog, error) {
+	review, err := s.failedReview(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(review).UpdateColumns(map[string]interface{}{"retry_canceled": true, "next_retry_at": nil}).Error; err != nil {
+		return nil, err
+	}
+	review.RetryCanceled, review.NextRetryAt = true, nil
+	return review, nil
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestProjectRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		project     models.Project
+		wantMax     int
+		wantBackoff time.Duration
+	}{
+		{name: "defaults", project: models.Project{}, wantMax: MaxRetryCount, wantBackoff: DefaultRetryBackoff},
+		{name: "project overrides", project: models.Project{RetryMaxAttempts: intPtr(6), RetryBackoff: 15}, wantMax: 6, wantBackoff: 15 * time.Minute},
+		{name: "retries disabled", project: models.Project{RetryMaxAttempts: intPtr(0)}, wantMax: 0, wantBackoff: DefaultRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := ProjectRetryPolicy(&tt.project)
+			if policy.MaxAttempts != tt.wantMax || policy.Backoff != tt.wantBackoff {
+				t.Errorf("ProjectRetryPolicy() = %+v, want max %d backoff %v", policy, tt.wantMax, tt.wantBackoff)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 20, Backoff: 5 * time.Minute}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 5 * time.Minute},
+		{attempts: 1, want: 10 * time.Minute},
+		{attempts: 3, want: 40 * time.Minute},
+		{attempts: 12, want: MaxRetryBackoff},
+		{attempts: 60, want: MaxRetryBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Delay(tt.attempts); got != tt.want {
+			t.Errorf("Delay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyNextAttempt(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	scheduled := updated.Add(time.Hour)
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: 5 * time.Minute}
+
+	tests := []struct {
+		name   string
+		review models.ReviewLog
+		want   *time.Time
+	}{
+		{name: "not scheduled yet", review: models.ReviewLog{RetryCount: 1, UpdatedAt: updated}, want: ptrTime(updated.Add(10 * time.Minute))},
+		{name: "scheduled", review: models.ReviewLog{RetryCount: 1, UpdatedAt: updated, NextRetryAt: &scheduled}, want: &scheduled},
+		{name: "exhausted", review: models.ReviewLog{RetryCount: 3, UpdatedAt: updated, NextRetryAt: &scheduled}},
+		{name: "canceled", review: models.ReviewLog{RetryCanceled: true, UpdatedAt: updated}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.NextAttempt(&tt.review)
+			if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) {
+				t.Errorf("NextAttempt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// seedFailedReview creates a failed review of a project last updated at updatedAt
+func seedFailedReview(t *testing.T, f *tenantFixture, tenantID uint, hash string, retryCount int, canceled bool, updatedAt time.Time) *models.ReviewLog {
+	t.Helper()
+	review := &models.ReviewLog{ProjectID: f.projects[tenantID].ID, EventType: "push", Branch: "main", CommitHash: hash, ReviewStatus: "failed"}
+	mustCreate(t, f.db, review)
+	if err := f.db.Model(review).UpdateColumns(map[string]interface{}{"retry_count": retryCount, "retry_canceled": canceled, "updated_at": updatedAt}).Error; err != nil {
+		t.Fatalf("update review: %v", err)
+	}
+	review.RetryCount, review.RetryCanceled, review.UpdatedAt = retryCount, canceled, updatedAt
+	return review
+}
+
+func TestRetryService_ScheduleRetries(t *testing.T) {
+	f := seedTenants(t)
+	service := &RetryService{db: f.db}
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	pending := seedFailedReview(t, f, 1, "pending", 1, false, updated)
+	exhausted := seedFailedReview(t, f, 1, "exhausted", MaxRetryCount, false, updated)
+	canceled := seedFailedReview(t, f, 1, "canceled", 0, true, updated)
+
+	service.scheduleRetries([]string{"failed"})
+
+	var got models.ReviewLog
+	f.db.First(&got, pending.ID)
+	if want := updated.Add(2 * DefaultRetryBackoff); got.NextRetryAt == nil || !got.NextRetryAt.Equal(want) {
+		t.Errorf("next retry of a pending review = %v, want %v", got.NextRetryAt, want)
+	}
+	for _, review := range []*models.ReviewLog{exhausted, canceled} {
+		got = models.ReviewLog{}
+		f.db.First(&got, review.ID)
+		if got.NextRetryAt != nil {
+			t.Errorf("next retry of %s = %v, want none", review.CommitHash, got.NextRetryAt)
+		}
+	}
+}
+
+func TestRetryService_ListRetries(t *testing.T) {
+	f := seedTenants(t)
+	service := &RetryService{db: f.db}
+	now := time.Now()
+	seedFailedReview(t, f, 1, "scheduled", 1, false, now)
+	seedFailedReview(t, f, 1, "exhausted", MaxRetryCount, false, now)
+	seedFailedReview(t, f, 1, "canceled", 0, true, now)
+	seedFailedReview(t, f, 2, "other-tenant", 0, false, now)
+
+	tests := []struct {
+		state    string
+		tenantID uint
+		want     []string
+	}{
+		{state: RetryStateScheduled, tenantID: 1, want: []string{"scheduled"}},
+		{state: RetryStateExhausted, tenantID: 1, want: []string{"exhausted"}},
+		{state: RetryStateCanceled, tenantID: 1, want: []string{"canceled"}},
+		{state: RetryStateScheduled, tenantID: 2, want: []string{"other-tenant"}},
+		{state: RetryStateScheduled, tenantID: 0, want: []string{"scheduled", "other-tenant"}},
+	}
+	for _, tt := range tests {
+		resp, err := service.ListRetries(&RetryListRequest{State: tt.state}, tt.tenantID)
+		if err != nil {
+			t.Fatalf("ListRetries(%s, tenant %d): %v", tt.state, tt.tenantID, err)
+		}
+		hashes := map[string]bool{}
+		for _, item := range resp.Items {
+			hashes[item.CommitHash] = true
+			if item.State != tt.state {
+				t.Errorf("item %s state = %q, want %q", item.CommitHash, item.State, tt.state)
+			}
+		}
+		if resp.Total != int64(len(tt.want)) || len(hashes) != len(tt.want) {
+			t.Errorf("ListRetries(%s, tenant %d) = %v, want %v", tt.state, tt.tenantID, hashes, tt.want)
+		}
+		for _, hash := range tt.want {
+			if !hashes[hash] {
+				t.Errorf("ListRetries(%s, tenant %d) misses %s", tt.state, tt.tenantID, hash)
+			}
+		}
+	}
+}
+
+func TestRetryService_ClaimRetry(t *testing.T) {
+	f := seedTenants(t)
+	service := &RetryService{db: f.db}
+	review := seedFailedReview(t, f, 1, "claimed", 1, true, time.Now())
+	stale := *review
+
+	if !service.claimRetry(review, 2) {
+		t.Fatal("first claim failed")
+	}
+	if review.RetryCount != 2 || review.RetryCanceled || review.NextRetryAt != nil {
+		t.Errorf("claimed review = %+v, want retry count 2 and no schedule", review)
+	}
+	if service.claimRetry(&stale, 2) {
+		t.Error("claim of an already claimed review succeeded, want it rejected")
+	}
+
+	var got models.ReviewLog
+	f.db.First(&got, review.ID)
+	if got.RetryCount != 2 {
+		t.Errorf("stored retry count = %d, want 2", got.RetryCount)
+	}
+}
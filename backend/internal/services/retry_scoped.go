@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// ErrScopedRetryNoChanges is returned when no changed file of a review lies under the retry's paths
+var ErrScopedRetryNoChanges = errors.New("no changed files under the given paths")
+
+// ScopedRetryRequest re-runs a review focused on some paths or with another prompt
+type ScopedRetryRequest struct {
+	Paths  []string `json:"paths"`  // Directories, files or globs such as "src/*.go"; empty = the whole diff
+	Prompt string   `json:"prompt"` // Replaces the project's review prompt; without {{diffs}} the diff is appended
+}
+
+// IsEmpty reports whether the request neither scopes the review nor overrides the prompt
+func (r *ScopedRetryRequest) IsEmpty() bool {
+	return len(r.Paths) == 0 && strings.TrimSpace(r.Prompt) == ""
+}
+
+// scopedRetryPrompt makes sure a prompt override includes the diff under review
+func scopedRetryPrompt(prompt string) string {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" || strings.Contains(prompt, "{{diffs}}") {
+		return prompt
+	}
+	return prompt + "\n\nCode changes:\n{{diffs}}"
+}
+
+// ScopedRetry records a new revision of a review, limited to req.Paths and reviewed with
+// req.Prompt, and runs it in the background. The original review is kept unchanged;
+// revisions of a revision point to the original as well. Revisions send no notifications.
+func (s *RetryService) ScopedRetry(reviewID uint, req *ScopedRetryRequest) (*models.ReviewLog, error) {
+	var original models.ReviewLog
+	if err := s.db.First(&original, reviewID).Error; err != nil {
+		return nil, err
+	}
+	var project models.Project
+	if err := s.db.First(&project, original.ProjectID).Error; err != nil {
+		return nil, err
+	}
+
+	var diff string
+	var err error
+	if original.EventType == "merge_request" && original.MRNumber != nil {
+		diff, err = fetchMergeRequestDiff(s.httpClient, &project, *original.MRNumber)
+	} else {
+		diff, err = fetchCommitDiff(s.httpClient, &project, original.CommitHash)
+	}
+	if err != nil {
+		return nil, err
+	}
+	diff = ScopeDiffToPaths(diff, req.Paths)
+	if strings.TrimSpace(diff) == "" {
+		return nil, ErrScopedRetryNoChanges
+	}
+
+	revisionOf := original.ID
+	if original.RevisionOf != nil {
+		revisionOf = *original.RevisionOf
+	}
+	revision := &models.ReviewLog{
+		ProjectID:      original.ProjectID,
+		EventType:      original.EventType,
+		CommitHash:     original.CommitHash,
+		CommitURL:      original.CommitURL,
+		Branch:         original.Branch,
+		TargetBranch:   original.TargetBranch,
+		Author:         original.Author,
+		AuthorEmail:    original.AuthorEmail,
+		AuthorAvatar:   original.AuthorAvatar,
+		AuthorURL:      original.AuthorURL,
+		CommitMessage:  original.CommitMessage,
+		MRNumber:       original.MRNumber,
+		MRURL:          original.MRURL,
+		ReviewStatus:   "analyzing",
+		Retroactive:    original.Retroactive,
+		RevisionOf:     &revisionOf,
+		ReviewScope:    strings.Join(req.Paths, ","),
+		PromptOverride: strings.TrimSpace(req.Prompt),
+	}
+	for _, file := range ParseDiffToFiles(diff) {
+		revision.FilesChanged++
+		revision.Additions += file.Additions
+		revision.Deletions += file.Deletions
+	}
+	if err := s.db.Create(revision).Error; err != nil {
+		return nil, err
+	}
+
+	logger.Infof("[Retry] Review %d re-run as revision %d, scope=%q, prompt override=%v",
+		original.ID, revision.ID, revision.ReviewScope, revision.PromptOverride != "")
+	go s.reviewRevision(revision, diff)
+	return revision, nil
+}
+
+// reviewRevision runs the AI review of a scoped retry and records its result
+func (s *RetryService) reviewRevision(revision *models.ReviewLog, diff string) {
+	PublishReviewEvent(revision.ID, revision.ProjectID, revision.CommitHash, "analyzing", nil, "")
+
+	result, err := s.aiService.Review(context.Background(), &ReviewRequest{
+		ProjectID:    revision.ProjectID,
+		Diffs:        diff,
+		Commits:      revision.CommitMessage,
+		CustomPrompt: scopedRetryPrompt(revision.PromptOverride),
+		Author:       revision.Author,
+		EventType:    revision.EventType,
+		Branch:       revision.Branch,
+		TargetBranch: revision.TargetBranch,
+		ReviewLogID:  revision.ID,
+	})
+	if err != nil {
+		logger.Warnf("[Retry] Revision %d failed: %v", revision.ID, err)
+		revision.ReviewStatus = "failed"
+		revision.ErrorMessage = err.Error()
+		s.db.Save(revision)
+		PublishReviewEvent(revision.ID, revision.ProjectID, revision.CommitHash, "failed", nil, revision.ErrorMessage)
+		return
+	}
+
+	ApplyReviewResult(revision, result)
+	s.db.Save(revision)
+	PublishReviewEvent(revision.ID, revision.ProjectID, revision.CommitHash, revision.ReviewStatus, revision.Score, "")
+	if err := NewReviewFindingService(s.db).Record(revision, diff); err != nil {
+		logger.Warnf("[Retry] Failed to record findings of revision %d: %v", revision.ID, err)
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import "testing"
+
+func TestScopedRetryPrompt(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		want   string
+	}{
+		{"empty", "  ", ""},
+		{"with placeholder", "Check errors:\n{{diffs}}", "Check errors:\n{{diffs}}"},
+		{"without placeholder", " Focus on error handling ", "Focus on error handling\n\nCode changes:\n{{diffs}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopedRetryPrompt(tt.prompt); got != tt.want {
+				t.Errorf("scopedRetryPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopedRetryRequestIsEmpty(t *testing.T) {
+	tests := []struct {
+		req  ScopedRetryRequest
+		want bool
+	}{
+		{ScopedRetryRequest{}, true},
+		{ScopedRetryRequest{Prompt: "  "}, true},
+		{ScopedRetryRequest{Paths: []string{"src"}}, false},
+		{ScopedRetryRequest{Prompt: "Focus on SQL"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.req.IsEmpty(); got != tt.want {
+			t.Errorf("IsEmpty(%+v) = %v, want %v", tt.req, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFetchGitLabDiffs_Paginates(t *testing.T) {
+	const files = 250
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		var diffs []map[string]string
+		for i := (page - 1) * perPage; i < files && i < page*perPage; i++ {
+			path := fmt.Sprintf("file%d.go", i)
+			diffs = append(diffs, map[string]string{"old_path": path, "new_path": path, "diff": "@@ -1 +1 @@\n-a\n+b\n"})
+		}
+		json.NewEncoder(w).Encode(diffs)
+	}))
+	defer srv.Close()
+
+	diff, err := fetchGitLabDiffs(srv.Client(), srv.URL+"/api/v4/projects/g%2Fp/repository/commits/abc/diff", "token")
+	if err != nil {
+		t.Fatalf("fetchGitLabDiffs() error = %v", err)
+	}
+	if got := strings.Count(diff, "diff --git "); got != files {
+		t.Errorf("diff has %d files, want %d", got, files)
+	}
+	if !strings.Contains(diff, "a/file249.go") {
+		t.Error("diff is missing the last page")
+	}
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// ReviewArchiveBatchSize is the number of review logs archived per transaction
+const ReviewArchiveBatchSize = 500
+
+// ReviewArchiveService moves review text and diffs of old review logs into review_log_archives,
+// keeping review_logs small for list queries while preserving scores and statistics.
+type ReviewArchiveService struct {
+	db            *gorm.DB
+	configService *SystemConfigService
+}
+
+func NewReviewArchiveService(db *gorm.DB) *ReviewArchiveService {
+	return &ReviewArchiveService{
+		db:            db,
+		configService: NewSystemConfigService(db),
+	}
+}
+
+// ArchiveOlderThan archives completed review logs created more than the given number of days ago
+func (s *ReviewArchiveService) ArchiveOlderThan(days int) (int64, error) {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	var total int64
+
+	for {
+		var logs []models.ReviewLog
+		err := s.db.Select("id", "review_result", "diff_content").
+			Where("created_at < ? AND archived = ? AND review_status IN ?", cutoff, false, []string{"completed", "skipped", ReviewStatusSkippedTooLarge, ReviewStatusSkippedByDirective, ReviewStatusNeedsAttention, "manual"}).
+			Order("id ASC").Limit(ReviewArchiveBatchSize).Find(&logs).Error
+		if err != nil {
+			return total, err
+		}
+		if len(logs) == 0 {
+			return total, nil
+		}
+
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			ids := make([]uint, 0, len(logs))
+			archives := make([]models.ReviewLogArchive, 0, len(logs))
+			for _, l := range logs {
+				ids = append(ids, l.ID)
+				archives = append(archives, models.ReviewLogArchive{
+					ReviewLogID:  l.ID,
+					ReviewResult: l.ReviewResult,
+					DiffContent:  l.DiffContent,
+					ArchivedAt:   now,
+				})
+			}
+			if err := tx.CreateInBatches(&archives, 100).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.ReviewLog{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+				"review_result": "",
+				"diff_content":  "",
+				"archived":      true,
+			}).Error
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int64(len(logs))
+
+		if len(logs) < ReviewArchiveBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *ReviewArchiveService) runArchive() {
+	cfg := s.configService.GetReviewArchiveConfig()
+	if !cfg.Enabled || cfg.ArchiveAfterDays <= 0 {
+		return
+	}
+
+	archived, err := s.ArchiveOlderThan(cfg.ArchiveAfterDays)
+	if err != nil {
+		logger.Errorf("[ReviewArchive] Failed to archive review logs: %v", err)
+		return
+	}
+	if archived > 0 {
+		logger.Infof("[ReviewArchive] Archived %d review logs older than %d days", archived, cfg.ArchiveAfterDays)
+	}
+}
+
+var reviewArchiveStopChan chan struct{}
+
+// StartReviewArchiveScheduler starts a goroutine that archives old review logs daily
+func StartReviewArchiveScheduler(db *gorm.DB) {
+	reviewArchiveStopChan = make(chan struct{})
+	go func() {
+		service := NewReviewArchiveService(db)
+		RegisterScheduler("review_archive", 24*time.Hour)
+		RunScheduled("review_archive", func() error { service.runArchive(); return nil })
+
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("review_archive", func() error { service.runArchive(); return nil })
+			case <-reviewArchiveStopChan:
+				logger.Infof("[ReviewArchive] Scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopReviewArchiveScheduler stops the review archive scheduler
+func StopReviewArchiveScheduler() {
+	if reviewArchiveStopChan != nil {
+		close(reviewArchiveStopChan)
+	}
+}
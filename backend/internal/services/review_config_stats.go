@@ -0,0 +1,330 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Prompt references recorded on reviews, see ReviewLog.PromptRef. Templates are referenced as
+// <kind>:<id>, e.g. prompt_template:3.
+const (
+	PromptRefReviewTemplate = "review_template" // Review template of a target branch policy or binding
+	PromptRefPromptTemplate = "prompt_template" // Linked or system default prompt template
+	PromptRefProject        = "project"         // Project custom prompt
+	PromptRefIaC            = "iac"             // Built-in infrastructure as code prompt
+	PromptRefCustom         = "custom"          // Prompt of the request, e.g. a scoped retry
+	PromptRefDefault        = "default"         // Built-in default prompt
+	PromptRefCached         = "cached"          // Result reused from the review cache, no prompt sent
+)
+
+// PromptRef references a template as <kind>:<id>
+func PromptRef(kind string, id uint) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+// parsePromptRef splits a template reference, id is 0 for other references
+func parsePromptRef(ref string) (kind string, id uint) {
+	kind, rawID, ok := strings.Cut(ref, ":")
+	if !ok {
+		return ref, 0
+	}
+	n, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		return ref, 0
+	}
+	return kind, uint(n)
+}
+
+// ReviewConfigStatsRequest selects the reviews of a per prompt or per LLM config breakdown
+type ReviewConfigStatsRequest struct {
+	StartDate string `form:"start_date"`
+	EndDate   string `form:"end_date"`
+	ProjectID uint   `form:"project_id"`
+	Interval  string `form:"interval" binding:"omitempty,oneof=day week month"` // Splits the range into periods, none by default
+}
+
+// ReviewConfigStats is the review outcome, latency and token cost of a prompt or LLM config
+// over a period
+type ReviewConfigStats struct {
+	Key              string  `json:"key"` // Prompt reference or LLM config ID, "unknown" for reviews made before they were recorded
+	Name             string  `json:"name"`
+	Period           string  `json:"period,omitempty"` // First day of the period, empty without interval
+	Reviews          int64   `json:"reviews"`
+	Scored           int64   `json:"scored"`
+	NeedsAttention   int64   `json:"needs_attention"` // Reviews without a parseable score
+	FailureRate      float64 `json:"failure_rate"`    // Percentage of reviews without a parseable score
+	AvgScore         float64 `json:"avg_score"`
+	LLMCalls         int64   `json:"llm_calls"`
+	FailedCalls      int64   `json:"failed_calls"`
+	CallFailureRate  float64 `json:"call_failure_rate"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+	TokensPerReview  float64 `json:"tokens_per_review"`
+}
+
+// configReviewRow is a day of reviews of a prompt or LLM config
+type configReviewRow struct {
+	GroupKey       string
+	Day            string
+	Reviews        int64
+	Scored         int64
+	NeedsAttention int64
+	ScoreSum       float64
+}
+
+// configUsageRow is a day of LLM calls of a prompt or LLM config
+type configUsageRow struct {
+	GroupKey         string
+	Day              string
+	Calls            int64
+	FailedCalls      int64
+	LatencySum       float64
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+}
+
+// GetStatsByPrompt breaks reviews down by the prompt they were made with. LLM calls count
+// for the prompt of their review, including the calls of failed LLMs before a fallback.
+func (s *AIUsageService) GetStatsByPrompt(req *ReviewConfigStatsRequest) ([]ReviewConfigStats, error) {
+	var reviews []configReviewRow
+	if err := s.scopeConfigReviews(req).Select(configReviewColumns("COALESCE(review_logs.prompt_ref, '')")).
+		Group("review_logs.prompt_ref, DATE(review_logs.created_at)").Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	var usage []configUsageRow
+	if err := s.scopeConfigUsage(req).Joins("JOIN review_logs ON review_logs.id = ai_usage_logs.review_log_id").
+		Select(configUsageColumns("COALESCE(review_logs.prompt_ref, '')")).
+		Group("review_logs.prompt_ref, DATE(ai_usage_logs.created_at)").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	stats := mergeConfigStats(reviews, usage, req.Interval)
+	s.namePrompts(stats)
+	return stats, nil
+}
+
+// GetStatsByLLMConfig breaks reviews down by the LLM config that produced them. LLM calls
+// count for the config they were sent to, so failed calls before a fallback count against
+// the failing config.
+func (s *AIUsageService) GetStatsByLLMConfig(req *ReviewConfigStatsRequest) ([]ReviewConfigStats, error) {
+	var reviews []configReviewRow
+	if err := s.scopeConfigReviews(req).Select(configReviewColumns("COALESCE(review_logs.llm_config_id, 0)")).
+		Group("review_logs.llm_config_id, DATE(review_logs.created_at)").Scan(&reviews).Error; err != nil {
+		return nil, err
+	}
+	var usage []configUsageRow
+	if err := s.scopeConfigUsage(req).Select(configUsageColumns("ai_usage_logs.llm_config_id")).
+		Group("ai_usage_logs.llm_config_id, DATE(ai_usage_logs.created_at)").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+
+	stats := mergeConfigStats(reviews, usage, req.Interval)
+	s.nameLLMConfigs(stats)
+	return stats, nil
+}
+
+// scopeConfigReviews selects the reviews that reached the LLM or the review cache
+func (s *AIUsageService) scopeConfigReviews(req *ReviewConfigStatsRequest) *gorm.DB {
+	query := s.db.Model(&models.ReviewLog{}).
+		Where("review_logs.review_status IN ?", []string{"completed", ReviewStatusNeedsAttention})
+	if req.StartDate != "" {
+		query = query.Where("review_logs.created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != "" {
+		query = query.Where("review_logs.created_at <= ?", req.EndDate+" 23:59:59")
+	}
+	if req.ProjectID > 0 {
+		query = query.Where("review_logs.project_id = ?", req.ProjectID)
+	}
+	return query
+}
+
+func (s *AIUsageService) scopeConfigUsage(req *ReviewConfigStatsRequest) *gorm.DB {
+	query := s.db.Model(&models.AIUsageLog{})
+	if req.StartDate != "" {
+		query = query.Where("ai_usage_logs.created_at >= ?", req.StartDate)
+	}
+	if req.EndDate != "" {
+		query = query.Where("ai_usage_logs.created_at <= ?", req.EndDate+" 23:59:59")
+	}
+	if req.ProjectID > 0 {
+		query = query.Where("ai_usage_logs.project_id = ?", req.ProjectID)
+	}
+	return query
+}
+
+func configReviewColumns(key string) string {
+	return key + " as group_key, DATE(review_logs.created_at) as day, COUNT(*) as reviews, " +
+		"COUNT(review_logs.score) as scored, " +
+		"COALESCE(SUM(CASE WHEN review_logs.review_status = '" + ReviewStatusNeedsAttention + "' THEN 1 ELSE 0 END), 0) as needs_attention, " +
+		"COALESCE(SUM(review_logs.score), 0) as score_sum"
+}
+
+func configUsageColumns(key string) string {
+	return key + " as group_key, DATE(ai_usage_logs.created_at) as day, COUNT(*) as calls, " +
+		"COALESCE(SUM(CASE WHEN ai_usage_logs.success = 0 THEN 1 ELSE 0 END), 0) as failed_calls, " +
+		"COALESCE(SUM(ai_usage_logs.latency_ms), 0) as latency_sum, " +
+		"COALESCE(SUM(ai_usage_logs.prompt_tokens), 0) as prompt_tokens, " +
+		"COALESCE(SUM(ai_usage_logs.completion_tokens), 0) as completion_tokens, " +
+		"COALESCE(SUM(ai_usage_logs.total_tokens), 0) as total_tokens"
+}
+
+// statsPeriod returns the first day of the day, week (Monday) or month a date falls in, and
+// "" without interval. Databases return DATE() as 2006-01-02 or as a timestamp.
+func statsPeriod(day, interval string) string {
+	if interval == "" || len(day) < 10 {
+		return ""
+	}
+	t, err := time.Parse("2006-01-02", day[:10])
+	if err != nil {
+		return ""
+	}
+	switch interval {
+	case "week":
+		t = t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	case "month":
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Format("2006-01-02")
+}
+
+// mergeConfigStats sums the daily review and usage rows of each key into its periods, sorted
+// by period then by review count
+func mergeConfigStats(reviews []configReviewRow, usage []configUsageRow, interval string) []ReviewConfigStats {
+	type bucket struct {
+		stats      ReviewConfigStats
+		scoreSum   float64
+		latencySum float64
+	}
+	buckets := make(map[[2]string]*bucket)
+	get := func(key, day string) *bucket {
+		if key == "" || key == "0" { // No prompt reference or LLM config recorded
+			key = "unknown"
+		}
+		id := [2]string{key, statsPeriod(day, interval)}
+		b, ok := buckets[id]
+		if !ok {
+			b = &bucket{stats: ReviewConfigStats{Key: id[0], Period: id[1]}}
+			buckets[id] = b
+		}
+		return b
+	}
+
+	for _, r := range reviews {
+		b := get(r.GroupKey, r.Day)
+		b.stats.Reviews += r.Reviews
+		b.stats.Scored += r.Scored
+		b.stats.NeedsAttention += r.NeedsAttention
+		b.scoreSum += r.ScoreSum
+	}
+	for _, u := range usage {
+		b := get(u.GroupKey, u.Day)
+		b.stats.LLMCalls += u.Calls
+		b.stats.FailedCalls += u.FailedCalls
+		b.stats.PromptTokens += u.PromptTokens
+		b.stats.CompletionTokens += u.CompletionTokens
+		b.stats.TotalTokens += u.TotalTokens
+		b.latencySum += u.LatencySum
+	}
+
+	stats := make([]ReviewConfigStats, 0, len(buckets))
+	for _, b := range buckets {
+		st := b.stats
+		if st.Reviews > 0 {
+			st.FailureRate = float64(st.NeedsAttention) / float64(st.Reviews) * 100
+			st.TokensPerReview = float64(st.TotalTokens) / float64(st.Reviews)
+		}
+		if st.Scored > 0 {
+			st.AvgScore = b.scoreSum / float64(st.Scored)
+		}
+		if st.LLMCalls > 0 {
+			st.CallFailureRate = float64(st.FailedCalls) / float64(st.LLMCalls) * 100
+			st.AvgLatencyMs = b.latencySum / float64(st.LLMCalls)
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Period != stats[j].Period {
+			return stats[i].Period < stats[j].Period
+		}
+		if stats[i].Reviews != stats[j].Reviews {
+			return stats[i].Reviews > stats[j].Reviews
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	return stats
+}
+
+// namePrompts names the prompt of each row after its template or kind
+func (s *AIUsageService) namePrompts(stats []ReviewConfigStats) {
+	names := map[string]string{
+		"unknown":        "Unknown",
+		PromptRefProject: "Project custom prompt",
+		PromptRefIaC:     "Infrastructure as code prompt",
+		PromptRefCustom:  "Request prompt",
+		PromptRefDefault: "Built-in default prompt",
+		PromptRefCached:  "Review cache",
+	}
+	var reviewTemplateIDs, promptTemplateIDs []uint
+	for _, st := range stats {
+		switch kind, id := parsePromptRef(st.Key); kind {
+		case PromptRefReviewTemplate:
+			reviewTemplateIDs = append(reviewTemplateIDs, id)
+		case PromptRefPromptTemplate:
+			promptTemplateIDs = append(promptTemplateIDs, id)
+		}
+	}
+	if len(reviewTemplateIDs) > 0 {
+		var templates []models.ReviewTemplate
+		s.db.Select("id, name").Where("id IN ?", reviewTemplateIDs).Find(&templates)
+		for _, t := range templates {
+			names[PromptRef(PromptRefReviewTemplate, t.ID)] = t.Name
+		}
+	}
+	if len(promptTemplateIDs) > 0 {
+		var templates []models.PromptTemplate
+		s.db.Select("id, name").Where("id IN ?", promptTemplateIDs).Find(&templates)
+		for _, t := range templates {
+			names[PromptRef(PromptRefPromptTemplate, t.ID)] = t.Name
+		}
+	}
+	for i := range stats {
+		stats[i].Name = names[stats[i].Key]
+		if stats[i].Name == "" {
+			stats[i].Name = stats[i].Key + " (deleted)"
+		}
+	}
+}
+
+// nameLLMConfigs names the LLM config of each row
+func (s *AIUsageService) nameLLMConfigs(stats []ReviewConfigStats) {
+	var ids []uint
+	for _, st := range stats {
+		if id, err := strconv.ParseUint(st.Key, 10, 32); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	names := map[string]string{"unknown": "Unknown"}
+	if len(ids) > 0 {
+		var configs []models.LLMConfig
+		s.db.Select("id, name, model").Where("id IN ?", ids).Find(&configs)
+		for _, c := range configs {
+			names[strconv.FormatUint(uint64(c.ID), 10)] = fmt.Sprintf("%s (%s)", c.Name, c.Model)
+		}
+	}
+	for i := range stats {
+		stats[i].Name = names[stats[i].Key]
+		if stats[i].Name == "" {
+			stats[i].Name = "LLM config " + stats[i].Key + " (deleted)"
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import "testing"
+
+func TestStatsPeriod(t *testing.T) {
+	tests := []struct {
+		day      string
+		interval string
+		want     string
+	}{
+		{"2026-10-14", "", ""},
+		{"2026-10-14", "day", "2026-10-14"},
+		{"2026-10-14", "week", "2026-10-12"},
+		{"2026-10-12", "week", "2026-10-12"},
+		{"2026-10-18", "week", "2026-10-12"},
+		{"2026-10-14", "month", "2026-10-01"},
+		{"2026-10-14T00:00:00Z", "day", "2026-10-14"},
+		{"", "day", ""},
+	}
+	for _, tt := range tests {
+		if got := statsPeriod(tt.day, tt.interval); got != tt.want {
+			t.Errorf("statsPeriod(%q, %q) = %q, want %q", tt.day, tt.interval, got, tt.want)
+		}
+	}
+}
+
+func TestParsePromptRef(t *testing.T) {
+	tests := []struct {
+		ref      string
+		wantKind string
+		wantID   uint
+	}{
+		{PromptRef(PromptRefReviewTemplate, 3), PromptRefReviewTemplate, 3},
+		{PromptRef(PromptRefPromptTemplate, 12), PromptRefPromptTemplate, 12},
+		{PromptRefProject, PromptRefProject, 0},
+		{"prompt_template:x", "prompt_template:x", 0},
+	}
+	for _, tt := range tests {
+		kind, id := parsePromptRef(tt.ref)
+		if kind != tt.wantKind || id != tt.wantID {
+			t.Errorf("parsePromptRef(%q) = %q, %d, want %q, %d", tt.ref, kind, id, tt.wantKind, tt.wantID)
+		}
+	}
+}
+
+func TestMergeConfigStats(t *testing.T) {
+	reviews := []configReviewRow{
+		{GroupKey: "1", Day: "2026-10-12", Reviews: 3, Scored: 2, NeedsAttention: 1, ScoreSum: 160},
+		{GroupKey: "1", Day: "2026-10-14", Reviews: 1, Scored: 1, ScoreSum: 90},
+		{GroupKey: "2", Day: "2026-10-14", Reviews: 2, Scored: 2, ScoreSum: 140},
+		{GroupKey: "0", Day: "2026-10-20", Reviews: 1, Scored: 1, ScoreSum: 50},
+	}
+	usage := []configUsageRow{
+		{GroupKey: "1", Day: "2026-10-12", Calls: 4, FailedCalls: 1, LatencySum: 8000, PromptTokens: 3000, CompletionTokens: 1000, TotalTokens: 4000},
+		{GroupKey: "2", Day: "2026-10-14", Calls: 2, LatencySum: 2000, TotalTokens: 1000},
+	}
+
+	tests := []struct {
+		name     string
+		interval string
+		want     []ReviewConfigStats
+	}{
+		{
+			name: "whole range",
+			want: []ReviewConfigStats{
+				{Key: "1", Reviews: 4, Scored: 3, NeedsAttention: 1, FailureRate: 25, AvgScore: 250.0 / 3, LLMCalls: 4, FailedCalls: 1, CallFailureRate: 25,
+					AvgLatencyMs: 2000, PromptTokens: 3000, CompletionTokens: 1000, TotalTokens: 4000, TokensPerReview: 1000},
+				{Key: "2", Reviews: 2, Scored: 2, AvgScore: 70, LLMCalls: 2, AvgLatencyMs: 1000, TotalTokens: 1000, TokensPerReview: 500},
+				{Key: "unknown", Reviews: 1, Scored: 1, AvgScore: 50},
+			},
+		},
+		{
+			name:     "weekly",
+			interval: "week",
+			want: []ReviewConfigStats{
+				{Key: "1", Period: "2026-10-12", Reviews: 4, Scored: 3, NeedsAttention: 1, FailureRate: 25, AvgScore: 250.0 / 3, LLMCalls: 4, FailedCalls: 1, CallFailureRate: 25,
+					AvgLatencyMs: 2000, PromptTokens: 3000, CompletionTokens: 1000, TotalTokens: 4000, TokensPerReview: 1000},
+				{Key: "2", Period: "2026-10-12", Reviews: 2, Scored: 2, AvgScore: 70, LLMCalls: 2, AvgLatencyMs: 1000, TotalTokens: 1000, TokensPerReview: 500},
+				{Key: "unknown", Period: "2026-10-19", Reviews: 1, Scored: 1, AvgScore: 50},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeConfigStats(reviews, usage, tt.interval)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -24,11 +24,11 @@ func NewReviewFeedbackService(db *gorm.DB, openAICfg *config.OpenAIConfig) *Revi
 	}
 }
 
-// Create creates a new feedback and triggers AI re-evaluation
-func (s *ReviewFeedbackService) Create(ctx context.Context, feedback *models.ReviewFeedback) error {
+// Create creates a new feedback on a review of the tenant and triggers AI re-evaluation
+func (s *ReviewFeedbackService) Create(ctx context.Context, feedback *models.ReviewFeedback, tenantID uint) error {
 	// Get original review
 	var reviewLog models.ReviewLog
-	if err := s.db.First(&reviewLog, feedback.ReviewLogID).Error; err != nil {
+	if err := ScopeReviewLogsByTenant(s.db.Model(&models.ReviewLog{}), tenantID).First(&reviewLog, feedback.ReviewLogID).Error; err != nil {
 		return fmt.Errorf("review not found: %w", err)
 	}
 
@@ -168,19 +168,28 @@ func (s *ReviewFeedbackService) parseScoreFromResponse(response string) *float64
 	return nil
 }
 
-// ListByReviewLog returns all feedback for a review
-func (s *ReviewFeedbackService) ListByReviewLog(reviewLogID uint) ([]models.ReviewFeedback, error) {
+// scopeFeedback limits a review_feedbacks query to reviews of a tenant's projects; 0 means all tenants
+func (s *ReviewFeedbackService) scopeFeedback(tenantID uint) *gorm.DB {
+	if tenantID == 0 {
+		return s.db
+	}
+	return s.db.Where("review_log_id IN (?)",
+		ScopeReviewLogsByTenant(s.db.Model(&models.ReviewLog{}), tenantID).Select("review_logs.id"))
+}
+
+// ListByReviewLog returns all feedback for a review of the tenant
+func (s *ReviewFeedbackService) ListByReviewLog(reviewLogID, tenantID uint) ([]models.ReviewFeedback, error) {
 	var feedbacks []models.ReviewFeedback
-	err := s.db.Where("review_log_id = ?", reviewLogID).
+	err := s.scopeFeedback(tenantID).Where("review_log_id = ?", reviewLogID).
 		Preload("User").
 		Order("created_at DESC").
 		Find(&feedbacks).Error
 	return feedbacks, err
 }
 
-// GetByID returns a feedback by ID
-func (s *ReviewFeedbackService) GetByID(id uint) (*models.ReviewFeedback, error) {
+// GetByID returns a feedback on a review of the tenant by ID
+func (s *ReviewFeedbackService) GetByID(id, tenantID uint) (*models.ReviewFeedback, error) {
 	var feedback models.ReviewFeedback
-	err := s.db.Preload("User").Preload("ReviewLog").First(&feedback, id).Error
+	err := s.scopeFeedback(tenantID).Preload("User").Preload("ReviewLog").First(&feedback, id).Error
 	return &feedback, err
 }
@@ -0,0 +1,684 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Review finding categories
+const (
+	FindingSecurity    = "security"
+	FindingCorrectness = "correctness"
+	FindingPerformance = "performance"
+	FindingTesting     = "testing"
+	FindingStyle       = "style"
+	FindingOther       = "other"
+)
+
+// FindingCategories lists the categories in classification priority order
+var FindingCategories = []string{FindingSecurity, FindingCorrectness, FindingPerformance, FindingTesting, FindingStyle, FindingOther}
+
+// findingKeywords are matched case-insensitively against finding titles and bodies
+var findingKeywords = map[string][]string{
+	FindingSecurity: {"security", "injection", "xss", "csrf", "ssrf", "vulnerab", "secret", "password", "credential",
+		"authenticat", "authoriz", "sanitiz", "escap", "permission", "privilege", "encrypt",
+		"安全", "注入", "漏洞", "密码", "密钥", "凭证", "越权", "鉴权", "权限", "加密"},
+	FindingCorrectness: {"bug", "error handling", "unhandled", "nil ", "null pointer", "panic", "race condition", "deadlock",
+		"incorrect", "wrong", "logic", "edge case", "off-by-one", "exception", "overflow",
+		"逻辑", "错误处理", "空指针", "异常", "竞态", "死锁", "边界", "溢出"},
+	FindingPerformance: {"performance", "slow", "n+1", "inefficien", "memory", "allocation", "latency", "leak", "blocking", "timeout",
+		"性能", "内存", "泄漏", "耗时", "效率", "阻塞", "超时"},
+	FindingTesting: {"test", "coverage", "assert", "mock",
+		"测试", "覆盖率", "断言"},
+	FindingStyle: {"naming", "readab", "duplicat", "refactor", "comment", "style", "magic number", "hardcod", "maintainab",
+		"complexity", "formatting", "lint", "convention",
+		"可读", "命名", "重复", "重构", "注释", "风格", "规范", "硬编码", "可维护", "复杂度"},
+}
+
+var (
+	// findingSectionRegex matches the headings of the issues section itself
+	findingSectionRegex = regexp.MustCompile(`(?i)(key\s+issues|issues\s*(&|and)\s*suggestions|关键问题|问题与.*建议)`)
+	// findingStopRegex matches the headings after the issues section
+	findingStopRegex = regexp.MustCompile(`(?i)(score\s+breakdown|total\s+score|评分|总分)`)
+	// findingPrefixRegex strips "Issue 1:", "问题1：" and similar title prefixes
+	findingPrefixRegex = regexp.MustCompile(`(?i)^(issue|problem|问题)\s*\d*\s*[:：.、-]?\s*`)
+	findingBulletRegex = regexp.MustCompile(`^[-*+]\s+\*\*`)
+	// findingCategoryRegex matches an explicit category line, as rendered for structured reviews
+	findingCategoryRegex = regexp.MustCompile(`(?i)\*\*category:\*\*\s*([a-z]+)`)
+	findingNormRegex     = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// ExtractedFinding is a finding parsed from review markdown
+type ExtractedFinding struct {
+	Category   string
+	Title      string
+	File       string // Changed file the finding mentions, if any
+	Compliance string // Cloud compliance category, kept for findings on IaC files only
+}
+
+// ExtractFindings parses the issues section of a review into categorized findings.
+// Issues are split at sub-headings, or at top-level numbered or bold bullet items when
+// the review has no sub-headings. Everything from the score section on is ignored.
+// Findings are attributed to the first of the changed files they mention.
+func ExtractFindings(review string, files []string) []ExtractedFinding {
+	lines := strings.Split(strings.ReplaceAll(review, "\r\n", "\n"), "\n")
+
+	// Only consider the issues section
+	var section []string
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if !inCode {
+			if m := mdHeadingRegex.FindStringSubmatch(trimmed); m != nil && findingStopRegex.MatchString(m[2]) {
+				break
+			}
+		}
+		section = append(section, line)
+	}
+
+	isItem := findingItemMatcher(section)
+	var findings []ExtractedFinding
+	var title string
+	var body []string
+	flush := func() {
+		if title != "" {
+			text := strings.Join(body, "\n")
+			category := ClassifyFinding(title, text)
+			if m := findingCategoryRegex.FindStringSubmatch(text); m != nil && isFindingCategory(strings.ToLower(m[1])) {
+				category = strings.ToLower(m[1])
+			}
+			findings = append(findings, ExtractedFinding{
+				Category:   category,
+				Title:      title,
+				File:       mentionedFile(title+"\n"+text, files),
+				Compliance: ClassifyCompliance(title, text),
+			})
+		}
+		title, body = "", nil
+	}
+
+	inCode = false
+	for _, line := range section {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+		}
+		if !inCode && isItem(line) {
+			flush()
+			title = findingTitle(trimmed)
+			continue
+		}
+		if title != "" {
+			body = append(body, line)
+		}
+	}
+	flush()
+	return findings
+}
+
+func isFindingCategory(category string) bool {
+	for _, c := range FindingCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// findingItemMatcher picks how issues are delimited in section: sub-headings when there are
+// any, otherwise unindented numbered items, otherwise unindented bold bullets
+func findingItemMatcher(section []string) func(string) bool {
+	isHeading := func(line string) bool {
+		m := mdHeadingRegex.FindStringSubmatch(strings.TrimSpace(line))
+		return m != nil && !findingSectionRegex.MatchString(m[2])
+	}
+	isNumbered := func(line string) bool {
+		return line == strings.TrimLeft(line, " \t") && mdOrderedItemRegex.MatchString(line)
+	}
+	isBullet := func(line string) bool {
+		return findingBulletRegex.MatchString(line)
+	}
+
+	for _, matcher := range []func(string) bool{isHeading, isNumbered, isBullet} {
+		inCode := false
+		for _, line := range section {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inCode = !inCode
+			}
+			if !inCode && matcher(line) {
+				return matcher
+			}
+		}
+	}
+	return func(string) bool { return false }
+}
+
+// findingTitle strips markdown and issue numbering from an item line. Items starting
+// with bold text, e.g. "1. **Missing nil check**: ...", are titled by the bold text.
+func findingTitle(line string) string {
+	if m := mdHeadingRegex.FindStringSubmatch(line); m != nil {
+		line = m[2]
+	}
+	if m := mdOrderedItemRegex.FindStringSubmatch(line); m != nil {
+		line = m[1]
+	}
+	line = strings.TrimLeft(line, "-+ ")
+	if strings.HasPrefix(line, "**") {
+		if end := strings.Index(line[2:], "**"); end > 0 {
+			line = line[2 : end+2]
+		}
+	}
+	line = strings.NewReplacer("**", "", "`", "").Replace(line)
+	line = findingPrefixRegex.ReplaceAllString(strings.TrimSpace(line), "")
+	line = strings.TrimSpace(strings.TrimRight(line, ":："))
+	if runes := []rune(line); len(runes) > 200 {
+		line = string(runes[:200])
+	}
+	return line
+}
+
+// mentionedFile returns the first file whose path, or else whose unique base name, appears in text
+func mentionedFile(text string, files []string) string {
+	for _, f := range files {
+		if strings.Contains(text, f) {
+			return f
+		}
+	}
+	bases := make(map[string]int, len(files))
+	for _, f := range files {
+		bases[path.Base(f)]++
+	}
+	for _, f := range files {
+		if base := path.Base(f); bases[base] == 1 && strings.Contains(text, base) {
+			return f
+		}
+	}
+	return ""
+}
+
+// ClassifyFinding returns the category whose keywords match a finding best.
+// Title matches weigh three times as much as body matches; ties go to the earlier category.
+func ClassifyFinding(title, body string) string {
+	title, body = strings.ToLower(title), strings.ToLower(body)
+	best, bestScore := FindingOther, 0
+	for _, category := range FindingCategories {
+		score := 0
+		for _, keyword := range findingKeywords[category] {
+			if strings.Contains(title, keyword) {
+				score += 3
+			}
+			if strings.Contains(body, keyword) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = category, score
+		}
+	}
+	return best
+}
+
+// normalizeFindingTitle folds case and punctuation so recurring findings group together
+func normalizeFindingTitle(title string) string {
+	return strings.TrimSpace(findingNormRegex.ReplaceAllString(strings.ToLower(title), " "))
+}
+
+// ReviewFindingService records review findings and aggregates them over time
+type ReviewFindingService struct {
+	db *gorm.DB
+}
+
+func NewReviewFindingService(db *gorm.DB) *ReviewFindingService {
+	return &ReviewFindingService{db: db}
+}
+
+// Record replaces the findings and changed files of a completed review with those
+// extracted from its result and reviewed diff. Untested files and unsigned commits
+// detected before the review add findings of their own. Findings on infrastructure-as-code
+// files, or on no file of a change made only of IaC, keep their compliance category.
+func (s *ReviewFindingService) Record(reviewLog *models.ReviewLog, diff string) error {
+	if reviewLog.ReviewStatus != "completed" {
+		return nil
+	}
+
+	var files []models.ReviewFile
+	var paths []string
+	diffFiles := ParseDiffToFiles(diff)
+	for _, f := range diffFiles {
+		if f.FilePath == "unknown" {
+			continue
+		}
+		paths = append(paths, f.FilePath)
+		files = append(files, models.ReviewFile{
+			ReviewLogID: reviewLog.ID,
+			ProjectID:   reviewLog.ProjectID,
+			Path:        f.FilePath,
+			Language:    LanguageOfFile(f.FilePath),
+			Additions:   f.Additions,
+			Deletions:   f.Deletions,
+			CreatedAt:   reviewLog.CreatedAt,
+		})
+	}
+
+	extracted := ExtractFindings(reviewLog.ReviewResult, paths)
+	if reviewLog.UntestedFiles > 0 {
+		extracted = append(extracted, ExtractedFinding{
+			Category: FindingTesting,
+			Title:    "Tests missing for changed source files",
+		})
+	}
+	if reviewLog.UnsignedCommits > 0 {
+		extracted = append(extracted, ExtractedFinding{
+			Category: FindingSecurity,
+			Title:    "Unsigned commits",
+		})
+	}
+
+	iacFiles := iacFilePaths(diffFiles)
+	iacOnly := len(iacFiles) > 0 && len(iacFiles) == len(paths)
+
+	findings := make([]models.ReviewFinding, 0, len(extracted))
+	for _, f := range extracted {
+		compliance := ""
+		if iacFiles[f.File] || (f.File == "" && iacOnly) {
+			compliance = f.Compliance
+		}
+		findings = append(findings, models.ReviewFinding{
+			ReviewLogID: reviewLog.ID,
+			ProjectID:   reviewLog.ProjectID,
+			Author:      reviewLog.Author,
+			Category:    f.Category,
+			Title:       f.Title,
+			FilePath:    f.File,
+			Compliance:  compliance,
+			CreatedAt:   reviewLog.CreatedAt,
+		})
+	}
+
+	reviewLog.LanguageStats, reviewLog.PrimaryLanguage = encodeLanguageStats(LanguageBreakdown(diffFiles))
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.ReviewLog{}).Where("id = ?", reviewLog.ID).Updates(map[string]interface{}{
+			"language_stats":   reviewLog.LanguageStats,
+			"primary_language": reviewLog.PrimaryLanguage,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("review_log_id = ?", reviewLog.ID).Delete(&models.ReviewFinding{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("review_log_id = ?", reviewLog.ID).Delete(&models.ReviewFile{}).Error; err != nil {
+			return err
+		}
+		if len(findings) > 0 {
+			if err := tx.Create(&findings).Error; err != nil {
+				return err
+			}
+		}
+		if len(files) == 0 {
+			return nil
+		}
+		return tx.Create(&files).Error
+	})
+}
+
+type FindingTrendRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"` // Comma-separated project group
+	Period     string `form:"period" binding:"omitempty,oneof=week month"`
+	TenantID   uint   `form:"-"`
+}
+
+type FindingTrendPoint struct {
+	Period string           `json:"period"` // YYYY-MM, or the Monday of the week as YYYY-MM-DD
+	Total  int64            `json:"total"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+type FindingTrendResponse struct {
+	Period string              `json:"period"`
+	Points []FindingTrendPoint `json:"points"`
+}
+
+type FindingAuthorRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	Category   string `form:"category"`
+	Limit      int    `form:"limit"`
+	TenantID   uint   `form:"-"`
+}
+
+type FindingAuthorStats struct {
+	Author string           `json:"author"`
+	Total  int64            `json:"total"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+type FindingRecurringRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	Category   string `form:"category"`
+	MinCount   int    `form:"min_count"`
+	Limit      int    `form:"limit"`
+	TenantID   uint   `form:"-"`
+}
+
+type RecurringFinding struct {
+	ProjectID   uint      `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	Category    string    `json:"category"`
+	Title       string    `json:"title"`
+	Count       int64     `json:"count"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+func (s *ReviewFindingService) scopeFindings(start, end time.Time, projectIDs []uint, category string, tenantID uint) *gorm.DB {
+	query := ScopeProjectColumnByTenant(s.db.Model(&models.ReviewFinding{}), "project_id", tenantID).
+		Where("created_at BETWEEN ? AND ?", start, end).
+		Where("review_log_id NOT IN (?)", s.db.Model(&models.ReviewLog{}).Select("id").Where("revision_of IS NOT NULL"))
+	if len(projectIDs) > 0 {
+		query = query.Where("project_id IN ?", projectIDs)
+	}
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	return query
+}
+
+// GetTrends returns finding counts per category and month (or week), defaulting to the last 180 days
+func (s *ReviewFindingService) GetTrends(req *FindingTrendRequest) (*FindingTrendResponse, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 180)
+	period := req.Period
+	if period == "" {
+		period = "month"
+	}
+
+	var rows []struct {
+		Date     string
+		Category string
+		Count    int64
+	}
+	err := s.scopeFindings(startDate, endDate, parseProjectIDs(req.ProjectID, req.ProjectIDs), "", req.TenantID).
+		Select("DATE(created_at) as date, category, COUNT(*) as count").
+		Group("DATE(created_at), category").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := findingPeriods(startDate, endDate, period)
+	index := make(map[string]int, len(points))
+	for i, p := range points {
+		index[p.Period] = i
+	}
+	for _, r := range rows {
+		day, err := time.Parse("2006-01-02", normalizeDate(r.Date))
+		if err != nil {
+			continue
+		}
+		i, ok := index[findingPeriodKey(day, period)]
+		if !ok {
+			continue
+		}
+		points[i].Counts[r.Category] += r.Count
+		points[i].Total += r.Count
+	}
+
+	return &FindingTrendResponse{Period: period, Points: points}, nil
+}
+
+// findingPeriods returns one empty point per month or week in range
+func findingPeriods(start, end time.Time, period string) []FindingTrendPoint {
+	var points []FindingTrendPoint
+	seen := make(map[string]bool)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	last := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	for !day.After(last) {
+		key := findingPeriodKey(day, period)
+		if !seen[key] {
+			seen[key] = true
+			counts := make(map[string]int64, len(FindingCategories))
+			for _, c := range FindingCategories {
+				counts[c] = 0
+			}
+			points = append(points, FindingTrendPoint{Period: key, Counts: counts})
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return points
+}
+
+// findingPeriodKey returns the month (YYYY-MM) or the Monday of the week (YYYY-MM-DD) of day
+func findingPeriodKey(day time.Time, period string) string {
+	if period == "week" {
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		return day.AddDate(0, 0, -(weekday - 1)).Format("2006-01-02")
+	}
+	return day.Format("2006-01")
+}
+
+// GetByAuthor returns finding counts per author and category, authors with the most findings first
+func (s *ReviewFindingService) GetByAuthor(req *FindingAuthorRequest) ([]FindingAuthorStats, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 90)
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var rows []struct {
+		Author   string
+		Category string
+		Count    int64
+	}
+	err := s.scopeFindings(startDate, endDate, parseProjectIDs(req.ProjectID, req.ProjectIDs), req.Category, req.TenantID).
+		Select("author, category, COUNT(*) as count").
+		Where("author <> ''").
+		Group("author, category").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byAuthor := make(map[string]*FindingAuthorStats)
+	var stats []*FindingAuthorStats
+	for _, r := range rows {
+		a, ok := byAuthor[r.Author]
+		if !ok {
+			a = &FindingAuthorStats{Author: r.Author, Counts: make(map[string]int64)}
+			byAuthor[r.Author] = a
+			stats = append(stats, a)
+		}
+		a.Counts[r.Category] += r.Count
+		a.Total += r.Count
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Author < stats[j].Author
+	})
+
+	result := make([]FindingAuthorStats, 0, limit)
+	for i := 0; i < len(stats) && i < limit; i++ {
+		result = append(result, *stats[i])
+	}
+	return result, nil
+}
+
+// GetRecurring returns findings reported repeatedly within a project, most frequent first.
+// Titles are compared ignoring case and punctuation.
+func (s *ReviewFindingService) GetRecurring(req *FindingRecurringRequest) ([]RecurringFinding, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 90)
+	minCount := int64(req.MinCount)
+	if minCount < 2 {
+		minCount = 2
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var findings []models.ReviewFinding
+	err := s.scopeFindings(startDate, endDate, parseProjectIDs(req.ProjectID, req.ProjectIDs), req.Category, req.TenantID).
+		Select("project_id, category, title, created_at").
+		Order("created_at ASC").
+		Find(&findings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*RecurringFinding)
+	var recurring []*RecurringFinding
+	for _, f := range findings {
+		norm := normalizeFindingTitle(f.Title)
+		if norm == "" {
+			continue
+		}
+		key := fmt.Sprintf("%d|%s|%s", f.ProjectID, f.Category, norm)
+		g, ok := groups[key]
+		if !ok {
+			g = &RecurringFinding{ProjectID: f.ProjectID, Category: f.Category}
+			groups[key] = g
+			recurring = append(recurring, g)
+		}
+		// Report the most recent wording of the finding
+		g.Title = f.Title
+		g.Count++
+		g.LastSeen = f.CreatedAt
+	}
+
+	var result []RecurringFinding
+	for _, g := range recurring {
+		if g.Count >= minCount {
+			result = append(result, *g)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].LastSeen.After(result[j].LastSeen)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	projectIDs := make([]uint, 0, len(result))
+	for _, r := range result {
+		projectIDs = append(projectIDs, r.ProjectID)
+	}
+	names := s.projectNames(projectIDs)
+	for i := range result {
+		result[i].ProjectName = names[result[i].ProjectID]
+	}
+	return result, nil
+}
+
+// projectNames returns the names of projects by ID
+func (s *ReviewFindingService) projectNames(projectIDs []uint) map[uint]string {
+	names := make(map[uint]string, len(projectIDs))
+	if len(projectIDs) == 0 {
+		return names
+	}
+	var projects []models.Project
+	s.db.Select("id, name").Where("id IN ?", projectIDs).Find(&projects)
+	for _, p := range projects {
+		names[p.ID] = p.Name
+	}
+	return names
+}
+
+type FindingComplianceRequest struct {
+	StartDate  string `form:"start_date"`
+	EndDate    string `form:"end_date"`
+	ProjectID  uint   `form:"project_id"`
+	ProjectIDs string `form:"project_ids"`
+	TenantID   uint   `form:"-"`
+}
+
+type FindingComplianceProject struct {
+	ProjectID   uint             `json:"project_id"`
+	ProjectName string           `json:"project_name"`
+	Total       int64            `json:"total"`
+	Counts      map[string]int64 `json:"counts"`
+}
+
+type FindingComplianceResponse struct {
+	Total    int64                      `json:"total"`
+	Counts   map[string]int64           `json:"counts"`   // Per compliance category, zero when none
+	Projects []FindingComplianceProject `json:"projects"` // Projects with the most IaC findings first
+}
+
+// GetCompliance returns the findings on infrastructure-as-code files per cloud compliance
+// category and project, defaulting to the last 90 days
+func (s *ReviewFindingService) GetCompliance(req *FindingComplianceRequest) (*FindingComplianceResponse, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 90)
+
+	var rows []struct {
+		ProjectID  uint
+		Compliance string
+		Count      int64
+	}
+	err := s.scopeFindings(startDate, endDate, parseProjectIDs(req.ProjectID, req.ProjectIDs), "", req.TenantID).
+		Select("project_id, compliance, COUNT(*) as count").
+		Where("compliance <> ''").
+		Group("project_id, compliance").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &FindingComplianceResponse{Counts: make(map[string]int64, len(ComplianceCategories)), Projects: []FindingComplianceProject{}}
+	for _, c := range ComplianceCategories {
+		resp.Counts[c] = 0
+	}
+	byProject := make(map[uint]int)
+	for _, r := range rows {
+		i, ok := byProject[r.ProjectID]
+		if !ok {
+			i = len(resp.Projects)
+			byProject[r.ProjectID] = i
+			resp.Projects = append(resp.Projects, FindingComplianceProject{ProjectID: r.ProjectID, Counts: make(map[string]int64)})
+		}
+		resp.Projects[i].Counts[r.Compliance] += r.Count
+		resp.Projects[i].Total += r.Count
+		resp.Counts[r.Compliance] += r.Count
+		resp.Total += r.Count
+	}
+	sort.SliceStable(resp.Projects, func(i, j int) bool {
+		if resp.Projects[i].Total != resp.Projects[j].Total {
+			return resp.Projects[i].Total > resp.Projects[j].Total
+		}
+		return resp.Projects[i].ProjectID < resp.Projects[j].ProjectID
+	})
+
+	projectIDs := make([]uint, 0, len(resp.Projects))
+	for _, p := range resp.Projects {
+		projectIDs = append(projectIDs, p.ProjectID)
+	}
+	names := s.projectNames(projectIDs)
+	for i := range resp.Projects {
+		resp.Projects[i].ProjectName = names[resp.Projects[i].ProjectID]
+	}
+	return resp, nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExtractFindings(t *testing.T) {
+	tests := []struct {
+		name   string
+		review string
+		want   []ExtractedFinding
+	}{
+		{
+			name: "sub-headings",
+			review: "### 1. Key Issues & Suggestions\n\n" +
+				"#### Issue 1: SQL injection in user lookup\n" +
+				"1. Problem: the query concatenates user input.\n" +
+				"2. Suggestion: use parameters.\n\n" +
+				"#### Issue 2: Inconsistent naming\n" +
+				"Variable names mix styles.\n\n" +
+				"### 2. Score Breakdown\n" +
+				"#### Security: 10/20\n\n" +
+				"### 3. Total Score\nTotal Score: 70/100\n",
+			want: []ExtractedFinding{
+				{Category: FindingSecurity, Title: "SQL injection in user lookup"},
+				{Category: FindingStyle, Title: "Inconsistent naming"},
+			},
+		},
+		{
+			name: "numbered items",
+			review: "### 1. Key Issues & Suggestions\n" +
+				"1. **Unbounded memory allocation**: the whole file is read into memory.\n" +
+				"   - Impact: slow requests\n" +
+				"2. **Missing nil check**: can panic on empty responses\n" +
+				"### 2. Score Breakdown\n1. Correctness: 20/30\n",
+			want: []ExtractedFinding{
+				{Category: FindingPerformance, Title: "Unbounded memory allocation"},
+				{Category: FindingCorrectness, Title: "Missing nil check"},
+			},
+		},
+		{
+			name: "bold bullets",
+			review: "- **No tests for the new parser**\n  Add unit tests.\n" +
+				"- **Something else**\n",
+			want: []ExtractedFinding{
+				{Category: FindingTesting, Title: "No tests for the new parser"},
+				{Category: FindingOther, Title: "Something else"},
+			},
+		},
+		{
+			name: "chinese review",
+			review: "### 一、关键问题与优化建议\n" +
+				"#### 问题1：密码明文写入日志\n存在安全风险。\n" +
+				"### 二、评分明细\n#### 代码质量\n",
+			want: []ExtractedFinding{
+				{Category: FindingSecurity, Title: "密码明文写入日志", Compliance: ComplianceSecrets},
+			},
+		},
+		{
+			name:   "headings in code blocks ignored",
+			review: "1. **Hardcoded retry count**\n```\n# Score Breakdown\n```\n### Total Score\nTotal Score: 90/100\n",
+			want: []ExtractedFinding{
+				{Category: FindingStyle, Title: "Hardcoded retry count"},
+			},
+		},
+		{
+			name:   "no issues",
+			review: "Looks good.\n\n### Total Score\nTotal Score: 95/100\n",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractFindings(tt.review, nil)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractFindings() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyFinding(t *testing.T) {
+	tests := []struct {
+		title string
+		body  string
+		want  string
+	}{
+		{"XSS in comment rendering", "", FindingSecurity},
+		{"Race condition in cache refresh", "", FindingCorrectness},
+		{"N+1 queries when listing projects", "", FindingPerformance},
+		{"Extract duplicated validation", "", FindingStyle},
+		{"Improve handler", "The password is logged in plain text.", FindingSecurity},
+		{"Improve handler", "", FindingOther},
+		// Title matches outweigh body matches
+		{"Rename confusing variables for readability", "a test could cover this", FindingStyle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := ClassifyFinding(tt.title, tt.body); got != tt.want {
+				t.Errorf("ClassifyFinding(%q, %q) = %q, want %q", tt.title, tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindingPeriods(t *testing.T) {
+	start := time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 3, 2, 23, 59, 59, 0, time.UTC)
+
+	var months []string
+	for _, p := range findingPeriods(start, end, "month") {
+		months = append(months, p.Period)
+	}
+	if want := []string{"2024-01", "2024-02", "2024-03"}; !reflect.DeepEqual(months, want) {
+		t.Errorf("month periods = %v, want %v", months, want)
+	}
+
+	weeks := findingPeriods(start, end, "week")
+	if len(weeks) != 5 || weeks[0].Period != "2024-01-29" || weeks[4].Period != "2024-02-26" {
+		t.Errorf("week periods = %v", weeks)
+	}
+	if len(weeks[0].Counts) != len(FindingCategories) {
+		t.Errorf("expected zero counts for every category, got %v", weeks[0].Counts)
+	}
+}
+
+func TestNormalizeFindingTitle(t *testing.T) {
+	if a, b := normalizeFindingTitle("Missing error handling!"), normalizeFindingTitle("missing  error-handling"); a != b {
+		t.Errorf("normalized titles differ: %q vs %q", a, b)
+	}
+}
@@ -0,0 +1,424 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// Review hook stages
+const (
+	HookStagePreReview  = "pre_review"
+	HookStagePostReview = "post_review"
+)
+
+const (
+	defaultHookTimeout = 10 * time.Second
+	maxHookTimeout     = 60 * time.Second
+	maxHookResponse    = 10 << 20
+)
+
+// ReviewHookTarget describes the change a hook is called for
+type ReviewHookTarget struct {
+	ReviewLogID uint   `json:"review_log_id"`
+	ProjectID   uint   `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	EventType   string `json:"event_type"`
+	Branch      string `json:"branch"`
+	CommitSHA   string `json:"commit_sha"`
+	Author      string `json:"author"`
+	MRNumber    *int   `json:"mr_number,omitempty"`
+	MRURL       string `json:"mr_url,omitempty"`
+}
+
+// PreReviewInput is what pre-review hooks receive and may change before the AI review
+type PreReviewInput struct {
+	Review   ReviewHookTarget `json:"review"`
+	Diff     string           `json:"diff"`
+	Prompt   string           `json:"prompt"`   // Prompt override, empty = the project's prompt
+	Findings string           `json:"findings"` // Notes added to the prompt, such as heuristic findings
+}
+
+// PreReviewOutput holds the changes of a pre-review hook; empty fields keep the input
+type PreReviewOutput struct {
+	Diff     *string `json:"diff"`     // Replaces the diff; an empty diff skips the review
+	Prompt   string  `json:"prompt"`   // Replaces the review prompt
+	Findings string  `json:"findings"` // Appended to the findings the AI takes into account
+}
+
+// PostReviewInput is what post-review hooks receive once the AI review completed
+type PostReviewInput struct {
+	Review  ReviewHookTarget `json:"review"`
+	Score   float64          `json:"score"`
+	Content string           `json:"content"`
+	Diff    string           `json:"diff"`
+}
+
+// PostReviewOutput holds the additions of a post-review hook
+type PostReviewOutput struct {
+	Content string `json:"content"` // Markdown appended to the review result
+}
+
+// PreReviewHook is implemented by compiled-in extensions that change a review's input
+type PreReviewHook interface {
+	Name() string
+	PreReview(ctx context.Context, in *PreReviewInput) (*PreReviewOutput, error)
+}
+
+// PostReviewHook is implemented by compiled-in extensions that act on a review's result
+type PostReviewHook interface {
+	Name() string
+	PostReview(ctx context.Context, in *PostReviewInput) (*PostReviewOutput, error)
+}
+
+var (
+	registeredHooksMu sync.RWMutex
+	registeredPre     []PreReviewHook
+	registeredPost    []PostReviewHook
+)
+
+// RegisterReviewHook registers a compiled-in hook implementing PreReviewHook, PostReviewHook
+// or both. Extensions call it from an init function of a package imported by the server, so
+// custom checks need no fork. Registered hooks run before the configured HTTP hooks of their
+// stage, in registration order.
+func RegisterReviewHook(hook interface{}) {
+	registeredHooksMu.Lock()
+	defer registeredHooksMu.Unlock()
+	if h, ok := hook.(PreReviewHook); ok {
+		registeredPre = append(registeredPre, h)
+	}
+	if h, ok := hook.(PostReviewHook); ok {
+		registeredPost = append(registeredPost, h)
+	}
+}
+
+// ReviewHookService runs the registered and configured review hooks and manages HTTP hooks
+type ReviewHookService struct {
+	db         *gorm.DB
+	httpClient *http.Client
+}
+
+func NewReviewHookService(db *gorm.DB) *ReviewHookService {
+	return &ReviewHookService{
+		db:         db,
+		httpClient: OutboundHTTPClient(maxHookTimeout),
+	}
+}
+
+// RunPreReview passes the input through every pre-review hook of the project in turn and
+// returns the changed input. Failing hooks are skipped, unless they are required.
+func (s *ReviewHookService) RunPreReview(ctx context.Context, project *models.Project, in *PreReviewInput) (*PreReviewInput, error) {
+	registeredHooksMu.RLock()
+	hooks := append([]PreReviewHook(nil), registeredPre...)
+	registeredHooksMu.RUnlock()
+
+	for _, h := range hooks {
+		out, err := h.PreReview(ctx, in)
+		if err != nil {
+			logger.Warnf("[ReviewHook] Pre-review hook %s failed for review %d: %v", h.Name(), in.Review.ReviewLogID, err)
+			continue
+		}
+		applyPreReviewOutput(in, out)
+	}
+
+	for _, hook := range s.activeHooks(project, HookStagePreReview) {
+		var out PreReviewOutput
+		if err := s.call(ctx, &hook, in, &out); err != nil {
+			if hook.Required {
+				return nil, fmt.Errorf("required pre-review hook %s failed: %w", hook.Name, err)
+			}
+			continue
+		}
+		applyPreReviewOutput(in, &out)
+	}
+	return in, nil
+}
+
+// RunPostReview calls every post-review hook of the project and returns the content they
+// add to the review result. Failing hooks are skipped.
+func (s *ReviewHookService) RunPostReview(ctx context.Context, project *models.Project, in *PostReviewInput) string {
+	registeredHooksMu.RLock()
+	hooks := append([]PostReviewHook(nil), registeredPost...)
+	registeredHooksMu.RUnlock()
+
+	var additions []string
+	for _, h := range hooks {
+		out, err := h.PostReview(ctx, in)
+		if err != nil {
+			logger.Warnf("[ReviewHook] Post-review hook %s failed for review %d: %v", h.Name(), in.Review.ReviewLogID, err)
+			continue
+		}
+		if out != nil && strings.TrimSpace(out.Content) != "" {
+			additions = append(additions, strings.TrimSpace(out.Content))
+		}
+	}
+
+	for _, hook := range s.activeHooks(project, HookStagePostReview) {
+		var out PostReviewOutput
+		if err := s.call(ctx, &hook, in, &out); err != nil {
+			continue
+		}
+		if content := strings.TrimSpace(out.Content); content != "" {
+			additions = append(additions, content)
+		}
+	}
+	return strings.Join(additions, "\n\n")
+}
+
+func applyPreReviewOutput(in *PreReviewInput, out *PreReviewOutput) {
+	if out == nil {
+		return
+	}
+	if out.Diff != nil {
+		in.Diff = *out.Diff
+	}
+	if strings.TrimSpace(out.Prompt) != "" {
+		in.Prompt = out.Prompt
+	}
+	if findings := strings.TrimSpace(out.Findings); findings != "" {
+		in.Findings = strings.TrimPrefix(in.Findings+"\n\n"+findings, "\n\n")
+	}
+}
+
+// activeHooks returns the active HTTP hooks of a stage that apply to the project, in priority order
+func (s *ReviewHookService) activeHooks(project *models.Project, stage string) []models.ReviewHook {
+	var hooks []models.ReviewHook
+	if err := s.db.Where("is_active = ? AND stage = ? AND (tenant_id = 0 OR tenant_id = ?)", true, stage, project.TenantID).Order("priority ASC, id ASC").Find(&hooks).Error; err != nil {
+		logger.Warnf("[ReviewHook] Failed to load review hooks: %v", err)
+		return nil
+	}
+
+	applicable := hooks[:0]
+	for _, hook := range hooks {
+		if hookAppliesTo(&hook, project.ID) {
+			applicable = append(applicable, hook)
+		}
+	}
+	return applicable
+}
+
+// hookAppliesTo reports whether a hook's project list includes the project
+func hookAppliesTo(hook *models.ReviewHook, projectID uint) bool {
+	if strings.TrimSpace(hook.ProjectIDs) == "" {
+		return true
+	}
+	for _, id := range strings.Split(hook.ProjectIDs, ",") {
+		if v, err := strconv.ParseUint(strings.TrimSpace(id), 10, 32); err == nil && uint(v) == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+// hookTimeout returns the timeout of a hook, defaulting to 10s and capped at 60s
+func hookTimeout(hook *models.ReviewHook) time.Duration {
+	if hook.TimeoutSeconds <= 0 {
+		return defaultHookTimeout
+	}
+	timeout := time.Duration(hook.TimeoutSeconds) * time.Second
+	if timeout > maxHookTimeout {
+		return maxHookTimeout
+	}
+	return timeout
+}
+
+// hookSignature signs a payload the way the X-CodeSentry-Signature header carries it
+func hookSignature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// call posts the payload to an HTTP hook and decodes its response into out. An empty
+// response keeps out unchanged. The outcome is recorded on the hook.
+func (s *ReviewHookService) call(ctx context.Context, hook *models.ReviewHook, payload, out interface{}) error {
+	err := s.post(ctx, hook, payload, out)
+	now := time.Now()
+	updates := map[string]interface{}{"last_called_at": &now, "last_error": ""}
+	if err != nil {
+		logger.Warnf("[ReviewHook] Hook %s (%s) failed: %v", hook.Name, hook.Stage, err)
+		updates["last_error"] = err.Error()
+	}
+	s.db.Model(&models.ReviewHook{}).Where("id = ?", hook.ID).Updates(updates)
+	return err
+}
+
+func (s *ReviewHookService) post(ctx context.Context, hook *models.ReviewHook, payload, out interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, hookTimeout(hook))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CodeSentry-Hook", hook.Stage)
+	if hook.Secret != "" {
+		secret, err := ResolveSecret(ctx, hook.Secret)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-CodeSentry-Signature", hookSignature(secret, body))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHookResponse))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(data) > 200 {
+			data = data[:200]
+		}
+		return fmt.Errorf("hook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("invalid hook response: %w", err)
+	}
+	return nil
+}
+
+// Test calls a hook with a sample payload of its stage and returns the hook's response
+func (s *ReviewHookService) Test(ctx context.Context, hook *models.ReviewHook) (interface{}, error) {
+	target := ReviewHookTarget{ProjectName: "example", EventType: "push", Branch: "main", CommitSHA: "0000000000000000000000000000000000000000", Author: "codesentry"}
+	diff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package main\n+package main // test\n"
+	if hook.Stage == HookStagePreReview {
+		var out PreReviewOutput
+		err := s.call(ctx, hook, &PreReviewInput{Review: target, Diff: diff}, &out)
+		return &out, err
+	}
+	var out PostReviewOutput
+	err := s.call(ctx, hook, &PostReviewInput{Review: target, Score: 85, Content: "Looks good.", Diff: diff}, &out)
+	return &out, err
+}
+
+// ValidateReviewHook checks the stage and URL of a hook
+func ValidateReviewHook(hook *models.ReviewHook) error {
+	if strings.TrimSpace(hook.Name) == "" {
+		return errors.New("name is required")
+	}
+	if hook.Stage != HookStagePreReview && hook.Stage != HookStagePostReview {
+		return fmt.Errorf("invalid stage %q, expected %s or %s", hook.Stage, HookStagePreReview, HookStagePostReview)
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid hook URL %q", hook.URL)
+	}
+	if hook.TimeoutSeconds < 0 || time.Duration(hook.TimeoutSeconds)*time.Second > maxHookTimeout {
+		return fmt.Errorf("timeout_seconds must be between 0 and %d", int(maxHookTimeout.Seconds()))
+	}
+	return nil
+}
+
+// --- CRUD for ReviewHook ---
+
+// List returns the hooks of a tenant, 0 = all tenants, with secrets masked
+func (s *ReviewHookService) List(tenantID uint) ([]models.ReviewHook, error) {
+	var hooks []models.ReviewHook
+	if err := ScopeTenant(s.db, tenantID).Order("stage ASC, priority ASC, id ASC").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		maskHookSecret(&hooks[i])
+	}
+	return hooks, nil
+}
+
+func maskHookSecret(hook *models.ReviewHook) {
+	if hook.Secret != "" {
+		hook.SecretMask = "****"
+	}
+	hook.Secret = ""
+}
+
+func (s *ReviewHookService) GetByID(id uint) (*models.ReviewHook, error) {
+	var hook models.ReviewHook
+	if err := s.db.First(&hook, id).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (s *ReviewHookService) Create(hook *models.ReviewHook) error {
+	if err := ValidateReviewHook(hook); err != nil {
+		return err
+	}
+	if err := s.db.Create(hook).Error; err != nil {
+		return err
+	}
+	maskHookSecret(hook)
+	return nil
+}
+
+// Update applies updates to a hook; an empty secret keeps the stored secret
+func (s *ReviewHookService) Update(id uint, updates map[string]interface{}) (*models.ReviewHook, error) {
+	hook, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if secret, ok := updates["secret"]; ok && secret == "" {
+		delete(updates, "secret")
+	}
+	delete(updates, "id")
+	delete(updates, "last_called_at")
+	delete(updates, "last_error")
+
+	check := *hook
+	if v, ok := updates["name"].(string); ok {
+		check.Name = v
+	}
+	if v, ok := updates["stage"].(string); ok {
+		check.Stage = v
+	}
+	if v, ok := updates["url"].(string); ok {
+		check.URL = v
+	}
+	if v, ok := updates["timeout_seconds"].(float64); ok {
+		check.TimeoutSeconds = int(v)
+	}
+	if err := ValidateReviewHook(&check); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(hook).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	hook, err = s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	maskHookSecret(hook)
+	return hook, nil
+}
+
+func (s *ReviewHookService) Delete(id uint) error {
+	return s.db.Delete(&models.ReviewHook{}, id).Error
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestHookAppliesTo(t *testing.T) {
+	tests := []struct {
+		projectIDs string
+		projectID  uint
+		want       bool
+	}{
+		{"", 3, true},
+		{"1,3", 3, true},
+		{" 1 , 3 ", 3, true},
+		{"1,2", 3, false},
+		{"x,13", 3, false},
+	}
+
+	for _, tt := range tests {
+		if got := hookAppliesTo(&models.ReviewHook{ProjectIDs: tt.projectIDs}, tt.projectID); got != tt.want {
+			t.Errorf("hookAppliesTo(%q, %d) = %v, want %v", tt.projectIDs, tt.projectID, got, tt.want)
+		}
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    time.Duration
+	}{
+		{0, 10 * time.Second},
+		{5, 5 * time.Second},
+		{600, 60 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := hookTimeout(&models.ReviewHook{TimeoutSeconds: tt.seconds}); got != tt.want {
+			t.Errorf("hookTimeout(%d) = %v, want %v", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestValidateReviewHook(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    models.ReviewHook
+		wantErr bool
+	}{
+		{"valid", models.ReviewHook{Name: "lint", Stage: HookStagePreReview, URL: "https://hooks.example.com/lint"}, false},
+		{"missing name", models.ReviewHook{Stage: HookStagePreReview, URL: "https://hooks.example.com"}, true},
+		{"invalid stage", models.ReviewHook{Name: "lint", Stage: "during", URL: "https://hooks.example.com"}, true},
+		{"invalid url", models.ReviewHook{Name: "lint", Stage: HookStagePostReview, URL: "ftp://hooks.example.com"}, true},
+		{"timeout too long", models.ReviewHook{Name: "lint", Stage: HookStagePostReview, URL: "https://hooks.example.com", TimeoutSeconds: 61}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateReviewHook(&tt.hook); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateReviewHook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyPreReviewOutput(t *testing.T) {
+	diff := "diff --git a/b.go b/b.go\n"
+	in := &PreReviewInput{Diff: "original", Prompt: "", Findings: "missing tests"}
+
+	applyPreReviewOutput(in, nil)
+	applyPreReviewOutput(in, &PreReviewOutput{Findings: "uses a banned API"})
+	applyPreReviewOutput(in, &PreReviewOutput{Diff: &diff, Prompt: "Review {{diffs}}"})
+
+	if in.Diff != diff {
+		t.Errorf("Diff = %q, want %q", in.Diff, diff)
+	}
+	if in.Prompt != "Review {{diffs}}" {
+		t.Errorf("Prompt = %q", in.Prompt)
+	}
+	if in.Findings != "missing tests\n\nuses a banned API" {
+		t.Errorf("Findings = %q", in.Findings)
+	}
+}
+
+func TestReviewHookPost(t *testing.T) {
+	var gotSignature, gotStage string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get("X-CodeSentry-Signature")
+		gotStage = r.Header.Get("X-CodeSentry-Hook")
+		var in PostReviewInput
+		json.NewDecoder(r.Body).Decode(&in)
+		json.NewEncoder(w).Encode(PostReviewOutput{Content: "checked " + in.Review.ProjectName})
+	}))
+	defer server.Close()
+
+	s := &ReviewHookService{httpClient: server.Client()}
+	hook := &models.ReviewHook{Stage: HookStagePostReview, URL: server.URL, Secret: "s3cret"}
+	var out PostReviewOutput
+	if err := s.post(context.Background(), hook, &PostReviewInput{Review: ReviewHookTarget{ProjectName: "api"}}, &out); err != nil {
+		t.Fatalf("post() error = %v", err)
+	}
+	if out.Content != "checked api" {
+		t.Errorf("Content = %q, want %q", out.Content, "checked api")
+	}
+	if gotStage != HookStagePostReview || len(gotSignature) != len("sha256=")+64 {
+		t.Errorf("headers: stage = %q, signature = %q", gotStage, gotSignature)
+	}
+}
+
+func TestReviewHookPostErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	s := &ReviewHookService{httpClient: server.Client()}
+	var out PreReviewOutput
+	if err := s.post(context.Background(), &models.ReviewHook{URL: server.URL}, &PreReviewInput{}, &out); err == nil {
+		t.Error("post() error = nil, want an error for status 502")
+	}
+}
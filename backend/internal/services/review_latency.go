@@ -0,0 +1,101 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Review pipeline stages timed on ReviewLog and in the codesentry_review_stage_duration_seconds histogram
+const (
+	ReviewStageDiffFetch    = "diff_fetch"    // Fetching the diff from the platform
+	ReviewStageQueueWait    = "queue_wait"    // Between queueing the task and a worker starting it
+	ReviewStageContextBuild = "context_build" // Reading the file context
+	ReviewStageLLM          = "llm"           // Every LLM call of the review, with chunk batches and fallbacks
+	ReviewStageCommentPost  = "comment_post"  // Posting the review comment
+	ReviewStageProcessing   = "processing"    // From a worker starting the task to the end of the review
+)
+
+// reviewStageBuckets are the histogram upper bounds in seconds
+var reviewStageBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+// ReviewStageHistogram is the duration distribution of a review stage
+type ReviewStageHistogram struct {
+	Stage   string
+	Buckets []float64 // Upper bounds in seconds
+	Counts  []uint64  // Cumulative count of each bucket
+	Sum     float64   // Seconds
+	Count   uint64
+}
+
+var (
+	reviewStageMu         sync.Mutex
+	reviewStageHistograms = map[string]*ReviewStageHistogram{}
+)
+
+// ObserveReviewStage adds the duration of a review stage to its histogram and returns it in
+// milliseconds, to record on the review
+func ObserveReviewStage(stage string, d time.Duration) int64 {
+	if d < 0 {
+		d = 0
+	}
+	seconds := d.Seconds()
+
+	reviewStageMu.Lock()
+	defer reviewStageMu.Unlock()
+	h, ok := reviewStageHistograms[stage]
+	if !ok {
+		h = &ReviewStageHistogram{Stage: stage, Buckets: reviewStageBuckets, Counts: make([]uint64, len(reviewStageBuckets))}
+		reviewStageHistograms[stage] = h
+	}
+	for i, bound := range h.Buckets {
+		if seconds <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Sum += seconds
+	h.Count++
+	return d.Milliseconds()
+}
+
+// StartReviewTiming stamps the start of the processing of a review and records how long its
+// task waited in the queue, queuedAt being ReviewTask.QueuedAt (0 when it wasn't queued).
+// The stage timings of an earlier run are reset.
+func StartReviewTiming(reviewLog *models.ReviewLog, queuedAt int64) {
+	now := time.Now()
+	reviewLog.StartedAt = &now
+	reviewLog.FinishedAt = nil
+	reviewLog.QueuedAt = nil
+	reviewLog.QueueWaitMs, reviewLog.ContextBuildMs, reviewLog.LLMMs, reviewLog.CommentPostMs = 0, 0, 0, 0
+	if queuedAt > 0 {
+		queued := time.UnixMilli(queuedAt)
+		reviewLog.QueuedAt = &queued
+		reviewLog.QueueWaitMs = ObserveReviewStage(ReviewStageQueueWait, now.Sub(queued))
+	}
+}
+
+// FinishReviewTiming stamps the end of the processing of a review started by StartReviewTiming
+func FinishReviewTiming(reviewLog *models.ReviewLog) {
+	if reviewLog.StartedAt == nil {
+		return
+	}
+	now := time.Now()
+	reviewLog.FinishedAt = &now
+	ObserveReviewStage(ReviewStageProcessing, now.Sub(*reviewLog.StartedAt))
+}
+
+// ReviewStageHistograms returns a copy of the histogram of each stage observed, by stage name
+func ReviewStageHistograms() []ReviewStageHistogram {
+	reviewStageMu.Lock()
+	defer reviewStageMu.Unlock()
+	histograms := make([]ReviewStageHistogram, 0, len(reviewStageHistograms))
+	for _, h := range reviewStageHistograms {
+		c := *h
+		c.Counts = append([]uint64(nil), h.Counts...)
+		histograms = append(histograms, c)
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Stage < histograms[j].Stage })
+	return histograms
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func stageHistogram(stage string) *ReviewStageHistogram {
+	for _, h := range ReviewStageHistograms() {
+		if h.Stage == stage {
+			return &h
+		}
+	}
+	return nil
+}
+
+func TestObserveReviewStage(t *testing.T) {
+	const stage = "test_stage"
+	durations := []time.Duration{50 * time.Millisecond, 700 * time.Millisecond, 3 * time.Second, 20 * time.Minute}
+	for _, d := range durations {
+		if ms := ObserveReviewStage(stage, d); ms != d.Milliseconds() {
+			t.Errorf("ObserveReviewStage(%v) = %d ms, want %d", d, ms, d.Milliseconds())
+		}
+	}
+
+	h := stageHistogram(stage)
+	if h == nil {
+		t.Fatal("no histogram for the observed stage")
+	}
+	if h.Count != 4 {
+		t.Errorf("count = %d, want 4", h.Count)
+	}
+	if want := 1203.75; h.Sum < want-0.001 || h.Sum > want+0.001 {
+		t.Errorf("sum = %g, want %g", h.Sum, want)
+	}
+	tests := []struct {
+		bound float64
+		want  uint64
+	}{
+		{0.1, 1},
+		{0.5, 1},
+		{1, 2},
+		{5, 3},
+		{600, 3},
+	}
+	for _, tt := range tests {
+		for i, bound := range h.Buckets {
+			if bound == tt.bound && h.Counts[i] != tt.want {
+				t.Errorf("bucket le=%g = %d, want %d", tt.bound, h.Counts[i], tt.want)
+			}
+		}
+	}
+}
+
+func TestReviewTiming(t *testing.T) {
+	tests := []struct {
+		name      string
+		queuedAgo time.Duration // 0: not queued
+		wantQueue bool
+	}{
+		{"queued", 2 * time.Second, true},
+		{"not queued", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviewLog := &models.ReviewLog{LLMMs: 1500, CommentPostMs: 300, QueueWaitMs: 10}
+			var queuedAt int64
+			if tt.queuedAgo > 0 {
+				queuedAt = time.Now().Add(-tt.queuedAgo).UnixMilli()
+			}
+
+			StartReviewTiming(reviewLog, queuedAt)
+			if reviewLog.StartedAt == nil || reviewLog.FinishedAt != nil {
+				t.Fatalf("started_at = %v, finished_at = %v, want only started_at", reviewLog.StartedAt, reviewLog.FinishedAt)
+			}
+			if reviewLog.LLMMs != 0 || reviewLog.CommentPostMs != 0 {
+				t.Errorf("stage timings of the earlier run not reset: llm %d, comment %d", reviewLog.LLMMs, reviewLog.CommentPostMs)
+			}
+			if (reviewLog.QueuedAt != nil) != tt.wantQueue {
+				t.Errorf("queued_at = %v, want set %v", reviewLog.QueuedAt, tt.wantQueue)
+			}
+			if tt.wantQueue && reviewLog.QueueWaitMs < tt.queuedAgo.Milliseconds() {
+				t.Errorf("queue_wait_ms = %d, want at least %d", reviewLog.QueueWaitMs, tt.queuedAgo.Milliseconds())
+			}
+			if !tt.wantQueue && reviewLog.QueueWaitMs != 0 {
+				t.Errorf("queue_wait_ms = %d, want 0", reviewLog.QueueWaitMs)
+			}
+
+			FinishReviewTiming(reviewLog)
+			if reviewLog.FinishedAt == nil || reviewLog.FinishedAt.Before(*reviewLog.StartedAt) {
+				t.Errorf("finished_at = %v, want after started_at %v", reviewLog.FinishedAt, reviewLog.StartedAt)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package services
 
 import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -27,13 +32,46 @@ type ReviewLogListRequest struct {
 	ReviewStatus string    `form:"review_status"`
 	MinScore     *float64  `form:"min_score"`
 	MaxScore     *float64  `form:"max_score"`
+	HumanVerdict string    `form:"human_verdict"` // accepted, rejected, or any for annotated reviews
+	// Cursor enables keyset pagination; pass "first" for the first page, then next_cursor.
+	Cursor string `form:"cursor"`
+	// TenantID is set from the request context, 0 = all tenants
+	TenantID uint `form:"-"`
 }
 
 type ReviewLogListResponse struct {
-	Total    int64              `json:"total"`
-	Page     int                `json:"page"`
-	PageSize int                `json:"page_size"`
-	Items    []models.ReviewLog `json:"items"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	Items      []models.ReviewLog `json:"items"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// encodeReviewLogCursor builds an opaque cursor from the last item of a page
+func encodeReviewLogCursor(createdAt time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", createdAt.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeReviewLogCursor parses a cursor produced by encodeReviewLogCursor
+func decodeReviewLogCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return time.Time{}, 0, errors.New("invalid cursor")
+	}
+	return time.Unix(0, nanos), uint(id), nil
 }
 
 // List returns paginated review logs
@@ -48,7 +86,7 @@ func (s *ReviewLogService) List(req *ReviewLogListRequest) (*ReviewLogListRespon
 	var logs []models.ReviewLog
 	var total int64
 
-	query := s.db.Model(&models.ReviewLog{}).Preload("Project")
+	query := ScopeReviewLogsByTenant(s.db.Model(&models.ReviewLog{}).Preload("Project"), req.TenantID)
 
 	if req.EventType != "" {
 		query = query.Where("event_type = ?", req.EventType)
@@ -57,7 +95,7 @@ func (s *ReviewLogService) List(req *ReviewLogListRequest) (*ReviewLogListRespon
 		query = query.Where("project_id = ?", req.ProjectID)
 	}
 	if req.Author != "" {
-		query = query.Where("author LIKE ?", "%"+req.Author+"%")
+		query = query.Where(LikeCondition("author"), ContainsPattern(req.Author))
 	}
 	if !req.StartDate.IsZero() {
 		query = query.Where("created_at >= ?", req.StartDate)
@@ -66,7 +104,7 @@ func (s *ReviewLogService) List(req *ReviewLogListRequest) (*ReviewLogListRespon
 		query = query.Where("created_at <= ?", req.EndDate)
 	}
 	if req.SearchText != "" {
-		query = query.Where("commit_message LIKE ?", "%"+req.SearchText+"%")
+		query = query.Where(LikeCondition("commit_message"), ContainsPattern(req.SearchText))
 	}
 	if req.ReviewStatus != "" {
 		query = query.Where("review_status = ?", req.ReviewStatus)
@@ -77,6 +115,15 @@ func (s *ReviewLogService) List(req *ReviewLogListRequest) (*ReviewLogListRespon
 	if req.MaxScore != nil {
 		query = query.Where("score <= ?", *req.MaxScore)
 	}
+	if req.HumanVerdict == "any" {
+		query = query.Where("human_verdict <> ''")
+	} else if req.HumanVerdict != "" {
+		query = query.Where("human_verdict = ?", req.HumanVerdict)
+	}
+
+	if req.Cursor != "" {
+		return s.listByCursor(query, req)
+	}
 
 	query.Count(&total)
 
@@ -93,18 +140,140 @@ func (s *ReviewLogService) List(req *ReviewLogListRequest) (*ReviewLogListRespon
 	}, nil
 }
 
-// GetByID returns a review log by ID
+// listByCursor pages with a (created_at, id) keyset instead of OFFSET and skips the total count
+func (s *ReviewLogService) listByCursor(query *gorm.DB, req *ReviewLogListRequest) (*ReviewLogListResponse, error) {
+	if req.Cursor != "first" {
+		createdAt, id, err := decodeReviewLogCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", createdAt, createdAt, id)
+	}
+
+	var logs []models.ReviewLog
+	if err := query.Order("created_at DESC, id DESC").Limit(req.PageSize + 1).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &ReviewLogListResponse{
+		Total:    -1,
+		PageSize: req.PageSize,
+	}
+	if len(logs) > req.PageSize {
+		logs = logs[:req.PageSize]
+		last := logs[len(logs)-1]
+		resp.NextCursor = encodeReviewLogCursor(last.CreatedAt, last.ID)
+	}
+	resp.Items = logs
+	return resp, nil
+}
+
+// GetByID returns a review log by ID, restoring archived review content
 func (s *ReviewLogService) GetByID(id uint) (*models.ReviewLog, error) {
 	var log models.ReviewLog
 	if err := s.db.Preload("Project").First(&log, id).Error; err != nil {
 		return nil, err
 	}
+	if log.Archived {
+		var archive models.ReviewLogArchive
+		if err := s.db.Where("review_log_id = ?", log.ID).First(&archive).Error; err == nil {
+			log.ReviewResult = archive.ReviewResult
+			log.DiffContent = archive.DiffContent
+		}
+	}
 	return &log, nil
 }
 
+// LatestReview is the current review state of a branch or merge request
+type LatestReview struct {
+	Review   *models.ReviewLog `json:"review"`
+	MinScore float64           `json:"min_score"`
+	Passed   *bool             `json:"passed"` // Set once the review completed or was skipped
+}
+
+// GetLatest returns the most recent review of a project's branch or merge request.
+// mrNumber takes precedence over branch when both are given.
+func (s *ReviewLogService) GetLatest(project *models.Project, branch string, mrNumber *int) (*LatestReview, error) {
+	// Scoped retries review part of the changes only, so they never count as the latest review
+	query := s.db.Where("project_id = ? AND revision_of IS NULL", project.ID)
+	if mrNumber != nil {
+		query = query.Where("mr_number = ?", *mrNumber)
+	} else {
+		query = query.Where("branch = ?", branch)
+	}
+
+	var latest models.ReviewLog
+	if err := query.Order("created_at DESC, id DESC").First(&latest).Error; err != nil {
+		return nil, err
+	}
+	log, err := s.GetByID(latest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	minScore := s.EffectiveMinScore(project)
+
+	result := &LatestReview{Review: log, MinScore: minScore}
+	switch log.ReviewStatus {
+	case "completed":
+		passed := ReviewPassed(log, minScore, NewSystemConfigService(s.db).GetHumanVerdictConfig().Gating)
+		result.Passed = &passed
+	case "skipped", ReviewStatusSkippedTooLarge, ReviewStatusSkippedByDirective:
+		passed := true
+		result.Passed = &passed
+	}
+	return result, nil
+}
+
+// EffectiveMinScore returns the minimum passing score of a project, falling back to the system default
+func (s *ReviewLogService) EffectiveMinScore(project *models.Project) float64 {
+	minScore := project.MinScore
+	if minScore <= 0 {
+		minScore, _ = strconv.ParseFloat(NewSystemConfigService(s.db).GetWithDefault("system.min_score", "60"), 64)
+	}
+	if minScore <= 0 {
+		minScore = 60
+	}
+	return minScore
+}
+
+// GetLatestScored returns the most recent scored review of a project, optionally of one branch.
+// It returns nil without error when there is none.
+func (s *ReviewLogService) GetLatestScored(projectID uint, branch string) (*models.ReviewLog, error) {
+	query := s.db.Where("project_id = ? AND score IS NOT NULL", projectID)
+	if branch != "" {
+		query = query.Where("branch = ?", branch)
+	}
+	var latest models.ReviewLog
+	err := query.Order("created_at DESC, id DESC").First(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
 // Create creates a new review log
 func (s *ReviewLogService) Create(log *models.ReviewLog) error {
-	return s.db.Create(log).Error
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(log).Error; err != nil {
+			return err
+		}
+		return replaceMissedCommit(tx, log)
+	})
+}
+
+// replaceMissedCommit deletes the row the coverage gap monitor recorded for a commit whose
+// webhook arrived late, or that was reviewed another way, so the commit isn't counted as
+// both missed and reviewed
+func replaceMissedCommit(tx *gorm.DB, log *models.ReviewLog) error {
+	if log.CommitHash == "" || log.ReviewStatus == ReviewStatusMissed {
+		return nil
+	}
+	return tx.Unscoped().Where("project_id = ? AND commit_hash = ? AND review_status = ? AND id <> ?",
+		log.ProjectID, log.CommitHash, ReviewStatusMissed, log.ID).Delete(&models.ReviewLog{}).Error
 }
 
 // Update updates a review log
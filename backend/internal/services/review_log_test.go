@@ -3,6 +3,8 @@ package services
 import (
 	"testing"
 	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
 )
 
 func TestReviewLogListRequest_Defaults(t *testing.T) {
@@ -107,3 +109,52 @@ func TestReviewLogListRequest_EmptyDates(t *testing.T) {
 		t.Error("EndDate should be zero by default")
 	}
 }
+
+func TestReviewLogCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 10, 30, 0, 123456789, time.UTC)
+
+	cursor := encodeReviewLogCursor(createdAt, 42)
+	gotTime, gotID, err := decodeReviewLogCursor(cursor)
+	if err != nil {
+		t.Fatalf("decodeReviewLogCursor() error = %v", err)
+	}
+	if !gotTime.Equal(createdAt) {
+		t.Errorf("created_at = %v, expected %v", gotTime, createdAt)
+	}
+	if gotID != 42 {
+		t.Errorf("id = %d, expected 42", gotID)
+	}
+}
+
+func TestDecodeReviewLogCursor_Invalid(t *testing.T) {
+	tests := []string{"not-base64!", "bm9jb2xvbg", "YWJjOjEyMw", "MTIzOmFiYw"}
+	for _, cursor := range tests {
+		if _, _, err := decodeReviewLogCursor(cursor); err == nil {
+			t.Errorf("decodeReviewLogCursor(%q) should fail", cursor)
+		}
+	}
+}
+
+func TestReviewLogServiceCreate_ReplacesMissedCommit(t *testing.T) {
+	db := newTestDB(t)
+	service := NewReviewLogService(db)
+	gap := &models.ReviewLog{ProjectID: 1, EventType: "push", CommitHash: "abc123", ReviewStatus: ReviewStatusMissed}
+	otherProject := &models.ReviewLog{ProjectID: 2, EventType: "push", CommitHash: "abc123", ReviewStatus: ReviewStatusMissed}
+	mustCreate(t, db, gap, otherProject)
+
+	review := &models.ReviewLog{ProjectID: 1, EventType: "push", CommitHash: "abc123", ReviewStatus: "pending"}
+	if err := service.Create(review); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var statuses []string
+	db.Unscoped().Model(&models.ReviewLog{}).Where("project_id = ? AND commit_hash = ?", 1, "abc123").Pluck("review_status", &statuses)
+	if len(statuses) != 1 || statuses[0] != "pending" {
+		t.Errorf("rows of the commit = %v, want only the review", statuses)
+	}
+	var count int64
+	db.Model(&models.ReviewLog{}).Where("id = ?", otherProject.ID).Count(&count)
+	if count != 1 {
+		t.Error("the missed commit of another project was deleted")
+	}
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Badge colors, matching the shields.io palette
+const (
+	BadgeColorGreen  = "#4c1"
+	BadgeColorYellow = "#dfb317"
+	BadgeColorRed    = "#e05d44"
+	BadgeColorGrey   = "#9f9f9f"
+)
+
+var (
+	mdHeadingRegex     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	mdOrderedItemRegex = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	mdCodeSpanRegex    = regexp.MustCompile("`([^`]+)`")
+	mdBoldRegex        = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	mdLinkRegex        = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^\s)]+)\)`)
+)
+
+// ScoreColor returns the badge color of a score: green when well above the minimum score,
+// yellow when passing and red when failing
+func ScoreColor(score, minScore float64) string {
+	switch {
+	case score >= 80 && score >= minScore:
+		return BadgeColorGreen
+	case score >= minScore:
+		return BadgeColorYellow
+	default:
+		return BadgeColorRed
+	}
+}
+
+// RenderBadgeSVG renders a flat shields.io style badge
+func RenderBadgeSVG(label, message, color string) string {
+	labelWidth := badgeTextWidth(label)
+	messageWidth := badgeTextWidth(message)
+	width := labelWidth + messageWidth
+	label, message = html.EscapeString(label), html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+
+		`<title>%s: %s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g></svg>`,
+		width, label, message,
+		label, message,
+		width,
+		labelWidth, labelWidth, messageWidth, html.EscapeString(color), width,
+		labelWidth/2, label, labelWidth+messageWidth/2, message)
+}
+
+// badgeTextWidth approximates the rendered width of badge text in Verdana 11px
+func badgeTextWidth(text string) int {
+	return len([]rune(text))*7 + 10
+}
+
+// RenderReviewMarkdown renders a review as a standalone Markdown document
+func RenderReviewMarkdown(log *models.ReviewLog, minScore float64) string {
+	var b strings.Builder
+	b.WriteString("## CodeSentry Review\n\n")
+	b.WriteString(reviewScoreLine(log, minScore) + "\n\n")
+	for _, row := range reviewMetaRows(log) {
+		b.WriteString(fmt.Sprintf("- **%s**: %s\n", row[0], row[1]))
+	}
+	if log.MRURL != "" {
+		b.WriteString(fmt.Sprintf("- **MR/PR**: %s\n", log.MRURL))
+	}
+	if log.ReviewResult != "" {
+		b.WriteString("\n---\n\n")
+		b.WriteString(strings.TrimSpace(log.ReviewResult))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// RenderReviewHTML renders a review as an HTML fragment for embedding. All review
+// content is escaped; only a small Markdown subset is converted to tags.
+func RenderReviewHTML(log *models.ReviewLog, minScore float64) string {
+	var b strings.Builder
+	b.WriteString(`<div class="codesentry-review">` + "\n")
+	b.WriteString("<h2>CodeSentry Review</h2>\n")
+
+	message, color := reviewBadgeMessage(log, minScore)
+	b.WriteString("<p>" + RenderBadgeSVG("codesentry", message, color) + "</p>\n")
+
+	b.WriteString("<table>\n")
+	for _, row := range reviewMetaRows(log) {
+		b.WriteString(fmt.Sprintf("<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(row[0]), html.EscapeString(row[1])))
+	}
+	if strings.HasPrefix(log.MRURL, "http://") || strings.HasPrefix(log.MRURL, "https://") {
+		url := html.EscapeString(log.MRURL)
+		b.WriteString(fmt.Sprintf(`<tr><th>MR/PR</th><td><a href="%s" rel="nofollow noopener">%s</a></td></tr>`+"\n", url, url))
+	}
+	b.WriteString("</table>\n")
+
+	if log.ReviewResult != "" {
+		b.WriteString("<hr>\n")
+		b.WriteString(markdownToHTML(log.ReviewResult))
+	}
+	b.WriteString("</div>\n")
+	return b.String()
+}
+
+func reviewMetaRows(log *models.ReviewLog) [][2]string {
+	var rows [][2]string
+	if log.Project != nil {
+		rows = append(rows, [2]string{"Project", log.Project.Name})
+	}
+	rows = append(rows,
+		[2]string{"Branch", log.Branch},
+		[2]string{"Author", log.Author},
+		[2]string{"Commit", shortCommit(log.CommitHash)},
+		[2]string{"Status", log.ReviewStatus},
+		[2]string{"Date", log.CreatedAt.Format("2006-01-02 15:04")},
+	)
+	return rows
+}
+
+func reviewScoreLine(log *models.ReviewLog, minScore float64) string {
+	if log.Score == nil {
+		return fmt.Sprintf("**Status**: %s", log.ReviewStatus)
+	}
+	verdict := "✅ passed"
+	if *log.Score < minScore {
+		verdict = "❌ failed"
+	}
+	return fmt.Sprintf("**Score**: %.0f/100 (min %.0f, %s)", *log.Score, minScore, verdict)
+}
+
+// reviewBadgeMessage returns the badge message and color of a review
+func reviewBadgeMessage(log *models.ReviewLog, minScore float64) (string, string) {
+	if log.Score == nil {
+		return log.ReviewStatus, BadgeColorGrey
+	}
+	return fmt.Sprintf("%.0f/100", *log.Score), ScoreColor(*log.Score, minScore)
+}
+
+// RenderReviewBadgeSVG renders the score badge of a review; log may be nil when there is none
+func RenderReviewBadgeSVG(log *models.ReviewLog, minScore float64) string {
+	if log == nil {
+		return RenderBadgeSVG("codesentry", "no reviews", BadgeColorGrey)
+	}
+	message, color := reviewBadgeMessage(log, minScore)
+	return RenderBadgeSVG("codesentry", message, color)
+}
+
+func shortCommit(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// markdownToHTML converts headings, lists, code blocks, blockquotes, bold text,
+// inline code and http(s) links. Everything else is escaped, so raw HTML in the
+// review never reaches the page.
+func markdownToHTML(md string) string {
+	var b strings.Builder
+	var para []string
+	listTag := ""
+	inCode := false
+
+	flushPara := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + strings.Join(para, "<br>\n") + "</p>\n")
+			para = nil
+		}
+	}
+	closeList := func() {
+		if listTag != "" {
+			b.WriteString("</" + listTag + ">\n")
+			listTag = ""
+		}
+	}
+	openList := func(tag string) {
+		if listTag != tag {
+			closeList()
+			b.WriteString("<" + tag + ">\n")
+			listTag = tag
+		}
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(md, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flushPara()
+			closeList()
+			if inCode {
+				b.WriteString("</code></pre>\n")
+			} else {
+				b.WriteString("<pre><code>")
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			b.WriteString(html.EscapeString(line) + "\n")
+			continue
+		}
+
+		if m := mdHeadingRegex.FindStringSubmatch(trimmed); m != nil {
+			flushPara()
+			closeList()
+			level := len(m[1])
+			b.WriteString(fmt.Sprintf("<h%d>%s</h%d>\n", level, markdownInline(m[2]), level))
+			continue
+		}
+		if m := mdOrderedItemRegex.FindStringSubmatch(trimmed); m != nil {
+			flushPara()
+			openList("ol")
+			b.WriteString("<li>" + markdownInline(m[1]) + "</li>\n")
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			flushPara()
+			closeList()
+		case trimmed == "---" || trimmed == "***":
+			flushPara()
+			closeList()
+			b.WriteString("<hr>\n")
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+			flushPara()
+			openList("ul")
+			b.WriteString("<li>" + markdownInline(trimmed[2:]) + "</li>\n")
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			closeList()
+			b.WriteString("<blockquote>" + markdownInline(strings.TrimSpace(trimmed[1:])) + "</blockquote>\n")
+		default:
+			closeList()
+			para = append(para, markdownInline(trimmed))
+		}
+	}
+	if inCode {
+		b.WriteString("</code></pre>\n")
+	}
+	flushPara()
+	closeList()
+	return b.String()
+}
+
+// markdownInline escapes text and converts inline code, bold text and links
+func markdownInline(text string) string {
+	text = html.EscapeString(text)
+	text = mdCodeSpanRegex.ReplaceAllString(text, "<code>$1</code>")
+	text = mdBoldRegex.ReplaceAllString(text, "<strong>$1</strong>")
+	text = mdLinkRegex.ReplaceAllString(text, `<a href="$2" rel="nofollow noopener">$1</a>`)
+	return text
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestMarkdownToHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "heading and paragraph",
+			markdown: "### Summary\nLooks good",
+			contains: []string{"<h3>Summary</h3>", "<p>Looks good</p>"},
+		},
+		{
+			name:     "unordered list with inline formatting",
+			markdown: "- **Bug** in `main.go`\n- second",
+			contains: []string{"<ul>", "<li><strong>Bug</strong> in <code>main.go</code></li>", "<li>second</li>", "</ul>"},
+		},
+		{
+			name:     "ordered list",
+			markdown: "1. first\n2. second",
+			contains: []string{"<ol>", "<li>first</li>", "<li>second</li>", "</ol>"},
+		},
+		{
+			name:     "code block is escaped",
+			markdown: "```go\nif a < b {\n```",
+			contains: []string{"<pre><code>if a &lt; b {\n</code></pre>"},
+		},
+		{
+			name:     "raw html is escaped",
+			markdown: "<script>alert(1)</script>\n<img src=x onerror=alert(1)>",
+			contains: []string{"&lt;script&gt;"},
+			excludes: []string{"<script>", "<img"},
+		},
+		{
+			name:     "http links only",
+			markdown: "[docs](https://example.com/a?b=1) [bad](javascript:alert(1))",
+			contains: []string{`<a href="https://example.com/a?b=1" rel="nofollow noopener">docs</a>`},
+			excludes: []string{`href="javascript`},
+		},
+		{
+			name:     "quotes cannot break out of links",
+			markdown: `[x](https://example.com/"onmouseover="alert(1))`,
+			excludes: []string{`"onmouseover="`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := markdownToHTML(tt.markdown)
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("output does not contain %q:\n%s", want, got)
+				}
+			}
+			for _, bad := range tt.excludes {
+				if strings.Contains(got, bad) {
+					t.Errorf("output contains %q:\n%s", bad, got)
+				}
+			}
+		})
+	}
+}
+
+func TestScoreColor(t *testing.T) {
+	tests := []struct {
+		score, minScore float64
+		want            string
+	}{
+		{95, 60, BadgeColorGreen},
+		{70, 60, BadgeColorYellow},
+		{50, 60, BadgeColorRed},
+		{85, 90, BadgeColorRed},
+	}
+	for _, tt := range tests {
+		if got := ScoreColor(tt.score, tt.minScore); got != tt.want {
+			t.Errorf("ScoreColor(%.0f, %.0f) = %s, want %s", tt.score, tt.minScore, got, tt.want)
+		}
+	}
+}
+
+func TestRenderReviewBadgeSVG(t *testing.T) {
+	score := 85.0
+	svg := RenderReviewBadgeSVG(&models.ReviewLog{Score: &score}, 60)
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "85/100") || !strings.Contains(svg, BadgeColorGreen) {
+		t.Errorf("unexpected badge: %s", svg)
+	}
+	if svg := RenderReviewBadgeSVG(nil, 60); !strings.Contains(svg, "no reviews") {
+		t.Errorf("expected a no reviews badge, got %s", svg)
+	}
+	if svg := RenderBadgeSVG("a<b", "c&d", BadgeColorGrey); strings.Contains(svg, "a<b") || strings.Contains(svg, "c&d") {
+		t.Errorf("badge text must be escaped: %s", svg)
+	}
+}
+
+func TestRenderReview(t *testing.T) {
+	score := 45.0
+	log := &models.ReviewLog{
+		Project:      &models.Project{Name: "demo"},
+		Branch:       "main",
+		Author:       "<alice>",
+		CommitHash:   "0123456789abcdef",
+		ReviewStatus: "completed",
+		Score:        &score,
+		ReviewResult: "### Issues\n- SQL injection",
+		MRURL:        "https://git.example.com/demo/-/merge_requests/1",
+		CreatedAt:    time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC),
+	}
+
+	md := RenderReviewMarkdown(log, 60)
+	for _, want := range []string{"**Score**: 45/100 (min 60, ❌ failed)", "- **Commit**: 01234567", "### Issues", "merge_requests/1"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, md)
+		}
+	}
+
+	page := RenderReviewHTML(log, 60)
+	for _, want := range []string{"<svg", "45/100", "&lt;alice&gt;", "<h3>Issues</h3>", `<a href="https://git.example.com/demo/-/merge_requests/1"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("html does not contain %q:\n%s", want, page)
+		}
+	}
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// ReviewReportBrand and ReviewReportColor brand exported reports of tenants without branding
+const (
+	ReviewReportBrand = "CodeSentry"
+	ReviewReportColor = "#06b6d4"
+)
+
+// ReviewReportMaxFiles caps the changed files listed in an exported report
+const ReviewReportMaxFiles = 100
+
+// scoreBreakdownItemRegex matches score lines such as "- **Security**: 18/20" or "| Security | 18/20 |"
+var scoreBreakdownItemRegex = regexp.MustCompile(`^[\s|>*+-]*(?:\d+[.)]\s*)?\**([^|:：*\d][^|:：*]*?)\**\s*(?:[:：|]\s*)+\**(\d+(?:\.\d+)?)\s*/\s*(\d+)`)
+
+// reportColorRegex matches the colors accepted from tenant branding
+var reportColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// scoreTotalRegex matches the total score line, left out of the breakdown
+var scoreTotalRegex = regexp.MustCompile(`(?i)total|overall|总分|总计`)
+
+// ScoreBreakdownItem is the score of one review criterion
+type ScoreBreakdownItem struct {
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
+	Max   float64 `json:"max"`
+}
+
+// ReviewReport is a printable report of a review for audit packets
+type ReviewReport struct {
+	Brand       string
+	Color       string // Accent color, #rgb or #rrggbb
+	GeneratedAt time.Time
+	Log         *models.ReviewLog
+	LLMName     string
+	MinScore    float64
+	Passed      bool
+	Breakdown   []ScoreBreakdownItem
+	Findings    []ReviewSummaryFinding
+	Languages   []LanguageShare
+	Files       []models.ReviewFile
+	TotalFiles  int // Files recorded, Files being capped at ReviewReportMaxFiles
+}
+
+// ParseScoreBreakdown returns the per-criterion scores listed in the score section of a review
+func ParseScoreBreakdown(review string) []ScoreBreakdownItem {
+	var items []ScoreBreakdownItem
+	inSection, inCode := false, false
+	for _, line := range strings.Split(strings.ReplaceAll(review, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		if m := mdHeadingRegex.FindStringSubmatch(trimmed); m != nil {
+			inSection = findingStopRegex.MatchString(m[2])
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		m := scoreBreakdownItemRegex.FindStringSubmatch(trimmed)
+		if m == nil || scoreTotalRegex.MatchString(m[1]) {
+			continue
+		}
+		score, _ := strconv.ParseFloat(m[2], 64)
+		max, _ := strconv.ParseFloat(m[3], 64)
+		if max <= 0 || score > max {
+			continue
+		}
+		items = append(items, ScoreBreakdownItem{Name: strings.TrimSpace(m[1]), Score: score, Max: max})
+	}
+	return items
+}
+
+// BuildReport gathers the data of the exported report of a review
+func (s *ReviewLogService) BuildReport(log *models.ReviewLog) (*ReviewReport, error) {
+	report := &ReviewReport{
+		Brand:       ReviewReportBrand,
+		Color:       ReviewReportColor,
+		GeneratedAt: time.Now(),
+		Log:         log,
+		Breakdown:   ParseScoreBreakdown(log.ReviewResult),
+	}
+	if log.Project != nil {
+		report.MinScore = s.EffectiveMinScore(log.Project)
+		if log.Project.TenantID > 0 {
+			var tenant models.Tenant
+			if s.db.First(&tenant, log.Project.TenantID).Error == nil {
+				if tenant.DisplayName != "" {
+					report.Brand = tenant.DisplayName
+				}
+				if reportColorRegex.MatchString(tenant.PrimaryColor) {
+					report.Color = tenant.PrimaryColor
+				}
+			}
+		}
+	}
+	report.Passed = ReviewPassed(log, report.MinScore, NewSystemConfigService(s.db).GetHumanVerdictConfig().Gating)
+
+	if log.LLMConfigID != nil {
+		var llm models.LLMConfig
+		if s.db.Unscoped().Select("name", "model").First(&llm, *log.LLMConfigID).Error == nil {
+			report.LLMName = fmt.Sprintf("%s (%s)", llm.Name, llm.Model)
+		}
+	}
+
+	var stored []models.ReviewFinding
+	if err := s.db.Where("review_log_id = ?", log.ID).Order("id").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range stored {
+		report.Findings = append(report.Findings, ReviewSummaryFinding{Category: f.Category, Title: f.Title, File: f.FilePath})
+	}
+	if len(report.Findings) == 0 {
+		report.Findings = summaryFindings(log.ReviewResult)
+	}
+	report.Findings = topFindings(report.Findings, len(report.Findings))
+
+	if log.LanguageStats != "" {
+		_ = json.Unmarshal([]byte(log.LanguageStats), &report.Languages)
+	}
+	var total int64
+	query := s.db.Model(&models.ReviewFile{}).Where("review_log_id = ?", log.ID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+	report.TotalFiles = int(total)
+	if err := query.Order("additions + deletions DESC, id").Limit(ReviewReportMaxFiles).Find(&report.Files).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// title is the document title of the report
+func (r *ReviewReport) title() string {
+	name := "Review"
+	if r.Log.Project != nil {
+		name = r.Log.Project.Name
+	}
+	return fmt.Sprintf("%s Code Review Report - %s #%d", r.Brand, name, r.Log.ID)
+}
+
+// verdict is the outcome line of the report
+func (r *ReviewReport) verdict() string {
+	if r.Log.Score == nil {
+		return "Status: " + r.Log.ReviewStatus
+	}
+	outcome := "PASSED"
+	if !r.Passed {
+		outcome = "FAILED"
+	}
+	return fmt.Sprintf("Score %.0f/100 (minimum %.0f) - %s", *r.Log.Score, r.MinScore, outcome)
+}
+
+// metadata returns the label and value rows describing the reviewed change
+func (r *ReviewReport) metadata() [][2]string {
+	log := r.Log
+	var rows [][2]string
+	add := func(label, value string) {
+		if value != "" {
+			rows = append(rows, [2]string{label, value})
+		}
+	}
+	if log.Project != nil {
+		add("Project", log.Project.Name)
+		add("Repository", log.Project.URL)
+	}
+	add("Event", log.EventType)
+	add("Branch", log.Branch)
+	add("Commit", log.CommitHash)
+	add("Commit URL", log.CommitURL)
+	add("MR/PR", log.MRURL)
+	author := log.Author
+	if log.AuthorEmail != "" {
+		author += " <" + log.AuthorEmail + ">"
+	}
+	add("Author", author)
+	add("Message", firstLine(log.CommitMessage))
+	add("Reviewed at", log.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+	add("Status", log.ReviewStatus)
+	add("Model", r.LLMName)
+	add("Prompt", log.PromptRef)
+	add("Request ID", log.RequestID)
+	if log.OriginalScore != nil {
+		add("AI score", fmt.Sprintf("%.0f (overridden: %s)", *log.OriginalScore, log.ScoreOverrideReason))
+	}
+	if log.HumanVerdict != "" {
+		verdict := log.HumanVerdict
+		if log.HumanVerdictBy != "" {
+			verdict += " by " + log.HumanVerdictBy
+		}
+		if log.HumanVerdictAt != nil {
+			verdict += " on " + log.HumanVerdictAt.Format("2006-01-02 15:04")
+		}
+		if log.HumanVerdictReason != "" {
+			verdict += ": " + log.HumanVerdictReason
+		}
+		add("Human verdict", verdict)
+	}
+	add("Fix PR/MR", log.FixPRURL)
+	return rows
+}
+
+// diffStats returns the label and value rows summarizing the reviewed diff
+func (r *ReviewReport) diffStats() [][2]string {
+	log := r.Log
+	rows := [][2]string{
+		{"Files changed", strconv.Itoa(log.FilesChanged)},
+		{"Lines", fmt.Sprintf("+%d / -%d", log.Additions, log.Deletions)},
+	}
+	if log.UntestedFiles > 0 {
+		rows = append(rows, [2]string{"Untested files", strconv.Itoa(log.UntestedFiles)})
+	}
+	if log.UnsignedCommits > 0 {
+		rows = append(rows, [2]string{"Unsigned commits", strconv.Itoa(log.UnsignedCommits)})
+	}
+	if len(r.Languages) > 0 {
+		languages := make([]string, len(r.Languages))
+		for i, l := range r.Languages {
+			languages[i] = fmt.Sprintf("%s %.0f%%", l.Language, l.Percent)
+		}
+		rows = append(rows, [2]string{"Languages", strings.Join(languages, ", ")})
+	}
+	return rows
+}
+
+func (r *ReviewReport) footer() string {
+	return fmt.Sprintf("%s - generated %s", r.Brand, r.GeneratedAt.Format("2006-01-02 15:04 MST"))
+}
+
+// RenderReviewReportHTML renders a review report as a standalone, printable HTML document.
+// All review content is escaped.
+func RenderReviewReportHTML(r *ReviewReport) string {
+	scoreColor := BadgeColorGrey
+	if r.Log.Score != nil {
+		scoreColor = ScoreColor(*r.Log.Score, r.MinScore)
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(r.title()))
+	fmt.Fprintf(&b, `<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; max-width: 900px; margin: 0 auto; padding: 24px; }
+header { border-bottom: 4px solid %[1]s; padding-bottom: 12px; margin-bottom: 16px; }
+header .brand { color: %[1]s; font-weight: bold; font-size: 14px; text-transform: uppercase; letter-spacing: 1px; }
+h1 { margin: 4px 0; font-size: 22px; }
+h2 { border-bottom: 1px solid %[1]s; padding-bottom: 4px; font-size: 16px; margin-top: 28px; }
+.verdict { display: inline-block; color: #fff; background: %[2]s; padding: 6px 12px; border-radius: 4px; font-weight: bold; }
+table { border-collapse: collapse; width: 100%%; font-size: 13px; }
+th, td { text-align: left; padding: 4px 8px; border-bottom: 1px solid #eee; vertical-align: top; word-break: break-word; }
+th { color: #666; width: 160px; }
+pre { background: #f6f8fa; padding: 8px; overflow-x: auto; font-size: 12px; }
+footer { margin-top: 32px; color: #888; font-size: 11px; }
+@media print { body { padding: 0; } h2 { break-after: avoid; } tr, pre { break-inside: avoid; } }
+</style>
+</head>
+<body>
+`, r.Color, scoreColor)
+
+	fmt.Fprintf(&b, "<header><div class=\"brand\">%s</div><h1>Code Review Report</h1>", html.EscapeString(r.Brand))
+	if r.Log.Project != nil {
+		fmt.Fprintf(&b, "<div>%s &middot; #%d</div>", html.EscapeString(r.Log.Project.Name), r.Log.ID)
+	}
+	b.WriteString("</header>\n")
+	fmt.Fprintf(&b, "<p class=\"verdict\">%s</p>\n", html.EscapeString(r.verdict()))
+
+	writeTable := func(title string, rows [][2]string) {
+		fmt.Fprintf(&b, "<h2>%s</h2>\n<table>\n", title)
+		for _, row := range rows {
+			fmt.Fprintf(&b, "<tr><th>%s</th><td>%s</td></tr>\n", html.EscapeString(row[0]), html.EscapeString(row[1]))
+		}
+		b.WriteString("</table>\n")
+	}
+	writeTable("Change", r.metadata())
+
+	if len(r.Breakdown) > 0 {
+		rows := make([][2]string, len(r.Breakdown))
+		for i, item := range r.Breakdown {
+			rows[i] = [2]string{item.Name, fmt.Sprintf("%g / %g", item.Score, item.Max)}
+		}
+		writeTable("Score Breakdown", rows)
+	}
+
+	fmt.Fprintf(&b, "<h2>Findings (%d)</h2>\n", len(r.Findings))
+	if len(r.Findings) == 0 {
+		b.WriteString("<p>No findings.</p>\n")
+	} else {
+		b.WriteString("<table>\n<tr><th>Category</th><th>Finding</th><th>File</th></tr>\n")
+		for _, f := range r.Findings {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				html.EscapeString(f.Category), html.EscapeString(f.Title), html.EscapeString(f.File))
+		}
+		b.WriteString("</table>\n")
+	}
+
+	writeTable("Diff Statistics", r.diffStats())
+	if len(r.Files) > 0 {
+		b.WriteString("<table>\n<tr><th>File</th><th>Language</th><th>+</th><th>-</th></tr>\n")
+		for _, f := range r.Files {
+			fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%d</td><td>%d</td></tr>\n",
+				html.EscapeString(f.Path), html.EscapeString(f.Language), f.Additions, f.Deletions)
+		}
+		b.WriteString("</table>\n")
+		if r.TotalFiles > len(r.Files) {
+			fmt.Fprintf(&b, "<p>...and %d more files</p>\n", r.TotalFiles-len(r.Files))
+		}
+	}
+
+	if r.Log.ReviewResult != "" {
+		b.WriteString("<h2>Review</h2>\n")
+		b.WriteString(markdownToHTML(r.Log.ReviewResult))
+	}
+	fmt.Fprintf(&b, "<footer>%s</footer>\n</body>\n</html>\n", html.EscapeString(r.footer()))
+	return b.String()
+}
+
+// RenderReviewReportPDF renders a review report as a PDF document. Characters the standard
+// PDF fonts lack, such as CJK text, are replaced by "?"; use the HTML export for those.
+func RenderReviewReportPDF(r *ReviewReport) []byte {
+	accent := parsePDFColor(r.Color, parsePDFColor(ReviewReportColor, pdfBlack))
+	scoreColor := parsePDFColor(BadgeColorGrey, pdfGrey)
+	if r.Log.Score != nil {
+		scoreColor = parsePDFColor(ScoreColor(*r.Log.Score, r.MinScore), pdfGrey)
+	}
+
+	d := newPDFDocument(r.title(), r.footer())
+	d.rect(0, pdfPageHeight-8, pdfPageWidth, 8, accent)
+	d.paragraph(pdfFontBold, 10, accent, 0, strings.ToUpper(r.Brand))
+	d.paragraph(pdfFontBold, 20, pdfBlack, 0, "Code Review Report")
+	if r.Log.Project != nil {
+		d.paragraph(pdfFontRegular, 11, pdfGrey, 0, fmt.Sprintf("%s - #%d", r.Log.Project.Name, r.Log.ID))
+	}
+	d.space(10)
+	d.rect(pdfMargin, d.y-24, pdfPageWidth-2*pdfMargin, 24, scoreColor)
+	d.textAt(pdfMargin+10, d.y-16, pdfFontBold, 12, pdfWhite, r.verdict())
+	d.space(24)
+
+	d.heading("Change", accent)
+	for _, row := range r.metadata() {
+		d.row(row[0], row[1])
+	}
+
+	if len(r.Breakdown) > 0 {
+		d.heading("Score Breakdown", accent)
+		for _, item := range r.Breakdown {
+			d.row(item.Name, fmt.Sprintf("%g / %g", item.Score, item.Max))
+		}
+	}
+
+	d.heading(fmt.Sprintf("Findings (%d)", len(r.Findings)), accent)
+	if len(r.Findings) == 0 {
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, "No findings.")
+	}
+	for i, f := range r.Findings {
+		line := fmt.Sprintf("%d. [%s] %s", i+1, f.Category, f.Title)
+		if f.File != "" {
+			line += " (" + f.File + ")"
+		}
+		d.paragraph(pdfFontRegular, 10, pdfBlack, 0, line)
+	}
+
+	d.heading("Diff Statistics", accent)
+	for _, row := range r.diffStats() {
+		d.row(row[0], row[1])
+	}
+	if len(r.Files) > 0 {
+		d.space(6)
+		for _, f := range r.Files {
+			d.paragraph(pdfFontMono, 8, pdfBlack, 0, fmt.Sprintf("+%-5d -%-5d %s", f.Additions, f.Deletions, f.Path))
+		}
+		if r.TotalFiles > len(r.Files) {
+			d.paragraph(pdfFontRegular, 8, pdfGrey, 0, fmt.Sprintf("...and %d more files", r.TotalFiles-len(r.Files)))
+		}
+	}
+
+	if r.Log.ReviewResult != "" {
+		d.heading("Review", accent)
+		inCode := false
+		for _, line := range strings.Split(strings.ReplaceAll(r.Log.ReviewResult, "\r\n", "\n"), "\n") {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "```") {
+				inCode = !inCode
+				continue
+			}
+			switch {
+			case inCode:
+				d.paragraph(pdfFontMono, 8, pdfBlack, 10, line)
+			case mdHeadingRegex.MatchString(trimmed):
+				d.space(4)
+				d.paragraph(pdfFontBold, 11, pdfBlack, 0, mdHeadingRegex.FindStringSubmatch(trimmed)[2])
+			case trimmed == "":
+				d.space(5)
+			default:
+				indent := float64(len(line)-len(strings.TrimLeft(line, " "))) * 3
+				d.paragraph(pdfFontRegular, 10, pdfBlack, indent, mdBoldRegex.ReplaceAllString(strings.ReplaceAll(trimmed, "`", ""), "$1"))
+			}
+		}
+	}
+	return d.Bytes()
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestParseScoreBreakdown(t *testing.T) {
+	review := "### Key Issues\n1. **Security: 10/20 is not a score line**\n\n" +
+		"### Score Breakdown\n" +
+		"- **Correctness**: 25/30\n" +
+		"| Security | 18/20 |\n" +
+		"2. Readability：14 / 20\n" +
+		"- Bogus: 40/20\n" +
+		"**Total Score: 57/70**\n\n" +
+		"### Summary\n- Style: 5/10\n"
+	want := []ScoreBreakdownItem{
+		{Name: "Correctness", Score: 25, Max: 30},
+		{Name: "Security", Score: 18, Max: 20},
+		{Name: "Readability", Score: 14, Max: 20},
+	}
+	if got := ParseScoreBreakdown(review); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseScoreBreakdown() = %+v, want %+v", got, want)
+	}
+	if got := ParseScoreBreakdown("Total Score: 80/100"); got != nil {
+		t.Errorf("ParseScoreBreakdown() without breakdown = %+v", got)
+	}
+}
+
+func testReviewReport() *ReviewReport {
+	score := 55.0
+	return &ReviewReport{
+		Brand:       ReviewReportBrand,
+		Color:       ReviewReportColor,
+		GeneratedAt: time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC),
+		Log: &models.ReviewLog{
+			ID: 42, Project: &models.Project{Name: "payments", URL: "https://git.example.com/acme/payments"},
+			EventType: "merge_request", Branch: "release/1.2", CommitHash: "abc123def456", Author: "alice",
+			AuthorEmail: "alice@example.com", CommitMessage: "feat: refunds <b>", Score: &score, ReviewStatus: "completed",
+			ReviewResult: "### Key Issues\n1. **Unescaped <script> output**\n\nTotal Score: 55/100",
+			FilesChanged: 3, Additions: 120, Deletions: 8, HumanVerdict: VerdictRejected, HumanVerdictBy: "bob",
+		},
+		MinScore:   60,
+		Breakdown:  []ScoreBreakdownItem{{Name: "Security", Score: 5, Max: 20}},
+		Findings:   []ReviewSummaryFinding{{Category: FindingSecurity, Title: "Unescaped <script> output", File: "web/view.go"}},
+		Languages:  []LanguageShare{{Language: "go", Percent: 100}},
+		Files:      []models.ReviewFile{{Path: "web/view.go", Language: "go", Additions: 100, Deletions: 8}},
+		TotalFiles: 3,
+	}
+}
+
+func TestRenderReviewReportHTML(t *testing.T) {
+	out := RenderReviewReportHTML(testReviewReport())
+	for _, want := range []string{
+		"<title>CodeSentry Code Review Report - payments #42</title>",
+		"Score 55/100 (minimum 60) - FAILED",
+		"alice &lt;alice@example.com&gt;",
+		"feat: refunds &lt;b&gt;",
+		"rejected by bob",
+		"<tr><th>Security</th><td>5 / 20</td></tr>",
+		"<td>security</td><td>Unescaped &lt;script&gt; output</td>",
+		"+120 / -8",
+		"...and 2 more files",
+		"border-bottom: 4px solid #06b6d4",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Error("report content is not escaped")
+	}
+}
+
+func TestRenderReviewReportPDF(t *testing.T) {
+	out := RenderReviewReportPDF(testReviewReport())
+	if !bytes.HasPrefix(out, []byte("%PDF-")) {
+		t.Fatal("not a PDF document")
+	}
+	for _, want := range []string{"(CODESENTRY)", "(payments - #42)", "(Score 55/100 \\(minimum 60\\) - FAILED)", "(1. [security] Unescaped <script> output \\(web/view.go\\))", "(Page 1 of 1)"} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// ReviewSummaryMaxFindings is the number of findings listed in a review summary
+const ReviewSummaryMaxFindings = 3
+
+// ReviewHeadlineMaxLength caps the one-line headline of a review summary, in characters
+const ReviewHeadlineMaxLength = 140
+
+// Review summary sources
+const (
+	SummarySourceTruncated = "truncated" // Built from the review text
+	SummarySourceLLM       = "llm"       // Headline written by a secondary LLM call
+)
+
+// reviewHeadlineSkipRegex matches review lines that make poor headlines: scores and separators
+var reviewHeadlineSkipRegex = regexp.MustCompile(`(?i)^(total\s+score|score|总分|评分)\b|^[-*_=]{3,}$|^\|`)
+
+// reviewHeadlineBulletRegex strips list and quote markers
+var reviewHeadlineBulletRegex = regexp.MustCompile(`^([-*+>]|\d+[.)])\s+`)
+
+// ReviewSummaryFinding is one of the top findings of a review
+type ReviewSummaryFinding struct {
+	Category string `json:"category"`
+	Title    string `json:"title"`
+	File     string `json:"file,omitempty"`
+}
+
+// ReviewSummaryLinks are the links of a reviewed change
+type ReviewSummaryLinks struct {
+	Commit string `json:"commit,omitempty"`
+	MR     string `json:"mr,omitempty"`
+	Fix    string `json:"fix,omitempty"` // Auto-fix PR/MR
+}
+
+// ReviewSummary is the compact form of a review for IM cards and mobile widgets
+type ReviewSummary struct {
+	ID            uint                   `json:"id"`
+	Project       string                 `json:"project"`
+	Branch        string                 `json:"branch"`
+	Author        string                 `json:"author"`
+	Commit        string                 `json:"commit"` // First line of the commit message
+	Status        string                 `json:"status"`
+	Score         *float64               `json:"score"`
+	MinScore      float64                `json:"min_score"`
+	Passed        bool                   `json:"passed"`
+	Headline      string                 `json:"headline"`
+	TopFindings   []ReviewSummaryFinding `json:"top_findings"`
+	TotalFindings int                    `json:"total_findings"`
+	Links         ReviewSummaryLinks     `json:"links"`
+	Source        string                 `json:"source"` // truncated, llm
+}
+
+// topFindings returns the first findings in category priority order, security first
+func topFindings(findings []ReviewSummaryFinding, max int) []ReviewSummaryFinding {
+	sorted := slices.Clone(findings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return findingPriority(sorted[i].Category) < findingPriority(sorted[j].Category)
+	})
+	if len(sorted) > max {
+		sorted = sorted[:max]
+	}
+	return sorted
+}
+
+func findingPriority(category string) int {
+	if i := slices.Index(FindingCategories, category); i >= 0 {
+		return i
+	}
+	return len(FindingCategories)
+}
+
+// summaryFindings extracts the findings of a review text
+func summaryFindings(review string) []ReviewSummaryFinding {
+	extracted := ExtractFindings(review, nil)
+	findings := make([]ReviewSummaryFinding, len(extracted))
+	for i, f := range extracted {
+		findings[i] = ReviewSummaryFinding{Category: f.Category, Title: f.Title, File: f.File}
+	}
+	return findings
+}
+
+// reviewHeadline returns the first line of prose of a review, skipping headings, code and scores
+func reviewHeadline(review string) string {
+	inCode := false
+	for _, line := range strings.Split(review, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || trimmed == "" || mdHeadingRegex.MatchString(trimmed) {
+			continue
+		}
+		trimmed = reviewHeadlineBulletRegex.ReplaceAllString(trimmed, "")
+		trimmed = mdBoldRegex.ReplaceAllString(trimmed, "$1")
+		if trimmed == "" || reviewHeadlineSkipRegex.MatchString(trimmed) {
+			continue
+		}
+		return truncateRunes(trimmed, ReviewHeadlineMaxLength)
+	}
+	return ""
+}
+
+// SummarizeReview builds the summary of a review deterministically from its stored findings,
+// falling back to the findings parsed from the review text when none were recorded
+func SummarizeReview(log *models.ReviewLog, stored []models.ReviewFinding, minScore float64, verdictGating bool) *ReviewSummary {
+	summary := &ReviewSummary{
+		ID:       log.ID,
+		Branch:   log.Branch,
+		Author:   log.Author,
+		Commit:   firstLine(log.CommitMessage),
+		Status:   log.ReviewStatus,
+		Score:    log.Score,
+		MinScore: minScore,
+		Passed:   ReviewPassed(log, minScore, verdictGating),
+		Headline: reviewHeadline(log.ReviewResult),
+		Links:    ReviewSummaryLinks{Commit: log.CommitURL, MR: log.MRURL, Fix: log.FixPRURL},
+		Source:   SummarySourceTruncated,
+	}
+	if log.Project != nil {
+		summary.Project = log.Project.Name
+	}
+	if summary.Headline == "" && log.ErrorMessage != "" {
+		summary.Headline = truncateRunes(firstLine(log.ErrorMessage), ReviewHeadlineMaxLength)
+	}
+
+	var findings []ReviewSummaryFinding
+	for _, f := range stored {
+		findings = append(findings, ReviewSummaryFinding{Category: f.Category, Title: f.Title, File: f.FilePath})
+	}
+	if len(findings) == 0 {
+		findings = summaryFindings(log.ReviewResult)
+	}
+	summary.TotalFindings = len(findings)
+	summary.TopFindings = topFindings(findings, ReviewSummaryMaxFindings)
+	return summary
+}
+
+// Summary returns the summary of a review
+func (s *ReviewLogService) Summary(log *models.ReviewLog) (*ReviewSummary, error) {
+	var stored []models.ReviewFinding
+	if err := s.db.Where("review_log_id = ?", log.ID).Order("id").Find(&stored).Error; err != nil {
+		return nil, err
+	}
+	minScore := 0.0
+	if log.Project != nil {
+		minScore = s.EffectiveMinScore(log.Project)
+	}
+	return SummarizeReview(log, stored, minScore, NewSystemConfigService(s.db).GetHumanVerdictConfig().Gating), nil
+}
+
+// ReviewHeadline asks the LLM the review was made with for a one-sentence summary of it. The
+// summary is stored on the review, so the LLM is only called again once the review is redone.
+func (s *AIService) ReviewHeadline(ctx context.Context, log *models.ReviewLog) (string, error) {
+	if strings.TrimSpace(log.ReviewResult) == "" {
+		return "", fmt.Errorf("review has no result")
+	}
+	hash := ComputeDiffHash(log.ReviewResult)
+	if log.SummaryHeadline != "" && log.SummaryHash == hash {
+		return log.SummaryHeadline, nil
+	}
+	var llmConfigID uint
+	if log.LLMConfigID != nil {
+		llmConfigID = *log.LLMConfigID
+	}
+	prompt := fmt.Sprintf("Summarize the following code review in a single sentence of at most %d characters, "+
+		"for a mobile notification. Mention the most important issue, if any. Reply with the sentence only, "+
+		"in the language of the review.\n\n%s", ReviewHeadlineMaxLength, truncateRunes(log.ReviewResult, 8000))
+	content, _, err := s.CallWithConfig(ctx, llmConfigID, prompt)
+	if err != nil {
+		return "", err
+	}
+	headline := strings.Trim(strings.TrimSpace(firstLine(strings.TrimSpace(content))), `"'`)
+	if headline == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	headline = truncateRunes(headline, ReviewHeadlineMaxLength)
+	if err := s.db.Model(&models.ReviewLog{}).Where("id = ?", log.ID).
+		UpdateColumns(map[string]interface{}{"summary_headline": headline, "summary_hash": hash}).Error; err != nil {
+		logger.Ctx(ctx).Warn().Err(err).Uint("review_log_id", log.ID).Msg("Failed to cache the review summary")
+	}
+	log.SummaryHeadline, log.SummaryHash = headline, hash
+	return headline, nil
+}
+
+// formatTopFindings renders the top findings of a review as a numbered list, noting how many
+// more there are
+func formatTopFindings(findings []ReviewSummaryFinding, total int, more string) string {
+	var sb strings.Builder
+	for i, f := range findings {
+		fmt.Fprintf(&sb, "%d. [%s] %s", i+1, f.Category, truncateRunes(f.Title, 100))
+		if f.File != "" {
+			fmt.Fprintf(&sb, " (`%s`)", f.File)
+		}
+		sb.WriteString("\n")
+	}
+	if total > len(findings) {
+		fmt.Fprintf(&sb, more+"\n", total-len(findings))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
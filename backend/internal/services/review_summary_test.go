@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+)
+
+const summaryReview = `**Overall**: the change adds caching but leaks the connection on errors.
+
+### Key Issues
+
+1. **Missing test for the cache**
+   No test covers the new code path.
+2. **SQL injection in the search query**
+   The term is concatenated into the query.
+3. **Unclear naming**
+   ` + "`d`" + ` should be renamed.
+4. **Connection is never closed on error**
+   This is a bug: the deferred close is skipped.
+
+### Score Breakdown
+
+Total Score: 62/100`
+
+func TestReviewHeadline(t *testing.T) {
+	tests := []struct {
+		name   string
+		review string
+		want   string
+	}{
+		{"first prose line", summaryReview, "Overall: the change adds caching but leaks the connection on errors."},
+		{"skips code and scores", "```go\nx := 1\n```\n---\nTotal Score: 90/100\nLooks good.", "Looks good."},
+		{"truncated", strings.Repeat("a", 200), strings.Repeat("a", ReviewHeadlineMaxLength-3) + "..."},
+		{"empty", "## Heading only", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reviewHeadline(tt.review); got != tt.want {
+				t.Errorf("reviewHeadline() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopFindings(t *testing.T) {
+	findings := []ReviewSummaryFinding{
+		{Category: FindingStyle, Title: "naming"},
+		{Category: FindingCorrectness, Title: "nil map"},
+		{Category: FindingSecurity, Title: "xss"},
+		{Category: FindingCorrectness, Title: "race"},
+	}
+	got := topFindings(findings, 3)
+	want := []ReviewSummaryFinding{findings[2], findings[1], findings[3]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("topFindings() = %+v, want %+v", got, want)
+	}
+	if findings[0].Title != "naming" {
+		t.Error("topFindings() reordered its input")
+	}
+}
+
+func TestSummarizeReview(t *testing.T) {
+	score := 62.0
+	log := &models.ReviewLog{
+		ID: 7, Project: &models.Project{Name: "api"}, Branch: "main", Author: "alice",
+		CommitMessage: "feat: cache search\n\nbody", ReviewStatus: "completed", Score: &score,
+		ReviewResult: summaryReview, MRURL: "https://git.example.com/mr/3",
+	}
+
+	summary := SummarizeReview(log, nil, 60, false)
+	if !summary.Passed || summary.Project != "api" || summary.Commit != "feat: cache search" || summary.Links.MR != log.MRURL {
+		t.Errorf("summary = %+v", summary)
+	}
+	if summary.TotalFindings != 4 || len(summary.TopFindings) != ReviewSummaryMaxFindings {
+		t.Fatalf("findings = %d, top %+v", summary.TotalFindings, summary.TopFindings)
+	}
+	if top := summary.TopFindings[0]; top.Category != FindingSecurity || top.Title != "SQL injection in the search query" {
+		t.Errorf("top finding = %+v", top)
+	}
+	if summary.Source != SummarySourceTruncated {
+		t.Errorf("source = %q", summary.Source)
+	}
+
+	stored := []models.ReviewFinding{{Category: FindingPerformance, Title: "N+1 query", FilePath: "db/search.go"}}
+	summary = SummarizeReview(log, stored, 70, false)
+	if summary.Passed || summary.TotalFindings != 1 || summary.TopFindings[0].File != "db/search.go" {
+		t.Errorf("summary with stored findings = %+v", summary)
+	}
+}
+
+func TestFormatTopFindings(t *testing.T) {
+	findings := []ReviewSummaryFinding{
+		{Category: FindingSecurity, Title: "xss", File: "web/app.js"},
+		{Category: FindingTesting, Title: "no tests"},
+	}
+	want := "1. [security] xss (`web/app.js`)\n2. [testing] no tests\n...and 3 more"
+	if got := formatTopFindings(findings, 5, "...and %d more"); got != want {
+		t.Errorf("formatTopFindings() = %q, want %q", got, want)
+	}
+}
+
+func TestAIService_ReviewHeadlineCached(t *testing.T) {
+	db := newTestDB(t)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Leaks the connection on errors."}}},
+		})
+	}))
+	defer srv.Close()
+	llm := models.LLMConfig{Name: "llm", Provider: "openai", BaseURL: srv.URL, Model: "test", IsActive: true}
+	mustCreate(t, db, &llm)
+	reviewLog := models.ReviewLog{ProjectID: 1, ReviewStatus: "completed", ReviewResult: summaryReview, LLMConfigID: &llm.ID}
+	mustCreate(t, db, &reviewLog)
+
+	s := NewAIService(db, nil)
+	for i := 0; i < 2; i++ {
+		var stored models.ReviewLog
+		db.First(&stored, reviewLog.ID)
+		headline, err := s.ReviewHeadline(context.Background(), &stored)
+		if err != nil || headline != "Leaks the connection on errors." {
+			t.Fatalf("ReviewHeadline() = %q, %v", headline, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("LLM called %d times, want the headline cached after the first call", calls)
+	}
+
+	db.Model(&reviewLog).Update("review_result", "Redone review.")
+	var redone models.ReviewLog
+	db.First(&redone, reviewLog.ID)
+	if _, err := s.ReviewHeadline(context.Background(), &redone); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("LLM called %d times, want a new headline for the redone review", calls)
+	}
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"errors"
+	"slices"
+
 	"github.com/huangang/codesentry/backend/internal/models"
 	"gorm.io/gorm"
 )
@@ -72,115 +75,108 @@ func (s *ReviewTemplateService) GetByType(templateType string) ([]models.ReviewT
 	return templates, err
 }
 
-// SeedDefaultTemplates creates built-in templates if they don't exist
+// SeedDefaultTemplates creates the built-in templates missing from the database. Installed
+// built-in templates keep their content; newer versions are offered by BuiltInUpdates.
 func (s *ReviewTemplateService) SeedDefaultTemplates() error {
-	var count int64
-	s.db.Model(&models.ReviewTemplate{}).Where("is_built_in = ?", true).Count(&count)
-	if count > 0 {
-		return nil // Already seeded
+	for _, builtIn := range builtInReviewTemplates {
+		var existing models.ReviewTemplate
+		err := s.db.Where("is_built_in = ? AND slug = ?", true, builtIn.Slug).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// Built-in templates seeded before slugs existed are matched by name
+		adopted := s.db.Model(&models.ReviewTemplate{}).
+			Where("is_built_in = ? AND (slug = '' OR slug IS NULL) AND name = ?", true, builtIn.Name).
+			Updates(map[string]interface{}{"slug": builtIn.Slug, "built_in_version": 1})
+		if adopted.Error != nil {
+			return adopted.Error
+		}
+		if adopted.RowsAffected > 0 {
+			continue
+		}
+
+		template := builtIn
+		if err := s.db.Create(&template).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuiltInTemplateUpdate is a newer version of an installed built-in template
+type BuiltInTemplateUpdate struct {
+	ID               uint   `json:"id"`
+	Slug             string `json:"slug"`
+	Name             string `json:"name"`
+	InstalledVersion int    `json:"installed_version"`
+	AvailableVersion int    `json:"available_version"`
+	Content          string `json:"content"` // Content of the available version
+}
+
+// BuiltInUpdates lists the built-in templates whose bundled version is newer than the installed one
+func (s *ReviewTemplateService) BuiltInUpdates() ([]BuiltInTemplateUpdate, error) {
+	var installed []models.ReviewTemplate
+	if err := s.db.Where("is_built_in = ? AND slug <> ''", true).Find(&installed).Error; err != nil {
+		return nil, err
 	}
 
-	templates := []models.ReviewTemplate{
-		{
-			Name:        "通用代码审查",
-			Type:        "general",
-			Description: "适用于各类项目的通用代码审查模板",
-			Content: `你是一位资深的软件开发工程师。请对以下代码变更进行审查，关注：
-1. 代码正确性和逻辑问题
-2. 安全漏洞
-3. 性能问题
-4. 代码可读性和最佳实践
-
-请给出0-100分的评分，格式：总分:XX分
-
-**代码变更**：
-{{diffs}}
-
-**提交信息**：
-{{commits}}`,
-			IsBuiltIn: true,
-			IsActive:  true,
-		},
-		{
-			Name:        "前端代码审查",
-			Type:        "frontend",
-			Description: "针对前端项目的专业审查模板（React/Vue/Angular）",
-			Content: `你是一位资深的前端开发工程师。请对以下前端代码变更进行审查，重点关注：
-
-## 审查维度
-1. **组件设计（25分）**：组件职责单一、可复用性、状态管理合理性
-2. **性能优化（25分）**：不必要的重渲染、大组件拆分、懒加载使用
-3. **用户体验（20分）**：交互反馈、错误处理、加载状态
-4. **代码质量（20分）**：TypeScript类型安全、代码规范、可读性
-5. **安全性（10分）**：XSS防护、敏感数据处理
-
-请针对最重要的3个问题给出建议，并给出评分。
-格式：总分:XX分
-
-**代码变更**：
-{{diffs}}
-
-**提交信息**：
-{{commits}}`,
-			IsBuiltIn: true,
-			IsActive:  true,
-		},
-		{
-			Name:        "后端代码审查",
-			Type:        "backend",
-			Description: "针对后端项目的专业审查模板（Go/Java/Python）",
-			Content: `你是一位资深的后端开发工程师。请对以下后端代码变更进行审查，重点关注：
-
-## 审查维度
-1. **业务逻辑（25分）**：逻辑正确性、边界处理、错误处理
-2. **安全性（25分）**：SQL注入、权限控制、敏感信息处理、输入验证
-3. **性能（20分）**：数据库查询优化、缓存使用、并发处理
-4. **可维护性（20分）**：代码结构、命名规范、注释完整性
-5. **可测试性（10分）**：单元测试覆盖、依赖注入
-
-请针对最重要的3个问题给出建议，并给出评分。
-格式：总分:XX分
-
-**代码变更**：
-{{diffs}}
-
-**提交信息**：
-{{commits}}`,
-			IsBuiltIn: true,
-			IsActive:  true,
-		},
-		{
-			Name:        "安全代码审查",
-			Type:        "security",
-			Description: "专注于安全漏洞检测的审查模板",
-			Content: `你是一位资深的安全工程师。请对以下代码变更进行安全审查，重点关注：
-
-## 安全审查维度
-1. **注入攻击（30分）**：SQL注入、命令注入、LDAP注入、XPath注入
-2. **认证与授权（25分）**：权限验证、会话管理、密码处理
-3. **敏感数据（20分）**：密钥硬编码、日志敏感信息、数据加密
-4. **输入验证（15分）**：XSS、CSRF、文件上传、参数校验
-5. **依赖安全（10分）**：已知漏洞组件、不安全的API调用
-
-请列出发现的所有安全问题，按严重程度排序（高/中/低）。
-格式：总分:XX分
-
-**代码变更**：
-{{diffs}}
-
-**提交信息**：
-{{commits}}`,
-			IsBuiltIn: true,
-			IsActive:  true,
-		},
+	updates := []BuiltInTemplateUpdate{}
+	for _, t := range installed {
+		builtIn := findBuiltInReviewTemplate(t.Slug)
+		if builtIn == nil || builtIn.BuiltInVersion <= t.BuiltInVersion {
+			continue
+		}
+		updates = append(updates, BuiltInTemplateUpdate{
+			ID:               t.ID,
+			Slug:             t.Slug,
+			Name:             builtIn.Name,
+			InstalledVersion: t.BuiltInVersion,
+			AvailableVersion: builtIn.BuiltInVersion,
+			Content:          builtIn.Content,
+		})
 	}
+	return updates, nil
+}
 
-	for _, t := range templates {
-		if err := s.db.Create(&t).Error; err != nil {
-			return err
+// ApplyBuiltInUpdates replaces installed built-in templates with their newer bundled version,
+// keeping whether they are active. Empty slugs apply every available update.
+func (s *ReviewTemplateService) ApplyBuiltInUpdates(slugs []string) ([]BuiltInTemplateUpdate, error) {
+	available, err := s.BuiltInUpdates()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []BuiltInTemplateUpdate{}
+	for _, update := range available {
+		if len(slugs) > 0 && !slices.Contains(slugs, update.Slug) {
+			continue
+		}
+		builtIn := findBuiltInReviewTemplate(update.Slug)
+		err := s.db.Model(&models.ReviewTemplate{}).Where("id = ?", update.ID).Updates(map[string]interface{}{
+			"name":             builtIn.Name,
+			"type":             builtIn.Type,
+			"description":      builtIn.Description,
+			"content":          builtIn.Content,
+			"built_in_version": builtIn.BuiltInVersion,
+		}).Error
+		if err != nil {
+			return applied, err
 		}
+		applied = append(applied, update)
 	}
+	return applied, nil
+}
 
+func findBuiltInReviewTemplate(slug string) *models.ReviewTemplate {
+	for i := range builtInReviewTemplates {
+		if builtInReviewTemplates[i].Slug == slug {
+			return &builtInReviewTemplates[i]
+		}
+	}
 	return nil
 }
 
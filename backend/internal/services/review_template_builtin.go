@@ -0,0 +1,213 @@
+package services
+
+import "github.com/huangang/codesentry/backend/internal/models"
+
+// builtInReviewTemplates are the curated templates seeded by SeedDefaultTemplates. Slug
+// identifies a template across releases; raising BuiltInVersion after changing a template
+// offers the new content as an update, which admins apply when they choose to.
+var builtInReviewTemplates = []models.ReviewTemplate{
+	{
+		Slug:           "general",
+		BuiltInVersion: 1,
+		Name:           "通用代码审查",
+		Type:           "general",
+		Description:    "适用于各类项目的通用代码审查模板",
+		Content: `你是一位资深的软件开发工程师。请对以下代码变更进行审查，关注：
+1. 代码正确性和逻辑问题
+2. 安全漏洞
+3. 性能问题
+4. 代码可读性和最佳实践
+
+请给出0-100分的评分，格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "frontend",
+		BuiltInVersion: 1,
+		Name:           "前端代码审查",
+		Type:           "frontend",
+		Description:    "针对前端项目的专业审查模板（React/Vue/Angular）",
+		Content: `你是一位资深的前端开发工程师。请对以下前端代码变更进行审查，重点关注：
+
+## 审查维度
+1. **组件设计（25分）**：组件职责单一、可复用性、状态管理合理性
+2. **性能优化（25分）**：不必要的重渲染、大组件拆分、懒加载使用
+3. **用户体验（20分）**：交互反馈、错误处理、加载状态
+4. **代码质量（20分）**：TypeScript类型安全、代码规范、可读性
+5. **安全性（10分）**：XSS防护、敏感数据处理
+
+请针对最重要的3个问题给出建议，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "backend",
+		BuiltInVersion: 1,
+		Name:           "后端代码审查",
+		Type:           "backend",
+		Description:    "针对后端项目的专业审查模板（Go/Java/Python）",
+		Content: `你是一位资深的后端开发工程师。请对以下后端代码变更进行审查，重点关注：
+
+## 审查维度
+1. **业务逻辑（25分）**：逻辑正确性、边界处理、错误处理
+2. **安全性（25分）**：SQL注入、权限控制、敏感信息处理、输入验证
+3. **性能（20分）**：数据库查询优化、缓存使用、并发处理
+4. **可维护性（20分）**：代码结构、命名规范、注释完整性
+5. **可测试性（10分）**：单元测试覆盖、依赖注入
+
+请针对最重要的3个问题给出建议，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "security",
+		BuiltInVersion: 1,
+		Name:           "安全代码审查",
+		Type:           "security",
+		Description:    "专注于安全漏洞检测的审查模板",
+		Content: `你是一位资深的安全工程师。请对以下代码变更进行安全审查，重点关注：
+
+## 安全审查维度
+1. **注入攻击（30分）**：SQL注入、命令注入、LDAP注入、XPath注入
+2. **认证与授权（25分）**：权限验证、会话管理、密码处理
+3. **敏感数据（20分）**：密钥硬编码、日志敏感信息、数据加密
+4. **输入验证（15分）**：XSS、CSRF、文件上传、参数校验
+5. **依赖安全（10分）**：已知漏洞组件、不安全的API调用
+
+请列出发现的所有安全问题，按严重程度排序（高/中/低）。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "go-api",
+		BuiltInVersion: 1,
+		Name:           "Go API 服务审查",
+		Type:           "backend",
+		Description:    "针对 Go HTTP/gRPC API 服务的审查模板（Gin/Echo/gRPC + GORM/sqlx）",
+		Content: `你是一位资深的 Go 后端工程师。请对以下 Go API 服务的代码变更进行审查，重点关注：
+
+## 审查维度
+1. **错误处理（25分）**：错误是否被检查和包装（%w）、是否吞掉错误、panic 的使用、HTTP 状态码是否与错误匹配
+2. **并发安全（20分）**：goroutine 泄漏、data race、context 取消与超时传递、锁的粒度
+3. **数据访问（20分）**：SQL 注入、N+1 查询、事务边界、连接和 rows 是否关闭
+4. **API 设计（20分）**：输入校验、鉴权与越权、分页、向后兼容
+5. **可维护性（15分）**：包结构、接口定义、命名、表驱动测试
+
+请针对最重要的3个问题给出建议和修改示例，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "react",
+		BuiltInVersion: 1,
+		Name:           "React 应用审查",
+		Type:           "frontend",
+		Description:    "针对 React + TypeScript 应用的审查模板",
+		Content: `你是一位资深的 React 工程师。请对以下 React 代码变更进行审查，重点关注：
+
+## 审查维度
+1. **Hooks 使用（25分）**：依赖数组是否完整、useEffect 清理、条件调用 Hooks、闭包陈旧值
+2. **渲染性能（20分）**：不必要的重渲染、memo/useMemo/useCallback 的合理使用、列表 key
+3. **状态管理（20分）**：状态提升与拆分、派生状态、服务端状态与缓存
+4. **类型与健壮性（20分）**：TypeScript 类型（避免 any）、空值处理、加载与错误状态
+5. **安全与可访问性（15分）**：dangerouslySetInnerHTML、XSS、语义化标签与 ARIA
+
+请针对最重要的3个问题给出建议，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "terraform",
+		BuiltInVersion: 1,
+		Name:           "Terraform 基础设施审查",
+		Type:           "infrastructure",
+		Description:    "针对 Terraform/HCL 基础设施即代码的审查模板",
+		Content: `你是一位资深的云基础设施工程师。请对以下 Terraform 代码变更进行审查，重点关注：
+
+## 审查维度
+1. **安全配置（30分）**：公网暴露（0.0.0.0/0）、过宽的 IAM 权限、未加密的存储、硬编码的密钥
+2. **变更风险（25分）**：会导致资源销毁重建的修改、缺少 prevent_destroy/lifecycle、数据库和存储的数据丢失风险
+3. **状态与模块（20分）**：provider 和模块版本锁定、远程状态、模块边界与复用
+4. **成本（15分）**：实例规格、冗余资源、缺少自动伸缩或生命周期策略
+5. **规范（10分）**：命名、标签（tags）、变量校验与描述
+
+请列出会被销毁或替换的资源及安全问题，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+	{
+		Slug:           "android",
+		BuiltInVersion: 1,
+		Name:           "Android 应用审查",
+		Type:           "mobile",
+		Description:    "针对 Android（Kotlin/Java、Jetpack）应用的审查模板",
+		Content: `你是一位资深的 Android 工程师。请对以下 Android 代码变更进行审查，重点关注：
+
+## 审查维度
+1. **生命周期与内存（25分）**：Context/Activity 泄漏、协程作用域（viewModelScope/lifecycleScope）、配置变更处理
+2. **线程与性能（20分）**：主线程 IO 与网络、Dispatchers 的使用、列表与图片加载性能
+3. **安全（20分）**：导出组件（exported）、权限申请、WebView 配置、明文存储敏感数据、网络安全配置
+4. **架构（20分）**：ViewModel/Repository 分层、状态管理（StateFlow/LiveData）、依赖注入
+5. **兼容性与体验（15分）**：API 级别检查、空安全、无障碍、多语言资源
+
+请针对最重要的3个问题给出建议，并给出评分。
+格式：总分:XX分
+
+**代码变更**：
+{{diffs}}
+
+**提交信息**：
+{{commits}}`,
+		IsBuiltIn: true,
+		IsActive:  true,
+	},
+}
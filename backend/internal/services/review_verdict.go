@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// Human verdicts a maintainer can record on a review
+const (
+	VerdictAccepted = "accepted"
+	VerdictRejected = "rejected"
+)
+
+// ErrVerdictNotAllowed is returned when the user is neither an admin nor a maintainer of the project
+var ErrVerdictNotAllowed = errors.New("only admins and project owners or maintainers can annotate reviews")
+
+// ReviewVerdictRequest records a maintainer's verdict on a review. The AI score is kept;
+// the human score is stored alongside it.
+type ReviewVerdictRequest struct {
+	Verdict string   `json:"verdict" binding:"required,oneof=accepted rejected"`
+	Score   *float64 `json:"score" binding:"omitempty,min=0,max=100"`
+	Reason  string   `json:"reason" binding:"required,max=1000"`
+}
+
+// CanAnnotate reports whether a user may record verdicts on the reviews of a project:
+// platform and tenant admins, and the project's owners and maintainers
+func (s *ReviewLogService) CanAnnotate(projectID, userID uint, role string) bool {
+	if role == "admin" || role == "tenant_admin" {
+		return true
+	}
+	var count int64
+	s.db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ? AND role IN ?", projectID, userID, []string{"owner", "maintainer"}).
+		Count(&count)
+	return count > 0
+}
+
+// SetVerdict records a human verdict on a review, replacing an earlier one
+func (s *ReviewLogService) SetVerdict(id uint, req *ReviewVerdictRequest, username string) (*models.ReviewLog, error) {
+	now := time.Now()
+	err := s.db.Model(&models.ReviewLog{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"human_verdict":        req.Verdict,
+		"human_score":          req.Score,
+		"human_verdict_reason": req.Reason,
+		"human_verdict_by":     username,
+		"human_verdict_at":     &now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// ClearVerdict removes the human verdict of a review, restoring the AI outcome
+func (s *ReviewLogService) ClearVerdict(id uint) (*models.ReviewLog, error) {
+	err := s.db.Model(&models.ReviewLog{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"human_verdict":        "",
+		"human_score":          nil,
+		"human_verdict_reason": "",
+		"human_verdict_by":     "",
+		"human_verdict_at":     nil,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// ReviewPassed reports whether a completed review passes the minimum score. With verdict
+// gating a human verdict overrides the AI score: accepted passes and rejected fails. The
+// human score is never compared to the minimum score, it only feeds statistics.
+func ReviewPassed(log *models.ReviewLog, minScore float64, verdictGating bool) bool {
+	if verdictGating {
+		switch log.HumanVerdict {
+		case VerdictAccepted:
+			return true
+		case VerdictRejected:
+			return false
+		}
+	}
+	return log.Score != nil && *log.Score >= minScore
+}
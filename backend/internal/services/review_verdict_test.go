@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestReviewPassed(t *testing.T) {
+	low, high := 40.0, 85.0
+	tests := []struct {
+		name   string
+		log    models.ReviewLog
+		gating bool
+		want   bool
+	}{
+		{"AI score passes", models.ReviewLog{Score: &high}, true, true},
+		{"AI score fails", models.ReviewLog{Score: &low}, true, false},
+		{"no score", models.ReviewLog{}, true, false},
+		{"accepted overrides a failing score", models.ReviewLog{Score: &low, HumanVerdict: VerdictAccepted}, true, true},
+		{"rejected overrides a passing score", models.ReviewLog{Score: &high, HumanVerdict: VerdictRejected}, true, false},
+		{"human score does not gate", models.ReviewLog{Score: &high, HumanScore: &low, HumanVerdict: VerdictAccepted}, true, true},
+		{"human score does not pass a rejection", models.ReviewLog{Score: &low, HumanScore: &high, HumanVerdict: VerdictRejected}, true, false},
+		{"verdict ignored without gating", models.ReviewLog{Score: &low, HumanVerdict: VerdictAccepted}, false, false},
+		{"rejection ignored without gating", models.ReviewLog{Score: &high, HumanVerdict: VerdictRejected}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReviewPassed(&tt.log, 60, tt.gating); got != tt.want {
+				t.Errorf("ReviewPassed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// riskHalfLife is the age at which a review weighs half as much in the low score rate
+const riskHalfLife = 30 * 24 * time.Hour
+
+// Risk score weights of the low score rate, critical finding density and churn
+const (
+	riskWeightLowScore = 0.45
+	riskWeightCritical = 0.35
+	riskWeightChurn    = 0.2
+)
+
+// criticalFindingCategories are the finding categories counted as critical
+var criticalFindingCategories = map[string]bool{FindingSecurity: true, FindingCorrectness: true}
+
+// RiskHeatmapService ranks the paths of a project by review risk
+type RiskHeatmapService struct {
+	db *gorm.DB
+}
+
+func NewRiskHeatmapService(db *gorm.DB) *RiskHeatmapService {
+	return &RiskHeatmapService{db: db}
+}
+
+type RiskHeatmapRequest struct {
+	Days  int    `form:"days"`
+	Level string `form:"level" binding:"omitempty,oneof=file directory"`
+	Depth int    `form:"depth"` // Directory depth when level=directory
+	Limit int    `form:"limit"`
+}
+
+type RiskHeatmapEntry struct {
+	Path             string  `json:"path"`
+	Reviews          int     `json:"reviews"`
+	LowScoreReviews  int     `json:"low_score_reviews"`
+	AvgScore         float64 `json:"avg_score"`
+	Churn            int     `json:"churn"` // Added plus deleted lines
+	Findings         int     `json:"findings"`
+	CriticalFindings int     `json:"critical_findings"` // Security and correctness findings
+	CriticalDensity  float64 `json:"critical_density"`  // Critical findings per review
+	RiskScore        float64 `json:"risk_score"`        // 0-100
+}
+
+type RiskHeatmapResponse struct {
+	ProjectID uint               `json:"project_id"`
+	Level     string             `json:"level"`
+	Days      int                `json:"days"`
+	MinScore  float64            `json:"min_score"`
+	Entries   []RiskHeatmapEntry `json:"entries"`
+}
+
+// riskFinding is a finding attributed to a changed file
+type riskFinding struct {
+	FilePath string
+	Category string
+}
+
+// GetHeatmap ranks the files or directories changed in the project's recent reviews by
+// recent low scores, critical finding density and churn
+func (s *RiskHeatmapService) GetHeatmap(project *models.Project, req *RiskHeatmapRequest) (*RiskHeatmapResponse, error) {
+	days := req.Days
+	if days <= 0 || days > 365 {
+		days = 90
+	}
+	level := req.Level
+	if level == "" {
+		level = "directory"
+	}
+	depth := req.Depth
+	if depth <= 0 {
+		depth = 2
+	}
+	limit := req.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+
+	var files []models.ReviewFile
+	if err := s.db.Where("project_id = ? AND created_at >= ?", project.ID, since).Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	var reviews []struct {
+		ID    uint
+		Score *float64
+	}
+	err := s.db.Model(&models.ReviewLog{}).
+		Select("id, score").
+		Where("project_id = ? AND created_at >= ? AND score IS NOT NULL", project.ID, since).
+		Scan(&reviews).Error
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[uint]float64, len(reviews))
+	for _, r := range reviews {
+		scores[r.ID] = *r.Score
+	}
+
+	var findings []riskFinding
+	err = s.db.Model(&models.ReviewFinding{}).
+		Select("file_path, category").
+		Where("project_id = ? AND created_at >= ? AND file_path <> ''", project.ID, since).
+		Scan(&findings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	minScore := NewReviewLogService(s.db).EffectiveMinScore(project)
+	keyOf := func(p string) string { return p }
+	if level == "directory" {
+		keyOf = func(p string) string { return riskDirectory(p, depth) }
+	}
+
+	entries := buildRiskHeatmap(files, scores, findings, minScore, keyOf, now)
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return &RiskHeatmapResponse{
+		ProjectID: project.ID,
+		Level:     level,
+		Days:      days,
+		MinScore:  minScore,
+		Entries:   entries,
+	}, nil
+}
+
+// riskDirectory returns the directory of a file truncated to depth levels, "." for root files
+func riskDirectory(file string, depth int) string {
+	parts := strings.Split(file, "/")
+	parts = parts[:len(parts)-1]
+	if len(parts) == 0 {
+		return "."
+	}
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// buildRiskHeatmap aggregates changed files, review scores and findings per key and ranks
+// the keys by risk score. Reviews touching several files of a key count once.
+func buildRiskHeatmap(files []models.ReviewFile, scores map[uint]float64, findings []riskFinding, minScore float64, keyOf func(string) string, now time.Time) []RiskHeatmapEntry {
+	type accumulator struct {
+		entry     *RiskHeatmapEntry
+		reviews   map[uint]bool
+		scored    int
+		scoreSum  float64
+		weight    float64 // Sum of recency weights of scored reviews
+		weightLow float64 // Sum of recency weights of low score reviews
+	}
+
+	byKey := make(map[string]*accumulator)
+	get := func(key string) *accumulator {
+		acc, ok := byKey[key]
+		if !ok {
+			acc = &accumulator{entry: &RiskHeatmapEntry{Path: key}, reviews: make(map[uint]bool)}
+			byKey[key] = acc
+		}
+		return acc
+	}
+
+	for _, f := range files {
+		acc := get(keyOf(f.Path))
+		acc.entry.Churn += f.Additions + f.Deletions
+		if acc.reviews[f.ReviewLogID] {
+			continue
+		}
+		acc.reviews[f.ReviewLogID] = true
+		acc.entry.Reviews++
+
+		score, ok := scores[f.ReviewLogID]
+		if !ok {
+			continue
+		}
+		w := math.Pow(0.5, float64(now.Sub(f.CreatedAt))/float64(riskHalfLife))
+		acc.scored++
+		acc.scoreSum += score
+		acc.weight += w
+		if score < minScore {
+			acc.entry.LowScoreReviews++
+			acc.weightLow += w
+		}
+	}
+
+	for _, f := range findings {
+		acc, ok := byKey[keyOf(f.FilePath)]
+		if !ok {
+			continue
+		}
+		acc.entry.Findings++
+		if criticalFindingCategories[f.Category] {
+			acc.entry.CriticalFindings++
+		}
+	}
+
+	maxChurn := 0
+	for _, acc := range byKey {
+		if acc.entry.Churn > maxChurn {
+			maxChurn = acc.entry.Churn
+		}
+	}
+
+	entries := make([]RiskHeatmapEntry, 0, len(byKey))
+	for _, acc := range byKey {
+		e := acc.entry
+		var lowRate, churn float64
+		if acc.scored > 0 {
+			e.AvgScore = acc.scoreSum / float64(acc.scored)
+		}
+		if acc.weight > 0 {
+			lowRate = acc.weightLow / acc.weight
+		}
+		if e.Reviews > 0 {
+			e.CriticalDensity = float64(e.CriticalFindings) / float64(e.Reviews)
+		}
+		if maxChurn > 0 {
+			churn = float64(e.Churn) / float64(maxChurn)
+		}
+		risk := riskWeightLowScore*lowRate + riskWeightCritical*math.Min(1, e.CriticalDensity) + riskWeightChurn*churn
+		e.RiskScore = math.Round(risk*1000) / 10
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].RiskScore != entries[j].RiskScore {
+			return entries[i].RiskScore > entries[j].RiskScore
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
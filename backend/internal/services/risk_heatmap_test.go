@@ -0,0 +1,92 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestRiskDirectory(t *testing.T) {
+	tests := []struct {
+		file  string
+		depth int
+		want  string
+	}{
+		{"main.go", 2, "."},
+		{"internal/services/ai.go", 2, "internal/services"},
+		{"internal/services/webhook/service.go", 2, "internal/services"},
+		{"internal/services/webhook/service.go", 1, "internal"},
+		{"cmd/main.go", 3, "cmd"},
+	}
+
+	for _, tt := range tests {
+		if got := riskDirectory(tt.file, tt.depth); got != tt.want {
+			t.Errorf("riskDirectory(%q, %d) = %q, want %q", tt.file, tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestBuildRiskHeatmap(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	recent := now.Add(-24 * time.Hour)
+	old := now.AddDate(0, 0, -120)
+
+	files := []models.ReviewFile{
+		{ReviewLogID: 1, Path: "auth/login.go", Additions: 40, Deletions: 10, CreatedAt: recent},
+		{ReviewLogID: 1, Path: "auth/session.go", Additions: 5, CreatedAt: recent},
+		{ReviewLogID: 2, Path: "auth/login.go", Additions: 20, CreatedAt: old},
+		{ReviewLogID: 3, Path: "docs/guide.go", Additions: 10, CreatedAt: recent},
+		{ReviewLogID: 4, Path: "util/strings.go", Additions: 100, CreatedAt: old},
+	}
+	scores := map[uint]float64{1: 40, 2: 90, 3: 95, 4: 50}
+	findings := []riskFinding{
+		{FilePath: "auth/login.go", Category: FindingSecurity},
+		{FilePath: "auth/session.go", Category: FindingStyle},
+		{FilePath: "unknown/file.go", Category: FindingSecurity},
+	}
+
+	byFile := buildRiskHeatmap(files, scores, findings, 60, func(p string) string { return p }, now)
+	if len(byFile) != 4 || byFile[0].Path != "auth/login.go" {
+		t.Fatalf("expected auth/login.go to rank first, got %+v", byFile)
+	}
+	login := byFile[0]
+	if login.Reviews != 2 || login.LowScoreReviews != 1 || login.Churn != 70 || login.AvgScore != 65 {
+		t.Errorf("login = %+v", login)
+	}
+	if login.CriticalFindings != 1 || login.CriticalDensity != 0.5 {
+		t.Errorf("login findings = %+v", login)
+	}
+	// The recent low score outweighs the old passing review
+	if login.RiskScore < 50 {
+		t.Errorf("login risk score = %v", login.RiskScore)
+	}
+
+	byDir := buildRiskHeatmap(files, scores, findings, 60, func(p string) string { return riskDirectory(p, 1) }, now)
+	if byDir[0].Path != "auth" || byDir[0].Reviews != 2 || byDir[0].Findings != 2 || byDir[0].Churn != 75 {
+		t.Errorf("auth directory = %+v", byDir[0])
+	}
+	last := byDir[len(byDir)-1]
+	if last.Path != "docs" || last.LowScoreReviews != 0 || last.Findings != 0 {
+		t.Errorf("expected docs to rank last, got %+v", last)
+	}
+}
+
+func TestMentionedFile(t *testing.T) {
+	files := []string{"internal/api/user.go", "pkg/user.go", "internal/db/query.go"}
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"See `pkg/user.go` line 10", "pkg/user.go"},
+		{"query.go builds SQL from input", "internal/db/query.go"},
+		{"user.go is ambiguous", ""},
+		{"no file mentioned", ""},
+	}
+
+	for _, tt := range tests {
+		if got := mentionedFile(tt.text, files); got != tt.want {
+			t.Errorf("mentionedFile(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// Saved dashboard metric keys
+const (
+	MetricStats             = "stats"
+	MetricTrends            = "trends"
+	MetricCompare           = "compare"
+	MetricFindingTrends     = "finding_trends"
+	MetricFindingAuthors    = "finding_authors"
+	MetricRecurringFindings = "recurring_findings"
+	MetricScorecards        = "scorecards"
+)
+
+// DashboardMetrics lists the metrics saved dashboards can compute
+var DashboardMetrics = []string{MetricStats, MetricTrends, MetricCompare, MetricFindingTrends, MetricFindingAuthors, MetricRecurringFindings, MetricScorecards}
+
+// ErrDashboardForbidden is returned when a user changes a dashboard they do not own
+var ErrDashboardForbidden = errors.New("only the owner can change this dashboard")
+
+type SavedDashboardService struct {
+	db *gorm.DB
+}
+
+func NewSavedDashboardService(db *gorm.DB) *SavedDashboardService {
+	return &SavedDashboardService{db: db}
+}
+
+// DashboardViewer identifies the user accessing saved dashboards
+type DashboardViewer struct {
+	UserID   uint
+	IsAdmin  bool
+	TenantID uint // 0 = all tenants
+}
+
+type CreateSavedDashboardRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=500"`
+	Shared      bool   `json:"shared"`
+	ProjectIDs  string `json:"project_ids"`
+	RangeDays   int    `json:"range_days" binding:"omitempty,min=1,max=730"`
+	StartDate   string `json:"start_date"`
+	EndDate     string `json:"end_date"`
+	Metrics     string `json:"metrics" binding:"required"`
+	Granularity string `json:"granularity" binding:"omitempty,oneof=day week month"`
+	TenantID    uint   `json:"-"`
+	UserID      uint   `json:"-"`
+}
+
+type UpdateSavedDashboardRequest struct {
+	Name        string  `json:"name" binding:"max=100"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	Shared      *bool   `json:"shared"`
+	ProjectIDs  *string `json:"project_ids"`
+	RangeDays   *int    `json:"range_days" binding:"omitempty,min=1,max=730"`
+	StartDate   *string `json:"start_date"`
+	EndDate     *string `json:"end_date"`
+	Metrics     *string `json:"metrics"`
+	Granularity *string `json:"granularity" binding:"omitempty,oneof=day week month"`
+}
+
+type DashboardDataRequest struct {
+	StartDate string `form:"start_date"` // Overrides the saved date range
+	EndDate   string `form:"end_date"`
+}
+
+type DashboardDataResponse struct {
+	Dashboard *models.SavedDashboard `json:"dashboard"`
+	StartDate string                 `json:"start_date"`
+	EndDate   string                 `json:"end_date"`
+	Metrics   map[string]interface{} `json:"metrics"`
+	Errors    map[string]string      `json:"errors,omitempty"` // Metrics that failed to compute
+}
+
+// ValidateDashboardMetrics checks comma-separated metric keys and returns them normalized
+func ValidateDashboardMetrics(metrics string) (string, error) {
+	keys := splitAndTrim(metrics, ",")
+	if len(keys) == 0 {
+		return "", fmt.Errorf("at least one metric is required")
+	}
+	seen := make(map[string]bool, len(keys))
+	var normalized []string
+	for _, key := range keys {
+		known := false
+		for _, m := range DashboardMetrics {
+			known = known || m == key
+		}
+		if !known {
+			return "", fmt.Errorf("unknown metric %q, expected one of %s", key, strings.Join(DashboardMetrics, ", "))
+		}
+		if !seen[key] {
+			seen[key] = true
+			normalized = append(normalized, key)
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// normalizeDashboardProjectIDs checks comma-separated project IDs and returns them normalized
+func normalizeDashboardProjectIDs(projectIDs string) (string, error) {
+	var ids []string
+	for _, s := range splitAndTrim(projectIDs, ",") {
+		if id, err := strconv.ParseUint(s, 10, 32); err != nil || id == 0 {
+			return "", fmt.Errorf("invalid project id %q", s)
+		}
+		ids = append(ids, s)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// checkProjectIDs normalizes the project IDs of a dashboard and rejects projects outside its tenant
+func (s *SavedDashboardService) checkProjectIDs(projectIDs string, tenantID uint) (string, error) {
+	normalized, err := normalizeDashboardProjectIDs(projectIDs)
+	if err != nil || normalized == "" {
+		return normalized, err
+	}
+	ids := parseProjectIDs(0, normalized)
+	var count int64
+	if err := ScopeTenant(s.db.Model(&models.Project{}), tenantID).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count != int64(len(ids)) {
+		return "", fmt.Errorf("unknown project id in %q", normalized)
+	}
+	return normalized, nil
+}
+
+func validateDashboardDates(start, end string) error {
+	for _, date := range []string{start, end} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if start == "" && end != "" {
+		return fmt.Errorf("end_date requires start_date")
+	}
+	if start != "" && end != "" && end < start {
+		return fmt.Errorf("end_date is before start_date")
+	}
+	return nil
+}
+
+// scopeVisible limits a query to the dashboards a viewer owns or that are shared in their tenant
+func (s *SavedDashboardService) scopeVisible(viewer *DashboardViewer) *gorm.DB {
+	return ScopeTenant(s.db.Model(&models.SavedDashboard{}), viewer.TenantID).
+		Where("user_id = ? OR shared = ?", viewer.UserID, true)
+}
+
+// List returns the dashboards visible to the viewer
+func (s *SavedDashboardService) List(viewer *DashboardViewer) ([]models.SavedDashboard, error) {
+	var dashboards []models.SavedDashboard
+	if err := s.scopeVisible(viewer).Order("name ASC").Find(&dashboards).Error; err != nil {
+		return nil, err
+	}
+	return dashboards, nil
+}
+
+// Get returns a dashboard visible to the viewer
+func (s *SavedDashboardService) Get(id uint, viewer *DashboardViewer) (*models.SavedDashboard, error) {
+	var dashboard models.SavedDashboard
+	if err := s.scopeVisible(viewer).Where("id = ?", id).First(&dashboard).Error; err != nil {
+		return nil, err
+	}
+	return &dashboard, nil
+}
+
+// getOwned returns a dashboard the viewer may change: their own, or any visible one for admins
+func (s *SavedDashboardService) getOwned(id uint, viewer *DashboardViewer) (*models.SavedDashboard, error) {
+	dashboard, err := s.Get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if dashboard.UserID != viewer.UserID && !viewer.IsAdmin {
+		return nil, ErrDashboardForbidden
+	}
+	return dashboard, nil
+}
+
+// Create saves a new dashboard
+func (s *SavedDashboardService) Create(req *CreateSavedDashboardRequest) (*models.SavedDashboard, error) {
+	metrics, err := ValidateDashboardMetrics(req.Metrics)
+	if err != nil {
+		return nil, err
+	}
+	projectIDs, err := s.checkProjectIDs(req.ProjectIDs, req.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDashboardDates(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	if req.RangeDays == 0 {
+		req.RangeDays = 30
+	}
+	if req.Granularity == "" {
+		req.Granularity = "day"
+	}
+
+	dashboard := models.SavedDashboard{
+		Name:        req.Name,
+		Description: req.Description,
+		UserID:      req.UserID,
+		Shared:      req.Shared,
+		ProjectIDs:  projectIDs,
+		RangeDays:   req.RangeDays,
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		Metrics:     metrics,
+		Granularity: req.Granularity,
+		TenantID:    req.TenantID,
+	}
+	if err := s.db.Create(&dashboard).Error; err != nil {
+		return nil, err
+	}
+	return &dashboard, nil
+}
+
+// Update changes a dashboard owned by the viewer
+func (s *SavedDashboardService) Update(id uint, viewer *DashboardViewer, req *UpdateSavedDashboardRequest) (*models.SavedDashboard, error) {
+	dashboard, err := s.getOwned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Shared != nil {
+		updates["shared"] = *req.Shared
+	}
+	if req.ProjectIDs != nil {
+		projectIDs, err := s.checkProjectIDs(*req.ProjectIDs, dashboard.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		updates["project_ids"] = projectIDs
+	}
+	if req.RangeDays != nil {
+		updates["range_days"] = *req.RangeDays
+	}
+	start, end := dashboard.StartDate, dashboard.EndDate
+	if req.StartDate != nil {
+		start = *req.StartDate
+		updates["start_date"] = start
+	}
+	if req.EndDate != nil {
+		end = *req.EndDate
+		updates["end_date"] = end
+	}
+	if err := validateDashboardDates(start, end); err != nil {
+		return nil, err
+	}
+	if req.Metrics != nil {
+		metrics, err := ValidateDashboardMetrics(*req.Metrics)
+		if err != nil {
+			return nil, err
+		}
+		updates["metrics"] = metrics
+	}
+	if req.Granularity != nil {
+		updates["granularity"] = *req.Granularity
+	}
+
+	if err := s.db.Model(dashboard).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.Get(id, viewer)
+}
+
+// Delete removes a dashboard owned by the viewer
+func (s *SavedDashboardService) Delete(id uint, viewer *DashboardViewer) error {
+	dashboard, err := s.getOwned(id, viewer)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(dashboard).Error
+}
+
+// dashboardDateRange resolves the dates a dashboard covers: the override, the fixed
+// range, or the last RangeDays days
+func dashboardDateRange(d *models.SavedDashboard, req *DashboardDataRequest, now time.Time) (string, string) {
+	if req != nil && req.StartDate != "" {
+		end := req.EndDate
+		if end == "" {
+			end = now.Format("2006-01-02")
+		}
+		return req.StartDate, end
+	}
+	if d.StartDate != "" {
+		end := d.EndDate
+		if end == "" {
+			end = now.Format("2006-01-02")
+		}
+		return d.StartDate, end
+	}
+	days := d.RangeDays
+	if days <= 0 {
+		days = 30
+	}
+	return now.AddDate(0, 0, -(days - 1)).Format("2006-01-02"), now.Format("2006-01-02")
+}
+
+// GetData computes the selected metrics of a dashboard visible to the viewer.
+// A failing metric is reported in Errors without failing the others.
+func (s *SavedDashboardService) GetData(id uint, viewer *DashboardViewer, req *DashboardDataRequest) (*DashboardDataResponse, error) {
+	dashboard, err := s.Get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDashboardDates(req.StartDate, req.EndDate); err != nil {
+		return nil, err
+	}
+	start, end := dashboardDateRange(dashboard, req, time.Now())
+
+	var aliaser *AuthorAliaser
+	if !viewer.IsAdmin {
+		aliaser = NewSystemConfigService(s.db).GetAuthorAliaser()
+	}
+
+	resp := &DashboardDataResponse{
+		Dashboard: dashboard,
+		StartDate: start,
+		EndDate:   end,
+		Metrics:   make(map[string]interface{}),
+	}
+	for _, metric := range splitAndTrim(dashboard.Metrics, ",") {
+		value, err := s.computeMetric(metric, dashboard, start, end)
+		if err != nil {
+			if resp.Errors == nil {
+				resp.Errors = make(map[string]string)
+			}
+			resp.Errors[metric] = err.Error()
+			continue
+		}
+		if aliaser != nil {
+			aliaser.Apply(value)
+		}
+		resp.Metrics[metric] = value
+	}
+	return resp, nil
+}
+
+func (s *SavedDashboardService) computeMetric(metric string, d *models.SavedDashboard, start, end string) (interface{}, error) {
+	trendGranularity := "day"
+	findingPeriod := "month"
+	if d.Granularity == "week" || d.Granularity == "month" {
+		trendGranularity = "week"
+	}
+	if d.Granularity == "week" || d.Granularity == "day" {
+		findingPeriod = "week"
+	}
+	comparePeriod := "week"
+	if d.Granularity == "month" {
+		comparePeriod = "month"
+	}
+
+	switch metric {
+	case MetricStats:
+		return NewDashboardService(s.db).GetStats(&DashboardStatsRequest{StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, TenantID: d.TenantID})
+	case MetricTrends:
+		return NewDashboardService(s.db).GetTrends(&DashboardTrendRequest{
+			StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, Granularity: trendGranularity, TenantID: d.TenantID,
+		})
+	case MetricCompare:
+		return NewDashboardService(s.db).Compare(&DashboardCompareRequest{Period: comparePeriod, ProjectIDs: d.ProjectIDs, TenantID: d.TenantID})
+	case MetricFindingTrends:
+		return NewReviewFindingService(s.db).GetTrends(&FindingTrendRequest{
+			StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, Period: findingPeriod, TenantID: d.TenantID,
+		})
+	case MetricFindingAuthors:
+		return NewReviewFindingService(s.db).GetByAuthor(&FindingAuthorRequest{StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, TenantID: d.TenantID})
+	case MetricRecurringFindings:
+		return NewReviewFindingService(s.db).GetRecurring(&FindingRecurringRequest{StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, TenantID: d.TenantID})
+	case MetricScorecards:
+		return NewMemberService(s.db).GetScorecards(&MemberScorecardRequest{StartDate: start, EndDate: end, ProjectIDs: d.ProjectIDs, TenantID: d.TenantID})
+	}
+	return nil, fmt.Errorf("unknown metric %q", metric)
+}
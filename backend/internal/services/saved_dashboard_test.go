@@ -0,0 +1,120 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestValidateDashboardMetrics(t *testing.T) {
+	tests := []struct {
+		metrics string
+		want    string
+		wantErr bool
+	}{
+		{"trends", "trends", false},
+		{" trends , scorecards,trends", "trends,scorecards", false},
+		{"", "", true},
+		{"trends,velocity", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ValidateDashboardMetrics(tt.metrics)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ValidateDashboardMetrics(%q) = %q, %v; want %q, error %v", tt.metrics, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNormalizeDashboardProjectIDs(t *testing.T) {
+	if got, err := normalizeDashboardProjectIDs(" 1, 2 ,,3"); err != nil || got != "1,2,3" {
+		t.Errorf("normalizeDashboardProjectIDs() = %q, %v", got, err)
+	}
+	for _, invalid := range []string{"1,a", "0", "-1"} {
+		if _, err := normalizeDashboardProjectIDs(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestValidateDashboardDates(t *testing.T) {
+	tests := []struct {
+		start, end string
+		wantErr    bool
+	}{
+		{"", "", false},
+		{"2024-01-01", "", false},
+		{"2024-01-01", "2024-01-31", false},
+		{"", "2024-01-31", true},
+		{"2024-02-01", "2024-01-31", true},
+		{"01/02/2024", "", true},
+	}
+
+	for _, tt := range tests {
+		if err := validateDashboardDates(tt.start, tt.end); (err != nil) != tt.wantErr {
+			t.Errorf("validateDashboardDates(%q, %q) error = %v, wantErr %v", tt.start, tt.end, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDashboardDateRange(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		dashboard  models.SavedDashboard
+		req        *DashboardDataRequest
+		start, end string
+	}{
+		{"relative", models.SavedDashboard{RangeDays: 7}, nil, "2024-03-04", "2024-03-10"},
+		{"default range", models.SavedDashboard{}, nil, "2024-02-10", "2024-03-10"},
+		{"fixed", models.SavedDashboard{StartDate: "2024-01-01", EndDate: "2024-01-31"}, nil, "2024-01-01", "2024-01-31"},
+		{"open fixed", models.SavedDashboard{StartDate: "2024-03-01"}, nil, "2024-03-01", "2024-03-10"},
+		{"override", models.SavedDashboard{RangeDays: 7}, &DashboardDataRequest{StartDate: "2023-12-01", EndDate: "2023-12-31"}, "2023-12-01", "2023-12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := dashboardDateRange(&tt.dashboard, tt.req, now)
+			if start != tt.start || end != tt.end {
+				t.Errorf("dashboardDateRange() = %s..%s, want %s..%s", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestSavedDashboard_ProjectScope(t *testing.T) {
+	f := seedTenants(t)
+	other := &models.Project{Name: "alice-other", URL: "https://git.example.com/alice-other", Platform: "gitlab", TenantID: 1}
+	mustCreate(t, f.db, other)
+	mustCreate(t, f.db, &models.ReviewLog{ProjectID: other.ID, EventType: "push", Author: "carol", ReviewStatus: "completed"})
+	service := NewSavedDashboardService(f.db)
+
+	foreign := strconv.FormatUint(uint64(f.projects[2].ID), 10)
+	if _, err := service.Create(&CreateSavedDashboardRequest{Name: "foreign", Metrics: MetricStats, ProjectIDs: foreign, TenantID: 1}); err == nil {
+		t.Error("Create with a project of another tenant should fail")
+	}
+
+	own := strconv.FormatUint(uint64(f.projects[1].ID), 10)
+	dashboard, err := service.Create(&CreateSavedDashboardRequest{Name: "own", Metrics: MetricStats, ProjectIDs: own, TenantID: 1, UserID: 1})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	viewer := &DashboardViewer{UserID: 1, TenantID: 1}
+	if _, err := service.Update(dashboard.ID, viewer, &UpdateSavedDashboardRequest{ProjectIDs: &foreign}); err == nil {
+		t.Error("Update with a project of another tenant should fail")
+	}
+
+	data, err := service.GetData(dashboard.ID, viewer, &DashboardDataRequest{})
+	if err != nil {
+		t.Fatalf("GetData: %v", err)
+	}
+	stats, ok := data.Metrics[MetricStats].(*DashboardResponse)
+	if !ok {
+		t.Fatalf("stats metric = %#v (errors %v)", data.Metrics[MetricStats], data.Errors)
+	}
+	if stats.Stats.TotalCommits != 2 || stats.Stats.ActiveProjects != 1 {
+		t.Errorf("stats of a dashboard limited to one project = %+v, want only its 2 reviews", stats.Stats)
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// SchedulerStatus describes a background scheduler of this instance
+type SchedulerStatus struct {
+	Name           string     `json:"name"`
+	Interval       string     `json:"interval,omitempty"` // Empty for cron schedules
+	StartedAt      time.Time  `json:"started_at"`
+	Running        bool       `json:"running"` // A run is in progress
+	Runs           int64      `json:"runs"`
+	LastRunAt      *time.Time `json:"last_run_at"`
+	LastDurationMs int64      `json:"last_duration_ms"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextRunAt      *time.Time `json:"next_run_at"`
+}
+
+type schedulerEntry struct {
+	status   SchedulerStatus
+	interval time.Duration
+	next     func() time.Time // Cron schedules
+}
+
+// nextRun returns when the scheduler runs next: the cron schedule's next time, or an
+// interval after the last run (after the start before the first run)
+func (e *schedulerEntry) nextRun() *time.Time {
+	var next time.Time
+	switch {
+	case e.next != nil:
+		next = e.next()
+	case e.interval > 0 && e.status.LastRunAt != nil:
+		next = e.status.LastRunAt.Add(e.interval)
+	case e.interval > 0:
+		next = e.status.StartedAt.Add(e.interval)
+	}
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// schedulerRegistry tracks the runs of the background schedulers for the ops endpoints
+type schedulerRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*schedulerEntry
+	now     func() time.Time
+}
+
+var schedulers = &schedulerRegistry{entries: make(map[string]*schedulerEntry), now: time.Now}
+
+// register adds a scheduler, or updates the schedule of a registered one keeping its runs
+func (r *schedulerRegistry) register(name string, interval time.Duration, next func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.entries[name]
+	if entry == nil {
+		entry = &schedulerEntry{status: SchedulerStatus{Name: name, StartedAt: r.now()}}
+		r.entries[name] = entry
+	}
+	entry.interval, entry.next = interval, next
+	entry.status.Interval = ""
+	if interval > 0 {
+		entry.status.Interval = interval.String()
+	}
+}
+
+func (r *schedulerRegistry) run(name string, run func() error) {
+	r.mu.Lock()
+	entry := r.entries[name]
+	if entry == nil {
+		entry = &schedulerEntry{status: SchedulerStatus{Name: name, StartedAt: r.now()}}
+		r.entries[name] = entry
+	}
+	start := r.now()
+	entry.status.Running = true
+	r.mu.Unlock()
+
+	err := run()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry.status.Running = false
+	entry.status.Runs++
+	entry.status.LastRunAt = &start
+	entry.status.LastDurationMs = r.now().Sub(start).Milliseconds()
+	entry.status.LastError = ""
+	if err != nil {
+		entry.status.LastError = err.Error()
+	}
+}
+
+func (r *schedulerRegistry) statuses() []SchedulerStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]SchedulerStatus, 0, len(r.entries))
+	for _, entry := range r.entries {
+		status := entry.status
+		status.NextRunAt = entry.nextRun()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// RegisterScheduler records a scheduler running every interval
+func RegisterScheduler(name string, interval time.Duration) {
+	schedulers.register(name, interval, nil)
+}
+
+// RegisterCronScheduler records a scheduler on a cron schedule, next returning its next run
+func RegisterCronScheduler(name string, next func() time.Time) {
+	schedulers.register(name, 0, next)
+}
+
+// RunScheduled runs a scheduled job, recording its outcome
+func RunScheduled(name string, run func() error) {
+	schedulers.run(name, run)
+}
+
+// SchedulerStatuses returns the schedulers of this instance by name
+func SchedulerStatuses() []SchedulerStatus {
+	return schedulers.statuses()
+}
+
+// SchedulerLockStatus is a lock held on a scheduled job across instances
+type SchedulerLockStatus struct {
+	models.SchedulerLock
+	Expired bool `json:"expired"` // Taken over by the next instance acquiring it
+}
+
+// SchedulerLocks returns the scheduler locks, newest first
+func SchedulerLocks(db *gorm.DB) ([]SchedulerLockStatus, error) {
+	var locks []models.SchedulerLock
+	if err := db.Order("locked_at DESC").Limit(100).Find(&locks).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	statuses := make([]SchedulerLockStatus, len(locks))
+	for i, lock := range locks {
+		statuses[i] = SchedulerLockStatus{SchedulerLock: lock, Expired: lock.ExpiresAt.Before(now)}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRegistry(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	registry := &schedulerRegistry{entries: make(map[string]*schedulerEntry), now: func() time.Time { return now }}
+	cronNext := start.Add(3 * time.Hour)
+
+	registry.register("retry", 5*time.Minute, nil)
+	registry.register("daily_report", 0, func() time.Time { return cronNext })
+	registry.register("idle", 0, nil)
+
+	tests := []struct {
+		name      string
+		run       func()
+		scheduler string
+		wantRuns  int64
+		wantNext  *time.Time
+		wantError string
+	}{
+		{
+			name:      "interval before the first run",
+			scheduler: "retry",
+			wantNext:  ptrTime(start.Add(5 * time.Minute)),
+		},
+		{
+			name: "interval after a failed run",
+			run: func() {
+				now = start.Add(10 * time.Minute)
+				registry.run("retry", func() error {
+					now = now.Add(2 * time.Second)
+					return errors.New("boom")
+				})
+			},
+			scheduler: "retry",
+			wantRuns:  1,
+			wantNext:  ptrTime(start.Add(15 * time.Minute)),
+			wantError: "boom",
+		},
+		{
+			name:      "successful run clears the error",
+			run:       func() { registry.run("retry", func() error { return nil }) },
+			scheduler: "retry",
+			wantRuns:  2,
+			wantNext:  ptrTime(start.Add(10*time.Minute + 2*time.Second + 5*time.Minute)),
+		},
+		{
+			name: "re-register keeps the runs",
+			run: func() {
+				registry.register("retry", 5*time.Minute, nil)
+			},
+			scheduler: "retry",
+			wantRuns:  2,
+			wantNext:  ptrTime(start.Add(10*time.Minute + 2*time.Second + 5*time.Minute)),
+		},
+		{
+			name:      "cron schedule",
+			scheduler: "daily_report",
+			wantNext:  &cronNext,
+		},
+		{
+			name:      "no schedule",
+			scheduler: "idle",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.run != nil {
+				tt.run()
+			}
+			var status *SchedulerStatus
+			statuses := registry.statuses()
+			for i := range statuses {
+				if statuses[i].Name == tt.scheduler {
+					status = &statuses[i]
+				}
+			}
+			if status == nil {
+				t.Fatalf("scheduler %s not listed", tt.scheduler)
+			}
+			if status.Runs != tt.wantRuns || status.LastError != tt.wantError || status.Running {
+				t.Errorf("status = %+v, expected %d runs and error %q", status, tt.wantRuns, tt.wantError)
+			}
+			if (status.NextRunAt == nil) != (tt.wantNext == nil) || (tt.wantNext != nil && !status.NextRunAt.Equal(*tt.wantNext)) {
+				t.Errorf("NextRunAt = %v, expected %v", status.NextRunAt, tt.wantNext)
+			}
+		})
+	}
+
+	if statuses := registry.statuses(); statuses[0].Name != "daily_report" || statuses[2].Name != "retry" {
+		t.Errorf("statuses are not sorted by name: %v", statuses)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,454 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+const (
+	// SecretLeaseRenewInterval is how often Vault leases and the Vault token are checked for renewal
+	SecretLeaseRenewInterval = time.Minute
+	// vaultRequestTimeout bounds a Vault API call
+	vaultRequestTimeout = 10 * time.Second
+	// vaultDefaultField is the field of a vault: reference without #field
+	vaultDefaultField = "value"
+)
+
+// secretHeaders are the authentication headers whose secret references are resolved when
+// an outbound request is sent, alone or after a scheme such as "Bearer "
+var secretHeaders = []string{"Authorization", "PRIVATE-TOKEN", "X-Api-Key", "X-Goog-Api-Key", "Api-Key"}
+
+// vaultSecrets reads the vault: references; nil when Vault is not configured
+var vaultSecrets *vaultClient
+
+// InitSecrets configures Vault for the vault: secret references. Call it at startup, after
+// the HTTP clients are built.
+func InitSecrets(cfg *config.VaultConfig) {
+	if cfg.Address == "" {
+		vaultSecrets = nil
+		return
+	}
+	vaultSecrets = newVaultClient(cfg, OutboundHTTPClient(vaultRequestTimeout))
+	logger.Infof("[Secrets] Vault secrets read from %s", cfg.Address)
+}
+
+// ResolveSecret returns the secret a token or key field refers to: the environment variable
+// of env:NAME, the Vault secret field of vault:path#field (field defaults to "value"), or
+// the value itself when it is not a reference
+func ResolveSecret(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, models.SecretRefEnv):
+		name := strings.TrimPrefix(value, models.SecretRefEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("secret %s: environment variable %s is not set", value, name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, models.SecretRefVault):
+		if vaultSecrets == nil {
+			return "", fmt.Errorf("secret %s: Vault is not configured", value)
+		}
+		return vaultSecrets.resolve(ctx, strings.TrimPrefix(value, models.SecretRefVault))
+	}
+	return value, nil
+}
+
+// ErrSecretRefNotAllowed is returned when someone other than a platform admin sets a secret
+// reference, or changes where a stored one is sent
+var ErrSecretRefNotAllowed = errors.New("env: and vault: secret references can only be set by platform admins")
+
+// CheckSecretRefs rejects secret references in fields written by someone other than a
+// platform admin. Tenants could otherwise reference a server secret, such as env:JWT_SECRET,
+// and point the project or bot at a host they control to receive it.
+func CheckSecretRefs(platformAdmin bool, values ...string) error {
+	if platformAdmin {
+		return nil
+	}
+	for _, value := range values {
+		if models.IsSecretRef(value) {
+			return ErrSecretRefNotAllowed
+		}
+	}
+	return nil
+}
+
+// CheckSecretRefUpdate is CheckSecretRefs for an update, which may send a stored reference
+// back unchanged
+func CheckSecretRefUpdate(platformAdmin bool, value, stored string) error {
+	if value == stored {
+		return nil
+	}
+	return CheckSecretRefs(platformAdmin, value)
+}
+
+// CheckSecretRefTarget rejects changing the URL a stored secret reference is sent to by
+// someone other than a platform admin; an empty new URL leaves it unchanged
+func CheckSecretRefTarget(platformAdmin bool, oldURL, newURL string, storedSecrets ...string) error {
+	if platformAdmin || newURL == "" || newURL == oldURL {
+		return nil
+	}
+	for _, secret := range storedSecrets {
+		if models.IsSecretRef(secret) {
+			return ErrSecretRefNotAllowed
+		}
+	}
+	return nil
+}
+
+// VerifyWithSecret checks a request against a webhook secret or API key that may be a
+// secret reference: unset secrets accept every request, unresolvable ones none
+func VerifyWithSecret(ctx context.Context, secret string, verify func(secret string) bool) bool {
+	if secret == "" {
+		return true
+	}
+	resolved, err := ResolveSecret(ctx, secret)
+	if err != nil {
+		logger.Module("secrets").Warn().Err(err).Msg("Failed to resolve secret, request rejected")
+		return false
+	}
+	return verify(resolved)
+}
+
+// withSecretRefs resolves the secret references of the authentication headers of each
+// request, so services can pass tokens stored as references along unchanged
+func withSecretRefs(next http.RoundTripper) http.RoundTripper {
+	return &secretRefTransport{next: next}
+}
+
+type secretRefTransport struct {
+	next http.RoundTripper
+}
+
+func (t *secretRefTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resolved := req
+	for _, name := range secretHeaders {
+		value := req.Header.Get(name)
+		scheme, token := "", value
+		if i := strings.IndexByte(value, ' '); i > 0 {
+			scheme, token = value[:i+1], value[i+1:]
+		}
+		if !models.IsSecretRef(token) {
+			continue
+		}
+		secret, err := ResolveSecret(req.Context(), token)
+		if err != nil {
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		if resolved == req {
+			resolved = req.Clone(req.Context())
+		}
+		resolved.Header.Set(name, scheme+secret)
+	}
+	return t.next.RoundTrip(resolved)
+}
+
+// vaultSecret is a secret read from Vault and the lease it was issued with
+type vaultSecret struct {
+	data          map[string]string
+	leaseID       string
+	leaseDuration time.Duration
+	renewable     bool
+	expiresAt     time.Time
+}
+
+// vaultClient reads secrets from the Vault HTTP API, caching them until their lease ends
+// (cache_ttl for secrets without a lease) and renewing renewable leases and the token
+type vaultClient struct {
+	address    string
+	token      string
+	namespace  string
+	kvV2Mounts []string
+	cacheTTL   time.Duration
+	client     *http.Client
+	now        func() time.Time
+
+	mu             sync.Mutex
+	cache          map[string]*vaultSecret // By path
+	tokenTTL       time.Duration           // 0: not looked up yet or never expires
+	tokenExpiresAt time.Time
+	tokenRenewable bool
+	tokenLooked    bool
+}
+
+func newVaultClient(cfg *config.VaultConfig, client *http.Client) *vaultClient {
+	mounts := cfg.KVv2Mounts
+	if len(mounts) == 0 {
+		mounts = []string{"secret", "kv"}
+	}
+	cacheTTL := cfg.CacheTTL
+	if cacheTTL <= 0 {
+		cacheTTL = config.DefaultVaultCacheTTL
+	}
+	return &vaultClient{
+		address:    strings.TrimRight(cfg.Address, "/"),
+		token:      cfg.Token,
+		namespace:  cfg.Namespace,
+		kvV2Mounts: mounts,
+		cacheTTL:   time.Duration(cacheTTL) * time.Second,
+		client:     client,
+		now:        time.Now,
+		cache:      make(map[string]*vaultSecret),
+	}
+}
+
+// resolve returns a field of the secret at path#field
+func (v *vaultClient) resolve(ctx context.Context, ref string) (string, error) {
+	path, field, _ := strings.Cut(ref, "#")
+	if field == "" {
+		field = vaultDefaultField
+	}
+	secret, err := v.read(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	value, ok := secret.data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	return value, nil
+}
+
+// read returns the secret at path from the cache, or from Vault once it expired. A stale
+// secret is served while Vault is unreachable.
+func (v *vaultClient) read(ctx context.Context, path string) (*vaultSecret, error) {
+	v.mu.Lock()
+	cached := v.cache[path]
+	v.mu.Unlock()
+	if cached != nil && v.now().Before(cached.expiresAt) {
+		return cached, nil
+	}
+
+	secret, err := v.fetch(ctx, path)
+	if err != nil {
+		if cached != nil {
+			logger.Module("secrets").Warn().Err(err).Str("path", path).Msg("Vault unavailable, using the expired secret")
+			return cached, nil
+		}
+		return nil, err
+	}
+	v.mu.Lock()
+	v.cache[path] = secret
+	v.mu.Unlock()
+	return secret, nil
+}
+
+// apiPath maps a secret path to its API path, inserting data/ after KV version 2 mounts,
+// and reports whether the secret is read from a KV version 2 engine
+func (v *vaultClient) apiPath(path string) (string, bool) {
+	path = strings.Trim(path, "/")
+	mount, rest, _ := strings.Cut(path, "/")
+	if rest == "" || !slices.Contains(v.kvV2Mounts, mount) {
+		return path, false
+	}
+	if !strings.HasPrefix(rest, "data/") {
+		rest = "data/" + rest
+	}
+	return mount + "/" + rest, true
+}
+
+func (v *vaultClient) fetch(ctx context.Context, path string) (*vaultSecret, error) {
+	var body struct {
+		LeaseID       string                 `json:"lease_id"`
+		LeaseDuration int                    `json:"lease_duration"`
+		Renewable     bool                   `json:"renewable"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	apiPath, kvV2 := v.apiPath(path)
+	if err := v.do(ctx, http.MethodGet, apiPath, nil, &body); err != nil {
+		return nil, fmt.Errorf("failed to read vault secret %s: %w", path, err)
+	}
+
+	data := body.Data
+	if kvV2 {
+		data, _ = body.Data["data"].(map[string]interface{})
+	}
+	secret := &vaultSecret{
+		data:          make(map[string]string, len(data)),
+		leaseID:       body.LeaseID,
+		leaseDuration: time.Duration(body.LeaseDuration) * time.Second,
+		renewable:     body.Renewable,
+		expiresAt:     v.now().Add(v.cacheTTL),
+	}
+	for key, value := range data {
+		if s, ok := value.(string); ok {
+			secret.data[key] = s
+		} else {
+			secret.data[key] = fmt.Sprint(value)
+		}
+	}
+	if secret.leaseID != "" && secret.leaseDuration > 0 {
+		secret.expiresAt = v.now().Add(secret.leaseDuration)
+	}
+	return secret, nil
+}
+
+// renew extends the leases and the token that passed two thirds of their duration. Leases
+// failing to renew are dropped from the cache and read again on their next use.
+func (v *vaultClient) renew(ctx context.Context) error {
+	now := v.now()
+	due := make(map[string]*vaultSecret)
+	v.mu.Lock()
+	for path, secret := range v.cache {
+		if secret.leaseID != "" && secret.renewable && secret.expiresAt.Sub(now) < secret.leaseDuration/3 {
+			due[path] = secret
+		}
+	}
+	v.mu.Unlock()
+
+	var errs []string
+	for path, secret := range due {
+		var body struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		}
+		err := v.do(ctx, http.MethodPut, "sys/leases/renew", map[string]string{"lease_id": secret.leaseID}, &body)
+
+		v.mu.Lock()
+		if err != nil {
+			delete(v.cache, path)
+			errs = append(errs, fmt.Sprintf("lease of %s: %v", path, err))
+		} else if v.cache[path] == secret {
+			renewed := *secret
+			renewed.leaseDuration = time.Duration(body.LeaseDuration) * time.Second
+			renewed.renewable = body.Renewable
+			renewed.expiresAt = v.now().Add(renewed.leaseDuration)
+			v.cache[path] = &renewed
+		}
+		v.mu.Unlock()
+	}
+
+	if err := v.renewToken(ctx); err != nil {
+		errs = append(errs, "token: "+err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("vault renewal failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// renewToken looks up the token's TTL once, then renews a renewable expiring token
+func (v *vaultClient) renewToken(ctx context.Context) error {
+	if !v.tokenLooked {
+		var body struct {
+			Data struct {
+				TTL       int  `json:"ttl"`
+				Renewable bool `json:"renewable"`
+			} `json:"data"`
+		}
+		if err := v.do(ctx, http.MethodGet, "auth/token/lookup-self", nil, &body); err != nil {
+			return err
+		}
+		v.tokenLooked = true
+		v.tokenTTL = time.Duration(body.Data.TTL) * time.Second
+		v.tokenRenewable = body.Data.Renewable
+		v.tokenExpiresAt = v.now().Add(v.tokenTTL)
+	}
+	if !v.tokenRenewable || v.tokenTTL <= 0 || v.tokenExpiresAt.Sub(v.now()) >= v.tokenTTL/3 {
+		return nil
+	}
+
+	var body struct {
+		Auth struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := v.do(ctx, http.MethodPost, "auth/token/renew-self", nil, &body); err != nil {
+		return err
+	}
+	v.tokenTTL = time.Duration(body.Auth.LeaseDuration) * time.Second
+	v.tokenRenewable = body.Auth.Renewable
+	v.tokenExpiresAt = v.now().Add(v.tokenTTL)
+	return nil
+}
+
+// do calls the Vault HTTP API
+func (v *vaultClient) do(ctx context.Context, method, apiPath string, payload, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.address+"/v1/"+apiPath, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+var secretRenewStopChan chan struct{}
+
+// StartSecretLeaseRenewal renews the Vault leases and token in the background
+func StartSecretLeaseRenewal() {
+	if vaultSecrets == nil {
+		return
+	}
+	vault := vaultSecrets
+	ticker := time.NewTicker(SecretLeaseRenewInterval)
+	secretRenewStopChan = make(chan struct{})
+	RegisterScheduler("vault_renewal", SecretLeaseRenewInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				RunScheduled("vault_renewal", func() error {
+					ctx, cancel := context.WithTimeout(context.Background(), SecretLeaseRenewInterval)
+					defer cancel()
+					err := vault.renew(ctx)
+					if err != nil {
+						logger.Module("secrets").Warn().Err(err).Msg("Vault renewal failed")
+					}
+					return err
+				})
+			case <-secretRenewStopChan:
+				return
+			}
+		}
+	}()
+	logger.Infof("[Secrets] Vault lease renewal started (interval: %v)", SecretLeaseRenewInterval)
+}
+
+func StopSecretLeaseRenewal() {
+	if secretRenewStopChan != nil {
+		close(secretRenewStopChan)
+	}
+}
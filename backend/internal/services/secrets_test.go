@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+)
+
+func TestResolveSecret_Env(t *testing.T) {
+	t.Setenv("CODESENTRY_TEST_TOKEN", "glpat-123")
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"plaintext", "glpat-plain", "glpat-plain", false},
+		{"empty", "", "", false},
+		{"env reference", "env:CODESENTRY_TEST_TOKEN", "glpat-123", false},
+		{"unset variable", "env:CODESENTRY_TEST_MISSING", "", true},
+		{"vault without config", "vault:kv/codesentry#openai", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveSecret(context.Background(), tt.value)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ResolveSecret(%q) = %q, %v; expected %q, error %v", tt.value, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretRefTransport(t *testing.T) {
+	t.Setenv("CODESENTRY_TEST_TOKEN", "secret-token")
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: withSecretRefs(http.DefaultTransport)}
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   string
+	}{
+		{"bearer reference", "Authorization", "Bearer env:CODESENTRY_TEST_TOKEN", "Bearer secret-token"},
+		{"github token reference", "Authorization", "token env:CODESENTRY_TEST_TOKEN", "token secret-token"},
+		{"bare reference", "PRIVATE-TOKEN", "env:CODESENTRY_TEST_TOKEN", "secret-token"},
+		{"plaintext untouched", "Authorization", "Bearer plain", "Bearer plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+			req.Header.Set(tt.header, tt.value)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if got := received.Get(tt.header); got != tt.want {
+				t.Errorf("%s sent = %q, expected %q", tt.header, got, tt.want)
+			}
+			if req.Header.Get(tt.header) != tt.value {
+				t.Error("the caller's request must not be modified")
+			}
+		})
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.Header.Set("Authorization", "Bearer env:CODESENTRY_TEST_MISSING")
+	if _, err := client.Do(req); err == nil {
+		t.Error("an unresolvable reference must fail the request")
+	}
+}
+
+func TestVaultClient(t *testing.T) {
+	var reads, renewals atomic.Int32
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/codesentry":
+			reads.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"data": map[string]interface{}{"openai": "sk-vault"}},
+			})
+		case "/v1/database/creds/reviewer":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"lease_id": "database/creds/reviewer/abc", "lease_duration": 60, "renewable": true,
+				"data": map[string]interface{}{"password": "p1"},
+			})
+		case "/v1/sys/leases/renew":
+			renewals.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{"lease_id": "database/creds/reviewer/abc", "lease_duration": 60, "renewable": true})
+		case "/v1/auth/token/lookup-self":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"ttl": 0}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	vault := newVaultClient(&config.VaultConfig{Address: server.URL, Token: "root", KVv2Mounts: []string{"kv"}, CacheTTL: 300}, server.Client())
+	vault.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		ref       string
+		advance   time.Duration
+		down      bool
+		want      string
+		wantErr   bool
+		wantReads int32
+	}{
+		{name: "kv v2 read", ref: "kv/codesentry#openai", want: "sk-vault", wantReads: 1},
+		{name: "cached", ref: "kv/codesentry#openai", advance: time.Minute, want: "sk-vault", wantReads: 1},
+		{name: "read again after the cache TTL", ref: "kv/codesentry#openai", advance: 5 * time.Minute, want: "sk-vault", wantReads: 2},
+		{name: "stale secret while vault is down", ref: "kv/codesentry#openai", advance: 10 * time.Minute, down: true, want: "sk-vault", wantReads: 2},
+		{name: "read again once vault is back, missing field", ref: "kv/codesentry#anthropic", wantErr: true, wantReads: 3},
+		{name: "dynamic secret", ref: "database/creds/reviewer#password", want: "p1", wantReads: 3},
+		{name: "unknown path", ref: "kv/missing", wantErr: true, wantReads: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			down.Store(tt.down)
+			got, err := vault.resolve(ctx, tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("resolve(%q) = %q, %v; expected %q, error %v", tt.ref, got, err, tt.want, tt.wantErr)
+			}
+			if reads.Load() != tt.wantReads {
+				t.Errorf("kv reads = %d, expected %d", reads.Load(), tt.wantReads)
+			}
+		})
+	}
+
+	// The dynamic secret's lease is renewed once two thirds of it have passed
+	if err := vault.renew(ctx); err != nil || renewals.Load() != 0 {
+		t.Fatalf("renew() = %v with %d renewals, expected none yet", err, renewals.Load())
+	}
+	now = now.Add(45 * time.Second)
+	if err := vault.renew(ctx); err != nil || renewals.Load() != 1 {
+		t.Fatalf("renew() = %v with %d renewals, expected 1", err, renewals.Load())
+	}
+	if expires := vault.cache["database/creds/reviewer"].expiresAt; !expires.Equal(now.Add(time.Minute)) {
+		t.Errorf("renewed lease expires at %v, expected %v", expires, now.Add(time.Minute))
+	}
+}
+
+func TestCheckSecretRefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   func(platformAdmin bool) error
+		admin   bool
+		wantErr bool
+	}{
+		{"tenant plaintext", func(a bool) error { return CheckSecretRefs(a, "glpat-123", "") }, false, false},
+		{"tenant env reference", func(a bool) error { return CheckSecretRefs(a, "glpat-123", "env:JWT_SECRET") }, false, true},
+		{"tenant vault reference", func(a bool) error { return CheckSecretRefs(a, "vault:secret/db#password") }, false, true},
+		{"admin reference", func(a bool) error { return CheckSecretRefs(a, "env:GITLAB_TOKEN") }, true, false},
+		{"tenant keeps stored reference", func(a bool) error { return CheckSecretRefUpdate(a, "env:GITLAB_TOKEN", "env:GITLAB_TOKEN") }, false, false},
+		{"tenant changes reference", func(a bool) error { return CheckSecretRefUpdate(a, "env:JWT_SECRET", "env:GITLAB_TOKEN") }, false, true},
+		{"tenant moves referenced token", func(a bool) error {
+			return CheckSecretRefTarget(a, "https://gitlab.example.com/g/p", "https://evil.example.com/g/p", "env:GITLAB_TOKEN")
+		}, false, true},
+		{"tenant keeps URL", func(a bool) error {
+			return CheckSecretRefTarget(a, "https://gitlab.example.com/g/p", "", "env:GITLAB_TOKEN")
+		}, false, false},
+		{"tenant moves plaintext token", func(a bool) error {
+			return CheckSecretRefTarget(a, "https://gitlab.example.com/g/p", "https://other.example.com/g/p", "glpat-123")
+		}, false, false},
+		{"admin moves referenced token", func(a bool) error {
+			return CheckSecretRefTarget(a, "https://gitlab.example.com/g/p", "https://gitlab2.example.com/g/p", "env:GITLAB_TOKEN")
+		}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(tt.admin); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
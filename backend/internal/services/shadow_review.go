@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// shadowReviewTimeout bounds a shadow review, which runs detached from the primary request
+const shadowReviewTimeout = 5 * time.Minute
+
+// startShadowReview samples a finished primary review and, when sampled, reviews the same
+// change in the background with the shadow LLM config and prompt. The shadow result is only
+// stored for comparison: it never changes commit statuses or triggers notifications.
+func (s *AIService) startShadowReview(req *ReviewRequest, primary *ReviewResult, primaryLatency time.Duration) {
+	cfg := NewSystemConfigService(s.db).GetShadowReviewConfig()
+	if !cfg.Enabled || cfg.LLMConfigID == 0 || rand.Float64()*100 >= cfg.SampleRate {
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("[Shadow] Shadow review panicked: %v", r)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), shadowReviewTimeout)
+		defer cancel()
+		if err := s.runShadowReview(ctx, cfg, req, primary, primaryLatency); err != nil {
+			logger.Warnf("[Shadow] Shadow review of project %d failed: %v", req.ProjectID, err)
+		}
+	}()
+}
+
+func (s *AIService) runShadowReview(ctx context.Context, cfg *ShadowReviewConfigResponse, req *ReviewRequest, primary *ReviewResult, primaryLatency time.Duration) error {
+	var project models.Project
+	if err := s.db.First(&project, req.ProjectID).Error; err != nil {
+		return fmt.Errorf("project not found: %w", err)
+	}
+	var llmConfig models.LLMConfig
+	if err := s.db.Where("id = ? AND is_active = ?", cfg.LLMConfigID, true).First(&llmConfig).Error; err != nil {
+		return fmt.Errorf("shadow LLM config %d unavailable: %w", cfg.LLMConfigID, err)
+	}
+
+	shadow := models.ShadowReview{
+		ReviewLogID:        req.ReviewLogID,
+		ProjectID:          project.ID,
+		PrimaryLLMConfigID: primary.LLMConfigID,
+		ShadowLLMConfigID:  llmConfig.ID,
+		MinScore:           NewReviewLogService(s.db).EffectiveMinScore(&project),
+		PrimaryScore:       primary.Score,
+		PrimaryLatencyMs:   primaryLatency.Milliseconds(),
+	}
+
+	template := ""
+	if cfg.PromptID > 0 {
+		var promptTemplate models.PromptTemplate
+		if err := s.db.First(&promptTemplate, cfg.PromptID).Error; err != nil {
+			return fmt.Errorf("shadow prompt %d not found: %w", cfg.PromptID, err)
+		}
+		promptID := promptTemplate.ID
+		shadow.ShadowPromptID = &promptID
+		template = promptTemplate.Content
+		if !containsScoringInstruction(template) {
+			template = appendScoringInstruction(template)
+		}
+	} else {
+		template = s.getPromptForProject(&project, req)
+	}
+
+	prompt, meta := s.buildReviewPrompt(&project, req, template)
+	start := time.Now()
+	result, err := s.callLLMWithMeta(ctx, &llmConfig, prompt, meta)
+	shadow.ShadowLatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		shadow.ErrorMessage = truncateRunes(err.Error(), 500)
+	} else {
+		score := result.Score
+		shadow.ShadowScore = &score
+		shadow.ShadowResult = result.Content
+		shadow.Success = true
+		logger.Infof("[Shadow] Project %d: primary score %.0f, shadow score %.0f (%s)", project.ID, primary.Score, score, llmConfig.Name)
+	}
+	return s.db.Create(&shadow).Error
+}
+
+// ShadowReviewService lists shadow reviews and compares them with their primary reviews
+type ShadowReviewService struct {
+	db *gorm.DB
+}
+
+func NewShadowReviewService(db *gorm.DB) *ShadowReviewService {
+	return &ShadowReviewService{db: db}
+}
+
+type ShadowReviewListRequest struct {
+	Page              int  `form:"page" binding:"omitempty,min=1"`
+	PageSize          int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	ProjectID         uint `form:"project_id"`
+	ShadowLLMConfigID uint `form:"shadow_llm_config_id"`
+}
+
+type ShadowReviewListResponse struct {
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"page"`
+	PageSize int                   `json:"page_size"`
+	Items    []models.ShadowReview `json:"items"`
+}
+
+// List returns paginated shadow reviews, newest first
+func (s *ShadowReviewService) List(req *ShadowReviewListRequest) (*ShadowReviewListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 20
+	}
+
+	query := s.filter(s.db.Model(&models.ShadowReview{}), req.ProjectID, req.ShadowLLMConfigID)
+
+	var total int64
+	query.Count(&total)
+
+	var items []models.ShadowReview
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Offset(offset).Limit(req.PageSize).Order("created_at DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return &ShadowReviewListResponse{
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		Items:    items,
+	}, nil
+}
+
+func (s *ShadowReviewService) filter(query *gorm.DB, projectID, shadowLLMConfigID uint) *gorm.DB {
+	if projectID > 0 {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if shadowLLMConfigID > 0 {
+		query = query.Where("shadow_llm_config_id = ?", shadowLLMConfigID)
+	}
+	return query
+}
+
+type ShadowCompareRequest struct {
+	StartDate         string `form:"start_date"`
+	EndDate           string `form:"end_date"`
+	ProjectID         uint   `form:"project_id"`
+	ShadowLLMConfigID uint   `form:"shadow_llm_config_id"`
+}
+
+// ShadowSideStats summarizes the scores and latency of one side of the comparison
+type ShadowSideStats struct {
+	AvgScore     float64 `json:"avg_score"`
+	PassRate     float64 `json:"pass_rate"`
+	P50LatencyMs int64   `json:"p50_latency_ms"`
+	P90LatencyMs int64   `json:"p90_latency_ms"`
+	ScoreBuckets [10]int `json:"score_buckets"` // Score distribution: 0-9, 10-19, ..., 90-100
+}
+
+// ShadowComparison compares the successful shadow reviews of a period with their primary reviews
+type ShadowComparison struct {
+	StartDate    string          `json:"start_date"`
+	EndDate      string          `json:"end_date"`
+	Samples      int             `json:"samples"`
+	Failures     int             `json:"failures"`
+	Primary      ShadowSideStats `json:"primary"`
+	Shadow       ShadowSideStats `json:"shadow"`
+	AvgScoreDiff float64         `json:"avg_score_diff"` // Shadow minus primary
+	MeanAbsDiff  float64         `json:"mean_abs_diff"`
+	Agreement    float64         `json:"agreement"` // Percentage of samples with the same pass/fail verdict
+}
+
+// Compare compares shadow and primary reviews over a date range, defaulting to the last 30 days
+func (s *ShadowReviewService) Compare(req *ShadowCompareRequest) (*ShadowComparison, error) {
+	startDate, endDate := parseDateRange(req.StartDate, req.EndDate, 30)
+
+	var rows []models.ShadowReview
+	query := s.filter(s.db.Model(&models.ShadowReview{}), req.ProjectID, req.ShadowLLMConfigID).
+		Select("min_score, primary_score, shadow_score, primary_latency_ms, shadow_latency_ms, success").
+		Where("created_at >= ? AND created_at <= ?", startDate, endDate)
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	comparison := compareShadowReviews(rows)
+	comparison.StartDate = startDate.Format("2006-01-02")
+	comparison.EndDate = endDate.Format("2006-01-02")
+	return comparison, nil
+}
+
+// compareShadowReviews aggregates shadow reviews; failed shadow reviews are only counted
+func compareShadowReviews(rows []models.ShadowReview) *ShadowComparison {
+	comparison := &ShadowComparison{}
+	var primaryLatency, shadowLatency []int64
+	var primarySum, shadowSum, diffSum, absDiffSum float64
+	var primaryPassed, shadowPassed, agreed int
+
+	for _, row := range rows {
+		if !row.Success || row.ShadowScore == nil {
+			comparison.Failures++
+			continue
+		}
+		comparison.Samples++
+		shadowScore := *row.ShadowScore
+
+		primarySum += row.PrimaryScore
+		shadowSum += shadowScore
+		diff := shadowScore - row.PrimaryScore
+		diffSum += diff
+		if diff < 0 {
+			diff = -diff
+		}
+		absDiffSum += diff
+
+		primaryPass := row.PrimaryScore >= row.MinScore
+		shadowPass := shadowScore >= row.MinScore
+		if primaryPass {
+			primaryPassed++
+		}
+		if shadowPass {
+			shadowPassed++
+		}
+		if primaryPass == shadowPass {
+			agreed++
+		}
+
+		comparison.Primary.ScoreBuckets[scoreBucket(row.PrimaryScore)]++
+		comparison.Shadow.ScoreBuckets[scoreBucket(shadowScore)]++
+		primaryLatency = append(primaryLatency, row.PrimaryLatencyMs)
+		shadowLatency = append(shadowLatency, row.ShadowLatencyMs)
+	}
+
+	n := float64(comparison.Samples)
+	if n == 0 {
+		return comparison
+	}
+	comparison.Primary.AvgScore = round1(primarySum / n)
+	comparison.Shadow.AvgScore = round1(shadowSum / n)
+	comparison.Primary.PassRate = round1(float64(primaryPassed) / n * 100)
+	comparison.Shadow.PassRate = round1(float64(shadowPassed) / n * 100)
+	comparison.Primary.P50LatencyMs = latencyPercentile(primaryLatency, 50)
+	comparison.Primary.P90LatencyMs = latencyPercentile(primaryLatency, 90)
+	comparison.Shadow.P50LatencyMs = latencyPercentile(shadowLatency, 50)
+	comparison.Shadow.P90LatencyMs = latencyPercentile(shadowLatency, 90)
+	comparison.AvgScoreDiff = round1(diffSum / n)
+	comparison.MeanAbsDiff = round1(absDiffSum / n)
+	comparison.Agreement = round1(float64(agreed) / n * 100)
+	return comparison
+}
+
+// scoreBucket returns the decile of a score, with 100 in the last bucket
+func scoreBucket(score float64) int {
+	bucket := int(score / 10)
+	if bucket < 0 {
+		return 0
+	}
+	if bucket > 9 {
+		return 9
+	}
+	return bucket
+}
+
+// latencyPercentile returns the nearest-rank percentile of the latencies
+func latencyPercentile(latencies []int64, p int) int64 {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func shadowRow(primary, shadow float64, primaryMs, shadowMs int64) models.ShadowReview {
+	return models.ShadowReview{
+		MinScore:         60,
+		PrimaryScore:     primary,
+		ShadowScore:      &shadow,
+		PrimaryLatencyMs: primaryMs,
+		ShadowLatencyMs:  shadowMs,
+		Success:          true,
+	}
+}
+
+func TestCompareShadowReviews(t *testing.T) {
+	rows := []models.ShadowReview{
+		shadowRow(80, 70, 1000, 500),
+		shadowRow(50, 65, 2000, 700),
+		shadowRow(90, 100, 3000, 900),
+		shadowRow(40, 30, 4000, 1100),
+		{MinScore: 60, PrimaryScore: 75, ErrorMessage: "timeout"},
+	}
+
+	got := compareShadowReviews(rows)
+	if got.Samples != 4 || got.Failures != 1 {
+		t.Fatalf("samples/failures = %d/%d, want 4/1", got.Samples, got.Failures)
+	}
+	if got.Primary.AvgScore != 65 || got.Shadow.AvgScore != 66.3 {
+		t.Errorf("avg scores = %v/%v, want 65/66.3", got.Primary.AvgScore, got.Shadow.AvgScore)
+	}
+	if got.Primary.PassRate != 50 || got.Shadow.PassRate != 75 {
+		t.Errorf("pass rates = %v/%v, want 50/75", got.Primary.PassRate, got.Shadow.PassRate)
+	}
+	if got.Agreement != 75 {
+		t.Errorf("agreement = %v, want 75", got.Agreement)
+	}
+	if got.AvgScoreDiff != 1.3 || got.MeanAbsDiff != 11.3 {
+		t.Errorf("diffs = %v/%v, want 1.3/11.3", got.AvgScoreDiff, got.MeanAbsDiff)
+	}
+	if got.Primary.P50LatencyMs != 2000 || got.Primary.P90LatencyMs != 4000 {
+		t.Errorf("primary latency = %d/%d, want 2000/4000", got.Primary.P50LatencyMs, got.Primary.P90LatencyMs)
+	}
+	if got.Shadow.P50LatencyMs != 700 || got.Shadow.P90LatencyMs != 1100 {
+		t.Errorf("shadow latency = %d/%d, want 700/1100", got.Shadow.P50LatencyMs, got.Shadow.P90LatencyMs)
+	}
+	if got.Shadow.ScoreBuckets[9] != 1 || got.Shadow.ScoreBuckets[3] != 1 {
+		t.Errorf("shadow buckets = %v", got.Shadow.ScoreBuckets)
+	}
+}
+
+func TestCompareShadowReviewsEmpty(t *testing.T) {
+	got := compareShadowReviews(nil)
+	if got.Samples != 0 || got.Agreement != 0 || got.Primary.P50LatencyMs != 0 {
+		t.Errorf("compareShadowReviews(nil) = %+v, want zero values", got)
+	}
+}
+
+func TestScoreBucket(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  int
+	}{
+		{-5, 0},
+		{0, 0},
+		{9.9, 0},
+		{10, 1},
+		{59, 5},
+		{99, 9},
+		{100, 9},
+	}
+	for _, tt := range tests {
+		if got := scoreBucket(tt.score); got != tt.want {
+			t.Errorf("scoreBucket(%v) = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	latencies := []int64{50, 10, 40, 20, 30}
+	tests := []struct {
+		p    int
+		want int64
+	}{
+		{50, 30},
+		{90, 50},
+		{100, 50},
+		{0, 10},
+	}
+	for _, tt := range tests {
+		if got := latencyPercentile(latencies, tt.p); got != tt.want {
+			t.Errorf("latencyPercentile(p%d) = %d, want %d", tt.p, got, tt.want)
+		}
+	}
+	if latencies[0] != 50 {
+		t.Error("latencyPercentile must not sort its input in place")
+	}
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+// ReviewStatusSkippedByDirective marks reviews the author skipped on purpose with a skip
+// directive in the commit message or a push option
+const ReviewStatusSkippedByDirective = "skipped_by_directive"
+
+// SkipPushOption is the GitLab push option skipping the review: git push -o codesentry.skip
+const SkipPushOption = "codesentry.skip"
+
+// skipDirectiveRegex matches [skip review] and [codesentry skip]
+var skipDirectiveRegex = regexp.MustCompile(`(?i)\[\s*(skip[ -]review|codesentry[ -]skip)\s*\]`)
+
+// FindSkipDirective returns the skip directive of a commit message, empty if it has none
+func FindSkipDirective(message string) string {
+	return skipDirectiveRegex.FindString(message)
+}
+
+// HasSkipPushOption reports whether GitLab push options ask to skip the review. GitLab
+// nests dotted options, -o codesentry.skip arrives as {"codesentry": {"skip": true}}.
+func HasSkipPushOption(options map[string]interface{}) bool {
+	key, option, _ := strings.Cut(SkipPushOption, ".")
+	nested, ok := options[key].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	switch v := nested[option].(type) {
+	case bool:
+		return v
+	case string:
+		return v != "" && v != "false"
+	}
+	return false
+}
+
+// SkipDirectiveAllowed reports whether the project honors skip directives on branch, the
+// target branch for merge requests: it must enable them and the branch must match
+// SkipBranches, when set
+func SkipDirectiveAllowed(project *models.Project, branch string) bool {
+	if !project.SkipDirectives {
+		return false
+	}
+	matched, empty := matchBranchFilter(project.SkipBranches, branch)
+	return matched || empty
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestFindSkipDirective(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"Bump version [skip review]", "[skip review]"},
+		{"Regenerate mocks\n\n[CodeSentry Skip]", "[CodeSentry Skip]"},
+		{"[skip-review] docs", "[skip-review]"},
+		{"Fix review skipping", ""},
+		{"[skip ci]", ""},
+	}
+	for _, tt := range tests {
+		if got := FindSkipDirective(tt.message); got != tt.want {
+			t.Errorf("FindSkipDirective(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestHasSkipPushOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options map[string]interface{}
+		want    bool
+	}{
+		{"none", nil, false},
+		{"bool", map[string]interface{}{"codesentry": map[string]interface{}{"skip": true}}, true},
+		{"string", map[string]interface{}{"codesentry": map[string]interface{}{"skip": "true"}}, true},
+		{"other option", map[string]interface{}{"ci": map[string]interface{}{"skip": true}}, false},
+		{"disabled", map[string]interface{}{"codesentry": map[string]interface{}{"skip": "false"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasSkipPushOption(tt.options); got != tt.want {
+				t.Errorf("HasSkipPushOption() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipDirectiveAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		project models.Project
+		branch  string
+		want    bool
+	}{
+		{"disabled", models.Project{}, "feature/x", false},
+		{"any branch", models.Project{SkipDirectives: true}, "main", true},
+		{"allowed branch", models.Project{SkipDirectives: true, SkipBranches: "feature/*, docs"}, "feature/x", true},
+		{"other branch", models.Project{SkipDirectives: true, SkipBranches: "feature/*, docs"}, "main", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SkipDirectiveAllowed(&tt.project, tt.branch); got != tt.want {
+				t.Errorf("SkipDirectiveAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"sync"
 )
 
+// DefaultSSEReplayBuffer is the number of recent review events kept for Last-Event-ID replay
+const DefaultSSEReplayBuffer = 256
+
 // ReviewEvent represents a real-time review status update event
 type ReviewEvent struct {
+	EventID   uint64   `json:"event_id,omitempty"` // Stream position, sent as the SSE id for Last-Event-ID replay
 	ID        uint     `json:"id"`
 	ProjectID uint     `json:"project_id"`
 	CommitSHA string   `json:"commit_sha"`
@@ -14,37 +18,89 @@ type ReviewEvent struct {
 	Error     string   `json:"error,omitempty"`
 }
 
-// ImportEvent represents a commit import completion event
+// ImportEvent represents a commit import completion event, or the progress of the
+// retroactive reviews of imported commits when Phase is "review"
 type ImportEvent struct {
 	ProjectID   uint   `json:"project_id"`
 	ProjectName string `json:"project_name"`
 	Imported    int    `json:"imported"`
 	Skipped     int    `json:"skipped"`
 	Error       string `json:"error,omitempty"`
+	// MergeRequests is the number of merge requests imported
+	MergeRequests int    `json:"merge_requests,omitempty"`
+	Phase         string `json:"phase,omitempty"`  // import (default) or review
+	Queued        int    `json:"queued,omitempty"` // Review phase: reviews enqueued so far
+	Failed        int    `json:"failed,omitempty"` // Review phase: commits whose diff could not be fetched
+	Total         int    `json:"total,omitempty"`  // Review phase: commits to review
+	Done          bool   `json:"done,omitempty"`   // Review phase: every commit was handled
 }
 
-// SSEHub manages SSE client connections and event broadcasting
+// SSEHub manages SSE client connections and event broadcasting. Recent events are kept
+// in a ring buffer so reconnecting clients can replay what they missed. With a Redis
+// fan-out, events are published to every replica and numbered by a shared counter.
 type SSEHub struct {
-	clients map[string]chan ReviewEvent
+	clients map[string]*sseClient
 	mu      sync.RWMutex
+
+	lastEventID uint64
+	replay      []ReviewEvent // Recent events, oldest first
+	replaySize  int
+	fanout      *sseFanout
 }
 
 // NewSSEHub creates a new SSE hub instance
 func NewSSEHub() *SSEHub {
 	return &SSEHub{
-		clients: make(map[string]chan ReviewEvent),
+		clients:    make(map[string]*sseClient),
+		replaySize: DefaultSSEReplayBuffer,
 	}
 }
 
+// SetReplaySize sets the number of recent events kept for replay
+func (h *SSEHub) SetReplaySize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size <= 0 {
+		size = DefaultSSEReplayBuffer
+	}
+	h.replaySize = size
+	if len(h.replay) > size {
+		h.replay = append([]ReviewEvent(nil), h.replay[len(h.replay)-size:]...)
+	}
+}
+
+// sseClient is a subscriber and the filter of the events it receives
+type sseClient struct {
+	ch     chan ReviewEvent
+	filter *ReviewEventFilter
+}
+
 // Subscribe registers a new client and returns a channel for receiving events
 func (h *SSEHub) Subscribe(clientID string) <-chan ReviewEvent {
+	ch, _ := h.SubscribeFrom(clientID, 0, nil)
+	return ch
+}
+
+// SubscribeFrom registers a client resuming a stream and returns, with its channel, the
+// buffered events after lastEventID. A lastEventID of 0 starts a new stream without replay.
+// Events older than the ring buffer are lost. A nil filter receives every event.
+func (h *SSEHub) SubscribeFrom(clientID string, lastEventID uint64, filter *ReviewEventFilter) (<-chan ReviewEvent, []ReviewEvent) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
 	// Create buffered channel to prevent blocking
 	ch := make(chan ReviewEvent, 100)
-	h.clients[clientID] = ch
-	return ch
+	h.clients[clientID] = &sseClient{ch: ch, filter: filter}
+
+	var missed []ReviewEvent
+	if lastEventID > 0 {
+		for _, event := range h.replay {
+			if event.EventID > lastEventID && filter.Match(&event) {
+				missed = append(missed, event)
+			}
+		}
+	}
+	return ch, missed
 }
 
 // Unsubscribe removes a client from the hub
@@ -52,21 +108,50 @@ func (h *SSEHub) Unsubscribe(clientID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if ch, ok := h.clients[clientID]; ok {
-		close(ch)
+	if client, ok := h.clients[clientID]; ok {
+		close(client.ch)
 		delete(h.clients, clientID)
 	}
 }
 
-// Publish broadcasts an event to all connected clients
+// Publish broadcasts an event to all connected clients, of every replica when the Redis
+// fan-out is running. Events are delivered locally when Redis is unreachable.
 func (h *SSEHub) Publish(event ReviewEvent) {
 	h.mu.RLock()
-	defer h.mu.RUnlock()
+	fanout := h.fanout
+	h.mu.RUnlock()
 
-	for _, ch := range h.clients {
+	if fanout != nil && fanout.publishReview(&event) == nil {
+		// Delivered to this replica's clients by the fan-out subscription
+		return
+	}
+	h.broadcast(event)
+}
+
+// broadcast numbers an event unless the fan-out did, buffers it for replay and sends it
+// to the clients of this replica
+func (h *SSEHub) broadcast(event ReviewEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if event.EventID == 0 {
+		event.EventID = h.lastEventID + 1
+	}
+	if event.EventID > h.lastEventID {
+		h.lastEventID = event.EventID
+	}
+	h.replay = append(h.replay, event)
+	if len(h.replay) > h.replaySize {
+		h.replay = h.replay[len(h.replay)-h.replaySize:]
+	}
+
+	for _, client := range h.clients {
+		if !client.filter.Match(&event) {
+			continue
+		}
 		// Non-blocking send - drop event if client buffer is full
 		select {
-		case ch <- event:
+		case client.ch <- event:
 		default:
 			// Client is slow, skip this event
 		}
@@ -108,6 +193,7 @@ func PublishReviewEvent(id uint, projectID uint, commitSHA, status string, score
 type ImportEventHub struct {
 	clients map[string]chan ImportEvent
 	mu      sync.RWMutex
+	fanout  *sseFanout
 }
 
 var globalImportHub *ImportEventHub
@@ -140,6 +226,17 @@ func (h *ImportEventHub) Unsubscribe(clientID string) {
 }
 
 func (h *ImportEventHub) Publish(event ImportEvent) {
+	h.mu.RLock()
+	fanout := h.fanout
+	h.mu.RUnlock()
+
+	if fanout != nil && fanout.publishImport(&event) == nil {
+		return
+	}
+	h.broadcast(event)
+}
+
+func (h *ImportEventHub) broadcast(event ImportEvent) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	for _, ch := range h.clients {
@@ -150,12 +247,26 @@ func (h *ImportEventHub) Publish(event ImportEvent) {
 	}
 }
 
-func PublishImportEvent(projectID uint, projectName string, imported, skipped int, errMsg string) {
+func PublishImportEvent(projectID uint, projectName string, imported, skipped, mergeRequests int, errMsg string) {
+	GetImportHub().Publish(ImportEvent{
+		ProjectID:     projectID,
+		ProjectName:   projectName,
+		Imported:      imported,
+		Skipped:       skipped,
+		MergeRequests: mergeRequests,
+		Error:         errMsg,
+	})
+}
+
+// PublishImportReviewProgress publishes the progress of the retroactive reviews of a project
+func PublishImportReviewProgress(projectID uint, projectName string, queued, failed, total int, done bool) {
 	GetImportHub().Publish(ImportEvent{
 		ProjectID:   projectID,
 		ProjectName: projectName,
-		Imported:    imported,
-		Skipped:     skipped,
-		Error:       errMsg,
+		Phase:       ImportPhaseReview,
+		Queued:      queued,
+		Failed:      failed,
+		Total:       total,
+		Done:        done,
 	})
 }
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// sseReviewChannel and sseImportChannel are the Redis pub/sub channels events are fanned out on
+	sseReviewChannel = "codesentry:sse:reviews"
+	sseImportChannel = "codesentry:sse:imports"
+	// sseEventIDKey numbers review events across replicas, so Last-Event-ID works on any replica
+	sseEventIDKey = "codesentry:sse:event_id"
+	// ssePublishTimeout bounds publishing an event to Redis
+	ssePublishTimeout = 2 * time.Second
+)
+
+// sseFanout publishes SSE events to Redis and delivers the events of every replica to
+// the local hubs
+type sseFanout struct {
+	client *redis.Client
+	pubsub *redis.PubSub
+	done   chan struct{}
+}
+
+var (
+	sseFanoutMu     sync.Mutex
+	activeSSEFanout *sseFanout
+)
+
+// StartSSEFanout connects the SSE hubs to Redis pub/sub, so clients of any replica receive
+// the events of all replicas
+func StartSSEFanout(cfg *config.RedisConfig) error {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pubsub := client.Subscribe(ctx, sseReviewChannel, sseImportChannel)
+	// Wait for the subscription, so no event published after start is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		client.Close()
+		return err
+	}
+
+	fanout := &sseFanout{client: client, pubsub: pubsub, done: make(chan struct{})}
+	go fanout.run()
+
+	sseFanoutMu.Lock()
+	activeSSEFanout = fanout
+	sseFanoutMu.Unlock()
+	reviewHub, importHub := GetSSEHub(), GetImportHub()
+	reviewHub.mu.Lock()
+	reviewHub.fanout = fanout
+	reviewHub.mu.Unlock()
+	importHub.mu.Lock()
+	importHub.fanout = fanout
+	importHub.mu.Unlock()
+
+	logger.Infof("[SSE] Redis fan-out started at %s", cfg.Addr)
+	return nil
+}
+
+// StopSSEFanout disconnects the SSE hubs from Redis; events are delivered locally again
+func StopSSEFanout() {
+	sseFanoutMu.Lock()
+	fanout := activeSSEFanout
+	activeSSEFanout = nil
+	sseFanoutMu.Unlock()
+	if fanout == nil {
+		return
+	}
+
+	reviewHub, importHub := GetSSEHub(), GetImportHub()
+	reviewHub.mu.Lock()
+	reviewHub.fanout = nil
+	reviewHub.mu.Unlock()
+	importHub.mu.Lock()
+	importHub.fanout = nil
+	importHub.mu.Unlock()
+
+	fanout.pubsub.Close()
+	<-fanout.done
+	fanout.client.Close()
+}
+
+// run delivers the events received from Redis until the subscription is closed. The
+// subscription reconnects by itself after connection errors.
+func (f *sseFanout) run() {
+	defer close(f.done)
+	for msg := range f.pubsub.Channel() {
+		switch msg.Channel {
+		case sseReviewChannel:
+			var event ReviewEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				logger.Warnf("[SSE] Invalid review event from Redis: %v", err)
+				continue
+			}
+			GetSSEHub().broadcast(event)
+		case sseImportChannel:
+			var event ImportEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				logger.Warnf("[SSE] Invalid import event from Redis: %v", err)
+				continue
+			}
+			GetImportHub().broadcast(event)
+		}
+	}
+}
+
+// publishReview numbers a review event with the shared counter and publishes it
+func (f *sseFanout) publishReview(event *ReviewEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ssePublishTimeout)
+	defer cancel()
+
+	id, err := f.client.Incr(ctx, sseEventIDKey).Result()
+	if err != nil {
+		logger.Warnf("[SSE] Failed to number review event, delivering locally: %v", err)
+		return err
+	}
+	event.EventID = uint64(id)
+	return f.publish(ctx, sseReviewChannel, event)
+}
+
+func (f *sseFanout) publishImport(event *ImportEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ssePublishTimeout)
+	defer cancel()
+	return f.publish(ctx, sseImportChannel, event)
+}
+
+func (f *sseFanout) publish(ctx context.Context, channel string, event interface{}) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := f.client.Publish(ctx, channel, payload).Err(); err != nil {
+		logger.Warnf("[SSE] Failed to publish to %s, delivering locally: %v", channel, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ReviewEventFilter selects the review events an SSE client receives, evaluated on the
+// server so clients watching one project do not receive every event of the instance.
+// Empty criteria match every event.
+type ReviewEventFilter struct {
+	ProjectIDs map[uint]bool
+	Statuses   map[string]bool
+	MinScore   *float64 // Only scored events at or above the score match
+	// Projects the client may see, e.g. those of its tenant; nil allows every project
+	AllowedProjectIDs map[uint]bool
+}
+
+// ParseReviewEventFilter builds a filter from query values. Project IDs and statuses may be
+// repeated or comma-separated. It returns nil when no criteria are given.
+func ParseReviewEventFilter(projectIDs, statuses []string, minScore string) (*ReviewEventFilter, error) {
+	filter := &ReviewEventFilter{}
+	for _, value := range splitQueryValues(projectIDs) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			return nil, fmt.Errorf("invalid project_id %q", value)
+		}
+		if filter.ProjectIDs == nil {
+			filter.ProjectIDs = make(map[uint]bool)
+		}
+		filter.ProjectIDs[uint(id)] = true
+	}
+	for _, value := range splitQueryValues(statuses) {
+		if filter.Statuses == nil {
+			filter.Statuses = make(map[string]bool)
+		}
+		filter.Statuses[strings.ToLower(value)] = true
+	}
+	if minScore = strings.TrimSpace(minScore); minScore != "" {
+		score, err := strconv.ParseFloat(minScore, 64)
+		if err != nil || score < 0 || score > 100 {
+			return nil, fmt.Errorf("invalid min_score %q", minScore)
+		}
+		filter.MinScore = &score
+	}
+
+	if filter.ProjectIDs == nil && filter.Statuses == nil && filter.MinScore == nil {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// splitQueryValues flattens repeated and comma-separated query values
+func splitQueryValues(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+// Restrict limits the filter to events of the allowed projects; a nil set leaves the
+// filter unchanged
+func (f *ReviewEventFilter) Restrict(allowed map[uint]bool) *ReviewEventFilter {
+	if allowed == nil {
+		return f
+	}
+	if f == nil {
+		f = &ReviewEventFilter{}
+	}
+	f.AllowedProjectIDs = allowed
+	return f
+}
+
+// Match reports whether the event passes the filter; a nil filter matches every event
+func (f *ReviewEventFilter) Match(event *ReviewEvent) bool {
+	if f == nil {
+		return true
+	}
+	if f.AllowedProjectIDs != nil && !f.AllowedProjectIDs[event.ProjectID] {
+		return false
+	}
+	if f.ProjectIDs != nil && !f.ProjectIDs[event.ProjectID] {
+		return false
+	}
+	if f.Statuses != nil && !f.Statuses[event.Status] {
+		return false
+	}
+	if f.MinScore != nil && (event.Score == nil || *event.Score < *f.MinScore) {
+		return false
+	}
+	return true
+}
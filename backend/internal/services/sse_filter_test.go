@@ -0,0 +1,118 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReviewEventFilter(t *testing.T) {
+	filter, err := ParseReviewEventFilter([]string{"1,2", "3"}, []string{"Completed, failed"}, "80")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(filter.ProjectIDs) != 3 || !filter.ProjectIDs[3] {
+		t.Errorf("ProjectIDs = %v", filter.ProjectIDs)
+	}
+	if len(filter.Statuses) != 2 || !filter.Statuses["completed"] {
+		t.Errorf("Statuses = %v", filter.Statuses)
+	}
+	if filter.MinScore == nil || *filter.MinScore != 80 {
+		t.Errorf("MinScore = %v", filter.MinScore)
+	}
+
+	if filter, err := ParseReviewEventFilter(nil, []string{""}, ""); err != nil || filter != nil {
+		t.Errorf("expected no filter, got %+v, %v", filter, err)
+	}
+	for _, tt := range []struct {
+		projectIDs []string
+		minScore   string
+	}{
+		{[]string{"abc"}, ""},
+		{[]string{"0"}, ""},
+		{nil, "high"},
+		{nil, "101"},
+	} {
+		if _, err := ParseReviewEventFilter(tt.projectIDs, nil, tt.minScore); err == nil {
+			t.Errorf("ParseReviewEventFilter(%v, %q) should fail", tt.projectIDs, tt.minScore)
+		}
+	}
+}
+
+func TestReviewEventFilter_Match(t *testing.T) {
+	low, high := 40.0, 90.0
+	minScore := 80.0
+	filter := &ReviewEventFilter{
+		ProjectIDs: map[uint]bool{1: true},
+		Statuses:   map[string]bool{"completed": true},
+		MinScore:   &minScore,
+	}
+
+	tests := []struct {
+		name  string
+		event ReviewEvent
+		want  bool
+	}{
+		{"matching", ReviewEvent{ProjectID: 1, Status: "completed", Score: &high}, true},
+		{"other project", ReviewEvent{ProjectID: 2, Status: "completed", Score: &high}, false},
+		{"other status", ReviewEvent{ProjectID: 1, Status: "failed", Score: &high}, false},
+		{"below min score", ReviewEvent{ProjectID: 1, Status: "completed", Score: &low}, false},
+		{"unscored", ReviewEvent{ProjectID: 1, Status: "completed"}, false},
+	}
+	for _, tt := range tests {
+		if got := filter.Match(&tt.event); got != tt.want {
+			t.Errorf("%s: Match() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	var none *ReviewEventFilter
+	if !none.Match(&ReviewEvent{ProjectID: 2, Status: "pending"}) {
+		t.Error("a nil filter should match every event")
+	}
+}
+
+func TestSSEHub_FilteredSubscription(t *testing.T) {
+	hub := NewSSEHub()
+	hub.Publish(ReviewEvent{ID: 1, ProjectID: 1, Status: "completed"})
+	hub.Publish(ReviewEvent{ID: 2, ProjectID: 2, Status: "completed"})
+
+	filter := &ReviewEventFilter{ProjectIDs: map[uint]bool{2: true}}
+	ch, missed := hub.SubscribeFrom("client1", 1, filter)
+	if len(missed) != 1 || missed[0].ID != 2 {
+		t.Fatalf("expected only the event of project 2 to be replayed, got %+v", missed)
+	}
+
+	hub.Publish(ReviewEvent{ID: 3, ProjectID: 1, Status: "pending"})
+	hub.Publish(ReviewEvent{ID: 4, ProjectID: 2, Status: "pending"})
+	select {
+	case received := <-ch:
+		if received.ID != 4 {
+			t.Errorf("received event %d, expected 4", received.ID)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestReviewEventFilter_Restrict(t *testing.T) {
+	tenantProjects := map[uint]bool{1: true}
+
+	var none *ReviewEventFilter
+	restricted := none.Restrict(tenantProjects)
+	if !restricted.Match(&ReviewEvent{ProjectID: 1}) || restricted.Match(&ReviewEvent{ProjectID: 2}) {
+		t.Error("a restricted nil filter should match only events of the allowed projects")
+	}
+
+	// Asking for a project of another tenant yields nothing
+	filter := (&ReviewEventFilter{ProjectIDs: map[uint]bool{2: true}}).Restrict(tenantProjects)
+	if filter.Match(&ReviewEvent{ProjectID: 2}) || filter.Match(&ReviewEvent{ProjectID: 1}) {
+		t.Error("a project filter outside the allowed projects should match nothing")
+	}
+
+	if none.Restrict(nil) != nil {
+		t.Error("restricting to every project should keep a nil filter")
+	}
+	empty := none.Restrict(map[uint]bool{})
+	if empty.Match(&ReviewEvent{ProjectID: 1}) {
+		t.Error("a tenant without projects should receive no events")
+	}
+}
@@ -188,3 +188,48 @@ func TestGetSSEHub_Singleton(t *testing.T) {
 		t.Error("GetSSEHub should return the same instance")
 	}
 }
+
+func TestSSEHub_ReplayAfterLastEventID(t *testing.T) {
+	hub := NewSSEHub()
+	hub.SetReplaySize(3)
+
+	for i := uint(1); i <= 5; i++ {
+		hub.Publish(ReviewEvent{ID: i, Status: "completed"})
+	}
+
+	// Events are numbered in publish order and only the last 3 are kept
+	_, missed := hub.SubscribeFrom("client1", 3, nil)
+	if len(missed) != 2 || missed[0].EventID != 4 || missed[1].EventID != 5 {
+		t.Fatalf("expected events 4 and 5 to be replayed, got %+v", missed)
+	}
+
+	_, missed = hub.SubscribeFrom("client2", 1, nil)
+	if len(missed) != 3 || missed[0].ID != 3 {
+		t.Errorf("expected the 3 buffered events to be replayed, got %+v", missed)
+	}
+
+	_, missed = hub.SubscribeFrom("client3", 0, nil)
+	if len(missed) != 0 {
+		t.Errorf("a new stream should not replay events, got %+v", missed)
+	}
+}
+
+func TestSSEHub_BroadcastKeepsFanoutIDs(t *testing.T) {
+	hub := NewSSEHub()
+	ch := hub.Subscribe("client1")
+
+	// Events numbered by the Redis fan-out keep their IDs; local events continue after them
+	hub.broadcast(ReviewEvent{EventID: 42, ID: 1})
+	hub.broadcast(ReviewEvent{ID: 2})
+
+	for _, want := range []uint64{42, 43} {
+		select {
+		case received := <-ch:
+			if received.EventID != want {
+				t.Errorf("EventID = %d, expected %d", received.EventID, want)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatal("timed out waiting for event")
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// reviewStreamKey is the Redis stream holding queued review tasks
+	reviewStreamKey = "codesentry:review_tasks"
+	// reviewDeadLetterStreamKey receives tasks that failed streamMaxDeliveries times
+	reviewDeadLetterStreamKey = "codesentry:review_tasks:dead"
+	// reviewStreamGroup is the consumer group shared by all CodeSentry instances
+	reviewStreamGroup = "codesentry-workers"
+	// reviewStreamMaxLen caps the stream length (approximately)
+	reviewStreamMaxLen = 100000
+	// streamMaxDeliveries matches the asynq backend: the first attempt plus 3 retries
+	streamMaxDeliveries = 4
+	// streamReadBlock is how long an idle consumer waits for new tasks
+	streamReadBlock = 2 * time.Second
+)
+
+// StreamQueue implements TaskQueue on a Redis stream with a consumer group.
+// Delivery is at-least-once: a task is acknowledged after it was processed, and a
+// task not acknowledged within the visibility timeout (e.g. its instance crashed)
+// is claimed by another consumer. Running tasks extend their visibility with a
+// heartbeat, so long reviews are not processed twice. Tasks failing
+// streamMaxDeliveries times are moved to a dead-letter stream.
+type StreamQueue struct {
+	client      *redis.Client
+	visibility  time.Duration
+	concurrency int
+	consumer    string
+	processor   func(context.Context, *ReviewTask) error
+
+	stop    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running bool
+}
+
+// NewStreamQueue connects to Redis and creates the consumer group if needed
+func NewStreamQueue(cfg *config.RedisConfig) (*StreamQueue, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.XGroupCreateMkStream(ctx, reviewStreamKey, reviewStreamGroup, "0").Err(); err != nil && !isBusyGroupErr(err) {
+		client.Close()
+		return nil, fmt.Errorf("create consumer group: %w", err)
+	}
+
+	visibility := time.Duration(cfg.VisibilityTimeout) * time.Second
+	if visibility <= 0 {
+		visibility = config.DefaultVisibilityTimeout * time.Second
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultRedisConcurrency
+	}
+
+	hostname, _ := os.Hostname()
+	queueCtx, queueCancel := context.WithCancel(context.Background())
+	return &StreamQueue{
+		client:      client,
+		visibility:  visibility,
+		concurrency: concurrency,
+		consumer:    fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		stop:        make(chan struct{}),
+		ctx:         queueCtx,
+		cancel:      queueCancel,
+	}, nil
+}
+
+// isBusyGroupErr reports whether creating the consumer group failed because it exists
+func isBusyGroupErr(err error) bool {
+	return strings.HasPrefix(err.Error(), "BUSYGROUP")
+}
+
+// Enqueue appends a review task to the stream
+func (q *StreamQueue) Enqueue(task *ReviewTask) error {
+	task.markQueued()
+	payload, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	id, err := q.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: reviewStreamKey,
+		MaxLen: reviewStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"payload": string(payload)},
+	}).Result()
+	if err != nil {
+		return err
+	}
+	logger.Infof("[StreamQueue] Task enqueued: id=%s, review_log_id=%d", id, task.ReviewLogID)
+	return nil
+}
+
+// IsAsync returns true for the stream queue
+func (q *StreamQueue) IsAsync() bool {
+	return true
+}
+
+// Close stops the consumers and closes the Redis client
+func (q *StreamQueue) Close() error {
+	q.Stop()
+	return q.client.Close()
+}
+
+// SetProcessor sets the function to process review tasks
+func (q *StreamQueue) SetProcessor(processor func(context.Context, *ReviewTask) error) {
+	q.processor = processor
+}
+
+// Start runs the consumers of this instance
+func (q *StreamQueue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.running {
+		return nil
+	}
+	if q.processor == nil {
+		return errors.New("no processor set")
+	}
+	q.running = true
+	for i := 0; i < q.concurrency; i++ {
+		q.wg.Add(1)
+		go q.consume()
+	}
+	logger.Infof("[StreamQueue] Started %d consumers as %s (visibility timeout %s)", q.concurrency, q.consumer, q.visibility)
+	return nil
+}
+
+// Stop stops the consumers. Running tasks get a grace period and are cancelled
+// afterwards; unacknowledged tasks are redelivered after the visibility timeout.
+func (q *StreamQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.running {
+		return
+	}
+	q.running = false
+	close(q.stop)
+	drainConsumers("StreamQueue", &q.wg, q.cancel)
+}
+
+func (q *StreamQueue) consume() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		msg, err := q.next()
+		if err != nil {
+			if q.ctx.Err() != nil {
+				return
+			}
+			logger.Warnf("[StreamQueue] Failed to read tasks: %v", err)
+			select {
+			case <-q.stop:
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if msg != nil {
+			q.handle(msg)
+		}
+	}
+}
+
+// next returns a task whose visibility timeout expired, or else waits for a new task
+func (q *StreamQueue) next() (*redis.XMessage, error) {
+	claimed, _, err := q.client.XAutoClaim(q.ctx, &redis.XAutoClaimArgs{
+		Stream:   reviewStreamKey,
+		Group:    reviewStreamGroup,
+		Consumer: q.consumer,
+		MinIdle:  q.visibility,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	for i := range claimed {
+		msg := claimed[i]
+		deliveries, err := q.deliveries(msg.ID)
+		if err != nil {
+			return nil, err
+		}
+		if deliveries > streamMaxDeliveries {
+			q.deadLetter(&msg, fmt.Sprintf("failed after %d deliveries", deliveries-1))
+			continue
+		}
+		logger.Infof("[StreamQueue] Redelivering task %s (delivery %d)", msg.ID, deliveries)
+		return &msg, nil
+	}
+
+	streams, err := q.client.XReadGroup(q.ctx, &redis.XReadGroupArgs{
+		Group:    reviewStreamGroup,
+		Consumer: q.consumer,
+		Streams:  []string{reviewStreamKey, ">"},
+		Count:    1,
+		Block:    streamReadBlock,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, stream := range streams {
+		if len(stream.Messages) > 0 {
+			return &stream.Messages[0], nil
+		}
+	}
+	return nil, nil
+}
+
+// deliveries returns how often a pending task was delivered
+func (q *StreamQueue) deliveries(id string) (int64, error) {
+	pending, err := q.client.XPendingExt(q.ctx, &redis.XPendingExtArgs{
+		Stream: reviewStreamKey,
+		Group:  reviewStreamGroup,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+	return pending[0].RetryCount, nil
+}
+
+func (q *StreamQueue) handle(msg *redis.XMessage) {
+	task, err := decodeStreamTask(msg)
+	if err != nil {
+		q.deadLetter(msg, err.Error())
+		return
+	}
+
+	heartbeatDone := make(chan struct{})
+	go q.heartbeat(msg.ID, heartbeatDone)
+
+	err = runReviewTask(q.ctx, q.processor, task)
+	close(heartbeatDone)
+
+	if err != nil {
+		// Left unacknowledged: retried once the visibility timeout expires
+		logger.Infof("[StreamQueue] Task %s failed: %v", msg.ID, err)
+		return
+	}
+	if q.ctx.Err() != nil {
+		return
+	}
+	if err := q.client.XAck(context.Background(), reviewStreamKey, reviewStreamGroup, msg.ID).Err(); err != nil {
+		logger.Warnf("[StreamQueue] Failed to acknowledge task %s: %v", msg.ID, err)
+		return
+	}
+	q.client.XDel(context.Background(), reviewStreamKey, msg.ID)
+}
+
+// heartbeat keeps a running task from being claimed by other consumers by
+// resetting its idle time; JUSTID claims do not count as deliveries
+func (q *StreamQueue) heartbeat(id string, done <-chan struct{}) {
+	ticker := time.NewTicker(q.visibility / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			err := q.client.XClaimJustID(q.ctx, &redis.XClaimArgs{
+				Stream:   reviewStreamKey,
+				Group:    reviewStreamGroup,
+				Consumer: q.consumer,
+				Messages: []string{id},
+			}).Err()
+			if err != nil && q.ctx.Err() == nil {
+				logger.Warnf("[StreamQueue] Heartbeat for task %s failed: %v", id, err)
+			}
+		}
+	}
+}
+
+// deadLetter moves a task that cannot be processed to the dead-letter stream
+func (q *StreamQueue) deadLetter(msg *redis.XMessage, reason string) {
+	logger.Warnf("[StreamQueue] Moving task %s to %s: %s", msg.ID, reviewDeadLetterStreamKey, reason)
+	ctx := context.Background()
+	payload, _ := msg.Values["payload"].(string)
+	err := q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: reviewDeadLetterStreamKey,
+		MaxLen: reviewStreamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"payload": payload, "id": msg.ID, "error": reason},
+	}).Err()
+	if err != nil {
+		logger.Warnf("[StreamQueue] Failed to dead-letter task %s: %v", msg.ID, err)
+		return
+	}
+	q.client.XAck(ctx, reviewStreamKey, reviewStreamGroup, msg.ID)
+	q.client.XDel(ctx, reviewStreamKey, msg.ID)
+}
+
+// decodeStreamTask reads the review task of a stream entry
+func decodeStreamTask(msg *redis.XMessage) (*ReviewTask, error) {
+	payload, ok := msg.Values["payload"].(string)
+	if !ok {
+		return nil, fmt.Errorf("stream entry %s has no payload", msg.ID)
+	}
+	var task ReviewTask
+	if err := json.Unmarshal([]byte(payload), &task); err != nil {
+		return nil, fmt.Errorf("invalid payload of stream entry %s: %w", msg.ID, err)
+	}
+	return &task, nil
+}
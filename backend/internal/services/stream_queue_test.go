@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisQueueBackend(t *testing.T) {
+	tests := []struct {
+		backend  string
+		expected string
+	}{
+		{"", config.QueueBackendAsynq},
+		{"asynq", config.QueueBackendAsynq},
+		{"streams", config.QueueBackendStreams},
+	}
+	for _, tt := range tests {
+		cfg := &config.RedisConfig{QueueBackend: tt.backend}
+		if got := redisQueueBackend(cfg); got != tt.expected {
+			t.Errorf("redisQueueBackend(%q) = %q, expected %q", tt.backend, got, tt.expected)
+		}
+	}
+}
+
+func TestDecodeStreamTask(t *testing.T) {
+	msg := &redis.XMessage{ID: "1-0", Values: map[string]interface{}{
+		"payload": `{"review_log_id":7,"project_id":3,"commit_sha":"abc"}`,
+	}}
+	task, err := decodeStreamTask(msg)
+	if err != nil {
+		t.Fatalf("decodeStreamTask: %v", err)
+	}
+	if task.ReviewLogID != 7 || task.ProjectID != 3 || task.CommitSHA != "abc" {
+		t.Errorf("decoded task = %+v", task)
+	}
+
+	if _, err := decodeStreamTask(&redis.XMessage{ID: "2-0", Values: map[string]interface{}{}}); err == nil {
+		t.Error("expected an error for an entry without payload")
+	}
+	if _, err := decodeStreamTask(&redis.XMessage{ID: "3-0", Values: map[string]interface{}{"payload": "{"}}); err == nil {
+		t.Error("expected an error for an invalid payload")
+	}
+}
+
+func TestIsBusyGroupErr(t *testing.T) {
+	if !isBusyGroupErr(errors.New("BUSYGROUP Consumer Group name already exists")) {
+		t.Error("BUSYGROUP error should be detected")
+	}
+	if isBusyGroupErr(errors.New("NOGROUP No such key")) {
+		t.Error("other errors should not be treated as BUSYGROUP")
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// structuredReviewToolName names the JSON schema and the Anthropic tool the review is submitted with
+const structuredReviewToolName = "submit_review"
+
+// structuredReviewInstruction is appended to the prompt when the provider enforces the review schema
+const structuredReviewInstruction = `
+
+---
+Respond with the JSON object defined by the response schema: "summary" is the overall assessment in markdown, "score" the total score from 0 to 100, and "findings" lists each issue with its category, severity, file, line (0 if unknown), description and suggestion.`
+
+// Finding severities of structured reviews
+var findingSeverities = []string{"critical", "major", "minor", "info"}
+
+// structuredReviewSchema is the JSON schema of a structured review. Every property is
+// required and additional properties are rejected, as OpenAI strict mode demands.
+var structuredReviewSchema = map[string]interface{}{
+	"type":                 "object",
+	"additionalProperties": false,
+	"required":             []string{"summary", "score", "findings"},
+	"properties": map[string]interface{}{
+		"summary": map[string]interface{}{"type": "string", "description": "Overall assessment of the change in markdown"},
+		"score":   map[string]interface{}{"type": "integer", "description": "Total score from 0 to 100"},
+		"findings": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"required":             []string{"category", "severity", "title", "file", "line", "description", "suggestion"},
+				"properties": map[string]interface{}{
+					"category":    map[string]interface{}{"type": "string", "enum": FindingCategories},
+					"severity":    map[string]interface{}{"type": "string", "enum": findingSeverities},
+					"title":       map[string]interface{}{"type": "string"},
+					"file":        map[string]interface{}{"type": "string", "description": "Changed file path, empty if not file specific"},
+					"line":        map[string]interface{}{"type": "integer", "description": "Line number, 0 if unknown"},
+					"description": map[string]interface{}{"type": "string"},
+					"suggestion":  map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	},
+}
+
+// structuredReviewSchemaJSON implements json.Marshaler for the OpenAI response format
+type structuredReviewSchemaJSON struct{}
+
+func (structuredReviewSchemaJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(structuredReviewSchema)
+}
+
+// StructuredReview is a review returned in the structured review schema
+type StructuredReview struct {
+	Summary  string              `json:"summary"`
+	Score    float64             `json:"score"`
+	Findings []StructuredFinding `json:"findings"`
+}
+
+type StructuredFinding struct {
+	Category    string `json:"category"`
+	Severity    string `json:"severity"`
+	Title       string `json:"title"`
+	File        string `json:"file"`
+	Line        int    `json:"line"`
+	Description string `json:"description"`
+	Suggestion  string `json:"suggestion"`
+}
+
+// parseStructuredReview decodes a structured review, tolerating a surrounding code fence
+func parseStructuredReview(raw string) (*StructuredReview, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "```") {
+		raw = strings.TrimPrefix(raw[3:], "json")
+		raw = strings.TrimSpace(strings.TrimSuffix(raw, "```"))
+	}
+	var review StructuredReview
+	if err := json.Unmarshal([]byte(raw), &review); err != nil {
+		return nil, err
+	}
+	if review.Score < 0 || review.Score > 100 {
+		return nil, fmt.Errorf("score %.0f out of range", review.Score)
+	}
+	if strings.TrimSpace(review.Summary) == "" && len(review.Findings) == 0 {
+		return nil, fmt.Errorf("empty structured review")
+	}
+	return &review, nil
+}
+
+// Markdown renders the review in the layout finding extraction and notifications expect:
+// the summary, one sub-heading per finding under "Key Issues", then the total score.
+func (r *StructuredReview) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(r.Summary))
+	sb.WriteString("\n\n")
+	if len(r.Findings) > 0 {
+		sb.WriteString("## Key Issues\n\n")
+		for _, f := range r.Findings {
+			sb.WriteString("### " + strings.TrimSpace(f.Title) + "\n\n")
+			meta := []string{"**Category:** " + f.Category}
+			if f.Severity != "" {
+				meta = append(meta, "**Severity:** "+f.Severity)
+			}
+			if f.File != "" {
+				location := f.File
+				if f.Line > 0 {
+					location = fmt.Sprintf("%s:%d", f.File, f.Line)
+				}
+				meta = append(meta, "`"+location+"`")
+			}
+			sb.WriteString(strings.Join(meta, " · ") + "\n\n")
+			if d := strings.TrimSpace(f.Description); d != "" {
+				sb.WriteString(d + "\n\n")
+			}
+			if s := strings.TrimSpace(f.Suggestion); s != "" {
+				sb.WriteString("**Suggestion:** " + s + "\n\n")
+			}
+		}
+	}
+	sb.WriteString(fmt.Sprintf("### Total Score: %.0f/100\n", r.Score))
+	return sb.String()
+}
+
+// applyStructuredReview replaces a structured response with its markdown rendering and
+// score. Responses that are not a valid structured review keep the text score extraction.
+func applyStructuredReview(result *ReviewResult) bool {
+	review, err := parseStructuredReview(result.Content)
+	if err != nil {
+		return false
+	}
+	result.Content = review.Markdown()
+	result.Score = review.Score
+	return true
+}
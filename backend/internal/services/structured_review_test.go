@@ -0,0 +1,105 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStructuredReview(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		wantErr   bool
+		wantScore float64
+	}{
+		{"plain json", `{"summary":"Looks good","score":88,"findings":[]}`, false, 88},
+		{"fenced json", "```json\n{\"summary\":\"ok\",\"score\":70,\"findings\":[]}\n```", false, 70},
+		{"markdown text", "## Review\nTotal Score: 80/100", true, 0},
+		{"score out of range", `{"summary":"x","score":150,"findings":[]}`, true, 0},
+		{"empty review", `{"summary":"","score":50,"findings":[]}`, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review, err := parseStructuredReview(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStructuredReview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && review.Score != tt.wantScore {
+				t.Errorf("score = %v, want %v", review.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestStructuredReviewMarkdown(t *testing.T) {
+	review := &StructuredReview{
+		Summary: "The change adds a login endpoint.",
+		Score:   62,
+		Findings: []StructuredFinding{
+			{Category: FindingCorrectness, Severity: "major", Title: "Password compared in plain text", File: "auth/login.go", Line: 42,
+				Description: "Missing nil check on the user lookup.", Suggestion: "Return early when the user is nil."},
+			{Category: FindingStyle, Severity: "minor", Title: "Unclear variable name", File: "auth/session.go"},
+		},
+	}
+	md := review.Markdown()
+
+	if got := extractScore(md); got != 62 {
+		t.Errorf("extractScore(markdown) = %v, want 62", got)
+	}
+	findings := ExtractFindings(md, []string{"auth/login.go", "auth/session.go"})
+	want := []ExtractedFinding{
+		// The explicit category wins over the security keywords in the title
+		{Category: FindingCorrectness, Title: "Password compared in plain text", File: "auth/login.go", Compliance: ComplianceSecrets},
+		{Category: FindingStyle, Title: "Unclear variable name", File: "auth/session.go"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("ExtractFindings() = %+v, want %+v", findings, want)
+	}
+	for i := range want {
+		if findings[i] != want[i] {
+			t.Errorf("finding %d = %+v, want %+v", i, findings[i], want[i])
+		}
+	}
+}
+
+func TestApplyStructuredReview(t *testing.T) {
+	result := &ReviewResult{Content: `{"summary":"Fine","score":91,"findings":[]}`}
+	if !applyStructuredReview(result) || result.Score != 91 || extractScore(result.Content) != 91 {
+		t.Errorf("applyStructuredReview() = %+v, want rendered review with score 91", result)
+	}
+
+	text := &ReviewResult{Content: "Total Score: 75/100", Score: 75}
+	if applyStructuredReview(text) || text.Content != "Total Score: 75/100" || text.Score != 75 {
+		t.Errorf("applyStructuredReview() changed a text review: %+v", text)
+	}
+}
+
+func TestStructuredReviewSchema(t *testing.T) {
+	if openAIResponseFormat(false) != nil {
+		t.Error("openAIResponseFormat(false) should not request a response format")
+	}
+	format := openAIResponseFormat(true)
+	if format == nil || !format.JSONSchema.Strict || format.JSONSchema.Name != structuredReviewToolName {
+		t.Fatalf("openAIResponseFormat(true) = %+v", format)
+	}
+	data, err := json.Marshal(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		JSONSchema struct {
+			Schema struct {
+				Required []string `json:"required"`
+			} `json:"schema"`
+		} `json:"json_schema"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.JSONSchema.Schema.Required) != 3 {
+		t.Errorf("schema required = %v, want summary, score and findings", decoded.JSONSchema.Schema.Required)
+	}
+	if tool := anthropicReviewTool(); tool.OfTool == nil || tool.OfTool.Name != structuredReviewToolName {
+		t.Errorf("anthropicReviewTool() = %+v", tool)
+	}
+}
@@ -1,6 +1,9 @@
 package services
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -98,10 +101,20 @@ type LDAPConfigResponse struct {
 	UserFilter  string `json:"user_filter"`
 	UseSSL      bool   `json:"use_ssl"`
 	PasswordSet bool   `json:"password_set"`
+
+	GroupAttribute    string             `json:"group_attribute"`
+	GroupMappings     []LDAPGroupMapping `json:"group_mappings"`
+	SyncEnabled       bool               `json:"sync_enabled"`
+	SyncIntervalHours int                `json:"sync_interval_hours"`
 }
 
 func (s *SystemConfigService) GetLDAPConfig() *LDAPConfigResponse {
 	port, _ := strconv.Atoi(s.GetWithDefault("ldap_port", "389"))
+	syncInterval, _ := strconv.Atoi(s.GetWithDefault("ldap_sync_interval_hours", "24"))
+	mappings, _ := ParseLDAPGroupMappings(s.GetWithDefault("ldap_group_mappings", ""))
+	if mappings == nil {
+		mappings = []LDAPGroupMapping{}
+	}
 	return &LDAPConfigResponse{
 		Enabled:     s.GetWithDefault("ldap_enabled", "false") == "true",
 		Host:        s.GetWithDefault("ldap_host", ""),
@@ -111,6 +124,11 @@ func (s *SystemConfigService) GetLDAPConfig() *LDAPConfigResponse {
 		UserFilter:  s.GetWithDefault("ldap_user_filter", "(uid=%s)"),
 		UseSSL:      s.GetWithDefault("ldap_use_ssl", "false") == "true",
 		PasswordSet: s.GetWithDefault("ldap_bind_password", "") != "",
+
+		GroupAttribute:    s.GetWithDefault("ldap_group_attribute", "memberOf"),
+		GroupMappings:     mappings,
+		SyncEnabled:       s.GetWithDefault("ldap_sync_enabled", "false") == "true",
+		SyncIntervalHours: syncInterval,
 	}
 }
 
@@ -123,9 +141,41 @@ type UpdateLDAPConfigRequest struct {
 	BindPassword *string `json:"bind_password"`
 	UserFilter   *string `json:"user_filter"`
 	UseSSL       *bool   `json:"use_ssl"`
+
+	GroupAttribute    *string             `json:"group_attribute"`
+	GroupMappings     *[]LDAPGroupMapping `json:"group_mappings"`
+	SyncEnabled       *bool               `json:"sync_enabled"`
+	SyncIntervalHours *int                `json:"sync_interval_hours" binding:"omitempty,min=1"`
 }
 
 func (s *SystemConfigService) UpdateLDAPConfig(req *UpdateLDAPConfigRequest) error {
+	if req.GroupMappings != nil {
+		if err := ValidateLDAPGroupMappings(*req.GroupMappings); err != nil {
+			return err
+		}
+		data, err := json.Marshal(*req.GroupMappings)
+		if err != nil {
+			return err
+		}
+		if err := s.Set("ldap_group_mappings", string(data)); err != nil {
+			return err
+		}
+	}
+	if req.GroupAttribute != nil {
+		if err := s.Set("ldap_group_attribute", *req.GroupAttribute); err != nil {
+			return err
+		}
+	}
+	if req.SyncEnabled != nil {
+		if err := s.Set("ldap_sync_enabled", strconv.FormatBool(*req.SyncEnabled)); err != nil {
+			return err
+		}
+	}
+	if req.SyncIntervalHours != nil {
+		if err := s.Set("ldap_sync_interval_hours", strconv.Itoa(*req.SyncIntervalHours)); err != nil {
+			return err
+		}
+	}
 	if req.Enabled != nil {
 		if err := s.Set("ldap_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
 			return err
@@ -179,11 +229,14 @@ type DailyReportConfigResponse struct {
 	IMBotIDs       []int  `json:"im_bot_ids"`
 	WorkdaysOnly   bool   `json:"workdays_only"`
 	HolidayCountry string `json:"holiday_country"`
+	HolidayMode    string `json:"holiday_mode"` // skip: drop non-workday reports, shift: fold them into the next workday's report
+	WeeklyDay      int    `json:"weekly_day"`   // Weekday weekly reports are published on, 0 = Sunday
 }
 
 func (s *SystemConfigService) GetDailyReportConfig() *DailyReportConfigResponse {
 	lowScore, _ := strconv.Atoi(s.GetWithDefault("daily_report_low_score", "60"))
 	llmConfigID, _ := strconv.Atoi(s.GetWithDefault("daily_report_llm_config_id", "0"))
+	weeklyDay, _ := strconv.Atoi(s.GetWithDefault("daily_report_weekly_day", "1"))
 	imBotIDsStr := s.GetWithDefault("daily_report_im_bot_ids", "")
 	var imBotIDs []int
 	if imBotIDsStr != "" {
@@ -202,6 +255,8 @@ func (s *SystemConfigService) GetDailyReportConfig() *DailyReportConfigResponse
 		IMBotIDs:       imBotIDs,
 		WorkdaysOnly:   s.GetWithDefault("daily_report_workdays_only", "true") == "true",
 		HolidayCountry: s.GetWithDefault("daily_report_holiday_country", "CN"),
+		HolidayMode:    s.GetWithDefault("daily_report_holiday_mode", "skip"),
+		WeeklyDay:      weeklyDay,
 	}
 }
 
@@ -228,6 +283,8 @@ type UpdateDailyReportConfigRequest struct {
 	IMBotIDs       []int   `json:"im_bot_ids"`
 	WorkdaysOnly   *bool   `json:"workdays_only"`
 	HolidayCountry *string `json:"holiday_country"`
+	HolidayMode    *string `json:"holiday_mode" binding:"omitempty,oneof=skip shift"`
+	WeeklyDay      *int    `json:"weekly_day" binding:"omitempty,min=0,max=6"`
 }
 
 func (s *SystemConfigService) UpdateDailyReportConfig(req *UpdateDailyReportConfigRequest) error {
@@ -275,6 +332,16 @@ func (s *SystemConfigService) UpdateDailyReportConfig(req *UpdateDailyReportConf
 			return err
 		}
 	}
+	if req.HolidayMode != nil {
+		if err := s.Set("daily_report_holiday_mode", *req.HolidayMode); err != nil {
+			return err
+		}
+	}
+	if req.WeeklyDay != nil {
+		if err := s.Set("daily_report_weekly_day", strconv.Itoa(*req.WeeklyDay)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -369,3 +436,792 @@ func (s *SystemConfigService) UpdateFileContextConfig(req *UpdateFileContextConf
 	}
 	return nil
 }
+
+// Review Archive Config - moves review text of old review logs to an archive table
+type ReviewArchiveConfigResponse struct {
+	Enabled          bool `json:"enabled"`
+	ArchiveAfterDays int  `json:"archive_after_days"`
+}
+
+func (s *SystemConfigService) GetReviewArchiveConfig() *ReviewArchiveConfigResponse {
+	days, _ := strconv.Atoi(s.GetWithDefault("review_archive_after_days", "180"))
+	return &ReviewArchiveConfigResponse{
+		Enabled:          s.GetWithDefault("review_archive_enabled", "false") == "true",
+		ArchiveAfterDays: days,
+	}
+}
+
+type UpdateReviewArchiveConfigRequest struct {
+	Enabled          *bool `json:"enabled"`
+	ArchiveAfterDays *int  `json:"archive_after_days" binding:"omitempty,min=1"`
+}
+
+func (s *SystemConfigService) UpdateReviewArchiveConfig(req *UpdateReviewArchiveConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("review_archive_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	if req.ArchiveAfterDays != nil {
+		if err := s.Set("review_archive_after_days", strconv.Itoa(*req.ArchiveAfterDays)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retention Config - per-entity retention in days, 0 keeps data forever
+type RetentionConfigResponse struct {
+	Enabled           bool `json:"enabled"`
+	ReviewTextDays    int  `json:"review_text_days"`    // Clear review text and diffs, keeping scores and stats
+	ReviewLogDays     int  `json:"review_log_days"`     // Permanently delete review logs
+	ReviewArchiveDays int  `json:"review_archive_days"` // Delete archived review content
+	DailyReportDays   int  `json:"daily_report_days"`
+	FeedbackDays      int  `json:"feedback_days"`
+	AIUsageDays       int  `json:"ai_usage_days"`
+	DeletedDays       int  `json:"deleted_days"` // Permanently remove soft-deleted rows
+}
+
+func (s *SystemConfigService) GetRetentionConfig() *RetentionConfigResponse {
+	reviewTextDays, _ := strconv.Atoi(s.GetWithDefault("retention_review_text_days", "0"))
+	reviewLogDays, _ := strconv.Atoi(s.GetWithDefault("retention_review_log_days", "0"))
+	reviewArchiveDays, _ := strconv.Atoi(s.GetWithDefault("retention_review_archive_days", "0"))
+	dailyReportDays, _ := strconv.Atoi(s.GetWithDefault("retention_daily_report_days", "0"))
+	feedbackDays, _ := strconv.Atoi(s.GetWithDefault("retention_feedback_days", "0"))
+	aiUsageDays, _ := strconv.Atoi(s.GetWithDefault("retention_ai_usage_days", "0"))
+	deletedDays, _ := strconv.Atoi(s.GetWithDefault("retention_deleted_days", "0"))
+	return &RetentionConfigResponse{
+		Enabled:           s.GetWithDefault("retention_enabled", "false") == "true",
+		ReviewTextDays:    reviewTextDays,
+		ReviewLogDays:     reviewLogDays,
+		ReviewArchiveDays: reviewArchiveDays,
+		DailyReportDays:   dailyReportDays,
+		FeedbackDays:      feedbackDays,
+		AIUsageDays:       aiUsageDays,
+		DeletedDays:       deletedDays,
+	}
+}
+
+type UpdateRetentionConfigRequest struct {
+	Enabled           *bool `json:"enabled"`
+	ReviewTextDays    *int  `json:"review_text_days" binding:"omitempty,min=0"`
+	ReviewLogDays     *int  `json:"review_log_days" binding:"omitempty,min=0"`
+	ReviewArchiveDays *int  `json:"review_archive_days" binding:"omitempty,min=0"`
+	DailyReportDays   *int  `json:"daily_report_days" binding:"omitempty,min=0"`
+	FeedbackDays      *int  `json:"feedback_days" binding:"omitempty,min=0"`
+	AIUsageDays       *int  `json:"ai_usage_days" binding:"omitempty,min=0"`
+	DeletedDays       *int  `json:"deleted_days" binding:"omitempty,min=0"`
+}
+
+func (s *SystemConfigService) UpdateRetentionConfig(req *UpdateRetentionConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("retention_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	days := map[string]*int{
+		"retention_review_text_days":    req.ReviewTextDays,
+		"retention_review_log_days":     req.ReviewLogDays,
+		"retention_review_archive_days": req.ReviewArchiveDays,
+		"retention_daily_report_days":   req.DailyReportDays,
+		"retention_feedback_days":       req.FeedbackDays,
+		"retention_ai_usage_days":       req.AIUsageDays,
+		"retention_deleted_days":        req.DeletedDays,
+	}
+	for key, value := range days {
+		if value == nil {
+			continue
+		}
+		if err := s.Set(key, strconv.Itoa(*value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Leaderboard Config - opt-in gamification endpoints
+type LeaderboardConfigResponse struct {
+	Enabled    bool `json:"enabled"`
+	MinCommits int  `json:"min_commits"` // Minimum qualifying commits per period to appear on a board
+	MinLines   int  `json:"min_lines"`   // Reviews with fewer changed lines are ignored
+}
+
+func (s *SystemConfigService) GetLeaderboardConfig() *LeaderboardConfigResponse {
+	minCommits, _ := strconv.Atoi(s.GetWithDefault("leaderboard_min_commits", "5"))
+	minLines, _ := strconv.Atoi(s.GetWithDefault("leaderboard_min_lines", "5"))
+	return &LeaderboardConfigResponse{
+		Enabled:    s.GetWithDefault("leaderboard_enabled", "false") == "true",
+		MinCommits: minCommits,
+		MinLines:   minLines,
+	}
+}
+
+type UpdateLeaderboardConfigRequest struct {
+	Enabled    *bool `json:"enabled"`
+	MinCommits *int  `json:"min_commits" binding:"omitempty,min=1"`
+	MinLines   *int  `json:"min_lines" binding:"omitempty,min=0"`
+}
+
+func (s *SystemConfigService) UpdateLeaderboardConfig(req *UpdateLeaderboardConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("leaderboard_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	if req.MinCommits != nil {
+		if err := s.Set("leaderboard_min_commits", strconv.Itoa(*req.MinCommits)); err != nil {
+			return err
+		}
+	}
+	if req.MinLines != nil {
+		if err := s.Set("leaderboard_min_lines", strconv.Itoa(*req.MinLines)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Mention Config - on-demand reviews requested by mentioning the bot in merge request comments
+type MentionConfigResponse struct {
+	BotName string `json:"bot_name"` // Username mentioned to request a review, e.g. codesentry for "@codesentry review"
+}
+
+func (s *SystemConfigService) GetMentionConfig() *MentionConfigResponse {
+	return &MentionConfigResponse{
+		BotName: s.GetWithDefault("mention_bot_name", "codesentry"),
+	}
+}
+
+type UpdateMentionConfigRequest struct {
+	BotName *string `json:"bot_name" binding:"omitempty,min=1,max=100"`
+}
+
+func (s *SystemConfigService) UpdateMentionConfig(req *UpdateMentionConfigRequest) error {
+	if req.BotName != nil {
+		return s.Set("mention_bot_name", strings.TrimPrefix(strings.TrimSpace(*req.BotName), "@"))
+	}
+	return nil
+}
+
+// Notification Link Config - links back to CodeSentry in IM notifications
+type NotificationLinkConfigResponse struct {
+	ExternalURL string `json:"external_url"` // URL of the web UI, e.g. https://codesentry.example.com; card buttons are omitted when empty
+}
+
+func (s *SystemConfigService) GetNotificationLinkConfig() *NotificationLinkConfigResponse {
+	return &NotificationLinkConfigResponse{
+		ExternalURL: strings.TrimRight(s.GetWithDefault("notification_external_url", ""), "/"),
+	}
+}
+
+type UpdateNotificationLinkConfigRequest struct {
+	ExternalURL *string `json:"external_url" binding:"omitempty,max=500"`
+}
+
+func (s *SystemConfigService) UpdateNotificationLinkConfig(req *UpdateNotificationLinkConfigRequest) error {
+	if req.ExternalURL == nil {
+		return nil
+	}
+	externalURL := strings.TrimRight(strings.TrimSpace(*req.ExternalURL), "/")
+	if externalURL != "" {
+		if u, err := url.Parse(externalURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("external_url must be an http(s) URL")
+		}
+	}
+	return s.Set("notification_external_url", externalURL)
+}
+
+// Diff Fetch Config - handling of reviews whose diff can't be fetched from the platform
+type DiffFetchConfigResponse struct {
+	CommitStatus string `json:"commit_status"` // error, pending or success
+	Retry        bool   `json:"retry"`         // Fetch the diff again from the retry scheduler
+}
+
+// Commit statuses of a change whose diff can't be fetched
+const (
+	DiffFetchStatusError   = "error"   // Fail the check
+	DiffFetchStatusPending = "pending" // Keep the check pending until a retry succeeds
+	DiffFetchStatusSuccess = "success" // Don't block the change
+)
+
+func (s *SystemConfigService) GetDiffFetchConfig() *DiffFetchConfigResponse {
+	status := s.GetWithDefault("diff_fetch_commit_status", DiffFetchStatusError)
+	if status != DiffFetchStatusPending && status != DiffFetchStatusSuccess {
+		status = DiffFetchStatusError
+	}
+	return &DiffFetchConfigResponse{
+		CommitStatus: status,
+		Retry:        s.GetWithDefault("diff_fetch_retry", "true") == "true",
+	}
+}
+
+type UpdateDiffFetchConfigRequest struct {
+	CommitStatus *string `json:"commit_status" binding:"omitempty,oneof=error pending success"`
+	Retry        *bool   `json:"retry"`
+}
+
+func (s *SystemConfigService) UpdateDiffFetchConfig(req *UpdateDiffFetchConfigRequest) error {
+	if req.CommitStatus != nil {
+		if err := s.Set("diff_fetch_commit_status", *req.CommitStatus); err != nil {
+			return err
+		}
+	}
+	if req.Retry != nil {
+		if err := s.Set("diff_fetch_retry", strconv.FormatBool(*req.Retry)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Test Coverage Config - flags source changes without matching test changes
+type TestCoverageConfigResponse struct {
+	Enabled bool              `json:"enabled"`
+	Rules   []TestMappingRule `json:"rules"` // Source to test file mapping per language
+}
+
+func (s *SystemConfigService) GetTestCoverageConfig() *TestCoverageConfigResponse {
+	rules, err := ParseTestMappingRules(s.GetWithDefault("test_coverage_rules", ""))
+	if err != nil {
+		rules = DefaultTestMappingRules
+	}
+	return &TestCoverageConfigResponse{
+		Enabled: s.GetWithDefault("test_coverage_enabled", "false") == "true",
+		Rules:   rules,
+	}
+}
+
+type UpdateTestCoverageConfigRequest struct {
+	Enabled *bool              `json:"enabled"`
+	Rules   *[]TestMappingRule `json:"rules"` // An empty list restores the default rules
+}
+
+func (s *SystemConfigService) UpdateTestCoverageConfig(req *UpdateTestCoverageConfigRequest) error {
+	if req.Rules != nil {
+		raw := ""
+		if len(*req.Rules) > 0 {
+			if err := ValidateTestMappingRules(*req.Rules); err != nil {
+				return err
+			}
+			data, err := json.Marshal(*req.Rules)
+			if err != nil {
+				return err
+			}
+			raw = string(data)
+		}
+		if err := s.Set("test_coverage_rules", raw); err != nil {
+			return err
+		}
+	}
+	if req.Enabled != nil {
+		if err := s.Set("test_coverage_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Scorecard Config - KPIs shown on member scorecards
+type ScorecardConfigResponse struct {
+	KPIs []ScorecardKPI `json:"kpis"`
+}
+
+func (s *SystemConfigService) GetScorecardConfig() *ScorecardConfigResponse {
+	kpis, err := ParseScorecardKPIs(s.GetWithDefault("scorecard_kpis", ""))
+	if err != nil {
+		kpis = DefaultScorecardKPIs
+	}
+	return &ScorecardConfigResponse{KPIs: kpis}
+}
+
+type UpdateScorecardConfigRequest struct {
+	KPIs *[]ScorecardKPI `json:"kpis"` // An empty list restores the default KPIs
+}
+
+func (s *SystemConfigService) UpdateScorecardConfig(req *UpdateScorecardConfigRequest) error {
+	if req.KPIs != nil {
+		raw := ""
+		if len(*req.KPIs) > 0 {
+			if err := ValidateScorecardKPIs(*req.KPIs); err != nil {
+				return err
+			}
+			data, err := json.Marshal(*req.KPIs)
+			if err != nil {
+				return err
+			}
+			raw = string(data)
+		}
+		if err := s.Set("scorecard_kpis", raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shadow Review Config - a shadow LLM config or prompt silently reviews sampled traffic
+type ShadowReviewConfigResponse struct {
+	Enabled     bool    `json:"enabled"`
+	LLMConfigID uint    `json:"llm_config_id"`
+	PromptID    uint    `json:"prompt_id"`   // Prompt template of the shadow review, 0 = the primary prompt
+	SampleRate  float64 `json:"sample_rate"` // Percentage of reviews shadowed
+}
+
+func (s *SystemConfigService) GetShadowReviewConfig() *ShadowReviewConfigResponse {
+	llmConfigID, _ := strconv.ParseUint(s.GetWithDefault("shadow_review_llm_config_id", "0"), 10, 64)
+	promptID, _ := strconv.ParseUint(s.GetWithDefault("shadow_review_prompt_id", "0"), 10, 64)
+	sampleRate, _ := strconv.ParseFloat(s.GetWithDefault("shadow_review_sample_rate", "10"), 64)
+	return &ShadowReviewConfigResponse{
+		Enabled:     s.GetWithDefault("shadow_review_enabled", "false") == "true",
+		LLMConfigID: uint(llmConfigID),
+		PromptID:    uint(promptID),
+		SampleRate:  sampleRate,
+	}
+}
+
+type UpdateShadowReviewConfigRequest struct {
+	Enabled     *bool    `json:"enabled"`
+	LLMConfigID *uint    `json:"llm_config_id"`
+	PromptID    *uint    `json:"prompt_id"`
+	SampleRate  *float64 `json:"sample_rate" binding:"omitempty,min=0,max=100"`
+}
+
+func (s *SystemConfigService) UpdateShadowReviewConfig(req *UpdateShadowReviewConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("shadow_review_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	if req.LLMConfigID != nil {
+		if err := s.Set("shadow_review_llm_config_id", strconv.FormatUint(uint64(*req.LLMConfigID), 10)); err != nil {
+			return err
+		}
+	}
+	if req.PromptID != nil {
+		if err := s.Set("shadow_review_prompt_id", strconv.FormatUint(uint64(*req.PromptID), 10)); err != nil {
+			return err
+		}
+	}
+	if req.SampleRate != nil {
+		if err := s.Set("shadow_review_sample_rate", strconv.FormatFloat(*req.SampleRate, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Human Verdict Config - how maintainers' verdicts on reviews are applied
+type HumanVerdictConfigResponse struct {
+	Gating       bool `json:"gating"`         // Verdicts decide pass/fail: accepted passes, rejected fails, a human score replaces the AI score
+	ScoreInStats bool `json:"score_in_stats"` // Human scores replace AI scores in dashboard averages
+}
+
+func (s *SystemConfigService) GetHumanVerdictConfig() *HumanVerdictConfigResponse {
+	return &HumanVerdictConfigResponse{
+		Gating:       s.GetWithDefault("human_verdict_gating", "true") == "true",
+		ScoreInStats: s.GetWithDefault("human_verdict_score_in_stats", "false") == "true",
+	}
+}
+
+type UpdateHumanVerdictConfigRequest struct {
+	Gating       *bool `json:"gating"`
+	ScoreInStats *bool `json:"score_in_stats"`
+}
+
+func (s *SystemConfigService) UpdateHumanVerdictConfig(req *UpdateHumanVerdictConfigRequest) error {
+	if req.Gating != nil {
+		if err := s.Set("human_verdict_gating", strconv.FormatBool(*req.Gating)); err != nil {
+			return err
+		}
+	}
+	if req.ScoreInStats != nil {
+		if err := s.Set("human_verdict_score_in_stats", strconv.FormatBool(*req.ScoreInStats)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LLM Outage Config - when failures of the whole LLM chain raise the "AI review degraded" alert
+type LLMOutageConfigResponse struct {
+	Threshold     int `json:"threshold"`      // Chain failures that raise the alert, 0 = disabled
+	WindowMinutes int `json:"window_minutes"` // Window the failures are counted in
+}
+
+func (s *SystemConfigService) GetLLMOutageConfig() *LLMOutageConfigResponse {
+	threshold, err := strconv.Atoi(s.GetWithDefault("llm_outage_threshold", "3"))
+	if err != nil || threshold < 0 {
+		threshold = 3
+	}
+	window, err := strconv.Atoi(s.GetWithDefault("llm_outage_window_minutes", "10"))
+	if err != nil || window <= 0 {
+		window = 10
+	}
+	return &LLMOutageConfigResponse{
+		Threshold:     threshold,
+		WindowMinutes: window,
+	}
+}
+
+type UpdateLLMOutageConfigRequest struct {
+	Threshold     *int `json:"threshold" binding:"omitempty,min=0,max=1000"`
+	WindowMinutes *int `json:"window_minutes" binding:"omitempty,min=1,max=1440"`
+}
+
+func (s *SystemConfigService) UpdateLLMOutageConfig(req *UpdateLLMOutageConfigRequest) error {
+	if req.Threshold != nil {
+		if err := s.Set("llm_outage_threshold", strconv.Itoa(*req.Threshold)); err != nil {
+			return err
+		}
+	}
+	if req.WindowMinutes != nil {
+		if err := s.Set("llm_outage_window_minutes", strconv.Itoa(*req.WindowMinutes)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backpressure Config - when webhook deliveries are deferred because reviews are over capacity
+type BackpressureConfigResponse struct {
+	Enabled           bool `json:"enabled"`
+	MaxQueueDepth     int  `json:"max_queue_depth"`     // Reviews waiting for a worker, 0 = no limit
+	MaxInFlight       int  `json:"max_in_flight"`       // Reviews being processed, 0 = no limit
+	RetryAfterSeconds int  `json:"retry_after_seconds"` // Retry-After of the 202 sent for deferred deliveries
+}
+
+func (s *SystemConfigService) GetBackpressureConfig() *BackpressureConfigResponse {
+	limit := func(key, def string) int {
+		n, err := strconv.Atoi(s.GetWithDefault(key, def))
+		if err != nil || n < 0 {
+			n, _ = strconv.Atoi(def)
+		}
+		return n
+	}
+	retryAfter := limit("backpressure_retry_after_seconds", "60")
+	if retryAfter == 0 {
+		retryAfter = 60
+	}
+	return &BackpressureConfigResponse{
+		Enabled:           s.GetWithDefault("backpressure_enabled", "false") == "true",
+		MaxQueueDepth:     limit("backpressure_max_queue_depth", "200"),
+		MaxInFlight:       limit("backpressure_max_in_flight", "50"),
+		RetryAfterSeconds: retryAfter,
+	}
+}
+
+type UpdateBackpressureConfigRequest struct {
+	Enabled           *bool `json:"enabled"`
+	MaxQueueDepth     *int  `json:"max_queue_depth" binding:"omitempty,min=0,max=100000"`
+	MaxInFlight       *int  `json:"max_in_flight" binding:"omitempty,min=0,max=10000"`
+	RetryAfterSeconds *int  `json:"retry_after_seconds" binding:"omitempty,min=1,max=3600"`
+}
+
+func (s *SystemConfigService) UpdateBackpressureConfig(req *UpdateBackpressureConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("backpressure_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	for key, value := range map[string]*int{
+		"backpressure_max_queue_depth":     req.MaxQueueDepth,
+		"backpressure_max_in_flight":       req.MaxInFlight,
+		"backpressure_retry_after_seconds": req.RetryAfterSeconds,
+	} {
+		if value == nil {
+			continue
+		}
+		if err := s.Set(key, strconv.Itoa(*value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Error Notify Config - deduplication and rate limits of the error alerts sent to IM bots
+type ErrorNotifyConfigResponse struct {
+	DedupMinutes int `json:"dedup_minutes"`  // Identical alerts in the window are collapsed into one message with a counter, 0 = off
+	BotRateLimit int `json:"bot_rate_limit"` // Alerts each bot receives per hour at most, 0 = unlimited
+}
+
+func (s *SystemConfigService) GetErrorNotifyConfig() *ErrorNotifyConfigResponse {
+	dedup, err := strconv.Atoi(s.GetWithDefault("error_notify_dedup_minutes", "10"))
+	if err != nil || dedup < 0 {
+		dedup = 10
+	}
+	rateLimit, err := strconv.Atoi(s.GetWithDefault("error_notify_bot_rate_limit", "30"))
+	if err != nil || rateLimit < 0 {
+		rateLimit = 30
+	}
+	return &ErrorNotifyConfigResponse{
+		DedupMinutes: dedup,
+		BotRateLimit: rateLimit,
+	}
+}
+
+type UpdateErrorNotifyConfigRequest struct {
+	DedupMinutes *int `json:"dedup_minutes" binding:"omitempty,min=0,max=1440"`
+	BotRateLimit *int `json:"bot_rate_limit" binding:"omitempty,min=0,max=10000"`
+}
+
+func (s *SystemConfigService) UpdateErrorNotifyConfig(req *UpdateErrorNotifyConfigRequest) error {
+	if req.DedupMinutes != nil {
+		if err := s.Set("error_notify_dedup_minutes", strconv.Itoa(*req.DedupMinutes)); err != nil {
+			return err
+		}
+	}
+	if req.BotRateLimit != nil {
+		if err := s.Set("error_notify_bot_rate_limit", strconv.Itoa(*req.BotRateLimit)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Log Shipping Config - forwards system logs to an external syslog server or Loki
+type LogShippingConfigResponse struct {
+	Enabled  bool   `json:"enabled"`
+	Type     string `json:"type"`      // syslog or loki
+	Endpoint string `json:"endpoint"`  // udp://host:514 or tcp://host:514 for syslog, base URL such as http://loki:3100 for Loki
+	MinLevel string `json:"min_level"` // Lowest level shipped: info, warning or error
+	TenantID string `json:"tenant_id"` // Loki X-Scope-OrgID header, optional
+}
+
+func (s *SystemConfigService) GetLogShippingConfig() *LogShippingConfigResponse {
+	minLevel := s.GetWithDefault("log_shipping_min_level", "info")
+	if _, ok := systemLogLevels[minLevel]; !ok {
+		minLevel = "info"
+	}
+	return &LogShippingConfigResponse{
+		Enabled:  s.GetWithDefault("log_shipping_enabled", "false") == "true",
+		Type:     s.GetWithDefault("log_shipping_type", LogShippingSyslog),
+		Endpoint: s.GetWithDefault("log_shipping_endpoint", ""),
+		MinLevel: minLevel,
+		TenantID: s.GetWithDefault("log_shipping_tenant_id", ""),
+	}
+}
+
+type UpdateLogShippingConfigRequest struct {
+	Enabled  *bool   `json:"enabled"`
+	Type     *string `json:"type" binding:"omitempty,oneof=syslog loki"`
+	Endpoint *string `json:"endpoint"`
+	MinLevel *string `json:"min_level" binding:"omitempty,oneof=info warning error"`
+	TenantID *string `json:"tenant_id"`
+}
+
+func (s *SystemConfigService) UpdateLogShippingConfig(req *UpdateLogShippingConfigRequest) error {
+	current := s.GetLogShippingConfig()
+	shippingType, endpoint, enabled := current.Type, current.Endpoint, current.Enabled
+	if req.Type != nil {
+		shippingType = *req.Type
+	}
+	if req.Endpoint != nil {
+		endpoint = strings.TrimSpace(*req.Endpoint)
+	}
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	if enabled || endpoint != "" {
+		if err := ValidateLogShippingEndpoint(shippingType, endpoint); err != nil {
+			return err
+		}
+	}
+
+	defer invalidateLogShippingConfig()
+	if req.Type != nil {
+		if err := s.Set("log_shipping_type", shippingType); err != nil {
+			return err
+		}
+	}
+	if req.Endpoint != nil {
+		if err := s.Set("log_shipping_endpoint", endpoint); err != nil {
+			return err
+		}
+	}
+	if req.MinLevel != nil {
+		if err := s.Set("log_shipping_min_level", *req.MinLevel); err != nil {
+			return err
+		}
+	}
+	if req.TenantID != nil {
+		if err := s.Set("log_shipping_tenant_id", strings.TrimSpace(*req.TenantID)); err != nil {
+			return err
+		}
+	}
+	if req.Enabled != nil {
+		if err := s.Set("log_shipping_enabled", strconv.FormatBool(enabled)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IP Allow-List Config
+type IPAllowListConfigResponse struct {
+	Admin   []string `json:"admin"`   // CIDR ranges allowed to reach the admin API, empty allows all
+	Webhook []string `json:"webhook"` // CIDR ranges allowed to deliver webhooks, e.g. platform egress ranges
+}
+
+func (s *SystemConfigService) GetIPAllowListConfig() *IPAllowListConfigResponse {
+	return &IPAllowListConfigResponse{
+		Admin:   splitCIDRList(s.GetWithDefault("ip_allowlist_admin", "")),
+		Webhook: splitCIDRList(s.GetWithDefault("ip_allowlist_webhook", "")),
+	}
+}
+
+type UpdateIPAllowListConfigRequest struct {
+	Admin   *[]string `json:"admin"`
+	Webhook *[]string `json:"webhook"`
+}
+
+func (s *SystemConfigService) UpdateIPAllowListConfig(req *UpdateIPAllowListConfigRequest) error {
+	if req.Admin != nil {
+		if _, err := ParseCIDRList(*req.Admin); err != nil {
+			return err
+		}
+		if err := s.Set("ip_allowlist_admin", joinCIDRList(*req.Admin)); err != nil {
+			return err
+		}
+	}
+	if req.Webhook != nil {
+		if _, err := ParseCIDRList(*req.Webhook); err != nil {
+			return err
+		}
+		if err := s.Set("ip_allowlist_webhook", joinCIDRList(*req.Webhook)); err != nil {
+			return err
+		}
+	}
+	invalidateIPAllowListCache()
+	return nil
+}
+
+func splitCIDRList(raw string) []string {
+	entries := []string{}
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func joinCIDRList(entries []string) string {
+	return strings.Join(splitCIDRList(strings.Join(entries, ",")), ",")
+}
+
+// Compliance Report Config - protected branches and the scheduled compliance report
+type ComplianceReportConfigResponse struct {
+	ProtectedBranches string `json:"protected_branches"` // Branch patterns besides each project's default branch: release/*,hotfix/*
+	Enabled           bool   `json:"enabled"`            // Send the report of each past period to the bots
+	Period            string `json:"period"`             // weekly or monthly
+	IMBotIDs          []uint `json:"im_bot_ids"`
+}
+
+func (s *SystemConfigService) GetComplianceReportConfig() *ComplianceReportConfigResponse {
+	period := s.GetWithDefault("compliance_report_period", ComplianceReportWeekly)
+	if period != ComplianceReportMonthly {
+		period = ComplianceReportWeekly
+	}
+	botIDs := []uint{}
+	for _, idStr := range splitAndTrim(s.GetWithDefault("compliance_report_im_bot_ids", ""), ",") {
+		if id, err := strconv.ParseUint(idStr, 10, 32); err == nil {
+			botIDs = append(botIDs, uint(id))
+		}
+	}
+	return &ComplianceReportConfigResponse{
+		ProtectedBranches: s.GetWithDefault("compliance_protected_branches", ""),
+		Enabled:           s.GetWithDefault("compliance_report_enabled", "false") == "true",
+		Period:            period,
+		IMBotIDs:          botIDs,
+	}
+}
+
+type UpdateComplianceReportConfigRequest struct {
+	ProtectedBranches *string `json:"protected_branches"`
+	Enabled           *bool   `json:"enabled"`
+	Period            *string `json:"period" binding:"omitempty,oneof=weekly monthly"`
+	IMBotIDs          *[]uint `json:"im_bot_ids"`
+}
+
+func (s *SystemConfigService) UpdateComplianceReportConfig(req *UpdateComplianceReportConfigRequest) error {
+	if req.ProtectedBranches != nil {
+		if err := s.Set("compliance_protected_branches", strings.Join(splitAndTrim(*req.ProtectedBranches, ","), ",")); err != nil {
+			return err
+		}
+	}
+	if req.Enabled != nil {
+		if err := s.Set("compliance_report_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	if req.Period != nil {
+		if err := s.Set("compliance_report_period", *req.Period); err != nil {
+			return err
+		}
+	}
+	if req.IMBotIDs != nil {
+		ids := make([]string, len(*req.IMBotIDs))
+		for i, id := range *req.IMBotIDs {
+			ids[i] = strconv.FormatUint(uint64(id), 10)
+		}
+		if err := s.Set("compliance_report_im_bot_ids", strings.Join(ids, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Coverage Gap Config - periodic check for commits that never triggered a webhook
+type CoverageGapConfigResponse struct {
+	Enabled       bool `json:"enabled"`
+	IntervalHours int  `json:"interval_hours"` // Hours between two scans
+	LookbackHours int  `json:"lookback_hours"` // Commits of the last hours are checked
+	AutoReview    bool `json:"auto_review"`    // Queue reviews of the missed commits
+}
+
+func (s *SystemConfigService) GetCoverageGapConfig() *CoverageGapConfigResponse {
+	interval, _ := strconv.Atoi(s.GetWithDefault("coverage_gap_interval_hours", "6"))
+	if interval <= 0 {
+		interval = 6
+	}
+	lookback, _ := strconv.Atoi(s.GetWithDefault("coverage_gap_lookback_hours", "48"))
+	if lookback <= 0 {
+		lookback = 48
+	}
+	return &CoverageGapConfigResponse{
+		Enabled:       s.GetWithDefault("coverage_gap_enabled", "false") == "true",
+		IntervalHours: interval,
+		LookbackHours: lookback,
+		AutoReview:    s.GetWithDefault("coverage_gap_auto_review", "false") == "true",
+	}
+}
+
+type UpdateCoverageGapConfigRequest struct {
+	Enabled       *bool `json:"enabled"`
+	IntervalHours *int  `json:"interval_hours" binding:"omitempty,min=1"`
+	LookbackHours *int  `json:"lookback_hours" binding:"omitempty,min=1,max=720"`
+	AutoReview    *bool `json:"auto_review"`
+}
+
+func (s *SystemConfigService) UpdateCoverageGapConfig(req *UpdateCoverageGapConfigRequest) error {
+	if req.Enabled != nil {
+		if err := s.Set("coverage_gap_enabled", strconv.FormatBool(*req.Enabled)); err != nil {
+			return err
+		}
+	}
+	if req.IntervalHours != nil {
+		if err := s.Set("coverage_gap_interval_hours", strconv.Itoa(*req.IntervalHours)); err != nil {
+			return err
+		}
+	}
+	if req.LookbackHours != nil {
+		if err := s.Set("coverage_gap_lookback_hours", strconv.Itoa(*req.LookbackHours)); err != nil {
+			return err
+		}
+	}
+	if req.AutoReview != nil {
+		if err := s.Set("coverage_gap_auto_review", strconv.FormatBool(*req.AutoReview)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
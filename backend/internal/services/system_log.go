@@ -1,9 +1,12 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/huangang/codesentry/backend/internal/models"
@@ -17,20 +20,36 @@ func InitSystemLogger(db *gorm.DB) {
 	globalDB = db
 }
 
-func LogInfo(module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
-	writeLog("info", module, action, message, userID, ip, userAgent, extra)
+// LogInfo writes an info system log; the request ID carried by ctx, if any, is recorded with it
+func LogInfo(ctx context.Context, module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
+	writeLog(ctx, "info", module, action, message, userID, ip, userAgent, extra)
 }
 
-func LogWarning(module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
-	writeLog("warning", module, action, message, userID, ip, userAgent, extra)
+func LogWarning(ctx context.Context, module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
+	writeLog(ctx, "warning", module, action, message, userID, ip, userAgent, extra)
 }
 
-func LogError(module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
-	writeLog("error", module, action, message, userID, ip, userAgent, extra)
-	go sendErrorNotification(module, action, message, extra)
+func LogError(ctx context.Context, module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
+	writeLog(ctx, "error", module, action, message, userID, ip, userAgent, extra)
+	go sendErrorNotification(logger.RequestID(ctx), module, action, message, extra)
 }
 
-func sendErrorNotification(module, action, message string, extra interface{}) {
+func sendErrorNotification(requestID, module, action, message string, extra interface{}) {
+	if globalDB == nil {
+		return
+	}
+	window := time.Duration(NewSystemConfigService(globalDB).GetErrorNotifyConfig().DedupMinutes) * time.Minute
+	if !errorNotifies.admit(module, action, message, window, func(fp errorFingerprint) {
+		notifyErrorBots(buildRepeatedErrorMessage(fp, window))
+	}) {
+		return
+	}
+	notifyErrorBots(buildErrorMessage(requestID, module, action, message, extra))
+}
+
+// notifyErrorBots sends a message to the active bots with error notification enabled,
+// within each bot's alert rate limit
+func notifyErrorBots(message string) {
 	if globalDB == nil {
 		return
 	}
@@ -45,23 +64,34 @@ func sendErrorNotification(module, action, message string, extra interface{}) {
 		return
 	}
 
+	limit := NewSystemConfigService(globalDB).GetErrorNotifyConfig().BotRateLimit
 	notificationService := NewNotificationService(globalDB)
-	errorMsg := buildErrorMessage(module, action, message, extra)
-
 	for _, bot := range bots {
-		if err := notificationService.SendErrorNotification(&bot, errorMsg); err != nil {
+		allowed, dropped := errorNotifies.allowBot(bot.ID, limit)
+		if !allowed {
+			logger.Warnf("[SystemLog] Error notification to bot %s dropped by its rate limit", bot.Name)
+			continue
+		}
+		botMessage := message
+		if dropped > 0 {
+			botMessage += fmt.Sprintf("\n\n_%d earlier alert(s) were dropped by the rate limit of %d per hour_", dropped, limit)
+		}
+		if err := notificationService.SendErrorNotification(&bot, botMessage); err != nil {
 			logger.Errorf("[SystemLog] Failed to send error notification to bot %s: %v", bot.Name, err)
 		}
 	}
 }
 
-func buildErrorMessage(module, action, message string, extra interface{}) string {
+func buildErrorMessage(requestID, module, action, message string, extra interface{}) string {
 	msg := fmt.Sprintf(`🚨 **System Error Alert**
 
 **Module**: %s
 **Action**: %s
 **Message**: %s
 **Time**: %s`, module, action, message, time.Now().Format("2006-01-02 15:04:05"))
+	if requestID != "" {
+		msg += "\n**Request ID**: " + requestID
+	}
 
 	if extra != nil {
 		if b, err := json.Marshal(extra); err == nil && len(b) > 2 {
@@ -76,7 +106,7 @@ func buildErrorMessage(module, action, message string, extra interface{}) string
 	return msg
 }
 
-func writeLog(level, module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
+func writeLog(ctx context.Context, level, module, action, message string, userID *uint, ip, userAgent string, extra interface{}) {
 	if globalDB == nil {
 		return
 	}
@@ -97,9 +127,13 @@ func writeLog(level, module, action, message string, userID *uint, ip, userAgent
 		IP:        ip,
 		UserAgent: userAgent,
 		Extra:     extraStr,
+		RequestID: logger.RequestID(ctx),
 		CreatedAt: time.Now(),
 	}
-	globalDB.Create(sysLog)
+	if err := globalDB.Create(sysLog).Error; err != nil {
+		return
+	}
+	shipLog(sysLog)
 }
 
 type SystemLogService struct {
@@ -115,14 +149,101 @@ func NewSystemLogService(db *gorm.DB) *SystemLogService {
 }
 
 type SystemLogListRequest struct {
-	Page      int    `form:"page" binding:"omitempty,min=1"`
-	PageSize  int    `form:"page_size" binding:"omitempty,min=1,max=100"`
-	Level     string `form:"level"`
-	Module    string `form:"module"`
-	Action    string `form:"action"`
-	StartDate string `form:"start_date"`
-	EndDate   string `form:"end_date"`
-	Search    string `form:"search"`
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+	SystemLogFilter
+}
+
+// SystemLogFilter selects system logs by their structured fields
+type SystemLogFilter struct {
+	Level     string   `form:"level"`  // Level, or comma-separated levels
+	Module    string   `form:"module"` // Module, or comma-separated modules
+	Action    string   `form:"action"`
+	StartDate string   `form:"start_date"`
+	EndDate   string   `form:"end_date"`
+	Since     string   `form:"since"` // RFC 3339 time, more precise than start_date
+	Until     string   `form:"until"` // RFC 3339 time, more precise than end_date
+	Search    string   `form:"search"`
+	RequestID string   `form:"request_id"` // Logs of one request, see middleware.RequestID
+	Extra     []string `form:"extra"`      // key:value pairs the extra JSON must all contain
+}
+
+// apply adds the filter's conditions to a system log query
+func (f *SystemLogFilter) apply(query *gorm.DB) (*gorm.DB, error) {
+	if levels := splitFilterList(f.Level); len(levels) > 0 {
+		query = query.Where("level IN ?", levels)
+	}
+	if modules := splitFilterList(f.Module); len(modules) > 0 {
+		query = query.Where("module IN ?", modules)
+	}
+	if f.Action != "" {
+		query = query.Where(LikeCondition("action"), ContainsPattern(f.Action))
+	}
+	if f.StartDate != "" {
+		query = query.Where("created_at >= ?", f.StartDate)
+	}
+	if f.EndDate != "" {
+		query = query.Where("created_at <= ?", f.EndDate+" 23:59:59")
+	}
+	if f.Since != "" {
+		since, err := time.Parse(time.RFC3339, f.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+		query = query.Where("created_at >= ?", since)
+	}
+	if f.Until != "" {
+		until, err := time.Parse(time.RFC3339, f.Until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+		query = query.Where("created_at <= ?", until)
+	}
+	if f.Search != "" {
+		query = query.Where(LikeCondition("message"), ContainsPattern(f.Search))
+	}
+	if f.RequestID != "" {
+		query = query.Where("request_id = ?", f.RequestID)
+	}
+	for _, pair := range f.Extra {
+		key, value, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid extra filter %q, expected key:value", pair)
+		}
+		patterns := extraMatchPatterns(strings.TrimSpace(key), strings.TrimSpace(value))
+		conds := make([]string, len(patterns))
+		args := make([]interface{}, len(patterns))
+		for i, pattern := range patterns {
+			conds[i] = LikeCondition("extra")
+			args[i] = pattern
+		}
+		query = query.Where("("+strings.Join(conds, " OR ")+")", args...)
+	}
+	return query, nil
+}
+
+// extraMatchPatterns returns the LIKE patterns matching a key of the extra JSON holding
+// value, either as a string or as a number, boolean or null literal. The extra JSON is
+// written by encoding/json, so it has no whitespace between keys and values.
+func extraMatchPatterns(key, value string) []string {
+	k, _ := json.Marshal(key)
+	v, _ := json.Marshal(value)
+	field := string(k) + ":"
+	patterns := []string{ContainsPattern(field + string(v))}
+	if json.Valid([]byte(value)) && !strings.HasPrefix(value, `"`) && !strings.ContainsAny(value, "{[") {
+		patterns = append(patterns, ContainsPattern(field+value+","), ContainsPattern(field+value+"}"))
+	}
+	return patterns
+}
+
+func splitFilterList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 type SystemLogListResponse struct {
@@ -143,25 +264,9 @@ func (s *SystemLogService) List(req *SystemLogListRequest) (*SystemLogListRespon
 	var logs []models.SystemLog
 	var total int64
 
-	query := s.db.Model(&models.SystemLog{})
-
-	if req.Level != "" {
-		query = query.Where("level = ?", req.Level)
-	}
-	if req.Module != "" {
-		query = query.Where("module = ?", req.Module)
-	}
-	if req.Action != "" {
-		query = query.Where("action LIKE ?", "%"+req.Action+"%")
-	}
-	if req.StartDate != "" {
-		query = query.Where("created_at >= ?", req.StartDate)
-	}
-	if req.EndDate != "" {
-		query = query.Where("created_at <= ?", req.EndDate+" 23:59:59")
-	}
-	if req.Search != "" {
-		query = query.Where("message LIKE ?", "%"+req.Search+"%")
+	query, err := req.SystemLogFilter.apply(s.db.Model(&models.SystemLog{}))
+	if err != nil {
+		return nil, err
 	}
 
 	query.Count(&total)
@@ -179,6 +284,40 @@ func (s *SystemLogService) List(req *SystemLogListRequest) (*SystemLogListRespon
 	}, nil
 }
 
+// ListSince returns up to limit logs matching the filter with an ID above afterID, oldest first
+func (s *SystemLogService) ListSince(filter *SystemLogFilter, afterID uint, limit int) ([]models.SystemLog, error) {
+	query, err := filter.apply(s.db.Model(&models.SystemLog{}).Where("id > ?", afterID))
+	if err != nil {
+		return nil, err
+	}
+	var logs []models.SystemLog
+	if err := query.Order("id ASC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// ListRecent returns the latest limit logs matching the filter, oldest first
+func (s *SystemLogService) ListRecent(filter *SystemLogFilter, limit int) ([]models.SystemLog, error) {
+	query, err := filter.apply(s.db.Model(&models.SystemLog{}))
+	if err != nil {
+		return nil, err
+	}
+	var logs []models.SystemLog
+	if err := query.Order("id DESC").Limit(limit).Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	slices.Reverse(logs)
+	return logs, nil
+}
+
+// LatestID returns the ID of the newest log, or 0 if there is none
+func (s *SystemLogService) LatestID() (uint, error) {
+	var id uint
+	err := s.db.Model(&models.SystemLog{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
 func (s *SystemLogService) GetModules() ([]string, error) {
 	var modules []string
 	if err := s.db.Model(&models.SystemLog{}).Distinct("module").Pluck("module", &modules).Error; err != nil {
@@ -230,7 +369,8 @@ func StartLogCleanupScheduler(db *gorm.DB) {
 		service := NewSystemLogService(db)
 
 		// Run cleanup immediately on startup
-		runCleanup(service)
+		RegisterScheduler("log_cleanup", 24*time.Hour)
+		RunScheduled("log_cleanup", func() error { runCleanup(service); return nil })
 
 		// Then run every 24 hours
 		ticker := time.NewTicker(24 * time.Hour)
@@ -239,7 +379,7 @@ func StartLogCleanupScheduler(db *gorm.DB) {
 		for {
 			select {
 			case <-ticker.C:
-				runCleanup(service)
+				RunScheduled("log_cleanup", func() error { runCleanup(service); return nil })
 			case <-logCleanupStopChan:
 				logger.Infof("[SystemLog] Log cleanup scheduler stopped")
 				return
@@ -256,6 +396,17 @@ func StopLogCleanupScheduler() {
 }
 
 func runCleanup(service *SystemLogService) {
+	// Polling tokens and idempotency keys expire after a day
+	if err := service.db.Where("expires_at < ?", time.Now()).Delete(&models.ReviewPoll{}).Error; err != nil {
+		logger.Errorf("[SystemLog] Failed to cleanup expired review polls: %v", err)
+	}
+	if err := service.db.Where("expires_at < ?", time.Now()).Delete(&models.IdempotencyKey{}).Error; err != nil {
+		logger.Errorf("[SystemLog] Failed to cleanup expired idempotency keys: %v", err)
+	}
+	if err := service.db.Where("received_at < ?", time.Now().Add(-WebhookReplayWindow)).Delete(&models.WebhookDelivery{}).Error; err != nil {
+		logger.Errorf("[SystemLog] Failed to cleanup webhook delivery IDs: %v", err)
+	}
+
 	retentionDays := service.GetRetentionDays()
 	if retentionDays <= 0 {
 		logger.Infof("[SystemLog] Log cleanup disabled (retention_days <= 0)")
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtraMatchPatterns(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+		want  []string
+	}{
+		{"string", "branch", "main", []string{`%"branch":"main"%`}},
+		{"number", "project_id", "42", []string{`%"project!_id":"42"%`, `%"project!_id":42,%`, `%"project!_id":42}%`}},
+		{"boolean", "retried", "true", []string{`%"retried":"true"%`, `%"retried":true,%`, `%"retried":true}%`}},
+		{"quotes are escaped", "msg", `say "hi"`, []string{`%"msg":"say \"hi\""%`}},
+		{"objects only match as strings", "obj", `{"a":1}`, []string{`%"obj":"{\"a\":1}"%`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extraMatchPatterns(tt.key, tt.value); !slices.Equal(got, tt.want) {
+				t.Errorf("extraMatchPatterns(%q, %q) = %q, want %q", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitFilterList(t *testing.T) {
+	if got := splitFilterList(" error, warning ,,"); !slices.Equal(got, []string{"error", "warning"}) {
+		t.Errorf("splitFilterList() = %q", got)
+	}
+	if got := splitFilterList(""); got != nil {
+		t.Errorf("splitFilterList(\"\") = %q, want nil", got)
+	}
+}
+
+func TestSystemLogFilterApplyRejectsInvalidInput(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter SystemLogFilter
+		want   string
+	}{
+		{"since", SystemLogFilter{Since: "yesterday"}, "invalid since"},
+		{"until", SystemLogFilter{Until: "2026-01-02"}, "invalid until"},
+		{"extra without value", SystemLogFilter{Extra: []string{"project_id"}}, "invalid extra filter"},
+		{"extra without key", SystemLogFilter{Extra: []string{":42"}}, "invalid extra filter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.filter.apply(nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("apply() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
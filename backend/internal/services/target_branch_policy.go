@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// TargetBranchPolicyInput sets review rules for merge requests into matching target branches
+type TargetBranchPolicyInput struct {
+	BranchPattern       string  `json:"branch_pattern" binding:"required"`
+	MinScore            float64 `json:"min_score" binding:"min=0,max=100"`
+	MaxCriticalFindings *int    `json:"max_critical_findings" binding:"omitempty,min=0"`
+	ReviewTemplateID    *uint   `json:"review_template_id"`
+	Urgent              bool    `json:"urgent"`
+}
+
+// replaceTargetBranchPolicies replaces all target branch policies of a project, whose review
+// templates must be usable by the project's tenant
+func replaceTargetBranchPolicies(tx *gorm.DB, projectID, tenantID uint, inputs []TargetBranchPolicyInput) error {
+	for _, in := range inputs {
+		if in.ReviewTemplateID != nil && !ReviewTemplateInTenant(tx, *in.ReviewTemplateID, tenantID) {
+			return fmt.Errorf("review template %d not found", *in.ReviewTemplateID)
+		}
+	}
+
+	if err := tx.Where("project_id = ?", projectID).Delete(&models.TargetBranchPolicy{}).Error; err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		policy := models.TargetBranchPolicy{
+			ProjectID:           projectID,
+			BranchPattern:       strings.TrimSpace(in.BranchPattern),
+			MinScore:            in.MinScore,
+			MaxCriticalFindings: in.MaxCriticalFindings,
+			ReviewTemplateID:    in.ReviewTemplateID,
+			Urgent:              in.Urgent,
+		}
+		if err := tx.Create(&policy).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveTargetBranchPolicy picks the policy of a merge request into targetBranch: the most
+// specific matching branch pattern, then the earliest created policy. Pushes have no target
+// branch and no policy.
+func ResolveTargetBranchPolicy(policies []models.TargetBranchPolicy, targetBranch string) *models.TargetBranchPolicy {
+	if targetBranch == "" {
+		return nil
+	}
+	var candidates []models.TargetBranchPolicy
+	for _, p := range policies {
+		if matchBranchPattern(p.BranchPattern, targetBranch) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if sa, sb := branchSpecificity(a.BranchPattern), branchSpecificity(b.BranchPattern); sa != sb {
+			return sa > sb
+		}
+		return a.ID < b.ID
+	})
+	return &candidates[0]
+}
+
+// CountCriticalFindings counts the security and correctness findings of a review
+func CountCriticalFindings(review string) int {
+	count := 0
+	for _, f := range ExtractFindings(review, nil) {
+		if criticalFindingCategories[f.Category] {
+			count++
+		}
+	}
+	return count
+}
+
+// CriticalFindingsExceeded returns why a review has more critical findings than the policy
+// allows, empty when it doesn't or the policy sets no limit
+func CriticalFindingsExceeded(policy *models.TargetBranchPolicy, review string) string {
+	if policy == nil || policy.MaxCriticalFindings == nil {
+		return ""
+	}
+	if count := CountCriticalFindings(review); count > *policy.MaxCriticalFindings {
+		return fmt.Sprintf("%d critical findings (max %d into %s)", count, *policy.MaxCriticalFindings, policy.BranchPattern)
+	}
+	return ""
+}
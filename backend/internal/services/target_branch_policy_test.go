@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestResolveTargetBranchPolicy(t *testing.T) {
+	policies := []models.TargetBranchPolicy{
+		{ID: 1, BranchPattern: "release/*", MinScore: 85},
+		{ID: 2, BranchPattern: "release/1.0", MinScore: 90},
+		{ID: 3, BranchPattern: "main", MinScore: 70},
+		{ID: 4, BranchPattern: "release/*", MinScore: 80}, // shadowed by ID 1
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		want   uint
+	}{
+		{"glob", "release/2.0", 1},
+		{"exact beats glob", "release/1.0", 2},
+		{"exact", "main", 3},
+		{"no match", "develop", 0},
+		{"push", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uint
+			if p := ResolveTargetBranchPolicy(policies, tt.target); p != nil {
+				got = p.ID
+			}
+			if got != tt.want {
+				t.Errorf("ResolveTargetBranchPolicy(%q) = %d, want %d", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriticalFindingsExceeded(t *testing.T) {
+	review := `Overall fine.
+
+## Key Issues
+
+### Unescaped user input
+
+**Category:** security · ` + "`api/user.go:12`" + `
+
+The name is concatenated into the SQL query.
+
+### Long function
+
+**Category:** style
+
+Split it up.
+
+### Total Score: 88/100
+`
+	zero, one := 0, 1
+	tests := []struct {
+		name   string
+		policy *models.TargetBranchPolicy
+		want   bool
+	}{
+		{"no policy", nil, false},
+		{"no limit", &models.TargetBranchPolicy{BranchPattern: "release/*"}, false},
+		{"zero critical", &models.TargetBranchPolicy{BranchPattern: "release/*", MaxCriticalFindings: &zero}, true},
+		{"one allowed", &models.TargetBranchPolicy{BranchPattern: "release/*", MaxCriticalFindings: &one}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CriticalFindingsExceeded(tt.policy, review); (got != "") != tt.want {
+				t.Errorf("CriticalFindingsExceeded() = %q, want exceeded %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceTargetBranchPolicies_TenantTemplates(t *testing.T) {
+	db := newTestDB(t)
+	other := models.User{Username: "other", TenantID: 2}
+	mustCreate(t, db, &other)
+	shared := models.ReviewTemplate{Name: "Shared", Type: "general", Content: "review"}
+	foreign := models.ReviewTemplate{Name: "Foreign", Type: "general", Content: "review", CreatedBy: other.ID}
+	mustCreate(t, db, &shared)
+	mustCreate(t, db, &foreign)
+
+	policy := func(templateID uint) []TargetBranchPolicyInput {
+		return []TargetBranchPolicyInput{{BranchPattern: "main", ReviewTemplateID: &templateID}}
+	}
+	if err := replaceTargetBranchPolicies(db, 1, 1, policy(foreign.ID)); err == nil {
+		t.Error("policy with another tenant's template accepted")
+	}
+	if err := replaceTemplateBindings(db, 1, 1, []TemplateBindingInput{{TemplateID: foreign.ID}}); err == nil {
+		t.Error("binding to another tenant's template accepted")
+	}
+	if err := replaceTargetBranchPolicies(db, 1, 1, policy(shared.ID)); err != nil {
+		t.Fatalf("policy with a shared template: %v", err)
+	}
+	if err := replaceTargetBranchPolicies(db, 1, 2, policy(foreign.ID)); err != nil {
+		t.Fatalf("policy with the tenant's own template: %v", err)
+	}
+}
@@ -3,11 +3,15 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/huangang/codesentry/backend/pkg/logger"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/huangang/codesentry/backend/internal/config"
+	"github.com/huangang/codesentry/backend/internal/models"
 )
 
 const (
@@ -16,23 +20,37 @@ const (
 
 // ReviewTask represents a review job to be processed
 type ReviewTask struct {
-	ReviewLogID   uint   `json:"review_log_id"`
-	ProjectID     uint   `json:"project_id"`
-	CommitSHA     string `json:"commit_sha"`
-	EventType     string `json:"event_type"` // push, merge_request
-	Branch        string `json:"branch"`
-	Author        string `json:"author"`
-	AuthorEmail   string `json:"author_email"`
-	AuthorAvatar  string `json:"author_avatar"`
-	CommitMessage string `json:"commit_message"`
-	Diff          string `json:"diff"`
-	CommitURL     string `json:"commit_url"`
-	MRNumber      *int   `json:"mr_number,omitempty"`
-	MRURL         string `json:"mr_url,omitempty"`
+	ReviewLogID   uint     `json:"review_log_id"`
+	ProjectID     uint     `json:"project_id"`
+	CommitSHA     string   `json:"commit_sha"`
+	EventType     string   `json:"event_type"` // push, merge_request, release
+	Branch        string   `json:"branch"`
+	Author        string   `json:"author"`
+	AuthorEmail   string   `json:"author_email"`
+	AuthorAvatar  string   `json:"author_avatar"`
+	CommitMessage string   `json:"commit_message"`
+	Diff          string   `json:"diff"`
+	CommitURL     string   `json:"commit_url"`
+	MRNumber      *int     `json:"mr_number,omitempty"`
+	MRURL         string   `json:"mr_url,omitempty"`
+	PreviousTag   string   `json:"previous_tag,omitempty"`  // Release events: tag the changes are compared against
+	TargetBranch  string   `json:"target_branch,omitempty"` // Merge request events: branch the changes merge into
+	CommitSHAs    []string `json:"commit_shas,omitempty"`   // Push events: the pushed commits, oldest first
+	Requested     bool     `json:"requested,omitempty"`     // Requested in a comment: the result is always posted as a comment
+	RequestID     string   `json:"request_id,omitempty"`    // Request that queued the task, to correlate its logs
+	PushHead      string   `json:"push_head,omitempty"`     // Per-commit reviews: head commit of the push, notified once all its commits are reviewed
+	QueuedAt      int64    `json:"queued_at,omitempty"`     // Unix milliseconds the task was first queued, see ReviewLog.QueueWaitMs
 	// GitLab specific
 	GitLabProjectID int `json:"gitlab_project_id,omitempty"`
 }
 
+// markQueued stamps the time the task is queued, unless it was queued before
+func (t *ReviewTask) markQueued() {
+	if t.QueuedAt == 0 {
+		t.QueuedAt = time.Now().UnixMilli()
+	}
+}
+
 // TaskQueue defines the interface for review task processing
 type TaskQueue interface {
 	// Enqueue adds a task to the queue
@@ -52,23 +70,101 @@ var (
 // InitTaskQueue initializes the global task queue based on config
 func InitTaskQueue(cfg *config.Config) TaskQueue {
 	taskQueueOnce.Do(func() {
-		if cfg.Redis.Enabled {
-			queue, err := NewAsyncQueue(&cfg.Redis)
+		if cfg.Queue.Backend != "" {
+			queue, err := newBrokerQueue(&cfg.Queue)
+			if err != nil {
+				logger.Infof("[TaskQueue] %s unavailable, falling back to sync mode: %v", cfg.Queue.Backend, err)
+				globalTaskQueue = newConfiguredSyncQueue(cfg)
+			} else {
+				logger.Infof("[TaskQueue] Async queue (%s) initialized", cfg.Queue.Backend)
+				globalTaskQueue = queue
+			}
+		} else if cfg.Redis.Enabled {
+			queue, err := newRedisQueue(&cfg.Redis)
 			if err != nil {
 				logger.Infof("[TaskQueue] Redis unavailable, falling back to sync mode: %v", err)
-				globalTaskQueue = NewSyncQueue()
+				globalTaskQueue = newConfiguredSyncQueue(cfg)
 			} else {
-				logger.Infof("[TaskQueue] Async queue initialized with Redis at %s", cfg.Redis.Addr)
+				logger.Infof("[TaskQueue] Async queue (%s) initialized with Redis at %s", redisQueueBackend(&cfg.Redis), cfg.Redis.Addr)
 				globalTaskQueue = queue
 			}
 		} else {
 			logger.Infof("[TaskQueue] Sync queue initialized (Redis disabled)")
-			globalTaskQueue = NewSyncQueue()
+			globalTaskQueue = newConfiguredSyncQueue(cfg)
 		}
 	})
 	return globalTaskQueue
 }
 
+// redisQueueBackend returns the configured Redis queue backend, defaulting to asynq. Unknown
+// backends are rejected when the configuration is loaded.
+func redisQueueBackend(cfg *config.RedisConfig) string {
+	if cfg.QueueBackend == config.QueueBackendStreams {
+		return config.QueueBackendStreams
+	}
+	return config.QueueBackendAsynq
+}
+
+// newRedisQueue creates the task queue of the configured Redis backend
+func newRedisQueue(cfg *config.RedisConfig) (TaskQueue, error) {
+	if redisQueueBackend(cfg) == config.QueueBackendStreams {
+		return NewStreamQueue(cfg)
+	}
+	return NewAsyncQueue(cfg)
+}
+
+// runReviewTask processes a task of a broker queue within the review timeout, turning a panic
+// into an error
+func runReviewTask(ctx context.Context, processor func(context.Context, *ReviewTask) error, task *ReviewTask) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, reviewTaskTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return processor(ctx, task)
+}
+
+// drainConsumers waits for the consumers of a broker queue to finish their running tasks,
+// cancelling them after the shutdown grace period
+func drainConsumers(name string, wg *sync.WaitGroup, cancel context.CancelFunc) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(queueShutdownGrace):
+		logger.Warnf("[%s] Cancelling running reviews on shutdown", name)
+		cancel()
+		<-done
+	}
+	cancel()
+	logger.Infof("[%s] Shutdown complete", name)
+}
+
+// newBrokerQueue creates the task queue of the configured broker, which consumes its own tasks
+func newBrokerQueue(cfg *config.QueueConfig) (TaskQueue, error) {
+	switch cfg.Backend {
+	case config.QueueBackendNATS:
+		return NewNATSQueue(cfg)
+	case config.QueueBackendKafka:
+		return NewKafkaQueue(cfg)
+	}
+	return nil, fmt.Errorf("unsupported queue backend %q", cfg.Backend)
+}
+
+// newConfiguredSyncQueue creates the sync queue, persisting tasks in the database when available
+func newConfiguredSyncQueue(cfg *config.Config) *SyncQueue {
+	var store PendingTaskStore = NewMemoryTaskStore()
+	if db := models.GetDB(); db != nil {
+		store = NewDBTaskStore(db)
+	}
+	return NewSyncQueueWithStore(store, cfg.Queue.Workers)
+}
+
 // GetTaskQueue returns the global task queue instance
 func GetTaskQueue() TaskQueue {
 	return globalTaskQueue
@@ -76,7 +172,8 @@ func GetTaskQueue() TaskQueue {
 
 // AsyncQueue implements TaskQueue using asynq (Redis-based)
 type AsyncQueue struct {
-	client *asynq.Client
+	client    *asynq.Client
+	inspector *asynq.Inspector
 }
 
 // NewAsyncQueue creates a new Redis-based async queue
@@ -91,20 +188,21 @@ func NewAsyncQueue(cfg *config.RedisConfig) (*AsyncQueue, error) {
 
 	// Test connection by pinging Redis
 	inspector := asynq.NewInspector(redisOpt)
-	defer inspector.Close()
 
 	// Try to get queue info to verify connection
 	_, err := inspector.Queues()
 	if err != nil {
+		inspector.Close()
 		client.Close()
 		return nil, err
 	}
 
-	return &AsyncQueue{client: client}, nil
+	return &AsyncQueue{client: client, inspector: inspector}, nil
 }
 
 // Enqueue adds a review task to the async queue
 func (q *AsyncQueue) Enqueue(task *ReviewTask) error {
+	task.markQueued()
 	payload, err := json.Marshal(task)
 	if err != nil {
 		return err
@@ -130,40 +228,184 @@ func (q *AsyncQueue) IsAsync() bool {
 
 // Close closes the async queue client
 func (q *AsyncQueue) Close() error {
+	q.inspector.Close()
 	return q.client.Close()
 }
 
-// SyncQueue implements TaskQueue with synchronous processing (no Redis)
+// SyncQueue implements TaskQueue without Redis: tasks are processed in the background
+// by a bounded pool of workers. Queued tasks are kept in a PendingTaskStore, so with the
+// database store they survive a restart.
 type SyncQueue struct {
 	processor func(context.Context, *ReviewTask) error
+	store     PendingTaskStore
+	workers   int
+
+	wake     chan struct{}
+	stop     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	active   atomic.Int64
+	started  bool
+	mu       sync.Mutex
+	stopOnce sync.Once
 }
 
-// NewSyncQueue creates a new synchronous queue
+const (
+	// DefaultQueueWorkers is the number of reviews processed concurrently in sync mode
+	DefaultQueueWorkers = 4
+	// reviewTaskTimeout bounds the processing time of a single review
+	reviewTaskTimeout = 10 * time.Minute
+	// syncQueuePollInterval is how often idle workers check the store for missed tasks
+	syncQueuePollInterval = 10 * time.Second
+	// queueShutdownGrace is how long Close waits for running reviews before cancelling them
+	queueShutdownGrace = 30 * time.Second
+)
+
+// NewSyncQueue creates an in-memory queue with the default number of workers
 func NewSyncQueue() *SyncQueue {
-	return &SyncQueue{}
+	return NewSyncQueueWithStore(NewMemoryTaskStore(), DefaultQueueWorkers)
 }
 
-// SetProcessor sets the function to process tasks synchronously
+// NewSyncQueueWithStore creates a queue keeping its tasks in store and processing
+// at most workers tasks at a time
+func NewSyncQueueWithStore(store PendingTaskStore, workers int) *SyncQueue {
+	if workers <= 0 {
+		workers = DefaultQueueWorkers
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SyncQueue{
+		store:   store,
+		workers: workers,
+		wake:    make(chan struct{}, workers),
+		stop:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// SetProcessor sets the function to process tasks
 func (q *SyncQueue) SetProcessor(processor func(context.Context, *ReviewTask) error) {
 	q.processor = processor
 }
 
-// Enqueue processes the task immediately in the current goroutine
+// Start recovers tasks interrupted by a previous shutdown and starts the workers
+func (q *SyncQueue) Start() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started || q.processor == nil {
+		return
+	}
+	q.started = true
+
+	if n, err := q.store.Recover(reviewTaskTimeout); err != nil {
+		logger.Warnf("[SyncQueue] Failed to recover interrupted tasks: %v", err)
+	} else if n > 0 {
+		logger.Infof("[SyncQueue] Re-queued %d interrupted review tasks", n)
+	}
+	if n, err := q.store.Count(); err == nil && n > 0 {
+		logger.Infof("[SyncQueue] Resuming %d pending review tasks", n)
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	logger.Infof("[SyncQueue] Started %d workers", q.workers)
+}
+
+// Enqueue stores the task and wakes an idle worker. Tasks enqueued before
+// Start are processed once the workers run.
 func (q *SyncQueue) Enqueue(task *ReviewTask) error {
-	if q.processor == nil {
-		logger.Infof("[SyncQueue] Warning: no processor set, task will be dropped")
-		return nil
+	task.markQueued()
+	if err := q.store.Add(task); err != nil {
+		return err
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+		// All workers are busy or already woken; the task is picked up when one is free
 	}
+	return nil
+}
 
-	// Process in a goroutine to not block the webhook response
-	go func() {
-		ctx := context.Background()
-		if err := q.processor(ctx, task); err != nil {
-			logger.Infof("[SyncQueue] Task processing failed: %v", err)
+// Pending returns the number of queued tasks, including the ones being processed
+func (q *SyncQueue) Pending() int64 {
+	n, err := q.store.Count()
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// Active returns the number of tasks being processed
+func (q *SyncQueue) Active() int64 {
+	return q.active.Load()
+}
+
+// Workers returns the size of the worker pool
+func (q *SyncQueue) Workers() int {
+	return q.workers
+}
+
+func (q *SyncQueue) work() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(syncQueuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		pending, err := q.store.Claim()
+		if err != nil {
+			logger.Warnf("[SyncQueue] Failed to claim task: %v", err)
+		}
+		if pending == nil {
+			select {
+			case <-q.stop:
+				return
+			case <-q.wake:
+			case <-ticker.C:
+			}
+			continue
 		}
+		q.process(pending)
+	}
+}
+
+func (q *SyncQueue) process(pending *PendingTask) {
+	q.active.Add(1)
+	defer q.active.Add(-1)
+
+	ctx, cancel := context.WithTimeout(q.ctx, reviewTaskTimeout)
+	defer cancel()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic: %v", r)
+			}
+		}()
+		return q.processor(ctx, pending.Task)
 	}()
+	if err != nil {
+		logger.Infof("[SyncQueue] Task processing failed: %v", err)
+	}
 
-	return nil
+	// Tasks cancelled by shutdown are kept and resumed after the restart
+	if q.ctx.Err() != nil {
+		if err := q.store.Requeue(pending.ID); err != nil {
+			logger.Warnf("[SyncQueue] Failed to requeue task %d: %v", pending.ID, err)
+		}
+		return
+	}
+	if err := q.store.Done(pending.ID); err != nil {
+		logger.Warnf("[SyncQueue] Failed to remove task %d: %v", pending.ID, err)
+	}
 }
 
 // IsAsync returns false for sync queue
@@ -171,7 +413,24 @@ func (q *SyncQueue) IsAsync() bool {
 	return false
 }
 
-// Close is a no-op for sync queue
+// Close stops the workers. Running reviews get a grace period to finish and are
+// cancelled afterwards; queued tasks stay in the store.
 func (q *SyncQueue) Close() error {
+	q.stopOnce.Do(func() {
+		close(q.stop)
+		done := make(chan struct{})
+		go func() {
+			q.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(queueShutdownGrace):
+			logger.Warnf("[SyncQueue] Cancelling %d running reviews on shutdown", q.active.Load())
+			q.cancel()
+			<-done
+		}
+		q.cancel()
+	})
 	return nil
 }
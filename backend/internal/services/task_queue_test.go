@@ -2,7 +2,9 @@ package services
 
 import (
 	"context"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestTaskTypeReview_Constant(t *testing.T) {
@@ -146,3 +148,100 @@ func TestAsyncQueue_IsAsync(t *testing.T) {
 		t.Error("AsyncQueue.IsAsync() should return true")
 	}
 }
+
+func TestSyncQueue_BoundedConcurrency(t *testing.T) {
+	const workers, tasks = 2, 8
+	queue := NewSyncQueueWithStore(NewMemoryTaskStore(), workers)
+
+	var running, maxRunning, processed atomic.Int64
+	release := make(chan struct{})
+	done := make(chan struct{}, tasks)
+	queue.SetProcessor(func(ctx context.Context, task *ReviewTask) error {
+		n := running.Add(1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		processed.Add(1)
+		done <- struct{}{}
+		return nil
+	})
+
+	for i := 0; i < tasks; i++ {
+		if err := queue.Enqueue(&ReviewTask{ReviewLogID: uint(i + 1)}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	queue.Start()
+	defer queue.Close()
+
+	close(release)
+	for i := 0; i < tasks; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d tasks processed", processed.Load(), tasks)
+		}
+	}
+
+	if got := maxRunning.Load(); got > workers {
+		t.Errorf("max concurrent tasks = %d, expected at most %d", got, workers)
+	}
+	if queue.Pending() != 0 {
+		t.Errorf("Pending() = %d after processing, expected 0", queue.Pending())
+	}
+}
+
+func TestSyncQueue_CloseKeepsQueuedTasks(t *testing.T) {
+	store := NewMemoryTaskStore()
+	queue := NewSyncQueueWithStore(store, 1)
+	queue.SetProcessor(func(ctx context.Context, task *ReviewTask) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	queue.Enqueue(&ReviewTask{ReviewLogID: 1})
+	queue.Enqueue(&ReviewTask{ReviewLogID: 2})
+	queue.Start()
+
+	for queue.Active() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	queue.cancel() // skip the shutdown grace period
+	queue.Close()
+
+	if n, _ := store.Count(); n != 2 {
+		t.Errorf("store holds %d tasks after Close, expected 2", n)
+	}
+}
+
+func TestMemoryTaskStore_ClaimOrder(t *testing.T) {
+	store := NewMemoryTaskStore()
+	store.Add(&ReviewTask{ReviewLogID: 1})
+	store.Add(&ReviewTask{ReviewLogID: 2})
+
+	first, _ := store.Claim()
+	if first == nil || first.Task.ReviewLogID != 1 {
+		t.Fatalf("first claim = %+v, expected review 1", first)
+	}
+	store.Requeue(first.ID)
+
+	again, _ := store.Claim()
+	if again == nil || again.Task.ReviewLogID != 1 {
+		t.Fatalf("claim after requeue = %+v, expected review 1", again)
+	}
+	store.Done(again.ID)
+
+	second, _ := store.Claim()
+	if second == nil || second.Task.ReviewLogID != 2 {
+		t.Fatalf("second claim = %+v, expected review 2", second)
+	}
+	store.Done(second.ID)
+
+	if task, _ := store.Claim(); task != nil {
+		t.Errorf("claim on empty store = %+v, expected nil", task)
+	}
+}
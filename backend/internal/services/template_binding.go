@@ -0,0 +1,105 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// TemplateBindingInput binds a review template to a project for matching events and branches
+type TemplateBindingInput struct {
+	TemplateID    uint   `json:"template_id" binding:"required"`
+	EventType     string `json:"event_type" binding:"omitempty,oneof=push merge_request"`
+	BranchPattern string `json:"branch_pattern"`
+	Priority      int    `json:"priority"`
+}
+
+// replaceTemplateBindings replaces all template bindings of a project, whose review templates
+// must be usable by the project's tenant
+func replaceTemplateBindings(tx *gorm.DB, projectID, tenantID uint, inputs []TemplateBindingInput) error {
+	for _, in := range inputs {
+		if !ReviewTemplateInTenant(tx, in.TemplateID, tenantID) {
+			return fmt.Errorf("review template %d not found", in.TemplateID)
+		}
+	}
+
+	if err := tx.Where("project_id = ?", projectID).Delete(&models.ProjectTemplateBinding{}).Error; err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		binding := models.ProjectTemplateBinding{
+			ProjectID:     projectID,
+			TemplateID:    in.TemplateID,
+			EventType:     in.EventType,
+			BranchPattern: strings.TrimSpace(in.BranchPattern),
+			Priority:      in.Priority,
+		}
+		if err := tx.Create(&binding).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchBranchPattern reports whether branch matches pattern.
+// Patterns ending in * match by prefix (release/* matches release/1.2/hotfix), others use glob syntax.
+func matchBranchPattern(pattern, branch string) bool {
+	if pattern == "" || pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") && !strings.ContainsAny(strings.TrimSuffix(pattern, "*"), "*?[") {
+		return strings.HasPrefix(branch, strings.TrimSuffix(pattern, "*"))
+	}
+	matched, _ := path.Match(pattern, branch)
+	return matched
+}
+
+// branchSpecificity ranks branch patterns: exact names beat globs, longer globs beat shorter ones
+// and an empty pattern (any branch) ranks lowest
+func branchSpecificity(pattern string) int {
+	if pattern == "" || pattern == "*" {
+		return 0
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return 10000
+	}
+	return 1 + len(pattern)
+}
+
+// resolveTemplateBinding picks the binding that applies to an event on a branch.
+// Precedence: most specific branch pattern, then a specific event type over any event,
+// then the higher priority, then the earliest created binding.
+func resolveTemplateBinding(bindings []models.ProjectTemplateBinding, eventType, branch string) *models.ProjectTemplateBinding {
+	var candidates []models.ProjectTemplateBinding
+	for _, b := range bindings {
+		if b.EventType != "" && b.EventType != eventType {
+			continue
+		}
+		if b.BranchPattern != "" && (branch == "" || !matchBranchPattern(b.BranchPattern, branch)) {
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if sa, sb := branchSpecificity(a.BranchPattern), branchSpecificity(b.BranchPattern); sa != sb {
+			return sa > sb
+		}
+		if (a.EventType != "") != (b.EventType != "") {
+			return a.EventType != ""
+		}
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return a.ID < b.ID
+	})
+	return &candidates[0]
+}
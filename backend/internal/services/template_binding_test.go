@@ -0,0 +1,70 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestMatchBranchPattern(t *testing.T) {
+	tests := []struct {
+		pattern, branch string
+		want            bool
+	}{
+		{"", "main", true},
+		{"main", "main", true},
+		{"main", "master", false},
+		{"release/*", "release/1.2", true},
+		{"release/*", "release/1.2/hotfix", true},
+		{"release/*", "feature/x", false},
+		{"feature/*-fast", "feature/a-fast", true},
+		{"v[0-9]*", "v1", true},
+	}
+
+	for _, tt := range tests {
+		if got := matchBranchPattern(tt.pattern, tt.branch); got != tt.want {
+			t.Errorf("matchBranchPattern(%q, %q) = %v, want %v", tt.pattern, tt.branch, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTemplateBinding(t *testing.T) {
+	bindings := []models.ProjectTemplateBinding{
+		{ID: 1, TemplateID: 10},                                                       // any event, any branch
+		{ID: 2, TemplateID: 20, EventType: "merge_request"},                           // MRs on any branch
+		{ID: 3, TemplateID: 30, BranchPattern: "release/*"},                           // strict release reviews
+		{ID: 4, TemplateID: 40, BranchPattern: "feature/*", EventType: "push"},        // fast feature pushes
+		{ID: 5, TemplateID: 50, BranchPattern: "release/*", Priority: 5},              // outranks ID 3
+		{ID: 6, TemplateID: 60, BranchPattern: "release/1.0", EventType: "push"},      // exact branch
+		{ID: 7, TemplateID: 70, BranchPattern: "release/hotfix/*", EventType: "push"}, // longer glob
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		branch    string
+		want      uint
+	}{
+		{"fallback binding", "push", "main", 10},
+		{"event type over any", "merge_request", "main", 20},
+		{"branch over event type", "merge_request", "release/2.0", 50},
+		{"priority breaks ties", "push", "release/2.0", 50},
+		{"exact branch wins", "push", "release/1.0", 60},
+		{"longer glob wins", "push", "release/hotfix/x", 70},
+		{"event filter excludes", "merge_request", "feature/x", 20},
+		{"event and branch", "push", "feature/x", 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveTemplateBinding(bindings, tt.eventType, tt.branch)
+			if got == nil || got.TemplateID != tt.want {
+				t.Errorf("resolveTemplateBinding(%q, %q) = %+v, want template %d", tt.eventType, tt.branch, got, tt.want)
+			}
+		})
+	}
+
+	if got := resolveTemplateBinding(bindings[3:4], "merge_request", "main"); got != nil {
+		t.Errorf("expected no binding, got %+v", got)
+	}
+}
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// TemplateBundleVersion is the format version of exported template bundles
+const TemplateBundleVersion = 1
+
+// Template import conflict policies, applied when a template with the same name exists
+const (
+	TemplateConflictSkip      = "skip"
+	TemplateConflictOverwrite = "overwrite"
+	TemplateConflictRename    = "rename"
+)
+
+// ErrInvalidTemplateBundle is returned for bundles of another format or with incomplete templates
+var ErrInvalidTemplateBundle = errors.New("invalid template bundle")
+
+// TemplateBundle is a shareable JSON export of review and prompt templates
+type TemplateBundle struct {
+	Version         int                     `json:"version"`
+	ExportedAt      time.Time               `json:"exported_at"`
+	ReviewTemplates []BundledReviewTemplate `json:"review_templates"`
+	PromptTemplates []BundledPromptTemplate `json:"prompt_templates"`
+}
+
+type BundledReviewTemplate struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+type BundledPromptTemplate struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+	Variables   string `json:"variables"`
+}
+
+type TemplateExportRequest struct {
+	ReviewTemplateIDs string `form:"review_template_ids"` // Comma-separated; when both lists are empty, every template is exported
+	PromptTemplateIDs string `form:"prompt_template_ids"` // Comma-separated
+}
+
+type TemplateImportRequest struct {
+	OnConflict string `form:"on_conflict"` // skip (default), overwrite, rename
+}
+
+// TemplateImportItem is the outcome of one imported template
+type TemplateImportItem struct {
+	Kind   string `json:"kind"` // review_template, prompt_template
+	Name   string `json:"name"` // Name the template was stored with
+	Action string `json:"action"`
+	ID     uint   `json:"id,omitempty"`
+}
+
+type TemplateImportResult struct {
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Skipped int                  `json:"skipped"`
+	Items   []TemplateImportItem `json:"items"`
+}
+
+func (r *TemplateImportResult) add(kind, name, action string, id uint) {
+	switch action {
+	case "created":
+		r.Created++
+	case "updated":
+		r.Updated++
+	default:
+		r.Skipped++
+	}
+	r.Items = append(r.Items, TemplateImportItem{Kind: kind, Name: name, Action: action, ID: id})
+}
+
+// TemplateBundleService exports and imports template bundles
+type TemplateBundleService struct {
+	db *gorm.DB
+}
+
+func NewTemplateBundleService(db *gorm.DB) *TemplateBundleService {
+	return &TemplateBundleService{db: db}
+}
+
+// Export bundles the requested templates; without IDs every active review template and every
+// prompt template is exported
+func (s *TemplateBundleService) Export(req *TemplateExportRequest) (*TemplateBundle, error) {
+	reviewIDs, err := parseIDList(req.ReviewTemplateIDs)
+	if err != nil {
+		return nil, err
+	}
+	promptIDs, err := parseIDList(req.PromptTemplateIDs)
+	if err != nil {
+		return nil, err
+	}
+	all := len(reviewIDs) == 0 && len(promptIDs) == 0
+
+	bundle := &TemplateBundle{
+		Version:         TemplateBundleVersion,
+		ExportedAt:      time.Now(),
+		ReviewTemplates: []BundledReviewTemplate{},
+		PromptTemplates: []BundledPromptTemplate{},
+	}
+
+	if all || len(reviewIDs) > 0 {
+		query := s.db.Order("id ASC")
+		if all {
+			query = query.Where("is_active = ?", true)
+		} else {
+			query = query.Where("id IN ?", reviewIDs)
+		}
+		var templates []models.ReviewTemplate
+		if err := query.Find(&templates).Error; err != nil {
+			return nil, err
+		}
+		for _, t := range templates {
+			bundle.ReviewTemplates = append(bundle.ReviewTemplates, BundledReviewTemplate{
+				Name: t.Name, Type: t.Type, Description: t.Description, Content: t.Content,
+			})
+		}
+	}
+
+	if all || len(promptIDs) > 0 {
+		query := s.db.Order("id ASC")
+		if !all {
+			query = query.Where("id IN ?", promptIDs)
+		}
+		var prompts []models.PromptTemplate
+		if err := query.Find(&prompts).Error; err != nil {
+			return nil, err
+		}
+		for _, p := range prompts {
+			bundle.PromptTemplates = append(bundle.PromptTemplates, BundledPromptTemplate{
+				Name: p.Name, Description: p.Description, Content: p.Content, Variables: p.Variables,
+			})
+		}
+	}
+	return bundle, nil
+}
+
+// ValidateTemplateBundle checks the format version and that every template has a name and content
+func ValidateTemplateBundle(bundle *TemplateBundle) error {
+	if bundle.Version < 1 || bundle.Version > TemplateBundleVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidTemplateBundle, bundle.Version)
+	}
+	for i, t := range bundle.ReviewTemplates {
+		if strings.TrimSpace(t.Name) == "" || strings.TrimSpace(t.Content) == "" {
+			return fmt.Errorf("%w: review template %d needs a name and content", ErrInvalidTemplateBundle, i+1)
+		}
+	}
+	for i, p := range bundle.PromptTemplates {
+		if strings.TrimSpace(p.Name) == "" || strings.TrimSpace(p.Content) == "" {
+			return fmt.Errorf("%w: prompt template %d needs a name and content", ErrInvalidTemplateBundle, i+1)
+		}
+	}
+	return nil
+}
+
+// Import stores the templates of a bundle in one transaction. A template named like an
+// existing one is skipped, overwrites it, or is stored under a free name such as "Go (2)",
+// depending on onConflict. Built-in review templates and system prompts are never overwritten.
+func (s *TemplateBundleService) Import(bundle *TemplateBundle, onConflict string, userID uint) (*TemplateImportResult, error) {
+	if onConflict == "" {
+		onConflict = TemplateConflictSkip
+	}
+	if onConflict != TemplateConflictSkip && onConflict != TemplateConflictOverwrite && onConflict != TemplateConflictRename {
+		return nil, fmt.Errorf("%w: unknown conflict policy %q", ErrInvalidTemplateBundle, onConflict)
+	}
+	if err := ValidateTemplateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	result := &TemplateImportResult{Items: []TemplateImportItem{}}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range bundle.ReviewTemplates {
+			if err := importReviewTemplate(tx, t, onConflict, userID, result); err != nil {
+				return err
+			}
+		}
+		for _, p := range bundle.PromptTemplates {
+			if err := importPromptTemplate(tx, p, onConflict, userID, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func importReviewTemplate(tx *gorm.DB, t BundledReviewTemplate, onConflict string, userID uint, result *TemplateImportResult) error {
+	const kind = "review_template"
+	templateType := t.Type
+	if templateType == "" {
+		templateType = "custom"
+	}
+	template := models.ReviewTemplate{
+		Name: t.Name, Type: templateType, Description: t.Description, Content: t.Content,
+		IsActive: true, CreatedBy: userID,
+	}
+
+	var existing models.ReviewTemplate
+	err := tx.Where("name = ?", t.Name).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		switch {
+		case onConflict == TemplateConflictOverwrite && !existing.IsBuiltIn:
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"type": templateType, "description": t.Description, "content": t.Content,
+			}).Error; err != nil {
+				return err
+			}
+			result.add(kind, existing.Name, "updated", existing.ID)
+			return nil
+		case onConflict == TemplateConflictRename:
+			name, err := freeTemplateName(tx, &models.ReviewTemplate{}, t.Name)
+			if err != nil {
+				return err
+			}
+			template.Name = name
+		default:
+			result.add(kind, t.Name, "skipped", existing.ID)
+			return nil
+		}
+	}
+
+	if err := tx.Create(&template).Error; err != nil {
+		return err
+	}
+	result.add(kind, template.Name, "created", template.ID)
+	return nil
+}
+
+func importPromptTemplate(tx *gorm.DB, p BundledPromptTemplate, onConflict string, userID uint, result *TemplateImportResult) error {
+	const kind = "prompt_template"
+	prompt := models.PromptTemplate{
+		Name: p.Name, Description: p.Description, Content: p.Content, Variables: p.Variables, CreatedBy: userID,
+	}
+
+	var existing models.PromptTemplate
+	err := tx.Where("name = ?", p.Name).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		switch {
+		case onConflict == TemplateConflictOverwrite && !existing.IsSystem:
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"description": p.Description, "content": p.Content, "variables": p.Variables,
+			}).Error; err != nil {
+				return err
+			}
+			result.add(kind, existing.Name, "updated", existing.ID)
+			return nil
+		case onConflict == TemplateConflictRename:
+			name, err := freeTemplateName(tx, &models.PromptTemplate{}, p.Name)
+			if err != nil {
+				return err
+			}
+			prompt.Name = name
+		default:
+			result.add(kind, p.Name, "skipped", existing.ID)
+			return nil
+		}
+	}
+
+	if err := tx.Create(&prompt).Error; err != nil {
+		return err
+	}
+	result.add(kind, prompt.Name, "created", prompt.ID)
+	return nil
+}
+
+// freeTemplateName returns the first of "name (2)", "name (3)", ... not used by a template of the model
+func freeTemplateName(tx *gorm.DB, model interface{}, name string) (string, error) {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		var count int64
+		if err := tx.Model(model).Where("name = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+}
+
+// parseIDList parses a comma-separated list of IDs
+func parseIDList(value string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q", part)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
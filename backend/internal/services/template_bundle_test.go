@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateTemplateBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		bundle  TemplateBundle
+		wantErr bool
+	}{
+		{"valid", TemplateBundle{Version: 1, ReviewTemplates: []BundledReviewTemplate{{Name: "Go", Content: "{{diffs}}"}}}, false},
+		{"empty", TemplateBundle{Version: 1}, false},
+		{"missing version", TemplateBundle{}, true},
+		{"future version", TemplateBundle{Version: TemplateBundleVersion + 1}, true},
+		{"review template without content", TemplateBundle{Version: 1, ReviewTemplates: []BundledReviewTemplate{{Name: "Go"}}}, true},
+		{"prompt template without name", TemplateBundle{Version: 1, PromptTemplates: []BundledPromptTemplate{{Content: "x"}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplateBundle(&tt.bundle)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTemplateBundle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidTemplateBundle) {
+				t.Errorf("error %v does not wrap ErrInvalidTemplateBundle", err)
+			}
+		})
+	}
+}
+
+func TestParseIDList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []uint
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"1, 2,,3", []uint{1, 2, 3}, false},
+		{"1,x", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIDList(tt.value)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIDList(%q) = %v, %v; want %v, wantErr %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestBuiltInReviewTemplates(t *testing.T) {
+	slugs := make(map[string]bool)
+	for _, tmpl := range builtInReviewTemplates {
+		if tmpl.Slug == "" || slugs[tmpl.Slug] {
+			t.Errorf("built-in template %q needs a unique slug, got %q", tmpl.Name, tmpl.Slug)
+		}
+		slugs[tmpl.Slug] = true
+		if tmpl.BuiltInVersion < 1 || !tmpl.IsBuiltIn || !tmpl.IsActive {
+			t.Errorf("built-in template %s: version %d, built-in %v, active %v", tmpl.Slug, tmpl.BuiltInVersion, tmpl.IsBuiltIn, tmpl.IsActive)
+		}
+		if !containsScoringInstruction(tmpl.Content) || !reflect.DeepEqual(findBuiltInReviewTemplate(tmpl.Slug), &tmpl) {
+			t.Errorf("built-in template %s lacks scoring instructions or cannot be found by slug", tmpl.Slug)
+		}
+	}
+	for _, slug := range []string{"go-api", "react", "terraform", "android"} {
+		if !slugs[slug] {
+			t.Errorf("missing curated built-in template %s", slug)
+		}
+	}
+}
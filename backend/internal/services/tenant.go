@@ -0,0 +1,273 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+var tenantSlugRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// TenantService manages tenants (workspaces) and their branding
+type TenantService struct {
+	db *gorm.DB
+}
+
+func NewTenantService(db *gorm.DB) *TenantService {
+	return &TenantService{db: db}
+}
+
+// ScopeTenant limits a query on a tenant-owned table to one tenant; 0 means all tenants
+func ScopeTenant(db *gorm.DB, tenantID uint) *gorm.DB {
+	if tenantID == 0 {
+		return db
+	}
+	return db.Where("tenant_id = ?", tenantID)
+}
+
+// ScopeReviewLogsByTenant limits a review_logs query to projects of one tenant; 0 means all tenants
+func ScopeReviewLogsByTenant(db *gorm.DB, tenantID uint) *gorm.DB {
+	return ScopeProjectColumnByTenant(db, "review_logs.project_id", tenantID)
+}
+
+// ScopeStatsReviewLogs limits a review_logs query to the original reviews of a tenant, for
+// statistics: a scoped retry stores its revision as another row of the same commit
+func ScopeStatsReviewLogs(db *gorm.DB, tenantID uint) *gorm.DB {
+	return ScopeReviewLogsByTenant(db, tenantID).Where("review_logs.revision_of IS NULL")
+}
+
+// ScopeProjectColumnByTenant limits a query to rows whose project column references a
+// project of one tenant; 0 means all tenants
+func ScopeProjectColumnByTenant(db *gorm.DB, column string, tenantID uint) *gorm.DB {
+	if tenantID == 0 {
+		return db
+	}
+	return db.Where(column+" IN (?)",
+		db.Session(&gorm.Session{NewDB: true}).Model(&models.Project{}).Select("id").Where("tenant_id = ?", tenantID))
+}
+
+// inDefaultTenant reports whether a record of the tenant belongs to the default tenant;
+// records created before tenants existed have tenant 0
+func inDefaultTenant(db *gorm.DB, tenantID uint) bool {
+	if tenantID == 0 {
+		return true
+	}
+	var tenant models.Tenant
+	if err := db.Select("id").Where("slug = ?", models.DefaultTenantSlug).First(&tenant).Error; err != nil {
+		return false
+	}
+	return tenant.ID == tenantID
+}
+
+// LLMConfigInTenant reports whether an LLM config exists and may be used by projects of the
+// tenant; 0 means any tenant
+func LLMConfigInTenant(db *gorm.DB, id, tenantID uint) bool {
+	var config models.LLMConfig
+	if err := db.Select("id, tenant_id").First(&config, id).Error; err != nil {
+		return false
+	}
+	return tenantID == 0 || config.TenantID == tenantID || (config.TenantID == 0 && inDefaultTenant(db, tenantID))
+}
+
+// PromptTemplateInTenant reports whether a prompt template exists and may be used by projects
+// of the tenant; 0 means any tenant. Prompts are shared, except those created by a user of
+// another tenant.
+func PromptTemplateInTenant(db *gorm.DB, id, tenantID uint) bool {
+	var prompt models.PromptTemplate
+	if err := db.Select("id, created_by, is_system").First(&prompt, id).Error; err != nil {
+		return false
+	}
+	return tenantID == 0 || prompt.IsSystem || createdInTenant(db, prompt.CreatedBy, tenantID)
+}
+
+// ReviewTemplateInTenant reports whether a review template exists and may be used by projects
+// of the tenant; 0 means any tenant. Like prompts, templates are shared except those created
+// by a user of another tenant.
+func ReviewTemplateInTenant(db *gorm.DB, id, tenantID uint) bool {
+	var template models.ReviewTemplate
+	if err := db.Select("id, created_by, is_built_in").First(&template, id).Error; err != nil {
+		return false
+	}
+	return tenantID == 0 || template.IsBuiltIn || createdInTenant(db, template.CreatedBy, tenantID)
+}
+
+// createdInTenant reports whether a shared record created by a user may be used by a tenant:
+// records without a creator, or whose creator is gone or belongs to no tenant, are shared
+func createdInTenant(db *gorm.DB, createdBy, tenantID uint) bool {
+	if createdBy == 0 {
+		return true
+	}
+	var creator models.User
+	if err := db.Unscoped().Select("id, tenant_id").First(&creator, createdBy).Error; err != nil {
+		return true
+	}
+	return creator.TenantID == 0 || creator.TenantID == tenantID
+}
+
+// TenantProjectIDs returns the set of project IDs of a tenant, or nil for 0 (all tenants)
+func TenantProjectIDs(db *gorm.DB, tenantID uint) (map[uint]bool, error) {
+	if tenantID == 0 {
+		return nil, nil
+	}
+	var ids []uint
+	if err := db.Model(&models.Project{}).Where("tenant_id = ?", tenantID).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+type CreateTenantRequest struct {
+	Name         string `json:"name" binding:"required"`
+	Slug         string `json:"slug" binding:"required"`
+	DisplayName  string `json:"display_name"`
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+}
+
+type UpdateTenantRequest struct {
+	Name         *string `json:"name"`
+	IsActive     *bool   `json:"is_active"`
+	DisplayName  *string `json:"display_name"`
+	LogoURL      *string `json:"logo_url"`
+	PrimaryColor *string `json:"primary_color"`
+}
+
+// TenantBranding is the public, per-tenant UI customization
+type TenantBranding struct {
+	TenantID     uint   `json:"tenant_id"`
+	Name         string `json:"name"`
+	DisplayName  string `json:"display_name"`
+	LogoURL      string `json:"logo_url"`
+	PrimaryColor string `json:"primary_color"`
+}
+
+// List returns all tenants
+func (s *TenantService) List() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	if err := s.db.Order("id ASC").Find(&tenants).Error; err != nil {
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// GetByID returns a tenant by ID
+func (s *TenantService) GetByID(id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := s.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// Create creates a new tenant
+func (s *TenantService) Create(req *CreateTenantRequest) (*models.Tenant, error) {
+	if !tenantSlugRegex.MatchString(req.Slug) {
+		return nil, errors.New("slug must be lowercase letters, digits or dashes")
+	}
+
+	var count int64
+	s.db.Model(&models.Tenant{}).Where("slug = ?", req.Slug).Count(&count)
+	if count > 0 {
+		return nil, errors.New("slug already exists")
+	}
+
+	tenant := models.Tenant{
+		Name:         req.Name,
+		Slug:         req.Slug,
+		IsActive:     true,
+		DisplayName:  req.DisplayName,
+		LogoURL:      req.LogoURL,
+		PrimaryColor: req.PrimaryColor,
+	}
+	if err := s.db.Create(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// Update updates a tenant
+func (s *TenantService) Update(id uint, req *UpdateTenantRequest) (*models.Tenant, error) {
+	tenant, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.IsActive != nil {
+		if !*req.IsActive && tenant.Slug == models.DefaultTenantSlug {
+			return nil, errors.New("the default tenant cannot be disabled")
+		}
+		updates["is_active"] = *req.IsActive
+	}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.LogoURL != nil {
+		updates["logo_url"] = *req.LogoURL
+	}
+	if req.PrimaryColor != nil {
+		updates["primary_color"] = *req.PrimaryColor
+	}
+
+	if err := s.db.Model(tenant).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+	return s.GetByID(id)
+}
+
+// Delete deletes an empty tenant
+func (s *TenantService) Delete(id uint) error {
+	tenant, err := s.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if tenant.Slug == models.DefaultTenantSlug {
+		return errors.New("the default tenant cannot be deleted")
+	}
+
+	var users, projects int64
+	s.db.Model(&models.User{}).Where("tenant_id = ?", id).Count(&users)
+	s.db.Model(&models.Project{}).Where("tenant_id = ?", id).Count(&projects)
+	if users > 0 || projects > 0 {
+		return errors.New("tenant still has users or projects")
+	}
+
+	return s.db.Delete(tenant).Error
+}
+
+// GetBranding returns the branding of a tenant
+func (s *TenantService) GetBranding(id uint) (*TenantBranding, error) {
+	tenant, err := s.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return brandingOf(tenant), nil
+}
+
+// GetBrandingBySlug returns the branding of an active tenant by slug, for the login page
+func (s *TenantService) GetBrandingBySlug(slug string) (*TenantBranding, error) {
+	var tenant models.Tenant
+	if err := s.db.Where("slug = ? AND is_active = ?", slug, true).First(&tenant).Error; err != nil {
+		return nil, err
+	}
+	return brandingOf(&tenant), nil
+}
+
+func brandingOf(tenant *models.Tenant) *TenantBranding {
+	return &TenantBranding{
+		TenantID:     tenant.ID,
+		Name:         tenant.Name,
+		DisplayName:  tenant.DisplayName,
+		LogoURL:      tenant.LogoURL,
+		PrimaryColor: tenant.PrimaryColor,
+	}
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// tenantFixture holds one project per tenant, each with reviews by its own author:
+// alice in tenant 1 and bob in tenant 2
+type tenantFixture struct {
+	db       *gorm.DB
+	projects map[uint]*models.Project // By tenant ID
+	reviews  map[uint]*models.ReviewLog
+}
+
+func seedTenants(t *testing.T) *tenantFixture {
+	t.Helper()
+	db := newTestDB(t)
+	f := &tenantFixture{db: db, projects: map[uint]*models.Project{}, reviews: map[uint]*models.ReviewLog{}}
+	authors := map[uint]string{1: "alice", 2: "bob"}
+	now := time.Now()
+	for _, tenantID := range []uint{1, 2} {
+		project := &models.Project{Name: authors[tenantID] + "-repo", URL: "https://git.example.com/" + authors[tenantID], Platform: "gitlab", TenantID: tenantID, DefaultBranch: "main"}
+		mustCreate(t, db, project)
+		f.projects[tenantID] = project
+		for i := 0; i < 2; i++ {
+			score := 70.0 + float64(tenantID)*10
+			review := &models.ReviewLog{
+				ProjectID: project.ID, EventType: "push", Branch: "main", Author: authors[tenantID],
+				AuthorEmail: authors[tenantID] + "@example.com", CommitHash: authors[tenantID] + string(rune('a'+i)),
+				Additions: 20, Deletions: 5, FilesChanged: 1, Score: &score, ReviewStatus: "completed",
+			}
+			mustCreate(t, db, review)
+			f.reviews[tenantID] = review
+			mustCreate(t, db,
+				&models.ReviewFinding{ReviewLogID: review.ID, ProjectID: project.ID, Author: review.Author, Category: "security", Title: "Hardcoded secret", Compliance: "secrets", CreatedAt: now},
+				&models.ReviewFile{ReviewLogID: review.ID, ProjectID: project.ID, Path: "main.go", Language: "Go", Additions: 20, Deletions: 5, CreatedAt: now},
+			)
+		}
+		projectID := project.ID
+		mustCreate(t, db, &models.AIUsageLog{ProjectID: &projectID, TotalTokens: 1000 * int(tenantID), CreatedAt: now})
+	}
+	return f
+}
+
+func TestTenantScope_Dashboard(t *testing.T) {
+	f := seedTenants(t)
+	service := NewDashboardService(f.db)
+
+	stats, err := service.GetStats(&DashboardStatsRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.Stats.TotalCommits != 2 || stats.Stats.ActiveProjects != 1 || stats.Stats.Contributors != 1 {
+		t.Errorf("GetStats tenant 1 = %+v, want only the tenant's 2 reviews", stats.Stats)
+	}
+	for _, a := range stats.AuthorStats {
+		if a.Author != "alice" {
+			t.Errorf("GetStats tenant 1 lists author %q of another tenant", a.Author)
+		}
+	}
+	all, _ := service.GetStats(&DashboardStatsRequest{})
+	if all.Stats.TotalCommits != 4 {
+		t.Errorf("GetStats all tenants TotalCommits = %d, want 4", all.Stats.TotalCommits)
+	}
+
+	trends, err := service.GetTrends(&DashboardTrendRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("GetTrends: %v", err)
+	}
+	var reviews, tokens int64
+	for _, p := range trends.Points {
+		reviews += p.Reviews
+		tokens += p.Tokens
+	}
+	if reviews != 2 || tokens != 2000 {
+		t.Errorf("GetTrends tenant 2 = %d reviews, %d tokens; want 2 and 2000", reviews, tokens)
+	}
+	other, _ := service.GetTrends(&DashboardTrendRequest{TenantID: 1, ProjectID: f.projects[2].ID})
+	for _, p := range other.Points {
+		if p.Reviews != 0 || p.Tokens != 0 {
+			t.Errorf("GetTrends tenant 1 with a project of tenant 2 = %+v, want nothing", p)
+		}
+	}
+
+	compare, err := service.Compare(&DashboardCompareRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("Compare: %v", err)
+	}
+	if compare.Current.Reviews != 2 || compare.Current.Tokens != 1000 || compare.Current.Authors != 1 {
+		t.Errorf("Compare tenant 1 current = %+v, want only the tenant's reviews", compare.Current)
+	}
+
+	health, err := service.GetBranchHealth(&BranchHealthRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("GetBranchHealth: %v", err)
+	}
+	if health.DefaultBranch.Reviews != 2 {
+		t.Errorf("GetBranchHealth tenant 2 default branch reviews = %d, want 2", health.DefaultBranch.Reviews)
+	}
+}
+
+func TestTenantScope_Members(t *testing.T) {
+	f := seedTenants(t)
+	service := NewMemberService(f.db)
+
+	list, err := service.List(&MemberListRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if list.Total != 1 || len(list.Items) != 1 || list.Items[0].Author != "alice" {
+		t.Errorf("List tenant 1 = %+v, want only alice", list.Items)
+	}
+
+	detail, err := service.GetDetail(&MemberDetailRequest{Author: "bob", TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetDetail: %v", err)
+	}
+	if detail.TotalStats.CommitCount != 0 || len(detail.ProjectStats) != 0 || len(detail.Trend) != 0 || len(detail.Languages) != 0 {
+		t.Errorf("GetDetail of another tenant's member = %+v, want nothing", detail)
+	}
+	own, _ := service.GetDetail(&MemberDetailRequest{Author: "bob", TenantID: 2})
+	if own.TotalStats.CommitCount != 2 || len(own.Languages) != 1 {
+		t.Errorf("GetDetail of own member = %+v, want 2 commits and 1 language", own.TotalStats)
+	}
+
+	overview, err := service.GetTeamOverview(&TeamOverviewRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("GetTeamOverview: %v", err)
+	}
+	if overview.TotalMembers != 1 || overview.TotalCommits != 2 || len(overview.TopMembers) != 1 || overview.ScoreDistrib.Excellent != 1 {
+		t.Errorf("GetTeamOverview tenant 2 = %+v, want only bob", overview)
+	}
+
+	heatmap, err := service.GetHeatmap(&HeatmapRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetHeatmap: %v", err)
+	}
+	if heatmap.TotalCount != 2 {
+		t.Errorf("GetHeatmap tenant 1 TotalCount = %d, want 2", heatmap.TotalCount)
+	}
+
+	cards, err := service.GetScorecards(&MemberScorecardRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetScorecards: %v", err)
+	}
+	if len(cards.Items) != 1 || cards.Items[0].Author != "alice" || cards.Items[0].Findings != 2 {
+		t.Errorf("GetScorecards tenant 1 = %+v, want only alice with her 2 findings", cards.Items)
+	}
+}
+
+func TestTenantScope_Findings(t *testing.T) {
+	f := seedTenants(t)
+	service := NewReviewFindingService(f.db)
+
+	trends, err := service.GetTrends(&FindingTrendRequest{TenantID: 1})
+	if err != nil {
+		t.Fatalf("GetTrends: %v", err)
+	}
+	var total int64
+	for _, p := range trends.Points {
+		total += p.Total
+	}
+	if total != 2 {
+		t.Errorf("GetTrends tenant 1 total = %d, want 2", total)
+	}
+
+	authors, err := service.GetByAuthor(&FindingAuthorRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("GetByAuthor: %v", err)
+	}
+	if len(authors) != 1 || authors[0].Author != "bob" {
+		t.Errorf("GetByAuthor tenant 2 = %+v, want only bob", authors)
+	}
+
+	recurring, err := service.GetRecurring(&FindingRecurringRequest{TenantID: 1, MinCount: 1})
+	if err != nil {
+		t.Fatalf("GetRecurring: %v", err)
+	}
+	for _, r := range recurring {
+		if r.ProjectID != f.projects[1].ID {
+			t.Errorf("GetRecurring tenant 1 lists project %d of another tenant", r.ProjectID)
+		}
+	}
+
+	compliance, err := service.GetCompliance(&FindingComplianceRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("GetCompliance: %v", err)
+	}
+	if compliance.Total != 2 || len(compliance.Projects) != 1 || compliance.Projects[0].ProjectID != f.projects[2].ID {
+		t.Errorf("GetCompliance tenant 2 = %+v, want only the tenant's project", compliance)
+	}
+}
+
+func TestTenantScope_Leaderboard(t *testing.T) {
+	f := seedTenants(t)
+	config := NewSystemConfigService(f.db)
+	config.Set("leaderboard_enabled", "true")
+	config.Set("leaderboard_min_commits", "1")
+
+	board, err := NewLeaderboardService(f.db).Get(&LeaderboardRequest{TenantID: 2})
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(board.TopQuality) != 1 || board.TopQuality[0].Author != "bob" {
+		t.Errorf("Get tenant 2 top quality = %+v, want only bob", board.TopQuality)
+	}
+	if len(board.Streaks) != 1 || board.Streaks[0].Author != "bob" {
+		t.Errorf("Get tenant 2 streaks = %+v, want only bob", board.Streaks)
+	}
+}
+
+func TestTenantScope_ReviewFeedback(t *testing.T) {
+	f := seedTenants(t)
+	user := &models.User{Username: "reviewer"}
+	mustCreate(t, f.db, user)
+	feedback := &models.ReviewFeedback{ReviewLogID: f.reviews[2].ID, UserID: user.ID, FeedbackType: "question", UserMessage: "Why?", ProcessStatus: "completed"}
+	mustCreate(t, f.db, feedback)
+	service := &ReviewFeedbackService{db: f.db}
+
+	if list, err := service.ListByReviewLog(f.reviews[2].ID, 1); err != nil || len(list) != 0 {
+		t.Errorf("ListByReviewLog from another tenant = %d items, %v; want none", len(list), err)
+	}
+	if list, err := service.ListByReviewLog(f.reviews[2].ID, 2); err != nil || len(list) != 1 {
+		t.Errorf("ListByReviewLog from own tenant = %d items, %v; want 1", len(list), err)
+	}
+	if _, err := service.GetByID(feedback.ID, 1); err == nil {
+		t.Error("GetByID from another tenant should fail")
+	}
+	if got, err := service.GetByID(feedback.ID, 0); err != nil || got.ID != feedback.ID {
+		t.Errorf("GetByID for all tenants = %v, %v", got, err)
+	}
+	err := service.Create(context.Background(), &models.ReviewFeedback{ReviewLogID: f.reviews[2].ID, UserID: user.ID, FeedbackType: "agree", UserMessage: "ok"}, 1)
+	if err == nil {
+		t.Error("Create on a review of another tenant should fail")
+	}
+}
+
+func TestTenantProjectIDs(t *testing.T) {
+	f := seedTenants(t)
+
+	all, err := TenantProjectIDs(f.db, 0)
+	if err != nil || all != nil {
+		t.Errorf("TenantProjectIDs(0) = %v, %v; want nil", all, err)
+	}
+	ids, err := TenantProjectIDs(f.db, 1)
+	if err != nil || len(ids) != 1 || !ids[f.projects[1].ID] {
+		t.Errorf("TenantProjectIDs(1) = %v, %v; want only the tenant's project", ids, err)
+	}
+	empty, _ := TenantProjectIDs(f.db, 3)
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("TenantProjectIDs of a tenant without projects = %v, want an empty set", empty)
+	}
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TestMappingRule maps the source files of a language to their test files.
+// TestPatterns are matched against file names; {name} stands for the source
+// file name without extension, e.g. "{name}_test.go" or "{name}.spec.*".
+type TestMappingRule struct {
+	Language     string   `json:"language"`
+	Extensions   []string `json:"extensions"`
+	TestPatterns []string `json:"test_patterns"`
+}
+
+// DefaultTestMappingRules are used when no rules are configured
+var DefaultTestMappingRules = []TestMappingRule{
+	{Language: "go", Extensions: []string{".go"}, TestPatterns: []string{"{name}_test.go"}},
+	{Language: "javascript", Extensions: []string{".js", ".jsx", ".mjs", ".ts", ".tsx", ".vue"}, TestPatterns: []string{"{name}.test.*", "{name}.spec.*"}},
+	{Language: "python", Extensions: []string{".py"}, TestPatterns: []string{"test_{name}.py", "{name}_test.py"}},
+	{Language: "java", Extensions: []string{".java", ".kt"}, TestPatterns: []string{"{name}Test.*", "{name}Tests.*", "{name}IT.*"}},
+	{Language: "ruby", Extensions: []string{".rb"}, TestPatterns: []string{"{name}_spec.rb", "{name}_test.rb"}},
+	{Language: "php", Extensions: []string{".php"}, TestPatterns: []string{"{name}Test.php"}},
+	{Language: "csharp", Extensions: []string{".cs"}, TestPatterns: []string{"{name}Test.cs", "{name}Tests.cs"}},
+}
+
+// testDirectories mark every file below them as a test file
+var testDirectories = []string{"test/", "tests/", "__tests__/", "spec/"}
+
+// ParseTestMappingRules decodes configured rules, falling back to the defaults when empty
+func ParseTestMappingRules(raw string) ([]TestMappingRule, error) {
+	if strings.TrimSpace(raw) == "" {
+		return DefaultTestMappingRules, nil
+	}
+	var rules []TestMappingRule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("invalid test mapping rules: %w", err)
+	}
+	if err := ValidateTestMappingRules(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ValidateTestMappingRules checks that every rule has extensions and {name} test patterns
+func ValidateTestMappingRules(rules []TestMappingRule) error {
+	for i, rule := range rules {
+		if len(rule.Extensions) == 0 {
+			return fmt.Errorf("rule %d (%s): at least one extension is required", i+1, rule.Language)
+		}
+		if len(rule.TestPatterns) == 0 {
+			return fmt.Errorf("rule %d (%s): at least one test pattern is required", i+1, rule.Language)
+		}
+		for _, pattern := range rule.TestPatterns {
+			if !strings.Contains(pattern, "{name}") {
+				return fmt.Errorf("rule %d (%s): test pattern %q must contain {name}", i+1, rule.Language, pattern)
+			}
+			if _, err := path.Match(strings.ReplaceAll(pattern, "{name}", "x"), ""); err != nil {
+				return fmt.Errorf("rule %d (%s): invalid test pattern %q", i+1, rule.Language, pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// FindUntestedFiles returns the source files changed by diff without a change
+// to a matching test file. Deleted files and files of languages without a rule are ignored.
+func FindUntestedFiles(diff string, rules []TestMappingRule) []string {
+	files := ParseDiffToFiles(diff)
+
+	var changedTests []string
+	var sources []string
+	for _, f := range files {
+		if f.FilePath == "unknown" || isDeletedFileDiff(f.Content) {
+			continue
+		}
+		if isTestFile(f.FilePath, rules) {
+			changedTests = append(changedTests, path.Base(f.FilePath))
+		} else {
+			sources = append(sources, f.FilePath)
+		}
+	}
+
+	var untested []string
+	for _, source := range sources {
+		rule := testRuleFor(source, rules)
+		if rule == nil {
+			continue
+		}
+		base := path.Base(source)
+		name := strings.TrimSuffix(base, path.Ext(base))
+		if !hasMatchingTest(name, rule, changedTests) {
+			untested = append(untested, source)
+		}
+	}
+	sort.Strings(untested)
+	return untested
+}
+
+// FormatUntestedFinding describes the untested files as a review finding
+func FormatUntestedFinding(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("**Tests missing**: %d changed source file(s) have no corresponding test changes:\n", len(files)))
+	for _, f := range files {
+		b.WriteString("- `" + f + "`\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func testRuleFor(file string, rules []TestMappingRule) *TestMappingRule {
+	ext := strings.ToLower(path.Ext(file))
+	for i := range rules {
+		for _, e := range rules[i].Extensions {
+			if strings.ToLower(e) == ext {
+				return &rules[i]
+			}
+		}
+	}
+	return nil
+}
+
+func isTestFile(file string, rules []TestMappingRule) bool {
+	lower := strings.ToLower(file)
+	for _, dir := range testDirectories {
+		if strings.HasPrefix(lower, dir) || strings.Contains(lower, "/"+dir) {
+			return true
+		}
+	}
+	base := path.Base(file)
+	for _, rule := range rules {
+		for _, pattern := range rule.TestPatterns {
+			if ok, _ := path.Match(strings.ReplaceAll(pattern, "{name}", "?*"), base); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasMatchingTest(name string, rule *TestMappingRule, changedTests []string) bool {
+	for _, pattern := range rule.TestPatterns {
+		expected := strings.ReplaceAll(pattern, "{name}", name)
+		for _, test := range changedTests {
+			if ok, _ := path.Match(expected, test); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isDeletedFileDiff(content string) bool {
+	return strings.Contains(content, "\ndeleted file mode") || strings.Contains(content, "\n+++ /dev/null")
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func fileDiff(path string) string {
+	return "diff --git a/" + path + " b/" + path + "\n--- a/" + path + "\n+++ b/" + path + "\n@@ -1 +1 @@\n-old\n+new\n"
+}
+
+func TestFindUntestedFiles(t *testing.T) {
+	deleted := "diff --git a/pkg/old.go b/pkg/old.go\ndeleted file mode 100644\n--- a/pkg/old.go\n+++ /dev/null\n@@ -1 +0,0 @@\n-old\n"
+
+	tests := []struct {
+		name  string
+		files []string
+		extra string
+		want  []string
+	}{
+		{"go source with test", []string{"pkg/user.go", "pkg/user_test.go"}, "", nil},
+		{"go source without test", []string{"pkg/user.go", "pkg/order_test.go"}, "", []string{"pkg/user.go"}},
+		{"js spec", []string{"src/app.ts", "src/app.spec.ts"}, "", nil},
+		{"python test prefix", []string{"app/models.py", "tests/test_models.py"}, "", nil},
+		{"java test class", []string{"src/main/java/UserService.java", "src/test/java/UserServiceTest.java"}, "", nil},
+		{"unmapped language ignored", []string{"README.md", "config.yaml"}, "", nil},
+		{"test only change", []string{"pkg/user_test.go"}, "", nil},
+		{"deleted file ignored", nil, deleted, nil},
+		{"sorted output", []string{"b.go", "a.py"}, "", []string{"a.py", "b.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diff strings.Builder
+			for _, f := range tt.files {
+				diff.WriteString(fileDiff(f))
+			}
+			diff.WriteString(tt.extra)
+			got := FindUntestedFiles(diff.String(), DefaultTestMappingRules)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindUntestedFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindUntestedFiles_CustomRules(t *testing.T) {
+	rules := []TestMappingRule{{Language: "go", Extensions: []string{".go"}, TestPatterns: []string{"{name}_integration_test.go"}}}
+	diff := fileDiff("store.go") + fileDiff("store_integration_test.go")
+	if got := FindUntestedFiles(diff, rules); len(got) != 0 {
+		t.Errorf("expected store.go to be covered, got %v", got)
+	}
+	if got := FindUntestedFiles(fileDiff("main.py"), rules); len(got) != 0 {
+		t.Errorf("expected languages without a rule to be ignored, got %v", got)
+	}
+}
+
+func TestParseTestMappingRules(t *testing.T) {
+	rules, err := ParseTestMappingRules("")
+	if err != nil || len(rules) != len(DefaultTestMappingRules) {
+		t.Fatalf("empty config should return the defaults, got %v, %v", rules, err)
+	}
+
+	rules, err = ParseTestMappingRules(`[{"language":"go","extensions":[".go"],"test_patterns":["{name}_test.go"]}]`)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("ParseTestMappingRules() = %v, %v", rules, err)
+	}
+
+	invalid := []string{
+		`not json`,
+		`[{"language":"go","test_patterns":["{name}_test.go"]}]`,
+		`[{"language":"go","extensions":[".go"]}]`,
+		`[{"language":"go","extensions":[".go"],"test_patterns":["user_test.go"]}]`,
+		`[{"language":"go","extensions":[".go"],"test_patterns":["[{name}_test.go"]}]`,
+	}
+	for _, raw := range invalid {
+		if _, err := ParseTestMappingRules(raw); err == nil {
+			t.Errorf("ParseTestMappingRules(%s) should fail", raw)
+		}
+	}
+}
+
+func TestFormatUntestedFinding(t *testing.T) {
+	if got := FormatUntestedFinding(nil); got != "" {
+		t.Errorf("expected no finding, got %q", got)
+	}
+	got := FormatUntestedFinding([]string{"a.go", "b.go"})
+	if !strings.Contains(got, "Tests missing") || !strings.Contains(got, "`a.go`") || !strings.Contains(got, "2 changed source file(s)") {
+		t.Errorf("unexpected finding: %q", got)
+	}
+}
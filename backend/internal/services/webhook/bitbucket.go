@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
@@ -22,6 +23,16 @@ func (s *Service) HandleBitbucketWebhook(ctx context.Context, projectID uint, ev
 		return fmt.Errorf("project not found: %w", err)
 	}
 
+	if project.Archived {
+		logger.For(ctx, "webhook").Info().Str("platform", "bitbucket").Uint("project_id", projectID).Msg("Project is archived, skipping")
+		return nil
+	}
+
+	if project.PendingApproval {
+		logger.For(ctx, "webhook").Info().Str("platform", "bitbucket").Uint("project_id", projectID).Msg("Project is pending approval, skipping")
+		return nil
+	}
+
 	if !project.AIEnabled {
 		return nil
 	}
@@ -46,6 +57,26 @@ func (s *Service) HandleBitbucketWebhook(ctx context.Context, projectID uint, ev
 			return err
 		}
 		return s.processBitbucketPR(ctx, project, &event)
+
+	case "repo:refs_changed":
+		if !strings.Contains(project.ReviewEvents, "push") {
+			return nil
+		}
+		var event BitbucketServerPushEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return s.processBitbucketPush(ctx, project, s.fromBitbucketServerPush(ctx, project, &event))
+
+	case "pr:opened", "pr:from_ref_updated":
+		if !strings.Contains(project.ReviewEvents, "merge_request") {
+			return nil
+		}
+		var event BitbucketServerPREvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return s.processBitbucketPR(ctx, project, fromBitbucketServerPR(&event))
 	}
 
 	return nil
@@ -55,6 +86,8 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 	if len(event.Push.Changes) == 0 {
 		return nil
 	}
+	// Bitbucket push events don't carry the main branch
+	s.trackDefaultBranch(ctx, project, "")
 
 	for _, change := range event.Push.Changes {
 		if change.New.Type != "branch" || len(change.Commits) == 0 {
@@ -62,12 +95,60 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 		}
 
 		branch := change.New.Name
-		if s.isBranchIgnored(branch, project.BranchFilter) {
+		if s.isBranchIgnored(branch, project) {
 			continue
 		}
 
 		commitSHA := change.New.Target.Hash
-		if s.isCommitAlreadyReviewed(project.ID, commitSHA) {
+		if s.isCommitAlreadyReviewed(project.ID, commitSHA) || s.isDuplicateEvent(ctx, project.ID, commitSHA, "push") {
+			continue
+		}
+
+		head := change.Commits[0]
+		for _, c := range change.Commits {
+			if c.Hash == commitSHA {
+				head = c
+			}
+		}
+		if s.skipByDirective(ctx, project, &models.ReviewLog{
+			EventType:     "push",
+			CommitHash:    commitSHA,
+			CommitURL:     change.New.Target.Links.HTML.Href,
+			Branch:        branch,
+			Author:        event.Actor.DisplayName,
+			AuthorAvatar:  event.Actor.Links.Avatar.Href,
+			AuthorURL:     event.Actor.Links.HTML.Href,
+			CommitMessage: head.Message,
+		}, branch, services.FindSkipDirective(head.Message), 0) {
+			continue
+		}
+
+		if project.PerCommitReview {
+			push := &commitPush{
+				head:         commitSHA,
+				branch:       branch,
+				author:       event.Actor.DisplayName,
+				authorAvatar: event.Actor.Links.Avatar.Href,
+				authorURL:    event.Actor.Links.HTML.Href,
+			}
+			// Bitbucket lists the commits of a change newest first
+			for i := len(change.Commits) - 1; i >= 0; i-- {
+				c := change.Commits[i]
+				name, email := parseGitIdentity(c.Author.Raw)
+				if c.Author.User.DisplayName != "" {
+					name = c.Author.User.DisplayName
+				}
+				commit := pushCommit{sha: c.Hash, message: c.Message, url: c.Links.HTML.Href, author: name, authorEmail: email}
+				if name == event.Actor.DisplayName {
+					commit.authorAvatar, commit.authorURL = event.Actor.Links.Avatar.Href, event.Actor.Links.HTML.Href
+				}
+				push.commits = append(push.commits, commit)
+			}
+			if err := s.reviewCommitsSeparately(ctx, project, push, func(ctx context.Context, sha string) (string, error) {
+				return s.getBitbucketDiff(ctx, project, sha)
+			}); err != nil {
+				logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Str("commit", commitSHA).Msg("Failed to enqueue Bitbucket commit reviews")
+			}
 			continue
 		}
 
@@ -79,26 +160,42 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 		}
 
 		var diff string
+		var fetchErr error
 
+		fetchStart := time.Now()
 		beforeSHA := change.Old.Target.Hash
 		if !isNullSHA(beforeSHA) && beforeSHA != "" {
 			compareDiff, err := s.getBitbucketCompareDiff(project, beforeSHA, commitSHA)
 			if err != nil {
-				logger.Infof("[Webhook] Bitbucket compare API failed, falling back to per-commit diffs: %v", err)
+				logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Str("commit", commitSHA).Msg("Bitbucket compare API failed, falling back to per-commit diffs")
 			} else if compareDiff != "" {
 				diff = compareDiff
-				logger.Infof("[Webhook] Got Bitbucket compare diff (before=%s, after=%s), length: %d bytes",
-					beforeSHA[:8], commitSHA[:8], len(diff))
+				logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("commit", commitSHA).Str("before", beforeSHA).Int("bytes", len(diff)).Msg("Got Bitbucket compare diff")
 			}
 		}
 
 		if diff == "" {
-			var allDiffs strings.Builder
+			shas := make([]string, 0, len(change.Commits))
 			for _, c := range change.Commits {
-				d, _ := s.getBitbucketDiff(project, c.Hash)
-				allDiffs.WriteString(fmt.Sprintf("\n### Commit: %s\n%s\n", c.Hash[:8], d))
+				shas = append(shas, c.Hash)
+			}
+			results := fetchCommitDiffs(ctx, shas, func(ctx context.Context, sha string) (string, error) {
+				return s.getBitbucketDiff(ctx, project, sha)
+			})
+
+			var allDiffs strings.Builder
+			for _, r := range results {
+				if r.err != nil {
+					logger.For(ctx, "webhook").Warn().Err(r.err).Uint("project_id", project.ID).Str("commit", r.sha).Msg("Failed to get Bitbucket commit diff")
+					fetchErr = r.err
+					continue
+				}
+				allDiffs.WriteString(fmt.Sprintf("\n### Commit: %s\n%s\n", r.sha[:8], r.diff))
 			}
 			diff = allDiffs.String()
+			if diff != "" {
+				fetchErr = nil
+			}
 		}
 
 		additions, deletions, filesChanged := ParseDiffStats(diff)
@@ -116,13 +213,19 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 			FilesChanged:  filesChanged,
 			Additions:     additions,
 			Deletions:     deletions,
+			DiffFetchMs:   diffFetchMs(fetchStart),
 			ReviewStatus:  "pending",
 		}
-		s.reviewService.Create(reviewLog)
+		s.createReviewLog(ctx, reviewLog)
+		if fetchErr != nil {
+			s.failDiffFetch(ctx, project, reviewLog, 0, fetchErr)
+			continue
+		}
 
 		// Enqueue review task for async processing
 		task := &services.ReviewTask{
 			ReviewLogID:   reviewLog.ID,
+			RequestID:     logger.RequestID(ctx),
 			ProjectID:     project.ID,
 			CommitSHA:     commitSHA,
 			EventType:     "push",
@@ -135,14 +238,15 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 		}
 
 		if err := services.GetTaskQueue().Enqueue(task); err != nil {
-			logger.Infof("[Webhook] Failed to enqueue Bitbucket push review task: %v", err)
+			logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", commitSHA).Msg("Failed to enqueue Bitbucket push review task")
 			reviewLog.ReviewStatus = "failed"
 			reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 			s.reviewService.Update(reviewLog)
+			s.releaseEvent(reviewLog)
 			continue
 		}
 
-		logger.Infof("[Webhook] Bitbucket push review task enqueued for project %d, commit %s", project.ID, commitSHA[:8])
+		logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", commitSHA).Msg("Bitbucket push review task enqueued")
 	}
 
 	return nil
@@ -150,16 +254,34 @@ func (s *Service) processBitbucketPush(ctx context.Context, project *models.Proj
 
 func (s *Service) processBitbucketPR(ctx context.Context, project *models.Project, event *BitbucketPREvent) error {
 	branch := event.PullRequest.Source.Branch.Name
-	if s.isBranchIgnored(branch, project.BranchFilter) {
+	if s.isMergeRequestIgnored(branch, event.PullRequest.Destination.Branch.Name, project) {
 		return nil
 	}
 
 	prNumber := event.PullRequest.ID
 	commitSHA := event.PullRequest.Source.Commit.Hash
+	if s.isDuplicateEvent(ctx, project.ID, commitSHA, "merge_request") {
+		return nil
+	}
+	if s.skipByDirective(ctx, project, &models.ReviewLog{
+		EventType:     "merge_request",
+		CommitHash:    commitSHA,
+		Branch:        branch,
+		TargetBranch:  event.PullRequest.Destination.Branch.Name,
+		Author:        event.PullRequest.Author.DisplayName,
+		AuthorAvatar:  event.PullRequest.Author.Links.Avatar.Href,
+		AuthorURL:     event.PullRequest.Author.Links.HTML.Href,
+		CommitMessage: event.PullRequest.Title,
+		MRNumber:      &prNumber,
+		MRURL:         event.PullRequest.Links.HTML.Href,
+	}, event.PullRequest.Destination.Branch.Name, services.FindSkipDirective(event.PullRequest.Title+"\n"+event.PullRequest.Description), 0) {
+		return nil
+	}
 
 	s.setBitbucketCommitStatus(project, commitSHA, "INPROGRESS", "AI Review in progress...")
 
-	diff, _ := s.getBitbucketPRDiff(project, prNumber)
+	fetchStart := time.Now()
+	diff, fetchErr := s.getBitbucketPRDiff(project, prNumber)
 	additions, deletions, filesChanged := ParseDiffStats(diff)
 
 	reviewLog := &models.ReviewLog{
@@ -174,15 +296,21 @@ func (s *Service) processBitbucketPR(ctx context.Context, project *models.Projec
 		FilesChanged:  filesChanged,
 		Additions:     additions,
 		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
 		MRNumber:      &prNumber,
 		MRURL:         event.PullRequest.Links.HTML.Href,
 		ReviewStatus:  "pending",
 	}
-	s.reviewService.Create(reviewLog)
+	s.createReviewLog(ctx, reviewLog)
+	if fetchErr != nil {
+		s.failDiffFetch(ctx, project, reviewLog, 0, fetchErr)
+		return nil
+	}
 
 	// Enqueue review task for async processing
 	task := &services.ReviewTask{
 		ReviewLogID:   reviewLog.ID,
+		RequestID:     logger.RequestID(ctx),
 		ProjectID:     project.ID,
 		CommitSHA:     commitSHA,
 		EventType:     "merge_request",
@@ -193,123 +321,106 @@ func (s *Service) processBitbucketPR(ctx context.Context, project *models.Projec
 		Diff:          diff,
 		MRNumber:      &prNumber,
 		MRURL:         event.PullRequest.Links.HTML.Href,
+		TargetBranch:  event.PullRequest.Destination.Branch.Name,
 	}
 
 	if err := services.GetTaskQueue().Enqueue(task); err != nil {
-		logger.Infof("[Webhook] Failed to enqueue Bitbucket PR review task: %v", err)
+		logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Int("mr", prNumber).Msg("Failed to enqueue Bitbucket PR review task")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
 		return err
 	}
 
-	logger.Infof("[Webhook] Bitbucket PR review task enqueued for project %d, PR #%d", project.ID, prNumber)
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Int("mr", prNumber).Msg("Bitbucket PR review task enqueued")
 	return nil
 }
 
-func (s *Service) getBitbucketDiff(project *models.Project, commitSHA string) (string, error) {
-	info, _ := parseRepoInfo(project.URL)
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/diff/%s", info.projectPath, commitSHA)
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
-	}
-	resp, err := s.httpClient.Do(req)
+func (s *Service) getBitbucketDiff(ctx context.Context, project *models.Project, commitSHA string) (string, error) {
+	api, err := s.bitbucketAPI(project)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	return string(body), nil
+	return api.GetText(ctx, api.CommitDiffURL(commitSHA))
 }
 
 func (s *Service) getBitbucketCompareDiff(project *models.Project, from, to string) (string, error) {
-	info, err := parseRepoInfo(project.URL)
+	api, err := s.bitbucketAPI(project)
 	if err != nil {
 		return "", err
 	}
 
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/diff/%s..%s", info.projectPath, from, to)
-	logger.Infof("[Webhook] Fetching Bitbucket compare diff: %s...%s", from[:8], to[:8])
-
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+	logger.Module("webhook").Info().Uint("project_id", project.ID).Str("from", from).Str("to", to).Msg("Fetching Bitbucket compare diff")
+	diff, err := api.GetText(context.Background(), api.CompareDiffURL(from, to))
+	if err != nil {
+		return "", fmt.Errorf("Bitbucket compare API failed: %w", err)
 	}
+	return diff, nil
+}
 
-	resp, err := s.httpClient.Do(req)
+func (s *Service) getBitbucketPRDiff(project *models.Project, prNumber int) (string, error) {
+	api, err := s.bitbucketAPI(project)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("Bitbucket compare API returned status %d: %s", resp.StatusCode, string(body))
-	}
+	return api.GetText(context.Background(), api.PullRequestDiffURL(prNumber))
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	return string(body), nil
+// bitbucketAPI returns the Cloud or Server API of a Bitbucket project, with its authentication
+func (s *Service) bitbucketAPI(project *models.Project) (*services.BitbucketAPI, error) {
+	return services.NewBitbucketAPI(s.db, s.httpClient, project)
 }
 
-func (s *Service) getBitbucketPRDiff(project *models.Project, prNumber int) (string, error) {
-	info, _ := parseRepoInfo(project.URL)
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/pullrequests/%d/diff", info.projectPath, prNumber)
-	req, _ := http.NewRequest("GET", apiURL, nil)
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+// postBitbucketJSON posts a JSON body to the Bitbucket API
+func (s *Service) postBitbucketJSON(api *services.BitbucketAPI, apiURL string, body interface{}) error {
+	payload, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if err := api.Authorize(req); err != nil {
+		return err
 	}
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", err
+		return err
 	}
 	defer resp.Body.Close()
-	body, _ := io.ReadAll(resp.Body)
-	return string(body), nil
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &platformStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return nil
 }
 
+// bitbucketStatusStates maps the generic commit status states to Bitbucket's
+var bitbucketStatusStates = map[string]string{"pending": "INPROGRESS", "success": "SUCCESSFUL", "failed": "FAILED", "error": "FAILED"}
+
 func (s *Service) setBitbucketCommitStatus(project *models.Project, sha, state, description string) {
-	info, _ := parseRepoInfo(project.URL)
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/commit/%s/statuses/build", info.projectPath, sha)
-	data := map[string]string{"state": state, "key": "codesentry-ai-review", "name": "CodeSentry AI Review", "description": description}
-	payload, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+	if mapped, ok := bitbucketStatusStates[state]; ok {
+		state = mapped
+	}
+	api, err := s.bitbucketAPI(project)
+	if err == nil {
+		data := map[string]string{"state": state, "key": "codesentry-ai-review", "name": "CodeSentry AI Review", "url": project.URL, "description": description}
+		err = s.postBitbucketJSON(api, api.CommitStatusURL(sha), data)
 	}
-	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		logger.Infof("[Webhook] Failed to send Bitbucket commit status: %v", err)
-		return
+		logger.Module("webhook").Warn().Err(err).Uint("project_id", project.ID).Str("commit", sha).Str("state", state).Msg("Failed to send Bitbucket commit status")
 	}
-	defer resp.Body.Close()
 }
 
 func (s *Service) postBitbucketCommitComment(project *models.Project, commitSHA, comment string) error {
-	info, _ := parseRepoInfo(project.URL)
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/commit/%s/comments", info.projectPath, commitSHA)
-	data := map[string]interface{}{"content": map[string]string{"raw": comment}}
-	payload, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+	api, err := s.bitbucketAPI(project)
+	if err != nil {
+		return err
 	}
-	s.httpClient.Do(req)
-	return nil
+	return s.postBitbucketJSON(api, api.CommitCommentURL(commitSHA), api.CommentBody(comment))
 }
 
 func (s *Service) postBitbucketPRComment(project *models.Project, prNumber int, comment string) error {
-	info, _ := parseRepoInfo(project.URL)
-	apiURL := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/pullrequests/%d/comments", info.projectPath, prNumber)
-	data := map[string]interface{}{"content": map[string]string{"raw": comment}}
-	payload, _ := json.Marshal(data)
-	req, _ := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
-	req.Header.Set("Content-Type", "application/json")
-	if project.AccessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+project.AccessToken)
+	api, err := s.bitbucketAPI(project)
+	if err != nil {
+		return err
 	}
-	s.httpClient.Do(req)
-	return nil
+	return s.postBitbucketJSON(api, api.PullRequestCommentURL(prNumber), api.CommentBody(comment))
 }
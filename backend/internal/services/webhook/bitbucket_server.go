@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// fromBitbucketServerPush converts a Bitbucket Server push to the shape of a Bitbucket Cloud
+// push. Server events only carry the old and new hashes of each ref, so the head commit is
+// looked up for its message; deleted refs and tags are left out.
+func (s *Service) fromBitbucketServerPush(ctx context.Context, project *models.Project, event *BitbucketServerPushEvent) *BitbucketPushEvent {
+	var push BitbucketPushEvent
+	push.Actor.DisplayName = bitbucketServerUserName(event.Actor)
+
+	api, err := s.bitbucketAPI(project)
+	if err != nil {
+		logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Msg("Failed to resolve Bitbucket Server repository")
+		return &push
+	}
+	for _, change := range event.Changes {
+		if change.Ref.Type != "BRANCH" || change.Type == "DELETE" || isNullSHA(change.ToHash) {
+			continue
+		}
+		n := len(push.Push.Changes)
+		push.Push.Changes = slices.Grow(push.Push.Changes, 1)[:n+1]
+		c := &push.Push.Changes[n]
+		c.New.Type = "branch"
+		c.New.Name = change.Ref.DisplayID
+		c.New.Target.Hash = change.ToHash
+		c.New.Target.Links.HTML.Href = api.CommitWebURL(change.ToHash)
+		if change.Type != "ADD" {
+			c.Old.Name = change.Ref.DisplayID
+			c.Old.Target.Hash = change.FromHash
+		}
+
+		c.Commits = slices.Grow(c.Commits, 1)[:1]
+		c.Commits[0].Hash = change.ToHash
+		c.Commits[0].Links.HTML.Href = c.New.Target.Links.HTML.Href
+		if commit, err := s.getRefCommit(ctx, project, change.ToHash); err != nil {
+			logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Str("commit", change.ToHash).Msg("Failed to get Bitbucket Server commit")
+		} else {
+			c.Commits[0].Message = commit.Message
+			c.New.Target.Message = commit.Message
+		}
+	}
+	return &push
+}
+
+// fromBitbucketServerPR converts a Bitbucket Server pull request event to the shape of a
+// Bitbucket Cloud one
+func fromBitbucketServerPR(event *BitbucketServerPREvent) *BitbucketPREvent {
+	pr := &event.PullRequest
+	var converted BitbucketPREvent
+	converted.PullRequest.ID = pr.ID
+	converted.PullRequest.Title = pr.Title
+	converted.PullRequest.Description = pr.Description
+	converted.PullRequest.State = pr.State
+	converted.PullRequest.Source.Branch.Name = pr.FromRef.DisplayID
+	converted.PullRequest.Source.Commit.Hash = pr.FromRef.LatestCommit
+	converted.PullRequest.Destination.Branch.Name = pr.ToRef.DisplayID
+	converted.PullRequest.Author.DisplayName = bitbucketServerUserName(pr.Author.User)
+	converted.PullRequest.Author.Nickname = pr.Author.User.Name
+	if len(pr.Links.Self) > 0 {
+		converted.PullRequest.Links.HTML.Href = pr.Links.Self[0].Href
+	}
+	converted.Actor.DisplayName = bitbucketServerUserName(event.Actor)
+	return &converted
+}
+
+func bitbucketServerUserName(user BitbucketServerUser) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Name
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// reviewDedupeWindow is how long a (project, head SHA, event type) review request
+// suppresses identical events, e.g. webhook redeliveries or repeated "synchronize" events
+const reviewDedupeWindow = 5 * time.Minute
+
+// dedupePruneThreshold triggers the removal of expired keys
+const dedupePruneThreshold = 1024
+
+var (
+	reviewEventDeduper = newEventDeduper(reviewDedupeWindow)
+	dedupedEvents      atomic.Int64
+)
+
+// DedupedEventCount returns the number of webhook events skipped as duplicates
+func DedupedEventCount() int64 {
+	return dedupedEvents.Load()
+}
+
+// eventDeduper remembers recently handled event keys of this instance
+type eventDeduper struct {
+	window time.Duration
+	mu     sync.Mutex
+	seen   map[string]time.Time
+}
+
+func newEventDeduper(window time.Duration) *eventDeduper {
+	return &eventDeduper{window: window, seen: make(map[string]time.Time)}
+}
+
+// claim records key and reports whether it was not seen within the window
+func (d *eventDeduper) claim(key string, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.seen) >= dedupePruneThreshold {
+		for k, at := range d.seen {
+			if now.Sub(at) >= d.window {
+				delete(d.seen, k)
+			}
+		}
+	}
+	if at, ok := d.seen[key]; ok && now.Sub(at) < d.window {
+		return false
+	}
+	d.seen[key] = now
+	return true
+}
+
+// release forgets key, so an event whose processing failed isn't suppressed when redelivered
+func (d *eventDeduper) release(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, key)
+}
+
+// reviewEventKey is the dedupe key of a review of commitSHA requested by an event type
+func reviewEventKey(projectID uint, commitSHA, eventType string) string {
+	return fmt.Sprintf("%d:%s:%s", projectID, eventType, commitSHA)
+}
+
+// releaseEvent releases the dedupe claim of a review that failed before it was enqueued, so
+// a webhook redelivery or retry of the same event isn't skipped as a duplicate
+func (s *Service) releaseEvent(reviewLog *models.ReviewLog) {
+	reviewEventDeduper.release(reviewEventKey(reviewLog.ProjectID, reviewLog.CommitHash, reviewLog.EventType))
+}
+
+// isDuplicateEvent reports whether a review of the same head SHA and event type was
+// requested within the dedupe window, either on this instance or, as recorded in the
+// database, on another one. Duplicates are counted and bound to a waiting poll. Callers
+// release the claim with releaseEvent when the review fails before it is enqueued.
+func (s *Service) isDuplicateEvent(ctx context.Context, projectID uint, commitSHA, eventType string) bool {
+	if commitSHA == "" {
+		return false
+	}
+
+	now := time.Now()
+	duplicate := !reviewEventDeduper.claim(reviewEventKey(projectID, commitSHA, eventType), now)
+	if !duplicate {
+		var count int64
+		s.db.Model(&models.ReviewLog{}).
+			Where("project_id = ? AND commit_hash = ? AND event_type = ? AND created_at > ? AND review_status IN ?",
+				projectID, commitSHA, eventType, now.Add(-reviewDedupeWindow), []string{"completed", "pending", "processing", "analyzing"}).
+			Count(&count)
+		duplicate = count > 0
+	}
+	if !duplicate {
+		return false
+	}
+
+	dedupedEvents.Add(1)
+	logger.For(ctx, "webhook").Info().Uint("project_id", projectID).Str("commit", commitSHA).Str("event_type", eventType).Msg("Duplicate event, skipping")
+	s.bindPollToCommit(ctx, projectID, commitSHA)
+	return true
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestEventDeduper_Claim(t *testing.T) {
+	d := newEventDeduper(time.Minute)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		key      string
+		at       time.Time
+		expected bool
+	}{
+		{"first event", "1:merge_request:abc", now, true},
+		{"redelivery", "1:merge_request:abc", now.Add(10 * time.Second), false},
+		{"other event type", "1:push:abc", now.Add(10 * time.Second), true},
+		{"other project", "2:merge_request:abc", now.Add(10 * time.Second), true},
+		{"after the window", "1:merge_request:abc", now.Add(2 * time.Minute), true},
+	}
+	for _, tt := range tests {
+		if got := d.claim(tt.key, tt.at); got != tt.expected {
+			t.Errorf("%s: claim(%q) = %v, expected %v", tt.name, tt.key, got, tt.expected)
+		}
+	}
+}
+
+func TestEventDeduper_Release(t *testing.T) {
+	d := newEventDeduper(time.Minute)
+	now := time.Now()
+	key := reviewEventKey(1, "abc", "push")
+	d.claim(key, now)
+	d.release(key)
+	if !d.claim(key, now.Add(10*time.Second)) {
+		t.Error("claim after release = false, expected the redelivery to be processed")
+	}
+}
+
+func TestEventDeduper_PrunesExpiredKeys(t *testing.T) {
+	d := newEventDeduper(time.Minute)
+	now := time.Now()
+	for i := 0; i < dedupePruneThreshold; i++ {
+		d.claim(fmt.Sprintf("1:push:%d", i), now)
+	}
+	d.claim("1:push:new", now.Add(2*time.Minute))
+	if len(d.seen) != 1 {
+		t.Errorf("seen holds %d keys after pruning, expected 1", len(d.seen))
+	}
+}
+
+func TestShortSHA(t *testing.T) {
+	if got := shortSHA("0123456789abcdef"); got != "01234567" {
+		t.Errorf("shortSHA = %q, expected %q", got, "01234567")
+	}
+	if got := shortSHA("abc"); got != "abc" {
+		t.Errorf("shortSHA = %q, expected %q", got, "abc")
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// trackDefaultBranch records the default branch a push event reports for the project. Events
+// that don't carry it (Bitbucket) fetch it from the platform while it isn't known yet.
+func (s *Service) trackDefaultBranch(ctx context.Context, project *models.Project, reported string) {
+	if reported == "" {
+		if project.DefaultBranch != "" {
+			return
+		}
+		branch, err := s.fetchDefaultBranch(ctx, project)
+		if err != nil {
+			logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Msg("Failed to fetch default branch")
+			return
+		}
+		reported = branch
+	}
+	if reported == "" || reported == project.DefaultBranch {
+		return
+	}
+
+	if err := s.db.Model(&models.Project{}).Where("id = ?", project.ID).Update("default_branch", reported).Error; err != nil {
+		logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Msg("Failed to save default branch")
+		return
+	}
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("branch", reported).Str("previous", project.DefaultBranch).Msg("Default branch changed")
+	project.DefaultBranch = reported
+}
+
+// fetchDefaultBranch returns the default branch of the project's repository
+func (s *Service) fetchDefaultBranch(ctx context.Context, project *models.Project) (string, error) {
+	if project.URL == "" || !services.HasPlatformCredentials(s.db, project) {
+		return "", fmt.Errorf("project URL or access token not configured")
+	}
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return "", err
+	}
+
+	switch project.Platform {
+	case "gitlab":
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		apiURL := fmt.Sprintf("%s/api/v4/projects/%s", info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"))
+		if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &repo); err != nil {
+			return "", err
+		}
+		return repo.DefaultBranch, nil
+
+	case "github":
+		baseURL := "https://api.github.com"
+		if info.baseURL != "https://github.com" {
+			baseURL = info.baseURL + "/api/v3"
+		}
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		apiURL := fmt.Sprintf("%s/repos/%s/%s", baseURL, info.owner, info.repo)
+		if err := s.getPlatformJSON(ctx, apiURL, "Authorization", "token "+project.AccessToken, &repo); err != nil {
+			return "", err
+		}
+		return repo.DefaultBranch, nil
+
+	case "bitbucket":
+		api, err := s.bitbucketAPI(project)
+		if err != nil {
+			return "", err
+		}
+		auth, err := api.Authorization(ctx)
+		if err != nil {
+			return "", err
+		}
+		var repo struct {
+			MainBranch struct {
+				Name string `json:"name"`
+			} `json:"mainbranch"`
+			DisplayID string `json:"displayId"` // Bitbucket Server default branch
+		}
+		if err := s.getPlatformJSON(ctx, api.DefaultBranchURL(), "Authorization", auth, &repo); err != nil {
+			return "", err
+		}
+		if api.Server {
+			return repo.DisplayID, nil
+		}
+		return repo.MainBranch.Name, nil
+	}
+	return "", fmt.Errorf("unsupported platform: %s", project.Platform)
+}
@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestFetchDefaultBranch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/acme/api":
+			if r.Header.Get("Authorization") != "token secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"default_branch": "trunk"}`))
+		case "/api/v4/projects/acme%2Fapi", "/api/v4/projects/acme/api":
+			w.Write([]byte(`{"default_branch": "develop"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	s := &Service{httpClient: server.Client()}
+	tests := []struct {
+		name    string
+		project models.Project
+		want    string
+		wantErr bool
+	}{
+		{"github enterprise", models.Project{Platform: "github", URL: server.URL + "/acme/api", AccessToken: "secret"}, "trunk", false},
+		{"gitlab", models.Project{Platform: "gitlab", URL: server.URL + "/acme/api.git", AccessToken: "secret"}, "develop", false},
+		{"bad token", models.Project{Platform: "github", URL: server.URL + "/acme/api", AccessToken: "wrong"}, "", true},
+		{"no token", models.Project{Platform: "github", URL: server.URL + "/acme/api"}, "", true},
+		{"unsupported", models.Project{Platform: "gitea", URL: server.URL + "/acme/api", AccessToken: "secret"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.fetchDefaultBranch(context.Background(), &tt.project)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("fetchDefaultBranch() = %q, %v, want %q, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// DeferredReplayInterval is how often webhook deliveries deferred under backpressure are replayed
+const DeferredReplayInterval = 30 * time.Second
+
+// ReplayDeferredWebhooks processes the webhook deliveries deferred while reviews were over
+// capacity, as many as there is room for, and returns the number replayed
+func (s *Service) ReplayDeferredWebhooks(ctx context.Context, backpressure *services.BackpressureService) int {
+	deferred := backpressure.ClaimDeferred()
+	for i := range deferred {
+		d := &deferred[i]
+		ctx := logger.WithRequestID(ctx, d.RequestID)
+		handleCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		err := s.handleDeferredWebhook(handleCtx, d)
+		cancel()
+		log := logger.For(ctx, "webhook").With().Uint("project_id", d.ProjectID).Str("platform", d.Platform).
+			Str("event_type", d.EventType).Int("attempt", d.Attempts).Logger()
+		if err == nil {
+			if err := backpressure.CompleteDeferred(d); err != nil {
+				log.Warn().Err(err).Msg("Failed to remove replayed deferred webhook")
+			}
+			continue
+		}
+		log.Warn().Err(err).Msg("Failed to process deferred webhook")
+		if dropped, err := backpressure.ReleaseDeferred(d); err != nil {
+			log.Warn().Err(err).Msg("Failed to release deferred webhook")
+		} else if dropped {
+			log.Error().Msg("Dropping deferred webhook after repeated failures")
+		}
+	}
+	if len(deferred) > 0 {
+		logger.Infof("[Backpressure] Replayed %d deferred webhook deliveries", len(deferred))
+	}
+	return len(deferred)
+}
+
+func (s *Service) handleDeferredWebhook(ctx context.Context, d *models.DeferredWebhook) error {
+	body := []byte(d.Body)
+	switch d.Platform {
+	case "gitlab":
+		return s.HandleGitLabWebhook(ctx, d.ProjectID, d.EventType, body)
+	case "github":
+		return s.HandleGitHubWebhook(ctx, d.ProjectID, d.EventType, body)
+	case "bitbucket":
+		return s.HandleBitbucketWebhook(ctx, d.ProjectID, d.EventType, body)
+	}
+	return fmt.Errorf("unsupported platform: %s", d.Platform)
+}
+
+var deferredStopChan chan struct{}
+
+// StartDeferredWebhookScheduler starts a goroutine that periodically replays deferred webhook deliveries
+func StartDeferredWebhookScheduler(s *Service) {
+	deferredStopChan = make(chan struct{})
+	go func() {
+		backpressure := services.NewBackpressureService(s.db)
+		services.RegisterScheduler("deferred_webhooks", DeferredReplayInterval)
+		ticker := time.NewTicker(DeferredReplayInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				services.RunScheduled("deferred_webhooks", func() error {
+					s.ReplayDeferredWebhooks(context.Background(), backpressure)
+					return nil
+				})
+			case <-deferredStopChan:
+				logger.Infof("[Backpressure] Deferred webhook scheduler stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopDeferredWebhookScheduler stops the deferred webhook scheduler
+func StopDeferredWebhookScheduler() {
+	if deferredStopChan != nil {
+		close(deferredStopChan)
+	}
+}
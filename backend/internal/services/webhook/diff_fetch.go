@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// diffFetchConcurrency bounds the diff requests in flight for one push
+const diffFetchConcurrency = 5
+
+// diffFetchTimeout limits each diff request
+var diffFetchTimeout = 30 * time.Second
+
+// commitDiff is the fetched diff of one commit
+type commitDiff struct {
+	sha  string
+	diff string
+	err  error
+	took time.Duration
+}
+
+// fetchCommitDiffs fetches the diff of each commit concurrently, with at most
+// diffFetchConcurrency requests in flight. Results keep the order of shas.
+func fetchCommitDiffs(ctx context.Context, shas []string, fetch func(ctx context.Context, sha string) (string, error)) []commitDiff {
+	results := make([]commitDiff, len(shas))
+	sem := make(chan struct{}, diffFetchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, sha := range shas {
+		results[i].sha = sha
+		wg.Add(1)
+		go func(result *commitDiff) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				result.err = ctx.Err()
+				return
+			}
+
+			reqCtx, cancel := context.WithTimeout(ctx, diffFetchTimeout)
+			defer cancel()
+			start := time.Now()
+			result.diff, result.err = fetch(reqCtx, result.sha)
+			result.took = time.Since(start)
+		}(&results[i])
+	}
+
+	wg.Wait()
+	return results
+}
+
+// diffFetchMs records the diff fetch that began at start in the stage histogram and returns
+// its duration in milliseconds, see ReviewLog.DiffFetchMs
+func diffFetchMs(start time.Time) int64 {
+	return services.ObserveReviewStage(services.ReviewStageDiffFetch, time.Since(start))
+}
+
+// failDiffFetch records that the diff of a review couldn't be fetched, instead of reviewing
+// the error, and sets the configured commit status; the retry scheduler fetches it again
+func (s *Service) failDiffFetch(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, gitlabProjectID int, fetchErr error) {
+	logger.For(ctx, "webhook").Warn().Err(fetchErr).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", reviewLog.CommitHash).Msg("Failed to fetch diff, review left to the retry scheduler")
+	reviewLog.ReviewStatus = services.ReviewStatusDiffFetchFailed
+	reviewLog.ErrorMessage = "Failed to fetch diff: " + fetchErr.Error()
+	s.reviewService.Update(reviewLog)
+	s.releaseEvent(reviewLog)
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, services.ReviewStatusDiffFetchFailed, nil, reviewLog.ErrorMessage)
+	services.LogWarning(ctx, "Webhook", "DiffFetchFailed", fmt.Sprintf("Failed to fetch diff of %s: %v", shortSHA(reviewLog.CommitHash), fetchErr), nil, "", "", map[string]interface{}{
+		"project_id":    project.ID,
+		"review_log_id": reviewLog.ID,
+		"commit":        reviewLog.CommitHash,
+	})
+
+	cfg := s.configService.GetDiffFetchConfig()
+	retrying := ""
+	if cfg.Retry {
+		retrying = ", retrying"
+	}
+	switch cfg.CommitStatus {
+	case services.DiffFetchStatusPending:
+		s.setCommitStatus(project, reviewLog.CommitHash, "pending", "AI Review waiting: diff could not be fetched"+retrying, gitlabProjectID)
+	case services.DiffFetchStatusSuccess:
+		s.setCommitStatus(project, reviewLog.CommitHash, "success", "AI Review skipped: diff could not be fetched"+retrying, gitlabProjectID)
+	default:
+		s.setCommitStatus(project, reviewLog.CommitHash, "error", "AI Review error: diff could not be fetched"+retrying, gitlabProjectID)
+	}
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchCommitDiffs_PreservesOrder(t *testing.T) {
+	shas := []string{"c1", "c2", "c3", "c4", "c5", "c6", "c7", "c8"}
+	var inFlight, maxInFlight int32
+
+	results := fetchCommitDiffs(context.Background(), shas, func(ctx context.Context, sha string) (string, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		// Later commits finish first
+		time.Sleep(time.Duration(len(shas)-int(sha[1]-'0')) * time.Millisecond)
+		if sha == "c3" {
+			return "", errors.New("not found")
+		}
+		return "diff " + sha, nil
+	})
+
+	if maxInFlight > diffFetchConcurrency {
+		t.Errorf("expected at most %d concurrent fetches, got %d", diffFetchConcurrency, maxInFlight)
+	}
+	for i, r := range results {
+		if r.sha != shas[i] {
+			t.Fatalf("result %d is for %s, want %s", i, r.sha, shas[i])
+		}
+		if r.sha == "c3" {
+			if r.err == nil {
+				t.Error("expected error for c3")
+			}
+		} else if r.diff != "diff "+r.sha {
+			t.Errorf("unexpected diff for %s: %q", r.sha, r.diff)
+		}
+	}
+}
+
+func TestFetchCommitDiffs_Timeout(t *testing.T) {
+	old := diffFetchTimeout
+	diffFetchTimeout = 10 * time.Millisecond
+	defer func() { diffFetchTimeout = old }()
+
+	results := fetchCommitDiffs(context.Background(), []string{"slow"}, func(ctx context.Context, sha string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	if !errors.Is(results[0].err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", results[0].err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
@@ -22,6 +23,16 @@ func (s *Service) HandleGitHubWebhook(ctx context.Context, projectID uint, event
 		return fmt.Errorf("project not found: %w", err)
 	}
 
+	if project.Archived {
+		logger.For(ctx, "webhook").Info().Str("platform", "github").Uint("project_id", projectID).Msg("Project is archived, skipping")
+		return nil
+	}
+
+	if project.PendingApproval {
+		logger.For(ctx, "webhook").Info().Str("platform", "github").Uint("project_id", projectID).Msg("Project is pending approval, skipping")
+		return nil
+	}
+
 	if !project.AIEnabled {
 		return nil
 	}
@@ -46,29 +57,83 @@ func (s *Service) HandleGitHubWebhook(ctx context.Context, projectID uint, event
 			return err
 		}
 		return s.processGitHubPR(ctx, project, &event)
+
+	case "release":
+		if !releaseEventsEnabled(project) {
+			return nil
+		}
+		var event GitHubReleaseEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			return err
+		}
+		return s.processGitHubRelease(ctx, project, &event)
 	}
 
 	return nil
 }
 
 func (s *Service) processGitHubPush(ctx context.Context, project *models.Project, event *GitHubPushEvent) error {
+	s.trackDefaultBranch(ctx, project, event.Repository.DefaultBranch)
 	if len(event.Commits) == 0 {
 		return nil
 	}
 
 	branch := strings.TrimPrefix(event.Ref, "refs/heads/")
-	if s.isBranchIgnored(branch, project.BranchFilter) {
+	if s.isBranchIgnored(branch, project) {
 		return nil
 	}
 
 	if s.isCommitAlreadyReviewed(project.ID, event.After) {
 		return nil
 	}
+	if s.isDuplicateEvent(ctx, project.ID, event.After, "push") {
+		return nil
+	}
 
-	var commits []string
+	head := event.Commits[len(event.Commits)-1]
+	if s.skipByDirective(ctx, project, &models.ReviewLog{
+		EventType:     "push",
+		CommitHash:    event.After,
+		CommitURL:     head.URL,
+		Branch:        branch,
+		Author:        event.Sender.Login,
+		AuthorEmail:   event.Pusher.Email,
+		AuthorAvatar:  event.Sender.AvatarURL,
+		AuthorURL:     event.Sender.HTMLURL,
+		CommitMessage: head.Message,
+	}, branch, services.FindSkipDirective(head.Message), 0) {
+		return nil
+	}
+
+	if project.PerCommitReview {
+		push := &commitPush{
+			head:         event.After,
+			branch:       branch,
+			author:       event.Sender.Login,
+			authorEmail:  event.Pusher.Email,
+			authorAvatar: event.Sender.AvatarURL,
+			authorURL:    event.Sender.HTMLURL,
+		}
+		for _, c := range event.Commits {
+			commit := pushCommit{sha: c.ID, message: c.Message, url: c.URL, author: c.Author.Username, authorEmail: c.Author.Email}
+			if commit.author == "" {
+				commit.author = c.Author.Name
+			}
+			if c.Author.Username != "" && c.Author.Username == event.Sender.Login {
+				commit.authorAvatar, commit.authorURL = event.Sender.AvatarURL, event.Sender.HTMLURL
+			}
+			push.commits = append(push.commits, commit)
+		}
+		return s.reviewCommitsSeparately(ctx, project, push, func(ctx context.Context, sha string) (string, error) {
+			return s.getGitHubDiff(project, sha)
+		})
+	}
+
+	var commits, commitSHAs []string
 	var commitURL string
 	for _, c := range event.Commits {
 		commits = append(commits, fmt.Sprintf("%s: %s", c.ID[:8], c.Message))
+		commitSHAs = append(commitSHAs, c.ID)
 		if commitURL == "" && c.URL != "" {
 			commitURL = c.URL
 		}
@@ -76,24 +141,20 @@ func (s *Service) processGitHubPush(ctx context.Context, project *models.Project
 
 	var diff string
 
+	fetchStart := time.Now()
 	if !isNullSHA(event.Before) && event.Before != "" {
 		compareDiff, err := s.getGitHubCompareDiff(project, event.Before, event.After)
 		if err != nil {
-			logger.Infof("[Webhook] GitHub compare API failed, falling back to single commit diff: %v", err)
+			logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Str("commit", event.After).Msg("GitHub compare API failed, falling back to single commit diff")
 		} else if compareDiff != "" {
 			diff = compareDiff
-			logger.Infof("[Webhook] Got GitHub compare diff (before=%s, after=%s), length: %d bytes",
-				event.Before[:8], event.After[:8], len(diff))
+			logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("commit", event.After).Str("before", event.Before).Int("bytes", len(diff)).Msg("Got GitHub compare diff")
 		}
 	}
 
+	var fetchErr error
 	if diff == "" {
-		d, err := s.getGitHubDiff(project, event.After)
-		if err != nil {
-			diff = "Failed to get diff: " + err.Error()
-		} else {
-			diff = d
-		}
+		diff, fetchErr = s.getGitHubDiff(project, event.After)
 	}
 
 	additions, deletions, filesChanged := ParseDiffStats(diff)
@@ -112,13 +173,19 @@ func (s *Service) processGitHubPush(ctx context.Context, project *models.Project
 		FilesChanged:  filesChanged,
 		Additions:     additions,
 		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
 		ReviewStatus:  "pending",
 	}
-	s.reviewService.Create(reviewLog)
+	s.createReviewLog(ctx, reviewLog)
+	if fetchErr != nil {
+		s.failDiffFetch(ctx, project, reviewLog, 0, fetchErr)
+		return nil
+	}
 
 	// Enqueue review task for async processing
 	task := &services.ReviewTask{
 		ReviewLogID:   reviewLog.ID,
+		RequestID:     logger.RequestID(ctx),
 		ProjectID:     project.ID,
 		CommitSHA:     event.After,
 		EventType:     "push",
@@ -129,17 +196,19 @@ func (s *Service) processGitHubPush(ctx context.Context, project *models.Project
 		CommitMessage: strings.Join(commits, "\n"),
 		Diff:          diff,
 		CommitURL:     commitURL,
+		CommitSHAs:    commitSHAs,
 	}
 
 	if err := services.GetTaskQueue().Enqueue(task); err != nil {
-		logger.Infof("[Webhook] Failed to enqueue GitHub push review task: %v", err)
+		logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", event.After).Msg("Failed to enqueue GitHub push review task")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
 		return err
 	}
 
-	logger.Infof("[Webhook] GitHub push review task enqueued for project %d, commit %s", project.ID, event.After[:8])
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", event.After).Msg("GitHub push review task enqueued")
 	return nil
 }
 
@@ -148,17 +217,34 @@ func (s *Service) processGitHubPR(ctx context.Context, project *models.Project,
 		return nil
 	}
 
-	if s.isBranchIgnored(event.PullRequest.Head.Ref, project.BranchFilter) {
+	if s.isMergeRequestIgnored(event.PullRequest.Head.Ref, event.PullRequest.Base.Ref, project) {
 		return nil
 	}
 
-	mrNumber := event.Number
+	// "synchronize" is sent again for redeliveries and pushes that keep the head SHA
+	if s.isDuplicateEvent(ctx, project.ID, event.PullRequest.Head.SHA, "merge_request") {
+		return nil
+	}
 
-	diff, err := s.getGitHubPRDiff(project, mrNumber)
-	if err != nil {
-		diff = "Failed to get diff: " + err.Error()
+	mrNumber := event.Number
+	if s.skipByDirective(ctx, project, &models.ReviewLog{
+		EventType:     "merge_request",
+		CommitHash:    event.PullRequest.Head.SHA,
+		Branch:        event.PullRequest.Head.Ref,
+		TargetBranch:  event.PullRequest.Base.Ref,
+		Author:        event.PullRequest.User.Login,
+		AuthorAvatar:  event.PullRequest.User.AvatarURL,
+		AuthorURL:     event.PullRequest.User.HTMLURL,
+		CommitMessage: event.PullRequest.Title,
+		MRNumber:      &mrNumber,
+		MRURL:         event.PullRequest.HTMLURL,
+	}, event.PullRequest.Base.Ref, services.FindSkipDirective(event.PullRequest.Title+"\n"+event.PullRequest.Body), 0) {
+		return nil
 	}
 
+	fetchStart := time.Now()
+	diff, fetchErr := s.getGitHubPRDiff(project, mrNumber)
+
 	additions, deletions, filesChanged := ParseDiffStats(diff)
 
 	reviewLog := &models.ReviewLog{
@@ -173,19 +259,26 @@ func (s *Service) processGitHubPR(ctx context.Context, project *models.Project,
 		FilesChanged:  filesChanged,
 		Additions:     additions,
 		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
 		MRNumber:      &mrNumber,
 		MRURL:         event.PullRequest.HTMLURL,
 		ReviewStatus:  "pending",
 	}
-	s.reviewService.Create(reviewLog)
+	s.createReviewLog(ctx, reviewLog)
+	if fetchErr != nil {
+		s.failDiffFetch(ctx, project, reviewLog, 0, fetchErr)
+		return nil
+	}
 
 	// Enqueue review task for async processing
 	task := &services.ReviewTask{
 		ReviewLogID:   reviewLog.ID,
+		RequestID:     logger.RequestID(ctx),
 		ProjectID:     project.ID,
 		CommitSHA:     event.PullRequest.Head.SHA,
 		EventType:     "merge_request",
 		Branch:        event.PullRequest.Head.Ref,
+		TargetBranch:  event.PullRequest.Base.Ref,
 		Author:        event.PullRequest.User.Login,
 		AuthorAvatar:  event.PullRequest.User.AvatarURL,
 		CommitMessage: event.PullRequest.Title + "\n" + event.PullRequest.Body,
@@ -195,14 +288,15 @@ func (s *Service) processGitHubPR(ctx context.Context, project *models.Project,
 	}
 
 	if err := services.GetTaskQueue().Enqueue(task); err != nil {
-		logger.Infof("[Webhook] Failed to enqueue GitHub PR review task: %v", err)
+		logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Int("mr", mrNumber).Msg("Failed to enqueue GitHub PR review task")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
 		return err
 	}
 
-	logger.Infof("[Webhook] GitHub PR review task enqueued for project %d, PR #%d", project.ID, mrNumber)
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Int("mr", mrNumber).Str("commit", event.PullRequest.Head.SHA).Msg("GitHub PR review task enqueued")
 	return nil
 }
 
@@ -227,7 +321,7 @@ func (s *Service) getGitHubCompareDiff(project *models.Project, before, after st
 	}
 
 	apiURL := fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s", baseURL, info.owner, info.repo, before, after)
-	logger.Infof("[Webhook] Fetching GitHub compare diff: %s...%s", before[:8], after[:8])
+	logger.Module("webhook").Info().Uint("project_id", project.ID).Str("from", before).Str("to", after).Msg("Fetching GitHub compare diff")
 	return s.fetchGitHubDiff(apiURL, project.AccessToken)
 }
 
@@ -252,6 +346,9 @@ func (s *Service) fetchGitHubDiff(apiURL, accessToken string) (string, error) {
 	}
 	defer resp.Body.Close()
 	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, string(body))
+	}
 	return string(body), nil
 }
 
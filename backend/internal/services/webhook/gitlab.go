@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
@@ -17,59 +19,95 @@ import (
 
 // HandleGitLabWebhook processes GitLab webhook events
 func (s *Service) HandleGitLabWebhook(ctx context.Context, projectID uint, eventType string, body []byte) error {
-	logger.Infof("[Webhook] Received GitLab webhook: projectID=%d, eventType=%s", projectID, eventType)
+	log := logger.For(ctx, "webhook").With().Str("platform", "gitlab").Uint("project_id", projectID).Str("event_type", eventType).Logger()
+	log.Info().Msg("Received webhook")
 
 	project, err := s.projectService.GetByID(projectID)
 	if err != nil {
-		logger.Infof("[Webhook] Project not found: %d, error: %v", projectID, err)
+		log.Warn().Err(err).Msg("Project not found")
 		return fmt.Errorf("project not found: %w", err)
 	}
 
+	if project.Archived {
+		log.Info().Msg("Project is archived, skipping")
+		return nil
+	}
+
+	if project.PendingApproval {
+		log.Info().Msg("Project is pending approval, skipping")
+		return nil
+	}
+
 	if !project.AIEnabled {
-		logger.Infof("[Webhook] AI disabled for project %d, skipping", projectID)
+		log.Info().Msg("AI disabled for project, skipping")
 		return nil
 	}
 
 	switch eventType {
 	case "Push Hook":
 		if !strings.Contains(project.ReviewEvents, "push") {
-			logger.Infof("[Webhook] Push events not enabled for project %d, skipping", projectID)
+			log.Info().Msg("Push events not enabled for project, skipping")
 			return nil
 		}
 		var event GitLabPushEvent
 		if err := json.Unmarshal(body, &event); err != nil {
-			logger.Infof("[Webhook] Failed to parse GitLab push event: %v", err)
+			log.Warn().Err(err).Msg("Failed to parse push event")
 			return err
 		}
 		return s.processGitLabPush(ctx, project, &event)
 
+	case "Tag Push Hook":
+		if !releaseEventsEnabled(project) {
+			log.Info().Msg("Tag events not enabled for project, skipping")
+			return nil
+		}
+		var event GitLabPushEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			log.Warn().Err(err).Msg("Failed to parse tag push event")
+			return err
+		}
+		return s.processGitLabTagPush(ctx, project, &event)
+
 	case "Merge Request Hook":
 		if !strings.Contains(project.ReviewEvents, "merge_request") {
-			logger.Infof("[Webhook] MR events not enabled for project %d, skipping", projectID)
+			log.Info().Msg("MR events not enabled for project, skipping")
 			return nil
 		}
 		var event GitLabMREvent
 		if err := json.Unmarshal(body, &event); err != nil {
-			logger.Infof("[Webhook] Failed to parse GitLab MR event: %v", err)
+			log.Warn().Err(err).Msg("Failed to parse MR event")
 			return err
 		}
 		return s.processGitLabMR(ctx, project, &event)
 
+	case "Note Hook":
+		if !commentEventsEnabled(project) {
+			log.Info().Msg("Comment events not enabled for project, skipping")
+			return nil
+		}
+		var event GitLabNoteEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			log.Warn().Err(err).Msg("Failed to parse note event")
+			return err
+		}
+		return s.processGitLabNote(ctx, project, &event)
+
 	default:
-		logger.Infof("[Webhook] Unknown GitLab event type: %s, skipping", eventType)
+		log.Info().Msg("Unknown event type, skipping")
 	}
 
 	return nil
 }
 
 func (s *Service) processGitLabPush(ctx context.Context, project *models.Project, event *GitLabPushEvent) error {
+	s.trackDefaultBranch(ctx, project, event.Project.DefaultBranch)
 	if len(event.Commits) == 0 {
 		return nil
 	}
 
 	branch := strings.TrimPrefix(event.Ref, "refs/heads/")
-	if s.isBranchIgnored(branch, project.BranchFilter) {
-		logger.Infof("[Webhook] Branch %s is in ignore list, skipping review", branch)
+	if s.isBranchIgnored(branch, project) {
+		logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("branch", branch).Msg("Branch is in ignore list, skipping review")
 		return nil
 	}
 
@@ -77,65 +115,141 @@ func (s *Service) processGitLabPush(ctx context.Context, project *models.Project
 	if commitSHA == "" && len(event.Commits) > 0 {
 		commitSHA = event.Commits[len(event.Commits)-1].ID
 	}
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Str("branch", branch).Str("commit", commitSHA).Logger()
 
 	if s.isCommitAlreadyReviewed(project.ID, commitSHA) {
-		logger.Infof("[Webhook] Commit %s already reviewed, skipping", commitSHA[:8])
+		log.Info().Msg("Commit already reviewed, skipping")
+		s.bindPollToCommit(ctx, project.ID, commitSHA)
+		return nil
+	}
+	if s.isDuplicateEvent(ctx, project.ID, commitSHA, "push") {
+		return nil
+	}
+
+	head := event.Commits[len(event.Commits)-1]
+	for _, c := range event.Commits {
+		if c.ID == commitSHA {
+			head = c
+		}
+	}
+	directive := services.FindSkipDirective(head.Message)
+	if services.HasSkipPushOption(event.PushOptions) {
+		directive = "-o " + services.SkipPushOption
+	}
+	if s.skipByDirective(ctx, project, &models.ReviewLog{
+		EventType:     "push",
+		CommitHash:    commitSHA,
+		CommitURL:     head.URL,
+		Branch:        branch,
+		Author:        event.UserName,
+		AuthorEmail:   event.UserEmail,
+		AuthorAvatar:  event.UserAvatar,
+		CommitMessage: head.Message,
+	}, branch, directive, event.ProjectID) {
 		return nil
 	}
 
-	var commits []string
+	var commits, commitSHAs []string
 	var commitURL string
 	for _, c := range event.Commits {
 		commits = append(commits, fmt.Sprintf("%s: %s", c.ID[:8], c.Message))
+		commitSHAs = append(commitSHAs, c.ID)
 		if commitURL == "" && c.URL != "" {
 			commitURL = c.URL
 		}
 	}
 
-	logger.Infof("[Webhook] Processing GitLab push: %d commits, branch=%s, commit=%s",
-		len(event.Commits), branch, commitSHA[:8])
+	log.Info().Int("commits", len(event.Commits)).Msg("Processing GitLab push")
 
-	services.LogInfo("Webhook", "GitLabPush", fmt.Sprintf("Processing push from %s: %d commits", event.UserName, len(event.Commits)), nil, "", "", map[string]interface{}{
+	services.LogInfo(ctx, "Webhook", "GitLabPush", fmt.Sprintf("Processing push from %s: %d commits", event.UserName, len(event.Commits)), nil, "", "", map[string]interface{}{
 		"project_id": project.ID,
 		"branch":     branch,
 		"commit":     commitSHA,
 	})
 
+	if project.PerCommitReview {
+		push := &commitPush{
+			head:            commitSHA,
+			branch:          branch,
+			author:          event.UserName,
+			authorEmail:     event.UserEmail,
+			authorAvatar:    event.UserAvatar,
+			gitlabProjectID: event.ProjectID,
+		}
+		for _, c := range event.Commits {
+			commit := pushCommit{sha: c.ID, message: c.Message, url: c.URL, author: c.Author.Name, authorEmail: c.Author.Email}
+			if c.Author.Email == event.UserEmail {
+				commit.authorAvatar = event.UserAvatar
+			}
+			push.commits = append(push.commits, commit)
+		}
+		// GitLab lists at most 20 commits in the event, the rest of a larger push comes from
+		// comparing it against the commit before it
+		if event.TotalCommitsCount > len(event.Commits) && !isNullSHA(event.Before) {
+			commits, err := s.getGitLabPushCommits(ctx, project, event.Before, commitSHA)
+			if err != nil {
+				log.Warn().Err(err).Int("total_commits", event.TotalCommitsCount).Msg("Failed to list all commits of the push, reviewing the listed ones")
+			} else if len(commits) > 0 {
+				for i := range commits {
+					if commits[i].authorEmail == event.UserEmail {
+						commits[i].authorAvatar = event.UserAvatar
+					}
+				}
+				push.commits = commits
+			}
+		}
+		return s.reviewCommitsSeparately(ctx, project, push, func(ctx context.Context, sha string) (string, error) {
+			return s.getGitLabDiff(ctx, project, sha)
+		})
+	}
+
 	s.setGitLabCommitStatus(project, commitSHA, "pending", "AI Review in progress...", event.ProjectID)
 
 	var diff string
+	var fetchErr error
 
+	fetchStart := time.Now()
 	// Use compare API (before→after) for accurate diffs, especially for merge commits
 	if !isNullSHA(event.Before) && event.Before != "" {
 		compareDiff, err := s.getGitLabCompareDiff(project, event.Before, commitSHA)
 		if err != nil {
-			logger.Infof("[Webhook] Compare API failed, falling back to per-commit diffs: %v", err)
+			log.Warn().Err(err).Msg("Compare API failed, falling back to per-commit diffs")
 		} else if compareDiff != "" {
 			diff = compareDiff
-			logger.Infof("[Webhook] Got compare diff (before=%s, after=%s), length: %d bytes",
-				event.Before[:8], commitSHA[:8], len(diff))
+			log.Info().Str("before", event.Before).Int("bytes", len(diff)).Msg("Got compare diff")
 		}
 	}
 
 	// Fallback: fetch diffs per commit (for initial push or compare API failure)
 	if diff == "" {
-		var allDiffs strings.Builder
+		shas := make([]string, 0, len(event.Commits))
 		for _, c := range event.Commits {
-			d, err := s.getGitLabDiff(project, c.ID)
-			if err != nil {
-				logger.Infof("[Webhook] Failed to get diff for commit %s: %v", c.ID[:8], err)
+			shas = append(shas, c.ID)
+		}
+		results := fetchCommitDiffs(ctx, shas, func(ctx context.Context, sha string) (string, error) {
+			return s.getGitLabDiff(ctx, project, sha)
+		})
+
+		var allDiffs strings.Builder
+		for _, r := range results {
+			if r.err != nil {
+				log.Warn().Err(r.err).Str("diff_commit", r.sha).Msg("Failed to get commit diff")
+				fetchErr = r.err
 				continue
 			}
-			allDiffs.WriteString(fmt.Sprintf("\n### Commit: %s\n%s\n", c.ID[:8], d))
+			allDiffs.WriteString(fmt.Sprintf("\n### Commit: %s\n%s\n", r.sha[:8], r.diff))
 		}
 		diff = allDiffs.String()
 	}
 
 	if diff == "" {
-		diff = "Failed to get diff for all commits"
-		logger.Infof("[Webhook] No diffs retrieved for any commits")
+		if fetchErr == nil {
+			fetchErr = fmt.Errorf("no diff retrieved for any commit")
+		}
+		log.Warn().Msg("No diffs retrieved for any commits")
 	} else {
-		logger.Infof("[Webhook] Got combined diffs, total length: %d bytes", len(diff))
+		fetchErr = nil
+		log.Info().Int("bytes", len(diff)).Msg("Got combined diffs")
 	}
 
 	additions, deletions, filesChanged := ParseDiffStats(diff)
@@ -153,15 +267,21 @@ func (s *Service) processGitLabPush(ctx context.Context, project *models.Project
 		FilesChanged:  filesChanged,
 		Additions:     additions,
 		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
 		ReviewStatus:  "pending",
 	}
-	s.reviewService.Create(reviewLog)
+	s.createReviewLog(ctx, reviewLog)
+	if fetchErr != nil {
+		s.failDiffFetch(ctx, project, reviewLog, event.ProjectID, fetchErr)
+		return nil
+	}
 
-	logger.Infof("[Webhook] Starting AI review for project %d, commit %s", project.ID, commitSHA[:8])
+	log.Info().Uint("review_id", reviewLog.ID).Msg("Starting AI review")
 
 	// Enqueue review task for async processing
 	task := &services.ReviewTask{
 		ReviewLogID:     reviewLog.ID,
+		RequestID:       logger.RequestID(ctx),
 		ProjectID:       project.ID,
 		CommitSHA:       commitSHA,
 		EventType:       "push",
@@ -172,18 +292,20 @@ func (s *Service) processGitLabPush(ctx context.Context, project *models.Project
 		CommitMessage:   strings.Join(commits, "\n"),
 		Diff:            diff,
 		CommitURL:       commitURL,
+		CommitSHAs:      commitSHAs,
 		GitLabProjectID: event.ProjectID,
 	}
 
 	if err := services.GetTaskQueue().Enqueue(task); err != nil {
-		logger.Infof("[Webhook] Failed to enqueue review task: %v", err)
+		log.Error().Err(err).Uint("review_id", reviewLog.ID).Msg("Failed to enqueue review task")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
 		return err
 	}
 
-	logger.Infof("[Webhook] Review task enqueued for project %d, commit %s", project.ID, commitSHA[:8])
+	log.Info().Uint("review_id", reviewLog.ID).Msg("Review task enqueued")
 	return nil
 }
 
@@ -192,24 +314,40 @@ func (s *Service) processGitLabMR(ctx context.Context, project *models.Project,
 		return nil
 	}
 
-	if s.isBranchIgnored(event.ObjectAttributes.SourceBranch, project.BranchFilter) {
-		logger.Infof("[Webhook] Branch %s is in ignore list, skipping review", event.ObjectAttributes.SourceBranch)
+	mrIID := event.ObjectAttributes.IID
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Int("mr", mrIID).Str("branch", event.ObjectAttributes.SourceBranch).Logger()
+	if s.isMergeRequestIgnored(event.ObjectAttributes.SourceBranch, event.ObjectAttributes.TargetBranch, project) {
+		log.Info().Msg("Branch is in ignore list, skipping review")
 		return nil
 	}
 
-	mrIID := event.ObjectAttributes.IID
 	commitSHA, err := s.getGitLabRequestSHA(project, mrIID)
 	if err != nil {
-		logger.Infof("[Webhook] Failed to get MR commit SHA: %v", err)
+		log.Warn().Err(err).Msg("Failed to get MR commit SHA")
 		return err
 	}
+	if s.isDuplicateEvent(ctx, project.ID, commitSHA, "merge_request") {
+		return nil
+	}
+	if s.skipByDirective(ctx, project, &models.ReviewLog{
+		EventType:     "merge_request",
+		CommitHash:    commitSHA,
+		Branch:        event.ObjectAttributes.SourceBranch,
+		TargetBranch:  event.ObjectAttributes.TargetBranch,
+		Author:        event.User.Username,
+		AuthorEmail:   event.User.Email,
+		AuthorAvatar:  event.User.AvatarURL,
+		CommitMessage: event.ObjectAttributes.Title,
+		MRNumber:      &mrIID,
+		MRURL:         event.ObjectAttributes.URL,
+	}, event.ObjectAttributes.TargetBranch, services.FindSkipDirective(event.ObjectAttributes.Title+"\n"+event.ObjectAttributes.Description), event.Project.ID) {
+		return nil
+	}
 
 	s.setGitLabCommitStatus(project, commitSHA, "pending", "AI Review in progress...", event.Project.ID)
 
-	diff, err := s.getGitLabMRDiff(project, mrIID)
-	if err != nil {
-		diff = "Failed to get diff: " + err.Error()
-	}
+	fetchStart := time.Now()
+	diff, fetchErr := s.getGitLabMRDiff(ctx, project, mrIID)
 
 	additions, deletions, filesChanged := ParseDiffStats(diff)
 
@@ -225,19 +363,26 @@ func (s *Service) processGitLabMR(ctx context.Context, project *models.Project,
 		FilesChanged:  filesChanged,
 		Additions:     additions,
 		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
 		MRNumber:      &mrIID,
 		MRURL:         event.ObjectAttributes.URL,
 		ReviewStatus:  "pending",
 	}
-	s.reviewService.Create(reviewLog)
+	s.createReviewLog(ctx, reviewLog)
+	if fetchErr != nil {
+		s.failDiffFetch(ctx, project, reviewLog, event.Project.ID, fetchErr)
+		return nil
+	}
 
 	// Enqueue review task for async processing
 	task := &services.ReviewTask{
 		ReviewLogID:     reviewLog.ID,
+		RequestID:       logger.RequestID(ctx),
 		ProjectID:       project.ID,
 		CommitSHA:       commitSHA,
 		EventType:       "merge_request",
 		Branch:          event.ObjectAttributes.SourceBranch,
+		TargetBranch:    event.ObjectAttributes.TargetBranch,
 		Author:          event.User.Username,
 		AuthorEmail:     event.User.Email,
 		AuthorAvatar:    event.User.AvatarURL,
@@ -249,18 +394,19 @@ func (s *Service) processGitLabMR(ctx context.Context, project *models.Project,
 	}
 
 	if err := services.GetTaskQueue().Enqueue(task); err != nil {
-		logger.Infof("[Webhook] Failed to enqueue MR review task: %v", err)
+		log.Error().Err(err).Uint("review_id", reviewLog.ID).Msg("Failed to enqueue MR review task")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
 		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
 		return err
 	}
 
-	logger.Infof("[Webhook] MR review task enqueued for project %d, MR #%d", project.ID, mrIID)
+	log.Info().Uint("review_id", reviewLog.ID).Str("commit", commitSHA).Msg("MR review task enqueued")
 	return nil
 }
 
-func (s *Service) getGitLabDiff(project *models.Project, commitSHA string) (string, error) {
+func (s *Service) getGitLabDiff(ctx context.Context, project *models.Project, commitSHA string) (string, error) {
 	info, err := parseRepoInfo(project.URL)
 	if err != nil {
 		return "", err
@@ -269,7 +415,7 @@ func (s *Service) getGitLabDiff(project *models.Project, commitSHA string) (stri
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s/diff",
 		info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), commitSHA)
 
-	return s.fetchDiff(apiURL, project.AccessToken, "PRIVATE-TOKEN")
+	return s.fetchDiff(ctx, apiURL, project.AccessToken, "PRIVATE-TOKEN")
 }
 
 func (s *Service) getGitLabCompareDiff(project *models.Project, from, to string) (string, error) {
@@ -279,9 +425,9 @@ func (s *Service) getGitLabCompareDiff(project *models.Project, from, to string)
 	}
 
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/compare?from=%s&to=%s&straight=false",
-		info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), from, to)
+		info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), url.QueryEscape(from), url.QueryEscape(to))
 
-	logger.Infof("[Webhook] Fetching GitLab compare diff: %s...%s", from[:8], to[:8])
+	logger.Module("webhook").Info().Uint("project_id", project.ID).Str("from", from).Str("to", to).Msg("Fetching GitLab compare diff")
 
 	req, _ := http.NewRequest("GET", apiURL, nil)
 	if project.AccessToken != "" {
@@ -304,18 +450,53 @@ func (s *Service) getGitLabCompareDiff(project *models.Project, from, to string)
 	}
 
 	var result struct {
-		Diffs []struct {
-			Diff    string `json:"diff"`
-			OldPath string `json:"old_path"`
-			NewPath string `json:"new_path"`
-		} `json:"diffs"`
+		Diffs []gitLabDiff `json:"diffs"`
 	}
 	if err := json.Unmarshal(body, &result); err != nil {
 		return "", fmt.Errorf("failed to parse compare response: %w", err)
 	}
 
+	return formatGitLabDiffs(result.Diffs), nil
+}
+
+// gitLabDiff is a file diff returned by the GitLab compare API
+type gitLabDiff struct {
+	Diff    string `json:"diff"`
+	OldPath string `json:"old_path"`
+	NewPath string `json:"new_path"`
+}
+
+// formatGitLabDiffs joins GitLab file diffs into a unified diff
+// getGitLabPushCommits lists the commits between before and after, oldest first
+func (s *Service) getGitLabPushCommits(ctx context.Context, project *models.Project, before, after string) ([]pushCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	var compare struct {
+		Commits []struct {
+			ID          string `json:"id"`
+			Message     string `json:"message"`
+			AuthorName  string `json:"author_name"`
+			AuthorEmail string `json:"author_email"`
+			WebURL      string `json:"web_url"`
+		} `json:"commits"`
+	}
+	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/compare?from=%s&to=%s",
+		info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), url.QueryEscape(before), url.QueryEscape(after))
+	if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &compare); err != nil {
+		return nil, err
+	}
+	commits := make([]pushCommit, 0, len(compare.Commits))
+	for _, c := range compare.Commits {
+		commits = append(commits, pushCommit{sha: c.ID, message: c.Message, url: c.WebURL, author: c.AuthorName, authorEmail: c.AuthorEmail})
+	}
+	return commits, nil
+}
+
+func formatGitLabDiffs(diffs []gitLabDiff) string {
 	var diffBuilder strings.Builder
-	for _, d := range result.Diffs {
+	for _, d := range diffs {
 		diffBuilder.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", d.OldPath, d.NewPath))
 		diffBuilder.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", d.OldPath, d.NewPath))
 		diffBuilder.WriteString(d.Diff)
@@ -323,11 +504,10 @@ func (s *Service) getGitLabCompareDiff(project *models.Project, from, to string)
 			diffBuilder.WriteString("\n")
 		}
 	}
-
-	return diffBuilder.String(), nil
+	return diffBuilder.String()
 }
 
-func (s *Service) getGitLabMRDiff(project *models.Project, mrIID int) (string, error) {
+func (s *Service) getGitLabMRDiff(ctx context.Context, project *models.Project, mrIID int) (string, error) {
 	info, err := parseRepoInfo(project.URL)
 	if err != nil {
 		return "", err
@@ -336,7 +516,7 @@ func (s *Service) getGitLabMRDiff(project *models.Project, mrIID int) (string, e
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/diffs",
 		info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), mrIID)
 
-	return s.fetchDiff(apiURL, project.AccessToken, "PRIVATE-TOKEN")
+	return s.fetchDiff(ctx, apiURL, project.AccessToken, "PRIVATE-TOKEN")
 }
 
 func (s *Service) getGitLabRequestSHA(project *models.Project, mrIID int) (string, error) {
@@ -371,9 +551,10 @@ func (s *Service) getGitLabRequestSHA(project *models.Project, mrIID int) (strin
 }
 
 func (s *Service) setGitLabCommitStatus(project *models.Project, sha string, state string, description string, gitlabProjectID int) {
+	log := logger.Module("webhook").With().Uint("project_id", project.ID).Str("commit", sha).Str("state", state).Logger()
 	info, err := parseRepoInfo(project.URL)
 	if err != nil {
-		logger.Infof("[Webhook] Failed to parse repo info for GitLab status update: %v", err)
+		log.Warn().Err(err).Msg("Failed to parse repo info for GitLab status update")
 		return
 	}
 
@@ -385,6 +566,10 @@ func (s *Service) setGitLabCommitStatus(project *models.Project, sha string, sta
 	apiURL := fmt.Sprintf("%s/api/v4/projects/%s/statuses/%s",
 		info.baseURL, projectIdentifier, sha)
 
+	// GitLab has no error state
+	if state == "error" {
+		state = "failed"
+	}
 	data := map[string]string{
 		"state":       state,
 		"context":     "codesentry/ai-review",
@@ -394,7 +579,7 @@ func (s *Service) setGitLabCommitStatus(project *models.Project, sha string, sta
 	payload, _ := json.Marshal(data)
 	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
 	if err != nil {
-		logger.Infof("[Webhook] Failed to create GitLab status request: %v", err)
+		log.Warn().Err(err).Msg("Failed to create GitLab status request")
 		return
 	}
 
@@ -405,16 +590,16 @@ func (s *Service) setGitLabCommitStatus(project *models.Project, sha string, sta
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		logger.Infof("[Webhook] Failed to send GitLab commit status: %v", err)
+		log.Warn().Err(err).Msg("Failed to send GitLab commit status")
 		return
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		logger.Infof("[Webhook] Failed to set GitLab commit status (code %d): %s", resp.StatusCode, string(body))
+		log.Warn().Int("status", resp.StatusCode).Str("body", string(body)).Msg("Failed to set GitLab commit status")
 	} else {
-		logger.Infof("[Webhook] Set GitLab commit status for %s to %s", sha[:8], state)
+		log.Info().Msg("Set GitLab commit status")
 	}
 }
 
@@ -448,7 +633,7 @@ func (s *Service) postGitLabMRComment(project *models.Project, mrIID int, commen
 		return fmt.Errorf("GitLab API returned %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	logger.Infof("[Webhook] Posted comment to GitLab MR %d", mrIID)
+	logger.Module("webhook").Info().Uint("project_id", project.ID).Int("mr", mrIID).Msg("Posted comment to GitLab MR")
 	return nil
 }
 
@@ -482,6 +667,6 @@ func (s *Service) postGitLabCommitComment(project *models.Project, commitSHA str
 		return fmt.Errorf("GitLab API returned %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	logger.Infof("[Webhook] Posted comment to GitLab commit %s", commitSHA[:8])
+	logger.Module("webhook").Info().Uint("project_id", project.ID).Str("commit", commitSHA).Msg("Posted comment to GitLab commit")
 	return nil
 }
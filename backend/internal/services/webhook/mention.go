@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// commentEventsEnabled reports whether reviews can be requested in merge request comments
+func commentEventsEnabled(project *models.Project) bool {
+	return strings.Contains(project.ReviewEvents, "comment")
+}
+
+// mentionCommand is a review requested by mentioning the bot in a comment, such as
+// "@codesentry review src/payment"
+type mentionCommand struct {
+	Paths []string // Paths the review is scoped to, empty = the whole diff
+}
+
+// parseMentionCommand finds a "@<bot> review [paths...]" command in a comment. The first
+// line with the command counts; the words after "review" on that line are paths.
+func parseMentionCommand(note, botName string) (*mentionCommand, bool) {
+	mention := "@" + strings.TrimPrefix(botName, "@")
+	for _, line := range strings.Split(note, "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i+1 < len(fields); i++ {
+			if !strings.EqualFold(strings.TrimRight(fields[i], ",:"), mention) || !strings.EqualFold(fields[i+1], "review") {
+				continue
+			}
+			cmd := &mentionCommand{}
+			for _, p := range fields[i+2:] {
+				p = strings.Trim(p, "`'\",;")
+				if p != "" {
+					cmd.Paths = append(cmd.Paths, p)
+				}
+			}
+			return cmd, true
+		}
+	}
+	return nil, false
+}
+
+// gitLabDeveloperAccess is the GitLab access level of the Developer role
+const gitLabDeveloperAccess = 30
+
+// gitLabMergeRequest is the part of a GitLab merge request a requested review needs
+type gitLabMergeRequest struct {
+	SHA    string `json:"sha"`
+	Author struct {
+		Username  string `json:"username"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"author"`
+}
+
+func gitLabProjectAPI(project *models.Project) (string, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/api/v4/projects/%s", info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F")), nil
+}
+
+// canRequestGitLabReview reports whether a GitLab user has at least Developer access to the
+// project, including access inherited from groups
+func (s *Service) canRequestGitLabReview(ctx context.Context, project *models.Project, userID int) (bool, error) {
+	apiBase, err := gitLabProjectAPI(project)
+	if err != nil {
+		return false, err
+	}
+	var member struct {
+		AccessLevel int `json:"access_level"`
+	}
+	err = s.getPlatformJSON(ctx, fmt.Sprintf("%s/members/all/%d", apiBase, userID), "PRIVATE-TOKEN", project.AccessToken, &member)
+	var statusErr *platformStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.AccessLevel >= gitLabDeveloperAccess, nil
+}
+
+// processGitLabNote reviews a merge request on demand when a comment mentions the bot. Only
+// project developers may request reviews; the review is of the merge request's author.
+func (s *Service) processGitLabNote(ctx context.Context, project *models.Project, event *GitLabNoteEvent) (err error) {
+	if event.ObjectAttributes.NoteableType != "MergeRequest" || event.MergeRequest == nil {
+		return nil
+	}
+	botName := s.configService.GetMentionConfig().BotName
+	if strings.EqualFold(event.User.Username, botName) {
+		return nil
+	}
+	cmd, ok := parseMentionCommand(event.ObjectAttributes.Note, botName)
+	if !ok {
+		return nil
+	}
+	noteKey := fmt.Sprintf("%d:note:%d", project.ID, event.ObjectAttributes.ID)
+	if !reviewEventDeduper.claim(noteKey, time.Now()) {
+		dedupedEvents.Add(1)
+		return nil
+	}
+	// A failed request is released, so redelivering the note requests the review again
+	defer func() {
+		if err != nil {
+			reviewEventDeduper.release(noteKey)
+		}
+	}()
+
+	mr := event.MergeRequest
+	mrIID := mr.IID
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Int("mr", mrIID).Str("requested_by", event.User.Username).Logger()
+	if s.isMergeRequestIgnored(mr.SourceBranch, mr.TargetBranch, project) {
+		log.Info().Msg("Branch is in ignore list, skipping requested review")
+		return nil
+	}
+	allowed, err := s.canRequestGitLabReview(ctx, project, event.User.ID)
+	if err != nil {
+		return fmt.Errorf("failed to check the access of %s: %w", event.User.Username, err)
+	}
+	if !allowed {
+		log.Info().Msg("Review requested by a user without Developer access, ignoring")
+		return nil
+	}
+
+	apiBase, err := gitLabProjectAPI(project)
+	if err != nil {
+		return err
+	}
+	var details gitLabMergeRequest
+	if err := s.getPlatformJSON(ctx, fmt.Sprintf("%s/merge_requests/%d", apiBase, mrIID), "PRIVATE-TOKEN", project.AccessToken, &details); err != nil {
+		log.Warn().Err(err).Msg("Failed to get MR")
+		return err
+	}
+	commitSHA := details.SHA
+
+	fetchStart := time.Now()
+	diff, err := s.getGitLabMRDiff(ctx, project, mrIID)
+	if err != nil {
+		return fmt.Errorf("failed to get diff of MR !%d: %w", mrIID, err)
+	}
+	commitMessage := mr.Title + "\n" + mr.Description
+	if len(cmd.Paths) > 0 {
+		diff = services.ScopeDiffToPaths(diff, cmd.Paths)
+		if IsEmptyDiff(diff) {
+			log.Info().Strs("paths", cmd.Paths).Msg("No changes under the requested paths, skipping requested review")
+			return nil
+		}
+		commitMessage += "\n\nReview requested for: " + strings.Join(cmd.Paths, ", ")
+	}
+
+	log.Info().Str("commit", commitSHA).Strs("paths", cmd.Paths).Msg("Review requested in a comment")
+	s.setGitLabCommitStatus(project, commitSHA, "pending", "AI Review in progress...", event.Project.ID)
+
+	additions, deletions, filesChanged := ParseDiffStats(diff)
+	reviewLog := &models.ReviewLog{
+		ProjectID:     project.ID,
+		EventType:     "merge_request",
+		CommitHash:    commitSHA,
+		Branch:        mr.SourceBranch,
+		TargetBranch:  mr.TargetBranch,
+		Author:        details.Author.Username,
+		AuthorAvatar:  details.Author.AvatarURL,
+		CommitMessage: mr.Title,
+		FilesChanged:  filesChanged,
+		Additions:     additions,
+		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
+		MRNumber:      &mrIID,
+		MRURL:         mr.URL,
+		ReviewStatus:  "pending",
+	}
+	s.createReviewLog(ctx, reviewLog)
+
+	task := &services.ReviewTask{
+		ReviewLogID:     reviewLog.ID,
+		RequestID:       logger.RequestID(ctx),
+		ProjectID:       project.ID,
+		CommitSHA:       commitSHA,
+		EventType:       "merge_request",
+		Branch:          mr.SourceBranch,
+		TargetBranch:    mr.TargetBranch,
+		Author:          details.Author.Username,
+		AuthorAvatar:    details.Author.AvatarURL,
+		CommitMessage:   commitMessage,
+		Diff:            diff,
+		MRNumber:        &mrIID,
+		MRURL:           mr.URL,
+		Requested:       true,
+		GitLabProjectID: event.Project.ID,
+	}
+	if err := services.GetTaskQueue().Enqueue(task); err != nil {
+		log.Error().Err(err).Uint("review_id", reviewLog.ID).Msg("Failed to enqueue requested MR review task")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
+		s.reviewService.Update(reviewLog)
+		return err
+	}
+	return nil
+}
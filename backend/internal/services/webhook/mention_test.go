@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestParseMentionCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		note   string
+		bot    string
+		wantOK bool
+		paths  []string
+	}{
+		{"whole diff", "@codesentry review", "codesentry", true, nil},
+		{"scoped", "@codesentry review src/payment", "codesentry", true, []string{"src/payment"}},
+		{"several paths", "@codesentry review `src/payment`, \"lib/*.go\"", "codesentry", true, []string{"src/payment", "lib/*.go"}},
+		{"case insensitive", "@CodeSentry Review docs", "codesentry", true, []string{"docs"}},
+		{"inside text", "LGTM\nplease @codesentry: review api/", "codesentry", true, []string{"api/"}},
+		{"bot name with at", "@bot review", "@bot", true, nil},
+		{"other bot", "@someone review src", "codesentry", false, nil},
+		{"mention only", "thanks @codesentry", "codesentry", false, nil},
+		{"other command", "@codesentry explain", "codesentry", false, nil},
+		{"mention as prefix", "@codesentry2 review", "codesentry", false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, ok := parseMentionCommand(tt.note, tt.bot)
+			if ok != tt.wantOK {
+				t.Fatalf("parseMentionCommand() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && !reflect.DeepEqual(cmd.Paths, tt.paths) {
+				t.Errorf("parseMentionCommand() paths = %q, want %q", cmd.Paths, tt.paths)
+			}
+		})
+	}
+}
+
+func TestCanRequestGitLabReview(t *testing.T) {
+	members := map[string]struct {
+		status int
+		body   string
+	}{
+		"/api/v4/projects/group%2Frepo/members/all/1": {http.StatusOK, `{"access_level":30}`},
+		"/api/v4/projects/group%2Frepo/members/all/2": {http.StatusOK, `{"access_level":20}`},
+		"/api/v4/projects/group%2Frepo/members/all/4": {http.StatusInternalServerError, "error"},
+	}
+	s := &Service{httpClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		m, ok := members[req.URL.EscapedPath()]
+		if !ok {
+			m.status, m.body = http.StatusNotFound, `{"message":"404 Not found"}`
+		}
+		return &http.Response{StatusCode: m.status, Body: io.NopCloser(strings.NewReader(m.body)), Request: req}, nil
+	})}}
+	project := &models.Project{Platform: "gitlab", URL: "https://gitlab.example.com/group/repo"}
+
+	tests := []struct {
+		name    string
+		userID  int
+		want    bool
+		wantErr bool
+	}{
+		{"developer", 1, true, false},
+		{"reporter", 2, false, false},
+		{"not a member", 3, false, false},
+		{"API error", 4, false, true},
+	}
+	for _, tt := range tests {
+		got, err := s.canRequestGitLabReview(context.Background(), project, tt.userID)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("%s: canRequestGitLabReview() = %v, %v; want %v, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// ErrMigrationAckNotPending is returned when a review has no destructive migration awaiting acknowledgment
+var ErrMigrationAckNotPending = errors.New("review has no destructive migration awaiting acknowledgment")
+
+// gateMigrations holds a passing commit status while the destructive migrations of a
+// review await acknowledgment, and marks the review as awaiting it
+func (s *Service) gateMigrations(reviewLog *models.ReviewLog, check *services.MigrationCheck, state, description string) (string, string) {
+	gated, gatedDesc := check.Gate(state, description)
+	if gated != state {
+		reviewLog.MigrationAck = services.MigrationAckPending
+		s.reviewService.Update(reviewLog)
+	}
+	return gated, gatedDesc
+}
+
+// AcknowledgeMigration records that a maintainer checked the destructive migrations of a
+// review and releases the commit status they held
+func (s *Service) AcknowledgeMigration(reviewLog *models.ReviewLog, username string) (*models.ReviewLog, error) {
+	res := s.db.Model(&models.ReviewLog{}).
+		Where("id = ? AND migration_ack = ?", reviewLog.ID, services.MigrationAckPending).
+		Updates(map[string]interface{}{
+			"migration_ack":    services.MigrationAckAcknowledged,
+			"migration_ack_by": username,
+			"migration_ack_at": time.Now(),
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, ErrMigrationAckNotPending
+	}
+
+	var project models.Project
+	if err := s.db.First(&project, reviewLog.ProjectID).Error; err != nil {
+		return nil, err
+	}
+	if reviewLog.CommitHash != "" && reviewLog.Score != nil {
+		description := fmt.Sprintf("AI Review Passed: %.0f/%.0f; destructive migration acknowledged by %s",
+			*reviewLog.Score, s.getEffectiveMinScore(&project), username)
+		s.setCommitStatus(&project, reviewLog.CommitHash, "success", description, 0)
+	}
+
+	logger.Module("webhook").Info().Uint("project_id", reviewLog.ProjectID).Uint("review_id", reviewLog.ID).Str("acknowledged_by", username).Msg("Destructive migration acknowledged")
+	return s.reviewService.GetByID(reviewLog.ID)
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// pushCommit is one commit of a push reviewed on its own. The author is the commit's
+// author, who may not be the one pushing it; empty falls back to the pusher.
+type pushCommit struct {
+	sha          string
+	message      string
+	url          string
+	author       string
+	authorEmail  string
+	authorAvatar string
+	authorURL    string
+}
+
+// commitPush is a push whose commits are reviewed separately, see Project.PerCommitReview
+type commitPush struct {
+	head            string // Head commit, groups the reviews of the push
+	branch          string
+	author          string
+	authorEmail     string
+	authorAvatar    string
+	authorURL       string
+	gitlabProjectID int
+	commits         []pushCommit // Oldest first
+}
+
+// reviewCommitsSeparately creates a review per commit of a push, each with the diff of its
+// commit only. Commits already reviewed in the project, e.g. pushed to another branch
+// before, are left out. The review notification is sent once for the whole push by
+// notifyPushGroup.
+func (s *Service) reviewCommitsSeparately(ctx context.Context, project *models.Project, push *commitPush, fetch func(ctx context.Context, sha string) (string, error)) error {
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Str("branch", push.branch).Str("push_head", push.head).Logger()
+
+	commits := make([]pushCommit, 0, len(push.commits))
+	shas := make([]string, 0, len(push.commits))
+	for _, c := range push.commits {
+		if c.sha != push.head && s.isCommitAlreadyReviewed(project.ID, c.sha) {
+			continue
+		}
+		commits = append(commits, c)
+		shas = append(shas, c.sha)
+	}
+	log.Info().Int("commits", len(commits)).Msg("Reviewing push commits separately")
+
+	var enqueueErr error
+	for i, r := range fetchCommitDiffs(ctx, shas, fetch) {
+		c := commits[i]
+		message := fmt.Sprintf("%s: %s", shortSHA(c.sha), c.message)
+		author := commitAuthor(push, c)
+		s.setCommitStatus(project, c.sha, "pending", "AI Review in progress...", push.gitlabProjectID)
+
+		additions, deletions, filesChanged := ParseDiffStats(r.diff)
+		reviewLog := &models.ReviewLog{
+			ProjectID:     project.ID,
+			EventType:     "push",
+			CommitHash:    c.sha,
+			CommitURL:     c.url,
+			Branch:        push.branch,
+			Author:        author.author,
+			AuthorEmail:   author.authorEmail,
+			AuthorAvatar:  author.authorAvatar,
+			AuthorURL:     author.authorURL,
+			CommitMessage: message,
+			FilesChanged:  filesChanged,
+			Additions:     additions,
+			Deletions:     deletions,
+			DiffFetchMs:   services.ObserveReviewStage(services.ReviewStageDiffFetch, r.took),
+			ReviewStatus:  "pending",
+			PushHead:      push.head,
+		}
+		s.createReviewLog(ctx, reviewLog)
+		if r.err != nil {
+			s.failDiffFetch(ctx, project, reviewLog, push.gitlabProjectID, r.err)
+			continue
+		}
+
+		task := &services.ReviewTask{
+			ReviewLogID:     reviewLog.ID,
+			RequestID:       logger.RequestID(ctx),
+			ProjectID:       project.ID,
+			CommitSHA:       c.sha,
+			EventType:       "push",
+			Branch:          push.branch,
+			Author:          author.author,
+			AuthorEmail:     author.authorEmail,
+			AuthorAvatar:    author.authorAvatar,
+			CommitMessage:   message,
+			Diff:            r.diff,
+			CommitURL:       c.url,
+			CommitSHAs:      []string{c.sha},
+			PushHead:        push.head,
+			GitLabProjectID: push.gitlabProjectID,
+		}
+		if err := services.GetTaskQueue().Enqueue(task); err != nil {
+			log.Error().Err(err).Uint("review_id", reviewLog.ID).Str("commit", c.sha).Msg("Failed to enqueue commit review task")
+			reviewLog.ReviewStatus = "failed"
+			reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
+			s.reviewService.Update(reviewLog)
+			s.releaseEvent(reviewLog)
+			enqueueErr = err
+		}
+	}
+
+	// Every commit may have failed before reaching the queue
+	s.notifyPushGroup(ctx, project, push.head)
+	return enqueueErr
+}
+
+// commitAuthor returns the commit with its author filled in from the push when the
+// platform didn't tell who wrote it
+func commitAuthor(push *commitPush, c pushCommit) pushCommit {
+	if c.author == "" && c.authorEmail == "" {
+		c.author, c.authorEmail, c.authorAvatar, c.authorURL = push.author, push.authorEmail, push.authorAvatar, push.authorURL
+	}
+	return c
+}
+
+// reviewInProgress reports whether a review has not reached a final status yet
+func reviewInProgress(status string) bool {
+	return status == "pending" || status == "processing" || status == "analyzing"
+}
+
+// notifyPushGroup sends the review notification of a push reviewed per commit once none of
+// its commit reviews is in progress. The push_notified flag makes sure only one worker sends it.
+func (s *Service) notifyPushGroup(ctx context.Context, project *models.Project, head string) {
+	var reviews []models.ReviewLog
+	if err := s.db.Select("id, commit_hash, commit_message, branch, author, review_status, score").
+		Where("project_id = ? AND push_head = ?", project.ID, head).Order("id").Find(&reviews).Error; err != nil || len(reviews) == 0 {
+		return
+	}
+	for _, r := range reviews {
+		if reviewInProgress(r.ReviewStatus) {
+			return
+		}
+	}
+	claimed := s.db.Model(&models.ReviewLog{}).
+		Where("project_id = ? AND push_head = ? AND push_notified = ?", project.ID, head, false).
+		Update("push_notified", true)
+	if claimed.Error != nil || claimed.RowsAffected == 0 {
+		return
+	}
+
+	score, scored, summary := summarizePushReviews(reviews, s.getEffectiveMinScore(project))
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Str("push_head", head).Logger()
+	if scored == 0 {
+		message := fmt.Sprintf("[CodeSentry] AI review of %s (%s) by %s: no commit was scored\n%s", project.Name, reviews[0].Branch, reviews[0].Author, summary)
+		if err := s.notificationService.SendTextNotification(project, message); err != nil {
+			log.Warn().Err(err).Msg("Failed to send push notification")
+		}
+		return
+	}
+	if err := s.notificationService.SendReviewNotification(ctx, project, &services.ReviewNotification{
+		ProjectName:   project.Name,
+		Branch:        reviews[0].Branch,
+		Author:        reviews[0].Author,
+		CommitMessage: fmt.Sprintf("%d commits reviewed separately, up to %s", len(reviews), shortSHA(head)),
+		Score:         score,
+		ReviewResult:  summary,
+		EventType:     "push",
+	}); err != nil {
+		log.Warn().Err(err).Msg("Failed to send push notification")
+	}
+}
+
+// summarizePushReviews lists the result of each commit review of a push. The push score is
+// the lowest commit score, so the push only passes when every commit does; scored is the
+// number of commits with a score.
+func summarizePushReviews(reviews []models.ReviewLog, minScore float64) (score float64, scored int, summary string) {
+	var b strings.Builder
+	var total float64
+	failed := 0
+	for _, r := range reviews {
+		subject := r.CommitMessage
+		if i := strings.Index(subject, ": "); i >= 0 {
+			subject = subject[i+2:]
+		}
+		if i := strings.IndexByte(subject, '\n'); i >= 0 {
+			subject = subject[:i]
+		}
+		result := r.ReviewStatus
+		if r.Score != nil {
+			result = fmt.Sprintf("%.0f", *r.Score)
+			if *r.Score < minScore {
+				result += " (failed)"
+				failed++
+			}
+			if scored == 0 || *r.Score < score {
+				score = *r.Score
+			}
+			total += *r.Score
+			scored++
+		}
+		fmt.Fprintf(&b, "- `%s` %s: %s\n", shortSHA(r.CommitHash), subject, result)
+	}
+	if scored > 0 {
+		fmt.Fprintf(&b, "\nLowest score %.0f, average %.0f, %d of %d commits below the minimum of %.0f", score, total/float64(scored), failed, len(reviews), minScore)
+	}
+	return score, scored, b.String()
+}
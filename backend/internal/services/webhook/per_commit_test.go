@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestSummarizePushReviews(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name       string
+		reviews    []models.ReviewLog
+		wantScore  float64
+		wantScored int
+		wantLines  []string
+	}{
+		{
+			name: "lowest score wins",
+			reviews: []models.ReviewLog{
+				{CommitHash: "aaaaaaaa11", CommitMessage: "aaaaaaaa: Add parser\n\nDetails", ReviewStatus: "completed", Score: score(90)},
+				{CommitHash: "bbbbbbbb22", CommitMessage: "bbbbbbbb: Fix lexer", ReviewStatus: "completed", Score: score(50)},
+			},
+			wantScore:  50,
+			wantScored: 2,
+			wantLines:  []string{"- `aaaaaaaa` Add parser: 90", "- `bbbbbbbb` Fix lexer: 50 (failed)", "Lowest score 50, average 70, 1 of 2 commits below the minimum of 60"},
+		},
+		{
+			name: "unscored commits are listed with their status",
+			reviews: []models.ReviewLog{
+				{CommitHash: "aaaaaaaa11", CommitMessage: "aaaaaaaa: Bump deps", ReviewStatus: "skipped"},
+				{CommitHash: "bbbbbbbb22", CommitMessage: "bbbbbbbb: Fix lexer", ReviewStatus: "completed", Score: score(80)},
+			},
+			wantScore:  80,
+			wantScored: 1,
+			wantLines:  []string{"- `aaaaaaaa` Bump deps: skipped", "0 of 2 commits below"},
+		},
+		{
+			name: "nothing scored",
+			reviews: []models.ReviewLog{
+				{CommitHash: "aaaaaaaa11", CommitMessage: "aaaaaaaa: Bump deps", ReviewStatus: "diff_fetch_failed"},
+			},
+			wantScored: 0,
+			wantLines:  []string{"- `aaaaaaaa` Bump deps: diff_fetch_failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, scored, summary := summarizePushReviews(tt.reviews, 60)
+			if got != tt.wantScore || scored != tt.wantScored {
+				t.Errorf("score, scored = %v, %d, want %v, %d", got, scored, tt.wantScore, tt.wantScored)
+			}
+			for _, line := range tt.wantLines {
+				if !strings.Contains(summary, line) {
+					t.Errorf("summary %q doesn't contain %q", summary, line)
+				}
+			}
+		})
+	}
+}
+
+func TestCommitAuthor(t *testing.T) {
+	push := &commitPush{author: "pusher", authorEmail: "pusher@example.com", authorAvatar: "avatar"}
+
+	got := commitAuthor(push, pushCommit{sha: "a", author: "Alice", authorEmail: "alice@example.com"})
+	if got.author != "Alice" || got.authorEmail != "alice@example.com" || got.authorAvatar != "" {
+		t.Errorf("commit author = %+v, want Alice without the pusher's avatar", got)
+	}
+	got = commitAuthor(push, pushCommit{sha: "b"})
+	if got.author != "pusher" || got.authorEmail != "pusher@example.com" || got.authorAvatar != "avatar" {
+		t.Errorf("commit author = %+v, want the pusher", got)
+	}
+}
+
+func TestGetGitLabPushCommits(t *testing.T) {
+	var query string
+	s := &Service{httpClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query = req.URL.RawQuery
+		body := `{"commits":[
+			{"id":"aaa","message":"First","author_name":"Alice","author_email":"alice@example.com","web_url":"https://gitlab.example.com/c/aaa"},
+			{"id":"bbb","message":"Second","author_name":"Bob","author_email":"bob@example.com","web_url":"https://gitlab.example.com/c/bbb"}
+		]}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}}
+	project := &models.Project{Platform: "gitlab", URL: "https://gitlab.example.com/group/repo"}
+
+	commits, err := s.getGitLabPushCommits(context.Background(), project, "before", "after")
+	if err != nil {
+		t.Fatalf("getGitLabPushCommits() error = %v", err)
+	}
+	if query != "from=before&to=after" {
+		t.Errorf("query = %q", query)
+	}
+	if len(commits) != 2 || commits[0].sha != "aaa" || commits[1].author != "Bob" || commits[1].authorEmail != "bob@example.com" || commits[1].url == "" {
+		t.Errorf("commits = %+v", commits)
+	}
+}
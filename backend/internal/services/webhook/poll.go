@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// pollTTL is how long a polling token stays valid
+const pollTTL = 24 * time.Hour
+
+type pollTokenKey struct{}
+
+// WithPollToken returns a context that binds the review created while handling
+// a webhook to the given polling token
+func WithPollToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, pollTokenKey{}, token)
+}
+
+func pollTokenFrom(ctx context.Context) string {
+	token, _ := ctx.Value(pollTokenKey{}).(string)
+	return token
+}
+
+// CreatePoll registers a webhook received in wait mode and returns its polling token
+func (s *Service) CreatePoll(projectID uint) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	poll := &models.ReviewPoll{
+		Token:     hex.EncodeToString(buf),
+		ProjectID: projectID,
+		Status:    "pending",
+		ExpiresAt: time.Now().Add(pollTTL),
+	}
+	if err := s.db.Create(poll).Error; err != nil {
+		return "", err
+	}
+	return poll.Token, nil
+}
+
+// FinishPoll marks the webhook handling of a poll as done. Reviews run
+// asynchronously afterwards; their progress is read from the review log.
+func (s *Service) FinishPoll(token string, handleErr error) {
+	updates := map[string]interface{}{"status": "done"}
+	if handleErr != nil {
+		updates["status"] = "failed"
+		updates["error"] = handleErr.Error()
+	}
+	if err := s.db.Model(&models.ReviewPoll{}).Where("token = ?", token).Updates(updates).Error; err != nil {
+		logger.Module("webhook").Warn().Err(err).Msg("Failed to finish review poll")
+	}
+}
+
+// createReviewLog creates a review log and binds it to the polling token of ctx, if any
+func (s *Service) createReviewLog(ctx context.Context, reviewLog *models.ReviewLog) error {
+	if err := s.reviewService.Create(reviewLog); err != nil {
+		return err
+	}
+	s.bindPoll(ctx, reviewLog.ID)
+	return nil
+}
+
+// bindPollToCommit binds the polling token of ctx to an existing review of the commit
+func (s *Service) bindPollToCommit(ctx context.Context, projectID uint, commitSHA string) {
+	if pollTokenFrom(ctx) == "" {
+		return
+	}
+	var reviewLog models.ReviewLog
+	if err := s.db.Select("id").Where("project_id = ? AND commit_hash = ?", projectID, commitSHA).
+		Order("created_at DESC").First(&reviewLog).Error; err == nil {
+		s.bindPoll(ctx, reviewLog.ID)
+	}
+}
+
+func (s *Service) bindPoll(ctx context.Context, reviewLogID uint) {
+	token := pollTokenFrom(ctx)
+	if token == "" || reviewLogID == 0 {
+		return
+	}
+	if err := s.db.Model(&models.ReviewPoll{}).Where("token = ?", token).Update("review_log_id", reviewLogID).Error; err != nil {
+		logger.For(ctx, "webhook").Warn().Err(err).Uint("review_id", reviewLogID).Msg("Failed to bind review to poll")
+	}
+}
+
+// GetPollStatus returns the outcome of a webhook received in wait mode.
+// Done is set once no further change is expected; Passed is then set unless the review failed.
+func (s *Service) GetPollStatus(token string) (*PollStatusResponse, error) {
+	var poll models.ReviewPoll
+	if err := s.db.Where("token = ? AND expires_at > ?", token, time.Now()).First(&poll).Error; err != nil {
+		return nil, fmt.Errorf("poll token not found or expired")
+	}
+
+	resp := &PollStatusResponse{Token: poll.Token, ProjectID: poll.ProjectID}
+
+	if poll.ReviewLogID == nil {
+		switch poll.Status {
+		case "pending":
+			resp.Status = "pending"
+			resp.Message = "Webhook is being processed"
+		case "failed":
+			resp.Status = "failed"
+			resp.Done = true
+			resp.Message = "Webhook processing failed: " + poll.Error
+		default:
+			// The event did not need a review (disabled event type, ignored branch, ...)
+			passed := true
+			resp.Status = "skipped"
+			resp.Done = true
+			resp.Passed = &passed
+			resp.Message = "No review required for this event"
+		}
+		return resp, nil
+	}
+
+	var reviewLog models.ReviewLog
+	if err := s.db.First(&reviewLog, *poll.ReviewLogID).Error; err != nil {
+		return nil, fmt.Errorf("review not found: %d", *poll.ReviewLogID)
+	}
+	resp.ReviewScoreResponse = *s.reviewScore(&reviewLog)
+	switch reviewLog.ReviewStatus {
+	case "completed", "skipped", services.ReviewStatusSkippedTooLarge, services.ReviewStatusSkippedByDirective, "failed", services.ReviewStatusNeedsAttention, services.ReviewStatusDiffFetchFailed:
+		resp.Done = true
+	}
+	return resp, nil
+}
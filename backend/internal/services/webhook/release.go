@@ -0,0 +1,388 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// releaseCommitLimit bounds the commits listed for a first release without a previous tag
+const releaseCommitLimit = 50
+
+// releaseChanges are the commits and diff shipped by a tag
+type releaseChanges struct {
+	Tag          string
+	PreviousTag  string
+	CommitSHA    string
+	URL          string
+	Author       string
+	AuthorEmail  string
+	AuthorAvatar string
+	Commits      []string // "<short sha>: <title>"
+	Diff         string
+	// GitLab specific
+	GitLabProjectID int
+}
+
+// releaseEventsEnabled reports whether tag and release reviews are enabled for a project
+func releaseEventsEnabled(project *models.Project) bool {
+	return strings.Contains(project.ReviewEvents, "tag")
+}
+
+// previousTag returns the first tag of tags, newest first, that is not current
+func previousTag(tags []string, current string) string {
+	for _, tag := range tags {
+		if tag != "" && tag != current {
+			return tag
+		}
+	}
+	return ""
+}
+
+// formatReleaseCommit formats a commit as a changelog line
+func formatReleaseCommit(sha, message string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return fmt.Sprintf("%s: %s", shortSHA(sha), title)
+}
+
+func (s *Service) processGitLabTagPush(ctx context.Context, project *models.Project, event *GitLabPushEvent) error {
+	if isNullSHA(event.After) {
+		// Tag deleted
+		return nil
+	}
+
+	tag := strings.TrimPrefix(event.Ref, "refs/tags/")
+	commitSHA := event.CheckoutSHA
+	if commitSHA == "" {
+		commitSHA = event.After
+	}
+	if s.isDuplicateEvent(ctx, project.ID, commitSHA, services.EventTypeRelease) {
+		return nil
+	}
+
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("tag", tag).Str("commit", commitSHA).Msg("Processing GitLab tag push")
+
+	changes, err := s.getGitLabTagChanges(ctx, project, tag)
+	if err != nil {
+		return fmt.Errorf("failed to get changes of tag %s: %w", tag, err)
+	}
+	changes.CommitSHA = commitSHA
+	changes.Author = event.UserName
+	changes.AuthorEmail = event.UserEmail
+	changes.AuthorAvatar = event.UserAvatar
+	changes.GitLabProjectID = event.ProjectID
+	if event.Project.WebURL != "" {
+		changes.URL = event.Project.WebURL + "/-/tags/" + url.PathEscape(tag)
+	}
+	return s.enqueueReleaseReview(ctx, project, changes)
+}
+
+func (s *Service) getGitLabTagChanges(ctx context.Context, project *models.Project, tag string) (*releaseChanges, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	apiBase := fmt.Sprintf("%s/api/v4/projects/%s/repository", info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"))
+
+	var tags []struct {
+		Name string `json:"name"`
+	}
+	if err := s.getPlatformJSON(ctx, apiBase+"/tags?order_by=updated&sort=desc&per_page=10", "PRIVATE-TOKEN", project.AccessToken, &tags); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(tags))
+	for _, t := range tags {
+		names = append(names, t.Name)
+	}
+	changes := &releaseChanges{Tag: tag, PreviousTag: previousTag(names, tag)}
+
+	type gitLabCommit struct {
+		ID      string `json:"id"`
+		Title   string `json:"title"`
+		Message string `json:"message"`
+	}
+
+	if changes.PreviousTag == "" {
+		var commits []gitLabCommit
+		apiURL := fmt.Sprintf("%s/commits?ref_name=%s&per_page=%d", apiBase, url.QueryEscape(tag), releaseCommitLimit)
+		if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &commits); err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			changes.Commits = append(changes.Commits, formatReleaseCommit(c.ID, c.Title))
+		}
+		return changes, nil
+	}
+
+	var compare struct {
+		Commits []gitLabCommit `json:"commits"`
+		Diffs   []gitLabDiff   `json:"diffs"`
+	}
+	apiURL := fmt.Sprintf("%s/compare?from=%s&to=%s", apiBase, url.QueryEscape(changes.PreviousTag), url.QueryEscape(tag))
+	if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &compare); err != nil {
+		return nil, err
+	}
+	for _, c := range compare.Commits {
+		changes.Commits = append(changes.Commits, formatReleaseCommit(c.ID, c.Title))
+	}
+	changes.Diff = formatGitLabDiffs(compare.Diffs)
+	return changes, nil
+}
+
+func (s *Service) processGitHubRelease(ctx context.Context, project *models.Project, event *GitHubReleaseEvent) error {
+	if event.Action != "published" || event.Release.Draft {
+		return nil
+	}
+
+	tag := event.Release.TagName
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("tag", tag).Msg("Processing GitHub release")
+
+	changes, err := s.getGitHubReleaseChanges(ctx, project, tag)
+	if err != nil {
+		return fmt.Errorf("failed to get changes of release %s: %w", tag, err)
+	}
+	if changes.CommitSHA == "" {
+		logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("tag", tag).Msg("No commits found for release, skipping")
+		return nil
+	}
+	if s.isDuplicateEvent(ctx, project.ID, changes.CommitSHA, services.EventTypeRelease) {
+		return nil
+	}
+	changes.URL = event.Release.HTMLURL
+	changes.Author = event.Release.Author.Login
+	changes.AuthorAvatar = event.Release.Author.AvatarURL
+	return s.enqueueReleaseReview(ctx, project, changes)
+}
+
+func (s *Service) getGitHubReleaseChanges(ctx context.Context, project *models.Project, tag string) (*releaseChanges, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := "https://api.github.com"
+	if info.baseURL != "https://github.com" {
+		baseURL = info.baseURL + "/api/v3"
+	}
+	apiBase := fmt.Sprintf("%s/repos/%s/%s", baseURL, info.owner, info.repo)
+	token := ""
+	if project.AccessToken != "" {
+		token = "token " + project.AccessToken
+	}
+
+	// Releases are listed newest first; drafts have no tag yet
+	var releases []struct {
+		TagName string `json:"tag_name"`
+		Draft   bool   `json:"draft"`
+	}
+	if err := s.getPlatformJSON(ctx, apiBase+"/releases?per_page=10", "Authorization", token, &releases); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(releases))
+	for _, r := range releases {
+		if !r.Draft {
+			names = append(names, r.TagName)
+		}
+	}
+	changes := &releaseChanges{Tag: tag, PreviousTag: previousTag(names, tag)}
+
+	type gitHubCommit struct {
+		SHA    string `json:"sha"`
+		Commit struct {
+			Message string `json:"message"`
+		} `json:"commit"`
+	}
+
+	if changes.PreviousTag == "" {
+		var commits []gitHubCommit
+		apiURL := fmt.Sprintf("%s/commits?sha=%s&per_page=%d", apiBase, url.QueryEscape(tag), releaseCommitLimit)
+		if err := s.getPlatformJSON(ctx, apiURL, "Authorization", token, &commits); err != nil {
+			return nil, err
+		}
+		for _, c := range commits {
+			changes.Commits = append(changes.Commits, formatReleaseCommit(c.SHA, c.Commit.Message))
+		}
+		if len(commits) > 0 {
+			changes.CommitSHA = commits[0].SHA
+		}
+		return changes, nil
+	}
+
+	var compare struct {
+		Commits []gitHubCommit `json:"commits"`
+		Files   []struct {
+			Filename string `json:"filename"`
+			Patch    string `json:"patch"`
+		} `json:"files"`
+	}
+	apiURL := fmt.Sprintf("%s/compare/%s...%s", apiBase, url.PathEscape(changes.PreviousTag), url.PathEscape(tag))
+	if err := s.getPlatformJSON(ctx, apiURL, "Authorization", token, &compare); err != nil {
+		return nil, err
+	}
+	// Compare lists commits oldest first
+	for _, c := range compare.Commits {
+		changes.Commits = append(changes.Commits, formatReleaseCommit(c.SHA, c.Commit.Message))
+	}
+	if n := len(compare.Commits); n > 0 {
+		changes.CommitSHA = compare.Commits[n-1].SHA
+	}
+	var diff strings.Builder
+	for _, f := range compare.Files {
+		if f.Patch == "" {
+			continue
+		}
+		diff.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", f.Filename, f.Filename, f.Filename, f.Filename))
+		diff.WriteString(f.Patch)
+		if !strings.HasSuffix(f.Patch, "\n") {
+			diff.WriteString("\n")
+		}
+	}
+	changes.Diff = diff.String()
+	return changes, nil
+}
+
+// getPlatformJSON fetches a Git platform API URL and decodes the JSON response into out
+func (s *Service) getPlatformJSON(ctx context.Context, apiURL, tokenHeader, token string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set(tokenHeader, token)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &platformStatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return json.Unmarshal(body, out)
+}
+
+// platformStatusError is returned by getPlatformJSON for responses other than 200 OK
+type platformStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *platformStatusError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// enqueueReleaseReview records a release review and queues it for processing
+func (s *Service) enqueueReleaseReview(ctx context.Context, project *models.Project, changes *releaseChanges) error {
+	header := fmt.Sprintf("Release %s", changes.Tag)
+	if changes.PreviousTag != "" {
+		header += fmt.Sprintf(" (since %s)", changes.PreviousTag)
+	}
+	commitMessage := header + "\n" + strings.Join(changes.Commits, "\n")
+
+	additions, deletions, filesChanged := ParseDiffStats(changes.Diff)
+	reviewLog := &models.ReviewLog{
+		ProjectID:     project.ID,
+		EventType:     services.EventTypeRelease,
+		CommitHash:    changes.CommitSHA,
+		CommitURL:     changes.URL,
+		Branch:        changes.Tag,
+		Author:        changes.Author,
+		AuthorEmail:   changes.AuthorEmail,
+		AuthorAvatar:  changes.AuthorAvatar,
+		CommitMessage: commitMessage,
+		FilesChanged:  filesChanged,
+		Additions:     additions,
+		Deletions:     deletions,
+		MRURL:         changes.URL,
+		ReviewStatus:  "pending",
+	}
+	if err := s.createReviewLog(ctx, reviewLog); err != nil {
+		return err
+	}
+
+	task := &services.ReviewTask{
+		ReviewLogID:     reviewLog.ID,
+		RequestID:       logger.RequestID(ctx),
+		ProjectID:       project.ID,
+		CommitSHA:       changes.CommitSHA,
+		EventType:       services.EventTypeRelease,
+		Branch:          changes.Tag,
+		Author:          changes.Author,
+		AuthorEmail:     changes.AuthorEmail,
+		AuthorAvatar:    changes.AuthorAvatar,
+		CommitMessage:   commitMessage,
+		Diff:            changes.Diff,
+		CommitURL:       changes.URL,
+		MRURL:           changes.URL,
+		PreviousTag:     changes.PreviousTag,
+		GitLabProjectID: changes.GitLabProjectID,
+	}
+	if err := services.GetTaskQueue().Enqueue(task); err != nil {
+		logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("tag", changes.Tag).Msg("Failed to enqueue release review task")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
+		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
+		return err
+	}
+
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("tag", changes.Tag).Int("commits", len(changes.Commits)).Msg("Release review enqueued")
+	return nil
+}
+
+// processReleaseTask reviews the changes of a tag and sends the summary to the release channel
+func (s *Service) processReleaseTask(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, task *services.ReviewTask) error {
+	filteredDiff := s.filterDiff(task.Diff, project)
+
+	result, err := s.aiService.ReviewRelease(ctx, &services.ReleaseReviewRequest{
+		ProjectID:   project.ID,
+		Tag:         task.Branch,
+		PreviousTag: task.PreviousTag,
+		Commits:     task.CommitMessage,
+		Diffs:       filteredDiff,
+	})
+	if err != nil {
+		logger.For(ctx, "task_queue").Warn().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Msg("Release review failed")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = err.Error()
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "failed", nil, err.Error())
+		return err
+	}
+
+	reviewLog.ReviewStatus = "completed"
+	reviewLog.ReviewResult = result.Content
+	reviewLog.Score = &result.Score
+	s.reviewService.Update(reviewLog)
+	s.recordFindings(reviewLog, filteredDiff)
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "completed", &result.Score, "")
+
+	if err := s.notificationService.SendReleaseNotification(project, &services.ReviewNotification{
+		ProjectName:   project.Name,
+		Branch:        task.Branch,
+		Author:        task.Author,
+		CommitMessage: task.CommitMessage,
+		Score:         result.Score,
+		ReviewResult:  result.Content,
+		EventType:     services.EventTypeRelease,
+		MRURL:         task.MRURL,
+		ReviewLogID:   reviewLog.ID,
+	}); err != nil {
+		logger.For(ctx, "task_queue").Warn().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Msg("Failed to send release notification")
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestPreviousTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		current string
+		want    string
+	}{
+		{"current first", []string{"v1.2.0", "v1.1.0", "v1.0.0"}, "v1.2.0", "v1.1.0"},
+		{"current missing", []string{"v1.1.0", "v1.0.0"}, "v1.2.0", "v1.1.0"},
+		{"only current", []string{"v1.0.0"}, "v1.0.0", ""},
+		{"no tags", nil, "v1.0.0", ""},
+		{"empty names skipped", []string{"v2.0.0", "", "v1.0.0"}, "v2.0.0", "v1.0.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previousTag(tt.tags, tt.current); got != tt.want {
+				t.Errorf("previousTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatReleaseCommit(t *testing.T) {
+	tests := []struct {
+		sha     string
+		message string
+		want    string
+	}{
+		{"0123456789abcdef", "Add login page", "01234567: Add login page"},
+		{"0123456789abcdef", "Fix crash\n\nLong description", "01234567: Fix crash"},
+		{"abc", "  Trim spaces  \n", "abc: Trim spaces"},
+	}
+
+	for _, tt := range tests {
+		if got := formatReleaseCommit(tt.sha, tt.message); got != tt.want {
+			t.Errorf("formatReleaseCommit(%q, %q) = %q, want %q", tt.sha, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestReleaseEventsEnabled(t *testing.T) {
+	tests := []struct {
+		events string
+		want   bool
+	}{
+		{"push,merge_request", false},
+		{"push,merge_request,tag", true},
+		{"tag", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := releaseEventsEnabled(&models.Project{ReviewEvents: tt.events}); got != tt.want {
+			t.Errorf("releaseEventsEnabled(%q) = %v, want %v", tt.events, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+var (
+	replayedDeliveries atomic.Int64
+	staleDeliveries    atomic.Int64
+)
+
+var (
+	ErrReplayedDelivery = errors.New("webhook delivery was already received")
+	ErrStaleDelivery    = errors.New("webhook event is too old")
+)
+
+// ReplayRejectedCounts returns the number of webhook deliveries rejected as replayed and as stale
+func ReplayRejectedCounts() (replayed, stale int64) {
+	return replayedDeliveries.Load(), staleDeliveries.Load()
+}
+
+// CheckReplay rejects deliveries of a project with replay protection that were already
+// received within the replay window, by any replica, and events older than the window.
+// It must only be called for authenticated deliveries, so forged requests cannot claim
+// delivery IDs. Deliveries are let through when their ID cannot be stored.
+func (s *Service) CheckReplay(project *models.Project, platform string, header http.Header, body []byte) error {
+	if !project.ReplayProtection {
+		return nil
+	}
+
+	now := time.Now()
+	if at, ok := EventTimestamp(platform, body); ok && now.Sub(at) > services.WebhookReplayWindow {
+		staleDeliveries.Add(1)
+		return ErrStaleDelivery
+	}
+	id := DeliveryID(platform, header)
+	if id == "" {
+		return nil
+	}
+	claimed, err := services.ClaimWebhookDelivery(s.db, project.ID, platform, id, now)
+	if err != nil {
+		logger.Warnf("[Webhook] Failed to record delivery %s of project %d: %v", id, project.ID, err)
+		return nil
+	}
+	if !claimed {
+		replayedDeliveries.Add(1)
+		return ErrReplayedDelivery
+	}
+	return nil
+}
+
+// DeliveryID returns the unique ID a platform sends with each webhook delivery
+func DeliveryID(platform string, header http.Header) string {
+	switch platform {
+	case "gitlab":
+		return header.Get("X-Gitlab-Event-UUID")
+	case "github":
+		return header.Get("X-GitHub-Delivery")
+	case "bitbucket":
+		if id := header.Get("X-Request-UUID"); id != "" {
+			return id
+		}
+		return header.Get("X-Request-Id") // Bitbucket Server
+	}
+	return ""
+}
+
+// gitLabTimeLayouts covers the timestamp formats of GitLab webhook payloads
+var gitLabTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"}
+
+// EventTimestamp returns when a webhook event happened, for payloads that carry it:
+// merge request and note updates, pull request updates and releases. Push payloads
+// only carry commit dates, which may be arbitrarily old, so they are not checked.
+func EventTimestamp(platform string, body []byte) (time.Time, bool) {
+	var payload struct {
+		Date             string `json:"date"` // Bitbucket Server
+		ObjectAttributes struct {
+			UpdatedAt string `json:"updated_at"`
+		} `json:"object_attributes"`
+		PullRequest struct {
+			UpdatedAt string `json:"updated_at"`
+		} `json:"pull_request"`
+		Release struct {
+			PublishedAt string `json:"published_at"`
+		} `json:"release"`
+		Pullrequest struct {
+			UpdatedOn string `json:"updated_on"`
+		} `json:"pullrequest"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return time.Time{}, false
+	}
+
+	var value string
+	layouts := []string{time.RFC3339Nano}
+	switch platform {
+	case "gitlab":
+		value, layouts = payload.ObjectAttributes.UpdatedAt, gitLabTimeLayouts
+	case "github":
+		value = payload.PullRequest.UpdatedAt
+		if value == "" {
+			value = payload.Release.PublishedAt
+		}
+	case "bitbucket":
+		value = payload.Pullrequest.UpdatedOn
+		if value == "" {
+			value = payload.Date
+		}
+		layouts = append(layouts, "2006-01-02T15:04:05-0700")
+	}
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range layouts {
+		if at, err := time.Parse(layout, value); err == nil {
+			return at, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestEventTimestamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		body     string
+		want     string
+		wantOK   bool
+	}{
+		{"gitlab merge request", "gitlab", `{"object_attributes":{"updated_at":"2024-05-01T10:00:00Z"}}`, "2024-05-01T10:00:00Z", true},
+		{"gitlab legacy format", "gitlab", `{"object_attributes":{"updated_at":"2024-05-01 10:00:00 UTC"}}`, "2024-05-01T10:00:00Z", true},
+		{"gitlab push", "gitlab", `{"object_kind":"push","commits":[{"timestamp":"2020-01-01T00:00:00Z"}]}`, "", false},
+		{"github pull request", "github", `{"pull_request":{"updated_at":"2024-05-01T10:00:00Z"}}`, "2024-05-01T10:00:00Z", true},
+		{"github release", "github", `{"release":{"published_at":"2024-05-01T10:00:00Z"}}`, "2024-05-01T10:00:00Z", true},
+		{"github push", "github", `{"head_commit":{"timestamp":"2020-01-01T00:00:00Z"}}`, "", false},
+		{"bitbucket pull request", "bitbucket", `{"pullrequest":{"updated_on":"2024-05-01T10:00:00.123456+00:00"}}`, "2024-05-01T10:00:00.123456Z", true},
+		{"bitbucket server", "bitbucket", `{"date":"2024-05-01T20:00:00+1000"}`, "2024-05-01T10:00:00Z", true},
+		{"invalid json", "github", `{`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EventTimestamp(tt.platform, []byte(tt.body))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			want, _ := time.Parse(time.RFC3339Nano, tt.want)
+			if !got.Equal(want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCheckReplay(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.WebhookDelivery{}); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	s := &Service{db: db}
+
+	protected := &models.Project{ID: 4130, ReplayProtection: true}
+	header := http.Header{}
+	header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	fresh := []byte(`{"pull_request":{"updated_at":"` + time.Now().UTC().Format(time.RFC3339) + `"}}`)
+	stale := []byte(`{"pull_request":{"updated_at":"` + time.Now().Add(-2*services.WebhookReplayWindow).UTC().Format(time.RFC3339) + `"}}`)
+
+	if err := s.CheckReplay(protected, "github", header, fresh); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	// Another replica, sharing the database, rejects the replay too
+	other := &Service{db: db}
+	if err := other.CheckReplay(protected, "github", header, fresh); !errors.Is(err, ErrReplayedDelivery) {
+		t.Errorf("replayed delivery: got %v, want %v", err, ErrReplayedDelivery)
+	}
+
+	otherHeader := http.Header{}
+	otherHeader.Set("X-GitHub-Delivery", "another-delivery")
+	if err := s.CheckReplay(protected, "github", otherHeader, stale); !errors.Is(err, ErrStaleDelivery) {
+		t.Errorf("stale delivery: got %v, want %v", err, ErrStaleDelivery)
+	}
+
+	unprotected := &models.Project{ID: 4131}
+	for i := 0; i < 2; i++ {
+		if err := s.CheckReplay(unprotected, "github", header, stale); err != nil {
+			t.Errorf("unprotected project: %v", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// processRetroactiveTask reviews an imported historical commit. Only the review is
+// recorded: no notifications, comments, commit statuses or issues are created for history.
+func (s *Service) processRetroactiveTask(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, task *services.ReviewTask) error {
+	filteredDiff := s.filterDiff(task.Diff, project)
+	limits := services.DiffLimitsOf(project)
+	filteredDiff, droppedFiles := limits.DropOversizedFiles(filteredDiff)
+
+	if IsEmptyDiff(filteredDiff) && len(droppedFiles) == 0 {
+		reviewLog.ReviewStatus = "skipped"
+		reviewLog.ReviewResult = "Empty commit - no code changes to review"
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "skipped", nil, "Empty commit - no code changes")
+		return nil
+	}
+	reason := limits.Exceeded(filteredDiff)
+	if reason == "" && IsEmptyDiff(filteredDiff) {
+		reason = fmt.Sprintf("all %d files exceed the per-file limit of %d bytes", len(droppedFiles), limits.MaxFileBytes)
+	}
+	if reason != "" {
+		logger.For(ctx, "task_queue").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("reason", reason).Msg("Skipping retroactive review, change too large")
+		reviewLog.ReviewStatus = services.ReviewStatusSkippedTooLarge
+		reviewLog.ReviewResult = "Review skipped, change too large: " + reason
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, services.ReviewStatusSkippedTooLarge, nil, reason)
+		return nil
+	}
+
+	var findings string
+	reviewLog.UntestedFiles, findings = s.testCoverageFinding(filteredDiff)
+	reviewLog.DiffHash = services.ComputeDiffHash(filteredDiff)
+
+	if cached := s.reviewCacheService.FindCachedReview(project.ID, reviewLog.DiffHash); cached != nil {
+		reviewLog.ReviewStatus = "completed"
+		reviewLog.ReviewResult = cached.ReviewResult
+		reviewLog.Score = &cached.Score
+		s.reviewService.Update(reviewLog)
+		s.recordFindings(reviewLog, filteredDiff)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "completed", &cached.Score, "")
+		return nil
+	}
+	s.reviewService.Update(reviewLog)
+
+	result, err := s.aiService.ReviewChunked(ctx, &services.ReviewRequest{
+		ProjectID:   project.ID,
+		Diffs:       filteredDiff,
+		Commits:     task.CommitMessage,
+		Findings:    findings,
+		Author:      task.Author,
+		EventType:   task.EventType,
+		Branch:      task.Branch,
+		ReviewLogID: reviewLog.ID,
+		CommitHash:  task.CommitSHA,
+	})
+	if err != nil {
+		logger.For(ctx, "task_queue").Warn().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Msg("Retroactive AI review failed")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = err.Error()
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "failed", nil, err.Error())
+		return err
+	}
+
+	logger.For(ctx, "task_queue").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Float64("score", result.Score).Msg("Retroactive AI review completed")
+	if note := limits.FormatDroppedFiles(droppedFiles); note != "" {
+		result.Content += "\n\n" + note
+	}
+	if findings != "" {
+		result.Content += "\n\n" + findings
+	}
+	services.ApplyReviewResult(reviewLog, result)
+	s.reviewService.Update(reviewLog)
+	s.recordFindings(reviewLog, filteredDiff)
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, reviewLog.ReviewStatus, reviewLog.Score, "")
+	return nil
+}
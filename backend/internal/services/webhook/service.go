@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/huangang/codesentry/backend/pkg/logger"
 
 	"github.com/huangang/codesentry/backend/internal/config"
@@ -26,6 +27,8 @@ type Service struct {
 	fileContextService  *services.FileContextService
 	reviewCacheService  *services.ReviewCacheService
 	issueTrackerService *services.IssueTrackerService
+	reviewHookService   *services.ReviewHookService
+	findingService      *services.ReviewFindingService
 	httpClient          *http.Client
 }
 
@@ -42,7 +45,9 @@ func NewService(db *gorm.DB, aiCfg *config.OpenAIConfig) *Service {
 		fileContextService:  services.NewFileContextService(configService),
 		reviewCacheService:  services.NewReviewCacheService(db),
 		issueTrackerService: services.NewIssueTrackerService(db),
-		httpClient:          &http.Client{Timeout: 30 * time.Second},
+		reviewHookService:   services.NewReviewHookService(db),
+		findingService:      services.NewReviewFindingService(db),
+		httpClient:          services.PlatformHTTPClient(),
 	}
 }
 
@@ -52,9 +57,13 @@ func (s *Service) GetReviewScore(commitSHA string) (*ReviewScoreResponse, error)
 	if err := s.db.Where("commit_hash = ?", commitSHA).Order("created_at DESC").First(&reviewLog).Error; err != nil {
 		return nil, fmt.Errorf("review not found for commit: %s", commitSHA)
 	}
+	return s.reviewScore(&reviewLog), nil
+}
 
+// reviewScore summarizes the status and pass/fail outcome of a review
+func (s *Service) reviewScore(reviewLog *models.ReviewLog) *ReviewScoreResponse {
 	resp := &ReviewScoreResponse{
-		CommitSHA: commitSHA,
+		CommitSHA: reviewLog.CommitHash,
 		Status:    reviewLog.ReviewStatus,
 		ReviewID:  reviewLog.ID,
 	}
@@ -66,33 +75,60 @@ func (s *Service) GetReviewScore(commitSHA string) (*ReviewScoreResponse, error)
 		var project models.Project
 		s.db.First(&project, reviewLog.ProjectID)
 		minScore := s.getEffectiveMinScore(&project)
-		passed := reviewLog.Score != nil && *reviewLog.Score >= minScore
+		passed := services.ReviewPassed(reviewLog, minScore, services.NewSystemConfigService(s.db).GetHumanVerdictConfig().Gating)
 		resp.Score = reviewLog.Score
 		resp.MinScore = minScore
-		resp.Passed = &passed
 		resp.Message = "Review completed"
-	case "skipped":
+		if passed && reviewLog.MigrationAck == services.MigrationAckPending {
+			passed = false
+			resp.Message = "Review completed, destructive migration awaiting acknowledgment"
+		}
+		resp.Passed = &passed
+	case "skipped", services.ReviewStatusSkippedTooLarge, services.ReviewStatusSkippedByDirective:
 		passed := true
 		resp.Passed = &passed
 		resp.Message = "Skipped: " + reviewLog.ReviewResult
 	case "failed":
 		resp.Message = "Review failed: " + reviewLog.ErrorMessage
+	case services.ReviewStatusDiffFetchFailed:
+		resp.Message = "Diff could not be fetched: " + reviewLog.ErrorMessage
+	case services.ReviewStatusNeedsAttention:
+		passed := false
+		resp.Passed = &passed
+		resp.Message = "Review needs attention: no score found in the AI response"
 	}
 
-	return resp, nil
+	return resp
 }
 
 // SyncReview performs a synchronous review for the given project and request
 func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *SyncReviewRequest) (*SyncReviewResponse, error) {
 	minScore := s.getEffectiveMinScore(project)
 
+	if project.Archived {
+		return &SyncReviewResponse{
+			Passed:   true,
+			Score:    100,
+			MinScore: minScore,
+			Message:  "Project is archived, skipping review",
+		}, nil
+	}
+	if project.PendingApproval {
+		return &SyncReviewResponse{
+			Passed:   true,
+			Score:    100,
+			MinScore: minScore,
+			Message:  "Project is pending approval, skipping review",
+		}, nil
+	}
+
 	branch := strings.TrimPrefix(req.Ref, "refs/heads/")
-	if s.isBranchIgnored(branch, project.BranchFilter) {
+	if s.isBranchIgnored(branch, project) {
 		return &SyncReviewResponse{
 			Passed:   true,
 			Score:    100,
 			MinScore: minScore,
-			Message:  "Branch is in ignore list, skipping review",
+			Message:  "Branch is excluded by the branch filter, skipping review",
 		}, nil
 	}
 
@@ -127,8 +163,31 @@ func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *
 	reviewLog.ReviewStatus = "processing"
 	s.reviewService.Update(reviewLog)
 
+	// Oversized files are left out like in queued reviews
+	limits := services.DiffLimitsOf(project)
+	diff, droppedFiles := limits.DropOversizedFiles(req.Diffs)
+	reason := limits.Exceeded(diff)
+	if reason == "" && IsEmptyDiff(diff) && len(droppedFiles) > 0 {
+		reason = fmt.Sprintf("all %d files exceed the per-file limit of %d bytes", len(droppedFiles), limits.MaxFileBytes)
+	}
+	if reason != "" {
+		reviewLog.ReviewStatus = services.ReviewStatusSkippedTooLarge
+		reviewLog.ReviewResult = "Review skipped, change too large: " + reason
+		s.reviewService.Update(reviewLog)
+		return &SyncReviewResponse{
+			Passed:   true,
+			Score:    100,
+			MinScore: minScore,
+			Message:  reviewLog.ReviewResult,
+			ReviewID: reviewLog.ID,
+		}, nil
+	}
+
+	var findings string
+	reviewLog.UntestedFiles, findings = s.testCoverageFinding(diff)
+
 	// Compute diff hash and check cache
-	diffHash := services.ComputeDiffHash(req.Diffs)
+	diffHash := services.ComputeDiffHash(diff)
 	reviewLog.DiffHash = diffHash
 	s.reviewService.Update(reviewLog)
 
@@ -136,7 +195,9 @@ func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *
 		reviewLog.ReviewStatus = "completed"
 		reviewLog.ReviewResult = cached.ReviewResult
 		reviewLog.Score = &cached.Score
+		reviewLog.PromptRef = services.PromptRefCached
 		s.reviewService.Update(reviewLog)
+		s.recordFindings(reviewLog, diff)
 
 		passed := cached.Score >= minScore
 		message := fmt.Sprintf("Score: %.0f/100 (min: %.0f) [cached]", cached.Score, minScore)
@@ -155,17 +216,23 @@ func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *
 
 	var fileContext string
 	if s.fileContextService.IsEnabled() {
-		fileContext, _ = s.fileContextService.BuildFileContext(project, req.Diffs, req.CommitSHA)
+		fileContext, _ = s.fileContextService.BuildFileContext(project, diff, req.CommitSHA)
 		if fileContext != "" {
-			logger.Infof("[Webhook] Built file context for sync review: %d chars", len(fileContext))
+			logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Str("commit", req.CommitSHA).Int("chars", len(fileContext)).Msg("Built file context for sync review")
 		}
 	}
 
 	result, err := s.aiService.ReviewChunked(ctx, &services.ReviewRequest{
 		ProjectID:   project.ID,
-		Diffs:       req.Diffs,
+		Diffs:       diff,
 		Commits:     req.Message,
 		FileContext: fileContext,
+		Findings:    findings,
+		Author:      req.Author,
+		EventType:   "push",
+		Branch:      branch,
+		ReviewLogID: reviewLog.ID,
+		CommitHash:  req.CommitSHA,
 	})
 
 	if err != nil {
@@ -174,11 +241,25 @@ func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *
 		s.reviewService.Update(reviewLog)
 		return nil, fmt.Errorf("AI review failed: %w", err)
 	}
+	if note := limits.FormatDroppedFiles(droppedFiles); note != "" {
+		result.Content += "\n\n" + note
+	}
+	if findings != "" {
+		result.Content += "\n\n" + findings
+	}
 
-	reviewLog.ReviewStatus = "completed"
-	reviewLog.ReviewResult = result.Content
-	reviewLog.Score = &result.Score
+	services.ApplyReviewResult(reviewLog, result)
 	s.reviewService.Update(reviewLog)
+	if result.ScoreMissing {
+		return &SyncReviewResponse{
+			Passed:      false,
+			MinScore:    minScore,
+			Message:     "Review needs attention: no score found in the AI response",
+			ReviewID:    reviewLog.ID,
+			FullContent: result.Content,
+		}, nil
+	}
+	s.recordFindings(reviewLog, diff)
 
 	passed := result.Score >= minScore
 	message := fmt.Sprintf("Score: %.0f/100 (min: %.0f)", result.Score, minScore)
@@ -196,16 +277,71 @@ func (s *Service) SyncReview(ctx context.Context, project *models.Project, req *
 	}, nil
 }
 
+// recordFindings stores the categorized findings and changed files of a completed review for analytics
+func (s *Service) recordFindings(reviewLog *models.ReviewLog, diff string) {
+	if err := s.findingService.Record(reviewLog, diff); err != nil {
+		logger.Module("webhook").Warn().Err(err).Uint("project_id", reviewLog.ProjectID).Uint("review_id", reviewLog.ID).Msg("Failed to record findings")
+	}
+}
+
+// skipTooLargeReview marks a review whose diff exceeds the project's size limits as
+// skipped and explains why in the commit status and an IM notification
+func (s *Service) skipTooLargeReview(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, task *services.ReviewTask, reason string) {
+	log := logger.For(ctx, "task_queue").With().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", task.CommitSHA).Logger()
+	log.Info().Str("reason", reason).Msg("Skipping review, change too large")
+	reviewLog.ReviewStatus = services.ReviewStatusSkippedTooLarge
+	reviewLog.ReviewResult = "Review skipped, change too large: " + reason
+	s.reviewService.Update(reviewLog)
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, services.ReviewStatusSkippedTooLarge, nil, reason)
+
+	s.setCommitStatus(project, task.CommitSHA, "success", "AI Review skipped: "+reason, task.GitLabProjectID)
+
+	target := task.Branch
+	if task.MRURL != "" {
+		target = task.MRURL
+	}
+	message := fmt.Sprintf("[CodeSentry] AI review skipped for %s (%s) by %s: change too large, %s", project.Name, target, task.Author, reason)
+	if err := s.notificationService.SendTextNotification(project, message); err != nil {
+		log.Warn().Err(err).Msg("Failed to send skip notification")
+	}
+}
+
+// flagMissingScore records a review whose AI response has no parseable score as needing
+// attention instead of scoring it 0, and says so in the commit status and an IM notification
+func (s *Service) flagMissingScore(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, task *services.ReviewTask, result *services.ReviewResult) {
+	log := logger.For(ctx, "task_queue").With().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", task.CommitSHA).Logger()
+	log.Warn().Msg("AI review has no score, marking it as needing attention")
+	services.ApplyReviewResult(reviewLog, result)
+	s.reviewService.Update(reviewLog)
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, services.ReviewStatusNeedsAttention, nil, "no score found in the AI response")
+
+	s.setCommitStatus(project, task.CommitSHA, "failed", "AI Review needs attention: no score in the response", task.GitLabProjectID)
+
+	target := task.Branch
+	if task.MRURL != "" {
+		target = task.MRURL
+	}
+	message := fmt.Sprintf("[CodeSentry] AI review of %s (%s) by %s needs attention: no score found in the AI response, retry it or check it manually", project.Name, target, task.Author)
+	if err := s.notificationService.SendTextNotification(project, message); err != nil {
+		log.Warn().Err(err).Msg("Failed to send needs-attention notification")
+	}
+}
+
 // ProcessReviewTask processes a review task from the async queue
 func (s *Service) ProcessReviewTask(ctx context.Context, task *services.ReviewTask) (retErr error) {
-	logger.Infof("[TaskQueue] Processing review task: review_log_id=%d, project=%d, commit=%s",
-		task.ReviewLogID, task.ProjectID, task.CommitSHA)
+	// Tasks queued outside a request, e.g. by imports, get their own ID to correlate their logs
+	if task.RequestID == "" {
+		task.RequestID = uuid.New().String()
+	}
+	ctx = logger.WithRequestID(ctx, task.RequestID)
+	log := logger.For(ctx, "task_queue").With().Uint("project_id", task.ProjectID).Uint("review_id", task.ReviewLogID).Str("commit", task.CommitSHA).Logger()
+	log.Info().Str("event_type", task.EventType).Msg("Processing review task")
 
 	// Recover from panic to ensure review status is updated to "failed"
 	defer func() {
 		if r := recover(); r != nil {
 			panicMsg := fmt.Sprintf("panic: %v", r)
-			logger.Infof("[TaskQueue] Recovered from panic in review task %d: %s", task.ReviewLogID, panicMsg)
+			log.Error().Str("panic", panicMsg).Msg("Recovered from panic in review task")
 			// Update review status to failed
 			if reviewLog, err := s.reviewService.GetByID(task.ReviewLogID); err == nil {
 				reviewLog.ReviewStatus = "failed"
@@ -227,15 +363,47 @@ func (s *Service) ProcessReviewTask(ctx context.Context, task *services.ReviewTa
 		return fmt.Errorf("project not found: %w", err)
 	}
 
+	// Tasks queued before the project was disabled, or re-queued by retries, must not reach the AI
+	if project.PendingApproval {
+		log.Info().Msg("Project is pending approval, skipping review task")
+		reviewLog.ReviewStatus = "skipped"
+		reviewLog.ReviewResult = "Project is pending approval"
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "skipped", nil, "Project is pending approval")
+		return nil
+	}
+
+	if task.PushHead != "" {
+		defer s.notifyPushGroup(ctx, project, task.PushHead)
+	}
+	services.StartReviewTiming(reviewLog, task.QueuedAt)
+	defer s.finishReviewTiming(reviewLog)
+
 	reviewLog.ReviewStatus = "analyzing"
+	reviewLog.RequestID = task.RequestID
 	s.reviewService.Update(reviewLog)
 	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "analyzing", nil, "")
 
-	filteredDiff := s.filterDiff(task.Diff, project.FileExtensions, project.IgnorePatterns)
+	if task.EventType == services.EventTypeRelease {
+		return s.processReleaseTask(ctx, project, reviewLog, task)
+	}
+	if reviewLog.Retroactive {
+		return s.processRetroactiveTask(ctx, project, reviewLog, task)
+	}
 
+	filteredDiff := s.filterDiff(task.Diff, project)
+
+	if IsEmptyDiff(filteredDiff) && !IsEmptyDiff(task.Diff) {
+		log.Info().Msg("No changed file passes the path filter, skipping AI review")
+		reviewLog.ReviewStatus = "skipped"
+		reviewLog.ReviewResult = "No changed file passes the project's path filter"
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "skipped", nil, reviewLog.ReviewResult)
+		return nil
+	}
 	if IsEmptyDiff(filteredDiff) {
-		logger.Warnf("[TaskQueue] WARNING: Empty commit detected for review_log_id=%d - skipping AI review", task.ReviewLogID)
-		services.LogWarning("TaskQueue", "EmptyCommit", fmt.Sprintf("Empty commit %s detected, skipping AI review", task.CommitSHA[:8]), nil, "", "", map[string]interface{}{
+		log.Warn().Msg("Empty commit detected, skipping AI review")
+		services.LogWarning(ctx, "TaskQueue", "EmptyCommit", fmt.Sprintf("Empty commit %s detected, skipping AI review", task.CommitSHA[:8]), nil, "", "", map[string]interface{}{
 			"project_id":    task.ProjectID,
 			"review_log_id": task.ReviewLogID,
 			"commit":        task.CommitSHA,
@@ -247,58 +415,144 @@ func (s *Service) ProcessReviewTask(ctx context.Context, task *services.ReviewTa
 		return nil
 	}
 
-	// Compute diff hash and check cache
+	limits := services.DiffLimitsOf(project)
+	filteredDiff, droppedFiles := limits.DropOversizedFiles(filteredDiff)
+	if len(droppedFiles) > 0 {
+		log.Info().Strs("files", droppedFiles).Msg("Leaving oversized files out of review")
+	}
+	reason := limits.Exceeded(filteredDiff)
+	if reason == "" && IsEmptyDiff(filteredDiff) {
+		reason = fmt.Sprintf("all %d files exceed the per-file limit of %d bytes", len(droppedFiles), limits.MaxFileBytes)
+	}
+	if reason != "" {
+		s.skipTooLargeReview(ctx, project, reviewLog, task, reason)
+		return nil
+	}
+
+	var findings string
+	reviewLog.UntestedFiles, findings = s.testCoverageFinding(filteredDiff)
+
+	signatures := s.checkCommitSignatures(ctx, project, task)
+	if finding := signatures.Finding(); finding != "" {
+		reviewLog.UnsignedCommits = len(signatures.Unsigned)
+		findings = strings.TrimPrefix(findings+"\n\n"+finding, "\n\n")
+	}
+
+	migrations := services.NewMigrationCheck(project.MigrationPolicy, services.ParseDiffToFiles(filteredDiff))
+	if finding := migrations.Finding(); finding != "" {
+		reviewLog.MigrationStatements = strings.Join(migrations.Destructive, "\n")
+		findings = strings.TrimPrefix(findings+"\n\n"+finding, "\n\n")
+	}
+
+	// Pre-review hooks may change the diff, the prompt and the findings
+	hookTarget := reviewHookTarget(project, reviewLog, task)
+	hooked, err := s.reviewHookService.RunPreReview(ctx, project, &services.PreReviewInput{
+		Review:   hookTarget,
+		Diff:     filteredDiff,
+		Findings: findings,
+	})
+	if err != nil {
+		log.Warn().Err(err).Msg("Pre-review hooks failed")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = err.Error()
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "failed", nil, err.Error())
+		s.setCommitStatus(project, task.CommitSHA, "failed", "AI Review Failed", task.GitLabProjectID)
+		return err
+	}
+	filteredDiff, findings = hooked.Diff, hooked.Findings
+	if IsEmptyDiff(filteredDiff) {
+		reviewLog.ReviewStatus = "skipped"
+		reviewLog.ReviewResult = "No code changes left to review after pre-review hooks"
+		s.reviewService.Update(reviewLog)
+		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "skipped", nil, reviewLog.ReviewResult)
+		s.setCommitStatus(project, task.CommitSHA, "success", "AI Review skipped: no changes left after pre-review hooks", task.GitLabProjectID)
+		return nil
+	}
+
+	// Merge requests into branches with a policy get its minimum score, findings limit and template
+	policy := services.ResolveTargetBranchPolicy(project.TargetBranchPolicies, task.TargetBranch)
+	minScore := s.getEffectiveMinScore(project)
+	if policy != nil && policy.MinScore > 0 {
+		minScore = policy.MinScore
+	}
+
+	// Compute diff hash and check cache; reviews with a hook or policy prompt are never served from the cache
 	diffHash := services.ComputeDiffHash(filteredDiff)
 	reviewLog.DiffHash = diffHash
 	s.reviewService.Update(reviewLog)
 
-	if cached := s.reviewCacheService.FindCachedReview(project.ID, diffHash); cached != nil {
+	policyPrompt := policy != nil && policy.ReviewTemplateID != nil
+	if cached := s.reviewCacheService.FindCachedReview(project.ID, diffHash); cached != nil && hooked.Prompt == "" && !policyPrompt {
+		critical := services.CriticalFindingsExceeded(policy, cached.ReviewResult)
 		reviewLog.ReviewStatus = "completed"
 		reviewLog.ReviewResult = cached.ReviewResult
 		reviewLog.Score = &cached.Score
+		reviewLog.PromptRef = services.PromptRefCached
 		s.reviewService.Update(reviewLog)
+		s.recordFindings(reviewLog, filteredDiff)
 		services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "completed", &cached.Score, "")
 
 		// Still send notification and set commit status for cached results
-		s.notificationService.SendReviewNotification(project, &services.ReviewNotification{
-			ProjectName:   project.Name,
-			Branch:        task.Branch,
-			Author:        task.Author,
-			CommitMessage: task.CommitMessage,
-			Score:         cached.Score,
-			ReviewResult:  cached.ReviewResult,
-			EventType:     task.EventType,
-			MRURL:         task.MRURL,
-		})
+		if task.PushHead == "" {
+			s.notificationService.SendReviewNotification(ctx, project, &services.ReviewNotification{
+				ProjectName:   project.Name,
+				Branch:        task.Branch,
+				Author:        task.Author,
+				CommitMessage: task.CommitMessage,
+				Score:         cached.Score,
+				ReviewResult:  cached.ReviewResult,
+				EventType:     task.EventType,
+				MRURL:         task.MRURL,
+				Urgent:        policyUrgent(policy, cached.Score < minScore || critical != ""),
+				ReviewLogID:   reviewLog.ID,
+			})
+		}
 
 		// Auto-create issues for low-score reviews
 		go s.issueTrackerService.CheckAndCreateIssue(reviewLog, project.Name)
 
-		minScore := s.getEffectiveMinScore(project)
 		statusState := "success"
 		statusDesc := fmt.Sprintf("AI Review Passed: %.0f/%.0f [cached]", cached.Score, minScore)
 		if cached.Score < minScore {
 			statusState = "failed"
 			statusDesc = fmt.Sprintf("AI Review Failed: %.0f (Min: %.0f) [cached]", cached.Score, minScore)
+		} else if critical != "" {
+			statusState = "failed"
+			statusDesc = "AI Review Failed: " + critical + " [cached]"
 		}
+		statusState, statusDesc = signatures.Gate(statusState, statusDesc)
+		statusState, statusDesc = s.gateMigrations(reviewLog, migrations, statusState, statusDesc)
 		s.setCommitStatus(project, task.CommitSHA, statusState, statusDesc, task.GitLabProjectID)
 		return nil
 	}
 
 	var fileContext string
 	if s.fileContextService.IsEnabled() {
+		contextStart := time.Now()
 		fileContext, _ = s.fileContextService.BuildFileContext(project, filteredDiff, task.CommitSHA)
+		reviewLog.ContextBuildMs = services.ObserveReviewStage(services.ReviewStageContextBuild, time.Since(contextStart))
 	}
 
+	llmStart := time.Now()
 	result, err := s.aiService.ReviewChunked(ctx, &services.ReviewRequest{
-		ProjectID:   project.ID,
-		Diffs:       filteredDiff,
-		Commits:     task.CommitMessage,
-		FileContext: fileContext,
+		ProjectID:    project.ID,
+		Diffs:        filteredDiff,
+		Commits:      task.CommitMessage,
+		FileContext:  fileContext,
+		CustomPrompt: hooked.Prompt,
+		Findings:     findings,
+		Author:       task.Author,
+		EventType:    task.EventType,
+		Branch:       task.Branch,
+		TargetBranch: task.TargetBranch,
+		ReviewLogID:  reviewLog.ID,
+		CommitHash:   task.CommitSHA,
 	})
+	reviewLog.LLMMs = services.ObserveReviewStage(services.ReviewStageLLM, time.Since(llmStart))
 
 	if err != nil {
-		logger.Infof("[TaskQueue] AI review failed: %v", err)
+		log.Warn().Err(err).Msg("AI review failed")
 		reviewLog.ReviewStatus = "failed"
 		reviewLog.ErrorMessage = err.Error()
 		s.reviewService.Update(reviewLog)
@@ -307,30 +561,54 @@ func (s *Service) ProcessReviewTask(ctx context.Context, task *services.ReviewTa
 		return err
 	}
 
-	logger.Infof("[TaskQueue] AI review completed, score: %.1f", result.Score)
-	reviewLog.ReviewStatus = "completed"
-	reviewLog.ReviewResult = result.Content
-	reviewLog.Score = &result.Score
+	log.Info().Float64("score", result.Score).Msg("AI review completed")
+	if note := limits.FormatDroppedFiles(droppedFiles); note != "" {
+		result.Content += "\n\n" + note
+	}
+	if findings != "" {
+		result.Content += "\n\n" + findings
+	}
+	if added := s.reviewHookService.RunPostReview(ctx, project, &services.PostReviewInput{
+		Review:  hookTarget,
+		Score:   result.Score,
+		Content: result.Content,
+		Diff:    filteredDiff,
+	}); added != "" {
+		result.Content += "\n\n" + added
+	}
+	if result.ScoreMissing {
+		s.flagMissingScore(ctx, project, reviewLog, task, result)
+		return nil
+	}
+	services.ApplyReviewResult(reviewLog, result)
 	s.reviewService.Update(reviewLog)
+	s.recordFindings(reviewLog, filteredDiff)
+	critical := services.CriticalFindingsExceeded(policy, result.Content)
 	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, "completed", &result.Score, "")
 
-	s.notificationService.SendReviewNotification(project, &services.ReviewNotification{
-		ProjectName:   project.Name,
-		Branch:        task.Branch,
-		Author:        task.Author,
-		CommitMessage: task.CommitMessage,
-		Score:         result.Score,
-		ReviewResult:  result.Content,
-		EventType:     task.EventType,
-		MRURL:         task.MRURL,
-	})
+	// Commits reviewed separately are notified together by notifyPushGroup
+	if task.PushHead == "" {
+		s.notificationService.SendReviewNotification(ctx, project, &services.ReviewNotification{
+			ProjectName:   project.Name,
+			Branch:        task.Branch,
+			Author:        task.Author,
+			CommitMessage: task.CommitMessage,
+			Score:         result.Score,
+			ReviewResult:  result.Content,
+			EventType:     task.EventType,
+			MRURL:         task.MRURL,
+			Urgent:        policyUrgent(policy, result.Score < minScore || critical != ""),
+			ReviewLogID:   reviewLog.ID,
+		})
+	}
 
 	// Auto-create issues for low-score reviews
 	go s.issueTrackerService.CheckAndCreateIssue(reviewLog, project.Name)
 
-	if project.CommentEnabled {
+	if project.CommentEnabled || task.Requested {
 		comment := s.formatReviewComment(result.Score, result.Content)
 		var commentErr error
+		commentStart := time.Now()
 
 		if task.MRNumber != nil {
 			// Post MR/PR comment for merge request events
@@ -354,22 +632,76 @@ func (s *Service) ProcessReviewTask(ctx context.Context, task *services.ReviewTa
 			}
 		}
 
+		reviewLog.CommentPostMs = services.ObserveReviewStage(services.ReviewStageCommentPost, time.Since(commentStart))
 		if commentErr != nil {
-			logger.Infof("[TaskQueue] Failed to post comment: %v", commentErr)
+			log.Warn().Err(commentErr).Msg("Failed to post comment")
 		} else {
 			reviewLog.CommentPosted = true
 			s.reviewService.Update(reviewLog)
 		}
 	}
 
-	minScore := s.getEffectiveMinScore(project)
 	statusState := "success"
 	statusDesc := fmt.Sprintf("AI Review Passed: %.0f/%.0f", result.Score, minScore)
 	if result.Score < minScore {
 		statusState = "failed"
 		statusDesc = fmt.Sprintf("AI Review Failed: %.0f (Min: %.0f)", result.Score, minScore)
+	} else if critical != "" {
+		statusState = "failed"
+		statusDesc = "AI Review Failed: " + critical
 	}
+	statusState, statusDesc = signatures.Gate(statusState, statusDesc)
+	statusState, statusDesc = s.gateMigrations(reviewLog, migrations, statusState, statusDesc)
 	s.setCommitStatus(project, task.CommitSHA, statusState, statusDesc, task.GitLabProjectID)
 
 	return nil
 }
+
+// finishReviewTiming stamps the end of a review task and saves its stage timings, which
+// the paths ending without a final update would lose
+func (s *Service) finishReviewTiming(reviewLog *models.ReviewLog) {
+	services.FinishReviewTiming(reviewLog)
+	if err := s.db.Model(reviewLog).UpdateColumns(map[string]interface{}{
+		"queued_at":        reviewLog.QueuedAt,
+		"started_at":       reviewLog.StartedAt,
+		"finished_at":      reviewLog.FinishedAt,
+		"queue_wait_ms":    reviewLog.QueueWaitMs,
+		"context_build_ms": reviewLog.ContextBuildMs,
+		"llm_ms":           reviewLog.LLMMs,
+		"comment_post_ms":  reviewLog.CommentPostMs,
+	}).Error; err != nil {
+		logger.Module("task_queue").Warn().Err(err).Uint("review_id", reviewLog.ID).Msg("Failed to save review timings")
+	}
+}
+
+// policyUrgent reports whether the notification of a merge request is sent right away: its
+// target branch policy asks for it, or the review fails the policy
+func policyUrgent(policy *models.TargetBranchPolicy, failed bool) bool {
+	return policy != nil && (policy.Urgent || failed)
+}
+
+// testCoverageFinding returns the number of changed source files without a matching
+// test change and the finding to add to the review, if test coverage nudging is enabled
+func (s *Service) testCoverageFinding(diff string) (int, string) {
+	coverage := s.configService.GetTestCoverageConfig()
+	if !coverage.Enabled {
+		return 0, ""
+	}
+	untested := services.FindUntestedFiles(diff, coverage.Rules)
+	return len(untested), services.FormatUntestedFinding(untested)
+}
+
+// reviewHookTarget describes the reviewed change to review hooks
+func reviewHookTarget(project *models.Project, reviewLog *models.ReviewLog, task *services.ReviewTask) services.ReviewHookTarget {
+	return services.ReviewHookTarget{
+		ReviewLogID: reviewLog.ID,
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+		EventType:   task.EventType,
+		Branch:      task.Branch,
+		CommitSHA:   task.CommitSHA,
+		Author:      task.Author,
+		MRNumber:    task.MRNumber,
+		MRURL:       task.MRURL,
+	}
+}
@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// maxSignatureChecks bounds the commits verified per review; GitHub lists at most 250 commits
+// of a pull request
+const maxSignatureChecks = 250
+
+// signatureCommitsPerPage is the page size of the commit listings of a merge request
+const signatureCommitsPerPage = 100
+
+// checkCommitSignatures verifies the commits of a review task through the platform API,
+// following the signature policy of the project. It returns nil when the policy is off.
+// Commits that could not be checked count as unverified, so an enforced policy fails closed.
+func (s *Service) checkCommitSignatures(ctx context.Context, project *models.Project, task *services.ReviewTask) *services.SignatureCheck {
+	branch := task.Branch
+	if task.TargetBranch != "" {
+		branch = task.TargetBranch
+	}
+	check := services.NewSignatureCheck(project.SignaturePolicy, project.SignedBranches, branch)
+	if check == nil {
+		return nil
+	}
+
+	var commits []verifiedCommit
+	var err error
+	switch project.Platform {
+	case "github":
+		commits, err = s.getGitHubCommitVerification(ctx, project, task)
+	case "gitlab":
+		commits, err = s.getGitLabCommitVerification(ctx, project, task)
+	default:
+		check.Unverified = fmt.Sprintf("%s offers no commit signature verification", project.Platform)
+		return check
+	}
+	if err != nil {
+		logger.For(ctx, "webhook").Warn().Err(err).Uint("project_id", project.ID).Str("commit", task.CommitSHA).Msg("Failed to verify commit signatures")
+		check.Unverified = "the platform API failed: " + err.Error()
+		return check
+	}
+
+	if total := len(task.CommitSHAs); task.MRNumber == nil && total > maxSignatureChecks {
+		check.Unverified = fmt.Sprintf("only the newest %d of %d pushed commits were checked", maxSignatureChecks, total)
+	}
+	if task.MRNumber != nil && len(commits) >= maxSignatureChecks {
+		check.Unverified = fmt.Sprintf("only the first %d commits of the merge request were checked", maxSignatureChecks)
+	}
+	for _, c := range commits {
+		check.Checked++
+		if !c.verified {
+			check.Unsigned = append(check.Unsigned, c.sha)
+		}
+	}
+	return check
+}
+
+// verifiedCommit is the signature verification result of a commit
+type verifiedCommit struct {
+	sha      string
+	verified bool
+}
+
+// pushedCommitSHAs returns the commits of a push task, at most maxSignatureChecks of the newest
+func pushedCommitSHAs(task *services.ReviewTask) []string {
+	shas := task.CommitSHAs
+	if len(shas) == 0 && task.CommitSHA != "" {
+		shas = []string{task.CommitSHA}
+	}
+	if len(shas) > maxSignatureChecks {
+		shas = shas[len(shas)-maxSignatureChecks:]
+	}
+	return shas
+}
+
+type gitHubVerifiedCommit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Verification struct {
+			Verified bool `json:"verified"`
+		} `json:"verification"`
+	} `json:"commit"`
+}
+
+func (s *Service) getGitHubCommitVerification(ctx context.Context, project *models.Project, task *services.ReviewTask) ([]verifiedCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	baseURL := "https://api.github.com"
+	if info.baseURL != "https://github.com" {
+		baseURL = info.baseURL + "/api/v3"
+	}
+	apiBase := fmt.Sprintf("%s/repos/%s/%s", baseURL, info.owner, info.repo)
+	token := ""
+	if project.AccessToken != "" {
+		token = "token " + project.AccessToken
+	}
+
+	var result []verifiedCommit
+	if task.MRNumber != nil {
+		for page := 1; len(result) < maxSignatureChecks; page++ {
+			var commits []gitHubVerifiedCommit
+			apiURL := fmt.Sprintf("%s/pulls/%d/commits?per_page=%d&page=%d", apiBase, *task.MRNumber, signatureCommitsPerPage, page)
+			if err := s.getPlatformJSON(ctx, apiURL, "Authorization", token, &commits); err != nil {
+				return nil, err
+			}
+			for _, c := range commits {
+				result = append(result, verifiedCommit{sha: c.SHA, verified: c.Commit.Verification.Verified})
+			}
+			if len(commits) < signatureCommitsPerPage {
+				break
+			}
+		}
+		return result, nil
+	}
+
+	for _, sha := range pushedCommitSHAs(task) {
+		var commit gitHubVerifiedCommit
+		if err := s.getPlatformJSON(ctx, apiBase+"/commits/"+sha, "Authorization", token, &commit); err != nil {
+			return nil, err
+		}
+		result = append(result, verifiedCommit{sha: sha, verified: commit.Commit.Verification.Verified})
+	}
+	return result, nil
+}
+
+func (s *Service) getGitLabCommitVerification(ctx context.Context, project *models.Project, task *services.ReviewTask) ([]verifiedCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+	apiBase := fmt.Sprintf("%s/api/v4/projects/%s", info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"))
+
+	shas := pushedCommitSHAs(task)
+	if task.MRNumber != nil {
+		shas = nil
+		for page := 1; len(shas) < maxSignatureChecks; page++ {
+			var commits []struct {
+				ID string `json:"id"`
+			}
+			apiURL := fmt.Sprintf("%s/merge_requests/%d/commits?per_page=%d&page=%d", apiBase, *task.MRNumber, signatureCommitsPerPage, page)
+			if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &commits); err != nil {
+				return nil, err
+			}
+			for _, c := range commits {
+				shas = append(shas, c.ID)
+			}
+			if len(commits) < signatureCommitsPerPage {
+				break
+			}
+		}
+	}
+
+	result := make([]verifiedCommit, 0, len(shas))
+	for _, sha := range shas {
+		var signature struct {
+			VerificationStatus string `json:"verification_status"`
+		}
+		err := s.getPlatformJSON(ctx, apiBase+"/repository/commits/"+sha+"/signature", "PRIVATE-TOKEN", project.AccessToken, &signature)
+		var statusErr *platformStatusError
+		switch {
+		case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+			// GitLab answers 404 for commits without a signature
+			result = append(result, verifiedCommit{sha: sha})
+		case err != nil:
+			return nil, err
+		default:
+			result = append(result, verifiedCommit{sha: sha, verified: signature.VerificationStatus == "verified"})
+		}
+	}
+	return result, nil
+}
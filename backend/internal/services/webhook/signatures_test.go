@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+)
+
+// signatureAPI serves a GitLab merge request of n commits, all signed, unless failing
+func signatureAPI(n int, failing bool, requests *[]string) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests = append(*requests, req.URL.RequestURI())
+		respond := func(status int, body string) (*http.Response, error) {
+			return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+		}
+		if failing {
+			return respond(http.StatusBadGateway, "bad gateway")
+		}
+		if strings.HasSuffix(req.URL.Path, "/signature") {
+			return respond(http.StatusOK, `{"verification_status":"verified"}`)
+		}
+		var page int
+		fmt.Sscanf(req.URL.Query().Get("page"), "%d", &page)
+		var ids []string
+		for i := (page - 1) * signatureCommitsPerPage; i < n && i < page*signatureCommitsPerPage; i++ {
+			ids = append(ids, fmt.Sprintf(`{"id":"sha%d"}`, i))
+		}
+		return respond(http.StatusOK, "["+strings.Join(ids, ",")+"]")
+	})}
+}
+
+func TestCheckCommitSignatures_Pagination(t *testing.T) {
+	var requests []string
+	s := &Service{httpClient: signatureAPI(120, false, &requests)}
+	project := &models.Project{Platform: "gitlab", URL: "https://gitlab.example.com/group/repo", SignaturePolicy: services.SignaturePolicyEnforce}
+	mr := 7
+	check := s.checkCommitSignatures(context.Background(), project, &services.ReviewTask{MRNumber: &mr, TargetBranch: "main"})
+
+	if check.Checked != 120 || len(check.Unsigned) != 0 || check.Unverified != "" {
+		t.Errorf("check = %+v, want all 120 commits of both pages verified", check)
+	}
+	if state, _ := check.Gate("success", "passed"); state != "success" {
+		t.Errorf("Gate() = %q, want success", state)
+	}
+}
+
+func TestCheckCommitSignatures_FailClosed(t *testing.T) {
+	var requests []string
+	s := &Service{httpClient: signatureAPI(1, true, &requests)}
+	mr := 7
+	task := &services.ReviewTask{MRNumber: &mr, TargetBranch: "main"}
+
+	for _, project := range []*models.Project{
+		{Platform: "gitlab", URL: "https://gitlab.example.com/group/repo", SignaturePolicy: services.SignaturePolicyEnforce},
+		{Platform: "bitbucket", URL: "https://bitbucket.org/team/repo", SignaturePolicy: services.SignaturePolicyEnforce},
+	} {
+		check := s.checkCommitSignatures(context.Background(), project, task)
+		if check == nil || check.Unverified == "" {
+			t.Fatalf("%s: check = %+v, want the commits unverified", project.Platform, check)
+		}
+		if state, _ := check.Gate("success", "passed"); state != "failed" {
+			t.Errorf("%s: Gate() = %q, want failed", project.Platform, state)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+)
+
+// SimulateReviewRequest is a diff to run through a project's review pipeline
+type SimulateReviewRequest struct {
+	Diff          string `json:"diff" binding:"required"` // Unified diff, e.g. the output of git diff
+	CommitMessage string `json:"commit_message"`
+	Author        string `json:"author"`
+	Branch        string `json:"branch"`
+	EventType     string `json:"event_type"` // push (default) or merge_request; selects bound review templates
+	CommitSHA     string `json:"commit_sha"` // Commit the file context is read at, none without it
+	SkipLLM       bool   `json:"skip_llm"`   // Stop after building the prompt
+}
+
+// SimulatedLLMCall is the LLM step of a simulated review
+type SimulatedLLMCall struct {
+	LLMConfigID      uint   `json:"llm_config_id"`
+	Content          string `json:"content"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	LatencyMs        int64  `json:"latency_ms"`
+	Error            string `json:"error,omitempty"`
+}
+
+// SimulateReviewResponse holds the artifacts of each step of a simulated review. A step is
+// empty when the pipeline stopped before it, SkipReason tells why.
+type SimulateReviewResponse struct {
+	BranchReviewed bool                         `json:"branch_reviewed"`
+	Files          []services.PathPatternResult `json:"files"` // Path filter decision for each file of the diff
+	FilteredDiff   string                       `json:"filtered_diff"`
+	DroppedFiles   []string                     `json:"dropped_files"` // Files over the per-file size limit
+	SkipReason     string                       `json:"skip_reason,omitempty"`
+	CacheHit       bool                         `json:"cache_hit"` // A real review would reuse the cached review of the same diff
+	Findings       string                       `json:"findings"`
+	FileContext    string                       `json:"file_context"`
+	Prompt         string                       `json:"prompt"`
+	LLM            *SimulatedLLMCall            `json:"llm,omitempty"`
+	Score          *float64                     `json:"score,omitempty"`
+	ScorePattern   string                       `json:"score_pattern,omitempty"` // Pattern the score was extracted with, empty if none matched
+	MinScore       float64                      `json:"min_score"`
+	Passed         *bool                        `json:"passed,omitempty"`
+	Warnings       []string                     `json:"warnings"`
+}
+
+// SimulateReview runs a diff through the project's review pipeline — branch and path
+// filters, size limits, findings, file context, prompt, LLM and score extraction — and
+// returns the artifact of each step. Nothing is recorded and no comment, commit status or
+// notification is sent; pre-review hooks don't run and the diff is reviewed in one call.
+func (s *Service) SimulateReview(ctx context.Context, project *models.Project, req *SimulateReviewRequest) *SimulateReviewResponse {
+	if req.EventType == "" {
+		req.EventType = "push"
+	}
+	resp := &SimulateReviewResponse{
+		BranchReviewed: req.Branch == "" || !s.isBranchIgnored(req.Branch, project),
+		DroppedFiles:   []string{},
+		MinScore:       s.getEffectiveMinScore(project),
+		Warnings:       []string{},
+	}
+	if !resp.BranchReviewed {
+		resp.Warnings = append(resp.Warnings, fmt.Sprintf("branch %s is excluded by the branch filter, a real review would not run", req.Branch))
+	}
+
+	filteredDiff, files := filterDiffExplained(req.Diff, services.NewProjectPathFilter(project))
+	resp.Files = files
+	if resp.Files == nil {
+		resp.Files = []services.PathPatternResult{}
+		resp.Warnings = append(resp.Warnings, "no file header (--- a/path) found, the diff is not a unified diff")
+	}
+	if IsEmptyDiff(filteredDiff) {
+		resp.SkipReason = "empty diff, no code changes to review"
+		if !anyReviewed(files) && len(files) > 0 {
+			resp.SkipReason = "no file passes the path filter"
+		}
+		return resp
+	}
+
+	limits := services.DiffLimitsOf(project)
+	filteredDiff, resp.DroppedFiles = limits.DropOversizedFiles(filteredDiff)
+	if resp.DroppedFiles == nil {
+		resp.DroppedFiles = []string{}
+	}
+	resp.FilteredDiff = filteredDiff
+	reason := limits.Exceeded(filteredDiff)
+	if reason == "" && IsEmptyDiff(filteredDiff) {
+		reason = fmt.Sprintf("all %d files exceed the per-file limit of %d bytes", len(resp.DroppedFiles), limits.MaxFileBytes)
+	}
+	if reason != "" {
+		resp.SkipReason = "change too large: " + reason
+		return resp
+	}
+
+	_, findings := s.testCoverageFinding(filteredDiff)
+	migrations := services.NewMigrationCheck(project.MigrationPolicy, services.ParseDiffToFiles(filteredDiff))
+	if finding := migrations.Finding(); finding != "" {
+		findings = strings.TrimPrefix(findings+"\n\n"+finding, "\n\n")
+	}
+	resp.Findings = findings
+	resp.CacheHit = s.reviewCacheService.FindCachedReview(project.ID, services.ComputeDiffHash(filteredDiff)) != nil
+
+	if s.fileContextService.IsEnabled() && req.CommitSHA != "" {
+		fileContext, err := s.fileContextService.BuildFileContext(project, filteredDiff, req.CommitSHA)
+		if err != nil {
+			resp.Warnings = append(resp.Warnings, "file context: "+err.Error())
+		}
+		resp.FileContext = fileContext
+	}
+
+	reviewReq := &services.ReviewRequest{
+		ProjectID:   project.ID,
+		Diffs:       filteredDiff,
+		Commits:     req.CommitMessage,
+		FileContext: resp.FileContext,
+		Findings:    findings,
+		Author:      req.Author,
+		EventType:   req.EventType,
+		Branch:      req.Branch,
+		Simulation:  true,
+	}
+	resp.Prompt = s.aiService.BuildPrompt(project, reviewReq)
+	if req.SkipLLM {
+		return resp
+	}
+
+	start := time.Now()
+	result, err := s.aiService.Review(ctx, reviewReq)
+	resp.LLM = &SimulatedLLMCall{LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		resp.LLM.Error = err.Error()
+		return resp
+	}
+	resp.LLM.LLMConfigID = result.LLMConfigID
+	resp.LLM.Content = result.Content
+	resp.LLM.PromptTokens = result.PromptTokens
+	resp.LLM.CompletionTokens = result.CompletionTokens
+
+	if result.ScoreMissing {
+		resp.Warnings = append(resp.Warnings, "no score found in the LLM response, a real review would need attention")
+		return resp
+	}
+	score, pattern := services.ExplainScore(result.Content)
+	if score != result.Score || pattern == "" {
+		// Structured reviews carry the score in their JSON
+		score, pattern = result.Score, "structured"
+	}
+	passed := score >= resp.MinScore
+	resp.Score, resp.ScorePattern, resp.Passed = &score, pattern, &passed
+	return resp
+}
+
+func anyReviewed(files []services.PathPatternResult) bool {
+	for _, f := range files {
+		if f.Reviewed {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+// skipByDirective records a review the author skipped with a directive, keeping who skipped
+// it and how in the review and the system log, and passes the commit status. branch is the
+// target branch for merge requests. It returns false, and the change is reviewed, when there
+// is no directive or the project doesn't honor it on the branch.
+func (s *Service) skipByDirective(ctx context.Context, project *models.Project, reviewLog *models.ReviewLog, branch, directive string, gitlabProjectID int) bool {
+	if directive == "" {
+		return false
+	}
+	log := logger.For(ctx, "webhook").With().Uint("project_id", project.ID).Str("branch", branch).Str("commit", reviewLog.CommitHash).Str("directive", directive).Logger()
+	if !services.SkipDirectiveAllowed(project, branch) {
+		log.Info().Msg("Skip directive not honored for this project or branch, reviewing")
+		return false
+	}
+
+	reviewLog.ProjectID = project.ID
+	reviewLog.ReviewStatus = services.ReviewStatusSkippedByDirective
+	reviewLog.ReviewResult = fmt.Sprintf("Review skipped by %s, requested by %s", directive, reviewLog.Author)
+	if err := s.createReviewLog(ctx, reviewLog); err != nil {
+		log.Error().Err(err).Msg("Failed to record review skipped by directive")
+		return true
+	}
+	log.Info().Uint("review_id", reviewLog.ID).Msg("Review skipped by directive")
+	services.PublishReviewEvent(reviewLog.ID, reviewLog.ProjectID, reviewLog.CommitHash, services.ReviewStatusSkippedByDirective, nil, reviewLog.ReviewResult)
+	services.LogInfo(ctx, "Webhook", "ReviewSkippedByDirective", fmt.Sprintf("%s skipped the review of %s with %s", reviewLog.Author, shortSHA(reviewLog.CommitHash), directive), nil, "", "", map[string]interface{}{
+		"project_id":    project.ID,
+		"review_log_id": reviewLog.ID,
+		"commit":        reviewLog.CommitHash,
+		"branch":        branch,
+		"author":        reviewLog.Author,
+		"directive":     directive,
+	})
+	s.setCommitStatus(project, reviewLog.CommitHash, "success", "AI Review skipped by "+directive, gitlabProjectID)
+	return true
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSyncReview_DropsOversizedFiles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	previous := models.DB
+	models.DB = db
+	t.Cleanup(func() { models.DB = previous })
+	if err := models.AutoMigrate(); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	var prompt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		prompt = req.Messages[len(req.Messages)-1].Content
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Looks fine.\n\nTotal Score: 90/100"}}},
+		})
+	}))
+	defer srv.Close()
+	project := &models.Project{Name: "p", URL: "https://gitlab.example.com/g/p", Platform: "gitlab", MaxFileBytes: 500}
+	for _, record := range []interface{}{project, &models.LLMConfig{Name: "llm", Provider: "openai", BaseURL: srv.URL, Model: "test", IsActive: true, IsDefault: true}} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	small := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-a\n+b\n"
+	bundle := "diff --git a/dist/bundle.js b/dist/bundle.js\n--- a/dist/bundle.js\n+++ b/dist/bundle.js\n@@ -1 +1 @@\n+" + strings.Repeat("x", 1000) + "\n"
+	s := NewService(db, nil)
+
+	resp, err := s.SyncReview(context.Background(), project, &SyncReviewRequest{CommitSHA: "aaaa1111", Ref: "refs/heads/main", Diffs: bundle})
+	if err != nil {
+		t.Fatalf("SyncReview() error = %v", err)
+	}
+	if !resp.Passed || !strings.Contains(resp.Message, "all 1 files exceed the per-file limit") {
+		t.Errorf("oversized-only review = %+v, want skipped as too large", resp)
+	}
+
+	resp, err = s.SyncReview(context.Background(), project, &SyncReviewRequest{CommitSHA: "bbbb2222", Ref: "refs/heads/main", Diffs: small + bundle})
+	if err != nil {
+		t.Fatalf("SyncReview() error = %v", err)
+	}
+	if strings.Contains(prompt, "dist/bundle.js") || !strings.Contains(prompt, "main.go") {
+		t.Error("the oversized file was sent to the LLM")
+	}
+	if !strings.Contains(resp.FullContent, "Not reviewed (file diff larger than 500 bytes): dist/bundle.js") {
+		t.Errorf("review %q doesn't list the dropped file", resp.FullContent)
+	}
+}
@@ -0,0 +1,258 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
+	"github.com/huangang/codesentry/backend/pkg/logger"
+)
+
+var (
+	// ErrInvalidReviewTarget is returned when a triggered review names neither a commit nor a complete range
+	ErrInvalidReviewTarget = errors.New("either commit_sha or both from_ref and to_ref are required")
+	// ErrTriggerNoChanges is returned when the commit or range has no changes left to review
+	ErrTriggerNoChanges = errors.New("no changes to review")
+)
+
+// TriggerReviewRequest names the commit or the range of a review triggered through the API
+type TriggerReviewRequest struct {
+	CommitSHA string `json:"commit_sha"`
+	FromRef   string `json:"from_ref"` // Base of the range, e.g. a tag, branch or SHA
+	ToRef     string `json:"to_ref"`   // Head of the range
+	Branch    string `json:"branch"`   // Branch the review is recorded on, defaults to to_ref (or the commit SHA)
+}
+
+// Validate checks the request names exactly one commit or one range
+func (r *TriggerReviewRequest) Validate() error {
+	r.CommitSHA = strings.TrimSpace(r.CommitSHA)
+	r.FromRef = strings.TrimSpace(r.FromRef)
+	r.ToRef = strings.TrimSpace(r.ToRef)
+	r.Branch = strings.TrimSpace(r.Branch)
+	isCommit := r.CommitSHA != ""
+	isRange := r.FromRef != "" && r.ToRef != ""
+	if isCommit == isRange || (isCommit && (r.FromRef != "" || r.ToRef != "")) {
+		return ErrInvalidReviewTarget
+	}
+	return nil
+}
+
+// refCommit is the commit a ref resolves to
+type refCommit struct {
+	SHA         string
+	Author      string
+	AuthorEmail string
+	Message     string
+	URL         string
+}
+
+// TriggerReview fetches the diff of a commit or range from the project's platform and
+// queues a standard review of it, recorded as a push to the branch of the request
+func (s *Service) TriggerReview(ctx context.Context, project *models.Project, req *TriggerReviewRequest, requestedBy string) (*models.ReviewLog, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if project.URL == "" || project.AccessToken == "" {
+		return nil, fmt.Errorf("project URL or access token not configured")
+	}
+
+	head := req.CommitSHA
+	if head == "" {
+		head = req.ToRef
+	}
+	commit, err := s.getRefCommit(ctx, project, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", head, err)
+	}
+
+	var diff string
+	fetchStart := time.Now()
+	if req.CommitSHA != "" {
+		diff, err = s.getCommitDiff(ctx, project, commit.SHA)
+	} else {
+		diff, err = s.getCompareDiff(project, req.FromRef, commit.SHA)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %w", err)
+	}
+	if IsEmptyDiff(diff) {
+		return nil, ErrTriggerNoChanges
+	}
+
+	branch := req.Branch
+	if branch == "" {
+		branch = head
+	}
+	commitMessage := commit.Message
+	if req.CommitSHA == "" {
+		commitMessage = fmt.Sprintf("Changes from %s to %s\n%s", req.FromRef, req.ToRef, commit.Message)
+	}
+
+	additions, deletions, filesChanged := ParseDiffStats(diff)
+	reviewLog := &models.ReviewLog{
+		ProjectID:     project.ID,
+		EventType:     "push",
+		CommitHash:    commit.SHA,
+		CommitURL:     commit.URL,
+		Branch:        branch,
+		Author:        commit.Author,
+		AuthorEmail:   commit.AuthorEmail,
+		CommitMessage: commitMessage,
+		FilesChanged:  filesChanged,
+		Additions:     additions,
+		Deletions:     deletions,
+		DiffFetchMs:   diffFetchMs(fetchStart),
+		ReviewStatus:  "pending",
+	}
+	if err := s.createReviewLog(ctx, reviewLog); err != nil {
+		return nil, err
+	}
+	s.setCommitStatus(project, commit.SHA, "pending", "AI Review in progress...", 0)
+
+	task := &services.ReviewTask{
+		ReviewLogID:   reviewLog.ID,
+		RequestID:     logger.RequestID(ctx),
+		ProjectID:     project.ID,
+		CommitSHA:     commit.SHA,
+		EventType:     "push",
+		Branch:        branch,
+		Author:        commit.Author,
+		AuthorEmail:   commit.AuthorEmail,
+		CommitMessage: commitMessage,
+		Diff:          diff,
+		CommitURL:     commit.URL,
+	}
+	if err := services.GetTaskQueue().Enqueue(task); err != nil {
+		logger.For(ctx, "webhook").Error().Err(err).Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Msg("Failed to enqueue triggered review task")
+		reviewLog.ReviewStatus = "failed"
+		reviewLog.ErrorMessage = "Failed to enqueue: " + err.Error()
+		s.reviewService.Update(reviewLog)
+		s.releaseEvent(reviewLog)
+		return nil, err
+	}
+
+	logger.For(ctx, "webhook").Info().Uint("project_id", project.ID).Uint("review_id", reviewLog.ID).Str("commit", commit.SHA).
+		Str("from", req.FromRef).Str("to", req.ToRef).Str("requested_by", requestedBy).Msg("Review triggered")
+	return reviewLog, nil
+}
+
+// getCommitDiff fetches the diff of a single commit
+func (s *Service) getCommitDiff(ctx context.Context, project *models.Project, sha string) (string, error) {
+	switch project.Platform {
+	case "gitlab":
+		return s.getGitLabDiff(ctx, project, sha)
+	case "github":
+		return s.getGitHubDiff(project, sha)
+	case "bitbucket":
+		return s.getBitbucketDiff(ctx, project, sha)
+	}
+	return "", fmt.Errorf("unsupported platform: %s", project.Platform)
+}
+
+// getCompareDiff fetches the changes between two refs
+func (s *Service) getCompareDiff(project *models.Project, from, to string) (string, error) {
+	switch project.Platform {
+	case "gitlab":
+		return s.getGitLabCompareDiff(project, from, to)
+	case "github":
+		return s.getGitHubCompareDiff(project, from, to)
+	case "bitbucket":
+		return s.getBitbucketCompareDiff(project, from, to)
+	}
+	return "", fmt.Errorf("unsupported platform: %s", project.Platform)
+}
+
+// getRefCommit resolves a SHA, branch or tag to its commit
+func (s *Service) getRefCommit(ctx context.Context, project *models.Project, ref string) (*refCommit, error) {
+	info, err := parseRepoInfo(project.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch project.Platform {
+	case "gitlab":
+		var commit struct {
+			ID          string `json:"id"`
+			AuthorName  string `json:"author_name"`
+			AuthorEmail string `json:"author_email"`
+			Message     string `json:"message"`
+			WebURL      string `json:"web_url"`
+		}
+		apiURL := fmt.Sprintf("%s/api/v4/projects/%s/repository/commits/%s",
+			info.baseURL, strings.ReplaceAll(info.projectPath, "/", "%2F"), url.PathEscape(ref))
+		if err := s.getPlatformJSON(ctx, apiURL, "PRIVATE-TOKEN", project.AccessToken, &commit); err != nil {
+			return nil, err
+		}
+		return &refCommit{SHA: commit.ID, Author: commit.AuthorName, AuthorEmail: commit.AuthorEmail, Message: commit.Message, URL: commit.WebURL}, nil
+
+	case "github":
+		baseURL := "https://api.github.com"
+		if info.baseURL != "https://github.com" {
+			baseURL = info.baseURL + "/api/v3"
+		}
+		var commit struct {
+			SHA    string `json:"sha"`
+			Commit struct {
+				Author struct {
+					Name  string `json:"name"`
+					Email string `json:"email"`
+				} `json:"author"`
+				Message string `json:"message"`
+			} `json:"commit"`
+			HTMLURL string `json:"html_url"`
+		}
+		apiURL := fmt.Sprintf("%s/repos/%s/%s/commits/%s", baseURL, info.owner, info.repo, url.PathEscape(ref))
+		if err := s.getPlatformJSON(ctx, apiURL, "Authorization", "token "+project.AccessToken, &commit); err != nil {
+			return nil, err
+		}
+		return &refCommit{SHA: commit.SHA, Author: commit.Commit.Author.Name, AuthorEmail: commit.Commit.Author.Email, Message: commit.Commit.Message, URL: commit.HTMLURL}, nil
+
+	case "bitbucket":
+		api, err := s.bitbucketAPI(project)
+		if err != nil {
+			return nil, err
+		}
+		auth, err := api.Authorization(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var commit struct {
+			Hash   string `json:"hash"`
+			ID     string `json:"id"` // Bitbucket Server
+			Author struct {
+				Raw          string `json:"raw"` // "Name <email>"
+				Name         string `json:"name"`
+				EmailAddress string `json:"emailAddress"`
+			} `json:"author"`
+			Message string `json:"message"`
+			Links   struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+		}
+		if err := s.getPlatformJSON(ctx, api.CommitURL(ref), "Authorization", auth, &commit); err != nil {
+			return nil, err
+		}
+		if api.Server {
+			return &refCommit{SHA: commit.ID, Author: commit.Author.Name, AuthorEmail: commit.Author.EmailAddress, Message: commit.Message, URL: api.CommitWebURL(commit.ID)}, nil
+		}
+		name, email := parseGitIdentity(commit.Author.Raw)
+		return &refCommit{SHA: commit.Hash, Author: name, AuthorEmail: email, Message: commit.Message, URL: commit.Links.HTML.Href}, nil
+	}
+	return nil, fmt.Errorf("unsupported platform: %s", project.Platform)
+}
+
+// parseGitIdentity splits "Name <email>" into its name and email
+func parseGitIdentity(raw string) (string, string) {
+	open, end := strings.LastIndex(raw, "<"), strings.LastIndex(raw, ">")
+	if open < 0 || end < open {
+		return strings.TrimSpace(raw), ""
+	}
+	return strings.TrimSpace(raw[:open]), strings.TrimSpace(raw[open+1 : end])
+}
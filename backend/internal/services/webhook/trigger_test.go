@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTriggerReviewRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     TriggerReviewRequest
+		wantErr bool
+	}{
+		{"commit", TriggerReviewRequest{CommitSHA: " abc123 "}, false},
+		{"range", TriggerReviewRequest{FromRef: "v1.0.0", ToRef: "main"}, false},
+		{"empty", TriggerReviewRequest{}, true},
+		{"blank commit", TriggerReviewRequest{CommitSHA: "  "}, true},
+		{"half range", TriggerReviewRequest{FromRef: "v1.0.0"}, true},
+		{"commit and range", TriggerReviewRequest{CommitSHA: "abc123", FromRef: "v1.0.0", ToRef: "main"}, true},
+		{"commit and from", TriggerReviewRequest{CommitSHA: "abc123", FromRef: "v1.0.0"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidReviewTarget) {
+				t.Errorf("Validate() error = %v, want ErrInvalidReviewTarget", err)
+			}
+		})
+	}
+
+	req := TriggerReviewRequest{CommitSHA: " abc123 "}
+	req.Validate()
+	if req.CommitSHA != "abc123" {
+		t.Errorf("Validate() should trim commit_sha, got %q", req.CommitSHA)
+	}
+}
+
+func TestParseGitIdentity(t *testing.T) {
+	tests := []struct {
+		raw       string
+		wantName  string
+		wantEmail string
+	}{
+		{"Jane Doe <jane@example.com>", "Jane Doe", "jane@example.com"},
+		{"jane", "jane", ""},
+		{"<jane@example.com>", "", "jane@example.com"},
+		{"Broken <jane@example.com", "Broken <jane@example.com", ""},
+	}
+
+	for _, tt := range tests {
+		name, email := parseGitIdentity(tt.raw)
+		if name != tt.wantName || email != tt.wantEmail {
+			t.Errorf("parseGitIdentity(%q) = %q, %q, want %q, %q", tt.raw, name, email, tt.wantName, tt.wantEmail)
+		}
+	}
+}
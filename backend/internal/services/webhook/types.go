@@ -13,10 +13,11 @@ type GitLabPushEvent struct {
 	UserAvatar  string `json:"user_avatar"`
 	ProjectID   int    `json:"project_id"`
 	Project     struct {
-		Name      string `json:"name"`
-		URL       string `json:"url"`
-		WebURL    string `json:"web_url"`
-		Namespace string `json:"namespace"`
+		Name          string `json:"name"`
+		URL           string `json:"url"`
+		WebURL        string `json:"web_url"`
+		Namespace     string `json:"namespace"`
+		DefaultBranch string `json:"default_branch"`
 	} `json:"project"`
 	Commits []struct {
 		ID        string `json:"id"`
@@ -31,7 +32,8 @@ type GitLabPushEvent struct {
 		Modified []string `json:"modified"`
 		Removed  []string `json:"removed"`
 	} `json:"commits"`
-	TotalCommitsCount int `json:"total_commits_count"`
+	TotalCommitsCount int                    `json:"total_commits_count"`
+	PushOptions       map[string]interface{} `json:"push_options"` // git push -o options, e.g. {"codesentry": {"skip": true}}
 }
 
 // GitLabMREvent represents a GitLab merge request webhook event
@@ -62,6 +64,37 @@ type GitLabMREvent struct {
 	} `json:"object_attributes"`
 }
 
+// GitLabNoteEvent represents a GitLab comment ("Note Hook") webhook event
+type GitLabNoteEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID        int    `json:"id"`
+		Name      string `json:"name"`
+		Username  string `json:"username"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	} `json:"user"`
+	Project struct {
+		ID     int    `json:"id"`
+		WebURL string `json:"web_url"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		ID           int    `json:"id"`
+		Note         string `json:"note"`
+		NoteableType string `json:"noteable_type"` // MergeRequest, Commit, Issue, Snippet
+		URL          string `json:"url"`
+	} `json:"object_attributes"`
+	MergeRequest *struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		SourceBranch string `json:"source_branch"`
+		TargetBranch string `json:"target_branch"`
+		State        string `json:"state"`
+		URL          string `json:"url"`
+	} `json:"merge_request"`
+}
+
 // GitHubPushEvent represents a GitHub push webhook event
 type GitHubPushEvent struct {
 	Ref    string `json:"ref"`
@@ -77,10 +110,11 @@ type GitHubPushEvent struct {
 		HTMLURL   string `json:"html_url"`
 	} `json:"sender"`
 	Repository struct {
-		ID       int    `json:"id"`
-		Name     string `json:"name"`
-		FullName string `json:"full_name"`
-		URL      string `json:"url"`
+		ID            int    `json:"id"`
+		Name          string `json:"name"`
+		FullName      string `json:"full_name"`
+		URL           string `json:"url"`
+		DefaultBranch string `json:"default_branch"`
 	} `json:"repository"`
 	Commits []struct {
 		ID        string `json:"id"`
@@ -98,6 +132,22 @@ type GitHubPushEvent struct {
 	} `json:"commits"`
 }
 
+// GitHubReleaseEvent represents a GitHub release webhook event
+type GitHubReleaseEvent struct {
+	Action  string `json:"action"`
+	Release struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		HTMLURL    string `json:"html_url"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+		Author     struct {
+			Login     string `json:"login"`
+			AvatarURL string `json:"avatar_url"`
+		} `json:"author"`
+	} `json:"release"`
+}
+
 // GitHubPREvent represents a GitHub pull request webhook event
 type GitHubPREvent struct {
 	Action      string `json:"action"`
@@ -273,6 +323,54 @@ type BitbucketPREvent struct {
 	} `json:"actor"`
 }
 
+// BitbucketServerUser is a user of Bitbucket Server / Data Center
+type BitbucketServerUser struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+// BitbucketServerPushEvent represents a Bitbucket Server repo:refs_changed webhook event
+type BitbucketServerPushEvent struct {
+	Actor   BitbucketServerUser `json:"actor"`
+	Changes []struct {
+		Ref struct {
+			ID        string `json:"id"`
+			DisplayID string `json:"displayId"`
+			Type      string `json:"type"` // BRANCH or TAG
+		} `json:"ref"`
+		FromHash string `json:"fromHash"`
+		ToHash   string `json:"toHash"`
+		Type     string `json:"type"` // ADD, UPDATE or DELETE
+	} `json:"changes"`
+}
+
+// BitbucketServerPREvent represents a Bitbucket Server pr:opened / pr:from_ref_updated webhook event
+type BitbucketServerPREvent struct {
+	Actor       BitbucketServerUser `json:"actor"`
+	PullRequest struct {
+		ID          int    `json:"id"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		State       string `json:"state"`
+		FromRef     struct {
+			DisplayID    string `json:"displayId"`
+			LatestCommit string `json:"latestCommit"`
+		} `json:"fromRef"`
+		ToRef struct {
+			DisplayID string `json:"displayId"`
+		} `json:"toRef"`
+		Author struct {
+			User BitbucketServerUser `json:"user"`
+		} `json:"author"`
+		Links struct {
+			Self []struct {
+				Href string `json:"href"`
+			} `json:"self"`
+		} `json:"links"`
+	} `json:"pullRequest"`
+}
+
 // ReviewScoreResponse represents the response for commit score queries
 type ReviewScoreResponse struct {
 	CommitSHA string   `json:"commit_sha"`
@@ -284,6 +382,14 @@ type ReviewScoreResponse struct {
 	Message   string   `json:"message"`
 }
 
+// PollStatusResponse represents the outcome of a webhook received in wait mode
+type PollStatusResponse struct {
+	ReviewScoreResponse
+	Token     string `json:"token"`
+	ProjectID uint   `json:"project_id"`
+	Done      bool   `json:"done"`
+}
+
 // SyncReviewRequest represents a synchronous review request
 type SyncReviewRequest struct {
 	ProjectURL string
@@ -308,6 +308,38 @@ func TestBitbucketPREvent_Parse(t *testing.T) {
 	}
 }
 
+func TestBitbucketServerPREvent_Convert(t *testing.T) {
+	jsonData := `{
+		"eventKey": "pr:opened",
+		"actor": {"name": "alice", "displayName": "Alice"},
+		"pullRequest": {
+			"id": 7,
+			"title": "Feature PR",
+			"state": "OPEN",
+			"fromRef": {"id": "refs/heads/feature", "displayId": "feature", "latestCommit": "sourcehash"},
+			"toRef": {"id": "refs/heads/main", "displayId": "main"},
+			"author": {"user": {"name": "bob", "displayName": ""}},
+			"links": {"self": [{"href": "https://git.example.com/projects/PRJ/repos/repo/pull-requests/7"}]}
+		}
+	}`
+
+	var event BitbucketServerPREvent
+	if err := json.Unmarshal([]byte(jsonData), &event); err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	pr := fromBitbucketServerPR(&event).PullRequest
+
+	if pr.ID != 7 || pr.Source.Branch.Name != "feature" || pr.Source.Commit.Hash != "sourcehash" || pr.Destination.Branch.Name != "main" {
+		t.Errorf("converted pull request = %+v", pr)
+	}
+	if pr.Author.DisplayName != "bob" {
+		t.Errorf("Author.DisplayName = %q, expected the user name when there is no display name", pr.Author.DisplayName)
+	}
+	if pr.Links.HTML.Href != "https://git.example.com/projects/PRJ/repos/repo/pull-requests/7" {
+		t.Errorf("Links.HTML.Href = %q", pr.Links.HTML.Href)
+	}
+}
+
 func TestSyncReviewRequest_Structure(t *testing.T) {
 	req := SyncReviewRequest{
 		ProjectURL: "https://github.com/org/repo",
@@ -1,6 +1,7 @@
 package webhook
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -9,18 +10,14 @@ import (
 	"github.com/huangang/codesentry/backend/pkg/logger"
 	"io"
 	"net/http"
-	"path/filepath"
 	"strings"
 
 	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
 )
 
 // DefaultIgnorePatterns - files that should be skipped by default (config, lock, generated files)
-const DefaultIgnorePatterns = "*.json,*.yaml,*.yml,*.toml,*.xml,*.ini,*.env,*.config," +
-	"*.lock,package-lock.json,yarn.lock,pnpm-lock.yaml,go.sum,Cargo.lock,composer.lock,Gemfile.lock,poetry.lock," +
-	"*.min.js,*.min.css,*.bundle.js,*.bundle.css," +
-	"dist/,build/,out/,target/,.next/," +
-	"vendor/,node_modules/,__pycache__/,.venv/,venv/"
+const DefaultIgnorePatterns = services.DefaultIgnorePatterns
 
 type repoInfo struct {
 	owner       string
@@ -84,134 +81,103 @@ func IsEmptyDiff(diff string) bool {
 	return true
 }
 
-func (s *Service) isBranchIgnored(branch string, branchFilter string) bool {
-	if branchFilter == "" {
-		return false
-	}
+// isBranchIgnored reports whether the project's branch filter excludes branch from review
+func (s *Service) isBranchIgnored(branch string, project *models.Project) bool {
+	return !services.ShouldReviewBranch(project, branch)
+}
 
-	for _, pattern := range strings.Split(branchFilter, ",") {
-		pattern = strings.TrimSpace(pattern)
-		if pattern == "" {
-			continue
-		}
+// isMergeRequestIgnored reports whether the project's branch filter excludes a merge request
+// from source into target from review, see services.ShouldReviewMergeRequest
+func (s *Service) isMergeRequestIgnored(source, target string, project *models.Project) bool {
+	return !services.ShouldReviewMergeRequest(project, source, target)
+}
 
-		if strings.HasSuffix(pattern, "*") {
-			prefix := strings.TrimSuffix(pattern, "*")
-			if strings.HasPrefix(branch, prefix) {
-				return true
-			}
-		} else if pattern == branch {
-			return true
-		}
-	}
-	return false
+// filterDiff keeps the files of the diff the project's path filter reviews: its file
+// extensions and include patterns, minus the default and project ignore patterns
+func (s *Service) filterDiff(diff string, project *models.Project) string {
+	filtered, _ := filterDiffExplained(diff, services.NewProjectPathFilter(project))
+	return filtered
 }
 
-func (s *Service) filterDiff(diff string, extensions string, ignorePatterns string) string {
-	extMap := make(map[string]bool)
-	if extensions != "" {
-		for _, ext := range strings.Split(extensions, ",") {
-			ext = strings.TrimSpace(ext)
-			if ext != "" {
-				if !strings.HasPrefix(ext, ".") {
-					ext = "." + ext
+// filterDiffExplained is filterDiff with the decision of the path filter for each file. Files
+// are matched on their new path, or their old path when deleted. A diff whose files all fail
+// the filter filters to nothing; only text without file headers is returned unchanged.
+func filterDiffExplained(diff string, filter *services.PathFilter) (string, []services.PathPatternResult) {
+	lines := strings.Split(diff, "\n")
+	var result strings.Builder
+	var files []services.PathPatternResult
+	var section []string // Lines of the current file
+	var sectionPath string
+	inHeader := false // Between a "diff --git" line and the file's ---/+++ lines
+
+	flush := func() {
+		if sectionPath == "" {
+			// Text outside a file, e.g. the commit separators of a push diff
+			for _, l := range section {
+				result.WriteString(l + "\n")
+			}
+		} else {
+			reason, pattern := filter.Explain(sectionPath)
+			files = append(files, services.PathPatternResult{Path: sectionPath, Reviewed: reason == "", Reason: reason, Pattern: pattern})
+			if reason == "" {
+				for _, l := range section {
+					result.WriteString(l + "\n")
 				}
-				extMap[strings.ToLower(ext)] = true
 			}
 		}
-	}
-
-	ignoreSet := make(map[string]bool)
-	for _, pattern := range strings.Split(DefaultIgnorePatterns, ",") {
-		pattern = strings.TrimSpace(pattern)
-		if pattern != "" {
-			ignoreSet[pattern] = true
-		}
-	}
-	if ignorePatterns != "" {
-		for _, pattern := range strings.Split(ignorePatterns, ",") {
-			pattern = strings.TrimSpace(pattern)
-			if pattern != "" {
-				ignoreSet[pattern] = true
+		section, sectionPath = nil, ""
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			section = append(section, line)
+			// Binary files and pure renames have no ---/+++ lines
+			if j := strings.LastIndex(line, " b/"); j >= 0 {
+				sectionPath = line[j+3:]
 			}
-		}
-	}
-
-	var ignoreList []string
-	for pattern := range ignoreSet {
-		ignoreList = append(ignoreList, pattern)
-	}
-
-	if len(extMap) == 0 && len(ignoreList) == 0 {
-		return diff
-	}
-
-	lines := strings.Split(diff, "\n")
-	var result strings.Builder
-	var include bool
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ ") {
-			filePath := strings.TrimPrefix(strings.TrimPrefix(line, "--- "), "+++ ")
-			filePath = strings.TrimPrefix(filePath, "a/")
-			filePath = strings.TrimPrefix(filePath, "b/")
-
-			if strings.HasPrefix(line, "--- ") {
-				include = s.shouldIncludeFile(filePath, extMap, ignoreList)
+			inHeader = true
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if !inHeader {
+				flush()
 			}
-			if include {
-				result.WriteString(line + "\n")
+			oldPath := strings.TrimPrefix(strings.TrimPrefix(line, "--- "), "a/")
+			newPath := strings.TrimPrefix(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+			sectionPath = newPath
+			if newPath == "/dev/null" {
+				sectionPath = oldPath
 			}
-		} else if include {
-			result.WriteString(line + "\n")
-		}
-	}
-
-	filtered := result.String()
-	if filtered == "" {
-		return diff
-	}
-	return filtered
-}
-
-func (s *Service) shouldIncludeFile(filePath string, extMap map[string]bool, ignoreList []string) bool {
-	for _, pattern := range ignoreList {
-		if s.matchIgnorePattern(filePath, pattern) {
-			return false
+			section = append(section, line, lines[i+1])
+			i++
+			inHeader = false
+		default:
+			if inHeader && !isExtendedHeader(line) {
+				inHeader = false
+			}
+			section = append(section, line)
 		}
 	}
+	flush()
 
-	if len(extMap) == 0 {
-		return true
+	if len(files) == 0 {
+		return diff, files
 	}
-
-	ext := strings.ToLower(filepath.Ext(filePath))
-	return extMap[ext]
+	return strings.TrimSuffix(result.String(), "\n"), files
 }
 
-func (s *Service) matchIgnorePattern(filePath, pattern string) bool {
-	pattern = strings.ToLower(pattern)
-	filePath = strings.ToLower(filePath)
+// diffExtendedHeaders start the lines git writes between "diff --git" and the ---/+++ lines
+var diffExtendedHeaders = []string{"index ", "old mode ", "new mode ", "deleted file mode ", "new file mode ",
+	"similarity index ", "dissimilarity index ", "rename from ", "rename to ", "copy from ", "copy to "}
 
-	if strings.HasSuffix(pattern, "/") {
-		dir := strings.TrimSuffix(pattern, "/")
-		if strings.HasPrefix(filePath, dir+"/") || strings.Contains(filePath, "/"+dir+"/") {
+func isExtendedHeader(line string) bool {
+	for _, prefix := range diffExtendedHeaders {
+		if strings.HasPrefix(line, prefix) {
 			return true
 		}
 	}
-
-	if strings.HasPrefix(pattern, "*") {
-		suffix := strings.TrimPrefix(pattern, "*")
-		if strings.HasSuffix(filePath, suffix) {
-			return true
-		}
-	}
-
-	if matched, _ := filepath.Match(pattern, filepath.Base(filePath)); matched {
-		return true
-	}
-
-	return strings.Contains(filePath, pattern)
+	return false
 }
 
 // isNullSHA checks if a SHA is all zeros (initial push, branch creation, etc.)
@@ -227,9 +193,16 @@ func isNullSHA(sha string) bool {
 	return true
 }
 
+// shortSHA abbreviates a commit SHA for logging
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
 // fetchRawDiff fetches a raw diff (non-JSON) from the given URL
 func (s *Service) fetchRawDiff(apiURL, token, tokenHeader string) (string, error) {
-	logger.Infof("[Webhook] Fetching raw diff from: %s", apiURL)
 
 	req, _ := http.NewRequest("GET", apiURL, nil)
 	if token != "" {
@@ -247,7 +220,7 @@ func (s *Service) fetchRawDiff(apiURL, token, tokenHeader string) (string, error
 		return "", err
 	}
 
-	logger.Infof("[Webhook] Raw diff API response status: %d, body length: %d", resp.StatusCode, len(body))
+	logger.Module("webhook").Info().Str("url", apiURL).Int("status", resp.StatusCode).Int("bytes", len(body)).Msg("Fetched raw diff")
 
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
@@ -256,10 +229,9 @@ func (s *Service) fetchRawDiff(apiURL, token, tokenHeader string) (string, error
 	return string(body), nil
 }
 
-func (s *Service) fetchDiff(apiURL, token, tokenHeader string) (string, error) {
-	logger.Infof("[Webhook] Fetching diff from: %s", apiURL)
+func (s *Service) fetchDiff(ctx context.Context, apiURL, token, tokenHeader string) (string, error) {
 
-	req, _ := http.NewRequest("GET", apiURL, nil)
+	req, _ := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if token != "" {
 		req.Header.Set(tokenHeader, token)
 	}
@@ -275,7 +247,7 @@ func (s *Service) fetchDiff(apiURL, token, tokenHeader string) (string, error) {
 		return "", err
 	}
 
-	logger.Infof("[Webhook] Diff API response status: %d, body length: %d", resp.StatusCode, len(body))
+	logger.For(ctx, "webhook").Info().Str("url", apiURL).Int("status", resp.StatusCode).Int("bytes", len(body)).Msg("Fetched diff")
 
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
@@ -340,7 +312,9 @@ func ParseDiffStats(diff string) (additions, deletions, filesChanged int) {
 func (s *Service) isCommitAlreadyReviewed(projectID uint, commitSHA string) bool {
 	var count int64
 	// Check for any existing review regardless of status (completed, pending, processing, analyzing)
-	// This prevents duplicate reviews when the same commit is pushed to multiple branches simultaneously
+	// This prevents duplicate reviews when the same commit is pushed to multiple branches simultaneously.
+	// Commits the coverage gap monitor recorded as missed are reviewed; their gap row is
+	// replaced by the review, see ReviewLogService.Create
 	s.db.Model(&models.ReviewLog{}).
 		Where("project_id = ? AND commit_hash = ? AND review_status IN ?", projectID, commitSHA, []string{"completed", "pending", "processing", "analyzing"}).
 		Count(&count)
@@ -1,7 +1,14 @@
 package webhook
 
 import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"github.com/huangang/codesentry/backend/internal/services"
 )
 
 func TestParseRepoInfo(t *testing.T) {
@@ -343,3 +350,121 @@ func containsPattern(patterns, pattern string) bool {
 	}
 	return false
 }
+
+func TestFilterDiffIgnorePatterns(t *testing.T) {
+	diff := "diff --git a/internal/latest/handler.go b/internal/latest/handler.go\n" +
+		"--- a/internal/latest/handler.go\n+++ b/internal/latest/handler.go\n+code\n" +
+		"diff --git a/api/service.pb.go b/api/service.pb.go\n" +
+		"--- a/api/service.pb.go\n+++ b/api/service.pb.go\n+generated\n" +
+		"diff --git a/deploy/app.yaml b/deploy/app.yaml\n" +
+		"--- a/deploy/app.yaml\n+++ b/deploy/app.yaml\n+replicas: 2\n"
+
+	s := &Service{}
+	got := s.filterDiff(diff, &models.Project{IgnorePatterns: "*.pb.go,test,!deploy/*.yaml"})
+
+	if !containsPattern(got, "+code") {
+		t.Error("handler.go should be kept: patterns no longer match substrings")
+	}
+	if containsPattern(got, "+generated") {
+		t.Error("service.pb.go should be ignored")
+	}
+	if !containsPattern(got, "+replicas: 2") {
+		t.Error("deploy/app.yaml should be re-included by the negated pattern")
+	}
+}
+
+func TestFilterDiffExplained(t *testing.T) {
+	diff := "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n+code\n" +
+		"diff --git a/api/service.pb.go b/api/service.pb.go\n--- a/api/service.pb.go\n+++ b/api/service.pb.go\n+generated\n" +
+		"diff --git a/README.md b/README.md\n--- a/README.md\n+++ b/README.md\n+docs\n"
+
+	filter := services.NewPathFilter(".go", "", services.NewIgnoreMatcher("*.pb.go"))
+	_, files := filterDiffExplained(diff, filter)
+
+	want := []services.PathPatternResult{
+		{Path: "main.go", Reviewed: true},
+		{Path: "api/service.pb.go", Reason: services.PathSkipIgnored, Pattern: "*.pb.go"},
+		{Path: "README.md", Reason: services.PathSkipExtension},
+	}
+	if len(files) != len(want) {
+		t.Fatalf("got %d files, want %d: %+v", len(files), len(want), files)
+	}
+	for i := range want {
+		if files[i] != want[i] {
+			t.Errorf("file %d = %+v, want %+v", i, files[i], want[i])
+		}
+	}
+
+	filtered, _ := filterDiffExplained(diff, services.NewPathFilter(".py", "", services.NewIgnoreMatcher()))
+	if !IsEmptyDiff(filtered) {
+		t.Errorf("a diff without reviewed files should filter to nothing, got %q", filtered)
+	}
+	if text := "not a unified diff"; func() string { got, _ := filterDiffExplained(text, filter); return got }() != text {
+		t.Error("text without file headers should be returned unchanged")
+	}
+}
+
+func TestFilterDiffExplained_Paths(t *testing.T) {
+	diff := "diff --git a/old.txt b/src/new.go\nsimilarity index 90%\nrename from old.txt\nrename to src/new.go\n--- a/old.txt\n+++ b/src/new.go\n+renamed\n" +
+		"diff --git a/gone.go b/gone.go\ndeleted file mode 100644\n--- a/gone.go\n+++ /dev/null\n-removed\n" +
+		"diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n" +
+		"--- a/notes.txt\n+++ b/notes.txt\n+-- not a header\n"
+
+	filtered, files := filterDiffExplained(diff, services.NewPathFilter(".go", "", services.NewIgnoreMatcher()))
+	want := []string{"src/new.go", "gone.go", "logo.png", "notes.txt"}
+	if len(files) != len(want) {
+		t.Fatalf("got files %+v, want %v", files, want)
+	}
+	for i, path := range want {
+		if files[i].Path != path {
+			t.Errorf("file %d = %q, want %q", i, files[i].Path, path)
+		}
+	}
+	for _, kept := range []string{"+renamed", "-removed"} {
+		if !strings.Contains(filtered, kept) {
+			t.Errorf("filtered diff misses %q:\n%s", kept, filtered)
+		}
+	}
+	for _, dropped := range []string{"logo.png", "notes.txt"} {
+		if strings.Contains(filtered, dropped) {
+			t.Errorf("filtered diff keeps %q:\n%s", dropped, filtered)
+		}
+	}
+}
+
+func TestSetCommitStatus_States(t *testing.T) {
+	tests := []struct {
+		platform string
+		state    string
+		want     string
+	}{
+		{"github", "error", "error"},
+		{"github", "failed", "failure"},
+		{"gitlab", "error", "failed"},
+		{"gitlab", "pending", "pending"},
+		{"bitbucket", "error", "FAILED"},
+		{"bitbucket", "success", "SUCCESSFUL"},
+		{"bitbucket", "INPROGRESS", "INPROGRESS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform+"/"+tt.state, func(t *testing.T) {
+			var got map[string]string
+			s := &Service{httpClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				json.NewDecoder(req.Body).Decode(&got)
+				return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+			})}}
+			project := &models.Project{Platform: tt.platform, URL: "https://example.com/group/repo"}
+			if tt.platform == "bitbucket" {
+				project.URL = "https://bitbucket.org/group/repo"
+			}
+			s.setCommitStatus(project, "abc123", tt.state, "AI Review", 0)
+			if got["state"] != tt.want {
+				t.Errorf("state = %q, want %q", got["state"], tt.want)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// WebhookReplayWindow is how long webhook delivery IDs are remembered and the maximum age
+// of webhook events whose payload carries an event time
+const WebhookReplayWindow = time.Hour
+
+// ClaimWebhookDelivery records a delivery ID of a project and reports whether it was not
+// received within WebhookReplayWindow. The unique index on the delivery makes a single
+// claim win when replicas receive the same delivery concurrently.
+func ClaimWebhookDelivery(db *gorm.DB, projectID uint, platform, deliveryID string, now time.Time) (bool, error) {
+	// Free the ID of a delivery received before the window that has not been cleaned up yet
+	if err := db.Where("project_id = ? AND platform = ? AND delivery_id = ? AND received_at <= ?", projectID, platform, deliveryID, now.Add(-WebhookReplayWindow)).
+		Delete(&models.WebhookDelivery{}).Error; err != nil {
+		return false, err
+	}
+
+	delivery := &models.WebhookDelivery{ProjectID: projectID, Platform: platform, DeliveryID: deliveryID, ReceivedAt: now}
+	if err := db.Create(delivery).Error; err != nil {
+		var count int64
+		if countErr := db.Model(&models.WebhookDelivery{}).
+			Where("project_id = ? AND platform = ? AND delivery_id = ?", projectID, platform, deliveryID).
+			Count(&count).Error; countErr == nil && count > 0 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
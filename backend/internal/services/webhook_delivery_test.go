@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/huangang/codesentry/backend/internal/models"
+)
+
+func TestClaimWebhookDelivery(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+
+	claim := func(projectID uint, platform, id string, at time.Time) bool {
+		t.Helper()
+		claimed, err := ClaimWebhookDelivery(db, projectID, platform, id, at)
+		if err != nil {
+			t.Fatalf("ClaimWebhookDelivery: %v", err)
+		}
+		return claimed
+	}
+
+	if !claim(1, "github", "abc", now) {
+		t.Fatal("first delivery was not claimed")
+	}
+	if claim(1, "github", "abc", now.Add(time.Minute)) {
+		t.Error("replayed delivery was claimed")
+	}
+	if !claim(2, "github", "abc", now) || !claim(1, "gitlab", "abc", now) {
+		t.Error("the same delivery ID of another project or platform was not claimed")
+	}
+	if !claim(1, "github", "abc", now.Add(WebhookReplayWindow+time.Minute)) {
+		t.Error("delivery received after the replay window was not claimed")
+	}
+
+	var count int64
+	db.Model(&models.WebhookDelivery{}).Where("project_id = ? AND platform = ? AND delivery_id = ?", 1, "github", "abc").Count(&count)
+	if count != 1 {
+		t.Errorf("stored %d rows for the delivery, want 1", count)
+	}
+}
@@ -10,7 +10,17 @@ import (
 	"github.com/huangang/codesentry/backend/internal/config"
 )
 
-// Worker processes async tasks from the queue
+// TaskWorker consumes review tasks of an async queue
+type TaskWorker interface {
+	// SetProcessor sets the function to process review tasks
+	SetProcessor(processor func(context.Context, *ReviewTask) error)
+	// Start begins processing tasks
+	Start() error
+	// Stop gracefully shuts down the worker
+	Stop()
+}
+
+// Worker processes async tasks from the asynq queue
 type Worker struct {
 	server    *asynq.Server
 	mux       *asynq.ServeMux
@@ -32,10 +42,15 @@ func NewWorker(cfg *config.RedisConfig) *Worker {
 		DB:       cfg.DB,
 	}
 
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = config.DefaultRedisConcurrency
+	}
+
 	server := asynq.NewServer(
 		redisOpt,
 		asynq.Config{
-			Concurrency: 10,
+			Concurrency: concurrency,
 			Queues: map[string]int{
 				"default": 1,
 			},
@@ -119,19 +134,38 @@ func (w *Worker) handleReviewTask(ctx context.Context, t *asynq.Task) error {
 
 // Global worker instance
 var (
-	globalWorker *Worker
+	globalWorker TaskWorker
 	workerOnce   sync.Once
 )
 
-// InitWorker initializes the global worker
-func InitWorker(cfg *config.RedisConfig) *Worker {
+// InitWorker initializes the global worker for the configured queue backend.
+// The stream, NATS and Kafka queues consume their own tasks; otherwise an asynq
+// worker is created. Returns nil when the in-process queue is used.
+func InitWorker(cfg *config.Config) TaskWorker {
 	workerOnce.Do(func() {
-		globalWorker = NewWorker(cfg)
+		switch queue := globalTaskQueue.(type) {
+		case *StreamQueue:
+			globalWorker = queue
+			return
+		case *NATSQueue:
+			globalWorker = queue
+			return
+		case *KafkaQueue:
+			globalWorker = queue
+			return
+		}
+		if !cfg.Redis.Enabled || cfg.Queue.Backend != "" || redisQueueBackend(&cfg.Redis) == config.QueueBackendStreams {
+			// The broker was unavailable at startup and the sync queue is used instead
+			return
+		}
+		if worker := NewWorker(&cfg.Redis); worker != nil {
+			globalWorker = worker
+		}
 	})
 	return globalWorker
 }
 
 // GetWorker returns the global worker instance
-func GetWorker() *Worker {
+func GetWorker() TaskWorker {
 	return globalWorker
 }
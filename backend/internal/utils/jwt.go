@@ -8,6 +8,7 @@ import (
 
 type Claims struct {
 	UserID   uint   `json:"user_id"`
+	TenantID uint   `json:"tenant_id,omitempty"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
 	jwt.RegisteredClaims
@@ -21,8 +22,14 @@ func SetJWTSecret(secret string) {
 
 // GenerateToken generates a JWT token for a user
 func GenerateToken(userID uint, username, role string, expireHours int) (string, error) {
+	return GenerateTenantToken(userID, 0, username, role, expireHours)
+}
+
+// GenerateTenantToken generates a JWT token for a user that belongs to a tenant
+func GenerateTenantToken(userID, tenantID uint, username, role string, expireHours int) (string, error) {
 	claims := Claims{
 		UserID:   userID,
+		TenantID: tenantID,
 		Username: username,
 		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -0,0 +1,279 @@
+// Package client is a typed Go client for the CodeSentry HTTP API, intended for
+// CI tooling and scripts. The full API is described by GET /api/openapi.json.
+//
+//	c := client.New("https://codesentry.example.com", client.WithToken(token))
+//	score, err := c.GetReviewScore(ctx, sha)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the CodeSentry API. It is safe for concurrent use once configured.
+type Client struct {
+	baseURL    string
+	token      string
+	tenantID   uint
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates requests with a bearer access token
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithTenant scopes requests of a platform admin to one tenant
+func WithTenant(tenantID uint) Option {
+	return func(c *Client) { c.tenantID = tenantID }
+}
+
+// WithHTTPClient replaces the default HTTP client (30s timeout)
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// New creates a client for the server at baseURL, e.g. https://codesentry.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is returned when the server responds with an error
+type APIError struct {
+	StatusCode int
+	Code       int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("codesentry: %s (status %d)", e.Message, e.StatusCode)
+}
+
+// envelope is the unified {code, message, data} response format
+type envelope struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Login authenticates with username and password and uses the returned token for later requests
+func (c *Client) Login(ctx context.Context, username, password string) (*LoginResult, error) {
+	body := map[string]string{"username": username, "password": password}
+	var result LoginResult
+	if err := c.do(ctx, http.MethodPost, "/api/auth/login", nil, body, nil, &result); err != nil {
+		return nil, err
+	}
+	c.token = result.Token
+	return &result, nil
+}
+
+// CurrentUser returns the authenticated user
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.do(ctx, http.MethodGet, "/api/auth/me", nil, nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListProjects returns one page of projects
+func (c *Client) ListProjects(ctx context.Context, opts *ListProjectsOptions) (*ProjectList, error) {
+	query := url.Values{}
+	if opts != nil {
+		setInt(query, "page", opts.Page)
+		setInt(query, "page_size", opts.PageSize)
+		setString(query, "name", opts.Name)
+		setString(query, "platform", opts.Platform)
+	}
+	var list ProjectList
+	if err := c.do(ctx, http.MethodGet, "/api/projects", query, nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetProject returns a project by ID
+func (c *Client) GetProject(ctx context.Context, id uint) (*Project, error) {
+	var project Project
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/projects/%d", id), nil, nil, nil, &project); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// ListReviewLogs returns one page of review logs
+func (c *Client) ListReviewLogs(ctx context.Context, opts *ListReviewLogsOptions) (*ReviewLogList, error) {
+	query := url.Values{}
+	if opts != nil {
+		setInt(query, "page", opts.Page)
+		setInt(query, "page_size", opts.PageSize)
+		setInt(query, "project_id", int(opts.ProjectID))
+		setString(query, "event_type", opts.EventType)
+		setString(query, "author", opts.Author)
+		setString(query, "review_status", opts.ReviewStatus)
+		setString(query, "search_text", opts.SearchText)
+		setString(query, "cursor", opts.Cursor)
+	}
+	var list ReviewLogList
+	if err := c.do(ctx, http.MethodGet, "/api/review-logs", query, nil, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetReviewLog returns a review log by ID, including the review result
+func (c *Client) GetReviewLog(ctx context.Context, id uint) (*ReviewLog, error) {
+	var log ReviewLog
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/review-logs/%d", id), nil, nil, nil, &log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// RetryReview queues a failed review for another attempt (admin only)
+func (c *Client) RetryReview(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/review-logs/%d/retry", id), nil, nil, nil, nil)
+}
+
+// GetLatestReview returns the most recent review of a branch, or of a merge request if mrNumber > 0
+func (c *Client) GetLatestReview(ctx context.Context, projectID uint, branch string, mrNumber int) (*LatestReview, error) {
+	query := url.Values{}
+	if mrNumber > 0 {
+		query.Set("mr_number", strconv.Itoa(mrNumber))
+	} else {
+		query.Set("branch", branch)
+	}
+	var latest LatestReview
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/projects/%d/reviews/latest", projectID), query, nil, nil, &latest); err != nil {
+		return nil, err
+	}
+	return &latest, nil
+}
+
+// GetReviewScore returns the review status and score of a commit
+func (c *Client) GetReviewScore(ctx context.Context, commitSHA string) (*ReviewScore, error) {
+	query := url.Values{"commit_sha": {commitSHA}}
+	var score ReviewScore
+	if err := c.do(ctx, http.MethodGet, "/api/review/score", query, nil, nil, &score); err != nil {
+		return nil, err
+	}
+	return &score, nil
+}
+
+// GetReviewStatus returns the outcome of a webhook sent with ?wait=true.
+// Poll until Done is set; Passed then tells whether the change may proceed.
+func (c *Client) GetReviewStatus(ctx context.Context, token string) (*ReviewStatus, error) {
+	var status ReviewStatus
+	if err := c.do(ctx, http.MethodGet, "/api/review/status/"+url.PathEscape(token), nil, nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SyncReview reviews a diff synchronously and returns whether it passes the project's minimum score.
+// apiKey is the project's webhook secret; pass "" if the project has none.
+func (c *Client) SyncReview(ctx context.Context, apiKey string, req *SyncReviewRequest) (*SyncReviewResult, error) {
+	var header http.Header
+	if apiKey != "" {
+		header = http.Header{"X-API-Key": {apiKey}}
+	}
+	var result SyncReviewResult
+	if err := c.do(ctx, http.MethodPost, "/api/review/sync", nil, req, header, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// do sends a request and decodes the data field of the response envelope into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body interface{}, header http.Header, out interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.tenantID > 0 {
+		req.Header.Set("X-Tenant-ID", strconv.FormatUint(uint64(c.tenantID), 10))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(respBody, &env); err != nil {
+		if resp.StatusCode >= 400 {
+			return &APIError{StatusCode: resp.StatusCode, Code: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return fmt.Errorf("codesentry: invalid response: %w", err)
+	}
+	if resp.StatusCode >= 400 || env.Code != 0 {
+		return &APIError{StatusCode: resp.StatusCode, Code: env.Code, Message: env.Message}
+	}
+
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+func setString(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setInt(query url.Values, key string, value int) {
+	if value > 0 {
+		query.Set(key, strconv.Itoa(value))
+	}
+}
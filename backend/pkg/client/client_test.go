@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return New(srv.URL + "/")
+}
+
+func writeEnvelope(w http.ResponseWriter, status, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "message": message, "data": data})
+}
+
+func TestLoginStoresToken(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/login":
+			writeEnvelope(w, 200, 0, "ok", map[string]interface{}{"token": "abc", "user": map[string]interface{}{"id": 1, "username": "admin"}})
+		case "/api/auth/me":
+			if r.Header.Get("Authorization") != "Bearer abc" {
+				writeEnvelope(w, 401, 401, "authorization required", nil)
+				return
+			}
+			writeEnvelope(w, 200, 0, "ok", map[string]interface{}{"id": 1, "username": "admin"})
+		}
+	})
+
+	result, err := c.Login(context.Background(), "admin", "secret")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if result.Token != "abc" || result.User.Username != "admin" {
+		t.Errorf("unexpected login result: %+v", result)
+	}
+
+	user, err := c.CurrentUser(context.Background())
+	if err != nil {
+		t.Fatalf("CurrentUser() error = %v", err)
+	}
+	if user.ID != 1 {
+		t.Errorf("expected user 1, got %d", user.ID)
+	}
+}
+
+func TestListReviewLogsQuery(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("project_id") != "3" || q.Get("cursor") != "first" || q.Has("author") {
+			t.Errorf("unexpected query: %s", r.URL.RawQuery)
+		}
+		writeEnvelope(w, 200, 0, "ok", map[string]interface{}{
+			"total":       1,
+			"items":       []map[string]interface{}{{"id": 7, "commit_hash": "deadbeef", "score": 85}},
+			"next_cursor": "next",
+		})
+	})
+
+	list, err := c.ListReviewLogs(context.Background(), &ListReviewLogsOptions{ProjectID: 3, Cursor: "first"})
+	if err != nil {
+		t.Fatalf("ListReviewLogs() error = %v", err)
+	}
+	if len(list.Items) != 1 || *list.Items[0].Score != 85 || list.NextCursor != "next" {
+		t.Errorf("unexpected list: %+v", list)
+	}
+}
+
+func TestSyncReviewHeaders(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "key" || r.Header.Get("X-Tenant-ID") != "2" {
+			t.Errorf("missing headers: %v", r.Header)
+		}
+		var req SyncReviewRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		writeEnvelope(w, 200, 0, "ok", map[string]interface{}{"passed": req.CommitSHA == "abc", "score": 90})
+	})
+	WithTenant(2)(c)
+
+	result, err := c.SyncReview(context.Background(), "key", &SyncReviewRequest{ProjectURL: "u", CommitSHA: "abc", Diffs: "d"})
+	if err != nil {
+		t.Fatalf("SyncReview() error = %v", err)
+	}
+	if !result.Passed || result.Score != 90 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name: "envelope error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				writeEnvelope(w, 404, 404, "review not found for commit: x", nil)
+			},
+			wantCode: 404,
+			wantMsg:  "review not found for commit: x",
+		},
+		{
+			name: "non-JSON error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "bad gateway", http.StatusBadGateway)
+			},
+			wantCode: 502,
+			wantMsg:  "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestServer(t, tt.handler)
+			_, err := c.GetReviewScore(context.Background(), "x")
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %v", err)
+			}
+			if apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMsg {
+				t.Errorf("got code %d message %q", apiErr.Code, apiErr.Message)
+			}
+		})
+	}
+}
+
+func TestGetLatestReviewQuery(t *testing.T) {
+	c := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/projects/4/reviews/latest" || r.URL.Query().Get("mr_number") != "12" || r.URL.Query().Has("branch") {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		writeEnvelope(w, 200, 0, "ok", map[string]interface{}{"review": map[string]interface{}{"id": 9, "mr_number": 12}, "min_score": 60, "passed": false})
+	})
+
+	latest, err := c.GetLatestReview(context.Background(), 4, "main", 12)
+	if err != nil {
+		t.Fatalf("GetLatestReview() error = %v", err)
+	}
+	if latest.Review.ID != 9 || latest.Passed == nil || *latest.Passed {
+		t.Errorf("unexpected result: %+v", latest)
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import "time"
+
+// User is a CodeSentry user
+type User struct {
+	ID        uint       `json:"id"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Nickname  string     `json:"nickname"`
+	Role      string     `json:"role"`
+	AuthType  string     `json:"auth_type"`
+	IsActive  bool       `json:"is_active"`
+	TenantID  uint       `json:"tenant_id"`
+	LastLogin *time.Time `json:"last_login"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoginResult is returned by Login
+type LoginResult struct {
+	Token    string    `json:"token"`
+	User     *User     `json:"user"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// Project is a repository registered for review
+type Project struct {
+	ID               uint      `json:"id"`
+	Name             string    `json:"name"`
+	URL              string    `json:"url"`
+	Platform         string    `json:"platform"`
+	FileExtensions   string    `json:"file_extensions"`
+	ReviewEvents     string    `json:"review_events"`
+	BranchFilter     string    `json:"branch_filter"`
+	BranchFilterMode string    `json:"branch_filter_mode"`
+	AIEnabled        bool      `json:"ai_enabled"`
+	AIPromptID       *uint     `json:"ai_prompt_id"`
+	LLMConfigID      *uint     `json:"llm_config_id"`
+	IgnorePatterns   string    `json:"ignore_patterns"`
+	CommentEnabled   bool      `json:"comment_enabled"`
+	IMEnabled        bool      `json:"im_enabled"`
+	IMBotID          *uint     `json:"im_bot_id"`
+	ReleaseIMBotID   *uint     `json:"release_im_bot_id"`
+	MinScore         float64   `json:"min_score"`
+	MaxChangedLines  int       `json:"max_changed_lines"`
+	MaxFiles         int       `json:"max_files"`
+	MaxDiffBytes     int       `json:"max_diff_bytes"`
+	MaxFileBytes     int       `json:"max_file_bytes"`
+	BadgeEnabled     bool      `json:"badge_enabled"`
+	ReplayProtection bool      `json:"replay_protection"`
+	SignaturePolicy  string    `json:"signature_policy"`
+	SignedBranches   string    `json:"signed_branches"`
+	TenantID         uint      `json:"tenant_id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// ProjectList is one page of projects
+type ProjectList struct {
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+	Items    []Project `json:"items"`
+}
+
+// ListProjectsOptions filters ListProjects
+type ListProjectsOptions struct {
+	Page     int
+	PageSize int
+	Name     string
+	Platform string
+}
+
+// ReviewLog is the review of one commit or merge request
+type ReviewLog struct {
+	ID            uint      `json:"id"`
+	ProjectID     uint      `json:"project_id"`
+	Project       *Project  `json:"project,omitempty"`
+	EventType     string    `json:"event_type"`
+	CommitHash    string    `json:"commit_hash"`
+	CommitURL     string    `json:"commit_url"`
+	Branch        string    `json:"branch"`
+	Author        string    `json:"author"`
+	AuthorEmail   string    `json:"author_email"`
+	CommitMessage string    `json:"commit_message"`
+	FilesChanged  int       `json:"files_changed"`
+	Additions     int       `json:"additions"`
+	Deletions     int       `json:"deletions"`
+	Score         *float64  `json:"score"`
+	ReviewResult  string    `json:"review_result"`
+	ReviewStatus  string    `json:"review_status"` // pending, processing, analyzing, completed, failed, skipped
+	ErrorMessage  string    `json:"error_message"`
+	RetryCount    int       `json:"retry_count"`
+	IsManual      bool      `json:"is_manual"`
+	MRNumber      *int      `json:"mr_number"`
+	MRURL         string    `json:"mr_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// LatestReview is the current review state of a branch or merge request
+type LatestReview struct {
+	Review   *ReviewLog `json:"review"`
+	MinScore float64    `json:"min_score"`
+	Passed   *bool      `json:"passed"` // nil while the review is in progress or failed
+}
+
+// ReviewLogList is one page of review logs
+type ReviewLogList struct {
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	Items      []ReviewLog `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// ListReviewLogsOptions filters ListReviewLogs. Set Cursor to "first" to use
+// keyset pagination, then pass NextCursor of each page.
+type ListReviewLogsOptions struct {
+	Page         int
+	PageSize     int
+	ProjectID    uint
+	EventType    string
+	Author       string
+	ReviewStatus string
+	SearchText   string
+	Cursor       string
+}
+
+// ReviewScore is the review status of a commit
+type ReviewScore struct {
+	CommitSHA string   `json:"commit_sha"`
+	Status    string   `json:"status"`
+	Score     *float64 `json:"score,omitempty"`
+	MinScore  float64  `json:"min_score,omitempty"`
+	Passed    *bool    `json:"passed,omitempty"`
+	ReviewID  uint     `json:"review_id"`
+	Message   string   `json:"message"`
+}
+
+// ReviewStatus is the outcome of a webhook received in wait mode
+type ReviewStatus struct {
+	ReviewScore
+	Token     string `json:"token"`
+	ProjectID uint   `json:"project_id"`
+	Done      bool   `json:"done"`
+}
+
+// SyncReviewRequest asks for a synchronous review of a diff
+type SyncReviewRequest struct {
+	ProjectURL string `json:"project_url"`
+	CommitSHA  string `json:"commit_sha"`
+	Ref        string `json:"ref,omitempty"`
+	Author     string `json:"author,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Diffs      string `json:"diffs"`
+}
+
+// SyncReviewResult is the outcome of a synchronous review
+type SyncReviewResult struct {
+	Passed      bool    `json:"passed"`
+	Score       float64 `json:"score"`
+	MinScore    float64 `json:"min_score"`
+	Message     string  `json:"message"`
+	ReviewID    uint    `json:"review_id,omitempty"`
+	FullContent string  `json:"full_content,omitempty"`
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"os"
 	"time"
@@ -68,6 +69,47 @@ func Fatalf(format string, v ...interface{}) {
 	log.Fatal().Msgf(format, v...)
 }
 
+// RequestIDHeader is the header carrying the request ID of API requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying a request (correlation) ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Ctx returns the logger with the request ID carried by ctx, if any, for tracing
+// one request across webhook processing, queued tasks, AI calls and notifications.
+func Ctx(ctx context.Context) *zerolog.Logger {
+	l := log
+	if id := RequestID(ctx); id != "" {
+		l = log.With().Str("request_id", id).Logger()
+	}
+	return &l
+}
+
+// Module returns the logger of a module; its entries carry the module in a "module" field.
+func Module(module string) *zerolog.Logger {
+	l := log.With().Str("module", module).Logger()
+	return &l
+}
+
+// For returns the logger of a module with the request ID carried by ctx, if any.
+func For(ctx context.Context, module string) *zerolog.Logger {
+	l := Ctx(ctx).With().Str("module", module).Logger()
+	return &l
+}
+
 // Get returns the underlying zerolog.Logger for advanced usage.
 func Get() zerolog.Logger {
 	return log
@@ -92,6 +134,9 @@ func GinLogger() gin.HandlerFunc {
 			event = log.Warn()
 		}
 
+		if id := RequestID(c.Request.Context()); id != "" {
+			event = event.Str("request_id", id)
+		}
 		event.
 			Int("status", status).
 			Str("method", c.Request.Method).
@@ -108,6 +153,7 @@ func GinLogger() gin.HandlerFunc {
 func GinRecovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
 		log.Error().
+			Str("request_id", RequestID(c.Request.Context())).
 			Interface("panic", recovered).
 			Str("method", c.Request.Method).
 			Str("path", c.Request.URL.Path).
@@ -5,13 +5,15 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 )
 
 // Response is the unified API response format.
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int         `json:"code"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data,omitempty"`
+	RequestID string      `json:"request_id,omitempty"` // Set on errors, to find the request in the logs
 }
 
 // AppError represents a structured application error with HTTP status and error code.
@@ -71,41 +73,53 @@ func Created(c *gin.Context, data interface{}) {
 	})
 }
 
+// Accepted sends a 202 Accepted response with data, for work that will be done later.
+func Accepted(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusAccepted, Response{
+		Code:    0,
+		Message: "accepted",
+		Data:    data,
+	})
+}
+
 // Error sends an error response. If err is an *AppError, its code and status
 // are used; otherwise a generic 500 internal server error is returned.
 func Error(c *gin.Context, err error) {
 	var appErr *AppError
 	if errors.As(err, &appErr) {
-		c.JSON(appErr.HTTPStatus, Response{
-			Code:    appErr.Code,
-			Message: appErr.Message,
-		})
+		Fail(c, appErr.HTTPStatus, appErr.Code, appErr.Message)
 		return
 	}
-	c.JSON(http.StatusInternalServerError, Response{
-		Code:    500,
-		Message: err.Error(),
-	})
+	Fail(c, http.StatusInternalServerError, 500, err.Error())
+}
+
+// Fail sends an error response with the request ID of the request.
+func Fail(c *gin.Context, status, code int, msg string) {
+	var requestID string
+	if c.Request != nil {
+		requestID = logger.RequestID(c.Request.Context())
+	}
+	c.JSON(status, Response{Code: code, Message: msg, RequestID: requestID})
 }
 
 // Convenience error response functions
 
 func BadRequest(c *gin.Context, msg string) {
-	c.JSON(http.StatusBadRequest, Response{Code: 400, Message: msg})
+	Fail(c, http.StatusBadRequest, 400, msg)
 }
 
 func Unauthorized(c *gin.Context, msg string) {
-	c.JSON(http.StatusUnauthorized, Response{Code: 401, Message: msg})
+	Fail(c, http.StatusUnauthorized, 401, msg)
 }
 
 func Forbidden(c *gin.Context, msg string) {
-	c.JSON(http.StatusForbidden, Response{Code: 403, Message: msg})
+	Fail(c, http.StatusForbidden, 403, msg)
 }
 
 func NotFound(c *gin.Context, msg string) {
-	c.JSON(http.StatusNotFound, Response{Code: 404, Message: msg})
+	Fail(c, http.StatusNotFound, 404, msg)
 }
 
 func ServerError(c *gin.Context, msg string) {
-	c.JSON(http.StatusInternalServerError, Response{Code: 500, Message: msg})
+	Fail(c, http.StatusInternalServerError, 500, msg)
 }
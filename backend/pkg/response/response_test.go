@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/huangang/codesentry/backend/pkg/logger"
 )
 
 func init() {
@@ -64,6 +65,21 @@ func TestCreated(t *testing.T) {
 	}
 }
 
+func TestAccepted(t *testing.T) {
+	w := performRequest(func(c *gin.Context) {
+		Accepted(c, map[string]bool{"deferred": true})
+	})
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	resp := parseResponse(t, w)
+	if resp.Code != 0 || resp.Message != "accepted" {
+		t.Errorf("expected code 0 and message accepted, got %d %q", resp.Code, resp.Message)
+	}
+}
+
 func TestBadRequest(t *testing.T) {
 	w := performRequest(func(c *gin.Context) {
 		BadRequest(c, "invalid input")
@@ -182,3 +198,22 @@ func TestAppError_ErrorInterface(t *testing.T) {
 		t.Errorf("expected 'user not found', got %q", err.Error())
 	}
 }
+
+func TestErrorIncludesRequestID(t *testing.T) {
+	w := performRequest(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), "req-123"))
+		BadRequest(c, "bad input")
+	})
+
+	if resp := parseResponse(t, w); resp.RequestID != "req-123" {
+		t.Errorf("expected request_id 'req-123', got %q", resp.RequestID)
+	}
+
+	w = performRequest(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), "req-123"))
+		Success(c, nil)
+	})
+	if resp := parseResponse(t, w); resp.RequestID != "" {
+		t.Errorf("expected no request_id on success, got %q", resp.RequestID)
+	}
+}
@@ -5,6 +5,8 @@ export const REVIEW_STATUS = {
   COMPLETED: 'completed',
   FAILED: 'failed',
   SKIPPED: 'skipped',
+  NEEDS_ATTENTION: 'needs_attention',
+  DIFF_FETCH_FAILED: 'diff_fetch_failed',
 } as const;
 
 export type ReviewStatus = typeof REVIEW_STATUS[keyof typeof REVIEW_STATUS];
@@ -6,6 +6,7 @@ export interface ProjectFilters {
     page_size?: number;
     name?: string;
     platform?: string;
+    status?: 'active' | 'archived' | 'all';
 }
 
 // Query keys
@@ -109,6 +110,20 @@ export function useUpdateProject() {
     });
 }
 
+export function useArchiveProject() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async ({ id, archived }: { id: number; archived: boolean }) => {
+            const res = archived ? await projectApi.archive(id) : await projectApi.unarchive(id);
+            return res.data;
+        },
+        onSuccess: (_, variables) => {
+            queryClient.invalidateQueries({ queryKey: projectKeys.lists() });
+            queryClient.invalidateQueries({ queryKey: projectKeys.detail(variables.id) });
+        },
+    });
+}
+
 export function useDeleteProject() {
     const queryClient = useQueryClient();
     return useMutation({
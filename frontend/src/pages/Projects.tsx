@@ -29,6 +29,8 @@ import {
   CopyOutlined,
   UploadOutlined,
   TeamOutlined,
+  InboxOutlined,
+  RollbackOutlined,
 } from '@ant-design/icons';
 import type { ColumnsType } from 'antd/es/table';
 import { useTranslation } from 'react-i18next';
@@ -39,6 +41,7 @@ import {
   useCreateProject,
   useUpdateProject,
   useDeleteProject,
+  useArchiveProject,
   useDefaultPrompt,
   useActiveImBots,
   useActivePromptTemplates,
@@ -72,6 +75,7 @@ const Projects: React.FC = () => {
   const createProject = useCreateProject();
   const updateProject = useUpdateProject();
   const deleteProject = useDeleteProject();
+  const archiveProject = useArchiveProject();
 
   const modal = useModal<Project>();
   const [promptDrawerVisible, setPromptDrawerVisible] = useState(false);
@@ -291,6 +295,15 @@ const Projects: React.FC = () => {
     }
   };
 
+  const handleArchive = async (id: number, archived: boolean) => {
+    try {
+      await archiveProject.mutateAsync({ id, archived });
+      message.success(archived ? t('projects.archiveSuccess', 'Project archived') : t('projects.unarchiveSuccess', 'Project restored'));
+    } catch (error: any) {
+      message.error(error.response?.data?.error || t('common.error'));
+    }
+  };
+
   const copyWebhookUrl = (record: Project) => {
     const url = getWebhookUrl(record);
     navigator.clipboard.writeText(url);
@@ -340,6 +353,12 @@ const Projects: React.FC = () => {
       key: 'name',
       width: 150,
       ellipsis: true,
+      render: (name: string, record) => (
+        <Space size={4}>
+          {name}
+          {record.archived && <Tag>{t('projects.archived', 'Archived')}</Tag>}
+        </Space>
+      ),
     },
     {
       title: t('projects.platform'),
@@ -382,7 +401,7 @@ const Projects: React.FC = () => {
     {
       title: t('common.actions'),
       key: 'action',
-      width: 160,
+      width: 190,
       render: (_, record) => (
         <Space>
           {isAdmin && (
@@ -408,6 +427,17 @@ const Projects: React.FC = () => {
               <Button type="link" size="small" icon={<UploadOutlined />} onClick={() => showManualModal(record.id)} />
             </Tooltip>
           )}
+          {isAdmin && (record.archived ? (
+            <Tooltip title={t('projects.unarchive', 'Restore')}>
+              <Button type="link" size="small" icon={<RollbackOutlined />} onClick={() => handleArchive(record.id, false)} />
+            </Tooltip>
+          ) : (
+            <Popconfirm title={t('projects.archiveConfirm', 'Archive this project? Its webhooks will be ignored and its history kept.')} onConfirm={() => handleArchive(record.id, true)}>
+              <Tooltip title={t('projects.archive', 'Archive')}>
+                <Button type="link" size="small" icon={<InboxOutlined />} />
+              </Tooltip>
+            </Popconfirm>
+          ))}
           {isAdmin && (
             <Popconfirm title={t('projects.deleteConfirm')} onConfirm={() => handleDelete(record.id)}>
               <Button type="link" size="small" danger icon={<DeleteOutlined />} />
@@ -429,6 +459,16 @@ const Projects: React.FC = () => {
             onChange={(e) => setSearchName(e.target.value)}
             onPressEnter={handleSearch}
           />
+          <Select
+            style={{ width: 140 }}
+            value={filters.status ?? 'active'}
+            onChange={(status) => setFilters(prev => ({ ...prev, page: 1, status }))}
+            options={[
+              { value: 'active', label: t('projects.statusActive', 'Active') },
+              { value: 'archived', label: t('projects.archived', 'Archived') },
+              { value: 'all', label: t('projects.statusAll', 'All') },
+            ]}
+          />
           <Button type="primary" icon={<SearchOutlined />} onClick={handleSearch}>
             {t('common.search')}
           </Button>
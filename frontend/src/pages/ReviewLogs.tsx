@@ -1,4 +1,5 @@
-import React, { useState, useCallback } from 'react';
+import React, { useState, useCallback, useEffect } from 'react';
+import { useSearchParams } from 'react-router-dom';
 import { useQueryClient } from '@tanstack/react-query';
 import {
   Card,
@@ -20,6 +21,7 @@ import {
   Avatar,
   Spin,
   Tooltip,
+  Modal,
 } from 'antd';
 import { SearchOutlined, ReloadOutlined, EyeOutlined, LinkOutlined, DeleteOutlined, SendOutlined, CommentOutlined, CheckCircleOutlined, CloseCircleOutlined, QuestionCircleOutlined, InfoCircleOutlined, DownloadOutlined, EditOutlined, ToolOutlined } from '@ant-design/icons';
 import type { ColumnsType } from 'antd/es/table';
@@ -185,6 +187,7 @@ const ReviewLogs: React.FC = () => {
   const { data: projectsData } = useProjects({ page_size: 100 });
   const retryReview = useRetryReview();
   const deleteReviewLog = useDeleteReviewLog();
+  const [searchParams, setSearchParams] = useSearchParams();
   const updateScore = useUpdateScore();
   const queryClient = useQueryClient();
 
@@ -325,9 +328,33 @@ const ReviewLogs: React.FC = () => {
   };
 
   const canRetry = (status: string) => {
-    return status === REVIEW_STATUS.FAILED;
+    return status === REVIEW_STATUS.FAILED || status === REVIEW_STATUS.NEEDS_ATTENTION || status === REVIEW_STATUS.DIFF_FETCH_FAILED;
   };
 
+  // Notification links open a review with ?id=N, and ask to retry it with &action=retry
+  useEffect(() => {
+    const id = Number(searchParams.get('id'));
+    if (!id) return;
+    const action = searchParams.get('action');
+    setSearchParams({}, { replace: true });
+    reviewLogApi.getById(id).then(res => {
+      const log = res.data;
+      showDetail(log);
+      if (action !== 'retry') return;
+      if (!isAdmin || !canRetry(log.review_status)) {
+        message.info(t('reviewLogs.retryNotAvailable', 'This review cannot be retried'));
+        return;
+      }
+      Modal.confirm({
+        title: t('reviewLogs.retryConfirm', 'Retry this review?'),
+        okText: t('common.yes'),
+        cancelText: t('common.no'),
+        onOk: () => handleRetry(log.id),
+      });
+    }).catch(() => message.error(t('reviewLogs.notFound', 'Review not found')));
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [searchParams]);
+
   const columns: ColumnsType<ReviewLog> = [
     {
       title: t('reviewLogs.project'),
@@ -641,7 +668,7 @@ const ReviewLogs: React.FC = () => {
                     {t('reviewLogs.retryCount', 'Retries')}: {selectedLog.retry_count}/3
                   </Tag>
                 )}
-                {selectedLog.review_status === REVIEW_STATUS.FAILED && (
+                {isAdmin && canRetry(selectedLog.review_status) && (
                   <Button
                     type="link"
                     size="small"
@@ -37,7 +37,7 @@ export const dashboardApi = {
 
 // Projects
 export const projectApi = {
-  list: (params?: { page?: number; page_size?: number; name?: string; platform?: string }) =>
+  list: (params?: { page?: number; page_size?: number; name?: string; platform?: string; status?: 'active' | 'archived' | 'all' }) =>
     api.get<PaginatedResponse<Project>>('/projects', { params }),
 
   getById: (id: number) => api.get<Project>(`/projects/${id}`),
@@ -50,6 +50,10 @@ export const projectApi = {
 
   delete: (id: number) => api.delete(`/projects/${id}`),
 
+  archive: (id: number) => api.post<Project>(`/projects/${id}/archive`),
+
+  unarchive: (id: number) => api.post<Project>(`/projects/${id}/unarchive`),
+
   getDefaultPrompt: () => api.get<{ prompt: string }>('/projects/default-prompt'),
 };
 
@@ -34,6 +34,8 @@ export interface Project {
   created_at: string;
   updated_at: string;
   min_score: number;
+  archived: boolean;
+  archived_at: string | null;
 }
 
 export interface ReviewLog {